@@ -8,6 +8,7 @@ import (
 
 	"github.com/spf13/cobra"
 
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/aws"
 	"github.com/scttfrdmn/aws-research-wizard/go/internal/config"
 	"github.com/scttfrdmn/aws-research-wizard/go/internal/tui"
 )
@@ -34,7 +35,11 @@ This tool provides:
 
 	// Add flags
 	rootCmd.PersistentFlags().StringVar(&configRoot, "config", "", "Configuration root directory (default: find configs/)")
-	rootCmd.PersistentFlags().StringVar(&region, "region", "us-east-1", "AWS region")
+	rootCmd.PersistentFlags().StringVar(&region, "region", "", "AWS region (default: AWS_REGION or AWS_DEFAULT_REGION, then the profile's region)")
+
+	rootCmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		return resolveRegion(cmd)
+	}
 
 	// Add subcommands
 	rootCmd.AddCommand(
@@ -48,6 +53,23 @@ This tool provides:
 	}
 }
 
+// resolveRegion settles --region the way the main binary does: the flag when
+// given, else the environment or the profile
+func resolveRegion(cmd *cobra.Command) error {
+	flag := ""
+	if cmd.Flags().Changed("region") {
+		flag = region
+	}
+
+	resolved, err := aws.RegionResolver{}.Resolve(cmd.Context(), flag)
+	if err != nil {
+		return err
+	}
+	region = resolved.Region
+	aws.SetResolvedRegion(resolved)
+	return nil
+}
+
 func runInteractiveConfig(cmd *cobra.Command, args []string) {
 	// Find config root if not specified
 	if configRoot == "" {
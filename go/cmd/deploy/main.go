@@ -42,13 +42,17 @@ This deployment tool provides:
 
 	// Add flags
 	rootCmd.PersistentFlags().StringVar(&configRoot, "config", "", "Configuration root directory")
-	rootCmd.PersistentFlags().StringVar(&region, "region", "us-east-1", "AWS region")
+	rootCmd.PersistentFlags().StringVar(&region, "region", "", "AWS region (default: AWS_REGION or AWS_DEFAULT_REGION, then the profile's region)")
 	rootCmd.PersistentFlags().StringVar(&stackName, "stack", "", "CloudFormation stack name")
 	rootCmd.PersistentFlags().StringVar(&domainName, "domain", "", "Research domain name")
 	rootCmd.PersistentFlags().StringVar(&instanceType, "instance", "", "EC2 instance type")
 	rootCmd.PersistentFlags().BoolVar(&dryRun, "dry-run", false, "Show deployment plan without executing")
 	rootCmd.PersistentFlags().DurationVar(&timeout, "timeout", 30*time.Minute, "Deployment timeout")
 
+	rootCmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		return resolveRegion(cmd)
+	}
+
 	// Add subcommands
 	rootCmd.AddCommand(
 		createDeployCommand(),
@@ -63,6 +67,26 @@ This deployment tool provides:
 	}
 }
 
+// resolveRegion settles --region the way the main binary does: the flag when
+// given, else the environment, the profile, or a prompt. The region is
+// announced so a region picked up from the wrong place is noticed.
+func resolveRegion(cmd *cobra.Command) error {
+	flag := ""
+	if cmd.Flags().Changed("region") {
+		flag = region
+	}
+
+	resolver := aws.RegionResolver{Prompt: aws.TerminalRegionPrompt(os.Stderr)}
+	resolved, err := resolver.Resolve(cmd.Context(), flag)
+	if err != nil {
+		return err
+	}
+	region = resolved.Region
+	aws.SetResolvedRegion(resolved)
+	fmt.Fprintf(os.Stderr, "Region: %s\n", resolved)
+	return nil
+}
+
 func runInteractiveDeploy(cmd *cobra.Command, args []string) {
 	ctx := context.Background()
 
@@ -7,11 +7,15 @@ import (
 
 	"github.com/spf13/cobra"
 
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/aws"
 	"github.com/scttfrdmn/aws-research-wizard/go/internal/commands/config"
 	"github.com/scttfrdmn/aws-research-wizard/go/internal/commands/data"
 	"github.com/scttfrdmn/aws-research-wizard/go/internal/commands/deploy"
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/commands/doctor"
 	"github.com/scttfrdmn/aws-research-wizard/go/internal/commands/gui"
 	"github.com/scttfrdmn/aws-research-wizard/go/internal/commands/monitor"
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/commands/recommend"
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/commands/tutorial"
 )
 
 var (
@@ -21,6 +25,13 @@ var (
 )
 
 func main() {
+	if err := newRootCommand().Execute(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// newRootCommand builds the command tree with its global flags
+func newRootCommand() *cobra.Command {
 	var rootCmd = &cobra.Command{
 		Use:   "aws-research-wizard",
 		Short: "AWS Research Wizard - Complete research environment management",
@@ -45,19 +56,79 @@ Perfect for:
 	}
 
 	// Global flags
-	rootCmd.PersistentFlags().String("region", "us-east-1", "AWS region")
+	rootCmd.PersistentFlags().String("region", "", "AWS region (default: AWS_REGION or AWS_DEFAULT_REGION, then the profile's region)")
 	rootCmd.PersistentFlags().String("config-root", "", "Configuration root directory")
-	rootCmd.PersistentFlags().Bool("debug", false, "Enable debug logging")
+	rootCmd.PersistentFlags().Bool("debug", false, "Enable debug logging and print AWS API call statistics on exit")
+	rootCmd.PersistentFlags().Int("aws-max-attempts", aws.DefaultMaxAttempts, "Maximum attempts per AWS API call, with adaptive backoff on throttling")
+	rootCmd.PersistentFlags().String("assume-role", "", "ARN of an IAM role to assume for all AWS calls, e.g. a deployer role in another account")
+	rootCmd.PersistentFlags().String("external-id", "", "External ID required by the assumed role's trust policy")
+	rootCmd.PersistentFlags().String("role-session-name", "", "Session name for the assumed role (default: aws-research-wizard-<user>)")
+
+	rootCmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		maxAttempts, _ := cmd.Flags().GetInt("aws-max-attempts")
+		aws.SetRetryOptions(aws.RetryOptions{MaxAttempts: maxAttempts})
+
+		if err := resolveRegion(cmd); err != nil {
+			return err
+		}
+
+		roleARN, _ := cmd.Flags().GetString("assume-role")
+		if roleARN == "" {
+			return nil
+		}
+		externalID, _ := cmd.Flags().GetString("external-id")
+		sessionName, _ := cmd.Flags().GetString("role-session-name")
+		return aws.SetAssumeRole(&aws.AssumeRoleOptions{
+			RoleARN:     roleARN,
+			ExternalID:  externalID,
+			SessionName: sessionName,
+		})
+	}
+	rootCmd.PersistentPostRun = func(cmd *cobra.Command, args []string) {
+		if debug, _ := cmd.Flags().GetBool("debug"); debug {
+			fmt.Fprintln(os.Stderr, "\nAWS API calls:")
+			aws.PrintAPIMetrics(os.Stderr)
+		}
+	}
 
 	// Add subcommands
 	rootCmd.AddCommand(
 		config.NewConfigCommand(),
 		data.DataCmd,
 		deploy.NewDeployCommand(),
+		doctor.NewDoctorCommand(),
 		gui.GuiCmd,
 		monitor.NewMonitorCommand(),
+		recommend.NewRecommendCommand(),
+		tutorial.NewTutorialCommand(),
 	)
 
+	// Whoami command
+	rootCmd.AddCommand(&cobra.Command{
+		Use:   "whoami",
+		Short: "Show the AWS account and identity commands run as",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			region, _ := cmd.Flags().GetString("region")
+			client, err := aws.NewClient(cmd.Context(), region)
+			if err != nil {
+				return err
+			}
+			identity, err := client.CallerIdentity(cmd.Context())
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("Account: %s\n", identity.Account)
+			fmt.Printf("ARN: %s\n", identity.ARN)
+			fmt.Printf("User ID: %s\n", identity.UserID)
+			if identity.AssumedRole != "" {
+				fmt.Printf("Assumed role: %s\n", identity.AssumedRole)
+			}
+			fmt.Printf("Region: %s\n", client.Region)
+			return nil
+		},
+	})
+
 	// Version command
 	rootCmd.AddCommand(&cobra.Command{
 		Use:   "version",
@@ -70,10 +141,52 @@ Perfect for:
 		},
 	})
 
-	// Execute root command
-	if err := rootCmd.Execute(); err != nil {
-		log.Fatal(err)
+	return rootCmd
+}
+
+// resolveRegion settles the region every command reads from --region: the flag
+// when given, else the environment, the profile, or a prompt. Deploy and monitor
+// say which region they will use before doing anything, since a region picked
+// up from the wrong place has led to deployments in the wrong region.
+func resolveRegion(cmd *cobra.Command) error {
+	regionFlag := cmd.Flags().Lookup("region")
+	if regionFlag == nil {
+		return nil
+	}
+	// A command's own --region of another type, such as a list of regions,
+	// is not the global flag and is left alone
+	if regionFlag.Value.Type() != "string" {
+		return nil
+	}
+	flag := ""
+	if regionFlag.Changed {
+		flag = regionFlag.Value.String()
+	}
+
+	announce := false
+	for c := cmd; c != nil; c = c.Parent() {
+		if c.Name() == "deploy" || c.Name() == "monitor" {
+			announce = true
+		}
+	}
+	resolver := aws.RegionResolver{}
+	if announce {
+		resolver.Prompt = aws.TerminalRegionPrompt(os.Stderr)
+	}
+
+	resolved, err := resolver.Resolve(cmd.Context(), flag)
+	if err != nil {
+		return err
+	}
+	// Set without marking the flag changed, so a wizard file environment can still set the region
+	if err := regionFlag.Value.Set(resolved.Region); err != nil {
+		return err
+	}
+	aws.SetResolvedRegion(resolved)
+	if announce {
+		fmt.Fprintf(os.Stderr, "Region: %s\n", resolved)
 	}
+	return nil
 }
 
 func getGoVersion() string {
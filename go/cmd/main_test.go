@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+// isolateAWSConfig keeps the host's AWS configuration and instance metadata out of a test
+func isolateAWSConfig(t *testing.T) {
+	t.Helper()
+	dir := t.TempDir()
+	for name, value := range map[string]string{
+		"AWS_REGION":                  "",
+		"AWS_DEFAULT_REGION":          "",
+		"AWS_PROFILE":                 "",
+		"AWS_ACCESS_KEY_ID":           "",
+		"AWS_SECRET_ACCESS_KEY":       "",
+		"AWS_SESSION_TOKEN":           "",
+		"AWS_CONFIG_FILE":             filepath.Join(dir, "config"),
+		"AWS_SHARED_CREDENTIALS_FILE": filepath.Join(dir, "credentials"),
+		"AWS_EC2_METADATA_DISABLED":   "true",
+	} {
+		t.Setenv(name, value)
+	}
+}
+
+// captureStdout returns what run writes to standard output
+func captureStdout(t *testing.T, run func()) string {
+	t.Helper()
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = writer
+	defer func() { os.Stdout = stdout }()
+
+	output := make(chan string)
+	go func() {
+		content, _ := io.ReadAll(reader)
+		output <- string(content)
+	}()
+	run()
+	writer.Close()
+	return <-output
+}
+
+func TestResolveRegionLeavesLocalRegionList(t *testing.T) {
+	isolateAWSConfig(t)
+
+	var regions []string
+	rootCmd := newRootCommand()
+	probe := &cobra.Command{Use: "probe", Run: func(cmd *cobra.Command, args []string) {}}
+	probe.Flags().StringSliceVar(&regions, "region", nil, "Regions")
+	rootCmd.AddCommand(probe)
+
+	rootCmd.SetArgs([]string{"probe", "--region", "eu-west-1", "--region", "us-west-2"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if want := []string{"eu-west-1", "us-west-2"}; !reflect.DeepEqual(regions, want) {
+		t.Errorf("regions = %v, want %v", regions, want)
+	}
+}
+
+func TestCostCompareWithGlobalRegion(t *testing.T) {
+	isolateAWSConfig(t)
+	configRoot, err := filepath.Abs(filepath.Join("..", ".."))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rootCmd := newRootCommand()
+	rootCmd.SetArgs([]string{
+		"config", "cost", "compare", "--config", configRoot,
+		"--domain", "genomics", "--region", "eu-west-1", "--regions", "eu-west-1,us-west-2", "--json",
+	})
+	output := captureStdout(t, func() {
+		if err := rootCmd.Execute(); err != nil {
+			t.Errorf("Execute() error = %v", err)
+		}
+	})
+
+	var matrix struct {
+		Regions []string `json:"regions"`
+	}
+	if err := json.Unmarshal([]byte(output), &matrix); err != nil {
+		t.Fatalf("output is not a cost matrix: %v\n%s", err, output)
+	}
+	if want := []string{"eu-west-1", "us-west-2"}; !reflect.DeepEqual(matrix.Regions, want) {
+		t.Errorf("compared regions = %v, want %v", matrix.Regions, want)
+	}
+	if region, _ := rootCmd.PersistentFlags().GetString("region"); region != "eu-west-1" {
+		t.Errorf("global region = %q, want eu-west-1", region)
+	}
+}
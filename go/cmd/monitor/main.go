@@ -41,7 +41,7 @@ func main() {
 	}
 
 	// Add flags
-	rootCmd.PersistentFlags().StringVar(&region, "region", "us-east-1", "AWS region")
+	rootCmd.PersistentFlags().StringVar(&region, "region", "", "AWS region (default: AWS_REGION or AWS_DEFAULT_REGION, then the profile's region)")
 	rootCmd.PersistentFlags().IntVar(&refreshRate, "refresh", 30, "Refresh interval in seconds")
 	rootCmd.PersistentFlags().StringVar(&stackName, "stack", "", "CloudFormation stack name to monitor")
 	rootCmd.PersistentFlags().StringVar(&instanceID, "instance", "", "EC2 instance ID to monitor")
@@ -50,6 +50,10 @@ func main() {
 	rootCmd.PersistentFlags().BoolVar(&autoRefresh, "auto-refresh", true, "Enable auto-refresh")
 	rootCmd.PersistentFlags().StringVar(&outputFormat, "format", "dashboard", "Output format: dashboard, json, table")
 
+	rootCmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		return resolveRegion(cmd)
+	}
+
 	// Add subcommands
 	rootCmd.AddCommand(
 		createDashboardCommand(),
@@ -64,6 +68,26 @@ func main() {
 	}
 }
 
+// resolveRegion settles --region the way the main binary does: the flag when
+// given, else the environment, the profile, or a prompt. The region is
+// announced so a region picked up from the wrong place is noticed.
+func resolveRegion(cmd *cobra.Command) error {
+	flag := ""
+	if cmd.Flags().Changed("region") {
+		flag = region
+	}
+
+	resolver := aws.RegionResolver{Prompt: aws.TerminalRegionPrompt(os.Stderr)}
+	resolved, err := resolver.Resolve(cmd.Context(), flag)
+	if err != nil {
+		return err
+	}
+	region = resolved.Region
+	aws.SetResolvedRegion(resolved)
+	fmt.Fprintf(os.Stderr, "Region: %s\n", resolved)
+	return nil
+}
+
 func runInteractiveMonitor(cmd *cobra.Command, args []string) {
 	ctx := context.Background()
 
@@ -0,0 +1,155 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+// capacityAPI is the subset of the EC2 API used to look up capacity reservations
+type capacityAPI interface {
+	DescribeCapacityReservations(ctx context.Context, params *ec2.DescribeCapacityReservationsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeCapacityReservationsOutput, error)
+	DescribeSubnets(ctx context.Context, params *ec2.DescribeSubnetsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeSubnetsOutput, error)
+}
+
+// CapacityManager looks up On-Demand Capacity Reservations (ODCRs) that
+// research instances can be launched into
+type CapacityManager struct {
+	api capacityAPI
+}
+
+// NewCapacityManager creates a new capacity manager
+func NewCapacityManager(client *Client) *CapacityManager {
+	return &CapacityManager{api: client.EC2}
+}
+
+// CapacityReservation describes an On-Demand Capacity Reservation
+type CapacityReservation struct {
+	ID               string
+	InstanceType     string
+	Platform         string
+	AvailabilityZone string
+	State            string
+	Tenancy          string
+	// InstanceMatchCriteria is "open" when matching instances use the
+	// reservation automatically, or "targeted" when they must name it
+	InstanceMatchCriteria string
+	TotalCount            int32
+	AvailableCount        int32
+	// EndDate is nil for a reservation that lasts until it is cancelled
+	EndDate *time.Time
+}
+
+// Expiry describes when the reservation ends
+func (r CapacityReservation) Expiry() string {
+	if r.EndDate == nil {
+		return "until cancelled"
+	}
+	return r.EndDate.UTC().Format("2006-01-02 15:04 MST")
+}
+
+// CheckDeployment reports why a deployment cannot use the reservation, given
+// how many instances of each type it launches and the Availability Zone of its
+// subnet. An empty zone is not checked, since instances launched outside a
+// subnet are placed in the reservation's zone.
+func (r CapacityReservation) CheckDeployment(instanceTypes map[string]int, availabilityZone string) error {
+	if r.State != string(ec2types.CapacityReservationStateActive) {
+		return fmt.Errorf("capacity reservation %s is %s, not active", r.ID, r.State)
+	}
+	var types []string
+	for instanceType := range instanceTypes {
+		types = append(types, instanceType)
+	}
+	sort.Strings(types)
+	for _, instanceType := range types {
+		if instanceType != r.InstanceType {
+			return fmt.Errorf("capacity reservation %s is for %s instances, not %s", r.ID, r.InstanceType, instanceType)
+		}
+	}
+	if availabilityZone != "" && availabilityZone != r.AvailabilityZone {
+		return fmt.Errorf("capacity reservation %s is in %s, but the deployment subnet is in %s", r.ID, r.AvailabilityZone, availabilityZone)
+	}
+	if needed := int32(instanceTypes[r.InstanceType]); needed > r.AvailableCount {
+		return fmt.Errorf("capacity reservation %s has %d of %d instances available, and the deployment launches %d",
+			r.ID, r.AvailableCount, r.TotalCount, needed)
+	}
+	return nil
+}
+
+// ListCapacityReservations returns the active capacity reservations for an
+// instance type, most available first
+func (cm *CapacityManager) ListCapacityReservations(ctx context.Context, instanceType string) ([]CapacityReservation, error) {
+	input := &ec2.DescribeCapacityReservationsInput{
+		Filters: []ec2types.Filter{
+			{Name: aws.String("instance-type"), Values: []string{instanceType}},
+			{Name: aws.String("state"), Values: []string{string(ec2types.CapacityReservationStateActive)}},
+		},
+	}
+
+	var reservations []CapacityReservation
+	paginator := ec2.NewDescribeCapacityReservationsPaginator(cm.api, input)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe capacity reservations: %w", err)
+		}
+		for _, reservation := range page.CapacityReservations {
+			reservations = append(reservations, newCapacityReservation(reservation))
+		}
+	}
+
+	sort.SliceStable(reservations, func(i, j int) bool {
+		if reservations[i].AvailableCount != reservations[j].AvailableCount {
+			return reservations[i].AvailableCount > reservations[j].AvailableCount
+		}
+		return reservations[i].ID < reservations[j].ID
+	})
+	return reservations, nil
+}
+
+// GetCapacityReservation looks up a capacity reservation by ID
+func (cm *CapacityManager) GetCapacityReservation(ctx context.Context, id string) (*CapacityReservation, error) {
+	result, err := cm.api.DescribeCapacityReservations(ctx, &ec2.DescribeCapacityReservationsInput{
+		CapacityReservationIds: []string{id},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe capacity reservation %s: %w", id, err)
+	}
+	if len(result.CapacityReservations) == 0 {
+		return nil, fmt.Errorf("capacity reservation %s not found", id)
+	}
+	reservation := newCapacityReservation(result.CapacityReservations[0])
+	return &reservation, nil
+}
+
+// SubnetAvailabilityZone returns the Availability Zone a subnet is in
+func (cm *CapacityManager) SubnetAvailabilityZone(ctx context.Context, subnetID string) (string, error) {
+	result, err := cm.api.DescribeSubnets(ctx, &ec2.DescribeSubnetsInput{SubnetIds: []string{subnetID}})
+	if err != nil {
+		return "", fmt.Errorf("failed to describe subnet %s: %w", subnetID, err)
+	}
+	if len(result.Subnets) == 0 {
+		return "", fmt.Errorf("subnet %s not found", subnetID)
+	}
+	return aws.ToString(result.Subnets[0].AvailabilityZone), nil
+}
+
+func newCapacityReservation(reservation ec2types.CapacityReservation) CapacityReservation {
+	return CapacityReservation{
+		ID:                    aws.ToString(reservation.CapacityReservationId),
+		InstanceType:          aws.ToString(reservation.InstanceType),
+		Platform:              string(reservation.InstancePlatform),
+		AvailabilityZone:      aws.ToString(reservation.AvailabilityZone),
+		State:                 string(reservation.State),
+		Tenancy:               string(reservation.Tenancy),
+		InstanceMatchCriteria: string(reservation.InstanceMatchCriteria),
+		TotalCount:            aws.ToInt32(reservation.TotalInstanceCount),
+		AvailableCount:        aws.ToInt32(reservation.AvailableInstanceCount),
+		EndDate:               reservation.EndDate,
+	}
+}
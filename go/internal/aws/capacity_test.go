@@ -0,0 +1,163 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+// fakeCapacityEC2 serves DescribeCapacityReservations one page per call and
+// looks subnets up by ID
+type fakeCapacityEC2 struct {
+	pages   [][]ec2types.CapacityReservation
+	subnets map[string]string
+	inputs  []*ec2.DescribeCapacityReservationsInput
+}
+
+func (f *fakeCapacityEC2) DescribeCapacityReservations(ctx context.Context, params *ec2.DescribeCapacityReservationsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeCapacityReservationsOutput, error) {
+	f.inputs = append(f.inputs, params)
+
+	if len(params.CapacityReservationIds) > 0 {
+		var found []ec2types.CapacityReservation
+		for _, page := range f.pages {
+			for _, reservation := range page {
+				if aws.ToString(reservation.CapacityReservationId) == params.CapacityReservationIds[0] {
+					found = append(found, reservation)
+				}
+			}
+		}
+		return &ec2.DescribeCapacityReservationsOutput{CapacityReservations: found}, nil
+	}
+
+	page := 0
+	if params.NextToken != nil {
+		fmt.Sscanf(*params.NextToken, "page-%d", &page)
+	}
+	output := &ec2.DescribeCapacityReservationsOutput{CapacityReservations: f.pages[page]}
+	if page+1 < len(f.pages) {
+		output.NextToken = aws.String(fmt.Sprintf("page-%d", page+1))
+	}
+	return output, nil
+}
+
+func (f *fakeCapacityEC2) DescribeSubnets(ctx context.Context, params *ec2.DescribeSubnetsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeSubnetsOutput, error) {
+	zone, exists := f.subnets[params.SubnetIds[0]]
+	if !exists {
+		return &ec2.DescribeSubnetsOutput{}, nil
+	}
+	return &ec2.DescribeSubnetsOutput{Subnets: []ec2types.Subnet{{AvailabilityZone: aws.String(zone)}}}, nil
+}
+
+func testReservation(id, zone string, total, available int32, end *time.Time) ec2types.CapacityReservation {
+	return ec2types.CapacityReservation{
+		CapacityReservationId:  aws.String(id),
+		InstanceType:           aws.String("p4d.24xlarge"),
+		InstancePlatform:       ec2types.CapacityReservationInstancePlatformLinuxUnix,
+		AvailabilityZone:       aws.String(zone),
+		State:                  ec2types.CapacityReservationStateActive,
+		Tenancy:                ec2types.CapacityReservationTenancyDefault,
+		InstanceMatchCriteria:  ec2types.InstanceMatchCriteriaTargeted,
+		TotalInstanceCount:     aws.Int32(total),
+		AvailableInstanceCount: aws.Int32(available),
+		EndDate:                end,
+	}
+}
+
+func TestListCapacityReservations(t *testing.T) {
+	end := time.Date(2026, 12, 31, 23, 0, 0, 0, time.UTC)
+	api := &fakeCapacityEC2{pages: [][]ec2types.CapacityReservation{
+		{testReservation("cr-0aaaaaaaaaaaaaaa1", "us-east-1a", 4, 1, &end)},
+		{testReservation("cr-0bbbbbbbbbbbbbbb2", "us-east-1b", 8, 6, nil)},
+	}}
+
+	reservations, err := (&CapacityManager{api: api}).ListCapacityReservations(context.Background(), "p4d.24xlarge")
+	if err != nil {
+		t.Fatalf("ListCapacityReservations() error = %v", err)
+	}
+	if len(reservations) != 2 || reservations[0].ID != "cr-0bbbbbbbbbbbbbbb2" {
+		t.Fatalf("reservations = %+v, want both pages with the most available first", reservations)
+	}
+	if reservations[0].Expiry() != "until cancelled" || reservations[1].Expiry() != "2026-12-31 23:00 UTC" {
+		t.Errorf("Expiry() = %q, %q", reservations[0].Expiry(), reservations[1].Expiry())
+	}
+	if reservations[1].AvailableCount != 1 || reservations[1].TotalCount != 4 || reservations[1].InstanceMatchCriteria != "targeted" {
+		t.Errorf("unexpected reservation %+v", reservations[1])
+	}
+
+	filters := map[string][]string{}
+	for _, filter := range api.inputs[0].Filters {
+		filters[aws.ToString(filter.Name)] = filter.Values
+	}
+	if filters["instance-type"][0] != "p4d.24xlarge" || filters["state"][0] != "active" {
+		t.Errorf("unexpected filters %v", filters)
+	}
+}
+
+func TestGetCapacityReservation(t *testing.T) {
+	api := &fakeCapacityEC2{
+		pages:   [][]ec2types.CapacityReservation{{testReservation("cr-0aaaaaaaaaaaaaaa1", "us-east-1a", 4, 2, nil)}},
+		subnets: map[string]string{"subnet-0123": "us-east-1a"},
+	}
+	manager := &CapacityManager{api: api}
+
+	reservation, err := manager.GetCapacityReservation(context.Background(), "cr-0aaaaaaaaaaaaaaa1")
+	if err != nil || reservation.AvailabilityZone != "us-east-1a" {
+		t.Fatalf("GetCapacityReservation() = %+v, %v", reservation, err)
+	}
+	if _, err := manager.GetCapacityReservation(context.Background(), "cr-0fffffffffffffff9"); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("expected a missing reservation error, got %v", err)
+	}
+
+	zone, err := manager.SubnetAvailabilityZone(context.Background(), "subnet-0123")
+	if err != nil || zone != "us-east-1a" {
+		t.Errorf("SubnetAvailabilityZone() = %q, %v", zone, err)
+	}
+	if _, err := manager.SubnetAvailabilityZone(context.Background(), "subnet-9999"); err == nil {
+		t.Error("expected a missing subnet error")
+	}
+}
+
+func TestCapacityReservationCheckDeployment(t *testing.T) {
+	reservation := newCapacityReservation(testReservation("cr-0aaaaaaaaaaaaaaa1", "us-east-1a", 4, 2, nil))
+
+	tests := []struct {
+		name          string
+		instanceTypes map[string]int
+		zone          string
+		state         string
+		wantErr       string
+	}{
+		{name: "matching", instanceTypes: map[string]int{"p4d.24xlarge": 1}, zone: "us-east-1a"},
+		{name: "zone not yet chosen", instanceTypes: map[string]int{"p4d.24xlarge": 2}},
+		{name: "wrong instance type", instanceTypes: map[string]int{"p5.48xlarge": 1}, wantErr: "for p4d.24xlarge instances, not p5.48xlarge"},
+		{name: "mixed instance types", instanceTypes: map[string]int{"p4d.24xlarge": 2, "c6i.large": 1}, wantErr: "not c6i.large"},
+		{name: "wrong zone", instanceTypes: map[string]int{"p4d.24xlarge": 1}, zone: "us-east-1b", wantErr: "is in us-east-1a, but the deployment subnet is in us-east-1b"},
+		{name: "not enough available", instanceTypes: map[string]int{"p4d.24xlarge": 3}, wantErr: "has 2 of 4 instances available, and the deployment launches 3"},
+		{name: "expired", instanceTypes: map[string]int{"p4d.24xlarge": 1}, state: "expired", wantErr: "is expired, not active"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := reservation
+			if tt.state != "" {
+				r.State = tt.state
+			}
+			err := r.CheckDeployment(tt.instanceTypes, tt.zone)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("CheckDeployment() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("CheckDeployment() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,251 @@
+package aws
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	ssmtypes "github.com/aws/aws-sdk-go-v2/service/ssm/types"
+)
+
+// Limits on what a check reports back, so that every check's result fits in
+// the 24,000 characters of output SSM returns for an invocation
+const (
+	checkOutputBytes  = 1500
+	checkTimedOutCode = 124
+)
+
+// cloudInitWait bounds how long checks wait for the instance to finish booting
+const cloudInitWait = 30 * time.Minute
+
+// checkMarker prefixes the lines that delimit each check's output
+const checkMarker = "==ARW-CHECK"
+
+// InstanceCheck is a smoke test command run on an instance
+type InstanceCheck struct {
+	Name           string
+	Command        string
+	ExpectExitCode int
+	// ExpectOutput must appear in the command's combined stdout and stderr
+	ExpectOutput string
+	Timeout      time.Duration
+	Critical     bool
+}
+
+// CheckResult is the outcome of one check
+type CheckResult struct {
+	Name     string
+	Critical bool
+	Passed   bool
+	// ExitCode is -1 when the check produced no result
+	ExitCode int
+	// Output is the tail of the command's combined stdout and stderr
+	Output string
+	// Detail explains why a check failed
+	Detail string
+}
+
+// CheckResults are the outcomes of a run of checks
+type CheckResults []CheckResult
+
+// Passed reports whether every critical check passed
+func (r CheckResults) Passed() bool {
+	for _, result := range r {
+		if result.Critical && !result.Passed {
+			return false
+		}
+	}
+	return true
+}
+
+// Counts returns the number of checks that passed and failed
+func (r CheckResults) Counts() (passed, failed int) {
+	for _, result := range r {
+		if result.Passed {
+			passed++
+		} else {
+			failed++
+		}
+	}
+	return passed, failed
+}
+
+// RunChecks runs smoke test commands on an instance in a single SSM
+// invocation and reports each one's outcome. Checks start once cloud-init has
+// finished and run one after another in a login shell, so tools set up by
+// profile scripts are on the PATH. For a newly launched instance, agentWait
+// allows its SSM agent time to register before the command is sent.
+func (cr *CommandRunner) RunChecks(ctx context.Context, instanceID string, checks []InstanceCheck, agentWait time.Duration) (CheckResults, error) {
+	if len(checks) == 0 {
+		return nil, nil
+	}
+
+	nonce, err := checkNonce()
+	if err != nil {
+		return nil, err
+	}
+
+	// Allow booting and every check's full timeout, plus time for SSM to start the script
+	timeout := cloudInitWait + time.Minute
+	for _, check := range checks {
+		timeout += check.Timeout
+	}
+
+	commandID, err := cr.startWhenRegistered(ctx, instanceID, checkScript(checks, nonce), timeout, agentWait)
+	if err != nil {
+		return nil, fmt.Errorf("%w (is the SSM agent running and the instance role attached?)", err)
+	}
+	result, err := cr.Wait(ctx, instanceID, commandID)
+	if err != nil {
+		return nil, err
+	}
+
+	results := parseCheckOutput(result.Stdout, nonce, checks)
+	for i := range results {
+		if results[i].ExitCode < 0 {
+			results[i].Detail = fmt.Sprintf("no result (invocation %s)", result.Status)
+		}
+	}
+	if !result.Succeeded() && allUnfinished(results) {
+		return nil, fmt.Errorf("checks on %s finished with status %s: %s",
+			instanceID, result.Status, strings.TrimSpace(result.Stderr))
+	}
+	return results, nil
+}
+
+// startWhenRegistered sends a command, retrying for up to agentWait while the
+// instance is not yet registered with SSM
+func (cr *CommandRunner) startWhenRegistered(ctx context.Context, instanceID string, commands []string, timeout, agentWait time.Duration) (string, error) {
+	deadline := time.Now().Add(agentWait)
+	for {
+		commandID, err := cr.Start(ctx, instanceID, commands, timeout)
+		var unregistered *ssmtypes.InvalidInstanceId
+		if err == nil || !errors.As(err, &unregistered) || time.Now().After(deadline) {
+			return commandID, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(cr.pollInterval):
+		}
+	}
+}
+
+// checkScript returns the shell lines that run each check between marker
+// lines carrying its index, exit code and whether the expected output appeared.
+// Matching on the instance sees all of the output, not only the tail sent back.
+func checkScript(checks []InstanceCheck, nonce string) []string {
+	lines := []string{fmt.Sprintf("timeout %d cloud-init status --wait >/dev/null 2>&1 || true", int(cloudInitWait.Seconds()))}
+	for i, check := range checks {
+		seconds := int(check.Timeout.Seconds())
+		if seconds <= 0 {
+			seconds = 1
+		}
+
+		lines = append(lines,
+			fmt.Sprintf("echo '%s %s BEGIN %d'", checkMarker, nonce, i),
+			fmt.Sprintf("out=$(timeout %d bash -lc %s 2>&1 </dev/null); code=$?", seconds, shellQuote(check.Command)),
+			fmt.Sprintf(`printf '%%s\n' "$out" | tail -c %d`, checkOutputBytes),
+			"matched=-",
+		)
+		if check.ExpectOutput != "" {
+			lines = append(lines, fmt.Sprintf(
+				`if printf '%%s' "$out" | grep -qF -- %s; then matched=1; else matched=0; fi`, shellQuote(check.ExpectOutput)))
+		}
+		lines = append(lines, fmt.Sprintf(`echo "%s %s END %d $code $matched"`, checkMarker, nonce, i))
+	}
+	return append(lines, "exit 0")
+}
+
+// parseCheckOutput reads the results of checkScript from the invocation's
+// output. Checks without an END marker, because the script stopped or the
+// output was truncated, are failed with an exit code of -1.
+func parseCheckOutput(stdout, nonce string, checks []InstanceCheck) CheckResults {
+	results := make(CheckResults, len(checks))
+	for i, check := range checks {
+		results[i] = CheckResult{Name: check.Name, Critical: check.Critical, ExitCode: -1}
+	}
+
+	prefix := checkMarker + " " + nonce + " "
+	current := -1
+	var output []string
+	for _, line := range strings.Split(stdout, "\n") {
+		if !strings.HasPrefix(line, prefix) {
+			if current >= 0 {
+				output = append(output, line)
+			}
+			continue
+		}
+
+		fields := strings.Fields(strings.TrimPrefix(line, prefix))
+		if len(fields) < 2 {
+			continue
+		}
+		index, err := strconv.Atoi(fields[1])
+		if err != nil || index < 0 || index >= len(checks) {
+			continue
+		}
+
+		switch fields[0] {
+		case "BEGIN":
+			current, output = index, nil
+		case "END":
+			if index != current || len(fields) != 4 {
+				continue
+			}
+			code, err := strconv.Atoi(fields[2])
+			if err != nil {
+				continue
+			}
+			results[index].ExitCode = code
+			results[index].Output = strings.TrimRight(strings.Join(output, "\n"), "\n")
+			results[index].Passed, results[index].Detail = judgeCheck(checks[index], code, fields[3])
+			current, output = -1, nil
+		}
+	}
+	return results
+}
+
+// judgeCheck decides whether a check passed from its exit code and whether
+// its expected output was found ("1", "0", or "-" when none was expected)
+func judgeCheck(check InstanceCheck, code int, matched string) (bool, string) {
+	switch {
+	case code == checkTimedOutCode && check.ExpectExitCode != checkTimedOutCode:
+		return false, fmt.Sprintf("timed out after %s", check.Timeout)
+	case code != check.ExpectExitCode:
+		return false, fmt.Sprintf("exit code %d, expected %d", code, check.ExpectExitCode)
+	case matched == "0":
+		return false, fmt.Sprintf("output does not contain %q", check.ExpectOutput)
+	}
+	return true, ""
+}
+
+func allUnfinished(results CheckResults) bool {
+	for _, result := range results {
+		if result.ExitCode >= 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// checkNonce returns a random token that keeps a check's own output from
+// being mistaken for a marker line
+func checkNonce() (string, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate check marker: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// shellQuote wraps a value in single quotes for a POSIX shell
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'"'"'`) + "'"
+}
@@ -0,0 +1,249 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	ssmtypes "github.com/aws/aws-sdk-go-v2/service/ssm/types"
+)
+
+// fakeSSM answers every invocation with output produced from the commands sent
+type fakeSSM struct {
+	commands []string
+	timeout  []string
+	status   ssmtypes.CommandInvocationStatus
+	stderr   string
+	output   func(commands []string) string
+	// sendErrs are returned by successive SendCommand calls before any succeeds
+	sendErrs []error
+	sends    int
+}
+
+func (f *fakeSSM) SendCommand(ctx context.Context, params *ssm.SendCommandInput, optFns ...func(*ssm.Options)) (*ssm.SendCommandOutput, error) {
+	f.sends++
+	if len(f.sendErrs) > 0 {
+		err := f.sendErrs[0]
+		f.sendErrs = f.sendErrs[1:]
+		return nil, err
+	}
+	f.commands = params.Parameters["commands"]
+	f.timeout = params.Parameters["executionTimeout"]
+	return &ssm.SendCommandOutput{Command: &ssmtypes.Command{CommandId: aws.String("cmd-1")}}, nil
+}
+
+func (f *fakeSSM) GetCommandInvocation(ctx context.Context, params *ssm.GetCommandInvocationInput, optFns ...func(*ssm.Options)) (*ssm.GetCommandInvocationOutput, error) {
+	status := f.status
+	if status == "" {
+		status = ssmtypes.CommandInvocationStatusSuccess
+	}
+	return &ssm.GetCommandInvocationOutput{
+		Status:                status,
+		StandardOutputContent: aws.String(f.output(f.commands)),
+		StandardErrorContent:  aws.String(f.stderr),
+	}, nil
+}
+
+var nonceInScript = regexp.MustCompile(checkMarker + ` ([0-9a-f]+) BEGIN`)
+
+// cannedOutput returns invocation output in the layout checkScript prints
+func cannedOutput(blocks ...string) func([]string) string {
+	return func(commands []string) string {
+		nonce := nonceInScript.FindStringSubmatch(strings.Join(commands, "\n"))[1]
+		return strings.ReplaceAll(strings.Join(blocks, ""), "NONCE", nonce)
+	}
+}
+
+var smokeChecks = []InstanceCheck{
+	{Name: "nextflow", Command: "nextflow -version", ExpectOutput: "nextflow", Timeout: time.Minute, Critical: true},
+	{Name: "torch", Command: `python3 -c "import torch"`, Timeout: 2 * time.Minute, Critical: true},
+	{Name: "gpu", Command: "nvidia-smi", Timeout: time.Minute},
+}
+
+func TestRunChecks(t *testing.T) {
+	api := &fakeSSM{output: cannedOutput(
+		"==ARW-CHECK NONCE BEGIN 0\n      N E X T F L O W\n      version 23.10.0\n==ARW-CHECK NONCE END 0 0 0\n",
+		"==ARW-CHECK NONCE BEGIN 1\n\n==ARW-CHECK NONCE END 1 0 -\n",
+		"==ARW-CHECK NONCE BEGIN 2\nbash: nvidia-smi: command not found\n==ARW-CHECK NONCE END 2 127 -\n",
+	)}
+	runner := &CommandRunner{api: api, pollInterval: time.Millisecond}
+
+	results, err := runner.RunChecks(context.Background(), "i-123", smokeChecks, 0)
+	if err != nil {
+		t.Fatalf("RunChecks() error = %v", err)
+	}
+
+	// The whole run allows for booting and a minute on top of the checks' own timeouts
+	if len(api.timeout) != 1 || api.timeout[0] != "2100" {
+		t.Errorf("executionTimeout = %v", api.timeout)
+	}
+
+	want := []struct {
+		passed bool
+		code   int
+		detail string
+	}{
+		{false, 0, `output does not contain "nextflow"`},
+		{true, 0, ""},
+		{false, 127, "exit code 127, expected 0"},
+	}
+	for i, w := range want {
+		got := results[i]
+		if got.Passed != w.passed || got.ExitCode != w.code || got.Detail != w.detail {
+			t.Errorf("%s = %+v, want passed %v, exit %d, %q", got.Name, got, w.passed, w.code, w.detail)
+		}
+	}
+	if results[0].Output != "      N E X T F L O W\n      version 23.10.0" {
+		t.Errorf("output = %q", results[0].Output)
+	}
+	if results.Passed() {
+		t.Error("a failed critical check should fail the run")
+	}
+	if passed, failed := results.Counts(); passed != 1 || failed != 2 {
+		t.Errorf("Counts() = %d, %d", passed, failed)
+	}
+
+	// Failures of optional checks alone do not fail the run
+	results[0].Passed = true
+	if !results.Passed() {
+		t.Error("only the optional check failed; the run should pass")
+	}
+}
+
+func TestRunChecksUnfinished(t *testing.T) {
+	// The script was cut off during the second check
+	api := &fakeSSM{
+		status: ssmtypes.CommandInvocationStatusTimedOut,
+		output: cannedOutput(
+			"==ARW-CHECK NONCE BEGIN 0\nnextflow version 23.10.0\n==ARW-CHECK NONCE END 0 0 1\n",
+			"==ARW-CHECK NONCE BEGIN 1\n",
+		),
+	}
+	runner := &CommandRunner{api: api, pollInterval: time.Millisecond}
+
+	results, err := runner.RunChecks(context.Background(), "i-123", smokeChecks, 0)
+	if err != nil {
+		t.Fatalf("RunChecks() error = %v", err)
+	}
+	if !results[0].Passed {
+		t.Errorf("first check = %+v", results[0])
+	}
+	for _, result := range results[1:] {
+		if result.Passed || result.ExitCode != -1 || result.Detail != "no result (invocation TimedOut)" {
+			t.Errorf("%s = %+v", result.Name, result)
+		}
+	}
+
+	// Nothing ran at all, such as when the instance has no bash
+	api.status = ssmtypes.CommandInvocationStatusFailed
+	api.stderr = "sh: 1: bash: not found"
+	api.output = func([]string) string { return "" }
+	if _, err := runner.RunChecks(context.Background(), "i-123", smokeChecks, 0); err == nil || !strings.Contains(err.Error(), "bash: not found") {
+		t.Errorf("RunChecks() error = %v", err)
+	}
+
+	api.sendErrs = []error{fmt.Errorf("AccessDeniedException")}
+	if _, err := runner.RunChecks(context.Background(), "i-123", smokeChecks, 0); err == nil || !strings.Contains(err.Error(), "SSM agent") {
+		t.Errorf("RunChecks() error = %v", err)
+	}
+}
+
+func TestRunChecksWaitsForAgent(t *testing.T) {
+	unregistered := &ssmtypes.InvalidInstanceId{Message: aws.String("Instances not in a valid state for account")}
+	api := &fakeSSM{
+		sendErrs: []error{unregistered, unregistered},
+		output:   cannedOutput("==ARW-CHECK NONCE BEGIN 0\n==ARW-CHECK NONCE END 0 0 1\n"),
+	}
+	runner := &CommandRunner{api: api, pollInterval: time.Millisecond}
+	checks := smokeChecks[:1]
+
+	results, err := runner.RunChecks(context.Background(), "i-123", checks, time.Minute)
+	if err != nil || api.sends != 3 || !results.Passed() {
+		t.Errorf("RunChecks() = %+v, %v after %d sends", results, err, api.sends)
+	}
+
+	// Without a wait an unregistered instance fails at once
+	api.sendErrs, api.sends = []error{unregistered, unregistered}, 0
+	if _, err := runner.RunChecks(context.Background(), "i-123", checks, 0); err == nil || api.sends != 1 {
+		t.Errorf("RunChecks() error = %v after %d sends", err, api.sends)
+	}
+}
+
+func TestParseCheckOutputIgnoresForgedMarkers(t *testing.T) {
+	checks := []InstanceCheck{{Name: "echo", Command: "echo", Critical: true}}
+	stdout := "==ARW-CHECK abc BEGIN 0\n" +
+		"==ARW-CHECK other END 0 0 -\n" + // printed by the command itself
+		"==ARW-CHECK abc END 0 3 -\n" +
+		"==ARW-CHECK abc END 7 0 -\n"
+
+	results := parseCheckOutput(stdout, "abc", checks)
+	if results[0].ExitCode != 3 || results[0].Passed || results[0].Output != "==ARW-CHECK other END 0 0 -" {
+		t.Errorf("result = %+v", results[0])
+	}
+}
+
+func TestJudgeCheck(t *testing.T) {
+	check := InstanceCheck{ExpectExitCode: 1, ExpectOutput: "usage", Timeout: 30 * time.Second}
+	tests := []struct {
+		code    int
+		matched string
+		passed  bool
+		detail  string
+	}{
+		{1, "1", true, ""},
+		{0, "1", false, "exit code 0, expected 1"},
+		{1, "0", false, `output does not contain "usage"`},
+		{124, "0", false, "timed out after 30s"},
+	}
+	for _, tt := range tests {
+		passed, detail := judgeCheck(check, tt.code, tt.matched)
+		if passed != tt.passed || detail != tt.detail {
+			t.Errorf("judgeCheck(%d, %s) = %v, %q", tt.code, tt.matched, passed, detail)
+		}
+	}
+}
+
+// TestCheckScriptRunsInShell runs the generated script locally the way the
+// SSM agent does, to check its quoting and markers against the parser
+func TestCheckScriptRunsInShell(t *testing.T) {
+	for _, tool := range []string{"sh", "bash", "timeout"} {
+		if _, err := exec.LookPath(tool); err != nil {
+			t.Skipf("%s not available", tool)
+		}
+	}
+
+	checks := []InstanceCheck{
+		{Name: "quoting", Command: `printf '%s\n' "it's \"quoted\""`, ExpectOutput: `it's "quoted"`, Timeout: 10 * time.Second, Critical: true},
+		{Name: "stderr", Command: "echo oops >&2; exit 3", ExpectExitCode: 3, ExpectOutput: "oops", Timeout: 10 * time.Second, Critical: true},
+		{Name: "missing", Command: "echo hello", ExpectOutput: "goodbye", Timeout: 10 * time.Second, Critical: true},
+		{Name: "slow", Command: "sleep 5", Timeout: time.Second, Critical: true},
+	}
+	runner := &CommandRunner{api: &fakeSSM{output: func(commands []string) string {
+		out, _ := exec.Command("sh", "-c", strings.Join(commands, "\n")).Output()
+		return string(out)
+	}}, pollInterval: time.Millisecond}
+
+	results, err := runner.RunChecks(context.Background(), "i-123", checks, 0)
+	if err != nil {
+		t.Fatalf("RunChecks() error = %v", err)
+	}
+	want := map[string]bool{"quoting": true, "stderr": true, "missing": false, "slow": false}
+	for _, result := range results {
+		if result.Passed != want[result.Name] {
+			t.Errorf("%s = %+v", result.Name, result)
+		}
+	}
+	// Login profile scripts may print before the command's own output
+	if !strings.HasSuffix(results[0].Output, `it's "quoted"`) {
+		t.Errorf("quoting output = %q", results[0].Output)
+	}
+	if results[3].Detail != "timed out after 1s" {
+		t.Errorf("slow detail = %q", results[3].Detail)
+	}
+}
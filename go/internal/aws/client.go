@@ -10,23 +10,36 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
 	"github.com/aws/aws-sdk-go-v2/service/costexplorer"
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/efs"
 	"github.com/aws/aws-sdk-go-v2/service/iam"
+	"github.com/aws/aws-sdk-go-v2/service/pricing"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/servicequotas"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
 )
 
 // Client provides comprehensive AWS service access
 type Client struct {
 	cfg            aws.Config
 	EC2            *ec2.Client
+	EFS            *efs.Client
 	CloudFormation *cloudformation.Client
 	CloudWatch     *cloudwatch.Client
 	CostExplorer   *costexplorer.Client
 	IAM            *iam.Client
+	Pricing        *pricing.Client
 	S3             *s3.Client
+	ServiceQuotas  *servicequotas.Client
+	SSM            *ssm.Client
+	STS            *sts.Client
 	Region         string
+	// AssumedRole is the role the client's credentials were assumed from, if any
+	AssumedRole string
 }
 
-// NewClient creates a new AWS client with all required services
+// NewClient creates a new AWS client with all required services. When a role
+// is set with SetAssumeRole, the services use the role's credentials.
 func NewClient(ctx context.Context, region string) (*Client, error) {
 	cfg, err := config.LoadDefaultConfig(ctx,
 		config.WithRegion(region),
@@ -34,16 +47,32 @@ func NewClient(ctx context.Context, region string) (*Client, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to load AWS config: %w", err)
 	}
+	configureClient(&cfg, currentRetryOptions(), defaultAPIMetrics)
+
+	var assumedRole string
+	if role := currentAssumeRole(); role != nil {
+		// STS is called with the base credentials the config was loaded with
+		cfg.Credentials = assumeRoleCredentials(sts.NewFromConfig(cfg), *role)
+		assumedRole = role.RoleARN
+	}
 
 	return &Client{
 		cfg:            cfg,
 		EC2:            ec2.NewFromConfig(cfg),
+		EFS:            efs.NewFromConfig(cfg),
 		CloudFormation: cloudformation.NewFromConfig(cfg),
 		CloudWatch:     cloudwatch.NewFromConfig(cfg),
 		CostExplorer:   costexplorer.NewFromConfig(cfg),
 		IAM:            iam.NewFromConfig(cfg),
-		S3:             s3.NewFromConfig(cfg),
-		Region:         region,
+		Pricing: pricing.NewFromConfig(cfg, func(o *pricing.Options) {
+			o.Region = pricingAPIRegion
+		}),
+		S3:            s3.NewFromConfig(cfg),
+		ServiceQuotas: servicequotas.NewFromConfig(cfg),
+		SSM:           ssm.NewFromConfig(cfg),
+		STS:           sts.NewFromConfig(cfg),
+		Region:        region,
+		AssumedRole:   assumedRole,
 	}, nil
 }
 
@@ -99,3 +128,30 @@ func (c *Client) GetAvailabilityZones(ctx context.Context) ([]string, error) {
 
 	return zones, nil
 }
+
+// ListKeyPairNames returns the names of the EC2 key pairs in the current region
+func (c *Client) ListKeyPairNames(ctx context.Context) ([]string, error) {
+	result, err := c.EC2.DescribeKeyPairs(ctx, &ec2.DescribeKeyPairsInput{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe key pairs: %w", err)
+	}
+
+	names := make([]string, 0, len(result.KeyPairs))
+	for _, keyPair := range result.KeyPairs {
+		names = append(names, aws.ToString(keyPair.KeyName))
+	}
+
+	return names, nil
+}
+
+// ValidatePricingAccess checks that the AWS Price List API can be queried
+func (c *Client) ValidatePricingAccess(ctx context.Context) error {
+	_, err := c.Pricing.DescribeServices(ctx, &pricing.DescribeServicesInput{
+		ServiceCode: aws.String("AmazonEC2"),
+		MaxResults:  aws.Int32(1),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to query the pricing API: %w", err)
+	}
+	return nil
+}
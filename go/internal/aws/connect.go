@@ -0,0 +1,90 @@
+package aws
+
+import "fmt"
+
+// Connectivity modes published in the Connectivity stack output
+const (
+	// ConnectivitySSH stacks accept SSH from the deployer's CIDR
+	ConnectivitySSH = "ssh"
+	// ConnectivitySSM stacks have no inbound rules and are reached through
+	// Session Manager
+	ConnectivitySSM = "ssm"
+)
+
+// portForwardingDocument is the SSM document that forwards a local port to a
+// port on the instance
+const portForwardingDocument = "AWS-StartPortForwardingSession"
+
+// Connectivity returns how the stack's research instance is reached. Stacks
+// deployed before the output existed accept SSH.
+func (s *StackInfo) Connectivity() string {
+	if mode := s.Outputs[OutputConnectivity]; mode != "" {
+		return mode
+	}
+	return ConnectivitySSH
+}
+
+// ConnectOptions controls how PlanConnect reaches a research instance
+type ConnectOptions struct {
+	User         string
+	IdentityFile string
+	// ForceSSM uses Session Manager even when the stack accepts SSH
+	ForceSSM bool
+	// Jupyter forwards the instance's Jupyter port instead of opening a shell
+	Jupyter   bool
+	LocalPort int
+	Region    string
+}
+
+// ConnectPlan is the command that connects to a research instance
+type ConnectPlan struct {
+	Mode    string
+	Program string
+	Args    []string
+	// Fallback explains why an SSH stack is reached through Session Manager
+	Fallback string
+}
+
+// PlanConnect chooses between SSH and Session Manager for a stack. SSH is used
+// when the stack accepts it and the instance has a public IP; otherwise the
+// plan runs "aws ssm start-session", with a port forwarding document when
+// Jupyter is requested.
+func PlanConnect(stackInfo *StackInfo, opts ConnectOptions) (*ConnectPlan, error) {
+	localPort := opts.LocalPort
+	if localPort == 0 {
+		localPort = DefaultJupyterPort
+	}
+
+	var fallback string
+	if !opts.ForceSSM && stackInfo.Connectivity() == ConnectivitySSH {
+		if _, err := stackInfo.HostName(); err != nil {
+			fallback = fmt.Sprintf("no public SSH path (%v)", err)
+		} else {
+			host, err := SSHHostFromStack(stackInfo, opts.User, opts.IdentityFile)
+			if err != nil {
+				return nil, err
+			}
+			args := []string{"-i", host.IdentityFile}
+			if opts.Jupyter {
+				args = append(args, "-N", "-L", fmt.Sprintf("%d:localhost:%d", localPort, DefaultJupyterPort))
+			}
+			args = append(args, host.User+"@"+host.HostName)
+			return &ConnectPlan{Mode: ConnectivitySSH, Program: "ssh", Args: args}, nil
+		}
+	}
+
+	instanceID, err := stackInfo.InstanceID()
+	if err != nil {
+		return nil, err
+	}
+	args := []string{"ssm", "start-session", "--target", instanceID}
+	if opts.Jupyter {
+		args = append(args,
+			"--document-name", portForwardingDocument,
+			"--parameters", fmt.Sprintf("portNumber=%d,localPortNumber=%d", DefaultJupyterPort, localPort))
+	}
+	if opts.Region != "" {
+		args = append(args, "--region", opts.Region)
+	}
+	return &ConnectPlan{Mode: ConnectivitySSM, Program: "aws", Args: args, Fallback: fallback}, nil
+}
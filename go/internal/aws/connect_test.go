@@ -0,0 +1,121 @@
+package aws
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func connectTestStack(outputs map[string]string) *StackInfo {
+	return &StackInfo{
+		StackName:  "research-wizard-genomics",
+		Outputs:    outputs,
+		Parameters: map[string]string{"KeyName": "lab"},
+	}
+}
+
+func TestStackConnectivity(t *testing.T) {
+	if mode := connectTestStack(nil).Connectivity(); mode != ConnectivitySSH {
+		t.Errorf("Connectivity() without output = %q, want %q", mode, ConnectivitySSH)
+	}
+	stackInfo := connectTestStack(map[string]string{OutputConnectivity: ConnectivitySSM})
+	if mode := stackInfo.Connectivity(); mode != ConnectivitySSM {
+		t.Errorf("Connectivity() = %q, want %q", mode, ConnectivitySSM)
+	}
+}
+
+func TestPlanConnect(t *testing.T) {
+	sshOutputs := map[string]string{
+		OutputInstanceID:   "i-0123456789abcdef0",
+		OutputPublicIP:     "203.0.113.10",
+		OutputConnectivity: ConnectivitySSH,
+	}
+	ssmOutputs := map[string]string{
+		OutputInstanceID:   "i-0123456789abcdef0",
+		OutputConnectivity: ConnectivitySSM,
+	}
+	noPublicIP := map[string]string{OutputInstanceID: "i-0123456789abcdef0"}
+
+	tests := []struct {
+		name     string
+		outputs  map[string]string
+		opts     ConnectOptions
+		program  string
+		args     []string
+		fallback bool
+	}{
+		{
+			name:    "ssh stack",
+			outputs: sshOutputs,
+			program: "ssh",
+			args:    []string{"-i", "~/.ssh/lab.pem", "ec2-user@203.0.113.10"},
+		},
+		{
+			name:    "ssh jupyter tunnel",
+			outputs: sshOutputs,
+			opts:    ConnectOptions{Jupyter: true, LocalPort: 9999},
+			program: "ssh",
+			args:    []string{"-i", "~/.ssh/lab.pem", "-N", "-L", "9999:localhost:8888", "ec2-user@203.0.113.10"},
+		},
+		{
+			name:    "ssm stack",
+			outputs: ssmOutputs,
+			opts:    ConnectOptions{Region: "us-west-2"},
+			program: "aws",
+			args:    []string{"ssm", "start-session", "--target", "i-0123456789abcdef0", "--region", "us-west-2"},
+		},
+		{
+			name:    "ssm jupyter forward",
+			outputs: ssmOutputs,
+			opts:    ConnectOptions{Jupyter: true},
+			program: "aws",
+			args: []string{"ssm", "start-session", "--target", "i-0123456789abcdef0",
+				"--document-name", "AWS-StartPortForwardingSession", "--parameters", "portNumber=8888,localPortNumber=8888"},
+		},
+		{
+			name:    "forced ssm",
+			outputs: sshOutputs,
+			opts:    ConnectOptions{ForceSSM: true},
+			program: "aws",
+			args:    []string{"ssm", "start-session", "--target", "i-0123456789abcdef0"},
+		},
+		{
+			name:     "ssh stack without public ip",
+			outputs:  noPublicIP,
+			program:  "aws",
+			args:     []string{"ssm", "start-session", "--target", "i-0123456789abcdef0"},
+			fallback: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := PlanConnect(connectTestStack(tt.outputs), tt.opts)
+			if err != nil {
+				t.Fatalf("PlanConnect() error = %v", err)
+			}
+			if plan.Program != tt.program || !reflect.DeepEqual(plan.Args, tt.args) {
+				t.Errorf("PlanConnect() = %s %v, want %s %v", plan.Program, plan.Args, tt.program, tt.args)
+			}
+			if (plan.Fallback != "") != tt.fallback {
+				t.Errorf("Fallback = %q, want fallback %v", plan.Fallback, tt.fallback)
+			}
+			if tt.program == "aws" && plan.Mode != ConnectivitySSM {
+				t.Errorf("Mode = %q, want %q", plan.Mode, ConnectivitySSM)
+			}
+		})
+	}
+}
+
+func TestPlanConnectErrors(t *testing.T) {
+	if _, err := PlanConnect(connectTestStack(map[string]string{OutputConnectivity: ConnectivitySSM}), ConnectOptions{}); err == nil {
+		t.Error("Expected error for an SSM stack without an instance ID")
+	}
+
+	stackInfo := connectTestStack(map[string]string{OutputPublicIP: "203.0.113.10"})
+	stackInfo.Parameters = nil
+	_, err := PlanConnect(stackInfo, ConnectOptions{})
+	if err == nil || !strings.Contains(err.Error(), "KeyName") {
+		t.Errorf("Expected key name error for an SSH stack, got %v", err)
+	}
+}
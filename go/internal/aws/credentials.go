@@ -0,0 +1,147 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"os/user"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+// Assumed role sessions last an hour and are renewed this long before they
+// expire, so calls in flight during a long stack wait never carry stale keys
+const (
+	assumeRoleDuration     = time.Hour
+	assumeRoleExpiryWindow = 5 * time.Minute
+)
+
+// maxSessionNameLength is the longest role session name STS accepts
+const maxSessionNameLength = 64
+
+var roleARNPattern = regexp.MustCompile(`^arn:aws[a-z-]*:iam::\d{12}:role/[\w+=,.@/-]+$`)
+
+var sessionNameInvalid = regexp.MustCompile(`[^\w+=,.@-]`)
+
+// AssumeRoleOptions configures a role that clients assume on top of the base
+// credentials, such as a deployer role in a delegated research account
+type AssumeRoleOptions struct {
+	RoleARN string
+	// ExternalID is required by roles whose trust policy asks for one
+	ExternalID string
+	// SessionName identifies the session in CloudTrail; empty derives one from the local user
+	SessionName string
+}
+
+// Validate checks the role ARN and session name
+func (o AssumeRoleOptions) Validate() error {
+	if !roleARNPattern.MatchString(o.RoleARN) {
+		return fmt.Errorf("invalid role ARN %q: expected arn:aws:iam::<account>:role/<name>", o.RoleARN)
+	}
+	if o.SessionName != "" && (len(o.SessionName) < 2 || len(o.SessionName) > maxSessionNameLength || sessionNameInvalid.MatchString(o.SessionName)) {
+		return fmt.Errorf("invalid role session name %q: use 2-%d letters, digits or +=,.@-", o.SessionName, maxSessionNameLength)
+	}
+	return nil
+}
+
+var (
+	assumeRoleMu sync.Mutex
+	assumeRole   *AssumeRoleOptions
+)
+
+// SetAssumeRole makes clients created afterwards assume a role; nil uses the
+// base credentials directly
+func SetAssumeRole(opts *AssumeRoleOptions) error {
+	if opts != nil {
+		if err := opts.Validate(); err != nil {
+			return err
+		}
+	}
+
+	assumeRoleMu.Lock()
+	defer assumeRoleMu.Unlock()
+	assumeRole = opts
+	return nil
+}
+
+func currentAssumeRole() *AssumeRoleOptions {
+	assumeRoleMu.Lock()
+	defer assumeRoleMu.Unlock()
+	return assumeRole
+}
+
+// assumeRoleCredentials returns credentials for a role, obtained through api
+// with the base credentials and refreshed automatically before they expire
+func assumeRoleCredentials(api stscreds.AssumeRoleAPIClient, opts AssumeRoleOptions) aws.CredentialsProvider {
+	sessionName := opts.SessionName
+	if sessionName == "" {
+		sessionName = defaultSessionName()
+	}
+
+	provider := stscreds.NewAssumeRoleProvider(api, opts.RoleARN, func(o *stscreds.AssumeRoleOptions) {
+		o.RoleSessionName = sessionName
+		o.Duration = assumeRoleDuration
+		if opts.ExternalID != "" {
+			o.ExternalID = aws.String(opts.ExternalID)
+		}
+	})
+	return aws.NewCredentialsCache(provider, func(o *aws.CredentialsCacheOptions) {
+		o.ExpiryWindow = assumeRoleExpiryWindow
+	})
+}
+
+// defaultSessionName names sessions after the local user, so CloudTrail shows
+// who deployed through a shared role
+func defaultSessionName() string {
+	name := "aws-research-wizard"
+	if current, err := user.Current(); err == nil && current.Username != "" {
+		// Windows usernames include the domain, e.g. LAB\alice
+		username := current.Username[strings.LastIndex(current.Username, `\`)+1:]
+		name += "-" + sessionNameInvalid.ReplaceAllString(username, "_")
+	}
+	if len(name) > maxSessionNameLength {
+		name = name[:maxSessionNameLength]
+	}
+	return name
+}
+
+// CallerIdentity describes the credentials a client calls AWS with
+type CallerIdentity struct {
+	Account string
+	ARN     string
+	UserID  string
+	// AssumedRole is the role ARN from --assume-role, if any
+	AssumedRole string
+}
+
+// stsIdentityAPI is the subset of the STS API used to identify the caller
+type stsIdentityAPI interface {
+	GetCallerIdentity(ctx context.Context, params *sts.GetCallerIdentityInput, optFns ...func(*sts.Options)) (*sts.GetCallerIdentityOutput, error)
+}
+
+// CallerIdentity returns the account and principal the client's credentials belong to
+func (c *Client) CallerIdentity(ctx context.Context) (*CallerIdentity, error) {
+	identity, err := getCallerIdentity(ctx, c.STS)
+	if err != nil {
+		return nil, err
+	}
+	identity.AssumedRole = c.AssumedRole
+	return identity, nil
+}
+
+func getCallerIdentity(ctx context.Context, api stsIdentityAPI) (*CallerIdentity, error) {
+	output, err := api.GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get caller identity: %w", err)
+	}
+	return &CallerIdentity{
+		Account: aws.ToString(output.Account),
+		ARN:     aws.ToString(output.Arn),
+		UserID:  aws.ToString(output.UserId),
+	}, nil
+}
@@ -0,0 +1,155 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	ststypes "github.com/aws/aws-sdk-go-v2/service/sts/types"
+)
+
+// fakeSTS issues numbered credentials that expire after lifetime
+type fakeSTS struct {
+	inputs   []*sts.AssumeRoleInput
+	lifetime time.Duration
+	err      error
+}
+
+func (f *fakeSTS) AssumeRole(ctx context.Context, params *sts.AssumeRoleInput, optFns ...func(*sts.Options)) (*sts.AssumeRoleOutput, error) {
+	f.inputs = append(f.inputs, params)
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &sts.AssumeRoleOutput{Credentials: &ststypes.Credentials{
+		AccessKeyId:     aws.String("ASIA" + strings.Repeat("X", len(f.inputs))),
+		SecretAccessKey: aws.String("secret"),
+		SessionToken:    aws.String("token"),
+		Expiration:      aws.Time(time.Now().Add(f.lifetime)),
+	}}, nil
+}
+
+func (f *fakeSTS) GetCallerIdentity(ctx context.Context, params *sts.GetCallerIdentityInput, optFns ...func(*sts.Options)) (*sts.GetCallerIdentityOutput, error) {
+	return &sts.GetCallerIdentityOutput{
+		Account: aws.String("210987654321"),
+		Arn:     aws.String("arn:aws:sts::210987654321:assumed-role/ResearchDeployer/aws-research-wizard-alice"),
+		UserId:  aws.String("AROAEXAMPLE:aws-research-wizard-alice"),
+	}, nil
+}
+
+const deployerRole = "arn:aws:iam::210987654321:role/ResearchDeployer"
+
+func TestAssumeRoleCredentials(t *testing.T) {
+	api := &fakeSTS{lifetime: time.Hour}
+	provider := assumeRoleCredentials(api, AssumeRoleOptions{
+		RoleARN:     deployerRole,
+		ExternalID:  "lab-42",
+		SessionName: "alice-laptop",
+	})
+
+	creds, err := provider.Retrieve(context.Background())
+	if err != nil {
+		t.Fatalf("Retrieve() error = %v", err)
+	}
+	if creds.AccessKeyID != "ASIAX" || creds.SessionToken != "token" || !creds.CanExpire {
+		t.Errorf("credentials = %+v", creds)
+	}
+
+	input := api.inputs[0]
+	if aws.ToString(input.RoleArn) != deployerRole || aws.ToString(input.ExternalId) != "lab-42" ||
+		aws.ToString(input.RoleSessionName) != "alice-laptop" || aws.ToInt32(input.DurationSeconds) != 3600 {
+		t.Errorf("AssumeRole input = %+v", input)
+	}
+
+	// Valid credentials are reused rather than assuming the role for every call
+	if _, err := provider.Retrieve(context.Background()); err != nil || len(api.inputs) != 1 {
+		t.Errorf("second Retrieve() = %v after %d AssumeRole calls", err, len(api.inputs))
+	}
+}
+
+func TestAssumeRoleCredentialsRefresh(t *testing.T) {
+	// Credentials inside the expiry window are renewed before a call can fail with them
+	api := &fakeSTS{lifetime: assumeRoleExpiryWindow / 2}
+	provider := assumeRoleCredentials(api, AssumeRoleOptions{RoleARN: deployerRole})
+
+	first, err := provider.Retrieve(context.Background())
+	if err != nil {
+		t.Fatalf("Retrieve() error = %v", err)
+	}
+	second, err := provider.Retrieve(context.Background())
+	if err != nil {
+		t.Fatalf("Retrieve() error = %v", err)
+	}
+	if len(api.inputs) != 2 || first.AccessKeyID == second.AccessKeyID {
+		t.Errorf("credentials were not refreshed: %s then %s", first.AccessKeyID, second.AccessKeyID)
+	}
+
+	// Without an external ID none is sent, and the session is named for the tool
+	if api.inputs[0].ExternalId != nil || !strings.HasPrefix(aws.ToString(api.inputs[0].RoleSessionName), "aws-research-wizard") {
+		t.Errorf("AssumeRole input = %+v", api.inputs[0])
+	}
+
+	api.err = errors.New("AccessDenied: not authorized to perform sts:AssumeRole")
+	api.lifetime = 0
+	if _, err := assumeRoleCredentials(api, AssumeRoleOptions{RoleARN: deployerRole}).Retrieve(context.Background()); err == nil || !strings.Contains(err.Error(), "AccessDenied") {
+		t.Errorf("Retrieve() error = %v", err)
+	}
+}
+
+func TestAssumeRoleOptionsValidate(t *testing.T) {
+	tests := []struct {
+		opts  AssumeRoleOptions
+		valid bool
+	}{
+		{AssumeRoleOptions{RoleARN: deployerRole}, true},
+		{AssumeRoleOptions{RoleARN: "arn:aws-us-gov:iam::210987654321:role/team/Deployer"}, true},
+		{AssumeRoleOptions{RoleARN: deployerRole, SessionName: "alice@lab.edu"}, true},
+		{AssumeRoleOptions{RoleARN: "ResearchDeployer"}, false},
+		{AssumeRoleOptions{RoleARN: "arn:aws:iam::210987654321:user/alice"}, false},
+		{AssumeRoleOptions{RoleARN: deployerRole, SessionName: "alice laptop"}, false},
+		{AssumeRoleOptions{RoleARN: deployerRole, SessionName: strings.Repeat("a", 65)}, false},
+	}
+	for _, tt := range tests {
+		if err := tt.opts.Validate(); (err == nil) != tt.valid {
+			t.Errorf("Validate(%+v) = %v, want valid %v", tt.opts, err, tt.valid)
+		}
+	}
+
+	if name := defaultSessionName(); len(name) > maxSessionNameLength || sessionNameInvalid.MatchString(name) {
+		t.Errorf("defaultSessionName() = %q", name)
+	}
+}
+
+func TestGetCallerIdentity(t *testing.T) {
+	identity, err := getCallerIdentity(context.Background(), &fakeSTS{})
+	if err != nil {
+		t.Fatalf("getCallerIdentity() error = %v", err)
+	}
+	if identity.Account != "210987654321" || !strings.Contains(identity.ARN, "assumed-role/ResearchDeployer") {
+		t.Errorf("identity = %+v", identity)
+	}
+}
+
+func TestNewClientAssumesRole(t *testing.T) {
+	if err := SetAssumeRole(&AssumeRoleOptions{RoleARN: "not-a-role"}); err == nil {
+		t.Error("SetAssumeRole() should reject an invalid ARN")
+	}
+	if err := SetAssumeRole(&AssumeRoleOptions{RoleARN: deployerRole}); err != nil {
+		t.Fatalf("SetAssumeRole() error = %v", err)
+	}
+	defer SetAssumeRole(nil)
+
+	client, err := NewClient(context.Background(), "us-west-2")
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	if client.AssumedRole != deployerRole {
+		t.Errorf("AssumedRole = %q", client.AssumedRole)
+	}
+	if _, ok := client.cfg.Credentials.(*aws.CredentialsCache); !ok {
+		t.Errorf("credentials = %T, want a refreshing cache", client.cfg.Credentials)
+	}
+}
@@ -0,0 +1,101 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+// ElasticIPHourlyCost is the price of a public IPv4 address. An Elastic IP
+// address is billed whether or not its instance is running, so it keeps
+// costing this while the instance is stopped.
+const ElasticIPHourlyCost = 0.005
+
+// ElasticIPMonthlyCost is ElasticIPHourlyCost over an average month
+const ElasticIPMonthlyCost = ElasticIPHourlyCost * hoursPerMonth
+
+// IdleElasticIP is an Elastic IP address that is billed without serving a
+// running instance
+type IdleElasticIP struct {
+	PublicIP     string
+	AllocationID string
+	// InstanceID and InstanceState are empty for an unassociated address
+	InstanceID    string
+	InstanceState string
+	// StackName is the stack that created the address, if any
+	StackName   string
+	MonthlyCost float64
+}
+
+// Reason describes why the address is idle
+func (a IdleElasticIP) Reason() string {
+	if a.InstanceID == "" {
+		return "not associated with an instance"
+	}
+	return fmt.Sprintf("instance %s is %s", a.InstanceID, a.InstanceState)
+}
+
+// ListIdleElasticIPs returns the Elastic IP addresses in the region that are
+// unassociated or associated with an instance that is not running
+func (mm *MonitoringManager) ListIdleElasticIPs(ctx context.Context) ([]IdleElasticIP, error) {
+	result, err := mm.client.EC2.DescribeAddresses(ctx, &ec2.DescribeAddressesInput{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe Elastic IP addresses: %w", err)
+	}
+
+	var instanceIDs []string
+	for _, address := range result.Addresses {
+		if id := aws.ToString(address.InstanceId); id != "" {
+			instanceIDs = append(instanceIDs, id)
+		}
+	}
+	states := make(map[string]string, len(instanceIDs))
+	if len(instanceIDs) > 0 {
+		instances, err := listInstances(ctx, mm.client.EC2, map[string][]string{"instance-id": instanceIDs})
+		if err != nil {
+			return nil, err
+		}
+		for _, instance := range instances {
+			states[instance.InstanceID] = instance.State
+		}
+	}
+	return idleElasticIPs(result.Addresses, states), nil
+}
+
+// idleElasticIPs picks the addresses whose instance is missing from states
+// or in a state other than running, sorted by public IP address
+func idleElasticIPs(addresses []ec2types.Address, states map[string]string) []IdleElasticIP {
+	var idle []IdleElasticIP
+	for _, address := range addresses {
+		instanceID := aws.ToString(address.InstanceId)
+		state := states[instanceID]
+		if instanceID != "" && state == string(ec2types.InstanceStateNameRunning) {
+			continue
+		}
+		if instanceID != "" && state == "" {
+			state = "unknown"
+		}
+
+		entry := IdleElasticIP{
+			PublicIP:      aws.ToString(address.PublicIp),
+			AllocationID:  aws.ToString(address.AllocationId),
+			InstanceID:    instanceID,
+			InstanceState: state,
+			MonthlyCost:   ElasticIPMonthlyCost,
+		}
+		for _, tag := range address.Tags {
+			if aws.ToString(tag.Key) == cfnStackNameTag {
+				entry.StackName = aws.ToString(tag.Value)
+			}
+		}
+		idle = append(idle, entry)
+	}
+	sort.Slice(idle, func(i, j int) bool {
+		return idle[i].PublicIP < idle[j].PublicIP
+	})
+	return idle
+}
@@ -0,0 +1,44 @@
+package aws
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+func TestIdleElasticIPs(t *testing.T) {
+	addresses := []ec2types.Address{
+		{PublicIp: aws.String("203.0.113.30"), AllocationId: aws.String("eipalloc-3"), InstanceId: aws.String("i-running")},
+		{
+			PublicIp:     aws.String("203.0.113.20"),
+			AllocationId: aws.String("eipalloc-2"),
+			InstanceId:   aws.String("i-stopped"),
+			Tags:         []ec2types.Tag{{Key: aws.String(cfnStackNameTag), Value: aws.String("research-wizard-genomics")}},
+		},
+		{PublicIp: aws.String("203.0.113.10"), AllocationId: aws.String("eipalloc-1")},
+		{PublicIp: aws.String("203.0.113.40"), AllocationId: aws.String("eipalloc-4"), InstanceId: aws.String("i-terminated")},
+	}
+	states := map[string]string{"i-running": "running", "i-stopped": "stopped"}
+
+	idle := idleElasticIPs(addresses, states)
+	if len(idle) != 3 {
+		t.Fatalf("idleElasticIPs() = %+v, want 3 addresses", idle)
+	}
+
+	want := []struct {
+		publicIP, stack, reason string
+	}{
+		{"203.0.113.10", "", "not associated with an instance"},
+		{"203.0.113.20", "research-wizard-genomics", "instance i-stopped is stopped"},
+		{"203.0.113.40", "", "instance i-terminated is unknown"},
+	}
+	for i, w := range want {
+		if idle[i].PublicIP != w.publicIP || idle[i].StackName != w.stack || idle[i].Reason() != w.reason {
+			t.Errorf("idle[%d] = %+v (%s), want %s in %q: %s", i, idle[i], idle[i].Reason(), w.publicIP, w.stack, w.reason)
+		}
+		if idle[i].MonthlyCost != ElasticIPMonthlyCost {
+			t.Errorf("idle[%d] costs $%.2f/month, want $%.2f", i, idle[i].MonthlyCost, ElasticIPMonthlyCost)
+		}
+	}
+}
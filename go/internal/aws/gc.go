@@ -0,0 +1,753 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/efs"
+	efstypes "github.com/aws/aws-sdk-go-v2/service/efs/types"
+)
+
+// EFSStorageCostPerGBMonth is the EFS Standard storage price
+const EFSStorageCostPerGBMonth = 0.30
+
+// Kinds of resource swept by garbage collection
+const (
+	GCKindFileSystem    = "efs"
+	GCKindSecurityGroup = "security-group"
+	GCKindSnapshot      = "snapshot"
+	GCKindKeyPair       = "key-pair"
+)
+
+// gcDeletionOrder deletes file systems first, so their mount target network
+// interfaces are gone before the security groups they use
+var gcDeletionOrder = map[string]int{
+	GCKindFileSystem:    0,
+	GCKindSecurityGroup: 1,
+	GCKindSnapshot:      2,
+	GCKindKeyPair:       3,
+}
+
+// Tags that tie a resource to the wizard and to a stack
+const (
+	createdByTagKey   = "CreatedBy"
+	createdByTagValue = "AWS-Research-Wizard"
+	stackNameTagKey   = "StackName"
+	cfnStackNameTag   = "aws:cloudformation:stack-name"
+	cfnStackIDTag     = "aws:cloudformation:stack-id"
+)
+
+// GCNetworkInterface is a network interface using a swept security group or
+// created for a file system mount target
+type GCNetworkInterface struct {
+	ID         string
+	Status     string
+	InstanceID string
+}
+
+// InUse reports whether the interface is attached to something
+func (n GCNetworkInterface) InUse() bool {
+	return n.Status != string(ec2types.NetworkInterfaceStatusAvailable)
+}
+
+// GCResource is a wizard-tagged resource found by a sweep
+type GCResource struct {
+	Kind string
+	ID   string
+	Name string
+	Tags map[string]string
+	// CreatedAt is zero when the age is unknown
+	CreatedAt   time.Time
+	MonthlyCost float64
+	Detail      string
+	// NetworkInterfaces use a security group, or belong to a file system's mount targets
+	NetworkInterfaces []GCNetworkInterface
+	// MountTargets are a file system's mount target IDs
+	MountTargets []string
+	// References are other security groups named in a security group's rules
+	References []string
+}
+
+// GCIndex records which stacks exist, from live DescribeStacks and the local state file
+type GCIndex struct {
+	stacks    map[string]bool
+	stackIDs  map[string]bool
+	keyPairs  map[string]string
+	snapshots map[string]string
+}
+
+// NewGCIndex creates an empty index
+func NewGCIndex() *GCIndex {
+	return &GCIndex{
+		stacks:    make(map[string]bool),
+		stackIDs:  make(map[string]bool),
+		keyPairs:  make(map[string]string),
+		snapshots: make(map[string]string),
+	}
+}
+
+// AddStack records a stack returned by DescribeStacks. Deleted stacks own nothing.
+func (i *GCIndex) AddStack(stack StackInfo) {
+	if stack.Status == StackStatusDeleteComplete {
+		return
+	}
+	i.stacks[stack.StackName] = true
+	if stack.StackID != "" {
+		i.stackIDs[stack.StackID] = true
+	}
+	if keyName := stack.Parameters["KeyName"]; keyName != "" {
+		i.keyPairs[keyName] = stack.StackName
+	}
+}
+
+// AddStateSnapshot records a snapshot the local state file lists for a stack
+func (i *GCIndex) AddStateSnapshot(snapshotID, stackName string) {
+	i.snapshots[snapshotID] = stackName
+}
+
+// Live reports whether a stack exists
+func (i *GCIndex) Live(stackName string) bool {
+	return i.stacks[stackName]
+}
+
+// owner finds the stack a resource belongs to. It returns the stack name, if
+// any, and an empty reason when the stack still exists, or why the resource is
+// orphaned otherwise.
+func (i *GCIndex) owner(resource GCResource) (string, string) {
+	if resource.Kind == GCKindKeyPair {
+		if stackName, used := i.keyPairs[resource.Name]; used {
+			return stackName, ""
+		}
+	}
+
+	stackName := resource.Tags[cfnStackNameTag]
+	if stackName == "" {
+		stackName = resource.Tags[stackNameTagKey]
+	}
+	source := ""
+	if stackName == "" && resource.Kind == GCKindSnapshot {
+		stackName = i.snapshots[resource.ID]
+		source = " (from local state)"
+	}
+
+	// The stack ID tells a deleted stack from a newer one with the same name
+	if stackID := resource.Tags[cfnStackIDTag]; stackID != "" {
+		if i.stackIDs[stackID] {
+			return stackName, ""
+		}
+		if i.Live(stackName) {
+			return stackName, fmt.Sprintf("left by an earlier stack named %s", stackName)
+		}
+		return stackName, fmt.Sprintf("stack %s no longer exists", stackName)
+	}
+
+	switch {
+	case stackName == "":
+		if resource.Kind == GCKindKeyPair {
+			return "", "not used by any stack"
+		}
+		return "", "not linked to any stack"
+	case i.Live(stackName):
+		return stackName, ""
+	default:
+		return stackName, fmt.Sprintf("stack %s no longer exists%s", stackName, source)
+	}
+}
+
+// GCFinding is a resource considered for deletion
+type GCFinding struct {
+	GCResource
+	StackName string
+	// Age is zero when the creation time is unknown
+	Age    time.Duration
+	Reason string
+}
+
+// GCPlan lists the orphaned resources a sweep would delete
+type GCPlan struct {
+	// Orphans are in deletion order
+	Orphans []GCFinding
+	// Skipped are orphans kept because of their age or something still using them
+	Skipped []GCFinding
+	// Associated counts wizard resources that belong to an existing stack
+	Associated  int
+	MonthlyCost float64
+}
+
+// PlanGC matches wizard-tagged resources against the existing stacks. Orphans
+// younger than olderThan, or of unknown age when olderThan is set, are skipped.
+// Resources without the CreatedBy=AWS-Research-Wizard tag are never considered.
+func PlanGC(resources []GCResource, index *GCIndex, now time.Time, olderThan time.Duration) *GCPlan {
+	plan := &GCPlan{}
+
+	var candidates []GCFinding
+	for _, resource := range resources {
+		if resource.Tags[createdByTagKey] != createdByTagValue {
+			continue
+		}
+		stackName, reason := index.owner(resource)
+		if reason == "" {
+			plan.Associated++
+			continue
+		}
+
+		finding := GCFinding{GCResource: resource, StackName: stackName, Reason: reason}
+		if !resource.CreatedAt.IsZero() && now.After(resource.CreatedAt) {
+			finding.Age = now.Sub(resource.CreatedAt)
+		}
+
+		switch {
+		case olderThan > 0 && resource.CreatedAt.IsZero():
+			finding.Reason = "age unknown"
+			plan.Skipped = append(plan.Skipped, finding)
+		case finding.Age < olderThan:
+			finding.Reason = fmt.Sprintf("younger than %s", formatGCAge(olderThan))
+			plan.Skipped = append(plan.Skipped, finding)
+		default:
+			candidates = append(candidates, finding)
+		}
+	}
+
+	orphans, blocked := resolveGCDependencies(candidates, resources)
+	plan.Skipped = append(plan.Skipped, blocked...)
+	plan.Orphans = orderGCDeletions(orphans)
+	for _, orphan := range plan.Orphans {
+		plan.MonthlyCost += orphan.MonthlyCost
+	}
+
+	sort.SliceStable(plan.Skipped, func(a, b int) bool {
+		return gcDeletionOrder[plan.Skipped[a].Kind] < gcDeletionOrder[plan.Skipped[b].Kind]
+	})
+	return plan
+}
+
+// resolveGCDependencies skips security groups that cannot be deleted: groups
+// attached to an interface that no orphan file system frees, and groups named
+// in the rules of a group that is staying. Skipping one group can keep another,
+// so this repeats until nothing changes.
+func resolveGCDependencies(candidates []GCFinding, resources []GCResource) ([]GCFinding, []GCFinding) {
+	var blocked []GCFinding
+	for {
+		deleting := make(map[string]bool, len(candidates))
+		freed := make(map[string]bool)
+		for _, candidate := range candidates {
+			deleting[candidate.ID] = true
+			if candidate.Kind == GCKindFileSystem {
+				for _, eni := range candidate.NetworkInterfaces {
+					freed[eni.ID] = true
+				}
+			}
+		}
+
+		referencedBy := make(map[string]string)
+		for _, resource := range resources {
+			if resource.Kind != GCKindSecurityGroup || deleting[resource.ID] {
+				continue
+			}
+			for _, groupID := range resource.References {
+				referencedBy[groupID] = resource.ID
+			}
+		}
+
+		kept := candidates[:0:0]
+		changed := false
+		for _, candidate := range candidates {
+			if candidate.Kind == GCKindSecurityGroup {
+				if reason := securityGroupBlocker(candidate, freed, referencedBy); reason != "" {
+					candidate.Reason = reason
+					blocked = append(blocked, candidate)
+					changed = true
+					continue
+				}
+			}
+			kept = append(kept, candidate)
+		}
+		candidates = kept
+		if !changed {
+			return candidates, blocked
+		}
+	}
+}
+
+func securityGroupBlocker(group GCFinding, freed map[string]bool, referencedBy map[string]string) string {
+	for _, eni := range group.NetworkInterfaces {
+		if !eni.InUse() || freed[eni.ID] {
+			continue
+		}
+		if eni.InstanceID != "" {
+			return fmt.Sprintf("in use by %s (instance %s)", eni.ID, eni.InstanceID)
+		}
+		return fmt.Sprintf("in use by %s", eni.ID)
+	}
+	if groupID, referenced := referencedBy[group.ID]; referenced {
+		return fmt.Sprintf("referenced by %s", groupID)
+	}
+	return ""
+}
+
+// orderGCDeletions sorts orphans by kind, and security groups so that a group is
+// deleted before the groups its rules reference
+func orderGCDeletions(orphans []GCFinding) []GCFinding {
+	referencing := make(map[string][]string)
+	for _, orphan := range orphans {
+		if orphan.Kind != GCKindSecurityGroup {
+			continue
+		}
+		for _, groupID := range orphan.References {
+			if groupID != orphan.ID {
+				referencing[groupID] = append(referencing[groupID], orphan.ID)
+			}
+		}
+	}
+
+	// depth is how many groups must go before this one; cycles stop at the group count
+	depth := make(map[string]int)
+	var visit func(id string, seen map[string]bool) int
+	visit = func(id string, seen map[string]bool) int {
+		if d, done := depth[id]; done {
+			return d
+		}
+		if seen[id] {
+			return 0
+		}
+		seen[id] = true
+		d := 0
+		for _, parent := range referencing[id] {
+			if pd := visit(parent, seen) + 1; pd > d {
+				d = pd
+			}
+		}
+		depth[id] = d
+		return d
+	}
+	for _, orphan := range orphans {
+		if orphan.Kind == GCKindSecurityGroup {
+			visit(orphan.ID, make(map[string]bool))
+		}
+	}
+
+	ordered := append([]GCFinding(nil), orphans...)
+	sort.SliceStable(ordered, func(a, b int) bool {
+		ka, kb := gcDeletionOrder[ordered[a].Kind], gcDeletionOrder[ordered[b].Kind]
+		if ka != kb {
+			return ka < kb
+		}
+		if da, db := depth[ordered[a].ID], depth[ordered[b].ID]; da != db {
+			return da < db
+		}
+		return ordered[a].ID < ordered[b].ID
+	})
+	return ordered
+}
+
+// formatGCAge renders an age in whole days, or hours under a day
+func formatGCAge(d time.Duration) string {
+	if d < 24*time.Hour {
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	}
+	return fmt.Sprintf("%dd", int(d.Hours()/24))
+}
+
+// gcEC2API is the subset of the EC2 API used to sweep orphaned resources
+type gcEC2API interface {
+	DescribeSecurityGroups(ctx context.Context, params *ec2.DescribeSecurityGroupsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeSecurityGroupsOutput, error)
+	DescribeNetworkInterfaces(ctx context.Context, params *ec2.DescribeNetworkInterfacesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeNetworkInterfacesOutput, error)
+	DescribeKeyPairs(ctx context.Context, params *ec2.DescribeKeyPairsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeKeyPairsOutput, error)
+	DescribeSnapshots(ctx context.Context, params *ec2.DescribeSnapshotsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeSnapshotsOutput, error)
+	DeleteNetworkInterface(ctx context.Context, params *ec2.DeleteNetworkInterfaceInput, optFns ...func(*ec2.Options)) (*ec2.DeleteNetworkInterfaceOutput, error)
+	DeleteSecurityGroup(ctx context.Context, params *ec2.DeleteSecurityGroupInput, optFns ...func(*ec2.Options)) (*ec2.DeleteSecurityGroupOutput, error)
+	DeleteKeyPair(ctx context.Context, params *ec2.DeleteKeyPairInput, optFns ...func(*ec2.Options)) (*ec2.DeleteKeyPairOutput, error)
+	DeleteSnapshot(ctx context.Context, params *ec2.DeleteSnapshotInput, optFns ...func(*ec2.Options)) (*ec2.DeleteSnapshotOutput, error)
+}
+
+// gcEFSAPI is the subset of the EFS API used to sweep orphaned file systems
+type gcEFSAPI interface {
+	DescribeFileSystems(ctx context.Context, params *efs.DescribeFileSystemsInput, optFns ...func(*efs.Options)) (*efs.DescribeFileSystemsOutput, error)
+	DescribeMountTargets(ctx context.Context, params *efs.DescribeMountTargetsInput, optFns ...func(*efs.Options)) (*efs.DescribeMountTargetsOutput, error)
+	DeleteMountTarget(ctx context.Context, params *efs.DeleteMountTargetInput, optFns ...func(*efs.Options)) (*efs.DeleteMountTargetOutput, error)
+	DeleteFileSystem(ctx context.Context, params *efs.DeleteFileSystemInput, optFns ...func(*efs.Options)) (*efs.DeleteFileSystemOutput, error)
+}
+
+// GarbageCollector finds and deletes orphaned research wizard resources
+type GarbageCollector struct {
+	ec2          gcEC2API
+	efs          gcEFSAPI
+	stacks       cloudformation.DescribeStacksAPIClient
+	pollInterval time.Duration
+	timeout      time.Duration
+}
+
+// NewGarbageCollector creates a garbage collector for the client's region
+func NewGarbageCollector(client *Client) *GarbageCollector {
+	return &GarbageCollector{
+		ec2:          client.EC2,
+		efs:          client.EFS,
+		stacks:       client.CloudFormation,
+		pollInterval: 10 * time.Second,
+		timeout:      10 * time.Minute,
+	}
+}
+
+// Index lists the existing stacks
+func (gc *GarbageCollector) Index(ctx context.Context) (*GCIndex, error) {
+	index := NewGCIndex()
+	paginator := cloudformation.NewDescribeStacksPaginator(gc.stacks, &cloudformation.DescribeStacksInput{})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe stacks: %w", err)
+		}
+		for _, stack := range page.Stacks {
+			index.AddStack(*newStackInfo(stack))
+		}
+	}
+	return index, nil
+}
+
+// Collect finds every wizard-tagged security group, key pair, snapshot and EFS
+// file system in the region
+func (gc *GarbageCollector) Collect(ctx context.Context) ([]GCResource, error) {
+	var resources []GCResource
+	for _, collect := range []func(context.Context) ([]GCResource, error){
+		gc.collectFileSystems,
+		gc.collectSecurityGroups,
+		gc.collectSnapshots,
+		gc.collectKeyPairs,
+	} {
+		found, err := collect(ctx)
+		if err != nil {
+			return nil, err
+		}
+		resources = append(resources, found...)
+	}
+	return resources, nil
+}
+
+func wizardTagFilter() []ec2types.Filter {
+	return []ec2types.Filter{
+		{Name: aws.String("tag:" + createdByTagKey), Values: []string{createdByTagValue}},
+	}
+}
+
+func ec2TagMap(tags []ec2types.Tag) map[string]string {
+	result := make(map[string]string, len(tags))
+	for _, tag := range tags {
+		if tag.Key != nil && tag.Value != nil {
+			result[*tag.Key] = *tag.Value
+		}
+	}
+	return result
+}
+
+func (gc *GarbageCollector) collectSecurityGroups(ctx context.Context) ([]GCResource, error) {
+	var resources []GCResource
+	paginator := ec2.NewDescribeSecurityGroupsPaginator(gc.ec2, &ec2.DescribeSecurityGroupsInput{Filters: wizardTagFilter()})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe security groups: %w", err)
+		}
+		for _, group := range page.SecurityGroups {
+			resources = append(resources, GCResource{
+				Kind:       GCKindSecurityGroup,
+				ID:         aws.ToString(group.GroupId),
+				Name:       aws.ToString(group.GroupName),
+				Tags:       ec2TagMap(group.Tags),
+				Detail:     aws.ToString(group.VpcId),
+				References: securityGroupReferences(group),
+			})
+		}
+	}
+	if len(resources) == 0 {
+		return nil, nil
+	}
+
+	// Security groups have no creation time; use the creation time of the stack that made them
+	created := make(map[string]time.Time)
+	for i := range resources {
+		stackID := resources[i].Tags[cfnStackIDTag]
+		if stackID == "" {
+			continue
+		}
+		if _, done := created[stackID]; !done {
+			created[stackID] = gc.stackCreated(ctx, stackID)
+		}
+		resources[i].CreatedAt = created[stackID]
+	}
+
+	groupIDs := make([]string, len(resources))
+	for i, resource := range resources {
+		groupIDs[i] = resource.ID
+	}
+	interfaces, err := gc.groupInterfaces(ctx, groupIDs)
+	if err != nil {
+		return nil, err
+	}
+	for i := range resources {
+		resources[i].NetworkInterfaces = interfaces[resources[i].ID]
+	}
+	return resources, nil
+}
+
+// securityGroupReferences lists the other groups a group's rules refer to
+func securityGroupReferences(group ec2types.SecurityGroup) []string {
+	seen := map[string]bool{aws.ToString(group.GroupId): true}
+	var references []string
+	for _, permission := range append(append([]ec2types.IpPermission(nil), group.IpPermissions...), group.IpPermissionsEgress...) {
+		for _, pair := range permission.UserIdGroupPairs {
+			groupID := aws.ToString(pair.GroupId)
+			if groupID != "" && !seen[groupID] {
+				seen[groupID] = true
+				references = append(references, groupID)
+			}
+		}
+	}
+	return references
+}
+
+// stackCreated returns when a stack, possibly deleted, was created, or zero if it cannot be described
+func (gc *GarbageCollector) stackCreated(ctx context.Context, stackID string) time.Time {
+	result, err := gc.stacks.DescribeStacks(ctx, &cloudformation.DescribeStacksInput{StackName: aws.String(stackID)})
+	if err != nil || len(result.Stacks) == 0 {
+		return time.Time{}
+	}
+	return aws.ToTime(result.Stacks[0].CreationTime)
+}
+
+// groupInterfaces returns the network interfaces using each security group
+func (gc *GarbageCollector) groupInterfaces(ctx context.Context, groupIDs []string) (map[string][]GCNetworkInterface, error) {
+	interfaces := make(map[string][]GCNetworkInterface)
+	paginator := ec2.NewDescribeNetworkInterfacesPaginator(gc.ec2, &ec2.DescribeNetworkInterfacesInput{
+		Filters: []ec2types.Filter{{Name: aws.String("group-id"), Values: groupIDs}},
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe network interfaces: %w", err)
+		}
+		for _, eni := range page.NetworkInterfaces {
+			info := GCNetworkInterface{
+				ID:     aws.ToString(eni.NetworkInterfaceId),
+				Status: string(eni.Status),
+			}
+			if eni.Attachment != nil {
+				info.InstanceID = aws.ToString(eni.Attachment.InstanceId)
+			}
+			for _, group := range eni.Groups {
+				groupID := aws.ToString(group.GroupId)
+				interfaces[groupID] = append(interfaces[groupID], info)
+			}
+		}
+	}
+	return interfaces, nil
+}
+
+func (gc *GarbageCollector) collectSnapshots(ctx context.Context) ([]GCResource, error) {
+	var resources []GCResource
+	paginator := ec2.NewDescribeSnapshotsPaginator(gc.ec2, &ec2.DescribeSnapshotsInput{
+		OwnerIds: []string{"self"},
+		Filters:  wizardTagFilter(),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe snapshots: %w", err)
+		}
+		for _, snapshot := range page.Snapshots {
+			info := newSnapshotInfo(snapshot)
+			resources = append(resources, GCResource{
+				Kind:        GCKindSnapshot,
+				ID:          info.SnapshotID,
+				Name:        info.Tags["Name"],
+				Tags:        info.Tags,
+				CreatedAt:   info.StartTime,
+				MonthlyCost: info.MonthlyCost,
+				Detail:      fmt.Sprintf("%d GB from %s", info.SizeGB, info.VolumeID),
+			})
+		}
+	}
+	return resources, nil
+}
+
+func (gc *GarbageCollector) collectKeyPairs(ctx context.Context) ([]GCResource, error) {
+	result, err := gc.ec2.DescribeKeyPairs(ctx, &ec2.DescribeKeyPairsInput{Filters: wizardTagFilter()})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe key pairs: %w", err)
+	}
+
+	resources := make([]GCResource, 0, len(result.KeyPairs))
+	for _, keyPair := range result.KeyPairs {
+		resources = append(resources, GCResource{
+			Kind:      GCKindKeyPair,
+			ID:        aws.ToString(keyPair.KeyPairId),
+			Name:      aws.ToString(keyPair.KeyName),
+			Tags:      ec2TagMap(keyPair.Tags),
+			CreatedAt: aws.ToTime(keyPair.CreateTime),
+			Detail:    string(keyPair.KeyType),
+		})
+	}
+	return resources, nil
+}
+
+func (gc *GarbageCollector) collectFileSystems(ctx context.Context) ([]GCResource, error) {
+	var resources []GCResource
+	paginator := efs.NewDescribeFileSystemsPaginator(gc.efs, &efs.DescribeFileSystemsInput{})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe file systems: %w", err)
+		}
+		for _, fileSystem := range page.FileSystems {
+			tags := efsTagMap(fileSystem.Tags)
+			if tags[createdByTagKey] != createdByTagValue {
+				continue
+			}
+
+			var sizeGB float64
+			if fileSystem.SizeInBytes != nil {
+				sizeGB = float64(fileSystem.SizeInBytes.Value) / (1 << 30)
+			}
+			resource := GCResource{
+				Kind:        GCKindFileSystem,
+				ID:          aws.ToString(fileSystem.FileSystemId),
+				Name:        aws.ToString(fileSystem.Name),
+				Tags:        tags,
+				CreatedAt:   aws.ToTime(fileSystem.CreationTime),
+				MonthlyCost: sizeGB * EFSStorageCostPerGBMonth,
+				Detail:      fmt.Sprintf("%.1f GB, %d mount targets", sizeGB, fileSystem.NumberOfMountTargets),
+			}
+
+			targets, err := gc.efs.DescribeMountTargets(ctx, &efs.DescribeMountTargetsInput{FileSystemId: fileSystem.FileSystemId})
+			if err != nil {
+				return nil, fmt.Errorf("failed to describe mount targets for %s: %w", resource.ID, err)
+			}
+			for _, target := range targets.MountTargets {
+				resource.MountTargets = append(resource.MountTargets, aws.ToString(target.MountTargetId))
+				resource.NetworkInterfaces = append(resource.NetworkInterfaces, GCNetworkInterface{
+					ID:     aws.ToString(target.NetworkInterfaceId),
+					Status: string(ec2types.NetworkInterfaceStatusInUse),
+				})
+			}
+			resources = append(resources, resource)
+		}
+	}
+	return resources, nil
+}
+
+func efsTagMap(tags []efstypes.Tag) map[string]string {
+	result := make(map[string]string, len(tags))
+	for _, tag := range tags {
+		if tag.Key != nil && tag.Value != nil {
+			result[*tag.Key] = *tag.Value
+		}
+	}
+	return result
+}
+
+// GCResult is the outcome of deleting one orphan
+type GCResult struct {
+	Finding GCFinding
+	Err     error
+}
+
+// Delete removes the orphans in order. A failure is recorded for that resource
+// and the sweep moves on to the next.
+func (gc *GarbageCollector) Delete(ctx context.Context, orphans []GCFinding) []GCResult {
+	results := make([]GCResult, 0, len(orphans))
+	for _, orphan := range orphans {
+		results = append(results, GCResult{Finding: orphan, Err: gc.delete(ctx, orphan)})
+	}
+	return results
+}
+
+func (gc *GarbageCollector) delete(ctx context.Context, orphan GCFinding) error {
+	switch orphan.Kind {
+	case GCKindFileSystem:
+		return gc.deleteFileSystem(ctx, orphan)
+	case GCKindSecurityGroup:
+		return gc.deleteSecurityGroup(ctx, orphan)
+	case GCKindSnapshot:
+		if _, err := gc.ec2.DeleteSnapshot(ctx, &ec2.DeleteSnapshotInput{SnapshotId: aws.String(orphan.ID)}); err != nil {
+			return fmt.Errorf("failed to delete snapshot: %w", err)
+		}
+		return nil
+	case GCKindKeyPair:
+		if _, err := gc.ec2.DeleteKeyPair(ctx, &ec2.DeleteKeyPairInput{KeyPairId: aws.String(orphan.ID)}); err != nil {
+			return fmt.Errorf("failed to delete key pair: %w", err)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown resource kind %q", orphan.Kind)
+	}
+}
+
+// deleteFileSystem removes the mount targets, waits for them to go, then deletes the file system
+func (gc *GarbageCollector) deleteFileSystem(ctx context.Context, orphan GCFinding) error {
+	for _, targetID := range orphan.MountTargets {
+		if _, err := gc.efs.DeleteMountTarget(ctx, &efs.DeleteMountTargetInput{MountTargetId: aws.String(targetID)}); err != nil {
+			return fmt.Errorf("failed to delete mount target %s: %w", targetID, err)
+		}
+	}
+
+	if len(orphan.MountTargets) > 0 {
+		err := gc.poll(ctx, "mount targets of "+orphan.ID+" to be deleted", func() (bool, error) {
+			result, err := gc.efs.DescribeMountTargets(ctx, &efs.DescribeMountTargetsInput{FileSystemId: aws.String(orphan.ID)})
+			if err != nil {
+				return false, fmt.Errorf("failed to describe mount targets: %w", err)
+			}
+			return len(result.MountTargets) == 0, nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if _, err := gc.efs.DeleteFileSystem(ctx, &efs.DeleteFileSystemInput{FileSystemId: aws.String(orphan.ID)}); err != nil {
+		return fmt.Errorf("failed to delete file system: %w", err)
+	}
+	return nil
+}
+
+// deleteSecurityGroup removes detached network interfaces still using the group, then the group
+func (gc *GarbageCollector) deleteSecurityGroup(ctx context.Context, orphan GCFinding) error {
+	for _, eni := range orphan.NetworkInterfaces {
+		if eni.InUse() {
+			continue // mount target interfaces go with their file system
+		}
+		if _, err := gc.ec2.DeleteNetworkInterface(ctx, &ec2.DeleteNetworkInterfaceInput{NetworkInterfaceId: aws.String(eni.ID)}); err != nil {
+			return fmt.Errorf("failed to delete network interface %s: %w", eni.ID, err)
+		}
+	}
+
+	if _, err := gc.ec2.DeleteSecurityGroup(ctx, &ec2.DeleteSecurityGroupInput{GroupId: aws.String(orphan.ID)}); err != nil {
+		return fmt.Errorf("failed to delete security group: %w", err)
+	}
+	return nil
+}
+
+func (gc *GarbageCollector) poll(ctx context.Context, what string, check func() (bool, error)) error {
+	ctx, cancel := context.WithTimeout(ctx, gc.timeout)
+	defer cancel()
+
+	for {
+		done, err := check()
+		if err != nil {
+			return err
+		}
+		if done {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("timeout waiting for %s", what)
+		case <-time.After(jitteredInterval(gc.pollInterval)):
+		}
+	}
+}
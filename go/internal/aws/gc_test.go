@@ -0,0 +1,437 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/efs"
+	efstypes "github.com/aws/aws-sdk-go-v2/service/efs/types"
+)
+
+var gcNow = time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+
+const (
+	liveStackID    = "arn:aws:cloudformation:us-east-1:123456789012:stack/live/1111"
+	deletedStackID = "arn:aws:cloudformation:us-east-1:123456789012:stack/gone/2222"
+	earlierStackID = "arn:aws:cloudformation:us-east-1:123456789012:stack/live/0000"
+)
+
+// wizardTags returns the tags CloudFormation puts on a resource of the named stack
+func wizardTags(stackName, stackID string) map[string]string {
+	tags := map[string]string{createdByTagKey: createdByTagValue}
+	if stackName != "" {
+		tags[cfnStackNameTag] = stackName
+	}
+	if stackID != "" {
+		tags[cfnStackIDTag] = stackID
+	}
+	return tags
+}
+
+func daysAgo(days int) time.Time {
+	return gcNow.Add(-time.Duration(days) * 24 * time.Hour)
+}
+
+func gcTestIndex() *GCIndex {
+	index := NewGCIndex()
+	index.AddStack(StackInfo{
+		StackName:  "live",
+		StackID:    liveStackID,
+		Status:     StackStatusCreateComplete,
+		Parameters: map[string]string{"KeyName": "live-key"},
+	})
+	index.AddStack(StackInfo{StackName: "deleting", StackID: "deleting-id", Status: StackStatusDeleteInProgress})
+	index.AddStack(StackInfo{StackName: "finished", StackID: "finished-id", Status: StackStatusDeleteComplete})
+	index.AddStateSnapshot("snap-state-live", "live")
+	index.AddStateSnapshot("snap-state-gone", "gone")
+	return index
+}
+
+func TestPlanGCMatcher(t *testing.T) {
+	tests := []struct {
+		name       string
+		resource   GCResource
+		wantOrphan bool
+		wantStack  string
+		wantReason string
+	}{
+		{
+			name:     "security group of live stack",
+			resource: GCResource{Kind: GCKindSecurityGroup, ID: "sg-live", Tags: wizardTags("live", liveStackID)},
+		},
+		{
+			name:       "security group of deleted stack",
+			resource:   GCResource{Kind: GCKindSecurityGroup, ID: "sg-gone", Tags: wizardTags("gone", deletedStackID)},
+			wantOrphan: true, wantStack: "gone", wantReason: "stack gone no longer exists",
+		},
+		{
+			name:       "security group of an earlier stack with a reused name",
+			resource:   GCResource{Kind: GCKindSecurityGroup, ID: "sg-old", Tags: wizardTags("live", earlierStackID)},
+			wantOrphan: true, wantStack: "live", wantReason: "earlier stack named live",
+		},
+		{
+			name:     "file system of a stack being deleted",
+			resource: GCResource{Kind: GCKindFileSystem, ID: "fs-deleting", Tags: wizardTags("deleting", "deleting-id")},
+		},
+		{
+			name:       "file system of a completed deletion",
+			resource:   GCResource{Kind: GCKindFileSystem, ID: "fs-finished", Tags: wizardTags("finished", "finished-id")},
+			wantOrphan: true, wantStack: "finished", wantReason: "stack finished no longer exists",
+		},
+		{
+			name: "snapshot tagged with a live stack",
+			resource: GCResource{Kind: GCKindSnapshot, ID: "snap-live",
+				Tags: map[string]string{createdByTagKey: createdByTagValue, stackNameTagKey: "live"}},
+		},
+		{
+			name: "snapshot tagged with a deleted stack",
+			resource: GCResource{Kind: GCKindSnapshot, ID: "snap-gone",
+				Tags: map[string]string{createdByTagKey: createdByTagValue, stackNameTagKey: "gone"}},
+			wantOrphan: true, wantStack: "gone", wantReason: "stack gone no longer exists",
+		},
+		{
+			name:     "untagged snapshot recorded in state for a live stack",
+			resource: GCResource{Kind: GCKindSnapshot, ID: "snap-state-live", Tags: wizardTags("", "")},
+		},
+		{
+			name:       "untagged snapshot recorded in state for a deleted stack",
+			resource:   GCResource{Kind: GCKindSnapshot, ID: "snap-state-gone", Tags: wizardTags("", "")},
+			wantOrphan: true, wantStack: "gone", wantReason: "from local state",
+		},
+		{
+			name:       "snapshot linked to nothing",
+			resource:   GCResource{Kind: GCKindSnapshot, ID: "snap-stray", Tags: wizardTags("", "")},
+			wantOrphan: true, wantReason: "not linked to any stack",
+		},
+		{
+			name:     "key pair used by a live stack",
+			resource: GCResource{Kind: GCKindKeyPair, ID: "key-1", Name: "live-key", Tags: wizardTags("", "")},
+		},
+		{
+			name:       "key pair used by no stack",
+			resource:   GCResource{Kind: GCKindKeyPair, ID: "key-2", Name: "old-key", Tags: wizardTags("", "")},
+			wantOrphan: true, wantReason: "not used by any stack",
+		},
+		{
+			name:     "key pair tagged with a deleted stack but used by a live one",
+			resource: GCResource{Kind: GCKindKeyPair, ID: "key-3", Name: "live-key", Tags: wizardTags("gone", deletedStackID)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.resource.CreatedAt = daysAgo(30)
+			plan := PlanGC([]GCResource{tt.resource}, gcTestIndex(), gcNow, 0)
+
+			if !tt.wantOrphan {
+				if len(plan.Orphans) != 0 || plan.Associated != 1 {
+					t.Fatalf("expected the resource to be associated, got orphans %+v", plan.Orphans)
+				}
+				return
+			}
+			if len(plan.Orphans) != 1 {
+				t.Fatalf("expected one orphan, got %+v (skipped %+v)", plan.Orphans, plan.Skipped)
+			}
+			orphan := plan.Orphans[0]
+			if orphan.StackName != tt.wantStack {
+				t.Errorf("stack = %q, want %q", orphan.StackName, tt.wantStack)
+			}
+			if !strings.Contains(orphan.Reason, tt.wantReason) {
+				t.Errorf("reason = %q, want it to mention %q", orphan.Reason, tt.wantReason)
+			}
+			if orphan.Age != 30*24*time.Hour {
+				t.Errorf("age = %v, want 30 days", orphan.Age)
+			}
+		})
+	}
+}
+
+func TestPlanGCIgnoresResourcesNotCreatedByTheWizard(t *testing.T) {
+	resources := []GCResource{
+		{Kind: GCKindSecurityGroup, ID: "sg-user", Tags: map[string]string{cfnStackNameTag: "gone"}},
+		{Kind: GCKindSnapshot, ID: "snap-other", Tags: map[string]string{createdByTagKey: "someone-else"}},
+		{Kind: GCKindKeyPair, ID: "key-untagged", Name: "untagged"},
+	}
+
+	plan := PlanGC(resources, gcTestIndex(), gcNow, 0)
+	if len(plan.Orphans) != 0 || len(plan.Skipped) != 0 || plan.Associated != 0 {
+		t.Errorf("expected untagged resources to be ignored, got %+v", plan)
+	}
+}
+
+func TestPlanGCAgeFilter(t *testing.T) {
+	resources := []GCResource{
+		{Kind: GCKindSnapshot, ID: "snap-old", Tags: wizardTags("gone", ""), CreatedAt: daysAgo(10)},
+		{Kind: GCKindSnapshot, ID: "snap-new", Tags: wizardTags("gone", ""), CreatedAt: daysAgo(2)},
+		{Kind: GCKindSecurityGroup, ID: "sg-unknown", Tags: wizardTags("gone", deletedStackID)},
+	}
+
+	plan := PlanGC(resources, gcTestIndex(), gcNow, 7*24*time.Hour)
+	if got := findingIDs(plan.Orphans); got != "snap-old" {
+		t.Errorf("orphans = %s, want snap-old", got)
+	}
+	if got := findingIDs(plan.Skipped); got != "sg-unknown,snap-new" {
+		t.Errorf("skipped = %s, want sg-unknown,snap-new", got)
+	}
+	for _, skipped := range plan.Skipped {
+		switch skipped.ID {
+		case "snap-new":
+			if skipped.Reason != "younger than 7d" {
+				t.Errorf("snap-new reason = %q", skipped.Reason)
+			}
+		case "sg-unknown":
+			if skipped.Reason != "age unknown" {
+				t.Errorf("sg-unknown reason = %q", skipped.Reason)
+			}
+		}
+	}
+
+	// Without a filter resources of unknown age are swept too
+	plan = PlanGC(resources, gcTestIndex(), gcNow, 0)
+	if len(plan.Orphans) != 3 || len(plan.Skipped) != 0 {
+		t.Errorf("expected every orphan without a filter, got %s (skipped %s)", findingIDs(plan.Orphans), findingIDs(plan.Skipped))
+	}
+}
+
+// headComputeLeftovers is what a failed head-compute deletion leaves behind: the
+// shared file system with its mount target, the NFS security group that admits
+// the cluster group, and the cluster group itself
+func headComputeLeftovers() []GCResource {
+	tags := wizardTags("gone", deletedStackID)
+	created := daysAgo(20)
+	return []GCResource{
+		{
+			Kind: GCKindSecurityGroup, ID: "sg-cluster", Tags: tags, CreatedAt: created,
+			References: []string{},
+			NetworkInterfaces: []GCNetworkInterface{
+				{ID: "eni-detached", Status: "available"},
+			},
+		},
+		{
+			Kind: GCKindSecurityGroup, ID: "sg-efs", Tags: tags, CreatedAt: created,
+			References:        []string{"sg-cluster"},
+			NetworkInterfaces: []GCNetworkInterface{{ID: "eni-mount", Status: "in-use"}},
+		},
+		{Kind: GCKindSnapshot, ID: "snap-1", Tags: tags, CreatedAt: created, MonthlyCost: 5},
+		{
+			Kind: GCKindFileSystem, ID: "fs-1", Tags: tags, CreatedAt: created, MonthlyCost: 3,
+			MountTargets:      []string{"fsmt-1"},
+			NetworkInterfaces: []GCNetworkInterface{{ID: "eni-mount", Status: "in-use"}},
+		},
+		{Kind: GCKindKeyPair, ID: "key-1", Name: "gone-key", Tags: tags, CreatedAt: created},
+	}
+}
+
+func TestPlanGCDeletionOrder(t *testing.T) {
+	plan := PlanGC(headComputeLeftovers(), gcTestIndex(), gcNow, 7*24*time.Hour)
+
+	// The file system frees its mount target interface, and the NFS group goes
+	// before the cluster group its rule references
+	if got := findingIDs(plan.Orphans); got != "fs-1,sg-efs,sg-cluster,snap-1,key-1" {
+		t.Errorf("deletion order = %s", got)
+	}
+	if len(plan.Skipped) != 0 {
+		t.Errorf("unexpected skipped resources: %+v", plan.Skipped)
+	}
+	if math.Abs(plan.MonthlyCost-8) > 1e-9 {
+		t.Errorf("monthly cost = %f, want 8", plan.MonthlyCost)
+	}
+}
+
+func TestPlanGCBlockedSecurityGroups(t *testing.T) {
+	t.Run("file system kept by the age filter", func(t *testing.T) {
+		resources := headComputeLeftovers()
+		resources[3].CreatedAt = daysAgo(1)
+
+		plan := PlanGC(resources, gcTestIndex(), gcNow, 7*24*time.Hour)
+		if got := findingIDs(plan.Orphans); got != "snap-1,key-1" {
+			t.Errorf("orphans = %s", got)
+		}
+		// The NFS group is still attached to the mount target, and the cluster
+		// group is still referenced by the NFS group
+		reasons := findingReasons(plan.Skipped)
+		if !strings.Contains(reasons["sg-efs"], "in use by eni-mount") {
+			t.Errorf("sg-efs reason = %q", reasons["sg-efs"])
+		}
+		if reasons["sg-cluster"] != "referenced by sg-efs" {
+			t.Errorf("sg-cluster reason = %q", reasons["sg-cluster"])
+		}
+		if !strings.Contains(reasons["fs-1"], "younger than") {
+			t.Errorf("fs-1 reason = %q", reasons["fs-1"])
+		}
+	})
+
+	t.Run("group attached to a running instance", func(t *testing.T) {
+		resources := []GCResource{{
+			Kind: GCKindSecurityGroup, ID: "sg-busy", Tags: wizardTags("gone", deletedStackID), CreatedAt: daysAgo(20),
+			NetworkInterfaces: []GCNetworkInterface{{ID: "eni-1", Status: "in-use", InstanceID: "i-123"}},
+		}}
+		plan := PlanGC(resources, gcTestIndex(), gcNow, 0)
+		if len(plan.Orphans) != 0 || len(plan.Skipped) != 1 {
+			t.Fatalf("expected the group to be skipped, got %+v", plan)
+		}
+		if plan.Skipped[0].Reason != "in use by eni-1 (instance i-123)" {
+			t.Errorf("reason = %q", plan.Skipped[0].Reason)
+		}
+	})
+
+	t.Run("group referenced by a live stack's group", func(t *testing.T) {
+		resources := []GCResource{
+			{Kind: GCKindSecurityGroup, ID: "sg-live", Tags: wizardTags("live", liveStackID), References: []string{"sg-gone"}},
+			{Kind: GCKindSecurityGroup, ID: "sg-gone", Tags: wizardTags("gone", deletedStackID), CreatedAt: daysAgo(20)},
+		}
+		plan := PlanGC(resources, gcTestIndex(), gcNow, 0)
+		if len(plan.Orphans) != 0 || plan.Associated != 1 {
+			t.Fatalf("expected no deletable orphans, got %+v", plan.Orphans)
+		}
+		if plan.Skipped[0].Reason != "referenced by sg-live" {
+			t.Errorf("reason = %q", plan.Skipped[0].Reason)
+		}
+	})
+
+	t.Run("groups referencing each other", func(t *testing.T) {
+		tags := wizardTags("gone", deletedStackID)
+		resources := []GCResource{
+			{Kind: GCKindSecurityGroup, ID: "sg-a", Tags: tags, References: []string{"sg-b"}},
+			{Kind: GCKindSecurityGroup, ID: "sg-b", Tags: tags, References: []string{"sg-a"}},
+		}
+		plan := PlanGC(resources, gcTestIndex(), gcNow, 0)
+		if got := findingIDs(plan.Orphans); got != "sg-a,sg-b" && got != "sg-b,sg-a" {
+			t.Errorf("expected both groups to be swept, got %s", got)
+		}
+	})
+}
+
+func findingIDs(findings []GCFinding) string {
+	ids := make([]string, len(findings))
+	for i, finding := range findings {
+		ids[i] = finding.ID
+	}
+	return strings.Join(ids, ",")
+}
+
+func findingReasons(findings []GCFinding) map[string]string {
+	reasons := make(map[string]string, len(findings))
+	for _, finding := range findings {
+		reasons[finding.ID] = finding.Reason
+	}
+	return reasons
+}
+
+// fakeGCAPI records deletions. Mount targets disappear after one describe, and
+// operations on IDs in failOn return an error.
+type fakeGCAPI struct {
+	gcEC2API
+	gcEFSAPI
+	failOn         map[string]bool
+	mountDescribes int
+	calls          []string
+}
+
+func (f *fakeGCAPI) record(op, id string) error {
+	f.calls = append(f.calls, op+" "+id)
+	if f.failOn[id] {
+		return fmt.Errorf("injected failure for %s", id)
+	}
+	return nil
+}
+
+func (f *fakeGCAPI) DeleteNetworkInterface(ctx context.Context, params *ec2.DeleteNetworkInterfaceInput, optFns ...func(*ec2.Options)) (*ec2.DeleteNetworkInterfaceOutput, error) {
+	return &ec2.DeleteNetworkInterfaceOutput{}, f.record("DeleteNetworkInterface", aws.ToString(params.NetworkInterfaceId))
+}
+
+func (f *fakeGCAPI) DeleteSecurityGroup(ctx context.Context, params *ec2.DeleteSecurityGroupInput, optFns ...func(*ec2.Options)) (*ec2.DeleteSecurityGroupOutput, error) {
+	return &ec2.DeleteSecurityGroupOutput{}, f.record("DeleteSecurityGroup", aws.ToString(params.GroupId))
+}
+
+func (f *fakeGCAPI) DeleteKeyPair(ctx context.Context, params *ec2.DeleteKeyPairInput, optFns ...func(*ec2.Options)) (*ec2.DeleteKeyPairOutput, error) {
+	return &ec2.DeleteKeyPairOutput{}, f.record("DeleteKeyPair", aws.ToString(params.KeyPairId))
+}
+
+func (f *fakeGCAPI) DeleteSnapshot(ctx context.Context, params *ec2.DeleteSnapshotInput, optFns ...func(*ec2.Options)) (*ec2.DeleteSnapshotOutput, error) {
+	return &ec2.DeleteSnapshotOutput{}, f.record("DeleteSnapshot", aws.ToString(params.SnapshotId))
+}
+
+func (f *fakeGCAPI) DescribeMountTargets(ctx context.Context, params *efs.DescribeMountTargetsInput, optFns ...func(*efs.Options)) (*efs.DescribeMountTargetsOutput, error) {
+	f.mountDescribes++
+	if f.mountDescribes > 1 {
+		return &efs.DescribeMountTargetsOutput{}, nil
+	}
+	return &efs.DescribeMountTargetsOutput{MountTargets: []efstypes.MountTargetDescription{{MountTargetId: aws.String("fsmt-1")}}}, nil
+}
+
+func (f *fakeGCAPI) DeleteMountTarget(ctx context.Context, params *efs.DeleteMountTargetInput, optFns ...func(*efs.Options)) (*efs.DeleteMountTargetOutput, error) {
+	return &efs.DeleteMountTargetOutput{}, f.record("DeleteMountTarget", aws.ToString(params.MountTargetId))
+}
+
+func (f *fakeGCAPI) DeleteFileSystem(ctx context.Context, params *efs.DeleteFileSystemInput, optFns ...func(*efs.Options)) (*efs.DeleteFileSystemOutput, error) {
+	return &efs.DeleteFileSystemOutput{}, f.record("DeleteFileSystem", aws.ToString(params.FileSystemId))
+}
+
+func newFakeGarbageCollector(api *fakeGCAPI) *GarbageCollector {
+	return &GarbageCollector{ec2: api, efs: api, pollInterval: time.Millisecond, timeout: time.Second}
+}
+
+func TestGarbageCollectorDelete(t *testing.T) {
+	api := &fakeGCAPI{failOn: map[string]bool{"snap-1": true}}
+	plan := PlanGC(headComputeLeftovers(), gcTestIndex(), gcNow, 0)
+
+	results := newFakeGarbageCollector(api).Delete(context.Background(), plan.Orphans)
+
+	want := []string{
+		"DeleteMountTarget fsmt-1",
+		"DeleteFileSystem fs-1",
+		"DeleteSecurityGroup sg-efs",
+		"DeleteNetworkInterface eni-detached",
+		"DeleteSecurityGroup sg-cluster",
+		"DeleteSnapshot snap-1",
+		"DeleteKeyPair key-1",
+	}
+	if got := strings.Join(api.calls, "\n"); got != strings.Join(want, "\n") {
+		t.Errorf("unexpected calls:\n%s\nwant:\n%s", got, strings.Join(want, "\n"))
+	}
+	if api.mountDescribes != 2 {
+		t.Errorf("expected to wait for the mount target, got %d describes", api.mountDescribes)
+	}
+
+	// The snapshot failure is reported and the key pair is still deleted
+	if len(results) != 5 {
+		t.Fatalf("expected a result per orphan, got %d", len(results))
+	}
+	for _, result := range results {
+		if result.Finding.ID == "snap-1" {
+			if result.Err == nil || !strings.Contains(result.Err.Error(), "failed to delete snapshot") {
+				t.Errorf("snap-1 error = %v", result.Err)
+			}
+			continue
+		}
+		if result.Err != nil {
+			t.Errorf("%s: unexpected error %v", result.Finding.ID, result.Err)
+		}
+	}
+}
+
+func TestGarbageCollectorDeleteStopsAtFailedInterface(t *testing.T) {
+	api := &fakeGCAPI{failOn: map[string]bool{"eni-detached": true}}
+	orphans := []GCFinding{{GCResource: GCResource{
+		Kind:              GCKindSecurityGroup,
+		ID:                "sg-cluster",
+		NetworkInterfaces: []GCNetworkInterface{{ID: "eni-detached", Status: "available"}},
+	}}}
+
+	results := newFakeGarbageCollector(api).Delete(context.Background(), orphans)
+	if results[0].Err == nil || !strings.Contains(results[0].Err.Error(), "eni-detached") {
+		t.Errorf("expected the interface failure, got %v", results[0].Err)
+	}
+	for _, call := range api.calls {
+		if strings.HasPrefix(call, "DeleteSecurityGroup") {
+			t.Error("security group should not be deleted while an interface remains")
+		}
+	}
+}
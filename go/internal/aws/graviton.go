@@ -0,0 +1,112 @@
+package aws
+
+import (
+	"strings"
+)
+
+// CPU architectures of EC2 instance types, as named by AMIs
+const (
+	ArchitectureX86_64 = "x86_64"
+	ArchitectureARM64  = "arm64"
+)
+
+// gravitonFamily is the Graviton family an x86_64 family maps to and the
+// sizes it comes in
+type gravitonFamily struct {
+	family string
+	sizes  []string
+	// resize maps x86_64 sizes the Graviton family lacks to its closest size
+	resize map[string]string
+}
+
+var (
+	gravitonGeneralSizes = []string{"medium", "large", "xlarge", "2xlarge", "4xlarge", "8xlarge", "12xlarge", "16xlarge", "metal"}
+	gravitonBurstSizes   = []string{"nano", "micro", "small", "medium", "large", "xlarge", "2xlarge"}
+	gravitonStorageSizes = []string{"large", "xlarge", "2xlarge", "4xlarge", "8xlarge", "16xlarge"}
+	gravitonHPCSizes     = []string{"4xlarge", "8xlarge", "16xlarge"}
+)
+
+// gravitonFamilies maps x86_64 instance families to their current Graviton
+// equivalent. Families with accelerators or unusual shapes, such as GPU,
+// high-frequency and high-memory instances, have none.
+var gravitonFamilies = map[string]gravitonFamily{
+	"m5": {family: "m7g", sizes: gravitonGeneralSizes}, "m5a": {family: "m7g", sizes: gravitonGeneralSizes},
+	"m6i": {family: "m7g", sizes: gravitonGeneralSizes}, "m6a": {family: "m7g", sizes: gravitonGeneralSizes},
+	"m7i": {family: "m7g", sizes: gravitonGeneralSizes}, "m7a": {family: "m7g", sizes: gravitonGeneralSizes},
+	"m5d": {family: "m7gd", sizes: gravitonGeneralSizes}, "m6id": {family: "m7gd", sizes: gravitonGeneralSizes},
+
+	"c5": {family: "c7g", sizes: gravitonGeneralSizes}, "c5a": {family: "c7g", sizes: gravitonGeneralSizes},
+	"c6i": {family: "c7g", sizes: gravitonGeneralSizes}, "c6a": {family: "c7g", sizes: gravitonGeneralSizes},
+	"c7i": {family: "c7g", sizes: gravitonGeneralSizes}, "c7a": {family: "c7g", sizes: gravitonGeneralSizes},
+	"c5d": {family: "c7gd", sizes: gravitonGeneralSizes}, "c6id": {family: "c7gd", sizes: gravitonGeneralSizes},
+	"c5n": {family: "c7gn", sizes: gravitonGeneralSizes}, "c6in": {family: "c7gn", sizes: gravitonGeneralSizes},
+
+	"r5": {family: "r7g", sizes: gravitonGeneralSizes}, "r5a": {family: "r7g", sizes: gravitonGeneralSizes},
+	"r6i": {family: "r7g", sizes: gravitonGeneralSizes}, "r6a": {family: "r7g", sizes: gravitonGeneralSizes},
+	"r7i": {family: "r7g", sizes: gravitonGeneralSizes}, "r7a": {family: "r7g", sizes: gravitonGeneralSizes},
+	"r5d": {family: "r7gd", sizes: gravitonGeneralSizes}, "r6id": {family: "r7gd", sizes: gravitonGeneralSizes},
+	"x2idn": {family: "x2gd", sizes: gravitonGeneralSizes}, "x2iedn": {family: "x2gd", sizes: gravitonGeneralSizes},
+
+	"t3": {family: "t4g", sizes: gravitonBurstSizes}, "t3a": {family: "t4g", sizes: gravitonBurstSizes},
+
+	"i3": {family: "im4gn", sizes: gravitonStorageSizes}, "i4i": {family: "im4gn", sizes: gravitonStorageSizes},
+
+	// HPC instances come in one or a few sizes per family; the whole-node
+	// sizes map to the whole Graviton node
+	"hpc6a": {family: "hpc7g", sizes: gravitonHPCSizes, resize: map[string]string{"48xlarge": "16xlarge"}},
+	"hpc7a": {family: "hpc7g", sizes: gravitonHPCSizes, resize: map[string]string{
+		"12xlarge": "16xlarge", "24xlarge": "16xlarge", "48xlarge": "16xlarge", "96xlarge": "16xlarge",
+	}},
+}
+
+// InstanceArchitecture returns the CPU architecture of an instance type.
+// Graviton families have a "g" attribute after the generation, as in c7g,
+// c7gn and im4gn; a1 is the first-generation Graviton family.
+func InstanceArchitecture(instanceType string) string {
+	family, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(instanceType)), ".")
+	if family == "a1" {
+		return ArchitectureARM64
+	}
+
+	attributes := strings.TrimLeft(family[len(leadingLetters(family)):], "0123456789")
+	if strings.HasPrefix(attributes, "g") {
+		return ArchitectureARM64
+	}
+	return ArchitectureX86_64
+}
+
+// GravitonEquivalent returns the Graviton instance type closest to an x86_64
+// one, such as r7g.4xlarge for r6i.4xlarge. It reports false when the family
+// has no Graviton equivalent or the equivalent family lacks the size.
+func GravitonEquivalent(instanceType string) (string, bool) {
+	family, size, found := strings.Cut(strings.ToLower(strings.TrimSpace(instanceType)), ".")
+	if !found {
+		return "", false
+	}
+	target, ok := gravitonFamilies[family]
+	if !ok {
+		return "", false
+	}
+
+	if resized, ok := target.resize[size]; ok {
+		size = resized
+	}
+	for _, available := range target.sizes {
+		if available == size {
+			return target.family + "." + size, true
+		}
+	}
+	return "", false
+}
+
+// PreferGraviton returns the Graviton equivalent of an instance type, or the
+// instance type itself when it is already arm64 or has no equivalent
+func PreferGraviton(instanceType string) string {
+	if InstanceArchitecture(instanceType) == ArchitectureARM64 {
+		return instanceType
+	}
+	if equivalent, ok := GravitonEquivalent(instanceType); ok {
+		return equivalent
+	}
+	return instanceType
+}
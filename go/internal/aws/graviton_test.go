@@ -0,0 +1,94 @@
+package aws
+
+import "testing"
+
+func TestInstanceArchitecture(t *testing.T) {
+	tests := map[string]string{
+		"r6i.4xlarge":     ArchitectureX86_64,
+		"c7a.large":       ArchitectureX86_64,
+		"g5.xlarge":       ArchitectureX86_64,
+		"p4d.24xlarge":    ArchitectureX86_64,
+		"hpc6a.48xlarge":  ArchitectureX86_64,
+		"u-6tb1.metal":    ArchitectureX86_64,
+		"r7g.4xlarge":     ArchitectureARM64,
+		"c7gn.16xlarge":   ArchitectureARM64,
+		"im4gn.large":     ArchitectureARM64,
+		"x2gd.xlarge":     ArchitectureARM64,
+		"g5g.2xlarge":     ArchitectureARM64,
+		"hpc7g.16xlarge":  ArchitectureARM64,
+		"a1.large":        ArchitectureARM64,
+		" T4G.Medium ":    ArchitectureARM64,
+		"c8g.metal-24xl":  ArchitectureARM64,
+		"inf2.8xlarge":    ArchitectureX86_64,
+		"mac1.metal":      ArchitectureX86_64,
+		"trn1.32xlarge":   ArchitectureX86_64,
+		"m7i-flex.large":  ArchitectureX86_64,
+		"is4gen.2xlarge":  ArchitectureARM64,
+		"hpc7a.96xlarge":  ArchitectureX86_64,
+		"x2iedn.32xlarge": ArchitectureX86_64,
+	}
+
+	for instanceType, want := range tests {
+		if got := InstanceArchitecture(instanceType); got != want {
+			t.Errorf("InstanceArchitecture(%q) = %s, want %s", instanceType, got, want)
+		}
+	}
+}
+
+func TestGravitonEquivalent(t *testing.T) {
+	tests := []struct {
+		instanceType string
+		want         string
+		ok           bool
+	}{
+		{"r6i.4xlarge", "r7g.4xlarge", true},
+		{"r5.large", "r7g.large", true},
+		{"c6i.8xlarge", "c7g.8xlarge", true},
+		{"c5n.18xlarge", "", false},
+		{"c6in.16xlarge", "c7gn.16xlarge", true},
+		{"m6i.2xlarge", "m7g.2xlarge", true},
+		{"m6id.xlarge", "m7gd.xlarge", true},
+		{"t3.micro", "t4g.micro", true},
+		{"t3.nano", "t4g.nano", true},
+		{"i4i.4xlarge", "im4gn.4xlarge", true},
+		{"hpc6a.48xlarge", "hpc7g.16xlarge", true},
+		{"hpc7a.96xlarge", "hpc7g.16xlarge", true},
+		// No Graviton family offers the size or the accelerator
+		{"r6i.24xlarge", "", false},
+		{"p4d.24xlarge", "", false},
+		{"g5.xlarge", "", false},
+		{"r7g.4xlarge", "", false},
+		{"r6i", "", false},
+	}
+
+	for _, tt := range tests {
+		got, ok := GravitonEquivalent(tt.instanceType)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("GravitonEquivalent(%q) = %q, %v, want %q, %v", tt.instanceType, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestPreferGraviton(t *testing.T) {
+	tests := map[string]string{
+		"r6i.4xlarge":  "r7g.4xlarge",
+		"r7g.4xlarge":  "r7g.4xlarge",
+		"p4d.24xlarge": "p4d.24xlarge",
+		"r6i.32xlarge": "r6i.32xlarge",
+	}
+	for instanceType, want := range tests {
+		if got := PreferGraviton(instanceType); got != want {
+			t.Errorf("PreferGraviton(%q) = %s, want %s", instanceType, got, want)
+		}
+	}
+
+	// Every equivalent must itself be arm64
+	for family, target := range gravitonFamilies {
+		if InstanceArchitecture(family+".large") != ArchitectureX86_64 {
+			t.Errorf("%s is mapped but is not x86_64", family)
+		}
+		if InstanceArchitecture(target.family+".large") != ArchitectureARM64 {
+			t.Errorf("%s maps to %s, which is not arm64", family, target.family)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package aws
 import (
 	"context"
 	"fmt"
+	"sort"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -50,8 +51,74 @@ type StackInfo struct {
 	Parameters  map[string]string
 }
 
+// Output keys published by research wizard stack templates
+const (
+	OutputInstanceID      = "InstanceId"
+	OutputPublicIP        = "PublicIP"
+	OutputPrivateIP       = "PrivateIP"
+	OutputSecurityGroupID = "SecurityGroupId"
+	OutputSSHCommand      = "SSHCommand"
+	OutputSSMCommand      = "SSMCommand"
+	OutputConnectivity    = "Connectivity"
+	// OutputElasticIP and OutputDNSName are published by stacks deployed with
+	// an Elastic IP address and a Route 53 record pointed at it
+	OutputElasticIP = "ElasticIP"
+	OutputDNSName   = "DNSName"
+)
+
+// Output returns a stack output, failing if it is missing or empty
+func (s *StackInfo) Output(key string) (string, error) {
+	value, exists := s.Outputs[key]
+	if !exists {
+		return "", fmt.Errorf("stack %s has no %s output", s.StackName, key)
+	}
+	if value == "" {
+		return "", fmt.Errorf("stack %s output %s is empty", s.StackName, key)
+	}
+	return value, nil
+}
+
+// InstanceID returns the ID of the stack's research instance
+func (s *StackInfo) InstanceID() (string, error) {
+	return s.Output(OutputInstanceID)
+}
+
+// PublicIP returns the public IP address of the stack's research instance
+func (s *StackInfo) PublicIP() (string, error) {
+	return s.Output(OutputPublicIP)
+}
+
+// HostName returns the address users connect to the stack's research instance
+// by: its DNS name or Elastic IP address when it has one, since those survive
+// the instance being stopped and started, otherwise its public IP address
+func (s *StackInfo) HostName() (string, error) {
+	for _, key := range []string{OutputDNSName, OutputElasticIP} {
+		if value := s.Outputs[key]; value != "" {
+			return value, nil
+		}
+	}
+	return s.PublicIP()
+}
+
+// PrivateIP returns the private IP address of the stack's research instance
+func (s *StackInfo) PrivateIP() (string, error) {
+	return s.Output(OutputPrivateIP)
+}
+
+// SSHCommand returns the SSH command for connecting to the stack's research instance
+func (s *StackInfo) SSHCommand() (string, error) {
+	return s.Output(OutputSSHCommand)
+}
+
 // CreateStack creates a new CloudFormation stack
 func (im *InfrastructureManager) CreateStack(ctx context.Context, stackName string, templateBody string, parameters map[string]string) (*StackInfo, error) {
+	return im.CreateStackWithTags(ctx, stackName, templateBody, parameters, nil)
+}
+
+// CreateStackWithTags creates a new CloudFormation stack with extra stack tags,
+// which CloudFormation propagates to the resources it creates. The wizard's own
+// CreatedBy and Purpose tags cannot be overridden.
+func (im *InfrastructureManager) CreateStackWithTags(ctx context.Context, stackName string, templateBody string, parameters map[string]string, tags map[string]string) (*StackInfo, error) {
 	// Convert parameters to CloudFormation format
 	cfParams := make([]types.Parameter, 0, len(parameters))
 	for key, value := range parameters {
@@ -69,16 +136,7 @@ func (im *InfrastructureManager) CreateStack(ctx context.Context, stackName stri
 			types.CapabilityCapabilityIam,
 			types.CapabilityCapabilityNamedIam,
 		},
-		Tags: []types.Tag{
-			{
-				Key:   aws.String("CreatedBy"),
-				Value: aws.String("AWS-Research-Wizard"),
-			},
-			{
-				Key:   aws.String("Purpose"),
-				Value: aws.String("Research-Infrastructure"),
-			},
-		},
+		Tags: stackTags(tags),
 	}
 
 	result, err := im.client.CloudFormation.CreateStack(ctx, input)
@@ -93,6 +151,29 @@ func (im *InfrastructureManager) CreateStack(ctx context.Context, stackName stri
 	}, nil
 }
 
+// purposeTagValue marks stacks created by the wizard as research infrastructure
+const purposeTagValue = "Research-Infrastructure"
+
+// stackTags returns the wizard's stack tags followed by the extra tags in key order
+func stackTags(extra map[string]string) []types.Tag {
+	tags := []types.Tag{
+		{Key: aws.String(createdByTagKey), Value: aws.String(createdByTagValue)},
+		{Key: aws.String("Purpose"), Value: aws.String(purposeTagValue)},
+	}
+
+	keys := make([]string, 0, len(extra))
+	for key := range extra {
+		if key != createdByTagKey && key != "Purpose" {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		tags = append(tags, types.Tag{Key: aws.String(key), Value: aws.String(extra[key])})
+	}
+	return tags
+}
+
 // GetStackInfo retrieves information about a CloudFormation stack
 func (im *InfrastructureManager) GetStackInfo(ctx context.Context, stackName string) (*StackInfo, error) {
 	input := &cloudformation.DescribeStacksInput{
@@ -108,8 +189,22 @@ func (im *InfrastructureManager) GetStackInfo(ctx context.Context, stackName str
 		return nil, fmt.Errorf("stack not found: %s", stackName)
 	}
 
-	stack := result.Stacks[0]
+	return newStackInfo(result.Stacks[0]), nil
+}
+
+// GetStackTemplate retrieves the template body a stack was last deployed from
+func (im *InfrastructureManager) GetStackTemplate(ctx context.Context, stackName string) (string, error) {
+	result, err := im.client.CloudFormation.GetTemplate(ctx, &cloudformation.GetTemplateInput{
+		StackName:     aws.String(stackName),
+		TemplateStage: types.TemplateStageOriginal,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to get stack template: %w", err)
+	}
+	return aws.ToString(result.TemplateBody), nil
+}
 
+func newStackInfo(stack types.Stack) *StackInfo {
 	// Extract outputs
 	outputs := make(map[string]string)
 	for _, output := range stack.Outputs {
@@ -127,10 +222,10 @@ func (im *InfrastructureManager) GetStackInfo(ctx context.Context, stackName str
 	}
 
 	stackInfo := &StackInfo{
-		StackName:   *stack.StackName,
-		StackID:     *stack.StackId,
+		StackName:   aws.ToString(stack.StackName),
+		StackID:     aws.ToString(stack.StackId),
 		Status:      StackStatus(stack.StackStatus),
-		CreatedTime: *stack.CreationTime,
+		CreatedTime: aws.ToTime(stack.CreationTime),
 		Outputs:     outputs,
 		Parameters:  parameters,
 	}
@@ -139,7 +234,7 @@ func (im *InfrastructureManager) GetStackInfo(ctx context.Context, stackName str
 		stackInfo.UpdatedTime = stack.LastUpdatedTime
 	}
 
-	return stackInfo, nil
+	return stackInfo
 }
 
 // DeleteStack deletes a CloudFormation stack
@@ -156,19 +251,19 @@ func (im *InfrastructureManager) DeleteStack(ctx context.Context, stackName stri
 	return nil
 }
 
+// stackPollInterval is how often stack status is checked while waiting
+const stackPollInterval = 30 * time.Second
+
 // WaitForStackComplete waits for a stack operation to complete
 func (im *InfrastructureManager) WaitForStackComplete(ctx context.Context, stackName string, timeout time.Duration) (*StackInfo, error) {
 	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
-	ticker := time.NewTicker(30 * time.Second)
-	defer ticker.Stop()
-
 	for {
 		select {
 		case <-ctx.Done():
 			return nil, fmt.Errorf("timeout waiting for stack operation to complete")
-		case <-ticker.C:
+		case <-time.After(jitteredInterval(stackPollInterval)):
 			stackInfo, err := im.GetStackInfo(ctx, stackName)
 			if err != nil {
 				return nil, err
@@ -186,18 +281,70 @@ func (im *InfrastructureManager) WaitForStackComplete(ctx context.Context, stack
 
 // InstanceInfo contains EC2 instance information
 type InstanceInfo struct {
-	InstanceID       string
-	InstanceType     string
-	State            string
-	PublicIP         string
-	PrivateIP        string
-	AvailabilityZone string
-	LaunchTime       time.Time
-	Tags             map[string]string
+	InstanceID       string            `json:"instance_id"`
+	InstanceType     string            `json:"instance_type"`
+	State            string            `json:"state"`
+	PublicIP         string            `json:"public_ip,omitempty"`
+	PrivateIP        string            `json:"private_ip,omitempty"`
+	AvailabilityZone string            `json:"availability_zone"`
+	LaunchTime       time.Time         `json:"launch_time"`
+	Tags             map[string]string `json:"tags"`
+}
+
+// InstanceFilter narrows research instance listings
+type InstanceFilter struct {
+	Domain        string
+	Project       string
+	States        []string
+	LaunchedAfter time.Time
+	Tags          map[string]string
+}
+
+// ec2Filters converts the filter to DescribeInstances filters; LaunchedAfter is applied client-side
+func (f InstanceFilter) ec2Filters() map[string][]string {
+	filters := make(map[string][]string)
+	if f.Domain != "" {
+		filters["tag:Domain"] = []string{f.Domain}
+	}
+	if f.Project != "" {
+		filters["tag:Project"] = []string{f.Project}
+	}
+	if len(f.States) > 0 {
+		filters["instance-state-name"] = f.States
+	}
+	for key, value := range f.Tags {
+		filters["tag:"+key] = []string{value}
+	}
+	return filters
 }
 
 // ListInstances lists EC2 instances with optional filtering
 func (im *InfrastructureManager) ListInstances(ctx context.Context, filters map[string][]string) ([]InstanceInfo, error) {
+	return listInstances(ctx, im.client.EC2, filters)
+}
+
+// ListFilteredInstances lists EC2 instances matching an InstanceFilter
+func (im *InfrastructureManager) ListFilteredInstances(ctx context.Context, filter InstanceFilter) ([]InstanceInfo, error) {
+	instances, err := im.ListInstances(ctx, filter.ec2Filters())
+	if err != nil {
+		return nil, err
+	}
+
+	if filter.LaunchedAfter.IsZero() {
+		return instances, nil
+	}
+
+	filtered := instances[:0]
+	for _, instance := range instances {
+		if instance.LaunchTime.After(filter.LaunchedAfter) {
+			filtered = append(filtered, instance)
+		}
+	}
+	return filtered, nil
+}
+
+// listInstances pages through DescribeInstances results
+func listInstances(ctx context.Context, api ec2.DescribeInstancesAPIClient, filters map[string][]string) ([]InstanceInfo, error) {
 	// Convert filters to EC2 format
 	ec2Filters := make([]ec2types.Filter, 0, len(filters))
 	for name, values := range filters {
@@ -211,44 +358,57 @@ func (im *InfrastructureManager) ListInstances(ctx context.Context, filters map[
 		Filters: ec2Filters,
 	}
 
-	result, err := im.client.EC2.DescribeInstances(ctx, input)
-	if err != nil {
-		return nil, fmt.Errorf("failed to describe instances: %w", err)
-	}
-
 	var instances []InstanceInfo
-	for _, reservation := range result.Reservations {
-		for _, instance := range reservation.Instances {
-			// Extract tags
-			tags := make(map[string]string)
-			for _, tag := range instance.Tags {
-				if tag.Key != nil && tag.Value != nil {
-					tags[*tag.Key] = *tag.Value
-				}
-			}
-
-			instanceInfo := InstanceInfo{
-				InstanceID:       *instance.InstanceId,
-				InstanceType:     string(instance.InstanceType),
-				State:            string(instance.State.Name),
-				AvailabilityZone: *instance.Placement.AvailabilityZone,
-				LaunchTime:       *instance.LaunchTime,
-				Tags:             tags,
-			}
+	paginator := ec2.NewDescribeInstancesPaginator(api, input)
+	for paginator.HasMorePages() {
+		result, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe instances: %w", err)
+		}
 
-			if instance.PublicIpAddress != nil {
-				instanceInfo.PublicIP = *instance.PublicIpAddress
+		for _, reservation := range result.Reservations {
+			for _, instance := range reservation.Instances {
+				instances = append(instances, newInstanceInfo(instance))
 			}
+		}
+	}
 
-			if instance.PrivateIpAddress != nil {
-				instanceInfo.PrivateIP = *instance.PrivateIpAddress
-			}
+	return instances, nil
+}
 
-			instances = append(instances, instanceInfo)
+func newInstanceInfo(instance ec2types.Instance) InstanceInfo {
+	// Extract tags
+	tags := make(map[string]string)
+	for _, tag := range instance.Tags {
+		if tag.Key != nil && tag.Value != nil {
+			tags[*tag.Key] = *tag.Value
 		}
 	}
 
-	return instances, nil
+	instanceInfo := InstanceInfo{
+		InstanceID:   aws.ToString(instance.InstanceId),
+		InstanceType: string(instance.InstanceType),
+		LaunchTime:   aws.ToTime(instance.LaunchTime),
+		Tags:         tags,
+	}
+
+	if instance.State != nil {
+		instanceInfo.State = string(instance.State.Name)
+	}
+
+	if instance.Placement != nil {
+		instanceInfo.AvailabilityZone = aws.ToString(instance.Placement.AvailabilityZone)
+	}
+
+	if instance.PublicIpAddress != nil {
+		instanceInfo.PublicIP = *instance.PublicIpAddress
+	}
+
+	if instance.PrivateIpAddress != nil {
+		instanceInfo.PrivateIP = *instance.PrivateIpAddress
+	}
+
+	return instanceInfo
 }
 
 // TerminateInstance terminates an EC2 instance
@@ -0,0 +1,171 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+func TestStackInfoAccessors(t *testing.T) {
+	stackInfo := &StackInfo{
+		StackName: "research-wizard-genomics",
+		Outputs: map[string]string{
+			OutputInstanceID: "i-0123456789abcdef0",
+			OutputPublicIP:   "203.0.113.10",
+			OutputPrivateIP:  "",
+			OutputSSHCommand: "ssh -i ~/.ssh/lab.pem ec2-user@203.0.113.10",
+		},
+	}
+
+	instanceID, err := stackInfo.InstanceID()
+	if err != nil || instanceID != "i-0123456789abcdef0" {
+		t.Errorf("InstanceID() = %q, %v", instanceID, err)
+	}
+
+	publicIP, err := stackInfo.PublicIP()
+	if err != nil || publicIP != "203.0.113.10" {
+		t.Errorf("PublicIP() = %q, %v", publicIP, err)
+	}
+
+	sshCommand, err := stackInfo.SSHCommand()
+	if err != nil || sshCommand == "" {
+		t.Errorf("SSHCommand() = %q, %v", sshCommand, err)
+	}
+
+	if _, err := stackInfo.PrivateIP(); err == nil {
+		t.Error("Expected error for empty PrivateIP output")
+	}
+
+	if _, err := stackInfo.Output("PublicIp"); err == nil {
+		t.Error("Expected error for misspelled output key")
+	}
+}
+
+func TestStackInfoAccessorsWithoutOutputs(t *testing.T) {
+	stackInfo := &StackInfo{StackName: "pending-stack"}
+
+	if _, err := stackInfo.InstanceID(); err == nil {
+		t.Error("Expected error when stack has no outputs")
+	}
+}
+
+// fakeDescribeInstances serves DescribeInstances results one page per call
+type fakeDescribeInstances struct {
+	pages  []*ec2.DescribeInstancesOutput
+	inputs []*ec2.DescribeInstancesInput
+}
+
+func (f *fakeDescribeInstances) DescribeInstances(ctx context.Context, params *ec2.DescribeInstancesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInstancesOutput, error) {
+	f.inputs = append(f.inputs, params)
+
+	page := 0
+	if params.NextToken != nil {
+		fmt.Sscanf(*params.NextToken, "page-%d", &page)
+	}
+	if page >= len(f.pages) {
+		return nil, fmt.Errorf("unexpected page %d", page)
+	}
+
+	output := *f.pages[page]
+	if page+1 < len(f.pages) {
+		output.NextToken = aws.String(fmt.Sprintf("page-%d", page+1))
+	}
+	return &output, nil
+}
+
+func testInstancePage(ids ...string) *ec2.DescribeInstancesOutput {
+	instances := make([]ec2types.Instance, 0, len(ids))
+	for _, id := range ids {
+		instances = append(instances, ec2types.Instance{
+			InstanceId:   aws.String(id),
+			InstanceType: ec2types.InstanceTypeR6i4xlarge,
+			State:        &ec2types.InstanceState{Name: ec2types.InstanceStateNameRunning},
+			Placement:    &ec2types.Placement{AvailabilityZone: aws.String("us-east-1a")},
+			LaunchTime:   aws.Time(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)),
+			Tags:         []ec2types.Tag{{Key: aws.String("Domain"), Value: aws.String("genomics")}},
+		})
+	}
+	return &ec2.DescribeInstancesOutput{
+		Reservations: []ec2types.Reservation{{Instances: instances}},
+	}
+}
+
+func TestListInstancesPaginates(t *testing.T) {
+	api := &fakeDescribeInstances{
+		pages: []*ec2.DescribeInstancesOutput{
+			testInstancePage("i-1", "i-2"),
+			testInstancePage("i-3"),
+			testInstancePage("i-4", "i-5"),
+		},
+	}
+
+	filters := map[string][]string{"tag:Domain": {"genomics"}}
+	instances, err := listInstances(context.Background(), api, filters)
+	if err != nil {
+		t.Fatalf("listInstances failed: %v", err)
+	}
+
+	if len(api.inputs) != 3 {
+		t.Errorf("Expected 3 DescribeInstances calls, got %d", len(api.inputs))
+	}
+	if len(instances) != 5 {
+		t.Fatalf("Expected 5 instances across pages, got %d", len(instances))
+	}
+	if instances[4].InstanceID != "i-5" || instances[4].Tags["Domain"] != "genomics" {
+		t.Errorf("Unexpected last instance: %+v", instances[4])
+	}
+
+	for i, input := range api.inputs {
+		if len(input.Filters) != 1 || aws.ToString(input.Filters[0].Name) != "tag:Domain" {
+			t.Errorf("Call %d did not carry filters: %+v", i, input.Filters)
+		}
+	}
+}
+
+func TestListInstancesHandlesSparseInstances(t *testing.T) {
+	api := &fakeDescribeInstances{
+		pages: []*ec2.DescribeInstancesOutput{{
+			Reservations: []ec2types.Reservation{{
+				Instances: []ec2types.Instance{{InstanceId: aws.String("i-sparse")}},
+			}},
+		}},
+	}
+
+	instances, err := listInstances(context.Background(), api, nil)
+	if err != nil {
+		t.Fatalf("listInstances failed: %v", err)
+	}
+	if len(instances) != 1 || instances[0].InstanceID != "i-sparse" {
+		t.Errorf("Unexpected instances: %+v", instances)
+	}
+}
+
+func TestInstanceFilterEC2Filters(t *testing.T) {
+	filter := InstanceFilter{
+		Domain:  "genomics",
+		Project: "cancer-atlas",
+		States:  []string{"running", "stopped"},
+		Tags:    map[string]string{"CreatedBy": "AWS-Research-Wizard"},
+	}
+
+	filters := filter.ec2Filters()
+	expected := map[string][]string{
+		"tag:Domain":          {"genomics"},
+		"tag:Project":         {"cancer-atlas"},
+		"instance-state-name": {"running", "stopped"},
+		"tag:CreatedBy":       {"AWS-Research-Wizard"},
+	}
+	if !reflect.DeepEqual(filters, expected) {
+		t.Errorf("ec2Filters() = %v, want %v", filters, expected)
+	}
+
+	if len(InstanceFilter{}.ec2Filters()) != 0 {
+		t.Error("Empty filter should produce no EC2 filters")
+	}
+}
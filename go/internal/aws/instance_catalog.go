@@ -0,0 +1,71 @@
+package aws
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+//go:embed instance_catalog.json
+var bundledInstanceCatalog []byte
+
+// InstanceCatalog lists the specifications and on-demand Linux prices of the
+// instance types domain packs recommend
+type InstanceCatalog struct {
+	Source    string                  `json:"source"`
+	Region    string                  `json:"region"`
+	Instances map[string]InstanceSpec `json:"instances"`
+}
+
+// InstanceSpec describes one instance type
+type InstanceSpec struct {
+	VCPUs     int     `json:"vcpus"`
+	MemoryGiB float64 `json:"memory_gib"`
+	GPUs      int     `json:"gpus,omitempty"`
+	HourlyUSD float64 `json:"hourly_usd"`
+}
+
+// Lookup returns an instance type's specification
+func (c *InstanceCatalog) Lookup(instanceType string) (InstanceSpec, bool) {
+	spec, ok := c.Instances[instanceType]
+	return spec, ok
+}
+
+// BundledInstanceCatalog returns the instance catalog shipped with the binary,
+// priced in BaselinePricingRegion
+func BundledInstanceCatalog() (*InstanceCatalog, error) {
+	return parseInstanceCatalog(bundledInstanceCatalog)
+}
+
+// LoadInstanceCatalog reads an instance catalog in the bundled catalog's format
+func LoadInstanceCatalog(path string) (*InstanceCatalog, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read instance catalog: %w", err)
+	}
+	catalog, err := parseInstanceCatalog(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return catalog, nil
+}
+
+func parseInstanceCatalog(data []byte) (*InstanceCatalog, error) {
+	var catalog InstanceCatalog
+	if err := json.Unmarshal(data, &catalog); err != nil {
+		return nil, fmt.Errorf("failed to parse instance catalog: %w", err)
+	}
+	if len(catalog.Instances) == 0 {
+		return nil, fmt.Errorf("instance catalog lists no instance types")
+	}
+	for instanceType, spec := range catalog.Instances {
+		if spec.VCPUs <= 0 || spec.MemoryGiB <= 0 || spec.HourlyUSD < 0 || spec.GPUs < 0 {
+			return nil, fmt.Errorf("instance catalog entry %s is invalid: %+v", instanceType, spec)
+		}
+	}
+	if catalog.Region == "" {
+		catalog.Region = BaselinePricingRegion
+	}
+	return &catalog, nil
+}
@@ -0,0 +1,69 @@
+package aws
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBundledInstanceCatalog(t *testing.T) {
+	catalog, err := BundledInstanceCatalog()
+	if err != nil {
+		t.Fatalf("BundledInstanceCatalog() error = %v", err)
+	}
+	if catalog.Region != BaselinePricingRegion {
+		t.Errorf("Region = %s, want %s", catalog.Region, BaselinePricingRegion)
+	}
+
+	spec, ok := catalog.Lookup("c6i.xlarge")
+	if !ok || spec.VCPUs != 4 || spec.MemoryGiB != 8 || spec.GPUs != 0 || spec.HourlyUSD != 0.17 {
+		t.Errorf("c6i.xlarge = %+v (found %v)", spec, ok)
+	}
+	if spec, ok := catalog.Lookup("g5.12xlarge"); !ok || spec.GPUs != 4 {
+		t.Errorf("g5.12xlarge = %+v (found %v), want 4 GPUs", spec, ok)
+	}
+	if _, ok := catalog.Lookup("c6i.huge"); ok {
+		t.Error("Lookup() found an instance type that does not exist")
+	}
+
+	for _, instanceType := range []string{"t3.medium", "r6i.4xlarge", "p4d.24xlarge", "hpc6a.48xlarge", "r7g.4xlarge"} {
+		if _, ok := catalog.Lookup(instanceType); !ok {
+			t.Errorf("bundled catalog is missing %s", instanceType)
+		}
+	}
+}
+
+func TestLoadInstanceCatalog(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	catalog, err := LoadInstanceCatalog(write("custom.json", `{"source": "custom", "instances": {"x9.large": {"vcpus": 2, "memory_gib": 6, "hourly_usd": 0.1}}}`))
+	if err != nil {
+		t.Fatalf("LoadInstanceCatalog() error = %v", err)
+	}
+	if catalog.Source != "custom" || catalog.Region != BaselinePricingRegion {
+		t.Errorf("catalog = %+v", catalog)
+	}
+	if spec, ok := catalog.Lookup("x9.large"); !ok || spec.MemoryGiB != 6 {
+		t.Errorf("x9.large = %+v (found %v)", spec, ok)
+	}
+
+	for name, content := range map[string]string{
+		"empty.json":   `{"instances": {}}`,
+		"invalid.json": `{"instances": {"x9.large": {"vcpus": 0, "memory_gib": 6}}}`,
+		"garbage.json": `not json`,
+	} {
+		if _, err := LoadInstanceCatalog(write(name, content)); err == nil {
+			t.Errorf("LoadInstanceCatalog(%s) succeeded, want error", name)
+		}
+	}
+	if _, err := LoadInstanceCatalog(filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("LoadInstanceCatalog() of a missing file succeeded")
+	}
+}
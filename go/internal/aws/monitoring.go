@@ -3,6 +3,8 @@ package aws
 import (
 	"context"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -131,6 +133,72 @@ func (mm *MonitoringManager) getMetricStatistics(ctx context.Context, instanceID
 	return dataPoints, nil
 }
 
+// AverageCPUUtilization averages an instance's CPU utilization over a window,
+// reporting false when CloudWatch has no datapoints for it
+func (mm *MonitoringManager) AverageCPUUtilization(ctx context.Context, instanceID string, startTime, endTime time.Time) (float64, bool, error) {
+	dataPoints, err := mm.getMetricStatistics(ctx, instanceID, "CPUUtilization", types.StatisticAverage, startTime, endTime)
+	if err != nil {
+		return 0, false, err
+	}
+	if len(dataPoints) == 0 {
+		return 0, false, nil
+	}
+
+	var sum float64
+	for _, dp := range dataPoints {
+		sum += dp.Value
+	}
+	return sum / float64(len(dataPoints)), true, nil
+}
+
+// CostsByTag returns the unblended cost since start, grouped by the values of
+// a cost allocation tag. Values only appear once the tag has been activated
+// for cost allocation; untagged spend is left out.
+func (mm *MonitoringManager) CostsByTag(ctx context.Context, tagKey string, start, end time.Time) (map[string]float64, error) {
+	input := &costexplorer.GetCostAndUsageInput{
+		TimePeriod: &costtypes.DateInterval{
+			Start: aws.String(start.Format("2006-01-02")),
+			End:   aws.String(end.Format("2006-01-02")),
+		},
+		Granularity: costtypes.GranularityMonthly,
+		Metrics:     []string{"UnblendedCost"},
+		GroupBy: []costtypes.GroupDefinition{
+			{Type: costtypes.GroupDefinitionTypeTag, Key: aws.String(tagKey)},
+		},
+	}
+
+	costs := make(map[string]float64)
+	for {
+		result, err := mm.client.CostExplorer.GetCostAndUsage(ctx, input)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get cost and usage data: %w", err)
+		}
+		for _, resultEntry := range result.ResultsByTime {
+			for _, group := range resultEntry.Groups {
+				if len(group.Keys) == 0 {
+					continue
+				}
+				// Tag groups are keyed "<tag>$<value>"; an empty value is untagged spend
+				value := strings.TrimPrefix(group.Keys[0], tagKey+"$")
+				cost, exists := group.Metrics["UnblendedCost"]
+				if value == "" || !exists || cost.Amount == nil {
+					continue
+				}
+				amount, err := strconv.ParseFloat(*cost.Amount, 64)
+				if err != nil {
+					return nil, fmt.Errorf("invalid cost amount %q: %w", *cost.Amount, err)
+				}
+				costs[value] += amount
+			}
+		}
+
+		if result.NextPageToken == nil {
+			return costs, nil
+		}
+		input.NextPageToken = result.NextPageToken
+	}
+}
+
 // CostData represents cost information
 type CostData struct {
 	Service   string
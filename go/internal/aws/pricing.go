@@ -70,6 +70,21 @@ func (pc *PricingCalculator) CalculateCost(instanceType string) (*CostEstimate,
 		"t3.xlarge":  0.1664,
 		"t3.2xlarge": 0.3328,
 
+		"t4g.micro":   0.0084,
+		"t4g.small":   0.0168,
+		"t4g.medium":  0.0336,
+		"t4g.large":   0.0672,
+		"t4g.xlarge":  0.1344,
+		"t4g.2xlarge": 0.2688,
+
+		"m7g.large":    0.0816,
+		"m7g.xlarge":   0.1632,
+		"m7g.2xlarge":  0.3264,
+		"m7g.4xlarge":  0.6528,
+		"m7g.8xlarge":  1.3056,
+		"m7g.12xlarge": 1.9584,
+		"m7g.16xlarge": 2.6112,
+
 		// Compute Optimized
 		"c6i.large":    0.085,
 		"c6i.xlarge":   0.17,
@@ -80,6 +95,14 @@ func (pc *PricingCalculator) CalculateCost(instanceType string) (*CostEstimate,
 		"c6i.16xlarge": 2.72,
 		"c6i.24xlarge": 4.08,
 
+		"c7g.large":    0.0725,
+		"c7g.xlarge":   0.145,
+		"c7g.2xlarge":  0.29,
+		"c7g.4xlarge":  0.58,
+		"c7g.8xlarge":  1.16,
+		"c7g.12xlarge": 1.74,
+		"c7g.16xlarge": 2.32,
+
 		// Memory Optimized
 		"r6i.large":    0.126,
 		"r6i.xlarge":   0.252,
@@ -90,6 +113,14 @@ func (pc *PricingCalculator) CalculateCost(instanceType string) (*CostEstimate,
 		"r6i.16xlarge": 4.032,
 		"r6i.24xlarge": 6.048,
 
+		"r7g.large":    0.1071,
+		"r7g.xlarge":   0.2142,
+		"r7g.2xlarge":  0.4284,
+		"r7g.4xlarge":  0.8568,
+		"r7g.8xlarge":  1.7136,
+		"r7g.12xlarge": 2.5704,
+		"r7g.16xlarge": 3.4272,
+
 		// GPU Instances
 		"p4d.24xlarge": 32.7726,
 		"p3.2xlarge":   3.06,
@@ -99,6 +130,7 @@ func (pc *PricingCalculator) CalculateCost(instanceType string) (*CostEstimate,
 		// High Performance Computing
 		"hpc6a.48xlarge":  2.88,
 		"hpc6id.32xlarge": 3.456,
+		"hpc7g.16xlarge":  1.6832,
 	}
 
 	hourlyCost, exists := baseCosts[instanceType]
@@ -0,0 +1,242 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/servicequotas"
+)
+
+// VCPUQuotaFamily identifies an EC2 On-Demand vCPU service quota
+type VCPUQuotaFamily struct {
+	Name      string
+	QuotaCode string
+	// Prefixes are the instance family letters counted against the quota
+	Prefixes []string
+}
+
+// vcpuQuotaFamilies lists the On-Demand vCPU quotas. The longest matching prefix
+// wins, so "inf" and "dl" are not mistaken for "i" and "d".
+var vcpuQuotaFamilies = []VCPUQuotaFamily{
+	{Name: "Running On-Demand Standard (A, C, D, H, I, M, R, T, Z) instances", QuotaCode: "L-1216C47A", Prefixes: []string{"a", "c", "d", "h", "i", "m", "r", "t", "z"}},
+	{Name: "Running On-Demand F instances", QuotaCode: "L-74FC7D96", Prefixes: []string{"f"}},
+	{Name: "Running On-Demand G and VT instances", QuotaCode: "L-DB2E81BA", Prefixes: []string{"g", "vt"}},
+	{Name: "Running On-Demand Inf instances", QuotaCode: "L-1945791B", Prefixes: []string{"inf"}},
+	{Name: "Running On-Demand P instances", QuotaCode: "L-417A185B", Prefixes: []string{"p"}},
+	{Name: "Running On-Demand X instances", QuotaCode: "L-7295265B", Prefixes: []string{"x"}},
+	{Name: "Running On-Demand DL instances", QuotaCode: "L-6E869C2A", Prefixes: []string{"dl"}},
+	{Name: "Running On-Demand Trn instances", QuotaCode: "L-2C3B7624", Prefixes: []string{"trn"}},
+	{Name: "Running On-Demand High Memory instances", QuotaCode: "L-43DA4232", Prefixes: []string{"u"}},
+	{Name: "Running On-Demand HPC instances", QuotaCode: "L-F7808C92", Prefixes: []string{"hpc"}},
+}
+
+// InstanceQuotaFamily returns the vCPU quota an instance type counts against
+func InstanceQuotaFamily(instanceType string) (*VCPUQuotaFamily, error) {
+	family, _, found := strings.Cut(strings.ToLower(strings.TrimSpace(instanceType)), ".")
+	if !found || family == "" {
+		return nil, fmt.Errorf("invalid instance type: %q", instanceType)
+	}
+
+	// High memory instances are named like u-6tb1 or u7i-12tb, so only the
+	// leading letters identify the family
+	letters := leadingLetters(family)
+	if letters == "mac" {
+		return nil, fmt.Errorf("%s runs on dedicated hosts and has no vCPU quota", instanceType)
+	}
+
+	var best *VCPUQuotaFamily
+	bestLen := 0
+	for i := range vcpuQuotaFamilies {
+		for _, prefix := range vcpuQuotaFamilies[i].Prefixes {
+			if classMatches(letters, prefix) && len(prefix) > bestLen {
+				best = &vcpuQuotaFamilies[i]
+				bestLen = len(prefix)
+			}
+		}
+	}
+
+	if best == nil {
+		return nil, fmt.Errorf("no vCPU quota family known for instance type %s", instanceType)
+	}
+	return best, nil
+}
+
+// classMatches reports whether an instance family's leading letters belong to a quota prefix.
+// Multi-letter prefixes must match exactly; single letters match the first letter
+// (so "r6i" and "c7gn" count as standard).
+func classMatches(letters, prefix string) bool {
+	if len(prefix) > 1 {
+		return letters == prefix
+	}
+	return strings.HasPrefix(letters, prefix)
+}
+
+func leadingLetters(family string) string {
+	for i, r := range family {
+		if r < 'a' || r > 'z' {
+			return family[:i]
+		}
+	}
+	return family
+}
+
+// VCPUQuotaCheck is the result of comparing a planned launch against its vCPU quota
+type VCPUQuotaCheck struct {
+	InstanceType   string
+	Family         VCPUQuotaFamily
+	Region         string
+	Limit          float64
+	CurrentUsage   int32
+	RequestedVCPUs int32
+}
+
+// Required returns the vCPUs needed after the launch
+func (c *VCPUQuotaCheck) Required() int32 {
+	return c.CurrentUsage + c.RequestedVCPUs
+}
+
+// Exceeded reports whether the launch would exceed the quota
+func (c *VCPUQuotaCheck) Exceeded() bool {
+	return float64(c.Required()) > c.Limit
+}
+
+// IncreaseCommand returns the AWS CLI command that requests enough quota for the launch
+func (c *VCPUQuotaCheck) IncreaseCommand() string {
+	return fmt.Sprintf("aws service-quotas request-service-quota-increase --service-code ec2 --quota-code %s --desired-value %d --region %s",
+		c.Family.QuotaCode, c.Required(), c.Region)
+}
+
+// QuotaExceededError reports a launch that would exceed a vCPU quota
+type QuotaExceededError struct {
+	Check *VCPUQuotaCheck
+}
+
+func (e *QuotaExceededError) Error() string {
+	c := e.Check
+	return fmt.Sprintf("%s needs %d vCPUs but the %q quota in %s is %.0f with %d in use; request an increase with:\n  %s",
+		c.InstanceType, c.RequestedVCPUs, c.Family.Name, c.Region, c.Limit, c.CurrentUsage, c.IncreaseCommand())
+}
+
+// quotaEC2API is the subset of the EC2 API used for quota checks
+type quotaEC2API interface {
+	ec2.DescribeInstancesAPIClient
+	DescribeInstanceTypes(ctx context.Context, params *ec2.DescribeInstanceTypesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInstanceTypesOutput, error)
+}
+
+// serviceQuotasAPI is the subset of the Service Quotas API used for quota checks
+type serviceQuotasAPI interface {
+	GetServiceQuota(ctx context.Context, params *servicequotas.GetServiceQuotaInput, optFns ...func(*servicequotas.Options)) (*servicequotas.GetServiceQuotaOutput, error)
+}
+
+// QuotaChecker compares planned launches against EC2 service quotas
+type QuotaChecker struct {
+	ec2    quotaEC2API
+	quotas serviceQuotasAPI
+	region string
+}
+
+// NewQuotaChecker creates a new quota checker
+func NewQuotaChecker(client *Client) *QuotaChecker {
+	return &QuotaChecker{
+		ec2:    client.EC2,
+		quotas: client.ServiceQuotas,
+		region: client.Region,
+	}
+}
+
+// CheckVCPUQuota compares the vCPU quota for an instance type with current usage plus
+// one more instance, returning a QuotaExceededError when the launch would not fit
+func (qc *QuotaChecker) CheckVCPUQuota(ctx context.Context, instanceType string) (*VCPUQuotaCheck, error) {
+	family, err := InstanceQuotaFamily(instanceType)
+	if err != nil {
+		return nil, err
+	}
+
+	requested, err := qc.instanceTypeVCPUs(ctx, instanceType)
+	if err != nil {
+		return nil, err
+	}
+
+	quota, err := qc.quotas.GetServiceQuota(ctx, &servicequotas.GetServiceQuotaInput{
+		ServiceCode: aws.String("ec2"),
+		QuotaCode:   aws.String(family.QuotaCode),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get service quota %s: %w", family.QuotaCode, err)
+	}
+	if quota.Quota == nil || quota.Quota.Value == nil {
+		return nil, fmt.Errorf("service quota %s has no value", family.QuotaCode)
+	}
+
+	usage, err := qc.familyUsage(ctx, family)
+	if err != nil {
+		return nil, err
+	}
+
+	check := &VCPUQuotaCheck{
+		InstanceType:   instanceType,
+		Family:         *family,
+		Region:         qc.region,
+		Limit:          *quota.Quota.Value,
+		CurrentUsage:   usage,
+		RequestedVCPUs: requested,
+	}
+
+	if check.Exceeded() {
+		return check, &QuotaExceededError{Check: check}
+	}
+	return check, nil
+}
+
+func (qc *QuotaChecker) instanceTypeVCPUs(ctx context.Context, instanceType string) (int32, error) {
+	result, err := qc.ec2.DescribeInstanceTypes(ctx, &ec2.DescribeInstanceTypesInput{
+		InstanceTypes: []ec2types.InstanceType{ec2types.InstanceType(instanceType)},
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to describe instance type %s: %w", instanceType, err)
+	}
+	if len(result.InstanceTypes) == 0 || result.InstanceTypes[0].VCpuInfo == nil {
+		return 0, fmt.Errorf("instance type %s is not offered in %s", instanceType, qc.region)
+	}
+	return aws.ToInt32(result.InstanceTypes[0].VCpuInfo.DefaultVCpus), nil
+}
+
+// familyUsage sums the vCPUs of running and pending instances counted against a quota
+func (qc *QuotaChecker) familyUsage(ctx context.Context, family *VCPUQuotaFamily) (int32, error) {
+	input := &ec2.DescribeInstancesInput{
+		Filters: []ec2types.Filter{
+			{Name: aws.String("instance-state-name"), Values: []string{"pending", "running"}},
+		},
+	}
+
+	var usage int32
+	paginator := ec2.NewDescribeInstancesPaginator(qc.ec2, input)
+	for paginator.HasMorePages() {
+		result, err := paginator.NextPage(ctx)
+		if err != nil {
+			return 0, fmt.Errorf("failed to describe instances: %w", err)
+		}
+
+		for _, reservation := range result.Reservations {
+			for _, instance := range reservation.Instances {
+				// Spot instances count against separate quotas
+				if instance.InstanceLifecycle == ec2types.InstanceLifecycleTypeSpot {
+					continue
+				}
+				instanceFamily, err := InstanceQuotaFamily(string(instance.InstanceType))
+				if err != nil || instanceFamily.QuotaCode != family.QuotaCode {
+					continue
+				}
+				if instance.CpuOptions != nil {
+					usage += aws.ToInt32(instance.CpuOptions.CoreCount) * aws.ToInt32(instance.CpuOptions.ThreadsPerCore)
+				}
+			}
+		}
+	}
+
+	return usage, nil
+}
@@ -0,0 +1,163 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/servicequotas"
+	sqtypes "github.com/aws/aws-sdk-go-v2/service/servicequotas/types"
+)
+
+func TestInstanceQuotaFamily(t *testing.T) {
+	tests := []struct {
+		instanceType string
+		want         string
+		wantErr      bool
+	}{
+		{"r6i.4xlarge", "L-1216C47A", false},
+		{"c7gn.16xlarge", "L-1216C47A", false},
+		{"t3.micro", "L-1216C47A", false},
+		{"m7i-flex.large", "L-1216C47A", false},
+		{"i4i.large", "L-1216C47A", false},
+		{"im4gn.large", "L-1216C47A", false},
+		{"d3en.xlarge", "L-1216C47A", false},
+		{"z1d.large", "L-1216C47A", false},
+		{"h1.2xlarge", "L-1216C47A", false},
+		{"a1.medium", "L-1216C47A", false},
+		{"R6I.LARGE", "L-1216C47A", false},
+		{"p4d.24xlarge", "L-417A185B", false},
+		{"p5.48xlarge", "L-417A185B", false},
+		{"g5.xlarge", "L-DB2E81BA", false},
+		{"g5g.xlarge", "L-DB2E81BA", false},
+		{"vt1.3xlarge", "L-DB2E81BA", false},
+		{"inf2.xlarge", "L-1945791B", false},
+		{"dl1.24xlarge", "L-6E869C2A", false},
+		{"trn1.32xlarge", "L-2C3B7624", false},
+		{"f1.2xlarge", "L-74FC7D96", false},
+		{"x2iedn.xlarge", "L-7295265B", false},
+		{"u-6tb1.metal", "L-43DA4232", false},
+		{"u7i-12tb.224xlarge", "L-43DA4232", false},
+		{"hpc6a.48xlarge", "L-F7808C92", false},
+		{"mac2.metal", "", true},
+		{"r6i", "", true},
+		{"", "", true},
+		{"9xl.large", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.instanceType, func(t *testing.T) {
+			family, err := InstanceQuotaFamily(tt.instanceType)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected error, got %s", family.QuotaCode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if family.QuotaCode != tt.want {
+				t.Errorf("InstanceQuotaFamily(%s) = %s (%s), want %s", tt.instanceType, family.QuotaCode, family.Name, tt.want)
+			}
+		})
+	}
+}
+
+type fakeQuotaEC2 struct {
+	vcpus     map[string]int32
+	instances []ec2types.Instance
+}
+
+func (f *fakeQuotaEC2) DescribeInstances(ctx context.Context, params *ec2.DescribeInstancesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInstancesOutput, error) {
+	return &ec2.DescribeInstancesOutput{Reservations: []ec2types.Reservation{{Instances: f.instances}}}, nil
+}
+
+func (f *fakeQuotaEC2) DescribeInstanceTypes(ctx context.Context, params *ec2.DescribeInstanceTypesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInstanceTypesOutput, error) {
+	output := &ec2.DescribeInstanceTypesOutput{}
+	for _, instanceType := range params.InstanceTypes {
+		if vcpus, exists := f.vcpus[string(instanceType)]; exists {
+			output.InstanceTypes = append(output.InstanceTypes, ec2types.InstanceTypeInfo{
+				InstanceType: instanceType,
+				VCpuInfo:     &ec2types.VCpuInfo{DefaultVCpus: aws.Int32(vcpus)},
+			})
+		}
+	}
+	return output, nil
+}
+
+type fakeServiceQuotas struct {
+	limits map[string]float64
+	err    error
+}
+
+func (f *fakeServiceQuotas) GetServiceQuota(ctx context.Context, params *servicequotas.GetServiceQuotaInput, optFns ...func(*servicequotas.Options)) (*servicequotas.GetServiceQuotaOutput, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &servicequotas.GetServiceQuotaOutput{
+		Quota: &sqtypes.ServiceQuota{Value: aws.Float64(f.limits[aws.ToString(params.QuotaCode)])},
+	}, nil
+}
+
+func runningInstance(instanceType string, cores int32, lifecycle ec2types.InstanceLifecycleType) ec2types.Instance {
+	return ec2types.Instance{
+		InstanceType:      ec2types.InstanceType(instanceType),
+		InstanceLifecycle: lifecycle,
+		CpuOptions:        &ec2types.CpuOptions{CoreCount: aws.Int32(cores), ThreadsPerCore: aws.Int32(2)},
+	}
+}
+
+func TestCheckVCPUQuota(t *testing.T) {
+	api := &fakeQuotaEC2{
+		vcpus: map[string]int32{"p4d.24xlarge": 96, "r6i.4xlarge": 16},
+		instances: []ec2types.Instance{
+			runningInstance("p3.8xlarge", 16, ""),
+			runningInstance("p3.2xlarge", 4, ec2types.InstanceLifecycleTypeSpot),
+			runningInstance("r6i.large", 1, ""),
+		},
+	}
+	quotas := &fakeServiceQuotas{limits: map[string]float64{"L-417A185B": 96, "L-1216C47A": 64}}
+	checker := &QuotaChecker{ec2: api, quotas: quotas, region: "us-east-1"}
+
+	check, err := checker.CheckVCPUQuota(context.Background(), "p4d.24xlarge")
+	var exceeded *QuotaExceededError
+	if !errors.As(err, &exceeded) {
+		t.Fatalf("Expected QuotaExceededError, got %v", err)
+	}
+	if check.CurrentUsage != 32 {
+		t.Errorf("Expected 32 on-demand P vCPUs in use (spot excluded), got %d", check.CurrentUsage)
+	}
+	if check.Required() != 128 {
+		t.Errorf("Expected 128 vCPUs required, got %d", check.Required())
+	}
+	if !strings.Contains(err.Error(), "--quota-code L-417A185B --desired-value 128 --region us-east-1") {
+		t.Errorf("Error missing increase command: %v", err)
+	}
+
+	check, err = checker.CheckVCPUQuota(context.Background(), "r6i.4xlarge")
+	if err != nil {
+		t.Fatalf("Expected standard launch to fit: %v", err)
+	}
+	if check.CurrentUsage != 2 || check.Exceeded() {
+		t.Errorf("Unexpected standard check: %+v", check)
+	}
+}
+
+func TestCheckVCPUQuotaAPIErrors(t *testing.T) {
+	api := &fakeQuotaEC2{vcpus: map[string]int32{"r6i.4xlarge": 16}}
+
+	checker := &QuotaChecker{ec2: api, quotas: &fakeServiceQuotas{err: errors.New("AccessDenied")}, region: "us-east-1"}
+	if _, err := checker.CheckVCPUQuota(context.Background(), "r6i.4xlarge"); err == nil || errors.As(err, new(*QuotaExceededError)) {
+		t.Errorf("Expected API error, got %v", err)
+	}
+
+	checker.quotas = &fakeServiceQuotas{}
+	if _, err := checker.CheckVCPUQuota(context.Background(), "g5.xlarge"); err == nil {
+		t.Error("Expected error for instance type not offered in region")
+	}
+}
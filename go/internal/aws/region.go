@@ -0,0 +1,152 @@
+package aws
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/mattn/go-isatty"
+)
+
+// DefaultRegion is used when no region is configured and there is no terminal to ask on
+const DefaultRegion = "us-east-1"
+
+// Where a region came from, other than the environment variable or profile named
+const (
+	RegionSourceFlag    = "--region flag"
+	RegionSourcePrompt  = "prompt"
+	RegionSourceDefault = "default"
+)
+
+// regionEnvVars are read in order; the SDK reads AWS_REGION and the AWS CLI AWS_DEFAULT_REGION
+var regionEnvVars = []string{"AWS_REGION", "AWS_DEFAULT_REGION"}
+
+var regionPattern = regexp.MustCompile(`^[a-z]{2}(-[a-z]+)+-\d+$`)
+
+// ResolvedRegion is the region commands run in and where it was found
+type ResolvedRegion struct {
+	Region string `json:"region"`
+	Source string `json:"source"`
+}
+
+func (r ResolvedRegion) String() string {
+	return fmt.Sprintf("%s (from %s)", r.Region, r.Source)
+}
+
+// RegionResolver finds the region when --region is not given: from the
+// environment, then the active profile in the shared config file, then by
+// asking. Its inputs are fields so they can be controlled in tests.
+type RegionResolver struct {
+	// Getenv reads the environment; nil uses os.Getenv
+	Getenv func(string) string
+	// ConfigFiles are the shared config files searched for the profile; nil
+	// uses AWS_CONFIG_FILE or ~/.aws/config
+	ConfigFiles []string
+	// Prompt asks for a region; nil when there is no terminal to ask on
+	Prompt func() (string, error)
+}
+
+// Resolve returns the region to use, in order of precedence: the flag, AWS_REGION
+// or AWS_DEFAULT_REGION, the profile's region, the prompt, and DefaultRegion
+func (r RegionResolver) Resolve(ctx context.Context, flag string) (ResolvedRegion, error) {
+	if flag != "" {
+		return ResolvedRegion{Region: flag, Source: RegionSourceFlag}, nil
+	}
+
+	getenv := r.Getenv
+	if getenv == nil {
+		getenv = os.Getenv
+	}
+	for _, name := range regionEnvVars {
+		if region := getenv(name); region != "" {
+			return ResolvedRegion{Region: region, Source: name}, nil
+		}
+	}
+
+	profile := getenv("AWS_PROFILE")
+	if profile == "" {
+		profile = "default"
+	}
+	configFiles := r.ConfigFiles
+	if configFiles == nil {
+		if file := getenv("AWS_CONFIG_FILE"); file != "" {
+			configFiles = []string{file}
+		}
+	}
+	shared, err := config.LoadSharedConfigProfile(ctx, profile, func(o *config.LoadSharedConfigOptions) {
+		o.ConfigFiles = configFiles
+		o.CredentialsFiles = []string{}
+	})
+	var notExist config.SharedConfigProfileNotExistError
+	switch {
+	case err == nil && shared.Region != "":
+		return ResolvedRegion{Region: shared.Region, Source: "profile " + profile}, nil
+	case err != nil && !errors.As(err, &notExist):
+		return ResolvedRegion{}, fmt.Errorf("failed to read the region of profile %s: %w", profile, err)
+	}
+
+	if r.Prompt != nil {
+		region, err := r.Prompt()
+		if err != nil {
+			return ResolvedRegion{}, fmt.Errorf("failed to read region: %w", err)
+		}
+		region = strings.TrimSpace(region)
+		if region == "" {
+			region = DefaultRegion
+		}
+		if !regionPattern.MatchString(region) {
+			return ResolvedRegion{}, fmt.Errorf("invalid region %q, expected a name like eu-west-1", region)
+		}
+		return ResolvedRegion{Region: region, Source: RegionSourcePrompt}, nil
+	}
+
+	return ResolvedRegion{Region: DefaultRegion, Source: RegionSourceDefault}, nil
+}
+
+// TerminalRegionPrompt asks for a region on stdin, or returns nil when stdin
+// is not a terminal so scripts never block waiting for an answer
+func TerminalRegionPrompt(out io.Writer) func() (string, error) {
+	if !isatty.IsTerminal(os.Stdin.Fd()) && !isatty.IsCygwinTerminal(os.Stdin.Fd()) {
+		return nil
+	}
+	return func() (string, error) {
+		fmt.Fprintf(out, "No AWS region is configured. Region to use [%s]: ", DefaultRegion)
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return "", err
+		}
+		return line, nil
+	}
+}
+
+var (
+	resolvedRegionMu sync.Mutex
+	resolvedRegion   ResolvedRegion
+)
+
+// SetResolvedRegion records the region resolved for this run, so commands can
+// say where it came from
+func SetResolvedRegion(region ResolvedRegion) {
+	resolvedRegionMu.Lock()
+	defer resolvedRegionMu.Unlock()
+	resolvedRegion = region
+}
+
+// DescribeRegion names a region with where it was resolved from, e.g.
+// "eu-west-1 (from AWS_REGION)". A region set another way, such as by a
+// wizard file environment, is returned as is.
+func DescribeRegion(region string) string {
+	resolvedRegionMu.Lock()
+	defer resolvedRegionMu.Unlock()
+	if region == resolvedRegion.Region && resolvedRegion.Source != "" {
+		return resolvedRegion.String()
+	}
+	return region
+}
@@ -0,0 +1,152 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeAWSConfig(t *testing.T, contents string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config")
+	if err := os.WriteFile(path, []byte(contents), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestRegionResolverPrecedence(t *testing.T) {
+	configFile := writeAWSConfig(t, `[default]
+region = eu-west-1
+
+[profile research]
+region = ap-southeast-2
+
+[profile noregion]
+output = json
+`)
+	prompt := func() (string, error) { return "ca-central-1", nil }
+
+	tests := []struct {
+		name   string
+		flag   string
+		env    map[string]string
+		prompt func() (string, error)
+		want   ResolvedRegion
+	}{
+		{
+			name: "flag wins over everything",
+			flag: "us-west-2",
+			env:  map[string]string{"AWS_REGION": "eu-central-1", "AWS_DEFAULT_REGION": "eu-north-1"},
+			want: ResolvedRegion{Region: "us-west-2", Source: RegionSourceFlag},
+		},
+		{
+			name: "AWS_REGION wins over AWS_DEFAULT_REGION and profile",
+			env:  map[string]string{"AWS_REGION": "eu-central-1", "AWS_DEFAULT_REGION": "eu-north-1"},
+			want: ResolvedRegion{Region: "eu-central-1", Source: "AWS_REGION"},
+		},
+		{
+			name: "AWS_DEFAULT_REGION wins over profile",
+			env:  map[string]string{"AWS_DEFAULT_REGION": "eu-north-1"},
+			want: ResolvedRegion{Region: "eu-north-1", Source: "AWS_DEFAULT_REGION"},
+		},
+		{
+			name: "default profile",
+			want: ResolvedRegion{Region: "eu-west-1", Source: "profile default"},
+		},
+		{
+			name: "AWS_PROFILE selects the profile",
+			env:  map[string]string{"AWS_PROFILE": "research"},
+			want: ResolvedRegion{Region: "ap-southeast-2", Source: "profile research"},
+		},
+		{
+			name:   "profile without a region falls through to the prompt",
+			env:    map[string]string{"AWS_PROFILE": "noregion"},
+			prompt: prompt,
+			want:   ResolvedRegion{Region: "ca-central-1", Source: RegionSourcePrompt},
+		},
+		{
+			name: "missing profile without a terminal uses the default",
+			env:  map[string]string{"AWS_PROFILE": "missing"},
+			want: ResolvedRegion{Region: DefaultRegion, Source: RegionSourceDefault},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resolver := RegionResolver{
+				Getenv:      func(name string) string { return tt.env[name] },
+				ConfigFiles: []string{configFile},
+				Prompt:      tt.prompt,
+			}
+			got, err := resolver.Resolve(context.Background(), tt.flag)
+			if err != nil {
+				t.Fatalf("Resolve() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Resolve() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegionResolverConfigFileFromEnvironment(t *testing.T) {
+	configFile := writeAWSConfig(t, "[default]\nregion = sa-east-1\n")
+	env := map[string]string{"AWS_CONFIG_FILE": configFile}
+	resolver := RegionResolver{Getenv: func(name string) string { return env[name] }}
+
+	got, err := resolver.Resolve(context.Background(), "")
+	if err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+	if got.Region != "sa-east-1" || got.Source != "profile default" {
+		t.Errorf("Resolve() = %+v, want sa-east-1 from profile default", got)
+	}
+}
+
+func TestRegionResolverPrompt(t *testing.T) {
+	noConfig := func(string) string { return "" }
+	tests := []struct {
+		name    string
+		answer  string
+		err     error
+		want    string
+		wantErr bool
+	}{
+		{name: "answer", answer: " eu-west-3\n", want: "eu-west-3"},
+		{name: "empty answer takes the default", answer: "\n", want: DefaultRegion},
+		{name: "invalid answer", answer: "Ireland\n", wantErr: true},
+		{name: "read error", err: errors.New("closed"), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resolver := RegionResolver{
+				Getenv:      noConfig,
+				ConfigFiles: []string{},
+				Prompt:      func() (string, error) { return tt.answer, tt.err },
+			}
+			got, err := resolver.Resolve(context.Background(), "")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Resolve() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got.Region != tt.want {
+				t.Errorf("Resolve() region = %s, want %s", got.Region, tt.want)
+			}
+		})
+	}
+}
+
+func TestDescribeRegion(t *testing.T) {
+	SetResolvedRegion(ResolvedRegion{Region: "eu-west-1", Source: "AWS_REGION"})
+	defer SetResolvedRegion(ResolvedRegion{})
+
+	if got := DescribeRegion("eu-west-1"); got != "eu-west-1 (from AWS_REGION)" {
+		t.Errorf("DescribeRegion(resolved) = %q", got)
+	}
+	if got := DescribeRegion("us-west-2"); got != "us-west-2" {
+		t.Errorf("DescribeRegion(other) = %q", got)
+	}
+}
@@ -0,0 +1,193 @@
+package aws
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/pricing"
+	pricingtypes "github.com/aws/aws-sdk-go-v2/service/pricing/types"
+)
+
+// BaselinePricingRegion is the region the static price tables and domain cost
+// estimates are based on
+const BaselinePricingRegion = "us-east-1"
+
+// pricingAPIRegion hosts the AWS Price List API endpoint
+const pricingAPIRegion = "us-east-1"
+
+// regionPriceMultipliers approximate on-demand prices relative to us-east-1 for
+// use when the Price List API is unavailable
+var regionPriceMultipliers = map[string]float64{
+	"us-east-1":      1.00,
+	"us-east-2":      1.00,
+	"us-west-1":      1.17,
+	"us-west-2":      1.00,
+	"ca-central-1":   1.10,
+	"eu-west-1":      1.11,
+	"eu-west-2":      1.16,
+	"eu-west-3":      1.17,
+	"eu-central-1":   1.19,
+	"eu-north-1":     1.05,
+	"ap-south-1":     1.05,
+	"ap-southeast-1": 1.24,
+	"ap-southeast-2": 1.25,
+	"ap-northeast-1": 1.28,
+	"ap-northeast-2": 1.22,
+	"sa-east-1":      1.55,
+}
+
+// RegionPrices holds on-demand Linux prices for one region
+type RegionPrices struct {
+	Region         string             `json:"region"`
+	InstanceHourly map[string]float64 `json:"instance_hourly"`
+	StorageGBMonth float64            `json:"storage_gb_month"`
+	// Live is set when every price came from the AWS Price List API
+	Live bool `json:"live"`
+}
+
+// pricingAPI is the subset of the Price List API used for regional prices
+type pricingAPI interface {
+	GetProducts(ctx context.Context, params *pricing.GetProductsInput, optFns ...func(*pricing.Options)) (*pricing.GetProductsOutput, error)
+}
+
+// RegionalPricer looks up prices per region, preferring live Price List data
+type RegionalPricer struct {
+	api pricingAPI
+}
+
+// NewRegionalPricer creates a new regional pricer
+func NewRegionalPricer(client *Client) *RegionalPricer {
+	return &RegionalPricer{api: client.Pricing}
+}
+
+// FetchRegionPrices returns prices for instance types and gp3 storage in a region.
+// Prices the Price List API cannot provide fall back to static estimates.
+func (rp *RegionalPricer) FetchRegionPrices(ctx context.Context, region string, instanceTypes []string) (*RegionPrices, error) {
+	estimated, err := EstimatedRegionPrices(region, instanceTypes)
+	if err != nil {
+		return nil, err
+	}
+	if rp.api == nil {
+		return estimated, nil
+	}
+
+	prices := &RegionPrices{
+		Region:         region,
+		InstanceHourly: make(map[string]float64, len(instanceTypes)),
+		Live:           true,
+	}
+
+	for _, instanceType := range instanceTypes {
+		price, err := rp.lookup(ctx, "AmazonEC2", map[string]string{
+			"instanceType":    instanceType,
+			"regionCode":      region,
+			"operatingSystem": "Linux",
+			"tenancy":         "Shared",
+			"preInstalledSw":  "NA",
+			"capacitystatus":  "Used",
+		})
+		if err != nil {
+			prices.InstanceHourly[instanceType] = estimated.InstanceHourly[instanceType]
+			prices.Live = false
+			continue
+		}
+		prices.InstanceHourly[instanceType] = price
+	}
+
+	storage, err := rp.lookup(ctx, "AmazonEC2", map[string]string{
+		"productFamily": "Storage",
+		"volumeApiName": "gp3",
+		"regionCode":    region,
+	})
+	if err != nil {
+		prices.StorageGBMonth = estimated.StorageGBMonth
+		prices.Live = false
+	} else {
+		prices.StorageGBMonth = storage
+	}
+
+	return prices, nil
+}
+
+func (rp *RegionalPricer) lookup(ctx context.Context, serviceCode string, attributes map[string]string) (float64, error) {
+	filters := make([]pricingtypes.Filter, 0, len(attributes))
+	for field, value := range attributes {
+		filters = append(filters, pricingtypes.Filter{
+			Type:  pricingtypes.FilterTypeTermMatch,
+			Field: aws.String(field),
+			Value: aws.String(value),
+		})
+	}
+
+	result, err := rp.api.GetProducts(ctx, &pricing.GetProductsInput{
+		ServiceCode: aws.String(serviceCode),
+		Filters:     filters,
+		MaxResults:  aws.Int32(10),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to get products: %w", err)
+	}
+
+	return parseOnDemandPrice(result.PriceList)
+}
+
+// priceListProduct is the part of a Price List product document holding on-demand prices
+type priceListProduct struct {
+	Terms struct {
+		OnDemand map[string]struct {
+			PriceDimensions map[string]struct {
+				Unit         string            `json:"unit"`
+				PricePerUnit map[string]string `json:"pricePerUnit"`
+			} `json:"priceDimensions"`
+		} `json:"OnDemand"`
+	} `json:"terms"`
+}
+
+// parseOnDemandPrice returns the first non-zero USD on-demand price in a price list
+func parseOnDemandPrice(priceList []string) (float64, error) {
+	for _, document := range priceList {
+		var product priceListProduct
+		if err := json.Unmarshal([]byte(document), &product); err != nil {
+			return 0, fmt.Errorf("failed to parse price list: %w", err)
+		}
+
+		for _, term := range product.Terms.OnDemand {
+			for _, dimension := range term.PriceDimensions {
+				price, err := strconv.ParseFloat(dimension.PricePerUnit["USD"], 64)
+				if err == nil && price > 0 {
+					return price, nil
+				}
+			}
+		}
+	}
+
+	return 0, fmt.Errorf("no on-demand price found")
+}
+
+// EstimatedRegionPrices prices instance types from the static table, scaled by an
+// approximate regional multiplier
+func EstimatedRegionPrices(region string, instanceTypes []string) (*RegionPrices, error) {
+	multiplier, ok := regionPriceMultipliers[region]
+	if !ok {
+		return nil, fmt.Errorf("no pricing estimate available for region %s", region)
+	}
+
+	calculator := &PricingCalculator{region: region}
+	prices := &RegionPrices{
+		Region:         region,
+		InstanceHourly: make(map[string]float64, len(instanceTypes)),
+		StorageGBMonth: EBSStorageCostPerGBMonth * multiplier,
+	}
+	for _, instanceType := range instanceTypes {
+		estimate, err := calculator.CalculateCost(instanceType)
+		if err != nil {
+			return nil, err
+		}
+		prices.InstanceHourly[instanceType] = estimate.HourlyCost * multiplier
+	}
+
+	return prices, nil
+}
@@ -0,0 +1,101 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/pricing"
+)
+
+const fixturePriceList = `{
+  "product": {"attributes": {"instanceType": "c6i.large"}},
+  "terms": {
+    "OnDemand": {
+      "ABC.JRTCKXETXF": {
+        "priceDimensions": {
+          "ABC.JRTCKXETXF.6YS6EN2CT7": {
+            "unit": "Hrs",
+            "pricePerUnit": {"USD": "0.0960000000"}
+          }
+        }
+      }
+    }
+  }
+}`
+
+type fakePricingAPI struct {
+	priceList []string
+	err       error
+	calls     int
+}
+
+func (f *fakePricingAPI) GetProducts(ctx context.Context, params *pricing.GetProductsInput, optFns ...func(*pricing.Options)) (*pricing.GetProductsOutput, error) {
+	f.calls++
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &pricing.GetProductsOutput{PriceList: f.priceList}, nil
+}
+
+func TestParseOnDemandPrice(t *testing.T) {
+	price, err := parseOnDemandPrice([]string{fixturePriceList})
+	if err != nil {
+		t.Fatalf("parseOnDemandPrice failed: %v", err)
+	}
+	if math.Abs(price-0.096) > 1e-9 {
+		t.Errorf("Expected 0.096, got %f", price)
+	}
+
+	if _, err := parseOnDemandPrice(nil); err == nil {
+		t.Error("Expected error for empty price list")
+	}
+	if _, err := parseOnDemandPrice([]string{"not json"}); err == nil {
+		t.Error("Expected error for malformed price list")
+	}
+}
+
+func TestFetchRegionPricesLive(t *testing.T) {
+	api := &fakePricingAPI{priceList: []string{fixturePriceList}}
+	pricer := &RegionalPricer{api: api}
+
+	prices, err := pricer.FetchRegionPrices(context.Background(), "eu-west-1", []string{"c6i.large"})
+	if err != nil {
+		t.Fatalf("FetchRegionPrices failed: %v", err)
+	}
+	if !prices.Live {
+		t.Error("Expected live pricing")
+	}
+	if prices.InstanceHourly["c6i.large"] != 0.096 {
+		t.Errorf("Unexpected instance price: %f", prices.InstanceHourly["c6i.large"])
+	}
+	if api.calls != 2 {
+		t.Errorf("Expected one instance and one storage lookup, got %d", api.calls)
+	}
+}
+
+func TestFetchRegionPricesFallback(t *testing.T) {
+	pricer := &RegionalPricer{api: &fakePricingAPI{err: errors.New("access denied")}}
+
+	prices, err := pricer.FetchRegionPrices(context.Background(), "eu-west-1", []string{"c6i.large"})
+	if err != nil {
+		t.Fatalf("FetchRegionPrices failed: %v", err)
+	}
+	if prices.Live {
+		t.Error("Expected estimated pricing after API errors")
+	}
+	want := 0.085 * regionPriceMultipliers["eu-west-1"]
+	if math.Abs(prices.InstanceHourly["c6i.large"]-want) > 1e-9 {
+		t.Errorf("Expected estimated price %f, got %f", want, prices.InstanceHourly["c6i.large"])
+	}
+	if prices.StorageGBMonth <= EBSStorageCostPerGBMonth {
+		t.Errorf("Expected regional storage markup, got %f", prices.StorageGBMonth)
+	}
+}
+
+func TestEstimatedRegionPricesUnknownRegion(t *testing.T) {
+	if _, err := EstimatedRegionPrices("mars-north-1", []string{"c6i.large"}); err == nil {
+		t.Error("Expected error for unknown region")
+	}
+}
@@ -0,0 +1,250 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/smithy-go/middleware"
+)
+
+// Retry defaults for AWS API calls. Busy accounts throttle EC2 and CloudFormation
+// often enough that the SDK's three attempts are not sufficient.
+const (
+	DefaultMaxAttempts = 10
+	DefaultMaxBackoff  = 20 * time.Second
+)
+
+// RetryOptions controls how AWS API calls are retried
+type RetryOptions struct {
+	// Mode is the SDK retry mode; adaptive mode also rate limits the client after
+	// throttling responses
+	Mode aws.RetryMode
+	// MaxAttempts is the total number of attempts per call, including the first
+	MaxAttempts int
+	// MaxBackoff caps the exponential backoff between attempts
+	MaxBackoff time.Duration
+}
+
+// DefaultRetryOptions returns adaptive retries with the default limits
+func DefaultRetryOptions() RetryOptions {
+	return RetryOptions{
+		Mode:        aws.RetryModeAdaptive,
+		MaxAttempts: DefaultMaxAttempts,
+		MaxBackoff:  DefaultMaxBackoff,
+	}
+}
+
+var (
+	retryOptionsMu sync.Mutex
+	retryOptions   = DefaultRetryOptions()
+)
+
+// SetRetryOptions changes the retry behaviour of clients created afterwards.
+// Zero fields keep their defaults.
+func SetRetryOptions(opts RetryOptions) {
+	defaults := DefaultRetryOptions()
+	if opts.Mode == "" {
+		opts.Mode = defaults.Mode
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = defaults.MaxAttempts
+	}
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = defaults.MaxBackoff
+	}
+
+	retryOptionsMu.Lock()
+	defer retryOptionsMu.Unlock()
+	retryOptions = opts
+}
+
+func currentRetryOptions() RetryOptions {
+	retryOptionsMu.Lock()
+	defer retryOptionsMu.Unlock()
+	return retryOptions
+}
+
+// newRetryer builds the retryer for a set of options. The SDK backs off
+// exponentially with full jitter between attempts.
+func newRetryer(opts RetryOptions) func() aws.Retryer {
+	standard := func(o *retry.StandardOptions) {
+		o.MaxAttempts = opts.MaxAttempts
+		o.MaxBackoff = opts.MaxBackoff
+		o.Backoff = retry.NewExponentialJitterBackoff(opts.MaxBackoff)
+	}
+
+	return func() aws.Retryer {
+		if opts.Mode == aws.RetryModeStandard {
+			return retry.NewStandard(standard)
+		}
+		return retry.NewAdaptiveMode(func(o *retry.AdaptiveModeOptions) {
+			o.StandardOptions = append(o.StandardOptions, standard)
+		})
+	}
+}
+
+// APICallStats counts the calls made to one AWS API operation
+type APICallStats struct {
+	Service   string `json:"service"`
+	Operation string `json:"operation"`
+	// Calls counts operations; Attempts includes every retry of them
+	Calls     int `json:"calls"`
+	Attempts  int `json:"attempts"`
+	Retries   int `json:"retries"`
+	Throttles int `json:"throttles"`
+	Failures  int `json:"failures"`
+}
+
+// APIMetrics counts AWS API calls, retries and throttling per operation
+type APIMetrics struct {
+	mu    sync.Mutex
+	calls map[string]*APICallStats
+}
+
+// NewAPIMetrics creates an empty set of API metrics
+func NewAPIMetrics() *APIMetrics {
+	return &APIMetrics{calls: make(map[string]*APICallStats)}
+}
+
+// defaultAPIMetrics collects metrics for every client created by NewClient
+var defaultAPIMetrics = NewAPIMetrics()
+
+// GetAPIMetrics returns the API calls made by clients created with NewClient,
+// busiest operation first
+func GetAPIMetrics() []APICallStats {
+	return defaultAPIMetrics.Snapshot()
+}
+
+// PrintAPIMetrics writes a table of the API calls made by clients created with
+// NewClient
+func PrintAPIMetrics(w io.Writer) {
+	defaultAPIMetrics.Print(w)
+}
+
+// Snapshot returns a copy of the counters, busiest operation first
+func (m *APIMetrics) Snapshot() []APICallStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stats := make([]APICallStats, 0, len(m.calls))
+	for _, call := range m.calls {
+		stats = append(stats, *call)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Attempts != stats[j].Attempts {
+			return stats[i].Attempts > stats[j].Attempts
+		}
+		if stats[i].Service != stats[j].Service {
+			return stats[i].Service < stats[j].Service
+		}
+		return stats[i].Operation < stats[j].Operation
+	})
+	return stats
+}
+
+// Reset clears the counters
+func (m *APIMetrics) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.calls = make(map[string]*APICallStats)
+}
+
+// Print writes the counters as a table
+func (m *APIMetrics) Print(w io.Writer) {
+	stats := m.Snapshot()
+	if len(stats) == 0 {
+		fmt.Fprintln(w, "No AWS API calls made")
+		return
+	}
+
+	var total APICallStats
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SERVICE\tOPERATION\tCALLS\tRETRIES\tTHROTTLES\tFAILURES")
+	for _, call := range stats {
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%d\t%d\n", call.Service, call.Operation, call.Calls, call.Retries, call.Throttles, call.Failures)
+		total.Calls += call.Calls
+		total.Retries += call.Retries
+		total.Throttles += call.Throttles
+		total.Failures += call.Failures
+	}
+	fmt.Fprintf(tw, "Total\t\t%d\t%d\t%d\t%d\n", total.Calls, total.Retries, total.Throttles, total.Failures)
+	tw.Flush()
+}
+
+func (m *APIMetrics) record(ctx context.Context, update func(*APICallStats)) {
+	service := awsmiddleware.GetServiceID(ctx)
+	operation := awsmiddleware.GetOperationName(ctx)
+	key := service + "." + operation
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	call, exists := m.calls[key]
+	if !exists {
+		call = &APICallStats{Service: service, Operation: operation}
+		m.calls[key] = call
+	}
+	update(call)
+}
+
+// addMiddleware registers the counters on a client's middleware stack. The retry
+// middleware reports every attempt of a call in the response metadata.
+func (m *APIMetrics) addMiddleware(stack *middleware.Stack) error {
+	throttles := retry.IsErrorThrottles(retry.DefaultThrottles)
+	counter := middleware.InitializeMiddlewareFunc("APIMetrics", func(
+		ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler,
+	) (middleware.InitializeOutput, middleware.Metadata, error) {
+		out, metadata, err := next.HandleInitialize(ctx, in)
+
+		attempts := 1
+		throttled := 0
+		if results, ok := retry.GetAttemptResults(metadata); ok && len(results.Results) > 0 {
+			attempts = len(results.Results)
+			for _, result := range results.Results {
+				if result.Err != nil && throttles.IsErrorThrottle(result.Err) == aws.TrueTernary {
+					throttled++
+				}
+			}
+		}
+
+		m.record(ctx, func(call *APICallStats) {
+			call.Calls++
+			call.Attempts += attempts
+			call.Retries += attempts - 1
+			call.Throttles += throttled
+			if err != nil {
+				call.Failures++
+			}
+		})
+		return out, metadata, err
+	})
+	// Service and operation names are only in the context after the service metadata
+	// middleware has run
+	return stack.Initialize.Insert(counter, (&awsmiddleware.RegisterServiceMetadata{}).ID(), middleware.After)
+}
+
+// configureClient applies retry options and API metrics to a config that
+// service clients are created from
+func configureClient(cfg *aws.Config, opts RetryOptions, metrics *APIMetrics) {
+	cfg.RetryMode = opts.Mode
+	cfg.RetryMaxAttempts = opts.MaxAttempts
+	cfg.Retryer = newRetryer(opts)
+	cfg.APIOptions = append(cfg.APIOptions, metrics.addMiddleware)
+}
+
+// pollJitter is the fraction polling intervals of long waits are varied by
+const pollJitter = 0.2
+
+// jitteredInterval varies a polling interval by up to pollJitter either way, so
+// concurrent waits spread their calls instead of polling in lockstep
+func jitteredInterval(base time.Duration) time.Duration {
+	return base + time.Duration((rand.Float64()*2-1)*pollJitter*float64(base))
+}
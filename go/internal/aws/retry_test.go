@@ -0,0 +1,221 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+)
+
+const (
+	ec2ThrottleBody = `<Response><Errors><Error><Code>RequestLimitExceeded</Code><Message>Request limit exceeded.</Message></Error></Errors><RequestID>1</RequestID></Response>`
+	ec2RegionsBody  = `<DescribeRegionsResponse xmlns="http://ec2.amazonaws.com/doc/2016-11-15/"><requestId>1</requestId><regionInfo><item><regionName>us-east-1</regionName></item></regionInfo></DescribeRegionsResponse>`
+	cfnThrottleBody = `<ErrorResponse><Error><Type>Sender</Type><Code>Throttling</Code><Message>Rate exceeded</Message></Error><RequestId>1</RequestId></ErrorResponse>`
+	cfnStacksBody   = `<DescribeStacksResponse><DescribeStacksResult><Stacks></Stacks></DescribeStacksResult></DescribeStacksResponse>`
+)
+
+type stubResponse struct {
+	status int
+	body   string
+}
+
+// stubHTTPClient replays canned responses in order and records when each request
+// was made
+type stubHTTPClient struct {
+	mu        sync.Mutex
+	responses []stubResponse
+	requests  []time.Time
+}
+
+func (c *stubHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.requests) >= len(c.responses) {
+		return nil, errors.New("unexpected request")
+	}
+	response := c.responses[len(c.requests)]
+	c.requests = append(c.requests, time.Now())
+
+	return &http.Response{
+		StatusCode: response.status,
+		Header:     http.Header{"Content-Type": []string{"text/xml"}},
+		Body:       io.NopCloser(strings.NewReader(response.body)),
+		Request:    req,
+	}, nil
+}
+
+func repeatResponse(response stubResponse, n int) []stubResponse {
+	responses := make([]stubResponse, n)
+	for i := range responses {
+		responses[i] = response
+	}
+	return responses
+}
+
+func testRetryConfig(httpClient *stubHTTPClient, opts RetryOptions, metrics *APIMetrics) aws.Config {
+	cfg := aws.Config{
+		Region:      "us-east-1",
+		Credentials: aws.AnonymousCredentials{},
+		HTTPClient:  httpClient,
+	}
+	configureClient(&cfg, opts, metrics)
+	return cfg
+}
+
+func testRetryOptions(mode aws.RetryMode, maxAttempts int) RetryOptions {
+	return RetryOptions{Mode: mode, MaxAttempts: maxAttempts, MaxBackoff: 20 * time.Millisecond}
+}
+
+func TestRetryThrottlingThenSuccess(t *testing.T) {
+	httpClient := &stubHTTPClient{responses: append(
+		repeatResponse(stubResponse{503, ec2ThrottleBody}, 2),
+		stubResponse{200, ec2RegionsBody},
+	)}
+	metrics := NewAPIMetrics()
+	client := ec2.NewFromConfig(testRetryConfig(httpClient, testRetryOptions(aws.RetryModeStandard, 5), metrics))
+
+	result, err := client.DescribeRegions(context.Background(), &ec2.DescribeRegionsInput{})
+	if err != nil {
+		t.Fatalf("DescribeRegions() error = %v", err)
+	}
+	if len(result.Regions) != 1 {
+		t.Errorf("got %d regions, want 1", len(result.Regions))
+	}
+
+	stats := metrics.Snapshot()
+	if len(stats) != 1 {
+		t.Fatalf("got %d operations, want 1", len(stats))
+	}
+	want := APICallStats{Service: "EC2", Operation: "DescribeRegions", Calls: 1, Attempts: 3, Retries: 2, Throttles: 2}
+	if stats[0] != want {
+		t.Errorf("stats = %+v, want %+v", stats[0], want)
+	}
+}
+
+func TestRetryGivesUpAtMaxAttempts(t *testing.T) {
+	httpClient := &stubHTTPClient{responses: repeatResponse(stubResponse{400, cfnThrottleBody}, 10)}
+	metrics := NewAPIMetrics()
+	client := cloudformation.NewFromConfig(testRetryConfig(httpClient, testRetryOptions(aws.RetryModeStandard, 4), metrics))
+
+	_, err := client.DescribeStacks(context.Background(), &cloudformation.DescribeStacksInput{})
+	if err == nil {
+		t.Fatal("expected throttling error after max attempts")
+	}
+	var maxAttempts *retry.MaxAttemptsError
+	if !errors.As(err, &maxAttempts) {
+		t.Errorf("error = %v, want a max attempts error", err)
+	}
+	if len(httpClient.requests) != 4 {
+		t.Errorf("made %d requests, want 4", len(httpClient.requests))
+	}
+
+	stats := metrics.Snapshot()
+	want := APICallStats{Service: "CloudFormation", Operation: "DescribeStacks", Calls: 1, Attempts: 4, Retries: 3, Throttles: 4, Failures: 1}
+	if len(stats) != 1 || stats[0] != want {
+		t.Errorf("stats = %+v, want %+v", stats, want)
+	}
+}
+
+func TestRetryAdaptiveMode(t *testing.T) {
+	httpClient := &stubHTTPClient{responses: []stubResponse{
+		{400, cfnThrottleBody},
+		{200, cfnStacksBody},
+		{200, cfnStacksBody},
+	}}
+	metrics := NewAPIMetrics()
+	cfg := testRetryConfig(httpClient, testRetryOptions(aws.RetryModeAdaptive, 3), metrics)
+	if _, ok := cfg.Retryer().(*retry.AdaptiveMode); !ok {
+		t.Fatalf("retryer = %T, want adaptive mode", cfg.Retryer())
+	}
+
+	client := cloudformation.NewFromConfig(cfg)
+	for i := 0; i < 2; i++ {
+		if _, err := client.DescribeStacks(context.Background(), &cloudformation.DescribeStacksInput{}); err != nil {
+			t.Fatalf("DescribeStacks() error = %v", err)
+		}
+	}
+
+	stats := metrics.Snapshot()
+	want := APICallStats{Service: "CloudFormation", Operation: "DescribeStacks", Calls: 2, Attempts: 3, Retries: 1, Throttles: 1}
+	if len(stats) != 1 || stats[0] != want {
+		t.Errorf("stats = %+v, want %+v", stats, want)
+	}
+}
+
+func TestRetryBackoffIsCapped(t *testing.T) {
+	opts := RetryOptions{Mode: aws.RetryModeStandard, MaxAttempts: 10, MaxBackoff: 50 * time.Millisecond}
+	retryer := newRetryer(opts)()
+	if retryer.MaxAttempts() != 10 {
+		t.Errorf("MaxAttempts() = %d, want 10", retryer.MaxAttempts())
+	}
+
+	throttle := errors.New("throttled")
+	for attempt := 1; attempt <= 10; attempt++ {
+		delay, err := retryer.RetryDelay(attempt, throttle)
+		if err != nil {
+			t.Fatalf("RetryDelay(%d) error = %v", attempt, err)
+		}
+		if delay < 0 || delay > opts.MaxBackoff {
+			t.Errorf("RetryDelay(%d) = %v, want between 0 and %v", attempt, delay, opts.MaxBackoff)
+		}
+	}
+}
+
+func TestSetRetryOptionsDefaults(t *testing.T) {
+	defer SetRetryOptions(DefaultRetryOptions())
+
+	SetRetryOptions(RetryOptions{MaxAttempts: 4})
+	opts := currentRetryOptions()
+	if opts.MaxAttempts != 4 || opts.Mode != aws.RetryModeAdaptive || opts.MaxBackoff != DefaultMaxBackoff {
+		t.Errorf("options = %+v, want 4 adaptive attempts with the default backoff", opts)
+	}
+}
+
+func TestAPIMetricsOrderAndReset(t *testing.T) {
+	metrics := NewAPIMetrics()
+	metrics.calls["EC2.DescribeInstances"] = &APICallStats{Service: "EC2", Operation: "DescribeInstances", Calls: 1, Attempts: 1}
+	metrics.calls["CloudFormation.DescribeStacks"] = &APICallStats{Service: "CloudFormation", Operation: "DescribeStacks", Calls: 3, Attempts: 5}
+
+	stats := metrics.Snapshot()
+	if len(stats) != 2 || stats[0].Operation != "DescribeStacks" {
+		t.Errorf("snapshot = %+v, want busiest operation first", stats)
+	}
+
+	var out strings.Builder
+	metrics.Print(&out)
+	if !strings.Contains(out.String(), "DescribeStacks") || !strings.Contains(out.String(), "Total") {
+		t.Errorf("Print() output missing rows:\n%s", out.String())
+	}
+
+	metrics.Reset()
+	if len(metrics.Snapshot()) != 0 {
+		t.Error("expected no metrics after Reset()")
+	}
+}
+
+func TestJitteredInterval(t *testing.T) {
+	base := 30 * time.Second
+	low := time.Duration(float64(base) * (1 - pollJitter))
+	high := time.Duration(float64(base) * (1 + pollJitter))
+	seen := make(map[time.Duration]bool)
+	for i := 0; i < 100; i++ {
+		interval := jitteredInterval(base)
+		if interval < low || interval > high {
+			t.Fatalf("jitteredInterval() = %v, want between %v and %v", interval, low, high)
+		}
+		seen[interval] = true
+	}
+	if len(seen) < 2 {
+		t.Error("expected jittered intervals to vary")
+	}
+}
@@ -0,0 +1,619 @@
+package aws
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/aws/smithy-go"
+)
+
+// ScheduleSkipTag exempts an instance from its stack's scheduled stop, so a
+// long-running job keeps running; tagged instances are still started
+const ScheduleSkipTag = "no-schedule"
+
+// scheduleStackTag marks a schedule stack with the stack it starts and stops
+const scheduleStackTag = "ScheduledStack"
+
+// Parameters of the schedule stack template, which also record the friendly
+// schedule so it can be read back
+const (
+	scheduleParamStack           = "TargetStack"
+	scheduleParamStart           = "StartTime"
+	scheduleParamStop            = "StopTime"
+	scheduleParamDays            = "Days"
+	scheduleParamTimezone        = "Timezone"
+	scheduleParamStartExpression = "StartExpression"
+	scheduleParamStopExpression  = "StopExpression"
+)
+
+// cronWeekdays are the EventBridge cron day-of-week names in cron order
+var cronWeekdays = []string{"SUN", "MON", "TUE", "WED", "THU", "FRI", "SAT"}
+
+// InstanceSchedule starts a stack's instances and stops them again on the
+// same days, at wall-clock times in a time zone
+type InstanceSchedule struct {
+	Stack string
+	// Start and Stop are HH:MM times
+	Start string
+	Stop  string
+	// Days is the cron day-of-week field, such as MON-FRI, or * for every day
+	Days     string
+	Timezone string
+}
+
+// NewInstanceSchedule validates and normalizes a schedule given in the
+// friendly syntax: times such as 8:00 or 18:30, days such as mon-fri, sat,sun,
+// weekdays or daily, and an IANA time zone
+func NewInstanceSchedule(stack, start, stop, days, timezone string) (*InstanceSchedule, error) {
+	if stack == "" {
+		return nil, fmt.Errorf("stack name is required")
+	}
+	startTime, err := parseClockTime(start)
+	if err != nil {
+		return nil, fmt.Errorf("invalid start time: %w", err)
+	}
+	stopTime, err := parseClockTime(stop)
+	if err != nil {
+		return nil, fmt.Errorf("invalid stop time: %w", err)
+	}
+	if startTime == stopTime {
+		return nil, fmt.Errorf("start and stop times are both %s", startTime)
+	}
+	weekdays, err := ParseScheduleDays(days)
+	if err != nil {
+		return nil, err
+	}
+	if timezone == "" {
+		timezone = "UTC"
+	}
+	if _, err := time.LoadLocation(timezone); err != nil {
+		return nil, fmt.Errorf("unknown time zone %q", timezone)
+	}
+
+	return &InstanceSchedule{
+		Stack:    stack,
+		Start:    startTime,
+		Stop:     stopTime,
+		Days:     cronDays(weekdays),
+		Timezone: timezone,
+	}, nil
+}
+
+// parseClockTime parses an H:MM or HH:MM time into HH:MM
+func parseClockTime(value string) (string, error) {
+	parsed, err := time.Parse("15:04", strings.TrimSpace(value))
+	if err != nil {
+		return "", fmt.Errorf("%q is not an HH:MM time", value)
+	}
+	return parsed.Format("15:04"), nil
+}
+
+// scheduleDayNames maps day names and abbreviations to weekdays
+var scheduleDayNames = map[string]time.Weekday{
+	"sun": time.Sunday, "sunday": time.Sunday,
+	"mon": time.Monday, "monday": time.Monday,
+	"tue": time.Tuesday, "tues": time.Tuesday, "tuesday": time.Tuesday,
+	"wed": time.Wednesday, "wednesday": time.Wednesday,
+	"thu": time.Thursday, "thurs": time.Thursday, "thursday": time.Thursday,
+	"fri": time.Friday, "friday": time.Friday,
+	"sat": time.Saturday, "saturday": time.Saturday,
+}
+
+// ParseScheduleDays parses a comma-separated list of days, ranges such as
+// mon-fri or fri-mon (which wraps over the weekend), and the shorthands
+// weekdays, weekends and daily. The days are returned Sunday first.
+func ParseScheduleDays(value string) ([]time.Weekday, error) {
+	selected := make(map[time.Weekday]bool)
+	for _, part := range strings.Split(strings.ToLower(value), ",") {
+		part = strings.TrimSpace(part)
+		switch part {
+		case "":
+			continue
+		case "daily", "everyday", "all", "*":
+			for day := time.Sunday; day <= time.Saturday; day++ {
+				selected[day] = true
+			}
+			continue
+		case "weekdays":
+			part = "mon-fri"
+		case "weekends":
+			part = "sat,sun"
+		}
+
+		for _, item := range strings.Split(part, ",") {
+			from, to, isRange := strings.Cut(item, "-")
+			first, ok := scheduleDayNames[strings.TrimSpace(from)]
+			if !ok {
+				return nil, fmt.Errorf("unknown day %q", strings.TrimSpace(from))
+			}
+			last := first
+			if isRange {
+				if last, ok = scheduleDayNames[strings.TrimSpace(to)]; !ok {
+					return nil, fmt.Errorf("unknown day %q", strings.TrimSpace(to))
+				}
+			}
+			for day := first; ; day = (day + 1) % 7 {
+				selected[day] = true
+				if day == last {
+					break
+				}
+			}
+		}
+	}
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("no days given")
+	}
+
+	days := make([]time.Weekday, 0, len(selected))
+	for day := range selected {
+		days = append(days, day)
+	}
+	sort.Slice(days, func(i, j int) bool { return days[i] < days[j] })
+	return days, nil
+}
+
+// cronDays renders weekdays as a cron day-of-week field, joining runs of
+// consecutive days into ranges: MON-FRI, SUN,SAT, or * for every day
+func cronDays(days []time.Weekday) string {
+	if len(days) == 7 {
+		return "*"
+	}
+	var parts []string
+	for i := 0; i < len(days); {
+		j := i
+		for j+1 < len(days) && days[j+1] == days[j]+1 {
+			j++
+		}
+		if j > i {
+			parts = append(parts, cronWeekdays[days[i]]+"-"+cronWeekdays[days[j]])
+		} else {
+			parts = append(parts, cronWeekdays[days[i]])
+		}
+		i = j + 1
+	}
+	return strings.Join(parts, ",")
+}
+
+// cronExpression builds an EventBridge Scheduler cron expression firing at an
+// HH:MM time on the schedule's days. Day-of-month must be ? when days of the
+// week are given, and day-of-week must be ? when every day is.
+func (s *InstanceSchedule) cronExpression(clock string) string {
+	hour, minute, _ := strings.Cut(clock, ":")
+	hour = strings.TrimPrefix(hour, "0")
+	if hour == "" {
+		hour = "0"
+	}
+	minute = strings.TrimPrefix(minute, "0")
+	if minute == "" {
+		minute = "0"
+	}
+	if s.Days == "*" {
+		return fmt.Sprintf("cron(%s %s * * ? *)", minute, hour)
+	}
+	return fmt.Sprintf("cron(%s %s ? * %s *)", minute, hour, s.Days)
+}
+
+// StartExpression is the cron expression that starts the instances
+func (s *InstanceSchedule) StartExpression() string {
+	return s.cronExpression(s.Start)
+}
+
+// StopExpression is the cron expression that stops the instances
+func (s *InstanceSchedule) StopExpression() string {
+	return s.cronExpression(s.Stop)
+}
+
+// DaysDescription describes the schedule's days for people
+func (s *InstanceSchedule) DaysDescription() string {
+	if s.Days == "*" {
+		return "daily"
+	}
+	return strings.ToLower(s.Days)
+}
+
+// ScheduleStackName is the name of the stack holding a stack's schedule
+func ScheduleStackName(stack string) string {
+	return stack + "-schedule"
+}
+
+// Parameters returns the schedule stack's template parameters
+func (s *InstanceSchedule) Parameters() map[string]string {
+	return map[string]string{
+		scheduleParamStack:           s.Stack,
+		scheduleParamStart:           s.Start,
+		scheduleParamStop:            s.Stop,
+		scheduleParamDays:            s.Days,
+		scheduleParamTimezone:        s.Timezone,
+		scheduleParamStartExpression: s.StartExpression(),
+		scheduleParamStopExpression:  s.StopExpression(),
+	}
+}
+
+// scheduleFromParameters reads a schedule back from its stack parameters
+func scheduleFromParameters(parameters map[string]string) *InstanceSchedule {
+	return &InstanceSchedule{
+		Stack:    parameters[scheduleParamStack],
+		Start:    parameters[scheduleParamStart],
+		Stop:     parameters[scheduleParamStop],
+		Days:     parameters[scheduleParamDays],
+		Timezone: parameters[scheduleParamTimezone],
+	}
+}
+
+// schedulerFunctionCode starts or stops a stack's instances, leaving any tagged
+// with ScheduleSkipTag running when stopping. Only instances in the opposite state are
+// touched, so a repeated or late invocation is harmless.
+var schedulerFunctionCode = `import boto3
+
+ec2 = boto3.client("ec2")
+SKIP_TAG = "` + ScheduleSkipTag + `"
+
+
+def handler(event, context):
+    action = event["action"]
+    state = "stopped" if action == "start" else "running"
+    paginator = ec2.get_paginator("describe_instances")
+    pages = paginator.paginate(Filters=[
+        {"Name": "tag:` + cfnStackNameTag + `", "Values": [event["stack"]]},
+        {"Name": "instance-state-name", "Values": [state]},
+    ])
+
+    instance_ids = []
+    for page in pages:
+        for reservation in page["Reservations"]:
+            for instance in reservation["Instances"]:
+                tags = {tag["Key"] for tag in instance.get("Tags", [])}
+                if action == "stop" and SKIP_TAG in tags:
+                    print("Skipping %s: tagged %s" % (instance["InstanceId"], SKIP_TAG))
+                    continue
+                instance_ids.append(instance["InstanceId"])
+
+    if instance_ids:
+        if action == "start":
+            ec2.start_instances(InstanceIds=instance_ids)
+        else:
+            ec2.stop_instances(InstanceIds=instance_ids)
+    print("%s: %s" % (action, ", ".join(instance_ids) or "nothing to do"))
+    return {"action": action, "instances": instance_ids}
+`
+
+// ScheduleTemplate returns the CloudFormation template for a schedule stack:
+// two EventBridge Scheduler schedules invoking a small Lambda function that
+// starts or stops the target stack's instances. The function may only start
+// and stop instances belonging to that stack.
+func ScheduleTemplate() (string, error) {
+	parameter := func(description string) map[string]interface{} {
+		return map[string]interface{}{"Type": "String", "Description": description}
+	}
+	stackCondition := map[string]interface{}{
+		"StringEquals": map[string]interface{}{
+			"aws:ResourceTag/" + cfnStackNameTag: map[string]interface{}{"Ref": scheduleParamStack},
+		},
+	}
+	schedule := func(action, verb, expression string) map[string]interface{} {
+		return map[string]interface{}{
+			"Type": "AWS::Scheduler::Schedule",
+			"Properties": map[string]interface{}{
+				"Description": map[string]interface{}{
+					"Fn::Sub": fmt.Sprintf("%s the instances of ${%s}", verb, scheduleParamStack),
+				},
+				"ScheduleExpression":         map[string]interface{}{"Ref": expression},
+				"ScheduleExpressionTimezone": map[string]interface{}{"Ref": scheduleParamTimezone},
+				"FlexibleTimeWindow":         map[string]interface{}{"Mode": "OFF"},
+				"State":                      "ENABLED",
+				"Target": map[string]interface{}{
+					"Arn":     map[string]interface{}{"Fn::GetAtt": []string{"SchedulerFunction", "Arn"}},
+					"RoleArn": map[string]interface{}{"Fn::GetAtt": []string{"SchedulerInvokeRole", "Arn"}},
+					"Input": map[string]interface{}{
+						"Fn::Sub": fmt.Sprintf(`{"action": "%s", "stack": "${%s}"}`, action, scheduleParamStack),
+					},
+				},
+			},
+		}
+	}
+
+	template := map[string]interface{}{
+		"AWSTemplateFormatVersion": "2010-09-09",
+		"Description":              "AWS Research Wizard office-hours instance schedule",
+		"Parameters": map[string]interface{}{
+			scheduleParamStack:           parameter("Stack whose instances are started and stopped"),
+			scheduleParamStart:           parameter("Start time (HH:MM)"),
+			scheduleParamStop:            parameter("Stop time (HH:MM)"),
+			scheduleParamDays:            parameter("Days of the week"),
+			scheduleParamTimezone:        parameter("Time zone of the start and stop times"),
+			scheduleParamStartExpression: parameter("Cron expression that starts the instances"),
+			scheduleParamStopExpression:  parameter("Cron expression that stops the instances"),
+		},
+		"Resources": map[string]interface{}{
+			"SchedulerFunctionRole": map[string]interface{}{
+				"Type": "AWS::IAM::Role",
+				"Properties": map[string]interface{}{
+					"AssumeRolePolicyDocument": assumeRolePolicy("lambda.amazonaws.com"),
+					"ManagedPolicyArns": []interface{}{
+						map[string]interface{}{"Fn::Sub": "arn:${AWS::Partition}:iam::aws:policy/service-role/AWSLambdaBasicExecutionRole"},
+					},
+					"Policies": []interface{}{
+						map[string]interface{}{
+							"PolicyName": "StartStopStackInstances",
+							"PolicyDocument": map[string]interface{}{
+								"Version": "2012-10-17",
+								"Statement": []interface{}{
+									map[string]interface{}{
+										"Effect":   "Allow",
+										"Action":   "ec2:DescribeInstances",
+										"Resource": "*",
+									},
+									map[string]interface{}{
+										"Effect":    "Allow",
+										"Action":    []string{"ec2:StartInstances", "ec2:StopInstances"},
+										"Resource":  map[string]interface{}{"Fn::Sub": "arn:${AWS::Partition}:ec2:${AWS::Region}:${AWS::AccountId}:instance/*"},
+										"Condition": stackCondition,
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			"SchedulerFunction": map[string]interface{}{
+				"Type": "AWS::Lambda::Function",
+				"Properties": map[string]interface{}{
+					"Description": map[string]interface{}{"Fn::Sub": fmt.Sprintf("Starts and stops the instances of ${%s}", scheduleParamStack)},
+					"Runtime":     "python3.12",
+					"Handler":     "index.handler",
+					"Timeout":     60,
+					"Role":        map[string]interface{}{"Fn::GetAtt": []string{"SchedulerFunctionRole", "Arn"}},
+					"Code":        map[string]interface{}{"ZipFile": schedulerFunctionCode},
+				},
+			},
+			"SchedulerInvokeRole": map[string]interface{}{
+				"Type": "AWS::IAM::Role",
+				"Properties": map[string]interface{}{
+					"AssumeRolePolicyDocument": assumeRolePolicy("scheduler.amazonaws.com"),
+					"Policies": []interface{}{
+						map[string]interface{}{
+							"PolicyName": "InvokeSchedulerFunction",
+							"PolicyDocument": map[string]interface{}{
+								"Version": "2012-10-17",
+								"Statement": []interface{}{
+									map[string]interface{}{
+										"Effect":   "Allow",
+										"Action":   "lambda:InvokeFunction",
+										"Resource": map[string]interface{}{"Fn::GetAtt": []string{"SchedulerFunction", "Arn"}},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			"StartSchedule": schedule("start", "Start", scheduleParamStartExpression),
+			"StopSchedule":  schedule("stop", "Stop", scheduleParamStopExpression),
+		},
+	}
+
+	body, err := json.MarshalIndent(template, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to render schedule template: %w", err)
+	}
+	return string(body), nil
+}
+
+// assumeRolePolicy lets an AWS service assume a role
+func assumeRolePolicy(service string) map[string]interface{} {
+	return map[string]interface{}{
+		"Version": "2012-10-17",
+		"Statement": []interface{}{
+			map[string]interface{}{
+				"Effect":    "Allow",
+				"Principal": map[string]interface{}{"Service": service},
+				"Action":    "sts:AssumeRole",
+			},
+		},
+	}
+}
+
+// scheduleAPI is the subset of the CloudFormation API used for schedules
+type scheduleAPI interface {
+	cloudformation.DescribeStacksAPIClient
+	CreateStack(ctx context.Context, params *cloudformation.CreateStackInput, optFns ...func(*cloudformation.Options)) (*cloudformation.CreateStackOutput, error)
+	UpdateStack(ctx context.Context, params *cloudformation.UpdateStackInput, optFns ...func(*cloudformation.Options)) (*cloudformation.UpdateStackOutput, error)
+	DeleteStack(ctx context.Context, params *cloudformation.DeleteStackInput, optFns ...func(*cloudformation.Options)) (*cloudformation.DeleteStackOutput, error)
+}
+
+// ScheduleManager keeps office-hours schedules for research stacks, each in
+// its own CloudFormation stack next to the stack it schedules
+type ScheduleManager struct {
+	api          scheduleAPI
+	pollInterval time.Duration
+	timeout      time.Duration
+}
+
+// NewScheduleManager creates a new schedule manager
+func NewScheduleManager(client *Client) *ScheduleManager {
+	return &ScheduleManager{
+		api:          client.CloudFormation,
+		pollInterval: 10 * time.Second,
+		timeout:      15 * time.Minute,
+	}
+}
+
+// Set creates a stack's schedule, or replaces the existing one, and waits
+// for it to take effect
+func (sm *ScheduleManager) Set(ctx context.Context, schedule *InstanceSchedule) error {
+	templateBody, err := ScheduleTemplate()
+	if err != nil {
+		return err
+	}
+	stackName := ScheduleStackName(schedule.Stack)
+
+	parameterValues := schedule.Parameters()
+	keys := make([]string, 0, len(parameterValues))
+	for key := range parameterValues {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	parameters := make([]types.Parameter, 0, len(keys))
+	for _, key := range keys {
+		parameters = append(parameters, types.Parameter{
+			ParameterKey:   aws.String(key),
+			ParameterValue: aws.String(parameterValues[key]),
+		})
+	}
+	capabilities := []types.Capability{types.CapabilityCapabilityIam}
+	tags := stackTags(map[string]string{scheduleStackTag: schedule.Stack})
+
+	existing, err := sm.Get(ctx, schedule.Stack)
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		_, err = sm.api.CreateStack(ctx, &cloudformation.CreateStackInput{
+			StackName:    aws.String(stackName),
+			TemplateBody: aws.String(templateBody),
+			Parameters:   parameters,
+			Capabilities: capabilities,
+			Tags:         tags,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create schedule stack %s: %w", stackName, err)
+		}
+	} else {
+		_, err = sm.api.UpdateStack(ctx, &cloudformation.UpdateStackInput{
+			StackName:    aws.String(stackName),
+			TemplateBody: aws.String(templateBody),
+			Parameters:   parameters,
+			Capabilities: capabilities,
+			Tags:         tags,
+		})
+		if err != nil {
+			if isNoUpdatesError(err) {
+				return nil
+			}
+			return fmt.Errorf("failed to update schedule stack %s: %w", stackName, err)
+		}
+	}
+
+	return sm.wait(ctx, stackName)
+}
+
+// Get returns a stack's schedule, or nil when it has none
+func (sm *ScheduleManager) Get(ctx context.Context, stack string) (*InstanceSchedule, error) {
+	stackName := ScheduleStackName(stack)
+	result, err := sm.api.DescribeStacks(ctx, &cloudformation.DescribeStacksInput{StackName: aws.String(stackName)})
+	if err != nil {
+		if isStackNotFoundError(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to describe schedule stack %s: %w", stackName, err)
+	}
+	for _, stack := range result.Stacks {
+		if stack.StackStatus != types.StackStatusDeleteComplete {
+			return scheduleFromParameters(newStackInfo(stack).Parameters), nil
+		}
+	}
+	return nil, nil
+}
+
+// List returns every schedule in the region, ordered by stack
+func (sm *ScheduleManager) List(ctx context.Context) ([]*InstanceSchedule, error) {
+	var schedules []*InstanceSchedule
+	paginator := cloudformation.NewDescribeStacksPaginator(sm.api, &cloudformation.DescribeStacksInput{})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe stacks: %w", err)
+		}
+		for _, stack := range page.Stacks {
+			if stack.StackStatus == types.StackStatusDeleteComplete {
+				continue
+			}
+			for _, tag := range stack.Tags {
+				if aws.ToString(tag.Key) == scheduleStackTag {
+					schedules = append(schedules, scheduleFromParameters(newStackInfo(stack).Parameters))
+					break
+				}
+			}
+		}
+	}
+	sort.Slice(schedules, func(i, j int) bool { return schedules[i].Stack < schedules[j].Stack })
+	return schedules, nil
+}
+
+// Remove deletes a stack's schedule and waits for it to be gone. The
+// instances are left in whatever state they are in.
+func (sm *ScheduleManager) Remove(ctx context.Context, stack string) error {
+	existing, err := sm.Get(ctx, stack)
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		return fmt.Errorf("stack %s has no schedule", stack)
+	}
+
+	stackName := ScheduleStackName(stack)
+	if _, err := sm.api.DeleteStack(ctx, &cloudformation.DeleteStackInput{StackName: aws.String(stackName)}); err != nil {
+		return fmt.Errorf("failed to delete schedule stack %s: %w", stackName, err)
+	}
+	return sm.wait(ctx, stackName)
+}
+
+// wait polls a schedule stack until its create, update or delete finishes
+func (sm *ScheduleManager) wait(ctx context.Context, stackName string) error {
+	ctx, cancel := context.WithTimeout(ctx, sm.timeout)
+	defer cancel()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("timeout waiting for schedule stack %s", stackName)
+		case <-time.After(jitteredInterval(sm.pollInterval)):
+		}
+
+		result, err := sm.api.DescribeStacks(ctx, &cloudformation.DescribeStacksInput{StackName: aws.String(stackName)})
+		if err != nil {
+			if isStackNotFoundError(err) {
+				return nil
+			}
+			return fmt.Errorf("failed to describe schedule stack %s: %w", stackName, err)
+		}
+		if len(result.Stacks) == 0 {
+			return nil
+		}
+
+		stack := result.Stacks[0]
+		status := string(stack.StackStatus)
+		switch {
+		case stack.StackStatus == types.StackStatusCreateComplete,
+			stack.StackStatus == types.StackStatusUpdateComplete,
+			stack.StackStatus == types.StackStatusDeleteComplete:
+			return nil
+		case strings.HasSuffix(status, "_IN_PROGRESS"):
+			continue
+		default:
+			return fmt.Errorf("schedule stack %s failed with status %s: %s", stackName, status, aws.ToString(stack.StackStatusReason))
+		}
+	}
+}
+
+// isStackNotFoundError reports whether CloudFormation rejected a request
+// because the stack does not exist
+func isStackNotFoundError(err error) bool {
+	var apiErr smithy.APIError
+	return errors.As(err, &apiErr) && apiErr.ErrorCode() == "ValidationError" &&
+		strings.Contains(apiErr.ErrorMessage(), "does not exist")
+}
+
+// isNoUpdatesError reports whether an update was rejected because nothing changed
+func isNoUpdatesError(err error) bool {
+	var apiErr smithy.APIError
+	return errors.As(err, &apiErr) && strings.Contains(apiErr.ErrorMessage(), "No updates are to be performed")
+}
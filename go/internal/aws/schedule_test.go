@@ -0,0 +1,359 @@
+package aws
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/aws/smithy-go"
+)
+
+func TestParseScheduleDays(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"mon-fri", "MON-FRI"},
+		{"Mon-Fri", "MON-FRI"},
+		{"weekdays", "MON-FRI"},
+		{"sat,sun", "SUN,SAT"},
+		{"weekends", "SUN,SAT"},
+		{"fri-mon", "SUN-MON,FRI-SAT"},
+		{"mon,wed,fri", "MON,WED,FRI"},
+		{"monday-thursday, sat", "MON-THU,SAT"},
+		{"tue", "TUE"},
+		{"daily", "*"},
+		{"sun-sat", "*"},
+		{"weekdays,weekends", "*"},
+	}
+
+	for _, tt := range tests {
+		days, err := ParseScheduleDays(tt.input)
+		if err != nil {
+			t.Errorf("ParseScheduleDays(%q) error = %v", tt.input, err)
+			continue
+		}
+		if got := cronDays(days); got != tt.want {
+			t.Errorf("ParseScheduleDays(%q) = %s, want %s", tt.input, got, tt.want)
+		}
+	}
+
+	for _, input := range []string{"", "funday", "mon-xyz", ","} {
+		if _, err := ParseScheduleDays(input); err == nil {
+			t.Errorf("ParseScheduleDays(%q) should fail", input)
+		}
+	}
+}
+
+func TestNewInstanceSchedule(t *testing.T) {
+	schedule, err := NewInstanceSchedule("genomics-lab", "8:00", "18:30", "mon-fri", "America/New_York")
+	if err != nil {
+		t.Fatalf("NewInstanceSchedule() error = %v", err)
+	}
+	if schedule.Start != "08:00" || schedule.Stop != "18:30" || schedule.Days != "MON-FRI" {
+		t.Errorf("schedule = %+v", schedule)
+	}
+	if got := schedule.StartExpression(); got != "cron(0 8 ? * MON-FRI *)" {
+		t.Errorf("StartExpression() = %s", got)
+	}
+	if got := schedule.StopExpression(); got != "cron(30 18 ? * MON-FRI *)" {
+		t.Errorf("StopExpression() = %s", got)
+	}
+
+	daily, err := NewInstanceSchedule("genomics-lab", "00:05", "23:00", "daily", "")
+	if err != nil {
+		t.Fatalf("NewInstanceSchedule() error = %v", err)
+	}
+	// Every day needs day-of-month * and day-of-week ?
+	if got := daily.StartExpression(); got != "cron(5 0 * * ? *)" {
+		t.Errorf("daily StartExpression() = %s", got)
+	}
+	if daily.Timezone != "UTC" || daily.DaysDescription() != "daily" {
+		t.Errorf("daily schedule = %+v", daily)
+	}
+
+	invalid := []struct {
+		name                         string
+		stack, start, stop, timezone string
+	}{
+		{"missing stack", "", "08:00", "18:00", "UTC"},
+		{"bad start", "lab", "8am", "18:00", "UTC"},
+		{"bad stop", "lab", "08:00", "25:00", "UTC"},
+		{"same times", "lab", "08:00", "8:00", "UTC"},
+		{"bad time zone", "lab", "08:00", "18:00", "Mars/Olympus_Mons"},
+	}
+	for _, tt := range invalid {
+		if _, err := NewInstanceSchedule(tt.stack, tt.start, tt.stop, "mon-fri", tt.timezone); err == nil {
+			t.Errorf("%s: NewInstanceSchedule() should fail", tt.name)
+		}
+	}
+}
+
+func TestScheduleParametersRoundTrip(t *testing.T) {
+	schedule, err := NewInstanceSchedule("genomics-lab", "07:45", "19:00", "sat,sun", "Europe/London")
+	if err != nil {
+		t.Fatalf("NewInstanceSchedule() error = %v", err)
+	}
+	parameters := schedule.Parameters()
+	if parameters[scheduleParamStopExpression] != "cron(0 19 ? * SUN,SAT *)" {
+		t.Errorf("stop expression parameter = %s", parameters[scheduleParamStopExpression])
+	}
+	if got := scheduleFromParameters(parameters); *got != *schedule {
+		t.Errorf("scheduleFromParameters() = %+v, want %+v", got, schedule)
+	}
+}
+
+func TestScheduleTemplate(t *testing.T) {
+	body, err := ScheduleTemplate()
+	if err != nil {
+		t.Fatalf("ScheduleTemplate() error = %v", err)
+	}
+
+	var template struct {
+		Parameters map[string]interface{}
+		Resources  map[string]struct {
+			Type       string
+			Properties map[string]interface{}
+		}
+	}
+	if err := json.Unmarshal([]byte(body), &template); err != nil {
+		t.Fatalf("template is not valid JSON: %v", err)
+	}
+
+	// Every parameter a schedule passes must be declared
+	schedule, _ := NewInstanceSchedule("lab", "08:00", "18:00", "mon-fri", "UTC")
+	for key := range schedule.Parameters() {
+		if _, ok := template.Parameters[key]; !ok {
+			t.Errorf("template does not declare parameter %s", key)
+		}
+	}
+
+	for name, expression := range map[string]string{"StartSchedule": "StartExpression", "StopSchedule": "StopExpression"} {
+		resource := template.Resources[name]
+		if resource.Type != "AWS::Scheduler::Schedule" {
+			t.Fatalf("%s type = %s", name, resource.Type)
+		}
+		ref := resource.Properties["ScheduleExpression"].(map[string]interface{})["Ref"]
+		if ref != expression {
+			t.Errorf("%s expression = %v, want Ref %s", name, ref, expression)
+		}
+		if tz := resource.Properties["ScheduleExpressionTimezone"].(map[string]interface{})["Ref"]; tz != "Timezone" {
+			t.Errorf("%s time zone = %v", name, tz)
+		}
+		target := resource.Properties["Target"].(map[string]interface{})
+		input := target["Input"].(map[string]interface{})["Fn::Sub"].(string)
+		action := strings.ToLower(strings.TrimSuffix(name, "Schedule"))
+		if !strings.Contains(input, `"action": "`+action+`"`) || !strings.Contains(input, "${TargetStack}") {
+			t.Errorf("%s input = %s", name, input)
+		}
+	}
+
+	// Starting and stopping is limited to the target stack's instances
+	if !strings.Contains(body, `"aws:ResourceTag/aws:cloudformation:stack-name"`) {
+		t.Error("start/stop permissions should be conditioned on the stack tag")
+	}
+	code := template.Resources["SchedulerFunction"].Properties["Code"].(map[string]interface{})["ZipFile"].(string)
+	for _, want := range []string{`SKIP_TAG = "no-schedule"`, `if action == "stop" and SKIP_TAG in tags:`, "ec2.stop_instances", "ec2.start_instances"} {
+		if !strings.Contains(code, want) {
+			t.Errorf("function code missing %q", want)
+		}
+	}
+}
+
+// fakeBoto3 serves one tagged and one untagged instance to the scheduler function
+const fakeBoto3 = `INSTANCES = [
+    {"InstanceId": "i-tagged", "Tags": [{"Key": "no-schedule", "Value": "true"}]},
+    {"InstanceId": "i-plain", "Tags": []},
+]
+
+
+class _Paginator:
+    def paginate(self, Filters):
+        return [{"Reservations": [{"Instances": INSTANCES}]}]
+
+
+class _Client:
+    def get_paginator(self, name):
+        return _Paginator()
+
+    def start_instances(self, InstanceIds):
+        pass
+
+    def stop_instances(self, InstanceIds):
+        pass
+
+
+def client(name):
+    return _Client()
+`
+
+func TestSchedulerFunctionSkipTag(t *testing.T) {
+	python, err := exec.LookPath("python3")
+	if err != nil {
+		t.Skip("python3 is not installed")
+	}
+	dir := t.TempDir()
+	for name, content := range map[string]string{"boto3.py": fakeBoto3, "scheduler.py": schedulerFunctionCode} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Tagged instances keep running past the stop but are started with the stack
+	for action, want := range map[string][]string{
+		"start": {"i-tagged", "i-plain"},
+		"stop":  {"i-plain"},
+	} {
+		// The handler logs to stdout, so the result is written to stderr
+		script := `import json, sys, scheduler
+result = scheduler.handler({"action": sys.argv[1], "stack": "research"}, None)
+sys.stderr.write(json.dumps(result["instances"]))`
+		cmd := exec.Command(python, "-c", script, action)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), "PYTHONPATH="+dir)
+		var stderr strings.Builder
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err != nil {
+			t.Fatalf("%s handler failed: %v\n%s", action, err, stderr.String())
+		}
+		var instances []string
+		if err := json.Unmarshal([]byte(stderr.String()), &instances); err != nil {
+			t.Fatalf("%s handler output %q: %v", action, stderr.String(), err)
+		}
+		if !reflect.DeepEqual(instances, want) {
+			t.Errorf("%s touched %v, want %v", action, instances, want)
+		}
+	}
+}
+
+// fakeScheduleAPI keeps schedule stacks in memory
+type fakeScheduleAPI struct {
+	stacks  map[string]types.Stack
+	created *cloudformation.CreateStackInput
+	updated *cloudformation.UpdateStackInput
+	deleted string
+	// noChanges makes updates fail the way CloudFormation does when nothing changed
+	noChanges bool
+}
+
+func newFakeScheduleAPI() *fakeScheduleAPI {
+	return &fakeScheduleAPI{stacks: make(map[string]types.Stack)}
+}
+
+func (f *fakeScheduleAPI) DescribeStacks(ctx context.Context, params *cloudformation.DescribeStacksInput, optFns ...func(*cloudformation.Options)) (*cloudformation.DescribeStacksOutput, error) {
+	if name := aws.ToString(params.StackName); name != "" {
+		stack, ok := f.stacks[name]
+		if !ok {
+			return nil, &smithy.GenericAPIError{Code: "ValidationError", Message: "Stack with id " + name + " does not exist"}
+		}
+		return &cloudformation.DescribeStacksOutput{Stacks: []types.Stack{stack}}, nil
+	}
+	output := &cloudformation.DescribeStacksOutput{}
+	for _, stack := range f.stacks {
+		output.Stacks = append(output.Stacks, stack)
+	}
+	output.Stacks = append(output.Stacks, types.Stack{StackName: aws.String("unrelated"), StackStatus: types.StackStatusCreateComplete})
+	return output, nil
+}
+
+func (f *fakeScheduleAPI) CreateStack(ctx context.Context, params *cloudformation.CreateStackInput, optFns ...func(*cloudformation.Options)) (*cloudformation.CreateStackOutput, error) {
+	f.created = params
+	f.stacks[aws.ToString(params.StackName)] = types.Stack{
+		StackName:   params.StackName,
+		StackStatus: types.StackStatusCreateComplete,
+		Parameters:  params.Parameters,
+		Tags:        params.Tags,
+	}
+	return &cloudformation.CreateStackOutput{StackId: aws.String("stack-id")}, nil
+}
+
+func (f *fakeScheduleAPI) UpdateStack(ctx context.Context, params *cloudformation.UpdateStackInput, optFns ...func(*cloudformation.Options)) (*cloudformation.UpdateStackOutput, error) {
+	if f.noChanges {
+		return nil, &smithy.GenericAPIError{Code: "ValidationError", Message: "No updates are to be performed."}
+	}
+	f.updated = params
+	f.stacks[aws.ToString(params.StackName)] = types.Stack{
+		StackName:   params.StackName,
+		StackStatus: types.StackStatusUpdateComplete,
+		Parameters:  params.Parameters,
+		Tags:        params.Tags,
+	}
+	return &cloudformation.UpdateStackOutput{}, nil
+}
+
+func (f *fakeScheduleAPI) DeleteStack(ctx context.Context, params *cloudformation.DeleteStackInput, optFns ...func(*cloudformation.Options)) (*cloudformation.DeleteStackOutput, error) {
+	f.deleted = aws.ToString(params.StackName)
+	delete(f.stacks, f.deleted)
+	return &cloudformation.DeleteStackOutput{}, nil
+}
+
+func TestScheduleManager(t *testing.T) {
+	ctx := context.Background()
+	api := newFakeScheduleAPI()
+	manager := &ScheduleManager{api: api, pollInterval: time.Millisecond, timeout: time.Second}
+
+	if schedule, err := manager.Get(ctx, "genomics-lab"); err != nil || schedule != nil {
+		t.Fatalf("Get() before Set = %+v, %v", schedule, err)
+	}
+
+	schedule, _ := NewInstanceSchedule("genomics-lab", "08:00", "18:30", "mon-fri", "America/New_York")
+	if err := manager.Set(ctx, schedule); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if api.created == nil || aws.ToString(api.created.StackName) != "genomics-lab-schedule" {
+		t.Fatalf("Set() should create the schedule stack, got %+v", api.created)
+	}
+	tags := api.created.Tags
+	if aws.ToString(tags[len(tags)-1].Key) != scheduleStackTag || aws.ToString(tags[len(tags)-1].Value) != "genomics-lab" {
+		t.Errorf("schedule stack tags = %+v", tags)
+	}
+
+	got, err := manager.Get(ctx, "genomics-lab")
+	if err != nil || got == nil || *got != *schedule {
+		t.Fatalf("Get() = %+v, %v", got, err)
+	}
+
+	// Setting again replaces the schedule in place
+	later, _ := NewInstanceSchedule("genomics-lab", "09:00", "17:00", "mon-thu", "America/New_York")
+	if err := manager.Set(ctx, later); err != nil {
+		t.Fatalf("Set() update error = %v", err)
+	}
+	if api.updated == nil {
+		t.Fatal("Set() should update an existing schedule")
+	}
+	api.noChanges = true
+	if err := manager.Set(ctx, later); err != nil {
+		t.Errorf("Set() with no changes should succeed, got %v", err)
+	}
+
+	other, _ := NewInstanceSchedule("climate-lab", "06:00", "20:00", "daily", "UTC")
+	if err := manager.Set(ctx, other); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	schedules, err := manager.List(ctx)
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(schedules) != 2 || schedules[0].Stack != "climate-lab" || schedules[1].Start != "09:00" {
+		t.Errorf("List() = %+v", schedules)
+	}
+
+	if err := manager.Remove(ctx, "genomics-lab"); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	if api.deleted != "genomics-lab-schedule" {
+		t.Errorf("Remove() deleted %q", api.deleted)
+	}
+	if err := manager.Remove(ctx, "genomics-lab"); err == nil {
+		t.Error("Remove() of a missing schedule should fail")
+	}
+}
@@ -0,0 +1,517 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+// SnapshotStorageCostPerGBMonth is the standard tier EBS snapshot storage price
+const SnapshotStorageCostPerGBMonth = 0.05
+
+// snapshotAPI is the subset of the EC2 API used for snapshot and restore operations
+type snapshotAPI interface {
+	DescribeInstances(ctx context.Context, params *ec2.DescribeInstancesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInstancesOutput, error)
+	DescribeVolumes(ctx context.Context, params *ec2.DescribeVolumesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeVolumesOutput, error)
+	DescribeSnapshots(ctx context.Context, params *ec2.DescribeSnapshotsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeSnapshotsOutput, error)
+	CreateSnapshot(ctx context.Context, params *ec2.CreateSnapshotInput, optFns ...func(*ec2.Options)) (*ec2.CreateSnapshotOutput, error)
+	CreateVolume(ctx context.Context, params *ec2.CreateVolumeInput, optFns ...func(*ec2.Options)) (*ec2.CreateVolumeOutput, error)
+	DeleteVolume(ctx context.Context, params *ec2.DeleteVolumeInput, optFns ...func(*ec2.Options)) (*ec2.DeleteVolumeOutput, error)
+	AttachVolume(ctx context.Context, params *ec2.AttachVolumeInput, optFns ...func(*ec2.Options)) (*ec2.AttachVolumeOutput, error)
+	DetachVolume(ctx context.Context, params *ec2.DetachVolumeInput, optFns ...func(*ec2.Options)) (*ec2.DetachVolumeOutput, error)
+	StopInstances(ctx context.Context, params *ec2.StopInstancesInput, optFns ...func(*ec2.Options)) (*ec2.StopInstancesOutput, error)
+	StartInstances(ctx context.Context, params *ec2.StartInstancesInput, optFns ...func(*ec2.Options)) (*ec2.StartInstancesOutput, error)
+}
+
+// SnapshotManager handles EBS snapshots of research instances
+type SnapshotManager struct {
+	api          snapshotAPI
+	pollInterval time.Duration
+	timeout      time.Duration
+}
+
+// NewSnapshotManager creates a new snapshot manager
+func NewSnapshotManager(client *Client) *SnapshotManager {
+	return &SnapshotManager{
+		api:          client.EC2,
+		pollInterval: 15 * time.Second,
+		timeout:      30 * time.Minute,
+	}
+}
+
+// VolumeInfo contains EBS volume attachment information
+type VolumeInfo struct {
+	VolumeID   string
+	DeviceName string
+	SizeGB     int32
+	VolumeType string
+}
+
+// SnapshotInfo contains EBS snapshot information
+type SnapshotInfo struct {
+	SnapshotID  string
+	VolumeID    string
+	DeviceName  string
+	SizeGB      int32
+	State       string
+	StartTime   time.Time
+	Tags        map[string]string
+	MonthlyCost float64
+}
+
+// RestoreResult describes a completed restore
+type RestoreResult struct {
+	InstanceID       string
+	DeviceName       string
+	NewVolumeID      string
+	OriginalVolumeID string
+}
+
+// RestoreError reports a restore that failed after modifying the instance,
+// with the steps needed to recover by hand
+type RestoreError struct {
+	Step          string
+	Err           error
+	RecoverySteps []string
+}
+
+func (e *RestoreError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "restore failed while %s: %v", e.Step, e.Err)
+	if len(e.RecoverySteps) > 0 {
+		b.WriteString("\nmanual recovery steps:")
+		for i, step := range e.RecoverySteps {
+			fmt.Fprintf(&b, "\n  %d. %s", i+1, step)
+		}
+	}
+	return b.String()
+}
+
+func (e *RestoreError) Unwrap() error {
+	return e.Err
+}
+
+// InstanceVolumes returns the EBS volumes attached to an instance
+func (sm *SnapshotManager) InstanceVolumes(ctx context.Context, instanceID string) ([]VolumeInfo, error) {
+	instance, err := sm.describeInstance(ctx, instanceID)
+	if err != nil {
+		return nil, err
+	}
+
+	devices := make(map[string]string)
+	volumeIDs := make([]string, 0, len(instance.BlockDeviceMappings))
+	for _, mapping := range instance.BlockDeviceMappings {
+		if mapping.Ebs == nil || mapping.Ebs.VolumeId == nil {
+			continue
+		}
+		devices[*mapping.Ebs.VolumeId] = aws.ToString(mapping.DeviceName)
+		volumeIDs = append(volumeIDs, *mapping.Ebs.VolumeId)
+	}
+
+	if len(volumeIDs) == 0 {
+		return nil, nil
+	}
+
+	result, err := sm.api.DescribeVolumes(ctx, &ec2.DescribeVolumesInput{VolumeIds: volumeIDs})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe volumes: %w", err)
+	}
+
+	volumes := make([]VolumeInfo, 0, len(result.Volumes))
+	for _, volume := range result.Volumes {
+		volumeID := aws.ToString(volume.VolumeId)
+		volumes = append(volumes, VolumeInfo{
+			VolumeID:   volumeID,
+			DeviceName: devices[volumeID],
+			SizeGB:     aws.ToInt32(volume.Size),
+			VolumeType: string(volume.VolumeType),
+		})
+	}
+
+	return volumes, nil
+}
+
+// SnapshotInstance snapshots every EBS volume attached to an instance
+func (sm *SnapshotManager) SnapshotInstance(ctx context.Context, instanceID string, tags map[string]string) ([]SnapshotInfo, error) {
+	volumes, err := sm.InstanceVolumes(ctx, instanceID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(volumes) == 0 {
+		return nil, fmt.Errorf("instance %s has no EBS volumes", instanceID)
+	}
+
+	snapshots := make([]SnapshotInfo, 0, len(volumes))
+	for _, volume := range volumes {
+		snapshotTags := map[string]string{
+			"CreatedBy":  "AWS-Research-Wizard",
+			"InstanceId": instanceID,
+			"DeviceName": volume.DeviceName,
+		}
+		for key, value := range tags {
+			snapshotTags[key] = value
+		}
+
+		result, err := sm.api.CreateSnapshot(ctx, &ec2.CreateSnapshotInput{
+			VolumeId:    aws.String(volume.VolumeID),
+			Description: aws.String(fmt.Sprintf("AWS Research Wizard snapshot of %s (%s)", instanceID, volume.DeviceName)),
+			TagSpecifications: []ec2types.TagSpecification{
+				{
+					ResourceType: ec2types.ResourceTypeSnapshot,
+					Tags:         toEC2Tags(snapshotTags),
+				},
+			},
+		})
+		if err != nil {
+			return snapshots, fmt.Errorf("failed to snapshot volume %s: %w", volume.VolumeID, err)
+		}
+
+		snapshots = append(snapshots, SnapshotInfo{
+			SnapshotID:  aws.ToString(result.SnapshotId),
+			VolumeID:    volume.VolumeID,
+			DeviceName:  volume.DeviceName,
+			SizeGB:      volume.SizeGB,
+			State:       string(result.State),
+			StartTime:   aws.ToTime(result.StartTime),
+			Tags:        snapshotTags,
+			MonthlyCost: float64(volume.SizeGB) * SnapshotStorageCostPerGBMonth,
+		})
+	}
+
+	return snapshots, nil
+}
+
+// ListSnapshots lists the snapshots taken of a stack
+func (sm *SnapshotManager) ListSnapshots(ctx context.Context, stackName string) ([]SnapshotInfo, error) {
+	input := &ec2.DescribeSnapshotsInput{
+		OwnerIds: []string{"self"},
+		Filters: []ec2types.Filter{
+			{Name: aws.String("tag:StackName"), Values: []string{stackName}},
+			{Name: aws.String("tag:CreatedBy"), Values: []string{"AWS-Research-Wizard"}},
+		},
+	}
+
+	var snapshots []SnapshotInfo
+	paginator := ec2.NewDescribeSnapshotsPaginator(sm.api, input)
+	for paginator.HasMorePages() {
+		result, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe snapshots: %w", err)
+		}
+
+		for _, snapshot := range result.Snapshots {
+			snapshots = append(snapshots, newSnapshotInfo(snapshot))
+		}
+	}
+
+	return snapshots, nil
+}
+
+// copyVolumePerformance carries the original volume's provisioned IOPS and
+// throughput over to the new volume. DescribeVolumes reports IOPS for every
+// type, but CreateVolume only accepts it for io1, io2 and gp3, and throughput
+// only for gp3.
+func copyVolumePerformance(input *ec2.CreateVolumeInput, original ec2types.Volume) {
+	switch original.VolumeType {
+	case ec2types.VolumeTypeIo1, ec2types.VolumeTypeIo2:
+		input.Iops = original.Iops
+	case ec2types.VolumeTypeGp3:
+		input.Iops = original.Iops
+		input.Throughput = original.Throughput
+	}
+}
+
+// RestoreSnapshot replaces the volume a snapshot was taken from with a new volume
+// created from the snapshot. The original volume is detached but kept.
+func (sm *SnapshotManager) RestoreSnapshot(ctx context.Context, instanceID, snapshotID string) (*RestoreResult, error) {
+	snapshot, err := sm.describeSnapshot(ctx, snapshotID)
+	if err != nil {
+		return nil, err
+	}
+
+	instance, err := sm.describeInstance(ctx, instanceID)
+	if err != nil {
+		return nil, err
+	}
+
+	deviceName, originalVolumeID, err := restoreTarget(instance, snapshot)
+	if err != nil {
+		return nil, err
+	}
+
+	volumes, err := sm.api.DescribeVolumes(ctx, &ec2.DescribeVolumesInput{VolumeIds: []string{originalVolumeID}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe volume %s: %w", originalVolumeID, err)
+	}
+	if len(volumes.Volumes) == 0 {
+		return nil, fmt.Errorf("volume %s not found", originalVolumeID)
+	}
+	original := volumes.Volumes[0]
+
+	if instance.Placement == nil || instance.Placement.AvailabilityZone == nil {
+		return nil, fmt.Errorf("instance %s has no availability zone", instanceID)
+	}
+
+	wasRunning := instance.State != nil && instance.State.Name == ec2types.InstanceStateNameRunning
+	startStep := fmt.Sprintf("aws ec2 start-instances --instance-ids %s", instanceID)
+
+	// Stop the instance so the volume can be swapped
+	if _, err := sm.api.StopInstances(ctx, &ec2.StopInstancesInput{InstanceIds: []string{instanceID}}); err != nil {
+		return nil, fmt.Errorf("failed to stop instance %s: %w", instanceID, err)
+	}
+	if err := sm.waitForInstanceState(ctx, instanceID, ec2types.InstanceStateNameStopped); err != nil {
+		return nil, sm.abortRestore(ctx, instanceID, wasRunning, "stopping the instance", err, nil)
+	}
+
+	// Create the replacement volume in the instance's availability zone
+	createInput := &ec2.CreateVolumeInput{
+		SnapshotId:       aws.String(snapshotID),
+		AvailabilityZone: instance.Placement.AvailabilityZone,
+		VolumeType:       original.VolumeType,
+		TagSpecifications: []ec2types.TagSpecification{
+			{
+				ResourceType: ec2types.ResourceTypeVolume,
+				Tags:         toEC2Tags(mergeTags(snapshot.Tags, map[string]string{"RestoredFrom": snapshotID})),
+			},
+		},
+	}
+	copyVolumePerformance(createInput, original)
+	created, err := sm.api.CreateVolume(ctx, createInput)
+	if err != nil {
+		return nil, sm.abortRestore(ctx, instanceID, wasRunning, "creating the volume", err, nil)
+	}
+	newVolumeID := aws.ToString(created.VolumeId)
+
+	if err := sm.waitForVolumeState(ctx, newVolumeID, ec2types.VolumeStateAvailable); err != nil {
+		return nil, sm.abortRestore(ctx, instanceID, wasRunning, "waiting for the new volume", err, &newVolumeID)
+	}
+
+	// Swap the attachment
+	if _, err := sm.api.DetachVolume(ctx, &ec2.DetachVolumeInput{VolumeId: aws.String(originalVolumeID), InstanceId: aws.String(instanceID)}); err != nil {
+		return nil, sm.abortRestore(ctx, instanceID, wasRunning, "detaching the original volume", err, &newVolumeID)
+	}
+	if err := sm.waitForVolumeState(ctx, originalVolumeID, ec2types.VolumeStateAvailable); err != nil {
+		return nil, &RestoreError{
+			Step: "detaching the original volume",
+			Err:  err,
+			RecoverySteps: []string{
+				fmt.Sprintf("Check the state of %s: aws ec2 describe-volumes --volume-ids %s", originalVolumeID, originalVolumeID),
+				fmt.Sprintf("If it is detached, reattach it: aws ec2 attach-volume --volume-id %s --instance-id %s --device %s", originalVolumeID, instanceID, deviceName),
+				fmt.Sprintf("Delete the unused restored volume: aws ec2 delete-volume --volume-id %s", newVolumeID),
+				startStep,
+			},
+		}
+	}
+
+	attachErr := sm.attachAndWait(ctx, newVolumeID, instanceID, deviceName)
+	if attachErr != nil {
+		// Put the original volume back before giving up
+		if err := sm.attachAndWait(ctx, originalVolumeID, instanceID, deviceName); err != nil {
+			return nil, &RestoreError{
+				Step: "attaching the restored volume",
+				Err:  fmt.Errorf("%v (reattaching the original volume also failed: %v)", attachErr, err),
+				RecoverySteps: []string{
+					fmt.Sprintf("aws ec2 detach-volume --volume-id %s", newVolumeID),
+					fmt.Sprintf("aws ec2 attach-volume --volume-id %s --instance-id %s --device %s", originalVolumeID, instanceID, deviceName),
+					startStep,
+				},
+			}
+		}
+		return nil, sm.abortRestore(ctx, instanceID, wasRunning, "attaching the restored volume", attachErr, &newVolumeID)
+	}
+
+	result := &RestoreResult{
+		InstanceID:       instanceID,
+		DeviceName:       deviceName,
+		NewVolumeID:      newVolumeID,
+		OriginalVolumeID: originalVolumeID,
+	}
+
+	if wasRunning {
+		if _, err := sm.api.StartInstances(ctx, &ec2.StartInstancesInput{InstanceIds: []string{instanceID}}); err != nil {
+			return result, &RestoreError{
+				Step:          "restarting the instance",
+				Err:           err,
+				RecoverySteps: []string{startStep},
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// abortRestore restarts the instance with its original volume attached and cleans up
+// a partially created volume
+func (sm *SnapshotManager) abortRestore(ctx context.Context, instanceID string, restart bool, step string, cause error, newVolumeID *string) error {
+	var recovery []string
+
+	if newVolumeID != nil {
+		if _, err := sm.api.DeleteVolume(ctx, &ec2.DeleteVolumeInput{VolumeId: newVolumeID}); err != nil {
+			recovery = append(recovery, fmt.Sprintf("aws ec2 delete-volume --volume-id %s", *newVolumeID))
+		}
+	}
+
+	if restart {
+		if _, err := sm.api.StartInstances(ctx, &ec2.StartInstancesInput{InstanceIds: []string{instanceID}}); err != nil {
+			recovery = append(recovery, fmt.Sprintf("aws ec2 start-instances --instance-ids %s", instanceID))
+		}
+	}
+
+	if len(recovery) == 0 {
+		return fmt.Errorf("restore failed while %s (original volume left attached): %w", step, cause)
+	}
+
+	return &RestoreError{Step: step, Err: cause, RecoverySteps: recovery}
+}
+
+func (sm *SnapshotManager) attachAndWait(ctx context.Context, volumeID, instanceID, deviceName string) error {
+	_, err := sm.api.AttachVolume(ctx, &ec2.AttachVolumeInput{
+		VolumeId:   aws.String(volumeID),
+		InstanceId: aws.String(instanceID),
+		Device:     aws.String(deviceName),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to attach volume %s: %w", volumeID, err)
+	}
+	return sm.waitForVolumeState(ctx, volumeID, ec2types.VolumeStateInUse)
+}
+
+// restoreTarget finds the device and current volume that a snapshot should replace
+func restoreTarget(instance *ec2types.Instance, snapshot *SnapshotInfo) (string, string, error) {
+	for _, mapping := range instance.BlockDeviceMappings {
+		if mapping.Ebs == nil {
+			continue
+		}
+		device := aws.ToString(mapping.DeviceName)
+		if (snapshot.DeviceName != "" && device == snapshot.DeviceName) ||
+			(snapshot.DeviceName == "" && aws.ToString(mapping.Ebs.VolumeId) == snapshot.VolumeID) {
+			return device, aws.ToString(mapping.Ebs.VolumeId), nil
+		}
+	}
+	return "", "", fmt.Errorf("snapshot %s does not match any volume attached to instance %s", snapshot.SnapshotID, aws.ToString(instance.InstanceId))
+}
+
+func (sm *SnapshotManager) describeInstance(ctx context.Context, instanceID string) (*ec2types.Instance, error) {
+	result, err := sm.api.DescribeInstances(ctx, &ec2.DescribeInstancesInput{InstanceIds: []string{instanceID}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe instance %s: %w", instanceID, err)
+	}
+	for _, reservation := range result.Reservations {
+		for _, instance := range reservation.Instances {
+			return &instance, nil
+		}
+	}
+	return nil, fmt.Errorf("instance not found: %s", instanceID)
+}
+
+func (sm *SnapshotManager) describeSnapshot(ctx context.Context, snapshotID string) (*SnapshotInfo, error) {
+	result, err := sm.api.DescribeSnapshots(ctx, &ec2.DescribeSnapshotsInput{SnapshotIds: []string{snapshotID}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe snapshot %s: %w", snapshotID, err)
+	}
+	if len(result.Snapshots) == 0 {
+		return nil, fmt.Errorf("snapshot not found: %s", snapshotID)
+	}
+
+	snapshot := newSnapshotInfo(result.Snapshots[0])
+	if snapshot.State != string(ec2types.SnapshotStateCompleted) {
+		return nil, fmt.Errorf("snapshot %s is %s, not completed", snapshotID, snapshot.State)
+	}
+	return &snapshot, nil
+}
+
+func (sm *SnapshotManager) waitForInstanceState(ctx context.Context, instanceID string, want ec2types.InstanceStateName) error {
+	return sm.poll(ctx, fmt.Sprintf("instance %s to be %s", instanceID, want), func() (bool, error) {
+		instance, err := sm.describeInstance(ctx, instanceID)
+		if err != nil {
+			return false, err
+		}
+		return instance.State != nil && instance.State.Name == want, nil
+	})
+}
+
+func (sm *SnapshotManager) waitForVolumeState(ctx context.Context, volumeID string, want ec2types.VolumeState) error {
+	return sm.poll(ctx, fmt.Sprintf("volume %s to be %s", volumeID, want), func() (bool, error) {
+		result, err := sm.api.DescribeVolumes(ctx, &ec2.DescribeVolumesInput{VolumeIds: []string{volumeID}})
+		if err != nil {
+			return false, fmt.Errorf("failed to describe volume %s: %w", volumeID, err)
+		}
+		if len(result.Volumes) == 0 {
+			return false, fmt.Errorf("volume not found: %s", volumeID)
+		}
+		if result.Volumes[0].State == ec2types.VolumeStateError {
+			return false, fmt.Errorf("volume %s entered error state", volumeID)
+		}
+		return result.Volumes[0].State == want, nil
+	})
+}
+
+func (sm *SnapshotManager) poll(ctx context.Context, what string, check func() (bool, error)) error {
+	ctx, cancel := context.WithTimeout(ctx, sm.timeout)
+	defer cancel()
+
+	for {
+		done, err := check()
+		if err != nil {
+			return err
+		}
+		if done {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("timeout waiting for %s", what)
+		case <-time.After(jitteredInterval(sm.pollInterval)):
+		}
+	}
+}
+
+func newSnapshotInfo(snapshot ec2types.Snapshot) SnapshotInfo {
+	tags := make(map[string]string)
+	for _, tag := range snapshot.Tags {
+		if tag.Key != nil && tag.Value != nil {
+			tags[*tag.Key] = *tag.Value
+		}
+	}
+
+	size := aws.ToInt32(snapshot.VolumeSize)
+	return SnapshotInfo{
+		SnapshotID:  aws.ToString(snapshot.SnapshotId),
+		VolumeID:    aws.ToString(snapshot.VolumeId),
+		DeviceName:  tags["DeviceName"],
+		SizeGB:      size,
+		State:       string(snapshot.State),
+		StartTime:   aws.ToTime(snapshot.StartTime),
+		Tags:        tags,
+		MonthlyCost: float64(size) * SnapshotStorageCostPerGBMonth,
+	}
+}
+
+func toEC2Tags(tags map[string]string) []ec2types.Tag {
+	ec2Tags := make([]ec2types.Tag, 0, len(tags))
+	for key, value := range tags {
+		ec2Tags = append(ec2Tags, ec2types.Tag{Key: aws.String(key), Value: aws.String(value)})
+	}
+	return ec2Tags
+}
+
+func mergeTags(base, overrides map[string]string) map[string]string {
+	merged := make(map[string]string, len(base)+len(overrides))
+	for key, value := range base {
+		if strings.HasPrefix(key, "aws:") {
+			continue // reserved tag keys cannot be copied
+		}
+		merged[key] = value
+	}
+	for key, value := range overrides {
+		merged[key] = value
+	}
+	return merged
+}
@@ -0,0 +1,395 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+// fakeSnapshotEC2 simulates a single instance with attachable volumes. Operations
+// named in failOn return an error instead of taking effect.
+type fakeSnapshotEC2 struct {
+	instanceState ec2types.InstanceStateName
+	attached      map[string]string // device -> volume ID
+	volumes       map[string]ec2types.Volume
+	snapshots     map[string]ec2types.Snapshot
+	failOn        map[string]bool
+	nextVolume    int
+	calls         []string
+	created       []*ec2.CreateVolumeInput
+}
+
+func newFakeSnapshotEC2() *fakeSnapshotEC2 {
+	return &fakeSnapshotEC2{
+		instanceState: ec2types.InstanceStateNameRunning,
+		attached:      map[string]string{"/dev/xvda": "vol-root"},
+		volumes: map[string]ec2types.Volume{
+			"vol-root": {VolumeId: aws.String("vol-root"), Size: aws.Int32(100), VolumeType: ec2types.VolumeTypeGp3, State: ec2types.VolumeStateInUse},
+		},
+		snapshots: map[string]ec2types.Snapshot{
+			"snap-1": {
+				SnapshotId: aws.String("snap-1"),
+				VolumeId:   aws.String("vol-root"),
+				VolumeSize: aws.Int32(100),
+				State:      ec2types.SnapshotStateCompleted,
+				Tags:       []ec2types.Tag{{Key: aws.String("DeviceName"), Value: aws.String("/dev/xvda")}, {Key: aws.String("StackName"), Value: aws.String("stack")}},
+			},
+		},
+		failOn: map[string]bool{},
+	}
+}
+
+func (f *fakeSnapshotEC2) record(op string) error {
+	f.calls = append(f.calls, op)
+	if f.failOn[op] {
+		return fmt.Errorf("injected %s failure", op)
+	}
+	return nil
+}
+
+func (f *fakeSnapshotEC2) setVolumeState(id string, state ec2types.VolumeState) {
+	volume := f.volumes[id]
+	volume.State = state
+	f.volumes[id] = volume
+}
+
+func (f *fakeSnapshotEC2) DescribeInstances(ctx context.Context, params *ec2.DescribeInstancesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInstancesOutput, error) {
+	mappings := make([]ec2types.InstanceBlockDeviceMapping, 0, len(f.attached))
+	for device, volumeID := range f.attached {
+		mappings = append(mappings, ec2types.InstanceBlockDeviceMapping{
+			DeviceName: aws.String(device),
+			Ebs:        &ec2types.EbsInstanceBlockDevice{VolumeId: aws.String(volumeID)},
+		})
+	}
+	return &ec2.DescribeInstancesOutput{
+		Reservations: []ec2types.Reservation{{
+			Instances: []ec2types.Instance{{
+				InstanceId:          aws.String("i-1"),
+				State:               &ec2types.InstanceState{Name: f.instanceState},
+				Placement:           &ec2types.Placement{AvailabilityZone: aws.String("us-east-1a")},
+				BlockDeviceMappings: mappings,
+			}},
+		}},
+	}, nil
+}
+
+func (f *fakeSnapshotEC2) DescribeVolumes(ctx context.Context, params *ec2.DescribeVolumesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeVolumesOutput, error) {
+	output := &ec2.DescribeVolumesOutput{}
+	for _, id := range params.VolumeIds {
+		if volume, exists := f.volumes[id]; exists {
+			output.Volumes = append(output.Volumes, volume)
+		}
+	}
+	return output, nil
+}
+
+func (f *fakeSnapshotEC2) DescribeSnapshots(ctx context.Context, params *ec2.DescribeSnapshotsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeSnapshotsOutput, error) {
+	output := &ec2.DescribeSnapshotsOutput{}
+	if len(params.SnapshotIds) == 0 {
+		for _, snapshot := range f.snapshots {
+			output.Snapshots = append(output.Snapshots, snapshot)
+		}
+		return output, nil
+	}
+	for _, id := range params.SnapshotIds {
+		if snapshot, exists := f.snapshots[id]; exists {
+			output.Snapshots = append(output.Snapshots, snapshot)
+		}
+	}
+	return output, nil
+}
+
+func (f *fakeSnapshotEC2) CreateSnapshot(ctx context.Context, params *ec2.CreateSnapshotInput, optFns ...func(*ec2.Options)) (*ec2.CreateSnapshotOutput, error) {
+	if err := f.record("CreateSnapshot"); err != nil {
+		return nil, err
+	}
+	id := "snap-" + aws.ToString(params.VolumeId)
+	return &ec2.CreateSnapshotOutput{SnapshotId: aws.String(id), State: ec2types.SnapshotStatePending}, nil
+}
+
+func (f *fakeSnapshotEC2) CreateVolume(ctx context.Context, params *ec2.CreateVolumeInput, optFns ...func(*ec2.Options)) (*ec2.CreateVolumeOutput, error) {
+	if err := f.record("CreateVolume"); err != nil {
+		return nil, err
+	}
+	// Like EC2, reject performance settings the volume type does not take
+	switch params.VolumeType {
+	case ec2types.VolumeTypeIo1, ec2types.VolumeTypeIo2, ec2types.VolumeTypeGp3:
+	default:
+		if params.Iops != nil {
+			return nil, fmt.Errorf("InvalidParameterCombination: the parameter iops is not supported for %s volumes", params.VolumeType)
+		}
+	}
+	if params.Throughput != nil && params.VolumeType != ec2types.VolumeTypeGp3 {
+		return nil, fmt.Errorf("InvalidParameterCombination: the parameter throughput is only supported for gp3 volumes")
+	}
+	f.created = append(f.created, params)
+	f.nextVolume++
+	id := fmt.Sprintf("vol-new%d", f.nextVolume)
+	f.volumes[id] = ec2types.Volume{VolumeId: aws.String(id), State: ec2types.VolumeStateAvailable, AvailabilityZone: params.AvailabilityZone}
+	return &ec2.CreateVolumeOutput{VolumeId: aws.String(id)}, nil
+}
+
+func (f *fakeSnapshotEC2) DeleteVolume(ctx context.Context, params *ec2.DeleteVolumeInput, optFns ...func(*ec2.Options)) (*ec2.DeleteVolumeOutput, error) {
+	if err := f.record("DeleteVolume"); err != nil {
+		return nil, err
+	}
+	delete(f.volumes, aws.ToString(params.VolumeId))
+	return &ec2.DeleteVolumeOutput{}, nil
+}
+
+func (f *fakeSnapshotEC2) AttachVolume(ctx context.Context, params *ec2.AttachVolumeInput, optFns ...func(*ec2.Options)) (*ec2.AttachVolumeOutput, error) {
+	op := "AttachVolume:" + aws.ToString(params.VolumeId)
+	if err := f.record(op); err != nil {
+		return nil, err
+	}
+	f.attached[aws.ToString(params.Device)] = aws.ToString(params.VolumeId)
+	f.setVolumeState(aws.ToString(params.VolumeId), ec2types.VolumeStateInUse)
+	return &ec2.AttachVolumeOutput{}, nil
+}
+
+func (f *fakeSnapshotEC2) DetachVolume(ctx context.Context, params *ec2.DetachVolumeInput, optFns ...func(*ec2.Options)) (*ec2.DetachVolumeOutput, error) {
+	if err := f.record("DetachVolume"); err != nil {
+		return nil, err
+	}
+	for device, volumeID := range f.attached {
+		if volumeID == aws.ToString(params.VolumeId) {
+			delete(f.attached, device)
+		}
+	}
+	f.setVolumeState(aws.ToString(params.VolumeId), ec2types.VolumeStateAvailable)
+	return &ec2.DetachVolumeOutput{}, nil
+}
+
+func (f *fakeSnapshotEC2) StopInstances(ctx context.Context, params *ec2.StopInstancesInput, optFns ...func(*ec2.Options)) (*ec2.StopInstancesOutput, error) {
+	if err := f.record("StopInstances"); err != nil {
+		return nil, err
+	}
+	f.instanceState = ec2types.InstanceStateNameStopped
+	return &ec2.StopInstancesOutput{}, nil
+}
+
+func (f *fakeSnapshotEC2) StartInstances(ctx context.Context, params *ec2.StartInstancesInput, optFns ...func(*ec2.Options)) (*ec2.StartInstancesOutput, error) {
+	if err := f.record("StartInstances"); err != nil {
+		return nil, err
+	}
+	f.instanceState = ec2types.InstanceStateNameRunning
+	return &ec2.StartInstancesOutput{}, nil
+}
+
+func newTestSnapshotManager(api snapshotAPI) *SnapshotManager {
+	return &SnapshotManager{api: api, pollInterval: time.Millisecond, timeout: time.Second}
+}
+
+func TestSnapshotInstance(t *testing.T) {
+	api := newFakeSnapshotEC2()
+	sm := newTestSnapshotManager(api)
+
+	snapshots, err := sm.SnapshotInstance(context.Background(), "i-1", map[string]string{"StackName": "stack", "Domain": "genomics"})
+	if err != nil {
+		t.Fatalf("SnapshotInstance failed: %v", err)
+	}
+
+	if len(snapshots) != 1 {
+		t.Fatalf("Expected 1 snapshot, got %d", len(snapshots))
+	}
+	snapshot := snapshots[0]
+	if snapshot.DeviceName != "/dev/xvda" || snapshot.SizeGB != 100 {
+		t.Errorf("Unexpected snapshot: %+v", snapshot)
+	}
+	if snapshot.Tags["StackName"] != "stack" || snapshot.Tags["CreatedBy"] != "AWS-Research-Wizard" {
+		t.Errorf("Unexpected tags: %v", snapshot.Tags)
+	}
+	if snapshot.MonthlyCost != 5.0 {
+		t.Errorf("Expected $5.00/month for 100 GB, got %.2f", snapshot.MonthlyCost)
+	}
+}
+
+func TestRestoreSnapshotSwapsVolume(t *testing.T) {
+	api := newFakeSnapshotEC2()
+	sm := newTestSnapshotManager(api)
+
+	result, err := sm.RestoreSnapshot(context.Background(), "i-1", "snap-1")
+	if err != nil {
+		t.Fatalf("RestoreSnapshot failed: %v", err)
+	}
+
+	if result.OriginalVolumeID != "vol-root" || result.DeviceName != "/dev/xvda" {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+	if api.attached["/dev/xvda"] != result.NewVolumeID {
+		t.Errorf("Expected %s attached, got %s", result.NewVolumeID, api.attached["/dev/xvda"])
+	}
+	if aws.ToString(api.volumes[result.NewVolumeID].AvailabilityZone) != "us-east-1a" {
+		t.Error("Restored volume created in the wrong availability zone")
+	}
+	if _, kept := api.volumes["vol-root"]; !kept {
+		t.Error("Original volume should be kept")
+	}
+	if api.instanceState != ec2types.InstanceStateNameRunning {
+		t.Error("Expected instance to be restarted")
+	}
+}
+
+func TestRestoreSnapshotVolumePerformance(t *testing.T) {
+	tests := []struct {
+		name           string
+		original       ec2types.Volume
+		wantIops       *int32
+		wantThroughput *int32
+	}{
+		{
+			name:     "gp2 reports iops it was not created with",
+			original: ec2types.Volume{VolumeType: ec2types.VolumeTypeGp2, Iops: aws.Int32(300)},
+		},
+		{
+			name:           "gp3 keeps iops and throughput",
+			original:       ec2types.Volume{VolumeType: ec2types.VolumeTypeGp3, Iops: aws.Int32(6000), Throughput: aws.Int32(500)},
+			wantIops:       aws.Int32(6000),
+			wantThroughput: aws.Int32(500),
+		},
+		{
+			name:     "io2 keeps iops",
+			original: ec2types.Volume{VolumeType: ec2types.VolumeTypeIo2, Iops: aws.Int32(16000)},
+			wantIops: aws.Int32(16000),
+		},
+		{
+			name:     "st1 takes neither",
+			original: ec2types.Volume{VolumeType: ec2types.VolumeTypeSt1, Iops: aws.Int32(500), Throughput: aws.Int32(500)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := newFakeSnapshotEC2()
+			original := tt.original
+			original.VolumeId = aws.String("vol-root")
+			original.Size = aws.Int32(100)
+			original.State = ec2types.VolumeStateInUse
+			api.volumes["vol-root"] = original
+			sm := newTestSnapshotManager(api)
+
+			if _, err := sm.RestoreSnapshot(context.Background(), "i-1", "snap-1"); err != nil {
+				t.Fatalf("RestoreSnapshot failed: %v", err)
+			}
+
+			if len(api.created) != 1 {
+				t.Fatalf("Expected 1 volume created, got %d", len(api.created))
+			}
+			created := api.created[0]
+			if created.VolumeType != original.VolumeType {
+				t.Errorf("Volume type = %s, want %s", created.VolumeType, original.VolumeType)
+			}
+			if aws.ToInt32(created.Iops) != aws.ToInt32(tt.wantIops) || (created.Iops == nil) != (tt.wantIops == nil) {
+				t.Errorf("Iops = %v, want %v", aws.ToInt32(created.Iops), aws.ToInt32(tt.wantIops))
+			}
+			if aws.ToInt32(created.Throughput) != aws.ToInt32(tt.wantThroughput) || (created.Throughput == nil) != (tt.wantThroughput == nil) {
+				t.Errorf("Throughput = %v, want %v", aws.ToInt32(created.Throughput), aws.ToInt32(tt.wantThroughput))
+			}
+		})
+	}
+}
+
+func TestRestoreSnapshotFailures(t *testing.T) {
+	tests := []struct {
+		name          string
+		failOn        []string
+		wantAttached  string
+		wantRecovery  bool
+		wantNewVolume bool
+	}{
+		{
+			name:         "create volume fails",
+			failOn:       []string{"CreateVolume"},
+			wantAttached: "vol-root",
+		},
+		{
+			name:         "detach fails",
+			failOn:       []string{"DetachVolume"},
+			wantAttached: "vol-root",
+		},
+		{
+			name:         "attach fails and original is reattached",
+			failOn:       []string{"AttachVolume:vol-new1"},
+			wantAttached: "vol-root",
+		},
+		{
+			name:          "attach and rollback both fail",
+			failOn:        []string{"AttachVolume:vol-new1", "AttachVolume:vol-root"},
+			wantAttached:  "",
+			wantRecovery:  true,
+			wantNewVolume: true,
+		},
+		{
+			name:          "cleanup fails",
+			failOn:        []string{"DetachVolume", "DeleteVolume"},
+			wantAttached:  "vol-root",
+			wantRecovery:  true,
+			wantNewVolume: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := newFakeSnapshotEC2()
+			for _, op := range tt.failOn {
+				api.failOn[op] = true
+			}
+			sm := newTestSnapshotManager(api)
+
+			_, err := sm.RestoreSnapshot(context.Background(), "i-1", "snap-1")
+			if err == nil {
+				t.Fatal("Expected restore to fail")
+			}
+
+			if api.attached["/dev/xvda"] != tt.wantAttached {
+				t.Errorf("Expected %q attached, got %q", tt.wantAttached, api.attached["/dev/xvda"])
+			}
+
+			var restoreErr *RestoreError
+			isRestoreErr := errors.As(err, &restoreErr)
+			if isRestoreErr != tt.wantRecovery {
+				t.Errorf("Expected recovery steps = %v, got error: %v", tt.wantRecovery, err)
+			}
+			if tt.wantRecovery && !strings.Contains(err.Error(), "aws ec2") {
+				t.Errorf("Recovery steps missing commands: %v", err)
+			}
+			if !tt.wantRecovery && !strings.Contains(err.Error(), "original volume left attached") {
+				t.Errorf("Expected error to confirm original volume state: %v", err)
+			}
+
+			if _, exists := api.volumes["vol-new1"]; exists != tt.wantNewVolume {
+				t.Errorf("Expected restored volume to exist = %v", tt.wantNewVolume)
+			}
+
+			if !tt.wantRecovery && api.instanceState != ec2types.InstanceStateNameRunning {
+				t.Error("Expected instance to be restarted after rollback")
+			}
+		})
+	}
+}
+
+func TestRestoreSnapshotRejectsForeignSnapshot(t *testing.T) {
+	api := newFakeSnapshotEC2()
+	api.snapshots["snap-other"] = ec2types.Snapshot{
+		SnapshotId: aws.String("snap-other"),
+		VolumeId:   aws.String("vol-elsewhere"),
+		State:      ec2types.SnapshotStateCompleted,
+	}
+	sm := newTestSnapshotManager(api)
+
+	if _, err := sm.RestoreSnapshot(context.Background(), "i-1", "snap-other"); err == nil {
+		t.Fatal("Expected error for snapshot of another instance")
+	}
+	for _, call := range api.calls {
+		if call == "StopInstances" {
+			t.Error("Instance should not be stopped when the snapshot does not match")
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package aws
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	sshBlockBegin = "# BEGIN research-wizard "
+	sshBlockEnd   = "# END research-wizard "
+
+	// DefaultSSHUser is the login user of the research instance AMI
+	DefaultSSHUser = "ec2-user"
+
+	// DefaultJupyterPort is forwarded to the research instance's Jupyter server
+	DefaultJupyterPort = 8888
+)
+
+// SSHHost describes an OpenSSH Host block for a research stack
+type SSHHost struct {
+	Alias        string
+	HostName     string
+	User         string
+	IdentityFile string
+	LocalForward int
+}
+
+// DefaultSSHConfigPath returns the file that research wizard Host blocks are written to.
+// It is meant to be pulled into ~/.ssh/config with "Include config.d/*".
+func DefaultSSHConfigPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to determine home directory: %w", err)
+	}
+	return filepath.Join(home, ".ssh", "config.d", "research-wizard"), nil
+}
+
+// SSHHostFromStack builds the Host block for a deployed stack
+func SSHHostFromStack(stackInfo *StackInfo, user, identityFile string) (*SSHHost, error) {
+	hostName, err := stackInfo.HostName()
+	if err != nil {
+		return nil, err
+	}
+
+	if user == "" {
+		user = DefaultSSHUser
+	}
+
+	if identityFile == "" {
+		keyName := stackInfo.Parameters["KeyName"]
+		if keyName == "" {
+			return nil, fmt.Errorf("stack %s has no KeyName parameter; specify an identity file", stackInfo.StackName)
+		}
+		identityFile = fmt.Sprintf("~/.ssh/%s.pem", keyName)
+	}
+
+	return &SSHHost{
+		Alias:        stackInfo.StackName,
+		HostName:     hostName,
+		User:         user,
+		IdentityFile: identityFile,
+		LocalForward: DefaultJupyterPort,
+	}, nil
+}
+
+// Block renders the Host block, including the markers used to find it again
+func (h *SSHHost) Block() string {
+	var b strings.Builder
+	b.WriteString(sshBlockBegin + h.Alias + "\n")
+	fmt.Fprintf(&b, "Host %s\n", h.Alias)
+	fmt.Fprintf(&b, "    HostName %s\n", h.HostName)
+	fmt.Fprintf(&b, "    User %s\n", h.User)
+	fmt.Fprintf(&b, "    IdentityFile %s\n", h.IdentityFile)
+	if h.LocalForward > 0 {
+		fmt.Fprintf(&b, "    LocalForward %d localhost:%d\n", h.LocalForward, h.LocalForward)
+	}
+	b.WriteString(sshBlockEnd + h.Alias + "\n")
+	return b.String()
+}
+
+// UpsertSSHHost writes the Host block to the config file, replacing any previous block
+// for the same alias and leaving all other content untouched
+func UpsertSSHHost(path string, host *SSHHost) error {
+	content, err := readSSHConfig(path)
+	if err != nil {
+		return err
+	}
+
+	updated, _ := removeSSHBlock(content, host.Alias)
+	if updated != "" && !strings.HasSuffix(updated, "\n") {
+		updated += "\n"
+	}
+	updated += host.Block()
+
+	return writeSSHConfig(path, updated)
+}
+
+// RemoveSSHHost deletes the Host block for an alias, reporting whether one was found
+func RemoveSSHHost(path, alias string) (bool, error) {
+	content, err := readSSHConfig(path)
+	if err != nil {
+		return false, err
+	}
+
+	updated, removed := removeSSHBlock(content, alias)
+	if !removed {
+		return false, nil
+	}
+
+	return true, writeSSHConfig(path, updated)
+}
+
+// removeSSHBlock strips the marked block for an alias from config content
+func removeSSHBlock(content, alias string) (string, bool) {
+	begin := sshBlockBegin + alias
+	end := sshBlockEnd + alias
+
+	lines := strings.SplitAfter(content, "\n")
+	kept := make([]string, 0, len(lines))
+	inBlock := false
+	removed := false
+
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case !inBlock && trimmed == begin:
+			inBlock = true
+			removed = true
+		case inBlock && trimmed == end:
+			inBlock = false
+		case !inBlock:
+			kept = append(kept, line)
+		}
+	}
+
+	return strings.Join(kept, ""), removed
+}
+
+func readSSHConfig(path string) (string, error) {
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read SSH config: %w", err)
+	}
+	return string(content), nil
+}
+
+func writeSSHConfig(path, content string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create SSH config directory: %w", err)
+	}
+
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, []byte(content), 0600); err != nil {
+		return fmt.Errorf("failed to write SSH config: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to replace SSH config: %w", err)
+	}
+
+	return nil
+}
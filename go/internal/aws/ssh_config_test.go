@@ -0,0 +1,219 @@
+package aws
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const userSSHConfig = `Host bastion
+    HostName bastion.example.org
+    User admin
+
+Host *
+    ServerAliveInterval 60
+`
+
+func testSSHHost(alias, hostName string) *SSHHost {
+	return &SSHHost{
+		Alias:        alias,
+		HostName:     hostName,
+		User:         DefaultSSHUser,
+		IdentityFile: "~/.ssh/lab.pem",
+		LocalForward: DefaultJupyterPort,
+	}
+}
+
+func readTestConfig(t *testing.T, path string) string {
+	t.Helper()
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read SSH config: %v", err)
+	}
+	return string(content)
+}
+
+func TestSSHHostFromStack(t *testing.T) {
+	stackInfo := &StackInfo{
+		StackName:  "research-wizard-genomics",
+		Outputs:    map[string]string{OutputPublicIP: "203.0.113.10"},
+		Parameters: map[string]string{"KeyName": "lab"},
+	}
+
+	host, err := SSHHostFromStack(stackInfo, "", "")
+	if err != nil {
+		t.Fatalf("SSHHostFromStack failed: %v", err)
+	}
+
+	if host.Alias != "research-wizard-genomics" || host.HostName != "203.0.113.10" {
+		t.Errorf("Unexpected host: %+v", host)
+	}
+	if host.User != DefaultSSHUser {
+		t.Errorf("Expected default user, got %s", host.User)
+	}
+	if host.IdentityFile != "~/.ssh/lab.pem" {
+		t.Errorf("Expected identity file derived from key name, got %s", host.IdentityFile)
+	}
+
+	block := host.Block()
+	for _, want := range []string{"Host research-wizard-genomics", "HostName 203.0.113.10", "LocalForward 8888 localhost:8888"} {
+		if !strings.Contains(block, want) {
+			t.Errorf("Block missing %q:\n%s", want, block)
+		}
+	}
+
+	// A stable address is preferred over the instance's public IP address
+	stackInfo.Outputs[OutputElasticIP] = "198.51.100.7"
+	if host, err := SSHHostFromStack(stackInfo, "", ""); err != nil || host.HostName != "198.51.100.7" {
+		t.Errorf("HostName = %+v, %v; want the Elastic IP address", host, err)
+	}
+	stackInfo.Outputs[OutputDNSName] = "jupyter.lab.example.com"
+	if host, err := SSHHostFromStack(stackInfo, "", ""); err != nil || host.HostName != "jupyter.lab.example.com" {
+		t.Errorf("HostName = %+v, %v; want the DNS name", host, err)
+	}
+
+	stackInfo.Parameters = nil
+	if _, err := SSHHostFromStack(stackInfo, "", ""); err == nil {
+		t.Error("Expected error without key name or identity file")
+	}
+
+	stackInfo.Outputs = nil
+	if _, err := SSHHostFromStack(stackInfo, "", "~/.ssh/id_ed25519"); err == nil {
+		t.Error("Expected error without public IP output")
+	}
+}
+
+func TestUpsertSSHHostCreatesFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.d", "research-wizard")
+
+	if err := UpsertSSHHost(path, testSSHHost("stack-a", "203.0.113.10")); err != nil {
+		t.Fatalf("UpsertSSHHost failed: %v", err)
+	}
+
+	content := readTestConfig(t, path)
+	if content != testSSHHost("stack-a", "203.0.113.10").Block() {
+		t.Errorf("Unexpected content:\n%s", content)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Stat failed: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Expected mode 0600, got %o", info.Mode().Perm())
+	}
+}
+
+func TestUpsertSSHHostIsIdempotent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "research-wizard")
+	if err := os.WriteFile(path, []byte(userSSHConfig), 0600); err != nil {
+		t.Fatalf("Failed to seed SSH config: %v", err)
+	}
+
+	host := testSSHHost("stack-a", "203.0.113.10")
+	for i := 0; i < 3; i++ {
+		if err := UpsertSSHHost(path, host); err != nil {
+			t.Fatalf("UpsertSSHHost run %d failed: %v", i, err)
+		}
+	}
+
+	content := readTestConfig(t, path)
+	if !strings.HasPrefix(content, userSSHConfig) {
+		t.Errorf("User config was modified:\n%s", content)
+	}
+	if count := strings.Count(content, "Host stack-a\n"); count != 1 {
+		t.Errorf("Expected one Host block, found %d:\n%s", count, content)
+	}
+}
+
+func TestUpsertSSHHostUpdatesInPlace(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "research-wizard")
+	if err := os.WriteFile(path, []byte(userSSHConfig), 0600); err != nil {
+		t.Fatalf("Failed to seed SSH config: %v", err)
+	}
+
+	if err := UpsertSSHHost(path, testSSHHost("stack-a", "203.0.113.10")); err != nil {
+		t.Fatal(err)
+	}
+	if err := UpsertSSHHost(path, testSSHHost("stack-b", "203.0.113.20")); err != nil {
+		t.Fatal(err)
+	}
+	// stack-a gets a new IP after a stop/start
+	if err := UpsertSSHHost(path, testSSHHost("stack-a", "203.0.113.30")); err != nil {
+		t.Fatal(err)
+	}
+
+	content := readTestConfig(t, path)
+	if strings.Contains(content, "203.0.113.10") {
+		t.Errorf("Stale HostName left behind:\n%s", content)
+	}
+	for _, want := range []string{"HostName 203.0.113.20", "HostName 203.0.113.30", "HostName bastion.example.org"} {
+		if !strings.Contains(content, want) {
+			t.Errorf("Missing %q:\n%s", want, content)
+		}
+	}
+}
+
+func TestUpsertSSHHostWithoutTrailingNewline(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "research-wizard")
+	existing := strings.TrimSuffix(userSSHConfig, "\n")
+	if err := os.WriteFile(path, []byte(existing), 0600); err != nil {
+		t.Fatalf("Failed to seed SSH config: %v", err)
+	}
+
+	if err := UpsertSSHHost(path, testSSHHost("stack-a", "203.0.113.10")); err != nil {
+		t.Fatal(err)
+	}
+
+	content := readTestConfig(t, path)
+	if !strings.Contains(content, "ServerAliveInterval 60\n"+sshBlockBegin+"stack-a\n") {
+		t.Errorf("Block not separated from existing content:\n%s", content)
+	}
+}
+
+func TestRemoveSSHHost(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "research-wizard")
+	if err := os.WriteFile(path, []byte(userSSHConfig), 0600); err != nil {
+		t.Fatalf("Failed to seed SSH config: %v", err)
+	}
+
+	if err := UpsertSSHHost(path, testSSHHost("stack-a", "203.0.113.10")); err != nil {
+		t.Fatal(err)
+	}
+	if err := UpsertSSHHost(path, testSSHHost("stack-ab", "203.0.113.20")); err != nil {
+		t.Fatal(err)
+	}
+
+	removed, err := RemoveSSHHost(path, "stack-a")
+	if err != nil || !removed {
+		t.Fatalf("RemoveSSHHost = %v, %v", removed, err)
+	}
+
+	content := readTestConfig(t, path)
+	if strings.Contains(content, "Host stack-a\n") {
+		t.Errorf("Block not removed:\n%s", content)
+	}
+	if !strings.Contains(content, "Host stack-ab\n") {
+		t.Errorf("Block with shared prefix was removed:\n%s", content)
+	}
+
+	removed, err = RemoveSSHHost(path, "stack-a")
+	if err != nil || removed {
+		t.Errorf("Second RemoveSSHHost = %v, %v", removed, err)
+	}
+
+	if _, err := RemoveSSHHost(path, "stack-ab"); err != nil {
+		t.Fatal(err)
+	}
+	if content := readTestConfig(t, path); content != userSSHConfig {
+		t.Errorf("User config not restored:\n%s", content)
+	}
+}
+
+func TestRemoveSSHHostMissingFile(t *testing.T) {
+	removed, err := RemoveSSHHost(filepath.Join(t.TempDir(), "missing"), "stack-a")
+	if err != nil || removed {
+		t.Errorf("RemoveSSHHost on missing file = %v, %v", removed, err)
+	}
+}
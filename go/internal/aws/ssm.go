@@ -0,0 +1,154 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	ssmtypes "github.com/aws/aws-sdk-go-v2/service/ssm/types"
+)
+
+// shellScriptDocument is the SSM document that runs shell commands on Linux instances
+const shellScriptDocument = "AWS-RunShellScript"
+
+// ssmAPI is the subset of the Systems Manager API used to run commands on instances
+type ssmAPI interface {
+	SendCommand(ctx context.Context, params *ssm.SendCommandInput, optFns ...func(*ssm.Options)) (*ssm.SendCommandOutput, error)
+	GetCommandInvocation(ctx context.Context, params *ssm.GetCommandInvocationInput, optFns ...func(*ssm.Options)) (*ssm.GetCommandInvocationOutput, error)
+}
+
+// CommandRunner runs shell commands on research instances through SSM Run Command
+type CommandRunner struct {
+	api          ssmAPI
+	pollInterval time.Duration
+}
+
+// NewCommandRunner creates a new SSM command runner
+func NewCommandRunner(client *Client) *CommandRunner {
+	return &CommandRunner{
+		api:          client.SSM,
+		pollInterval: 5 * time.Second,
+	}
+}
+
+// CommandResult describes the state of a command invocation on one instance
+type CommandResult struct {
+	CommandID  string
+	InstanceID string
+	Status     string
+	ExitCode   int32
+	Stdout     string
+	Stderr     string
+}
+
+// Done reports whether the invocation has finished, successfully or not
+func (r *CommandResult) Done() bool {
+	switch ssmtypes.CommandInvocationStatus(r.Status) {
+	case ssmtypes.CommandInvocationStatusPending,
+		ssmtypes.CommandInvocationStatusInProgress,
+		ssmtypes.CommandInvocationStatusDelayed,
+		ssmtypes.CommandInvocationStatusCancelling:
+		return false
+	}
+	return true
+}
+
+// Succeeded reports whether the invocation finished with a zero exit code
+func (r *CommandResult) Succeeded() bool {
+	return ssmtypes.CommandInvocationStatus(r.Status) == ssmtypes.CommandInvocationStatusSuccess
+}
+
+// Start sends shell commands to an instance without waiting for them to finish
+func (cr *CommandRunner) Start(ctx context.Context, instanceID string, commands []string, timeout time.Duration) (string, error) {
+	input := &ssm.SendCommandInput{
+		DocumentName: aws.String(shellScriptDocument),
+		InstanceIds:  []string{instanceID},
+		Parameters: map[string][]string{
+			"commands": commands,
+		},
+		Comment: aws.String("aws-research-wizard"),
+	}
+	if timeout > 0 {
+		input.Parameters["executionTimeout"] = []string{fmt.Sprintf("%d", int(timeout.Seconds()))}
+	}
+
+	result, err := cr.api.SendCommand(ctx, input)
+	if err != nil {
+		return "", fmt.Errorf("failed to send command to %s: %w", instanceID, err)
+	}
+	if result.Command == nil || result.Command.CommandId == nil {
+		return "", fmt.Errorf("SSM returned no command ID for %s", instanceID)
+	}
+
+	return *result.Command.CommandId, nil
+}
+
+// Status returns the current state of a command invocation
+func (cr *CommandRunner) Status(ctx context.Context, instanceID, commandID string) (*CommandResult, error) {
+	result, err := cr.api.GetCommandInvocation(ctx, &ssm.GetCommandInvocationInput{
+		CommandId:  aws.String(commandID),
+		InstanceId: aws.String(instanceID),
+	})
+	if err != nil {
+		// The invocation is not visible for a moment after SendCommand returns
+		var notFound *ssmtypes.InvocationDoesNotExist
+		if errors.As(err, &notFound) {
+			return &CommandResult{
+				CommandID:  commandID,
+				InstanceID: instanceID,
+				Status:     string(ssmtypes.CommandInvocationStatusPending),
+			}, nil
+		}
+		return nil, fmt.Errorf("failed to get command invocation %s: %w", commandID, err)
+	}
+
+	return &CommandResult{
+		CommandID:  commandID,
+		InstanceID: instanceID,
+		Status:     string(result.Status),
+		ExitCode:   result.ResponseCode,
+		Stdout:     aws.ToString(result.StandardOutputContent),
+		Stderr:     aws.ToString(result.StandardErrorContent),
+	}, nil
+}
+
+// Wait polls a command invocation until it finishes
+func (cr *CommandRunner) Wait(ctx context.Context, instanceID, commandID string) (*CommandResult, error) {
+	for {
+		result, err := cr.Status(ctx, instanceID, commandID)
+		if err != nil {
+			return nil, err
+		}
+		if result.Done() {
+			return result, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(cr.pollInterval):
+		}
+	}
+}
+
+// Run sends shell commands to an instance and waits for them to succeed
+func (cr *CommandRunner) Run(ctx context.Context, instanceID string, commands []string) (*CommandResult, error) {
+	commandID, err := cr.Start(ctx, instanceID, commands, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := cr.Wait(ctx, instanceID, commandID)
+	if err != nil {
+		return nil, err
+	}
+	if !result.Succeeded() {
+		return result, fmt.Errorf("command %s on %s finished with status %s (exit code %d): %s",
+			commandID, instanceID, result.Status, result.ExitCode, result.Stderr)
+	}
+
+	return result, nil
+}
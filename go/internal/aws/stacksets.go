@@ -0,0 +1,334 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+)
+
+// DefaultStackSetExecutionRole is the role CloudFormation assumes in each
+// target account for self-managed stack sets
+const DefaultStackSetExecutionRole = "AWSCloudFormationStackSetExecutionRole"
+
+// stackSetAPI is the subset of the CloudFormation API used for stack sets
+type stackSetAPI interface {
+	cloudformation.ListStackInstancesAPIClient
+	CreateStackSet(ctx context.Context, params *cloudformation.CreateStackSetInput, optFns ...func(*cloudformation.Options)) (*cloudformation.CreateStackSetOutput, error)
+	CreateStackInstances(ctx context.Context, params *cloudformation.CreateStackInstancesInput, optFns ...func(*cloudformation.Options)) (*cloudformation.CreateStackInstancesOutput, error)
+	DeleteStackInstances(ctx context.Context, params *cloudformation.DeleteStackInstancesInput, optFns ...func(*cloudformation.Options)) (*cloudformation.DeleteStackInstancesOutput, error)
+	DeleteStackSet(ctx context.Context, params *cloudformation.DeleteStackSetInput, optFns ...func(*cloudformation.Options)) (*cloudformation.DeleteStackSetOutput, error)
+	DescribeStackSetOperation(ctx context.Context, params *cloudformation.DescribeStackSetOperationInput, optFns ...func(*cloudformation.Options)) (*cloudformation.DescribeStackSetOperationOutput, error)
+}
+
+// StackSetManager rolls a research environment out to several accounts and
+// regions with CloudFormation StackSets
+type StackSetManager struct {
+	api          stackSetAPI
+	pollInterval time.Duration
+	timeout      time.Duration
+}
+
+// NewStackSetManager creates a new stack set manager
+func NewStackSetManager(client *Client) *StackSetManager {
+	return &StackSetManager{
+		api:          client.CloudFormation,
+		pollInterval: 15 * time.Second,
+		timeout:      60 * time.Minute,
+	}
+}
+
+// StackSetSpec describes a stack set and where its instances go
+type StackSetSpec struct {
+	Name         string
+	TemplateBody string
+	Parameters   map[string]string
+	Tags         map[string]string
+	Accounts     []string
+	Regions      []string
+	// AdministrationRoleARN defaults to the account's AWSCloudFormationStackSetAdministrationRole
+	AdministrationRoleARN string
+	// ExecutionRoleName defaults to DefaultStackSetExecutionRole
+	ExecutionRoleName string
+}
+
+// StackSetInstance is the state of one account and region in a stack set
+type StackSetInstance struct {
+	Account string
+	Region  string
+	// Status is CURRENT, OUTDATED or INOPERABLE
+	Status string
+	// DetailedStatus is the last operation's outcome here, such as RUNNING or FAILED
+	DetailedStatus string
+	Reason         string
+	StackID        string
+}
+
+// Failed reports whether the last operation failed in this account and region
+func (i StackSetInstance) Failed() bool {
+	switch types.StackInstanceDetailedStatus(i.DetailedStatus) {
+	case types.StackInstanceDetailedStatusFailed,
+		types.StackInstanceDetailedStatusCancelled,
+		types.StackInstanceDetailedStatusInoperable,
+		types.StackInstanceDetailedStatusFailedImport:
+		return true
+	}
+	return i.Status == string(types.StackInstanceStatusInoperable)
+}
+
+// Pending reports whether the last operation is still rolling out here
+func (i StackSetInstance) Pending() bool {
+	switch types.StackInstanceDetailedStatus(i.DetailedStatus) {
+	case types.StackInstanceDetailedStatusPending, types.StackInstanceDetailedStatusRunning:
+		return true
+	}
+	return false
+}
+
+// StackSetStatus is a stack set's latest operation and its instances
+type StackSetStatus struct {
+	Name            string
+	OperationID     string
+	OperationAction string
+	OperationStatus string
+	OperationReason string
+	Instances       []StackSetInstance
+}
+
+// StackSetSummary counts instances by outcome
+type StackSetSummary struct {
+	Total     int
+	Succeeded int
+	Pending   int
+	Failed    int
+}
+
+// Summary counts the instances by outcome. Failures are counted, not
+// returned as errors, so one broken account does not hide the others.
+func (s *StackSetStatus) Summary() StackSetSummary {
+	summary := StackSetSummary{Total: len(s.Instances)}
+	for _, instance := range s.Instances {
+		switch {
+		case instance.Failed():
+			summary.Failed++
+		case instance.Pending():
+			summary.Pending++
+		default:
+			summary.Succeeded++
+		}
+	}
+	return summary
+}
+
+// FailedInstances returns the accounts and regions whose last operation failed
+func (s *StackSetStatus) FailedInstances() []StackSetInstance {
+	var failed []StackSetInstance
+	for _, instance := range s.Instances {
+		if instance.Failed() {
+			failed = append(failed, instance)
+		}
+	}
+	return failed
+}
+
+// Done reports whether the operation has finished
+func (s *StackSetStatus) Done() bool {
+	switch types.StackSetOperationStatus(s.OperationStatus) {
+	case types.StackSetOperationStatusRunning, types.StackSetOperationStatusQueued, types.StackSetOperationStatusStopping:
+		return false
+	}
+	return true
+}
+
+// stackSetOperationPreferences roll out to every region in parallel and keep
+// going when individual accounts fail, so each failure can be reported
+func stackSetOperationPreferences() *types.StackSetOperationPreferences {
+	return &types.StackSetOperationPreferences{
+		RegionConcurrencyType:      types.RegionConcurrencyTypeParallel,
+		FailureTolerancePercentage: aws.Int32(100),
+		MaxConcurrentPercentage:    aws.Int32(100),
+	}
+}
+
+// CreateStackSet creates a self-managed stack set and starts deploying its
+// instances, returning the operation ID of the rollout
+func (sm *StackSetManager) CreateStackSet(ctx context.Context, spec StackSetSpec) (string, error) {
+	if spec.Name == "" {
+		return "", fmt.Errorf("stack set name is required")
+	}
+	if len(spec.Accounts) == 0 || len(spec.Regions) == 0 {
+		return "", fmt.Errorf("at least one account and one region are required")
+	}
+	executionRole := spec.ExecutionRoleName
+	if executionRole == "" {
+		executionRole = DefaultStackSetExecutionRole
+	}
+
+	keys := make([]string, 0, len(spec.Parameters))
+	for key := range spec.Parameters {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	parameters := make([]types.Parameter, 0, len(keys))
+	for _, key := range keys {
+		parameters = append(parameters, types.Parameter{
+			ParameterKey:   aws.String(key),
+			ParameterValue: aws.String(spec.Parameters[key]),
+		})
+	}
+
+	input := &cloudformation.CreateStackSetInput{
+		StackSetName:      aws.String(spec.Name),
+		TemplateBody:      aws.String(spec.TemplateBody),
+		Parameters:        parameters,
+		PermissionModel:   types.PermissionModelsSelfManaged,
+		ExecutionRoleName: aws.String(executionRole),
+		Capabilities: []types.Capability{
+			types.CapabilityCapabilityIam,
+			types.CapabilityCapabilityNamedIam,
+		},
+		Tags: stackTags(spec.Tags),
+	}
+	if spec.AdministrationRoleARN != "" {
+		input.AdministrationRoleARN = aws.String(spec.AdministrationRoleARN)
+	}
+	if _, err := sm.api.CreateStackSet(ctx, input); err != nil {
+		return "", fmt.Errorf("failed to create stack set %s: %w", spec.Name, err)
+	}
+
+	result, err := sm.api.CreateStackInstances(ctx, &cloudformation.CreateStackInstancesInput{
+		StackSetName:         aws.String(spec.Name),
+		Accounts:             spec.Accounts,
+		Regions:              spec.Regions,
+		OperationPreferences: stackSetOperationPreferences(),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to create stack instances for %s: %w", spec.Name, err)
+	}
+	return aws.ToString(result.OperationId), nil
+}
+
+// Status returns a stack set's instances and, when an operation ID is
+// given, that operation's progress
+func (sm *StackSetManager) Status(ctx context.Context, name, operationID string) (*StackSetStatus, error) {
+	status := &StackSetStatus{Name: name, OperationID: operationID}
+
+	if operationID != "" {
+		result, err := sm.api.DescribeStackSetOperation(ctx, &cloudformation.DescribeStackSetOperationInput{
+			StackSetName: aws.String(name),
+			OperationId:  aws.String(operationID),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe stack set operation %s: %w", operationID, err)
+		}
+		if operation := result.StackSetOperation; operation != nil {
+			status.OperationAction = string(operation.Action)
+			status.OperationStatus = string(operation.Status)
+			status.OperationReason = aws.ToString(operation.StatusReason)
+		}
+	}
+
+	paginator := cloudformation.NewListStackInstancesPaginator(sm.api, &cloudformation.ListStackInstancesInput{
+		StackSetName: aws.String(name),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list instances of stack set %s: %w", name, err)
+		}
+		for _, summary := range page.Summaries {
+			instance := StackSetInstance{
+				Account: aws.ToString(summary.Account),
+				Region:  aws.ToString(summary.Region),
+				Status:  string(summary.Status),
+				Reason:  aws.ToString(summary.StatusReason),
+				StackID: aws.ToString(summary.StackId),
+			}
+			if summary.StackInstanceStatus != nil {
+				instance.DetailedStatus = string(summary.StackInstanceStatus.DetailedStatus)
+			}
+			status.Instances = append(status.Instances, instance)
+		}
+	}
+
+	sort.Slice(status.Instances, func(i, j int) bool {
+		a, b := status.Instances[i], status.Instances[j]
+		if a.Account != b.Account {
+			return a.Account < b.Account
+		}
+		return a.Region < b.Region
+	})
+	return status, nil
+}
+
+// WaitForOperation polls an operation until it finishes, calling progress
+// after each poll so callers can show instances as they roll out
+func (sm *StackSetManager) WaitForOperation(ctx context.Context, name, operationID string, progress func(*StackSetStatus)) (*StackSetStatus, error) {
+	ctx, cancel := context.WithTimeout(ctx, sm.timeout)
+	defer cancel()
+
+	for {
+		status, err := sm.Status(ctx, name, operationID)
+		if err != nil {
+			return nil, err
+		}
+		if progress != nil {
+			progress(status)
+		}
+		if status.Done() {
+			return status, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return status, fmt.Errorf("timeout waiting for stack set operation %s", operationID)
+		case <-time.After(jitteredInterval(sm.pollInterval)):
+		}
+	}
+}
+
+// DeleteInstances starts deleting a stack set's instances and their stacks,
+// returning the operation ID
+func (sm *StackSetManager) DeleteInstances(ctx context.Context, name string, accounts, regions []string) (string, error) {
+	result, err := sm.api.DeleteStackInstances(ctx, &cloudformation.DeleteStackInstancesInput{
+		StackSetName:         aws.String(name),
+		Accounts:             accounts,
+		Regions:              regions,
+		RetainStacks:         aws.Bool(false),
+		OperationPreferences: stackSetOperationPreferences(),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to delete instances of stack set %s: %w", name, err)
+	}
+	return aws.ToString(result.OperationId), nil
+}
+
+// DeleteStackSet deletes a stack set that no longer has instances
+func (sm *StackSetManager) DeleteStackSet(ctx context.Context, name string) error {
+	if _, err := sm.api.DeleteStackSet(ctx, &cloudformation.DeleteStackSetInput{StackSetName: aws.String(name)}); err != nil {
+		return fmt.Errorf("failed to delete stack set %s: %w", name, err)
+	}
+	return nil
+}
+
+// InstanceTargets returns the distinct accounts and regions of a stack set's instances
+func (s *StackSetStatus) InstanceTargets() (accounts, regions []string) {
+	seenAccounts, seenRegions := make(map[string]bool), make(map[string]bool)
+	for _, instance := range s.Instances {
+		if !seenAccounts[instance.Account] {
+			seenAccounts[instance.Account] = true
+			accounts = append(accounts, instance.Account)
+		}
+		if !seenRegions[instance.Region] {
+			seenRegions[instance.Region] = true
+			regions = append(regions, instance.Region)
+		}
+	}
+	sort.Strings(accounts)
+	sort.Strings(regions)
+	return accounts, regions
+}
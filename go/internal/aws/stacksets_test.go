@@ -0,0 +1,217 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+)
+
+type fakeStackSetAPI struct {
+	createSet       *cloudformation.CreateStackSetInput
+	createInstances *cloudformation.CreateStackInstancesInput
+	deleteInstances *cloudformation.DeleteStackInstancesInput
+	deletedSet      string
+	createSetErr    error
+
+	// pages of instances returned by ListStackInstances
+	pages [][]types.StackInstanceSummary
+	// operation statuses returned by successive DescribeStackSetOperation calls
+	operationStatuses []types.StackSetOperationStatus
+	describeCalls     int
+}
+
+func (f *fakeStackSetAPI) CreateStackSet(ctx context.Context, params *cloudformation.CreateStackSetInput, optFns ...func(*cloudformation.Options)) (*cloudformation.CreateStackSetOutput, error) {
+	if f.createSetErr != nil {
+		return nil, f.createSetErr
+	}
+	f.createSet = params
+	return &cloudformation.CreateStackSetOutput{StackSetId: aws.String("lab-env:1")}, nil
+}
+
+func (f *fakeStackSetAPI) CreateStackInstances(ctx context.Context, params *cloudformation.CreateStackInstancesInput, optFns ...func(*cloudformation.Options)) (*cloudformation.CreateStackInstancesOutput, error) {
+	f.createInstances = params
+	return &cloudformation.CreateStackInstancesOutput{OperationId: aws.String("op-create")}, nil
+}
+
+func (f *fakeStackSetAPI) DeleteStackInstances(ctx context.Context, params *cloudformation.DeleteStackInstancesInput, optFns ...func(*cloudformation.Options)) (*cloudformation.DeleteStackInstancesOutput, error) {
+	f.deleteInstances = params
+	return &cloudformation.DeleteStackInstancesOutput{OperationId: aws.String("op-delete")}, nil
+}
+
+func (f *fakeStackSetAPI) DeleteStackSet(ctx context.Context, params *cloudformation.DeleteStackSetInput, optFns ...func(*cloudformation.Options)) (*cloudformation.DeleteStackSetOutput, error) {
+	f.deletedSet = aws.ToString(params.StackSetName)
+	return &cloudformation.DeleteStackSetOutput{}, nil
+}
+
+func (f *fakeStackSetAPI) DescribeStackSetOperation(ctx context.Context, params *cloudformation.DescribeStackSetOperationInput, optFns ...func(*cloudformation.Options)) (*cloudformation.DescribeStackSetOperationOutput, error) {
+	status := f.operationStatuses[min(f.describeCalls, len(f.operationStatuses)-1)]
+	f.describeCalls++
+	return &cloudformation.DescribeStackSetOperationOutput{StackSetOperation: &types.StackSetOperation{
+		OperationId: params.OperationId,
+		Action:      types.StackSetOperationActionCreate,
+		Status:      status,
+	}}, nil
+}
+
+func (f *fakeStackSetAPI) ListStackInstances(ctx context.Context, params *cloudformation.ListStackInstancesInput, optFns ...func(*cloudformation.Options)) (*cloudformation.ListStackInstancesOutput, error) {
+	page := 0
+	if params.NextToken != nil {
+		page = 1
+	}
+	output := &cloudformation.ListStackInstancesOutput{Summaries: f.pages[page]}
+	if page+1 < len(f.pages) {
+		output.NextToken = aws.String("next")
+	}
+	return output, nil
+}
+
+func stackInstance(account, region string, status types.StackInstanceStatus, detailed types.StackInstanceDetailedStatus, reason string) types.StackInstanceSummary {
+	return types.StackInstanceSummary{
+		Account:             aws.String(account),
+		Region:              aws.String(region),
+		Status:              status,
+		StatusReason:        aws.String(reason),
+		StackInstanceStatus: &types.StackInstanceComprehensiveStatus{DetailedStatus: detailed},
+	}
+}
+
+func TestCreateStackSet(t *testing.T) {
+	api := &fakeStackSetAPI{}
+	manager := &StackSetManager{api: api}
+
+	operationID, err := manager.CreateStackSet(context.Background(), StackSetSpec{
+		Name:         "lab-env",
+		TemplateBody: "{}",
+		Parameters:   map[string]string{"SSHCIDR": "10.0.0.0/8", "InstanceType": "r6i.large"},
+		Tags:         map[string]string{"Lab": "smith"},
+		Accounts:     []string{"111111111111", "222222222222"},
+		Regions:      []string{"us-east-1", "us-west-2"},
+	})
+	if err != nil {
+		t.Fatalf("CreateStackSet() error = %v", err)
+	}
+	if operationID != "op-create" {
+		t.Errorf("operation ID = %s", operationID)
+	}
+
+	set := api.createSet
+	if set.PermissionModel != types.PermissionModelsSelfManaged || aws.ToString(set.ExecutionRoleName) != DefaultStackSetExecutionRole {
+		t.Errorf("permission model = %s, execution role = %s", set.PermissionModel, aws.ToString(set.ExecutionRoleName))
+	}
+	if set.AdministrationRoleARN != nil {
+		t.Error("administration role should be left to the CloudFormation default")
+	}
+	if len(set.Parameters) != 2 || aws.ToString(set.Parameters[0].ParameterKey) != "InstanceType" {
+		t.Errorf("parameters should be sorted by key: %+v", set.Parameters)
+	}
+	if aws.ToString(set.Tags[0].Key) != createdByTagKey || aws.ToString(set.Tags[2].Key) != "Lab" {
+		t.Errorf("tags = %+v", set.Tags)
+	}
+
+	instances := api.createInstances
+	if len(instances.Accounts) != 2 || len(instances.Regions) != 2 {
+		t.Errorf("targets = %v %v", instances.Accounts, instances.Regions)
+	}
+	if aws.ToInt32(instances.OperationPreferences.FailureTolerancePercentage) != 100 {
+		t.Error("one account failing should not stop the rollout to the others")
+	}
+}
+
+func TestCreateStackSetErrors(t *testing.T) {
+	manager := &StackSetManager{api: &fakeStackSetAPI{}}
+	if _, err := manager.CreateStackSet(context.Background(), StackSetSpec{Name: "lab-env", Regions: []string{"us-east-1"}}); err == nil {
+		t.Error("CreateStackSet() should require accounts")
+	}
+
+	manager = &StackSetManager{api: &fakeStackSetAPI{createSetErr: errors.New("NameAlreadyExistsException")}}
+	_, err := manager.CreateStackSet(context.Background(), StackSetSpec{Name: "lab-env", Accounts: []string{"111111111111"}, Regions: []string{"us-east-1"}})
+	if err == nil {
+		t.Error("CreateStackSet() should return the create error")
+	}
+}
+
+func TestStackSetStatusListsFailuresWithoutFailing(t *testing.T) {
+	api := &fakeStackSetAPI{
+		operationStatuses: []types.StackSetOperationStatus{types.StackSetOperationStatusSucceeded},
+		pages: [][]types.StackInstanceSummary{
+			{
+				stackInstance("222222222222", "us-east-1", types.StackInstanceStatusOutdated, types.StackInstanceDetailedStatusFailed, "Account 222222222222 should have 'AWSCloudFormationStackSetExecutionRole' role"),
+				stackInstance("111111111111", "us-west-2", types.StackInstanceStatusCurrent, types.StackInstanceDetailedStatusSucceeded, ""),
+			},
+			{
+				stackInstance("111111111111", "us-east-1", types.StackInstanceStatusCurrent, types.StackInstanceDetailedStatusSucceeded, ""),
+				stackInstance("333333333333", "us-east-1", types.StackInstanceStatusOutdated, types.StackInstanceDetailedStatusRunning, ""),
+			},
+		},
+	}
+	manager := &StackSetManager{api: api}
+
+	status, err := manager.Status(context.Background(), "lab-env", "op-create")
+	if err != nil {
+		t.Fatalf("Status() error = %v", err)
+	}
+	if status.OperationStatus != "SUCCEEDED" || !status.Done() {
+		t.Errorf("operation status = %s", status.OperationStatus)
+	}
+	if len(status.Instances) != 4 || status.Instances[0].Account != "111111111111" || status.Instances[0].Region != "us-east-1" {
+		t.Fatalf("instances should be sorted by account and region: %+v", status.Instances)
+	}
+
+	summary := status.Summary()
+	if summary != (StackSetSummary{Total: 4, Succeeded: 2, Pending: 1, Failed: 1}) {
+		t.Errorf("summary = %+v", summary)
+	}
+	failed := status.FailedInstances()
+	if len(failed) != 1 || failed[0].Account != "222222222222" || failed[0].Reason == "" {
+		t.Errorf("failed instances = %+v", failed)
+	}
+
+	accounts, regions := status.InstanceTargets()
+	if len(accounts) != 3 || len(regions) != 2 {
+		t.Errorf("targets = %v %v", accounts, regions)
+	}
+}
+
+func TestWaitForStackSetOperation(t *testing.T) {
+	api := &fakeStackSetAPI{
+		operationStatuses: []types.StackSetOperationStatus{
+			types.StackSetOperationStatusQueued,
+			types.StackSetOperationStatusRunning,
+			types.StackSetOperationStatusFailed,
+		},
+		pages: [][]types.StackInstanceSummary{{
+			stackInstance("111111111111", "us-east-1", types.StackInstanceStatusInoperable, types.StackInstanceDetailedStatusInoperable, "stack was deleted outside the stack set"),
+		}},
+	}
+	manager := &StackSetManager{api: api, pollInterval: time.Millisecond, timeout: time.Second}
+
+	polls := 0
+	status, err := manager.WaitForOperation(context.Background(), "lab-env", "op-create", func(*StackSetStatus) { polls++ })
+	if err != nil {
+		t.Fatalf("WaitForOperation() error = %v", err)
+	}
+	if polls != 3 || status.OperationStatus != "FAILED" || status.Summary().Failed != 1 {
+		t.Errorf("polls = %d, status = %+v", polls, status)
+	}
+}
+
+func TestDeleteStackSet(t *testing.T) {
+	api := &fakeStackSetAPI{}
+	manager := &StackSetManager{api: api}
+
+	operationID, err := manager.DeleteInstances(context.Background(), "lab-env", []string{"111111111111"}, []string{"us-east-1"})
+	if err != nil || operationID != "op-delete" {
+		t.Fatalf("DeleteInstances() = %s, %v", operationID, err)
+	}
+	if aws.ToBool(api.deleteInstances.RetainStacks) {
+		t.Error("deleting instances should delete their stacks")
+	}
+	if err := manager.DeleteStackSet(context.Background(), "lab-env"); err != nil || api.deletedSet != "lab-env" {
+		t.Errorf("DeleteStackSet() = %v, deleted %q", err, api.deletedSet)
+	}
+}
@@ -0,0 +1,274 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// storageMetricPeriodSeconds is the resolution of the daily S3 storage metrics
+const storageMetricPeriodSeconds = 86400
+
+// DefaultStorageListLimit bounds how many objects a storage listing reads
+const DefaultStorageListLimit = 1000000
+
+// s3StorageTypeClasses maps the StorageType dimension of S3 storage metrics to the
+// storage class the bytes are billed as. Overhead types are billed with their class,
+// except the S3 Standard overhead kept for archived objects.
+var s3StorageTypeClasses = map[string]string{
+	"StandardStorage":                    "STANDARD",
+	"StandardIAStorage":                  "STANDARD_IA",
+	"StandardIASizeOverhead":             "STANDARD_IA",
+	"OneZoneIAStorage":                   "ONEZONE_IA",
+	"OneZoneIASizeOverhead":              "ONEZONE_IA",
+	"ReducedRedundancyStorage":           "REDUCED_REDUNDANCY",
+	"GlacierInstantRetrievalStorage":     "GLACIER_IR",
+	"GlacierIRSizeOverhead":              "GLACIER_IR",
+	"GlacierStorage":                     "GLACIER",
+	"GlacierStagingStorage":              "GLACIER",
+	"GlacierObjectOverhead":              "GLACIER",
+	"GlacierS3ObjectOverhead":            "STANDARD",
+	"DeepArchiveStorage":                 "DEEP_ARCHIVE",
+	"DeepArchiveStagingStorage":          "DEEP_ARCHIVE",
+	"DeepArchiveObjectOverhead":          "DEEP_ARCHIVE",
+	"DeepArchiveS3ObjectOverhead":        "STANDARD",
+	"IntelligentTieringFAStorage":        "INTELLIGENT_TIERING",
+	"IntelligentTieringIAStorage":        "INTELLIGENT_TIERING",
+	"IntelligentTieringAIAStorage":       "INTELLIGENT_TIERING",
+	"IntelligentTieringAAStorage":        "INTELLIGENT_TIERING",
+	"IntelligentTieringDAAStorage":       "INTELLIGENT_TIERING",
+	"IntelligentTieringAAObjectOverhead": "INTELLIGENT_TIERING",
+	"ExpressOneZone":                     "EXPRESS_ONEZONE",
+}
+
+// StorageClassForType returns the storage class a StorageType dimension bills as
+func StorageClassForType(storageType string) string {
+	if class, exists := s3StorageTypeClasses[storageType]; exists {
+		return class
+	}
+	return strings.ToUpper(strings.TrimSuffix(storageType, "Storage"))
+}
+
+// ObjectUsage is the count and size of a set of objects
+type ObjectUsage struct {
+	Objects int64
+	Bytes   int64
+}
+
+// BucketStorageDay is a bucket's size on one day from the S3 storage metrics
+type BucketStorageDay struct {
+	Date    time.Time
+	Objects int64
+	Bytes   int64
+	// StorageClasses holds bytes per storage class
+	StorageClasses map[string]int64
+}
+
+// PrefixStorage is the size of the objects under a prefix, from a listing
+type PrefixStorage struct {
+	ObjectUsage
+	StorageClasses map[string]int64
+	// Prefixes holds usage per child prefix; objects directly under the prefix are
+	// grouped under ""
+	Prefixes map[string]ObjectUsage
+	// Partial is set when the listing stopped at the object limit
+	Partial bool
+}
+
+// storageMetricsAPI is the subset of CloudWatch used to read S3 storage metrics
+type storageMetricsAPI interface {
+	cloudwatch.ListMetricsAPIClient
+	metricDataAPI
+}
+
+// StorageCollector measures S3 bucket and prefix sizes
+type StorageCollector struct {
+	metrics storageMetricsAPI
+	s3      s3.ListObjectsV2APIClient
+}
+
+// NewStorageCollector creates a new storage collector
+func NewStorageCollector(client *Client) *StorageCollector {
+	return &StorageCollector{metrics: client.CloudWatch, s3: client.S3}
+}
+
+// BucketStorageHistory reads up to days of daily bucket sizes from the S3 storage
+// metrics CloudWatch publishes in the bucket's region, oldest first
+func (sc *StorageCollector) BucketStorageHistory(ctx context.Context, bucket string, days int) ([]BucketStorageDay, error) {
+	storageTypes, err := sc.bucketStorageTypes(ctx, bucket)
+	if err != nil {
+		return nil, err
+	}
+	if len(storageTypes) == 0 {
+		return nil, fmt.Errorf("no S3 storage metrics for bucket %s; they are published daily in the bucket's region", bucket)
+	}
+
+	query := func(id, metricName, storageType string) types.MetricDataQuery {
+		return types.MetricDataQuery{
+			Id: aws.String(id),
+			MetricStat: &types.MetricStat{
+				Metric: &types.Metric{
+					Namespace:  aws.String("AWS/S3"),
+					MetricName: aws.String(metricName),
+					Dimensions: []types.Dimension{
+						{Name: aws.String("BucketName"), Value: aws.String(bucket)},
+						{Name: aws.String("StorageType"), Value: aws.String(storageType)},
+					},
+				},
+				Period: aws.Int32(storageMetricPeriodSeconds),
+				Stat:   aws.String(string(types.StatisticAverage)),
+			},
+		}
+	}
+
+	queries := []types.MetricDataQuery{query("objects", "NumberOfObjects", "AllStorageTypes")}
+	queryTypes := make(map[string]string, len(storageTypes))
+	for i, storageType := range storageTypes {
+		id := fmt.Sprintf("size%d", i)
+		queryTypes[id] = storageType
+		queries = append(queries, query(id, "BucketSizeBytes", storageType))
+	}
+
+	end := time.Now().UTC()
+	// Storage metrics lag by a day or more, so look back further than requested
+	start := end.AddDate(0, 0, -(days + 2))
+
+	byDate := make(map[time.Time]*BucketStorageDay)
+	dayFor := func(timestamp time.Time) *BucketStorageDay {
+		date := timestamp.UTC().Truncate(24 * time.Hour)
+		day, exists := byDate[date]
+		if !exists {
+			day = &BucketStorageDay{Date: date, StorageClasses: make(map[string]int64)}
+			byDate[date] = day
+		}
+		return day
+	}
+
+	paginator := cloudwatch.NewGetMetricDataPaginator(sc.metrics, &cloudwatch.GetMetricDataInput{
+		StartTime:         aws.Time(start),
+		EndTime:           aws.Time(end),
+		ScanBy:            types.ScanByTimestampAscending,
+		MetricDataQueries: queries,
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get storage metrics for %s: %w", bucket, err)
+		}
+
+		for _, result := range page.MetricDataResults {
+			id := aws.ToString(result.Id)
+			for i, timestamp := range result.Timestamps {
+				if i >= len(result.Values) {
+					break
+				}
+				day := dayFor(timestamp)
+				value := int64(result.Values[i])
+				if id == "objects" {
+					day.Objects = value
+					continue
+				}
+				class := StorageClassForType(queryTypes[id])
+				day.StorageClasses[class] += value
+				day.Bytes += value
+			}
+		}
+	}
+
+	history := make([]BucketStorageDay, 0, len(byDate))
+	for _, day := range byDate {
+		history = append(history, *day)
+	}
+	sort.Slice(history, func(i, j int) bool {
+		return history[i].Date.Before(history[j].Date)
+	})
+	if len(history) > days {
+		history = history[len(history)-days:]
+	}
+	return history, nil
+}
+
+// bucketStorageTypes lists the StorageType dimensions a bucket publishes sizes for
+func (sc *StorageCollector) bucketStorageTypes(ctx context.Context, bucket string) ([]string, error) {
+	paginator := cloudwatch.NewListMetricsPaginator(sc.metrics, &cloudwatch.ListMetricsInput{
+		Namespace:  aws.String("AWS/S3"),
+		MetricName: aws.String("BucketSizeBytes"),
+		Dimensions: []types.DimensionFilter{{Name: aws.String("BucketName"), Value: aws.String(bucket)}},
+	})
+
+	var storageTypes []string
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list storage metrics for %s: %w", bucket, err)
+		}
+		for _, metric := range page.Metrics {
+			for _, dimension := range metric.Dimensions {
+				if aws.ToString(dimension.Name) == "StorageType" {
+					storageTypes = append(storageTypes, aws.ToString(dimension.Value))
+				}
+			}
+		}
+	}
+	sort.Strings(storageTypes)
+	return storageTypes, nil
+}
+
+// ListPrefixStorage sizes the objects under a prefix by listing them, grouped by
+// storage class and by child prefix. Listing stops after maxObjects objects.
+func (sc *StorageCollector) ListPrefixStorage(ctx context.Context, bucket, prefix string, maxObjects int64) (*PrefixStorage, error) {
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+
+	storage := &PrefixStorage{
+		StorageClasses: make(map[string]int64),
+		Prefixes:       make(map[string]ObjectUsage),
+	}
+
+	paginator := s3.NewListObjectsV2Paginator(sc.s3, &s3.ListObjectsV2Input{
+		Bucket: aws.String(bucket),
+		Prefix: aws.String(prefix),
+	})
+	for paginator.HasMorePages() {
+		if maxObjects > 0 && storage.Objects >= maxObjects {
+			storage.Partial = true
+			break
+		}
+
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list s3://%s/%s: %w", bucket, prefix, err)
+		}
+
+		for _, object := range page.Contents {
+			size := aws.ToInt64(object.Size)
+			class := string(object.StorageClass)
+			if class == "" {
+				class = "STANDARD"
+			}
+
+			storage.Objects++
+			storage.Bytes += size
+			storage.StorageClasses[class] += size
+
+			child := ""
+			if rest := strings.TrimPrefix(aws.ToString(object.Key), prefix); strings.Contains(rest, "/") {
+				child = prefix + rest[:strings.Index(rest, "/")+1]
+			}
+			usage := storage.Prefixes[child]
+			usage.Objects++
+			usage.Bytes += size
+			storage.Prefixes[child] = usage
+		}
+	}
+
+	return storage, nil
+}
@@ -0,0 +1,162 @@
+package aws
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+type fakeStorageMetricsAPI struct {
+	fakeMetricDataAPI
+	storageTypes []string
+}
+
+func (f *fakeStorageMetricsAPI) ListMetrics(ctx context.Context, params *cloudwatch.ListMetricsInput, optFns ...func(*cloudwatch.Options)) (*cloudwatch.ListMetricsOutput, error) {
+	output := &cloudwatch.ListMetricsOutput{}
+	for _, storageType := range f.storageTypes {
+		output.Metrics = append(output.Metrics, types.Metric{
+			Namespace:  params.Namespace,
+			MetricName: params.MetricName,
+			Dimensions: []types.Dimension{
+				{Name: aws.String("BucketName"), Value: params.Dimensions[0].Value},
+				{Name: aws.String("StorageType"), Value: aws.String(storageType)},
+			},
+		})
+	}
+	return output, nil
+}
+
+type fakeListObjectsAPI struct {
+	pages []*s3.ListObjectsV2Output
+	calls int
+}
+
+func (f *fakeListObjectsAPI) ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	page := f.pages[f.calls]
+	f.calls++
+	return page, nil
+}
+
+func TestBucketStorageHistory(t *testing.T) {
+	day1 := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	day2 := day1.AddDate(0, 0, 1)
+	day3 := day1.AddDate(0, 0, 2)
+
+	// size0 is DeepArchiveStorage and size1 StandardStorage, in sorted dimension order
+	api := &fakeStorageMetricsAPI{
+		storageTypes: []string{"StandardStorage", "DeepArchiveStorage"},
+		fakeMetricDataAPI: fakeMetricDataAPI{pages: []*cloudwatch.GetMetricDataOutput{{
+			MetricDataResults: []types.MetricDataResult{
+				metricResult("objects", []time.Time{day1, day2, day3}, []float64{10, 12, 15}),
+				metricResult("size0", []time.Time{day2, day3}, []float64{500, 500}),
+				metricResult("size1", []time.Time{day1, day2, day3}, []float64{1000, 1200, 1500}),
+			},
+		}}},
+	}
+	collector := &StorageCollector{metrics: api}
+
+	history, err := collector.BucketStorageHistory(context.Background(), "research", 2)
+	if err != nil {
+		t.Fatalf("BucketStorageHistory() error = %v", err)
+	}
+	if len(history) != 2 {
+		t.Fatalf("got %d days, want 2 (trimmed to the newest)", len(history))
+	}
+	if !history[0].Date.Equal(day2) || !history[1].Date.Equal(day3) {
+		t.Errorf("dates = %v, %v; want %v, %v", history[0].Date, history[1].Date, day2, day3)
+	}
+
+	latest := history[1]
+	if latest.Objects != 15 || latest.Bytes != 2000 {
+		t.Errorf("latest = %d objects, %d bytes; want 15 objects, 2000 bytes", latest.Objects, latest.Bytes)
+	}
+	if latest.StorageClasses["STANDARD"] != 1500 || latest.StorageClasses["DEEP_ARCHIVE"] != 500 {
+		t.Errorf("storage classes = %v", latest.StorageClasses)
+	}
+}
+
+func TestBucketStorageHistoryNoMetrics(t *testing.T) {
+	collector := &StorageCollector{metrics: &fakeStorageMetricsAPI{}}
+	if _, err := collector.BucketStorageHistory(context.Background(), "research", 7); err == nil {
+		t.Fatal("expected an error for a bucket without storage metrics")
+	}
+}
+
+func TestStorageClassForType(t *testing.T) {
+	tests := map[string]string{
+		"StandardStorage":             "STANDARD",
+		"GlacierS3ObjectOverhead":     "STANDARD",
+		"IntelligentTieringAAStorage": "INTELLIGENT_TIERING",
+		"FutureStorage":               "FUTURE",
+	}
+	for storageType, want := range tests {
+		if got := StorageClassForType(storageType); got != want {
+			t.Errorf("StorageClassForType(%q) = %q, want %q", storageType, got, want)
+		}
+	}
+}
+
+func TestListPrefixStorage(t *testing.T) {
+	object := func(key string, size int64, class s3types.ObjectStorageClass) s3types.Object {
+		return s3types.Object{Key: aws.String(key), Size: aws.Int64(size), StorageClass: class}
+	}
+	api := &fakeListObjectsAPI{pages: []*s3.ListObjectsV2Output{
+		{
+			IsTruncated:           aws.Bool(true),
+			NextContinuationToken: aws.String("next"),
+			Contents: []s3types.Object{
+				object("runs/manifest.json", 10, ""),
+				object("runs/a/out.dat", 100, s3types.ObjectStorageClassStandard),
+			},
+		},
+		{
+			IsTruncated:           aws.Bool(true),
+			NextContinuationToken: aws.String("more"),
+			Contents: []s3types.Object{
+				object("runs/a/b/deep.dat", 200, s3types.ObjectStorageClassGlacier),
+				object("runs/c/out.dat", 50, s3types.ObjectStorageClassStandard),
+			},
+		},
+		{
+			Contents: []s3types.Object{object("runs/d/never.dat", 1, "")},
+		},
+	}}
+	collector := &StorageCollector{s3: api}
+
+	storage, err := collector.ListPrefixStorage(context.Background(), "research", "runs", 4)
+	if err != nil {
+		t.Fatalf("ListPrefixStorage() error = %v", err)
+	}
+	if !storage.Partial {
+		t.Error("expected a partial listing at the object limit")
+	}
+	if api.calls != 2 {
+		t.Errorf("listed %d pages, want 2", api.calls)
+	}
+	if storage.Objects != 4 || storage.Bytes != 360 {
+		t.Errorf("totals = %d objects, %d bytes; want 4 objects, 360 bytes", storage.Objects, storage.Bytes)
+	}
+	if storage.StorageClasses["STANDARD"] != 160 || storage.StorageClasses["GLACIER"] != 200 {
+		t.Errorf("storage classes = %v", storage.StorageClasses)
+	}
+
+	want := map[string]ObjectUsage{
+		"":        {Objects: 1, Bytes: 10},
+		"runs/a/": {Objects: 2, Bytes: 300},
+		"runs/c/": {Objects: 1, Bytes: 50},
+	}
+	if len(storage.Prefixes) != len(want) {
+		t.Fatalf("prefixes = %v, want %v", storage.Prefixes, want)
+	}
+	for prefix, usage := range want {
+		if storage.Prefixes[prefix] != usage {
+			t.Errorf("prefix %q = %+v, want %+v", prefix, storage.Prefixes[prefix], usage)
+		}
+	}
+}
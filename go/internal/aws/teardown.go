@@ -0,0 +1,413 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"gopkg.in/yaml.v3"
+)
+
+// EBSStorageCostPerGBMonth is the gp3 EBS volume storage price
+const EBSStorageCostPerGBMonth = 0.08
+
+// hoursPerMonth is the average number of hours in a month used for storage pricing
+const hoursPerMonth = 730
+
+// CloudFormation resource types enumerated before a stack is deleted
+const (
+	resourceTypeInstance   = "AWS::EC2::Instance"
+	resourceTypeVolume     = "AWS::EC2::Volume"
+	resourceTypeFileSystem = "AWS::EFS::FileSystem"
+	resourceTypeBucket     = "AWS::S3::Bucket"
+)
+
+// TeardownVolume is an EBS volume belonging to a stack
+type TeardownVolume struct {
+	VolumeID   string
+	DeviceName string
+	SizeGB     int32
+	VolumeType string
+	// Retained is set for volumes that outlive the instance or stack
+	Retained bool
+}
+
+// TeardownFileSystem is an EFS file system created by a stack
+type TeardownFileSystem struct {
+	FileSystemID string
+	LogicalID    string
+	Retained     bool
+}
+
+// TeardownBucket is an S3 bucket created by a stack
+type TeardownBucket struct {
+	Name      string
+	LogicalID string
+	Objects   int
+	SizeBytes int64
+	Retained  bool
+}
+
+// TeardownInventory is the resource data collected for a stack before it is deleted
+type TeardownInventory struct {
+	StackName    string
+	Region       string
+	StackCreated time.Time
+	InstanceID   string
+	InstanceType string
+	State        string
+	LaunchTime   time.Time
+	HourlyCost   float64
+	Volumes      []TeardownVolume
+	Snapshots    []SnapshotInfo
+	FileSystems  []TeardownFileSystem
+	Buckets      []TeardownBucket
+}
+
+// TeardownItem is one resource listed in a teardown report
+type TeardownItem struct {
+	Kind   string
+	ID     string
+	Detail string
+}
+
+// TeardownReport summarizes what an environment cost and what deleting it destroys
+type TeardownReport struct {
+	StackName    string
+	Region       string
+	InstanceType string
+	Lifetime     time.Duration
+	Uptime       time.Duration
+	ComputeCost  float64
+	StorageCost  float64
+	Deleted      []TeardownItem
+	Retained     []TeardownItem
+	// RetainedMonthlyCost is what the retained resources keep costing after deletion
+	RetainedMonthlyCost float64
+	Warnings            []string
+}
+
+// EstimatedTotalCost returns the estimated lifetime cost of the environment
+func (r *TeardownReport) EstimatedTotalCost() float64 {
+	return r.ComputeCost + r.StorageCost
+}
+
+// BuildTeardownReport summarizes an inventory as of now. Compute is charged from the
+// instance's last launch, storage from stack creation.
+func BuildTeardownReport(inventory *TeardownInventory, now time.Time) *TeardownReport {
+	report := &TeardownReport{
+		StackName:    inventory.StackName,
+		Region:       inventory.Region,
+		InstanceType: inventory.InstanceType,
+	}
+
+	if !inventory.StackCreated.IsZero() && now.After(inventory.StackCreated) {
+		report.Lifetime = now.Sub(inventory.StackCreated)
+	}
+	// Stopped instances keep their last launch time but accrue no compute charges
+	if inventory.State == "running" && !inventory.LaunchTime.IsZero() && now.After(inventory.LaunchTime) {
+		report.Uptime = now.Sub(inventory.LaunchTime)
+	}
+	report.ComputeCost = report.Uptime.Hours() * inventory.HourlyCost
+
+	if inventory.InstanceID != "" {
+		report.Deleted = append(report.Deleted, TeardownItem{
+			Kind:   "instance",
+			ID:     inventory.InstanceID,
+			Detail: fmt.Sprintf("%s, %s", inventory.InstanceType, inventory.State),
+		})
+	}
+
+	lifetimeMonths := report.Lifetime.Hours() / hoursPerMonth
+	for _, volume := range inventory.Volumes {
+		monthly := float64(volume.SizeGB) * EBSStorageCostPerGBMonth
+		report.StorageCost += monthly * lifetimeMonths
+
+		item := TeardownItem{
+			Kind:   "volume",
+			ID:     volume.VolumeID,
+			Detail: fmt.Sprintf("%d GB %s at %s", volume.SizeGB, volume.VolumeType, volume.DeviceName),
+		}
+		if volume.Retained {
+			report.Retained = append(report.Retained, item)
+			report.RetainedMonthlyCost += monthly
+		} else {
+			report.Deleted = append(report.Deleted, item)
+		}
+	}
+
+	// Snapshots are not stack resources, so they survive deletion
+	for _, snapshot := range inventory.Snapshots {
+		report.Retained = append(report.Retained, TeardownItem{
+			Kind:   "snapshot",
+			ID:     snapshot.SnapshotID,
+			Detail: fmt.Sprintf("%d GB from %s, taken %s", snapshot.SizeGB, snapshot.VolumeID, snapshot.StartTime.Format("2006-01-02")),
+		})
+		report.RetainedMonthlyCost += snapshot.MonthlyCost
+	}
+
+	for _, fileSystem := range inventory.FileSystems {
+		item := TeardownItem{Kind: "efs", ID: fileSystem.FileSystemID, Detail: fileSystem.LogicalID}
+		if fileSystem.Retained {
+			report.Retained = append(report.Retained, item)
+		} else {
+			report.Deleted = append(report.Deleted, item)
+		}
+	}
+
+	for _, bucket := range inventory.Buckets {
+		item := TeardownItem{
+			Kind:   "bucket",
+			ID:     bucket.Name,
+			Detail: fmt.Sprintf("%d objects, %d bytes", bucket.Objects, bucket.SizeBytes),
+		}
+		if bucket.Retained {
+			report.Retained = append(report.Retained, item)
+			continue
+		}
+		report.Deleted = append(report.Deleted, item)
+		if bucket.Objects > 0 {
+			report.Warnings = append(report.Warnings, fmt.Sprintf(
+				"bucket %s holds %d objects; CloudFormation cannot delete a non-empty bucket, so copy out any results and empty it first",
+				bucket.Name, bucket.Objects))
+		}
+	}
+
+	if len(inventory.Snapshots) == 0 && hasDeletedVolume(inventory.Volumes) {
+		report.Warnings = append(report.Warnings,
+			"no snapshots exist for this stack; data on deleted volumes cannot be recovered (take one with 'deploy snapshot')")
+	}
+
+	return report
+}
+
+func hasDeletedVolume(volumes []TeardownVolume) bool {
+	for _, volume := range volumes {
+		if !volume.Retained {
+			return true
+		}
+	}
+	return false
+}
+
+// teardownCFNAPI is the subset of the CloudFormation API used to inventory a stack
+type teardownCFNAPI interface {
+	DescribeStackResources(ctx context.Context, params *cloudformation.DescribeStackResourcesInput, optFns ...func(*cloudformation.Options)) (*cloudformation.DescribeStackResourcesOutput, error)
+	GetTemplate(ctx context.Context, params *cloudformation.GetTemplateInput, optFns ...func(*cloudformation.Options)) (*cloudformation.GetTemplateOutput, error)
+}
+
+// TeardownCollector gathers the resources a stack deletion affects
+type TeardownCollector struct {
+	cfn       teardownCFNAPI
+	ec2       snapshotAPI
+	s3        s3.ListObjectsV2APIClient
+	infra     *InfrastructureManager
+	snapshots *SnapshotManager
+	region    string
+}
+
+// NewTeardownCollector creates a new teardown collector
+func NewTeardownCollector(client *Client) *TeardownCollector {
+	return &TeardownCollector{
+		cfn:       client.CloudFormation,
+		ec2:       client.EC2,
+		s3:        client.S3,
+		infra:     NewInfrastructureManager(client),
+		snapshots: NewSnapshotManager(client),
+		region:    client.Region,
+	}
+}
+
+// Collect inventories a stack's instance, storage and buckets
+func (tc *TeardownCollector) Collect(ctx context.Context, stackName string) (*TeardownInventory, error) {
+	stackInfo, err := tc.infra.GetStackInfo(ctx, stackName)
+	if err != nil {
+		return nil, err
+	}
+
+	inventory := &TeardownInventory{
+		StackName:    stackName,
+		Region:       tc.region,
+		StackCreated: stackInfo.CreatedTime,
+	}
+
+	resources, err := tc.cfn.DescribeStackResources(ctx, &cloudformation.DescribeStackResourcesInput{
+		StackName: aws.String(stackName),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe stack resources: %w", err)
+	}
+
+	retained, err := tc.retainedResources(ctx, stackName)
+	if err != nil {
+		return nil, err
+	}
+
+	stackVolumes := make(map[string]bool)
+	for _, resource := range resources.StackResources {
+		physicalID := aws.ToString(resource.PhysicalResourceId)
+		logicalID := aws.ToString(resource.LogicalResourceId)
+		if physicalID == "" {
+			continue
+		}
+
+		switch aws.ToString(resource.ResourceType) {
+		case resourceTypeInstance:
+			inventory.InstanceID = physicalID
+		case resourceTypeVolume:
+			stackVolumes[physicalID] = retained[logicalID]
+		case resourceTypeFileSystem:
+			inventory.FileSystems = append(inventory.FileSystems, TeardownFileSystem{
+				FileSystemID: physicalID,
+				LogicalID:    logicalID,
+				Retained:     retained[logicalID],
+			})
+		case resourceTypeBucket:
+			bucket, err := tc.bucketContents(ctx, physicalID)
+			if err != nil {
+				return nil, err
+			}
+			bucket.LogicalID = logicalID
+			bucket.Retained = retained[logicalID]
+			inventory.Buckets = append(inventory.Buckets, *bucket)
+		}
+	}
+
+	if inventory.InstanceID != "" {
+		if err := tc.collectInstance(ctx, inventory, stackVolumes); err != nil {
+			return nil, err
+		}
+	}
+
+	snapshots, err := tc.snapshots.ListSnapshots(ctx, stackName)
+	if err != nil {
+		return nil, err
+	}
+	inventory.Snapshots = snapshots
+
+	return inventory, nil
+}
+
+// collectInstance records the instance and its volumes. Volumes without
+// DeleteOnTermination survive the instance unless the stack itself owns them.
+func (tc *TeardownCollector) collectInstance(ctx context.Context, inventory *TeardownInventory, stackVolumes map[string]bool) error {
+	result, err := tc.ec2.DescribeInstances(ctx, &ec2.DescribeInstancesInput{InstanceIds: []string{inventory.InstanceID}})
+	if err != nil {
+		return fmt.Errorf("failed to describe instance %s: %w", inventory.InstanceID, err)
+	}
+	if len(result.Reservations) == 0 || len(result.Reservations[0].Instances) == 0 {
+		return nil
+	}
+	instance := result.Reservations[0].Instances[0]
+
+	inventory.InstanceType = string(instance.InstanceType)
+	inventory.LaunchTime = aws.ToTime(instance.LaunchTime)
+	if instance.State != nil {
+		inventory.State = string(instance.State.Name)
+	}
+
+	devices := make(map[string]ec2types.InstanceBlockDeviceMapping)
+	volumeIDs := make([]string, 0, len(instance.BlockDeviceMappings))
+	for _, mapping := range instance.BlockDeviceMappings {
+		if mapping.Ebs == nil || mapping.Ebs.VolumeId == nil {
+			continue
+		}
+		devices[*mapping.Ebs.VolumeId] = mapping
+		volumeIDs = append(volumeIDs, *mapping.Ebs.VolumeId)
+	}
+	for volumeID := range stackVolumes {
+		if _, attached := devices[volumeID]; !attached {
+			volumeIDs = append(volumeIDs, volumeID)
+		}
+	}
+	if len(volumeIDs) == 0 {
+		return nil
+	}
+	sort.Strings(volumeIDs)
+
+	volumes, err := tc.ec2.DescribeVolumes(ctx, &ec2.DescribeVolumesInput{VolumeIds: volumeIDs})
+	if err != nil {
+		return fmt.Errorf("failed to describe volumes: %w", err)
+	}
+
+	for _, volume := range volumes.Volumes {
+		volumeID := aws.ToString(volume.VolumeId)
+		teardownVolume := TeardownVolume{
+			VolumeID:   volumeID,
+			SizeGB:     aws.ToInt32(volume.Size),
+			VolumeType: string(volume.VolumeType),
+		}
+
+		if retained, owned := stackVolumes[volumeID]; owned {
+			teardownVolume.Retained = retained
+		} else if mapping, ok := devices[volumeID]; ok {
+			teardownVolume.Retained = !aws.ToBool(mapping.Ebs.DeleteOnTermination)
+		}
+		if mapping, ok := devices[volumeID]; ok {
+			teardownVolume.DeviceName = aws.ToString(mapping.DeviceName)
+		}
+
+		inventory.Volumes = append(inventory.Volumes, teardownVolume)
+	}
+
+	return nil
+}
+
+// retainedResources returns the logical IDs whose DeletionPolicy keeps them after the
+// stack is deleted. Templates that cannot be parsed are treated as retaining nothing.
+func (tc *TeardownCollector) retainedResources(ctx context.Context, stackName string) (map[string]bool, error) {
+	result, err := tc.cfn.GetTemplate(ctx, &cloudformation.GetTemplateInput{
+		StackName: aws.String(stackName),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stack template: %w", err)
+	}
+
+	return parseRetainedResources(aws.ToString(result.TemplateBody)), nil
+}
+
+// parseRetainedResources reads DeletionPolicy from a JSON or YAML template body
+func parseRetainedResources(templateBody string) map[string]bool {
+	var template struct {
+		Resources map[string]struct {
+			DeletionPolicy string `yaml:"DeletionPolicy"`
+		} `yaml:"Resources"`
+	}
+
+	retained := make(map[string]bool)
+	if err := yaml.Unmarshal([]byte(templateBody), &template); err != nil {
+		return retained
+	}
+
+	for logicalID, resource := range template.Resources {
+		if resource.DeletionPolicy == "Retain" || resource.DeletionPolicy == "RetainExceptOnCreate" {
+			retained[logicalID] = true
+		}
+	}
+	return retained
+}
+
+// bucketContents counts the objects in a bucket
+func (tc *TeardownCollector) bucketContents(ctx context.Context, bucketName string) (*TeardownBucket, error) {
+	bucket := &TeardownBucket{Name: bucketName}
+
+	paginator := s3.NewListObjectsV2Paginator(tc.s3, &s3.ListObjectsV2Input{Bucket: aws.String(bucketName)})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list bucket %s: %w", bucketName, err)
+		}
+		for _, object := range page.Contents {
+			bucket.Objects++
+			bucket.SizeBytes += aws.ToInt64(object.Size)
+		}
+	}
+
+	return bucket, nil
+}
@@ -0,0 +1,129 @@
+package aws
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuildTeardownReport(t *testing.T) {
+	created := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := created.Add(hoursPerMonth * time.Hour)
+
+	inventory := &TeardownInventory{
+		StackName:    "research-wizard-genomics",
+		Region:       "us-east-1",
+		StackCreated: created,
+		InstanceID:   "i-123",
+		InstanceType: "r6i.xlarge",
+		State:        "running",
+		LaunchTime:   now.Add(-10 * time.Hour),
+		HourlyCost:   0.25,
+		Volumes: []TeardownVolume{
+			{VolumeID: "vol-root", DeviceName: "/dev/xvda", SizeGB: 100, VolumeType: "gp3"},
+			{VolumeID: "vol-data", DeviceName: "/dev/xvdf", SizeGB: 50, VolumeType: "gp3", Retained: true},
+		},
+		Snapshots: []SnapshotInfo{
+			{SnapshotID: "snap-1", VolumeID: "vol-root", SizeGB: 100, StartTime: created, MonthlyCost: 5},
+		},
+		FileSystems: []TeardownFileSystem{{FileSystemID: "fs-1", LogicalID: "SharedFS"}},
+		Buckets: []TeardownBucket{
+			{Name: "results", LogicalID: "ResultsBucket", Objects: 3, SizeBytes: 1024},
+			{Name: "archive", LogicalID: "ArchiveBucket", Retained: true},
+		},
+	}
+
+	report := BuildTeardownReport(inventory, now)
+
+	if report.Uptime != 10*time.Hour {
+		t.Errorf("Expected 10h uptime, got %v", report.Uptime)
+	}
+	if math.Abs(report.ComputeCost-2.5) > 1e-9 {
+		t.Errorf("Expected compute cost 2.5, got %f", report.ComputeCost)
+	}
+	// One month of 150 GB at the gp3 rate
+	if math.Abs(report.StorageCost-12) > 1e-9 {
+		t.Errorf("Expected storage cost 12, got %f", report.StorageCost)
+	}
+	if math.Abs(report.EstimatedTotalCost()-14.5) > 1e-9 {
+		t.Errorf("Expected total cost 14.5, got %f", report.EstimatedTotalCost())
+	}
+	// Retained data volume plus the snapshot
+	if math.Abs(report.RetainedMonthlyCost-9) > 1e-9 {
+		t.Errorf("Expected retained monthly cost 9, got %f", report.RetainedMonthlyCost)
+	}
+
+	if got := itemIDs(report.Deleted); got != "i-123,vol-root,fs-1,results" {
+		t.Errorf("Unexpected deleted resources: %s", got)
+	}
+	if got := itemIDs(report.Retained); got != "vol-data,snap-1,archive" {
+		t.Errorf("Unexpected retained resources: %s", got)
+	}
+
+	if len(report.Warnings) != 1 || !strings.Contains(report.Warnings[0], "results") {
+		t.Errorf("Expected a non-empty bucket warning, got %v", report.Warnings)
+	}
+}
+
+func TestBuildTeardownReportStoppedWithoutSnapshots(t *testing.T) {
+	now := time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)
+	inventory := &TeardownInventory{
+		StackName:    "stopped",
+		StackCreated: now.Add(-24 * time.Hour),
+		InstanceID:   "i-456",
+		State:        "stopped",
+		LaunchTime:   now.Add(-12 * time.Hour),
+		HourlyCost:   1,
+		Volumes:      []TeardownVolume{{VolumeID: "vol-root", SizeGB: 10}},
+	}
+
+	report := BuildTeardownReport(inventory, now)
+
+	if report.Uptime != 0 || report.ComputeCost != 0 {
+		t.Errorf("Stopped instances should not accrue compute cost, got %v / %f", report.Uptime, report.ComputeCost)
+	}
+	if report.Lifetime != 24*time.Hour {
+		t.Errorf("Expected 24h lifetime, got %v", report.Lifetime)
+	}
+	if len(report.Warnings) != 1 || !strings.Contains(report.Warnings[0], "no snapshots") {
+		t.Errorf("Expected a missing snapshot warning, got %v", report.Warnings)
+	}
+}
+
+func TestParseRetainedResources(t *testing.T) {
+	jsonTemplate := `{"Resources": {
+		"ResearchInstance": {"Type": "AWS::EC2::Instance"},
+		"DataVolume": {"Type": "AWS::EC2::Volume", "DeletionPolicy": "Retain"},
+		"Scratch": {"Type": "AWS::EC2::Volume", "DeletionPolicy": "Snapshot"}
+	}}`
+	retained := parseRetainedResources(jsonTemplate)
+	if !retained["DataVolume"] || retained["ResearchInstance"] || retained["Scratch"] {
+		t.Errorf("Unexpected retained resources from JSON: %v", retained)
+	}
+
+	yamlTemplate := `
+Resources:
+  ResultsBucket:
+    Type: AWS::S3::Bucket
+    DeletionPolicy: RetainExceptOnCreate
+    Properties:
+      BucketName: !Sub "${AWS::StackName}-results"
+`
+	retained = parseRetainedResources(yamlTemplate)
+	if !retained["ResultsBucket"] {
+		t.Errorf("Expected ResultsBucket to be retained, got %v", retained)
+	}
+
+	if len(parseRetainedResources("{not a template")) != 0 {
+		t.Error("Expected unparseable template to retain nothing")
+	}
+}
+
+func itemIDs(items []TeardownItem) string {
+	ids := make([]string, 0, len(items))
+	for _, item := range items {
+		ids = append(ids, item.ID)
+	}
+	return strings.Join(ids, ",")
+}
@@ -0,0 +1,134 @@
+package templates
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/aws"
+)
+
+// Logical IDs of the resources that give an instance a stable address
+const (
+	ElasticIPLogicalID            = "ResearchElasticIP"
+	ElasticIPAssociationLogicalID = "ResearchElasticIPAssociation"
+	DNSRecordLogicalID            = "ResearchDNSRecord"
+)
+
+const (
+	// dnsRecordTTL is the TTL, in seconds, of the Route 53 record; the address
+	// it points at only changes when the stack is replaced
+	dnsRecordTTL     = "300"
+	maxDNSNameLength = 253
+)
+
+var (
+	dnsLabelPattern   = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+	hostedZonePattern = regexp.MustCompile(`^[A-Z0-9]{1,32}$`)
+)
+
+// validateAddress checks the Elastic IP and DNS record options
+func (o Options) validateAddress() error {
+	if o.RetainElasticIP && !o.ElasticIP {
+		return fmt.Errorf("retaining the Elastic IP address requires an Elastic IP address")
+	}
+	if o.ElasticIP && o.Compliance.Settings().NoPublicIP {
+		return fmt.Errorf("the %s compliance profile does not allow an Elastic IP address", o.Compliance)
+	}
+	if o.DNSName == "" && o.HostedZoneID == "" {
+		return nil
+	}
+	if o.DNSName == "" || o.HostedZoneID == "" {
+		return fmt.Errorf("a DNS record needs both a DNS name and a hosted zone ID")
+	}
+	if !o.ElasticIP {
+		return fmt.Errorf("a DNS record needs an Elastic IP address to point at")
+	}
+	if err := validateDNSName(o.DNSName); err != nil {
+		return err
+	}
+	if !hostedZonePattern.MatchString(o.HostedZoneID) {
+		return fmt.Errorf("invalid hosted zone ID %q", o.HostedZoneID)
+	}
+	return nil
+}
+
+// validateDNSName checks a fully qualified host name, with or without the
+// trailing dot
+func validateDNSName(name string) error {
+	trimmed := strings.TrimSuffix(name, ".")
+	labels := strings.Split(trimmed, ".")
+	if len(trimmed) > maxDNSNameLength || len(labels) < 2 {
+		return fmt.Errorf("invalid DNS name %q: use a fully qualified name such as jupyter.lab.example.com", name)
+	}
+	for _, label := range labels {
+		if !dnsLabelPattern.MatchString(label) {
+			return fmt.Errorf("invalid DNS name %q: label %q must be lowercase letters, digits and hyphens", name, label)
+		}
+	}
+	return nil
+}
+
+// addElasticIP gives the research instance an Elastic IP address, and a
+// Route 53 A record when a DNS name is set, so the address users connect to
+// survives the instance being stopped and started. The address is released
+// with the stack unless it is retained. The PublicIP output and SSH command
+// use the stable address, since the instance's own public IP attribute is
+// read before the Elastic IP address is associated.
+func addElasticIP(template *Template, opts Options) {
+	address := Resource{
+		Type: "AWS::EC2::EIP",
+		Properties: EIPProperties{
+			Domain: "vpc",
+			Tags:   instanceTags("research-wizard-address"),
+		},
+	}
+	if opts.RetainElasticIP {
+		address.DeletionPolicy = "Retain"
+		address.UpdateReplacePolicy = "Retain"
+	}
+	template.Resources[ElasticIPLogicalID] = address
+	template.Resources[ElasticIPAssociationLogicalID] = Resource{
+		Type: "AWS::EC2::EIPAssociation",
+		Properties: EIPAssociationProperties{
+			AllocationId: getAtt(ElasticIPLogicalID, "AllocationId"),
+			InstanceId:   ref(InstanceLogicalID),
+		},
+	}
+
+	template.Outputs[aws.OutputElasticIP] = Output{
+		Description: "Elastic IP address of the research environment",
+		Value:       ref(ElasticIPLogicalID),
+	}
+	template.Outputs[aws.OutputPublicIP] = Output{
+		Description: "Public IP address of the research environment",
+		Value:       ref(ElasticIPLogicalID),
+	}
+	host := "${" + ElasticIPLogicalID + "}"
+
+	if opts.DNSName != "" {
+		name := strings.TrimSuffix(opts.DNSName, ".")
+		template.Resources[DNSRecordLogicalID] = Resource{
+			Type: "AWS::Route53::RecordSet",
+			Properties: RecordSetProperties{
+				HostedZoneId:    opts.HostedZoneID,
+				Name:            name,
+				Type:            "A",
+				TTL:             dnsRecordTTL,
+				ResourceRecords: []interface{}{ref(ElasticIPLogicalID)},
+			},
+		}
+		template.Outputs[aws.OutputDNSName] = Output{
+			Description: "DNS name of the research environment",
+			Value:       name,
+		}
+		host = name
+	}
+
+	if _, exists := template.Outputs[aws.OutputSSHCommand]; exists {
+		template.Outputs[aws.OutputSSHCommand] = Output{
+			Description: "SSH command to connect to the instance",
+			Value:       sub("ssh -i ~/.ssh/${KeyName}.pem ec2-user@" + host),
+		}
+	}
+}
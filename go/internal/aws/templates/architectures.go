@@ -0,0 +1,267 @@
+package templates
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/aws"
+)
+
+// ecrReadOnlyPolicy lets a container host pull images from ECR
+const ecrReadOnlyPolicy = "arn:aws:iam::aws:policy/AmazonEC2ContainerRegistryReadOnly"
+
+// gpuInstanceFamilies are the EC2 families with NVIDIA GPUs
+var gpuInstanceFamilies = map[string]bool{
+	"g4dn": true, "g5": true, "g5g": true, "g6": true, "g6e": true, "gr6": true,
+	"p3": true, "p3dn": true, "p4d": true, "p4de": true, "p5": true, "p5e": true,
+}
+
+// ComputeNodeLogicalID returns the logical ID of the nth compute node, from 1
+func ComputeNodeLogicalID(n int) string {
+	return fmt.Sprintf("ComputeNode%d", n)
+}
+
+// buildSingle is the original one-instance research environment
+func buildSingle(opts Options) *Template {
+	template := baseTemplate(opts)
+	template.Resources[SecurityGroupLogicalID] = securityGroup(opts, false)
+
+	properties := instance(opts, ref("InstanceType"), "research-wizard-instance", sub(ResearchUserData))
+	if opts.PlacementGroup {
+		properties.PlacementGroupName = ref(PlacementGroupLogicalID)
+		template.Resources[PlacementGroupLogicalID] = placementGroup()
+	}
+	template.Resources[InstanceLogicalID] = Resource{Type: "AWS::EC2::Instance", Properties: properties}
+
+	if opts.IAMRole {
+		addInstanceRole(template, opts)
+	}
+	return template
+}
+
+func validateHeadCompute(opts Options) error {
+	if opts.ComputeNodes < 1 || opts.ComputeNodes > MaxComputeNodes {
+		return fmt.Errorf("compute node count %d is outside 1-%d", opts.ComputeNodes, MaxComputeNodes)
+	}
+	// Every node boots the same image, so they must share an architecture
+	if opts.ComputeInstanceType != "" {
+		head, compute := opts.InstanceArchitecture(), aws.InstanceArchitecture(opts.ComputeInstanceType)
+		if compute != head {
+			return fmt.Errorf("compute instance type %s is %s but the head node is %s", opts.ComputeInstanceType, compute, head)
+		}
+	}
+	return nil
+}
+
+// sharedFileSystemUserData mounts the shared EFS file system at /shared
+func sharedFileSystemUserData(role string) string {
+	return "#!/bin/bash\n" +
+		"yum update -y\n" +
+		"yum install -y amazon-efs-utils git\n" +
+		"mkdir -p /shared\n" +
+		"echo '${" + SharedFileSystemLogicalID + "}:/ /shared efs _netdev,tls 0 0' >> /etc/fstab\n" +
+		"mount -a -t efs\n" +
+		"chown ec2-user:ec2-user /shared\n" +
+		"echo 'Research " + role + " node setup complete' > /tmp/setup.log\n"
+}
+
+// clusterRule is a security group rule between groups, a separate resource
+// so groups can refer to each other
+func clusterRule(groupID, sourceGroupID string) Resource {
+	return Resource{
+		Type: "AWS::EC2::SecurityGroupIngress",
+		Properties: SecurityGroupIngressProperties{
+			GroupId:               ref(groupID),
+			IpProtocol:            "-1",
+			FromPort:              -1,
+			ToPort:                -1,
+			SourceSecurityGroupId: ref(sourceGroupID),
+		},
+	}
+}
+
+// primaryInterface places an instance in the subnet with its security groups,
+// deciding whether it gets a public IP address whatever the subnet's default
+func primaryInterface(groupIDs []interface{}, public bool) []NetworkInterface {
+	return []NetworkInterface{{
+		DeviceIndex:              "0",
+		AssociatePublicIpAddress: public,
+		DeleteOnTermination:      true,
+		SubnetId:                 ref("SubnetId"),
+		GroupSet:                 groupIDs,
+	}}
+}
+
+// buildHeadCompute is a login node and compute nodes in one subnet, sharing an
+// EFS file system mounted at /shared. Only the head node is reachable from
+// outside; the head and compute nodes reach each other on any port.
+func buildHeadCompute(opts Options) *Template {
+	// Compute nodes have no public address and accept traffic only from the
+	// cluster, so they are reached through SSM or from the head node
+	opts.IAMRole = true
+
+	template := baseTemplate(opts)
+	addNetworkParameters(template)
+
+	computeType := opts.ComputeInstanceType
+	if computeType == "" {
+		computeType = opts.InstanceType
+	}
+	template.Parameters["ComputeInstanceType"] = Parameter{
+		Type:        "String",
+		Default:     computeType,
+		Description: "EC2 instance type for the compute nodes",
+	}
+
+	template.Resources[SecurityGroupLogicalID] = securityGroup(opts, true)
+	template.Resources[ComputeSecurityGroupLogicalID] = Resource{
+		Type: "AWS::EC2::SecurityGroup",
+		Properties: SecurityGroupProperties{
+			GroupDescription: "Security group for research compute nodes, open only to the cluster",
+			VpcId:            ref("VpcId"),
+			Tags: []Tag{
+				{Key: "Name", Value: "research-wizard-compute-sg"},
+				{Key: "Domain", Value: ref("DomainName")},
+			},
+		},
+	}
+	template.Resources[ClusterIngressLogicalID] = clusterRule(SecurityGroupLogicalID, ComputeSecurityGroupLogicalID)
+	template.Resources[ComputeHeadIngressLogicalID] = clusterRule(ComputeSecurityGroupLogicalID, SecurityGroupLogicalID)
+	template.Resources[ComputeSelfIngressLogicalID] = clusterRule(ComputeSecurityGroupLogicalID, ComputeSecurityGroupLogicalID)
+
+	template.Resources[FileSystemSecurityGroupLogicalID] = Resource{
+		Type: "AWS::EC2::SecurityGroup",
+		Properties: SecurityGroupProperties{
+			GroupDescription: "NFS access to the research shared file system",
+			VpcId:            ref("VpcId"),
+			SecurityGroupIngress: []IngressRule{
+				{IpProtocol: "tcp", FromPort: nfsPort, ToPort: nfsPort, SourceSecurityGroupId: ref(SecurityGroupLogicalID)},
+				{IpProtocol: "tcp", FromPort: nfsPort, ToPort: nfsPort, SourceSecurityGroupId: ref(ComputeSecurityGroupLogicalID)},
+			},
+			Tags: []Tag{
+				{Key: "Name", Value: "research-wizard-efs-sg"},
+				{Key: "Domain", Value: ref("DomainName")},
+			},
+		},
+	}
+	template.Resources[SharedFileSystemLogicalID] = Resource{
+		Type: "AWS::EFS::FileSystem",
+		Properties: FileSystemProperties{
+			Encrypted:       true,
+			PerformanceMode: "generalPurpose",
+			ThroughputMode:  "elastic",
+			FileSystemTags: []Tag{
+				{Key: "Name", Value: "research-wizard-shared"},
+				{Key: "Domain", Value: ref("DomainName")},
+			},
+		},
+	}
+	template.Resources[MountTargetLogicalID] = Resource{
+		Type: "AWS::EFS::MountTarget",
+		Properties: MountTargetProperties{
+			FileSystemId:   ref(SharedFileSystemLogicalID),
+			SubnetId:       ref("SubnetId"),
+			SecurityGroups: []interface{}{ref(FileSystemSecurityGroupLogicalID)},
+		},
+	}
+
+	// The head is the login node, so it gets a public address even in a subnet
+	// that does not assign one by default, which the PublicIp output needs
+	head := instance(opts, ref("InstanceType"), "research-wizard-head", sub(sharedFileSystemUserData("head")))
+	head.NetworkInterfaces = primaryInterface(head.SecurityGroupIds, true)
+	head.SecurityGroupIds = nil
+	head.Tags = append(head.Tags, Tag{Key: "Role", Value: "head"})
+	template.Resources[InstanceLogicalID] = Resource{
+		Type:       "AWS::EC2::Instance",
+		DependsOn:  []string{MountTargetLogicalID},
+		Properties: head,
+	}
+
+	if opts.PlacementGroup {
+		template.Resources[PlacementGroupLogicalID] = placementGroup()
+	}
+
+	computeIDs := make([]interface{}, 0, opts.ComputeNodes)
+	for n := 1; n <= opts.ComputeNodes; n++ {
+		node := instance(opts, ref("ComputeInstanceType"), fmt.Sprintf("research-wizard-compute-%d", n), sub(sharedFileSystemUserData("compute")))
+		node.NetworkInterfaces = primaryInterface([]interface{}{ref(ComputeSecurityGroupLogicalID)}, false)
+		node.SecurityGroupIds = nil
+		node.Tags = append(node.Tags, Tag{Key: "Role", Value: "compute"})
+		if opts.PlacementGroup {
+			node.PlacementGroupName = ref(PlacementGroupLogicalID)
+		}
+		template.Resources[ComputeNodeLogicalID(n)] = Resource{
+			Type:       "AWS::EC2::Instance",
+			DependsOn:  []string{MountTargetLogicalID},
+			Properties: node,
+		}
+		computeIDs = append(computeIDs, ref(ComputeNodeLogicalID(n)))
+	}
+
+	addInstanceRole(template, opts)
+
+	template.Outputs["SharedFileSystemId"] = Output{
+		Description: "EFS file system mounted at /shared on every node",
+		Value:       ref(SharedFileSystemLogicalID),
+	}
+	template.Outputs["ComputeNodeIds"] = Output{
+		Description: "Instance IDs of the compute nodes",
+		Value:       map[string]interface{}{"Fn::Join": []interface{}{",", computeIDs}},
+	}
+	return template
+}
+
+func validateContainerHost(opts Options) error {
+	if !opts.GPU {
+		return nil
+	}
+	family := strings.SplitN(opts.InstanceType, ".", 2)[0]
+	if !gpuInstanceFamilies[family] {
+		return fmt.Errorf("GPU container host needs a GPU instance type, got %s", opts.InstanceType)
+	}
+	return nil
+}
+
+// containerHostUserData installs Docker with the ECR credential helper, and the
+// NVIDIA container runtime for GPU hosts
+func containerHostUserData(gpu bool) string {
+	script := "#!/bin/bash\n" +
+		"yum update -y\n" +
+		"yum install -y docker git amazon-ecr-credential-helper\n" +
+		"mkdir -p /home/ec2-user/.docker\n" +
+		"echo '{\"credsStore\": \"ecr-login\"}' > /home/ec2-user/.docker/config.json\n" +
+		"chown -R ec2-user:ec2-user /home/ec2-user/.docker\n" +
+		"usermod -aG docker ec2-user\n"
+	if gpu {
+		script += "curl -s -L https://nvidia.github.io/libnvidia-container/stable/rpm/nvidia-container-toolkit.repo > /etc/yum.repos.d/nvidia-container-toolkit.repo\n" +
+			"yum install -y nvidia-container-toolkit\n" +
+			"nvidia-ctk runtime configure --runtime=docker\n"
+	}
+	return script +
+		"systemctl enable --now docker\n" +
+		"echo 'Research container host setup complete' > /tmp/setup.log\n"
+}
+
+// buildContainerHost is a single instance set up to run containers from ECR
+func buildContainerHost(opts Options) *Template {
+	// Pulling from ECR needs the instance role
+	opts.IAMRole = true
+
+	template := baseTemplate(opts)
+	template.Resources[SecurityGroupLogicalID] = securityGroup(opts, false)
+
+	properties := instance(opts, ref("InstanceType"), "research-wizard-container-host", sub(containerHostUserData(opts.GPU)))
+	if opts.PlacementGroup {
+		properties.PlacementGroupName = ref(PlacementGroupLogicalID)
+		template.Resources[PlacementGroupLogicalID] = placementGroup()
+	}
+	template.Resources[InstanceLogicalID] = Resource{Type: "AWS::EC2::Instance", Properties: properties}
+
+	addInstanceRole(template, opts, ecrReadOnlyPolicy)
+
+	template.Outputs["ContainerRegistry"] = Output{
+		Description: "ECR registry the instance can pull from",
+		Value:       sub("${AWS::AccountId}.dkr.ecr.${AWS::Region}.amazonaws.com"),
+	}
+	return template
+}
@@ -0,0 +1,56 @@
+package templates
+
+import (
+	"fmt"
+	"regexp"
+)
+
+var (
+	capacityReservationPattern = regexp.MustCompile(`^cr-[0-9a-f]{8,17}$`)
+	availabilityZonePattern    = regexp.MustCompile(`^[a-z]{2}(-[a-z]+)+-[0-9][a-z]$`)
+	resourceGroupARNPattern    = regexp.MustCompile(`^arn:aws[a-z-]*:resource-groups:[a-z0-9-]+:[0-9]{12}:group/[A-Za-z0-9_.-]+$`)
+)
+
+// validateCapacityReservation checks the capacity reservation options
+func (o Options) validateCapacityReservation() error {
+	if o.CapacityReservationID != "" && o.CapacityReservationGroupARN != "" {
+		return fmt.Errorf("target a capacity reservation or a capacity reservation group, not both")
+	}
+	if o.CapacityReservationID != "" && !capacityReservationPattern.MatchString(o.CapacityReservationID) {
+		return fmt.Errorf("invalid capacity reservation ID %q", o.CapacityReservationID)
+	}
+	if o.CapacityReservationGroupARN != "" && !resourceGroupARNPattern.MatchString(o.CapacityReservationGroupARN) {
+		return fmt.Errorf("invalid capacity reservation group %q: use the resource group ARN", o.CapacityReservationGroupARN)
+	}
+	if o.AvailabilityZone != "" {
+		if o.CapacityReservationID == "" {
+			return fmt.Errorf("an Availability Zone is only set for a capacity reservation")
+		}
+		if !availabilityZonePattern.MatchString(o.AvailabilityZone) {
+			return fmt.Errorf("invalid Availability Zone %q", o.AvailabilityZone)
+		}
+	}
+	return nil
+}
+
+// addCapacityReservation launches every instance in the template into the
+// capacity reservation or reservation group. Instances that are not placed in
+// a subnet are pinned to AvailabilityZone, the reservation's zone.
+func addCapacityReservation(template *Template, opts Options) {
+	target := CapacityReservationTarget{
+		CapacityReservationId:               opts.CapacityReservationID,
+		CapacityReservationResourceGroupArn: opts.CapacityReservationGroupARN,
+	}
+	for logicalID, resource := range template.Resources {
+		properties, ok := resource.Properties.(InstanceProperties)
+		if !ok {
+			continue
+		}
+		properties.CapacityReservationSpecification = &CapacityReservationSpecification{CapacityReservationTarget: target}
+		if properties.SubnetId == nil && len(properties.NetworkInterfaces) == 0 {
+			properties.AvailabilityZone = opts.AvailabilityZone
+		}
+		resource.Properties = properties
+		template.Resources[logicalID] = resource
+	}
+}
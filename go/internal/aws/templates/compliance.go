@@ -0,0 +1,426 @@
+package templates
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+)
+
+// ComplianceProfile names a set of security controls a research environment
+// is built to meet
+type ComplianceProfile string
+
+// Compliance profiles
+const (
+	// ComplianceNone adds no controls beyond the deploy options
+	ComplianceNone ComplianceProfile = "none"
+	// ComplianceHIPAA covers the technical safeguards for protected health information
+	ComplianceHIPAA ComplianceProfile = "hipaa"
+	// ComplianceCUI covers Controlled Unclassified Information under NIST SP 800-171
+	ComplianceCUI ComplianceProfile = "cui"
+)
+
+// complianceAliases are other names a profile can be selected by
+var complianceAliases = map[string]ComplianceProfile{
+	"nist-800-171": ComplianceCUI,
+	"nist800171":   ComplianceCUI,
+}
+
+// Logical IDs of the resources compliance profiles add
+const (
+	AuditLogBucketLogicalID          = "AuditLogBucket"
+	AuditLogBucketPolicyLogicalID    = "AuditLogBucketPolicy"
+	TrailLogicalID                   = "ResearchTrail"
+	FlowLogLogicalID                 = "ResearchFlowLog"
+	ClusterEgressLogicalID           = "ResearchSecurityGroupClusterEgress"
+	FileSystemEgressLogicalID        = "ResearchSecurityGroupFileSystemEgress"
+	ComputeHeadEgressLogicalID       = "ComputeSecurityGroupHeadEgress"
+	ComputeSelfEgressLogicalID       = "ComputeSecurityGroupSelfEgress"
+	ComputeFileSystemEgressLogicalID = "ComputeSecurityGroupFileSystemEgress"
+)
+
+// Values of the Tenancy parameter compliance profiles add
+const (
+	TenancyDefault   = "default"
+	TenancyDedicated = "dedicated"
+)
+
+const httpsPort = 443
+
+// ComplianceSettings are the settings a compliance profile forces on an
+// environment, with the controls they address and the ones left to the user
+type ComplianceSettings struct {
+	// Framework is the regulation or standard the profile follows
+	Framework           string
+	EncryptionAtRest    bool
+	EncryptionInTransit bool
+	// TenancyOption adds a Tenancy parameter so the stack can be launched on
+	// dedicated hardware
+	TenancyOption bool
+	// FlowLogs and CloudTrail write VPC traffic and API activity to an
+	// encrypted audit log bucket the stack keeps when deleted
+	FlowLogs   bool
+	CloudTrail bool
+	// NoPublicIP places instances in the given subnet without public addresses
+	NoPublicIP bool
+	// RestrictedEgress limits outbound traffic to HTTPS and the environment's
+	// own resources
+	RestrictedEgress bool
+	// PrivateIngress rejects inbound rules open to public addresses
+	PrivateIngress bool
+
+	ControlsAddressed    []string
+	UserResponsibilities []string
+}
+
+// Enforced reports whether the settings add any controls
+func (s ComplianceSettings) Enforced() bool {
+	return s.EncryptionAtRest || s.EncryptionInTransit || s.TenancyOption || s.FlowLogs ||
+		s.CloudTrail || s.NoPublicIP || s.RestrictedEgress || s.PrivateIngress
+}
+
+var complianceProfiles = map[ComplianceProfile]ComplianceSettings{
+	ComplianceNone: {},
+	ComplianceHIPAA: {
+		Framework:           "HIPAA",
+		EncryptionAtRest:    true,
+		EncryptionInTransit: true,
+		TenancyOption:       true,
+		FlowLogs:            true,
+		CloudTrail:          true,
+		NoPublicIP:          true,
+		RestrictedEgress:    true,
+		PrivateIngress:      true,
+		ControlsAddressed: []string{
+			"Encryption at rest: encrypted EBS root volumes and EFS file systems (§164.312(a)(2)(iv))",
+			"Encryption in transit: EFS mounted over TLS and a TLS-only audit log bucket (§164.312(e)(2)(ii))",
+			"Audit controls: CloudTrail with log file validation and VPC flow logs (§164.312(b))",
+			"Access control: no public IP addresses and no inbound rules open to public addresses (§164.312(a)(1))",
+			"Transmission security: outbound traffic limited to HTTPS (§164.312(e)(1))",
+		},
+		UserResponsibilities: []string{
+			"Sign the AWS Business Associate Addendum before storing PHI, and use only HIPAA eligible services",
+			"Provide a NAT gateway or VPC endpoints (SSM, S3) so instances in the private subnet can reach AWS",
+			"Manage user accounts, MFA and least-privilege IAM policies (§164.312(a)(1), (d))",
+			"Review audit logs and keep them for your retention period (§164.308(a)(1)(ii)(D))",
+			"Encrypt PHI in S3 buckets and databases outside this stack",
+			"Patch instances and back up data (§164.308(a)(7))",
+			"Launch with dedicated tenancy if your risk analysis requires it",
+		},
+	},
+	ComplianceCUI: {
+		Framework:           "NIST SP 800-171",
+		EncryptionAtRest:    true,
+		EncryptionInTransit: true,
+		TenancyOption:       true,
+		FlowLogs:            true,
+		CloudTrail:          true,
+		NoPublicIP:          true,
+		RestrictedEgress:    true,
+		PrivateIngress:      true,
+		ControlsAddressed: []string{
+			"3.13.16 Protect CUI at rest: encrypted EBS root volumes and EFS file systems",
+			"3.13.8 Protect CUI in transit: EFS mounted over TLS and a TLS-only audit log bucket",
+			"3.3.1 Create and retain audit records: CloudTrail with log file validation and VPC flow logs",
+			"3.13.1 Monitor communications at the boundary: no public IP addresses and no inbound rules open to public addresses",
+			"3.13.6 Deny network traffic by default: outbound traffic limited to HTTPS",
+		},
+		UserResponsibilities: []string{
+			"Choose a region your contract allows, e.g. AWS GovCloud (US) for export-controlled CUI",
+			"Provide a NAT gateway or VPC endpoints (SSM, S3) so instances in the private subnet can reach AWS",
+			"3.1.1 and 3.5.3 Limit access to authorized users and require MFA",
+			"3.3.8 Protect audit logs from unauthorized access and deletion",
+			"3.14.1 Patch instances and correct system flaws",
+			"3.12.4 Document this environment in your system security plan",
+			"Launch with dedicated tenancy if your contract requires it",
+		},
+	},
+}
+
+// ComplianceProfiles lists the compliance profiles
+func ComplianceProfiles() []ComplianceProfile {
+	names := make([]ComplianceProfile, 0, len(complianceProfiles))
+	for name := range complianceProfiles {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return names[i] < names[j]
+	})
+	return names
+}
+
+// ParseComplianceProfile looks up a compliance profile by name or alias; an
+// empty name selects none
+func ParseComplianceProfile(name string) (ComplianceProfile, error) {
+	key := strings.ToLower(strings.TrimSpace(name))
+	if key == "" {
+		return ComplianceNone, nil
+	}
+	if profile, exists := complianceAliases[key]; exists {
+		return profile, nil
+	}
+	if _, exists := complianceProfiles[ComplianceProfile(key)]; !exists {
+		return "", fmt.Errorf("unknown compliance profile %q (available: %s, nist-800-171)", name, complianceProfileList())
+	}
+	return ComplianceProfile(key), nil
+}
+
+// Settings returns the settings the profile forces; an empty profile is none
+func (p ComplianceProfile) Settings() ComplianceSettings {
+	return complianceProfiles[p]
+}
+
+func complianceProfileList() string {
+	names := make([]string, 0, len(complianceProfiles))
+	for _, name := range ComplianceProfiles() {
+		names = append(names, string(name))
+	}
+	return strings.Join(names, ", ")
+}
+
+// NeedsNetwork reports whether deployments must supply VpcId and SubnetId
+// parameters, because the architecture or the compliance profile places
+// resources in a specific subnet
+func (o Options) NeedsNetwork(arch Architecture) bool {
+	settings := o.Compliance.Settings()
+	return arch.NeedsNetwork() || settings.NoPublicIP || settings.FlowLogs
+}
+
+// validateCompliance checks the options can meet the compliance profile
+func (o Options) validateCompliance() error {
+	if o.Compliance == "" {
+		return nil
+	}
+	if _, exists := complianceProfiles[o.Compliance]; !exists {
+		return fmt.Errorf("unknown compliance profile %q (available: %s)", o.Compliance, complianceProfileList())
+	}
+	if o.Compliance.Settings().PrivateIngress && !o.NoSSH && !privateCIDR(o.SSHCIDR) {
+		return fmt.Errorf("the %s compliance profile does not allow SSH (port %d) and Jupyter (port %d) open to %s; connect through Session Manager or allow a private CIDR range",
+			o.Compliance, sshPort, JupyterPort, o.SSHCIDR)
+	}
+	return nil
+}
+
+// privateBlocks are the RFC 1918 and unique local address ranges
+var privateBlocks = []string{"10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "fc00::/7"}
+
+// privateCIDR reports whether a CIDR range lies inside a private address block
+func privateCIDR(cidr string) bool {
+	_, network, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return false
+	}
+	ones, _ := network.Mask.Size()
+	for _, block := range privateBlocks {
+		_, private, _ := net.ParseCIDR(block)
+		blockOnes, _ := private.Mask.Size()
+		if private.Contains(network.IP) && ones >= blockOnes {
+			return true
+		}
+	}
+	return false
+}
+
+// addComplianceControls adds the resources and settings a compliance profile
+// forces to a built template. Encryption at rest is applied to the options
+// before the architecture builds the template.
+func addComplianceControls(template *Template, settings ComplianceSettings) {
+	if settings.NoPublicIP || settings.FlowLogs {
+		if _, exists := template.Parameters["VpcId"]; !exists {
+			addNetworkParameters(template)
+		}
+	}
+	if settings.TenancyOption {
+		template.Parameters["Tenancy"] = Parameter{
+			Type:          "String",
+			Default:       TenancyDefault,
+			AllowedValues: []string{TenancyDefault, TenancyDedicated},
+			Description:   "Instance tenancy; dedicated runs instances on single-tenant hardware",
+		}
+	}
+
+	for logicalID, resource := range template.Resources {
+		switch properties := resource.Properties.(type) {
+		case InstanceProperties:
+			if settings.TenancyOption {
+				properties.Tenancy = ref("Tenancy")
+			}
+			if settings.NoPublicIP {
+				if len(properties.NetworkInterfaces) == 0 {
+					properties.NetworkInterfaces = primaryInterface(properties.SecurityGroupIds, false)
+					properties.SubnetId = nil
+					properties.SecurityGroupIds = nil
+				}
+				for i := range properties.NetworkInterfaces {
+					properties.NetworkInterfaces[i].AssociatePublicIpAddress = false
+				}
+			}
+			resource.Properties = properties
+		case SecurityGroupProperties:
+			if properties.VpcId == nil && (settings.NoPublicIP || settings.FlowLogs) {
+				properties.VpcId = ref("VpcId")
+			}
+			if settings.RestrictedEgress && (logicalID == SecurityGroupLogicalID || logicalID == ComputeSecurityGroupLogicalID) {
+				properties.SecurityGroupEgress = []EgressRule{
+					{IpProtocol: "tcp", FromPort: httpsPort, ToPort: httpsPort, CidrIp: "0.0.0.0/0"},
+				}
+			}
+			resource.Properties = properties
+		}
+		template.Resources[logicalID] = resource
+	}
+
+	if settings.RestrictedEgress {
+		addRestrictedClusterEgress(template)
+	}
+	if settings.FlowLogs || settings.CloudTrail {
+		addAuditLogging(template, settings)
+	}
+}
+
+// addRestrictedClusterEgress lets cluster nodes keep reaching each other and
+// the shared file system once outbound traffic is limited to HTTPS. The rules
+// are separate resources because they refer back to the groups they belong to.
+func addRestrictedClusterEgress(template *Template) {
+	egress := func(groupID, destinationID, protocol string, port int) Resource {
+		return Resource{
+			Type: "AWS::EC2::SecurityGroupEgress",
+			Properties: SecurityGroupEgressProperties{
+				GroupId:                    ref(groupID),
+				IpProtocol:                 protocol,
+				FromPort:                   port,
+				ToPort:                     port,
+				DestinationSecurityGroupId: ref(destinationID),
+			},
+		}
+	}
+
+	_, cluster := template.Resources[ComputeSecurityGroupLogicalID]
+	if cluster {
+		template.Resources[ClusterEgressLogicalID] = egress(SecurityGroupLogicalID, ComputeSecurityGroupLogicalID, "-1", -1)
+		template.Resources[ComputeHeadEgressLogicalID] = egress(ComputeSecurityGroupLogicalID, SecurityGroupLogicalID, "-1", -1)
+		template.Resources[ComputeSelfEgressLogicalID] = egress(ComputeSecurityGroupLogicalID, ComputeSecurityGroupLogicalID, "-1", -1)
+	}
+	if _, exists := template.Resources[FileSystemSecurityGroupLogicalID]; exists {
+		template.Resources[FileSystemEgressLogicalID] = egress(SecurityGroupLogicalID, FileSystemSecurityGroupLogicalID, "tcp", nfsPort)
+		if cluster {
+			template.Resources[ComputeFileSystemEgressLogicalID] = egress(ComputeSecurityGroupLogicalID, FileSystemSecurityGroupLogicalID, "tcp", nfsPort)
+		}
+	}
+}
+
+// addAuditLogging adds the audit log bucket and the CloudTrail trail and VPC
+// flow log writing to it. The bucket is retained when the stack is deleted so
+// the audit trail outlives the environment.
+func addAuditLogging(template *Template, settings ComplianceSettings) {
+	template.Resources[AuditLogBucketLogicalID] = Resource{
+		Type: "AWS::S3::Bucket",
+		Properties: BucketProperties{
+			BucketEncryption: BucketEncryption{
+				ServerSideEncryptionConfiguration: []ServerSideEncryptionRule{
+					{ServerSideEncryptionByDefault: ServerSideEncryptionByDefault{SSEAlgorithm: "AES256"}},
+				},
+			},
+			PublicAccessBlockConfiguration: PublicAccessBlockConfiguration{
+				BlockPublicAcls:       true,
+				BlockPublicPolicy:     true,
+				IgnorePublicAcls:      true,
+				RestrictPublicBuckets: true,
+			},
+			VersioningConfiguration: VersioningConfiguration{Status: "Enabled"},
+			Tags: []Tag{
+				{Key: "Domain", Value: ref("DomainName")},
+			},
+		},
+		DeletionPolicy:      "Retain",
+		UpdateReplacePolicy: "Retain",
+	}
+
+	var services []string
+	if settings.CloudTrail {
+		services = append(services, "cloudtrail.amazonaws.com")
+	}
+	if settings.FlowLogs {
+		services = append(services, "delivery.logs.amazonaws.com")
+	}
+	bucketARN := getAtt(AuditLogBucketLogicalID, "Arn")
+	statements := []map[string]interface{}{
+		{
+			"Sid":       "AuditLogAclCheck",
+			"Effect":    "Allow",
+			"Principal": map[string]interface{}{"Service": services},
+			"Action":    "s3:GetBucketAcl",
+			"Resource":  bucketARN,
+		},
+		{
+			"Sid":       "AuditLogWrite",
+			"Effect":    "Allow",
+			"Principal": map[string]interface{}{"Service": services},
+			"Action":    "s3:PutObject",
+			"Resource":  sub("${" + AuditLogBucketLogicalID + ".Arn}/AWSLogs/${AWS::AccountId}/*"),
+			"Condition": map[string]interface{}{
+				"StringEquals": map[string]string{"s3:x-amz-acl": "bucket-owner-full-control"},
+			},
+		},
+	}
+	if settings.EncryptionInTransit {
+		statements = append(statements, map[string]interface{}{
+			"Sid":       "DenyInsecureTransport",
+			"Effect":    "Deny",
+			"Principal": "*",
+			"Action":    "s3:*",
+			"Resource":  []interface{}{bucketARN, sub("${" + AuditLogBucketLogicalID + ".Arn}/*")},
+			"Condition": map[string]interface{}{
+				"Bool": map[string]string{"aws:SecureTransport": "false"},
+			},
+		})
+	}
+	template.Resources[AuditLogBucketPolicyLogicalID] = Resource{
+		Type: "AWS::S3::BucketPolicy",
+		Properties: BucketPolicyProperties{
+			Bucket: ref(AuditLogBucketLogicalID),
+			PolicyDocument: map[string]interface{}{
+				"Version":   "2012-10-17",
+				"Statement": statements,
+			},
+		},
+	}
+
+	if settings.CloudTrail {
+		template.Resources[TrailLogicalID] = Resource{
+			Type:      "AWS::CloudTrail::Trail",
+			DependsOn: []string{AuditLogBucketPolicyLogicalID},
+			Properties: TrailProperties{
+				IsLogging:                  true,
+				S3BucketName:               ref(AuditLogBucketLogicalID),
+				EnableLogFileValidation:    true,
+				IncludeGlobalServiceEvents: true,
+				Tags: []Tag{
+					{Key: "Domain", Value: ref("DomainName")},
+				},
+			},
+		}
+	}
+	if settings.FlowLogs {
+		template.Resources[FlowLogLogicalID] = Resource{
+			Type:      "AWS::EC2::FlowLog",
+			DependsOn: []string{AuditLogBucketPolicyLogicalID},
+			Properties: FlowLogProperties{
+				ResourceId:         ref("VpcId"),
+				ResourceType:       "VPC",
+				TrafficType:        "ALL",
+				LogDestinationType: "s3",
+				LogDestination:     bucketARN,
+				Tags: []Tag{
+					{Key: "Domain", Value: ref("DomainName")},
+				},
+			},
+		}
+	}
+
+	template.Outputs["AuditLogBucket"] = Output{
+		Description: "S3 bucket holding CloudTrail and VPC flow logs; kept when the stack is deleted",
+		Value:       ref(AuditLogBucketLogicalID),
+	}
+}
@@ -0,0 +1,155 @@
+package templates
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestComplianceProfileSettings(t *testing.T) {
+	tests := []struct {
+		name      string
+		profile   string
+		want      ComplianceProfile
+		framework string
+		// enforced profiles force every setting and list their controls
+		enforced bool
+	}{
+		{name: "empty is none", profile: "", want: ComplianceNone},
+		{name: "none", profile: "none", want: ComplianceNone},
+		{name: "hipaa", profile: "hipaa", want: ComplianceHIPAA, framework: "HIPAA", enforced: true},
+		{name: "hipaa any case", profile: " HIPAA ", want: ComplianceHIPAA, framework: "HIPAA", enforced: true},
+		{name: "cui", profile: "cui", want: ComplianceCUI, framework: "NIST SP 800-171", enforced: true},
+		{name: "nist alias", profile: "NIST-800-171", want: ComplianceCUI, framework: "NIST SP 800-171", enforced: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			profile, err := ParseComplianceProfile(tt.profile)
+			if err != nil || profile != tt.want {
+				t.Fatalf("ParseComplianceProfile(%q) = %q, %v; want %q", tt.profile, profile, err, tt.want)
+			}
+
+			settings := profile.Settings()
+			if settings.Framework != tt.framework {
+				t.Errorf("Framework = %q, want %q", settings.Framework, tt.framework)
+			}
+			if settings.Enforced() != tt.enforced {
+				t.Errorf("Enforced() = %v, want %v", settings.Enforced(), tt.enforced)
+			}
+			for name, on := range map[string]bool{
+				"EncryptionAtRest":    settings.EncryptionAtRest,
+				"EncryptionInTransit": settings.EncryptionInTransit,
+				"TenancyOption":       settings.TenancyOption,
+				"FlowLogs":            settings.FlowLogs,
+				"CloudTrail":          settings.CloudTrail,
+				"NoPublicIP":          settings.NoPublicIP,
+				"RestrictedEgress":    settings.RestrictedEgress,
+				"PrivateIngress":      settings.PrivateIngress,
+			} {
+				if on != tt.enforced {
+					t.Errorf("%s = %v, want %v", name, on, tt.enforced)
+				}
+			}
+			if got := len(settings.ControlsAddressed) > 0 && len(settings.UserResponsibilities) > 0; got != tt.enforced {
+				t.Errorf("controls listed = %v, want %v", got, tt.enforced)
+			}
+
+			opts := testOptions("r6i.4xlarge")
+			opts.Compliance = profile
+			if opts.NeedsNetwork(ArchitectureSingle) != tt.enforced {
+				t.Errorf("NeedsNetwork(single) = %v, want %v", opts.NeedsNetwork(ArchitectureSingle), tt.enforced)
+			}
+		})
+	}
+
+	if _, err := ParseComplianceProfile("pci"); err == nil || !strings.Contains(err.Error(), "hipaa") {
+		t.Errorf("expected an error listing the profiles, got %v", err)
+	}
+}
+
+func TestBuildCompliance(t *testing.T) {
+	for _, arch := range Architectures() {
+		opts := testOptions("m6i.xlarge")
+		opts.Compliance = ComplianceHIPAA
+		opts.NoSSH = true
+		result := build(t, arch, opts)
+
+		for _, logicalID := range []string{AuditLogBucketLogicalID, AuditLogBucketPolicyLogicalID, TrailLogicalID, FlowLogLogicalID} {
+			if _, exists := result.Resources[logicalID]; !exists {
+				t.Errorf("%s: missing %s", arch, logicalID)
+			}
+		}
+		for _, key := range []string{"VpcId", "SubnetId", "Tenancy"} {
+			if _, exists := result.Parameters[key]; !exists {
+				t.Errorf("%s: missing %s parameter", arch, key)
+			}
+		}
+
+		instance := result.Resources[InstanceLogicalID].Properties
+		if _, exists := instance["SecurityGroupIds"]; exists {
+			t.Errorf("%s: security groups should move to the network interface", arch)
+		}
+		nic := instance["NetworkInterfaces"].([]interface{})[0].(map[string]interface{})
+		if nic["AssociatePublicIpAddress"] != false {
+			t.Errorf("%s: instance gets a public IP address: %v", arch, nic)
+		}
+		volume := instance["BlockDeviceMappings"].([]interface{})[0].(map[string]interface{})["Ebs"].(map[string]interface{})
+		if volume["Encrypted"] != true {
+			t.Errorf("%s: root volume is not encrypted", arch)
+		}
+
+		group := result.Resources[SecurityGroupLogicalID].Properties
+		if group["VpcId"] == nil {
+			t.Errorf("%s: security group is not in the VPC parameter", arch)
+		}
+		egress := group["SecurityGroupEgress"].([]interface{})
+		if len(egress) != 1 || egress[0].(map[string]interface{})["FromPort"] != float64(httpsPort) {
+			t.Errorf("%s: egress = %v, want HTTPS only", arch, egress)
+		}
+		if _, exists := result.Outputs["PublicIP"]; exists {
+			t.Errorf("%s: PublicIP output without a public IP address", arch)
+		}
+	}
+
+	// Cluster nodes keep reaching each other and the file system
+	opts := testOptions("r6i.4xlarge")
+	opts.Compliance = ComplianceCUI
+	opts.NoSSH = true
+	result := build(t, ArchitectureHeadCompute, opts)
+	for _, logicalID := range []string{ClusterEgressLogicalID, FileSystemEgressLogicalID} {
+		if result.Resources[logicalID].Type != "AWS::EC2::SecurityGroupEgress" {
+			t.Errorf("missing %s", logicalID)
+		}
+	}
+
+	// Without a profile nothing is added
+	result = build(t, ArchitectureSingle, testOptions("r6i.4xlarge"))
+	for _, logicalID := range []string{AuditLogBucketLogicalID, TrailLogicalID, FlowLogLogicalID} {
+		if _, exists := result.Resources[logicalID]; exists {
+			t.Errorf("unexpected %s without a compliance profile", logicalID)
+		}
+	}
+	if _, exists := result.Parameters["Tenancy"]; exists {
+		t.Error("unexpected Tenancy parameter without a compliance profile")
+	}
+}
+
+func TestPrivateCIDR(t *testing.T) {
+	tests := map[string]bool{
+		"10.0.0.0/8":      true,
+		"10.20.30.0/24":   true,
+		"172.16.0.0/12":   true,
+		"172.32.0.0/16":   false,
+		"192.168.1.10/32": true,
+		"0.0.0.0/0":       false,
+		"10.0.0.0/7":      false,
+		"198.51.100.0/24": false,
+		"fd00:1::/64":     true,
+		"not-a-cidr":      false,
+	}
+	for cidr, want := range tests {
+		if got := privateCIDR(cidr); got != want {
+			t.Errorf("privateCIDR(%q) = %v, want %v", cidr, got, want)
+		}
+	}
+}
@@ -0,0 +1,301 @@
+package templates
+
+import (
+	"fmt"
+
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/aws"
+)
+
+// Logical IDs shared by the architectures. The research instance is the login
+// node where an architecture has several instances, so stack outputs keep
+// pointing at the machine users connect to.
+const (
+	SecurityGroupLogicalID           = "ResearchSecurityGroup"
+	ClusterIngressLogicalID          = "ResearchSecurityGroupClusterIngress"
+	ComputeSecurityGroupLogicalID    = "ComputeSecurityGroup"
+	ComputeHeadIngressLogicalID      = "ComputeSecurityGroupHeadIngress"
+	ComputeSelfIngressLogicalID      = "ComputeSecurityGroupSelfIngress"
+	InstanceLogicalID                = "ResearchInstance"
+	InstanceRoleLogicalID            = "ResearchInstanceRole"
+	InstanceProfileLogicalID         = "ResearchInstanceProfile"
+	PlacementGroupLogicalID          = "ResearchPlacementGroup"
+	SharedFileSystemLogicalID        = "SharedFileSystem"
+	FileSystemSecurityGroupLogicalID = "SharedFileSystemSecurityGroup"
+	MountTargetLogicalID             = "SharedFileSystemMountTarget"
+)
+
+// Ports opened by the research security group
+const (
+	sshPort     = 22
+	JupyterPort = 8888
+	nfsPort     = 2049
+)
+
+// ssmManagedPolicy lets Session Manager and Run Command reach the instance
+const ssmManagedPolicy = "arn:aws:iam::aws:policy/AmazonSSMManagedInstanceCore"
+
+// ResearchUserData is the boot script of the single-instance research environment
+const ResearchUserData = "#!/bin/bash\nyum update -y\nyum install -y docker git\nservice docker start\necho 'Research environment setup complete' > /tmp/setup.log\n"
+
+// baseTemplate starts a template with the parameters every architecture takes
+func baseTemplate(opts Options) *Template {
+	template := &Template{
+		AWSTemplateFormatVersion: templateFormatVersion,
+		Description:              opts.Description,
+		Parameters: map[string]Parameter{
+			"InstanceType": {
+				Type:        "String",
+				Default:     opts.InstanceType,
+				Description: "EC2 instance type for the research environment",
+			},
+			"DomainName": {
+				Type:        "String",
+				Default:     opts.DomainName,
+				Description: "Research domain name",
+			},
+		},
+		Resources: make(map[string]Resource),
+		Outputs:   instanceOutputs(opts),
+	}
+	if !opts.NoSSH {
+		template.Parameters["KeyName"] = Parameter{
+			Type:        "AWS::EC2::KeyPair::KeyName",
+			Description: "EC2 Key Pair for SSH access",
+		}
+	}
+	return template
+}
+
+// addNetworkParameters adds the VPC and subnet parameters for architectures that
+// place resources in a specific subnet
+func addNetworkParameters(template *Template) {
+	template.Parameters["VpcId"] = Parameter{
+		Type:        "AWS::EC2::VPC::Id",
+		Description: "VPC for the research environment",
+	}
+	template.Parameters["SubnetId"] = Parameter{
+		Type:        "AWS::EC2::Subnet::Id",
+		Description: "Subnet in the VPC for instances and the file system mount target",
+	}
+}
+
+// addInstanceRole adds the IAM role and instance profile. The role gets the given
+// policies plus the options' policies, or the SSM policy when the options name none.
+func addInstanceRole(template *Template, opts Options, policies ...string) {
+	template.Resources[InstanceRoleLogicalID] = iamRole(opts, policies)
+	template.Resources[InstanceProfileLogicalID] = instanceProfile()
+}
+
+// securityGroup opens SSH and Jupyter to the SSH CIDR. Without SSH it has no
+// inbound rules; Jupyter is then reached by Session Manager port forwarding.
+func securityGroup(opts Options, inVPC bool) Resource {
+	properties := SecurityGroupProperties{
+		GroupDescription: "Security group for research environment",
+		Tags: []Tag{
+			{Key: "Name", Value: "research-wizard-sg"},
+			{Key: "Domain", Value: ref("DomainName")},
+		},
+	}
+	if !opts.NoSSH {
+		properties.SecurityGroupIngress = []IngressRule{
+			{IpProtocol: "tcp", FromPort: sshPort, ToPort: sshPort, CidrIp: opts.SSHCIDR},
+			{IpProtocol: "tcp", FromPort: JupyterPort, ToPort: JupyterPort, CidrIp: opts.SSHCIDR},
+		}
+	}
+	if inVPC {
+		properties.VpcId = ref("VpcId")
+	}
+
+	return Resource{
+		Type:       "AWS::EC2::SecurityGroup",
+		Properties: properties,
+	}
+}
+
+// instance builds an EC2 instance in the research security group
+func instance(opts Options, instanceType interface{}, name string, userData interface{}) InstanceProperties {
+	properties := InstanceProperties{
+		InstanceType:     instanceType,
+		ImageId:          opts.ResolvedImageID(),
+		SecurityGroupIds: []interface{}{ref(SecurityGroupLogicalID)},
+		BlockDeviceMappings: []BlockDeviceMapping{
+			{
+				DeviceName: "/dev/xvda",
+				Ebs: EBSVolume{
+					VolumeSize: opts.VolumeSizeGB,
+					VolumeType: "gp3",
+					Encrypted:  opts.EncryptVolume,
+				},
+			},
+		},
+		UserData: base64(userData),
+		Tags:     instanceTags(name),
+	}
+
+	if !opts.NoSSH {
+		properties.KeyName = ref("KeyName")
+	}
+	if opts.IAMRole {
+		properties.IamInstanceProfile = ref(InstanceProfileLogicalID)
+	}
+	return properties
+}
+
+func instanceTags(name string) []Tag {
+	return []Tag{
+		{Key: "Name", Value: name},
+		{Key: "Domain", Value: ref("DomainName")},
+		{Key: "CreatedBy", Value: "AWS-Research-Wizard"},
+	}
+}
+
+// InstanceRolePolicies returns the managed policies attached to the instance
+// role: the options' policies, or the SSM policy when they name none, followed
+// by the given policies without duplicates. Without SSH the SSM policy is
+// always attached, since Session Manager is the only way in.
+func InstanceRolePolicies(opts Options, policies ...string) []string {
+	managed := []string{ssmManagedPolicy}
+	seen := map[string]bool{ssmManagedPolicy: true}
+	if len(opts.ManagedPolicies) > 0 && !opts.NoSSH {
+		managed, seen = nil, make(map[string]bool)
+	}
+	for _, policy := range append(opts.ManagedPolicies, policies...) {
+		if !seen[policy] {
+			seen[policy] = true
+			managed = append(managed, policy)
+		}
+	}
+	return managed
+}
+
+func iamRole(opts Options, policies []string) Resource {
+	managed := InstanceRolePolicies(opts, policies...)
+
+	return Resource{
+		Type: "AWS::IAM::Role",
+		Properties: IAMRoleProperties{
+			AssumeRolePolicyDocument: map[string]interface{}{
+				"Version": "2012-10-17",
+				"Statement": []map[string]interface{}{
+					{
+						"Effect":    "Allow",
+						"Principal": map[string]interface{}{"Service": "ec2.amazonaws.com"},
+						"Action":    "sts:AssumeRole",
+					},
+				},
+			},
+			ManagedPolicyArns: managed,
+			Tags: []Tag{
+				{Key: "Domain", Value: ref("DomainName")},
+			},
+		},
+	}
+}
+
+func instanceProfile() Resource {
+	return Resource{
+		Type: "AWS::IAM::InstanceProfile",
+		Properties: InstanceProfileProperties{
+			Roles: []interface{}{ref(InstanceRoleLogicalID)},
+		},
+	}
+}
+
+func placementGroup() Resource {
+	return Resource{
+		Type:       "AWS::EC2::PlacementGroup",
+		Properties: PlacementGroupProperties{Strategy: "cluster"},
+	}
+}
+
+// idleStopPeriod is the CloudWatch period, in seconds, of idle stop alarms
+const idleStopPeriod = 300
+
+// IdleStopAlarmLogicalID returns the logical ID of the idle stop alarm for an instance
+func IdleStopAlarmLogicalID(instanceLogicalID string) string {
+	return instanceLogicalID + "IdleStopAlarm"
+}
+
+// addIdleStopAlarms adds an alarm to every instance in the template that stops it
+// once its CPU has stayed idle for the configured time
+func addIdleStopAlarms(template *Template, opts Options) {
+	threshold := opts.IdleCPUPercent
+	if threshold == 0 {
+		threshold = DefaultIdleCPUPercent
+	}
+	periods := (opts.IdleStopMinutes*60 + idleStopPeriod - 1) / idleStopPeriod
+
+	var instances []string
+	for logicalID, resource := range template.Resources {
+		if resource.Type == "AWS::EC2::Instance" {
+			instances = append(instances, logicalID)
+		}
+	}
+
+	for _, logicalID := range instances {
+		template.Resources[IdleStopAlarmLogicalID(logicalID)] = Resource{
+			Type: "AWS::CloudWatch::Alarm",
+			Properties: AlarmProperties{
+				AlarmDescription:   fmt.Sprintf("Stop %s after %d idle minutes", logicalID, opts.IdleStopMinutes),
+				Namespace:          "AWS/EC2",
+				MetricName:         "CPUUtilization",
+				Dimensions:         []MetricDimension{{Name: "InstanceId", Value: ref(logicalID)}},
+				Statistic:          "Average",
+				Period:             idleStopPeriod,
+				EvaluationPeriods:  periods,
+				Threshold:          threshold,
+				ComparisonOperator: "LessThanThreshold",
+				AlarmActions:       []interface{}{sub("arn:aws:automate:${AWS::Region}:ec2:stop")},
+			},
+		}
+	}
+}
+
+// instanceOutputs are the outputs other commands read from every research
+// stack, with the command to connect by SSH or by Session Manager. Instances
+// without a public IP address are reached by SSH on their private address.
+func instanceOutputs(opts Options) map[string]Output {
+	outputs := map[string]Output{
+		aws.OutputInstanceID: {
+			Description: "Instance ID of the research environment",
+			Value:       ref(InstanceLogicalID),
+		},
+		aws.OutputPrivateIP: {
+			Description: "Private IP address of the research environment",
+			Value:       getAtt(InstanceLogicalID, "PrivateIp"),
+		},
+		aws.OutputSecurityGroupID: {
+			Description: "Security Group ID",
+			Value:       ref(SecurityGroupLogicalID),
+		},
+	}
+	sshAddress := "PrivateIp"
+	if !opts.Compliance.Settings().NoPublicIP {
+		sshAddress = "PublicIp"
+		outputs[aws.OutputPublicIP] = Output{
+			Description: "Public IP address of the research environment",
+			Value:       getAtt(InstanceLogicalID, "PublicIp"),
+		}
+	}
+
+	if opts.NoSSH {
+		outputs[aws.OutputConnectivity] = Output{
+			Description: "How users connect to the instance",
+			Value:       aws.ConnectivitySSM,
+		}
+		outputs[aws.OutputSSMCommand] = Output{
+			Description: "Session Manager command to connect to the instance",
+			Value:       sub("aws ssm start-session --target ${" + InstanceLogicalID + "} --region ${AWS::Region}"),
+		}
+		return outputs
+	}
+	outputs[aws.OutputConnectivity] = Output{
+		Description: "How users connect to the instance",
+		Value:       aws.ConnectivitySSH,
+	}
+	outputs[aws.OutputSSHCommand] = Output{
+		Description: "SSH command to connect to the instance",
+		Value:       sub("ssh -i ~/.ssh/${KeyName}.pem ec2-user@${" + InstanceLogicalID + "." + sshAddress + "}"),
+	}
+	return outputs
+}
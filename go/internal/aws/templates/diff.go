@@ -0,0 +1,353 @@
+package templates
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ChangeAction is what an update does to a resource
+type ChangeAction string
+
+// Change actions, named as CloudFormation change sets describe them
+const (
+	ChangeAdd     ChangeAction = "add"
+	ChangeModify  ChangeAction = "modify-in-place"
+	ChangeReplace ChangeAction = "replace"
+	ChangeDelete  ChangeAction = "delete"
+)
+
+// UpdateBehavior is how CloudFormation applies a change to one property
+type UpdateBehavior string
+
+// Update behaviors from the "Update requires" notes of the resource reference
+const (
+	UpdateNoInterruption UpdateBehavior = "no-interruption"
+	// UpdateInterruption stops and restarts the resource, as changing the type of an EBS-backed instance does
+	UpdateInterruption UpdateBehavior = "interruption"
+	UpdateReplacement  UpdateBehavior = "replacement"
+)
+
+// updateBehaviors lists the properties whose changes interrupt or replace the
+// resource, per resource type. Properties not listed update without interruption.
+var updateBehaviors = map[string]map[string]UpdateBehavior{
+	"AWS::EC2::Instance": {
+		"AvailabilityZone":    UpdateReplacement,
+		"BlockDeviceMappings": UpdateReplacement,
+		"CpuOptions":          UpdateReplacement,
+		"HibernationOptions":  UpdateReplacement,
+		"ImageId":             UpdateReplacement,
+		"KeyName":             UpdateReplacement,
+		"LaunchTemplate":      UpdateReplacement,
+		"NetworkInterfaces":   UpdateReplacement,
+		"PlacementGroupName":  UpdateReplacement,
+		"PrivateIpAddress":    UpdateReplacement,
+		"SecurityGroups":      UpdateReplacement,
+		"SubnetId":            UpdateReplacement,
+		"Tenancy":             UpdateReplacement,
+		"EbsOptimized":        UpdateInterruption,
+		"InstanceType":        UpdateInterruption,
+		"UserData":            UpdateInterruption,
+	},
+	"AWS::EC2::SecurityGroup": {
+		"GroupDescription": UpdateReplacement,
+		"GroupName":        UpdateReplacement,
+		"VpcId":            UpdateReplacement,
+	},
+	"AWS::EC2::SecurityGroupIngress": {
+		"CidrIp":                UpdateReplacement,
+		"FromPort":              UpdateReplacement,
+		"GroupId":               UpdateReplacement,
+		"IpProtocol":            UpdateReplacement,
+		"SourceSecurityGroupId": UpdateReplacement,
+		"ToPort":                UpdateReplacement,
+	},
+	"AWS::EC2::PlacementGroup": {
+		"PartitionCount": UpdateReplacement,
+		"SpreadLevel":    UpdateReplacement,
+		"Strategy":       UpdateReplacement,
+	},
+	"AWS::EC2::Volume": {
+		"Encrypted":  UpdateReplacement,
+		"SnapshotId": UpdateReplacement,
+	},
+	"AWS::EFS::FileSystem": {
+		"AvailabilityZoneName": UpdateReplacement,
+		"Encrypted":            UpdateReplacement,
+		"KmsKeyId":             UpdateReplacement,
+		"PerformanceMode":      UpdateReplacement,
+	},
+	"AWS::EFS::MountTarget": {
+		"FileSystemId": UpdateReplacement,
+		"IpAddress":    UpdateReplacement,
+		"SubnetId":     UpdateReplacement,
+	},
+	"AWS::IAM::Role": {
+		"Path":     UpdateReplacement,
+		"RoleName": UpdateReplacement,
+	},
+	"AWS::IAM::InstanceProfile": {
+		"InstanceProfileName": UpdateReplacement,
+		"Path":                UpdateReplacement,
+	},
+	"AWS::CloudWatch::Alarm": {
+		"AlarmName": UpdateReplacement,
+	},
+}
+
+// PropertyUpdateBehavior returns how a change at path, a property path such as
+// BlockDeviceMappings[0].Ebs.VolumeSize, updates a resource of the given type
+func PropertyUpdateBehavior(resourceType, path string) UpdateBehavior {
+	property := path
+	if i := strings.IndexAny(property, ".["); i >= 0 {
+		property = property[:i]
+	}
+
+	// Only DeleteOnTermination can change without replacing the instance's block devices
+	if resourceType == "AWS::EC2::Instance" && property == "BlockDeviceMappings" && strings.HasSuffix(path, ".DeleteOnTermination") {
+		return UpdateNoInterruption
+	}
+
+	if behavior, exists := updateBehaviors[resourceType][property]; exists {
+		return behavior
+	}
+	return UpdateNoInterruption
+}
+
+// PropertyChange is one changed value in a resource's properties
+type PropertyChange struct {
+	Path     string         `json:"path"`
+	Old      interface{}    `json:"old,omitempty"`
+	New      interface{}    `json:"new,omitempty"`
+	Behavior UpdateBehavior `json:"behavior"`
+}
+
+// ResourceChange is what an update does to one resource
+type ResourceChange struct {
+	LogicalID  string           `json:"logical_id"`
+	Type       string           `json:"type"`
+	Action     ChangeAction     `json:"action"`
+	Properties []PropertyChange `json:"properties,omitempty"`
+}
+
+// Interrupts reports whether a modification stops and restarts the resource
+func (c ResourceChange) Interrupts() bool {
+	for _, property := range c.Properties {
+		if property.Behavior == UpdateInterruption {
+			return true
+		}
+	}
+	return false
+}
+
+// ParameterChange is a changed stack parameter value
+type ParameterChange struct {
+	Name string `json:"name"`
+	Old  string `json:"old"`
+	New  string `json:"new"`
+}
+
+// StackDiff is the difference between a live stack and the template and
+// parameters that would replace it
+type StackDiff struct {
+	Parameters []ParameterChange `json:"parameters"`
+	Resources  []ResourceChange  `json:"resources"`
+}
+
+// HasChanges reports whether an update would change anything
+func (d *StackDiff) HasChanges() bool {
+	return len(d.Parameters) > 0 || len(d.Resources) > 0
+}
+
+// Count returns the number of resource changes with the given action
+func (d *StackDiff) Count(action ChangeAction) int {
+	count := 0
+	for _, change := range d.Resources {
+		if change.Action == action {
+			count++
+		}
+	}
+	return count
+}
+
+// stackDocument is the part of a template the diff compares
+type stackDocument struct {
+	Parameters map[string]struct {
+		Default interface{} `json:"Default"`
+	} `json:"Parameters"`
+	Resources map[string]struct {
+		Type       string                 `json:"Type"`
+		Properties map[string]interface{} `json:"Properties"`
+	} `json:"Resources"`
+}
+
+// DiffStack compares a live stack's template and parameters with the ones a
+// redeploy would use. References to parameters are resolved first, so a new
+// parameter value shows up on the properties that use it.
+func DiffStack(liveBody string, liveParameters map[string]string, localBody string, localParameters map[string]string) (*StackDiff, error) {
+	var live, local stackDocument
+	if err := json.Unmarshal([]byte(liveBody), &live); err != nil {
+		return nil, fmt.Errorf("failed to parse live template: %w", err)
+	}
+	if err := json.Unmarshal([]byte(localBody), &local); err != nil {
+		return nil, fmt.Errorf("failed to parse local template: %w", err)
+	}
+
+	liveValues := parameterValues(live, liveParameters)
+	localValues := parameterValues(local, localParameters)
+
+	diff := &StackDiff{
+		Parameters: diffParameters(liveValues, localValues),
+		Resources:  []ResourceChange{},
+	}
+
+	for _, logicalID := range unionKeys(live.Resources, local.Resources) {
+		before, inLive := live.Resources[logicalID]
+		after, inLocal := local.Resources[logicalID]
+
+		switch {
+		case !inLive:
+			diff.Resources = append(diff.Resources, ResourceChange{LogicalID: logicalID, Type: after.Type, Action: ChangeAdd})
+		case !inLocal:
+			diff.Resources = append(diff.Resources, ResourceChange{LogicalID: logicalID, Type: before.Type, Action: ChangeDelete})
+		case before.Type != after.Type:
+			diff.Resources = append(diff.Resources, ResourceChange{LogicalID: logicalID, Type: after.Type, Action: ChangeReplace})
+		default:
+			var properties []PropertyChange
+			diffValues("", resolveRefs(before.Properties, liveValues), resolveRefs(after.Properties, localValues), func(path string, old, new interface{}) {
+				properties = append(properties, PropertyChange{
+					Path:     path,
+					Old:      old,
+					New:      new,
+					Behavior: PropertyUpdateBehavior(after.Type, path),
+				})
+			})
+			if len(properties) == 0 {
+				continue
+			}
+
+			action := ChangeModify
+			for _, property := range properties {
+				if property.Behavior == UpdateReplacement {
+					action = ChangeReplace
+				}
+			}
+			diff.Resources = append(diff.Resources, ResourceChange{LogicalID: logicalID, Type: after.Type, Action: action, Properties: properties})
+		}
+	}
+	return diff, nil
+}
+
+// parameterValues returns the value of every template parameter, falling back
+// to its default
+func parameterValues(document stackDocument, values map[string]string) map[string]string {
+	resolved := make(map[string]string, len(document.Parameters))
+	for name, parameter := range document.Parameters {
+		if parameter.Default != nil {
+			resolved[name] = fmt.Sprint(parameter.Default)
+		}
+	}
+	for name, value := range values {
+		if _, declared := document.Parameters[name]; declared {
+			resolved[name] = value
+		}
+	}
+	return resolved
+}
+
+func diffParameters(live, local map[string]string) []ParameterChange {
+	changes := []ParameterChange{}
+	for _, name := range unionKeys(live, local) {
+		if live[name] != local[name] {
+			changes = append(changes, ParameterChange{Name: name, Old: live[name], New: local[name]})
+		}
+	}
+	return changes
+}
+
+// resolveRefs replaces {"Ref": parameter} with the parameter's value
+func resolveRefs(value interface{}, parameters map[string]string) interface{} {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		if name, isRef := typed["Ref"].(string); isRef && len(typed) == 1 {
+			if resolved, exists := parameters[name]; exists {
+				return resolved
+			}
+		}
+		resolved := make(map[string]interface{}, len(typed))
+		for key, child := range typed {
+			resolved[key] = resolveRefs(child, parameters)
+		}
+		return resolved
+	case []interface{}:
+		resolved := make([]interface{}, len(typed))
+		for i, child := range typed {
+			resolved[i] = resolveRefs(child, parameters)
+		}
+		return resolved
+	default:
+		return value
+	}
+}
+
+// diffValues reports the changed leaves between two decoded JSON values.
+// Intrinsic functions such as {"Fn::Sub": ...} are compared whole.
+func diffValues(path string, old, new interface{}, report func(path string, old, new interface{})) {
+	if reflect.DeepEqual(old, new) {
+		return
+	}
+
+	oldMap, oldIsMap := old.(map[string]interface{})
+	newMap, newIsMap := new.(map[string]interface{})
+	if oldIsMap && newIsMap && !isIntrinsic(oldMap) && !isIntrinsic(newMap) {
+		for _, key := range unionKeys(oldMap, newMap) {
+			diffValues(joinPath(path, key), oldMap[key], newMap[key], report)
+		}
+		return
+	}
+
+	oldList, oldIsList := old.([]interface{})
+	newList, newIsList := new.([]interface{})
+	if oldIsList && newIsList && len(oldList) == len(newList) {
+		for i := range oldList {
+			diffValues(fmt.Sprintf("%s[%d]", path, i), oldList[i], newList[i], report)
+		}
+		return
+	}
+
+	report(path, old, new)
+}
+
+func isIntrinsic(value map[string]interface{}) bool {
+	if len(value) != 1 {
+		return false
+	}
+	for key := range value {
+		return key == "Ref" || strings.HasPrefix(key, "Fn::")
+	}
+	return false
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// unionKeys returns the keys of both maps in order
+func unionKeys[V1, V2 any](a map[string]V1, b map[string]V2) []string {
+	keys := make([]string, 0, len(a)+len(b))
+	for key := range a {
+		keys = append(keys, key)
+	}
+	for key := range b {
+		if _, exists := a[key]; !exists {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
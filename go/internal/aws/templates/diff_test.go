@@ -0,0 +1,153 @@
+package templates
+
+import (
+	"testing"
+)
+
+func TestPropertyUpdateBehavior(t *testing.T) {
+	tests := []struct {
+		resourceType string
+		path         string
+		want         UpdateBehavior
+	}{
+		{"AWS::EC2::Instance", "ImageId", UpdateReplacement},
+		{"AWS::EC2::Instance", "KeyName", UpdateReplacement},
+		{"AWS::EC2::Instance", "SubnetId", UpdateReplacement},
+		{"AWS::EC2::Instance", "BlockDeviceMappings[0].Ebs.VolumeSize", UpdateReplacement},
+		{"AWS::EC2::Instance", "BlockDeviceMappings[0].Ebs.Encrypted", UpdateReplacement},
+		{"AWS::EC2::Instance", "BlockDeviceMappings[0].Ebs.DeleteOnTermination", UpdateNoInterruption},
+		{"AWS::EC2::Instance", "InstanceType", UpdateInterruption},
+		{"AWS::EC2::Instance", "UserData", UpdateInterruption},
+		{"AWS::EC2::Instance", "SecurityGroupIds[0]", UpdateNoInterruption},
+		{"AWS::EC2::Instance", "Tags[1].Value", UpdateNoInterruption},
+		{"AWS::EC2::Instance", "IamInstanceProfile", UpdateNoInterruption},
+		{"AWS::EC2::SecurityGroup", "GroupDescription", UpdateReplacement},
+		{"AWS::EC2::SecurityGroup", "VpcId", UpdateReplacement},
+		{"AWS::EC2::SecurityGroup", "SecurityGroupIngress[0].CidrIp", UpdateNoInterruption},
+		{"AWS::EC2::SecurityGroupIngress", "FromPort", UpdateReplacement},
+		{"AWS::EC2::PlacementGroup", "Strategy", UpdateReplacement},
+		{"AWS::EFS::FileSystem", "Encrypted", UpdateReplacement},
+		{"AWS::EFS::FileSystem", "FileSystemTags[0].Value", UpdateNoInterruption},
+		{"AWS::EFS::MountTarget", "SubnetId", UpdateReplacement},
+		{"AWS::EFS::MountTarget", "SecurityGroups[0]", UpdateNoInterruption},
+		{"AWS::IAM::Role", "ManagedPolicyArns[1]", UpdateNoInterruption},
+		{"AWS::IAM::Role", "RoleName", UpdateReplacement},
+		{"AWS::CloudWatch::Alarm", "Threshold", UpdateNoInterruption},
+		{"AWS::S3::Bucket", "BucketName", UpdateNoInterruption},
+	}
+
+	for _, tt := range tests {
+		if got := PropertyUpdateBehavior(tt.resourceType, tt.path); got != tt.want {
+			t.Errorf("PropertyUpdateBehavior(%s, %s) = %s, want %s", tt.resourceType, tt.path, got, tt.want)
+		}
+	}
+}
+
+func renderJSON(t *testing.T, arch Architecture, opts Options) string {
+	t.Helper()
+	template, err := Build(arch, opts)
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	body, err := template.JSON()
+	if err != nil {
+		t.Fatalf("JSON() error = %v", err)
+	}
+	return body
+}
+
+func findChange(diff *StackDiff, logicalID string) *ResourceChange {
+	for i := range diff.Resources {
+		if diff.Resources[i].LogicalID == logicalID {
+			return &diff.Resources[i]
+		}
+	}
+	return nil
+}
+
+func TestDiffStackUnchanged(t *testing.T) {
+	body := renderJSON(t, ArchitectureSingle, testOptions("r6i.4xlarge"))
+	parameters := map[string]string{"InstanceType": "r6i.4xlarge", "DomainName": "genomics", "KeyName": ""}
+
+	diff, err := DiffStack(body, parameters, body, parameters)
+	if err != nil {
+		t.Fatalf("DiffStack() error = %v", err)
+	}
+	if diff.HasChanges() {
+		t.Errorf("identical stacks should not differ: %+v", diff)
+	}
+}
+
+func TestDiffStackClassifiesChanges(t *testing.T) {
+	before := testOptions("r6i.4xlarge")
+	after := testOptions("r6i.4xlarge")
+	after.IdleStopMinutes = 30
+	after.SSHCIDR = "10.0.0.0/8"
+
+	liveParameters := map[string]string{"InstanceType": "r6i.4xlarge", "DomainName": "genomics", "KeyName": "lab"}
+	localParameters := map[string]string{"InstanceType": "r6i.8xlarge", "DomainName": "genomics", "KeyName": "lab"}
+
+	diff, err := DiffStack(renderJSON(t, ArchitectureSingle, before), liveParameters, renderJSON(t, ArchitectureSingle, after), localParameters)
+	if err != nil {
+		t.Fatalf("DiffStack() error = %v", err)
+	}
+
+	if len(diff.Parameters) != 1 || diff.Parameters[0] != (ParameterChange{Name: "InstanceType", Old: "r6i.4xlarge", New: "r6i.8xlarge"}) {
+		t.Errorf("parameters = %+v", diff.Parameters)
+	}
+
+	instance := findChange(diff, InstanceLogicalID)
+	if instance == nil || instance.Action != ChangeModify || !instance.Interrupts() {
+		t.Fatalf("instance change = %+v, want an interrupting in-place modification", instance)
+	}
+	if len(instance.Properties) != 1 || instance.Properties[0].Path != "InstanceType" || instance.Properties[0].New != "r6i.8xlarge" {
+		t.Errorf("instance properties = %+v, want the resolved InstanceType", instance.Properties)
+	}
+
+	group := findChange(diff, SecurityGroupLogicalID)
+	if group == nil || group.Action != ChangeModify || group.Interrupts() {
+		t.Errorf("security group change = %+v, want an in-place modification", group)
+	}
+
+	alarm := findChange(diff, IdleStopAlarmLogicalID(InstanceLogicalID))
+	if alarm == nil || alarm.Action != ChangeAdd {
+		t.Errorf("alarm change = %+v, want an addition", alarm)
+	}
+}
+
+func TestDiffStackReplacementAndDeletion(t *testing.T) {
+	before := testOptions("r6i.4xlarge")
+	before.PlacementGroup = true
+	after := testOptions("r6i.4xlarge")
+	after.ImageID = "ami-0123456789abcdef0"
+	parameters := map[string]string{"InstanceType": "r6i.4xlarge"}
+
+	diff, err := DiffStack(renderJSON(t, ArchitectureSingle, before), parameters, renderJSON(t, ArchitectureSingle, after), parameters)
+	if err != nil {
+		t.Fatalf("DiffStack() error = %v", err)
+	}
+
+	instance := findChange(diff, InstanceLogicalID)
+	if instance == nil || instance.Action != ChangeReplace {
+		t.Fatalf("instance change = %+v, want a replacement", instance)
+	}
+	for _, property := range instance.Properties {
+		if property.Path == "ImageId" && property.Behavior != UpdateReplacement {
+			t.Errorf("ImageId behavior = %s", property.Behavior)
+		}
+	}
+
+	group := findChange(diff, PlacementGroupLogicalID)
+	if group == nil || group.Action != ChangeDelete {
+		t.Errorf("placement group change = %+v, want a deletion", group)
+	}
+	if diff.Count(ChangeReplace) != 1 || diff.Count(ChangeDelete) != 1 {
+		t.Errorf("counts: %d replace, %d delete", diff.Count(ChangeReplace), diff.Count(ChangeDelete))
+	}
+}
+
+func TestDiffStackRejectsInvalidTemplates(t *testing.T) {
+	if _, err := DiffStack("Resources: {}", nil, "{}", nil); err == nil {
+		t.Error("DiffStack() should reject a template that is not JSON")
+	}
+}
@@ -0,0 +1,294 @@
+package templates
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/aws"
+)
+
+// Architecture names a research environment layout in the template library
+type Architecture string
+
+// Architectures in the template library
+const (
+	// ArchitectureSingle is one research instance
+	ArchitectureSingle Architecture = "single"
+	// ArchitectureHeadCompute is a login node and compute nodes sharing an EFS file system
+	ArchitectureHeadCompute Architecture = "head-compute"
+	// ArchitectureContainerHost is an instance that runs containers pulled from ECR
+	ArchitectureContainerHost Architecture = "container-host"
+)
+
+// DefaultArchitecture is used when neither the command line nor the domain pack
+// selects one
+const DefaultArchitecture = ArchitectureSingle
+
+// Defaults and limits for template options
+const (
+	DefaultImageID = "ami-0c02fb55956c7d316"
+	// DefaultARM64ImageParameter is the public SSM parameter holding the latest
+	// arm64 Amazon Linux 2 AMI, used in place of the x86_64 DefaultImageID
+	DefaultARM64ImageParameter = "/aws/service/ami-amazon-linux-latest/amzn2-ami-hvm-arm64-gp2"
+	DefaultSSHCIDR             = "0.0.0.0/0"
+	DefaultVolumeSizeGB        = 100
+	DefaultComputeNodes        = 2
+
+	MinVolumeSizeGB = 8
+	MaxVolumeSizeGB = 16384
+	MaxComputeNodes = 16
+
+	// Idle stop alarms evaluate average CPU over 5-minute periods, for up to a day
+	DefaultIdleCPUPercent = 5.0
+	MinIdleStopMinutes    = 5
+	MaxIdleStopMinutes    = 1440
+)
+
+// Options are the settings a template is built from
+type Options struct {
+	DomainName   string
+	Description  string
+	InstanceType string
+	ImageID      string
+	// CPUArchitecture is x86_64 or arm64; empty derives it from InstanceType
+	CPUArchitecture string
+	SSHCIDR         string
+	// NoSSH leaves out the key pair and inbound rules; instances are reached
+	// through Session Manager, so they always get an instance role with SSM
+	NoSSH           bool
+	VolumeSizeGB    int
+	EncryptVolume   bool
+	IAMRole         bool
+	ManagedPolicies []string
+	PlacementGroup  bool
+
+	// ComputeNodes and ComputeInstanceType size the head-compute compute nodes;
+	// the compute instance type defaults to InstanceType
+	ComputeNodes        int
+	ComputeInstanceType string
+
+	// GPU installs the NVIDIA container runtime on a container host
+	GPU bool
+
+	// IdleStopMinutes adds an alarm per instance that stops it after its average
+	// CPU stays under IdleCPUPercent for this long; zero disables auto-shutdown
+	IdleStopMinutes int
+	IdleCPUPercent  float64
+
+	// Compliance forces the settings of a compliance profile and adds its
+	// audit logging; empty is none
+	Compliance ComplianceProfile
+
+	// ElasticIP gives the research instance an address that survives a stop
+	// and start, released with the stack unless RetainElasticIP is set.
+	// DNSName adds an A record for it in the Route 53 zone HostedZoneID.
+	ElasticIP       bool
+	RetainElasticIP bool
+	DNSName         string
+	HostedZoneID    string
+
+	// CapacityReservationID or CapacityReservationGroupARN launches every
+	// instance into a capacity reservation. AvailabilityZone pins instances
+	// outside a subnet to the reservation's zone.
+	CapacityReservationID       string
+	CapacityReservationGroupARN string
+	AvailabilityZone            string
+}
+
+// DefaultOptions returns the defaults for a domain's research environment
+func DefaultOptions(domainName, instanceType string) Options {
+	return Options{
+		DomainName:   domainName,
+		Description:  fmt.Sprintf("AWS Research Wizard - %s Environment", domainName),
+		InstanceType: instanceType,
+		ImageID:      DefaultImageID,
+		SSHCIDR:      DefaultSSHCIDR,
+		VolumeSizeGB: DefaultVolumeSizeGB,
+		ComputeNodes: DefaultComputeNodes,
+	}
+}
+
+// InstanceArchitecture returns the CPU architecture the instances run
+func (o Options) InstanceArchitecture() string {
+	if o.CPUArchitecture != "" {
+		return o.CPUArchitecture
+	}
+	return aws.InstanceArchitecture(o.InstanceType)
+}
+
+// ImageParameter returns the SSM parameter the image is looked up from when
+// the instances launch, or "" when ImageID is used as given. The default image
+// is x86_64, so arm64 instances look up the latest arm64 image instead.
+func (o Options) ImageParameter() string {
+	if o.ImageID == DefaultImageID && o.InstanceArchitecture() == aws.ArchitectureARM64 {
+		return DefaultARM64ImageParameter
+	}
+	return ""
+}
+
+// ResolvedImageID returns the ImageId instances are given in a template, as a
+// dynamic reference when the image is looked up from SSM
+func (o Options) ResolvedImageID() string {
+	if parameter := o.ImageParameter(); parameter != "" {
+		return "{{resolve:ssm:" + parameter + "}}"
+	}
+	return o.ImageID
+}
+
+// architecture is one entry in the template library
+type architecture struct {
+	description string
+	// needsNetwork is set when the template takes VpcId and SubnetId parameters
+	needsNetwork bool
+	validate     func(Options) error
+	build        func(Options) *Template
+}
+
+var library = map[Architecture]architecture{
+	ArchitectureSingle: {
+		description: "One research instance with SSH and Jupyter access",
+		build:       buildSingle,
+	},
+	ArchitectureHeadCompute: {
+		description:  "Login node and N compute nodes sharing an EFS file system",
+		needsNetwork: true,
+		validate:     validateHeadCompute,
+		build:        buildHeadCompute,
+	},
+	ArchitectureContainerHost: {
+		description: "Docker host with an ECR pull role and optional NVIDIA GPU runtime",
+		validate:    validateContainerHost,
+		build:       buildContainerHost,
+	},
+}
+
+// Architectures lists the architectures in the library
+func Architectures() []Architecture {
+	names := make([]Architecture, 0, len(library))
+	for name := range library {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return names[i] < names[j]
+	})
+	return names
+}
+
+// ParseArchitecture looks up an architecture by name; an empty name selects the default
+func ParseArchitecture(name string) (Architecture, error) {
+	if name == "" {
+		return DefaultArchitecture, nil
+	}
+	arch := Architecture(strings.ToLower(strings.TrimSpace(name)))
+	if _, exists := library[arch]; !exists {
+		return "", fmt.Errorf("unknown architecture %q (available: %s)", name, architectureList())
+	}
+	return arch, nil
+}
+
+// Description summarizes the architecture
+func (a Architecture) Description() string {
+	return library[a].description
+}
+
+// NeedsNetwork reports whether deployments must supply VpcId and SubnetId parameters
+func (a Architecture) NeedsNetwork() bool {
+	return library[a].needsNetwork
+}
+
+// Validate checks the options can be built into a template for the architecture
+func (o Options) Validate(arch Architecture) error {
+	spec, exists := library[arch]
+	if !exists {
+		return fmt.Errorf("unknown architecture %q (available: %s)", arch, architectureList())
+	}
+
+	if o.InstanceType == "" {
+		return fmt.Errorf("instance type is required")
+	}
+	if o.ImageID == "" {
+		return fmt.Errorf("image ID is required")
+	}
+	if err := o.validateCPUArchitecture(); err != nil {
+		return err
+	}
+	if o.VolumeSizeGB < MinVolumeSizeGB || o.VolumeSizeGB > MaxVolumeSizeGB {
+		return fmt.Errorf("volume size %d GB is outside %d-%d GB", o.VolumeSizeGB, MinVolumeSizeGB, MaxVolumeSizeGB)
+	}
+	if _, _, err := net.ParseCIDR(o.SSHCIDR); err != nil {
+		return fmt.Errorf("invalid SSH CIDR %q: %w", o.SSHCIDR, err)
+	}
+	if o.IdleStopMinutes != 0 && (o.IdleStopMinutes < MinIdleStopMinutes || o.IdleStopMinutes > MaxIdleStopMinutes) {
+		return fmt.Errorf("auto-shutdown after %d minutes is outside %d-%d minutes", o.IdleStopMinutes, MinIdleStopMinutes, MaxIdleStopMinutes)
+	}
+	if o.IdleCPUPercent < 0 || o.IdleCPUPercent >= 100 {
+		return fmt.Errorf("idle CPU threshold %.1f%% is outside 0-100%%", o.IdleCPUPercent)
+	}
+	if err := o.validateCompliance(); err != nil {
+		return err
+	}
+	if err := o.validateAddress(); err != nil {
+		return err
+	}
+	if err := o.validateCapacityReservation(); err != nil {
+		return err
+	}
+
+	if spec.validate != nil {
+		return spec.validate(o)
+	}
+	return nil
+}
+
+// validateCPUArchitecture checks the instance type runs the requested CPU
+// architecture
+func (o Options) validateCPUArchitecture() error {
+	switch o.CPUArchitecture {
+	case "", aws.ArchitectureX86_64, aws.ArchitectureARM64:
+	default:
+		return fmt.Errorf("CPU architecture %q must be %s or %s", o.CPUArchitecture, aws.ArchitectureX86_64, aws.ArchitectureARM64)
+	}
+
+	if actual := aws.InstanceArchitecture(o.InstanceType); actual != o.InstanceArchitecture() {
+		return fmt.Errorf("instance type %s is %s, not %s", o.InstanceType, actual, o.CPUArchitecture)
+	}
+	return nil
+}
+
+// Build validates the options and composes the template for an architecture
+func Build(arch Architecture, opts Options) (*Template, error) {
+	if err := opts.Validate(arch); err != nil {
+		return nil, fmt.Errorf("invalid %s template options: %w", arch, err)
+	}
+	if opts.NoSSH {
+		opts.IAMRole = true
+	}
+	compliance := opts.Compliance.Settings()
+	if compliance.EncryptionAtRest {
+		opts.EncryptVolume = true
+	}
+	template := library[arch].build(opts)
+	if compliance.Enforced() {
+		addComplianceControls(template, compliance)
+	}
+	if opts.ElasticIP {
+		addElasticIP(template, opts)
+	}
+	if opts.CapacityReservationID != "" || opts.CapacityReservationGroupARN != "" {
+		addCapacityReservation(template, opts)
+	}
+	if opts.IdleStopMinutes > 0 {
+		addIdleStopAlarms(template, opts)
+	}
+	return template, nil
+}
+
+func architectureList() string {
+	names := make([]string, 0, len(library))
+	for _, name := range Architectures() {
+		names = append(names, string(name))
+	}
+	return strings.Join(names, ", ")
+}
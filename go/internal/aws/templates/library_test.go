@@ -0,0 +1,437 @@
+package templates
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite golden template files")
+
+func testOptions(instanceType string) Options {
+	opts := DefaultOptions("genomics", instanceType)
+	opts.VolumeSizeGB = 500
+	return opts
+}
+
+func TestBuildGolden(t *testing.T) {
+	tests := []struct {
+		name   string
+		arch   Architecture
+		modify func(*Options)
+	}{
+		{name: "single", arch: ArchitectureSingle},
+		{name: "single_full", arch: ArchitectureSingle, modify: func(o *Options) {
+			o.SSHCIDR = "198.51.100.0/24"
+			o.EncryptVolume = true
+			o.IAMRole = true
+			o.PlacementGroup = true
+		}},
+		{name: "head_compute", arch: ArchitectureHeadCompute, modify: func(o *Options) {
+			o.ComputeNodes = 3
+			o.ComputeInstanceType = "c6i.8xlarge"
+			o.PlacementGroup = true
+		}},
+		{name: "single_no_ssh", arch: ArchitectureSingle, modify: func(o *Options) {
+			o.NoSSH = true
+		}},
+		{name: "container_host", arch: ArchitectureContainerHost},
+		{name: "container_host_gpu", arch: ArchitectureContainerHost, modify: func(o *Options) {
+			o.InstanceType = "g5.2xlarge"
+			o.GPU = true
+			o.ManagedPolicies = []string{"arn:aws:iam::aws:policy/AmazonS3ReadOnlyAccess"}
+		}},
+		{name: "single_hipaa", arch: ArchitectureSingle, modify: func(o *Options) {
+			o.Compliance = ComplianceHIPAA
+			o.NoSSH = true
+		}},
+		{name: "head_compute_cui", arch: ArchitectureHeadCompute, modify: func(o *Options) {
+			o.Compliance = ComplianceCUI
+			o.SSHCIDR = "10.20.0.0/16"
+		}},
+		{name: "single_elastic_ip", arch: ArchitectureSingle, modify: func(o *Options) {
+			o.ElasticIP = true
+			o.RetainElasticIP = true
+		}},
+		{name: "head_compute_dns", arch: ArchitectureHeadCompute, modify: func(o *Options) {
+			o.ElasticIP = true
+			o.DNSName = "jupyter.lab.example.com."
+			o.HostedZoneID = "Z0123456789ABCDEFGHIJ"
+		}},
+		{name: "single_no_ssh_elastic_ip", arch: ArchitectureSingle, modify: func(o *Options) {
+			o.NoSSH = true
+			o.ElasticIP = true
+		}},
+		{name: "single_capacity_reservation", arch: ArchitectureSingle, modify: func(o *Options) {
+			o.InstanceType = "p4d.24xlarge"
+			o.CapacityReservationID = "cr-0123456789abcdef0"
+			o.AvailabilityZone = "us-east-1a"
+		}},
+		{name: "head_compute_capacity_group", arch: ArchitectureHeadCompute, modify: func(o *Options) {
+			o.InstanceType = "p4d.24xlarge"
+			o.CapacityReservationGroupARN = "arn:aws:resource-groups:us-east-1:123456789012:group/lab-gpu-odcrs"
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := testOptions("r6i.4xlarge")
+			if tt.modify != nil {
+				tt.modify(&opts)
+			}
+
+			template, err := Build(tt.arch, opts)
+			if err != nil {
+				t.Fatalf("Build() error = %v", err)
+			}
+			body, err := template.JSON()
+			if err != nil {
+				t.Fatalf("JSON() error = %v", err)
+			}
+
+			golden := filepath.Join("testdata", tt.name+".json")
+			if *update {
+				if err := os.WriteFile(golden, []byte(body+"\n"), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("read golden file (run with -update to create): %v", err)
+			}
+			if body+"\n" != string(want) {
+				t.Errorf("template differs from %s; run go test -update to review the change\n%s", golden, body)
+			}
+		})
+	}
+}
+
+// parsed is the generic shape used to inspect rendered templates
+type parsed struct {
+	Parameters map[string]map[string]interface{}
+	Resources  map[string]struct {
+		Type       string
+		DependsOn  []string
+		Properties map[string]interface{}
+	}
+	Outputs map[string]map[string]interface{}
+}
+
+func build(t *testing.T, arch Architecture, opts Options) parsed {
+	t.Helper()
+	template, err := Build(arch, opts)
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	body, err := template.JSON()
+	if err != nil {
+		t.Fatalf("JSON() error = %v", err)
+	}
+	var result parsed
+	if err := json.Unmarshal([]byte(body), &result); err != nil {
+		t.Fatalf("template is not valid JSON: %v", err)
+	}
+	return result
+}
+
+func TestEveryArchitecturePublishesInstanceOutputs(t *testing.T) {
+	for _, arch := range Architectures() {
+		result := build(t, arch, testOptions("g5.xlarge"))
+		for _, key := range []string{"InstanceId", "PublicIP", "PrivateIP", "SecurityGroupId", "SSHCommand"} {
+			if _, exists := result.Outputs[key]; !exists {
+				t.Errorf("%s: missing output %s", arch, key)
+			}
+		}
+		if result.Resources[InstanceLogicalID].Type != "AWS::EC2::Instance" {
+			t.Errorf("%s: missing research instance", arch)
+		}
+		if result.Resources[SecurityGroupLogicalID].Type != "AWS::EC2::SecurityGroup" {
+			t.Errorf("%s: missing security group", arch)
+		}
+	}
+}
+
+func TestBuildHeadCompute(t *testing.T) {
+	opts := testOptions("r6i.4xlarge")
+	opts.ComputeNodes = 4
+	result := build(t, ArchitectureHeadCompute, opts)
+
+	for n := 1; n <= 4; n++ {
+		node := result.Resources[ComputeNodeLogicalID(n)]
+		if node.Type != "AWS::EC2::Instance" {
+			t.Fatalf("missing compute node %d", n)
+		}
+		if len(node.DependsOn) != 1 || node.DependsOn[0] != MountTargetLogicalID {
+			t.Errorf("compute node %d should wait for the mount target: %v", n, node.DependsOn)
+		}
+		if node.Properties["InstanceType"].(map[string]interface{})["Ref"] != "ComputeInstanceType" {
+			t.Errorf("compute node %d does not use the compute instance type", n)
+		}
+		nic := node.Properties["NetworkInterfaces"].([]interface{})[0].(map[string]interface{})
+		groups := nic["GroupSet"].([]interface{})
+		if nic["AssociatePublicIpAddress"] != false || len(groups) != 1 || groups[0].(map[string]interface{})["Ref"] != ComputeSecurityGroupLogicalID {
+			t.Errorf("compute node %d should have no public address and only the compute group: %v", n, nic)
+		}
+	}
+
+	// Compute nodes take traffic only from the cluster, never from the SSH CIDR
+	if rules, exists := result.Resources[ComputeSecurityGroupLogicalID].Properties["SecurityGroupIngress"]; exists {
+		t.Errorf("compute security group should have no inline rules: %v", rules)
+	}
+	for _, logicalID := range []string{ComputeHeadIngressLogicalID, ComputeSelfIngressLogicalID} {
+		if result.Resources[logicalID].Type != "AWS::EC2::SecurityGroupIngress" {
+			t.Errorf("missing cluster rule %s", logicalID)
+		}
+	}
+
+	// The head always gets the public address its PublicIp output refers to
+	headNIC := result.Resources[InstanceLogicalID].Properties["NetworkInterfaces"].([]interface{})[0].(map[string]interface{})
+	if headNIC["AssociatePublicIpAddress"] != true {
+		t.Errorf("head node should get a public address in any subnet: %v", headNIC)
+	}
+	if _, exists := result.Resources[ComputeNodeLogicalID(5)]; exists {
+		t.Error("unexpected fifth compute node")
+	}
+
+	if result.Parameters["ComputeInstanceType"]["Default"] != "r6i.4xlarge" {
+		t.Errorf("compute instance type should default to the head type: %v", result.Parameters["ComputeInstanceType"])
+	}
+	for _, key := range []string{"VpcId", "SubnetId"} {
+		if _, exists := result.Parameters[key]; !exists {
+			t.Errorf("missing %s parameter", key)
+		}
+	}
+	if result.Resources[SharedFileSystemLogicalID].Properties["Encrypted"] != true {
+		t.Error("shared file system should be encrypted")
+	}
+	if result.Resources[InstanceProfileLogicalID].Type != "AWS::IAM::InstanceProfile" {
+		t.Error("head-compute should always attach an instance profile")
+	}
+	if !ArchitectureHeadCompute.NeedsNetwork() || ArchitectureSingle.NeedsNetwork() {
+		t.Error("only head-compute should need network parameters")
+	}
+}
+
+func TestBuildContainerHostPolicies(t *testing.T) {
+	result := build(t, ArchitectureContainerHost, testOptions("m6i.xlarge"))
+
+	policies := result.Resources[InstanceRoleLogicalID].Properties["ManagedPolicyArns"].([]interface{})
+	if len(policies) != 2 || policies[0] != ssmManagedPolicy || policies[1] != ecrReadOnlyPolicy {
+		t.Errorf("policies = %v, want SSM and ECR read-only", policies)
+	}
+
+	userData := result.Resources[InstanceLogicalID].Properties["UserData"].(map[string]interface{})["Fn::Base64"].(map[string]interface{})["Fn::Sub"].(string)
+	if strings.Contains(userData, "nvidia") {
+		t.Error("CPU container host should not install the NVIDIA runtime")
+	}
+}
+
+func TestBuildNoSSH(t *testing.T) {
+	for _, arch := range Architectures() {
+		opts := testOptions("m6i.xlarge")
+		opts.NoSSH = true
+		opts.ManagedPolicies = []string{"arn:aws:iam::aws:policy/AmazonS3ReadOnlyAccess"}
+		result := build(t, arch, opts)
+
+		if _, exists := result.Parameters["KeyName"]; exists {
+			t.Errorf("%s: KeyName parameter without SSH", arch)
+		}
+		if _, exists := result.Resources[InstanceLogicalID].Properties["KeyName"]; exists {
+			t.Errorf("%s: instance has a key pair without SSH", arch)
+		}
+		if ingress, exists := result.Resources[SecurityGroupLogicalID].Properties["SecurityGroupIngress"]; exists {
+			t.Errorf("%s: security group ingress without SSH: %v", arch, ingress)
+		}
+		if result.Resources[InstanceLogicalID].Properties["IamInstanceProfile"] == nil {
+			t.Errorf("%s: no instance profile without SSH", arch)
+		}
+		policies := result.Resources[InstanceRoleLogicalID].Properties["ManagedPolicyArns"].([]interface{})
+		if len(policies) < 2 || policies[0] != ssmManagedPolicy || policies[1] != opts.ManagedPolicies[0] {
+			t.Errorf("%s: policies = %v, want SSM before the named policies", arch, policies)
+		}
+
+		if result.Outputs["Connectivity"]["Value"] != "ssm" {
+			t.Errorf("%s: Connectivity output = %v, want ssm", arch, result.Outputs["Connectivity"])
+		}
+		if _, exists := result.Outputs["SSHCommand"]; exists {
+			t.Errorf("%s: SSHCommand output without SSH", arch)
+		}
+		if _, exists := result.Outputs["SSMCommand"]; !exists {
+			t.Errorf("%s: missing SSMCommand output", arch)
+		}
+	}
+
+	// With SSH the key pair, ingress and SSH output stay as they were
+	result := build(t, ArchitectureSingle, testOptions("m6i.xlarge"))
+	if _, exists := result.Parameters["KeyName"]; !exists {
+		t.Error("KeyName parameter missing with SSH")
+	}
+	if ingress := result.Resources[SecurityGroupLogicalID].Properties["SecurityGroupIngress"].([]interface{}); len(ingress) != 2 {
+		t.Errorf("ingress = %v, want SSH and Jupyter", ingress)
+	}
+	if result.Outputs["Connectivity"]["Value"] != "ssh" {
+		t.Errorf("Connectivity output = %v, want ssh", result.Outputs["Connectivity"])
+	}
+}
+
+func TestBuildIdleStopAlarms(t *testing.T) {
+	opts := testOptions("r6i.4xlarge")
+	opts.ComputeNodes = 2
+	opts.IdleStopMinutes = 42
+	result := build(t, ArchitectureHeadCompute, opts)
+
+	for _, instance := range []string{InstanceLogicalID, ComputeNodeLogicalID(1), ComputeNodeLogicalID(2)} {
+		alarm := result.Resources[IdleStopAlarmLogicalID(instance)]
+		if alarm.Type != "AWS::CloudWatch::Alarm" {
+			t.Fatalf("missing idle stop alarm for %s", instance)
+		}
+		if alarm.Properties["EvaluationPeriods"] != float64(9) {
+			t.Errorf("%s: evaluation periods = %v, want 9 five-minute periods", instance, alarm.Properties["EvaluationPeriods"])
+		}
+		if alarm.Properties["Threshold"] != DefaultIdleCPUPercent {
+			t.Errorf("%s: threshold = %v", instance, alarm.Properties["Threshold"])
+		}
+		dimension := alarm.Properties["Dimensions"].([]interface{})[0].(map[string]interface{})
+		if dimension["Value"].(map[string]interface{})["Ref"] != instance {
+			t.Errorf("%s: alarm watches %v", instance, dimension["Value"])
+		}
+	}
+
+	opts.IdleStopMinutes = 0
+	for logicalID, resource := range build(t, ArchitectureSingle, opts).Resources {
+		if resource.Type == "AWS::CloudWatch::Alarm" {
+			t.Errorf("unexpected alarm %s without auto-shutdown", logicalID)
+		}
+	}
+}
+
+func TestOptionsValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		arch    Architecture
+		modify  func(*Options)
+		wantErr string
+	}{
+		{name: "defaults", arch: ArchitectureSingle},
+		{name: "unknown architecture", arch: "mainframe", wantErr: "unknown architecture"},
+		{name: "missing instance type", arch: ArchitectureSingle, modify: func(o *Options) { o.InstanceType = "" }, wantErr: "instance type is required"},
+		{name: "missing image", arch: ArchitectureContainerHost, modify: func(o *Options) { o.ImageID = "" }, wantErr: "image ID"},
+		{name: "volume too small", arch: ArchitectureSingle, modify: func(o *Options) { o.VolumeSizeGB = 4 }, wantErr: "volume size"},
+		{name: "volume too large", arch: ArchitectureHeadCompute, modify: func(o *Options) { o.VolumeSizeGB = MaxVolumeSizeGB + 1 }, wantErr: "volume size"},
+		{name: "bad CIDR", arch: ArchitectureSingle, modify: func(o *Options) { o.SSHCIDR = "10.0.0.1" }, wantErr: "invalid SSH CIDR"},
+		{name: "no compute nodes", arch: ArchitectureHeadCompute, modify: func(o *Options) { o.ComputeNodes = 0 }, wantErr: "compute node count"},
+		{name: "too many compute nodes", arch: ArchitectureHeadCompute, modify: func(o *Options) { o.ComputeNodes = MaxComputeNodes + 1 }, wantErr: "compute node count"},
+		{name: "compute nodes ignored for single", arch: ArchitectureSingle, modify: func(o *Options) { o.ComputeNodes = 0 }},
+		{name: "GPU on CPU instance", arch: ArchitectureContainerHost, modify: func(o *Options) { o.GPU = true }, wantErr: "GPU instance type"},
+		{name: "GPU on GPU instance", arch: ArchitectureContainerHost, modify: func(o *Options) { o.GPU = true; o.InstanceType = "p4d.24xlarge" }},
+		{name: "auto-shutdown too soon", arch: ArchitectureSingle, modify: func(o *Options) { o.IdleStopMinutes = 1 }, wantErr: "auto-shutdown"},
+		{name: "arm64 instance", arch: ArchitectureSingle, modify: func(o *Options) { o.InstanceType = "r7g.4xlarge"; o.CPUArchitecture = "arm64" }},
+		{name: "architecture mismatch", arch: ArchitectureSingle, modify: func(o *Options) { o.CPUArchitecture = "arm64" }, wantErr: "is x86_64, not arm64"},
+		{name: "unknown CPU architecture", arch: ArchitectureSingle, modify: func(o *Options) { o.CPUArchitecture = "sparc" }, wantErr: "CPU architecture"},
+		{name: "mixed compute architecture", arch: ArchitectureHeadCompute, modify: func(o *Options) { o.ComputeInstanceType = "c7g.8xlarge" }, wantErr: "head node is x86_64"},
+		{name: "idle threshold too high", arch: ArchitectureSingle, modify: func(o *Options) { o.IdleStopMinutes = 60; o.IdleCPUPercent = 100 }, wantErr: "idle CPU threshold"},
+		{name: "hipaa with public Jupyter", arch: ArchitectureSingle, modify: func(o *Options) { o.Compliance = ComplianceHIPAA }, wantErr: "does not allow SSH (port 22) and Jupyter (port 8888) open to 0.0.0.0/0"},
+		{name: "hipaa with institutional CIDR", arch: ArchitectureContainerHost, modify: func(o *Options) { o.Compliance = ComplianceHIPAA; o.SSHCIDR = "198.51.100.0/24" }, wantErr: "hipaa compliance profile"},
+		{name: "hipaa with private CIDR", arch: ArchitectureHeadCompute, modify: func(o *Options) { o.Compliance = ComplianceHIPAA; o.SSHCIDR = "172.16.4.0/22" }},
+		{name: "hipaa without SSH", arch: ArchitectureSingle, modify: func(o *Options) { o.Compliance = ComplianceHIPAA; o.NoSSH = true }},
+		{name: "cui CIDR wider than a private block", arch: ArchitectureSingle, modify: func(o *Options) { o.Compliance = ComplianceCUI; o.SSHCIDR = "10.0.0.0/7" }, wantErr: "cui compliance profile"},
+		{name: "no compliance with public SSH", arch: ArchitectureSingle, modify: func(o *Options) { o.Compliance = ComplianceNone }},
+		{name: "unknown compliance profile", arch: ArchitectureSingle, modify: func(o *Options) { o.Compliance = "sox" }, wantErr: "unknown compliance profile"},
+		{name: "elastic IP", arch: ArchitectureContainerHost, modify: func(o *Options) { o.ElasticIP = true }},
+		{name: "retain without elastic IP", arch: ArchitectureSingle, modify: func(o *Options) { o.RetainElasticIP = true }, wantErr: "requires an Elastic IP"},
+		{name: "elastic IP under hipaa", arch: ArchitectureSingle, modify: func(o *Options) { o.Compliance = ComplianceHIPAA; o.NoSSH = true; o.ElasticIP = true }, wantErr: "does not allow an Elastic IP"},
+		{name: "DNS without elastic IP", arch: ArchitectureSingle, modify: func(o *Options) { o.DNSName = "jupyter.lab.example.com"; o.HostedZoneID = "Z123" }, wantErr: "needs an Elastic IP"},
+		{name: "DNS without hosted zone", arch: ArchitectureSingle, modify: func(o *Options) { o.ElasticIP = true; o.DNSName = "jupyter.lab.example.com" }, wantErr: "hosted zone ID"},
+		{name: "hosted zone without DNS", arch: ArchitectureSingle, modify: func(o *Options) { o.ElasticIP = true; o.HostedZoneID = "Z123" }, wantErr: "DNS name"},
+		{name: "unqualified DNS name", arch: ArchitectureSingle, modify: func(o *Options) { o.ElasticIP = true; o.DNSName = "jupyter"; o.HostedZoneID = "Z123" }, wantErr: "fully qualified"},
+		{name: "DNS name with underscore", arch: ArchitectureSingle, modify: func(o *Options) { o.ElasticIP = true; o.DNSName = "my_lab.example.com"; o.HostedZoneID = "Z123" }, wantErr: "label \"my_lab\""},
+		{name: "capacity reservation", arch: ArchitectureSingle, modify: func(o *Options) {
+			o.CapacityReservationID = "cr-0123456789abcdef0"
+			o.AvailabilityZone = "us-gov-west-1b"
+		}},
+		{name: "capacity reservation and group", arch: ArchitectureSingle, modify: func(o *Options) {
+			o.CapacityReservationID = "cr-0123456789abcdef0"
+			o.CapacityReservationGroupARN = "arn:aws:resource-groups:us-east-1:123456789012:group/odcrs"
+		}, wantErr: "not both"},
+		{name: "bad capacity reservation ID", arch: ArchitectureSingle, modify: func(o *Options) { o.CapacityReservationID = "odcr-1234" }, wantErr: "invalid capacity reservation ID"},
+		{name: "capacity group name", arch: ArchitectureSingle, modify: func(o *Options) { o.CapacityReservationGroupARN = "lab-gpu-odcrs" }, wantErr: "resource group ARN"},
+		{name: "zone without capacity reservation", arch: ArchitectureSingle, modify: func(o *Options) { o.AvailabilityZone = "us-east-1a" }, wantErr: "only set for a capacity reservation"},
+		{name: "bad zone", arch: ArchitectureSingle, modify: func(o *Options) { o.CapacityReservationID = "cr-0123456789abcdef0"; o.AvailabilityZone = "us-east-1" }, wantErr: "invalid Availability Zone"},
+		{name: "hosted zone path", arch: ArchitectureSingle, modify: func(o *Options) {
+			o.ElasticIP = true
+			o.DNSName = "lab.example.com"
+			o.HostedZoneID = "/hostedzone/Z123"
+		}, wantErr: "invalid hosted zone ID"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := testOptions("r6i.4xlarge")
+			if tt.modify != nil {
+				tt.modify(&opts)
+			}
+
+			err := opts.Validate(tt.arch)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() error = %v, want %q", err, tt.wantErr)
+			}
+			if _, buildErr := Build(tt.arch, opts); buildErr == nil {
+				t.Error("Build() should reject invalid options")
+			}
+		})
+	}
+}
+
+func TestImageForArchitecture(t *testing.T) {
+	// The default image is x86_64; arm64 instances look up the arm64 image
+	if image := testOptions("r6i.4xlarge").ResolvedImageID(); image != DefaultImageID {
+		t.Errorf("x86_64 image = %s", image)
+	}
+	arm := build(t, ArchitectureSingle, testOptions("r7g.4xlarge"))
+	if want := "{{resolve:ssm:" + DefaultARM64ImageParameter + "}}"; arm.Resources[InstanceLogicalID].Properties["ImageId"] != want {
+		t.Errorf("arm64 ImageId = %v, want %s", arm.Resources[InstanceLogicalID].Properties["ImageId"], want)
+	}
+
+	// Including a GPU container host on Graviton, which needs the arm64 image too
+	gpuOpts := testOptions("g5g.2xlarge")
+	gpuOpts.GPU = true
+	gpu := build(t, ArchitectureContainerHost, gpuOpts)
+	if want := "{{resolve:ssm:" + DefaultARM64ImageParameter + "}}"; gpu.Resources[InstanceLogicalID].Properties["ImageId"] != want {
+		t.Errorf("g5g GPU host ImageId = %v, want %s", gpu.Resources[InstanceLogicalID].Properties["ImageId"], want)
+	}
+
+	// An image chosen explicitly is used as given
+	opts := testOptions("r7g.4xlarge")
+	opts.ImageID = "ami-0123456789abcdef0"
+	if image := opts.ResolvedImageID(); image != opts.ImageID || opts.ImageParameter() != "" {
+		t.Errorf("explicit image resolved to %s", image)
+	}
+}
+
+func TestParseArchitecture(t *testing.T) {
+	tests := map[string]Architecture{
+		"":                ArchitectureSingle,
+		"single":          ArchitectureSingle,
+		"Head-Compute":    ArchitectureHeadCompute,
+		" container-host": ArchitectureContainerHost,
+	}
+	for name, want := range tests {
+		got, err := ParseArchitecture(name)
+		if err != nil || got != want {
+			t.Errorf("ParseArchitecture(%q) = %q, %v; want %q", name, got, err, want)
+		}
+	}
+
+	if _, err := ParseArchitecture("cluster"); err == nil || !strings.Contains(err.Error(), "head-compute") {
+		t.Errorf("expected an error listing the architectures, got %v", err)
+	}
+}
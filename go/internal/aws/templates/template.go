@@ -0,0 +1,316 @@
+// Package templates builds the CloudFormation templates research environments
+// are deployed from. Each architecture in the library composes the same
+// security group, IAM and tagging components.
+package templates
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+const templateFormatVersion = "2010-09-09"
+
+// Template is the top level of a CloudFormation template document
+type Template struct {
+	AWSTemplateFormatVersion string               `json:"AWSTemplateFormatVersion"`
+	Description              string               `json:"Description,omitempty"`
+	Parameters               map[string]Parameter `json:"Parameters,omitempty"`
+	Resources                map[string]Resource  `json:"Resources"`
+	Outputs                  map[string]Output    `json:"Outputs,omitempty"`
+}
+
+// Parameter is a CloudFormation template parameter
+type Parameter struct {
+	Type          string   `json:"Type"`
+	Default       string   `json:"Default,omitempty"`
+	Description   string   `json:"Description,omitempty"`
+	MinValue      string   `json:"MinValue,omitempty"`
+	MaxValue      string   `json:"MaxValue,omitempty"`
+	AllowedValues []string `json:"AllowedValues,omitempty"`
+}
+
+// Resource is a CloudFormation resource with typed properties
+type Resource struct {
+	Type       string      `json:"Type"`
+	DependsOn  []string    `json:"DependsOn,omitempty"`
+	Properties interface{} `json:"Properties,omitempty"`
+	// DeletionPolicy and UpdateReplacePolicy keep resources such as audit
+	// logs when the stack is deleted or the resource replaced
+	DeletionPolicy      string `json:"DeletionPolicy,omitempty"`
+	UpdateReplacePolicy string `json:"UpdateReplacePolicy,omitempty"`
+}
+
+// Output is a CloudFormation stack output
+type Output struct {
+	Description string      `json:"Description,omitempty"`
+	Value       interface{} `json:"Value"`
+}
+
+// Tag is a CloudFormation resource tag
+type Tag struct {
+	Key   string      `json:"Key"`
+	Value interface{} `json:"Value"`
+}
+
+// IngressRule is a security group ingress rule from a CIDR range or another group
+type IngressRule struct {
+	IpProtocol            string      `json:"IpProtocol"`
+	FromPort              int         `json:"FromPort"`
+	ToPort                int         `json:"ToPort"`
+	CidrIp                string      `json:"CidrIp,omitempty"`
+	SourceSecurityGroupId interface{} `json:"SourceSecurityGroupId,omitempty"`
+}
+
+// EgressRule is a security group egress rule to a CIDR range or another group
+type EgressRule struct {
+	IpProtocol                 string      `json:"IpProtocol"`
+	FromPort                   int         `json:"FromPort"`
+	ToPort                     int         `json:"ToPort"`
+	CidrIp                     string      `json:"CidrIp,omitempty"`
+	DestinationSecurityGroupId interface{} `json:"DestinationSecurityGroupId,omitempty"`
+}
+
+// SecurityGroupProperties are the properties of AWS::EC2::SecurityGroup. A
+// group without egress rules allows all outbound traffic.
+type SecurityGroupProperties struct {
+	GroupDescription     string        `json:"GroupDescription"`
+	VpcId                interface{}   `json:"VpcId,omitempty"`
+	SecurityGroupIngress []IngressRule `json:"SecurityGroupIngress,omitempty"`
+	SecurityGroupEgress  []EgressRule  `json:"SecurityGroupEgress,omitempty"`
+	Tags                 []Tag         `json:"Tags,omitempty"`
+}
+
+// SecurityGroupIngressProperties are the properties of AWS::EC2::SecurityGroupIngress,
+// used for rules that refer to their own group
+type SecurityGroupIngressProperties struct {
+	GroupId               interface{} `json:"GroupId"`
+	IpProtocol            string      `json:"IpProtocol"`
+	FromPort              int         `json:"FromPort"`
+	ToPort                int         `json:"ToPort"`
+	SourceSecurityGroupId interface{} `json:"SourceSecurityGroupId"`
+}
+
+// SecurityGroupEgressProperties are the properties of AWS::EC2::SecurityGroupEgress,
+// used for rules that refer to their own group or to a group referring back
+type SecurityGroupEgressProperties struct {
+	GroupId                    interface{} `json:"GroupId"`
+	IpProtocol                 string      `json:"IpProtocol"`
+	FromPort                   int         `json:"FromPort"`
+	ToPort                     int         `json:"ToPort"`
+	DestinationSecurityGroupId interface{} `json:"DestinationSecurityGroupId"`
+}
+
+// EBSVolume describes the EBS settings of a block device mapping
+type EBSVolume struct {
+	VolumeSize int    `json:"VolumeSize"`
+	VolumeType string `json:"VolumeType"`
+	Encrypted  bool   `json:"Encrypted"`
+}
+
+// BlockDeviceMapping attaches an EBS volume to an instance
+type BlockDeviceMapping struct {
+	DeviceName string    `json:"DeviceName"`
+	Ebs        EBSVolume `json:"Ebs"`
+}
+
+// NetworkInterface is the primary network interface of an instance, used in
+// place of SubnetId and SecurityGroupIds to control public IP assignment
+type NetworkInterface struct {
+	DeviceIndex              string        `json:"DeviceIndex"`
+	AssociatePublicIpAddress bool          `json:"AssociatePublicIpAddress"`
+	DeleteOnTermination      bool          `json:"DeleteOnTermination"`
+	SubnetId                 interface{}   `json:"SubnetId"`
+	GroupSet                 []interface{} `json:"GroupSet"`
+}
+
+// InstanceProperties are the properties of AWS::EC2::Instance
+type InstanceProperties struct {
+	InstanceType        interface{}          `json:"InstanceType"`
+	ImageId             string               `json:"ImageId"`
+	KeyName             interface{}          `json:"KeyName,omitempty"`
+	SubnetId            interface{}          `json:"SubnetId,omitempty"`
+	SecurityGroupIds    []interface{}        `json:"SecurityGroupIds,omitempty"`
+	NetworkInterfaces   []NetworkInterface   `json:"NetworkInterfaces,omitempty"`
+	Tenancy             interface{}          `json:"Tenancy,omitempty"`
+	AvailabilityZone    string               `json:"AvailabilityZone,omitempty"`
+	IamInstanceProfile  interface{}          `json:"IamInstanceProfile,omitempty"`
+	PlacementGroupName  interface{}          `json:"PlacementGroupName,omitempty"`
+	BlockDeviceMappings []BlockDeviceMapping `json:"BlockDeviceMappings,omitempty"`
+	UserData            interface{}          `json:"UserData,omitempty"`
+	Tags                []Tag                `json:"Tags,omitempty"`
+
+	CapacityReservationSpecification *CapacityReservationSpecification `json:"CapacityReservationSpecification,omitempty"`
+}
+
+// CapacityReservationSpecification launches an instance into a capacity
+// reservation or any reservation in a resource group
+type CapacityReservationSpecification struct {
+	CapacityReservationTarget CapacityReservationTarget `json:"CapacityReservationTarget"`
+}
+
+// CapacityReservationTarget names the capacity reservation or resource group
+// an instance is launched into
+type CapacityReservationTarget struct {
+	CapacityReservationId               string `json:"CapacityReservationId,omitempty"`
+	CapacityReservationResourceGroupArn string `json:"CapacityReservationResourceGroupArn,omitempty"`
+}
+
+// IAMRoleProperties are the properties of AWS::IAM::Role
+type IAMRoleProperties struct {
+	AssumeRolePolicyDocument interface{} `json:"AssumeRolePolicyDocument"`
+	ManagedPolicyArns        []string    `json:"ManagedPolicyArns,omitempty"`
+	Tags                     []Tag       `json:"Tags,omitempty"`
+}
+
+// InstanceProfileProperties are the properties of AWS::IAM::InstanceProfile
+type InstanceProfileProperties struct {
+	Roles []interface{} `json:"Roles"`
+}
+
+// PlacementGroupProperties are the properties of AWS::EC2::PlacementGroup
+type PlacementGroupProperties struct {
+	Strategy string `json:"Strategy"`
+}
+
+// FileSystemProperties are the properties of AWS::EFS::FileSystem
+type FileSystemProperties struct {
+	Encrypted       bool   `json:"Encrypted"`
+	PerformanceMode string `json:"PerformanceMode"`
+	ThroughputMode  string `json:"ThroughputMode"`
+	FileSystemTags  []Tag  `json:"FileSystemTags,omitempty"`
+}
+
+// MountTargetProperties are the properties of AWS::EFS::MountTarget
+type MountTargetProperties struct {
+	FileSystemId   interface{}   `json:"FileSystemId"`
+	SubnetId       interface{}   `json:"SubnetId"`
+	SecurityGroups []interface{} `json:"SecurityGroups"`
+}
+
+// MetricDimension is a dimension of a CloudWatch alarm metric
+type MetricDimension struct {
+	Name  string      `json:"Name"`
+	Value interface{} `json:"Value"`
+}
+
+// AlarmProperties are the properties of AWS::CloudWatch::Alarm
+type AlarmProperties struct {
+	AlarmDescription   string            `json:"AlarmDescription"`
+	Namespace          string            `json:"Namespace"`
+	MetricName         string            `json:"MetricName"`
+	Dimensions         []MetricDimension `json:"Dimensions"`
+	Statistic          string            `json:"Statistic"`
+	Period             int               `json:"Period"`
+	EvaluationPeriods  int               `json:"EvaluationPeriods"`
+	Threshold          float64           `json:"Threshold"`
+	ComparisonOperator string            `json:"ComparisonOperator"`
+	AlarmActions       []interface{}     `json:"AlarmActions"`
+}
+
+// EIPProperties are the properties of AWS::EC2::EIP
+type EIPProperties struct {
+	Domain string `json:"Domain"`
+	Tags   []Tag  `json:"Tags,omitempty"`
+}
+
+// EIPAssociationProperties are the properties of AWS::EC2::EIPAssociation
+type EIPAssociationProperties struct {
+	AllocationId interface{} `json:"AllocationId"`
+	InstanceId   interface{} `json:"InstanceId"`
+}
+
+// RecordSetProperties are the properties of AWS::Route53::RecordSet
+type RecordSetProperties struct {
+	HostedZoneId    string        `json:"HostedZoneId"`
+	Name            string        `json:"Name"`
+	Type            string        `json:"Type"`
+	TTL             string        `json:"TTL"`
+	ResourceRecords []interface{} `json:"ResourceRecords"`
+}
+
+// BucketProperties are the properties of AWS::S3::Bucket
+type BucketProperties struct {
+	BucketEncryption               BucketEncryption               `json:"BucketEncryption"`
+	PublicAccessBlockConfiguration PublicAccessBlockConfiguration `json:"PublicAccessBlockConfiguration"`
+	VersioningConfiguration        VersioningConfiguration        `json:"VersioningConfiguration"`
+	Tags                           []Tag                          `json:"Tags,omitempty"`
+}
+
+// BucketEncryption is the default encryption of a bucket
+type BucketEncryption struct {
+	ServerSideEncryptionConfiguration []ServerSideEncryptionRule `json:"ServerSideEncryptionConfiguration"`
+}
+
+// ServerSideEncryptionRule sets the algorithm new objects are encrypted with
+type ServerSideEncryptionRule struct {
+	ServerSideEncryptionByDefault ServerSideEncryptionByDefault `json:"ServerSideEncryptionByDefault"`
+}
+
+// ServerSideEncryptionByDefault names a server-side encryption algorithm
+type ServerSideEncryptionByDefault struct {
+	SSEAlgorithm string `json:"SSEAlgorithm"`
+}
+
+// PublicAccessBlockConfiguration blocks public ACLs and policies on a bucket
+type PublicAccessBlockConfiguration struct {
+	BlockPublicAcls       bool `json:"BlockPublicAcls"`
+	BlockPublicPolicy     bool `json:"BlockPublicPolicy"`
+	IgnorePublicAcls      bool `json:"IgnorePublicAcls"`
+	RestrictPublicBuckets bool `json:"RestrictPublicBuckets"`
+}
+
+// VersioningConfiguration turns object versioning on or off
+type VersioningConfiguration struct {
+	Status string `json:"Status"`
+}
+
+// BucketPolicyProperties are the properties of AWS::S3::BucketPolicy
+type BucketPolicyProperties struct {
+	Bucket         interface{} `json:"Bucket"`
+	PolicyDocument interface{} `json:"PolicyDocument"`
+}
+
+// TrailProperties are the properties of AWS::CloudTrail::Trail
+type TrailProperties struct {
+	IsLogging                  bool        `json:"IsLogging"`
+	S3BucketName               interface{} `json:"S3BucketName"`
+	EnableLogFileValidation    bool        `json:"EnableLogFileValidation"`
+	IncludeGlobalServiceEvents bool        `json:"IncludeGlobalServiceEvents"`
+	IsMultiRegionTrail         bool        `json:"IsMultiRegionTrail"`
+	Tags                       []Tag       `json:"Tags,omitempty"`
+}
+
+// FlowLogProperties are the properties of AWS::EC2::FlowLog
+type FlowLogProperties struct {
+	ResourceId         interface{} `json:"ResourceId"`
+	ResourceType       string      `json:"ResourceType"`
+	TrafficType        string      `json:"TrafficType"`
+	LogDestinationType string      `json:"LogDestinationType"`
+	LogDestination     interface{} `json:"LogDestination"`
+	Tags               []Tag       `json:"Tags,omitempty"`
+}
+
+// JSON renders the template as indented JSON
+func (t *Template) JSON() (string, error) {
+	body, err := json.MarshalIndent(t, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal template: %w", err)
+	}
+	return string(body), nil
+}
+
+func ref(name string) map[string]string {
+	return map[string]string{"Ref": name}
+}
+
+func getAtt(resource, attribute string) map[string][]string {
+	return map[string][]string{"Fn::GetAtt": {resource, attribute}}
+}
+
+func sub(value string) map[string]string {
+	return map[string]string{"Fn::Sub": value}
+}
+
+func base64(value interface{}) map[string]interface{} {
+	return map[string]interface{}{"Fn::Base64": value}
+}
@@ -0,0 +1,127 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+)
+
+// utilizationPeriodSeconds is the resolution of collected utilization samples
+const utilizationPeriodSeconds = 300
+
+// InstanceUtilization holds raw utilization samples for one instance
+type InstanceUtilization struct {
+	InstanceID string
+	Start      time.Time
+	End        time.Time
+	// CPUPercent is average CPU utilization per period
+	CPUPercent []float64
+	// MemoryPercent is empty unless the CloudWatch agent publishes mem_used_percent
+	MemoryPercent []float64
+	// NetworkBytesPerSecond is combined inbound and outbound throughput per period
+	NetworkBytesPerSecond []float64
+}
+
+// HasMemory reports whether memory metrics were available
+func (u *InstanceUtilization) HasMemory() bool {
+	return len(u.MemoryPercent) > 0
+}
+
+// metricDataAPI is the subset of CloudWatch used to collect utilization
+type metricDataAPI interface {
+	GetMetricData(ctx context.Context, params *cloudwatch.GetMetricDataInput, optFns ...func(*cloudwatch.Options)) (*cloudwatch.GetMetricDataOutput, error)
+}
+
+// UtilizationCollector gathers historical utilization from CloudWatch
+type UtilizationCollector struct {
+	api metricDataAPI
+}
+
+// NewUtilizationCollector creates a new utilization collector
+func NewUtilizationCollector(client *Client) *UtilizationCollector {
+	return &UtilizationCollector{api: client.CloudWatch}
+}
+
+// Collect retrieves CPU, memory and network utilization for an instance over a window
+func (uc *UtilizationCollector) Collect(ctx context.Context, instanceID string, start, end time.Time) (*InstanceUtilization, error) {
+	dimensions := []types.Dimension{{Name: aws.String("InstanceId"), Value: aws.String(instanceID)}}
+	query := func(id, namespace, metricName string, stat types.Statistic) types.MetricDataQuery {
+		return types.MetricDataQuery{
+			Id: aws.String(id),
+			MetricStat: &types.MetricStat{
+				Metric: &types.Metric{
+					Namespace:  aws.String(namespace),
+					MetricName: aws.String(metricName),
+					Dimensions: dimensions,
+				},
+				Period: aws.Int32(utilizationPeriodSeconds),
+				Stat:   aws.String(string(stat)),
+			},
+		}
+	}
+
+	input := &cloudwatch.GetMetricDataInput{
+		StartTime: aws.Time(start),
+		EndTime:   aws.Time(end),
+		ScanBy:    types.ScanByTimestampAscending,
+		MetricDataQueries: []types.MetricDataQuery{
+			query("cpu", "AWS/EC2", "CPUUtilization", types.StatisticAverage),
+			query("mem", "CWAgent", "mem_used_percent", types.StatisticAverage),
+			query("netin", "AWS/EC2", "NetworkIn", types.StatisticSum),
+			query("netout", "AWS/EC2", "NetworkOut", types.StatisticSum),
+		},
+	}
+
+	// Inbound and outbound traffic share the "net" series so they sum per period
+	series := make(map[string]map[time.Time]float64)
+
+	paginator := cloudwatch.NewGetMetricDataPaginator(uc.api, input)
+	for paginator.HasMorePages() {
+		result, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get metric data: %w", err)
+		}
+
+		for _, metric := range result.MetricDataResults {
+			id := aws.ToString(metric.Id)
+			if id == "netin" || id == "netout" {
+				id = "net"
+			}
+			if series[id] == nil {
+				series[id] = make(map[time.Time]float64)
+			}
+			for i, timestamp := range metric.Timestamps {
+				if i >= len(metric.Values) {
+					break
+				}
+				series[id][timestamp] += metric.Values[i]
+			}
+		}
+	}
+
+	utilization := &InstanceUtilization{
+		InstanceID:    instanceID,
+		Start:         start,
+		End:           end,
+		CPUPercent:    seriesValues(series["cpu"]),
+		MemoryPercent: seriesValues(series["mem"]),
+	}
+	for _, bytes := range seriesValues(series["net"]) {
+		utilization.NetworkBytesPerSecond = append(utilization.NetworkBytesPerSecond, bytes/utilizationPeriodSeconds)
+	}
+
+	return utilization, nil
+}
+
+// seriesValues returns the values of a series; order does not matter for percentiles
+func seriesValues(series map[time.Time]float64) []float64 {
+	values := make([]float64, 0, len(series))
+	for _, value := range series {
+		values = append(values, value)
+	}
+	return values
+}
@@ -0,0 +1,86 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+)
+
+type fakeMetricDataAPI struct {
+	pages []*cloudwatch.GetMetricDataOutput
+	err   error
+	calls int
+}
+
+func (f *fakeMetricDataAPI) GetMetricData(ctx context.Context, params *cloudwatch.GetMetricDataInput, optFns ...func(*cloudwatch.Options)) (*cloudwatch.GetMetricDataOutput, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	page := f.pages[f.calls]
+	f.calls++
+	return page, nil
+}
+
+func metricResult(id string, timestamps []time.Time, values []float64) types.MetricDataResult {
+	return types.MetricDataResult{Id: aws.String(id), Timestamps: timestamps, Values: values}
+}
+
+func TestUtilizationCollectorCollect(t *testing.T) {
+	t0 := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	t1 := t0.Add(5 * time.Minute)
+
+	api := &fakeMetricDataAPI{pages: []*cloudwatch.GetMetricDataOutput{
+		{
+			MetricDataResults: []types.MetricDataResult{
+				metricResult("cpu", []time.Time{t0}, []float64{10}),
+				metricResult("mem", nil, nil),
+				metricResult("netin", []time.Time{t0, t1}, []float64{3000, 6000}),
+				metricResult("netout", []time.Time{t0}, []float64{300}),
+			},
+			NextToken: aws.String("page2"),
+		},
+		{
+			MetricDataResults: []types.MetricDataResult{
+				metricResult("cpu", []time.Time{t1}, []float64{30}),
+			},
+		},
+	}}
+
+	utilization, err := (&UtilizationCollector{api: api}).Collect(context.Background(), "i-123", t0, t1.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("Collect failed: %v", err)
+	}
+
+	if api.calls != 2 {
+		t.Errorf("Expected both pages to be read, got %d calls", api.calls)
+	}
+
+	cpu := append([]float64(nil), utilization.CPUPercent...)
+	sort.Float64s(cpu)
+	if len(cpu) != 2 || cpu[0] != 10 || cpu[1] != 30 {
+		t.Errorf("Expected CPU samples from both pages, got %v", utilization.CPUPercent)
+	}
+
+	if utilization.HasMemory() {
+		t.Error("Expected no memory data without the CloudWatch agent")
+	}
+
+	network := append([]float64(nil), utilization.NetworkBytesPerSecond...)
+	sort.Float64s(network)
+	if len(network) != 2 || network[0] != 11 || network[1] != 20 {
+		t.Errorf("Expected inbound and outbound bytes summed per period, got %v", network)
+	}
+}
+
+func TestUtilizationCollectorError(t *testing.T) {
+	api := &fakeMetricDataAPI{err: errors.New("throttled")}
+	if _, err := (&UtilizationCollector{api: api}).Collect(context.Background(), "i-123", time.Now().Add(-time.Hour), time.Now()); err == nil {
+		t.Error("Expected error from GetMetricData")
+	}
+}
@@ -0,0 +1,213 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/aws"
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/config"
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/spack"
+)
+
+// packageAudit is the audit report for one domain
+type packageAudit struct {
+	Domain      string          `json:"domain"`
+	IndexSource string          `json:"index_source"`
+	Findings    []spack.Finding `json:"findings"`
+	Problems    int             `json:"problems"`
+	// ArchitectureWarnings are specs that will not build for arm64, checked
+	// when the domain recommends Graviton instances or --prefer-arm is given
+	ArchitectureWarnings []spack.Finding `json:"architecture_warnings,omitempty"`
+}
+
+func createAuditPackagesCommand(configRoot *string) *cobra.Command {
+	var updateIndex bool
+	var indexPath string
+	var noSpack bool
+	var jsonOutput bool
+	var preferARM bool
+
+	cmd := &cobra.Command{
+		Use:   "audit-packages [domain]",
+		Short: "Check a domain's Spack specs against known packages",
+		Long: `Check every Spack spec in a domain pack for unknown packages, version
+constraints no known version satisfies, and deprecated names.
+
+When spack is on PATH the specs are checked against 'spack list'. Otherwise
+they are checked against a package-name index bundled with the binary, or the
+copy saved by --update-index. The bundled index has no versions, so version
+constraints are only checked when spack is available.
+
+When the domain recommends Graviton (arm64) instances, or --prefer-arm is
+given, specs that only build for x86_64 are listed as warnings, such as MKL,
+Intel MPI, CUDA and the Intel compilers.
+
+Exits with status 1 when any spec needs attention. Architecture warnings do
+not change the exit status.
+
+Examples:
+  # Audit the genomics domain pack
+  aws-research-wizard config audit-packages genomics
+
+  # Check the genomics packages will build on Graviton
+  aws-research-wizard config audit-packages genomics --prefer-arm
+
+  # Refresh the saved package index from the local Spack installation
+  aws-research-wizard config audit-packages --update-index`,
+		Args: cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			ctx := context.Background()
+
+			if indexPath == "" {
+				path, err := spack.DefaultIndexPath()
+				if err != nil {
+					log.Fatalf("Failed to locate package index: %v", err)
+				}
+				indexPath = path
+			}
+
+			if updateIndex {
+				if !spack.Available() {
+					log.Fatal("Updating the package index requires spack on PATH")
+				}
+				index, err := spack.IndexFromSpack(ctx)
+				if err != nil {
+					log.Fatalf("Failed to read packages from spack: %v", err)
+				}
+				if err := spack.SaveIndex(indexPath, index); err != nil {
+					log.Fatalf("Failed to save package index: %v", err)
+				}
+				fmt.Printf("✅ Saved %d packages to %s\n", len(index.Packages), indexPath)
+				if len(args) == 0 {
+					return
+				}
+			}
+
+			if len(args) == 0 {
+				log.Fatal("A domain is required unless --update-index is given")
+			}
+
+			if *configRoot == "" {
+				*configRoot = findConfigRoot()
+			}
+
+			domainName := args[0]
+			loader := config.NewConfigLoader(*configRoot)
+			domains, err := loader.LoadAllDomains()
+			if err != nil {
+				log.Fatalf("Failed to load domains: %v", err)
+			}
+
+			domain, exists := domains[domainName]
+			if !exists {
+				log.Fatalf("Domain '%s' not found", domainName)
+			}
+
+			var index *spack.PackageIndex
+			if !noSpack && spack.Available() {
+				index, err = spack.IndexFromSpack(ctx)
+			} else {
+				index, err = spack.LoadIndex(indexPath)
+			}
+			if err != nil {
+				log.Fatalf("Failed to load package index: %v", err)
+			}
+
+			specs := spack.DeclaredSpecs(domain.SpackPackages)
+			report := packageAudit{
+				Domain:      domainName,
+				IndexSource: index.Source,
+				Findings:    spack.Audit(specs, index),
+			}
+			if preferARM || recommendsARM(domain) {
+				report.ArchitectureWarnings = spack.AuditArchitecture(specs, aws.ArchitectureARM64)
+			}
+			for _, finding := range report.Findings {
+				if finding.IsProblem() {
+					report.Problems++
+				}
+			}
+
+			if jsonOutput {
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+				if err := encoder.Encode(report); err != nil {
+					log.Fatalf("Failed to encode audit: %v", err)
+				}
+			} else {
+				printPackageAudit(report)
+				printArchitectureWarnings(report.ArchitectureWarnings)
+			}
+
+			if report.Problems > 0 {
+				os.Exit(1)
+			}
+		},
+	}
+
+	cmd.Flags().BoolVar(&updateIndex, "update-index", false, "Refresh the saved package index from spack")
+	cmd.Flags().StringVar(&indexPath, "index", "", "Package index file (default ~/.aws-research-wizard/spack-package-index.json)")
+	cmd.Flags().BoolVar(&noSpack, "no-spack", false, "Check against the saved or bundled index even when spack is available")
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "Output as JSON")
+	cmd.Flags().BoolVar(&preferARM, "prefer-arm", false, "Warn about specs that do not build for Graviton (arm64) instances")
+
+	return cmd
+}
+
+// recommendsARM reports whether any of the domain's instance recommendations
+// is arm64
+func recommendsARM(domain *config.DomainPack) bool {
+	for _, rec := range domain.AWSInstanceRecommendations {
+		arch := rec.Architecture
+		if arch == "" && rec.InstanceType != "" {
+			arch = aws.InstanceArchitecture(rec.InstanceType)
+		}
+		if arch == aws.ArchitectureARM64 {
+			return true
+		}
+	}
+	return false
+}
+
+// printPackageAudit lists the specs that need attention and a summary
+func printPackageAudit(report packageAudit) {
+	fmt.Printf("📦 Spack package audit: %s (checked against %s index)\n\n", report.Domain, report.IndexSource)
+
+	if report.Problems == 0 {
+		fmt.Printf("✅ All %d packages found\n", len(report.Findings))
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CATEGORY\tPACKAGE\tSTATUS\tDETAIL\tSUGGESTION")
+	for _, finding := range report.Findings {
+		if !finding.IsProblem() {
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", finding.Category, finding.Package, finding.Status, finding.Message, finding.Suggestion)
+	}
+	w.Flush()
+
+	fmt.Printf("\n⚠️  %d of %d packages need attention\n", report.Problems, len(report.Findings))
+}
+
+// printArchitectureWarnings lists the specs that will not build for arm64
+func printArchitectureWarnings(warnings []spack.Finding) {
+	if len(warnings) == 0 {
+		return
+	}
+
+	fmt.Printf("\n⚠️  %d x86_64-only specs will not build on Graviton (arm64) instances:\n\n", len(warnings))
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CATEGORY\tPACKAGE\tDETAIL\tSUGGESTION")
+	for _, warning := range warnings {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", warning.Category, warning.Package, warning.Message, warning.Suggestion)
+	}
+	w.Flush()
+}
@@ -0,0 +1,282 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/aws"
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/config"
+)
+
+// priceFetcher looks up regional prices for a set of instance types
+type priceFetcher interface {
+	FetchRegionPrices(ctx context.Context, region string, instanceTypes []string) (*aws.RegionPrices, error)
+}
+
+// costCell is the estimated monthly cost of one domain in one region
+type costCell struct {
+	Domain  string  `json:"domain"`
+	Region  string  `json:"region"`
+	Compute float64 `json:"compute"`
+	Storage float64 `json:"storage"`
+	Total   float64 `json:"total"`
+	Live    bool    `json:"live_pricing"`
+}
+
+// costMatrix holds one cell per domain and region, rows in domain order
+type costMatrix struct {
+	Domains []string   `json:"domains"`
+	Regions []string   `json:"regions"`
+	Cells   []costCell `json:"cells"`
+	Best    *costCell  `json:"best"`
+	// PreferARM prices compute at the Graviton equivalents of x86_64 recommendations
+	PreferARM bool `json:"prefer_arm,omitempty"`
+}
+
+func createCostCompareCommand(configRoot *string) *cobra.Command {
+	var domainNames []string
+	var regions []string
+	var jsonOutput bool
+	var preferARM bool
+
+	cmd := &cobra.Command{
+		Use:   "compare",
+		Short: "Compare monthly costs across domains and regions",
+		Long: `Compare estimated monthly compute, storage and total costs for several
+domains across several regions.
+
+Prices come from the AWS Price List API when it is reachable and fall back to
+static estimates otherwise. Each region is priced once no matter how many
+domains are compared. The cheapest domain and region is highlighted.
+
+With --prefer-arm, compute is priced at the Graviton (arm64) equivalents of
+the domains' x86_64 instance recommendations, as 'deploy --prefer-arm' would
+launch them.
+
+Examples:
+  # Compare two domains in three regions
+  aws-research-wizard config cost compare --domain genomics --domain climate_modeling \
+    --regions us-east-1,us-west-2,eu-west-1
+
+  # See what moving genomics to Graviton would cost
+  aws-research-wizard config cost compare --domain genomics --prefer-arm`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if *configRoot == "" {
+				*configRoot = findConfigRoot()
+			}
+
+			if len(domainNames) == 0 {
+				log.Fatal("At least one --domain is required")
+			}
+
+			loader := config.NewConfigLoader(*configRoot)
+			domains, err := loader.LoadAllDomains()
+			if err != nil {
+				log.Fatalf("Failed to load domains: %v", err)
+			}
+
+			selected := make(map[string]*config.DomainPack, len(domainNames))
+			for _, name := range domainNames {
+				domain, exists := domains[name]
+				if !exists {
+					log.Fatalf("Domain '%s' not found", name)
+				}
+				selected[name] = domain
+			}
+
+			ctx := context.Background()
+			var fetcher priceFetcher = &aws.RegionalPricer{}
+			if awsClient, err := aws.NewClient(ctx, aws.BaselinePricingRegion); err == nil {
+				fetcher = aws.NewRegionalPricer(awsClient)
+			}
+
+			matrix, err := buildCostMatrix(ctx, fetcher, domainNames, selected, regions, preferARM)
+			if err != nil {
+				log.Fatalf("Failed to compare costs: %v", err)
+			}
+
+			if jsonOutput {
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+				if err := encoder.Encode(matrix); err != nil {
+					log.Fatalf("Failed to encode cost matrix: %v", err)
+				}
+				return
+			}
+
+			printCostMatrix(matrix)
+		},
+	}
+
+	cmd.Flags().StringSliceVar(&domainNames, "domain", nil, "Domain to compare (repeatable)")
+	// Not --region, which is the global flag naming the single region commands run in
+	cmd.Flags().StringSliceVar(&regions, "regions", []string{aws.BaselinePricingRegion}, "AWS regions to compare (comma-separated or repeatable)")
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "Output as JSON")
+	cmd.Flags().BoolVar(&preferARM, "prefer-arm", false, "Price compute at Graviton (arm64) equivalents of the recommended instance types")
+
+	return cmd
+}
+
+// buildCostMatrix scales each domain's baseline cost estimate by regional prices.
+// Prices are fetched once per region for the instance types of every domain.
+// With preferARM, compute is scaled by the price of each recommendation's
+// Graviton equivalent against the baseline price of the recommendation.
+func buildCostMatrix(ctx context.Context, fetcher priceFetcher, domainNames []string, domains map[string]*config.DomainPack, regions []string, preferARM bool) (*costMatrix, error) {
+	instanceTypes := collectInstanceTypes(domainNames, domains, preferARM)
+
+	prices := make(map[string]*aws.RegionPrices)
+	fetch := func(region string) error {
+		if _, done := prices[region]; done {
+			return nil
+		}
+		regionPrices, err := fetcher.FetchRegionPrices(ctx, region, instanceTypes)
+		if err != nil {
+			return fmt.Errorf("failed to fetch prices for %s: %w", region, err)
+		}
+		prices[region] = regionPrices
+		return nil
+	}
+
+	if err := fetch(aws.BaselinePricingRegion); err != nil {
+		return nil, err
+	}
+	baseline := prices[aws.BaselinePricingRegion]
+
+	matrix := &costMatrix{Domains: domainNames, PreferARM: preferARM}
+	for _, region := range regions {
+		if containsRegion(matrix.Regions, region) {
+			continue
+		}
+		if err := fetch(region); err != nil {
+			return nil, err
+		}
+		matrix.Regions = append(matrix.Regions, region)
+	}
+
+	for _, name := range domainNames {
+		domain := domains[name]
+		for _, region := range matrix.Regions {
+			regional := prices[region]
+			cell := costCell{
+				Domain:  name,
+				Region:  region,
+				Compute: domain.EstimatedCost.Compute * computeRatio(domain, baseline, regional, preferARM),
+				Storage: domain.EstimatedCost.Storage * priceRatio(baseline.StorageGBMonth, regional.StorageGBMonth),
+				Live:    regional.Live && baseline.Live,
+			}
+			cell.Total = cell.Compute + cell.Storage
+			matrix.Cells = append(matrix.Cells, cell)
+		}
+	}
+
+	for i := range matrix.Cells {
+		if matrix.Best == nil || matrix.Cells[i].Total < matrix.Best.Total {
+			matrix.Best = &matrix.Cells[i]
+		}
+	}
+
+	return matrix, nil
+}
+
+// collectInstanceTypes returns the sorted, unique instance types recommended by
+// the domains, along with their Graviton equivalents when preferARM is set
+func collectInstanceTypes(domainNames []string, domains map[string]*config.DomainPack, preferARM bool) []string {
+	seen := make(map[string]bool)
+	var instanceTypes []string
+	add := func(instanceType string) {
+		if instanceType == "" || seen[instanceType] {
+			return
+		}
+		seen[instanceType] = true
+		instanceTypes = append(instanceTypes, instanceType)
+	}
+	for _, name := range domainNames {
+		for _, recommendation := range domains[name].AWSInstanceRecommendations {
+			add(recommendation.InstanceType)
+			if preferARM {
+				add(aws.PreferGraviton(recommendation.InstanceType))
+			}
+		}
+	}
+	sort.Strings(instanceTypes)
+	return instanceTypes
+}
+
+// computeRatio averages the regional to baseline price ratio over a domain's
+// instances. With preferARM the regional price is that of the Graviton
+// equivalent, where the region has one.
+func computeRatio(domain *config.DomainPack, baseline, regional *aws.RegionPrices, preferARM bool) float64 {
+	var sum float64
+	var count int
+	for _, recommendation := range domain.AWSInstanceRecommendations {
+		base, ok := baseline.InstanceHourly[recommendation.InstanceType]
+		if !ok || base <= 0 {
+			continue
+		}
+		price := regional.InstanceHourly[recommendation.InstanceType]
+		if preferARM {
+			if graviton := regional.InstanceHourly[aws.PreferGraviton(recommendation.InstanceType)]; graviton > 0 {
+				price = graviton
+			}
+		}
+		sum += priceRatio(base, price)
+		count++
+	}
+	if count == 0 {
+		return 1
+	}
+	return sum / float64(count)
+}
+
+func priceRatio(base, regional float64) float64 {
+	if base <= 0 || regional <= 0 {
+		return 1
+	}
+	return regional / base
+}
+
+func containsRegion(regions []string, region string) bool {
+	for _, r := range regions {
+		if r == region {
+			return true
+		}
+	}
+	return false
+}
+
+func printCostMatrix(matrix *costMatrix) {
+	fmt.Printf("💰 Monthly Cost Comparison\n")
+	if matrix.PreferARM {
+		fmt.Printf("Compute priced at Graviton (arm64) equivalents\n")
+	}
+	fmt.Println()
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "DOMAIN\tREGION\tCOMPUTE\tSTORAGE\tTOTAL\tPRICING\t")
+	for i := range matrix.Cells {
+		cell := &matrix.Cells[i]
+		pricing := "estimated"
+		if cell.Live {
+			pricing = "live"
+		}
+		marker := ""
+		if cell == matrix.Best {
+			marker = "⭐ cheapest"
+		}
+		fmt.Fprintf(w, "%s\t%s\t$%.2f\t$%.2f\t$%.2f\t%s\t%s\n",
+			cell.Domain, cell.Region, cell.Compute, cell.Storage, cell.Total, pricing, marker)
+	}
+	w.Flush()
+
+	if matrix.Best != nil {
+		fmt.Printf("\n⭐ Cheapest: %s in %s at $%.2f/month\n", matrix.Best.Domain, matrix.Best.Region, matrix.Best.Total)
+	}
+}
@@ -0,0 +1,189 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/aws"
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/config"
+)
+
+// fakePriceFetcher serves fixture prices and counts lookups per region
+type fakePriceFetcher struct {
+	prices map[string]*aws.RegionPrices
+	calls  map[string]int
+}
+
+func (f *fakePriceFetcher) FetchRegionPrices(ctx context.Context, region string, instanceTypes []string) (*aws.RegionPrices, error) {
+	f.calls[region]++
+	prices, ok := f.prices[region]
+	if !ok {
+		return nil, fmt.Errorf("no fixture for %s", region)
+	}
+	return prices, nil
+}
+
+func fixtureFetcher() *fakePriceFetcher {
+	return &fakePriceFetcher{
+		prices: map[string]*aws.RegionPrices{
+			"us-east-1": {
+				Region:         "us-east-1",
+				InstanceHourly: map[string]float64{"c6i.large": 0.10, "r6i.large": 0.20, "c7g.large": 0.08, "r7g.large": 0.16},
+				StorageGBMonth: 0.08,
+				Live:           true,
+			},
+			"eu-west-1": {
+				Region:         "eu-west-1",
+				InstanceHourly: map[string]float64{"c6i.large": 0.12, "r6i.large": 0.30, "c7g.large": 0.09},
+				StorageGBMonth: 0.088,
+				Live:           true,
+			},
+			"us-west-2": {
+				Region:         "us-west-2",
+				InstanceHourly: map[string]float64{"c6i.large": 0.09, "r6i.large": 0.20},
+				StorageGBMonth: 0.08,
+			},
+		},
+		calls: make(map[string]int),
+	}
+}
+
+func fixtureDomains() map[string]*config.DomainPack {
+	return map[string]*config.DomainPack{
+		"genomics": {
+			Name: "Genomics",
+			AWSInstanceRecommendations: map[string]config.InstanceRecommendation{
+				"standard": {InstanceType: "c6i.large"},
+			},
+			EstimatedCost: config.EstimatedCost{Compute: 100, Storage: 50, Total: 150},
+		},
+		"climate": {
+			Name: "Climate",
+			AWSInstanceRecommendations: map[string]config.InstanceRecommendation{
+				"standard": {InstanceType: "c6i.large"},
+				"memory":   {InstanceType: "r6i.large"},
+			},
+			EstimatedCost: config.EstimatedCost{Compute: 200, Storage: 20, Total: 220},
+		},
+	}
+}
+
+func approxEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestBuildCostMatrix(t *testing.T) {
+	fetcher := fixtureFetcher()
+	regions := []string{"eu-west-1", "us-west-2", "eu-west-1"}
+
+	matrix, err := buildCostMatrix(context.Background(), fetcher, []string{"genomics", "climate"}, fixtureDomains(), regions, false)
+	if err != nil {
+		t.Fatalf("buildCostMatrix failed: %v", err)
+	}
+
+	if len(matrix.Regions) != 2 || matrix.Regions[0] != "eu-west-1" || matrix.Regions[1] != "us-west-2" {
+		t.Errorf("Expected deduplicated regions in order, got %v", matrix.Regions)
+	}
+	if len(matrix.Cells) != 4 {
+		t.Fatalf("Expected 4 cells, got %d", len(matrix.Cells))
+	}
+
+	for region, count := range fetcher.calls {
+		if count != 1 {
+			t.Errorf("Expected one price lookup for %s, got %d", region, count)
+		}
+	}
+	if fetcher.calls["us-east-1"] != 1 {
+		t.Errorf("Expected the baseline region to be priced once, got %d", fetcher.calls["us-east-1"])
+	}
+
+	// genomics in eu-west-1: compute 100 * 1.2, storage 50 * 1.1
+	genomicsEU := matrix.Cells[0]
+	if genomicsEU.Domain != "genomics" || genomicsEU.Region != "eu-west-1" {
+		t.Fatalf("Unexpected first cell: %+v", genomicsEU)
+	}
+	if !approxEqual(genomicsEU.Compute, 120) || !approxEqual(genomicsEU.Storage, 55) || !approxEqual(genomicsEU.Total, 175) {
+		t.Errorf("Unexpected genomics eu-west-1 costs: %+v", genomicsEU)
+	}
+	if !genomicsEU.Live {
+		t.Error("Expected live pricing when both regions are live")
+	}
+
+	// climate in eu-west-1 averages the c6i (1.2) and r6i (1.5) ratios
+	climateEU := matrix.Cells[2]
+	if !approxEqual(climateEU.Compute, 270) {
+		t.Errorf("Expected averaged compute ratio, got %.2f", climateEU.Compute)
+	}
+
+	genomicsWest := matrix.Cells[1]
+	if genomicsWest.Live {
+		t.Error("Expected estimated pricing when a region is not live")
+	}
+}
+
+func TestBuildCostMatrixBestChoice(t *testing.T) {
+	matrix, err := buildCostMatrix(context.Background(), fixtureFetcher(), []string{"genomics", "climate"}, fixtureDomains(), []string{"us-east-1", "eu-west-1", "us-west-2"}, false)
+	if err != nil {
+		t.Fatalf("buildCostMatrix failed: %v", err)
+	}
+
+	// genomics in us-west-2: compute 100 * 0.9 + storage 50 = 140
+	if matrix.Best == nil || matrix.Best.Domain != "genomics" || matrix.Best.Region != "us-west-2" {
+		t.Fatalf("Expected genomics in us-west-2 to be cheapest, got %+v", matrix.Best)
+	}
+	if !approxEqual(matrix.Best.Total, 140) {
+		t.Errorf("Expected best total 140, got %.2f", matrix.Best.Total)
+	}
+}
+
+func TestBuildCostMatrixTieKeepsFirst(t *testing.T) {
+	domains := map[string]*config.DomainPack{
+		"a": {EstimatedCost: config.EstimatedCost{Compute: 10, Storage: 10}},
+		"b": {EstimatedCost: config.EstimatedCost{Compute: 10, Storage: 10}},
+	}
+
+	matrix, err := buildCostMatrix(context.Background(), fixtureFetcher(), []string{"a", "b"}, domains, []string{"us-east-1"}, false)
+	if err != nil {
+		t.Fatalf("buildCostMatrix failed: %v", err)
+	}
+	if matrix.Best != &matrix.Cells[0] {
+		t.Errorf("Expected the first cell to win a tie, got %+v", matrix.Best)
+	}
+}
+
+func TestBuildCostMatrixFetchError(t *testing.T) {
+	_, err := buildCostMatrix(context.Background(), fixtureFetcher(), []string{"genomics"}, fixtureDomains(), []string{"ap-south-1"}, false)
+	if err == nil {
+		t.Fatal("Expected error when a region cannot be priced")
+	}
+}
+
+func TestBuildCostMatrixPreferARM(t *testing.T) {
+	matrix, err := buildCostMatrix(context.Background(), fixtureFetcher(), []string{"genomics", "climate"}, fixtureDomains(), []string{"us-east-1", "eu-west-1"}, true)
+	if err != nil {
+		t.Fatalf("buildCostMatrix failed: %v", err)
+	}
+	if !matrix.PreferARM {
+		t.Error("Expected the matrix to note Graviton pricing")
+	}
+
+	// genomics in us-east-1: c7g.large 0.08 against c6i.large 0.10
+	if cell := matrix.Cells[0]; cell.Region != "us-east-1" || !approxEqual(cell.Compute, 80) {
+		t.Errorf("Unexpected genomics us-east-1 cell: %+v", cell)
+	}
+	// climate in eu-west-1 averages c7g (0.9) with r6i (1.5), as the region
+	// has no r7g price
+	if cell := matrix.Cells[3]; cell.Region != "eu-west-1" || !approxEqual(cell.Compute, 240) {
+		t.Errorf("Unexpected climate eu-west-1 cell: %+v", cell)
+	}
+}
+
+func TestCollectInstanceTypesPreferARM(t *testing.T) {
+	got := collectInstanceTypes([]string{"genomics", "climate"}, fixtureDomains(), true)
+	want := []string{"c6i.large", "c7g.large", "r6i.large", "r7g.large"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("collectInstanceTypes() = %v, want %v", got, want)
+	}
+}
@@ -3,10 +3,13 @@ package config
 import (
 	"context"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 
@@ -46,6 +49,10 @@ Available operations:
 		createInfoCommand(&configRoot),
 		createCostCommand(&configRoot),
 		createSearchCommand(&configRoot),
+		createAuditPackagesCommand(&configRoot),
+		createRecalcCostsCommand(&configRoot),
+		createDataSourcesCommand(&configRoot),
+		createValidateCommand(&configRoot),
 	)
 
 	return configCmd
@@ -140,18 +147,49 @@ func createListCommand(configRoot *string) *cobra.Command {
 				log.Fatalf("Failed to load domains: %v", err)
 			}
 
-			fmt.Printf("Available Research Domains (%d total):\n\n", len(domains))
-
-			for name, domain := range domains {
-				fmt.Printf("📚 %s\n", name)
-				fmt.Printf("   %s\n", domain.Description)
-				fmt.Printf("   Target Users: %v\n", domain.TargetUsers)
-				fmt.Printf("   Monthly Cost: $%.0f\n\n", domain.EstimatedCost.Total)
-			}
+			printDomainList(os.Stdout, domains)
 		},
 	}
 }
 
+// printDomainList lists the domains by name, marking deprecated ones
+func printDomainList(w io.Writer, domains map[string]*config.DomainPack) {
+	names := make([]string, 0, len(domains))
+	for name := range domains {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintf(w, "Available Research Domains (%d total):\n\n", len(domains))
+	for _, name := range names {
+		domain := domains[name]
+		if label := deprecationLabel(domain); label != "" {
+			fmt.Fprintf(w, "📚 %s (%s)\n", name, label)
+		} else {
+			fmt.Fprintf(w, "📚 %s\n", name)
+		}
+		fmt.Fprintf(w, "   %s\n", domain.Description)
+		fmt.Fprintf(w, "   Target Users: %v\n", domain.TargetUsers)
+		fmt.Fprintf(w, "   Monthly Cost: $%.0f\n\n", domain.EstimatedCost.Total)
+	}
+}
+
+// deprecationLabel summarizes a deprecated domain's replacement and sunset
+// date for listings, or returns "" for a domain that is not deprecated
+func deprecationLabel(domain *config.DomainPack) string {
+	if !domain.Deprecated {
+		return ""
+	}
+	parts := []string{"deprecated"}
+	if domain.ReplacedBy != "" {
+		parts = append(parts, "use "+domain.ReplacedBy)
+	}
+	if domain.SunsetDate != "" {
+		parts = append(parts, "removed "+domain.SunsetDate)
+	}
+	return strings.Join(parts, ", ")
+}
+
 func createInfoCommand(configRoot *string) *cobra.Command {
 	return &cobra.Command{
 		Use:   "info [domain]",
@@ -175,6 +213,9 @@ func createInfoCommand(configRoot *string) *cobra.Command {
 			}
 
 			fmt.Printf("🔬 Domain: %s\n\n", domain.Name)
+			if notice := domain.DeprecationNotice(domainName, time.Now()); notice != "" {
+				fmt.Printf("⚠️  %s\n\n", notice)
+			}
 			fmt.Printf("Description: %s\n\n", domain.Description)
 
 			fmt.Printf("Target Users: %s\n", domain.TargetUsers)
@@ -190,6 +231,13 @@ func createInfoCommand(configRoot *string) *cobra.Command {
 					rec.UseCase, rec.InstanceType, rec.VCPUs, rec.MemoryGB, rec.CostPerHour)
 			}
 
+			if sizes := domain.InstanceTypesByWorkloadSize(); len(sizes) > 0 {
+				fmt.Printf("\nInstance by Workload Size:\n")
+				for _, size := range config.WorkloadSizes {
+					fmt.Printf("  • %s: %s\n", size, sizes[size])
+				}
+			}
+
 			fmt.Printf("\nEstimated Costs:\n")
 			fmt.Printf("  • Compute: $%.0f/month\n", domain.EstimatedCost.Compute)
 			fmt.Printf("  • Storage: $%.0f/month\n", domain.EstimatedCost.Storage)
@@ -199,7 +247,7 @@ func createInfoCommand(configRoot *string) *cobra.Command {
 }
 
 func createCostCommand(configRoot *string) *cobra.Command {
-	return &cobra.Command{
+	cmd := &cobra.Command{
 		Use:   "cost [domain]",
 		Short: "Calculate costs for a specific domain",
 		Args:  cobra.ExactArgs(1),
@@ -229,6 +277,10 @@ func createCostCommand(configRoot *string) *cobra.Command {
 			}
 		},
 	}
+
+	cmd.AddCommand(createCostCompareCommand(configRoot))
+
+	return cmd
 }
 
 func createSearchCommand(configRoot *string) *cobra.Command {
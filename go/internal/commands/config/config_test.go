@@ -0,0 +1,35 @@
+package config
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/config"
+)
+
+func TestPrintDomainListMarksDeprecated(t *testing.T) {
+	domains := map[string]*config.DomainPack{
+		"genomics":        {Description: "Genome analysis"},
+		"genomics-legacy": {Description: "Old genome analysis", Deprecated: true, ReplacedBy: "genomics", SunsetDate: "2027-01-31"},
+		"retired":         {Description: "Retired pack", Deprecated: true},
+	}
+
+	var out bytes.Buffer
+	printDomainList(&out, domains)
+	text := out.String()
+
+	for _, want := range []string{
+		"Available Research Domains (3 total)",
+		"📚 genomics\n",
+		"📚 genomics-legacy (deprecated, use genomics, removed 2027-01-31)\n",
+		"📚 retired (deprecated)\n",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("output missing %q:\n%s", want, text)
+		}
+	}
+	if strings.Index(text, "genomics-legacy") > strings.Index(text, "retired") {
+		t.Errorf("domains are not listed by name:\n%s", text)
+	}
+}
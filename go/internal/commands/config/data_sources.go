@@ -0,0 +1,101 @@
+package config
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/spf13/cobra"
+
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/config"
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/data"
+)
+
+func createDataSourcesCommand(configRoot *string) *cobra.Command {
+	return &cobra.Command{
+		Use:   "data-sources [domain]",
+		Short: "List the datasets available to a domain",
+		Long: `List the datasets a domain works with: the data sources declared in its
+domain pack and the AWS Open Data catalog datasets tagged with the domain.
+Sources declared only by name are located through the catalog.
+
+Any dataset listed can be staged with 'data stage' or sized with
+'data sources describe'.
+
+Examples:
+  aws-research-wizard config data-sources genomics`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if *configRoot == "" {
+				*configRoot = findConfigRoot()
+			}
+
+			domainName := args[0]
+			domains, err := config.NewConfigLoader(*configRoot).LoadAllDomains()
+			if err != nil {
+				log.Fatalf("Failed to load domains: %v", err)
+			}
+			domain, exists := domains[domainName]
+			if !exists {
+				log.Fatalf("Domain '%s' not found", domainName)
+			}
+
+			catalog, err := data.LoadOpenDataCatalog(data.OpenDataCatalogPath(*configRoot))
+			if err != nil {
+				log.Printf("Warning: %v; showing declared sources only", err)
+				catalog = &data.OpenDataCatalog{}
+			}
+
+			datasets := catalog.DomainDatasets(domainName, domain.DataSources())
+			fmt.Printf("📚 Data sources for %s (%d):\n", domain.Name, len(datasets))
+			for _, dataset := range datasets {
+				fmt.Printf("\n  %s\n", dataset.Name)
+				if dataset.Description != "" {
+					fmt.Printf("    %s\n", dataset.Description)
+				}
+				fmt.Printf("    Location: %s\n", dataset.Location)
+				if dataset.SizeTB > 0 {
+					fmt.Printf("    Size: ~%s TB\n", formatSizeTB(dataset.SizeTB))
+				}
+				if dataset.License != "" {
+					fmt.Printf("    License: %s\n", dataset.License)
+				}
+				if dataset.RegistryURL != "" {
+					fmt.Printf("    Registry: %s\n", dataset.RegistryURL)
+				}
+			}
+
+			// Sources neither declared with a bucket nor found in the catalog
+			var unlocated []config.DataSource
+			for _, source := range domain.DataSources() {
+				if source.Bucket == "" && !catalogHasName(catalog, source.Name) {
+					unlocated = append(unlocated, source)
+				}
+			}
+			if len(unlocated) > 0 {
+				fmt.Printf("\n⚠️  Not in the Open Data catalog; add a bucket to the domain pack to use these:\n")
+				for _, source := range unlocated {
+					fmt.Printf("  • %s\n", source.Name)
+				}
+			}
+		},
+	}
+}
+
+// catalogHasName reports whether a catalog dataset has a name, ignoring case
+// and punctuation
+func catalogHasName(catalog *data.OpenDataCatalog, name string) bool {
+	for _, dataset := range catalog.Datasets {
+		if data.SameDatasetName(dataset.Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// formatSizeTB shows whole terabytes without decimals
+func formatSizeTB(tb float64) string {
+	if tb == float64(int64(tb)) {
+		return fmt.Sprintf("%d", int64(tb))
+	}
+	return fmt.Sprintf("%.1f", tb)
+}
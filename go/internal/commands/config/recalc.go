@@ -0,0 +1,315 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/aws"
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/config"
+)
+
+// Defaults for recalculating domain cost estimates
+const (
+	defaultRecalcUtilization = 0.5
+	recalcHoursPerMonth      = 730
+)
+
+// estimatedCostKey is the domain YAML block holding the cost estimate
+const estimatedCostKey = "estimated_cost"
+
+// recalcOptions select what a domain's cost estimate is recalculated from
+type recalcOptions struct {
+	UseCase     string
+	Utilization float64
+	// DatasetGB overrides the domain's declared typical dataset size
+	DatasetGB float64
+}
+
+// costChange is one estimated_cost value before and after recalculation, as
+// written in the YAML
+type costChange struct {
+	Field string
+	Old   string
+	New   string
+}
+
+// costRecalc is the recalculated cost estimate of one domain
+type costRecalc struct {
+	Domain       string
+	Path         string
+	UseCase      string
+	InstanceType string
+	HourlyPrice  float64
+	DatasetGB    float64
+	Live         bool
+	Changes      []costChange
+	// Notes explain values that were kept, such as storage without a dataset size
+	Notes []string
+	// Skipped explains why nothing could be recalculated
+	Skipped string
+}
+
+func createRecalcCostsCommand(configRoot *string) *cobra.Command {
+	var domainName string
+	var opts recalcOptions
+	var write bool
+
+	cmd := &cobra.Command{
+		Use:   "recalc-costs",
+		Short: "Recalculate domain cost estimates from current prices",
+		Long: `Recalculate the estimated_cost block of domain packs from current prices and
+print the old and new values.
+
+Compute cost is the on-demand price of the domain's instance recommendation
+for --use-case running --utilization of the month. Storage cost is the gp3
+price of the typical_dataset_gb declared in the estimated_cost block; domains
+without one keep their storage cost. The total changes by the same amounts, so
+other line items such as data transfer are kept.
+
+Prices come from the AWS Price List API when it is reachable and fall back to
+static estimates otherwise. With --write the YAML files are updated in place;
+only the changed numbers are rewritten, so comments, key order and layout are
+kept.
+
+Examples:
+  # Show how every domain's estimate has drifted
+  aws-research-wizard config recalc-costs
+
+  # Update one domain, assuming instances run a third of the time
+  aws-research-wizard config recalc-costs --domain genomics --utilization 0.33 --write`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if *configRoot == "" {
+				*configRoot = findConfigRoot()
+			}
+			if opts.Utilization <= 0 || opts.Utilization > 1 {
+				log.Fatalf("--utilization must be between 0 and 1")
+			}
+			if opts.DatasetGB > 0 && domainName == "" {
+				log.Fatalf("--dataset-gb applies to a single --domain")
+			}
+
+			loader := config.NewConfigLoader(*configRoot)
+			files, err := loader.DomainFiles()
+			if err != nil {
+				log.Fatalf("Failed to find domains: %v", err)
+			}
+			names := make([]string, 0, len(files))
+			for name := range files {
+				if domainName == "" || name == domainName {
+					names = append(names, name)
+				}
+			}
+			if len(names) == 0 {
+				log.Fatalf("Domain '%s' not found", domainName)
+			}
+			sort.Strings(names)
+
+			domains := make(map[string]*config.DomainPack, len(names))
+			for _, name := range names {
+				domain, err := loader.LoadDomain(files[name])
+				if err != nil {
+					log.Fatalf("Failed to load domain %s: %v", name, err)
+				}
+				domains[name] = domain
+			}
+
+			ctx := context.Background()
+			var fetcher priceFetcher = &aws.RegionalPricer{}
+			if awsClient, err := aws.NewClient(ctx, aws.BaselinePricingRegion); err == nil {
+				fetcher = aws.NewRegionalPricer(awsClient)
+			}
+			prices, err := fetcher.FetchRegionPrices(ctx, aws.BaselinePricingRegion, collectInstanceTypes(names, domains, false))
+			if err != nil {
+				log.Fatalf("Failed to fetch prices: %v", err)
+			}
+
+			changed := 0
+			for _, name := range names {
+				path := files[name]
+				content, err := os.ReadFile(path)
+				if err != nil {
+					log.Fatalf("Failed to read %s: %v", path, err)
+				}
+
+				recalc, edits, err := recalculateDomainCost(name, content, domains[name], prices, opts)
+				if err != nil {
+					log.Fatalf("Failed to recalculate %s: %v", name, err)
+				}
+				recalc.Path = path
+				printCostRecalc(recalc)
+
+				if len(edits) == 0 {
+					continue
+				}
+				changed++
+				if !write {
+					continue
+				}
+				updated, err := config.EditYAMLScalars(content, edits)
+				if err != nil {
+					log.Fatalf("Failed to update %s: %v", path, err)
+				}
+				info, err := os.Stat(path)
+				if err != nil {
+					log.Fatalf("Failed to stat %s: %v", path, err)
+				}
+				if err := os.WriteFile(path, updated, info.Mode().Perm()); err != nil {
+					log.Fatalf("Failed to write %s: %v", path, err)
+				}
+			}
+
+			pricing := "estimated"
+			if prices.Live {
+				pricing = "live"
+			}
+			switch {
+			case changed == 0:
+				fmt.Printf("✅ All cost estimates are current (%s pricing)\n", pricing)
+			case write:
+				fmt.Printf("✅ Updated %d domain file(s) (%s pricing)\n", changed, pricing)
+			default:
+				fmt.Printf("💡 %d domain(s) would change (%s pricing); rerun with --write to update the files\n", changed, pricing)
+			}
+		},
+	}
+
+	cmd.Flags().StringVar(&domainName, "domain", "", "Domain to recalculate (default all)")
+	cmd.Flags().StringVar(&opts.UseCase, "use-case", config.StandardUseCase, "Instance recommendation to cost compute from")
+	cmd.Flags().Float64Var(&opts.Utilization, "utilization", defaultRecalcUtilization, "Fraction of the month the instance runs")
+	cmd.Flags().Float64Var(&opts.DatasetGB, "dataset-gb", 0, "Typical dataset size in GB, overriding the domain's typical_dataset_gb")
+	cmd.Flags().BoolVar(&write, "write", false, "Rewrite the YAML files in place")
+
+	return cmd
+}
+
+// recalculateDomainCost recomputes a domain's compute and storage cost and
+// returns the edits that bring its YAML up to date. Values are compared as
+// written, so a file is only edited when a rounded value actually changes.
+func recalculateDomainCost(name string, content []byte, domain *config.DomainPack, prices *aws.RegionPrices, opts recalcOptions) (*costRecalc, []config.YAMLScalarEdit, error) {
+	recalc := &costRecalc{Domain: name, Live: prices.Live}
+
+	raw := make(map[string]string)
+	for _, field := range []string{"compute", "storage", "total"} {
+		value, found, err := config.ReadYAMLScalar(content, estimatedCostKey, field)
+		if err != nil {
+			return nil, nil, err
+		}
+		if found {
+			raw[field] = value
+		}
+	}
+	if raw["total"] == "" {
+		recalc.Skipped = "no estimated_cost total"
+		return recalc, nil, nil
+	}
+
+	key, recommendation, ok := domain.Recommendation(opts.UseCase)
+	if !ok {
+		recalc.Skipped = fmt.Sprintf("no %s instance recommendation", opts.UseCase)
+		return recalc, nil, nil
+	}
+	recalc.UseCase = key
+	recalc.InstanceType = recommendation.InstanceType
+	recalc.HourlyPrice = prices.InstanceHourly[recommendation.InstanceType]
+	if recalc.HourlyPrice <= 0 {
+		recalc.Skipped = fmt.Sprintf("no price for %s", recommendation.InstanceType)
+		return recalc, nil, nil
+	}
+
+	values := make(map[string]float64)
+	for field, value := range raw {
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, nil, fmt.Errorf("estimated_cost.%s is not a number: %q", field, value)
+		}
+		values[field] = parsed
+	}
+	updated := map[string]float64{"total": values["total"]}
+
+	if _, ok := raw["compute"]; ok {
+		updated["compute"] = recalc.HourlyPrice * recalcHoursPerMonth * opts.Utilization
+		updated["total"] += updated["compute"] - values["compute"]
+	} else {
+		recalc.Notes = append(recalc.Notes, "no compute line item to update")
+	}
+
+	recalc.DatasetGB = domain.EstimatedCost.TypicalDatasetGB
+	if opts.DatasetGB > 0 {
+		recalc.DatasetGB = opts.DatasetGB
+	}
+	switch _, ok := raw["storage"]; {
+	case !ok:
+		recalc.Notes = append(recalc.Notes, "no storage line item to update")
+	case recalc.DatasetGB <= 0:
+		recalc.Notes = append(recalc.Notes, "storage kept: no typical_dataset_gb declared")
+	default:
+		updated["storage"] = recalc.DatasetGB * prices.StorageGBMonth
+		updated["total"] += updated["storage"] - values["storage"]
+	}
+
+	var edits []config.YAMLScalarEdit
+	for _, field := range []string{"compute", "storage", "total"} {
+		value, ok := updated[field]
+		if !ok {
+			continue
+		}
+		formatted := formatCostLike(raw[field], value)
+		if formatted == raw[field] {
+			continue
+		}
+		recalc.Changes = append(recalc.Changes, costChange{Field: field, Old: raw[field], New: formatted})
+		edits = append(edits, config.YAMLScalarEdit{Path: []string{estimatedCostKey, field}, Value: formatted})
+	}
+	return recalc, edits, nil
+}
+
+// formatCostLike formats a cost the way the value it replaces was written:
+// whole dollars, or with as many decimals as the old value had. A value that
+// rounds to the old one is left as written.
+func formatCostLike(old string, value float64) string {
+	decimals := 0
+	if dot := strings.IndexByte(old, '.'); dot >= 0 {
+		decimals = len(old) - dot - 1
+	}
+	if previous, err := strconv.ParseFloat(old, 64); err == nil {
+		scale := math.Pow(10, float64(decimals))
+		if math.Round(previous*scale) == math.Round(value*scale) {
+			return old
+		}
+	}
+	return strconv.FormatFloat(value, 'f', decimals, 64)
+}
+
+func printCostRecalc(recalc *costRecalc) {
+	fmt.Printf("--- %s\n", recalc.Path)
+	if recalc.Skipped != "" {
+		fmt.Printf("  skipped: %s\n\n", recalc.Skipped)
+		return
+	}
+
+	fmt.Printf("  %s: %s at $%.4f/hour", recalc.UseCase, recalc.InstanceType, recalc.HourlyPrice)
+	if recalc.DatasetGB > 0 {
+		fmt.Printf(", %.0f GB dataset", recalc.DatasetGB)
+	}
+	fmt.Println()
+	if len(recalc.Changes) == 0 {
+		fmt.Printf("  unchanged\n")
+	}
+	for _, change := range recalc.Changes {
+		fmt.Printf("- %s.%s: %s\n", estimatedCostKey, change.Field, change.Old)
+		fmt.Printf("+ %s.%s: %s\n", estimatedCostKey, change.Field, change.New)
+	}
+	for _, note := range recalc.Notes {
+		fmt.Printf("  %s\n", note)
+	}
+	fmt.Println()
+}
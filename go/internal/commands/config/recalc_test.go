@@ -0,0 +1,164 @@
+package config
+
+import (
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/aws"
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/config"
+)
+
+const recalcFixture = `name: Genomics
+# Instance recommendations are maintained by hand
+aws_instance_recommendations:
+  development:
+    instance_type: c6i.large
+    memory_gb: 4
+  standard_analysis:
+    instance_type: r6i.large   # GATK needs the memory
+    memory_gb: 16
+estimated_cost:
+  compute: 600
+  storage: 200
+  data_transfer: 100  # egress to collaborators
+  typical_dataset_gb: 1000
+  total: 900
+tutorials: []
+`
+
+func recalcPrices() *aws.RegionPrices {
+	return &aws.RegionPrices{
+		Region:         "us-east-1",
+		InstanceHourly: map[string]float64{"c6i.large": 0.10, "r6i.large": 0.20},
+		StorageGBMonth: 0.08,
+		Live:           true,
+	}
+}
+
+func loadRecalcFixture(t *testing.T, content string) *config.DomainPack {
+	t.Helper()
+	var domain config.DomainPack
+	if err := yaml.Unmarshal([]byte(content), &domain); err != nil {
+		t.Fatal(err)
+	}
+	return &domain
+}
+
+func TestRecalculateDomainCost(t *testing.T) {
+	domain := loadRecalcFixture(t, recalcFixture)
+	opts := recalcOptions{UseCase: config.StandardUseCase, Utilization: 0.5}
+
+	recalc, edits, err := recalculateDomainCost("genomics", []byte(recalcFixture), domain, recalcPrices(), opts)
+	if err != nil {
+		t.Fatalf("recalculateDomainCost() error = %v", err)
+	}
+	if recalc.UseCase != "standard_analysis" || recalc.InstanceType != "r6i.large" || !recalc.Live {
+		t.Errorf("recalc = %+v", recalc)
+	}
+
+	// compute 0.20 * 730 * 0.5 = 73, storage 1000 * 0.08 = 80, and the total
+	// keeps data transfer: 900 - 600 - 200 + 73 + 80 = 253
+	want := map[string][2]string{
+		"compute": {"600", "73"},
+		"storage": {"200", "80"},
+		"total":   {"900", "253"},
+	}
+	if len(recalc.Changes) != len(want) {
+		t.Fatalf("changes = %+v", recalc.Changes)
+	}
+	for _, change := range recalc.Changes {
+		if w := want[change.Field]; change.Old != w[0] || change.New != w[1] {
+			t.Errorf("%s: %s -> %s, want %s -> %s", change.Field, change.Old, change.New, w[0], w[1])
+		}
+	}
+
+	updated, err := config.EditYAMLScalars([]byte(recalcFixture), edits)
+	if err != nil {
+		t.Fatalf("EditYAMLScalars() error = %v", err)
+	}
+	expected := strings.NewReplacer(
+		"  compute: 600\n", "  compute: 73\n",
+		"  storage: 200\n", "  storage: 80\n",
+		"  total: 900\n", "  total: 253\n",
+	).Replace(recalcFixture)
+	if string(updated) != expected {
+		t.Errorf("rewritten YAML changed more than the costs:\n%s", updated)
+	}
+
+	// Recalculating the rewritten file finds nothing left to change
+	recalc, edits, err = recalculateDomainCost("genomics", updated, loadRecalcFixture(t, string(updated)), recalcPrices(), opts)
+	if err != nil || len(edits) != 0 || len(recalc.Changes) != 0 {
+		t.Errorf("second pass = %+v, %d edits, %v", recalc.Changes, len(edits), err)
+	}
+}
+
+func TestRecalculateDomainCostWithoutDataset(t *testing.T) {
+	content := strings.Replace(recalcFixture, "  typical_dataset_gb: 1000\n", "", 1)
+	content = strings.NewReplacer("compute: 600", "compute: 600.0", "total: 900", "total: 900.0").Replace(content)
+	opts := recalcOptions{UseCase: "development", Utilization: 1}
+
+	recalc, edits, err := recalculateDomainCost("genomics", []byte(content), loadRecalcFixture(t, content), recalcPrices(), opts)
+	if err != nil {
+		t.Fatalf("recalculateDomainCost() error = %v", err)
+	}
+	// Storage is kept; compute 0.10 * 730 = 73 keeps the file's one decimal
+	if len(edits) != 2 || recalc.Changes[0].New != "73.0" || recalc.Changes[1].New != "373.0" {
+		t.Errorf("changes = %+v", recalc.Changes)
+	}
+	if len(recalc.Notes) != 1 || !strings.Contains(recalc.Notes[0], "typical_dataset_gb") {
+		t.Errorf("notes = %v", recalc.Notes)
+	}
+
+	// A dataset size on the command line prices storage anyway
+	opts.DatasetGB = 500
+	recalc, _, err = recalculateDomainCost("genomics", []byte(content), loadRecalcFixture(t, content), recalcPrices(), opts)
+	if err != nil || len(recalc.Changes) != 3 || recalc.Changes[1].New != "40" {
+		t.Errorf("changes with --dataset-gb = %+v, %v", recalc.Changes, err)
+	}
+}
+
+func TestRecalculateDomainCostSkips(t *testing.T) {
+	opts := recalcOptions{UseCase: config.StandardUseCase, Utilization: 0.5}
+
+	noCost := "name: Visualization\naws_instance_recommendations:\n  standard:\n    instance_type: c6i.large\n"
+	recalc, edits, err := recalculateDomainCost("viz", []byte(noCost), loadRecalcFixture(t, noCost), recalcPrices(), opts)
+	if err != nil || recalc.Skipped == "" || edits != nil {
+		t.Errorf("domain without estimated_cost: %+v, %v", recalc, err)
+	}
+
+	opts.UseCase = "gpu_training"
+	recalc, _, err = recalculateDomainCost("genomics", []byte(recalcFixture), loadRecalcFixture(t, recalcFixture), recalcPrices(), opts)
+	if err != nil || !strings.Contains(recalc.Skipped, "gpu_training") {
+		t.Errorf("unknown use case: %+v, %v", recalc, err)
+	}
+
+	prices := recalcPrices()
+	delete(prices.InstanceHourly, "r6i.large")
+	opts.UseCase = config.StandardUseCase
+	recalc, _, err = recalculateDomainCost("genomics", []byte(recalcFixture), loadRecalcFixture(t, recalcFixture), prices, opts)
+	if err != nil || !strings.Contains(recalc.Skipped, "r6i.large") {
+		t.Errorf("unpriced instance: %+v, %v", recalc, err)
+	}
+}
+
+func TestFormatCostLike(t *testing.T) {
+	tests := []struct {
+		old   string
+		value float64
+		want  string
+	}{
+		{"600", 368.16, "368"},
+		{"600", 600.4, "600"},
+		{"1200.0", 248.2, "248.2"},
+		{"1200.0", 1200.04, "1200.0"},
+		{"15.50", 7.126, "7.13"},
+		{"oops", 12.6, "13"},
+	}
+	for _, tt := range tests {
+		if got := formatCostLike(tt.old, tt.value); got != tt.want {
+			t.Errorf("formatCostLike(%q, %v) = %q, want %q", tt.old, tt.value, got, tt.want)
+		}
+	}
+}
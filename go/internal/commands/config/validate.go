@@ -0,0 +1,367 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/aws"
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/config"
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/spack"
+)
+
+// Finding severities
+const (
+	severityError   = "error"
+	severityWarning = "warning"
+)
+
+// Tolerances for comparing declared values with the instance catalog
+const (
+	defaultPriceTolerance = 0.25
+	// memoryTolerance absorbs GB and GiB being used interchangeably
+	memoryTolerance = 0.1
+	// costSumTolerance is the relative rounding allowed between the total and
+	// its line items, never less than a dollar
+	costSumTolerance = 0.01
+)
+
+// recommendationsKey is the domain YAML block holding instance recommendations
+const recommendationsKey = "aws_instance_recommendations"
+
+// consistencyFinding is one cross-field problem in a domain pack, located by
+// its YAML path
+type consistencyFinding struct {
+	Domain   string `json:"domain"`
+	Path     string `json:"path"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+// consistencyOptions tune the semantic checks
+type consistencyOptions struct {
+	// PriceTolerance is the relative difference allowed between a declared
+	// cost_per_hour and the catalog price
+	PriceTolerance float64
+}
+
+func createValidateCommand(configRoot *string) *cobra.Command {
+	var catalogPath string
+	var opts consistencyOptions
+	var strict bool
+	var jsonOutput bool
+
+	cmd := &cobra.Command{
+		Use:   "validate [domain]",
+		Short: "Check domain packs for inconsistent instances, costs and packages",
+		Long: `Check domain packs for values that pass schema validation but contradict
+each other:
+
+- an instance recommendation's vcpus and memory_gb must match its instance type
+- its cost_per_hour should be within --price-tolerance of the on-demand price
+- estimated_cost.total must equal the sum of the other estimated_cost items
+- a domain whose Spack packages need CUDA must recommend a GPU instance
+- a deprecated domain's replaced_by must name another existing domain, and
+  its sunset_date should not have passed
+
+Instance types are looked up in a catalog bundled with the binary, priced in
+us-east-1; --catalog reads one in the same JSON format instead. Keys ending in
+_monthly under estimated_cost are totals for other team sizes, not line items.
+
+Exits with status 1 when any domain has an error, or with --strict a warning.
+
+Examples:
+  # Check every domain pack
+  aws-research-wizard config validate
+
+  # Check one domain, allowing prices 10% off the catalog
+  aws-research-wizard config validate genomics --price-tolerance 0.1`,
+		Args: cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if *configRoot == "" {
+				*configRoot = findConfigRoot()
+			}
+			if opts.PriceTolerance < 0 {
+				log.Fatal("--price-tolerance must not be negative")
+			}
+
+			var catalog *aws.InstanceCatalog
+			var err error
+			if catalogPath != "" {
+				catalog, err = aws.LoadInstanceCatalog(catalogPath)
+			} else {
+				catalog, err = aws.BundledInstanceCatalog()
+			}
+			if err != nil {
+				log.Fatalf("Failed to load instance catalog: %v", err)
+			}
+
+			loader := config.NewConfigLoader(*configRoot)
+			files, err := loader.DomainFiles()
+			if err != nil {
+				log.Fatalf("Failed to find domains: %v", err)
+			}
+			names := make([]string, 0, len(files))
+			for name := range files {
+				if len(args) == 0 || name == args[0] {
+					names = append(names, name)
+				}
+			}
+			if len(names) == 0 {
+				log.Fatalf("Domain '%s' not found", args[0])
+			}
+			sort.Strings(names)
+
+			var findings []consistencyFinding
+			for _, name := range names {
+				domain, err := loader.LoadDomain(files[name])
+				if err != nil {
+					findings = append(findings, consistencyFinding{Domain: name, Path: files[name], Severity: severityError, Message: err.Error()})
+					continue
+				}
+				findings = append(findings, checkDomainConsistency(name, domain, catalog, opts)...)
+				findings = append(findings, checkReplacement(name, domain, files, time.Now())...)
+			}
+
+			if jsonOutput {
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+				if err := encoder.Encode(findings); err != nil {
+					log.Fatalf("Failed to encode findings: %v", err)
+				}
+			} else {
+				printConsistencyFindings(len(names), findings)
+			}
+
+			for _, finding := range findings {
+				if finding.Severity == severityError || strict {
+					os.Exit(1)
+				}
+			}
+		},
+	}
+
+	cmd.Flags().StringVar(&catalogPath, "catalog", "", "Instance catalog JSON file (default: the bundled catalog)")
+	cmd.Flags().Float64Var(&opts.PriceTolerance, "price-tolerance", defaultPriceTolerance, "Relative difference allowed between cost_per_hour and the catalog price")
+	cmd.Flags().BoolVar(&strict, "strict", false, "Exit with status 1 on warnings too")
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "Output as JSON")
+
+	return cmd
+}
+
+// checkDomainConsistency runs every semantic check on a domain
+func checkDomainConsistency(name string, domain *config.DomainPack, catalog *aws.InstanceCatalog, opts consistencyOptions) []consistencyFinding {
+	var findings []consistencyFinding
+	findings = append(findings, checkInstanceSpecs(domain, catalog)...)
+	findings = append(findings, checkInstancePrices(domain, catalog, opts.PriceTolerance)...)
+	findings = append(findings, checkCostTotal(domain)...)
+	findings = append(findings, checkGPURecommendation(domain, catalog)...)
+	for i := range findings {
+		findings[i].Domain = name
+	}
+	return findings
+}
+
+// sortedRecommendationKeys returns the domain's recommendation keys in order
+func sortedRecommendationKeys(domain *config.DomainPack) []string {
+	keys := make([]string, 0, len(domain.AWSInstanceRecommendations))
+	for key := range domain.AWSInstanceRecommendations {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// recommendationPath is the YAML path of a recommendation's field
+func recommendationPath(key, field string) string {
+	return strings.Join([]string{recommendationsKey, key, field}, ".")
+}
+
+// checkInstanceSpecs compares each recommendation's declared vCPUs and memory
+// with its instance type. Undeclared values are not checked.
+func checkInstanceSpecs(domain *config.DomainPack, catalog *aws.InstanceCatalog) []consistencyFinding {
+	var findings []consistencyFinding
+	for _, key := range sortedRecommendationKeys(domain) {
+		rec := domain.AWSInstanceRecommendations[key]
+		if rec.InstanceType == "" {
+			continue
+		}
+		spec, ok := catalog.Lookup(rec.InstanceType)
+		if !ok {
+			findings = append(findings, consistencyFinding{
+				Path:     recommendationPath(key, "instance_type"),
+				Severity: severityWarning,
+				Message:  fmt.Sprintf("%s is not in the instance catalog; its specs and price are not checked", rec.InstanceType),
+			})
+			continue
+		}
+
+		if rec.VCPUs != 0 && rec.VCPUs != spec.VCPUs {
+			findings = append(findings, consistencyFinding{
+				Path:     recommendationPath(key, "vcpus"),
+				Severity: severityError,
+				Message:  fmt.Sprintf("%d vCPUs declared, but %s has %d", rec.VCPUs, rec.InstanceType, spec.VCPUs),
+			})
+		}
+		if rec.MemoryGB != 0 && math.Abs(float64(rec.MemoryGB)-spec.MemoryGiB) > spec.MemoryGiB*memoryTolerance {
+			findings = append(findings, consistencyFinding{
+				Path:     recommendationPath(key, "memory_gb"),
+				Severity: severityError,
+				Message:  fmt.Sprintf("%d GB memory declared, but %s has %g GiB", rec.MemoryGB, rec.InstanceType, spec.MemoryGiB),
+			})
+		}
+	}
+	return findings
+}
+
+// checkInstancePrices warns when a declared hourly cost is further than the
+// tolerance from the catalog's on-demand price
+func checkInstancePrices(domain *config.DomainPack, catalog *aws.InstanceCatalog, tolerance float64) []consistencyFinding {
+	var findings []consistencyFinding
+	for _, key := range sortedRecommendationKeys(domain) {
+		rec := domain.AWSInstanceRecommendations[key]
+		spec, ok := catalog.Lookup(rec.InstanceType)
+		if !ok || rec.CostPerHour == 0 || spec.HourlyUSD == 0 {
+			continue
+		}
+
+		difference := (rec.CostPerHour - spec.HourlyUSD) / spec.HourlyUSD
+		if math.Abs(difference) > tolerance {
+			findings = append(findings, consistencyFinding{
+				Path:     recommendationPath(key, "cost_per_hour"),
+				Severity: severityWarning,
+				Message: fmt.Sprintf("$%.4f/hour declared, %+.0f%% from the %s on-demand price of $%.4f in %s",
+					rec.CostPerHour, difference*100, rec.InstanceType, spec.HourlyUSD, catalog.Region),
+			})
+		}
+	}
+	return findings
+}
+
+// checkCostTotal requires estimated_cost.total to equal the sum of the other
+// numeric line items
+func checkCostTotal(domain *config.DomainPack) []consistencyFinding {
+	cost := domain.EstimatedCost
+	items := map[string]float64{"compute": cost.Compute, "storage": cost.Storage}
+	for key, value := range cost.Other {
+		if strings.HasSuffix(key, "_monthly") {
+			continue
+		}
+		switch number := value.(type) {
+		case int:
+			items[key] = float64(number)
+		case float64:
+			items[key] = number
+		}
+	}
+
+	var sum float64
+	for _, value := range items {
+		sum += value
+	}
+	if cost.Total == 0 && sum == 0 {
+		return nil
+	}
+
+	allowed := math.Max(1, cost.Total*costSumTolerance)
+	if math.Abs(sum-cost.Total) <= allowed {
+		return nil
+	}
+
+	keys := make([]string, 0, len(items))
+	for key := range items {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return []consistencyFinding{{
+		Path:     estimatedCostKey + ".total",
+		Severity: severityError,
+		Message:  fmt.Sprintf("total is %g, but %s sum to %g", cost.Total, strings.Join(keys, " + "), sum),
+	}}
+}
+
+// checkGPURecommendation requires a domain whose packages need CUDA to
+// recommend at least one GPU instance
+func checkGPURecommendation(domain *config.DomainPack, catalog *aws.InstanceCatalog) []consistencyFinding {
+	gpuSpecs := spack.GPUSpecs(spack.DeclaredSpecs(domain.SpackPackages))
+	if len(gpuSpecs) == 0 {
+		return nil
+	}
+	for _, rec := range domain.AWSInstanceRecommendations {
+		if spec, ok := catalog.Lookup(rec.InstanceType); ok && spec.GPUs > 0 {
+			return nil
+		}
+	}
+
+	needs := gpuSpecs[0].Raw
+	if len(gpuSpecs) > 1 {
+		needs = fmt.Sprintf("%s and %d more", needs, len(gpuSpecs)-1)
+	}
+	return []consistencyFinding{{
+		Path:     recommendationsKey,
+		Severity: severityError,
+		Message:  fmt.Sprintf("spack_packages need a GPU (%s), but no recommendation is a GPU instance", needs),
+	}}
+}
+
+// checkReplacement requires a deprecated domain's replacement to be another
+// domain pack in files, and warns when its sunset date has passed
+func checkReplacement(name string, domain *config.DomainPack, files map[string]string, now time.Time) []consistencyFinding {
+	var findings []consistencyFinding
+	switch {
+	case domain.ReplacedBy == "":
+	case domain.ReplacedBy == name:
+		findings = append(findings, consistencyFinding{
+			Domain:   name,
+			Path:     "replaced_by",
+			Severity: severityError,
+			Message:  "a domain cannot replace itself",
+		})
+	case files[domain.ReplacedBy] == "":
+		findings = append(findings, consistencyFinding{
+			Domain:   name,
+			Path:     "replaced_by",
+			Severity: severityError,
+			Message:  fmt.Sprintf("domain %s does not exist", domain.ReplacedBy),
+		})
+	}
+	if sunset, ok := domain.Sunset(); ok && !now.Before(sunset.AddDate(0, 0, 1)) {
+		findings = append(findings, consistencyFinding{
+			Domain:   name,
+			Path:     "sunset_date",
+			Severity: severityWarning,
+			Message:  fmt.Sprintf("the sunset date %s has passed; remove the domain pack", domain.SunsetDate),
+		})
+	}
+	return findings
+}
+
+// printConsistencyFindings lists findings by domain with a summary
+func printConsistencyFindings(domains int, findings []consistencyFinding) {
+	fmt.Printf("🔍 Domain pack consistency: %d domain(s)\n\n", domains)
+	if len(findings) == 0 {
+		fmt.Println("✅ No inconsistencies found")
+		return
+	}
+
+	errors := 0
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "DOMAIN\tSEVERITY\tPATH\tMESSAGE")
+	for _, finding := range findings {
+		if finding.Severity == severityError {
+			errors++
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", finding.Domain, finding.Severity, finding.Path, finding.Message)
+	}
+	w.Flush()
+
+	fmt.Printf("\n⚠️  %d error(s), %d warning(s)\n", errors, len(findings)-errors)
+}
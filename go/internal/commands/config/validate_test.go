@@ -0,0 +1,217 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/aws"
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/config"
+)
+
+func validateCatalog() *aws.InstanceCatalog {
+	return &aws.InstanceCatalog{
+		Source: "test",
+		Region: "us-east-1",
+		Instances: map[string]aws.InstanceSpec{
+			"c6i.xlarge": {VCPUs: 4, MemoryGiB: 8, HourlyUSD: 0.17},
+			"r6i.large":  {VCPUs: 2, MemoryGiB: 16, HourlyUSD: 0.126},
+			"p3.2xlarge": {VCPUs: 8, MemoryGiB: 61, GPUs: 1, HourlyUSD: 3.06},
+		},
+	}
+}
+
+const consistentDomain = `name: Genomics
+spack_packages:
+  core:
+  - samtools@1.18
+aws_instance_recommendations:
+  development:
+    instance_type: c6i.xlarge
+    vcpus: 4
+    memory_gb: 8
+    cost_per_hour: 0.17
+  standard_analysis:
+    instance_type: r6i.large
+    vcpus: 2
+    memory_gb: 16
+    cost_per_hour: 0.13
+estimated_cost:
+  compute: 600
+  storage: 200
+  data_transfer: 100
+  typical_dataset_gb: 1000
+  total: 900
+`
+
+// wantFindings checks findings by path and severity, in order
+func wantFindings(t *testing.T, findings []consistencyFinding, want ...string) {
+	t.Helper()
+	got := make([]string, len(findings))
+	for i, finding := range findings {
+		got[i] = finding.Severity + " " + finding.Path
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("findings = %q, want %q\n%+v", got, want, findings)
+	}
+}
+
+func TestCheckDomainConsistencyClean(t *testing.T) {
+	domain := loadRecalcFixture(t, consistentDomain)
+	findings := checkDomainConsistency("genomics", domain, validateCatalog(), consistencyOptions{PriceTolerance: defaultPriceTolerance})
+	wantFindings(t, findings)
+}
+
+func TestCheckInstanceSpecs(t *testing.T) {
+	domain := loadRecalcFixture(t, `aws_instance_recommendations:
+  memory_heavy:
+    instance_type: c6i.xlarge
+    vcpus: 4
+    memory_gb: 768
+  wrong_cpus:
+    instance_type: r6i.large
+    vcpus: 16
+    memory_gb: 16
+  gib_rounding:
+    instance_type: p3.2xlarge
+    vcpus: 8
+    memory_gb: 64
+  undeclared:
+    instance_type: r6i.large
+  unknown:
+    instance_type: c6i.huge
+    vcpus: 4
+`)
+
+	findings := checkInstanceSpecs(domain, validateCatalog())
+	wantFindings(t, findings,
+		"error aws_instance_recommendations.memory_heavy.memory_gb",
+		"warning aws_instance_recommendations.unknown.instance_type",
+		"error aws_instance_recommendations.wrong_cpus.vcpus",
+	)
+	if !strings.Contains(findings[0].Message, "768 GB") || !strings.Contains(findings[0].Message, "8 GiB") {
+		t.Errorf("message = %q", findings[0].Message)
+	}
+}
+
+func TestCheckInstancePrices(t *testing.T) {
+	domain := loadRecalcFixture(t, `aws_instance_recommendations:
+  cheap:
+    instance_type: c6i.xlarge
+    cost_per_hour: 0.05
+  close:
+    instance_type: r6i.large
+    cost_per_hour: 0.15
+  expensive:
+    instance_type: p3.2xlarge
+    cost_per_hour: 6.12
+  unpriced:
+    instance_type: c6i.xlarge
+`)
+
+	findings := checkInstancePrices(domain, validateCatalog(), 0.25)
+	wantFindings(t, findings,
+		"warning aws_instance_recommendations.cheap.cost_per_hour",
+		"warning aws_instance_recommendations.expensive.cost_per_hour",
+	)
+	if !strings.Contains(findings[1].Message, "+100%") {
+		t.Errorf("message = %q, want the relative difference", findings[1].Message)
+	}
+
+	// A wider tolerance accepts the cheap price too
+	wantFindings(t, checkInstancePrices(domain, validateCatalog(), 1.5))
+}
+
+func TestCheckCostTotal(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		want []string
+	}{
+		{name: "line items", yaml: "compute: 600\nstorage: 200\ndata_transfer: 100\ntotal: 900\n"},
+		{name: "rounding", yaml: "compute: 600.4\nstorage: 200.4\ntotal: 800\n"},
+		{name: "team tiers", yaml: "compute: 600\nstorage: 200\ntotal: 800\nsmall_team_monthly: 400\nlarge_team_monthly: 4000\n"},
+		{name: "no estimate", yaml: "typical_dataset_gb: 100\n"},
+		{name: "mismatch", yaml: "compute: 600\nstorage: 200\ndata_transfer: 100\ntotal: 1200\n", want: []string{"error estimated_cost.total"}},
+		{name: "missing total", yaml: "compute: 600\nstorage: 200\n", want: []string{"error estimated_cost.total"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			indented := "  " + strings.ReplaceAll(strings.TrimSuffix(tt.yaml, "\n"), "\n", "\n  ")
+			domain := loadRecalcFixture(t, "estimated_cost:\n"+indented+"\n")
+			wantFindings(t, checkCostTotal(domain), tt.want...)
+		})
+	}
+
+	domain := loadRecalcFixture(t, "estimated_cost:\n  compute: 600\n  storage: 200\n  data_transfer: 100\n  total: 1200\n")
+	if message := checkCostTotal(domain)[0].Message; !strings.Contains(message, "compute + data_transfer + storage sum to 900") {
+		t.Errorf("message = %q", message)
+	}
+}
+
+func TestCheckGPURecommendation(t *testing.T) {
+	const gpuPackages = `spack_packages:
+  gpu_computing:
+  - cuda@12.2.2 %gcc@11.4.0
+  - py-torch +cuda
+`
+	withoutGPU := loadRecalcFixture(t, gpuPackages+`aws_instance_recommendations:
+  training:
+    instance_type: c6i.xlarge
+`)
+	findings := checkGPURecommendation(withoutGPU, validateCatalog())
+	wantFindings(t, findings, "error aws_instance_recommendations")
+	if !strings.Contains(findings[0].Message, "cuda@12.2.2 %gcc@11.4.0 and 1 more") {
+		t.Errorf("message = %q", findings[0].Message)
+	}
+
+	withGPU := loadRecalcFixture(t, gpuPackages+`aws_instance_recommendations:
+  development:
+    instance_type: c6i.xlarge
+  training:
+    instance_type: p3.2xlarge
+`)
+	wantFindings(t, checkGPURecommendation(withGPU, validateCatalog()))
+
+	cpuOnly := loadRecalcFixture(t, consistentDomain)
+	wantFindings(t, checkGPURecommendation(cpuOnly, validateCatalog()))
+}
+
+func TestCheckDomainConsistencySetsDomain(t *testing.T) {
+	domain := loadRecalcFixture(t, strings.Replace(consistentDomain, "total: 900", "total: 9000", 1))
+	findings := checkDomainConsistency("genomics", domain, validateCatalog(), consistencyOptions{PriceTolerance: defaultPriceTolerance})
+	wantFindings(t, findings, "error estimated_cost.total")
+	if findings[0].Domain != "genomics" {
+		t.Errorf("Domain = %q, want genomics", findings[0].Domain)
+	}
+}
+
+func TestCheckReplacement(t *testing.T) {
+	files := map[string]string{"genomics": "genomics.yaml", "genomics-legacy": "genomics-legacy.yaml"}
+	now := time.Date(2026, 10, 15, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		domain *config.DomainPack
+		want   []string
+	}{
+		{"current", &config.DomainPack{}, nil},
+		{"existing replacement", &config.DomainPack{Deprecated: true, ReplacedBy: "genomics", SunsetDate: "2026-10-15"}, nil},
+		{"missing replacement", &config.DomainPack{Deprecated: true, ReplacedBy: "genomics-v3"}, []string{"error replaced_by"}},
+		{"replaces itself", &config.DomainPack{Deprecated: true, ReplacedBy: "genomics-legacy"}, []string{"error replaced_by"}},
+		{"sunset passed", &config.DomainPack{Deprecated: true, SunsetDate: "2026-10-14"}, []string{"warning sunset_date"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			findings := checkReplacement("genomics-legacy", tt.domain, files, now)
+			wantFindings(t, findings, tt.want...)
+			for _, finding := range findings {
+				if finding.Domain != "genomics-legacy" {
+					t.Errorf("finding domain = %q", finding.Domain)
+				}
+			}
+		})
+	}
+}
@@ -6,8 +6,13 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
 
 	"github.com/scttfrdmn/aws-research-wizard/go/internal/data"
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/intelligence"
 	"github.com/spf13/cobra"
 	"gopkg.in/yaml.v2"
 )
@@ -35,7 +40,20 @@ Examples:
   aws-research-wizard data analyze /data/genomics --output json --verbose
 
   # Generate project configuration from analysis
-  aws-research-wizard data analyze /data/genomics --generate-config project.yaml`,
+  aws-research-wizard data analyze /data/genomics --generate-config project.yaml
+
+  # Show how the research domain was detected
+  aws-research-wizard data analyze /data/genomics --explain
+
+  # Price the bundled scenario from a bundling dry run instead of an estimate
+  aws-research-wizard data analyze /data/genomics --plan-bundles
+
+  # Estimate replicating the dataset to Ireland with 5% monthly churn
+  aws-research-wizard data analyze /data/genomics --replicate-to eu-west-1 --change-rate 5%
+
+  # Compare against what the data already costs in S3, from a saved audit
+  aws-research-wizard data audit s3://lab-data/genomics --output json > audit.json
+  aws-research-wizard data analyze /data/genomics --current-state audit.json`,
 	Args: cobra.MaximumNArgs(1),
 	RunE: runAnalyze,
 }
@@ -47,6 +65,11 @@ var (
 	configOutput     string
 	includeEstimates bool
 	domainHint       string
+	explainDomain    bool
+	replicateTo      string
+	changeRate       string
+	planBundles      bool
+	currentState     string
 )
 
 func init() {
@@ -60,6 +83,11 @@ func init() {
 	analyzeCmd.Flags().StringVar(&configOutput, "config-output", "project.yaml", "Output file for generated config")
 	analyzeCmd.Flags().BoolVar(&includeEstimates, "include-estimates", true, "Include cost estimates")
 	analyzeCmd.Flags().StringVar(&domainHint, "domain", "", "Hint for research domain (genomics, climate, ml, etc.)")
+	analyzeCmd.Flags().BoolVar(&explainDomain, "explain", false, "Show per-factor domain detection scores")
+	analyzeCmd.Flags().StringVar(&replicateTo, "replicate-to", "", "Estimate cross-region replication to this region")
+	analyzeCmd.Flags().StringVar(&changeRate, "change-rate", "5%", "Share of the dataset that changes each month, for replication estimates")
+	analyzeCmd.Flags().BoolVar(&planBundles, "plan-bundles", false, "Dry-run the bundling strategy for exact bundled object counts")
+	analyzeCmd.Flags().StringVar(&currentState, "current-state", "", "Price the current state from a bucket audit saved by \"data audit --output json\"")
 }
 
 func runAnalyze(cmd *cobra.Command, args []string) error {
@@ -80,6 +108,15 @@ func runAnalyze(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("path does not exist: %s", absPath)
 	}
 
+	var replication *data.ReplicationOptions
+	if replicateTo != "" {
+		rate, err := parseChangeRate(changeRate)
+		if err != nil {
+			return err
+		}
+		replication = &data.ReplicationOptions{DestinationRegion: replicateTo, MonthlyChangeRate: rate}
+	}
+
 	fmt.Printf("🔍 Analyzing data patterns in: %s\n\n", absPath)
 
 	// Create analyzer
@@ -109,19 +146,27 @@ func runAnalyze(cmd *cobra.Command, args []string) error {
 	}
 
 	// Generate recommendations
-	recommendations, err := generateRecommendations(ctx, pattern, absPath)
+	recommendations, err := generateRecommendations(ctx, pattern, absPath, replication)
 	if err != nil {
 		fmt.Printf("⚠️  Warning: Could not generate recommendations: %v\n", err)
 	}
 
+	var detection *intelligence.DomainDetectionExplanation
+	if explainDomain {
+		detection = explainDomainDetection(absPath, pattern)
+	}
+
 	// Display results
 	switch outputFormat {
 	case "json":
-		err = outputJSON(pattern, recommendations)
+		err = outputJSON(pattern, recommendations, detection)
 	case "yaml":
-		err = outputYAML(pattern, recommendations)
+		err = outputYAML(pattern, recommendations, detection)
 	default:
 		err = outputTable(pattern, recommendations)
+		if err == nil && detection != nil {
+			outputDomainDetection(detection)
+		}
 	}
 
 	if err != nil {
@@ -140,9 +185,28 @@ func runAnalyze(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
-func generateRecommendations(ctx context.Context, pattern *data.DataPattern, path string) (*data.RecommendationResult, error) {
+func generateRecommendations(ctx context.Context, pattern *data.DataPattern, path string, replication *data.ReplicationOptions) (*data.RecommendationResult, error) {
 	// Create cost calculator
 	costCalculator := data.NewS3CostCalculator("us-east-1")
+	if replication != nil {
+		if err := costCalculator.SetReplication(*replication); err != nil {
+			return nil, err
+		}
+	}
+	if planBundles {
+		plan, err := data.NewSuitcaseEngine(nil).PlanBundles(ctx, path)
+		if err != nil {
+			return nil, fmt.Errorf("bundling dry run failed: %w", err)
+		}
+		costCalculator.SetBundlePlan(plan)
+	}
+	if currentState != "" {
+		audit, err := data.LoadBucketAudit(currentState)
+		if err != nil {
+			return nil, err
+		}
+		costCalculator.SetCurrentState(audit)
+	}
 
 	// Create recommendation engine
 	analyzer := data.NewPatternAnalyzer()
@@ -239,6 +303,8 @@ func outputTable(pattern *data.DataPattern, recommendations *data.Recommendation
 			if recommendations.CostAnalysis.PotentialSavings > 0 {
 				fmt.Printf("  Potential savings:    $%.2f/month\n", recommendations.CostAnalysis.PotentialSavings)
 			}
+			outputFileTypeCosts(recommendations.CostAnalysis)
+			outputReplicationCosts(recommendations.CostAnalysis)
 		}
 	}
 
@@ -253,6 +319,63 @@ func outputTable(pattern *data.DataPattern, recommendations *data.Recommendation
 	return nil
 }
 
+// outputFileTypeCosts shows which file types drive the current-state cost
+func outputFileTypeCosts(analysis *data.CostAnalysis) {
+	if len(analysis.FileTypeBreakdown) == 0 {
+		return
+	}
+
+	fmt.Printf("\n📦 Cost by File Type (current state, monthly):\n")
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "  TYPE\tCATEGORY\tOBJECTS\tSIZE\tSTORAGE\tREQUESTS\tTOTAL\tSHARE")
+	for _, cost := range analysis.FileTypeBreakdown {
+		fmt.Fprintf(w, "  %s\t%s\t%d\t%s\t$%.2f\t$%.2f\t$%.2f\t%.1f%%\n",
+			cost.Extension, cost.Category, cost.ObjectCount, formatBytes(int64(cost.StorageGB*1024*1024*1024)),
+			cost.StorageCost, cost.RequestCost, cost.TotalCost, cost.Percentage)
+	}
+	w.Flush()
+
+	if analysis.CostDrivers != "" {
+		fmt.Printf("\n  💡 %s\n", analysis.CostDrivers)
+	}
+}
+
+// outputReplicationCosts shows what replicating the dataset to another region adds
+func outputReplicationCosts(analysis *data.CostAnalysis) {
+	replication := analysis.Replication
+	if replication == nil {
+		return
+	}
+
+	fmt.Printf("\n🌍 Replication %s → %s (%.1f%% monthly change):\n",
+		replication.SourceRegion, replication.DestinationRegion, replication.MonthlyChangeRate*100)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "  COST\tMONTHLY\tONE-TIME")
+	fmt.Fprintf(w, "  Replica storage\t$%.2f\t-\n", replication.DestinationStorage)
+	fmt.Fprintf(w, "  Replication requests\t$%.2f\t$%.2f\n", replication.ReplicationRequests, replication.InitialRequests)
+	fmt.Fprintf(w, "  Inter-region transfer\t$%.2f\t$%.2f\n", replication.InterRegionTransfer, replication.InitialTransfer)
+	fmt.Fprintf(w, "  Total\t$%.2f\t$%.2f\n", replication.MonthlyTotal, replication.InitialTotal)
+	w.Flush()
+
+	for _, rec := range analysis.Recommendations {
+		if rec.Type == "replication" {
+			fmt.Printf("\n  💡 %s: %s\n     %s\n", rec.Title, rec.Description, rec.Implementation)
+		}
+	}
+}
+
+// parseChangeRate accepts a percentage such as "5%" or "5" and returns a fraction
+func parseChangeRate(value string) (float64, error) {
+	percent, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(value), "%"), 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid change rate %q: %w", value, err)
+	}
+	if percent < 0 || percent > 100 {
+		return 0, fmt.Errorf("change rate %q must be between 0%% and 100%%", value)
+	}
+	return percent / 100, nil
+}
+
 func showDomainRecommendations(domain string) {
 	dpm := data.NewResearchDomainProfileManager()
 	profile, exists := dpm.GetProfile(domain)
@@ -269,28 +392,83 @@ func showDomainRecommendations(domain string) {
 	}
 }
 
-func outputJSON(pattern *data.DataPattern, recommendations *data.RecommendationResult) error {
+func outputJSON(pattern *data.DataPattern, recommendations *data.RecommendationResult, detection *intelligence.DomainDetectionExplanation) error {
 	output := map[string]interface{}{
 		"pattern":         pattern,
 		"recommendations": recommendations,
 		"timestamp":       pattern.AnalysisTime,
 	}
+	if detection != nil {
+		output["domain_detection"] = detection
+	}
 
 	encoder := json.NewEncoder(os.Stdout)
 	encoder.SetIndent("", "  ")
 	return encoder.Encode(output)
 }
 
-func outputYAML(pattern *data.DataPattern, recommendations *data.RecommendationResult) error {
+func outputYAML(pattern *data.DataPattern, recommendations *data.RecommendationResult, detection *intelligence.DomainDetectionExplanation) error {
 	output := map[string]interface{}{
 		"pattern":         pattern,
 		"recommendations": recommendations,
 		"timestamp":       pattern.AnalysisTime,
 	}
+	if detection != nil {
+		output["domain_detection"] = detection
+	}
 
 	return yaml.NewEncoder(os.Stdout).Encode(output)
 }
 
+// explainDomainDetection scores candidate domains using the analyzed file types and hints
+func explainDomainDetection(path string, pattern *data.DataPattern) *intelligence.DomainDetectionExplanation {
+	hints := intelligence.DomainHints{ExplicitDomain: domainHint, Explain: true}
+	for ext := range pattern.FileTypes {
+		hints.FileExtensions = append(hints.FileExtensions, ext)
+	}
+	sort.Strings(hints.FileExtensions)
+
+	engine := intelligence.NewIntelligenceEngine(data.NewResearchDomainProfileManager(), nil)
+	return engine.ExplainDomainDetection(path, hints)
+}
+
+func outputDomainDetection(detection *intelligence.DomainDetectionExplanation) {
+	fmt.Printf("\n🧭 Domain Detection:\n")
+	if detection.Ambiguous() {
+		fmt.Printf("  Status: ⚠️  ambiguous (best %.2f)", detection.Confidence)
+		if len(detection.TopCandidates) > 0 {
+			fmt.Printf(" - top candidates: %s", strings.Join(detection.TopCandidates, ", "))
+		}
+		fmt.Println()
+	} else {
+		fmt.Printf("  Status: ✅ %s (%.2f)\n", detection.Domain, detection.Confidence)
+	}
+
+	if len(detection.Candidates) == 0 {
+		fmt.Println("  No domain matched any detection factor")
+		return
+	}
+
+	factors := []string{
+		intelligence.FactorFileExtensions,
+		intelligence.FactorExplicitDomain,
+		intelligence.FactorWorkflowHints,
+		intelligence.FactorToolHints,
+		intelligence.FactorContentPattern,
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "  RANK\tDOMAIN\tSCORE\tEXTENSIONS\tEXPLICIT\tWORKFLOW\tTOOLS\tCONTENT")
+	for i, candidate := range detection.Candidates {
+		fmt.Fprintf(w, "  %d\t%s\t%.2f", i+1, candidate.Domain, candidate.Score)
+		for _, factor := range factors {
+			fmt.Fprintf(w, "\t%.2f", candidate.Factor(factor).Score)
+		}
+		fmt.Fprintln(w)
+	}
+	w.Flush()
+}
+
 func generateProjectConfig(pattern *data.DataPattern, recommendations *data.RecommendationResult, outputFile string) error {
 	// Create project config manager
 	pcm := data.NewProjectConfigManager(filepath.Dir(outputFile))
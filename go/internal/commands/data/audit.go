@@ -0,0 +1,201 @@
+package data
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	awsClient "github.com/scttfrdmn/aws-research-wizard/go/internal/aws"
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/data"
+)
+
+// auditCmd reports the storage classes and cost of an existing bucket prefix
+var auditCmd = &cobra.Command{
+	Use:   "audit <s3-uri>",
+	Short: "Audit the storage classes and monthly cost of an existing bucket",
+	Long: `Report what an existing bucket prefix holds in each storage class and what
+it costs each month, split by top-level prefix, and flag common anti-patterns:
+
+  stale-standard        STANDARD objects last modified more than 180 days ago
+  small-objects         Objects under 128 KB in classes that bill a minimum
+                        size or add archive metadata (GLACIER, DEEP_ARCHIVE,
+                        GLACIER_IR, STANDARD_IA, ONEZONE_IA)
+  incomplete-multipart  Multipart uploads started more than 7 days ago
+
+Objects are listed from the bucket, or read from an S3 Inventory report with
+--inventory, which is much faster for buckets with millions of objects. Only
+CSV inventory reports are supported.
+
+Save the audit as JSON and pass it to "data analyze --current-state" to price
+the current state from the audit instead of assuming everything is STANDARD.
+
+Examples:
+  # Audit a prefix
+  aws-research-wizard data audit s3://lab-data/projects
+
+  # Audit from an inventory report and save it for analysis
+  aws-research-wizard data audit s3://lab-data \
+    --inventory s3://lab-inventory/lab-data/daily/2025-06-01T01-00Z/manifest.json \
+    --output json > audit.json
+  aws-research-wizard data analyze /data/projects --current-state audit.json`,
+	Args: cobra.ExactArgs(1),
+	RunE: runAudit,
+}
+
+var (
+	auditInventory string
+	auditOutput    string
+	auditTop       int
+)
+
+func init() {
+	DataCmd.AddCommand(auditCmd)
+
+	auditCmd.Flags().StringVar(&auditInventory, "inventory", "", "S3 URI of an S3 Inventory manifest.json to read instead of listing the bucket")
+	auditCmd.Flags().StringVarP(&auditOutput, "output", "o", "table", "Output format (table, json)")
+	auditCmd.Flags().IntVar(&auditTop, "top", 20, "Number of prefixes to show in the table (0 for all)")
+}
+
+func runAudit(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	bucket, prefix, err := parseS3URI(args[0])
+	if err != nil {
+		return err
+	}
+	if bucket == "" {
+		return fmt.Errorf("invalid S3 URI: bucket name is required")
+	}
+	if auditOutput != "table" && auditOutput != "json" {
+		return fmt.Errorf("unknown output format %q: use table or json", auditOutput)
+	}
+
+	region, _ := cmd.Flags().GetString("region")
+	client, err := awsClient.NewClient(ctx, region)
+	if err != nil {
+		return fmt.Errorf("failed to create AWS client: %w", err)
+	}
+
+	// Progress goes to stderr so JSON output can be redirected to a file
+	source := data.AuditSourceListing
+	if auditInventory != "" {
+		source = data.AuditSourceInventory
+	}
+	auditor := data.NewS3CostCalculator(client.Region).NewBucketAuditor(bucket, prefix, source, time.Now().UTC())
+	if auditInventory != "" {
+		manifestBucket, manifestKey, err := parseS3URI(auditInventory)
+		if err != nil {
+			return fmt.Errorf("invalid --inventory: %w", err)
+		}
+		fmt.Fprintf(os.Stderr, "📒 Reading inventory s3://%s/%s\n", manifestBucket, manifestKey)
+		manifest, err := data.LoadInventory(ctx, client.S3, manifestBucket, manifestKey, prefix, auditor)
+		if err != nil {
+			return err
+		}
+		if manifest.SourceBucket != "" && manifest.SourceBucket != bucket {
+			return fmt.Errorf("the inventory describes bucket %s, not %s", manifest.SourceBucket, bucket)
+		}
+	} else {
+		fmt.Fprintf(os.Stderr, "🔍 Listing s3://%s/%s\n", bucket, prefix)
+		if err := data.ListAuditObjects(ctx, client.S3, bucket, prefix, auditor); err != nil {
+			return err
+		}
+	}
+	if err := data.ListIncompleteUploads(ctx, client.S3, bucket, prefix, auditor); err != nil {
+		return err
+	}
+	audit := auditor.Finish()
+
+	if auditOutput == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(audit)
+	}
+	printAudit(audit, auditTop)
+	return nil
+}
+
+func printAudit(audit *data.BucketAudit, top int) {
+	fmt.Printf("\n🪣 s3://%s/%s (%s, %s)\n", audit.Bucket, audit.Prefix, audit.Region, audit.Source)
+	fmt.Printf("📦 %d objects, %s, $%.2f/month\n", audit.Objects, formatBytes(audit.Bytes), audit.MonthlyCost)
+
+	fmt.Printf("\n💾 By storage class:\n")
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "  CLASS\tOBJECTS\tSIZE\tBILLED\tMONTHLY\tSHARE")
+	for _, usage := range audit.StorageClasses {
+		cost := fmt.Sprintf("$%.2f", usage.MonthlyCost)
+		if !usage.Priced {
+			cost = "not priced"
+		}
+		fmt.Fprintf(w, "  %s\t%d\t%s\t%s\t%s\t%.1f%%\n", usage.StorageClass, usage.Objects,
+			formatBytes(usage.Bytes), formatBytes(usage.BillableBytes), cost, audit.StorageClassShare(usage.StorageClass)*100)
+	}
+	if audit.IncompleteUploads > 0 {
+		fmt.Fprintf(w, "  (incomplete uploads)\t%d\t%s\t%s\t$%.2f\t-\n", audit.IncompleteUploads,
+			formatBytes(audit.IncompleteUploadBytes), formatBytes(audit.IncompleteUploadBytes), audit.IncompleteUploadCost)
+	}
+	w.Flush()
+
+	prefixes := audit.Prefixes
+	if top > 0 && len(prefixes) > top {
+		prefixes = prefixes[:top]
+	}
+	fmt.Printf("\n📁 By prefix (most expensive first):\n")
+	w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "  PREFIX\tOBJECTS\tSIZE\tMONTHLY\tCLASSES")
+	for _, usage := range prefixes {
+		name := usage.Prefix
+		if name == "" {
+			name = "(top level)"
+		}
+		fmt.Fprintf(w, "  %s\t%d\t%s\t$%.2f\t%s\n", name, usage.Objects, formatBytes(usage.Bytes), usage.MonthlyCost, formatClassBytes(usage.ByClass))
+	}
+	w.Flush()
+	if len(prefixes) < len(audit.Prefixes) {
+		fmt.Printf("  ... %d more (use --top 0 to show all)\n", len(audit.Prefixes)-len(prefixes))
+	}
+
+	if len(audit.Findings) == 0 {
+		fmt.Printf("\n✅ No anti-patterns found\n")
+		return
+	}
+	fmt.Printf("\n⚠️  Findings:\n")
+	for _, finding := range audit.Findings {
+		fmt.Printf("\n  %s (%s)\n", finding.Title, finding.Type)
+		fmt.Printf("     %s\n", finding.Description)
+		fmt.Printf("     Costs $%.2f/month; fixing it saves about $%.2f/month\n", finding.MonthlyCost, finding.EstimatedSavings)
+		fmt.Printf("     💡 %s\n", finding.Recommendation)
+		if len(finding.Examples) > 0 {
+			fmt.Printf("     e.g. %s\n", strings.Join(finding.Examples, ", "))
+		}
+	}
+}
+
+// formatClassBytes lists a prefix's bytes per storage class, largest first
+func formatClassBytes(byClass map[string]int64) string {
+	classes := make([]string, 0, len(byClass))
+	for class := range byClass {
+		classes = append(classes, class)
+	}
+	sort.Slice(classes, func(i, j int) bool {
+		if byClass[classes[i]] != byClass[classes[j]] {
+			return byClass[classes[i]] > byClass[classes[j]]
+		}
+		return classes[i] < classes[j]
+	})
+	parts := make([]string, 0, len(classes))
+	for _, class := range classes {
+		parts = append(parts, fmt.Sprintf("%s %s", class, formatBytes(byClass[class])))
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,362 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	awsClient "github.com/scttfrdmn/aws-research-wizard/go/internal/aws"
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/data"
+)
+
+// bundleCmd groups commands that work with native tar bundles
+var bundleCmd = &cobra.Command{
+	Use:   "bundle",
+	Short: "Create bundles and retrieve single files from them",
+	Long: `Bundle small files into tar archives with an index sidecar recording where
+each file sits, and fetch individual files back out of bundles in S3 without
+downloading the whole bundle.`,
+}
+
+// bundleCreateCmd bundles a directory with the native tar backend
+var bundleCreateCmd = &cobra.Command{
+	Use:   "create <source>",
+	Short: "Bundle small files with an index for single-file retrieval",
+	Long: `Bundle the small files in a directory into tar archives, writing a
+<bundle>.idx.json index beside each one.
+
+Bundles are gzip-compressed by default; retrieving one file then streams the
+bundle from its start up to that file. With --seekable bundles are written as
+plain tar, so any file can be fetched with a single ranged read of exactly its
+bytes.
+
+Symlinks are stored as links, and their targets recorded in the manifest, unless
+--follow-symlinks is given. Named pipes, sockets and devices are skipped with a
+warning. Files with holes are stored as GNU sparse members, so only their data
+takes space. With --preserve-metadata, owners and full modes are kept for
+'bundle extract --preserve-metadata' to restore.
+
+With --chunked, files are grouped by path into chunks whose boundaries do not
+move when other files are added or removed, so re-bundling after a few files
+change rewrites only the chunks holding them. Uploading chunked bundles skips
+every chunk whose checksum matches the object already in S3, and records
+progress in a journal so an interrupted upload resumes where it stopped.
+
+Examples:
+  # Bundle a sequencing run into seekable bundles
+  aws-research-wizard data bundle create ./run-42 --output ./bundles --seekable
+
+  # Bundle and upload the bundles and their indexes
+  aws-research-wizard data bundle create ./run-42 --upload s3://my-bucket/bundles/run-42
+
+  # Re-upload only the chunks that changed since the last run
+  aws-research-wizard data bundle create ./run-42 --chunked --upload s3://my-bucket/bundles/run-42`,
+	Args: cobra.ExactArgs(1),
+	RunE: runBundleCreate,
+}
+
+// bundleExtractCmd unpacks a whole local bundle
+var bundleExtractCmd = &cobra.Command{
+	Use:   "extract <bundle>",
+	Short: "Unpack a native bundle into a directory",
+	Long: `Unpack a bundle written by 'bundle create', recreating symlinks and the holes
+in sparse files. The symlinks are then checked against the targets recorded in
+the bundle's index when the index is beside the bundle.
+
+With --preserve-metadata, modes, modification times and owners are restored as
+recorded; restoring owners needs root. Anything that cannot be restored on this
+platform, such as symlinks on Windows without the privilege to create them, is
+reported as a warning.
+
+Examples:
+  aws-research-wizard data bundle extract ./bundles/bundle_0000.tar --output ./run-42
+  sudo aws-research-wizard data bundle extract bundle_0000.tar.gz --output /data/run-42 --preserve-metadata`,
+	Args: cobra.ExactArgs(1),
+	RunE: runBundleExtract,
+}
+
+// bundleExtractFileCmd retrieves one file from a bundle in S3
+var bundleExtractFileCmd = &cobra.Command{
+	Use:   "extract-file",
+	Short: "Fetch one file from a bundle in S3 using its index",
+	Long: `Fetch a single file from a bundle in S3 using the bundle's index sidecar,
+reading only the bytes needed instead of downloading the whole bundle. The
+file's checksum is verified against the index before it is written.
+
+Examples:
+  aws-research-wizard data bundle extract-file \
+    --bundle s3://my-bucket/bundles/run-42/bundle_0000.tar \
+    --file reads/sample_1.fastq --output sample_1.fastq`,
+	Args: cobra.NoArgs,
+	RunE: runBundleExtractFile,
+}
+
+var (
+	bundleOutputDir     string
+	bundleTargetSize    string
+	bundleSizeThreshold string
+	bundleSeekable      bool
+	bundleChunked       bool
+	bundleUpload        string
+	bundleFollowLinks   bool
+	bundlePreserve      bool
+
+	unpackOutput   string
+	unpackPreserve bool
+
+	extractBundle string
+	extractIndex  string
+	extractFile   string
+	extractOutput string
+)
+
+func init() {
+	DataCmd.AddCommand(bundleCmd)
+	bundleCmd.AddCommand(bundleCreateCmd)
+	bundleCmd.AddCommand(bundleExtractCmd)
+	bundleCmd.AddCommand(bundleExtractFileCmd)
+
+	bundleCreateCmd.Flags().StringVar(&bundleOutputDir, "output", "", "Directory for bundles (default: bundles beside the source)")
+	bundleCreateCmd.Flags().StringVar(&bundleTargetSize, "target-size", "100MB", "Target size of each bundle")
+	bundleCreateCmd.Flags().StringVar(&bundleSizeThreshold, "size-threshold", "1MB", "Bundle files smaller than this")
+	bundleCreateCmd.Flags().BoolVar(&bundleSeekable, "seekable", false, "Write uncompressed tar so files can be fetched with exact ranged reads")
+	bundleCreateCmd.Flags().BoolVar(&bundleChunked, "chunked", false, "Group files into chunks that re-bundle identically, and skip unchanged chunks when uploading")
+	bundleCreateCmd.Flags().StringVar(&bundleUpload, "upload", "", "Upload bundles and their indexes to this S3 URI (s3://bucket/prefix)")
+	bundleCreateCmd.Flags().BoolVar(&bundleFollowLinks, "follow-symlinks", false, "Bundle the files symlinks point to instead of the links")
+	bundleCreateCmd.Flags().BoolVar(&bundlePreserve, "preserve-metadata", false, "Keep file owners and full modes in the bundle")
+
+	bundleExtractCmd.Flags().StringVar(&unpackOutput, "output", ".", "Directory to unpack into")
+	bundleExtractCmd.Flags().BoolVar(&unpackPreserve, "preserve-metadata", false, "Restore modes, modification times and owners")
+
+	bundleExtractFileCmd.Flags().StringVar(&extractBundle, "bundle", "", "S3 URI of the bundle")
+	bundleExtractFileCmd.Flags().StringVar(&extractIndex, "index", "", "S3 URI of the bundle index (default: the bundle's .idx.json sidecar)")
+	bundleExtractFileCmd.Flags().StringVar(&extractFile, "file", "", "Path of the file within the bundle")
+	bundleExtractFileCmd.Flags().StringVar(&extractOutput, "output", "", "Local path to write the file to (default: the file's base name)")
+	bundleExtractFileCmd.MarkFlagRequired("bundle")
+	bundleExtractFileCmd.MarkFlagRequired("file")
+}
+
+func runBundleCreate(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	var uploadBucket, uploadPrefix string
+	if bundleUpload != "" {
+		var err error
+		uploadBucket, uploadPrefix, err = parseS3URI(bundleUpload)
+		if err != nil {
+			return fmt.Errorf("invalid upload target: %w", err)
+		}
+		if err := initializeDataComponents(cmd); err != nil {
+			return err
+		}
+	}
+
+	engine := data.NewSuitcaseEngine(&data.SuitcaseConfig{
+		Backend:          data.NativeTarBackend,
+		Seekable:         bundleSeekable,
+		Chunked:          bundleChunked,
+		FollowSymlinks:   bundleFollowLinks,
+		PreserveMetadata: bundlePreserve,
+		TargetBundleSize: bundleTargetSize,
+		SizeThreshold:    bundleSizeThreshold,
+		CompressionLevel: 6,
+		OutputDirectory:  bundleOutputDir,
+	})
+	// Progress updates are not shown, but the channel must not fill up
+	go func() {
+		for range engine.GetProgress() {
+		}
+	}()
+
+	fmt.Printf("📦 Bundling %s\n", args[0])
+	result, err := engine.BundleFiles(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	for _, warning := range result.Warnings {
+		fmt.Printf("⚠️  %s\n", warning)
+	}
+	if len(result.BundleManifest) == 0 {
+		fmt.Println("No files below the size threshold to bundle")
+		return nil
+	}
+
+	for _, bundle := range result.BundleManifest {
+		fmt.Printf("  %s  %d files, %s (index: %s)\n",
+			bundle.BundleName, bundle.FileCount, formatBytes(bundle.Size), filepath.Base(bundle.IndexPath))
+	}
+	fmt.Printf("✅ Bundled %d files into %d bundles in %s\n", result.BundledFileCount, len(result.BundleManifest), result.OutputPath)
+
+	if uploadBucket == "" {
+		return nil
+	}
+	if bundleChunked {
+		return uploadChunkedBundles(cmd, result, uploadBucket, uploadPrefix)
+	}
+	for _, bundle := range result.BundleManifest {
+		for _, local := range []string{bundle.BundlePath, bundle.IndexPath} {
+			key := path.Join(uploadPrefix, filepath.Base(local))
+			if err := s3Manager.UploadFile(ctx, uploadBucket, key, local, nil); err != nil {
+				return fmt.Errorf("failed to upload %s: %w", local, err)
+			}
+		}
+	}
+	fmt.Printf("☁️  Uploaded bundles and indexes to s3://%s/%s\n", uploadBucket, uploadPrefix)
+	return nil
+}
+
+// uploadChunkedBundles uploads the chunks that differ from those already in S3
+func uploadChunkedBundles(cmd *cobra.Command, result *data.BundleResult, bucket, prefix string) error {
+	ctx := cmd.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	region, _ := cmd.Flags().GetString("region")
+	client, err := awsClient.NewClient(ctx, region)
+	if err != nil {
+		return fmt.Errorf("failed to create AWS client: %w", err)
+	}
+
+	journalPath := data.DefaultVerifyJournalPath(result.OutputPath, bucket, prefix)
+	journal, err := data.OpenVerifyJournal(journalPath)
+	if err != nil {
+		return err
+	}
+	defer journal.Close()
+
+	upload := func(ctx context.Context, key, localPath string) error {
+		return s3Manager.UploadFile(ctx, bucket, key, localPath, nil)
+	}
+	report, err := data.UploadChunkedBundles(ctx, client.S3, bucket, prefix, result.BundleManifest, journal, upload)
+	if err != nil {
+		return fmt.Errorf("upload interrupted (progress saved to %s): %w", journalPath, err)
+	}
+	if err := journal.Remove(); err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Failed to remove journal %s: %v\n", journalPath, err)
+	}
+
+	fmt.Printf("☁️  Uploaded %d of %d bundles (%s) to s3://%s/%s\n",
+		report.Uploaded, len(result.BundleManifest), formatBytes(report.UploadedBytes), bucket, prefix)
+	if skipped := report.Unchanged + report.Resumed; skipped > 0 {
+		fmt.Printf("   Skipped %d unchanged bundles (%s)", skipped, formatBytes(report.SkippedBytes))
+		if report.Resumed > 0 {
+			fmt.Printf(", %d from the journal", report.Resumed)
+		}
+		fmt.Println()
+	}
+	return nil
+}
+
+func runBundleExtract(cmd *cobra.Command, args []string) error {
+	bundlePath := args[0]
+	warnings, err := data.ExtractTarBundle(bundlePath, unpackOutput, unpackPreserve)
+	for _, warning := range warnings {
+		fmt.Printf("⚠️  %s\n", warning)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("✅ Extracted %s to %s\n", filepath.Base(bundlePath), unpackOutput)
+
+	indexFile, err := os.Open(data.BundleIndexPath(bundlePath))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open bundle index: %w", err)
+	}
+	defer indexFile.Close()
+	index, err := data.ReadBundleIndex(indexFile)
+	if err != nil {
+		return err
+	}
+	if links := index.Links(); len(links) > 0 {
+		if err := data.VerifyExtractedLinks(unpackOutput, links); err != nil {
+			return err
+		}
+		fmt.Printf("🔗 Verified %d symlinks against the index\n", len(links))
+	}
+	return nil
+}
+
+func runBundleExtractFile(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	bucket, key, err := parseS3URI(extractBundle)
+	if err != nil {
+		return fmt.Errorf("invalid bundle: %w", err)
+	}
+	if bucket == "" || key == "" {
+		return fmt.Errorf("invalid bundle: bucket and key are required")
+	}
+
+	indexBucket, indexKey := bucket, data.BundleIndexPath(key)
+	if extractIndex != "" {
+		if indexBucket, indexKey, err = parseS3URI(extractIndex); err != nil {
+			return fmt.Errorf("invalid index: %w", err)
+		}
+	}
+
+	output := extractOutput
+	if output == "" {
+		output = path.Base(filepath.ToSlash(extractFile))
+	}
+
+	region, _ := cmd.Flags().GetString("region")
+	client, err := awsClient.NewClient(ctx, region)
+	if err != nil {
+		return fmt.Errorf("failed to create AWS client: %w", err)
+	}
+
+	index, err := data.FetchBundleIndex(ctx, client.S3, indexBucket, indexKey)
+	if err != nil {
+		return err
+	}
+	if !index.Seekable {
+		fmt.Printf("⚠️  %s is compressed; reading from its start up to the file\n", index.Bundle)
+	}
+
+	if dir := filepath.Dir(output); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create output directory: %w", err)
+		}
+	}
+
+	// Write beside the destination and rename once the checksum has matched
+	partial := output + ".partial"
+	file, err := os.Create(partial)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", partial, err)
+	}
+	entry, err := data.ExtractBundleMember(ctx, client.S3, bucket, key, index, extractFile, file)
+	if closeErr := file.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("failed to write %s: %w", partial, closeErr)
+	}
+	if err != nil {
+		os.Remove(partial)
+		return err
+	}
+	if err := os.Rename(partial, output); err != nil {
+		return fmt.Errorf("failed to move %s into place: %w", output, err)
+	}
+	os.Chmod(output, os.FileMode(entry.Mode).Perm())
+	os.Chtimes(output, entry.ModTime, entry.ModTime)
+
+	size := entry.Length
+	if len(entry.Sparse) > 0 {
+		size = entry.Size
+	}
+	fmt.Printf("✅ Extracted %s (%s) to %s\n", entry.Path, formatBytes(size), output)
+	return nil
+}
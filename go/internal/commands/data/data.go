@@ -58,7 +58,7 @@ func init() {
 	DataCmd.AddCommand(GetWorkflowCmd())
 
 	// Global flags
-	DataCmd.PersistentFlags().String("region", "us-east-1", "AWS region")
+	DataCmd.PersistentFlags().String("region", "", "AWS region (default: AWS_REGION or AWS_DEFAULT_REGION, then the profile's region)")
 	DataCmd.PersistentFlags().String("config-path", "", "Configuration path for pipelines and settings")
 	DataCmd.PersistentFlags().Int("concurrency", 10, "Number of concurrent transfers")
 	DataCmd.PersistentFlags().String("part-size", "16MB", "Part size for multipart uploads (e.g., 16MB, 64MB)")
@@ -218,7 +218,11 @@ func initializeDataComponents(cmd *cobra.Command) error {
 
 	// Initialize pipeline manager
 	if configPath == "" {
-		configPath = filepath.Join(os.Getenv("HOME"), ".aws-research-wizard")
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return fmt.Errorf("failed to determine home directory: %w", err)
+		}
+		configPath = filepath.Join(homeDir, ".aws-research-wizard")
 	}
 	pipelineManager = data.NewPipelineManager(s3Manager, openDataRegistry, configPath)
 
@@ -505,7 +509,7 @@ func runPipelineCreate(cmd *cobra.Command, args []string) error {
 		RetryAttempts:     3,
 		RetryDelay:        5 * time.Second,
 		Timeout:           30 * time.Minute,
-		WorkingDirectory:  "/tmp",
+		WorkingDirectory:  data.DefaultWorkingDirectory(),
 	}
 
 	pipeline := pipelineManager.CreatePipeline(name, description, config)
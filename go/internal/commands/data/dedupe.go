@@ -0,0 +1,154 @@
+package data
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/data"
+)
+
+// dedupeCmd reports duplicate files before they are uploaded
+var dedupeCmd = &cobra.Command{
+	Use:   "dedupe",
+	Short: "Find duplicate files before uploading",
+	Long: `Find files with identical content under a directory and report how much
+upload time and storage they waste.
+
+Files are grouped by size and only files sharing a size are hashed with
+SHA-256, so most files are never read. --max-hash caps how much data is read;
+size groups beyond the cap are reported as unchecked.
+
+Nothing is changed. --script writes a shell script for review that replaces
+each duplicate with a hard link to the kept copy, or deletes duplicates when
+run with ACTION=remove.
+
+Examples:
+  # Find duplicates of 10MB or more
+  aws-research-wizard data dedupe --path /data --min-size 10MB
+
+  # Read at most 500GB and write a script to review
+  aws-research-wizard data dedupe --path /data --max-hash 500GB --script dedupe.sh`,
+	Args: cobra.NoArgs,
+	RunE: runDedupe,
+}
+
+var (
+	dedupePath         string
+	dedupeMinSize      string
+	dedupeMaxHash      string
+	dedupeWorkers      int
+	dedupeStorageClass string
+	dedupeScript       string
+	dedupeJSON         bool
+)
+
+func init() {
+	DataCmd.AddCommand(dedupeCmd)
+
+	dedupeCmd.Flags().StringVar(&dedupePath, "path", "", "Directory to analyze")
+	dedupeCmd.Flags().StringVar(&dedupeMinSize, "min-size", "1MB", "Ignore files smaller than this (e.g., 10MB)")
+	dedupeCmd.Flags().StringVar(&dedupeMaxHash, "max-hash", "", "Read at most this much data for hashing (e.g., 500GB)")
+	dedupeCmd.Flags().IntVar(&dedupeWorkers, "workers", data.DefaultDedupeWorkers, "Files to hash concurrently")
+	dedupeCmd.Flags().StringVar(&dedupeStorageClass, "storage-class", data.DefaultDedupeStorageClass, "S3 storage class used to estimate savings")
+	dedupeCmd.Flags().StringVar(&dedupeScript, "script", "", "Write a hard link/removal script for review to this file")
+	dedupeCmd.Flags().BoolVar(&dedupeJSON, "json", false, "Output the report as JSON")
+	dedupeCmd.MarkFlagRequired("path")
+}
+
+func runDedupe(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	opts := data.DedupeOptions{Workers: dedupeWorkers}
+	var err error
+	if opts.MinSize, err = parseSize(dedupeMinSize); err != nil {
+		return fmt.Errorf("invalid --min-size: %w", err)
+	}
+	if dedupeMaxHash != "" {
+		if opts.MaxHashBytes, err = parseSize(dedupeMaxHash); err != nil {
+			return fmt.Errorf("invalid --max-hash: %w", err)
+		}
+	}
+
+	root, err := filepath.Abs(dedupePath)
+	if err != nil {
+		return fmt.Errorf("failed to get absolute path: %w", err)
+	}
+	if info, err := os.Stat(root); err != nil {
+		return fmt.Errorf("path not accessible: %w", err)
+	} else if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", root)
+	}
+
+	if !dedupeJSON {
+		fmt.Printf("🔍 Looking for duplicates in %s...\n\n", root)
+	}
+	report, err := data.FindDuplicates(ctx, root, opts)
+	if err != nil {
+		return err
+	}
+
+	region, _ := cmd.Flags().GetString("region")
+	if err := report.EstimateSavings(data.NewS3CostCalculator(region), dedupeStorageClass); err != nil {
+		return err
+	}
+
+	if dedupeScript != "" && len(report.Sets) > 0 {
+		if err := os.WriteFile(dedupeScript, []byte(report.Script()), 0755); err != nil {
+			return fmt.Errorf("failed to write script: %w", err)
+		}
+	}
+
+	if dedupeJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	}
+
+	printDedupeReport(report)
+	return nil
+}
+
+func printDedupeReport(report *data.DedupeReport) {
+	fmt.Printf("  Scanned:    %d files (%s)\n", report.FilesScanned, formatBytes(report.BytesScanned))
+	fmt.Printf("  Hashed:     %d files (%s) sharing a size of %s or more\n", report.HashedFiles, formatBytes(report.HashedBytes), formatBytes(report.MinSize))
+	if report.UncheckedFiles > 0 {
+		fmt.Printf("  Unchecked:  %d files (%s) beyond --max-hash\n", report.UncheckedFiles, formatBytes(report.UncheckedBytes))
+	}
+	fmt.Println()
+
+	if len(report.Sets) == 0 {
+		fmt.Println("✅ No duplicate files found.")
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "COPIES\tSIZE\tWASTED\tKEEP\tDUPLICATES")
+	for _, set := range report.Sets {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s", len(set.Files), formatBytes(set.Size), formatBytes(set.WastedBytes), set.Files[0], set.Files[1])
+		if len(set.Files) > 2 {
+			fmt.Fprintf(w, " (+%d more)", len(set.Files)-2)
+		}
+		fmt.Fprintln(w)
+	}
+	w.Flush()
+
+	fmt.Printf("\n♻️  %d duplicate files in %d sets waste %s (%.1f%% of the data)\n",
+		report.DuplicateFiles, len(report.Sets), formatBytes(report.WastedBytes),
+		float64(report.WastedBytes)/float64(report.BytesScanned)*100)
+	fmt.Printf("💰 Skipping them saves $%.2f/month in S3 %s\n", report.MonthlySavings, report.StorageClass)
+
+	if dedupeScript != "" {
+		fmt.Printf("\n📝 Review %s before running it; nothing has been changed.\n", dedupeScript)
+	} else {
+		fmt.Printf("\n💡 Write a hard link/removal script to review with --script dedupe.sh\n")
+	}
+}
@@ -0,0 +1,92 @@
+package data
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/data"
+)
+
+// doctorCmd reports which optional tools the data features can use on this machine
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check optional tools and platform support for data features",
+	Long: `Report which optional external tools were found on this machine and which
+data features are degraded without them.
+
+The core S3 transfer, analysis and staging features need no external tools.
+Suitcase bundling needs Python 3 with the suitcase package, and the s5cmd and
+rclone transfer engines need their executables on PATH.
+
+Examples:
+  # Check tool availability
+  aws-research-wizard data doctor`,
+	Args: cobra.NoArgs,
+	RunE: runDoctor,
+}
+
+func init() {
+	DataCmd.AddCommand(doctorCmd)
+}
+
+func runDoctor(cmd *cobra.Command, args []string) error {
+	fmt.Printf("🩺 Data Feature Doctor\n")
+	fmt.Printf("Platform: %s/%s\n", runtime.GOOS, runtime.GOARCH)
+	fmt.Printf("Working directory: %s%s\n", data.DefaultWorkingDirectory(), writableNote(data.DefaultWorkingDirectory()))
+	fmt.Printf("Cache directory: %s%s\n\n", data.DefaultCacheDirectory(), writableNote(data.DefaultCacheDirectory()))
+
+	statuses := data.DetectTools(cmd.Context())
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TOOL\tSTATUS\tLOCATION")
+	var degraded []string
+	for _, status := range statuses {
+		if status.Found {
+			location := status.Path
+			if location == "" {
+				location = status.Command
+			}
+			fmt.Fprintf(w, "%s\t✅ found\t%s\n", status.Name, location)
+			continue
+		}
+		fmt.Fprintf(w, "%s\t❌ missing\t%s\n", status.Name, status.Command)
+		degraded = append(degraded, status.Features...)
+	}
+	w.Flush()
+
+	if len(degraded) == 0 {
+		fmt.Printf("\n✅ All optional data features are available\n")
+		return nil
+	}
+
+	fmt.Printf("\n⚠️  Degraded features:\n")
+	seen := make(map[string]bool)
+	for _, feature := range degraded {
+		if seen[feature] {
+			continue
+		}
+		seen[feature] = true
+		fmt.Printf("  - %s\n", feature)
+	}
+
+	return nil
+}
+
+// writableNote checks that a directory can be created and written to
+func writableNote(dir string) string {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return " (❌ not writable)"
+	}
+	probe, err := os.CreateTemp(dir, ".doctor-*")
+	if err != nil {
+		return " (❌ not writable)"
+	}
+	probe.Close()
+	os.Remove(filepath.Clean(probe.Name()))
+	return ""
+}
@@ -0,0 +1,175 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	awsClient "github.com/scttfrdmn/aws-research-wizard/go/internal/aws"
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/data"
+)
+
+// optimizeCmd applies analysis recommendations to a dataset
+var optimizeCmd = &cobra.Command{
+	Use:   "optimize <path>",
+	Short: "Apply analysis recommendations as a resumable execution plan",
+	Long: `Analyze a directory, turn the recommendations into an ordered execution
+plan and run it: bundle small files, upload to S3, then add a lifecycle rule
+moving the upload to the recommended storage class.
+
+Each completed step is checkpointed. If a step fails, rerunning the same
+command resumes from that step. Recommendations with nothing to apply, such as
+compression or tool choices, are listed but not carried out.
+
+Examples:
+  # Show the plan for a dataset without running it
+  aws-research-wizard data optimize /data/run1 --target s3://lab-data/run1 --dry-run
+
+  # Apply only the bundling recommendation, uploading as Standard-IA
+  aws-research-wizard data optimize /data/run1 --target s3://lab-data/run1 \
+    --only bundling-small-files --storage-class STANDARD_IA`,
+	Args: cobra.ExactArgs(1),
+	RunE: runOptimize,
+}
+
+var (
+	optimizeTarget       string
+	optimizeOnly         []string
+	optimizeStorageClass string
+	optimizeBundleDir    string
+	optimizeStateFile    string
+	optimizeYes          bool
+	optimizeDryRun       bool
+)
+
+func init() {
+	DataCmd.AddCommand(optimizeCmd)
+
+	optimizeCmd.Flags().StringVar(&optimizeTarget, "target", "", "S3 URI to upload the dataset to (s3://bucket/prefix)")
+	optimizeCmd.Flags().StringSliceVar(&optimizeOnly, "only", nil, "Apply only these suggestion IDs (default: all)")
+	optimizeCmd.Flags().StringVar(&optimizeStorageClass, "storage-class", "STANDARD", "Storage class to upload objects in")
+	optimizeCmd.Flags().StringVar(&optimizeBundleDir, "bundle-dir", "", "Directory to write bundles to before upload (default: in the cache directory)")
+	optimizeCmd.Flags().StringVar(&optimizeStateFile, "state-file", "", "Checkpoint file for resuming the plan (default: in the cache directory)")
+	optimizeCmd.Flags().BoolVarP(&optimizeYes, "yes", "y", false, "Skip confirmation")
+	optimizeCmd.Flags().BoolVar(&optimizeDryRun, "dry-run", false, "Show the plan without running it")
+	optimizeCmd.MarkFlagRequired("target")
+}
+
+func runOptimize(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	bucket, prefix, err := parseS3URI(optimizeTarget)
+	if err != nil {
+		return fmt.Errorf("invalid target: %w", err)
+	}
+	if bucket == "" {
+		return fmt.Errorf("invalid target: a bucket is required")
+	}
+	source, err := filepath.Abs(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to get absolute path: %w", err)
+	}
+	if info, err := os.Stat(source); err != nil || !info.IsDir() {
+		return fmt.Errorf("not a directory: %s", source)
+	}
+
+	fmt.Printf("🔍 Analyzing data patterns in: %s\n", source)
+	pattern, err := data.NewPatternAnalyzer().AnalyzePattern(ctx, source)
+	if err != nil {
+		return fmt.Errorf("pattern analysis failed: %w", err)
+	}
+	recommendations, err := generateRecommendations(ctx, pattern, source, nil)
+	if err != nil {
+		return fmt.Errorf("failed to generate recommendations: %w", err)
+	}
+
+	plan, err := data.BuildExecutionPlan(recommendations, data.ExecutionPlanOptions{
+		Source:       source,
+		Bucket:       bucket,
+		Prefix:       strings.Trim(prefix, "/"),
+		Suggestions:  optimizeOnly,
+		BundleDir:    optimizeBundleDir,
+		StorageClass: strings.ToUpper(optimizeStorageClass),
+	})
+	if err != nil {
+		return err
+	}
+	printExecutionPlan(plan)
+
+	if optimizeDryRun {
+		fmt.Printf("\n🧪 Dry run - nothing changed\n")
+		return nil
+	}
+
+	statePath := optimizeStateFile
+	if statePath == "" {
+		statePath = data.DefaultPlanStatePath(plan)
+	}
+
+	if !optimizeYes {
+		fmt.Printf("\n⚠️  Running this plan uploads to s3://%s/%s. Continue? (y/N): ", plan.Bucket, plan.Prefix)
+
+		var response string
+		fmt.Scanln(&response)
+		if response != "y" && response != "Y" {
+			fmt.Println("Optimization cancelled.")
+			return nil
+		}
+	}
+
+	if err := initializeDataComponents(cmd); err != nil {
+		return err
+	}
+	region, _ := cmd.Flags().GetString("region")
+	client, err := awsClient.NewClient(ctx, region)
+	if err != nil {
+		return fmt.Errorf("failed to create AWS client: %w", err)
+	}
+
+	fmt.Println()
+	executor := data.NewPlanExecutor(data.NewPlanStepRunners(s3Manager, client.S3), statePath, printPlanProgress)
+	if _, err := executor.Execute(ctx, plan); err != nil {
+		fmt.Printf("\n💾 Progress is saved in %s; rerun the same command to resume\n", statePath)
+		return err
+	}
+
+	fmt.Printf("\n✅ Plan %s complete\n", plan.ID)
+	return nil
+}
+
+func printExecutionPlan(plan *data.ExecutionPlan) {
+	fmt.Printf("\n📋 Execution plan %s\n", plan.ID)
+	for i, step := range plan.Steps {
+		fmt.Printf("  %d. %s\n", i+1, step.Description)
+	}
+	if len(plan.Skipped) > 0 {
+		fmt.Printf("\n⏭️  Not applied:\n")
+		for _, skipped := range plan.Skipped {
+			fmt.Printf("  • %s (%s): %s\n", skipped.Title, skipped.ID, skipped.Reason)
+		}
+	}
+	if plan.EstimatedSavings > 0 {
+		fmt.Printf("\n💰 Estimated savings: $%.2f/month\n", plan.EstimatedSavings)
+	}
+}
+
+func printPlanProgress(progress data.PlanProgress) {
+	prefix := fmt.Sprintf("[%d/%d]", progress.Index, progress.Total)
+	switch progress.Status {
+	case "skipped":
+		fmt.Printf("%s ⏭️  %s (already done)\n", prefix, progress.Step.ID)
+	case "running":
+		fmt.Printf("%s ▶️  %s\n", prefix, progress.Step.Description)
+	case "done":
+		fmt.Printf("%s ✅ %s\n", prefix, progress.Step.ID)
+	case "failed":
+		fmt.Printf("%s ❌ %s: %v\n", prefix, progress.Step.ID, progress.Err)
+	}
+}
@@ -0,0 +1,157 @@
+package data
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	awsClient "github.com/scttfrdmn/aws-research-wizard/go/internal/aws"
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/data"
+)
+
+// planUploadCmd estimates upload time and recommends transfer settings
+var planUploadCmd = &cobra.Command{
+	Use:   "plan-upload",
+	Short: "Estimate upload time and recommend multipart settings",
+	Long: `Estimate how long uploading a directory to S3 will take and recommend the
+multipart part size and concurrency to use, based on the directory's file
+sizes and the available bandwidth.
+
+Give the bandwidth with --bandwidth, or use --probe to measure it by uploading
+a few temporary objects to the target and deleting them again. The plan warns
+when per-file request overhead dominates the upload and bundling small files
+would help, and prints an upload command using the recommended settings.
+
+Examples:
+  # How long will this run take over a 500Mbps campus link?
+  aws-research-wizard data plan-upload --source /data/run-42 --target s3://my-bucket/run-42 --bandwidth 500Mbps
+
+  # Measure the bandwidth to the bucket first
+  aws-research-wizard data plan-upload --source /data/run-42 --target s3://my-bucket/run-42 --probe`,
+	Args: cobra.NoArgs,
+	RunE: runPlanUpload,
+}
+
+// Probe settings: enough parallel data to get past TCP slow start
+const (
+	probeStreams    = 4
+	probeObjectSize = 16 * 1024 * 1024
+)
+
+var (
+	planSource    string
+	planTarget    string
+	planBandwidth string
+	planProbe     bool
+	planJSON      bool
+)
+
+func init() {
+	DataCmd.AddCommand(planUploadCmd)
+
+	planUploadCmd.Flags().StringVar(&planSource, "source", "", "Local directory to upload")
+	planUploadCmd.Flags().StringVar(&planTarget, "target", "", "S3 destination (s3://bucket/prefix)")
+	planUploadCmd.Flags().StringVar(&planBandwidth, "bandwidth", "", "Available upload bandwidth (e.g., 500Mbps, 10Gbps, 100MB/s)")
+	planUploadCmd.Flags().BoolVar(&planProbe, "probe", false, "Measure bandwidth by uploading temporary objects to the target")
+	planUploadCmd.Flags().BoolVar(&planJSON, "json", false, "Output the plan as JSON")
+	planUploadCmd.MarkFlagRequired("source")
+	planUploadCmd.MarkFlagRequired("target")
+}
+
+func runPlanUpload(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if (planBandwidth == "") == !planProbe {
+		return fmt.Errorf("give exactly one of --bandwidth or --probe")
+	}
+
+	bucket, prefix, err := parseS3URI(planTarget)
+	if err != nil {
+		return fmt.Errorf("invalid target: %w", err)
+	}
+	if bucket == "" {
+		return fmt.Errorf("invalid target: bucket is required")
+	}
+
+	source, err := filepath.Abs(planSource)
+	if err != nil {
+		return fmt.Errorf("failed to get absolute path: %w", err)
+	}
+	if _, err := os.Stat(source); err != nil {
+		return fmt.Errorf("source not accessible: %w", err)
+	}
+
+	opts := data.UploadPlanOptions{BandwidthSource: "specified"}
+	if planProbe {
+		region, _ := cmd.Flags().GetString("region")
+		client, err := awsClient.NewClient(ctx, region)
+		if err != nil {
+			return fmt.Errorf("failed to create AWS client: %w", err)
+		}
+		if !planJSON {
+			fmt.Printf("📡 Measuring upload bandwidth to s3://%s ...\n", bucket)
+		}
+		opts.Bandwidth, err = data.MeasureUploadBandwidth(ctx, client.S3, bucket, prefix, probeStreams, probeObjectSize)
+		if err != nil {
+			return err
+		}
+		opts.BandwidthSource = "measured"
+	} else {
+		opts.Bandwidth, err = data.ParseBandwidth(planBandwidth)
+		if err != nil {
+			return err
+		}
+	}
+
+	pattern, err := data.NewPatternAnalyzer().AnalyzePattern(ctx, source)
+	if err != nil {
+		return fmt.Errorf("pattern analysis failed: %w", err)
+	}
+
+	plan, err := data.PlanUpload(pattern, opts)
+	if err != nil {
+		return err
+	}
+	command := plan.UploadCommand(source, planTarget)
+
+	if planJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(struct {
+			*data.UploadPlan
+			Command string `json:"command"`
+		}{plan, command})
+	}
+
+	fmt.Printf("📋 Upload plan: %s → %s\n\n", source, planTarget)
+	fmt.Printf("  Files:        %d (%s), %d under 1MB\n", plan.TotalFiles, formatBytes(plan.TotalBytes), plan.SmallFiles)
+	fmt.Printf("  Bandwidth:    %s (%s)\n", data.FormatBandwidth(plan.Bandwidth), plan.BandwidthSource)
+	fmt.Printf("  Part size:    %s\n", formatBytes(plan.PartSize))
+	fmt.Printf("  Concurrency:  %d\n", plan.Concurrency)
+	fmt.Printf("  Requests:     %d\n\n", plan.Requests)
+
+	fmt.Printf("⏱️  Estimated time: %s\n", data.FormatPlanDuration(plan.EstimatedTime))
+	fmt.Printf("  Data transfer:    %s\n", data.FormatPlanDuration(plan.TransferTime))
+	fmt.Printf("  Request overhead: %s (%.0f%%)\n", data.FormatPlanDuration(plan.OverheadTime), plan.OverheadShare()*100)
+
+	if len(plan.Warnings) > 0 {
+		fmt.Println()
+		for _, warning := range plan.Warnings {
+			fmt.Printf("⚠️  %s\n", warning)
+		}
+	}
+
+	fmt.Printf("\n🚀 Run:\n  %s\n", command)
+	if plan.RecommendsBundle {
+		fmt.Printf("\n💡 To bundle the small files first, preview the bundles with:\n")
+		fmt.Printf("  aws-research-wizard data analyze %s --plan-bundles\n", source)
+	}
+	return nil
+}
@@ -0,0 +1,278 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	awsClient "github.com/scttfrdmn/aws-research-wizard/go/internal/aws"
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/data"
+)
+
+// restoreCmd requests restores of archived objects under a prefix
+var restoreCmd = &cobra.Command{
+	Use:   "restore <s3-uri>",
+	Short: "Restore Glacier and Deep Archive objects under a prefix",
+	Long: `Request a temporary restore of every Glacier Flexible Retrieval and Deep
+Archive object under an S3 prefix, so the objects can be read again.
+
+The retrieval cost of the chosen tier is estimated and confirmed before any
+request is made. Objects already being restored are left alone, so an
+interrupted submission can be rerun. Use "data restore status" to follow the
+restores and download objects as they become available.
+
+Tiers (typical time for Glacier / Deep Archive):
+  expedited  1-5 minutes / not available
+  standard   3-5 hours / within 12 hours
+  bulk       5-12 hours / within 48 hours
+
+Examples:
+  # Restore a run for a week with the cheapest tier
+  aws-research-wizard data restore s3://lab-data/runs/2021-06 --tier bulk --days 7
+
+  # Show what a standard restore would cost
+  aws-research-wizard data restore s3://lab-data/runs/2021-06 --tier standard --dry-run`,
+	Args: cobra.ExactArgs(1),
+	RunE: runRestore,
+}
+
+// restoreStatusCmd reports the progress of restores under a prefix
+var restoreStatusCmd = &cobra.Command{
+	Use:   "status <s3-uri>",
+	Short: "Report restore progress and download restored objects",
+	Long: `Check the restore state of every archived object under an S3 prefix and
+report how many are not requested, in progress and restored, with an estimate
+of when the remaining restores finish.
+
+With --download-to, restored objects are copied locally, skipping files already
+downloaded. With --watch, status is polled until every object is restored.
+
+Examples:
+  # Check on a restore
+  aws-research-wizard data restore status s3://lab-data/runs/2021-06
+
+  # Download objects as they are restored, checking every 30 minutes
+  aws-research-wizard data restore status s3://lab-data/runs/2021-06 \
+    --download-to ./run-2021-06 --watch 30m`,
+	Args: cobra.ExactArgs(1),
+	RunE: runRestoreStatus,
+}
+
+var (
+	restoreTier       string
+	restoreDays       int32
+	restoreYes        bool
+	restoreDryRun     bool
+	restoreDownloadTo string
+	restoreWatch      time.Duration
+)
+
+func init() {
+	DataCmd.AddCommand(restoreCmd)
+	restoreCmd.AddCommand(restoreStatusCmd)
+
+	restoreCmd.Flags().StringVar(&restoreTier, "tier", data.RestoreTierBulk, "Retrieval tier: bulk, standard or expedited")
+	restoreCmd.Flags().Int32Var(&restoreDays, "days", 7, "Days to keep the restored copies")
+	restoreCmd.Flags().BoolVarP(&restoreYes, "yes", "y", false, "Skip confirmation")
+	restoreCmd.Flags().BoolVar(&restoreDryRun, "dry-run", false, "Estimate the cost without requesting restores")
+
+	restoreStatusCmd.Flags().StringVar(&restoreDownloadTo, "download-to", "", "Download restored objects to this directory")
+	restoreStatusCmd.Flags().DurationVar(&restoreWatch, "watch", 0, "Poll at this interval until every object is restored (e.g. 30m)")
+}
+
+func runRestore(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	bucket, prefix, err := parseS3URI(args[0])
+	if err != nil {
+		return err
+	}
+	if bucket == "" {
+		return fmt.Errorf("invalid S3 URI: bucket name is required")
+	}
+	tier, err := data.ValidateRestoreTier(restoreTier)
+	if err != nil {
+		return err
+	}
+
+	region, _ := cmd.Flags().GetString("region")
+	client, err := awsClient.NewClient(ctx, region)
+	if err != nil {
+		return fmt.Errorf("failed to create AWS client: %w", err)
+	}
+
+	fmt.Printf("🔍 Listing archived objects in s3://%s/%s\n", bucket, prefix)
+	objects, err := data.ListArchivedObjects(ctx, client.S3, bucket, prefix)
+	if err != nil {
+		return err
+	}
+	if len(objects) == 0 {
+		fmt.Println("✅ No Glacier or Deep Archive objects found; nothing to restore")
+		return nil
+	}
+
+	estimate, err := data.EstimateRestore(objects, tier, restoreDays)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("\n📦 %d objects (%s), %s tier, kept for %d days\n",
+		estimate.Objects, formatBytes(estimate.Bytes), tier, estimate.Days)
+	fmt.Printf("💰 Estimated cost: $%.2f retrieval + $%.2f temporary storage = $%.2f\n",
+		estimate.RetrievalCost, estimate.StorageCost, estimate.TotalCost())
+	fmt.Printf("⏱️  Typically complete within %s\n", estimate.Typical)
+
+	if restoreDryRun {
+		fmt.Printf("\n🧪 Dry run - no restores requested\n")
+		return nil
+	}
+
+	if !restoreYes {
+		fmt.Printf("\n⚠️  Request these restores? (y/N): ")
+
+		var response string
+		fmt.Scanln(&response)
+		if response != "y" && response != "Y" {
+			fmt.Println("Restore cancelled.")
+			return nil
+		}
+	}
+
+	concurrency, _ := cmd.Flags().GetInt("concurrency")
+	requestedAt := time.Now()
+	submission, err := data.RequestRestores(ctx, client.S3, bucket, objects, tier, restoreDays, concurrency)
+	if err != nil {
+		return fmt.Errorf("restore submission interrupted: %w", err)
+	}
+
+	jobPath := data.DefaultRestoreJobPath(bucket, prefix)
+	if err := data.SaveRestoreJob(jobPath, &data.RestoreJob{
+		Bucket:      bucket,
+		Prefix:      prefix,
+		Tier:        tier,
+		Days:        restoreDays,
+		RequestedAt: requestedAt,
+	}); err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  %v; status will not include an ETA\n", err)
+	}
+
+	fmt.Printf("\n✅ Requested %d restores", submission.Requested)
+	if submission.AlreadyInProgress > 0 {
+		fmt.Printf(" (%d already in progress)", submission.AlreadyInProgress)
+	}
+	fmt.Println()
+	if len(submission.Failures) > 0 {
+		fmt.Printf("\n❌ %d restores could not be requested:\n", len(submission.Failures))
+		for _, failure := range submission.Failures {
+			fmt.Printf("  • %s: %v\n", failure.Key, failure.Err)
+		}
+		return fmt.Errorf("%d of %d restore requests failed; rerun to retry them", len(submission.Failures), len(objects))
+	}
+
+	fmt.Printf("\nCheck progress with: aws-research-wizard data restore status %s\n", args[0])
+	return nil
+}
+
+func runRestoreStatus(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	bucket, prefix, err := parseS3URI(args[0])
+	if err != nil {
+		return err
+	}
+	if bucket == "" {
+		return fmt.Errorf("invalid S3 URI: bucket name is required")
+	}
+
+	job, err := data.LoadRestoreJob(data.DefaultRestoreJobPath(bucket, prefix))
+	if err != nil {
+		return err
+	}
+
+	region, _ := cmd.Flags().GetString("region")
+	client, err := awsClient.NewClient(ctx, region)
+	if err != nil {
+		return fmt.Errorf("failed to create AWS client: %w", err)
+	}
+	if restoreDownloadTo != "" {
+		if err := initializeDataComponents(cmd); err != nil {
+			return err
+		}
+	}
+
+	objects, err := data.ListArchivedObjects(ctx, client.S3, bucket, prefix)
+	if err != nil {
+		return err
+	}
+	if len(objects) == 0 {
+		fmt.Println("✅ No Glacier or Deep Archive objects found")
+		return nil
+	}
+
+	concurrency, _ := cmd.Flags().GetInt("concurrency")
+	for {
+		report, err := data.RestoreStatus(ctx, client.S3, bucket, objects, job, concurrency)
+		if err != nil {
+			return err
+		}
+		printRestoreStatus(report, len(objects))
+
+		if restoreDownloadTo != "" && len(report.Ready) > 0 {
+			downloaded, err := data.DownloadRestoredObjects(ctx, s3Manager, bucket, prefix, report.Ready, restoreDownloadTo)
+			if err != nil {
+				return err
+			}
+			if downloaded > 0 {
+				fmt.Printf("📥 Downloaded %d restored objects to %s\n", downloaded, restoreDownloadTo)
+			}
+		}
+
+		if report.Complete() {
+			fmt.Println("✅ Every object is restored")
+			return nil
+		}
+		if report.Counts[data.RestoreNotRequested] > 0 && report.Counts[data.RestoreInProgress] == 0 {
+			fmt.Printf("💡 Request the remaining restores with: aws-research-wizard data restore %s\n", args[0])
+			return nil
+		}
+		if restoreWatch <= 0 {
+			return nil
+		}
+
+		fmt.Printf("⏳ Checking again in %s\n\n", restoreWatch)
+		select {
+		case <-time.After(restoreWatch):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func printRestoreStatus(report *data.RestoreStatusReport, total int) {
+	fmt.Printf("\n📊 Restore status (%s)\n", time.Now().Format("2006-01-02 15:04"))
+	for _, state := range []string{data.RestoreRestored, data.RestoreInProgress, data.RestoreNotRequested} {
+		fmt.Printf("  %-14s %6d / %d (%s)\n", strings.ReplaceAll(state, "-", " ")+":",
+			report.Counts[state], total, formatBytes(report.Bytes[state]))
+	}
+
+	if report.Counts[data.RestoreInProgress] == 0 {
+		return
+	}
+	switch {
+	case report.ETA.IsZero():
+		fmt.Println("  ETA:            unknown (restores were not requested with this tool)")
+	case time.Until(report.ETA) <= 0:
+		fmt.Printf("  ETA:            overdue since %s; S3 may still be working through the batch\n", report.ETA.Format("2006-01-02 15:04"))
+	default:
+		fmt.Printf("  ETA:            %s (in about %s)\n", report.ETA.Format("2006-01-02 15:04"), time.Until(report.ETA).Round(time.Minute))
+	}
+}
@@ -0,0 +1,176 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/spf13/cobra"
+
+	awsClient "github.com/scttfrdmn/aws-research-wizard/go/internal/aws"
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/config"
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/data"
+)
+
+// sourcesCmd groups commands for browsing domain data sources
+var sourcesCmd = &cobra.Command{
+	Use:   "sources",
+	Short: "Browse the datasets available to research domains",
+	Long: `Browse the datasets research domains work with. List a domain's datasets
+with 'config data-sources <domain>'.`,
+}
+
+// sourcesDescribeCmd shows a dataset and measures its size in S3
+var sourcesDescribeCmd = &cobra.Command{
+	Use:   "describe <name>",
+	Short: "Describe a dataset and measure its size in S3",
+	Long: `Describe a dataset from a domain pack or the AWS Open Data catalog and
+count the objects and bytes under its S3 location.
+
+The dataset can be named by catalog ID, bucket name or display name. Listing
+stops after --max-objects objects, in which case the counts are a lower bound.
+Measurements are cached for a week; use --refresh to measure again.
+
+Examples:
+  aws-research-wizard data sources describe gnomad-public-us-east-1
+  aws-research-wizard data sources describe "1000 Genomes Project" --domain genomics --refresh`,
+	Args: cobra.ExactArgs(1),
+	RunE: runSourcesDescribe,
+}
+
+var (
+	sourcesDomain     string
+	sourcesConfigRoot string
+	sourcesMaxObjects int64
+	sourcesRefresh    bool
+)
+
+func init() {
+	DataCmd.AddCommand(sourcesCmd)
+	sourcesCmd.AddCommand(sourcesDescribeCmd)
+
+	sourcesDescribeCmd.Flags().StringVar(&sourcesDomain, "domain", "", "Look the dataset up among this domain's sources (default: all domains)")
+	sourcesDescribeCmd.Flags().StringVar(&sourcesConfigRoot, "config-root", "", "Directory containing configs/ (default: search upward from the current directory)")
+	sourcesDescribeCmd.Flags().Int64Var(&sourcesMaxObjects, "max-objects", data.DefaultSourceSizeObjectLimit, "Most objects to list when measuring")
+	sourcesDescribeCmd.Flags().BoolVar(&sourcesRefresh, "refresh", false, "Measure again instead of using a cached size")
+}
+
+func runSourcesDescribe(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	dataset, err := findSourceDataset(args[0])
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("📦 %s\n", dataset.Name)
+	if dataset.Description != "" {
+		fmt.Printf("   %s\n", dataset.Description)
+	}
+	fmt.Printf("   Location: %s\n", dataset.Location)
+	if dataset.SizeTB > 0 {
+		fmt.Printf("   Declared size: ~%.0f TB\n", dataset.SizeTB)
+	}
+	if dataset.Format != "" {
+		fmt.Printf("   Format: %s\n", dataset.Format)
+	}
+	if dataset.License != "" {
+		fmt.Printf("   License: %s\n", dataset.License)
+	}
+	if dataset.RegistryURL != "" {
+		fmt.Printf("   Registry: %s\n", dataset.RegistryURL)
+	}
+	if len(dataset.Domains) > 0 {
+		fmt.Printf("   Domains: %v\n", dataset.Domains)
+	}
+
+	bucket, prefix := dataset.Bucket(), dataset.Prefix()
+	cachePath := data.DefaultSourceSizePath(bucket, prefix)
+	var size *data.SourceSize
+	if !sourcesRefresh {
+		if size, err = data.LoadSourceSize(cachePath, data.DefaultSourceSizeMaxAge); err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️  %v\n", err)
+		}
+	}
+
+	if size == nil {
+		region, _ := cmd.Flags().GetString("region")
+		client, err := awsClient.NewClient(ctx, region)
+		if err != nil {
+			return fmt.Errorf("failed to create AWS client: %w", err)
+		}
+
+		fmt.Printf("\n🔍 Measuring s3://%s/%s (up to %d objects)...\n", bucket, prefix, sourcesMaxObjects)
+		size, err = data.MeasurePrefix(ctx, client.S3, bucket, prefix, sourcesMaxObjects)
+		if err != nil {
+			return err
+		}
+		if err := data.SaveSourceSize(cachePath, size); err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️  %v\n", err)
+		}
+	} else {
+		fmt.Printf("\n💾 Measured %s (use --refresh to measure again)\n", size.MeasuredAt.Format("2006-01-02 15:04"))
+	}
+
+	bound := ""
+	if !size.Complete {
+		bound = "at least "
+	}
+	fmt.Printf("   Objects: %s%d\n", bound, size.Objects)
+	fmt.Printf("   Size: %s%s\n", bound, formatBytes(size.Bytes))
+	if !size.Complete {
+		fmt.Printf("   Listing stopped at --max-objects %d; raise it for a full count\n", sourcesMaxObjects)
+	}
+	return nil
+}
+
+// findSourceDataset resolves a dataset name against one domain's sources, or
+// against every domain's sources and then the whole catalog
+func findSourceDataset(name string) (*data.OpenDataset, error) {
+	configRoot := sourcesConfigRoot
+	if configRoot == "" {
+		var err error
+		if configRoot, err = locateConfigRoot(); err != nil {
+			return nil, err
+		}
+	}
+
+	domains, err := config.NewConfigLoader(configRoot).LoadAllDomains()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load domains: %w", err)
+	}
+	catalog, err := data.LoadOpenDataCatalog(data.OpenDataCatalogPath(configRoot))
+	if err != nil {
+		return nil, err
+	}
+
+	if sourcesDomain != "" {
+		domain, exists := domains[sourcesDomain]
+		if !exists {
+			return nil, fmt.Errorf("domain '%s' not found", sourcesDomain)
+		}
+		return catalog.ResolveDataset(sourcesDomain, domain.DataSources(), name)
+	}
+
+	names := make([]string, 0, len(domains))
+	for domainName := range domains {
+		names = append(names, domainName)
+	}
+	sort.Strings(names)
+	for _, domainName := range names {
+		if dataset, err := catalog.ResolveDataset(domainName, domains[domainName].DataSources(), name); err == nil {
+			return dataset, nil
+		}
+	}
+	for i := range catalog.Datasets {
+		dataset := &catalog.Datasets[i]
+		if data.SameDatasetName(dataset.ID, name) || data.SameDatasetName(dataset.Bucket(), name) || data.SameDatasetName(dataset.Name, name) {
+			return dataset, nil
+		}
+	}
+	return nil, fmt.Errorf("dataset '%s' not found in any domain or the open data catalog", name)
+}
@@ -0,0 +1,304 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	awsClient "github.com/scttfrdmn/aws-research-wizard/go/internal/aws"
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/config"
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/data"
+)
+
+// stageCmd copies a subset of an AWS Open Data dataset into a research environment
+var stageCmd = &cobra.Command{
+	Use:   "stage",
+	Short: "Stage AWS Open Data into a research environment",
+	Long: `Copy a subset of an AWS Open Data dataset listed by a domain pack into a
+deployed research environment or another S3 bucket.
+
+The dataset's bucket is resolved from the domain configuration and the size of
+the selected prefix is estimated before anything is copied. Staging to an
+instance runs 'aws s3 cp --recursive' on the instance through SSM and refuses
+to start if the data would not fit on the instance's disk. Staging to an
+s3:// destination copies objects server-side.
+
+Examples:
+  # Stage the 1000 Genomes phase 3 release onto an instance
+  aws-research-wizard data stage --domain genomics --stack my-stack \
+    --dataset 1000genomes --prefix phase3/ --max-size 200GB
+
+  # Estimate size and transfer time only
+  aws-research-wizard data stage --domain genomics --dataset 1000genomes \
+    --prefix phase3/ --dry-run
+
+  # Copy into your own bucket instead of an instance
+  aws-research-wizard data stage --domain genomics --dataset 1000genomes \
+    --prefix phase3/20130502/ --destination s3://my-bucket/1000genomes/`,
+	RunE: runStage,
+}
+
+var (
+	stageDomain      string
+	stageStack       string
+	stageDataset     string
+	stagePrefix      string
+	stageMaxSize     string
+	stageDestination string
+	stageThroughput  float64
+	stageDryRun      bool
+)
+
+// stagePollInterval is how often staging progress is checked on the instance
+const stagePollInterval = 15 * time.Second
+
+func init() {
+	DataCmd.AddCommand(stageCmd)
+
+	stageCmd.Flags().StringVar(&stageDomain, "domain", "", "Research domain whose datasets to stage from")
+	stageCmd.Flags().StringVar(&stageStack, "stack", "", "Deployed stack to stage the data onto")
+	stageCmd.Flags().StringVar(&stageDataset, "dataset", "", "Dataset bucket, catalog ID, or name (e.g. 1000genomes)")
+	stageCmd.Flags().StringVar(&stagePrefix, "prefix", "", "Prefix within the dataset to stage")
+	stageCmd.Flags().StringVar(&stageMaxSize, "max-size", "100GB", "Refuse to stage more than this much data")
+	stageCmd.Flags().StringVar(&stageDestination, "destination", "", "Instance path or s3:// URI to stage into (default /data/<dataset>)")
+	stageCmd.Flags().Float64Var(&stageThroughput, "throughput", data.DefaultStageThroughputMBps, "Expected transfer throughput in MB/s for time estimates")
+	stageCmd.Flags().BoolVar(&stageDryRun, "dry-run", false, "Estimate size and transfer time without copying")
+	stageCmd.MarkFlagRequired("domain")
+	stageCmd.MarkFlagRequired("dataset")
+}
+
+func runStage(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	maxSize, err := parseSize(stageMaxSize)
+	if err != nil {
+		return fmt.Errorf("invalid max-size: %w", err)
+	}
+
+	dataset, err := resolveStageDataset(cmd, stageDomain, stageDataset)
+	if err != nil {
+		return err
+	}
+	bucket := dataset.Bucket()
+	prefix := dataset.Prefix() + strings.TrimPrefix(stagePrefix, "/")
+
+	destination := stageDestination
+	toS3 := strings.HasPrefix(destination, "s3://")
+	if destination == "" {
+		destination = "/data/" + bucket
+	}
+	if !toS3 && !stageDryRun && stageStack == "" {
+		return fmt.Errorf("--stack is required when staging to an instance")
+	}
+
+	region, _ := cmd.Flags().GetString("region")
+	client, err := awsClient.NewClient(ctx, region)
+	if err != nil {
+		return fmt.Errorf("failed to create AWS client: %w", err)
+	}
+
+	fmt.Printf("📦 Dataset: %s (s3://%s/%s)\n", dataset.Name, bucket, prefix)
+	fmt.Printf("🔍 Estimating size...\n")
+
+	estimate, err := data.EstimatePrefixSize(ctx, client.S3, bucket, prefix, maxSize)
+	if err != nil {
+		return err
+	}
+	if estimate.ExceedsLimit {
+		return fmt.Errorf("s3://%s/%s is larger than --max-size %s (over %s across %d objects listed so far); narrow --prefix or raise --max-size",
+			bucket, prefix, stageMaxSize, formatBytes(estimate.TotalBytes), estimate.Objects)
+	}
+	if estimate.Objects == 0 {
+		return fmt.Errorf("no objects found under s3://%s/%s", bucket, prefix)
+	}
+
+	fmt.Printf("   Objects: %d\n", estimate.Objects)
+	fmt.Printf("   Size: %s\n", formatBytes(estimate.TotalBytes))
+	fmt.Printf("   Estimated transfer time: %s at %.0f MB/s\n",
+		data.EstimateTransferDuration(estimate.TotalBytes, stageThroughput), stageThroughput)
+
+	if stageDryRun {
+		fmt.Printf("\n🧪 Dry run - nothing copied\n")
+		return nil
+	}
+
+	if toS3 {
+		return stageToS3(ctx, client, estimate, destination)
+	}
+	return stageToInstance(ctx, client, estimate, destination)
+}
+
+// resolveStageDataset looks up a dataset among those available to a domain
+func resolveStageDataset(cmd *cobra.Command, domainName, datasetName string) (*data.OpenDataset, error) {
+	configRoot, _ := cmd.Flags().GetString("config-root")
+	if configRoot == "" {
+		var err error
+		configRoot, err = locateConfigRoot()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	domains, err := config.NewConfigLoader(configRoot).LoadAllDomains()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load domains: %w", err)
+	}
+	domain, exists := domains[domainName]
+	if !exists {
+		return nil, fmt.Errorf("domain '%s' not found", domainName)
+	}
+
+	catalog, err := data.LoadOpenDataCatalog(data.OpenDataCatalogPath(configRoot))
+	if err != nil {
+		return nil, err
+	}
+
+	return catalog.ResolveDataset(domainName, domain.DataSources(), datasetName)
+}
+
+func stageToS3(ctx context.Context, client *awsClient.Client, estimate *data.StageEstimate, destination string) error {
+	dstBucket, dstPrefix, err := parseS3URI(destination)
+	if err != nil {
+		return fmt.Errorf("invalid destination: %w", err)
+	}
+
+	fmt.Printf("\n🚀 Copying to %s...\n", destination)
+	start := time.Now()
+	err = data.CopyPrefix(ctx, client.S3, estimate.Bucket, estimate.Prefix, dstBucket, dstPrefix, func(objects int, bytes int64) {
+		fmt.Printf("\rProgress: %.1f%% (%d/%d objects, %s)",
+			stagePercent(bytes, estimate.TotalBytes), objects, estimate.Objects, formatBytes(bytes))
+	})
+	if err != nil {
+		fmt.Println()
+		return fmt.Errorf("staging failed: %w", err)
+	}
+
+	fmt.Printf("\n✅ Staged %s to %s in %s\n", formatBytes(estimate.TotalBytes), destination, time.Since(start).Round(time.Second))
+	return nil
+}
+
+func stageToInstance(ctx context.Context, client *awsClient.Client, estimate *data.StageEstimate, destination string) error {
+	stackInfo, err := awsClient.NewInfrastructureManager(client).GetStackInfo(ctx, stageStack)
+	if err != nil {
+		return fmt.Errorf("failed to get stack info: %w", err)
+	}
+	instanceID, err := stackInfo.InstanceID()
+	if err != nil {
+		return err
+	}
+
+	runner := awsClient.NewCommandRunner(client)
+
+	fmt.Printf("\n💾 Checking free space on %s:%s...\n", instanceID, destination)
+	result, err := runner.Run(ctx, instanceID, []string{data.FreeSpaceCommand(destination)})
+	if err != nil {
+		return fmt.Errorf("failed to check free space (is the SSM agent running?): %w", err)
+	}
+	available, err := data.ParseFreeSpace(result.Stdout)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("   Free: %s\n", formatBytes(available))
+	if err := data.CheckFreeSpace(destination, estimate.TotalBytes, available); err != nil {
+		return err
+	}
+
+	before, err := instanceUsedBytes(ctx, runner, instanceID, destination)
+	if err != nil {
+		return err
+	}
+
+	// Leave generous slack over the estimate before SSM gives up on the copy
+	timeout := 3*data.EstimateTransferDuration(estimate.TotalBytes, stageThroughput) + time.Hour
+
+	fmt.Printf("\n🚀 Copying to %s:%s...\n", instanceID, destination)
+	start := time.Now()
+	commandID, err := runner.Start(ctx, instanceID,
+		[]string{data.StageCopyCommand(estimate.Bucket, estimate.Prefix, destination)}, timeout)
+	if err != nil {
+		return err
+	}
+
+	for {
+		status, err := runner.Status(ctx, instanceID, commandID)
+		if err != nil {
+			return err
+		}
+		if status.Done() {
+			if !status.Succeeded() {
+				fmt.Println()
+				return fmt.Errorf("staging failed with status %s: %s", status.Status, strings.TrimSpace(status.Stderr))
+			}
+			break
+		}
+
+		if used, err := instanceUsedBytes(ctx, runner, instanceID, destination); err == nil {
+			copied := used - before
+			fmt.Printf("\rProgress: %.1f%% (%s of %s)",
+				stagePercent(copied, estimate.TotalBytes), formatBytes(copied), formatBytes(estimate.TotalBytes))
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(stagePollInterval):
+		}
+	}
+
+	fmt.Printf("\n✅ Staged %s to %s:%s in %s\n", formatBytes(estimate.TotalBytes), instanceID, destination, time.Since(start).Round(time.Second))
+	return nil
+}
+
+// instanceUsedBytes reports how much data is stored under a path on an instance
+func instanceUsedBytes(ctx context.Context, runner *awsClient.CommandRunner, instanceID, path string) (int64, error) {
+	result, err := runner.Run(ctx, instanceID, []string{data.UsedSpaceCommand(path)})
+	if err != nil {
+		return 0, err
+	}
+
+	output := strings.TrimSpace(result.Stdout)
+	if output == "" {
+		return 0, nil
+	}
+	return strconv.ParseInt(output, 10, 64)
+}
+
+func stagePercent(done, total int64) float64 {
+	if total <= 0 {
+		return 100
+	}
+	percent := float64(done) / float64(total) * 100
+	if percent > 100 {
+		percent = 100
+	}
+	return percent
+}
+
+// locateConfigRoot looks for a configs directory in the current directory and its parents
+func locateConfigRoot() (string, error) {
+	currentDir, err := os.Getwd()
+	if err != nil {
+		return "", fmt.Errorf("failed to get current directory: %w", err)
+	}
+
+	for {
+		if _, err := os.Stat(filepath.Join(currentDir, "configs")); err == nil {
+			return currentDir, nil
+		}
+
+		parent := filepath.Dir(currentDir)
+		if parent == currentDir {
+			return "", fmt.Errorf("could not find configs directory; specify it with --config-root")
+		}
+		currentDir = parent
+	}
+}
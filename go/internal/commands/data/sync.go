@@ -0,0 +1,250 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+
+	"github.com/spf13/cobra"
+
+	awsClient "github.com/scttfrdmn/aws-research-wizard/go/internal/aws"
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/data"
+)
+
+// syncBundleDir is the directory under the target prefix that bundles of
+// small listed files are uploaded to
+const syncBundleDir = "_bundles"
+
+// syncCmd uploads only the files a manifest lists
+var syncCmd = &cobra.Command{
+	Use:   "sync",
+	Short: "Upload the files listed in a manifest",
+	Long: `Upload only the files a manifest selects from a source directory, leaving
+the rest of the directory alone.
+
+The manifest is a plain list with one path per line, or a CSV or TSV file with
+a header naming the path column (path, file, filepath or filename, or the
+column given by --column). Paths are relative to the source directory and may
+be glob patterns: * and ? match within a directory, ** matches any number of
+directories, and a pattern without a slash matches at any depth. A directory
+selects every file below it. Blank lines and lines starting with # are ignored.
+
+Entries that select no files are reported with their manifest line numbers;
+with --strict nothing is uploaded while any are missing.
+
+Files whose object in S3 already matches are skipped, so re-running a sync
+uploads only new and changed files. Progress is recorded in a journal so an
+interrupted sync resumes where it stopped; the journal is removed once the
+sync completes. With --bundle-small, listed files at or below the size are
+bundled into chunks under ` + syncBundleDir + `/ instead of uploaded one by one, and
+only chunks holding changed files are uploaded again.
+
+Examples:
+  # Upload the files listed one per line
+  aws-research-wizard data sync --source ./run-42 --target s3://my-bucket/runs/42 --manifest files.txt
+
+  # Upload the r1 column of a sample sheet, failing if any file is missing
+  aws-research-wizard data sync --source ./run-42 --target s3://my-bucket/runs/42 \
+    --manifest samples.csv --column r1 --strict
+
+  # Check what a pattern manifest selects without uploading
+  aws-research-wizard data sync --source ./run-42 --target s3://my-bucket/runs/42 --manifest patterns.txt --dry-run`,
+	Args: cobra.NoArgs,
+	RunE: runSync,
+}
+
+var (
+	syncSource      string
+	syncTarget      string
+	syncManifest    string
+	syncColumn      string
+	syncBundleSmall string
+	syncDryRun      bool
+	syncStrict      bool
+	syncRestart     bool
+)
+
+func init() {
+	DataCmd.AddCommand(syncCmd)
+
+	syncCmd.Flags().StringVar(&syncSource, "source", "", "Local directory the manifest paths are relative to")
+	syncCmd.Flags().StringVar(&syncTarget, "target", "", "S3 URI to upload to (s3://bucket/prefix)")
+	syncCmd.Flags().StringVar(&syncManifest, "manifest", "", "List, CSV or TSV file naming the files to upload")
+	syncCmd.Flags().StringVar(&syncColumn, "column", "", "Path column of a CSV or TSV manifest (default: path, file, filepath or filename)")
+	syncCmd.Flags().StringVar(&syncBundleSmall, "bundle-small", "", "Bundle listed files at or below this size (e.g. 1MB) instead of uploading them one by one")
+	syncCmd.Flags().BoolVar(&syncDryRun, "dry-run", false, "Show what the manifest selects without uploading")
+	syncCmd.Flags().BoolVar(&syncStrict, "strict", false, "Upload nothing if any manifest entry selects no files")
+	syncCmd.Flags().BoolVar(&syncRestart, "restart", false, "Discard the progress journal and check every file again")
+	syncCmd.MarkFlagRequired("source")
+	syncCmd.MarkFlagRequired("target")
+	syncCmd.MarkFlagRequired("manifest")
+}
+
+func runSync(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	bucket, prefix, err := parseS3URI(syncTarget)
+	if err != nil {
+		return fmt.Errorf("invalid target: %w", err)
+	}
+	if bucket == "" {
+		return fmt.Errorf("invalid target: bucket name is required")
+	}
+	var bundleThreshold int64
+	if syncBundleSmall != "" {
+		if bundleThreshold, err = parseSize(syncBundleSmall); err != nil {
+			return fmt.Errorf("invalid --bundle-small size: %w", err)
+		}
+	}
+
+	manifest, err := data.LoadSyncManifest(syncManifest, syncColumn)
+	if err != nil {
+		return err
+	}
+	resolution, err := data.ResolveSyncManifest(syncSource, manifest)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("📋 %s (%s): %d entries select %d files, %s\n",
+		syncManifest, manifest.Format, len(manifest.Entries), len(resolution.Files), formatBytes(resolution.TotalBytes))
+	if resolution.Duplicates > 0 {
+		fmt.Printf("   %d files selected by more than one entry are uploaded once\n", resolution.Duplicates)
+	}
+	if len(resolution.Missing) > 0 {
+		fmt.Printf("⚠️  %d manifest entries select no files:\n", len(resolution.Missing))
+		for _, miss := range resolution.Missing {
+			fmt.Printf("   line %d: %s (%s)\n", miss.Line, miss.Entry, miss.Reason)
+		}
+		if syncStrict {
+			return fmt.Errorf("%d manifest entries select no files; nothing was uploaded", len(resolution.Missing))
+		}
+	}
+	if len(resolution.Files) == 0 {
+		fmt.Println("No files to upload")
+		return nil
+	}
+
+	files := resolution.Files
+	var small []data.SyncFile
+	if bundleThreshold > 0 {
+		small, files = data.SplitSmallFiles(files, bundleThreshold)
+	}
+
+	if syncDryRun {
+		for _, file := range files {
+			fmt.Printf("  %s  %s\n", file.RelativePath, formatBytes(file.Size))
+		}
+		if len(small) > 0 {
+			fmt.Printf("  %d files at or below %s bundled under %s/\n", len(small), syncBundleSmall, syncBundleDir)
+		}
+		fmt.Println("💡 Dry run: nothing was uploaded")
+		return nil
+	}
+
+	if err := initializeDataComponents(cmd); err != nil {
+		return err
+	}
+	region, _ := cmd.Flags().GetString("region")
+	client, err := awsClient.NewClient(ctx, region)
+	if err != nil {
+		return fmt.Errorf("failed to create AWS client: %w", err)
+	}
+
+	journalPath := data.DefaultVerifyJournalPath(syncSource, bucket, prefix)
+	if syncRestart {
+		if err := os.Remove(journalPath); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove journal: %w", err)
+		}
+	}
+	journal, err := data.OpenVerifyJournal(journalPath)
+	if err != nil {
+		return err
+	}
+	defer journal.Close()
+	if resumable := journal.Len(); resumable > 0 {
+		fmt.Printf("⏯️  Resuming: %d files handled in a previous run\n", resumable)
+	}
+
+	upload := func(ctx context.Context, key, localPath string) error {
+		return s3Manager.UploadFile(ctx, bucket, key, localPath, nil)
+	}
+
+	if len(small) > 0 {
+		if err := syncBundles(ctx, client, small, bucket, path.Join(prefix, syncBundleDir), journal, upload); err != nil {
+			return fmt.Errorf("sync interrupted (progress saved to %s): %w", journalPath, err)
+		}
+	}
+
+	fmt.Printf("☁️  Syncing %d files to s3://%s/%s\n", len(files), bucket, prefix)
+	progress := func(done, total int, file data.SyncFile, status string) {
+		fmt.Fprintf(os.Stderr, "\rSynced %d/%d files", done, total)
+	}
+	report, err := data.SyncManifestFiles(ctx, client.S3, bucket, prefix, files, journal, upload, progress)
+	if len(files) > 0 {
+		fmt.Fprintln(os.Stderr)
+	}
+	if err != nil {
+		return fmt.Errorf("sync interrupted (progress saved to %s): %w", journalPath, err)
+	}
+	if err := journal.Remove(); err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Failed to remove journal %s: %v\n", journalPath, err)
+	}
+
+	fmt.Printf("✅ Uploaded %d files (%s)\n", report.Uploaded, formatBytes(report.UploadedBytes))
+	if skipped := report.Unchanged + report.Resumed; skipped > 0 {
+		fmt.Printf("   Skipped %d unchanged files (%s)", skipped, formatBytes(report.SkippedBytes))
+		if report.Resumed > 0 {
+			fmt.Printf(", %d from the journal", report.Resumed)
+		}
+		fmt.Println()
+	}
+	if len(resolution.Missing) > 0 {
+		fmt.Printf("⚠️  %d manifest entries selected no files and were not uploaded\n", len(resolution.Missing))
+	}
+	return nil
+}
+
+// syncBundles bundles small listed files into chunks in a temporary
+// directory and uploads the chunks that differ from those already in S3
+func syncBundles(ctx context.Context, client *awsClient.Client, files []data.SyncFile, bucket, prefix string, journal *data.VerifyJournal, upload data.BundleUploader) error {
+	outputDir, err := os.MkdirTemp("", "arw-sync-bundles-")
+	if err != nil {
+		return fmt.Errorf("failed to create bundle directory: %w", err)
+	}
+	defer os.RemoveAll(outputDir)
+
+	engine := data.NewSuitcaseEngine(&data.SuitcaseConfig{
+		Backend:          data.NativeTarBackend,
+		Chunked:          true,
+		TargetBundleSize: "100MB",
+		CompressionLevel: 6,
+		OutputDirectory:  outputDir,
+	})
+	// Progress updates are not shown, but the channel must not fill up
+	go func() {
+		for range engine.GetProgress() {
+		}
+	}()
+
+	fmt.Printf("📦 Bundling %d small files\n", len(files))
+	result, err := engine.BundleFileList(ctx, syncSource, data.FileEntries(files))
+	if err != nil {
+		return err
+	}
+	report, err := data.UploadChunkedBundles(ctx, client.S3, bucket, prefix, result.BundleManifest, journal, upload)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("☁️  Uploaded %d of %d bundles (%s) to s3://%s/%s\n",
+		report.Uploaded, len(result.BundleManifest), formatBytes(report.UploadedBytes), bucket, prefix)
+	if skipped := report.Unchanged + report.Resumed; skipped > 0 {
+		fmt.Printf("   Skipped %d unchanged bundles (%s)\n", skipped, formatBytes(report.SkippedBytes))
+	}
+	return nil
+}
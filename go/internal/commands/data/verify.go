@@ -0,0 +1,227 @@
+package data
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	awsClient "github.com/scttfrdmn/aws-research-wizard/go/internal/aws"
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/data"
+)
+
+// verifyCmd audits an upload by checksumming local files against their S3 objects
+var verifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Verify uploaded files against their S3 objects",
+	Long: `Walk a local directory and confirm every file arrived intact under an S3
+prefix, reporting missing, extra and mismatched objects.
+
+Each file is checksummed and compared with the object's SHA-256 checksum when
+S3 has one, otherwise with its ETag. Multipart ETags are matched by trying the
+part sizes common upload tools use. One that matches none of them is a
+mismatch when this tool's default part size or --part-size fits its part
+count, and is otherwise reported as unverifiable, since the part size used
+cannot be known. Objects encrypted
+with KMS or customer keys have no content ETag and are reported as unverifiable
+when their sizes match.
+
+Verified files are recorded in a progress journal so an interrupted run
+resumes where it stopped; files changed since are checked again. The journal
+is removed after a run with no discrepancies.
+
+Examples:
+  # Verify an uploaded directory
+  aws-research-wizard data verify --source ./sequencing-run --target s3://my-bucket/runs/2025-03
+
+  # Write discrepancies to a manifest for follow-up
+  aws-research-wizard data verify --source ./data --target s3://my-bucket/data --manifest discrepancies.json
+
+  # Ignore a previous journal and check everything again
+  aws-research-wizard data verify --source ./data --target s3://my-bucket/data --restart`,
+	Args: cobra.NoArgs,
+	RunE: runVerify,
+}
+
+var (
+	verifySource   string
+	verifyTarget   string
+	verifyWorkers  int
+	verifyJournal  string
+	verifyManifest string
+	verifyRestart  bool
+	verifyJSON     bool
+)
+
+func init() {
+	DataCmd.AddCommand(verifyCmd)
+
+	verifyCmd.Flags().StringVar(&verifySource, "source", "", "Local file or directory that was uploaded")
+	verifyCmd.Flags().StringVar(&verifyTarget, "target", "", "S3 URI the source was uploaded to (s3://bucket/prefix)")
+	verifyCmd.Flags().IntVar(&verifyWorkers, "workers", data.DefaultVerifyWorkers, "Files to checksum concurrently")
+	verifyCmd.Flags().StringVar(&verifyJournal, "journal", "", "Progress journal path (default under the cache directory)")
+	verifyCmd.Flags().StringVar(&verifyManifest, "manifest", "", "Write discrepancies as JSON to this file")
+	verifyCmd.Flags().BoolVar(&verifyRestart, "restart", false, "Discard the progress journal and verify every file")
+	verifyCmd.Flags().BoolVar(&verifyJSON, "json", false, "Output the full report as JSON")
+	verifyCmd.MarkFlagRequired("source")
+	verifyCmd.MarkFlagRequired("target")
+}
+
+func runVerify(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	bucket, prefix, err := parseS3URI(verifyTarget)
+	if err != nil {
+		return fmt.Errorf("invalid target: %w", err)
+	}
+	if bucket == "" {
+		return fmt.Errorf("invalid target: bucket name is required")
+	}
+
+	journalPath := verifyJournal
+	if journalPath == "" {
+		journalPath = data.DefaultVerifyJournalPath(verifySource, bucket, prefix)
+	}
+	if verifyRestart {
+		if err := os.Remove(journalPath); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove journal: %w", err)
+		}
+	}
+	journal, err := data.OpenVerifyJournal(journalPath)
+	if err != nil {
+		return err
+	}
+	defer journal.Close()
+
+	// The default part size is always tried; the flag records another
+	var partSize int64
+	if cmd.Flags().Changed("part-size") {
+		partSizeStr, _ := cmd.Flags().GetString("part-size")
+		if partSize, err = parseSize(partSizeStr); err != nil {
+			return fmt.Errorf("invalid part-size: %w", err)
+		}
+	}
+
+	region, _ := cmd.Flags().GetString("region")
+	client, err := awsClient.NewClient(ctx, region)
+	if err != nil {
+		return fmt.Errorf("failed to create AWS client: %w", err)
+	}
+
+	progress := func(checked, total int) {
+		fmt.Fprintf(os.Stderr, "\rVerified %d/%d files", checked, total)
+	}
+	if !verifyJSON {
+		fmt.Printf("🔍 Verifying %s against %s\n", verifySource, verifyTarget)
+		if resumable := journal.Len(); resumable > 0 {
+			fmt.Printf("⏯️  Resuming: %d files verified in a previous run\n", resumable)
+		}
+	} else {
+		progress = nil
+	}
+
+	report, err := data.VerifyUpload(ctx, client.S3, verifySource, bucket, prefix, data.VerifyOptions{
+		Workers:  verifyWorkers,
+		Journal:  journal,
+		Progress: progress,
+		PartSize: partSize,
+	})
+	if progress != nil {
+		fmt.Fprintln(os.Stderr)
+	}
+	if err != nil {
+		return fmt.Errorf("verification interrupted (progress saved to %s): %w", journalPath, err)
+	}
+
+	if verifyManifest != "" {
+		if err := writeVerifyManifest(verifyManifest, report); err != nil {
+			return err
+		}
+	}
+
+	if len(report.Discrepancies) == 0 {
+		if err := journal.Remove(); err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️  Failed to remove journal %s: %v\n", journalPath, err)
+		}
+	}
+
+	if verifyJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			return fmt.Errorf("failed to encode report: %w", err)
+		}
+	} else {
+		printVerifyReport(report)
+	}
+
+	if report.Failed() {
+		return fmt.Errorf("verification failed: %d missing, %d mismatched",
+			report.Counts[data.VerifyMissing], report.Counts[data.VerifyMismatch])
+	}
+	return nil
+}
+
+// writeVerifyManifest saves the discrepancies so they can be re-uploaded or investigated
+func writeVerifyManifest(path string, report *data.VerifyReport) error {
+	manifest := struct {
+		Source        string                   `json:"source"`
+		Target        string                   `json:"target"`
+		GeneratedAt   time.Time                `json:"generated_at"`
+		Counts        map[string]int           `json:"counts"`
+		Discrepancies []data.VerifyDiscrepancy `json:"discrepancies"`
+	}{
+		Source:        report.Source,
+		Target:        fmt.Sprintf("s3://%s/%s", report.Bucket, report.Prefix),
+		GeneratedAt:   report.CompletedAt,
+		Counts:        report.Counts,
+		Discrepancies: report.Discrepancies,
+	}
+	if manifest.Discrepancies == nil {
+		manifest.Discrepancies = []data.VerifyDiscrepancy{}
+	}
+
+	content, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	if err := os.WriteFile(path, append(content, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	return nil
+}
+
+func printVerifyReport(report *data.VerifyReport) {
+	fmt.Printf("\n📋 Verification Summary\n")
+	fmt.Printf("   Files checked: %d (%s)\n", report.Files, formatBytes(report.Bytes))
+	fmt.Printf("   Verified: %d", report.Verified)
+	if report.Resumed > 0 {
+		fmt.Printf(" (%d from journal)", report.Resumed)
+	}
+	fmt.Println()
+	fmt.Printf("   Missing: %d\n", report.Counts[data.VerifyMissing])
+	fmt.Printf("   Mismatched: %d\n", report.Counts[data.VerifyMismatch])
+	fmt.Printf("   Extra objects: %d\n", report.Counts[data.VerifyExtra])
+	fmt.Printf("   Unverifiable: %d\n", report.Counts[data.VerifyUnverifiable])
+	fmt.Printf("   Duration: %s\n", report.CompletedAt.Sub(report.StartedAt).Round(time.Second))
+
+	if len(report.Discrepancies) == 0 {
+		fmt.Printf("\n✅ All files verified\n")
+		return
+	}
+
+	fmt.Println()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "STATUS\tKEY\tDETAIL")
+	for _, discrepancy := range report.Discrepancies {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", discrepancy.Status, discrepancy.Key, discrepancy.Detail)
+	}
+	w.Flush()
+}
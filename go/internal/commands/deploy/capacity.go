@@ -0,0 +1,108 @@
+package deploy
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/aws"
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/aws/templates"
+)
+
+func createCapacityCommand(instanceType *string) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "capacity",
+		Short: "Work with EC2 On-Demand Capacity Reservations",
+		Long: `Find On-Demand Capacity Reservations (ODCRs) to deploy into.
+
+Deploy into a reservation with --capacity-reservation-id, or into any
+reservation in a resource group with --capacity-reservation-group.`,
+	}
+	cmd.AddCommand(createCapacityCheckCommand(instanceType))
+	return cmd
+}
+
+func createCapacityCheckCommand(instanceType *string) *cobra.Command {
+	return &cobra.Command{
+		Use:   "check",
+		Short: "List active capacity reservations for an instance type",
+		Example: `  aws-research-wizard deploy capacity check --instance p4d.24xlarge
+  aws-research-wizard deploy --domain machine_learning --instance p4d.24xlarge \
+    --capacity-reservation-id cr-0123456789abcdef0`,
+		Run: func(cmd *cobra.Command, args []string) {
+			instance := *instanceType
+			if instance == "" {
+				log.Fatal("Instance type is required. Use --instance flag.")
+			}
+
+			ctx := context.Background()
+			region, _ := cmd.Flags().GetString("region")
+			awsClient, err := aws.NewClient(ctx, region)
+			if err != nil {
+				log.Fatalf("Failed to initialize AWS client: %v", err)
+			}
+
+			reservations, err := aws.NewCapacityManager(awsClient).ListCapacityReservations(ctx, instance)
+			if err != nil {
+				log.Fatalf("Failed to list capacity reservations: %v", err)
+			}
+
+			fmt.Printf("🎟️  Active capacity reservations for %s in %s (%d total):\n\n", instance, awsClient.Region, len(reservations))
+			if len(reservations) == 0 {
+				fmt.Printf("No active reservations. Instances launch from On-Demand capacity, which may not be available for %s.\n", instance)
+				return
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "RESERVATION\tZONE\tAVAILABLE\tPLATFORM\tTENANCY\tMATCH\tEXPIRES")
+			for _, r := range reservations {
+				fmt.Fprintf(w, "%s\t%s\t%d of %d\t%s\t%s\t%s\t%s\n",
+					r.ID, r.AvailabilityZone, r.AvailableCount, r.TotalCount, r.Platform, r.Tenancy, r.InstanceMatchCriteria, r.Expiry())
+			}
+			w.Flush()
+
+			fmt.Printf("\n💡 Deploy into one with: aws-research-wizard deploy --instance %s --capacity-reservation-id %s\n", instance, reservations[0].ID)
+		},
+	}
+}
+
+// deploymentInstances counts the instances of each type a deployment launches
+func deploymentInstances(arch templates.Architecture, opts templates.Options) map[string]int {
+	counts := map[string]int{opts.InstanceType: 1}
+	if arch == templates.ArchitectureHeadCompute && opts.ComputeNodes > 0 {
+		computeType := opts.ComputeInstanceType
+		if computeType == "" {
+			computeType = opts.InstanceType
+		}
+		counts[computeType] += opts.ComputeNodes
+	}
+	return counts
+}
+
+// checkCapacityReservation fails when the targeted capacity reservation cannot
+// take the deployment's instances, and returns the reservation's zone
+func checkCapacityReservation(ctx context.Context, awsClient *aws.Client, arch templates.Architecture, opts templates.Options, subnetID string) (string, error) {
+	manager := aws.NewCapacityManager(awsClient)
+	reservation, err := manager.GetCapacityReservation(ctx, opts.CapacityReservationID)
+	if err != nil {
+		return "", err
+	}
+
+	var zone string
+	if subnetID != "" {
+		if zone, err = manager.SubnetAvailabilityZone(ctx, subnetID); err != nil {
+			return "", err
+		}
+	}
+	if err := reservation.CheckDeployment(deploymentInstances(arch, opts), zone); err != nil {
+		return "", fmt.Errorf("capacity reservation check failed: %w", err)
+	}
+
+	fmt.Printf("✅ Capacity reservation: %s, %d of %d %s available in %s (expires %s)\n\n",
+		reservation.ID, reservation.AvailableCount, reservation.TotalCount, reservation.InstanceType, reservation.AvailabilityZone, reservation.Expiry())
+	return reservation.AvailabilityZone, nil
+}
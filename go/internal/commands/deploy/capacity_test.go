@@ -0,0 +1,50 @@
+package deploy
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/aws/templates"
+)
+
+func TestDeploymentInstances(t *testing.T) {
+	opts := templates.DefaultOptions("ml", "p4d.24xlarge")
+
+	if got := deploymentInstances(templates.ArchitectureSingle, opts); !reflect.DeepEqual(got, map[string]int{"p4d.24xlarge": 1}) {
+		t.Errorf("single = %v", got)
+	}
+
+	opts.ComputeNodes = 4
+	if got := deploymentInstances(templates.ArchitectureHeadCompute, opts); !reflect.DeepEqual(got, map[string]int{"p4d.24xlarge": 5}) {
+		t.Errorf("head-compute = %v, want the head node and compute nodes together", got)
+	}
+
+	opts.ComputeInstanceType = "p5.48xlarge"
+	want := map[string]int{"p4d.24xlarge": 1, "p5.48xlarge": 4}
+	if got := deploymentInstances(templates.ArchitectureHeadCompute, opts); !reflect.DeepEqual(got, want) {
+		t.Errorf("head-compute = %v, want %v", got, want)
+	}
+}
+
+func TestGenerateCloudFormationTemplateCapacityReservation(t *testing.T) {
+	resources := resourceFlags{
+		SSHCIDR:               defaultSSHCIDR,
+		CapacityReservationID: "cr-0123456789abcdef0",
+		AvailabilityZone:      "us-east-1c",
+	}
+	parsed := renderTemplate(t, testDomain("ml"), "p4d.24xlarge", resources)
+
+	instance := parsed.Resources[templates.InstanceLogicalID].Properties
+	target := instance["CapacityReservationSpecification"].(map[string]interface{})["CapacityReservationTarget"].(map[string]interface{})
+	if target["CapacityReservationId"] != "cr-0123456789abcdef0" {
+		t.Errorf("Unexpected capacity reservation target %v", target)
+	}
+	if instance["AvailabilityZone"] != "us-east-1c" {
+		t.Errorf("Expected the instance pinned to the reservation's zone, got %v", instance["AvailabilityZone"])
+	}
+
+	resources.CapacityReservationGroup = "arn:aws:resource-groups:us-east-1:123456789012:group/odcrs"
+	if _, err := generateCloudFormationTemplate(testDomain("ml"), "p4d.24xlarge", resources); err == nil {
+		t.Error("Expected a reservation and a reservation group together to be rejected")
+	}
+}
@@ -2,16 +2,23 @@ package deploy
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/spf13/cobra"
 
 	"github.com/scttfrdmn/aws-research-wizard/go/internal/aws"
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/aws/templates"
 	"github.com/scttfrdmn/aws-research-wizard/go/internal/config"
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/state"
 )
 
 // NewDeployCommand creates the deploy subcommand
@@ -22,6 +29,11 @@ func NewDeployCommand() *cobra.Command {
 	var instanceType string
 	var dryRun bool
 	var timeout time.Duration
+	var resources resourceFlags
+	var skipQuotaCheck bool
+	var validateAfter bool
+	var envFlags environmentFlags
+	var yes bool
 
 	deployCmd := &cobra.Command{
 		Use:   "deploy",
@@ -34,9 +46,29 @@ This deployment tool provides:
 - EC2 instance provisioning
 - Security group configuration
 - Monitoring setup
-- Cost tracking`,
+- Cost tracking
+
+Named environments in a wizard.yaml file make deployments repeatable. Values
+come from the file's defaults, then the environment, then any flags given on
+the command line. ${VAR} and ${VAR:-default} in the file are read from the
+shell environment.
+
+Examples:
+  # Deploy the prod-genomics environment from ./wizard.yaml
+  aws-research-wizard deploy --env prod-genomics
+
+  # Override the instance type and show the merged configuration
+  aws-research-wizard deploy --env prod-genomics --instance r6i.8xlarge --show-config
+
+  # Check every environment in the file without calling AWS
+  aws-research-wizard deploy validate --file wizard.yaml`,
 		Run: func(cmd *cobra.Command, args []string) {
-			runInteractiveDeploy(cmd, configRoot, stackName, domainName, instanceType, dryRun, timeout)
+			region, _ := cmd.Flags().GetString("region")
+			settings := deploySettings{Region: &region, DomainName: &domainName, InstanceType: &instanceType, StackName: &stackName, Resources: &resources}
+			if !prepareDeploy(cmd, envFlags, settings) {
+				return
+			}
+			runInteractiveDeploy(region, configRoot, stackName, domainName, instanceType, dryRun, skipQuotaCheck, validateAfter, yes, timeout, resources)
 		},
 	}
 
@@ -47,20 +79,78 @@ This deployment tool provides:
 	deployCmd.PersistentFlags().StringVar(&instanceType, "instance", "", "EC2 instance type")
 	deployCmd.PersistentFlags().BoolVar(&dryRun, "dry-run", false, "Show deployment plan without executing")
 	deployCmd.PersistentFlags().DurationVar(&timeout, "timeout", 30*time.Minute, "Deployment timeout")
+	deployCmd.PersistentFlags().BoolVar(&skipQuotaCheck, "skip-quota-check", false, "Skip the EC2 vCPU quota pre-flight check")
+	deployCmd.PersistentFlags().BoolVar(&validateAfter, "validate-after", false, "Run the domain pack's validation checks on the instance once the stack completes")
+	deployCmd.PersistentFlags().StringVar(&resources.SSHCIDR, "ssh-cidr", defaultSSHCIDR, "CIDR range allowed to reach SSH and Jupyter")
+	deployCmd.PersistentFlags().BoolVar(&resources.EncryptVolume, "encrypt-volume", false, "Encrypt the root EBS volume")
+	deployCmd.PersistentFlags().BoolVar(&resources.InstanceRole, "instance-role", false, "Attach an IAM instance role (SSM managed)")
+	deployCmd.PersistentFlags().BoolVar(&resources.NoSSH, "no-ssh", false, "Open no inbound ports and connect through SSM Session Manager instead of SSH")
+	deployCmd.PersistentFlags().BoolVar(&resources.PlacementGroup, "placement-group", false, "Launch into a cluster placement group")
+	deployCmd.PersistentFlags().StringVar(&resources.Architecture, "architecture", "", "Template architecture: single, head-compute or container-host (default from the domain pack)")
+	deployCmd.PersistentFlags().IntVar(&resources.ComputeNodes, "compute-nodes", 0, "Number of compute nodes for head-compute (default 2)")
+	deployCmd.PersistentFlags().StringVar(&resources.ComputeInstanceType, "compute-instance", "", "Compute node instance type for head-compute (default --instance)")
+	deployCmd.PersistentFlags().BoolVar(&resources.GPU, "gpu", false, "Install the NVIDIA container runtime on a container-host")
+	deployCmd.PersistentFlags().StringVar(&resources.VPCID, "vpc", "", "VPC for architectures with a shared file system and for compliance profiles")
+	deployCmd.PersistentFlags().StringVar(&resources.SubnetID, "subnet", "", "Subnet for architectures with a shared file system and for compliance profiles")
+	deployCmd.PersistentFlags().StringVar(&resources.Compliance, "compliance", "", "Compliance profile: none, hipaa or cui/nist-800-171 (default from the domain pack)")
+	deployCmd.PersistentFlags().BoolVar(&resources.ElasticIP, "elastic-ip", false, "Give the instance an Elastic IP address that survives stopping and starting it")
+	deployCmd.PersistentFlags().BoolVar(&resources.RetainElasticIP, "retain-eip", false, "Keep the Elastic IP address when the stack is deleted")
+	deployCmd.PersistentFlags().StringVar(&resources.DNSName, "dns-name", "", "Route 53 A record to point at the Elastic IP address, e.g. jupyter.lab.example.com")
+	deployCmd.PersistentFlags().StringVar(&resources.HostedZoneID, "hosted-zone-id", "", "Route 53 hosted zone for --dns-name")
+	deployCmd.PersistentFlags().StringVar(&resources.CapacityReservationID, "capacity-reservation-id", "", "Launch instances into this On-Demand Capacity Reservation (cr-...)")
+	deployCmd.PersistentFlags().StringVar(&resources.CapacityReservationGroup, "capacity-reservation-group", "", "Launch instances into any capacity reservation in this resource group (ARN)")
+	deployCmd.PersistentFlags().BoolVar(&resources.FollowReplacement, "follow-replacement", false, "Deploy the domain that replaces a deprecated --domain instead")
+	deployCmd.PersistentFlags().BoolVar(&resources.PreferARM, "prefer-arm", false, "Pick Graviton (arm64) equivalents of the domain's recommended instance types; --instance still wins")
+	deployCmd.PersistentFlags().IntVar(&resources.VolumeSizeGB, "volume-size", 0, "Root volume size in GB (default from the domain recommendation)")
+	deployCmd.PersistentFlags().IntVar(&resources.IdleStopMinutes, "auto-shutdown", 0, "Stop instances after this many minutes of idle CPU (0 disables)")
+	deployCmd.PersistentFlags().Float64Var(&resources.MonthlyBudget, "budget", 0, "Refuse to deploy when the estimated monthly cost exceeds this many USD")
+	deployCmd.PersistentFlags().StringArrayVar(&envFlags.Tags, "tag", nil, "Stack tag as key=value (repeatable)")
+	deployCmd.PersistentFlags().StringVar(&envFlags.Name, "env", "", "Deploy a named environment from the wizard file")
+	deployCmd.PersistentFlags().StringVar(&envFlags.File, "file", config.DefaultWizardFile, "Wizard file defining deployment environments")
+	deployCmd.PersistentFlags().BoolVar(&envFlags.ShowConfig, "show-config", false, "Print the effective deployment configuration and exit")
+	deployCmd.Flags().BoolVarP(&yes, "yes", "y", false, "Deploy even when the domain has only been deployed to other regions")
 
 	// Add subcommands
 	deployCmd.AddCommand(
-		createDeployCommand(&configRoot, &stackName, &domainName, &instanceType, &dryRun, &timeout),
+		createDeployCommand(&configRoot, &stackName, &domainName, &instanceType, &dryRun, &skipQuotaCheck, &validateAfter, &timeout, &resources, &envFlags),
 		createStatusCommand(&configRoot, &stackName),
 		createDeleteCommand(&configRoot, &stackName),
-		createListCommand(&configRoot),
-		createValidateCommand(&configRoot, &domainName),
+		createListCommand(&domainName),
+		createValidateCommand(&configRoot, &domainName, &envFlags),
+		createExportTemplateCommand(&configRoot, &domainName, &instanceType, &resources),
+		createDiffCommand(&configRoot, &stackName, &domainName, &instanceType, &resources, &envFlags),
+		createSSHConfigCommand(&stackName),
+		createSSHCommand(&stackName),
+		createVerifyCommand(&configRoot, &stackName, &domainName),
+		createSnapshotCommand(&stackName),
+		createRestoreCommand(&stackName),
+		createGCCommand(),
+		createHistoryCommand(&domainName),
+		createStateCommand(&stackName),
+		createStackSetCommand(&configRoot, &stackName, &domainName, &instanceType, &resources, &envFlags),
+		createCapacityCommand(&instanceType),
 	)
 
 	return deployCmd
 }
 
-func runInteractiveDeploy(cmd *cobra.Command, configRoot, stackName, domainName, instanceType string, dryRun bool, timeout time.Duration) {
+// prepareDeploy merges the wizard file environment with the command line and
+// reports whether to go on with the deployment
+func prepareDeploy(cmd *cobra.Command, envFlags environmentFlags, settings deploySettings) bool {
+	env, err := resolveEnvironment(cmd, envFlags, settings)
+	if err != nil {
+		log.Fatalf("Failed to resolve deployment configuration: %v", err)
+	}
+	if envFlags.ShowConfig {
+		if err := printEffectiveConfig(envFlags.Name, env); err != nil {
+			log.Fatalf("Failed to print configuration: %v", err)
+		}
+		return false
+	}
+	return true
+}
+
+func runInteractiveDeploy(region, configRoot, stackName, domainName, instanceType string, dryRun, skipQuotaCheck, validateAfter, yes bool, timeout time.Duration, resources resourceFlags) {
 	ctx := context.Background()
 
 	// Find config root if not specified
@@ -68,11 +158,9 @@ func runInteractiveDeploy(cmd *cobra.Command, configRoot, stackName, domainName,
 		configRoot = findConfigRoot()
 	}
 
-	region, _ := cmd.Flags().GetString("region")
-
 	fmt.Printf("🚀 AWS Research Wizard - Infrastructure Deployment\n")
 	fmt.Printf("Config Root: %s\n", configRoot)
-	fmt.Printf("AWS Region: %s\n\n", region)
+	fmt.Printf("AWS Region: %s\n\n", aws.DescribeRegion(region))
 
 	// Initialize AWS client
 	awsClient, err := aws.NewClient(ctx, region)
@@ -89,7 +177,7 @@ func runInteractiveDeploy(cmd *cobra.Command, configRoot, stackName, domainName,
 
 	// Load domain configuration if specified
 	if domainName != "" {
-		if err := deployDomain(ctx, awsClient, configRoot, stackName, domainName, instanceType, dryRun, timeout); err != nil {
+		if err := deployDomain(ctx, awsClient, configRoot, stackName, domainName, instanceType, dryRun, skipQuotaCheck, validateAfter, yes, timeout, resources); err != nil {
 			log.Fatalf("Deployment failed: %v", err)
 		}
 	} else {
@@ -100,7 +188,7 @@ func runInteractiveDeploy(cmd *cobra.Command, configRoot, stackName, domainName,
 	}
 }
 
-func deployDomain(ctx context.Context, awsClient *aws.Client, configRoot, stackName, domainName, instanceType string, dryRun bool, timeout time.Duration) error {
+func deployDomain(ctx context.Context, awsClient *aws.Client, configRoot, stackName, domainName, instanceType string, dryRun, skipQuotaCheck, validateAfter, yes bool, timeout time.Duration, resources resourceFlags) error {
 	// Load domain configuration
 	loader := config.NewConfigLoader(configRoot)
 	domains, err := loader.LoadAllDomains()
@@ -108,29 +196,51 @@ func deployDomain(ctx context.Context, awsClient *aws.Client, configRoot, stackN
 		return fmt.Errorf("failed to load domains: %w", err)
 	}
 
-	domain, exists := domains[domainName]
-	if !exists {
-		return fmt.Errorf("domain '%s' not found", domainName)
+	requestedDomain := domainName
+	domainName, domain, err := resolveDomain(os.Stdout, domains, domainName, resources.FollowReplacement, time.Now())
+	if err != nil {
+		return err
+	}
+	if domainName == requestedDomain {
+		requestedDomain = ""
 	}
 
 	fmt.Printf("📋 Deploying Domain: %s\n", domain.Name)
 	fmt.Printf("Description: %s\n", domain.Description)
+	if awsClient.AssumedRole != "" {
+		fmt.Printf("Assumed Role: %s\n", awsClient.AssumedRole)
+	}
 
 	// Select instance type
 	selectedInstance := instanceType
 	if selectedInstance == "" {
-		// Use the first recommended instance if not specified
-		for _, rec := range domain.AWSInstanceRecommendations {
-			selectedInstance = rec.InstanceType
-			break
-		}
+		selectedInstance = recommendedInstanceType(domain, resources.PreferARM)
 	}
 
 	if selectedInstance == "" {
 		return fmt.Errorf("no instance type specified or available in domain recommendations")
 	}
 
-	fmt.Printf("Instance Type: %s\n", selectedInstance)
+	if instanceType == "" && resources.PreferARM {
+		fmt.Printf("Instance Type: %s (%s, --prefer-arm)\n", selectedInstance, aws.InstanceArchitecture(selectedInstance))
+	} else {
+		fmt.Printf("Instance Type: %s\n", selectedInstance)
+	}
+
+	arch, opts, err := templateOptions(domain, selectedInstance, resources)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Architecture: %s (%s)\n", arch, arch.Description())
+	if compliance := opts.Compliance.Settings(); compliance.Enforced() {
+		fmt.Printf("Compliance: %s (%s)\n", opts.Compliance, compliance.Framework)
+	}
+
+	// Check network parameters before any AWS calls
+	architectureParameters, err := resources.stackParameters(arch, opts)
+	if err != nil {
+		return err
+	}
 
 	// Generate stack name if not provided
 	if stackName == "" {
@@ -139,13 +249,44 @@ func deployDomain(ctx context.Context, awsClient *aws.Client, configRoot, stackN
 
 	fmt.Printf("Stack Name: %s\n\n", stackName)
 
+	if err := checkDeploymentRegion(domainName, awsClient.Region, yes, dryRun); err != nil {
+		return err
+	}
+
+	// Fail fast rather than minutes into stack creation with VcpuLimitExceeded
+	if !skipQuotaCheck {
+		if err := checkVCPUQuota(ctx, awsClient, selectedInstance); err != nil {
+			return err
+		}
+	}
+
+	// A reservation that cannot take the instances fails the launch only after
+	// the rest of the stack has been created
+	if opts.CapacityReservationID != "" {
+		zone, err := checkCapacityReservation(ctx, awsClient, arch, opts, resources.SubnetID)
+		if err != nil {
+			return err
+		}
+		if resources.SubnetID == "" {
+			resources.AvailabilityZone = zone
+		}
+	}
+
+	if err := checkBudget(awsClient.Region, arch, opts, resources.MonthlyBudget); err != nil {
+		return err
+	}
+
 	if dryRun {
 		fmt.Printf("🔍 DRY RUN - Deployment plan:\n")
 		fmt.Printf("  1. Create CloudFormation stack: %s\n", stackName)
-		fmt.Printf("  2. Launch EC2 instance: %s\n", selectedInstance)
+		fmt.Printf("  2. Launch %s architecture with instance type %s\n", arch, selectedInstance)
 		fmt.Printf("  3. Configure security groups\n")
 		fmt.Printf("  4. Set up monitoring and alarms\n")
 		fmt.Printf("  5. Configure cost tracking\n")
+		if validateAfter && len(domain.Validation) > 0 {
+			fmt.Printf("  6. Run %d validation check(s) on the instance\n", len(domain.Validation))
+		}
+		printElasticIPCost(opts)
 		fmt.Printf("\nTo execute, run without --dry-run flag\n")
 		return nil
 	}
@@ -154,23 +295,27 @@ func deployDomain(ctx context.Context, awsClient *aws.Client, configRoot, stackN
 	infraManager := aws.NewInfrastructureManager(awsClient)
 
 	// Generate CloudFormation template
-	template, err := generateCloudFormationTemplate(domain, selectedInstance)
+	template, err := generateCloudFormationTemplate(domain, selectedInstance, resources)
 	if err != nil {
 		return fmt.Errorf("failed to generate CloudFormation template: %w", err)
 	}
 
-	// Create stack parameters
-	parameters := map[string]string{
-		"InstanceType": selectedInstance,
-		"DomainName":   domainName,
-		"KeyName":      "", // User should specify key pair
+	parameters := resources.deployParameters(domainName, selectedInstance, architectureParameters)
+
+	var deployedBy string
+	if identity, err := awsClient.CallerIdentity(ctx); err == nil {
+		deployedBy = identity.ARN
+	}
+	if err := reserveStackName(stackName, awsClient.Region, deployedBy); err != nil {
+		return err
 	}
 
 	fmt.Printf("🏗️ Creating CloudFormation stack...\n")
 
 	// Create the stack
-	stackInfo, err := infraManager.CreateStack(ctx, stackName, template, parameters)
+	stackInfo, err := infraManager.CreateStackWithTags(ctx, stackName, template, parameters, resources.Tags)
 	if err != nil {
+		releaseStackName(stackName, awsClient.Region)
 		return fmt.Errorf("failed to create stack: %w", err)
 	}
 
@@ -184,6 +329,19 @@ func deployDomain(ctx context.Context, awsClient *aws.Client, configRoot, stackN
 	}
 
 	fmt.Printf("🎉 Deployment completed successfully!\n\n")
+
+	deployment := state.Deployment{
+		StackName:       finalStackInfo.StackName,
+		StackID:         finalStackInfo.StackID,
+		Domain:          domainName,
+		RequestedDomain: requestedDomain,
+		Region:          awsClient.Region,
+		InstanceType:    selectedInstance,
+		CreatedAt:       finalStackInfo.CreatedTime,
+		DeployedBy:      deployedBy,
+		AssumedRole:     awsClient.AssumedRole,
+	}
+	recordDeployment(deployment)
 	fmt.Printf("Stack Details:\n")
 	fmt.Printf("  Name: %s\n", finalStackInfo.StackName)
 	fmt.Printf("  Status: %s\n", finalStackInfo.Status)
@@ -195,130 +353,116 @@ func deployDomain(ctx context.Context, awsClient *aws.Client, configRoot, stackN
 			fmt.Printf("  %s: %s\n", key, value)
 		}
 	}
+	printElasticIPCost(opts)
+
+	scheduled := printSchedule(ctx, awsClient, stackName)
+
+	if validateAfter {
+		fmt.Println()
+		if err := verifyDeployment(ctx, awsClient, finalStackInfo, domain, agentRegistrationWait); err != nil {
+			return fmt.Errorf("stack %s deployed but validation failed: %w", stackName, err)
+		}
+	}
 
 	fmt.Printf("\n📊 Next Steps:\n")
 	fmt.Printf("  1. Monitor with: aws-research-wizard monitor --stack %s\n", stackName)
 	fmt.Printf("  2. Check costs: aws-research-wizard deploy status --stack %s\n", stackName)
-	fmt.Printf("  3. SSH to instance using outputs above\n")
+	if resources.NoSSH {
+		fmt.Printf("  3. Connect through Session Manager: aws-research-wizard deploy ssh --stack %s\n", stackName)
+	} else {
+		fmt.Printf("  3. Configure SSH: aws-research-wizard deploy ssh-config --stack %s\n", stackName)
+	}
+	if !scheduled {
+		fmt.Printf("  4. Stop outside office hours: aws-research-wizard monitor schedule set --stack %s --start 08:00 --stop 18:00\n", stackName)
+	}
 
 	return nil
 }
 
-func generateCloudFormationTemplate(domain *config.DomainPack, instanceType string) (string, error) {
-	// This is a simplified template - in production, you'd have more sophisticated templates
-	template := fmt.Sprintf(`{
-  "AWSTemplateFormatVersion": "2010-09-09",
-  "Description": "AWS Research Wizard - %s Environment",
-  "Parameters": {
-    "InstanceType": {
-      "Type": "String",
-      "Default": "%s",
-      "Description": "EC2 instance type for the research environment"
-    },
-    "DomainName": {
-      "Type": "String",
-      "Default": "%s",
-      "Description": "Research domain name"
-    },
-    "KeyName": {
-      "Type": "AWS::EC2::KeyPair::KeyName",
-      "Description": "EC2 Key Pair for SSH access"
-    }
-  },
-  "Resources": {
-    "ResearchSecurityGroup": {
-      "Type": "AWS::EC2::SecurityGroup",
-      "Properties": {
-        "GroupDescription": "Security group for research environment",
-        "SecurityGroupIngress": [
-          {
-            "IpProtocol": "tcp",
-            "FromPort": 22,
-            "ToPort": 22,
-            "CidrIp": "0.0.0.0/0"
-          },
-          {
-            "IpProtocol": "tcp",
-            "FromPort": 8888,
-            "ToPort": 8888,
-            "CidrIp": "0.0.0.0/0"
-          }
-        ],
-        "Tags": [
-          {
-            "Key": "Name",
-            "Value": "research-wizard-sg"
-          },
-          {
-            "Key": "Domain",
-            "Value": {"Ref": "DomainName"}
-          }
-        ]
-      }
-    },
-    "ResearchInstance": {
-      "Type": "AWS::EC2::Instance",
-      "Properties": {
-        "InstanceType": {"Ref": "InstanceType"},
-        "ImageId": "ami-0c02fb55956c7d316",
-        "KeyName": {"Ref": "KeyName"},
-        "SecurityGroupIds": [{"Ref": "ResearchSecurityGroup"}],
-        "UserData": {
-          "Fn::Base64": {
-            "Fn::Sub": "#!/bin/bash\nyum update -y\nyum install -y docker git\nservice docker start\necho 'Research environment setup complete' > /tmp/setup.log\n"
-          }
-        },
-        "Tags": [
-          {
-            "Key": "Name",
-            "Value": "research-wizard-instance"
-          },
-          {
-            "Key": "Domain",
-            "Value": {"Ref": "DomainName"}
-          },
-          {
-            "Key": "CreatedBy",
-            "Value": "AWS-Research-Wizard"
-          }
-        ]
-      }
-    }
-  },
-  "Outputs": {
-    "InstanceId": {
-      "Description": "Instance ID of the research environment",
-      "Value": {"Ref": "ResearchInstance"}
-    },
-    "PublicIP": {
-      "Description": "Public IP address of the research environment",
-      "Value": {"Fn::GetAtt": ["ResearchInstance", "PublicIp"]}
-    },
-    "PrivateIP": {
-      "Description": "Private IP address of the research environment",
-      "Value": {"Fn::GetAtt": ["ResearchInstance", "PrivateIp"]}
-    },
-    "SecurityGroupId": {
-      "Description": "Security Group ID",
-      "Value": {"Ref": "ResearchSecurityGroup"}
-    },
-    "SSHCommand": {
-      "Description": "SSH command to connect to the instance",
-      "Value": {"Fn::Sub": "ssh -i ~/.ssh/${KeyName}.pem ec2-user@${ResearchInstance.PublicIp}"}
-    }
-  }
-}`, domain.Name, instanceType, domain.Name)
-
-	return template, nil
+// printElasticIPCost notes that an Elastic IP address is billed while the
+// instance is stopped, and how to release one kept after the stack is deleted
+func printElasticIPCost(opts templates.Options) {
+	if !opts.ElasticIP {
+		return
+	}
+	fmt.Printf("\n💡 The Elastic IP address costs $%.3f/hour (~$%.2f/month), including while the instance is stopped\n",
+		aws.ElasticIPHourlyCost, aws.ElasticIPMonthlyCost)
+	if opts.RetainElasticIP {
+		fmt.Printf("   It is kept when the stack is deleted; release it with: aws ec2 release-address --allocation-id <id>\n")
+	}
 }
 
-func createDeployCommand(configRoot, stackName, domainName, instanceType *string, dryRun *bool, timeout *time.Duration) *cobra.Command {
-	return &cobra.Command{
+// deployParameters returns the stack parameters for a domain deployment.
+// Templates without SSH have no KeyName parameter.
+func (f resourceFlags) deployParameters(domainName, instanceType string, architectureParameters map[string]string) map[string]string {
+	parameters := map[string]string{
+		"InstanceType": instanceType,
+		"DomainName":   domainName,
+	}
+	if !f.NoSSH {
+		parameters["KeyName"] = "" // User should specify key pair
+	}
+	for key, value := range architectureParameters {
+		parameters[key] = value
+	}
+	return parameters
+}
+
+// recommendedInstanceType returns the first instance type the domain recommends,
+// taking recommendations in name order so the choice is stable. With preferARM
+// an arm64 recommendation comes first, and otherwise the Graviton equivalent of
+// an x86_64 one is used.
+func recommendedInstanceType(domain *config.DomainPack, preferARM bool) string {
+	keys := make([]string, 0, len(domain.AWSInstanceRecommendations))
+	for key := range domain.AWSInstanceRecommendations {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	if preferARM {
+		for _, key := range keys {
+			rec := domain.AWSInstanceRecommendations[key]
+			if rec.InstanceType != "" && recommendationArchitecture(rec) == aws.ArchitectureARM64 {
+				return rec.InstanceType
+			}
+		}
+	}
+
+	for _, key := range keys {
+		rec := domain.AWSInstanceRecommendations[key]
+		if rec.InstanceType != "" {
+			if preferARM {
+				return aws.PreferGraviton(rec.InstanceType)
+			}
+			return rec.InstanceType
+		}
+	}
+	return ""
+}
+
+// recommendationArchitecture returns the CPU architecture a recommendation
+// declares, or its instance type's
+func recommendationArchitecture(rec config.InstanceRecommendation) string {
+	if rec.Architecture != "" {
+		return rec.Architecture
+	}
+	return aws.InstanceArchitecture(rec.InstanceType)
+}
+
+func createDeployCommand(configRoot, stackName, domainName, instanceType *string, dryRun, skipQuotaCheck, validateAfter *bool, timeout *time.Duration, resources *resourceFlags, envFlags *environmentFlags) *cobra.Command {
+	var yes bool
+
+	cmd := &cobra.Command{
 		Use:   "start",
 		Short: "Deploy a research environment",
 		Run: func(cmd *cobra.Command, args []string) {
+			region, _ := cmd.Flags().GetString("region")
+			settings := deploySettings{Region: &region, DomainName: domainName, InstanceType: instanceType, StackName: stackName, Resources: resources}
+			if !prepareDeploy(cmd, *envFlags, settings) {
+				return
+			}
 			if *domainName == "" {
-				log.Fatal("Domain name is required. Use --domain flag.")
+				log.Fatal("Domain name is required. Use --domain flag or --env.")
 			}
 
 			ctx := context.Background()
@@ -326,17 +470,20 @@ func createDeployCommand(configRoot, stackName, domainName, instanceType *string
 				*configRoot = findConfigRoot()
 			}
 
-			region, _ := cmd.Flags().GetString("region")
 			awsClient, err := aws.NewClient(ctx, region)
 			if err != nil {
 				log.Fatalf("Failed to initialize AWS client: %v", err)
 			}
 
-			if err := deployDomain(ctx, awsClient, *configRoot, *stackName, *domainName, *instanceType, *dryRun, *timeout); err != nil {
+			if err := deployDomain(ctx, awsClient, *configRoot, *stackName, *domainName, *instanceType, *dryRun, *skipQuotaCheck, *validateAfter, yes, *timeout, *resources); err != nil {
 				log.Fatalf("Deployment failed: %v", err)
 			}
 		},
 	}
+
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "Deploy even when the domain has only been deployed to other regions")
+
+	return cmd
 }
 
 func createStatusCommand(configRoot, stackName *string) *cobra.Command {
@@ -370,103 +517,47 @@ func createStatusCommand(configRoot, stackName *string) *cobra.Command {
 				fmt.Printf("Updated: %s\n", stackInfo.UpdatedTime.Format(time.RFC3339))
 			}
 
+			fmt.Printf("Connectivity: %s\n", connectivityDescription(stackInfo))
+
 			if len(stackInfo.Outputs) > 0 {
 				fmt.Printf("\nOutputs:\n")
 				for key, value := range stackInfo.Outputs {
 					fmt.Printf("  %s: %s\n", key, value)
 				}
 			}
+
+			printSchedule(ctx, awsClient, *stackName)
 		},
 	}
 }
 
-func createDeleteCommand(configRoot, stackName *string) *cobra.Command {
-	return &cobra.Command{
-		Use:   "delete",
-		Short: "Delete a research environment",
-		Run: func(cmd *cobra.Command, args []string) {
-			if *stackName == "" {
-				log.Fatal("Stack name is required. Use --stack flag.")
-			}
-
-			ctx := context.Background()
-			region, _ := cmd.Flags().GetString("region")
-			awsClient, err := aws.NewClient(ctx, region)
-			if err != nil {
-				log.Fatalf("Failed to initialize AWS client: %v", err)
-			}
-
-			infraManager := aws.NewInfrastructureManager(awsClient)
-
-			fmt.Printf("⚠️  Deleting stack: %s\n", *stackName)
-			fmt.Printf("This action cannot be undone. Continue? (y/N): ")
-
-			var response string
-			fmt.Scanln(&response)
-
-			if response != "y" && response != "Y" {
-				fmt.Println("Deletion cancelled.")
-				return
-			}
-
-			if err := infraManager.DeleteStack(ctx, *stackName); err != nil {
-				log.Fatalf("Failed to delete stack: %v", err)
-			}
-
-			fmt.Printf("🗑️  Stack deletion initiated. Monitor progress with: aws-research-wizard deploy status --stack %s\n", *stackName)
-		},
+// connectivityDescription says how the stack's instance is reached
+func connectivityDescription(stackInfo *aws.StackInfo) string {
+	if stackInfo.Connectivity() == aws.ConnectivitySSM {
+		return "SSM Session Manager (no inbound ports)"
 	}
+	return "SSH"
 }
 
-func createListCommand(configRoot *string) *cobra.Command {
-	return &cobra.Command{
-		Use:   "list",
-		Short: "List deployed research environments",
-		Run: func(cmd *cobra.Command, args []string) {
-			ctx := context.Background()
-			region, _ := cmd.Flags().GetString("region")
-			awsClient, err := aws.NewClient(ctx, region)
-			if err != nil {
-				log.Fatalf("Failed to initialize AWS client: %v", err)
-			}
-
-			infraManager := aws.NewInfrastructureManager(awsClient)
-
-			// List instances with research wizard tags
-			filters := map[string][]string{
-				"tag:CreatedBy":       {"AWS-Research-Wizard"},
-				"instance-state-name": {"running", "pending", "stopping", "stopped"},
-			}
-
-			instances, err := infraManager.ListInstances(ctx, filters)
-			if err != nil {
-				log.Fatalf("Failed to list instances: %v", err)
-			}
-
-			fmt.Printf("🖥️  Research Environments (%d total):\n\n", len(instances))
-
-			for _, instance := range instances {
-				domain := instance.Tags["Domain"]
-				if domain == "" {
-					domain = "Unknown"
-				}
-
-				fmt.Printf("Instance: %s\n", instance.InstanceID)
-				fmt.Printf("  Domain: %s\n", domain)
-				fmt.Printf("  Type: %s\n", instance.InstanceType)
-				fmt.Printf("  State: %s\n", instance.State)
-				fmt.Printf("  Public IP: %s\n", instance.PublicIP)
-				fmt.Printf("  Launch Time: %s\n", instance.LaunchTime.Format(time.RFC3339))
-				fmt.Printf("\n")
-			}
-		},
+// printSchedule shows a stack's office-hours schedule and reports whether it
+// has one. Failing to look the schedule up is not worth failing the command.
+func printSchedule(ctx context.Context, awsClient *aws.Client, stackName string) bool {
+	schedule, err := aws.NewScheduleManager(awsClient).Get(ctx, stackName)
+	if err != nil || schedule == nil {
+		return false
 	}
+	fmt.Printf("\n⏰ Schedule: start %s, stop %s, %s (%s)\n", schedule.Start, schedule.Stop, schedule.DaysDescription(), schedule.Timezone)
+	fmt.Printf("  Instances tagged '%s' are not stopped\n", aws.ScheduleSkipTag)
+	return true
 }
 
-func createValidateCommand(configRoot, domainName *string) *cobra.Command {
+func createValidateCommand(configRoot, domainName *string, envFlags *environmentFlags) *cobra.Command {
 	return &cobra.Command{
 		Use:   "validate",
 		Short: "Validate deployment configuration",
+		Long: `Validate AWS credentials, the region and a domain, or with --file check
+every environment in a wizard file against the domain packs and template
+library without calling AWS.`,
 		Run: func(cmd *cobra.Command, args []string) {
 			ctx := context.Background()
 
@@ -474,6 +565,11 @@ func createValidateCommand(configRoot, domainName *string) *cobra.Command {
 				*configRoot = findConfigRoot()
 			}
 
+			if cmd.Flags().Changed("file") {
+				validateWizardFileCommand(*configRoot, envFlags.File)
+				return
+			}
+
 			region, _ := cmd.Flags().GetString("region")
 			fmt.Printf("🔍 Validating configuration...\n\n")
 
@@ -517,6 +613,25 @@ func createValidateCommand(configRoot, domainName *string) *cobra.Command {
 	}
 }
 
+// validateWizardFileCommand checks a wizard file offline, exiting 1 when it is invalid
+func validateWizardFileCommand(configRoot, path string) {
+	fmt.Printf("🔍 Validating %s...\n\n", path)
+
+	file, err := config.LoadWizardFile(path)
+	if err != nil {
+		log.Fatalf("Failed to load wizard file: %v", err)
+	}
+	domains, err := config.NewConfigLoader(configRoot).LoadAllDomains()
+	if err != nil {
+		log.Fatalf("Failed to load domains: %v", err)
+	}
+
+	if !printWizardFileProblems(file, validateWizardFile(file, domains)) {
+		os.Exit(1)
+	}
+	fmt.Printf("\n🎉 All environments valid!\n")
+}
+
 func findConfigRoot() string {
 	// Look for configs directory in current directory and parent directories
 	currentDir, err := os.Getwd()
@@ -540,3 +655,101 @@ func findConfigRoot() string {
 	log.Fatal("Could not find configs directory. Please specify with --config flag.")
 	return ""
 }
+
+// checkVCPUQuota verifies the account has vCPU quota for one more instance
+func checkVCPUQuota(ctx context.Context, awsClient *aws.Client, instanceType string) error {
+	check, err := aws.NewQuotaChecker(awsClient).CheckVCPUQuota(ctx, instanceType)
+	if err != nil {
+		var exceeded *aws.QuotaExceededError
+		if errors.As(err, &exceeded) {
+			return fmt.Errorf("vCPU quota check failed: %w", err)
+		}
+		return fmt.Errorf("vCPU quota check could not run (use --skip-quota-check to bypass): %w", err)
+	}
+
+	fmt.Printf("✅ vCPU quota: %d of %.0f in use, %d requested (%s)\n\n",
+		check.CurrentUsage, check.Limit, check.RequestedVCPUs, check.Family.QuotaCode)
+	return nil
+}
+
+// resolveDomain looks up the domain to deploy, warning on w when it is
+// deprecated. With followReplacement a deprecated domain is swapped for the
+// domain that replaces it, whose name is returned.
+func resolveDomain(w io.Writer, domains map[string]*config.DomainPack, domainName string, followReplacement bool, now time.Time) (string, *config.DomainPack, error) {
+	domain, exists := domains[domainName]
+	if !exists {
+		return "", nil, fmt.Errorf("domain '%s' not found", domainName)
+	}
+	notice := domain.DeprecationNotice(domainName, now)
+	if notice == "" {
+		return domainName, domain, nil
+	}
+	fmt.Fprintf(w, "⚠️  %s\n", notice)
+	if domain.ReplacedBy == "" {
+		fmt.Fprintln(w)
+		return domainName, domain, nil
+	}
+	if !followReplacement {
+		fmt.Fprintf(w, "💡 Pass --follow-replacement to deploy %s instead\n\n", domain.ReplacedBy)
+		return domainName, domain, nil
+	}
+
+	replacement, err := config.Replacement(domains, domainName)
+	if err != nil {
+		return "", nil, err
+	}
+	fmt.Fprintf(w, "➡️  Deploying %s in place of %s\n\n", replacement, domainName)
+	return replacement, domains[replacement], nil
+}
+
+// checkDeploymentRegion guards against deploying to the wrong region by
+// mistake: when the local history has deployments of the domain only in other
+// regions, it warns and requires --yes. A dry run only warns.
+func checkDeploymentRegion(domainName, region string, yes, dryRun bool) error {
+	store, err := state.OpenDefaultStore()
+	if err != nil {
+		return nil
+	}
+	deployments, err := store.Deployments()
+	if err != nil {
+		fmt.Printf("⚠️  Could not read deployment history: %v\n", err)
+		return nil
+	}
+
+	regions := state.DomainRegions(deployments, domainName)
+	if len(regions) == 0 || slices.Contains(regions, region) {
+		return nil
+	}
+	fmt.Printf("⚠️  %s has been deployed to %s, not %s\n", domainName, strings.Join(regions, ", "), aws.DescribeRegion(region))
+	if yes || dryRun {
+		fmt.Println()
+		return nil
+	}
+	return fmt.Errorf("refusing to deploy %s to %s; pass --region to choose another region, or --yes to deploy there anyway", domainName, region)
+}
+
+// recordDeployment adds a deployment to the local state file and the shared
+// state, warning rather than failing
+func recordDeployment(deployment state.Deployment) {
+	store, err := state.OpenDefaultStore()
+	if err == nil {
+		err = store.RecordDeployment(deployment)
+	}
+	if err != nil {
+		fmt.Printf("⚠️  Could not record deployment state: %v\n", err)
+	}
+	recordSharedDeployment(deployment)
+}
+
+// recordDeletion marks a deployment deleted in the local state file and the shared state
+func recordDeletion(stackName, region string) {
+	deletedAt := time.Now().UTC()
+	store, err := state.OpenDefaultStore()
+	if err == nil {
+		err = store.MarkDeleted(stackName, region, deletedAt)
+	}
+	if err != nil {
+		fmt.Printf("⚠️  Could not record deployment state: %v\n", err)
+	}
+	recordSharedDeletion(stackName, region, deletedAt)
+}
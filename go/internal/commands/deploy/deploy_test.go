@@ -0,0 +1,83 @@
+package deploy
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/config"
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/state"
+)
+
+func TestResolveDomain(t *testing.T) {
+	domains := map[string]*config.DomainPack{
+		"genomics":        testDomain("Genomics"),
+		"genomics-legacy": {Name: "Genomics (legacy)", Deprecated: true, ReplacedBy: "genomics", SunsetDate: "2026-12-31"},
+		"retired":         {Name: "Retired", Deprecated: true},
+		"dangling":        {Name: "Dangling", Deprecated: true, ReplacedBy: "missing"},
+	}
+	now := time.Date(2026, 10, 15, 9, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		domain     string
+		follow     bool
+		wantDomain string
+		wantOutput []string
+		wantErr    string
+	}{
+		{name: "current", domain: "genomics", wantDomain: "genomics"},
+		{
+			name: "deprecated warns", domain: "genomics-legacy", wantDomain: "genomics-legacy",
+			wantOutput: []string{
+				"⚠️  Domain genomics-legacy is deprecated and will be removed on 2026-12-31 (in 77 days); use genomics instead",
+				"Pass --follow-replacement to deploy genomics instead",
+			},
+		},
+		{
+			name: "follow replacement", domain: "genomics-legacy", follow: true, wantDomain: "genomics",
+			wantOutput: []string{"is deprecated", "Deploying genomics in place of genomics-legacy"},
+		},
+		{name: "no replacement to follow", domain: "retired", follow: true, wantDomain: "retired", wantOutput: []string{"Domain retired is deprecated\n"}},
+		{name: "missing replacement", domain: "dangling", follow: true, wantErr: "replaced by missing, which does not exist"},
+		{name: "unknown", domain: "astronomy", wantErr: "domain 'astronomy' not found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			name, domain, err := resolveDomain(&out, domains, tt.domain, tt.follow, now)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("resolveDomain() error = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolveDomain() error = %v", err)
+			}
+			if name != tt.wantDomain || domain != domains[tt.wantDomain] {
+				t.Errorf("resolveDomain() = %s, want %s", name, tt.wantDomain)
+			}
+			for _, want := range tt.wantOutput {
+				if !strings.Contains(out.String(), want) {
+					t.Errorf("output missing %q:\n%s", want, out.String())
+				}
+			}
+			if len(tt.wantOutput) == 0 && out.Len() != 0 {
+				t.Errorf("unexpected output:\n%s", out.String())
+			}
+		})
+	}
+}
+
+func TestHistoryDomain(t *testing.T) {
+	replaced := state.HistoryEntry{Deployment: state.Deployment{Domain: "genomics", RequestedDomain: "genomics-legacy"}}
+	if got := historyDomain(replaced); got != "genomics (for genomics-legacy)" {
+		t.Errorf("historyDomain() = %q", got)
+	}
+	if got := historyDomain(state.HistoryEntry{}); got != "-" {
+		t.Errorf("historyDomain() without a domain = %q", got)
+	}
+}
@@ -0,0 +1,227 @@
+package deploy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/spf13/cobra"
+
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/aws"
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/aws/templates"
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/config"
+)
+
+// destructiveStyle highlights changes that replace, delete or restart resources
+var destructiveStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("9"))
+
+// diffReport is the JSON form of a stack diff
+type diffReport struct {
+	StackName string `json:"stack_name"`
+	Region    string `json:"region"`
+	*templates.StackDiff
+}
+
+func createDiffCommand(configRoot, stackName, domainName, instanceType *string, resources *resourceFlags, envFlags *environmentFlags) *cobra.Command {
+	var jsonOutput bool
+
+	cmd := &cobra.Command{
+		Use:   "diff",
+		Short: "Show what redeploying would change in a live stack",
+		Long: `Compare the live stack's template and parameters with the ones a redeploy
+would generate now from the domain pack, wizard file and flags. Changes are
+listed per resource and property. Replacements, deletions and changes that stop
+an instance are highlighted.
+
+The domain defaults to the one the stack was deployed with.
+
+Examples:
+  # Preview a larger instance for a deployed stack
+  aws-research-wizard deploy diff --stack research-wizard-genomics --instance r6i.8xlarge
+
+  # Compare a wizard file environment with its live stack, as JSON
+  aws-research-wizard deploy diff --env prod-genomics --json`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			region, _ := cmd.Flags().GetString("region")
+			settings := deploySettings{Region: &region, DomainName: domainName, InstanceType: instanceType, StackName: stackName, Resources: resources}
+			if !prepareDeploy(cmd, *envFlags, settings) {
+				return
+			}
+
+			name := *stackName
+			if name == "" && *domainName != "" {
+				name = fmt.Sprintf("research-wizard-%s", *domainName)
+			}
+			if name == "" {
+				log.Fatal("Stack name is required. Use --stack, --domain or --env.")
+			}
+			if *configRoot == "" {
+				*configRoot = findConfigRoot()
+			}
+
+			ctx := context.Background()
+			awsClient, err := aws.NewClient(ctx, region)
+			if err != nil {
+				log.Fatalf("Failed to initialize AWS client: %v", err)
+			}
+
+			diff, err := diffStack(ctx, aws.NewInfrastructureManager(awsClient), *configRoot, name, *domainName, *instanceType, *resources)
+			if err != nil {
+				log.Fatalf("Failed to diff stack: %v", err)
+			}
+
+			if jsonOutput {
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+				if err := encoder.Encode(diffReport{StackName: name, Region: awsClient.Region, StackDiff: diff}); err != nil {
+					log.Fatalf("Failed to encode diff: %v", err)
+				}
+				return
+			}
+			printStackDiff(os.Stdout, name, diff)
+		},
+	}
+
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "Output changes as JSON")
+
+	return cmd
+}
+
+// diffStack compares a live stack with the template a redeploy would create
+func diffStack(ctx context.Context, infraManager *aws.InfrastructureManager, configRoot, stackName, domainName, instanceType string, resources resourceFlags) (*templates.StackDiff, error) {
+	live, err := infraManager.GetStackInfo(ctx, stackName)
+	if err != nil {
+		return nil, err
+	}
+	liveBody, err := infraManager.GetStackTemplate(ctx, stackName)
+	if err != nil {
+		return nil, err
+	}
+
+	if domainName == "" {
+		domainName = live.Parameters["DomainName"]
+	}
+	if domainName == "" {
+		return nil, fmt.Errorf("stack %s has no DomainName parameter; use --domain", stackName)
+	}
+
+	domains, err := config.NewConfigLoader(configRoot).LoadAllDomains()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load domains: %w", err)
+	}
+	domain, exists := domains[domainName]
+	if !exists {
+		return nil, fmt.Errorf("domain '%s' not found", domainName)
+	}
+
+	selectedInstance := instanceType
+	if selectedInstance == "" {
+		selectedInstance = recommendedInstanceType(domain, resources.PreferARM)
+	}
+
+	arch, opts, err := templateOptions(domain, selectedInstance, resources)
+	if err != nil {
+		return nil, err
+	}
+	localBody, err := generateCloudFormationTemplate(domain, selectedInstance, resources)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate CloudFormation template: %w", err)
+	}
+
+	// Keep the live network when the flags do not name one
+	architectureParameters, err := resources.stackParameters(arch, opts)
+	if err != nil {
+		architectureParameters = map[string]string{
+			"VpcId":    live.Parameters["VpcId"],
+			"SubnetId": live.Parameters["SubnetId"],
+		}
+	}
+	parameters := resources.deployParameters(domainName, selectedInstance, architectureParameters)
+	if keyName, exists := live.Parameters["KeyName"]; exists && !resources.NoSSH {
+		parameters["KeyName"] = keyName
+	}
+
+	return templates.DiffStack(liveBody, live.Parameters, localBody, parameters)
+}
+
+// printStackDiff writes the changes grouped by resource, highlighting the destructive ones
+func printStackDiff(w io.Writer, stackName string, diff *templates.StackDiff) {
+	if !diff.HasChanges() {
+		fmt.Fprintf(w, "✅ No changes: %s matches the generated template\n", stackName)
+		return
+	}
+
+	fmt.Fprintf(w, "📋 Changes for stack %s\n", stackName)
+
+	if len(diff.Parameters) > 0 {
+		fmt.Fprintf(w, "\nParameters:\n")
+		for _, change := range diff.Parameters {
+			fmt.Fprintf(w, "  ~ %s: %s → %s\n", change.Name, orDash(change.Old), orDash(change.New))
+		}
+	}
+
+	if len(diff.Resources) > 0 {
+		fmt.Fprintf(w, "\nResources:\n")
+	}
+	for _, change := range diff.Resources {
+		line := fmt.Sprintf("  %s %s (%s)", changeSymbol(change.Action), change.LogicalID, change.Type)
+		switch {
+		case change.Action == templates.ChangeReplace:
+			line = destructiveStyle.Render(line + " - REPLACE")
+		case change.Action == templates.ChangeDelete:
+			line = destructiveStyle.Render(line + " - DELETE")
+		case change.Interrupts():
+			line = destructiveStyle.Render(line + " - modify, stops and restarts")
+		}
+		fmt.Fprintln(w, line)
+
+		for _, property := range change.Properties {
+			line := fmt.Sprintf("      %s: %s → %s", property.Path, formatDiffValue(property.Old), formatDiffValue(property.New))
+			switch property.Behavior {
+			case templates.UpdateReplacement:
+				line = destructiveStyle.Render(line + "  (requires replacement)")
+			case templates.UpdateInterruption:
+				line = destructiveStyle.Render(line + "  (requires restart)")
+			}
+			fmt.Fprintln(w, line)
+		}
+	}
+
+	fmt.Fprintf(w, "\nSummary: %d to add, %d to modify, %d to replace, %d to delete\n",
+		diff.Count(templates.ChangeAdd), diff.Count(templates.ChangeModify),
+		diff.Count(templates.ChangeReplace), diff.Count(templates.ChangeDelete))
+}
+
+func changeSymbol(action templates.ChangeAction) string {
+	switch action {
+	case templates.ChangeAdd:
+		return "+"
+	case templates.ChangeDelete:
+		return "-"
+	case templates.ChangeReplace:
+		return "!"
+	default:
+		return "~"
+	}
+}
+
+// formatDiffValue renders a property value compactly, showing absent values as a dash
+func formatDiffValue(value interface{}) string {
+	if value == nil {
+		return "-"
+	}
+	body, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	const maxLength = 80
+	if len(body) > maxLength {
+		return string(body[:maxLength-3]) + "..."
+	}
+	return string(body)
+}
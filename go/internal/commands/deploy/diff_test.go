@@ -0,0 +1,45 @@
+package deploy
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/aws/templates"
+)
+
+func TestPrintStackDiff(t *testing.T) {
+	diff := &templates.StackDiff{
+		Parameters: []templates.ParameterChange{{Name: "InstanceType", Old: "r6i.4xlarge", New: "r6i.8xlarge"}},
+		Resources: []templates.ResourceChange{
+			{LogicalID: "ResearchInstanceIdleStopAlarm", Type: "AWS::CloudWatch::Alarm", Action: templates.ChangeAdd},
+			{LogicalID: "ResearchInstance", Type: "AWS::EC2::Instance", Action: templates.ChangeReplace, Properties: []templates.PropertyChange{
+				{Path: "ImageId", Old: "ami-1", New: "ami-2", Behavior: templates.UpdateReplacement},
+			}},
+			{LogicalID: "ResearchPlacementGroup", Type: "AWS::EC2::PlacementGroup", Action: templates.ChangeDelete},
+		},
+	}
+
+	var out bytes.Buffer
+	printStackDiff(&out, "research-wizard-genomics", diff)
+	text := out.String()
+
+	for _, want := range []string{
+		"~ InstanceType: r6i.4xlarge → r6i.8xlarge",
+		"+ ResearchInstanceIdleStopAlarm (AWS::CloudWatch::Alarm)",
+		"! ResearchInstance (AWS::EC2::Instance) - REPLACE",
+		`ImageId: "ami-1" → "ami-2"  (requires replacement)`,
+		"- ResearchPlacementGroup (AWS::EC2::PlacementGroup) - DELETE",
+		"1 to add, 0 to modify, 1 to replace, 1 to delete",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("output missing %q:\n%s", want, text)
+		}
+	}
+
+	out.Reset()
+	printStackDiff(&out, "research-wizard-genomics", &templates.StackDiff{})
+	if !strings.Contains(out.String(), "No changes") {
+		t.Errorf("empty diff output = %q", out.String())
+	}
+}
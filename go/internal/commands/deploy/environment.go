@@ -0,0 +1,269 @@
+package deploy
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/aws"
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/aws/templates"
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/config"
+)
+
+// environmentFlags select a wizard.yaml environment and show the merged result
+type environmentFlags struct {
+	Name       string
+	File       string
+	ShowConfig bool
+	Tags       []string
+}
+
+// deploySettings are the values an environment can set, shared by the deploy commands
+type deploySettings struct {
+	Region       *string
+	DomainName   *string
+	InstanceType *string
+	StackName    *string
+	Resources    *resourceFlags
+}
+
+// environment returns the settings as an environment, keeping only the flags
+// set on the command line so they override the wizard file
+func (s deploySettings) environment(cmd *cobra.Command, tags []string) (config.DeploymentEnvironment, error) {
+	changed := cmd.Flags().Changed
+	var env config.DeploymentEnvironment
+	if changed("region") {
+		env.Region = *s.Region
+	}
+	if changed("domain") {
+		env.Domain = *s.DomainName
+	}
+	if changed("instance") {
+		env.Instance = *s.InstanceType
+	}
+	if changed("stack") {
+		env.Stack = *s.StackName
+	}
+	if changed("architecture") {
+		env.Architecture = s.Resources.Architecture
+	}
+	if changed("volume-size") {
+		env.Storage.VolumeSizeGB = s.Resources.VolumeSizeGB
+	}
+	if changed("encrypt-volume") {
+		env.Storage.Encrypted = s.Resources.EncryptVolume
+	}
+	if changed("budget") {
+		env.MonthlyBudget = s.Resources.MonthlyBudget
+	}
+	if changed("auto-shutdown") {
+		env.AutoShutdown.IdleMinutes = s.Resources.IdleStopMinutes
+	}
+
+	parsed, err := parseTags(tags)
+	if err != nil {
+		return config.DeploymentEnvironment{}, err
+	}
+	env.Tags = parsed
+	return env, nil
+}
+
+// apply writes a merged environment back into the settings
+func (s deploySettings) apply(env config.DeploymentEnvironment) {
+	overrideSetting(s.Region, env.Region)
+	overrideSetting(s.DomainName, env.Domain)
+	overrideSetting(s.InstanceType, env.Instance)
+	overrideSetting(s.StackName, env.Stack)
+	applyEnvironmentResources(env, s.Resources)
+}
+
+// applyEnvironmentResources writes the template settings of an environment into resources
+func applyEnvironmentResources(env config.DeploymentEnvironment, resources *resourceFlags) {
+	overrideSetting(&resources.Architecture, env.Architecture)
+	resources.VolumeSizeGB = env.Storage.VolumeSizeGB
+	resources.EncryptVolume = env.Storage.Encrypted
+	resources.MonthlyBudget = env.MonthlyBudget
+	resources.IdleStopMinutes = env.AutoShutdown.IdleMinutes
+	resources.IdleCPUPercent = env.AutoShutdown.CPUPercent
+	resources.Tags = env.Tags
+}
+
+func overrideSetting(setting *string, value string) {
+	if value != "" {
+		*setting = value
+	}
+}
+
+// resolveEnvironment merges the selected wizard.yaml environment with the flags
+// set on the command line and writes the result back into the settings
+func resolveEnvironment(cmd *cobra.Command, envFlags environmentFlags, settings deploySettings) (config.DeploymentEnvironment, error) {
+	var base config.DeploymentEnvironment
+	if envFlags.Name != "" {
+		file, err := config.LoadWizardFile(envFlags.File)
+		if err != nil {
+			return base, err
+		}
+		base, err = file.Environment(envFlags.Name)
+		if err != nil {
+			return base, fmt.Errorf("%s: %w", envFlags.File, err)
+		}
+	}
+
+	overrides, err := settings.environment(cmd, envFlags.Tags)
+	if err != nil {
+		return base, err
+	}
+	merged := base.Merge(overrides)
+
+	if merged.Domain != "" {
+		if problems := merged.Problems(); len(problems) > 0 {
+			return merged, fmt.Errorf("invalid deployment configuration: %s", strings.Join(problems, "; "))
+		}
+	}
+
+	settings.apply(merged)
+	return merged, nil
+}
+
+// parseTags parses repeated --tag key=value flags
+func parseTags(values []string) (map[string]string, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+	tags := make(map[string]string, len(values))
+	for _, value := range values {
+		key, tagValue, found := strings.Cut(value, "=")
+		if !found || key == "" {
+			return nil, fmt.Errorf("invalid tag %q: expected key=value", value)
+		}
+		tags[key] = tagValue
+	}
+	return tags, nil
+}
+
+// printEffectiveConfig writes the merged environment as YAML
+func printEffectiveConfig(name string, env config.DeploymentEnvironment) error {
+	if name != "" {
+		fmt.Printf("# Effective configuration for environment %s\n", name)
+	} else {
+		fmt.Printf("# Effective configuration from flags\n")
+	}
+	encoder := yaml.NewEncoder(os.Stdout)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(env); err != nil {
+		return fmt.Errorf("failed to encode configuration: %w", err)
+	}
+	return encoder.Close()
+}
+
+// checkBudget refuses a deployment whose estimated monthly cost exceeds its budget
+func checkBudget(region string, arch templates.Architecture, opts templates.Options, budget float64) error {
+	if budget <= 0 {
+		return nil
+	}
+
+	pricing, err := aws.NewPricingCalculator(region)
+	if err != nil {
+		return fmt.Errorf("failed to check budget: %w", err)
+	}
+	estimate, err := estimateMonthlyCost(arch, opts, func(instanceType string) (float64, error) {
+		cost, err := pricing.CalculateCost(instanceType)
+		if err != nil {
+			return 0, err
+		}
+		return cost.MonthlyCost, nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to check budget: %w", err)
+	}
+
+	if estimate > budget {
+		return fmt.Errorf("estimated cost $%.2f/month exceeds the $%.2f/month budget", estimate, budget)
+	}
+	fmt.Printf("✅ Budget: ~$%.2f/month of $%.2f/month\n", estimate, budget)
+	return nil
+}
+
+// estimateMonthlyCost totals the monthly cost of the instances a template
+// launches, and of its Elastic IP address
+func estimateMonthlyCost(arch templates.Architecture, opts templates.Options, monthlyCost func(string) (float64, error)) (float64, error) {
+	total, err := monthlyCost(opts.InstanceType)
+	if err != nil {
+		return 0, err
+	}
+	if arch == templates.ArchitectureHeadCompute && opts.ComputeNodes > 0 {
+		computeType := opts.ComputeInstanceType
+		if computeType == "" {
+			computeType = opts.InstanceType
+		}
+		perNode, err := monthlyCost(computeType)
+		if err != nil {
+			return 0, err
+		}
+		total += perNode * float64(opts.ComputeNodes)
+	}
+	if opts.ElasticIP {
+		total += aws.ElasticIPMonthlyCost
+	}
+	return total, nil
+}
+
+// validateWizardFile checks every environment in a wizard file against the
+// domain packs and template library, without calling AWS
+func validateWizardFile(file *config.WizardFile, domains map[string]*config.DomainPack) map[string][]string {
+	problems := make(map[string][]string)
+	if err := file.Validate(); err != nil {
+		problems[""] = []string{err.Error()}
+		return problems
+	}
+
+	for _, name := range file.EnvironmentNames() {
+		env, _ := file.Environment(name)
+		domain, exists := domains[env.Domain]
+		if !exists {
+			problems[name] = append(problems[name], fmt.Sprintf("domain %q not found", env.Domain))
+			continue
+		}
+
+		instanceType := env.Instance
+		if instanceType == "" {
+			instanceType = recommendedInstanceType(domain, false)
+		}
+		var resources resourceFlags
+		applyEnvironmentResources(env, &resources)
+
+		arch, opts, err := templateOptions(domain, instanceType, resources)
+		if err == nil {
+			err = opts.Validate(arch)
+		}
+		if err != nil {
+			problems[name] = append(problems[name], err.Error())
+		}
+	}
+	return problems
+}
+
+// printWizardFileProblems reports validation results and returns false if any environment failed
+func printWizardFileProblems(file *config.WizardFile, problems map[string][]string) bool {
+	if fileProblems, exists := problems[""]; exists {
+		for _, problem := range fileProblems {
+			fmt.Printf("❌ %s\n", problem)
+		}
+		return false
+	}
+
+	for _, name := range file.EnvironmentNames() {
+		if len(problems[name]) == 0 {
+			fmt.Printf("✅ Environment valid: %s\n", name)
+			continue
+		}
+		fmt.Printf("❌ Environment %s:\n", name)
+		for _, problem := range problems[name] {
+			fmt.Printf("   - %s\n", problem)
+		}
+	}
+	return len(problems) == 0
+}
@@ -0,0 +1,138 @@
+package deploy
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/aws"
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/config"
+)
+
+const testEnvironmentFile = `
+version: 1
+defaults:
+  region: us-west-2
+  tags:
+    Project: dna
+environments:
+  prod-genomics:
+    domain: genomics
+    instance: r6i.4xlarge
+    storage:
+      volume_size_gb: 500
+    auto_shutdown:
+      idle_minutes: 60
+    monthly_budget: 1500
+`
+
+func TestResolveEnvironmentFlagsOverrideFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), config.DefaultWizardFile)
+	if err := os.WriteFile(path, []byte(testEnvironmentFile), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := NewDeployCommand()
+	cmd.PersistentFlags().String("region", "us-east-1", "")
+	args := []string{"--env", "prod-genomics", "--file", path, "--instance", "r6i.8xlarge", "--tag", "Stage=test", "--volume-size", "750"}
+	if err := cmd.ParseFlags(args); err != nil {
+		t.Fatalf("ParseFlags() error = %v", err)
+	}
+
+	var envFlags environmentFlags
+	envFlags.Name, _ = cmd.Flags().GetString("env")
+	envFlags.File, _ = cmd.Flags().GetString("file")
+	envFlags.Tags, _ = cmd.Flags().GetStringArray("tag")
+
+	region, _ := cmd.Flags().GetString("region")
+	domainName, _ := cmd.Flags().GetString("domain")
+	instanceType, _ := cmd.Flags().GetString("instance")
+	stackName := ""
+	resources := resourceFlags{}
+	resources.VolumeSizeGB, _ = cmd.Flags().GetInt("volume-size")
+
+	settings := deploySettings{Region: &region, DomainName: &domainName, InstanceType: &instanceType, StackName: &stackName, Resources: &resources}
+	env, err := resolveEnvironment(cmd, envFlags, settings)
+	if err != nil {
+		t.Fatalf("resolveEnvironment() error = %v", err)
+	}
+
+	if region != "us-west-2" {
+		t.Errorf("region = %s, want the file value over the unset flag default", region)
+	}
+	if domainName != "genomics" || instanceType != "r6i.8xlarge" {
+		t.Errorf("domain = %s, instance = %s", domainName, instanceType)
+	}
+	if resources.VolumeSizeGB != 750 || resources.IdleStopMinutes != 60 || resources.MonthlyBudget != 1500 {
+		t.Errorf("resources = %+v", resources)
+	}
+	if env.Tags["Project"] != "dna" || env.Tags["Stage"] != "test" || resources.Tags["Stage"] != "test" {
+		t.Errorf("tags = %v", env.Tags)
+	}
+}
+
+func TestParseTags(t *testing.T) {
+	tags, err := parseTags([]string{"Project=dna", "Note=a=b", "Empty="})
+	if err != nil {
+		t.Fatalf("parseTags() error = %v", err)
+	}
+	if tags["Project"] != "dna" || tags["Note"] != "a=b" || tags["Empty"] != "" {
+		t.Errorf("tags = %v", tags)
+	}
+
+	for _, bad := range []string{"Project", "=dna"} {
+		if _, err := parseTags([]string{bad}); err == nil {
+			t.Errorf("parseTags(%q) should fail", bad)
+		}
+	}
+}
+
+func TestValidateWizardFile(t *testing.T) {
+	file := &config.WizardFile{
+		Version: 1,
+		Environments: map[string]config.DeploymentEnvironment{
+			"ok":        {Domain: "genomics"},
+			"no-domain": {Domain: "astrology"},
+			"tiny-disk": {Domain: "genomics", Storage: config.StorageSettings{VolumeSizeGB: 2}},
+		},
+	}
+	domains := map[string]*config.DomainPack{"genomics": testDomain("genomics")}
+
+	problems := validateWizardFile(file, domains)
+	if len(problems["ok"]) != 0 {
+		t.Errorf("ok: unexpected problems %v", problems["ok"])
+	}
+	if len(problems["no-domain"]) != 1 || !strings.Contains(problems["no-domain"][0], "not found") {
+		t.Errorf("no-domain: problems = %v", problems["no-domain"])
+	}
+	if len(problems["tiny-disk"]) != 1 || !strings.Contains(problems["tiny-disk"][0], "volume size") {
+		t.Errorf("tiny-disk: problems = %v", problems["tiny-disk"])
+	}
+}
+
+func TestEstimateMonthlyCost(t *testing.T) {
+	prices := map[string]float64{"r6i.4xlarge": 700, "c6i.large": 60}
+	monthlyCost := func(instanceType string) (float64, error) {
+		return prices[instanceType], nil
+	}
+
+	opts := newTemplateOptions(testDomain("genomics"), "r6i.4xlarge")
+	single, _ := estimateMonthlyCost("single", opts, monthlyCost)
+	if single != 700 {
+		t.Errorf("single = %.2f, want 700", single)
+	}
+
+	opts.ComputeNodes = 3
+	opts.ComputeInstanceType = "c6i.large"
+	cluster, _ := estimateMonthlyCost("head-compute", opts, monthlyCost)
+	if cluster != 880 {
+		t.Errorf("head-compute = %.2f, want 880", cluster)
+	}
+
+	opts.ElasticIP = true
+	withAddress, _ := estimateMonthlyCost("head-compute", opts, monthlyCost)
+	if withAddress != 880+aws.ElasticIPMonthlyCost {
+		t.Errorf("head-compute with an Elastic IP = %.2f, want %.2f", withAddress, 880+aws.ElasticIPMonthlyCost)
+	}
+}
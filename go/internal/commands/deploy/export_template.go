@@ -0,0 +1,96 @@
+package deploy
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/aws/templates"
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/config"
+)
+
+func createExportTemplateCommand(configRoot, domainName, instanceType *string, resources *resourceFlags) *cobra.Command {
+	var outputPath string
+	var listArchitectures bool
+
+	cmd := &cobra.Command{
+		Use:   "export-template",
+		Short: "Write the CloudFormation template for a domain deployment",
+		Long: `Render the CloudFormation template 'deploy start' would create, without
+calling AWS. The architecture comes from --architecture, then the domain pack's
+aws_integration.architecture, and defaults to single.
+
+Examples:
+  # Print the template for a domain
+  aws-research-wizard deploy export-template --domain genomics
+
+  # Write a cluster template with four compute nodes
+  aws-research-wizard deploy export-template --domain climate_modeling --architecture head-compute --compute-nodes 4 -o cluster.json
+
+  # List the available architectures
+  aws-research-wizard deploy export-template --list`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if listArchitectures {
+				printArchitectures()
+				return
+			}
+			if *domainName == "" {
+				log.Fatal("Domain name is required. Use --domain flag.")
+			}
+			if *configRoot == "" {
+				*configRoot = findConfigRoot()
+			}
+
+			domains, err := config.NewConfigLoader(*configRoot).LoadAllDomains()
+			if err != nil {
+				log.Fatalf("Failed to load domains: %v", err)
+			}
+			// The template may be written to stdout, so warnings go to stderr
+			_, domain, err := resolveDomain(os.Stderr, domains, *domainName, resources.FollowReplacement, time.Now())
+			if err != nil {
+				log.Fatalf("Failed to resolve domain: %v", err)
+			}
+
+			selectedInstance := *instanceType
+			if selectedInstance == "" {
+				selectedInstance = recommendedInstanceType(domain, resources.PreferARM)
+			}
+
+			body, err := generateCloudFormationTemplate(domain, selectedInstance, *resources)
+			if err != nil {
+				log.Fatalf("Failed to generate template: %v", err)
+			}
+
+			if outputPath == "" {
+				fmt.Println(body)
+				return
+			}
+			if err := os.WriteFile(outputPath, []byte(body+"\n"), 0644); err != nil {
+				log.Fatalf("Failed to write template: %v", err)
+			}
+			fmt.Fprintf(os.Stderr, "✅ Template written to %s\n", outputPath)
+		},
+	}
+
+	cmd.Flags().StringVarP(&outputPath, "output", "o", "", "Write the template to a file instead of stdout")
+	cmd.Flags().BoolVar(&listArchitectures, "list", false, "List the available architectures")
+
+	return cmd
+}
+
+func printArchitectures() {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ARCHITECTURE\tDESCRIPTION")
+	for _, arch := range templates.Architectures() {
+		description := arch.Description()
+		if arch.NeedsNetwork() {
+			description += " (needs --vpc and --subnet to deploy)"
+		}
+		fmt.Fprintf(w, "%s\t%s\n", arch, description)
+	}
+	w.Flush()
+}
@@ -0,0 +1,292 @@
+package deploy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/aws"
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/state"
+)
+
+func createGCCommand() *cobra.Command {
+	var apply bool
+	var olderThan string
+	var jsonOutput bool
+
+	cmd := &cobra.Command{
+		Use:   "gc",
+		Short: "Find and delete orphaned research wizard resources",
+		Long: `Find security groups, key pairs, snapshots and EFS file systems tagged
+CreatedBy=AWS-Research-Wizard that no existing stack owns. Failed or
+half-deleted stacks leave these behind, and snapshots outlive the stacks
+they were taken from.
+
+A resource is kept when its CloudFormation stack still exists (checked by stack
+ID, so a new stack reusing a name does not protect an old stack's leftovers),
+when its snapshot is recorded in the local state for an existing stack, or when
+a key pair is the KeyName of an existing stack. Orphans younger than
+--older-than, or of unknown age, are listed but not deleted.
+
+Without --apply the orphans are only listed. With --apply they are deleted after
+confirmation: file systems and their mount targets first, then security groups
+with their detached network interfaces, then snapshots and key pairs. A failed
+deletion is reported and the sweep continues.
+
+Examples:
+  # List orphans older than a week
+  aws-research-wizard deploy gc
+
+  # Delete orphans older than 30 days
+  aws-research-wizard deploy gc --older-than 30d --apply`,
+		Run: func(cmd *cobra.Command, args []string) {
+			minAge, err := parseAge(olderThan)
+			if err != nil {
+				log.Fatalf("Invalid --older-than: %v", err)
+			}
+
+			ctx := context.Background()
+			region, _ := cmd.Flags().GetString("region")
+			awsClient, err := aws.NewClient(ctx, region)
+			if err != nil {
+				log.Fatalf("Failed to initialize AWS client: %v", err)
+			}
+
+			collector := aws.NewGarbageCollector(awsClient)
+			index, err := collector.Index(ctx)
+			if err != nil {
+				log.Fatalf("Failed to list stacks: %v", err)
+			}
+			stale := indexLocalState(index, awsClient.Region)
+
+			resources, err := collector.Collect(ctx)
+			if err != nil {
+				log.Fatalf("Failed to scan for wizard resources: %v", err)
+			}
+			plan := aws.PlanGC(resources, index, time.Now().UTC(), minAge)
+
+			if jsonOutput {
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+				if err := encoder.Encode(newGCReport(awsClient.Region, plan, stale)); err != nil {
+					log.Fatalf("Failed to encode report: %v", err)
+				}
+				if !apply {
+					return
+				}
+			} else {
+				printGCPlan(awsClient.Region, plan, stale)
+			}
+
+			if !apply {
+				if len(plan.Orphans) > 0 {
+					fmt.Println("Run with --apply to delete them.")
+				}
+				return
+			}
+			if len(plan.Orphans) == 0 && len(stale) == 0 {
+				return
+			}
+
+			fmt.Fprintf(os.Stderr, "⚠️  Deleting %d orphaned resources. This action cannot be undone. Continue? (y/N): ", len(plan.Orphans))
+			var response string
+			fmt.Scanln(&response)
+			if response != "y" && response != "Y" {
+				fmt.Fprintln(os.Stderr, "Cleanup cancelled.")
+				return
+			}
+
+			for _, stackName := range stale {
+				recordDeletion(stackName, awsClient.Region)
+			}
+
+			failed := 0
+			for _, result := range collector.Delete(ctx, plan.Orphans) {
+				if result.Err != nil {
+					failed++
+					fmt.Fprintf(os.Stderr, "❌ %s %s: %v\n", result.Finding.Kind, result.Finding.ID, result.Err)
+					continue
+				}
+				fmt.Fprintf(os.Stderr, "🗑️  Deleted %s %s\n", result.Finding.Kind, result.Finding.ID)
+			}
+
+			if failed > 0 {
+				fmt.Fprintf(os.Stderr, "\n⚠️  %d of %d deletions failed\n", failed, len(plan.Orphans))
+				os.Exit(1)
+			}
+			fmt.Fprintf(os.Stderr, "\n✅ Cleanup complete, saving ~$%.2f/month\n", plan.MonthlyCost)
+		},
+	}
+
+	cmd.Flags().BoolVar(&apply, "apply", false, "Delete the orphaned resources after confirmation")
+	cmd.Flags().StringVar(&olderThan, "older-than", "7d", "Only delete orphans at least this old (e.g. 12h, 7d, 0 for all)")
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "Output the orphans as JSON")
+
+	return cmd
+}
+
+// parseAge parses a duration that may be given in days, like 7d
+func parseAge(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	if value == "" || value == "0" {
+		return 0, nil
+	}
+	if days, found := strings.CutSuffix(value, "d"); found {
+		n, err := strconv.ParseFloat(days, 64)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid number of days %q", value)
+		}
+		return time.Duration(n * 24 * float64(time.Hour)), nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, err
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("age %q is negative", value)
+	}
+	return d, nil
+}
+
+// indexLocalState adds the region's recorded snapshots to the index and returns
+// the deployments the state file lists as active whose stacks no longer exist
+func indexLocalState(index *aws.GCIndex, region string) []string {
+	store, err := state.OpenDefaultStore()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Could not open deployment state: %v\n", err)
+		return nil
+	}
+	deployments, err := store.Deployments()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Could not read deployment state: %v\n", err)
+		return nil
+	}
+
+	var stale []string
+	for _, deployment := range deployments {
+		if deployment.Region != region {
+			continue
+		}
+		for _, snapshot := range deployment.Snapshots {
+			index.AddStateSnapshot(snapshot.SnapshotID, deployment.StackName)
+		}
+		if deployment.Active() && !index.Live(deployment.StackName) {
+			stale = append(stale, deployment.StackName)
+		}
+	}
+	return stale
+}
+
+// gcReportItem is one orphan in the JSON report
+type gcReportItem struct {
+	Kind        string     `json:"kind"`
+	ID          string     `json:"id"`
+	Name        string     `json:"name,omitempty"`
+	StackName   string     `json:"stack_name,omitempty"`
+	CreatedAt   *time.Time `json:"created_at,omitempty"`
+	AgeDays     float64    `json:"age_days,omitempty"`
+	MonthlyCost float64    `json:"monthly_cost"`
+	Detail      string     `json:"detail,omitempty"`
+	Reason      string     `json:"reason"`
+}
+
+// gcReport is the JSON form of a garbage collection plan
+type gcReport struct {
+	Region      string         `json:"region"`
+	Orphans     []gcReportItem `json:"orphans"`
+	Skipped     []gcReportItem `json:"skipped"`
+	Associated  int            `json:"associated"`
+	MonthlyCost float64        `json:"monthly_cost"`
+	// StaleDeployments are active in the local state but have no stack
+	StaleDeployments []string `json:"stale_deployments,omitempty"`
+}
+
+func newGCReport(region string, plan *aws.GCPlan, stale []string) gcReport {
+	report := gcReport{
+		Region:           region,
+		Orphans:          make([]gcReportItem, 0, len(plan.Orphans)),
+		Skipped:          make([]gcReportItem, 0, len(plan.Skipped)),
+		Associated:       plan.Associated,
+		MonthlyCost:      plan.MonthlyCost,
+		StaleDeployments: stale,
+	}
+	for _, finding := range plan.Orphans {
+		report.Orphans = append(report.Orphans, newGCReportItem(finding))
+	}
+	for _, finding := range plan.Skipped {
+		report.Skipped = append(report.Skipped, newGCReportItem(finding))
+	}
+	return report
+}
+
+func newGCReportItem(finding aws.GCFinding) gcReportItem {
+	item := gcReportItem{
+		Kind:        finding.Kind,
+		ID:          finding.ID,
+		Name:        finding.Name,
+		StackName:   finding.StackName,
+		AgeDays:     finding.Age.Hours() / 24,
+		MonthlyCost: finding.MonthlyCost,
+		Detail:      finding.Detail,
+		Reason:      finding.Reason,
+	}
+	if !finding.CreatedAt.IsZero() {
+		created := finding.CreatedAt
+		item.CreatedAt = &created
+	}
+	return item
+}
+
+func printGCPlan(region string, plan *aws.GCPlan, stale []string) {
+	fmt.Printf("🧹 Orphaned research wizard resources in %s\n\n", region)
+
+	if len(plan.Orphans) == 0 {
+		fmt.Printf("No orphaned resources found (%d wizard resources belong to existing stacks)\n\n", plan.Associated)
+	} else {
+		printGCFindings(plan.Orphans)
+		fmt.Printf("%d orphaned resources costing ~$%.2f/month (%d wizard resources belong to existing stacks)\n\n",
+			len(plan.Orphans), plan.MonthlyCost, plan.Associated)
+	}
+
+	if len(plan.Skipped) > 0 {
+		fmt.Println("⏭️  Skipped:")
+		printGCFindings(plan.Skipped)
+		fmt.Println()
+	}
+
+	if len(stale) > 0 {
+		fmt.Printf("ℹ️  The local state lists deployments with no stack: %s\n", strings.Join(stale, ", "))
+		fmt.Println("   --apply marks them deleted.")
+		fmt.Println()
+	}
+}
+
+func printGCFindings(findings []aws.GCFinding) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "KIND\tID\tNAME\tSTACK\tAGE\tMONTHLY\tREASON")
+	for _, finding := range findings {
+		age := "unknown"
+		if !finding.CreatedAt.IsZero() {
+			age = formatDuration(finding.Age)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t$%.2f\t%s\n",
+			finding.Kind, finding.ID, orDash(finding.Name), orDash(finding.StackName), age, finding.MonthlyCost, finding.Reason)
+	}
+	w.Flush()
+}
+
+func orDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
@@ -0,0 +1,29 @@
+package deploy
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseAge(t *testing.T) {
+	tests := map[string]time.Duration{
+		"":     0,
+		"0":    0,
+		"7d":   7 * 24 * time.Hour,
+		"1.5d": 36 * time.Hour,
+		"12h":  12 * time.Hour,
+		" 30m": 30 * time.Minute,
+	}
+	for value, want := range tests {
+		got, err := parseAge(value)
+		if err != nil || got != want {
+			t.Errorf("parseAge(%q) = %v, %v; want %v", value, got, err, want)
+		}
+	}
+
+	for _, value := range []string{"7days", "d", "-1d", "-2h", "week"} {
+		if _, err := parseAge(value); err == nil {
+			t.Errorf("parseAge(%q) should fail", value)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package deploy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/state"
+)
+
+func createHistoryCommand(domainName *string) *cobra.Command {
+	var shared bool
+	var jsonOutput bool
+
+	cmd := &cobra.Command{
+		Use:   "history",
+		Short: "Show the history of deployments",
+		Long: `Show the deployments recorded in the local deployments file.
+
+With --shared, deployments recorded by your team in the shared state (see
+'deploy state configure') are merged in. Stacks of the same name in the same
+region created by different principals while both existed are flagged as
+conflicts. When the shared state cannot be reached, only local history is shown.`,
+		Example: `  aws-research-wizard deploy history
+  aws-research-wizard deploy history --shared --domain genomics
+  aws-research-wizard deploy history --shared --json`,
+		Run: func(cmd *cobra.Command, args []string) {
+			store, err := state.OpenDefaultStore()
+			if err != nil {
+				log.Fatalf("Failed to open deployment state: %v", err)
+			}
+			local, err := store.Deployments()
+			if err != nil {
+				log.Fatalf("Failed to read deployment state: %v", err)
+			}
+
+			var sharedDeployments []state.Deployment
+			if shared {
+				sharedConfig, err := loadSharedConfig()
+				if err != nil {
+					log.Fatalf("Failed to read shared state configuration: %v", err)
+				}
+				if sharedConfig == nil {
+					log.Fatal("Shared state is not configured. Use 'deploy state configure'.")
+				}
+
+				region, _ := cmd.Flags().GetString("region")
+				err = withSharedStore(region, func(ctx context.Context, sharedStore *state.SharedStore) error {
+					sharedDeployments, err = sharedStore.Deployments(ctx)
+					return err
+				})
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "⚠️  Shared state unavailable, showing local history only: %v\n", err)
+				}
+			}
+
+			var entries []state.HistoryEntry
+			for _, entry := range state.MergeHistory(local, sharedDeployments) {
+				if *domainName == "" || entry.Domain == *domainName {
+					entries = append(entries, entry)
+				}
+			}
+
+			if jsonOutput {
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+				if err := encoder.Encode(entries); err != nil {
+					log.Fatalf("Failed to encode history: %v", err)
+				}
+				return
+			}
+
+			printHistory(entries, shared)
+		},
+	}
+
+	cmd.Flags().BoolVar(&shared, "shared", false, "Merge in the history shared by your team")
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "Output as JSON")
+
+	return cmd
+}
+
+func printHistory(entries []state.HistoryEntry, shared bool) {
+	fmt.Printf("📜 Deployment History (%d total):\n\n", len(entries))
+
+	if len(entries) == 0 {
+		fmt.Println("No deployments recorded.")
+		return
+	}
+
+	conflicts := 0
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	header := "STACK\tREGION\tDOMAIN\tTYPE\tDEPLOYED BY\tCREATED\tDELETED"
+	if shared {
+		header += "\tSOURCE"
+	}
+	fmt.Fprintln(w, header)
+	for _, entry := range entries {
+		deleted := "-"
+		if entry.DeletedAt != nil {
+			deleted = entry.DeletedAt.Format("2006-01-02 15:04")
+		}
+		row := fmt.Sprintf("%s\t%s\t%s\t%s\t%s\t%s\t%s",
+			entry.StackName, entry.Region, historyDomain(entry), orDash(entry.InstanceType),
+			orDash(principalName(entry.DeployedBy)), entry.CreatedAt.Format("2006-01-02 15:04"), deleted)
+		if shared {
+			row += "\t" + historySource(entry)
+		}
+		if entry.Conflict {
+			conflicts++
+			row += "\t⚠️  conflict"
+		}
+		fmt.Fprintln(w, row)
+	}
+	w.Flush()
+
+	if conflicts > 0 {
+		fmt.Printf("\n⚠️  %d deployment(s) share a stack name and region with another principal's stack\n", conflicts)
+	}
+}
+
+// historyDomain names the deployed domain, and the deprecated domain it was
+// deployed in place of
+func historyDomain(entry state.HistoryEntry) string {
+	if entry.RequestedDomain != "" {
+		return fmt.Sprintf("%s (for %s)", entry.Domain, entry.RequestedDomain)
+	}
+	return orDash(entry.Domain)
+}
+
+// historySource describes which sources recorded a deployment
+func historySource(entry state.HistoryEntry) string {
+	switch {
+	case entry.Local && entry.Shared:
+		return "local+shared"
+	case entry.Shared:
+		return "shared"
+	default:
+		return "local"
+	}
+}
+
+// principalName shortens an IAM or STS ARN to its resource part
+func principalName(arn string) string {
+	if i := strings.LastIndex(arn, ":"); i >= 0 {
+		return arn[i+1:]
+	}
+	return arn
+}
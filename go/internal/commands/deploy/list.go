@@ -0,0 +1,200 @@
+package deploy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/aws"
+)
+
+// listedInstance is a research instance with its estimated hourly cost
+type listedInstance struct {
+	aws.InstanceInfo
+	Domain     string  `json:"domain"`
+	Project    string  `json:"project,omitempty"`
+	HourlyCost float64 `json:"hourly_cost"`
+}
+
+// instanceSummary aggregates a listing for the footer
+type instanceSummary struct {
+	Total           int            `json:"total"`
+	DomainCounts    map[string]int `json:"domain_counts"`
+	TotalHourlyCost float64        `json:"total_hourly_cost"`
+}
+
+// instanceSortKeys maps --sort values to orderings
+var instanceSortKeys = map[string]func(a, b listedInstance) bool{
+	"launch": func(a, b listedInstance) bool { return a.LaunchTime.Before(b.LaunchTime) },
+	"domain": func(a, b listedInstance) bool { return a.Domain < b.Domain },
+	"type":   func(a, b listedInstance) bool { return a.InstanceType < b.InstanceType },
+	"state":  func(a, b listedInstance) bool { return a.State < b.State },
+	"cost":   func(a, b listedInstance) bool { return a.HourlyCost > b.HourlyCost },
+}
+
+func createListCommand(domainName *string) *cobra.Command {
+	var project string
+	var states []string
+	var launchedAfter string
+	var sortBy string
+	var jsonOutput bool
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List deployed research environments",
+		Example: `  aws-research-wizard deploy list
+  aws-research-wizard deploy list --domain genomics --state running --launched-after 2025-01-01
+  aws-research-wizard deploy list --sort cost --json`,
+		Run: func(cmd *cobra.Command, args []string) {
+			less, ok := instanceSortKeys[sortBy]
+			if !ok {
+				log.Fatalf("Unknown sort key '%s' (use launch, domain, type, state or cost)", sortBy)
+			}
+
+			filter := aws.InstanceFilter{
+				Domain:  *domainName,
+				Project: project,
+				States:  states,
+				Tags:    map[string]string{"CreatedBy": "AWS-Research-Wizard"},
+			}
+
+			if launchedAfter != "" {
+				after, err := time.Parse("2006-01-02", launchedAfter)
+				if err != nil {
+					log.Fatalf("Invalid --launched-after date (expected YYYY-MM-DD): %v", err)
+				}
+				filter.LaunchedAfter = after
+			}
+
+			ctx := context.Background()
+			region, _ := cmd.Flags().GetString("region")
+			awsClient, err := aws.NewClient(ctx, region)
+			if err != nil {
+				log.Fatalf("Failed to initialize AWS client: %v", err)
+			}
+
+			infraManager := aws.NewInfrastructureManager(awsClient)
+
+			instances, err := infraManager.ListFilteredInstances(ctx, filter)
+			if err != nil {
+				log.Fatalf("Failed to list instances: %v", err)
+			}
+
+			pricing, err := aws.NewPricingCalculator(region)
+			if err != nil {
+				log.Fatalf("Failed to initialize pricing calculator: %v", err)
+			}
+
+			listed := make([]listedInstance, 0, len(instances))
+			for _, instance := range instances {
+				listed = append(listed, newListedInstance(instance, pricing))
+			}
+
+			sort.SliceStable(listed, func(i, j int) bool { return less(listed[i], listed[j]) })
+			summary := summarizeInstances(listed)
+
+			if jsonOutput {
+				output := struct {
+					Instances []listedInstance `json:"instances"`
+					Summary   instanceSummary  `json:"summary"`
+				}{listed, summary}
+
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+				if err := encoder.Encode(output); err != nil {
+					log.Fatalf("Failed to encode instances: %v", err)
+				}
+				return
+			}
+
+			printInstanceTable(listed, summary)
+		},
+	}
+
+	cmd.Flags().StringVar(&project, "project", "", "Only list instances tagged with this project")
+	cmd.Flags().StringSliceVar(&states, "state", []string{"running", "pending", "stopping", "stopped"}, "Instance states to include")
+	cmd.Flags().StringVar(&launchedAfter, "launched-after", "", "Only list instances launched after this date (YYYY-MM-DD)")
+	cmd.Flags().StringVar(&sortBy, "sort", "launch", "Sort by launch, domain, type, state or cost")
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "Output as JSON")
+
+	return cmd
+}
+
+func newListedInstance(instance aws.InstanceInfo, pricing *aws.PricingCalculator) listedInstance {
+	listed := listedInstance{
+		InstanceInfo: instance,
+		Domain:       instance.Tags["Domain"],
+		Project:      instance.Tags["Project"],
+	}
+
+	if listed.Domain == "" {
+		listed.Domain = "Unknown"
+	}
+
+	// Stopped instances do not accrue compute charges
+	if instance.State == "running" || instance.State == "pending" {
+		if estimate, err := pricing.CalculateCost(instance.InstanceType); err == nil {
+			listed.HourlyCost = estimate.HourlyCost
+		}
+	}
+
+	return listed
+}
+
+func summarizeInstances(instances []listedInstance) instanceSummary {
+	summary := instanceSummary{
+		Total:        len(instances),
+		DomainCounts: make(map[string]int),
+	}
+
+	for _, instance := range instances {
+		summary.DomainCounts[instance.Domain]++
+		summary.TotalHourlyCost += instance.HourlyCost
+	}
+
+	return summary
+}
+
+func printInstanceTable(instances []listedInstance, summary instanceSummary) {
+	fmt.Printf("🖥️  Research Environments (%d total):\n\n", summary.Total)
+
+	if len(instances) == 0 {
+		fmt.Println("No research environments found.")
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "INSTANCE\tDOMAIN\tTYPE\tSTATE\tPUBLIC IP\tLAUNCHED\t$/HOUR")
+	for _, instance := range instances {
+		publicIP := instance.PublicIP
+		if publicIP == "" {
+			publicIP = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%.3f\n",
+			instance.InstanceID, instance.Domain, instance.InstanceType, instance.State,
+			publicIP, instance.LaunchTime.Format("2006-01-02 15:04"), instance.HourlyCost)
+	}
+	w.Flush()
+
+	domains := make([]string, 0, len(summary.DomainCounts))
+	for domain := range summary.DomainCounts {
+		domains = append(domains, domain)
+	}
+	sort.Strings(domains)
+
+	counts := make([]string, 0, len(domains))
+	for _, domain := range domains {
+		counts = append(counts, fmt.Sprintf("%s: %d", domain, summary.DomainCounts[domain]))
+	}
+
+	fmt.Printf("\n📊 By domain: %s\n", strings.Join(counts, ", "))
+	fmt.Printf("💰 Total: $%.3f/hour ($%.0f/month)\n", summary.TotalHourlyCost, summary.TotalHourlyCost*24*30.44)
+}
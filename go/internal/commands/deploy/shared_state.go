@@ -0,0 +1,256 @@
+package deploy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/aws"
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/state"
+)
+
+// sharedStateTimeout bounds each use of the shared state backend, so an
+// unreachable bucket falls back to local state instead of stalling a deployment
+const sharedStateTimeout = 15 * time.Second
+
+func createStateCommand(stackName *string) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "state",
+		Short: "Configure the deployment history shared with your team",
+		Long: `Share deployment history and stack name reservations with your team through
+an S3 bucket.
+
+Once configured, each deployment reserves its stack name in the bucket before
+the stack is created, so a teammate deploying the same name in the same region
+is stopped rather than clashing. Creating and deleting stacks is recorded in
+the bucket as well as the local deployments file. Writes are conditional, so
+concurrent deployments never overwrite each other's records. When the bucket
+cannot be reached, deployments go ahead with local state only.`,
+	}
+
+	cmd.AddCommand(
+		createStateConfigureCommand(),
+		createStateShowCommand(),
+		createStateReleaseCommand(stackName),
+	)
+
+	return cmd
+}
+
+func createStateConfigureCommand() *cobra.Command {
+	var bucketRegion string
+	var disable bool
+
+	cmd := &cobra.Command{
+		Use:   "configure [s3://bucket/prefix]",
+		Short: "Set the S3 location of the shared deployment history",
+		Example: `  aws-research-wizard deploy state configure s3://lab-wizard-state/deployments
+  aws-research-wizard deploy state configure s3://lab-wizard-state --bucket-region us-west-2
+  aws-research-wizard deploy state configure --disable`,
+		Args: cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			path, err := state.DefaultSharedConfigPath()
+			if err != nil {
+				log.Fatalf("Failed to locate shared state configuration: %v", err)
+			}
+
+			if disable {
+				if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+					log.Fatalf("Failed to remove shared state configuration: %v", err)
+				}
+				fmt.Println("✅ Shared state disabled; deployments are recorded locally only")
+				return
+			}
+			if len(args) == 0 {
+				log.Fatal("An s3://bucket/prefix location is required, or --disable")
+			}
+
+			sharedConfig, err := state.ParseSharedURI(args[0])
+			if err != nil {
+				log.Fatalf("Invalid location: %v", err)
+			}
+			sharedConfig.Region = bucketRegion
+
+			ctx, cancel := context.WithTimeout(context.Background(), sharedStateTimeout)
+			defer cancel()
+			region, _ := cmd.Flags().GetString("region")
+			store, err := newSharedStore(ctx, sharedConfig, region)
+			if err == nil {
+				err = store.Check(ctx)
+			}
+			if err != nil {
+				fmt.Printf("⚠️  Could not reach %s yet: %v\n", sharedConfig.URI(), err)
+			}
+
+			if err := state.SaveSharedConfig(path, sharedConfig); err != nil {
+				log.Fatalf("Failed to save shared state configuration: %v", err)
+			}
+			fmt.Printf("✅ Deployment history is shared through %s\n", sharedConfig.URI())
+		},
+	}
+
+	cmd.Flags().StringVar(&bucketRegion, "bucket-region", "", "Region of the bucket (default: the deployment region)")
+	cmd.Flags().BoolVar(&disable, "disable", false, "Stop sharing deployment history")
+
+	return cmd
+}
+
+func createStateShowCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "show",
+		Short: "Show where deployment history is shared",
+		Run: func(cmd *cobra.Command, args []string) {
+			sharedConfig, err := loadSharedConfig()
+			if err != nil {
+				log.Fatalf("Failed to read shared state configuration: %v", err)
+			}
+			if sharedConfig == nil {
+				fmt.Println("Shared state is not configured; deployments are recorded locally only.")
+				fmt.Println("Configure it with: aws-research-wizard deploy state configure s3://bucket/prefix")
+				return
+			}
+
+			fmt.Printf("Location: %s\n", sharedConfig.URI())
+			if sharedConfig.Region != "" {
+				fmt.Printf("Bucket Region: %s\n", sharedConfig.Region)
+			}
+		},
+	}
+}
+
+func createStateReleaseCommand(stackName *string) *cobra.Command {
+	return &cobra.Command{
+		Use:   "release",
+		Short: "Free a stack name reserved in the shared state",
+		Long: `Free a stack name whose reservation outlived its stack, for example when
+the stack was deleted outside the wizard.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if *stackName == "" {
+				log.Fatal("Stack name is required. Use --stack flag.")
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), sharedStateTimeout)
+			defer cancel()
+			region, _ := cmd.Flags().GetString("region")
+			store, err := openSharedStore(ctx, region)
+			if err != nil {
+				log.Fatalf("Failed to open shared state: %v", err)
+			}
+			if store == nil {
+				log.Fatal("Shared state is not configured. Use 'deploy state configure'.")
+			}
+
+			reservation, err := store.Reservation(ctx, *stackName, region)
+			if err != nil {
+				log.Fatalf("Failed to read reservation: %v", err)
+			}
+			if reservation == nil {
+				fmt.Printf("Stack name %s in %s is not reserved\n", *stackName, region)
+				return
+			}
+			if err := store.Release(ctx, *stackName, region); err != nil {
+				log.Fatalf("Failed to release reservation: %v", err)
+			}
+			fmt.Printf("✅ Released %s in %s, reserved by %s\n", *stackName, region, reservation.Owner)
+		},
+	}
+}
+
+// loadSharedConfig reads the shared state configuration, or nil when none is set
+func loadSharedConfig() (*state.SharedConfig, error) {
+	path, err := state.DefaultSharedConfigPath()
+	if err != nil {
+		return nil, err
+	}
+	return state.LoadSharedConfig(path)
+}
+
+// openSharedStore returns the configured shared store, or nil when none is set
+func openSharedStore(ctx context.Context, region string) (*state.SharedStore, error) {
+	sharedConfig, err := loadSharedConfig()
+	if err != nil || sharedConfig == nil {
+		return nil, err
+	}
+	return newSharedStore(ctx, *sharedConfig, region)
+}
+
+// newSharedStore connects to the shared bucket, in its own region when set
+func newSharedStore(ctx context.Context, sharedConfig state.SharedConfig, region string) (*state.SharedStore, error) {
+	if sharedConfig.Region != "" {
+		region = sharedConfig.Region
+	}
+	awsClient, err := aws.NewClient(ctx, region)
+	if err != nil {
+		return nil, err
+	}
+	return state.NewSharedStore(awsClient.S3, sharedConfig), nil
+}
+
+// withSharedStore runs fn against the shared store when one is configured
+func withSharedStore(region string, fn func(ctx context.Context, store *state.SharedStore) error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), sharedStateTimeout)
+	defer cancel()
+
+	store, err := openSharedStore(ctx, region)
+	if err != nil || store == nil {
+		return err
+	}
+	return fn(ctx, store)
+}
+
+// warnSharedState reports a shared state failure; the local state still has the record
+func warnSharedState(err error) {
+	if err != nil {
+		fmt.Printf("⚠️  Shared state unavailable, recorded locally only: %v\n", err)
+	}
+}
+
+// reserveStackName claims the stack name in the shared state before the stack
+// is created. Only a name held by another principal stops the deployment.
+func reserveStackName(stackName, region, owner string) error {
+	err := withSharedStore(region, func(ctx context.Context, store *state.SharedStore) error {
+		return store.Reserve(ctx, state.Reservation{
+			StackName:  stackName,
+			Region:     region,
+			Owner:      owner,
+			ReservedAt: time.Now().UTC(),
+		})
+	})
+
+	var conflict *state.ReservationConflictError
+	if errors.As(err, &conflict) {
+		return fmt.Errorf("%w; choose another --stack, or if that stack no longer exists run: aws-research-wizard deploy state release --stack %s --region %s",
+			err, stackName, region)
+	}
+	warnSharedState(err)
+	return nil
+}
+
+// releaseStackName frees the stack name in the shared state
+func releaseStackName(stackName, region string) {
+	warnSharedState(withSharedStore(region, func(ctx context.Context, store *state.SharedStore) error {
+		return store.Release(ctx, stackName, region)
+	}))
+}
+
+// recordSharedDeployment adds a deployment to the shared history
+func recordSharedDeployment(deployment state.Deployment) {
+	warnSharedState(withSharedStore(deployment.Region, func(ctx context.Context, store *state.SharedStore) error {
+		return store.RecordDeployment(ctx, deployment)
+	}))
+}
+
+// recordSharedDeletion marks a stack deleted in the shared history and frees its name
+func recordSharedDeletion(stackName, region string, deletedAt time.Time) {
+	warnSharedState(withSharedStore(region, func(ctx context.Context, store *state.SharedStore) error {
+		if err := store.MarkDeleted(ctx, stackName, region, deletedAt); err != nil {
+			return err
+		}
+		return store.Release(ctx, stackName, region)
+	}))
+}
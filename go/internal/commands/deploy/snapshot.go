@@ -0,0 +1,211 @@
+package deploy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/aws"
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/state"
+)
+
+func createSnapshotCommand(stackName *string) *cobra.Command {
+	snapshotCmd := &cobra.Command{
+		Use:   "snapshot",
+		Short: "Snapshot the EBS volumes of a research environment",
+		Long: `Create EBS snapshots of every volume attached to a stack's instance.
+
+Snapshots are tagged with the stack, domain and time, and recorded in the
+local deployment state file so they can be restored with "deploy restore".`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if *stackName == "" {
+				log.Fatal("Stack name is required. Use --stack flag.")
+			}
+
+			ctx := context.Background()
+			region, _ := cmd.Flags().GetString("region")
+			awsClient, err := aws.NewClient(ctx, region)
+			if err != nil {
+				log.Fatalf("Failed to initialize AWS client: %v", err)
+			}
+
+			stackInfo, err := aws.NewInfrastructureManager(awsClient).GetStackInfo(ctx, *stackName)
+			if err != nil {
+				log.Fatalf("Failed to get stack info: %v", err)
+			}
+
+			instanceID, err := stackInfo.InstanceID()
+			if err != nil {
+				log.Fatalf("Failed to find stack instance: %v", err)
+			}
+
+			now := time.Now().UTC()
+			tags := map[string]string{
+				"StackName":    *stackName,
+				"Domain":       stackInfo.Parameters["DomainName"],
+				"SnapshotTime": now.Format(time.RFC3339),
+			}
+
+			fmt.Printf("📸 Snapshotting volumes of %s (%s)...\n", *stackName, instanceID)
+
+			snapshots, err := aws.NewSnapshotManager(awsClient).SnapshotInstance(ctx, instanceID, tags)
+			if err != nil {
+				log.Fatalf("Snapshot failed: %v", err)
+			}
+
+			store, err := state.OpenDefaultStore()
+			if err != nil {
+				log.Fatalf("Failed to open deployment state: %v", err)
+			}
+
+			for _, snapshot := range snapshots {
+				fmt.Printf("✅ %s: %s (%d GB, ~$%.2f/month)\n", snapshot.DeviceName, snapshot.SnapshotID, snapshot.SizeGB, snapshot.MonthlyCost)
+
+				err := store.AddSnapshot(*stackName, region, state.Snapshot{
+					SnapshotID: snapshot.SnapshotID,
+					VolumeID:   snapshot.VolumeID,
+					DeviceName: snapshot.DeviceName,
+					SizeGB:     snapshot.SizeGB,
+					CreatedAt:  now,
+				})
+				if err != nil {
+					fmt.Printf("⚠️  Could not record snapshot in %s: %v\n", store.Path(), err)
+				}
+			}
+
+			fmt.Printf("\nSnapshots complete in the background. Check with: aws-research-wizard deploy snapshot list --stack %s\n", *stackName)
+		},
+	}
+
+	snapshotCmd.AddCommand(createSnapshotListCommand(stackName))
+
+	return snapshotCmd
+}
+
+func createSnapshotListCommand(stackName *string) *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List snapshots of a research environment",
+		Run: func(cmd *cobra.Command, args []string) {
+			if *stackName == "" {
+				log.Fatal("Stack name is required. Use --stack flag.")
+			}
+
+			ctx := context.Background()
+			region, _ := cmd.Flags().GetString("region")
+			awsClient, err := aws.NewClient(ctx, region)
+			if err != nil {
+				log.Fatalf("Failed to initialize AWS client: %v", err)
+			}
+
+			snapshots, err := aws.NewSnapshotManager(awsClient).ListSnapshots(ctx, *stackName)
+			if err != nil {
+				log.Fatalf("Failed to list snapshots: %v", err)
+			}
+
+			fmt.Printf("📸 Snapshots of %s (%d total):\n\n", *stackName, len(snapshots))
+			if len(snapshots) == 0 {
+				fmt.Printf("No snapshots found. Create one with: aws-research-wizard deploy snapshot --stack %s\n", *stackName)
+				return
+			}
+
+			sort.Slice(snapshots, func(i, j int) bool {
+				return snapshots[i].StartTime.After(snapshots[j].StartTime)
+			})
+
+			var totalCost float64
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "SNAPSHOT\tDEVICE\tSIZE\tSTATE\tCREATED\t$/MONTH")
+			for _, snapshot := range snapshots {
+				totalCost += snapshot.MonthlyCost
+				fmt.Fprintf(w, "%s\t%s\t%d GB\t%s\t%s\t%.2f\n",
+					snapshot.SnapshotID, snapshot.DeviceName, snapshot.SizeGB, snapshot.State,
+					snapshot.StartTime.Format("2006-01-02 15:04"), snapshot.MonthlyCost)
+			}
+			w.Flush()
+
+			fmt.Printf("\n💰 Estimated storage: $%.2f/month (upper bound; snapshots after the first are incremental)\n", totalCost)
+		},
+	}
+}
+
+func createRestoreCommand(stackName *string) *cobra.Command {
+	var snapshotID string
+	var yes bool
+
+	cmd := &cobra.Command{
+		Use:   "restore",
+		Short: "Restore a research environment volume from a snapshot",
+		Long: `Replace one of a stack's volumes with a new volume created from a snapshot.
+
+The instance is stopped, the new volume is created in the instance's
+availability zone and swapped in at the same device, and the instance is
+restarted. The original volume is detached and kept so it can be reattached
+or deleted afterwards.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if *stackName == "" {
+				log.Fatal("Stack name is required. Use --stack flag.")
+			}
+			if snapshotID == "" {
+				log.Fatal("Snapshot ID is required. Use --snapshot-id flag.")
+			}
+
+			ctx := context.Background()
+			region, _ := cmd.Flags().GetString("region")
+			awsClient, err := aws.NewClient(ctx, region)
+			if err != nil {
+				log.Fatalf("Failed to initialize AWS client: %v", err)
+			}
+
+			stackInfo, err := aws.NewInfrastructureManager(awsClient).GetStackInfo(ctx, *stackName)
+			if err != nil {
+				log.Fatalf("Failed to get stack info: %v", err)
+			}
+
+			instanceID, err := stackInfo.InstanceID()
+			if err != nil {
+				log.Fatalf("Failed to find stack instance: %v", err)
+			}
+
+			if !yes {
+				fmt.Printf("⚠️  Restoring %s will stop instance %s and replace the volume. Continue? (y/N): ", snapshotID, instanceID)
+
+				var response string
+				fmt.Scanln(&response)
+
+				if response != "y" && response != "Y" {
+					fmt.Println("Restore cancelled.")
+					return
+				}
+			}
+
+			fmt.Printf("🔄 Restoring %s onto %s...\n", snapshotID, instanceID)
+
+			result, err := aws.NewSnapshotManager(awsClient).RestoreSnapshot(ctx, instanceID, snapshotID)
+			if err != nil {
+				var restoreErr *aws.RestoreError
+				if errors.As(err, &restoreErr) {
+					fmt.Printf("❌ %v\n", restoreErr)
+					os.Exit(1)
+				}
+				log.Fatalf("Restore failed: %v", err)
+			}
+
+			fmt.Printf("✅ Restored %s to %s as %s\n", snapshotID, result.DeviceName, result.NewVolumeID)
+			fmt.Printf("Original volume %s is detached and kept. Delete it when no longer needed:\n", result.OriginalVolumeID)
+			fmt.Printf("  aws ec2 delete-volume --volume-id %s --region %s\n", result.OriginalVolumeID, region)
+		},
+	}
+
+	cmd.Flags().StringVar(&snapshotID, "snapshot-id", "", "Snapshot to restore")
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "Skip confirmation")
+
+	return cmd
+}
@@ -0,0 +1,116 @@
+package deploy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+
+	"github.com/spf13/cobra"
+
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/aws"
+)
+
+// sessionManagerPlugin is the helper the AWS CLI runs for Session Manager sessions
+const sessionManagerPlugin = "session-manager-plugin"
+
+func createSSHCommand(stackName *string) *cobra.Command {
+	var user string
+	var identityFile string
+	var forceSSM bool
+	var jupyter bool
+	var localPort int
+
+	cmd := &cobra.Command{
+		Use:   "ssh",
+		Short: "Open a shell or Jupyter tunnel to a research environment",
+		Long: `Connect to a deployed stack's research instance.
+
+Stacks deployed with SSH are reached with ssh and the stack's key pair. Stacks
+deployed with --no-ssh, and instances without a public IP, are reached with
+"aws ssm start-session", which needs the Session Manager plugin installed
+locally. --jupyter forwards the instance's Jupyter port instead of opening a
+shell, through an SSH tunnel or the SSM port forwarding document.
+
+Examples:
+  # Open a shell
+  aws-research-wizard deploy ssh --stack research-wizard-genomics
+
+  # Forward Jupyter to http://localhost:8888
+  aws-research-wizard deploy ssh --stack research-wizard-genomics --jupyter
+
+  # Use Session Manager even though the stack accepts SSH
+  aws-research-wizard deploy ssh --stack research-wizard-genomics --ssm`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if *stackName == "" {
+				log.Fatal("Stack name is required. Use --stack flag.")
+			}
+
+			ctx := context.Background()
+			region, _ := cmd.Flags().GetString("region")
+			awsClient, err := aws.NewClient(ctx, region)
+			if err != nil {
+				log.Fatalf("Failed to initialize AWS client: %v", err)
+			}
+
+			stackInfo, err := aws.NewInfrastructureManager(awsClient).GetStackInfo(ctx, *stackName)
+			if err != nil {
+				log.Fatalf("Failed to get stack info: %v", err)
+			}
+
+			plan, err := aws.PlanConnect(stackInfo, aws.ConnectOptions{
+				User:         user,
+				IdentityFile: identityFile,
+				ForceSSM:     forceSSM,
+				Jupyter:      jupyter,
+				LocalPort:    localPort,
+				Region:       awsClient.Region,
+			})
+			if err != nil {
+				log.Fatalf("Failed to plan connection: %v", err)
+			}
+
+			if plan.Mode == aws.ConnectivitySSM {
+				if _, err := exec.LookPath(sessionManagerPlugin); err != nil {
+					log.Fatalf("%s not found in PATH; install it to connect through Session Manager: https://docs.aws.amazon.com/systems-manager/latest/userguide/session-manager-working-with-install-plugin.html", sessionManagerPlugin)
+				}
+			}
+			if plan.Fallback != "" {
+				fmt.Fprintf(os.Stderr, "⚠️  Using Session Manager: %s\n", plan.Fallback)
+			}
+			if jupyter {
+				if localPort == 0 {
+					localPort = aws.DefaultJupyterPort
+				}
+				fmt.Fprintf(os.Stderr, "📓 Jupyter: http://localhost:%d (Ctrl-C to stop forwarding)\n", localPort)
+			}
+
+			if err := runConnect(plan); err != nil {
+				var exitErr *exec.ExitError
+				if errors.As(err, &exitErr) {
+					os.Exit(exitErr.ExitCode())
+				}
+				log.Fatalf("Failed to connect: %v", err)
+			}
+		},
+	}
+
+	cmd.Flags().StringVar(&user, "user", aws.DefaultSSHUser, "SSH login user")
+	cmd.Flags().StringVar(&identityFile, "identity-file", "", "SSH private key (default: ~/.ssh/<KeyName>.pem)")
+	cmd.Flags().BoolVar(&forceSSM, "ssm", false, "Connect through Session Manager even when the stack accepts SSH")
+	cmd.Flags().BoolVar(&jupyter, "jupyter", false, "Forward the Jupyter port instead of opening a shell")
+	cmd.Flags().IntVar(&localPort, "local-port", aws.DefaultJupyterPort, "Local port for --jupyter")
+
+	return cmd
+}
+
+// runConnect runs the connection command attached to the terminal
+func runConnect(plan *aws.ConnectPlan) error {
+	command := exec.Command(plan.Program, plan.Args...)
+	command.Stdin = os.Stdin
+	command.Stdout = os.Stdout
+	command.Stderr = os.Stderr
+	return command.Run()
+}
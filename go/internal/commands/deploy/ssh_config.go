@@ -0,0 +1,99 @@
+package deploy
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/spf13/cobra"
+
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/aws"
+)
+
+func createSSHConfigCommand(stackName *string) *cobra.Command {
+	var user string
+	var identityFile string
+	var configPath string
+
+	cmd := &cobra.Command{
+		Use:   "ssh-config",
+		Short: "Write an SSH Host entry for a research environment",
+		Long: `Write an OpenSSH Host block for a deployed stack to
+~/.ssh/config.d/research-wizard, forwarding the Jupyter port.
+
+Running the command again updates the existing entry in place. The entry is
+removed automatically when the stack is deleted with "deploy delete".
+
+Add "Include config.d/*" to the top of ~/.ssh/config to use the entries.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if *stackName == "" {
+				log.Fatal("Stack name is required. Use --stack flag.")
+			}
+
+			if configPath == "" {
+				defaultPath, err := aws.DefaultSSHConfigPath()
+				if err != nil {
+					log.Fatalf("Failed to locate SSH config: %v", err)
+				}
+				configPath = defaultPath
+			}
+
+			ctx := context.Background()
+			region, _ := cmd.Flags().GetString("region")
+			awsClient, err := aws.NewClient(ctx, region)
+			if err != nil {
+				log.Fatalf("Failed to initialize AWS client: %v", err)
+			}
+
+			infraManager := aws.NewInfrastructureManager(awsClient)
+
+			stackInfo, err := infraManager.GetStackInfo(ctx, *stackName)
+			if err != nil {
+				log.Fatalf("Failed to get stack info: %v", err)
+			}
+
+			if stackInfo.Connectivity() == aws.ConnectivitySSM {
+				log.Fatalf("Stack %s was deployed with --no-ssh; connect with: aws-research-wizard deploy ssh --stack %s", *stackName, *stackName)
+			}
+
+			host, err := aws.SSHHostFromStack(stackInfo, user, identityFile)
+			if err != nil {
+				log.Fatalf("Failed to build SSH config: %v", err)
+			}
+
+			if err := aws.UpsertSSHHost(configPath, host); err != nil {
+				log.Fatalf("Failed to update SSH config: %v", err)
+			}
+
+			fmt.Printf("🔑 SSH config updated: %s\n\n", configPath)
+			fmt.Print(host.Block())
+			fmt.Printf("\nConnect with: ssh %s\n", host.Alias)
+			fmt.Printf("Jupyter: http://localhost:%d\n", host.LocalForward)
+		},
+	}
+
+	cmd.Flags().StringVar(&user, "user", aws.DefaultSSHUser, "SSH login user")
+	cmd.Flags().StringVar(&identityFile, "identity-file", "", "SSH private key (default: ~/.ssh/<KeyName>.pem)")
+	cmd.Flags().StringVar(&configPath, "ssh-config", "", "SSH config file to update (default: ~/.ssh/config.d/research-wizard)")
+
+	return cmd
+}
+
+// removeSSHConfigEntry drops the stack's Host block, warning rather than failing
+func removeSSHConfigEntry(stackName string) {
+	configPath, err := aws.DefaultSSHConfigPath()
+	if err != nil {
+		fmt.Printf("⚠️  Could not locate SSH config: %v\n", err)
+		return
+	}
+
+	removed, err := aws.RemoveSSHHost(configPath, stackName)
+	if err != nil {
+		fmt.Printf("⚠️  Could not remove SSH config entry: %v\n", err)
+		return
+	}
+
+	if removed {
+		fmt.Printf("🔑 Removed SSH config entry for %s\n", stackName)
+	}
+}
@@ -0,0 +1,315 @@
+package deploy
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/aws"
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/config"
+)
+
+// stackSetFlags are shared by the stackset subcommands
+type stackSetFlags struct {
+	Accounts []string
+	Regions  []string
+	NoWait   bool
+}
+
+func createStackSetCommand(configRoot, stackName, domainName, instanceType *string, resources *resourceFlags, envFlags *environmentFlags) *cobra.Command {
+	var flags stackSetFlags
+
+	cmd := &cobra.Command{
+		Use:   "stackset",
+		Short: "Roll a research environment out to several accounts with StackSets",
+		Long: `Deploy the same research environment to several AWS accounts and regions
+with CloudFormation StackSets, for example one stack per lab account.
+
+Stack sets are self-managed: the account running the wizard needs the
+AWSCloudFormationStackSetAdministrationRole, and every target account needs
+an execution role it can assume (AWSCloudFormationStackSetExecutionRole by
+default). The stack set is named by --stack.
+
+Examples:
+  # Roll the genomics environment out to two lab accounts in two regions
+  aws-research-wizard deploy stackset create --domain genomics --stack lab-genomics \
+    --accounts 111111111111,222222222222 --regions us-east-1,us-west-2
+
+  # Show per-account, per-region status
+  aws-research-wizard deploy stackset status --stack lab-genomics
+
+  # Remove every stack and then the stack set
+  aws-research-wizard deploy stackset delete --stack lab-genomics`,
+	}
+
+	cmd.PersistentFlags().StringSliceVar(&flags.Accounts, "accounts", nil, "Target AWS account IDs (comma separated)")
+	cmd.PersistentFlags().StringSliceVar(&flags.Regions, "regions", nil, "Target regions (comma separated)")
+	cmd.PersistentFlags().BoolVar(&flags.NoWait, "no-wait", false, "Return once the operation has started")
+
+	cmd.AddCommand(
+		createStackSetCreateCommand(configRoot, stackName, domainName, instanceType, resources, envFlags, &flags),
+		createStackSetStatusCommand(stackName),
+		createStackSetDeleteCommand(stackName, &flags),
+	)
+
+	return cmd
+}
+
+func createStackSetCreateCommand(configRoot, stackName, domainName, instanceType *string, resources *resourceFlags, envFlags *environmentFlags, flags *stackSetFlags) *cobra.Command {
+	var executionRole string
+	var adminRoleARN string
+
+	cmd := &cobra.Command{
+		Use:   "create",
+		Short: "Create a stack set and deploy it to the target accounts and regions",
+		Run: func(cmd *cobra.Command, args []string) {
+			if *domainName == "" {
+				log.Fatal("Domain name is required. Use --domain flag.")
+			}
+			if len(flags.Accounts) == 0 || len(flags.Regions) == 0 {
+				log.Fatal("At least one account and one region are required. Use --accounts and --regions.")
+			}
+			if *configRoot == "" {
+				*configRoot = findConfigRoot()
+			}
+
+			domains, err := config.NewConfigLoader(*configRoot).LoadAllDomains()
+			if err != nil {
+				log.Fatalf("Failed to load domains: %v", err)
+			}
+			resolvedName, domain, err := resolveDomain(os.Stdout, domains, *domainName, resources.FollowReplacement, time.Now())
+			if err != nil {
+				log.Fatalf("Failed to resolve domain: %v", err)
+			}
+			*domainName = resolvedName
+
+			selectedInstance := *instanceType
+			if selectedInstance == "" {
+				selectedInstance = recommendedInstanceType(domain, resources.PreferARM)
+			}
+			if selectedInstance == "" {
+				log.Fatal("No instance type specified or available in domain recommendations")
+			}
+
+			arch, opts, err := templateOptions(domain, selectedInstance, *resources)
+			if err != nil {
+				log.Fatalf("Failed to resolve template options: %v", err)
+			}
+			architectureParameters, err := resources.stackParameters(arch, opts)
+			if err != nil {
+				log.Fatalf("Invalid deployment options: %v", err)
+			}
+			if opts.CapacityReservationID != "" {
+				log.Fatal("A capacity reservation belongs to one account and region; use --capacity-reservation-group with a group shared to every target account")
+			}
+			template, err := generateCloudFormationTemplate(domain, selectedInstance, *resources)
+			if err != nil {
+				log.Fatalf("Failed to generate CloudFormation template: %v", err)
+			}
+			tags, err := parseTags(envFlags.Tags)
+			if err != nil {
+				log.Fatalf("Invalid tags: %v", err)
+			}
+
+			name := *stackName
+			if name == "" {
+				name = fmt.Sprintf("research-wizard-%s", *domainName)
+			}
+
+			ctx := context.Background()
+			manager := newStackSetManager(ctx, cmd)
+
+			fmt.Printf("🏗️ Creating stack set %s for %d accounts in %d regions...\n", name, len(flags.Accounts), len(flags.Regions))
+			operationID, err := manager.CreateStackSet(ctx, aws.StackSetSpec{
+				Name:                  name,
+				TemplateBody:          template,
+				Parameters:            resources.deployParameters(*domainName, selectedInstance, architectureParameters),
+				Tags:                  tags,
+				Accounts:              flags.Accounts,
+				Regions:               flags.Regions,
+				AdministrationRoleARN: adminRoleARN,
+				ExecutionRoleName:     executionRole,
+			})
+			if err != nil {
+				log.Fatalf("Failed to create stack set: %v", err)
+			}
+			fmt.Printf("✅ Rollout started (operation %s)\n", operationID)
+
+			if flags.NoWait {
+				fmt.Printf("Check progress with: aws-research-wizard deploy stackset status --stack %s --operation %s\n", name, operationID)
+				return
+			}
+			waitForStackSet(ctx, manager, name, operationID)
+		},
+	}
+
+	cmd.Flags().StringVar(&executionRole, "execution-role", aws.DefaultStackSetExecutionRole, "Role CloudFormation assumes in each target account")
+	cmd.Flags().StringVar(&adminRoleARN, "admin-role-arn", "", "Administration role ARN (default AWSCloudFormationStackSetAdministrationRole)")
+
+	return cmd
+}
+
+func createStackSetStatusCommand(stackName *string) *cobra.Command {
+	var operationID string
+
+	cmd := &cobra.Command{
+		Use:   "status",
+		Short: "Show the status of every account and region in a stack set",
+		Run: func(cmd *cobra.Command, args []string) {
+			if *stackName == "" {
+				log.Fatal("Stack set name is required. Use --stack flag.")
+			}
+
+			ctx := context.Background()
+			status, err := newStackSetManager(ctx, cmd).Status(ctx, *stackName, operationID)
+			if err != nil {
+				log.Fatalf("Failed to get stack set status: %v", err)
+			}
+			printStackSetStatus(status)
+		},
+	}
+
+	cmd.Flags().StringVar(&operationID, "operation", "", "Also show the progress of this operation")
+
+	return cmd
+}
+
+func createStackSetDeleteCommand(stackName *string, flags *stackSetFlags) *cobra.Command {
+	return &cobra.Command{
+		Use:   "delete",
+		Short: "Delete a stack set's stacks, then the stack set",
+		Long: `Delete the stacks a stack set deployed and then the stack set itself.
+
+With --accounts and --regions only those stacks are deleted and the stack set
+is kept. Stacks that fail to delete are listed, and the stack set is kept so
+the deletion can be retried.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if *stackName == "" {
+				log.Fatal("Stack set name is required. Use --stack flag.")
+			}
+
+			ctx := context.Background()
+			manager := newStackSetManager(ctx, cmd)
+
+			status, err := manager.Status(ctx, *stackName, "")
+			if err != nil {
+				log.Fatalf("Failed to get stack set status: %v", err)
+			}
+
+			accounts, regions := status.InstanceTargets()
+			partial := len(flags.Accounts) > 0 || len(flags.Regions) > 0
+			if len(flags.Accounts) > 0 {
+				accounts = flags.Accounts
+			}
+			if len(flags.Regions) > 0 {
+				regions = flags.Regions
+			}
+
+			fmt.Printf("⚠️  Deleting stack set %s stacks in %d accounts and %d regions\n", *stackName, len(accounts), len(regions))
+			fmt.Printf("This action cannot be undone. Continue? (y/N): ")
+
+			var response string
+			fmt.Scanln(&response)
+			if response != "y" && response != "Y" {
+				fmt.Println("Deletion cancelled.")
+				return
+			}
+
+			if len(accounts) > 0 && len(regions) > 0 {
+				operationID, err := manager.DeleteInstances(ctx, *stackName, accounts, regions)
+				if err != nil {
+					log.Fatalf("Failed to delete stack instances: %v", err)
+				}
+				fmt.Printf("🗑️  Stack deletion started (operation %s)\n", operationID)
+				if flags.NoWait {
+					return
+				}
+				status = waitForStackSet(ctx, manager, *stackName, operationID)
+			}
+
+			if partial {
+				return
+			}
+			if len(status.Instances) > 0 {
+				fmt.Printf("\n⚠️  Keeping stack set %s: %d stacks remain. Retry with: aws-research-wizard deploy stackset delete --stack %s\n",
+					*stackName, len(status.Instances), *stackName)
+				return
+			}
+			if err := manager.DeleteStackSet(ctx, *stackName); err != nil {
+				log.Fatalf("Failed to delete stack set: %v", err)
+			}
+			fmt.Printf("✅ Stack set %s deleted\n", *stackName)
+		},
+	}
+}
+
+// newStackSetManager connects to CloudFormation in the administrator region
+func newStackSetManager(ctx context.Context, cmd *cobra.Command) *aws.StackSetManager {
+	region, _ := cmd.Flags().GetString("region")
+	awsClient, err := aws.NewClient(ctx, region)
+	if err != nil {
+		log.Fatalf("Failed to initialize AWS client: %v", err)
+	}
+	return aws.NewStackSetManager(awsClient)
+}
+
+// waitForStackSet reports progress as instances roll out and prints the final
+// status. Failed accounts are listed; they do not abort the wait.
+func waitForStackSet(ctx context.Context, manager *aws.StackSetManager, name, operationID string) *aws.StackSetStatus {
+	fmt.Printf("⏳ Waiting for operation %s...\n", operationID)
+	last := aws.StackSetSummary{}
+	status, err := manager.WaitForOperation(ctx, name, operationID, func(status *aws.StackSetStatus) {
+		if summary := status.Summary(); summary != last {
+			last = summary
+			fmt.Printf("  %d succeeded, %d in progress, %d failed of %d\n", summary.Succeeded, summary.Pending, summary.Failed, summary.Total)
+		}
+	})
+	if err != nil {
+		if status == nil {
+			log.Fatalf("Failed to wait for stack set operation: %v", err)
+		}
+		fmt.Printf("⚠️  %v\n", err)
+	}
+	fmt.Println()
+	printStackSetStatus(status)
+	return status
+}
+
+// printStackSetStatus lists every instance, then the failures with their reasons
+func printStackSetStatus(status *aws.StackSetStatus) {
+	fmt.Printf("📦 Stack set: %s\n", status.Name)
+	if status.OperationID != "" {
+		fmt.Printf("Operation: %s %s %s\n", status.OperationID, status.OperationAction, status.OperationStatus)
+		if status.OperationReason != "" {
+			fmt.Printf("  %s\n", status.OperationReason)
+		}
+	}
+	fmt.Println()
+
+	if len(status.Instances) == 0 {
+		fmt.Println("No stack instances.")
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ACCOUNT\tREGION\tSTATUS\tLAST OPERATION")
+	for _, instance := range status.Instances {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", instance.Account, instance.Region, instance.Status, instance.DetailedStatus)
+	}
+	w.Flush()
+
+	summary := status.Summary()
+	fmt.Printf("\n%d succeeded, %d in progress, %d failed of %d\n", summary.Succeeded, summary.Pending, summary.Failed, summary.Total)
+
+	if failed := status.FailedInstances(); len(failed) > 0 {
+		fmt.Printf("\n❌ Failed:\n")
+		for _, instance := range failed {
+			fmt.Printf("  %s %s: %s\n", instance.Account, instance.Region, instance.Reason)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package deploy
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/aws"
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/state"
+)
+
+func createDeleteCommand(configRoot, stackName *string) *cobra.Command {
+	var reportOnly bool
+
+	cmd := &cobra.Command{
+		Use:   "delete",
+		Short: "Delete a research environment",
+		Long: `Delete a research environment after summarizing what it cost and what
+deleting it destroys.
+
+The teardown report shows instance uptime, estimated lifetime cost, and which
+volumes, snapshots, file systems and buckets are deleted or retained. The
+report is saved to the local deployment history when the stack is deleted,
+and the deletion is recorded in the shared state when one is configured.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if *stackName == "" {
+				log.Fatal("Stack name is required. Use --stack flag.")
+			}
+
+			ctx := context.Background()
+			region, _ := cmd.Flags().GetString("region")
+			awsClient, err := aws.NewClient(ctx, region)
+			if err != nil {
+				log.Fatalf("Failed to initialize AWS client: %v", err)
+			}
+
+			report, err := buildTeardownReport(ctx, awsClient, *stackName)
+			if err != nil {
+				if reportOnly {
+					log.Fatalf("Failed to build teardown report: %v", err)
+				}
+				fmt.Printf("⚠️  Could not build teardown report: %v\n\n", err)
+			} else {
+				printTeardownReport(report)
+			}
+
+			if reportOnly {
+				return
+			}
+
+			fmt.Printf("⚠️  Deleting stack: %s\n", *stackName)
+			fmt.Printf("This action cannot be undone. Continue? (y/N): ")
+
+			var response string
+			fmt.Scanln(&response)
+
+			if response != "y" && response != "Y" {
+				fmt.Println("Deletion cancelled.")
+				return
+			}
+
+			if err := aws.NewInfrastructureManager(awsClient).DeleteStack(ctx, *stackName); err != nil {
+				log.Fatalf("Failed to delete stack: %v", err)
+			}
+
+			removeSSHConfigEntry(*stackName)
+			if report != nil {
+				recordClosure(report, region, time.Now().UTC())
+			} else {
+				recordDeletion(*stackName, region)
+			}
+
+			fmt.Printf("🗑️  Stack deletion initiated. Monitor progress with: aws-research-wizard deploy status --stack %s\n", *stackName)
+		},
+	}
+
+	cmd.Flags().BoolVar(&reportOnly, "report-only", false, "Show the teardown report without deleting the stack")
+
+	return cmd
+}
+
+// buildTeardownReport collects a stack's resources and prices its lifetime
+func buildTeardownReport(ctx context.Context, awsClient *aws.Client, stackName string) (*aws.TeardownReport, error) {
+	inventory, err := aws.NewTeardownCollector(awsClient).Collect(ctx, stackName)
+	if err != nil {
+		return nil, err
+	}
+
+	if inventory.InstanceType != "" {
+		pricing, err := aws.NewPricingCalculator(awsClient.Region)
+		if err != nil {
+			return nil, err
+		}
+		if estimate, err := pricing.CalculateCost(inventory.InstanceType); err == nil {
+			inventory.HourlyCost = estimate.HourlyCost
+		}
+	}
+
+	return aws.BuildTeardownReport(inventory, time.Now().UTC()), nil
+}
+
+func printTeardownReport(report *aws.TeardownReport) {
+	fmt.Printf("🧾 Teardown Report: %s (%s)\n", report.StackName, report.Region)
+	fmt.Printf("Lifetime: %s\n", formatDuration(report.Lifetime))
+	if report.InstanceType != "" {
+		fmt.Printf("Instance uptime: %s (%s)\n", formatDuration(report.Uptime), report.InstanceType)
+	}
+	fmt.Printf("Estimated cost: $%.2f (compute $%.2f, storage $%.2f)\n\n",
+		report.EstimatedTotalCost(), report.ComputeCost, report.StorageCost)
+
+	printTeardownItems("🗑️  Will be deleted:", report.Deleted)
+	printTeardownItems("📦 Will be retained:", report.Retained)
+	if report.RetainedMonthlyCost > 0 {
+		fmt.Printf("Retained resources keep costing ~$%.2f/month\n\n", report.RetainedMonthlyCost)
+	}
+
+	for _, warning := range report.Warnings {
+		fmt.Printf("⚠️  %s\n", warning)
+	}
+	if len(report.Warnings) > 0 {
+		fmt.Println()
+	}
+}
+
+func printTeardownItems(title string, items []aws.TeardownItem) {
+	if len(items) == 0 {
+		return
+	}
+
+	fmt.Println(title)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, item := range items {
+		fmt.Fprintf(w, "  %s\t%s\t%s\n", item.Kind, item.ID, item.Detail)
+	}
+	w.Flush()
+	fmt.Println()
+}
+
+// formatDuration renders a duration in days and hours
+func formatDuration(d time.Duration) string {
+	hours := int(d.Hours())
+	if hours < 24 {
+		return fmt.Sprintf("%dh", hours)
+	}
+	return fmt.Sprintf("%dd %dh", hours/24, hours%24)
+}
+
+// recordClosure saves the teardown report to the local deployment history and
+// marks the stack deleted in the shared state
+func recordClosure(report *aws.TeardownReport, region string, closedAt time.Time) {
+	closure := state.Closure{
+		ClosedAt:            closedAt,
+		UptimeHours:         report.Uptime.Hours(),
+		EstimatedCost:       report.EstimatedTotalCost(),
+		RetainedMonthlyCost: report.RetainedMonthlyCost,
+	}
+	for _, item := range report.Deleted {
+		closure.Deleted = append(closure.Deleted, item.Kind+" "+item.ID)
+	}
+	for _, item := range report.Retained {
+		closure.Retained = append(closure.Retained, item.Kind+" "+item.ID)
+	}
+
+	store, err := state.OpenDefaultStore()
+	if err == nil {
+		err = store.RecordClosure(report.StackName, region, closure)
+	}
+	if err != nil {
+		fmt.Printf("⚠️  Could not record deployment state: %v\n", err)
+	}
+	recordSharedDeletion(report.StackName, region, closedAt)
+}
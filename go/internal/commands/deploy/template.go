@@ -0,0 +1,187 @@
+package deploy
+
+import (
+	"fmt"
+
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/aws"
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/aws/templates"
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/config"
+)
+
+const defaultSSHCIDR = templates.DefaultSSHCIDR
+
+// newTemplateOptions returns the defaults for a domain deployment
+func newTemplateOptions(domain *config.DomainPack, instanceType string) templates.Options {
+	opts := templates.DefaultOptions(domain.Name, instanceType)
+
+	// Size the root volume from the matching domain recommendation, or the one
+	// --prefer-arm substituted a Graviton instance type for
+	for _, rec := range domain.AWSInstanceRecommendations {
+		if rec.InstanceType == instanceType {
+			opts.CPUArchitecture = rec.Architecture
+		} else if aws.PreferGraviton(rec.InstanceType) != instanceType {
+			continue
+		}
+		if rec.StorageGB > 0 {
+			opts.VolumeSizeGB = rec.StorageGB
+		}
+		break
+	}
+
+	return opts
+}
+
+// resourceFlags holds the deploy flags that select the architecture and add or
+// adjust template resources, along with the stack's tags and budget
+type resourceFlags struct {
+	Architecture        string
+	SSHCIDR             string
+	VolumeSizeGB        int
+	EncryptVolume       bool
+	InstanceRole        bool
+	NoSSH               bool
+	PlacementGroup      bool
+	ComputeNodes        int
+	ComputeInstanceType string
+	GPU                 bool
+	VPCID               string
+	SubnetID            string
+	IdleStopMinutes     int
+	IdleCPUPercent      float64
+	Compliance          string
+	ElasticIP           bool
+	RetainElasticIP     bool
+	DNSName             string
+	HostedZoneID        string
+
+	CapacityReservationID    string
+	CapacityReservationGroup string
+	// AvailabilityZone is not a flag: it is the capacity reservation's zone,
+	// set once the reservation is checked when no subnet is given
+	AvailabilityZone string
+
+	// PreferARM picks Graviton instance types when the instance type comes from
+	// the domain recommendations
+	PreferARM bool
+	// FollowReplacement deploys a deprecated domain's replacement instead
+	FollowReplacement bool
+
+	// Tags are added to the stack and MonthlyBudget caps its estimated cost
+	Tags          map[string]string
+	MonthlyBudget float64
+}
+
+// apply overlays the deploy flags onto the template options
+func (f resourceFlags) apply(opts *templates.Options) {
+	if f.SSHCIDR != "" {
+		opts.SSHCIDR = f.SSHCIDR
+	}
+	if f.VolumeSizeGB != 0 {
+		opts.VolumeSizeGB = f.VolumeSizeGB
+	}
+	opts.EncryptVolume = opts.EncryptVolume || f.EncryptVolume
+	opts.IAMRole = opts.IAMRole || f.InstanceRole
+	opts.NoSSH = opts.NoSSH || f.NoSSH
+	opts.PlacementGroup = opts.PlacementGroup || f.PlacementGroup
+	if f.ComputeNodes != 0 {
+		opts.ComputeNodes = f.ComputeNodes
+	}
+	if f.ComputeInstanceType != "" {
+		opts.ComputeInstanceType = f.ComputeInstanceType
+	}
+	opts.GPU = opts.GPU || f.GPU
+	if f.IdleStopMinutes != 0 {
+		opts.IdleStopMinutes = f.IdleStopMinutes
+		opts.IdleCPUPercent = f.IdleCPUPercent
+	}
+	opts.ElasticIP = opts.ElasticIP || f.ElasticIP
+	opts.RetainElasticIP = opts.RetainElasticIP || f.RetainElasticIP
+	if f.DNSName != "" {
+		opts.DNSName = f.DNSName
+	}
+	if f.HostedZoneID != "" {
+		opts.HostedZoneID = f.HostedZoneID
+	}
+	if f.CapacityReservationID != "" {
+		opts.CapacityReservationID = f.CapacityReservationID
+	}
+	if f.CapacityReservationGroup != "" {
+		opts.CapacityReservationGroupARN = f.CapacityReservationGroup
+	}
+	if f.AvailabilityZone != "" {
+		opts.AvailabilityZone = f.AvailabilityZone
+	}
+}
+
+// stackParameters returns the parameters the architecture and compliance
+// profile need beyond the template defaults
+func (f resourceFlags) stackParameters(arch templates.Architecture, opts templates.Options) (map[string]string, error) {
+	parameters := make(map[string]string)
+	if !opts.NeedsNetwork(arch) {
+		return parameters, nil
+	}
+	if f.VPCID == "" || f.SubnetID == "" {
+		if !arch.NeedsNetwork() {
+			return nil, fmt.Errorf("the %s compliance profile needs --vpc and --subnet for instances without public IP addresses", opts.Compliance)
+		}
+		return nil, fmt.Errorf("the %s architecture needs --vpc and --subnet", arch)
+	}
+	parameters["VpcId"] = f.VPCID
+	parameters["SubnetId"] = f.SubnetID
+	return parameters, nil
+}
+
+// resolveArchitecture picks the architecture from the flag, then the domain pack
+func resolveArchitecture(domain *config.DomainPack, flag string) (templates.Architecture, error) {
+	if flag != "" {
+		return templates.ParseArchitecture(flag)
+	}
+	arch, err := templates.ParseArchitecture(domain.AWSIntegration.Architecture)
+	if err != nil {
+		return "", fmt.Errorf("domain %s: %w", domain.Name, err)
+	}
+	return arch, nil
+}
+
+// resolveCompliance picks the compliance profile from the flag, then the domain pack
+func resolveCompliance(domain *config.DomainPack, flag string) (templates.ComplianceProfile, error) {
+	if flag != "" {
+		return templates.ParseComplianceProfile(flag)
+	}
+	profile, err := templates.ParseComplianceProfile(domain.AWSIntegration.ComplianceProfile)
+	if err != nil {
+		return "", fmt.Errorf("domain %s: %w", domain.Name, err)
+	}
+	return profile, nil
+}
+
+// templateOptions resolves the architecture and template options for a domain deployment
+func templateOptions(domain *config.DomainPack, instanceType string, resources resourceFlags) (templates.Architecture, templates.Options, error) {
+	arch, err := resolveArchitecture(domain, resources.Architecture)
+	if err != nil {
+		return "", templates.Options{}, err
+	}
+	compliance, err := resolveCompliance(domain, resources.Compliance)
+	if err != nil {
+		return "", templates.Options{}, err
+	}
+
+	opts := newTemplateOptions(domain, instanceType)
+	opts.Compliance = compliance
+	resources.apply(&opts)
+	return arch, opts, nil
+}
+
+// generateCloudFormationTemplate renders the template for a domain deployment
+func generateCloudFormationTemplate(domain *config.DomainPack, instanceType string, resources resourceFlags) (string, error) {
+	arch, opts, err := templateOptions(domain, instanceType, resources)
+	if err != nil {
+		return "", err
+	}
+
+	template, err := templates.Build(arch, opts)
+	if err != nil {
+		return "", err
+	}
+	return template.JSON()
+}
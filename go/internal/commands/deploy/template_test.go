@@ -0,0 +1,436 @@
+package deploy
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/aws/templates"
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/config"
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/export"
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/intelligence"
+)
+
+// parsedTemplate is the generic shape used to inspect rendered templates
+type parsedTemplate struct {
+	AWSTemplateFormatVersion string
+	Description              string
+	Parameters               map[string]map[string]interface{}
+	Resources                map[string]struct {
+		Type       string
+		Properties map[string]interface{}
+	}
+	Outputs map[string]map[string]interface{}
+}
+
+func testDomain(name string) *config.DomainPack {
+	return &config.DomainPack{
+		Name:        name,
+		Description: "Test domain",
+		AWSInstanceRecommendations: map[string]config.InstanceRecommendation{
+			"standard": {InstanceType: "r6i.4xlarge", StorageGB: 500},
+		},
+	}
+}
+
+func renderTemplate(t *testing.T, domain *config.DomainPack, instanceType string, resources resourceFlags) parsedTemplate {
+	t.Helper()
+
+	body, err := generateCloudFormationTemplate(domain, instanceType, resources)
+	if err != nil {
+		t.Fatalf("generateCloudFormationTemplate failed: %v", err)
+	}
+
+	var parsed parsedTemplate
+	if err := json.Unmarshal([]byte(body), &parsed); err != nil {
+		t.Fatalf("Template is not valid JSON: %v\n%s", err, body)
+	}
+	return parsed
+}
+
+func TestGenerateCloudFormationTemplateDefaults(t *testing.T) {
+	parsed := renderTemplate(t, testDomain("genomics"), "r6i.4xlarge", resourceFlags{})
+
+	if parsed.AWSTemplateFormatVersion != "2010-09-09" {
+		t.Errorf("Unexpected format version %q", parsed.AWSTemplateFormatVersion)
+	}
+	if parsed.Parameters["InstanceType"]["Default"] != "r6i.4xlarge" {
+		t.Errorf("Unexpected InstanceType default: %v", parsed.Parameters["InstanceType"])
+	}
+
+	if len(parsed.Resources) != 2 {
+		t.Errorf("Expected security group and instance only, got %d resources", len(parsed.Resources))
+	}
+
+	sg := parsed.Resources[templates.SecurityGroupLogicalID]
+	if sg.Type != "AWS::EC2::SecurityGroup" {
+		t.Errorf("Unexpected security group type %q", sg.Type)
+	}
+	ingress := sg.Properties["SecurityGroupIngress"].([]interface{})
+	if len(ingress) != 2 {
+		t.Fatalf("Expected 2 ingress rules, got %d", len(ingress))
+	}
+	if port := ingress[1].(map[string]interface{})["FromPort"]; port != float64(templates.JupyterPort) {
+		t.Errorf("Expected Jupyter port rule, got %v", port)
+	}
+
+	inst := parsed.Resources[templates.InstanceLogicalID]
+	if inst.Type != "AWS::EC2::Instance" {
+		t.Errorf("Unexpected instance type %q", inst.Type)
+	}
+	if _, exists := inst.Properties["IamInstanceProfile"]; exists {
+		t.Error("Instance profile should not be attached by default")
+	}
+	mappings := inst.Properties["BlockDeviceMappings"].([]interface{})
+	ebs := mappings[0].(map[string]interface{})["Ebs"].(map[string]interface{})
+	if ebs["VolumeSize"] != float64(500) {
+		t.Errorf("Expected volume sized from recommendation, got %v", ebs["VolumeSize"])
+	}
+	if ebs["Encrypted"] != false {
+		t.Errorf("Expected unencrypted volume by default, got %v", ebs["Encrypted"])
+	}
+
+	for _, key := range []string{"InstanceId", "PublicIP", "PrivateIP", "SecurityGroupId", "SSHCommand"} {
+		if _, exists := parsed.Outputs[key]; !exists {
+			t.Errorf("Missing output %s", key)
+		}
+	}
+}
+
+func TestGenerateCloudFormationTemplateConditionalResources(t *testing.T) {
+	resources := resourceFlags{
+		SSHCIDR:        "198.51.100.0/24",
+		EncryptVolume:  true,
+		InstanceRole:   true,
+		PlacementGroup: true,
+	}
+	parsed := renderTemplate(t, testDomain("climate"), "c6i.8xlarge", resources)
+
+	if parsed.Resources[templates.InstanceRoleLogicalID].Type != "AWS::IAM::Role" {
+		t.Error("Expected IAM role resource")
+	}
+	if parsed.Resources[templates.InstanceProfileLogicalID].Type != "AWS::IAM::InstanceProfile" {
+		t.Error("Expected instance profile resource")
+	}
+	if parsed.Resources[templates.PlacementGroupLogicalID].Properties["Strategy"] != "cluster" {
+		t.Error("Expected cluster placement group")
+	}
+
+	inst := parsed.Resources[templates.InstanceLogicalID].Properties
+	profile := inst["IamInstanceProfile"].(map[string]interface{})
+	if profile["Ref"] != templates.InstanceProfileLogicalID {
+		t.Errorf("Instance not attached to profile: %v", profile)
+	}
+	if inst["PlacementGroupName"].(map[string]interface{})["Ref"] != templates.PlacementGroupLogicalID {
+		t.Errorf("Instance not in placement group: %v", inst["PlacementGroupName"])
+	}
+
+	ebs := inst["BlockDeviceMappings"].([]interface{})[0].(map[string]interface{})["Ebs"].(map[string]interface{})
+	if ebs["Encrypted"] != true {
+		t.Error("Expected encrypted volume")
+	}
+	if ebs["VolumeSize"] != float64(templates.DefaultVolumeSizeGB) {
+		t.Errorf("Expected default volume size without a matching recommendation, got %v", ebs["VolumeSize"])
+	}
+
+	for _, rule := range parsed.Resources[templates.SecurityGroupLogicalID].Properties["SecurityGroupIngress"].([]interface{}) {
+		if cidr := rule.(map[string]interface{})["CidrIp"]; cidr != "198.51.100.0/24" {
+			t.Errorf("Expected restricted CIDR, got %v", cidr)
+		}
+	}
+}
+
+func TestGenerateCloudFormationTemplateNoSSH(t *testing.T) {
+	parsed := renderTemplate(t, testDomain("genomics"), "r6i.4xlarge", resourceFlags{NoSSH: true})
+
+	if _, exists := parsed.Parameters["KeyName"]; exists {
+		t.Error("KeyName parameter should be omitted with --no-ssh")
+	}
+	if _, exists := parsed.Resources[templates.SecurityGroupLogicalID].Properties["SecurityGroupIngress"]; exists {
+		t.Error("Security group should have no ingress with --no-ssh")
+	}
+	if parsed.Resources[templates.InstanceRoleLogicalID].Type != "AWS::IAM::Role" {
+		t.Error("--no-ssh should attach an instance role for Session Manager")
+	}
+	if parsed.Outputs["Connectivity"]["Value"] != "ssm" {
+		t.Errorf("Unexpected Connectivity output %v", parsed.Outputs["Connectivity"])
+	}
+}
+
+func TestGenerateCloudFormationTemplateElasticIP(t *testing.T) {
+	resources := resourceFlags{
+		SSHCIDR:         defaultSSHCIDR,
+		ElasticIP:       true,
+		RetainElasticIP: true,
+		DNSName:         "jupyter.lab.example.com",
+		HostedZoneID:    "Z0123456789ABC",
+	}
+	parsed := renderTemplate(t, testDomain("genomics"), "r6i.4xlarge", resources)
+
+	if parsed.Resources[templates.ElasticIPLogicalID].Type != "AWS::EC2::EIP" {
+		t.Error("Expected an Elastic IP address with --elastic-ip")
+	}
+	if parsed.Resources[templates.DNSRecordLogicalID].Properties["HostedZoneId"] != "Z0123456789ABC" {
+		t.Errorf("Unexpected DNS record %v", parsed.Resources[templates.DNSRecordLogicalID])
+	}
+	if parsed.Outputs["DNSName"]["Value"] != "jupyter.lab.example.com" {
+		t.Errorf("Unexpected DNSName output %v", parsed.Outputs["DNSName"])
+	}
+
+	resources.ElasticIP = false
+	if _, err := generateCloudFormationTemplate(testDomain("genomics"), "r6i.4xlarge", resources); err == nil {
+		t.Error("Expected --dns-name without --elastic-ip to be rejected")
+	}
+}
+
+func TestDeployParameters(t *testing.T) {
+	network := map[string]string{"VpcId": "vpc-1", "SubnetId": "subnet-1"}
+
+	parameters := resourceFlags{}.deployParameters("genomics", "r6i.4xlarge", network)
+	if _, exists := parameters["KeyName"]; !exists {
+		t.Errorf("Expected KeyName parameter with SSH, got %v", parameters)
+	}
+	if parameters["VpcId"] != "vpc-1" || parameters["InstanceType"] != "r6i.4xlarge" {
+		t.Errorf("Unexpected parameters %v", parameters)
+	}
+
+	parameters = resourceFlags{NoSSH: true}.deployParameters("genomics", "r6i.4xlarge", network)
+	if _, exists := parameters["KeyName"]; exists {
+		t.Errorf("KeyName parameter should be omitted with --no-ssh, got %v", parameters)
+	}
+}
+
+func TestApplyResourcePlan(t *testing.T) {
+	opts := newTemplateOptions(testDomain("genomics"), "")
+
+	export.ApplyResourcePlan(&opts, &intelligence.ResourcePlan{
+		RecommendedInstance: "r6i.8xlarge",
+		StorageConfiguration: intelligence.StorageConfiguration{
+			PrimaryStorage: intelligence.StorageType{SizeGB: 2000},
+		},
+		NetworkConfiguration:  intelligence.NetworkConfiguration{PlacementGroup: true},
+		SecurityConfiguration: intelligence.SecurityConfiguration{EncryptionAtRest: true, IAMRoles: []string{"research"}},
+	})
+
+	if opts.InstanceType != "r6i.8xlarge" {
+		t.Errorf("Expected plan instance type, got %s", opts.InstanceType)
+	}
+	if opts.VolumeSizeGB != 2000 {
+		t.Errorf("Expected plan storage size, got %d", opts.VolumeSizeGB)
+	}
+	if !opts.EncryptVolume || !opts.PlacementGroup || !opts.IAMRole {
+		t.Errorf("Expected plan to enable encryption, placement group and IAM role: %+v", opts)
+	}
+
+	export.ApplyResourcePlan(&opts, nil)
+	if opts.InstanceType != "r6i.8xlarge" {
+		t.Error("Nil plan should leave options unchanged")
+	}
+}
+
+func FuzzGenerateCloudFormationTemplate(f *testing.F) {
+	seeds := []string{
+		"genomics",
+		`quote"in"name`,
+		`back\slash`,
+		"new\nline\ttab",
+		"}{\"Resources\":null}",
+		"${AWS::StackName}",
+		"ünïcødé 基因组",
+		"\x00\x1f ",
+		string([]byte{0xff, 0xfe}),
+	}
+	for _, seed := range seeds {
+		f.Add(seed, seed)
+	}
+
+	f.Fuzz(func(t *testing.T, name, description string) {
+		domain := testDomain(name)
+		domain.Description = description
+
+		body, err := generateCloudFormationTemplate(domain, "r6i.4xlarge", resourceFlags{})
+		if err != nil {
+			t.Fatalf("generateCloudFormationTemplate failed: %v", err)
+		}
+
+		var parsed parsedTemplate
+		if err := json.Unmarshal([]byte(body), &parsed); err != nil {
+			t.Fatalf("Invalid JSON for name %q: %v", name, err)
+		}
+		if len(parsed.Resources) != 2 {
+			t.Fatalf("Name %q altered template structure: %d resources", name, len(parsed.Resources))
+		}
+	})
+}
+
+func TestResolveArchitecture(t *testing.T) {
+	domain := testDomain("climate")
+	if arch, err := resolveArchitecture(domain, ""); err != nil || arch != templates.ArchitectureSingle {
+		t.Errorf("Expected single by default, got %q (%v)", arch, err)
+	}
+
+	domain.AWSIntegration.Architecture = "head-compute"
+	if arch, err := resolveArchitecture(domain, ""); err != nil || arch != templates.ArchitectureHeadCompute {
+		t.Errorf("Expected the domain pack architecture, got %q (%v)", arch, err)
+	}
+	if arch, err := resolveArchitecture(domain, "container-host"); err != nil || arch != templates.ArchitectureContainerHost {
+		t.Errorf("Expected the flag to override the domain pack, got %q (%v)", arch, err)
+	}
+
+	domain.AWSIntegration.Architecture = "mainframe"
+	if _, err := resolveArchitecture(domain, ""); err == nil {
+		t.Error("Expected an error for an unknown domain pack architecture")
+	}
+}
+
+func TestGenerateCloudFormationTemplateArchitectures(t *testing.T) {
+	domain := testDomain("climate")
+	domain.AWSIntegration.Architecture = "head-compute"
+
+	parsed := renderTemplate(t, domain, "r6i.4xlarge", resourceFlags{ComputeNodes: 3})
+	if parsed.Resources[templates.SharedFileSystemLogicalID].Type != "AWS::EFS::FileSystem" {
+		t.Error("Expected the domain pack to select head-compute")
+	}
+	if parsed.Resources[templates.ComputeNodeLogicalID(3)].Type != "AWS::EC2::Instance" {
+		t.Error("Expected --compute-nodes to size the cluster")
+	}
+
+	parsed = renderTemplate(t, domain, "g5.xlarge", resourceFlags{Architecture: "container-host", GPU: true})
+	if _, exists := parsed.Outputs["ContainerRegistry"]; !exists {
+		t.Error("Expected --architecture to select container-host")
+	}
+
+	if _, err := generateCloudFormationTemplate(domain, "r6i.4xlarge", resourceFlags{Architecture: "container-host", GPU: true}); err == nil {
+		t.Error("Expected GPU container host on a CPU instance to be rejected")
+	}
+}
+
+func TestStackParameters(t *testing.T) {
+	single := templates.DefaultOptions("genomics", "r6i.4xlarge")
+	parameters, err := resourceFlags{}.stackParameters(templates.ArchitectureSingle, single)
+	if err != nil || len(parameters) != 0 {
+		t.Errorf("Expected no extra parameters for single, got %v (%v)", parameters, err)
+	}
+
+	if _, err := (resourceFlags{VPCID: "vpc-1"}).stackParameters(templates.ArchitectureHeadCompute, single); err == nil {
+		t.Error("Expected head-compute without a subnet to be rejected")
+	}
+
+	parameters, err = resourceFlags{VPCID: "vpc-1", SubnetID: "subnet-1"}.stackParameters(templates.ArchitectureHeadCompute, single)
+	if err != nil || parameters["VpcId"] != "vpc-1" || parameters["SubnetId"] != "subnet-1" {
+		t.Errorf("Unexpected network parameters %v (%v)", parameters, err)
+	}
+
+	// Compliance profiles place even a single instance in a private subnet
+	regulated := single
+	regulated.Compliance = templates.ComplianceHIPAA
+	if _, err := (resourceFlags{}).stackParameters(templates.ArchitectureSingle, regulated); err == nil || !strings.Contains(err.Error(), "hipaa compliance profile") {
+		t.Errorf("Expected hipaa without a network to be rejected, got %v", err)
+	}
+	parameters, err = resourceFlags{VPCID: "vpc-1", SubnetID: "subnet-1"}.stackParameters(templates.ArchitectureSingle, regulated)
+	if err != nil || parameters["SubnetId"] != "subnet-1" {
+		t.Errorf("Unexpected network parameters %v (%v)", parameters, err)
+	}
+}
+
+func TestResolveCompliance(t *testing.T) {
+	domain := testDomain("clinical")
+	if profile, err := resolveCompliance(domain, ""); err != nil || profile != templates.ComplianceNone {
+		t.Errorf("Expected none by default, got %q (%v)", profile, err)
+	}
+
+	domain.AWSIntegration.ComplianceProfile = "hipaa"
+	if profile, err := resolveCompliance(domain, ""); err != nil || profile != templates.ComplianceHIPAA {
+		t.Errorf("Expected the domain pack profile, got %q (%v)", profile, err)
+	}
+	if profile, err := resolveCompliance(domain, "nist-800-171"); err != nil || profile != templates.ComplianceCUI {
+		t.Errorf("Expected the flag to override the domain pack, got %q (%v)", profile, err)
+	}
+
+	domain.AWSIntegration.ComplianceProfile = "sox"
+	if _, err := resolveCompliance(domain, ""); err == nil {
+		t.Error("Expected an error for an unknown domain pack compliance profile")
+	}
+}
+
+func TestGenerateCloudFormationTemplateCompliance(t *testing.T) {
+	domain := testDomain("clinical")
+	domain.AWSIntegration.ComplianceProfile = "hipaa"
+
+	// The default SSH CIDR opens Jupyter to the internet, which hipaa forbids
+	if _, err := generateCloudFormationTemplate(domain, "r6i.4xlarge", resourceFlags{SSHCIDR: defaultSSHCIDR}); err == nil || !strings.Contains(err.Error(), "Jupyter") {
+		t.Errorf("Expected a public Jupyter port to be rejected, got %v", err)
+	}
+
+	parsed := renderTemplate(t, domain, "r6i.4xlarge", resourceFlags{SSHCIDR: defaultSSHCIDR, NoSSH: true})
+	if parsed.Resources[templates.TrailLogicalID].Type != "AWS::CloudTrail::Trail" {
+		t.Error("Expected the domain pack profile to add a CloudTrail trail")
+	}
+	if _, exists := parsed.Parameters["Tenancy"]; !exists {
+		t.Error("Expected a Tenancy parameter")
+	}
+
+	parsed = renderTemplate(t, domain, "r6i.4xlarge", resourceFlags{SSHCIDR: defaultSSHCIDR, Compliance: "none"})
+	if _, exists := parsed.Resources[templates.TrailLogicalID]; exists {
+		t.Error("Expected --compliance none to override the domain pack")
+	}
+}
+
+func TestRecommendedInstanceTypePreferARM(t *testing.T) {
+	domain := testDomain("genomics")
+	if got := recommendedInstanceType(domain, false); got != "r6i.4xlarge" {
+		t.Errorf("recommendedInstanceType() = %s", got)
+	}
+
+	// Without an arm64 recommendation the Graviton equivalent is used, sized
+	// like the recommendation it replaces
+	if got := recommendedInstanceType(domain, true); got != "r7g.4xlarge" {
+		t.Errorf("recommendedInstanceType(preferARM) = %s, want r7g.4xlarge", got)
+	}
+	parsed := renderTemplate(t, domain, "r7g.4xlarge", resourceFlags{PreferARM: true})
+	inst := parsed.Resources[templates.InstanceLogicalID]
+	if inst.Properties["ImageId"] != "{{resolve:ssm:"+templates.DefaultARM64ImageParameter+"}}" {
+		t.Errorf("Expected the arm64 image, got %v", inst.Properties["ImageId"])
+	}
+	ebs := inst.Properties["BlockDeviceMappings"].([]interface{})[0].(map[string]interface{})["Ebs"].(map[string]interface{})
+	if ebs["VolumeSize"] != float64(500) {
+		t.Errorf("Expected volume sized from the replaced recommendation, got %v", ebs["VolumeSize"])
+	}
+
+	// A declared arm64 recommendation is preferred as is
+	domain.AWSInstanceRecommendations["graviton"] = config.InstanceRecommendation{InstanceType: "c7g.8xlarge", Architecture: "arm64"}
+	if got := recommendedInstanceType(domain, true); got != "c7g.8xlarge" {
+		t.Errorf("recommendedInstanceType(preferARM) = %s, want c7g.8xlarge", got)
+	}
+}
+
+func TestRecommendedInstanceTypeStableOrder(t *testing.T) {
+	domain := &config.DomainPack{
+		AWSInstanceRecommendations: map[string]config.InstanceRecommendation{
+			"standard": {InstanceType: "m6i.2xlarge"},
+			"large":    {InstanceType: "r6i.8xlarge"},
+			"compute":  {InstanceType: "c6i.4xlarge"},
+			"empty":    {},
+		},
+	}
+
+	// Map iteration order varies, so repeat to catch a choice that depends on it
+	for i := 0; i < 50; i++ {
+		if got := recommendedInstanceType(domain, false); got != "c6i.4xlarge" {
+			t.Fatalf("recommendedInstanceType() = %s, want c6i.4xlarge", got)
+		}
+		if got := recommendedInstanceType(domain, true); got != "c7g.4xlarge" {
+			t.Fatalf("recommendedInstanceType(preferARM) = %s, want c7g.4xlarge", got)
+		}
+	}
+}
+
+func TestTemplateOptionsRecommendationArchitecture(t *testing.T) {
+	domain := testDomain("genomics")
+	domain.AWSInstanceRecommendations["standard"] = config.InstanceRecommendation{InstanceType: "r6i.4xlarge", Architecture: "arm64"}
+
+	// The recommendation's architecture is checked against its instance type
+	if _, err := generateCloudFormationTemplate(domain, "r6i.4xlarge", resourceFlags{}); err == nil {
+		t.Error("Expected an x86_64 instance type declared arm64 to be rejected")
+	}
+}
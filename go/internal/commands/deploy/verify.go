@@ -0,0 +1,174 @@
+package deploy
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/aws"
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/config"
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/state"
+)
+
+// agentRegistrationWait is how long checks after a deployment wait for the new
+// instance's SSM agent to come online
+const agentRegistrationWait = 10 * time.Minute
+
+func createVerifyCommand(configRoot, stackName, domainName *string) *cobra.Command {
+	return &cobra.Command{
+		Use:   "verify",
+		Short: "Run the domain pack's smoke tests on a deployed instance",
+		Long: `Run the validation checks declared in the stack's domain pack on its
+instance through SSM Run Command, and report each check's result.
+
+Checks wait for cloud-init to finish, then run in a login shell. The command
+fails when a check not marked optional fails. Results are appended to the
+deployment's record in the local state file.
+
+The instance needs the SSM agent and an instance role (--instance-role).
+
+Examples:
+  aws-research-wizard deploy verify --stack research-wizard-genomics`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if *stackName == "" {
+				log.Fatal("Stack name is required. Use --stack flag.")
+			}
+
+			ctx := context.Background()
+			if *configRoot == "" {
+				*configRoot = findConfigRoot()
+			}
+			region, _ := cmd.Flags().GetString("region")
+			awsClient, err := aws.NewClient(ctx, region)
+			if err != nil {
+				log.Fatalf("Failed to initialize AWS client: %v", err)
+			}
+
+			stackInfo, err := aws.NewInfrastructureManager(awsClient).GetStackInfo(ctx, *stackName)
+			if err != nil {
+				log.Fatalf("Failed to get stack info: %v", err)
+			}
+
+			name := *domainName
+			if name == "" {
+				name = stackInfo.Parameters["DomainName"]
+			}
+			if name == "" {
+				log.Fatalf("Stack %s has no DomainName parameter; use --domain", *stackName)
+			}
+			domains, err := config.NewConfigLoader(*configRoot).LoadAllDomains()
+			if err != nil {
+				log.Fatalf("Failed to load domains: %v", err)
+			}
+			domain, exists := domains[name]
+			if !exists {
+				log.Fatalf("Domain '%s' not found", name)
+			}
+
+			if err := verifyDeployment(ctx, awsClient, stackInfo, domain, 0); err != nil {
+				log.Fatalf("Verification failed: %v", err)
+			}
+		},
+	}
+}
+
+// verifyDeployment runs a domain's validation checks on a stack's instance,
+// prints the results and records them in the deployment's history. It fails
+// when a critical check fails.
+func verifyDeployment(ctx context.Context, awsClient *aws.Client, stackInfo *aws.StackInfo, domain *config.DomainPack, agentWait time.Duration) error {
+	if len(domain.Validation) == 0 {
+		fmt.Printf("ℹ️  Domain %s declares no validation checks\n", domain.Name)
+		return nil
+	}
+
+	instanceID, err := stackInfo.InstanceID()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("🧪 Running %d validation check(s) on %s...\n", len(domain.Validation), instanceID)
+	ranAt := time.Now().UTC()
+	results, err := aws.NewCommandRunner(awsClient).RunChecks(ctx, instanceID, instanceChecks(domain.Validation), agentWait)
+	if err != nil {
+		return err
+	}
+
+	printCheckResults(results)
+	recordValidation(stackInfo.StackName, awsClient.Region, validationRecord(ranAt, instanceID, results))
+
+	passed, failed := results.Counts()
+	if !results.Passed() {
+		return fmt.Errorf("%d of %d check(s) failed", failed, passed+failed)
+	}
+	fmt.Printf("✅ %d of %d check(s) passed\n", passed, passed+failed)
+	return nil
+}
+
+// instanceChecks converts a domain's validation checks for running over SSM
+func instanceChecks(checks []config.ValidationCheck) []aws.InstanceCheck {
+	converted := make([]aws.InstanceCheck, 0, len(checks))
+	for _, check := range checks {
+		converted = append(converted, aws.InstanceCheck{
+			Name:           check.DisplayName(),
+			Command:        check.Command,
+			ExpectExitCode: check.ExpectExitCode,
+			ExpectOutput:   check.ExpectOutput,
+			Timeout:        check.Timeout(),
+			Critical:       !check.Optional,
+		})
+	}
+	return converted
+}
+
+// validationRecord summarizes check results for the deployment history
+func validationRecord(ranAt time.Time, instanceID string, results aws.CheckResults) state.Validation {
+	validation := state.Validation{
+		RanAt:      ranAt,
+		InstanceID: instanceID,
+		Passed:     results.Passed(),
+		Checks:     make([]state.CheckResult, 0, len(results)),
+	}
+	for _, result := range results {
+		validation.Checks = append(validation.Checks, state.CheckResult{
+			Name:     result.Name,
+			Passed:   result.Passed,
+			Critical: result.Critical,
+			ExitCode: result.ExitCode,
+			Detail:   result.Detail,
+		})
+	}
+	return validation
+}
+
+func printCheckResults(results aws.CheckResults) {
+	for _, result := range results {
+		switch {
+		case result.Passed:
+			fmt.Printf("  ✅ %s\n", result.Name)
+		case result.Critical:
+			fmt.Printf("  ❌ %s: %s\n", result.Name, result.Detail)
+		default:
+			fmt.Printf("  ⚠️  %s (optional): %s\n", result.Name, result.Detail)
+		}
+		if !result.Passed && strings.TrimSpace(result.Output) != "" {
+			for _, line := range strings.Split(result.Output, "\n") {
+				fmt.Printf("       %s\n", line)
+			}
+		}
+	}
+}
+
+// recordValidation appends check results to a deployment in the local state file
+func recordValidation(stackName, region string, validation state.Validation) {
+	store, err := state.OpenDefaultStore()
+	if err == nil {
+		err = store.AddValidation(stackName, region, validation)
+	}
+	if err != nil {
+		fmt.Printf("⚠️  Could not record validation results: %v\n", err)
+	}
+}
@@ -0,0 +1,47 @@
+package deploy
+
+import (
+	"testing"
+	"time"
+
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/aws"
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/config"
+)
+
+func TestInstanceChecks(t *testing.T) {
+	checks := instanceChecks([]config.ValidationCheck{
+		{Name: "nextflow", Command: "nextflow -version", ExpectOutput: "nextflow", TimeoutSeconds: 300},
+		{Command: `python3 -c "import torch"`, ExpectExitCode: 0, Optional: true},
+	})
+
+	want := []aws.InstanceCheck{
+		{Name: "nextflow", Command: "nextflow -version", ExpectOutput: "nextflow", Timeout: 5 * time.Minute, Critical: true},
+		{Name: `python3 -c "import torch"`, Command: `python3 -c "import torch"`, Timeout: config.DefaultValidationTimeoutSeconds * time.Second},
+	}
+	for i := range want {
+		if checks[i] != want[i] {
+			t.Errorf("check %d = %+v, want %+v", i, checks[i], want[i])
+		}
+	}
+}
+
+func TestValidationRecord(t *testing.T) {
+	ranAt := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	results := aws.CheckResults{
+		{Name: "git", Critical: true, Passed: true, Output: "git version 2.40.1"},
+		{Name: "nextflow", ExitCode: 127, Detail: "exit code 127, expected 0"},
+	}
+
+	record := validationRecord(ranAt, "i-1", results)
+	if !record.Passed || record.InstanceID != "i-1" || !record.RanAt.Equal(ranAt) || len(record.Checks) != 2 {
+		t.Fatalf("record = %+v", record)
+	}
+	if check := record.Checks[1]; check.Passed || check.Critical || check.ExitCode != 127 || check.Detail != "exit code 127, expected 0" {
+		t.Errorf("optional check = %+v", check)
+	}
+
+	results[0].Passed = false
+	if validationRecord(ranAt, "i-1", results).Passed {
+		t.Error("a failed critical check should fail the record")
+	}
+}
@@ -0,0 +1,223 @@
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/aws"
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/aws/templates"
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/config"
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/doctor"
+)
+
+func init() {
+	doctor.Register(
+		doctor.NewCheck(doctor.CategoryAWS, "credentials", checkCredentials),
+		doctor.NewCheck(doctor.CategoryAWS, "region", checkRegion),
+		doctor.NewCheck(doctor.CategoryAWS, "key pairs", checkKeyPairs),
+		doctor.NewCheck(doctor.CategoryAWS, "pricing API", checkPricing),
+		doctor.NewCheck(doctor.CategoryConfig, "configs directory", checkConfigRoot),
+		doctor.NewCheck(doctor.CategoryConfig, "domain packs", checkDomains),
+		doctor.Tool("docker", "docker", "building and testing container-host images locally", "install Docker from https://docs.docker.com/get-docker/", false),
+		doctor.Tool("spack", "spack", "building domain software stacks locally", "git clone https://github.com/spack/spack.git and source share/spack/setup-env.sh", false),
+		doctor.Tool("session-manager-plugin", "session-manager-plugin", "deploy ssh to --no-ssh environments", "install the Session Manager plugin from https://docs.aws.amazon.com/systems-manager/latest/userguide/session-manager-working-with-install-plugin.html", false),
+		doctor.Tool("globus", "globus", "Globus transfers from institutional endpoints", "pip install globus-cli", false),
+	)
+}
+
+// awsSession creates the AWS client and validates credentials once per region,
+// so every AWS check reports against the same result
+type awsSession struct {
+	once   sync.Once
+	client *aws.Client
+	err    error
+}
+
+var (
+	sessionsMu sync.Mutex
+	sessions   = make(map[string]*awsSession)
+)
+
+func sessionFor(ctx context.Context, region string) (*aws.Client, error) {
+	sessionsMu.Lock()
+	session, exists := sessions[region]
+	if !exists {
+		session = &awsSession{}
+		sessions[region] = session
+	}
+	sessionsMu.Unlock()
+
+	session.once.Do(func() {
+		session.client, session.err = aws.NewClient(ctx, region)
+		if session.err == nil {
+			session.err = session.client.ValidateCredentials(ctx)
+		}
+	})
+	return session.client, session.err
+}
+
+// skippedWithoutCredentials is the result for AWS checks that need working credentials
+func skippedWithoutCredentials() doctor.Result {
+	return doctor.Warn("", "skipped: AWS credentials unavailable")
+}
+
+func checkCredentials(ctx context.Context, env doctor.Environment) doctor.Result {
+	if _, err := sessionFor(ctx, env.Region); err != nil {
+		return doctor.Fail("run 'aws configure', or set AWS_PROFILE or AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY", "%v", err)
+	}
+	return doctor.Pass("valid")
+}
+
+func checkRegion(ctx context.Context, env doctor.Environment) doctor.Result {
+	client, err := sessionFor(ctx, env.Region)
+	if err != nil {
+		return skippedWithoutCredentials()
+	}
+	zones, err := client.GetAvailabilityZones(ctx)
+	if err != nil {
+		return doctor.Fail("check the --region flag and that the region is enabled for the account", "%s: %v", env.Region, err)
+	}
+	return doctor.Pass("%s reachable (%d availability zones)", env.Region, len(zones))
+}
+
+func checkKeyPairs(ctx context.Context, env doctor.Environment) doctor.Result {
+	client, err := sessionFor(ctx, env.Region)
+	if err != nil {
+		return skippedWithoutCredentials()
+	}
+	names, err := client.ListKeyPairNames(ctx)
+	if err != nil {
+		return doctor.Warn("grant ec2:DescribeKeyPairs", "%v", err)
+	}
+	if len(names) == 0 {
+		return doctor.Warn(
+			fmt.Sprintf("aws ec2 create-key-pair --region %s --key-name research --query KeyMaterial --output text > ~/.ssh/research.pem", env.Region),
+			"no EC2 key pairs in %s; deployments need one for SSH", env.Region)
+	}
+	sort.Strings(names)
+	return doctor.Pass("%d found (%s)", len(names), strings.Join(names, ", "))
+}
+
+func checkPricing(ctx context.Context, env doctor.Environment) doctor.Result {
+	client, err := sessionFor(ctx, env.Region)
+	if err != nil {
+		return skippedWithoutCredentials()
+	}
+	if err := client.ValidatePricingAccess(ctx); err != nil {
+		return doctor.Warn("grant pricing:DescribeServices and pricing:GetProducts; cost figures fall back to built-in estimates", "%v", err)
+	}
+	return doctor.Pass("reachable")
+}
+
+// discoverConfigRoot looks for a configs directory in dir and its parents
+func discoverConfigRoot(dir string) (string, bool) {
+	for {
+		if info, err := os.Stat(filepath.Join(dir, "configs")); err == nil && info.IsDir() {
+			return dir, true
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
+
+// configRoot resolves the --config-root flag or discovers the configs directory
+func configRoot(env doctor.Environment) (string, error) {
+	if env.ConfigRoot != "" {
+		if info, err := os.Stat(filepath.Join(env.ConfigRoot, "configs")); err != nil || !info.IsDir() {
+			return "", fmt.Errorf("no configs directory under %s", env.ConfigRoot)
+		}
+		return env.ConfigRoot, nil
+	}
+
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", fmt.Errorf("failed to get current directory: %w", err)
+	}
+	root, found := discoverConfigRoot(dir)
+	if !found {
+		return "", fmt.Errorf("no configs directory in %s or its parents", dir)
+	}
+	return root, nil
+}
+
+func checkConfigRoot(ctx context.Context, env doctor.Environment) doctor.Result {
+	root, err := configRoot(env)
+	if err != nil {
+		return doctor.Fail("run from the repository checkout or pass --config-root", "%v", err)
+	}
+	return doctor.Pass("%s", filepath.Join(root, "configs"))
+}
+
+func checkDomains(ctx context.Context, env doctor.Environment) doctor.Result {
+	root, err := configRoot(env)
+	if err != nil {
+		return doctor.Warn("", "skipped: configs directory not found")
+	}
+
+	domains, err := config.NewConfigLoader(root).LoadAllDomains()
+	if err != nil {
+		return doctor.Fail("fix the YAML error in the named domain pack", "%v", err)
+	}
+	if len(domains) == 0 {
+		return doctor.Fail("add domain packs under configs/domains", "no domain packs found")
+	}
+
+	return summarizeDomainProblems(domains)
+}
+
+// domainProblems lists what keeps a domain pack from deploying
+func domainProblems(domain *config.DomainPack) []string {
+	var problems []string
+	if domain.Name == "" {
+		problems = append(problems, "missing name")
+	}
+	if len(domain.AWSInstanceRecommendations) == 0 {
+		problems = append(problems, "no instance recommendations")
+	}
+	for key, rec := range domain.AWSInstanceRecommendations {
+		if rec.InstanceType == "" {
+			problems = append(problems, fmt.Sprintf("recommendation %s has no instance type", key))
+		}
+	}
+	if _, err := templates.ParseArchitecture(domain.AWSIntegration.Architecture); err != nil {
+		problems = append(problems, err.Error())
+	}
+	if _, err := templates.ParseComplianceProfile(domain.AWSIntegration.ComplianceProfile); err != nil {
+		problems = append(problems, err.Error())
+	}
+	sort.Strings(problems)
+	return problems
+}
+
+func summarizeDomainProblems(domains map[string]*config.DomainPack) doctor.Result {
+	names := make([]string, 0, len(domains))
+	for name := range domains {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var invalid []string
+	for _, name := range names {
+		if problems := domainProblems(domains[name]); len(problems) > 0 {
+			invalid = append(invalid, fmt.Sprintf("%s (%s)", name, strings.Join(problems, "; ")))
+		}
+	}
+
+	valid := len(names) - len(invalid)
+	switch {
+	case len(invalid) == 0:
+		return doctor.Pass("%d domain packs load and validate", valid)
+	case valid == 0:
+		return doctor.Fail("fix the domain pack YAML", "no domain pack validates: %s", strings.Join(invalid, ", "))
+	default:
+		return doctor.Warn("fix the listed domain pack YAML", "%d of %d domain packs validate; invalid: %s", valid, len(names), strings.Join(invalid, ", "))
+	}
+}
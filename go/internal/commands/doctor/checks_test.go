@@ -0,0 +1,94 @@
+package doctor
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/config"
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/doctor"
+)
+
+func TestDiscoverConfigRoot(t *testing.T) {
+	root := t.TempDir()
+	nested := filepath.Join(root, "projects", "genomics")
+	if err := os.MkdirAll(filepath.Join(root, "configs"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(nested, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, found := discoverConfigRoot(nested); !found || got != root {
+		t.Errorf("discoverConfigRoot() = %q, %v; want %q", got, found, root)
+	}
+
+	if _, err := configRoot(doctor.Environment{ConfigRoot: nested}); err == nil {
+		t.Error("an explicit config root without a configs directory should fail")
+	}
+	if got, err := configRoot(doctor.Environment{ConfigRoot: root}); err != nil || got != root {
+		t.Errorf("configRoot() = %q, %v", got, err)
+	}
+}
+
+func validDomain(name string) *config.DomainPack {
+	return &config.DomainPack{
+		Name: name,
+		AWSInstanceRecommendations: map[string]config.InstanceRecommendation{
+			"standard": {InstanceType: "r6i.xlarge"},
+		},
+	}
+}
+
+func TestSummarizeDomainProblems(t *testing.T) {
+	broken := validDomain("")
+	broken.AWSIntegration.Architecture = "mainframe"
+	broken.AWSInstanceRecommendations["gpu"] = config.InstanceRecommendation{}
+	regulated := validDomain("Clinical")
+	regulated.AWSIntegration.ComplianceProfile = "sox"
+
+	tests := []struct {
+		name    string
+		domains map[string]*config.DomainPack
+		want    doctor.Status
+		message string
+	}{
+		{
+			name:    "all valid",
+			domains: map[string]*config.DomainPack{"genomics": validDomain("Genomics"), "climate": validDomain("Climate")},
+			want:    doctor.StatusPass,
+			message: "2 domain packs",
+		},
+		{
+			name:    "some invalid",
+			domains: map[string]*config.DomainPack{"genomics": validDomain("Genomics"), "broken": broken},
+			want:    doctor.StatusWarn,
+			message: "broken (missing name; recommendation gpu has no instance type; unknown architecture",
+		},
+		{
+			name:    "unknown compliance profile",
+			domains: map[string]*config.DomainPack{"genomics": validDomain("Genomics"), "clinical": regulated},
+			want:    doctor.StatusWarn,
+			message: "clinical (unknown compliance profile \"sox\"",
+		},
+		{
+			name:    "none valid",
+			domains: map[string]*config.DomainPack{"empty": {Name: "Empty"}},
+			want:    doctor.StatusFail,
+			message: "empty (no instance recommendations)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := summarizeDomainProblems(tt.domains)
+			if result.Status != tt.want {
+				t.Errorf("status = %s, want %s (%s)", result.Status, tt.want, result.Message)
+			}
+			if !strings.Contains(result.Message, tt.message) {
+				t.Errorf("message = %q, want it to contain %q", result.Message, tt.message)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package doctor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/doctor"
+)
+
+// doctorReport is the JSON form of a doctor run
+type doctorReport struct {
+	Region  string          `json:"region"`
+	Results []doctor.Result `json:"results"`
+	Passed  int             `json:"passed"`
+	Warned  int             `json:"warnings"`
+	Failed  int             `json:"failed"`
+}
+
+// NewDoctorCommand creates the doctor command
+func NewDoctorCommand() *cobra.Command {
+	var jsonOutput bool
+
+	cmd := &cobra.Command{
+		Use:   "doctor",
+		Short: "Check AWS access, configuration and external tools",
+		Long: `Run a battery of checks covering AWS credentials, region reachability, key
+pairs and the pricing API, the configs directory and domain packs, and the
+optional external tools (docker, Python 3 with suitcase, spack, s5cmd, rclone,
+globus). Each check passes, warns or fails with a hint on how to fix it.
+
+The command exits with status 1 when any check fails. Warnings mark optional
+features that are unavailable and do not change the exit status.
+
+Examples:
+  # Check everything for the default region
+  aws-research-wizard doctor
+
+  # Check another region and print JSON for scripts
+  aws-research-wizard doctor --region eu-west-1 --json`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			region, _ := cmd.Flags().GetString("region")
+			configRoot, _ := cmd.Flags().GetString("config-root")
+			env := doctor.Environment{Region: region, ConfigRoot: configRoot}
+
+			if !jsonOutput {
+				fmt.Printf("🩺 AWS Research Wizard Doctor\n")
+				fmt.Printf("Region: %s\n\n", region)
+			}
+
+			report := doctor.DefaultRegistry().Run(context.Background(), env)
+
+			if jsonOutput {
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+				err := encoder.Encode(doctorReport{
+					Region:  region,
+					Results: report.Results,
+					Passed:  report.Count(doctor.StatusPass),
+					Warned:  report.Count(doctor.StatusWarn),
+					Failed:  report.Count(doctor.StatusFail),
+				})
+				if err != nil {
+					log.Fatalf("Failed to encode report: %v", err)
+				}
+			} else {
+				report.Print(os.Stdout)
+			}
+
+			if code := report.ExitCode(); code != 0 {
+				os.Exit(code)
+			}
+		},
+	}
+
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "Output results as JSON")
+
+	return cmd
+}
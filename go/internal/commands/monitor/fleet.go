@@ -0,0 +1,290 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/aws"
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/config"
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/monitoring"
+)
+
+// fleetStates are the instance states shown in the fleet view
+var fleetStates = []string{"pending", "running", "stopping", "stopped"}
+
+func createFleetCommand(refreshRate *int) *cobra.Command {
+	var domainName string
+	var allDomains bool
+	var watch bool
+	var jsonOutput bool
+
+	cmd := &cobra.Command{
+		Use:   "fleet",
+		Short: "Summarize every research environment of a domain",
+		Long: `Summarize all wizard-deployed instances of a domain in one view: instance
+states, stacks, hourly and month-to-date cost, average CPU utilization over
+the last hour, and the longest-running instance.
+
+Instances are found by their Domain tag. Month-to-date cost comes from Cost
+Explorer once the Domain tag is activated as a cost allocation tag; until then
+it is estimated from the running instances' hours this month (marked ~).
+
+Examples:
+  # One view of every genomics environment
+  aws-research-wizard monitor fleet --domain genomics
+
+  # The whole account grouped by domain, refreshed every minute
+  aws-research-wizard monitor fleet --all-domains --watch --refresh 60`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if (domainName == "") == !allDomains {
+				log.Fatal("Use either --domain or --all-domains")
+			}
+
+			ctx := context.Background()
+			region, _ := cmd.Flags().GetString("region")
+			awsClient, err := aws.NewClient(ctx, region)
+			if err != nil {
+				log.Fatalf("Failed to initialize AWS client: %v", err)
+			}
+			pricing, err := aws.NewPricingCalculator(region)
+			if err != nil {
+				log.Fatalf("Failed to initialize pricing calculator: %v", err)
+			}
+
+			names := loadDomainTagNames()
+			infraManager := aws.NewInfrastructureManager(awsClient)
+			for {
+				fetcher := &fleetFetcher{
+					monitoring: aws.NewMonitoringManager(awsClient),
+					pricing:    pricing,
+					names:      names,
+				}
+				summaries, err := fetchFleet(ctx, infraManager, fetcher, names, domainName)
+				if err != nil {
+					log.Fatalf("Failed to summarize fleet: %v", err)
+				}
+
+				if jsonOutput {
+					output := struct {
+						Domains []monitoring.FleetSummary `json:"domains"`
+						Total   monitoring.FleetSummary   `json:"total"`
+					}{summaries, monitoring.TotalFleet(summaries)}
+					encoder := json.NewEncoder(os.Stdout)
+					encoder.SetIndent("", "  ")
+					if err := encoder.Encode(output); err != nil {
+						log.Fatalf("Failed to encode fleet: %v", err)
+					}
+				} else {
+					printFleet(summaries, allDomains)
+				}
+
+				if !watch {
+					return
+				}
+				time.Sleep(time.Duration(*refreshRate) * time.Second)
+				if !jsonOutput {
+					fmt.Print("\033[H\033[2J")
+				}
+			}
+		},
+	}
+
+	cmd.Flags().StringVar(&domainName, "domain", "", "Domain to summarize")
+	cmd.Flags().BoolVar(&allDomains, "all-domains", false, "Summarize every domain in the account")
+	cmd.Flags().BoolVar(&watch, "watch", false, "Refresh every --refresh seconds until interrupted")
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "Output as JSON")
+
+	return cmd
+}
+
+// fetchFleet lists the wizard's instances, of one domain when a name is given,
+// and aggregates them by domain
+func fetchFleet(ctx context.Context, infraManager *aws.InfrastructureManager, fetcher *fleetFetcher, names domainTagNames, domainName string) ([]monitoring.FleetSummary, error) {
+	filters := map[string][]string{
+		"tag:CreatedBy":       {"AWS-Research-Wizard"},
+		"instance-state-name": fleetStates,
+	}
+	if domainName != "" {
+		filters["tag:Domain"] = names.tagValues(domainName)
+	}
+
+	instances, err := infraManager.ListInstances(ctx, filters)
+	if err != nil {
+		return nil, err
+	}
+
+	fleet := make([]monitoring.FleetInstance, 0, len(instances))
+	for _, instance := range instances {
+		fleet = append(fleet, monitoring.FleetInstance{
+			InstanceID:   instance.InstanceID,
+			InstanceType: instance.InstanceType,
+			State:        instance.State,
+			Domain:       names.domain(instance.Tags["Domain"]),
+			Stack:        instance.Tags["aws:cloudformation:stack-name"],
+			LaunchTime:   instance.LaunchTime,
+		})
+	}
+	return monitoring.AggregateFleet(ctx, fleet, fetcher, fetcher, time.Now())
+}
+
+// domainTagNames maps the Domain tag, which holds a domain pack's display
+// name, to the domain's config name
+type domainTagNames map[string]string
+
+// loadDomainTagNames reads the domain packs when a configs directory is found
+// above the current directory; without one, instances are grouped by tag
+func loadDomainTagNames() domainTagNames {
+	names := make(domainTagNames)
+	dir, err := os.Getwd()
+	if err != nil {
+		return names
+	}
+	for {
+		if _, err := os.Stat(filepath.Join(dir, "configs")); err == nil {
+			break
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return names
+		}
+		dir = parent
+	}
+
+	domains, err := config.NewConfigLoader(dir).LoadAllDomains()
+	if err != nil {
+		return names
+	}
+	for key, domain := range domains {
+		names[domain.Name] = key
+	}
+	return names
+}
+
+// domain returns the config name for a Domain tag value
+func (n domainTagNames) domain(tag string) string {
+	if key, exists := n[tag]; exists {
+		return key
+	}
+	return tag
+}
+
+// tagValues returns the Domain tag values a domain's instances may carry
+func (n domainTagNames) tagValues(domain string) []string {
+	values := []string{domain}
+	for tag, key := range n {
+		if strings.EqualFold(key, domain) && tag != domain {
+			values = append(values, tag)
+		}
+	}
+	return values
+}
+
+// fleetFetcher backs the fleet view with CloudWatch, Cost Explorer and the
+// pricing table. Billed costs are fetched once per refresh.
+type fleetFetcher struct {
+	monitoring *aws.MonitoringManager
+	pricing    *aws.PricingCalculator
+	names      domainTagNames
+
+	billed       map[string]float64
+	billedLoaded bool
+}
+
+func (f *fleetFetcher) AverageCPU(ctx context.Context, instanceID string, start, end time.Time) (float64, bool, error) {
+	return f.monitoring.AverageCPUUtilization(ctx, instanceID, start, end)
+}
+
+func (f *fleetFetcher) HourlyCost(instanceType string) (float64, error) {
+	estimate, err := f.pricing.CalculateCost(instanceType)
+	if err != nil {
+		return 0, err
+	}
+	return estimate.HourlyCost, nil
+}
+
+// MonthToDate sums the Cost Explorer spend tagged with the domain. Billing
+// data that cannot be read leaves every domain estimated.
+func (f *fleetFetcher) MonthToDate(ctx context.Context, domain string, monthStart, now time.Time) (float64, bool, error) {
+	if !f.billedLoaded {
+		f.billedLoaded = true
+		// The end date is exclusive, so tomorrow includes today's spend
+		billed, err := f.monitoring.CostsByTag(ctx, "Domain", monthStart, now.AddDate(0, 0, 1))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️  Billing data unavailable, estimating month-to-date cost: %v\n", err)
+		}
+		f.billed = billed
+	}
+
+	var total float64
+	found := false
+	for _, tag := range f.names.tagValues(domain) {
+		if cost, exists := f.billed[tag]; exists {
+			total += cost
+			found = true
+		}
+	}
+	return total, found, nil
+}
+
+// printFleet renders one row per domain, with a total row for the whole account
+func printFleet(summaries []monitoring.FleetSummary, showTotal bool) {
+	fmt.Printf("🚢 Research fleet (%s)\n\n", time.Now().Format("2006-01-02 15:04"))
+	if len(summaries) == 0 {
+		fmt.Println("No research instances found.")
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "DOMAIN\tSTACKS\tINSTANCES\tSTATES\tHOURLY\tMONTH-TO-DATE\tAVG CPU\tOLDEST RUNNING\t")
+	rows := summaries
+	if showTotal && len(summaries) > 1 {
+		rows = append(append([]monitoring.FleetSummary{}, summaries...), monitoring.TotalFleet(summaries))
+	}
+	for _, summary := range rows {
+		mtd := fmt.Sprintf("$%.2f", summary.MonthToDateCost)
+		if summary.MonthToDateEstimated {
+			mtd = "~" + mtd
+		}
+		cpu := "-"
+		if summary.CPUInstances > 0 {
+			cpu = fmt.Sprintf("%.1f%%", summary.AverageCPU)
+		}
+		oldest := "-"
+		if summary.OldestRunning != "" {
+			oldest = fmt.Sprintf("%s (%s)", formatAge(summary.OldestRunningAge), summary.OldestRunning)
+		}
+		fmt.Fprintf(w, "%s\t%d\t%d\t%s\t$%.2f\t%s\t%s\t%s\t\n",
+			summary.Domain, len(summary.Stacks), summary.Instances, formatStates(summary.States), summary.HourlyCost, mtd, cpu, oldest)
+	}
+	w.Flush()
+}
+
+// formatStates lists state counts in lifecycle order
+func formatStates(states map[string]int) string {
+	var parts []string
+	for _, state := range fleetStates {
+		if count := states[state]; count > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", count, state))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// formatAge renders an age in days and hours
+func formatAge(d time.Duration) string {
+	hours := int(d.Hours())
+	if hours < 24 {
+		return fmt.Sprintf("%dh", hours)
+	}
+	return fmt.Sprintf("%dd %dh", hours/24, hours%24)
+}
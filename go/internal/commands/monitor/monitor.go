@@ -52,8 +52,11 @@ func NewMonitorCommand() *cobra.Command {
 		createDashboardCommand(&refreshRate, &stackName, &instanceID, &showCosts, &showAlerts, &autoRefresh, &outputFormat),
 		createCostCommand(),
 		createAlertsCommand(),
+		createStorageCommand(),
 		createInstancesCommand(&instanceID),
 		createStacksCommand(&stackName),
+		createScheduleCommand(&stackName),
+		createFleetCommand(&refreshRate),
 	)
 
 	return monitorCmd
@@ -167,7 +170,7 @@ func createCostCommand() *cobra.Command {
 }
 
 func createAlertsCommand() *cobra.Command {
-	return &cobra.Command{
+	cmd := &cobra.Command{
 		Use:   "alerts",
 		Short: "Show CloudWatch alerts and alarms",
 		Run: func(cmd *cobra.Command, args []string) {
@@ -190,7 +193,6 @@ func createAlertsCommand() *cobra.Command {
 
 			if len(alarms) == 0 {
 				fmt.Println("No CloudWatch alarms found.")
-				return
 			}
 
 			for _, alarm := range alarms {
@@ -210,8 +212,42 @@ func createAlertsCommand() *cobra.Command {
 				}
 				fmt.Printf("\n")
 			}
+
+			idle, err := monitoringManager.ListIdleElasticIPs(ctx)
+			if err != nil {
+				fmt.Printf("⚠️  Could not check Elastic IP addresses: %v\n", err)
+				return
+			}
+			printIdleElasticIPs(idle)
 		},
 	}
+
+	cmd.AddCommand(createAlertsRunCommand())
+
+	return cmd
+}
+
+// printIdleElasticIPs lists the Elastic IP addresses billed without serving a
+// running instance
+func printIdleElasticIPs(idle []aws.IdleElasticIP) {
+	if len(idle) == 0 {
+		return
+	}
+	var total float64
+	for _, address := range idle {
+		total += address.MonthlyCost
+	}
+	fmt.Printf("💸 Idle Elastic IP addresses (%d, ~$%.2f/month)\n\n", len(idle), total)
+	for _, address := range idle {
+		fmt.Printf("🟡 %s (%s)\n", address.PublicIP, address.AllocationID)
+		fmt.Printf("   Reason: %s\n", address.Reason())
+		if address.StackName != "" {
+			fmt.Printf("   Stack: %s\n", address.StackName)
+		}
+		fmt.Printf("   Cost: $%.3f/hour (~$%.2f/month) while idle\n", aws.ElasticIPHourlyCost, address.MonthlyCost)
+		fmt.Printf("\n")
+	}
+	fmt.Printf("💡 Release addresses you no longer need: aws ec2 release-address --allocation-id <id>\n")
 }
 
 func createInstancesCommand(instanceID *string) *cobra.Command {
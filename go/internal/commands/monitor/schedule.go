@@ -0,0 +1,160 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/aws"
+)
+
+func createScheduleCommand(stackName *string) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "schedule",
+		Short: "Start and stop a stack's instances on an office-hours schedule",
+		Long: `Start a stack's instances in the morning and stop them in the evening on the
+days they are needed, so idle research instances do not run overnight.
+
+Each schedule is a small CloudFormation stack named <stack>-schedule holding
+two EventBridge Scheduler schedules and the Lambda function they invoke.
+Instances tagged '` + aws.ScheduleSkipTag + `' are not stopped, so a long-running job can be kept
+running by tagging its instance; they are still started on schedule.`,
+	}
+
+	cmd.AddCommand(
+		createScheduleSetCommand(stackName),
+		createScheduleListCommand(),
+		createScheduleRemoveCommand(stackName),
+	)
+
+	return cmd
+}
+
+func createScheduleSetCommand(stackName *string) *cobra.Command {
+	var start string
+	var stop string
+	var days string
+	var timezone string
+
+	cmd := &cobra.Command{
+		Use:   "set",
+		Short: "Create or replace a stack's schedule",
+		Long: `Create or replace the office-hours schedule of a stack.
+
+Days are a comma-separated list of days and ranges (mon-fri, sat,sun,
+fri-mon) or one of weekdays, weekends and daily. Times are local to
+--timezone, so schedules follow daylight saving time.
+
+Examples:
+  # Run weekdays from 8:00 to 18:30 New York time
+  aws-research-wizard monitor schedule set --stack genomics-lab \
+    --start 08:00 --stop 18:30 --days mon-fri --timezone America/New_York
+
+  # Keep one instance running through the night
+  aws ec2 create-tags --resources i-0123456789abcdef0 --tags Key=` + aws.ScheduleSkipTag + `,Value=true`,
+		Run: func(cmd *cobra.Command, args []string) {
+			ctx := context.Background()
+
+			if *stackName == "" {
+				log.Fatalf("--stack is required")
+			}
+			schedule, err := aws.NewInstanceSchedule(*stackName, start, stop, days, timezone)
+			if err != nil {
+				log.Fatalf("Invalid schedule: %v", err)
+			}
+
+			region, _ := cmd.Flags().GetString("region")
+			awsClient, err := aws.NewClient(ctx, region)
+			if err != nil {
+				log.Fatalf("Failed to initialize AWS client: %v", err)
+			}
+
+			fmt.Printf("⏰ Scheduling %s: start %s, stop %s, %s (%s)\n", schedule.Stack, schedule.Start, schedule.Stop, schedule.DaysDescription(), schedule.Timezone)
+			fmt.Printf("   Start: %s\n", schedule.StartExpression())
+			fmt.Printf("   Stop:  %s\n\n", schedule.StopExpression())
+
+			if err := aws.NewScheduleManager(awsClient).Set(ctx, schedule); err != nil {
+				log.Fatalf("Failed to set schedule: %v", err)
+			}
+
+			fmt.Printf("✅ Schedule saved in stack %s\n", aws.ScheduleStackName(schedule.Stack))
+			fmt.Printf("💡 Tag an instance '%s' to keep it running past the scheduled stop\n", aws.ScheduleSkipTag)
+		},
+	}
+
+	cmd.Flags().StringVar(&start, "start", "", "Time to start the instances (HH:MM)")
+	cmd.Flags().StringVar(&stop, "stop", "", "Time to stop the instances (HH:MM)")
+	cmd.Flags().StringVar(&days, "days", "mon-fri", "Days to run (e.g., mon-fri, sat,sun, daily)")
+	cmd.Flags().StringVar(&timezone, "timezone", "UTC", "IANA time zone of the start and stop times")
+	cmd.MarkFlagRequired("start")
+	cmd.MarkFlagRequired("stop")
+
+	return cmd
+}
+
+func createScheduleListCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List instance schedules",
+		Run: func(cmd *cobra.Command, args []string) {
+			ctx := context.Background()
+
+			region, _ := cmd.Flags().GetString("region")
+			awsClient, err := aws.NewClient(ctx, region)
+			if err != nil {
+				log.Fatalf("Failed to initialize AWS client: %v", err)
+			}
+
+			schedules, err := aws.NewScheduleManager(awsClient).List(ctx)
+			if err != nil {
+				log.Fatalf("Failed to list schedules: %v", err)
+			}
+
+			if len(schedules) == 0 {
+				fmt.Printf("No instance schedules in %s.\n", region)
+				return
+			}
+
+			fmt.Printf("⏰ Instance Schedules (%d)\n\n", len(schedules))
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "STACK\tSTART\tSTOP\tDAYS\tTIME ZONE")
+			for _, schedule := range schedules {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", schedule.Stack, schedule.Start, schedule.Stop, schedule.DaysDescription(), schedule.Timezone)
+			}
+			w.Flush()
+		},
+	}
+}
+
+func createScheduleRemoveCommand(stackName *string) *cobra.Command {
+	return &cobra.Command{
+		Use:   "remove",
+		Short: "Remove a stack's schedule",
+		Long: `Remove a stack's schedule. Its instances are left in their current state.
+
+Examples:
+  aws-research-wizard monitor schedule remove --stack genomics-lab`,
+		Run: func(cmd *cobra.Command, args []string) {
+			ctx := context.Background()
+
+			if *stackName == "" {
+				log.Fatalf("--stack is required")
+			}
+
+			region, _ := cmd.Flags().GetString("region")
+			awsClient, err := aws.NewClient(ctx, region)
+			if err != nil {
+				log.Fatalf("Failed to initialize AWS client: %v", err)
+			}
+
+			if err := aws.NewScheduleManager(awsClient).Remove(ctx, *stackName); err != nil {
+				log.Fatalf("Failed to remove schedule: %v", err)
+			}
+			fmt.Printf("✅ Removed the schedule of %s; its instances were left as they are\n", *stackName)
+		},
+	}
+}
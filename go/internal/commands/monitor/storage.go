@@ -0,0 +1,397 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/aws"
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/data"
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/monitoring"
+)
+
+// storageBackfillDays is how much daily history is read from the S3 storage metrics
+const storageBackfillDays = 30
+
+// storageGrowthWindows are the windows growth is reported over
+var storageGrowthWindows = []int{7, 30}
+
+func createStorageCommand() *cobra.Command {
+	var bucket string
+	var prefix string
+	var source string
+	var growthAlert float64
+	var maxSizeGB float64
+	var maxObjects int64
+	var jsonOutput bool
+
+	cmd := &cobra.Command{
+		Use:   "storage",
+		Short: "Track S3 storage growth for a research bucket",
+		Long: `Record the size of an S3 bucket or prefix in a local history and report its
+growth, monthly cost per storage class, and fast-growing prefixes.
+
+Whole buckets are measured with the daily S3 storage metrics in CloudWatch,
+which also fill in the last 30 days of history on the first run. Prefixes are
+measured by listing their objects, which also breaks usage down by child
+prefix. Run the command daily (or use 'monitor alerts run') to build up history.
+
+Every tracked bucket and prefix is re-measured and checked against its
+thresholds by 'monitor alerts run'.
+
+Examples:
+  # Track a whole bucket
+  aws-research-wizard monitor storage --bucket my-research-data
+
+  # Track a prefix and flag child prefixes growing more than 5% a week
+  aws-research-wizard monitor storage --bucket my-research-data --prefix runs/ --growth-alert 5
+
+  # Alert when the bucket passes 10 TB
+  aws-research-wizard monitor storage --bucket my-research-data --max-size-gb 10240`,
+		Run: func(cmd *cobra.Command, args []string) {
+			ctx := context.Background()
+
+			if source == "" {
+				source = monitoring.StorageSourceCloudWatch
+				if prefix != "" {
+					source = monitoring.StorageSourceList
+				}
+			}
+			if source != monitoring.StorageSourceCloudWatch && source != monitoring.StorageSourceList {
+				log.Fatalf("Invalid --source %q: use %s or %s", source, monitoring.StorageSourceCloudWatch, monitoring.StorageSourceList)
+			}
+			if source == monitoring.StorageSourceCloudWatch && prefix != "" {
+				log.Fatalf("CloudWatch storage metrics cover whole buckets only; use --source %s with --prefix", monitoring.StorageSourceList)
+			}
+
+			region, _ := cmd.Flags().GetString("region")
+			awsClient, err := aws.NewClient(ctx, region)
+			if err != nil {
+				log.Fatalf("Failed to initialize AWS client: %v", err)
+			}
+
+			history, err := openStorageHistory()
+			if err != nil {
+				log.Fatalf("Failed to open storage history: %v", err)
+			}
+
+			target := monitoring.StorageSeries{
+				Bucket:             bucket,
+				Prefix:             prefix,
+				Region:             region,
+				Source:             source,
+				GrowthAlertPercent: growthAlert,
+				MaxBytes:           int64(maxSizeGB * 1024 * 1024 * 1024),
+			}
+
+			series, err := collectStorage(ctx, aws.NewStorageCollector(awsClient), history, target, maxObjects)
+			if err != nil {
+				log.Fatalf("Failed to measure storage: %v", err)
+			}
+
+			report := newStorageReport(series, data.NewS3CostCalculator(region))
+			if jsonOutput {
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+				if err := encoder.Encode(report); err != nil {
+					log.Fatalf("Failed to encode storage report: %v", err)
+				}
+				return
+			}
+			printStorageReport(report)
+		},
+	}
+
+	cmd.Flags().StringVar(&bucket, "bucket", "", "S3 bucket to track")
+	cmd.Flags().StringVar(&prefix, "prefix", "", "Prefix within the bucket to track")
+	cmd.Flags().StringVar(&source, "source", "", "Measurement source: cloudwatch or list (default cloudwatch for buckets, list for prefixes)")
+	cmd.Flags().Float64Var(&growthAlert, "growth-alert", monitoring.DefaultStorageGrowthAlertPercent, "Flag growth above this percentage per week")
+	cmd.Flags().Float64Var(&maxSizeGB, "max-size-gb", 0, "Alert when storage exceeds this many GB (0 disables)")
+	cmd.Flags().Int64Var(&maxObjects, "max-objects", aws.DefaultStorageListLimit, "Stop listing after this many objects")
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "Output as JSON")
+	cmd.MarkFlagRequired("bucket")
+
+	return cmd
+}
+
+// openStorageHistory opens the default local storage history
+func openStorageHistory() (*monitoring.StorageHistory, error) {
+	path, err := monitoring.DefaultStorageHistoryPath()
+	if err != nil {
+		return nil, err
+	}
+	return monitoring.NewStorageHistory(path), nil
+}
+
+// collectStorage measures a bucket or prefix and records the samples in the history
+func collectStorage(ctx context.Context, collector *aws.StorageCollector, history *monitoring.StorageHistory, target monitoring.StorageSeries, maxObjects int64) (*monitoring.StorageSeries, error) {
+	now := time.Now().UTC()
+	var samples []monitoring.StorageSample
+
+	switch target.Source {
+	case monitoring.StorageSourceList:
+		storage, err := collector.ListPrefixStorage(ctx, target.Bucket, target.Prefix, maxObjects)
+		if err != nil {
+			return nil, err
+		}
+		sample := monitoring.StorageSample{
+			Date:           monitoring.StorageDate(now),
+			CollectedAt:    now,
+			Source:         monitoring.StorageSourceList,
+			Objects:        storage.Objects,
+			Bytes:          storage.Bytes,
+			StorageClasses: storage.StorageClasses,
+			Prefixes:       make(map[string]monitoring.StorageUsage, len(storage.Prefixes)),
+			Partial:        storage.Partial,
+		}
+		for prefix, usage := range storage.Prefixes {
+			sample.Prefixes[prefix] = monitoring.StorageUsage{Objects: usage.Objects, Bytes: usage.Bytes}
+		}
+		samples = append(samples, sample)
+
+	default:
+		days, err := collector.BucketStorageHistory(ctx, target.Bucket, storageBackfillDays)
+		if err != nil {
+			return nil, err
+		}
+		for _, day := range days {
+			samples = append(samples, monitoring.StorageSample{
+				Date:           monitoring.StorageDate(day.Date),
+				CollectedAt:    now,
+				Source:         monitoring.StorageSourceCloudWatch,
+				Objects:        day.Objects,
+				Bytes:          day.Bytes,
+				StorageClasses: day.StorageClasses,
+			})
+		}
+	}
+
+	return history.Record(target, samples...)
+}
+
+// storageClassCost is the monthly cost of one storage class
+type storageClassCost struct {
+	StorageClass string  `json:"storage_class"`
+	Bytes        int64   `json:"bytes"`
+	MonthlyCost  float64 `json:"monthly_cost"`
+	Priced       bool    `json:"priced"`
+}
+
+// storageReport is the output of 'monitor storage'
+type storageReport struct {
+	Target       string                     `json:"target"`
+	Latest       monitoring.StorageSample   `json:"latest"`
+	Samples      int                        `json:"samples"`
+	Growth       []monitoring.StorageGrowth `json:"growth"`
+	Costs        []storageClassCost         `json:"costs"`
+	MonthlyCost  float64                    `json:"monthly_cost"`
+	Prefixes     []monitoring.PrefixGrowth  `json:"prefixes,omitempty"`
+	FastGrowing  []monitoring.PrefixGrowth  `json:"fast_growing,omitempty"`
+	GrowthAlert  float64                    `json:"growth_alert_percent"`
+	Alerts       []*monitoring.Alert        `json:"alerts,omitempty"`
+	HistoryStart string                     `json:"history_start"`
+}
+
+func newStorageReport(series *monitoring.StorageSeries, calculator *data.S3CostCalculator) *storageReport {
+	latest, _ := series.Latest()
+	report := &storageReport{
+		Target:      series.Target(),
+		Latest:      latest,
+		Samples:     len(series.Samples),
+		GrowthAlert: series.GrowthAlertPercent,
+		Alerts:      series.EvaluateThresholds(time.Now()),
+	}
+	if len(series.Samples) > 0 {
+		report.HistoryStart = series.Samples[0].Date
+	}
+
+	for _, days := range storageGrowthWindows {
+		if growth, ok := series.Growth(days); ok {
+			report.Growth = append(report.Growth, growth)
+		}
+	}
+
+	for class, bytes := range latest.StorageClasses {
+		cost, priced := calculator.MonthlyStorageCost(class, bytes)
+		report.Costs = append(report.Costs, storageClassCost{StorageClass: class, Bytes: bytes, MonthlyCost: cost, Priced: priced})
+		report.MonthlyCost += cost
+	}
+	sort.Slice(report.Costs, func(i, j int) bool {
+		return report.Costs[i].Bytes > report.Costs[j].Bytes
+	})
+
+	report.Prefixes = series.PrefixGrowth(monitoring.StorageGrowthRateDays)
+	report.FastGrowing = monitoring.FastGrowingPrefixes(report.Prefixes, series.GrowthAlertPercent)
+	return report
+}
+
+func printStorageReport(report *storageReport) {
+	fmt.Printf("🗄️  S3 Storage - %s\n", report.Target)
+	fmt.Printf("As of %s (%s, %d days of history since %s)\n\n", report.Latest.Date, report.Latest.Source, report.Samples, report.HistoryStart)
+
+	fmt.Printf("Objects: %d\n", report.Latest.Objects)
+	fmt.Printf("Size: %s\n", formatStorageBytes(report.Latest.Bytes))
+	if report.Latest.Partial {
+		fmt.Printf("⚠️  Listing stopped at the object limit; totals are a lower bound\n")
+	}
+
+	fmt.Printf("\nGrowth:\n")
+	if len(report.Growth) == 0 {
+		fmt.Printf("  Not enough history yet; run again on a later day\n")
+	}
+	for _, growth := range report.Growth {
+		span := ""
+		if growth.SpanDays < growth.Days {
+			span = fmt.Sprintf(" (only %d days of history)", growth.SpanDays)
+		}
+		fmt.Printf("  %2d days: %+s (%+.1f%%)%s\n", growth.Days, formatStorageDelta(growth.DeltaBytes), growth.Percent, span)
+	}
+
+	fmt.Printf("\nMonthly cost by storage class:\n")
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "  CLASS\tSIZE\t$/MONTH")
+	for _, cost := range report.Costs {
+		price := fmt.Sprintf("%.2f", cost.MonthlyCost)
+		if !cost.Priced {
+			price = "n/a"
+		}
+		fmt.Fprintf(w, "  %s\t%s\t%s\n", cost.StorageClass, formatStorageBytes(cost.Bytes), price)
+	}
+	w.Flush()
+	fmt.Printf("  Total: $%.2f/month\n", report.MonthlyCost)
+
+	if len(report.Prefixes) > 0 {
+		fmt.Printf("\nPrefixes (growth over %d days):\n", monitoring.StorageGrowthRateDays)
+		w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "  PREFIX\tSIZE\tGROWTH\t%/WEEK")
+		for _, prefix := range report.Prefixes {
+			name := prefix.Prefix
+			if name == "" {
+				name = "(objects at this level)"
+			}
+			weekly := fmt.Sprintf("%.1f", prefix.Growth.WeeklyPercent())
+			if prefix.New {
+				weekly = "new"
+			}
+			fmt.Fprintf(w, "  %s\t%s\t%s\t%s\n", name, formatStorageBytes(prefix.Growth.EndBytes), formatStorageDelta(prefix.Growth.DeltaBytes), weekly)
+		}
+		w.Flush()
+	}
+
+	if len(report.Alerts) == 0 {
+		fmt.Printf("\n✅ No storage thresholds exceeded\n")
+		return
+	}
+	fmt.Println()
+	for _, alert := range report.Alerts {
+		fmt.Printf("⚠️  %s: %s\n", alert.Title, alert.Description)
+	}
+}
+
+func createAlertsRunCommand() *cobra.Command {
+	var maxObjects int64
+
+	cmd := &cobra.Command{
+		Use:   "run",
+		Short: "Collect tracked metrics and evaluate alert thresholds",
+		Long: `Re-measure every bucket and prefix tracked with 'monitor storage' and check
+it against its size limit and growth threshold.
+
+The command exits with status 1 when any threshold is exceeded, so it can be
+scheduled with cron and wired into notifications.
+
+Examples:
+  # Evaluate all storage thresholds
+  aws-research-wizard monitor alerts run`,
+		Run: func(cmd *cobra.Command, args []string) {
+			ctx := context.Background()
+
+			history, err := openStorageHistory()
+			if err != nil {
+				log.Fatalf("Failed to open storage history: %v", err)
+			}
+			tracked, err := history.Series()
+			if err != nil {
+				log.Fatalf("Failed to read storage history: %v", err)
+			}
+			if len(tracked) == 0 {
+				fmt.Println("No storage is tracked yet; add a bucket with 'monitor storage --bucket NAME'.")
+				return
+			}
+
+			collectors := make(map[string]*aws.StorageCollector)
+			var alerts []*monitoring.Alert
+			failed := false
+			for _, target := range tracked {
+				collector, exists := collectors[target.Region]
+				if !exists {
+					awsClient, err := aws.NewClient(ctx, target.Region)
+					if err != nil {
+						log.Fatalf("Failed to initialize AWS client for %s: %v", target.Region, err)
+					}
+					collector = aws.NewStorageCollector(awsClient)
+					collectors[target.Region] = collector
+				}
+
+				series, err := collectStorage(ctx, collector, history, target, maxObjects)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "❌ %s: %v\n", target.Target(), err)
+					failed = true
+					continue
+				}
+
+				seriesAlerts := series.EvaluateThresholds(time.Now())
+				status := "🟢"
+				if len(seriesAlerts) > 0 {
+					status = "🔴"
+				}
+				latest, _ := series.Latest()
+				fmt.Printf("%s %s: %s\n", status, series.Target(), formatStorageBytes(latest.Bytes))
+				alerts = append(alerts, seriesAlerts...)
+			}
+
+			if len(alerts) > 0 {
+				fmt.Printf("\n🚨 %d storage alert(s):\n", len(alerts))
+				for _, alert := range alerts {
+					fmt.Printf("  [%s] %s: %s\n", alert.Severity, alert.Title, alert.Description)
+				}
+			}
+			if failed || len(alerts) > 0 {
+				os.Exit(1)
+			}
+		},
+	}
+
+	cmd.Flags().Int64Var(&maxObjects, "max-objects", aws.DefaultStorageListLimit, "Stop listing a tracked prefix after this many objects")
+
+	return cmd
+}
+
+// formatStorageBytes converts bytes to a human-readable size
+func formatStorageBytes(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit && exp < 5; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
+
+// formatStorageDelta formats a signed change in bytes
+func formatStorageDelta(bytes int64) string {
+	if bytes < 0 {
+		return "-" + formatStorageBytes(-bytes)
+	}
+	return "+" + formatStorageBytes(bytes)
+}
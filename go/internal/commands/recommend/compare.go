@@ -0,0 +1,139 @@
+package recommend
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/intelligence"
+)
+
+func createCompareCommand() *cobra.Command {
+	var hintsPathA string
+	var hintsPathB string
+	var automaticConfidence float64
+	var jsonOutput bool
+
+	cmd := &cobra.Command{
+		Use:   "compare [data-path]",
+		Short: "Compare the recommendations for two sets of hints",
+		Long: `Answer what changes when the same data is described differently, for
+example as climate data instead of general data.
+
+Each hints file is YAML using the hint names below. The data is analyzed once
+and both recommendations are made from that analysis; the instance, monthly
+cost, storage, implementation complexity and confidence are shown side by side.
+
+  explicit_domain: climate
+  workflow_hints: [regridding]
+  tool_hints: [cdo, nco]
+  file_extensions: [.nc]
+  data_size_hint: large
+  budget_constraint: 500
+
+Examples:
+  # What if this is climate data instead of general?
+  aws-research-wizard recommend compare /data/run-42 --hints-a general.yaml --hints-b climate.yaml`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			hintsA, err := intelligence.LoadDomainHints(hintsPathA)
+			if err != nil {
+				log.Fatalf("Failed to load --hints-a: %v", err)
+			}
+			hintsB, err := intelligence.LoadDomainHints(hintsPathB)
+			if err != nil {
+				log.Fatalf("Failed to load --hints-b: %v", err)
+			}
+
+			path, err := filepath.Abs(args[0])
+			if err != nil {
+				log.Fatalf("Failed to resolve data path: %v", err)
+			}
+			if _, err := os.Stat(path); err != nil {
+				log.Fatalf("Data path not accessible: %v", err)
+			}
+
+			region, _ := cmd.Flags().GetString("region")
+			if region == "" {
+				region = "us-east-1"
+			}
+			engine, err := newIntelligenceEngine(region, automaticConfidence)
+			if err != nil {
+				log.Fatalf("Invalid --automatic-confidence: %v", err)
+			}
+
+			comparison, err := engine.CompareRecommendations(context.Background(), path, hintsA, hintsB)
+			if err != nil {
+				log.Fatalf("Failed to compare recommendations: %v", err)
+			}
+
+			if jsonOutput {
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+				if err := encoder.Encode(comparison); err != nil {
+					log.Fatalf("Failed to encode comparison: %v", err)
+				}
+				return
+			}
+
+			printComparison(comparison, filepath.Base(hintsPathA), filepath.Base(hintsPathB))
+		},
+	}
+
+	cmd.Flags().StringVar(&hintsPathA, "hints-a", "", "YAML file with the first set of hints")
+	cmd.Flags().StringVar(&hintsPathB, "hints-b", "", "YAML file with the second set of hints")
+	cmd.Flags().Float64Var(&automaticConfidence, "automatic-confidence", intelligence.DefaultAutomaticConfidence, "Detection confidence needed for an automatic recommendation")
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "Output as JSON")
+	cmd.MarkFlagRequired("hints-a")
+	cmd.MarkFlagRequired("hints-b")
+
+	return cmd
+}
+
+func printComparison(comparison *intelligence.RecommendationComparison, labelA, labelB string) {
+	fmt.Printf("🔀 What-if: %s\n\n", comparison.DataPath)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "FIELD\tA: %s\tB: %s\tCHANGE\n", labelA, labelB)
+	for _, delta := range comparison.Delta {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", delta.Field, orDash(delta.A), orDash(delta.B), describeChange(delta))
+	}
+	w.Flush()
+
+	changed := comparison.Changed()
+	if len(changed) == 0 {
+		fmt.Println("\n✅ Both hint sets lead to the same recommendation")
+		return
+	}
+	fmt.Printf("\n💡 %d of %d field(s) differ\n", len(changed), len(comparison.Delta))
+}
+
+// describeChange summarizes a field delta for the CHANGE column
+func describeChange(delta intelligence.FieldDelta) string {
+	switch {
+	case !delta.Changed:
+		return "="
+	case delta.Difference == nil:
+		return "changed"
+	case delta.Field == intelligence.DeltaMonthlyCost:
+		if *delta.Difference < 0 {
+			return fmt.Sprintf("-$%.2f", -*delta.Difference)
+		}
+		return fmt.Sprintf("+$%.2f", *delta.Difference)
+	default:
+		return fmt.Sprintf("%+.2f", *delta.Difference)
+	}
+}
+
+func orDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
@@ -0,0 +1,195 @@
+package recommend
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/data"
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/export"
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/intelligence"
+)
+
+// planFlags select the domain and output of an environment recommendation
+type planFlags struct {
+	Domain       string
+	OutputFormat string
+	OutputDir    string
+	// Inventory is a manifest listing the dataset's files
+	Inventory           string
+	AutomaticConfidence float64
+	// Force exports recommendations that are not confident enough to apply automatically
+	Force bool
+	// Compliance selects a compliance profile over the domain pack's
+	Compliance string
+}
+
+// runPlanRecommendation recommends an environment for a data path and prints
+// it or exports it as infrastructure as code
+func runPlanRecommendation(cmd *cobra.Command, dataPath string, flags planFlags) {
+	var format export.Format
+	switch flags.OutputFormat {
+	case "text", "json":
+	default:
+		var err error
+		if format, err = export.ParseFormat(flags.OutputFormat); err != nil {
+			log.Fatalf("Invalid output format: %v", err)
+		}
+		if flags.OutputDir == "" {
+			log.Fatalf("--output-dir is required for %s output", format)
+		}
+	}
+
+	path, err := filepath.Abs(dataPath)
+	if err != nil {
+		log.Fatalf("Failed to resolve data path: %v", err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		log.Fatalf("Data path not accessible: %v", err)
+	}
+
+	region, _ := cmd.Flags().GetString("region")
+	if region == "" {
+		region = "us-east-1"
+	}
+
+	hints := intelligence.DomainHints{ExplicitDomain: flags.Domain, ComplianceProfile: flags.Compliance}
+	if flags.Inventory != "" {
+		manifest, err := os.Open(flags.Inventory)
+		if err != nil {
+			log.Fatalf("Failed to open inventory manifest: %v", err)
+		}
+		hints.FileExtensions, hints.InventoryFiles, err = intelligence.ReadInventoryManifest(manifest)
+		manifest.Close()
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+	}
+
+	engine, err := newIntelligenceEngine(region, flags.AutomaticConfidence)
+	if err != nil {
+		log.Fatalf("Invalid --automatic-confidence: %v", err)
+	}
+
+	recommendation, err := engine.GenerateIntelligentRecommendations(context.Background(), path, hints)
+	if err != nil {
+		log.Fatalf("Failed to generate recommendation: %v", err)
+	}
+
+	switch flags.OutputFormat {
+	case "text":
+		printPlanRecommendation(recommendation)
+		return
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(recommendation); err != nil {
+			log.Fatalf("Failed to encode recommendation: %v", err)
+		}
+		return
+	}
+
+	if recommendation.Mode != intelligence.ModeAutomatic && !flags.Force {
+		printConfidenceChecklist(recommendation)
+		log.Fatalf("The recommendation is %s (%s, confidence %.2f); review it with --output-format text and rerun with --force to export it anyway",
+			recommendation.Mode, recommendation.Domain, recommendation.Confidence)
+	}
+
+	env := export.NewEnvironment(recommendation.Domain, recommendation.ResourcePlan)
+	files, err := export.Generate(format, env)
+	if err != nil {
+		log.Fatalf("Failed to generate %s: %v", format, err)
+	}
+	if err := export.WriteFiles(flags.OutputDir, files); err != nil {
+		log.Fatalf("Failed to write %s: %v", format, err)
+	}
+
+	fmt.Printf("✅ Wrote %s for the %s environment to %s:\n", format, env.DomainName, flags.OutputDir)
+	for _, file := range files {
+		fmt.Printf("  %s\n", filepath.Join(flags.OutputDir, file.Name))
+	}
+	if format == export.FormatTerraform {
+		fmt.Printf("\nNext: cd %s && terraform init && terraform apply -var key_name=<your-key-pair>\n", flags.OutputDir)
+	}
+}
+
+// newIntelligenceEngine creates an engine pricing storage in the region
+func newIntelligenceEngine(region string, automaticConfidence float64) (*intelligence.IntelligenceEngine, error) {
+	recommendationEngine := data.NewRecommendationEngine(data.NewPatternAnalyzer(), data.NewS3CostCalculator(region), nil, nil)
+	engine := intelligence.NewIntelligenceEngine(data.NewResearchDomainProfileManager(), recommendationEngine)
+	thresholds := intelligence.DefaultConfidenceThresholds()
+	thresholds.Automatic = automaticConfidence
+	if err := engine.SetConfidenceThresholds(thresholds); err != nil {
+		return nil, err
+	}
+	return engine, nil
+}
+
+func printPlanRecommendation(rec *intelligence.IntelligentRecommendation) {
+	plan := rec.ResourcePlan
+	storage := plan.StorageConfiguration.PrimaryStorage
+
+	fmt.Printf("🧠 Recommended environment: %s (confidence %.2f, %s)\n", rec.Domain, rec.Confidence, rec.Mode)
+	switch rec.Mode {
+	case intelligence.ModeAdvisory:
+		fmt.Println("⚠️  Advisory: review before applying; exporting needs --force")
+	case intelligence.ModeInsufficientData:
+		fmt.Println("⚠️  Insufficient data: this is a guess; exporting needs --force")
+	}
+	fmt.Println()
+	fmt.Printf("  Instance:      %s\n", plan.RecommendedInstance)
+	if len(plan.AlternativeInstances) > 0 {
+		fmt.Printf("  Alternatives:  %s\n", strings.Join(plan.AlternativeInstances, ", "))
+	}
+	fmt.Printf("  Storage:       %d GB %s", storage.SizeGB, storage.Type)
+	if storage.IOPS > 0 {
+		fmt.Printf(" (%d IOPS, %d MB/s)", storage.IOPS, storage.Throughput)
+	}
+	fmt.Println()
+	fmt.Printf("  Encryption:    %v\n", plan.SecurityConfiguration.EncryptionAtRest)
+	fmt.Printf("  Placement:     %v\n", plan.NetworkConfiguration.PlacementGroup)
+	if rec.CostOptimization != nil {
+		fmt.Printf("  Monthly cost:  $%.2f (optimized $%.2f)\n", rec.CostOptimization.EstimatedMonthlyCost, rec.CostOptimization.OptimizedMonthlyCost)
+	}
+	if plan.Reasoning != "" {
+		fmt.Printf("\n💡 %s\n", plan.Reasoning)
+	}
+	printCompliance(rec)
+
+	printConfidenceChecklist(rec)
+	fmt.Println("\nExport with --output-format terraform or cloudformation and --output-dir.")
+}
+
+// printCompliance lists the controls a compliance profile addresses and the
+// ones left to the user
+func printCompliance(rec *intelligence.IntelligentRecommendation) {
+	security := rec.ResourcePlan.SecurityConfiguration
+	if security.ComplianceProfile == "" {
+		return
+	}
+	fmt.Printf("\n🔒 Compliance: %s (%s)\n", security.ComplianceProfile, security.ComplianceFramework)
+	for _, control := range security.ControlsAddressed {
+		fmt.Printf("  ✅ %s\n", control)
+	}
+	fmt.Println("\n  Your responsibility:")
+	for _, item := range security.UserResponsibilities {
+		fmt.Printf("  • %s\n", item)
+	}
+}
+
+// printConfidenceChecklist lists the hints that would make an insufficient-data recommendation usable
+func printConfidenceChecklist(rec *intelligence.IntelligentRecommendation) {
+	if len(rec.Checklist) == 0 {
+		return
+	}
+	fmt.Println("\n📝 To raise confidence:")
+	for _, item := range rec.Checklist {
+		fmt.Printf("  [ ] %s\n", item)
+	}
+}
@@ -0,0 +1,214 @@
+package recommend
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/aws"
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/intelligence"
+)
+
+// NewRecommendCommand creates the recommend subcommand
+func NewRecommendCommand() *cobra.Command {
+	var flags planFlags
+
+	recommendCmd := &cobra.Command{
+		Use:   "recommend [data-path]",
+		Short: "Recommend research environments and changes to existing ones",
+		Long: `Recommend a research environment for a dataset, or analyze running research
+environments and recommend changes.
+
+Given a data path, recommend analyzes the data, detects its research domain and
+prints the recommended instance, storage and security settings. The plan can
+be exported as infrastructure as code instead of printed:
+
+  --output-format terraform       main.tf, variables.tf and outputs.tf
+  --output-format cloudformation  template.json, as used by deploy
+
+Each recommendation has a mode. Automatic recommendations come from a
+confident detection over enough files and export as they are. Advisory ones
+are a plausible guess, and insufficient-data ones list the hints that would
+raise confidence; both need --force to export.
+
+Available operations:
+- Right-size instances from historical CloudWatch utilization
+- Compare the recommendations for two sets of hints
+
+Examples:
+  # Recommend an environment for a sequencing run
+  aws-research-wizard recommend /data/run-42 --domain genomics
+
+  # Write the recommendation as a Terraform module
+  aws-research-wizard recommend /data/run-42 --output-format terraform --output-dir ./tf`,
+		Args: cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) == 0 {
+				cmd.Help()
+				return
+			}
+			runPlanRecommendation(cmd, args[0], flags)
+		},
+	}
+
+	recommendCmd.Flags().StringVar(&flags.Domain, "domain", "", "Research domain (detected from the data when omitted)")
+	recommendCmd.Flags().StringVar(&flags.OutputFormat, "output-format", "text", "Output format (text, json, terraform, cloudformation)")
+	recommendCmd.Flags().StringVar(&flags.OutputDir, "output-dir", "", "Directory for terraform and cloudformation output")
+	recommendCmd.Flags().StringVar(&flags.Inventory, "inventory", "", "Manifest of the dataset's files (one path per line, or an S3 Inventory CSV)")
+	recommendCmd.Flags().Float64Var(&flags.AutomaticConfidence, "automatic-confidence", intelligence.DefaultAutomaticConfidence, "Detection confidence needed to export without --force")
+	recommendCmd.Flags().BoolVar(&flags.Force, "force", false, "Export advisory and insufficient-data recommendations")
+	recommendCmd.Flags().StringVar(&flags.Compliance, "compliance", "", "Compliance profile: none, hipaa or cui/nist-800-171 (default from the domain pack)")
+
+	recommendCmd.AddCommand(
+		createRightsizeCommand(),
+		createCompareCommand(),
+	)
+
+	return recommendCmd
+}
+
+func createRightsizeCommand() *cobra.Command {
+	var stackName string
+	var instanceIDs []string
+	var days int
+	var jsonOutput bool
+
+	cmd := &cobra.Command{
+		Use:   "rightsize",
+		Short: "Recommend instance sizes from historical utilization",
+		Long: `Recommend downsizing, upsizing or a burstable instance from CloudWatch
+CPU, memory and network utilization.
+
+Memory utilization is only available when the CloudWatch agent publishes
+mem_used_percent; without it downsizing is limited to one size.
+
+Examples:
+  # Right-size the instance in a deployed stack
+  aws-research-wizard recommend rightsize --stack research-wizard-genomics
+
+  # Right-size specific instances over the last 30 days
+  aws-research-wizard recommend rightsize --instance i-0123 --instance i-0456 --days 30`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if stackName == "" && len(instanceIDs) == 0 {
+				log.Fatal("Specify --stack or at least one --instance")
+			}
+
+			ctx := context.Background()
+			region, _ := cmd.Flags().GetString("region")
+			awsClient, err := aws.NewClient(ctx, region)
+			if err != nil {
+				log.Fatalf("Failed to initialize AWS client: %v", err)
+			}
+
+			filters := map[string][]string{"instance-state-name": {"running", "stopped"}}
+			if stackName != "" {
+				filters["tag:aws:cloudformation:stack-name"] = []string{stackName}
+			} else {
+				filters["instance-id"] = instanceIDs
+			}
+
+			instances, err := aws.NewInfrastructureManager(awsClient).ListInstances(ctx, filters)
+			if err != nil {
+				log.Fatalf("Failed to list instances: %v", err)
+			}
+			if len(instances) == 0 {
+				log.Fatal("No matching instances found")
+			}
+
+			pricing, err := aws.NewPricingCalculator(region)
+			if err != nil {
+				log.Fatalf("Failed to initialize pricing: %v", err)
+			}
+			price := func(instanceType string) (float64, error) {
+				estimate, err := pricing.CalculateCost(instanceType)
+				if err != nil {
+					return 0, err
+				}
+				return estimate.HourlyCost, nil
+			}
+
+			end := time.Now().UTC()
+			start := end.AddDate(0, 0, -days)
+			collector := aws.NewUtilizationCollector(awsClient)
+			analyzer := intelligence.NewResourceAnalyzer()
+
+			var recommendations []*intelligence.RightsizingRecommendation
+			for _, instance := range instances {
+				utilization, err := collector.Collect(ctx, instance.InstanceID, start, end)
+				if err != nil {
+					log.Fatalf("Failed to collect utilization for %s: %v", instance.InstanceID, err)
+				}
+
+				rec, err := analyzer.RecommendRightsizing(instance.InstanceID, instance.InstanceType, intelligence.UtilizationSamples{
+					CPUPercent:            utilization.CPUPercent,
+					MemoryPercent:         utilization.MemoryPercent,
+					NetworkBytesPerSecond: utilization.NetworkBytesPerSecond,
+				}, price)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "⚠️  Skipping %s: %v\n", instance.InstanceID, err)
+					continue
+				}
+				recommendations = append(recommendations, rec)
+			}
+
+			if jsonOutput {
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+				if err := encoder.Encode(recommendations); err != nil {
+					log.Fatalf("Failed to encode recommendations: %v", err)
+				}
+				return
+			}
+
+			printRightsizing(recommendations, days)
+		},
+	}
+
+	cmd.Flags().StringVar(&stackName, "stack", "", "CloudFormation stack whose instances to analyze")
+	cmd.Flags().StringSliceVar(&instanceIDs, "instance", nil, "EC2 instance ID to analyze (repeatable)")
+	cmd.Flags().IntVar(&days, "days", 14, "Days of utilization history to analyze")
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "Output as JSON")
+
+	return cmd
+}
+
+func printRightsizing(recommendations []*intelligence.RightsizingRecommendation, days int) {
+	fmt.Printf("📐 Right-sizing Recommendations (last %d days):\n\n", days)
+
+	if len(recommendations) == 0 {
+		fmt.Println("No instances had enough utilization data to analyze.")
+		return
+	}
+
+	var totalSavings float64
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "INSTANCE\tCURRENT\tCPU P50/P95\tMEM P95\tACTION\tRECOMMENDED\t$/MONTH SAVED")
+	for _, rec := range recommendations {
+		memory := "n/a"
+		if rec.Memory != nil {
+			memory = fmt.Sprintf("%.0f%%", rec.Memory.P95)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%.0f%%/%.0f%%\t%s\t%s\t%s\t%.2f\n",
+			rec.InstanceID, rec.CurrentType, rec.CPU.P50, rec.CPU.P95, memory,
+			rec.Action, rec.RecommendedType, rec.MonthlySavings)
+		totalSavings += rec.MonthlySavings
+	}
+	w.Flush()
+
+	fmt.Println()
+	for _, rec := range recommendations {
+		if rec.Action == intelligence.RightsizeKeep {
+			continue
+		}
+		fmt.Printf("💡 %s: %s\n", rec.InstanceID, strings.Join(rec.Reasoning, "; "))
+	}
+
+	fmt.Printf("\n💰 Projected savings: $%.2f/month\n", totalSavings)
+}
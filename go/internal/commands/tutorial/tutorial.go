@@ -0,0 +1,283 @@
+package tutorial
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/aws"
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/config"
+)
+
+// tutorialGuardBinary is the executable name of the tutorial-guard CLI
+const tutorialGuardBinary = "tutorial-guard"
+
+// tutorialOptions holds the flags shared by all tutorial subcommands
+type tutorialOptions struct {
+	domainName string
+	stackName  string
+	binaryPath string
+}
+
+// NewTutorialCommand creates the tutorial subcommand
+func NewTutorialCommand() *cobra.Command {
+	opts := &tutorialOptions{}
+
+	tutorialCmd := &cobra.Command{
+		Use:   "tutorial",
+		Short: "Validate research documentation with tutorial-guard",
+		Long: `Scan, run and validate tutorial documentation using tutorial-guard.
+
+tutorial-guard is maintained as a separate project
+(github.com/scttfrdmn/tutorial-guard) and must be installed on PATH, or
+pointed to with --tutorial-guard or TUTORIAL_GUARD_BIN.
+
+Tutorials can be given as paths, or picked up from the "tutorials:" list
+of a domain pack with --domain. With --stack, the deployed stack's
+instance is exported to tutorial-guard so tutorials can run against it.`,
+	}
+
+	tutorialCmd.PersistentFlags().StringVar(&opts.domainName, "domain", "", "Use the tutorials declared by this research domain")
+	tutorialCmd.PersistentFlags().StringVar(&opts.stackName, "stack", "", "Run against the instance of this deployed stack")
+	tutorialCmd.PersistentFlags().StringVar(&opts.binaryPath, "tutorial-guard", "", "Path to the tutorial-guard binary")
+
+	tutorialCmd.AddCommand(
+		createPassthroughCommand(opts, "scan", "Extract runnable examples from tutorials"),
+		createPassthroughCommand(opts, "run", "Execute tutorials end to end"),
+		createPassthroughCommand(opts, "validate", "Check tutorials for documentation quality issues"),
+	)
+
+	return tutorialCmd
+}
+
+func createPassthroughCommand(opts *tutorialOptions, name, short string) *cobra.Command {
+	return &cobra.Command{
+		Use:   name + " [paths...] [-- tutorial-guard flags]",
+		Short: short,
+		Example: fmt.Sprintf(`  aws-research-wizard tutorial %[1]s docs/quickstart.md
+  aws-research-wizard tutorial %[1]s --domain genomics
+  aws-research-wizard tutorial %[1]s --domain genomics --stack research-wizard-genomics -- --verbose`, name),
+		Run: func(cmd *cobra.Command, args []string) {
+			paths, extra := splitPassthroughArgs(cmd, args)
+
+			if err := runTutorialGuard(cmd, opts, name, paths, extra); err != nil {
+				log.Fatalf("tutorial %s failed: %v", name, err)
+			}
+		},
+	}
+}
+
+// splitPassthroughArgs separates tutorial paths from arguments given after "--"
+func splitPassthroughArgs(cmd *cobra.Command, args []string) ([]string, []string) {
+	dash := cmd.ArgsLenAtDash()
+	if dash < 0 {
+		return args, nil
+	}
+	// Cap the paths so appending domain tutorials cannot overwrite the extra arguments
+	return args[:dash:dash], args[dash:]
+}
+
+func runTutorialGuard(cmd *cobra.Command, opts *tutorialOptions, subcommand string, paths, extra []string) error {
+	binary, err := findTutorialGuard(opts.binaryPath)
+	if err != nil {
+		return err
+	}
+
+	if opts.domainName != "" {
+		configRoot, _ := cmd.Flags().GetString("config-root")
+		if configRoot == "" {
+			if configRoot, err = locateConfigRoot(); err != nil {
+				return err
+			}
+		}
+
+		domainTutorials, err := resolveDomainTutorials(configRoot, opts.domainName)
+		if err != nil {
+			return err
+		}
+		paths = append(paths, domainTutorials...)
+	}
+
+	if len(paths) == 0 {
+		return fmt.Errorf("no tutorials specified; pass paths or use --domain")
+	}
+
+	region, err := tutorialRegion(cmd)
+	if err != nil {
+		return err
+	}
+	debug, _ := cmd.Flags().GetBool("debug")
+
+	// tutorial-guard runs in the region the wizard resolved, not only one given by --region
+	env := map[string]string{
+		"AWS_REGION": region,
+	}
+	if debug {
+		env["TUTORIAL_GUARD_DEBUG"] = "true"
+	}
+
+	if opts.stackName != "" {
+		stackEnv, err := resolveStackEnvironment(cmd.Context(), region, opts.stackName)
+		if err != nil {
+			return err
+		}
+		for key, value := range stackEnv {
+			env[key] = value
+		}
+	}
+
+	guardCmd := exec.CommandContext(cmd.Context(), binary, buildTutorialGuardArgs(subcommand, paths, extra)...)
+	guardCmd.Env = mergeEnvironment(os.Environ(), env)
+	guardCmd.Stdin = os.Stdin
+	guardCmd.Stdout = os.Stdout
+	guardCmd.Stderr = os.Stderr
+
+	return guardCmd.Run()
+}
+
+// tutorialRegion resolves the region tutorial-guard runs in: the --region flag
+// when given, else the environment, the profile, or the default
+func tutorialRegion(cmd *cobra.Command) (string, error) {
+	flag := ""
+	if regionFlag := cmd.Flags().Lookup("region"); regionFlag != nil && regionFlag.Changed {
+		flag = regionFlag.Value.String()
+	}
+
+	resolved, err := aws.RegionResolver{}.Resolve(cmd.Context(), flag)
+	if err != nil {
+		return "", err
+	}
+	return resolved.Region, nil
+}
+
+// findTutorialGuard locates the tutorial-guard executable
+func findTutorialGuard(explicitPath string) (string, error) {
+	candidate := explicitPath
+	if candidate == "" {
+		candidate = os.Getenv("TUTORIAL_GUARD_BIN")
+	}
+	if candidate == "" {
+		candidate = tutorialGuardBinary
+	}
+
+	path, err := exec.LookPath(candidate)
+	if err != nil {
+		return "", fmt.Errorf("tutorial-guard not found (%s); install it from github.com/scttfrdmn/tutorial-guard or set --tutorial-guard", candidate)
+	}
+
+	return path, nil
+}
+
+// resolveDomainTutorials returns the tutorials declared by a domain pack, relative paths
+// being resolved against the config root
+func resolveDomainTutorials(configRoot, domainName string) ([]string, error) {
+	loader := config.NewConfigLoader(configRoot)
+	domains, err := loader.LoadAllDomains()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load domains: %w", err)
+	}
+
+	domain, exists := domains[domainName]
+	if !exists {
+		return nil, fmt.Errorf("domain '%s' not found", domainName)
+	}
+
+	if len(domain.Tutorials) == 0 {
+		return nil, fmt.Errorf("domain '%s' does not declare any tutorials", domainName)
+	}
+
+	tutorials := make([]string, 0, len(domain.Tutorials))
+	for _, tutorial := range domain.Tutorials {
+		if !filepath.IsAbs(tutorial) {
+			tutorial = filepath.Join(configRoot, tutorial)
+		}
+		tutorials = append(tutorials, tutorial)
+	}
+
+	return tutorials, nil
+}
+
+// resolveStackEnvironment describes a deployed stack for tutorial-guard's AWS environment
+func resolveStackEnvironment(ctx context.Context, region, stackName string) (map[string]string, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	awsClient, err := aws.NewClient(ctx, region)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize AWS client: %w", err)
+	}
+
+	stackInfo, err := aws.NewInfrastructureManager(awsClient).GetStackInfo(ctx, stackName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stack info: %w", err)
+	}
+
+	return stackEnvironment(stackInfo)
+}
+
+// stackEnvironment maps stack outputs to the variables exported to tutorial-guard
+func stackEnvironment(stackInfo *aws.StackInfo) (map[string]string, error) {
+	instanceID, err := stackInfo.InstanceID()
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]string{
+		"TUTORIAL_GUARD_ENVIRONMENT":  "aws",
+		"RESEARCH_WIZARD_STACK":       stackInfo.StackName,
+		"RESEARCH_WIZARD_INSTANCE_ID": instanceID,
+	}, nil
+}
+
+// buildTutorialGuardArgs assembles the tutorial-guard command line
+func buildTutorialGuardArgs(subcommand string, paths, extra []string) []string {
+	args := make([]string, 0, 1+len(extra)+len(paths))
+	args = append(args, subcommand)
+	args = append(args, extra...)
+	args = append(args, paths...)
+	return args
+}
+
+// mergeEnvironment overlays variables onto a base environment
+func mergeEnvironment(base []string, overrides map[string]string) []string {
+	merged := make([]string, 0, len(base)+len(overrides))
+	for _, entry := range base {
+		key, _, _ := strings.Cut(entry, "=")
+		if _, overridden := overrides[key]; !overridden {
+			merged = append(merged, entry)
+		}
+	}
+
+	for key, value := range overrides {
+		merged = append(merged, key+"="+value)
+	}
+
+	return merged
+}
+
+// locateConfigRoot looks for a configs directory in the current directory and its parents
+func locateConfigRoot() (string, error) {
+	currentDir, err := os.Getwd()
+	if err != nil {
+		return "", fmt.Errorf("failed to get current directory: %w", err)
+	}
+
+	for {
+		if _, err := os.Stat(filepath.Join(currentDir, "configs")); err == nil {
+			return currentDir, nil
+		}
+
+		parent := filepath.Dir(currentDir)
+		if parent == currentDir {
+			return "", fmt.Errorf("could not find configs directory; specify it with --config-root")
+		}
+		currentDir = parent
+	}
+}
@@ -0,0 +1,156 @@
+package tutorial
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+// fakeTutorialGuard records the arguments and environment it is run with
+const fakeTutorialGuard = `#!/bin/sh
+printf '%s\n' "$@" > "$TUTORIAL_GUARD_RECORD.args"
+printf 'AWS_REGION=%s\nTUTORIAL_GUARD_DEBUG=%s\n' "$AWS_REGION" "$TUTORIAL_GUARD_DEBUG" > "$TUTORIAL_GUARD_RECORD.env"
+`
+
+// installFakeTutorialGuard puts the fake tutorial-guard first on PATH and
+// returns the prefix of the files it records to
+func installFakeTutorialGuard(t *testing.T) string {
+	t.Helper()
+	binDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(binDir, tutorialGuardBinary), []byte(fakeTutorialGuard), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv("TUTORIAL_GUARD_BIN", "")
+
+	record := filepath.Join(t.TempDir(), "record")
+	t.Setenv("TUTORIAL_GUARD_RECORD", record)
+	return record
+}
+
+// writeTutorialDomain creates a config root with a genomics domain declaring tutorials
+func writeTutorialDomain(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	domainsDir := filepath.Join(root, "configs", "domains")
+	if err := os.MkdirAll(domainsDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	domain := `name: genomics
+description: Genome analysis
+tutorials:
+  - docs/quickstart.md
+  - /srv/tutorials/alignment.md
+`
+	if err := os.WriteFile(filepath.Join(domainsDir, "genomics.yaml"), []byte(domain), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return root
+}
+
+// newTestRootCommand mirrors the global flags the main binary defines
+func newTestRootCommand() *cobra.Command {
+	rootCmd := &cobra.Command{Use: "aws-research-wizard"}
+	rootCmd.PersistentFlags().String("region", "", "AWS region")
+	rootCmd.PersistentFlags().Bool("debug", false, "Enable debug logging")
+	rootCmd.PersistentFlags().String("config-root", "", "Configuration root directory")
+	rootCmd.AddCommand(NewTutorialCommand())
+	return rootCmd
+}
+
+func readRecord(t *testing.T, path string) string {
+	t.Helper()
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("tutorial-guard was not run: %v", err)
+	}
+	return string(content)
+}
+
+func TestTutorialRunDomain(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake tutorial-guard is a shell script")
+	}
+
+	tests := []struct {
+		name       string
+		args       []string
+		envRegion  string
+		wantRegion string
+		wantDebug  string
+	}{
+		{
+			name:       "region from the environment",
+			args:       []string{"--debug"},
+			envRegion:  "eu-west-1",
+			wantRegion: "eu-west-1",
+			wantDebug:  "true",
+		},
+		{
+			name:       "region flag overrides the environment",
+			args:       []string{"--region", "ap-southeast-2"},
+			envRegion:  "eu-west-1",
+			wantRegion: "ap-southeast-2",
+		},
+		{
+			name:       "no region configured",
+			wantRegion: "us-east-1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			record := installFakeTutorialGuard(t)
+			root := writeTutorialDomain(t)
+			t.Setenv("AWS_REGION", tt.envRegion)
+			t.Setenv("AWS_DEFAULT_REGION", "")
+			t.Setenv("AWS_PROFILE", "")
+			t.Setenv("AWS_CONFIG_FILE", filepath.Join(t.TempDir(), "config"))
+			t.Setenv("TUTORIAL_GUARD_DEBUG", "")
+
+			args := append([]string{"tutorial", "run", "--domain", "genomics", "--config-root", root}, tt.args...)
+			args = append(args, "--", "--verbose")
+			rootCmd := newTestRootCommand()
+			rootCmd.SetArgs(args)
+			if err := rootCmd.Execute(); err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+
+			wantArgs := strings.Join([]string{
+				"run",
+				"--verbose",
+				filepath.Join(root, "docs/quickstart.md"),
+				"/srv/tutorials/alignment.md",
+			}, "\n") + "\n"
+			if got := readRecord(t, record+".args"); got != wantArgs {
+				t.Errorf("tutorial-guard arguments = %q, want %q", got, wantArgs)
+			}
+
+			wantEnv := "AWS_REGION=" + tt.wantRegion + "\nTUTORIAL_GUARD_DEBUG=" + tt.wantDebug + "\n"
+			if got := readRecord(t, record+".env"); got != wantEnv {
+				t.Errorf("tutorial-guard environment = %q, want %q", got, wantEnv)
+			}
+		})
+	}
+}
+
+func TestResolveDomainTutorialsErrors(t *testing.T) {
+	root := writeTutorialDomain(t)
+	if err := os.WriteFile(filepath.Join(root, "configs", "domains", "chemistry.yaml"), []byte("name: chemistry\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for domain, wantErr := range map[string]string{
+		"missing":   "domain 'missing' not found",
+		"chemistry": "does not declare any tutorials",
+	} {
+		_, err := resolveDomainTutorials(root, domain)
+		if err == nil || !strings.Contains(err.Error(), wantErr) {
+			t.Errorf("resolveDomainTutorials(%q) error = %v, want one containing %q", domain, err, wantErr)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DataSource describes a dataset a domain works with, usually an AWS Open Data
+// bucket. Domain packs may list a source as a plain "Name - Description"
+// string, which leaves the location to be looked up in the Open Data catalog.
+type DataSource struct {
+	Name        string `yaml:"name"`
+	Bucket      string `yaml:"bucket,omitempty"`
+	Prefix      string `yaml:"prefix,omitempty"`
+	Description string `yaml:"description,omitempty"`
+	// SizeTB is the approximate size of the dataset
+	SizeTB      float64 `yaml:"size_tb,omitempty"`
+	License     string  `yaml:"license,omitempty"`
+	RegistryURL string  `yaml:"registry_url,omitempty"`
+}
+
+// UnmarshalYAML accepts either a structured entry or a plain string
+func (s *DataSource) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		name, description, _ := strings.Cut(node.Value, " - ")
+		*s = DataSource{Name: strings.TrimSpace(name), Description: strings.TrimSpace(description)}
+		return nil
+	}
+
+	// The alias type has no UnmarshalYAML, so decoding into it does not recurse
+	type plain DataSource
+	return node.Decode((*plain)(s))
+}
+
+// Location returns the source's S3 URI, or "" when it names no bucket
+func (s DataSource) Location() string {
+	if s.Bucket == "" {
+		return ""
+	}
+	return "s3://" + s.Bucket + "/" + strings.TrimPrefix(s.Prefix, "/")
+}
+
+// DataSources returns the domain's aws_data_sources followed by those listed
+// under aws_integration, skipping names listed in both
+func (d *DomainPack) DataSources() []DataSource {
+	sources := make([]DataSource, 0, len(d.AWSDataSources)+len(d.AWSIntegration.DataSources))
+	seen := make(map[string]bool)
+	for _, list := range [][]DataSource{d.AWSDataSources, d.AWSIntegration.DataSources} {
+		for _, source := range list {
+			key := strings.ToLower(source.Name)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			sources = append(sources, source)
+		}
+	}
+	return sources
+}
+
+// DataSourceProblems lists the schema errors in the domain's data sources
+func (d *DomainPack) DataSourceProblems() []string {
+	var problems []string
+	for i, source := range d.AWSDataSources {
+		label := fmt.Sprintf("aws_data_sources[%d]", i)
+		if source.Name != "" {
+			label = fmt.Sprintf("aws_data_sources %q", source.Name)
+		}
+
+		if strings.TrimSpace(source.Name) == "" {
+			problems = append(problems, label+": name is required")
+		}
+		if source.Prefix != "" && source.Bucket == "" {
+			problems = append(problems, label+": prefix needs a bucket")
+		}
+		if strings.HasPrefix(source.Bucket, "s3://") || strings.Contains(source.Bucket, "/") {
+			problems = append(problems, label+": bucket must be a bucket name, with any path in prefix")
+		}
+		if source.SizeTB < 0 {
+			problems = append(problems, label+": size_tb must not be negative")
+		}
+	}
+	return problems
+}
@@ -0,0 +1,98 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestDataSourceUnmarshalMixed(t *testing.T) {
+	content := `
+aws_data_sources:
+- 1000 Genomes Project - International genome sequencing consortium data
+- NCBI Sequence Read Archive
+- name: Genome Aggregation Database
+  bucket: gnomad-public-us-east-1
+  prefix: release/
+  description: Population genomics variant database
+  size_tb: 45
+  license: ODbL
+  registry_url: https://registry.opendata.aws/broad-gnomad/
+aws_integration:
+  data_sources: [surveys, NCBI Sequence Read Archive]
+`
+	var domain DomainPack
+	if err := yaml.Unmarshal([]byte(content), &domain); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+
+	want := []DataSource{
+		{Name: "1000 Genomes Project", Description: "International genome sequencing consortium data"},
+		{Name: "NCBI Sequence Read Archive"},
+		{
+			Name:        "Genome Aggregation Database",
+			Bucket:      "gnomad-public-us-east-1",
+			Prefix:      "release/",
+			Description: "Population genomics variant database",
+			SizeTB:      45,
+			License:     "ODbL",
+			RegistryURL: "https://registry.opendata.aws/broad-gnomad/",
+		},
+		{Name: "surveys"},
+	}
+	sources := domain.DataSources()
+	if len(sources) != len(want) {
+		t.Fatalf("DataSources() = %+v", sources)
+	}
+	for i := range want {
+		if sources[i] != want[i] {
+			t.Errorf("DataSources()[%d] = %+v, want %+v", i, sources[i], want[i])
+		}
+	}
+
+	if got := sources[2].Location(); got != "s3://gnomad-public-us-east-1/release/" {
+		t.Errorf("Location() = %q", got)
+	}
+	if got := sources[0].Location(); got != "" {
+		t.Errorf("Location() without a bucket = %q", got)
+	}
+}
+
+func TestDataSourceProblems(t *testing.T) {
+	domain := DomainPack{AWSDataSources: []DataSource{
+		{Name: "ok", Bucket: "open-data"},
+		{Bucket: "nameless"},
+		{Name: "orphan", Prefix: "runs/"},
+		{Name: "uri", Bucket: "s3://open-data/runs"},
+		{Name: "negative", Bucket: "open-data", SizeTB: -1},
+	}}
+
+	want := []string{
+		"aws_data_sources[1]: name is required",
+		`aws_data_sources "orphan": prefix needs a bucket`,
+		`aws_data_sources "uri": bucket must be a bucket name, with any path in prefix`,
+		`aws_data_sources "negative": size_tb must not be negative`,
+	}
+	if problems := domain.DataSourceProblems(); strings.Join(problems, "\n") != strings.Join(want, "\n") {
+		t.Errorf("DataSourceProblems() =\n%s\nwant\n%s", strings.Join(problems, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestLoadShippedDomainDataSources(t *testing.T) {
+	// Every shipped domain pack must still load, whichever form its sources take
+	root := filepath.Join("..", "..", "..")
+	if _, err := os.Stat(filepath.Join(root, "configs", "domains")); err != nil {
+		t.Skip("configs directory not available")
+	}
+	domains, err := NewConfigLoader(root).LoadAllDomains()
+	if err != nil {
+		t.Fatalf("LoadAllDomains() error = %v", err)
+	}
+	genomics, exists := domains["genomics"]
+	if !exists || len(genomics.DataSources()) == 0 {
+		t.Fatalf("genomics domain has no data sources")
+	}
+}
@@ -5,7 +5,9 @@ import (
 	"io/fs"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -14,6 +16,7 @@ import (
 type DomainPack struct {
 	Name                       string                            `yaml:"name"`
 	Description                string                            `yaml:"description"`
+	Version                    string                            `yaml:"version"`
 	PrimaryDomains             []string                          `yaml:"primary_domains"`
 	TargetUsers                string                            `yaml:"target_users"`
 	SpackPackages              map[string]interface{}            `yaml:"spack_packages"`
@@ -25,6 +28,99 @@ type DomainPack struct {
 	EstimatedCost              EstimatedCost                     `yaml:"estimated_cost"`
 	WorkflowOrchestration      WorkflowOrchestration             `yaml:"workflow_orchestration"`
 	AWSIntegration             AWSIntegration                    `yaml:"aws_integration"`
+	AWSDataSources             []DataSource                      `yaml:"aws_data_sources"`
+	Tutorials                  []string                          `yaml:"tutorials"`
+	DemoWorkflows              []DemoWorkflow                    `yaml:"demo_workflows"`
+	Validation                 []ValidationCheck                 `yaml:"validation"`
+	// Deprecated packs still deploy, with a warning naming ReplacedBy, the
+	// domain to use instead, and SunsetDate, when the pack will be removed
+	Deprecated bool   `yaml:"deprecated"`
+	ReplacedBy string `yaml:"replaced_by"`
+	SunsetDate string `yaml:"sunset_date"`
+}
+
+// sunsetDateLayout is the format of a domain pack's sunset_date
+const sunsetDateLayout = "2006-01-02"
+
+// DeprecationProblems lists the schema errors in the domain's deprecation
+// fields. Whether replaced_by names an existing domain is checked by config
+// validate, which sees every domain.
+func (d *DomainPack) DeprecationProblems() []string {
+	var problems []string
+	if !d.Deprecated {
+		if d.ReplacedBy != "" {
+			problems = append(problems, "replaced_by is only allowed on a deprecated domain")
+		}
+		if d.SunsetDate != "" {
+			problems = append(problems, "sunset_date is only allowed on a deprecated domain")
+		}
+	}
+	if d.SunsetDate != "" {
+		if _, err := time.Parse(sunsetDateLayout, d.SunsetDate); err != nil {
+			problems = append(problems, fmt.Sprintf("sunset_date %q must be a date such as 2026-12-31", d.SunsetDate))
+		}
+	}
+	return problems
+}
+
+// Sunset returns the date the deprecated domain will be removed, if set
+func (d *DomainPack) Sunset() (time.Time, bool) {
+	if d.SunsetDate == "" {
+		return time.Time{}, false
+	}
+	sunset, err := time.Parse(sunsetDateLayout, d.SunsetDate)
+	return sunset, err == nil
+}
+
+// DeprecationNotice describes a deprecated domain's replacement and removal
+// timeline as of now, or returns "" for a domain that is not deprecated
+func (d *DomainPack) DeprecationNotice(name string, now time.Time) string {
+	if !d.Deprecated {
+		return ""
+	}
+	notice := fmt.Sprintf("Domain %s is deprecated", name)
+	if sunset, ok := d.Sunset(); ok {
+		today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+		switch days := int(sunset.Sub(today).Hours() / 24); {
+		case days > 1:
+			notice += fmt.Sprintf(" and will be removed on %s (in %d days)", d.SunsetDate, days)
+		case days == 1:
+			notice += fmt.Sprintf(" and will be removed on %s (tomorrow)", d.SunsetDate)
+		case days == 0:
+			notice += fmt.Sprintf(" and will be removed today (%s)", d.SunsetDate)
+		default:
+			notice += fmt.Sprintf(" and was due to be removed on %s", d.SunsetDate)
+		}
+	}
+	if d.ReplacedBy != "" {
+		notice += fmt.Sprintf("; use %s instead", d.ReplacedBy)
+	}
+	return notice
+}
+
+// Replacement follows replaced_by from a deprecated domain to the domain that
+// replaces it, through any replacements that are themselves deprecated.
+// It returns name unchanged for a domain without a replacement.
+func Replacement(domains map[string]*DomainPack, name string) (string, error) {
+	seen := map[string]bool{name: true}
+	current := name
+	for {
+		domain, exists := domains[current]
+		if !exists {
+			return "", fmt.Errorf("domain '%s' not found", current)
+		}
+		if !domain.Deprecated || domain.ReplacedBy == "" {
+			return current, nil
+		}
+		if seen[domain.ReplacedBy] {
+			return "", fmt.Errorf("replaced_by of domain %s leads back to %s", current, domain.ReplacedBy)
+		}
+		if _, exists := domains[domain.ReplacedBy]; !exists {
+			return "", fmt.Errorf("domain %s is replaced by %s, which does not exist", current, domain.ReplacedBy)
+		}
+		seen[domain.ReplacedBy] = true
+		current = domain.ReplacedBy
+	}
 }
 
 // InstanceRecommendation represents AWS instance recommendations
@@ -35,6 +131,182 @@ type InstanceRecommendation struct {
 	MemoryGB     int     `yaml:"memory_gb"`
 	StorageGB    int     `yaml:"storage_gb"`
 	CostPerHour  float64 `yaml:"cost_per_hour"`
+	// Architecture is the CPU architecture, x86_64 or arm64; empty derives it
+	// from the instance type
+	Architecture string `yaml:"architecture"`
+}
+
+// RecommendationProblems lists the schema errors in the domain's instance
+// recommendations
+func (d *DomainPack) RecommendationProblems() []string {
+	keys := make([]string, 0, len(d.AWSInstanceRecommendations))
+	for key := range d.AWSInstanceRecommendations {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var problems []string
+	for _, key := range keys {
+		switch arch := d.AWSInstanceRecommendations[key].Architecture; arch {
+		case "", "x86_64", "arm64":
+		default:
+			problems = append(problems, fmt.Sprintf("aws_instance_recommendations %q: architecture %q must be x86_64 or arm64", key, arch))
+		}
+	}
+	return problems
+}
+
+// DemoWorkflow describes a runnable demonstration workflow
+type DemoWorkflow struct {
+	Name            string  `yaml:"name"`
+	Description     string  `yaml:"description"`
+	Dataset         string  `yaml:"dataset"`
+	ExpectedRuntime string  `yaml:"expected_runtime"`
+	CostEstimate    float64 `yaml:"cost_estimate"`
+}
+
+// ValidationCheck is a smoke test run on a deployed instance to confirm the
+// research environment works, not just that its stack finished
+type ValidationCheck struct {
+	Name    string `yaml:"name"`
+	Command string `yaml:"command"`
+	// ExpectExitCode is the exit code the command must return, 0 by default
+	ExpectExitCode int `yaml:"expect_exit_code"`
+	// ExpectOutput must appear in the command's combined stdout and stderr
+	ExpectOutput   string `yaml:"expect_output"`
+	TimeoutSeconds int    `yaml:"timeout_seconds"`
+	// Optional checks are reported but do not fail verification
+	Optional bool `yaml:"optional"`
+}
+
+// DisplayName returns the check's name, or its command when it has none
+func (c ValidationCheck) DisplayName() string {
+	if c.Name != "" {
+		return c.Name
+	}
+	return c.Command
+}
+
+// Limits on validation checks
+const (
+	DefaultValidationTimeoutSeconds = 120
+	maxValidationTimeoutSeconds     = 3600
+)
+
+// Timeout returns how long the check may run
+func (c ValidationCheck) Timeout() time.Duration {
+	if c.TimeoutSeconds > 0 {
+		return time.Duration(c.TimeoutSeconds) * time.Second
+	}
+	return DefaultValidationTimeoutSeconds * time.Second
+}
+
+// ValidationProblems lists the schema errors in the domain's validation checks
+func (d *DomainPack) ValidationProblems() []string {
+	var problems []string
+	names := make(map[string]bool, len(d.Validation))
+	for i, check := range d.Validation {
+		label := fmt.Sprintf("validation[%d]", i)
+		if check.Name != "" {
+			label = fmt.Sprintf("validation %q", check.Name)
+		}
+
+		if strings.TrimSpace(check.Command) == "" {
+			problems = append(problems, label+": command is required")
+		}
+		if check.ExpectExitCode < 0 || check.ExpectExitCode > 255 {
+			problems = append(problems, label+": expect_exit_code must be between 0 and 255")
+		}
+		if strings.ContainsAny(check.ExpectOutput, "\r\n") {
+			problems = append(problems, label+": expect_output must be a single line")
+		}
+		if check.TimeoutSeconds < 0 || check.TimeoutSeconds > maxValidationTimeoutSeconds {
+			problems = append(problems, fmt.Sprintf("%s: timeout_seconds must be between 1 and %d", label, maxValidationTimeoutSeconds))
+		}
+
+		name := check.DisplayName()
+		if name != "" && names[name] {
+			problems = append(problems, fmt.Sprintf("%s: duplicate check name %q", label, name))
+		}
+		names[name] = true
+	}
+	return problems
+}
+
+// WorkloadSizes are the workload size classes used by intelligent recommendations,
+// smallest first
+var WorkloadSizes = []string{"small", "medium", "large", "massive"}
+
+// InstanceTypesByWorkloadSize maps each workload size to one of the domain's
+// instance recommendations. Recommendations are ordered by memory, then vCPUs,
+// then instance type and key; the smallest serves "small", the largest "massive",
+// and sizes in between take the recommendation at the proportional position,
+// rounded to the nearest. A domain with one recommendation uses it for every size.
+func (d *DomainPack) InstanceTypesByWorkloadSize() map[string]string {
+	keys := make([]string, 0, len(d.AWSInstanceRecommendations))
+	for key, rec := range d.AWSInstanceRecommendations {
+		if rec.InstanceType != "" {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		return map[string]string{}
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := d.AWSInstanceRecommendations[keys[i]], d.AWSInstanceRecommendations[keys[j]]
+		if a.MemoryGB != b.MemoryGB {
+			return a.MemoryGB < b.MemoryGB
+		}
+		if a.VCPUs != b.VCPUs {
+			return a.VCPUs < b.VCPUs
+		}
+		if a.InstanceType != b.InstanceType {
+			return a.InstanceType < b.InstanceType
+		}
+		return keys[i] < keys[j]
+	})
+
+	sizes := make(map[string]string, len(WorkloadSizes))
+	last := len(WorkloadSizes) - 1
+	for i, size := range WorkloadSizes {
+		index := (i*(len(keys)-1) + last/2) / last
+		sizes[size] = d.AWSInstanceRecommendations[keys[index]].InstanceType
+	}
+	return sizes
+}
+
+// StandardUseCase is the use case typical workloads are costed at
+const StandardUseCase = "standard"
+
+// Recommendation returns the key and instance recommendation for a use case.
+// The standard use case also matches keys such as standard_analysis and
+// otherwise falls back to the recommendation serving medium workloads.
+func (d *DomainPack) Recommendation(useCase string) (string, InstanceRecommendation, bool) {
+	if rec, ok := d.AWSInstanceRecommendations[useCase]; ok && rec.InstanceType != "" {
+		return useCase, rec, true
+	}
+	if useCase != StandardUseCase {
+		return "", InstanceRecommendation{}, false
+	}
+
+	keys := make([]string, 0, len(d.AWSInstanceRecommendations))
+	for key := range d.AWSInstanceRecommendations {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if rec := d.AWSInstanceRecommendations[key]; strings.HasPrefix(key, StandardUseCase+"_") && rec.InstanceType != "" {
+			return key, rec, true
+		}
+	}
+	medium := d.InstanceTypesByWorkloadSize()["medium"]
+	for _, key := range keys {
+		if rec := d.AWSInstanceRecommendations[key]; medium != "" && rec.InstanceType == medium {
+			return key, rec, true
+		}
+	}
+	return "", InstanceRecommendation{}, false
 }
 
 // EstimatedCost represents cost breakdown
@@ -42,6 +314,11 @@ type EstimatedCost struct {
 	Compute float64 `yaml:"compute"`
 	Storage float64 `yaml:"storage"`
 	Total   float64 `yaml:"total"`
+	// TypicalDatasetGB is the data a typical project keeps, which storage
+	// cost is recalculated from
+	TypicalDatasetGB float64 `yaml:"typical_dataset_gb"`
+	// Other holds the remaining entries, such as data_transfer, as written
+	Other map[string]interface{} `yaml:",inline"`
 }
 
 // WorkflowOrchestration represents workflow tools
@@ -59,10 +336,16 @@ type WorkflowTool struct {
 
 // AWSIntegration represents AWS-specific configurations
 type AWSIntegration struct {
-	DataSources     []string               `yaml:"data_sources"`
+	DataSources     []DataSource           `yaml:"data_sources"`
 	StoragePatterns []string               `yaml:"storage_patterns"`
 	OptimizedFor    []string               `yaml:"optimized_for"`
 	CostStrategy    map[string]interface{} `yaml:"cost_strategy"`
+	// Architecture names the deployment template, e.g. single, head-compute or
+	// container-host; empty means single
+	Architecture string `yaml:"architecture"`
+	// ComplianceProfile names the controls deployments must meet, e.g. hipaa
+	// or cui; empty means none
+	ComplianceProfile string `yaml:"compliance_profile"`
 }
 
 // ConfigLoader handles loading domain configurations
@@ -79,7 +362,26 @@ func NewConfigLoader(configRoot string) *ConfigLoader {
 
 // LoadAllDomains loads all domain pack configurations
 func (cl *ConfigLoader) LoadAllDomains() (map[string]*DomainPack, error) {
-	domains := make(map[string]*DomainPack)
+	files, err := cl.DomainFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	domains := make(map[string]*DomainPack, len(files))
+	for domainName, path := range files {
+		domain, err := cl.LoadDomain(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load domain %s: %w", path, err)
+		}
+		domains[domainName] = domain
+	}
+
+	return domains, nil
+}
+
+// DomainFiles returns the path of each domain pack file, keyed by domain name
+func (cl *ConfigLoader) DomainFiles() (map[string]string, error) {
+	files := make(map[string]string)
 	domainsPath := filepath.Join(cl.configRoot, "configs", "domains")
 
 	err := filepath.WalkDir(domainsPath, func(path string, d fs.DirEntry, err error) error {
@@ -91,14 +393,7 @@ func (cl *ConfigLoader) LoadAllDomains() (map[string]*DomainPack, error) {
 			return nil
 		}
 
-		domain, err := cl.LoadDomain(path)
-		if err != nil {
-			return fmt.Errorf("failed to load domain %s: %w", path, err)
-		}
-
-		domainName := strings.TrimSuffix(d.Name(), ".yaml")
-		domains[domainName] = domain
-
+		files[strings.TrimSuffix(d.Name(), ".yaml")] = path
 		return nil
 	})
 
@@ -106,7 +401,7 @@ func (cl *ConfigLoader) LoadAllDomains() (map[string]*DomainPack, error) {
 		return nil, fmt.Errorf("failed to walk domains directory: %w", err)
 	}
 
-	return domains, nil
+	return files, nil
 }
 
 // LoadDomain loads a single domain pack configuration
@@ -122,6 +417,13 @@ func (cl *ConfigLoader) LoadDomain(path string) (*DomainPack, error) {
 		return nil, fmt.Errorf("failed to unmarshal YAML: %w", err)
 	}
 
+	problems := append(domain.ValidationProblems(), domain.DataSourceProblems()...)
+	problems = append(problems, domain.RecommendationProblems()...)
+	problems = append(problems, domain.DeprecationProblems()...)
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("invalid domain pack:\n  - %s", strings.Join(problems, "\n  - "))
+	}
+
 	return &domain, nil
 }
 
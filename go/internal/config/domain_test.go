@@ -0,0 +1,293 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestInstanceTypesByWorkloadSize(t *testing.T) {
+	recs := map[string]InstanceRecommendation{
+		"tiny":    {InstanceType: "c6i.large", VCPUs: 2, MemoryGB: 4},
+		"compute": {InstanceType: "c6i.2xlarge", VCPUs: 8, MemoryGB: 16},
+		"memory":  {InstanceType: "r6i.2xlarge", VCPUs: 8, MemoryGB: 64},
+		"large":   {InstanceType: "r6i.8xlarge", VCPUs: 32, MemoryGB: 256},
+		"huge":    {InstanceType: "x2iezn.8xlarge", VCPUs: 32, MemoryGB: 1024},
+	}
+
+	pick := func(keys ...string) map[string]InstanceRecommendation {
+		subset := make(map[string]InstanceRecommendation, len(keys))
+		for _, key := range keys {
+			subset[key] = recs[key]
+		}
+		return subset
+	}
+
+	tests := []struct {
+		name string
+		recs map[string]InstanceRecommendation
+		want map[string]string
+	}{
+		{
+			name: "none",
+			recs: nil,
+			want: map[string]string{},
+		},
+		{
+			name: "one",
+			recs: pick("memory"),
+			want: map[string]string{"small": "r6i.2xlarge", "medium": "r6i.2xlarge", "large": "r6i.2xlarge", "massive": "r6i.2xlarge"},
+		},
+		{
+			name: "two",
+			recs: pick("large", "tiny"),
+			want: map[string]string{"small": "c6i.large", "medium": "c6i.large", "large": "r6i.8xlarge", "massive": "r6i.8xlarge"},
+		},
+		{
+			name: "three",
+			recs: pick("tiny", "memory", "large"),
+			want: map[string]string{"small": "c6i.large", "medium": "r6i.2xlarge", "large": "r6i.2xlarge", "massive": "r6i.8xlarge"},
+		},
+		{
+			name: "four",
+			recs: pick("tiny", "compute", "memory", "large"),
+			want: map[string]string{"small": "c6i.large", "medium": "c6i.2xlarge", "large": "r6i.2xlarge", "massive": "r6i.8xlarge"},
+		},
+		{
+			name: "five",
+			recs: recs,
+			want: map[string]string{"small": "c6i.large", "medium": "c6i.2xlarge", "large": "r6i.8xlarge", "massive": "x2iezn.8xlarge"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			domain := &DomainPack{AWSInstanceRecommendations: tt.recs}
+
+			// Map iteration order must not affect the result
+			for i := 0; i < 10; i++ {
+				if got := domain.InstanceTypesByWorkloadSize(); !reflect.DeepEqual(got, tt.want) {
+					t.Fatalf("InstanceTypesByWorkloadSize() = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestInstanceTypesByWorkloadSizeTieBreak(t *testing.T) {
+	domain := &DomainPack{AWSInstanceRecommendations: map[string]InstanceRecommendation{
+		"b": {InstanceType: "m6i.2xlarge", VCPUs: 8, MemoryGB: 32},
+		"a": {InstanceType: "m6a.2xlarge", VCPUs: 8, MemoryGB: 32},
+	}}
+
+	sizes := domain.InstanceTypesByWorkloadSize()
+	if sizes["small"] != "m6a.2xlarge" || sizes["massive"] != "m6i.2xlarge" {
+		t.Errorf("expected equal specs to order by instance type, got %v", sizes)
+	}
+}
+
+func TestRecommendation(t *testing.T) {
+	domain := &DomainPack{AWSInstanceRecommendations: map[string]InstanceRecommendation{
+		"development":       {InstanceType: "c6i.large", VCPUs: 2, MemoryGB: 4},
+		"standard_analysis": {InstanceType: "r6i.4xlarge", VCPUs: 16, MemoryGB: 128},
+		"large_cohort":      {InstanceType: "r6i.8xlarge", VCPUs: 32, MemoryGB: 256},
+	}}
+
+	if key, rec, ok := domain.Recommendation("large_cohort"); !ok || key != "large_cohort" || rec.InstanceType != "r6i.8xlarge" {
+		t.Errorf("Recommendation(large_cohort) = %s, %+v, %v", key, rec, ok)
+	}
+	if key, _, ok := domain.Recommendation(StandardUseCase); !ok || key != "standard_analysis" {
+		t.Errorf("standard should match standard_analysis, got %s, %v", key, ok)
+	}
+	if _, _, ok := domain.Recommendation("gpu"); ok {
+		t.Error("an unknown use case should not match")
+	}
+
+	// Without a standard_ key the medium workload recommendation is used
+	delete(domain.AWSInstanceRecommendations, "standard_analysis")
+	domain.AWSInstanceRecommendations["memory_intensive"] = InstanceRecommendation{InstanceType: "r6i.2xlarge", VCPUs: 8, MemoryGB: 64}
+	if key, _, ok := domain.Recommendation(StandardUseCase); !ok || key != "memory_intensive" {
+		t.Errorf("standard should fall back to the medium recommendation, got %s, %v", key, ok)
+	}
+
+	if _, _, ok := (&DomainPack{}).Recommendation(StandardUseCase); ok {
+		t.Error("a domain without recommendations has no standard recommendation")
+	}
+}
+
+func TestValidationProblems(t *testing.T) {
+	valid := `name: Genomics
+validation:
+  - name: nextflow
+    command: nextflow -version
+    expect_output: nextflow
+  - command: python3 -c "import torch"
+    timeout_seconds: 300
+    optional: true
+  - name: missing tool
+    command: which not-installed
+    expect_exit_code: 1
+`
+	var domain DomainPack
+	if err := yaml.Unmarshal([]byte(valid), &domain); err != nil {
+		t.Fatal(err)
+	}
+	if problems := domain.ValidationProblems(); len(problems) != 0 {
+		t.Errorf("ValidationProblems() = %v", problems)
+	}
+	if check := domain.Validation[1]; check.DisplayName() != `python3 -c "import torch"` || check.Timeout() != 5*time.Minute || !check.Optional {
+		t.Errorf("check = %+v", check)
+	}
+	if timeout := domain.Validation[0].Timeout(); timeout != DefaultValidationTimeoutSeconds*time.Second {
+		t.Errorf("default timeout = %v", timeout)
+	}
+
+	domain.Validation = []ValidationCheck{
+		{Name: "empty", Command: "  "},
+		{Name: "exit", Command: "true", ExpectExitCode: 256},
+		{Command: "true", ExpectOutput: "two\nlines"},
+		{Name: "slow", Command: "sleep 1", TimeoutSeconds: 7200},
+		{Name: "exit", Command: "false"},
+	}
+	want := []string{
+		`validation "empty": command is required`,
+		`validation "exit": expect_exit_code must be between 0 and 255`,
+		"validation[2]: expect_output must be a single line",
+		`validation "slow": timeout_seconds must be between 1 and 3600`,
+		`validation "exit": duplicate check name "exit"`,
+	}
+	if problems := domain.ValidationProblems(); strings.Join(problems, "\n") != strings.Join(want, "\n") {
+		t.Errorf("ValidationProblems() =\n%s\nwant\n%s", strings.Join(problems, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestLoadDomainRejectsInvalidValidation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "broken.yaml")
+	if err := os.WriteFile(path, []byte("name: Broken\nvalidation:\n  - name: nothing\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, err := NewConfigLoader(t.TempDir()).LoadDomain(path)
+	if err == nil || !strings.Contains(err.Error(), `validation "nothing": command is required`) {
+		t.Errorf("LoadDomain() error = %v", err)
+	}
+}
+
+func TestRecommendationProblems(t *testing.T) {
+	domain := &DomainPack{AWSInstanceRecommendations: map[string]InstanceRecommendation{
+		"standard": {InstanceType: "r7g.4xlarge", Architecture: "arm64"},
+		"legacy":   {InstanceType: "r6i.4xlarge", Architecture: "x86_64"},
+		"derived":  {InstanceType: "c6i.large"},
+		"apple":    {InstanceType: "mac2.metal", Architecture: "aarch64"},
+	}}
+
+	problems := domain.RecommendationProblems()
+	want := `aws_instance_recommendations "apple": architecture "aarch64" must be x86_64 or arm64`
+	if len(problems) != 1 || problems[0] != want {
+		t.Errorf("RecommendationProblems() = %v, want [%s]", problems, want)
+	}
+}
+
+func TestDeprecationProblems(t *testing.T) {
+	tests := []struct {
+		name   string
+		domain DomainPack
+		want   []string
+	}{
+		{"current", DomainPack{}, nil},
+		{"deprecated", DomainPack{Deprecated: true, ReplacedBy: "genomics", SunsetDate: "2027-01-31"}, nil},
+		{"replacement without deprecation", DomainPack{ReplacedBy: "genomics"}, []string{"replaced_by is only allowed on a deprecated domain"}},
+		{"sunset without deprecation", DomainPack{SunsetDate: "2027-01-31"}, []string{"sunset_date is only allowed on a deprecated domain"}},
+		{"invalid sunset", DomainPack{Deprecated: true, SunsetDate: "31/01/2027"}, []string{`sunset_date "31/01/2027" must be a date such as 2026-12-31`}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if problems := tt.domain.DeprecationProblems(); strings.Join(problems, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("DeprecationProblems() = %v, want %v", problems, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeprecationNotice(t *testing.T) {
+	now := time.Date(2026, 10, 15, 14, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		domain DomainPack
+		want   string
+	}{
+		{"current", DomainPack{}, ""},
+		{"no timeline", DomainPack{Deprecated: true}, "Domain genomics-legacy is deprecated"},
+		{"replacement", DomainPack{Deprecated: true, ReplacedBy: "genomics"}, "Domain genomics-legacy is deprecated; use genomics instead"},
+		{"future sunset", DomainPack{Deprecated: true, ReplacedBy: "genomics", SunsetDate: "2026-12-31"},
+			"Domain genomics-legacy is deprecated and will be removed on 2026-12-31 (in 77 days); use genomics instead"},
+		{"sunset tomorrow", DomainPack{Deprecated: true, SunsetDate: "2026-10-16"}, "Domain genomics-legacy is deprecated and will be removed on 2026-10-16 (tomorrow)"},
+		{"sunset today", DomainPack{Deprecated: true, SunsetDate: "2026-10-15"}, "Domain genomics-legacy is deprecated and will be removed today (2026-10-15)"},
+		{"sunset passed", DomainPack{Deprecated: true, SunsetDate: "2026-09-30"}, "Domain genomics-legacy is deprecated and was due to be removed on 2026-09-30"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if notice := tt.domain.DeprecationNotice("genomics-legacy", now); notice != tt.want {
+				t.Errorf("DeprecationNotice() = %q, want %q", notice, tt.want)
+			}
+		})
+	}
+}
+
+func TestReplacement(t *testing.T) {
+	domains := map[string]*DomainPack{
+		"genomics":        {},
+		"genomics-v2":     {Deprecated: true, ReplacedBy: "genomics"},
+		"genomics-legacy": {Deprecated: true, ReplacedBy: "genomics-v2"},
+		"retired":         {Deprecated: true},
+		"dangling":        {Deprecated: true, ReplacedBy: "missing"},
+		"loop-a":          {Deprecated: true, ReplacedBy: "loop-b"},
+		"loop-b":          {Deprecated: true, ReplacedBy: "loop-a"},
+	}
+
+	tests := []struct {
+		name    string
+		want    string
+		wantErr string
+	}{
+		{name: "genomics", want: "genomics"},
+		{name: "genomics-v2", want: "genomics"},
+		{name: "genomics-legacy", want: "genomics"},
+		{name: "retired", want: "retired"},
+		{name: "dangling", wantErr: "replaced by missing, which does not exist"},
+		{name: "loop-a", wantErr: "leads back to loop-a"},
+		{name: "unknown", wantErr: "domain 'unknown' not found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Replacement(domains, tt.name)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Replacement() error = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("Replacement() = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadDomainRejectsInvalidDeprecation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "old.yaml")
+	if err := os.WriteFile(path, []byte("name: Old\nreplaced_by: genomics\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, err := NewConfigLoader(t.TempDir()).LoadDomain(path)
+	if err == nil || !strings.Contains(err.Error(), "replaced_by is only allowed on a deprecated domain") {
+		t.Errorf("LoadDomain() error = %v", err)
+	}
+}
@@ -0,0 +1,278 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultWizardFile is the project-local file describing deployment environments
+const DefaultWizardFile = "wizard.yaml"
+
+// wizardFileVersion is the current layout of wizard.yaml
+const wizardFileVersion = 1
+
+// WizardFile describes named deployment environments for a project. Each
+// environment starts from the defaults and overrides what it sets.
+type WizardFile struct {
+	Version      int                              `yaml:"version"`
+	Defaults     DeploymentEnvironment            `yaml:"defaults,omitempty"`
+	Environments map[string]DeploymentEnvironment `yaml:"environments"`
+}
+
+// DeploymentEnvironment is the configuration of one deployment
+type DeploymentEnvironment struct {
+	Domain       string            `yaml:"domain,omitempty"`
+	Instance     string            `yaml:"instance,omitempty"`
+	Region       string            `yaml:"region,omitempty"`
+	Stack        string            `yaml:"stack,omitempty"`
+	Architecture string            `yaml:"architecture,omitempty"`
+	Storage      StorageSettings   `yaml:"storage,omitempty"`
+	Tags         map[string]string `yaml:"tags,omitempty"`
+	// MonthlyBudget is the most the environment may be estimated to cost, in USD
+	MonthlyBudget float64              `yaml:"monthly_budget,omitempty"`
+	AutoShutdown  AutoShutdownSettings `yaml:"auto_shutdown,omitempty"`
+}
+
+// StorageSettings size and protect the root volume
+type StorageSettings struct {
+	VolumeSizeGB int  `yaml:"volume_size_gb,omitempty"`
+	Encrypted    bool `yaml:"encrypted,omitempty"`
+}
+
+// AutoShutdownSettings stop instances whose CPU stays idle
+type AutoShutdownSettings struct {
+	IdleMinutes int     `yaml:"idle_minutes,omitempty"`
+	CPUPercent  float64 `yaml:"cpu_percent,omitempty"`
+}
+
+// Merge returns the environment with the fields set in over replacing its own.
+// Tags are merged key by key, and encryption can be turned on but not off.
+func (e DeploymentEnvironment) Merge(over DeploymentEnvironment) DeploymentEnvironment {
+	merged := e
+	overrideString(&merged.Domain, over.Domain)
+	overrideString(&merged.Instance, over.Instance)
+	overrideString(&merged.Region, over.Region)
+	overrideString(&merged.Stack, over.Stack)
+	overrideString(&merged.Architecture, over.Architecture)
+	if over.Storage.VolumeSizeGB != 0 {
+		merged.Storage.VolumeSizeGB = over.Storage.VolumeSizeGB
+	}
+	merged.Storage.Encrypted = e.Storage.Encrypted || over.Storage.Encrypted
+	if over.MonthlyBudget != 0 {
+		merged.MonthlyBudget = over.MonthlyBudget
+	}
+	if over.AutoShutdown.IdleMinutes != 0 {
+		merged.AutoShutdown.IdleMinutes = over.AutoShutdown.IdleMinutes
+	}
+	if over.AutoShutdown.CPUPercent != 0 {
+		merged.AutoShutdown.CPUPercent = over.AutoShutdown.CPUPercent
+	}
+
+	if len(e.Tags) > 0 || len(over.Tags) > 0 {
+		merged.Tags = make(map[string]string, len(e.Tags)+len(over.Tags))
+		for key, value := range e.Tags {
+			merged.Tags[key] = value
+		}
+		for key, value := range over.Tags {
+			merged.Tags[key] = value
+		}
+	}
+	return merged
+}
+
+func overrideString(field *string, value string) {
+	if value != "" {
+		*field = value
+	}
+}
+
+// Environment returns a named environment merged over the defaults
+func (f *WizardFile) Environment(name string) (DeploymentEnvironment, error) {
+	env, exists := f.Environments[name]
+	if !exists {
+		return DeploymentEnvironment{}, fmt.Errorf("environment %q not found (available: %s)", name, strings.Join(f.EnvironmentNames(), ", "))
+	}
+	return f.Defaults.Merge(env), nil
+}
+
+// EnvironmentNames lists the environments in name order
+func (f *WizardFile) EnvironmentNames() []string {
+	names := make([]string, 0, len(f.Environments))
+	for name := range f.Environments {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+var (
+	environmentNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]*$`)
+	instanceTypePattern    = regexp.MustCompile(`^[a-z][a-z0-9-]*\.[a-z0-9]+$`)
+	regionPattern          = regexp.MustCompile(`^[a-z]{2}(-gov)?-[a-z]+-\d$`)
+	stackNamePattern       = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9-]{0,127}$`)
+)
+
+// Validate checks every environment, after merging the defaults, and reports
+// all problems at once
+func (f *WizardFile) Validate() error {
+	var problems []string
+	if f.Version != wizardFileVersion {
+		problems = append(problems, fmt.Sprintf("unsupported version %d (expected %d)", f.Version, wizardFileVersion))
+	}
+	if len(f.Environments) == 0 {
+		problems = append(problems, "no environments defined")
+	}
+
+	for _, name := range f.EnvironmentNames() {
+		if !environmentNamePattern.MatchString(name) {
+			problems = append(problems, fmt.Sprintf("environment %q: name may only contain letters, digits, '-' and '_'", name))
+		}
+		env, _ := f.Environment(name)
+		for _, problem := range env.Problems() {
+			problems = append(problems, fmt.Sprintf("environment %q: %s", name, problem))
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid wizard file:\n  - %s", strings.Join(problems, "\n  - "))
+	}
+	return nil
+}
+
+// Problems lists the schema errors in a merged environment
+func (e DeploymentEnvironment) Problems() []string {
+	var problems []string
+	if e.Domain == "" {
+		problems = append(problems, "domain is required")
+	}
+	if e.Instance != "" && !instanceTypePattern.MatchString(e.Instance) {
+		problems = append(problems, fmt.Sprintf("instance %q is not an EC2 instance type", e.Instance))
+	}
+	if e.Region != "" && !regionPattern.MatchString(e.Region) {
+		problems = append(problems, fmt.Sprintf("region %q is not an AWS region name", e.Region))
+	}
+	if e.Stack != "" && !stackNamePattern.MatchString(e.Stack) {
+		problems = append(problems, fmt.Sprintf("stack %q is not a valid CloudFormation stack name", e.Stack))
+	}
+	if e.Storage.VolumeSizeGB < 0 {
+		problems = append(problems, "storage.volume_size_gb must be positive")
+	}
+	if e.MonthlyBudget < 0 {
+		problems = append(problems, "monthly_budget must be positive")
+	}
+	if e.AutoShutdown.IdleMinutes < 0 {
+		problems = append(problems, "auto_shutdown.idle_minutes must be positive")
+	}
+	if e.AutoShutdown.CPUPercent < 0 || e.AutoShutdown.CPUPercent >= 100 {
+		problems = append(problems, "auto_shutdown.cpu_percent must be between 0 and 100")
+	}
+
+	keys := make([]string, 0, len(e.Tags))
+	for key := range e.Tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if key == "" || len(key) > 128 || strings.HasPrefix(strings.ToLower(key), "aws:") {
+			problems = append(problems, fmt.Sprintf("tag key %q is not allowed", key))
+		} else if len(e.Tags[key]) > 256 {
+			problems = append(problems, fmt.Sprintf("tag %q value is longer than 256 characters", key))
+		}
+	}
+	return problems
+}
+
+// LoadWizardFile reads a wizard file, expanding ${VAR} references from the
+// environment
+func LoadWizardFile(path string) (*WizardFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read wizard file: %w", err)
+	}
+	file, err := ParseWizardFile(data, os.LookupEnv)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return file, nil
+}
+
+// ParseWizardFile decodes a wizard file. ${VAR} and ${VAR:-default} in values
+// are replaced using lookup, and $$ is a literal dollar sign. Unknown keys and
+// undefined variables without a default are errors.
+func ParseWizardFile(data []byte, lookup func(string) (string, bool)) (*WizardFile, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("failed to parse YAML: %w", err)
+	}
+
+	missing := make(map[string]bool)
+	interpolateNode(&root, lookup, missing)
+	if len(missing) > 0 {
+		names := make([]string, 0, len(missing))
+		for name := range missing {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("undefined variables: %s", strings.Join(names, ", "))
+	}
+
+	// Re-encode the expanded document so unknown keys can be rejected
+	expanded, err := yaml.Marshal(&root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to expand variables: %w", err)
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(expanded))
+	decoder.KnownFields(true)
+
+	file := &WizardFile{}
+	if err := decoder.Decode(file); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to decode wizard file: %w", err)
+	}
+	return file, nil
+}
+
+// interpolateNode expands variables in every scalar value below node
+func interpolateNode(node *yaml.Node, lookup func(string) (string, bool), missing map[string]bool) {
+	if node.Kind == yaml.ScalarNode && node.Tag != "!!binary" {
+		expanded := Interpolate(node.Value, lookup, missing)
+		if expanded != node.Value {
+			node.Value = expanded
+			// Let the expanded text be typed by the field it decodes into
+			node.Tag = ""
+			node.Style = 0
+		}
+		return
+	}
+	for _, child := range node.Content {
+		interpolateNode(child, lookup, missing)
+	}
+}
+
+var variablePattern = regexp.MustCompile(`\$\$|\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+// Interpolate replaces ${VAR} and ${VAR:-default} in value. Variables that are
+// undefined and have no default are added to missing and left as written.
+func Interpolate(value string, lookup func(string) (string, bool), missing map[string]bool) string {
+	return variablePattern.ReplaceAllStringFunc(value, func(match string) string {
+		if match == "$$" {
+			return "$"
+		}
+		parts := variablePattern.FindStringSubmatch(match)
+		if resolved, found := lookup(parts[1]); found {
+			return resolved
+		}
+		if parts[2] != "" {
+			return parts[3]
+		}
+		missing[parts[1]] = true
+		return match
+	})
+}
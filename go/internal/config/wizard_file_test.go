@@ -0,0 +1,198 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+const testWizardFile = `
+version: 1
+defaults:
+  region: us-west-2
+  tags:
+    Project: ${PROJECT}
+    Owner: ${OWNER:-research-computing}
+  auto_shutdown:
+    idle_minutes: 60
+environments:
+  prod-genomics:
+    domain: genomics
+    instance: r6i.4xlarge
+    stack: ${PROJECT}-prod
+    storage:
+      volume_size_gb: 500
+      encrypted: true
+    tags:
+      Stage: prod
+    monthly_budget: 1500
+  dev:
+    domain: genomics
+    region: us-east-1
+    auto_shutdown:
+      idle_minutes: 15
+`
+
+func lookupFrom(values map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		value, found := values[name]
+		return value, found
+	}
+}
+
+func TestParseWizardFileMergesDefaults(t *testing.T) {
+	file, err := ParseWizardFile([]byte(testWizardFile), lookupFrom(map[string]string{"PROJECT": "dna"}))
+	if err != nil {
+		t.Fatalf("ParseWizardFile() error = %v", err)
+	}
+	if err := file.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+
+	prod, err := file.Environment("prod-genomics")
+	if err != nil {
+		t.Fatalf("Environment() error = %v", err)
+	}
+	if prod.Region != "us-west-2" || prod.Stack != "dna-prod" || prod.Storage.VolumeSizeGB != 500 || !prod.Storage.Encrypted {
+		t.Errorf("prod-genomics = %+v", prod)
+	}
+	if prod.Tags["Project"] != "dna" || prod.Tags["Owner"] != "research-computing" || prod.Tags["Stage"] != "prod" {
+		t.Errorf("prod-genomics tags = %v", prod.Tags)
+	}
+	if prod.AutoShutdown.IdleMinutes != 60 {
+		t.Errorf("prod-genomics should inherit auto-shutdown: %+v", prod.AutoShutdown)
+	}
+
+	dev, _ := file.Environment("dev")
+	if dev.Region != "us-east-1" || dev.AutoShutdown.IdleMinutes != 15 || dev.Storage.Encrypted {
+		t.Errorf("dev = %+v", dev)
+	}
+
+	if _, err := file.Environment("staging"); err == nil || !strings.Contains(err.Error(), "dev, prod-genomics") {
+		t.Errorf("Environment(staging) error = %v, want the available names", err)
+	}
+}
+
+func TestMergePrecedence(t *testing.T) {
+	base := DeploymentEnvironment{
+		Domain:        "genomics",
+		Instance:      "r6i.4xlarge",
+		Region:        "us-west-2",
+		Storage:       StorageSettings{VolumeSizeGB: 500, Encrypted: true},
+		Tags:          map[string]string{"Project": "dna", "Stage": "prod"},
+		MonthlyBudget: 1500,
+	}
+	over := DeploymentEnvironment{
+		Instance: "r6i.8xlarge",
+		Tags:     map[string]string{"Stage": "test"},
+	}
+
+	merged := base.Merge(over)
+	if merged.Instance != "r6i.8xlarge" {
+		t.Errorf("instance = %s, want the override", merged.Instance)
+	}
+	if merged.Domain != "genomics" || merged.Region != "us-west-2" || merged.MonthlyBudget != 1500 {
+		t.Errorf("unset fields should keep the base values: %+v", merged)
+	}
+	if !merged.Storage.Encrypted || merged.Storage.VolumeSizeGB != 500 {
+		t.Errorf("storage = %+v", merged.Storage)
+	}
+	if merged.Tags["Project"] != "dna" || merged.Tags["Stage"] != "test" {
+		t.Errorf("tags = %v, want merged key by key", merged.Tags)
+	}
+	if base.Tags["Stage"] != "prod" {
+		t.Error("Merge modified the base tags")
+	}
+}
+
+func TestInterpolate(t *testing.T) {
+	lookup := lookupFrom(map[string]string{"PROJECT": "dna", "EMPTY": ""})
+
+	tests := map[string]string{
+		"${PROJECT}-prod":       "dna-prod",
+		"${MISSING:-fallback}":  "fallback",
+		"${EMPTY:-fallback}":    "",
+		"$$PROJECT costs $$5":   "$PROJECT costs $5",
+		"plain $PROJECT value":  "plain $PROJECT value",
+		"${PROJECT}/${PROJECT}": "dna/dna",
+	}
+	for input, want := range tests {
+		missing := make(map[string]bool)
+		if got := Interpolate(input, lookup, missing); got != want {
+			t.Errorf("Interpolate(%q) = %q, want %q", input, got, want)
+		}
+		if len(missing) > 0 {
+			t.Errorf("Interpolate(%q) reported missing %v", input, missing)
+		}
+	}
+
+	missing := make(map[string]bool)
+	Interpolate("${UNSET}", lookup, missing)
+	if !missing["UNSET"] {
+		t.Error("undefined variable without a default should be reported")
+	}
+}
+
+func TestParseWizardFileErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		vars    map[string]string
+		wantErr string
+	}{
+		{
+			name:    "undefined variable",
+			yaml:    testWizardFile,
+			wantErr: "undefined variables: PROJECT",
+		},
+		{
+			name:    "unknown key",
+			yaml:    "version: 1\nenvironments:\n  dev:\n    domian: genomics\n",
+			wantErr: "field domian not found",
+		},
+		{
+			name:    "interpolated number",
+			yaml:    "version: 1\nenvironments:\n  dev:\n    domain: genomics\n    monthly_budget: ${PROJECT}\n",
+			vars:    map[string]string{"PROJECT": "dna"},
+			wantErr: "decode",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseWizardFile([]byte(tt.yaml), lookupFrom(tt.vars))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ParseWizardFile() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWizardFileValidate(t *testing.T) {
+	file := &WizardFile{
+		Version: 2,
+		Defaults: DeploymentEnvironment{
+			Tags: map[string]string{"aws:createdBy": "me"},
+		},
+		Environments: map[string]DeploymentEnvironment{
+			"ok":     {Domain: "genomics", Instance: "r6i.4xlarge", Region: "eu-west-1"},
+			"broken": {Instance: "large", Region: "mars-1", AutoShutdown: AutoShutdownSettings{CPUPercent: 150}},
+		},
+	}
+
+	err := file.Validate()
+	if err == nil {
+		t.Fatal("Validate() should fail")
+	}
+	for _, want := range []string{
+		"unsupported version 2",
+		`environment "broken": domain is required`,
+		`environment "broken": instance "large"`,
+		`environment "broken": region "mars-1"`,
+		`environment "broken": auto_shutdown.cpu_percent`,
+		`environment "ok": tag key "aws:createdBy"`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Validate() error missing %q:\n%v", want, err)
+		}
+	}
+}
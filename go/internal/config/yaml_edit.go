@@ -0,0 +1,201 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// YAMLScalarEdit sets the scalar at a path of mapping keys to a new value
+type YAMLScalarEdit struct {
+	Path  []string
+	Value string
+}
+
+// ReadYAMLScalar returns the scalar at a path of mapping keys as written in
+// the document, and whether the path exists
+func ReadYAMLScalar(content []byte, path ...string) (string, bool, error) {
+	root, err := parseYAMLDocument(content)
+	if err != nil {
+		return "", false, err
+	}
+	node := findYAMLNode(root, path)
+	if node == nil {
+		return "", false, nil
+	}
+	if node.Kind != yaml.ScalarNode {
+		return "", false, fmt.Errorf("%s is not a scalar", strings.Join(path, "."))
+	}
+	return node.Value, true, nil
+}
+
+// EditYAMLScalars sets existing scalars in a YAML document. The document is
+// parsed into nodes only to locate each scalar; the edit then replaces just
+// that scalar's bytes, so comments, key order, quoting and layout are kept
+// exactly as they were instead of being re-marshalled.
+func EditYAMLScalars(content []byte, edits []YAMLScalarEdit) ([]byte, error) {
+	root, err := parseYAMLDocument(content)
+	if err != nil {
+		return nil, err
+	}
+
+	type replacement struct {
+		start, end int
+		value      string
+	}
+	replacements := make([]replacement, 0, len(edits))
+	for _, edit := range edits {
+		name := strings.Join(edit.Path, ".")
+		node := findYAMLNode(root, edit.Path)
+		if node == nil {
+			return nil, fmt.Errorf("%s not found", name)
+		}
+		if node.Kind != yaml.ScalarNode {
+			return nil, fmt.Errorf("%s is not a scalar", name)
+		}
+		start, end, err := scalarSpan(content, node)
+		if err != nil {
+			return nil, fmt.Errorf("cannot edit %s: %w", name, err)
+		}
+		if err := checkScalarValue(edit.Value, node.Style); err != nil {
+			return nil, fmt.Errorf("cannot set %s: %w", name, err)
+		}
+		replacements = append(replacements, replacement{start, end, edit.Value})
+	}
+
+	// Apply from the end of the document so earlier offsets stay valid
+	sort.Slice(replacements, func(i, j int) bool { return replacements[i].start > replacements[j].start })
+	for i := 1; i < len(replacements); i++ {
+		if replacements[i].start == replacements[i-1].start {
+			return nil, fmt.Errorf("the same scalar is edited twice")
+		}
+	}
+
+	edited := append([]byte(nil), content...)
+	for _, r := range replacements {
+		edited = append(edited[:r.start], append([]byte(r.value), edited[r.end:]...)...)
+	}
+
+	// Refuse to hand back a document the edits have broken
+	if _, err := parseYAMLDocument(edited); err != nil {
+		return nil, fmt.Errorf("edit produced invalid YAML: %w", err)
+	}
+	return edited, nil
+}
+
+func parseYAMLDocument(content []byte) (*yaml.Node, error) {
+	var document yaml.Node
+	if err := yaml.Unmarshal(content, &document); err != nil {
+		return nil, fmt.Errorf("failed to parse YAML: %w", err)
+	}
+	if document.Kind != yaml.DocumentNode || len(document.Content) == 0 {
+		return nil, fmt.Errorf("empty YAML document")
+	}
+	return document.Content[0], nil
+}
+
+// findYAMLNode follows a path of mapping keys, returning nil when a key is missing
+func findYAMLNode(node *yaml.Node, path []string) *yaml.Node {
+	for _, key := range path {
+		if node.Kind == yaml.AliasNode {
+			node = node.Alias
+		}
+		if node.Kind != yaml.MappingNode {
+			return nil
+		}
+		var next *yaml.Node
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == key {
+				next = node.Content[i+1]
+				break
+			}
+		}
+		if next == nil {
+			return nil
+		}
+		node = next
+	}
+	return node
+}
+
+// scalarSpan returns the byte range of a scalar's value in the document. For
+// quoted scalars the range is inside the quotes. Scalars whose text differs
+// from their value, such as multi-line or escaped scalars, are refused.
+func scalarSpan(content []byte, node *yaml.Node) (int, int, error) {
+	offset, err := byteOffset(content, node.Line, node.Column)
+	if err != nil {
+		return 0, 0, err
+	}
+	text := content[offset:]
+
+	switch node.Style {
+	case 0:
+		if !strings.HasPrefix(string(text), node.Value) {
+			return 0, 0, fmt.Errorf("value at line %d is not a single-line plain scalar", node.Line)
+		}
+		end := offset + len(node.Value)
+		if end < len(content) && !strings.ContainsRune(" \t\r\n,]}", rune(content[end])) {
+			return 0, 0, fmt.Errorf("value at line %d is not a single-line plain scalar", node.Line)
+		}
+		return offset, end, nil
+	case yaml.DoubleQuotedStyle, yaml.SingleQuotedStyle:
+		quote := "\""
+		if node.Style == yaml.SingleQuotedStyle {
+			quote = "'"
+		}
+		if !strings.HasPrefix(string(text), quote+node.Value+quote) {
+			return 0, 0, fmt.Errorf("quoted value at line %d is escaped or spans lines", node.Line)
+		}
+		return offset + 1, offset + 1 + len(node.Value), nil
+	}
+	return 0, 0, fmt.Errorf("value at line %d is a block or tagged scalar", node.Line)
+}
+
+// byteOffset converts a 1-based line and character column to a byte offset
+func byteOffset(content []byte, line, column int) (int, error) {
+	offset := 0
+	for current := 1; current < line; current++ {
+		next := strings.IndexByte(string(content[offset:]), '\n')
+		if next < 0 {
+			return 0, fmt.Errorf("line %d is beyond the document", line)
+		}
+		offset += next + 1
+	}
+	for current := 1; current < column; current++ {
+		if offset >= len(content) || content[offset] == '\n' {
+			return 0, fmt.Errorf("column %d is beyond line %d", column, line)
+		}
+		_, size := utf8.DecodeRune(content[offset:])
+		offset += size
+	}
+	return offset, nil
+}
+
+// checkScalarValue refuses values that would change meaning or break the
+// document when written in the scalar's existing style
+func checkScalarValue(value string, style yaml.Style) error {
+	if strings.ContainsAny(value, "\r\n") {
+		return fmt.Errorf("value %q spans lines", value)
+	}
+	switch style {
+	case yaml.DoubleQuotedStyle:
+		if strings.ContainsAny(value, "\"\\") {
+			return fmt.Errorf("value %q needs escaping", value)
+		}
+	case yaml.SingleQuotedStyle:
+		if strings.Contains(value, "'") {
+			return fmt.Errorf("value %q needs escaping", value)
+		}
+	default:
+		negative := len(value) > 1 && value[0] == '-' && value[1] != ' '
+		if value == "" || (!negative && strings.ContainsAny(value[:1], "-?:,[]{}#&*!|>'\"%@` \t")) ||
+			strings.Contains(value, ": ") || strings.Contains(value, " #") ||
+			strings.HasSuffix(value, " ") || strings.HasSuffix(value, ":") {
+			return fmt.Errorf("value %q cannot be written as a plain scalar", value)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,197 @@
+package config
+
+import (
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// editFixture exercises what a re-marshal would lose: comments in every
+// position, unsorted keys, mixed indentation, quoting styles, flow
+// collections, anchors, block scalars and non-ASCII text before the edits
+const editFixture = `# Domain pack header comment
+name: "Génomique & Bioinformatics"   # trailing comment with ünïcode
+description: >
+  Folded block scalar
+  that spans lines
+zeta_key: 1
+alpha_key: 2
+spack_packages:
+    core:
+    - gatk@4.4.0   # four-space mapping, unindented sequence
+    - "samtools@1.18"
+flow: {compute: 10, storage: 20}
+defaults: &defaults
+  utilization: 0.5
+inherits: *defaults
+estimated_cost:
+  # compute is instance hours at list price
+  compute: 600
+  storage: 200.0  # gp3
+  data_transfer: 100
+  quoted: "300"
+  single: '42'
+  total: 900
+
+# Footer comment
+tutorials: []
+`
+
+func TestEditYAMLScalarsOnlyChangesEditedValues(t *testing.T) {
+	edited, err := EditYAMLScalars([]byte(editFixture), []YAMLScalarEdit{
+		{Path: []string{"estimated_cost", "total"}, Value: "1234"},
+		{Path: []string{"estimated_cost", "compute"}, Value: "934"},
+		{Path: []string{"estimated_cost", "storage"}, Value: "187.5"},
+	})
+	if err != nil {
+		t.Fatalf("EditYAMLScalars() error = %v", err)
+	}
+
+	want := strings.NewReplacer(
+		"  compute: 600\n", "  compute: 934\n",
+		"  storage: 200.0  # gp3\n", "  storage: 187.5  # gp3\n",
+		"  total: 900\n", "  total: 1234\n",
+	).Replace(editFixture)
+	if string(edited) != want {
+		t.Errorf("edited document differs beyond the edited values:\n%s", lineDiff(want, string(edited)))
+	}
+}
+
+func TestEditYAMLScalarsStyles(t *testing.T) {
+	tests := []struct {
+		name  string
+		path  []string
+		value string
+		old   string
+		new   string
+	}{
+		{"double quoted", []string{"estimated_cost", "quoted"}, "350", `quoted: "300"`, `quoted: "350"`},
+		{"single quoted", []string{"estimated_cost", "single"}, "43", `single: '42'`, `single: '43'`},
+		{"flow mapping", []string{"flow", "storage"}, "25", "{compute: 10, storage: 20}", "{compute: 10, storage: 25}"},
+		{"flow mapping first", []string{"flow", "compute"}, "15", "{compute: 10, storage: 20}", "{compute: 15, storage: 20}"},
+		{"after non-ASCII text", []string{"alpha_key"}, "3", "alpha_key: 2", "alpha_key: 3"},
+		{"anchored mapping", []string{"defaults", "utilization"}, "0.75", "utilization: 0.5", "utilization: 0.75"},
+		{"through an alias", []string{"inherits", "utilization"}, "0.25", "utilization: 0.5", "utilization: 0.25"},
+		{"quoted string", []string{"name"}, "Genomics", `"Génomique & Bioinformatics"`, `"Genomics"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			edited, err := EditYAMLScalars([]byte(editFixture), []YAMLScalarEdit{{Path: tt.path, Value: tt.value}})
+			if err != nil {
+				t.Fatalf("EditYAMLScalars() error = %v", err)
+			}
+			want := strings.Replace(editFixture, tt.old, tt.new, 1)
+			if string(edited) != want {
+				t.Errorf("unexpected edit:\n%s", lineDiff(want, string(edited)))
+			}
+
+			got, found, err := ReadYAMLScalar(edited, tt.path...)
+			if err != nil || !found || got != tt.value {
+				t.Errorf("ReadYAMLScalar() = %q, %v, %v; want %q", got, found, err, tt.value)
+			}
+		})
+	}
+}
+
+func TestEditYAMLScalarsKeepsDocumentEquivalent(t *testing.T) {
+	edited, err := EditYAMLScalars([]byte(editFixture), []YAMLScalarEdit{
+		{Path: []string{"estimated_cost", "compute"}, Value: "1"},
+	})
+	if err != nil {
+		t.Fatalf("EditYAMLScalars() error = %v", err)
+	}
+
+	var before, after map[string]interface{}
+	if err := yaml.Unmarshal([]byte(editFixture), &before); err != nil {
+		t.Fatal(err)
+	}
+	if err := yaml.Unmarshal(edited, &after); err != nil {
+		t.Fatalf("edited document does not parse: %v", err)
+	}
+	before["estimated_cost"].(map[string]interface{})["compute"] = 1
+	beforeText, _ := yaml.Marshal(before)
+	afterText, _ := yaml.Marshal(after)
+	if string(beforeText) != string(afterText) {
+		t.Errorf("edit changed more than compute:\n%s", lineDiff(string(beforeText), string(afterText)))
+	}
+}
+
+func TestEditYAMLScalarsErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		edits []YAMLScalarEdit
+	}{
+		{"missing key", []YAMLScalarEdit{{Path: []string{"estimated_cost", "gpu"}, Value: "1"}}},
+		{"missing parent", []YAMLScalarEdit{{Path: []string{"nope", "compute"}, Value: "1"}}},
+		{"not a scalar", []YAMLScalarEdit{{Path: []string{"estimated_cost"}, Value: "1"}}},
+		{"block scalar", []YAMLScalarEdit{{Path: []string{"description"}, Value: "short"}}},
+		{"plain value needing quotes", []YAMLScalarEdit{{Path: []string{"alpha_key"}, Value: "a: b"}}},
+		{"plain value starting a comment", []YAMLScalarEdit{{Path: []string{"alpha_key"}, Value: "#1"}}},
+		{"multi-line value", []YAMLScalarEdit{{Path: []string{"alpha_key"}, Value: "1\n2"}}},
+		{"quote in quoted value", []YAMLScalarEdit{{Path: []string{"estimated_cost", "quoted"}, Value: `3"0`}}},
+		{"same scalar twice", []YAMLScalarEdit{
+			{Path: []string{"zeta_key"}, Value: "1"},
+			{Path: []string{"zeta_key"}, Value: "2"},
+		}},
+		{"flow value breaking the mapping", []YAMLScalarEdit{{Path: []string{"flow", "compute"}, Value: "1}"}}},
+	}
+
+	for _, tt := range tests {
+		if _, err := EditYAMLScalars([]byte(editFixture), tt.edits); err == nil {
+			t.Errorf("%s: EditYAMLScalars() should fail", tt.name)
+		}
+	}
+
+	if _, err := EditYAMLScalars([]byte("compute: [1,\n"), nil); err == nil {
+		t.Error("EditYAMLScalars() should reject invalid YAML")
+	}
+}
+
+func TestEditYAMLScalarsNegativeAndEmptyEdits(t *testing.T) {
+	edited, err := EditYAMLScalars([]byte(editFixture), []YAMLScalarEdit{{Path: []string{"zeta_key"}, Value: "-5"}})
+	if err != nil {
+		t.Fatalf("EditYAMLScalars() error = %v", err)
+	}
+	if !strings.Contains(string(edited), "zeta_key: -5\n") {
+		t.Errorf("negative value not written:\n%s", edited)
+	}
+
+	unchanged, err := EditYAMLScalars([]byte(editFixture), nil)
+	if err != nil || string(unchanged) != editFixture {
+		t.Errorf("no edits should return the document unchanged, err = %v", err)
+	}
+}
+
+func TestReadYAMLScalar(t *testing.T) {
+	value, found, err := ReadYAMLScalar([]byte(editFixture), "estimated_cost", "storage")
+	if err != nil || !found || value != "200.0" {
+		t.Errorf("ReadYAMLScalar(storage) = %q, %v, %v", value, found, err)
+	}
+	if _, found, err := ReadYAMLScalar([]byte(editFixture), "estimated_cost", "gpu"); found || err != nil {
+		t.Errorf("ReadYAMLScalar(gpu) found = %v, err = %v", found, err)
+	}
+	if _, _, err := ReadYAMLScalar([]byte(editFixture), "estimated_cost"); err == nil {
+		t.Error("ReadYAMLScalar() of a mapping should fail")
+	}
+}
+
+// lineDiff lists the lines that differ between two documents
+func lineDiff(want, got string) string {
+	wantLines, gotLines := strings.Split(want, "\n"), strings.Split(got, "\n")
+	var b strings.Builder
+	for i := 0; i < len(wantLines) || i < len(gotLines); i++ {
+		var w, g string
+		if i < len(wantLines) {
+			w = wantLines[i]
+		}
+		if i < len(gotLines) {
+			g = gotLines[i]
+		}
+		if w != g {
+			b.WriteString("- " + w + "\n+ " + g + "\n")
+		}
+	}
+	return b.String()
+}
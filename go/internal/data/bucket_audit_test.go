@@ -0,0 +1,301 @@
+package data
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+var auditNow = time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+
+// fakeAuditAPI serves a paginated listing, multipart uploads and their parts
+type fakeAuditAPI struct {
+	fakeS3Lister
+	uploads []s3types.MultipartUpload
+	parts   map[string][]int64
+}
+
+func (f *fakeAuditAPI) ListMultipartUploads(ctx context.Context, params *s3.ListMultipartUploadsInput, optFns ...func(*s3.Options)) (*s3.ListMultipartUploadsOutput, error) {
+	return &s3.ListMultipartUploadsOutput{Uploads: f.uploads}, nil
+}
+
+func (f *fakeAuditAPI) ListParts(ctx context.Context, params *s3.ListPartsInput, optFns ...func(*s3.Options)) (*s3.ListPartsOutput, error) {
+	output := &s3.ListPartsOutput{}
+	for i, size := range f.parts[aws.ToString(params.UploadId)] {
+		output.Parts = append(output.Parts, s3types.Part{PartNumber: aws.Int32(int32(i + 1)), Size: aws.Int64(size)})
+	}
+	return output, nil
+}
+
+func auditObject(key string, size int64, class s3types.ObjectStorageClass, age time.Duration) s3types.Object {
+	return s3types.Object{
+		Key:          aws.String(key),
+		Size:         aws.Int64(size),
+		StorageClass: class,
+		LastModified: aws.Time(auditNow.Add(-age)),
+	}
+}
+
+func newFakeAuditAPI() *fakeAuditAPI {
+	day := 24 * time.Hour
+	return &fakeAuditAPI{
+		fakeS3Lister: fakeS3Lister{pages: [][]s3types.Object{
+			{
+				auditObject("lab/runs/2021/a.bam", 10*gib, s3types.ObjectStorageClassStandard, 400*day),
+				auditObject("lab/runs/2025/b.bam", 10*gib, s3types.ObjectStorageClassStandard, 10*day),
+			},
+			{
+				auditObject("lab/archive/c.tar", 100*gib, s3types.ObjectStorageClassGlacier, 300*day),
+				auditObject("lab/archive/small.txt", 1024, s3types.ObjectStorageClassGlacier, 300*day),
+				auditObject("lab/README", 2048, "", 10*day),
+			},
+		}},
+		uploads: []s3types.MultipartUpload{
+			{Key: aws.String("lab/runs/abandoned.bam"), UploadId: aws.String("old"), Initiated: aws.Time(auditNow.Add(-30 * day))},
+			{Key: aws.String("lab/runs/uploading.bam"), UploadId: aws.String("new"), Initiated: aws.Time(auditNow.Add(-time.Hour))},
+		},
+		parts: map[string][]int64{"old": {gib, gib}, "new": {gib}},
+	}
+}
+
+func auditFake(t *testing.T, api *fakeAuditAPI) *BucketAudit {
+	t.Helper()
+	auditor := NewS3CostCalculator("us-east-1").NewBucketAuditor("lab-data", "lab/", AuditSourceListing, auditNow)
+	if err := ListAuditObjects(context.Background(), api, "lab-data", "lab/", auditor); err != nil {
+		t.Fatalf("ListAuditObjects() error = %v", err)
+	}
+	if err := ListIncompleteUploads(context.Background(), api, "lab-data", "lab/", auditor); err != nil {
+		t.Fatalf("ListIncompleteUploads() error = %v", err)
+	}
+	return auditor.Finish()
+}
+
+func findFinding(audit *BucketAudit, findingType string) *AuditFinding {
+	for i := range audit.Findings {
+		if audit.Findings[i].Type == findingType {
+			return &audit.Findings[i]
+		}
+	}
+	return nil
+}
+
+func TestBucketAuditAggregation(t *testing.T) {
+	audit := auditFake(t, newFakeAuditAPI())
+
+	if audit.Objects != 5 || audit.Bytes != 120*gib+3072 {
+		t.Errorf("totals = %d objects, %d bytes", audit.Objects, audit.Bytes)
+	}
+	if len(audit.StorageClasses) != 2 || audit.StorageClasses[0].StorageClass != "GLACIER" || audit.StorageClasses[1].StorageClass != "STANDARD" {
+		t.Fatalf("StorageClasses = %+v, want GLACIER then STANDARD", audit.StorageClasses)
+	}
+	standard := audit.StorageClasses[1]
+	if standard.Objects != 3 || standard.Bytes != 20*gib+2048 {
+		t.Errorf("STANDARD = %+v, want the unlabelled object counted as STANDARD", standard)
+	}
+	glacier := audit.StorageClasses[0]
+	if glacier.BillableBytes != 100*gib+1024+2*archiveMetadataBytes {
+		t.Errorf("GLACIER billable bytes = %d, want archive metadata added", glacier.BillableBytes)
+	}
+	if math.Abs(glacier.MonthlyCost-0.4) > 0.001 || math.Abs(standard.MonthlyCost-0.46) > 0.001 {
+		t.Errorf("class costs = %.4f GLACIER, %.4f STANDARD", glacier.MonthlyCost, standard.MonthlyCost)
+	}
+
+	prefixes := make(map[string]PrefixUsage)
+	for _, prefix := range audit.Prefixes {
+		prefixes[prefix.Prefix] = prefix
+	}
+	if len(prefixes) != 3 || prefixes["runs/"].Objects != 2 || prefixes["archive/"].Objects != 2 || prefixes[""].Objects != 1 {
+		t.Errorf("Prefixes = %+v, want runs/, archive/ and the top level", audit.Prefixes)
+	}
+	if prefixes["archive/"].ByClass["GLACIER"] != 100*gib+1024 {
+		t.Errorf("archive/ by class = %v", prefixes["archive/"].ByClass)
+	}
+
+	var prefixCost float64
+	for _, prefix := range audit.Prefixes {
+		prefixCost += prefix.MonthlyCost
+	}
+	if math.Abs(prefixCost+audit.IncompleteUploadCost-audit.MonthlyCost) > 1e-9 {
+		t.Errorf("prefix costs %.6f + uploads %.6f != total %.6f", prefixCost, audit.IncompleteUploadCost, audit.MonthlyCost)
+	}
+	if audit.IncompleteUploads != 2 || audit.IncompleteUploadBytes != 3*gib {
+		t.Errorf("incomplete uploads = %d, %d bytes", audit.IncompleteUploads, audit.IncompleteUploadBytes)
+	}
+}
+
+func TestBucketAuditFindings(t *testing.T) {
+	audit := auditFake(t, newFakeAuditAPI())
+	if len(audit.Findings) != 3 {
+		t.Fatalf("Findings = %+v, want 3", audit.Findings)
+	}
+
+	stale := findFinding(audit, FindingStaleStandard)
+	if stale == nil || stale.Objects != 1 || stale.Bytes != 10*gib || stale.Examples[0] != "lab/runs/2021/a.bam" {
+		t.Errorf("stale finding = %+v, want only the 400-day-old object", stale)
+	}
+	if stale != nil && math.Abs(stale.EstimatedSavings-(0.23-0.125)) > 0.001 {
+		t.Errorf("stale savings = %.4f, want the STANDARD to STANDARD_IA difference", stale.EstimatedSavings)
+	}
+
+	small := findFinding(audit, FindingSmallObjects)
+	if small == nil || small.Objects != 1 || small.Examples[0] != "lab/archive/small.txt" {
+		t.Errorf("small finding = %+v, want only the small Glacier object", small)
+	}
+	if small != nil && small.EstimatedSavings <= 0 {
+		t.Errorf("small finding savings = %f, want the metadata overhead", small.EstimatedSavings)
+	}
+
+	uploads := findFinding(audit, FindingIncompleteMultipart)
+	if uploads == nil || uploads.Objects != 1 || uploads.Bytes != 2*gib || uploads.Examples[0] != "lab/runs/abandoned.bam" {
+		t.Errorf("upload finding = %+v, want only the month-old upload", uploads)
+	}
+}
+
+func TestBucketAuditNoFindings(t *testing.T) {
+	api := &fakeAuditAPI{fakeS3Lister: fakeS3Lister{pages: [][]s3types.Object{
+		{auditObject("lab/new.bam", gib, s3types.ObjectStorageClassStandard, time.Hour)},
+	}}}
+	audit := auditFake(t, api)
+	if len(audit.Findings) != 0 {
+		t.Errorf("Findings = %+v, want none", audit.Findings)
+	}
+	// Small Standard objects are billed at their size
+	auditor := NewS3CostCalculator("us-east-1").NewBucketAuditor("lab-data", "", AuditSourceListing, auditNow)
+	auditor.AddObject(AuditObject{Key: "tiny", Size: 10, StorageClass: "STANDARD", LastModified: auditNow})
+	if findings := auditor.Finish().Findings; len(findings) != 0 {
+		t.Errorf("Findings = %+v, want none for a small Standard object", findings)
+	}
+}
+
+func TestBucketAuditMinimumObjectSize(t *testing.T) {
+	auditor := NewS3CostCalculator("us-east-1").NewBucketAuditor("lab-data", "", AuditSourceListing, auditNow)
+	auditor.AddObject(AuditObject{Key: "a", Size: 1024, StorageClass: "STANDARD_IA", LastModified: auditNow})
+	audit := auditor.Finish()
+
+	if audit.StorageClasses[0].BillableBytes != smallObjectSize {
+		t.Errorf("billable bytes = %d, want the 128 KB minimum", audit.StorageClasses[0].BillableBytes)
+	}
+	if small := findFinding(audit, FindingSmallObjects); small == nil || !strings.Contains(small.Description, "STANDARD_IA") {
+		t.Errorf("small finding = %+v, want STANDARD_IA flagged", small)
+	}
+}
+
+func TestTopLevelPrefix(t *testing.T) {
+	tests := []struct{ prefix, key, want string }{
+		{"lab/", "lab/runs/a", "runs/"},
+		{"lab", "lab/runs/a", "runs/"},
+		{"lab/", "lab/a", ""},
+		{"", "runs/2021/a", "runs/"},
+	}
+	for _, tt := range tests {
+		if got := topLevelPrefix(tt.prefix, tt.key); got != tt.want {
+			t.Errorf("topLevelPrefix(%q, %q) = %q, want %q", tt.prefix, tt.key, got, tt.want)
+		}
+	}
+}
+
+const inventorySchema = "Bucket, Key, IsDeleteMarker, Size, LastModifiedDate, StorageClass"
+
+func TestReadInventoryCSV(t *testing.T) {
+	rows := `"lab-data","lab/runs/a%20b.bam","false","1024","2024-01-01T00:00:00.000Z","STANDARD"
+"lab-data","lab/runs/deleted.bam","true","","2024-01-01T00:00:00.000Z",""
+"lab-data","other/c.bam","false","2048","2024-01-01T00:00:00.000Z","GLACIER"
+"lab-data","lab/archive/d.tar","false","4096","2024-01-01T00:00:00.000Z","DEEP_ARCHIVE"
+`
+	auditor := NewS3CostCalculator("us-east-1").NewBucketAuditor("lab-data", "lab/", AuditSourceInventory, auditNow)
+	if err := ReadInventoryCSV(strings.NewReader(rows), inventorySchema, "lab/", auditor); err != nil {
+		t.Fatalf("ReadInventoryCSV() error = %v", err)
+	}
+	audit := auditor.Finish()
+	if audit.Objects != 2 || audit.Bytes != 1024+4096 {
+		t.Errorf("totals = %d objects, %d bytes; want delete markers and other prefixes skipped", audit.Objects, audit.Bytes)
+	}
+	if stale := findFinding(audit, FindingStaleStandard); stale == nil || stale.Examples[0] != "lab/runs/a b.bam" {
+		t.Errorf("stale finding = %+v, want the decoded key", stale)
+	}
+}
+
+func TestParseInventoryManifest(t *testing.T) {
+	manifest, err := ParseInventoryManifest(strings.NewReader(`{
+  "sourceBucket": "lab-data",
+  "destinationBucket": "arn:aws:s3:::lab-inventory",
+  "fileFormat": "CSV",
+  "fileSchema": "` + inventorySchema + `",
+  "files": [{"key": "inventory/lab-data/data/1.csv.gz", "size": 100}]
+}`))
+	if err != nil {
+		t.Fatalf("ParseInventoryManifest() error = %v", err)
+	}
+	if manifest.DestinationBucketName() != "lab-inventory" || len(manifest.Files) != 1 {
+		t.Errorf("manifest = %+v", manifest)
+	}
+
+	if _, err := ParseInventoryManifest(strings.NewReader(`{"fileFormat": "Parquet", "fileSchema": "` + inventorySchema + `"}`)); err == nil {
+		t.Error("ParseInventoryManifest(Parquet) succeeded, want an error")
+	}
+	if _, err := ParseInventoryManifest(strings.NewReader(`{"fileFormat": "CSV", "fileSchema": "Bucket, Key, Size"}`)); err == nil {
+		t.Error("ParseInventoryManifest(no StorageClass) succeeded, want an error")
+	}
+}
+
+// fakeObjectGetter serves objects from memory
+type fakeObjectGetter map[string][]byte
+
+func (f fakeObjectGetter) GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	content, ok := f[aws.ToString(params.Bucket)+"/"+aws.ToString(params.Key)]
+	if !ok {
+		return nil, &s3types.NoSuchKey{}
+	}
+	return &s3.GetObjectOutput{Body: io.NopCloser(bytes.NewReader(content))}, nil
+}
+
+func TestLoadInventory(t *testing.T) {
+	var data bytes.Buffer
+	gz := gzip.NewWriter(&data)
+	gz.Write([]byte(`"lab-data","lab/a.bam","false","1024","2025-05-01T00:00:00Z","STANDARD"` + "\n"))
+	gz.Close()
+	getter := fakeObjectGetter{
+		"lab-inventory/inventory/manifest.json": []byte(`{"destinationBucket": "arn:aws:s3:::lab-inventory", "fileFormat": "CSV",
+			"fileSchema": "` + inventorySchema + `", "files": [{"key": "inventory/data/1.csv.gz"}]}`),
+		"lab-inventory/inventory/data/1.csv.gz": data.Bytes(),
+	}
+
+	auditor := NewS3CostCalculator("us-east-1").NewBucketAuditor("lab-data", "", AuditSourceInventory, auditNow)
+	if _, err := LoadInventory(context.Background(), getter, "lab-inventory", "inventory/manifest.json", "", auditor); err != nil {
+		t.Fatalf("LoadInventory() error = %v", err)
+	}
+	if audit := auditor.Finish(); audit.Objects != 1 || audit.Bytes != 1024 {
+		t.Errorf("audit = %d objects, %d bytes", audit.Objects, audit.Bytes)
+	}
+}
+
+func TestAnalyzeCostsWithAuditedCurrentState(t *testing.T) {
+	audit := auditFake(t, newFakeAuditAPI())
+	calculator := NewS3CostCalculator("us-east-1")
+	calculator.SetCurrentState(audit)
+
+	pattern := &DataPattern{TotalFiles: 5, TotalSize: 120 * gib, FileTypes: map[string]FileTypeInfo{}}
+	analysis, err := calculator.AnalyzeCosts(context.Background(), pattern)
+	if err != nil {
+		t.Fatalf("AnalyzeCosts() error = %v", err)
+	}
+	current := analysis.Scenarios[0]
+	if current.Name != "Current State" || current.StorageClass != "GLACIER" {
+		t.Errorf("current state = %s in %s, want the audited dominant class", current.Name, current.StorageClass)
+	}
+	if current.MonthlyCosts.Storage != audit.MonthlyCost {
+		t.Errorf("current storage cost = %.4f, want the audited %.4f", current.MonthlyCosts.Storage, audit.MonthlyCost)
+	}
+	if current.MonthlyCosts.Retrieval == 0 {
+		t.Error("current state has no retrieval cost for Glacier downloads")
+	}
+}
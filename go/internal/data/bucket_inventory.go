@@ -0,0 +1,172 @@
+package data
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// InventoryManifest is the manifest.json S3 Inventory writes with each report
+type InventoryManifest struct {
+	SourceBucket      string          `json:"sourceBucket"`
+	DestinationBucket string          `json:"destinationBucket"`
+	FileFormat        string          `json:"fileFormat"`
+	FileSchema        string          `json:"fileSchema"`
+	Files             []InventoryFile `json:"files"`
+}
+
+// InventoryFile is one gzipped data file of an inventory report
+type InventoryFile struct {
+	Key  string `json:"key"`
+	Size int64  `json:"size"`
+}
+
+// s3GetObjectAPI is the subset of the S3 API used to read inventory reports
+type s3GetObjectAPI interface {
+	GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+}
+
+// ParseInventoryManifest reads an inventory manifest. Only CSV reports can be
+// read; ORC and Parquet reports are rejected.
+func ParseInventoryManifest(r io.Reader) (*InventoryManifest, error) {
+	var manifest InventoryManifest
+	if err := json.NewDecoder(r).Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse inventory manifest: %w", err)
+	}
+	if !strings.EqualFold(manifest.FileFormat, "CSV") {
+		return nil, fmt.Errorf("inventory format %s is not supported; configure the inventory to write CSV", manifest.FileFormat)
+	}
+	for _, column := range []string{"Key", "Size", "StorageClass"} {
+		if inventoryColumn(manifest.FileSchema, column) < 0 {
+			return nil, fmt.Errorf("inventory has no %s field; add it to the inventory configuration", column)
+		}
+	}
+	return &manifest, nil
+}
+
+// DestinationBucketName returns the bucket the report files were written to
+func (m *InventoryManifest) DestinationBucketName() string {
+	return strings.TrimPrefix(m.DestinationBucket, "arn:aws:s3:::")
+}
+
+// inventoryColumn returns the position of a field in the schema, or -1
+func inventoryColumn(schema, name string) int {
+	for i, field := range strings.Split(schema, ",") {
+		if strings.TrimSpace(field) == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// ReadInventoryCSV adds the rows of one inventory data file under prefix to
+// an audit. Delete markers are skipped; noncurrent versions are billed, so they
+// are kept.
+func ReadInventoryCSV(r io.Reader, schema, prefix string, auditor *BucketAuditor) error {
+	keyColumn := inventoryColumn(schema, "Key")
+	sizeColumn := inventoryColumn(schema, "Size")
+	classColumn := inventoryColumn(schema, "StorageClass")
+	modifiedColumn := inventoryColumn(schema, "LastModifiedDate")
+	deleteMarkerColumn := inventoryColumn(schema, "IsDeleteMarker")
+
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read inventory: %w", err)
+		}
+		field := func(column int) string {
+			if column < 0 || column >= len(record) {
+				return ""
+			}
+			return record[column]
+		}
+
+		if field(deleteMarkerColumn) == "true" {
+			continue
+		}
+		// Inventory keys are URL-encoded
+		key, err := url.QueryUnescape(field(keyColumn))
+		if err != nil {
+			return fmt.Errorf("invalid key %q in inventory: %w", field(keyColumn), err)
+		}
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		size, err := strconv.ParseInt(field(sizeColumn), 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid size %q for %s in inventory: %w", field(sizeColumn), key, err)
+		}
+		object := AuditObject{Key: key, Size: size, StorageClass: field(classColumn)}
+		if modified := field(modifiedColumn); modified != "" {
+			if object.LastModified, err = time.Parse(time.RFC3339, modified); err != nil {
+				return fmt.Errorf("invalid modification date %q for %s in inventory: %w", modified, key, err)
+			}
+		}
+		auditor.AddObject(object)
+	}
+}
+
+// LoadInventory reads an S3 Inventory report, given the location of its
+// manifest, into an audit. It returns the manifest so the caller knows which
+// bucket the report describes.
+func LoadInventory(ctx context.Context, api s3GetObjectAPI, manifestBucket, manifestKey, prefix string, auditor *BucketAuditor) (*InventoryManifest, error) {
+	body, err := getObjectBody(ctx, api, manifestBucket, manifestKey)
+	if err != nil {
+		return nil, err
+	}
+	manifest, err := ParseInventoryManifest(body)
+	body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	destination := manifest.DestinationBucketName()
+	for _, file := range manifest.Files {
+		if err := readInventoryFile(ctx, api, destination, file.Key, manifest.FileSchema, prefix, auditor); err != nil {
+			return nil, err
+		}
+	}
+	return manifest, nil
+}
+
+func readInventoryFile(ctx context.Context, api s3GetObjectAPI, bucket, key, schema, prefix string, auditor *BucketAuditor) error {
+	body, err := getObjectBody(ctx, api, bucket, key)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	gz, err := gzip.NewReader(body)
+	if err != nil {
+		return fmt.Errorf("failed to decompress s3://%s/%s: %w", bucket, key, err)
+	}
+	defer gz.Close()
+	if err := ReadInventoryCSV(gz, schema, prefix, auditor); err != nil {
+		return fmt.Errorf("s3://%s/%s: %w", bucket, key, err)
+	}
+	return nil
+}
+
+func getObjectBody(ctx context.Context, api s3GetObjectAPI, bucket, key string) (io.ReadCloser, error) {
+	output, err := api.GetObject(ctx, &s3.GetObjectInput{Bucket: aws.String(bucket), Key: aws.String(key)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read s3://%s/%s: %w", bucket, key, err)
+	}
+	return output.Body, nil
+}
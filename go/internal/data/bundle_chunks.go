@@ -0,0 +1,262 @@
+package data
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// ChunkBundlePrefix starts the name of every chunked bundle
+const ChunkBundlePrefix = "chunk_"
+
+// maxChunkSizeFactor bounds a chunk at this many times the target size.
+// Larger chunks are split again at a finer boundary spacing.
+const maxChunkSizeFactor = 4
+
+// chunkSplitFactor is how much finer each split of an oversized chunk is
+const chunkSplitFactor = 4
+
+// Chunked bundles group small files so that re-bundling a dataset after a few
+// files change rewrites only the bundles holding those files. Files are sorted
+// by relative path and a bundle ends after every file whose path hashes to a
+// boundary, so where a bundle ends depends only on the paths near it: adding
+// or removing a file moves no boundary except, at most, its own. A chunk that
+// grows too large is split at the boundaries of a smaller divisor, which
+// include its own, so the split is as stable as the chunking. A bundle is
+// named after the hash of its member paths and written deterministically, so
+// an unchanged group produces a byte-identical bundle under the same name.
+
+// chunkBoundaryDivisor returns how many files a chunk should hold on average:
+// the target size over the average small file size, rounded to a power of two.
+// Rounding keeps the divisor, and with it every boundary, fixed while the
+// average drifts as files come and go; when it does change, the boundaries of
+// the smaller power of two are a superset of the larger's.
+func chunkBoundaryDivisor(files []FileEntry, targetSize int64) uint64 {
+	if len(files) == 0 || targetSize <= 0 {
+		return 1
+	}
+	var total int64
+	for _, file := range files {
+		total += file.Size
+	}
+	average := float64(total) / float64(len(files))
+	if average < 1 {
+		average = 1
+	}
+
+	perChunk := float64(targetSize) / average
+	if perChunk <= 1 {
+		return 1
+	}
+	return uint64(1) << uint(math.Round(math.Log2(perChunk)))
+}
+
+// isChunkBoundary reports whether a chunk ends after the file at this path
+func isChunkBoundary(relativePath string, divisor uint64) bool {
+	hash := fnv.New64a()
+	hash.Write([]byte(relativePath))
+	return hash.Sum64()%divisor == divisor-1
+}
+
+// chunkName names a chunk after its member paths
+func chunkName(paths []string) string {
+	hash := sha256.New()
+	for _, p := range paths {
+		hash.Write([]byte(p))
+		hash.Write([]byte{0})
+	}
+	return ChunkBundlePrefix + hex.EncodeToString(hash.Sum(nil))[:16]
+}
+
+// groupFilesIntoChunks splits small files into content-defined chunks of
+// roughly the target size, in path order
+func (se *SuitcaseEngine) groupFilesIntoChunks(files []FileEntry, targetSize int64) []BundleGroup {
+	sorted := make([]FileEntry, len(files))
+	copy(sorted, files)
+	sort.Slice(sorted, func(i, j int) bool {
+		return filepath.ToSlash(sorted[i].RelativePath) < filepath.ToSlash(sorted[j].RelativePath)
+	})
+
+	chunks := splitChunks(sorted, chunkBoundaryDivisor(sorted, targetSize), targetSize*maxChunkSizeFactor)
+	groups := make([]BundleGroup, 0, len(chunks))
+	for _, chunk := range chunks {
+		paths := make([]string, len(chunk))
+		var size int64
+		for i, file := range chunk {
+			paths[i] = filepath.ToSlash(file.RelativePath)
+			size += file.Size
+		}
+		groups = append(groups, BundleGroup{
+			Name:           chunkName(paths),
+			Files:          chunk,
+			TargetSize:     targetSize,
+			ExpectedSize:   size,
+			CompressionEst: se.estimateCompressionRatio(chunk),
+		})
+	}
+	return groups
+}
+
+// splitChunks ends a chunk after every boundary file, then splits chunks over
+// maxSize again with a finer divisor. A divisor of one makes every file a
+// boundary, so only single files can remain over maxSize.
+func splitChunks(files []FileEntry, divisor uint64, maxSize int64) [][]FileEntry {
+	var chunks [][]FileEntry
+	start := 0
+	var size int64
+	for i, file := range files {
+		size += file.Size
+		if i < len(files)-1 && !isChunkBoundary(filepath.ToSlash(file.RelativePath), divisor) {
+			continue
+		}
+
+		chunk := files[start : i+1]
+		if size > maxSize && divisor > 1 {
+			finer := divisor / chunkSplitFactor
+			if finer < 1 {
+				finer = 1
+			}
+			chunks = append(chunks, splitChunks(chunk, finer, maxSize)...)
+		} else {
+			chunks = append(chunks, chunk)
+		}
+		start, size = i+1, 0
+	}
+	return chunks
+}
+
+// chunkModTime is the newest member modification time, given to a chunked
+// bundle so that an unchanged bundle keeps its size and time across runs
+func chunkModTime(files []FileEntry) time.Time {
+	var newest time.Time
+	for _, file := range files {
+		if file.ModTime.After(newest) {
+			newest = file.ModTime
+		}
+	}
+	return newest
+}
+
+// s3HeadAPI is the subset of the S3 API used to check uploaded bundles
+type s3HeadAPI interface {
+	HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error)
+}
+
+// BundleUploader uploads a local file to a key in the destination bucket
+type BundleUploader func(ctx context.Context, key, localPath string) error
+
+// ChunkUploadReport counts what an upload of chunked bundles did
+type ChunkUploadReport struct {
+	Uploaded      int   `json:"uploaded"`
+	Unchanged     int   `json:"unchanged"`
+	Resumed       int   `json:"resumed"`
+	UploadedBytes int64 `json:"uploaded_bytes"`
+	SkippedBytes  int64 `json:"skipped_bytes"`
+}
+
+// UploadChunkedBundles uploads bundles and their indexes under prefix,
+// skipping bundles whose recorded checksum matches the object already in S3.
+// Bundles uploaded or found unchanged are recorded in the journal, so an
+// interrupted run resumes without checking them again. Each index is uploaded
+// before its bundle, so a bundle in S3 always has its index.
+func UploadChunkedBundles(ctx context.Context, api s3HeadAPI, bucket, prefix string, bundles []BundleManifestEntry, journal *VerifyJournal, upload BundleUploader) (*ChunkUploadReport, error) {
+	report := &ChunkUploadReport{}
+	for _, bundle := range bundles {
+		key := path.Join(prefix, bundle.BundleName)
+		info, err := os.Stat(bundle.BundlePath)
+		if err != nil {
+			return report, fmt.Errorf("failed to stat bundle %s: %w", bundle.BundlePath, err)
+		}
+
+		if journal != nil && journal.Verified(key, info.Size(), info.ModTime()) {
+			report.Resumed++
+			report.SkippedBytes += info.Size()
+			continue
+		}
+
+		unchanged, err := bundleUnchanged(ctx, api, bucket, key, bundle, info.Size())
+		if err != nil {
+			return report, err
+		}
+		if unchanged {
+			report.Unchanged++
+			report.SkippedBytes += info.Size()
+		} else {
+			if bundle.IndexPath != "" {
+				indexKey := path.Join(prefix, filepath.Base(bundle.IndexPath))
+				if err := upload(ctx, indexKey, bundle.IndexPath); err != nil {
+					return report, fmt.Errorf("failed to upload %s: %w", bundle.IndexPath, err)
+				}
+			}
+			if err := upload(ctx, key, bundle.BundlePath); err != nil {
+				return report, fmt.Errorf("failed to upload %s: %w", bundle.BundlePath, err)
+			}
+			report.Uploaded++
+			report.UploadedBytes += info.Size()
+		}
+
+		if journal != nil {
+			if err := journal.Record(key, info.Size(), info.ModTime()); err != nil {
+				return report, err
+			}
+		}
+	}
+	return report, nil
+}
+
+// bundleUnchanged reports whether the object at key already holds the bundle.
+// The checksum recorded when bundling is compared with a single-part MD5 ETag;
+// multipart ETags and SHA-256 checksums need the bundle read again.
+func bundleUnchanged(ctx context.Context, api s3HeadAPI, bucket, key string, bundle BundleManifestEntry, size int64) (bool, error) {
+	head, err := api.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket:       aws.String(bucket),
+		Key:          aws.String(key),
+		ChecksumMode: s3types.ChecksumModeEnabled,
+	})
+	if err != nil {
+		var notFound *s3types.NotFound
+		if errors.As(err, &notFound) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to get metadata for s3://%s/%s: %w", bucket, key, err)
+	}
+
+	remote := &RemoteObject{
+		Key:            key,
+		Size:           aws.ToInt64(head.ContentLength),
+		ETag:           aws.ToString(head.ETag),
+		ChecksumSHA256: aws.ToString(head.ChecksumSHA256),
+		ChecksumType:   string(head.ChecksumType),
+		Encryption:     string(head.ServerSideEncryption),
+	}
+	if head.SSECustomerAlgorithm != nil {
+		remote.Encryption = "customer-key"
+	}
+	if remote.Size != size {
+		return false, nil
+	}
+
+	local := &LocalChecksums{Size: size, MD5: bundle.Checksum}
+	partSizes := partSizesFor(remote)
+	_, hasSHA256 := remote.fullObjectSHA256()
+	if len(partSizes) > 0 || hasSHA256 || len(bundle.Checksum) != 32 {
+		if local, err = ComputeLocalChecksums(bundle.BundlePath, partSizes); err != nil {
+			return false, fmt.Errorf("failed to checksum %s: %w", bundle.BundlePath, err)
+		}
+	}
+	status, _ := CompareChecksums(local, remote)
+	return status == VerifyOK, nil
+}
@@ -0,0 +1,441 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// syntheticChunkFiles returns n small files spread over a few directories
+func syntheticChunkFiles(random *rand.Rand, n int) []FileEntry {
+	files := make([]FileEntry, n)
+	for i := range files {
+		rel := fmt.Sprintf("run-%02d/sample_%05d.fastq", random.Intn(20), random.Intn(1_000_000))
+		files[i] = FileEntry{Path: "/data/" + rel, RelativePath: rel, Size: 1000 + random.Int63n(9000)}
+	}
+	return files
+}
+
+// changedChunks returns the chunks only one of a and b has
+func changedChunks(a, b []BundleGroup) []BundleGroup {
+	names := func(groups []BundleGroup) map[string]bool {
+		set := make(map[string]bool, len(groups))
+		for _, group := range groups {
+			set[group.Name] = true
+		}
+		return set
+	}
+	namesA, namesB := names(a), names(b)
+
+	var changed []BundleGroup
+	for _, group := range a {
+		if !namesB[group.Name] {
+			changed = append(changed, group)
+		}
+	}
+	for _, group := range b {
+		if !namesA[group.Name] {
+			changed = append(changed, group)
+		}
+	}
+	return changed
+}
+
+// affectedPaths returns the first and last path an edit at target may move
+// between chunks: the top-level chunk holding target, and the next one too
+// when target is itself a boundary
+func affectedPaths(files []FileEntry, divisor uint64, target string) (string, string) {
+	paths := make([]string, len(files))
+	for i, file := range files {
+		paths[i] = file.RelativePath
+	}
+	sort.Strings(paths)
+	at := sort.SearchStrings(paths, target)
+
+	first := at
+	for first > 0 && !isChunkBoundary(paths[first-1], divisor) {
+		first--
+	}
+	last := at
+	for last < len(paths)-1 && (last == at || !isChunkBoundary(paths[last], divisor)) {
+		last++
+	}
+	return paths[first], paths[last]
+}
+
+// checkEditConfined fails when a chunk outside the paths an edit may affect changed
+func checkEditConfined(t *testing.T, edit string, before, after, withTarget []FileEntry, target string, engine *SuitcaseEngine, targetSize int64) {
+	t.Helper()
+	divisor := chunkBoundaryDivisor(before, targetSize)
+	first, last := affectedPaths(withTarget, divisor, target)
+
+	changed := changedChunks(engine.groupFilesIntoChunks(before, targetSize), engine.groupFilesIntoChunks(after, targetSize))
+	if len(changed) == 0 {
+		t.Errorf("%s %s changed no chunk", edit, target)
+	}
+	for _, chunk := range changed {
+		for _, file := range chunk.Files {
+			if file.RelativePath < first || file.RelativePath > last {
+				t.Errorf("%s %s changed chunk %s holding %s, outside %s..%s", edit, target, chunk.Name, file.RelativePath, first, last)
+				break
+			}
+		}
+	}
+}
+
+func TestChunkBoundaryDivisor(t *testing.T) {
+	files := func(size int64, n int) []FileEntry {
+		entries := make([]FileEntry, n)
+		for i := range entries {
+			entries[i].Size = size
+		}
+		return entries
+	}
+
+	tests := []struct {
+		name   string
+		files  []FileEntry
+		target int64
+		want   uint64
+	}{
+		{"no files", nil, 1 << 20, 1},
+		{"exact power of two", files(1024, 10), 64 * 1024, 64},
+		{"rounds to nearest", files(1000, 10), 64 * 1024, 64},
+		{"files above target", files(2<<20, 10), 1 << 20, 1},
+		{"empty files", files(0, 10), 1024, 1024},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := chunkBoundaryDivisor(tt.files, tt.target); got != tt.want {
+				t.Errorf("chunkBoundaryDivisor() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGroupFilesIntoChunksIgnoresInputOrder(t *testing.T) {
+	engine := NewSuitcaseEngine(&SuitcaseConfig{Chunked: true})
+	random := rand.New(rand.NewSource(7))
+	files := syntheticChunkFiles(random, 500)
+
+	first := engine.groupFilesIntoChunks(files, 64*1024)
+	random.Shuffle(len(files), func(i, j int) { files[i], files[j] = files[j], files[i] })
+	second := engine.groupFilesIntoChunks(files, 64*1024)
+
+	if len(first) < 10 {
+		t.Fatalf("got %d chunks, want enough to exercise boundaries", len(first))
+	}
+	if len(first) != len(second) {
+		t.Fatalf("chunk count changed with input order: %d vs %d", len(first), len(second))
+	}
+	var total int
+	for i := range first {
+		if first[i].Name != second[i].Name || first[i].ExpectedSize != second[i].ExpectedSize {
+			t.Errorf("chunk %d differs: %s vs %s", i, first[i].Name, second[i].Name)
+		}
+		total += len(first[i].Files)
+	}
+	if total != len(files) {
+		t.Errorf("chunks hold %d files, want %d", total, len(files))
+	}
+}
+
+func TestGroupFilesIntoChunksStableAcrossEdits(t *testing.T) {
+	engine := NewSuitcaseEngine(&SuitcaseConfig{Chunked: true})
+	random := rand.New(rand.NewSource(42))
+	const target = 64 * 1024
+
+	for trial := 0; trial < 50; trial++ {
+		files := syntheticChunkFiles(random, 2000)
+		before := engine.groupFilesIntoChunks(files, target)
+
+		// Edits large enough to change the divisor rechunk everything; one
+		// file rarely is, and such trials check nothing
+		newFile := syntheticChunkFiles(random, 1)[0]
+		added := append(append([]FileEntry{}, files...), newFile)
+		if chunkBoundaryDivisor(added, target) == chunkBoundaryDivisor(files, target) {
+			checkEditConfined(t, "adding", files, added, added, newFile.RelativePath, engine, target)
+		}
+
+		i := random.Intn(len(files))
+		removed := append(append([]FileEntry{}, files[:i]...), files[i+1:]...)
+		if chunkBoundaryDivisor(removed, target) == chunkBoundaryDivisor(files, target) {
+			checkEditConfined(t, "removing", files, removed, files, files[i].RelativePath, engine, target)
+		}
+
+		// Editing a file's contents leaves every chunk's membership alone
+		edited := append([]FileEntry{}, files...)
+		edited[random.Intn(len(edited))].Size += 10
+		if changed := changedChunks(before, engine.groupFilesIntoChunks(edited, target)); len(changed) != 0 {
+			t.Errorf("trial %d: editing a file renamed %d chunks", trial, len(changed))
+		}
+	}
+}
+
+// TestGroupFilesIntoChunksChangesFewChunks checks the typical cost of an edit:
+// one chunk replaced, or two when the edited file is a boundary
+func TestGroupFilesIntoChunksChangesFewChunks(t *testing.T) {
+	engine := NewSuitcaseEngine(&SuitcaseConfig{Chunked: true})
+	random := rand.New(rand.NewSource(11))
+	// Uniform sizes keep every chunk under the size bound
+	files := syntheticChunkFiles(random, 3000)
+	for i := range files {
+		files[i].Size = 1000
+	}
+	const target = 16 * 1000
+	before := engine.groupFilesIntoChunks(files, target)
+
+	for trial := 0; trial < 100; trial++ {
+		newFile := syntheticChunkFiles(random, 1)[0]
+		newFile.Size = 1000
+		added := append(append([]FileEntry{}, files...), newFile)
+		if changed := changedChunks(before, engine.groupFilesIntoChunks(added, target)); len(changed) > 3 {
+			t.Errorf("adding %s changed %d chunks, want at most 3", newFile.RelativePath, len(changed))
+		}
+	}
+}
+
+func TestGroupFilesIntoChunksBoundsSize(t *testing.T) {
+	engine := NewSuitcaseEngine(&SuitcaseConfig{Chunked: true})
+	// Every path below shares one hash remainder for a large divisor, so no
+	// boundary turns up and only the size bound ends chunks
+	var files []FileEntry
+	for i := 0; len(files) < 200; i++ {
+		rel := fmt.Sprintf("file_%06d", i)
+		if !isChunkBoundary(rel, 1024) {
+			files = append(files, FileEntry{RelativePath: rel, Size: 1000})
+		}
+	}
+
+	const target = 10_000
+	groups := engine.groupFilesIntoChunks(files, target)
+	for _, group := range groups {
+		if group.ExpectedSize > target*maxChunkSizeFactor {
+			t.Errorf("%s holds %d bytes, above %d", group.Name, group.ExpectedSize, target*maxChunkSizeFactor)
+		}
+	}
+	if len(groups) < 2 {
+		t.Errorf("got %d chunks, want the size bound to split them", len(groups))
+	}
+}
+
+// writeChunkSource writes n small files with fixed modification times
+func writeChunkSource(t *testing.T, n int) string {
+	t.Helper()
+	root := t.TempDir()
+	random := rand.New(rand.NewSource(3))
+	modTime := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	for i := 0; i < n; i++ {
+		full := filepath.Join(root, fmt.Sprintf("dir%d", i%4), fmt.Sprintf("file_%04d.dat", i))
+		if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+			t.Fatal(err)
+		}
+		content := make([]byte, 500+random.Intn(1500))
+		random.Read(content)
+		if err := os.WriteFile(full, content, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(full, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+// bundleChunks bundles source in chunked mode and returns checksum and
+// modification time by bundle name
+func bundleChunks(t *testing.T, source, output string) (map[string]string, map[string]time.Time) {
+	t.Helper()
+	engine := NewSuitcaseEngine(&SuitcaseConfig{
+		Backend:          NativeTarBackend,
+		Chunked:          true,
+		TargetBundleSize: "16KB",
+		SizeThreshold:    "1MB",
+		CompressionLevel: 6,
+		OutputDirectory:  output,
+	})
+	go func() {
+		for range engine.GetProgress() {
+		}
+	}()
+
+	result, err := engine.BundleFiles(context.Background(), source)
+	if err != nil {
+		t.Fatalf("BundleFiles() error = %v", err)
+	}
+	checksums := make(map[string]string)
+	modTimes := make(map[string]time.Time)
+	for _, bundle := range result.BundleManifest {
+		info, err := os.Stat(bundle.BundlePath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checksums[bundle.BundleName] = bundle.Checksum
+		modTimes[bundle.BundleName] = info.ModTime()
+	}
+	return checksums, modTimes
+}
+
+func TestChunkedBundlesRebundleIdentically(t *testing.T) {
+	source := writeChunkSource(t, 200)
+	first, firstTimes := bundleChunks(t, source, t.TempDir())
+	if len(first) < 4 {
+		t.Fatalf("got %d chunks, want several", len(first))
+	}
+
+	second, secondTimes := bundleChunks(t, source, t.TempDir())
+	for name, checksum := range first {
+		if second[name] != checksum {
+			t.Errorf("%s: checksum %s on the second run, want %s", name, second[name], checksum)
+		}
+		if !secondTimes[name].Equal(firstTimes[name]) {
+			t.Errorf("%s: modification time %v on the second run, want %v", name, secondTimes[name], firstTimes[name])
+		}
+	}
+
+	// Changing one file changes only the chunk that holds it
+	changed := filepath.Join(source, "dir1", "file_0101.dat")
+	if err := os.WriteFile(changed, []byte("new contents"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	third, _ := bundleChunks(t, source, t.TempDir())
+	var differing []string
+	for name, checksum := range first {
+		if third[name] != checksum {
+			differing = append(differing, name)
+		}
+	}
+	if len(differing) != 1 {
+		t.Errorf("%d chunks changed after editing one file: %v", len(differing), differing)
+	}
+}
+
+func TestChunkedBundlesRequireNativeBackend(t *testing.T) {
+	engine := NewSuitcaseEngine(&SuitcaseConfig{Chunked: true, OutputDirectory: t.TempDir()})
+	if _, err := engine.BundleFiles(context.Background(), t.TempDir()); err == nil {
+		t.Error("expected an error for chunked bundles with the Suitcase backend")
+	}
+}
+
+// fakeHeadAPI serves HeadObject from a map, reporting other keys as missing
+type fakeHeadAPI struct {
+	heads map[string]*s3.HeadObjectOutput
+}
+
+func (f *fakeHeadAPI) HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+	if head, exists := f.heads[aws.ToString(params.Key)]; exists {
+		return head, nil
+	}
+	return nil, &s3types.NotFound{}
+}
+
+func TestUploadChunkedBundles(t *testing.T) {
+	dir := t.TempDir()
+	var bundles []BundleManifestEntry
+	for _, name := range []string{"chunk_a", "chunk_b", "chunk_c"} {
+		bundlePath := filepath.Join(dir, name+".tar.gz")
+		if err := os.WriteFile(bundlePath, []byte("contents of "+name), 0644); err != nil {
+			t.Fatal(err)
+		}
+		indexPath := BundleIndexPath(bundlePath)
+		if err := os.WriteFile(indexPath, []byte("{}"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		checksum, err := FileMD5(bundlePath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		bundles = append(bundles, BundleManifestEntry{
+			BundleName: filepath.Base(bundlePath),
+			BundlePath: bundlePath,
+			IndexPath:  indexPath,
+			Checksum:   checksum,
+		})
+	}
+
+	api := &fakeHeadAPI{heads: map[string]*s3.HeadObjectOutput{
+		// Unchanged: same size and MD5 ETag
+		"run/chunk_a.tar.gz": {ContentLength: aws.Int64(int64(len("contents of chunk_a"))), ETag: aws.String(`"` + bundles[0].Checksum + `"`)},
+		// Changed: same size, different content
+		"run/chunk_b.tar.gz": {ContentLength: aws.Int64(int64(len("contents of chunk_b"))), ETag: aws.String(`"00000000000000000000000000000000"`)},
+	}}
+	var uploaded []string
+	upload := func(ctx context.Context, key, localPath string) error {
+		uploaded = append(uploaded, key)
+		return nil
+	}
+
+	journal, err := OpenVerifyJournal(filepath.Join(t.TempDir(), "upload.journal"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer journal.Close()
+
+	report, err := UploadChunkedBundles(context.Background(), api, "bucket", "run", bundles, journal, upload)
+	if err != nil {
+		t.Fatalf("UploadChunkedBundles() error = %v", err)
+	}
+	if report.Unchanged != 1 || report.Uploaded != 2 || report.Resumed != 0 {
+		t.Errorf("report = %+v, want 1 unchanged and 2 uploaded", report)
+	}
+	want := []string{"run/chunk_b.idx.json", "run/chunk_b.tar.gz", "run/chunk_c.idx.json", "run/chunk_c.tar.gz"}
+	if fmt.Sprint(uploaded) != fmt.Sprint(want) {
+		t.Errorf("uploaded %v, want %v (each index before its bundle)", uploaded, want)
+	}
+
+	// A resumed run skips everything already recorded without asking S3
+	uploaded = nil
+	report, err = UploadChunkedBundles(context.Background(), &fakeHeadAPI{}, "bucket", "run", bundles, journal, upload)
+	if err != nil {
+		t.Fatalf("UploadChunkedBundles() resume error = %v", err)
+	}
+	if report.Resumed != 3 || len(uploaded) != 0 {
+		t.Errorf("resumed report = %+v, uploaded %v", report, uploaded)
+	}
+}
+
+func TestBundleUnchangedMultipart(t *testing.T) {
+	dir := t.TempDir()
+	bundlePath := filepath.Join(dir, "chunk_big.tar")
+	content := make([]byte, 12*mebibyte)
+	rand.New(rand.NewSource(9)).Read(content)
+	if err := os.WriteFile(bundlePath, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+	local, err := ComputeLocalChecksums(bundlePath, []int64{8 * mebibyte})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bundle := BundleManifestEntry{BundleName: "chunk_big.tar", BundlePath: bundlePath, Checksum: local.MD5}
+
+	api := &fakeHeadAPI{heads: map[string]*s3.HeadObjectOutput{
+		"chunk_big.tar": {ContentLength: aws.Int64(int64(len(content))), ETag: aws.String(`"` + local.PartETags[8*mebibyte] + `"`)},
+	}}
+	unchanged, err := bundleUnchanged(context.Background(), api, "bucket", "chunk_big.tar", bundle, int64(len(content)))
+	if err != nil || !unchanged {
+		t.Errorf("bundleUnchanged() = %v, %v; want a multipart ETag match", unchanged, err)
+	}
+}
+
+func TestChunkNameDependsOnMembers(t *testing.T) {
+	paths := []string{"a/1.txt", "a/2.txt"}
+	if chunkName(paths) != chunkName(append([]string{}, paths...)) {
+		t.Error("chunkName is not deterministic")
+	}
+	if chunkName(paths) == chunkName([]string{"a/1.txt", "a/3.txt"}) {
+		t.Error("chunkName ignores membership")
+	}
+	// The separator keeps concatenations from colliding
+	if chunkName([]string{"ab", "c"}) == chunkName([]string{"a", "bc"}) {
+		t.Error("chunkName collides on concatenation")
+	}
+}
@@ -0,0 +1,288 @@
+package data
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// holeBlockSize is the granularity at which runs of zeros in a sparse member
+// are skipped rather than written when it is extracted
+const holeBlockSize = 4096
+
+// ExtractTarBundle unpacks a native bundle into outputDir. Symlinks are
+// recreated as links and sparse files with their holes. With preserveMetadata
+// the recorded mode, modification time and ownership are restored as well;
+// ownership needs root. Whatever cannot be restored on this platform, such as
+// symlinks on Windows without the privilege to create them, is returned as a
+// warning rather than failing the extraction.
+func ExtractTarBundle(bundlePath, outputDir string, preserveMetadata bool) ([]string, error) {
+	bundle, err := os.Open(bundlePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open bundle: %w", err)
+	}
+	defer bundle.Close()
+
+	buffered := bufio.NewReader(bundle)
+	var stream io.Reader = buffered
+	if magic, err := buffered.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(buffered)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress bundle: %w", err)
+		}
+		defer gz.Close()
+		stream = gz
+	}
+
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	extractor := &tarExtractor{
+		root:             outputDir,
+		preserveMetadata: preserveMetadata,
+		unrestored:       make(map[string]int),
+	}
+	var links []*tar.Header
+	tr := tar.NewReader(stream)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return extractor.warnings, fmt.Errorf("failed to read bundle: %w", err)
+		}
+		target, err := extractor.path(header.Name)
+		if err != nil {
+			return extractor.warnings, err
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := extractor.mkdirs(target); err != nil {
+				return extractor.warnings, fmt.Errorf("failed to create %s: %w", header.Name, err)
+			}
+		case tar.TypeReg, tar.TypeGNUSparse:
+			if err := extractor.writeFile(tr, header, target); err != nil {
+				return extractor.warnings, err
+			}
+		case tar.TypeSymlink:
+			// Links are made after every file so no member can be written through one
+			links = append(links, header)
+		default:
+			extractor.warn("skipped %s: unsupported member type %q", header.Name, header.Typeflag)
+		}
+	}
+
+	for _, header := range links {
+		extractor.symlink(header)
+	}
+	return extractor.finish(), nil
+}
+
+// tarExtractor writes bundle members under root and collects warnings
+type tarExtractor struct {
+	root             string
+	preserveMetadata bool
+	warnings         []string
+	// unrestored counts the members each kind of metadata could not be restored on
+	unrestored map[string]int
+}
+
+func (e *tarExtractor) warn(format string, args ...interface{}) {
+	e.warnings = append(e.warnings, fmt.Sprintf(format, args...))
+}
+
+// path maps a member name into the output directory, rejecting names that escape it
+func (e *tarExtractor) path(name string) (string, error) {
+	local := filepath.FromSlash(name)
+	if !filepath.IsLocal(local) {
+		return "", fmt.Errorf("bundle member %s is outside the output directory", name)
+	}
+	return filepath.Join(e.root, local), nil
+}
+
+// mkdirs creates dir and its parents below root one at a time, refusing any
+// that is a symlink. A link left by an earlier extraction, or made by this
+// bundle, could otherwise carry a member outside the output directory.
+func (e *tarExtractor) mkdirs(dir string) error {
+	relative, err := filepath.Rel(e.root, dir)
+	if err != nil || relative == "." {
+		return err
+	}
+	current := e.root
+	for _, part := range strings.Split(relative, string(filepath.Separator)) {
+		current = filepath.Join(current, part)
+		info, err := os.Lstat(current)
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			if err := os.Mkdir(current, 0755); err != nil && !errors.Is(err, fs.ErrExist) {
+				return err
+			}
+		case err != nil:
+			return err
+		case info.Mode()&os.ModeSymlink != 0:
+			return fmt.Errorf("%s is a symlink", current)
+		case !info.IsDir():
+			return fmt.Errorf("%s is not a directory", current)
+		}
+	}
+	return nil
+}
+
+func (e *tarExtractor) writeFile(r io.Reader, header *tar.Header, target string) error {
+	if err := e.mkdirs(filepath.Dir(target)); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", header.Name, err)
+	}
+	// A symlink left by an earlier extraction must be replaced, not written through
+	if info, err := os.Lstat(target); err == nil && info.Mode()&os.ModeSymlink != 0 {
+		if err := os.Remove(target); err != nil {
+			return fmt.Errorf("failed to replace %s: %w", header.Name, err)
+		}
+	}
+
+	file, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, os.FileMode(header.Mode)&os.ModePerm)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", header.Name, err)
+	}
+	if header.Typeflag == tar.TypeGNUSparse {
+		err = writeWithHoles(file, r, header.Size)
+	} else {
+		_, err = io.Copy(file, r)
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to extract %s: %w", header.Name, err)
+	}
+
+	e.restore(target, header)
+	return nil
+}
+
+func (e *tarExtractor) symlink(header *tar.Header) {
+	target, _ := e.path(header.Name)
+	if err := e.mkdirs(filepath.Dir(target)); err != nil {
+		e.warn("could not create symlink %s -> %s: %v", header.Name, header.Linkname, err)
+		return
+	}
+	if _, err := os.Lstat(target); err == nil {
+		if err := os.Remove(target); err != nil {
+			e.warn("could not replace %s with a symlink: %v", header.Name, err)
+			return
+		}
+	}
+	if err := os.Symlink(header.Linkname, target); err != nil {
+		e.warn("could not create symlink %s -> %s: %v", header.Name, header.Linkname, err)
+		return
+	}
+	e.restore(target, header)
+}
+
+// restore applies a member's recorded metadata when metadata is preserved
+func (e *tarExtractor) restore(target string, header *tar.Header) {
+	if !e.preserveMetadata {
+		return
+	}
+	if err := os.Lchown(target, header.Uid, header.Gid); err != nil {
+		e.unrestored["ownership"]++
+	}
+
+	if header.Typeflag == tar.TypeSymlink {
+		if err := restoreLinkTime(target, header.ModTime); err != nil {
+			e.unrestored["symlink modification time"]++
+		}
+		return
+	}
+	// The mode is set after the owner, since changing the owner clears setuid and setgid
+	mode := header.FileInfo().Mode() & (os.ModePerm | os.ModeSetuid | os.ModeSetgid | os.ModeSticky)
+	if err := os.Chmod(target, mode); err != nil {
+		e.unrestored["mode"]++
+	}
+	if err := os.Chtimes(target, header.ModTime, header.ModTime); err != nil {
+		e.unrestored["modification time"]++
+	}
+}
+
+// finish summarizes metadata that could not be restored, one warning per kind
+func (e *tarExtractor) finish() []string {
+	kinds := make([]string, 0, len(e.unrestored))
+	for kind := range e.unrestored {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	for _, kind := range kinds {
+		e.warn("could not restore %s of %d member(s)", kind, e.unrestored[kind])
+	}
+	return e.warnings
+}
+
+// writeWithHoles writes r to file, seeking over blocks of zeros instead of
+// writing them so the file system leaves holes where the original had them
+func writeWithHoles(file *os.File, r io.Reader, size int64) error {
+	block := make([]byte, holeBlockSize)
+	for {
+		n, err := io.ReadFull(r, block)
+		if n > 0 {
+			if isZeroBlock(block[:n]) {
+				if _, err := file.Seek(int64(n), io.SeekCurrent); err != nil {
+					return err
+				}
+			} else if _, err := file.Write(block[:n]); err != nil {
+				return err
+			}
+		}
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	// Truncating sets the length when the file ends in a hole
+	return file.Truncate(size)
+}
+
+func isZeroBlock(block []byte) bool {
+	for _, b := range block {
+		if b != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// VerifyExtractedLinks checks that each recorded symlink exists under root
+// and points at its recorded target
+func VerifyExtractedLinks(root string, links map[string]string) error {
+	paths := make([]string, 0, len(links))
+	for path := range links {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var problems []string
+	for _, path := range paths {
+		target, err := os.Readlink(filepath.Join(root, filepath.FromSlash(path)))
+		switch {
+		case err != nil:
+			problems = append(problems, fmt.Sprintf("%s: %v", path, err))
+		case target != links[path]:
+			problems = append(problems, fmt.Sprintf("%s points to %s instead of %s", path, target, links[path]))
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%d of %d link(s) do not match the bundle: %s", len(problems), len(links), strings.Join(problems, "; "))
+	}
+	return nil
+}
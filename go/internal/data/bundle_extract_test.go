@@ -0,0 +1,401 @@
+//go:build linux || darwin
+
+package data
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+const sparseTestSize = 4 * mebibyte
+
+// writePOSIXSource creates a regular file, an executable, symlinks to a file,
+// a directory and nothing, a named pipe, and a file that ends in a hole
+func writePOSIXSource(t *testing.T) (string, map[string][]byte) {
+	t.Helper()
+	root := t.TempDir()
+	modTime := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	files := map[string][]byte{
+		"data.txt":           []byte("measurements\n"),
+		"run.sh":             []byte("#!/bin/sh\necho run\n"),
+		"reads/sample.fastq": bytes.Repeat([]byte("ACGT"), 1000),
+	}
+	modes := map[string]os.FileMode{"data.txt": 0640, "run.sh": 0755, "reads/sample.fastq": 0644}
+	for path, content := range files {
+		full := filepath.Join(root, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(full, content, modes[path]); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chmod(full, modes[path]); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(full, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Data at the start and in the middle, with holes between and after
+	sparse, err := os.Create(filepath.Join(root, "disk.img"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	image := make([]byte, sparseTestSize)
+	copy(image, bytes.Repeat([]byte{0xab}, 8192))
+	copy(image[mebibyte:], bytes.Repeat([]byte{0xcd}, 4096))
+	sparse.Write(image[:8192])
+	sparse.WriteAt(image[mebibyte:mebibyte+4096], mebibyte)
+	sparse.Truncate(sparseTestSize)
+	sparse.Close()
+	files["disk.img"] = image
+
+	for link, target := range map[string]string{"latest": "data.txt", "broken": "missing/file", "reads-link": "reads"} {
+		if err := os.Symlink(target, filepath.Join(root, link)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := syscall.Mkfifo(filepath.Join(root, "pipe"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return root, files
+}
+
+// sourceIsSparse reports whether the file system kept the holes in a file
+func sourceIsSparse(t *testing.T, path string) bool {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	regions, err := sparseDataRegions(file, sparseTestSize)
+	return err == nil && regions != nil
+}
+
+func bundlePOSIX(t *testing.T, source string, config SuitcaseConfig) *BundleResult {
+	t.Helper()
+	config.Backend = NativeTarBackend
+	config.TargetBundleSize = "1GB"
+	config.SizeThreshold = "10MB"
+	config.OutputDirectory = t.TempDir()
+	engine := NewSuitcaseEngine(&config)
+	go func() {
+		for range engine.GetProgress() {
+		}
+	}()
+
+	result, err := engine.BundleFiles(context.Background(), source)
+	if err != nil {
+		t.Fatalf("BundleFiles() error = %v", err)
+	}
+	return result
+}
+
+func readIndex(t *testing.T, path string) *BundleIndex {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	index, err := ReadBundleIndex(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return index
+}
+
+func TestNativeBundlePOSIXRoundTrip(t *testing.T) {
+	for _, seekable := range []bool{true, false} {
+		t.Run(fmt.Sprintf("seekable=%v", seekable), func(t *testing.T) {
+			source, files := writePOSIXSource(t)
+			sparse := sourceIsSparse(t, filepath.Join(source, "disk.img"))
+			if !sparse {
+				t.Log("file system does not report holes; sparse storage is not checked")
+			}
+
+			result := bundlePOSIX(t, source, SuitcaseConfig{Seekable: seekable, PreserveMetadata: true, CompressionLevel: 6})
+			if len(result.Warnings) != 1 || !strings.Contains(result.Warnings[0], "pipe: named pipe") {
+				t.Errorf("warnings = %v, want the named pipe skipped", result.Warnings)
+			}
+
+			links := make(map[string]string)
+			output := t.TempDir()
+			store := &localBundleStore{root: result.OutputPath}
+			for _, bundle := range result.BundleManifest {
+				for path, target := range bundle.Links {
+					links[path] = target
+				}
+				warnings, err := ExtractTarBundle(bundle.BundlePath, output, true)
+				if err != nil {
+					t.Fatalf("ExtractTarBundle() error = %v", err)
+				}
+				if len(warnings) > 0 {
+					t.Errorf("extraction warnings = %v", warnings)
+				}
+
+				index := readIndex(t, bundle.IndexPath)
+				entry, ok := index.Lookup("disk.img")
+				if !ok {
+					continue
+				}
+				if sparse && (len(entry.Sparse) == 0 || entry.Length >= entry.Size) {
+					t.Errorf("disk.img index entry = %+v, want only its data stored", entry)
+				}
+				var out bytes.Buffer
+				if _, err := ExtractBundleMember(context.Background(), store, "bundles", bundle.BundleName, index, "disk.img", &out); err != nil {
+					t.Fatalf("ExtractBundleMember(disk.img) error = %v", err)
+				}
+				if !bytes.Equal(out.Bytes(), files["disk.img"]) {
+					t.Error("disk.img fetched from the bundle differs from the original")
+				}
+			}
+
+			wantLinks := map[string]string{"latest": "data.txt", "broken": "missing/file", "reads-link": "reads"}
+			if !reflect.DeepEqual(links, wantLinks) {
+				t.Errorf("manifest links = %v, want %v", links, wantLinks)
+			}
+			if err := VerifyExtractedLinks(output, links); err != nil {
+				t.Errorf("VerifyExtractedLinks() error = %v", err)
+			}
+
+			for path, content := range files {
+				extracted := filepath.Join(output, filepath.FromSlash(path))
+				got, err := os.ReadFile(extracted)
+				if err != nil {
+					t.Fatalf("%s not extracted: %v", path, err)
+				}
+				if !bytes.Equal(got, content) {
+					t.Errorf("%s differs after extraction", path)
+				}
+				original, _ := os.Stat(filepath.Join(source, filepath.FromSlash(path)))
+				restored, _ := os.Stat(extracted)
+				if restored.Mode() != original.Mode() {
+					t.Errorf("%s mode = %v, want %v", path, restored.Mode(), original.Mode())
+				}
+				if !restored.ModTime().Equal(original.ModTime().Truncate(time.Second)) {
+					t.Errorf("%s mod time = %v, want %v", path, restored.ModTime(), original.ModTime())
+				}
+				if restored.Sys().(*syscall.Stat_t).Uid != original.Sys().(*syscall.Stat_t).Uid {
+					t.Errorf("%s owner not restored", path)
+				}
+			}
+
+			if sparse {
+				info, _ := os.Stat(filepath.Join(output, "disk.img"))
+				if allocated := info.Sys().(*syscall.Stat_t).Blocks * 512; allocated >= sparseTestSize {
+					t.Errorf("disk.img allocates %d bytes after extraction, want its holes kept", allocated)
+				}
+			}
+			if _, err := os.Lstat(filepath.Join(output, "pipe")); !os.IsNotExist(err) {
+				t.Errorf("named pipe should not be extracted, got %v", err)
+			}
+		})
+	}
+}
+
+func TestVerifyExtractedLinksDetectsRetargetedLink(t *testing.T) {
+	root := t.TempDir()
+	if err := os.Symlink("other.txt", filepath.Join(root, "latest")); err != nil {
+		t.Fatal(err)
+	}
+
+	err := VerifyExtractedLinks(root, map[string]string{"latest": "data.txt", "gone": "data.txt"})
+	if err == nil || !strings.Contains(err.Error(), "latest points to other.txt instead of data.txt") || !strings.Contains(err.Error(), "gone:") {
+		t.Errorf("VerifyExtractedLinks() error = %v", err)
+	}
+}
+
+func TestNativeBundleFollowSymlinks(t *testing.T) {
+	source, files := writePOSIXSource(t)
+	if err := os.Symlink(".", filepath.Join(source, "reads", "loop")); err != nil {
+		t.Fatal(err)
+	}
+
+	result := bundlePOSIX(t, source, SuitcaseConfig{FollowSymlinks: true})
+	warnings := strings.Join(result.Warnings, "\n")
+	for _, want := range []string{"pipe: named pipe", "broken: cannot follow", "loop: link to . leads back"} {
+		if !strings.Contains(warnings, want) {
+			t.Errorf("warnings = %v, want one containing %q", result.Warnings, want)
+		}
+	}
+
+	output := t.TempDir()
+	for _, bundle := range result.BundleManifest {
+		if _, err := ExtractTarBundle(bundle.BundlePath, output, false); err != nil {
+			t.Fatalf("ExtractTarBundle() error = %v", err)
+		}
+	}
+
+	// Followed links become copies of what they point to
+	for path, want := range map[string][]byte{"latest": files["data.txt"], "reads-link/sample.fastq": files["reads/sample.fastq"]} {
+		info, err := os.Lstat(filepath.Join(output, path))
+		if err != nil || !info.Mode().IsRegular() {
+			t.Fatalf("%s should be a regular file, got %v, %v", path, info, err)
+		}
+		if got, _ := os.ReadFile(filepath.Join(output, path)); !bytes.Equal(got, want) {
+			t.Errorf("%s differs from the file it linked to", path)
+		}
+	}
+	if target, err := os.Readlink(filepath.Join(output, "broken")); err != nil || target != "missing/file" {
+		t.Errorf("dangling link should be kept as a link, got %q, %v", target, err)
+	}
+}
+
+func TestNativeBundleDropsOwnershipWithoutPreserveMetadata(t *testing.T) {
+	source, _ := writePOSIXSource(t)
+	result := bundlePOSIX(t, source, SuitcaseConfig{})
+
+	modes := map[string]int64{"data.txt": 0644, "run.sh": 0755, "latest": 0777}
+	for _, bundle := range result.BundleManifest {
+		for _, entry := range readIndex(t, bundle.IndexPath).Entries {
+			if entry.Uid != 0 || entry.Gid != 0 {
+				t.Errorf("%s ownership = %d:%d, want none recorded", entry.Path, entry.Uid, entry.Gid)
+			}
+			if want, ok := modes[entry.Path]; ok && entry.Mode != want {
+				t.Errorf("%s mode = %o, want %o", entry.Path, entry.Mode, want)
+			}
+		}
+	}
+}
+
+func TestGNUSparseHeaderIsReadable(t *testing.T) {
+	// Many regions need extension blocks, and the long name a GNU long name entry
+	const size = 200 * 1024
+	content := make([]byte, size)
+	var regions []SparseRegion
+	for offset := int64(0); offset < size-4096; offset += 8192 {
+		copy(content[offset:], bytes.Repeat([]byte{byte(offset / 8192)}, 100))
+		regions = append(regions, SparseRegion{Offset: offset, Length: 100})
+	}
+	path := filepath.Join(t.TempDir(), "source")
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+	source, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer source.Close()
+
+	var archive bytes.Buffer
+	counter := &countingWriter{w: &archive}
+	tw := tar.NewWriter(counter)
+	name := strings.Repeat("long-directory-name/", 8) + "image.raw"
+	header := &tar.Header{Name: name, Mode: 0600, Size: size, ModTime: time.Unix(1700000000, 0), Typeflag: tar.TypeReg}
+	entry, err := writeSparseMember(tw, counter, source, header, regions)
+	if err != nil {
+		t.Fatalf("writeSparseMember() error = %v", err)
+	}
+	if err := tw.WriteHeader(&tar.Header{Name: "after.txt", Mode: 0644, Size: 5, Typeflag: tar.TypeReg}); err != nil {
+		t.Fatal(err)
+	}
+	tw.Write([]byte("after"))
+	tw.Close()
+
+	if stored := archive.Bytes()[entry.Offset : entry.Offset+100]; !bytes.Equal(stored, content[:100]) {
+		t.Error("index offset does not point at the first data region")
+	}
+
+	reader := tar.NewReader(&archive)
+	got, err := reader.Next()
+	if err != nil {
+		t.Fatalf("Next() error = %v", err)
+	}
+	if got.Name != name || got.Size != size || got.Typeflag != tar.TypeGNUSparse || got.Mode != 0600 {
+		t.Errorf("header = %q size %d type %q mode %o", got.Name, got.Size, got.Typeflag, got.Mode)
+	}
+	if data, _ := io.ReadAll(reader); !bytes.Equal(data, content) {
+		t.Error("sparse member reads back differently")
+	}
+	if next, err := reader.Next(); err != nil || next.Name != "after.txt" {
+		t.Errorf("member after the sparse one = %v, %v", next, err)
+	}
+}
+
+// writeTarMembers writes a bundle of the given members; regular files get their
+// name as content
+func writeTarMembers(t *testing.T, members []tar.Header) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "bundle.tar")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	tw := tar.NewWriter(file)
+	for _, member := range members {
+		header := member
+		if header.Typeflag == tar.TypeReg {
+			header.Size = int64(len(header.Name))
+		}
+		if header.Mode == 0 {
+			header.Mode = 0644
+		}
+		if err := tw.WriteHeader(&header); err != nil {
+			t.Fatal(err)
+		}
+		if header.Typeflag == tar.TypeReg {
+			tw.Write([]byte(header.Name))
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestExtractTarBundleRefusesSymlinkedParents(t *testing.T) {
+	outside := t.TempDir()
+	outputDir := t.TempDir()
+	// Left by an earlier extraction into the same directory
+	if err := os.Symlink(outside, filepath.Join(outputDir, "escape")); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, member := range []tar.Header{
+		{Name: "escape/payload.txt", Typeflag: tar.TypeReg},
+		{Name: "escape/sub/", Typeflag: tar.TypeDir, Mode: 0755},
+	} {
+		bundle := writeTarMembers(t, []tar.Header{member})
+		_, err := ExtractTarBundle(bundle, outputDir, false)
+		if err == nil || !strings.Contains(err.Error(), "is a symlink") {
+			t.Errorf("ExtractTarBundle(%s) error = %v, want a symlinked parent error", member.Name, err)
+		}
+	}
+
+	// Links from the bundle itself are made in order, so a later link must not
+	// be placed through an earlier one
+	bundle := writeTarMembers(t, []tar.Header{
+		{Name: "a", Typeflag: tar.TypeSymlink, Linkname: outside},
+		{Name: "a/b", Typeflag: tar.TypeSymlink, Linkname: "/etc/passwd"},
+	})
+	warnings, err := ExtractTarBundle(bundle, outputDir, false)
+	if err != nil {
+		t.Fatalf("ExtractTarBundle() error = %v", err)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "could not create symlink a/b") {
+		t.Errorf("warnings = %v, want one for the link through a", warnings)
+	}
+
+	entries, err := os.ReadDir(outside)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("extraction wrote outside the output directory: %v", entries)
+	}
+}
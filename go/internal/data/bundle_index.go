@@ -0,0 +1,371 @@
+package data
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// Bundle backends
+const (
+	SuitcaseBackend  = "suitcase" // Duke's Suitcase tool, run through Python
+	NativeTarBackend = "native"   // Go archive/tar, writes an index sidecar
+)
+
+// BundleIndexVersion is the current bundle index format
+const BundleIndexVersion = 1
+
+// BundleIndexSuffix replaces the archive extension to name a bundle's index sidecar
+const BundleIndexSuffix = ".idx.json"
+
+// BundleIndex records where each member sits in a bundle so single files can
+// be fetched with ranged reads instead of downloading the whole bundle
+type BundleIndex struct {
+	Version   int                `json:"version"`
+	Bundle    string             `json:"bundle"`
+	Format    string             `json:"format"`   // "tar" or "tar.gz"
+	Seekable  bool               `json:"seekable"` // Offsets are byte positions in the stored object
+	CreatedAt time.Time          `json:"created_at"`
+	Entries   []BundleIndexEntry `json:"entries"`
+}
+
+// Bundle member types other than regular files
+const (
+	SymlinkEntry = "symlink"
+)
+
+// BundleIndexEntry locates one member's data within the uncompressed tar stream
+type BundleIndexEntry struct {
+	Path    string    `json:"path"`
+	Offset  int64     `json:"offset"`
+	Length  int64     `json:"length"`
+	Mode    int64     `json:"mode"`
+	ModTime time.Time `json:"mod_time"`
+	SHA256  string    `json:"sha256"`
+
+	Type       string `json:"type,omitempty"`        // Empty for regular files
+	LinkTarget string `json:"link_target,omitempty"` // Target of a symlink
+	Uid        int    `json:"uid,omitempty"`
+	Gid        int    `json:"gid,omitempty"`
+	// Sparse files store only their data regions; Length counts the stored
+	// bytes and Size is the file's full length, holes included
+	Size   int64          `json:"size,omitempty"`
+	Sparse []SparseRegion `json:"sparse,omitempty"`
+}
+
+// Lookup returns the entry for a member path
+func (idx *BundleIndex) Lookup(path string) (*BundleIndexEntry, bool) {
+	path = strings.TrimPrefix(filepath.ToSlash(path), "./")
+	for i := range idx.Entries {
+		if idx.Entries[i].Path == path {
+			return &idx.Entries[i], true
+		}
+	}
+	return nil, false
+}
+
+// Links maps each symlink in the bundle to its target
+func (idx *BundleIndex) Links() map[string]string {
+	links := make(map[string]string)
+	for _, entry := range idx.Entries {
+		if entry.Type == SymlinkEntry {
+			links[entry.Path] = entry.LinkTarget
+		}
+	}
+	return links
+}
+
+// BundleIndexPath returns the sidecar path for a bundle, e.g. bundle.tar.gz -> bundle.idx.json
+func BundleIndexPath(bundlePath string) string {
+	for _, ext := range []string{".tar.gz", ".tgz", ".tar"} {
+		if strings.HasSuffix(bundlePath, ext) {
+			return strings.TrimSuffix(bundlePath, ext) + BundleIndexSuffix
+		}
+	}
+	return bundlePath + BundleIndexSuffix
+}
+
+// ReadBundleIndex decodes a bundle index
+func ReadBundleIndex(r io.Reader) (*BundleIndex, error) {
+	var index BundleIndex
+	if err := json.NewDecoder(r).Decode(&index); err != nil {
+		return nil, fmt.Errorf("failed to parse bundle index: %w", err)
+	}
+	if index.Version != BundleIndexVersion {
+		return nil, fmt.Errorf("unsupported bundle index version %d", index.Version)
+	}
+	return &index, nil
+}
+
+// WriteBundleIndex writes an index sidecar to path
+func WriteBundleIndex(path string, index *BundleIndex) error {
+	content, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode bundle index: %w", err)
+	}
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		return fmt.Errorf("failed to write bundle index: %w", err)
+	}
+	return nil
+}
+
+// countingWriter tracks how many bytes have passed through to the underlying writer
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
+
+// WriteTarBundle writes files as a tar archive, gzip-compressed unless seekable,
+// and returns an index of each member's data within the uncompressed stream.
+// Seekable bundles are plain tar so the offsets are also byte ranges in the object.
+// Symlinks are stored as links and files with holes as GNU sparse members.
+// Ownership and special mode bits are only kept with preserveMetadata.
+func WriteTarBundle(w io.Writer, files []FileEntry, seekable bool, compressionLevel int, preserveMetadata bool) (*BundleIndex, error) {
+	index := &BundleIndex{
+		Version:   BundleIndexVersion,
+		Format:    "tar.gz",
+		Seekable:  seekable,
+		CreatedAt: time.Now().UTC(),
+		Entries:   make([]BundleIndexEntry, 0, len(files)),
+	}
+
+	var gz *gzip.Writer
+	stream := w
+	if seekable {
+		index.Format = "tar"
+	} else {
+		if compressionLevel <= 0 || compressionLevel > gzip.BestCompression {
+			compressionLevel = gzip.DefaultCompression
+		}
+		var err error
+		gz, err = gzip.NewWriterLevel(w, compressionLevel)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create gzip writer: %w", err)
+		}
+		stream = gz
+	}
+
+	counter := &countingWriter{w: stream}
+	tw := tar.NewWriter(counter)
+	for _, file := range files {
+		entry, err := writeTarMember(tw, counter, file, preserveMetadata)
+		if err != nil {
+			return nil, err
+		}
+		index.Entries = append(index.Entries, *entry)
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to finish tar archive: %w", err)
+	}
+	if gz != nil {
+		if err := gz.Close(); err != nil {
+			return nil, fmt.Errorf("failed to finish gzip stream: %w", err)
+		}
+	}
+	return index, nil
+}
+
+// writeTarMember adds one file to the archive, recording where its data starts
+func writeTarMember(tw *tar.Writer, counter *countingWriter, file FileEntry, preserveMetadata bool) (*BundleIndexEntry, error) {
+	if file.LinkTarget != "" {
+		return writeTarLink(tw, counter, file, preserveMetadata)
+	}
+
+	source, err := os.Open(file.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", file.Path, err)
+	}
+	defer source.Close()
+
+	info, err := source.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat %s: %w", file.Path, err)
+	}
+	header, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to build tar header for %s: %w", file.Path, err)
+	}
+	header.Name = filepath.ToSlash(file.RelativePath)
+	normalizeTarHeader(header, preserveMetadata)
+
+	// A file whose holes cannot be found is stored in full, which is still correct
+	if regions, err := sparseDataRegions(source, header.Size); err == nil && regions != nil {
+		return writeSparseMember(tw, counter, source, header, regions)
+	}
+
+	// The header is written in full before any data, so the count after it is the data offset
+	if err := tw.WriteHeader(header); err != nil {
+		return nil, fmt.Errorf("failed to write tar header for %s: %w", file.Path, err)
+	}
+	entry := newIndexEntry(header, counter.n)
+
+	_, sum, err := copySHA256(tw, source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add %s to bundle: %w", file.Path, err)
+	}
+	entry.SHA256 = hex.EncodeToString(sum)
+	return entry, nil
+}
+
+// writeTarLink adds a symlink to the archive as a link to its target
+func writeTarLink(tw *tar.Writer, counter *countingWriter, file FileEntry, preserveMetadata bool) (*BundleIndexEntry, error) {
+	info, err := os.Lstat(file.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat %s: %w", file.Path, err)
+	}
+	header, err := tar.FileInfoHeader(info, file.LinkTarget)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build tar header for %s: %w", file.Path, err)
+	}
+	header.Name = filepath.ToSlash(file.RelativePath)
+	normalizeTarHeader(header, preserveMetadata)
+
+	if err := tw.WriteHeader(header); err != nil {
+		return nil, fmt.Errorf("failed to write tar header for %s: %w", file.Path, err)
+	}
+	entry := newIndexEntry(header, counter.n)
+	entry.Type = SymlinkEntry
+	entry.LinkTarget = header.Linkname
+	return entry, nil
+}
+
+// normalizeTarHeader drops ownership and special mode bits unless metadata is
+// preserved, so a bundle does not carry one machine's users onto another
+func normalizeTarHeader(header *tar.Header, preserveMetadata bool) {
+	if preserveMetadata {
+		return
+	}
+	header.Uid, header.Gid = 0, 0
+	header.Uname, header.Gname = "", ""
+	switch {
+	case header.Typeflag == tar.TypeSymlink:
+		header.Mode = 0777
+	case header.Mode&0111 != 0:
+		header.Mode = 0755
+	default:
+		header.Mode = 0644
+	}
+}
+
+func newIndexEntry(header *tar.Header, offset int64) *BundleIndexEntry {
+	return &BundleIndexEntry{
+		Path:    header.Name,
+		Offset:  offset,
+		Length:  header.Size,
+		Mode:    header.Mode,
+		ModTime: header.ModTime.UTC(),
+		Uid:     header.Uid,
+		Gid:     header.Gid,
+	}
+}
+
+// s3RangeAPI is the subset of the S3 API used to read bundles in place
+type s3RangeAPI interface {
+	GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+}
+
+// FetchBundleIndex downloads and decodes a bundle's index sidecar
+func FetchBundleIndex(ctx context.Context, api s3RangeAPI, bucket, key string) (*BundleIndex, error) {
+	output, err := api.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bundle index s3://%s/%s: %w", bucket, key, err)
+	}
+	defer output.Body.Close()
+	return ReadBundleIndex(output.Body)
+}
+
+// ExtractBundleMember writes one member of a bundle stored in S3 to w. Seekable
+// bundles are read with a single ranged GET of the member's bytes; compressed
+// bundles are streamed from the start and the read stops once the member is
+// complete. The member's SHA-256 is checked against the index.
+func ExtractBundleMember(ctx context.Context, api s3RangeAPI, bucket, key string, index *BundleIndex, path string, w io.Writer) (*BundleIndexEntry, error) {
+	entry, ok := index.Lookup(path)
+	if !ok {
+		return nil, fmt.Errorf("%s is not in bundle s3://%s/%s", path, bucket, key)
+	}
+	if entry.Type == SymlinkEntry {
+		return nil, fmt.Errorf("%s is a symlink to %s and has no data", path, entry.LinkTarget)
+	}
+
+	input := &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	}
+	if index.Seekable {
+		if entry.Length == 0 {
+			// Nothing is stored, though a sparse file may still be all hole
+			digest := sha256.New()
+			if err := expandSparse(io.MultiWriter(w, digest), strings.NewReader(""), entry.Sparse, entry.Size); err != nil {
+				return nil, fmt.Errorf("failed to write %s: %w", path, err)
+			}
+			return entry, verifyMemberChecksum(entry, digest)
+		}
+		input.Range = aws.String(fmt.Sprintf("bytes=%d-%d", entry.Offset, entry.Offset+entry.Length-1))
+	}
+
+	output, err := api.GetObject(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read s3://%s/%s: %w", bucket, key, err)
+	}
+	defer output.Body.Close()
+
+	var stream io.Reader = output.Body
+	if !index.Seekable {
+		if index.Format == "tar.gz" {
+			gz, err := gzip.NewReader(output.Body)
+			if err != nil {
+				return nil, fmt.Errorf("failed to decompress bundle: %w", err)
+			}
+			defer gz.Close()
+			stream = gz
+		}
+		if _, err := io.CopyN(io.Discard, stream, entry.Offset); err != nil {
+			return nil, fmt.Errorf("failed to seek to %s in bundle: %w", path, err)
+		}
+	}
+
+	digest := sha256.New()
+	if len(entry.Sparse) > 0 {
+		err = expandSparse(io.MultiWriter(w, digest), stream, entry.Sparse, entry.Size)
+	} else {
+		_, err = io.CopyN(io.MultiWriter(w, digest), stream, entry.Length)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s from bundle: %w", path, err)
+	}
+	return entry, verifyMemberChecksum(entry, digest)
+}
+
+// verifyMemberChecksum compares extracted bytes with the checksum in the index
+func verifyMemberChecksum(entry *BundleIndexEntry, digest hash.Hash) error {
+	if entry.SHA256 == "" {
+		return nil
+	}
+	if sum := hex.EncodeToString(digest.Sum(nil)); sum != entry.SHA256 {
+		return fmt.Errorf("checksum mismatch for %s: index has %s, got %s", entry.Path, entry.SHA256, sum)
+	}
+	return nil
+}
@@ -0,0 +1,219 @@
+package data
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// localBundleStore stands in for S3 by serving objects, and byte ranges of
+// them, from a local directory
+type localBundleStore struct {
+	root   string
+	served int64
+}
+
+func (s *localBundleStore) GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	content, err := os.ReadFile(filepath.Join(s.root, aws.ToString(params.Key)))
+	if err != nil {
+		return nil, err
+	}
+	if byteRange := aws.ToString(params.Range); byteRange != "" {
+		var start, end int64
+		if _, err := fmt.Sscanf(byteRange, "bytes=%d-%d", &start, &end); err != nil {
+			return nil, fmt.Errorf("bad range %q: %w", byteRange, err)
+		}
+		content = content[start : end+1]
+	}
+	s.served += int64(len(content))
+	return &s3.GetObjectOutput{Body: io.NopCloser(bytes.NewReader(content))}, nil
+}
+
+// writeBundleSource creates files of assorted sizes, including an empty file
+// and a path long enough to need a PAX header
+func writeBundleSource(t *testing.T) (string, map[string][]byte) {
+	t.Helper()
+	root := t.TempDir()
+	random := rand.New(rand.NewSource(1))
+	longDir := strings.Repeat("nested-directory/", 8)
+
+	files := map[string][]byte{
+		"reads/sample_1.fastq":        make([]byte, 70_000),
+		"reads/sample_2.fastq":        make([]byte, 513),
+		"logs/empty.log":              {},
+		longDir + "deep/variants.vcf": make([]byte, 4096),
+	}
+	for path, content := range files {
+		random.Read(content)
+		full := filepath.Join(root, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(full, content, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root, files
+}
+
+func bundleNatively(t *testing.T, seekable bool) (*BundleResult, map[string][]byte) {
+	t.Helper()
+	source, files := writeBundleSource(t)
+	engine := NewSuitcaseEngine(&SuitcaseConfig{
+		Backend:          NativeTarBackend,
+		Seekable:         seekable,
+		TargetBundleSize: "1GB",
+		SizeThreshold:    "10MB",
+		CompressionLevel: 6,
+		OutputDirectory:  t.TempDir(),
+	})
+	if err := engine.IsAvailable(context.Background()); err != nil {
+		t.Fatalf("native backend should always be available: %v", err)
+	}
+
+	result, err := engine.BundleFiles(context.Background(), source)
+	if err != nil {
+		t.Fatalf("BundleFiles() error = %v", err)
+	}
+	if len(result.BundleManifest) == 0 {
+		t.Fatal("no bundles created")
+	}
+	return result, files
+}
+
+func TestNativeBundleRoundTrip(t *testing.T) {
+	for _, seekable := range []bool{true, false} {
+		t.Run(fmt.Sprintf("seekable=%v", seekable), func(t *testing.T) {
+			result, files := bundleNatively(t, seekable)
+			store := &localBundleStore{root: result.OutputPath}
+
+			recovered := 0
+			for _, bundle := range result.BundleManifest {
+				if bundle.IndexPath != BundleIndexPath(bundle.BundlePath) {
+					t.Fatalf("index path = %q", bundle.IndexPath)
+				}
+				index, err := FetchBundleIndex(context.Background(), store, "bundles", filepath.Base(bundle.IndexPath))
+				if err != nil {
+					t.Fatalf("FetchBundleIndex() error = %v", err)
+				}
+				if index.Seekable != seekable || index.Bundle != bundle.BundleName {
+					t.Errorf("index = %+v", index)
+				}
+
+				for _, entry := range index.Entries {
+					store.served = 0
+					var out bytes.Buffer
+					if _, err := ExtractBundleMember(context.Background(), store, "bundles", bundle.BundleName, index, entry.Path, &out); err != nil {
+						t.Fatalf("ExtractBundleMember(%s) error = %v", entry.Path, err)
+					}
+					if !bytes.Equal(out.Bytes(), files[entry.Path]) {
+						t.Errorf("%s: recovered %d bytes that differ from the %d-byte original", entry.Path, out.Len(), len(files[entry.Path]))
+					}
+					if seekable && store.served != entry.Length {
+						t.Errorf("%s: served %d bytes, want only the member's %d", entry.Path, store.served, entry.Length)
+					}
+					recovered++
+				}
+			}
+			if recovered != len(files) {
+				t.Errorf("recovered %d files, want %d", recovered, len(files))
+			}
+		})
+	}
+}
+
+func TestNativeBundleIsStandardTar(t *testing.T) {
+	result, files := bundleNatively(t, false)
+
+	bundle, err := os.Open(result.BundlePaths[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bundle.Close()
+	gz, err := gzip.NewReader(bundle)
+	if err != nil {
+		t.Fatalf("bundle is not gzip: %v", err)
+	}
+
+	reader := tar.NewReader(gz)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("tar read error = %v", err)
+		}
+		content, _ := io.ReadAll(reader)
+		if !bytes.Equal(content, files[header.Name]) {
+			t.Errorf("%s differs when read with archive/tar", header.Name)
+		}
+	}
+}
+
+func TestExtractBundleMemberDetectsCorruption(t *testing.T) {
+	result, _ := bundleNatively(t, true)
+
+	// Files are grouped by directory, so find the bundle holding the FASTQ
+	var bundle BundleManifestEntry
+	var index *BundleIndex
+	var entry *BundleIndexEntry
+	for _, candidate := range result.BundleManifest {
+		indexFile, err := os.Open(candidate.IndexPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		candidateIndex, err := ReadBundleIndex(indexFile)
+		indexFile.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if found, ok := candidateIndex.Lookup("reads/sample_1.fastq"); ok {
+			bundle, index, entry = candidate, candidateIndex, found
+		}
+	}
+	if entry == nil {
+		t.Fatal("reads/sample_1.fastq is in no bundle index")
+	}
+
+	content, _ := os.ReadFile(bundle.BundlePath)
+	content[entry.Offset+10] ^= 0xff
+	if err := os.WriteFile(bundle.BundlePath, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	store := &localBundleStore{root: result.OutputPath}
+	_, err := ExtractBundleMember(context.Background(), store, "bundles", bundle.BundleName, index, entry.Path, io.Discard)
+	if err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Errorf("expected checksum mismatch, got %v", err)
+	}
+
+	if _, err := ExtractBundleMember(context.Background(), store, "bundles", bundle.BundleName, index, "missing.txt", io.Discard); err == nil {
+		t.Error("expected an error for a file not in the bundle")
+	}
+}
+
+func TestBundleIndexPath(t *testing.T) {
+	tests := map[string]string{
+		"bundles/bundle_0001.tar.gz": "bundles/bundle_0001.idx.json",
+		"bundle_0001.tar":            "bundle_0001.idx.json",
+		"bundle.tgz":                 "bundle.idx.json",
+		"bundle.zip":                 "bundle.zip.idx.json",
+	}
+	for bundle, want := range tests {
+		if got := BundleIndexPath(bundle); got != want {
+			t.Errorf("BundleIndexPath(%q) = %q, want %q", bundle, got, want)
+		}
+	}
+}
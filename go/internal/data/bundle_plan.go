@@ -0,0 +1,132 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+)
+
+// DefaultBundleTargetSize is the bundle size used when none is configured
+const DefaultBundleTargetSize int64 = 100 * 1024 * 1024
+
+// smallFileThreshold is the size below which the pattern analyzer counts a file as small
+const smallFileThreshold int64 = 1024 * 1024
+
+// BundlePlan is the outcome of a bundling dry run: how many objects the
+// bundling strategy would produce without writing any bundles
+type BundlePlan struct {
+	SourcePath       string            `json:"source_path"`
+	TargetBundleSize int64             `json:"target_bundle_size"`
+	SmallFiles       int64             `json:"small_files"`
+	SmallFileBytes   int64             `json:"small_file_bytes"`
+	UnbundledFiles   int64             `json:"unbundled_files"`
+	BundleCount      int64             `json:"bundle_count"`
+	Groups           []BundlePlanGroup `json:"groups"`
+}
+
+// BundlePlanGroup is one bundle the strategy would create
+type BundlePlanGroup struct {
+	Name      string `json:"name"`
+	FileCount int64  `json:"file_count"`
+	Size      int64  `json:"size_bytes"`
+}
+
+// ObjectCount is the number of S3 objects after bundling
+func (p *BundlePlan) ObjectCount() int64 {
+	return p.UnbundledFiles + p.BundleCount
+}
+
+// BundlingRatio is the average number of small files per bundle
+func (p *BundlePlan) BundlingRatio() float64 {
+	return bundlingRatio(p.SmallFiles, p.BundleCount)
+}
+
+// PlanBundles runs the bundling strategy over a source directory without
+// creating any bundles
+func (se *SuitcaseEngine) PlanBundles(ctx context.Context, sourcePath string) (*BundlePlan, error) {
+	if _, err := os.Stat(sourcePath); err != nil {
+		return nil, fmt.Errorf("source path not accessible: %w", err)
+	}
+
+	analysis, err := se.analyzeSourceFiles(ctx, sourcePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to analyze source files: %w", err)
+	}
+	strategy, err := se.createBundlingStrategy(analysis)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create bundling strategy: %w", err)
+	}
+
+	plan := &BundlePlan{
+		SourcePath:       sourcePath,
+		TargetBundleSize: se.targetBundleSize(),
+		SmallFiles:       int64(len(analysis.SmallFiles)),
+		UnbundledFiles:   int64(len(analysis.LargeFiles)),
+		BundleCount:      int64(len(strategy.BundleGroups)),
+		Groups:           make([]BundlePlanGroup, 0, len(strategy.BundleGroups)),
+	}
+	for _, file := range analysis.SmallFiles {
+		plan.SmallFileBytes += file.Size
+	}
+	for _, group := range strategy.BundleGroups {
+		plan.Groups = append(plan.Groups, BundlePlanGroup{
+			Name:      group.Name,
+			FileCount: int64(len(group.Files)),
+			Size:      group.ExpectedSize,
+		})
+	}
+	return plan, nil
+}
+
+// SetBundlePlan makes the bundled scenario use a dry run's exact object counts
+// instead of estimating them from the pattern
+func (c *S3CostCalculator) SetBundlePlan(plan *BundlePlan) {
+	c.bundlePlan = plan
+}
+
+// targetBundleSize parses the configured bundle size, falling back to the default
+func (se *SuitcaseEngine) targetBundleSize() int64 {
+	size, err := se.parseSize(se.config.TargetBundleSize)
+	if err != nil || size <= 0 {
+		return DefaultBundleTargetSize
+	}
+	return size
+}
+
+// estimateBundleCount predicts how many bundles the strategy would make from
+// the pattern alone. The strategy bundles each file type separately and packs
+// each group up to the target size, so there is at least one bundle per file
+// type made of small files, and enough bundles to hold the small-file bytes.
+func estimateBundleCount(pattern *DataPattern, targetSize int64) int64 {
+	small := pattern.FileSizes.SmallFiles
+	if small.CountUnder1MB == 0 {
+		return 0
+	}
+
+	var smallTypes int64
+	for _, info := range pattern.FileTypes {
+		if info.Count > 0 && info.AverageSize <= smallFileThreshold {
+			smallTypes++
+		}
+	}
+
+	bundles := int64(math.Ceil(float64(small.SizeUnder1MB) / float64(targetSize)))
+	if smallTypes > bundles {
+		bundles = smallTypes
+	}
+	if bundles < 1 {
+		bundles = 1
+	}
+	if bundles > small.CountUnder1MB {
+		bundles = small.CountUnder1MB
+	}
+	return bundles
+}
+
+func bundlingRatio(files, bundles int64) float64 {
+	if bundles == 0 {
+		return 0
+	}
+	return float64(files) / float64(bundles)
+}
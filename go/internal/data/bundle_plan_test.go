@@ -0,0 +1,151 @@
+package data
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// skewedPattern builds a pattern from per-type file counts and sizes
+func skewedPattern(types map[string][2]int64) *DataPattern {
+	pattern := &DataPattern{FileTypes: make(map[string]FileTypeInfo)}
+	for ext, spec := range types {
+		count, size := spec[0], spec[1]
+		pattern.FileTypes[ext] = FileTypeInfo{Extension: ext, Count: count, TotalSize: count * size, AverageSize: size}
+		pattern.TotalFiles += count
+		pattern.TotalSize += count * size
+		if size < smallFileThreshold {
+			pattern.FileSizes.SmallFiles.CountUnder1MB += count
+			pattern.FileSizes.SmallFiles.SizeUnder1MB += count * size
+		}
+	}
+	return pattern
+}
+
+func TestEstimateBundleCountAgainstConstantRatio(t *testing.T) {
+	tests := []struct {
+		name        string
+		types       map[string][2]int64
+		wantBundles int64
+		oldBundles  int64
+	}{
+		{
+			// A million 1KB logs fill ten 100MB bundles, not ten thousand
+			name:        "millions of tiny files",
+			types:       map[string][2]int64{".log": {1_000_000, 1024}, ".bam": {10, 10 * gib}},
+			wantBundles: 10,
+			oldBundles:  10_000,
+		},
+		{
+			// Files just under the threshold need about as many bundles as the old guess
+			name:        "files near the small-file threshold",
+			types:       map[string][2]int64{".csv": {2000, 900 * 1024}},
+			wantBundles: 18,
+			oldBundles:  20,
+		},
+		{
+			// Too few small files for the constant ratio to leave any bundle at all
+			name:        "a handful of small files",
+			types:       map[string][2]int64{".json": {50, 4096}, ".nc": {20, 2 * gib}},
+			wantBundles: 1,
+			oldBundles:  0,
+		},
+		{
+			// Each small file type is bundled on its own
+			name:        "many small file types",
+			types:       map[string][2]int64{".txt": {10, 1024}, ".json": {10, 1024}, ".xml": {10, 1024}},
+			wantBundles: 3,
+			oldBundles:  0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pattern := skewedPattern(tt.types)
+			got := estimateBundleCount(pattern, DefaultBundleTargetSize)
+			if got != tt.wantBundles {
+				t.Errorf("estimateBundleCount() = %d, want %d", got, tt.wantBundles)
+			}
+
+			old := int64(float64(pattern.FileSizes.SmallFiles.CountUnder1MB) * 0.01)
+			if old != tt.oldBundles {
+				t.Fatalf("constant-ratio estimate = %d, test expects %d", old, tt.oldBundles)
+			}
+
+			scenario := NewS3CostCalculator("us-east-1").createBundledScenario(pattern)
+			wantObjects := pattern.TotalFiles - pattern.FileSizes.SmallFiles.CountUnder1MB + tt.wantBundles
+			if scenario.Configuration.FileCount != wantObjects {
+				t.Errorf("scenario objects = %d, want %d", scenario.Configuration.FileCount, wantObjects)
+			}
+			if !strings.Contains(strings.Join(scenario.Assumptions, "\n"), "estimated from the file size distribution") {
+				t.Errorf("assumptions should say the count is estimated: %v", scenario.Assumptions)
+			}
+		})
+	}
+}
+
+func TestPlanBundlesFeedsBundledScenario(t *testing.T) {
+	root := t.TempDir()
+	write := func(path string, size int) {
+		full := filepath.Join(root, path)
+		if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(full, make([]byte, size), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < 30; i++ {
+		write(filepath.Join("logs", strings.Repeat("a", i+1)+".txt"), 1024)
+	}
+	for i := 0; i < 5; i++ {
+		write(filepath.Join("tables", strings.Repeat("b", i+1)+".csv"), 2048)
+	}
+	write("raw/one.bin", 2*1024*1024)
+	write("raw/two.bin", 2*1024*1024)
+
+	plan, err := NewSuitcaseEngine(nil).PlanBundles(context.Background(), root)
+	if err != nil {
+		t.Fatalf("PlanBundles() error = %v", err)
+	}
+	if plan.SmallFiles != 35 || plan.UnbundledFiles != 2 || plan.BundleCount != 2 || plan.ObjectCount() != 4 {
+		t.Fatalf("plan = %+v", plan)
+	}
+	if plan.SmallFileBytes != 30*1024+5*2048 || len(plan.Groups) != 2 {
+		t.Errorf("plan bytes = %d, groups = %+v", plan.SmallFileBytes, plan.Groups)
+	}
+	if entries, _ := os.ReadDir(filepath.Join(filepath.Dir(root), "bundles")); len(entries) > 0 {
+		t.Error("a dry run should not write bundles")
+	}
+
+	pattern, err := NewPatternAnalyzer().AnalyzePattern(context.Background(), root)
+	if err != nil {
+		t.Fatalf("AnalyzePattern() error = %v", err)
+	}
+	if estimate := estimateBundleCount(pattern, DefaultBundleTargetSize); estimate != plan.BundleCount {
+		t.Errorf("estimate %d bundles, dry run made %d", estimate, plan.BundleCount)
+	}
+
+	calculator := NewS3CostCalculator("us-east-1")
+	calculator.SetBundlePlan(plan)
+	analysis, err := calculator.AnalyzeCosts(context.Background(), pattern)
+	if err != nil {
+		t.Fatalf("AnalyzeCosts() error = %v", err)
+	}
+	for _, scenario := range analysis.Scenarios {
+		if scenario.Name != "Bundled Small Files" {
+			continue
+		}
+		if scenario.Configuration.FileCount != 4 {
+			t.Errorf("bundled scenario objects = %d, want the dry run's 4", scenario.Configuration.FileCount)
+		}
+		assumptions := strings.Join(scenario.Assumptions, "\n")
+		if !strings.Contains(assumptions, "35 small files packed into 2 bundles") || !strings.Contains(assumptions, "from a bundling dry run") {
+			t.Errorf("assumptions = %v", scenario.Assumptions)
+		}
+		return
+	}
+	t.Error("no bundled scenario")
+}
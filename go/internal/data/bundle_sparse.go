@@ -0,0 +1,202 @@
+package data
+
+import (
+	"archive/tar"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+)
+
+// SparseRegion is a run of data in a sparse file; the gaps between regions are holes
+type SparseRegion struct {
+	Offset int64 `json:"offset"`
+	Length int64 `json:"length"`
+}
+
+// archive/tar reads sparse members but cannot write them, so sparse files are
+// written as GNU old-format sparse headers ('S') directly into the stream
+const (
+	tarBlockSize         = 512
+	gnuLongNameFlag      = 'L'
+	gnuSparseFlag        = 'S'
+	gnuHeaderEntries     = 4  // Sparse map entries held in the main header
+	gnuExtensionEntries  = 21 // Sparse map entries held in each extension block
+	gnuHeaderSparseStart = 386
+	gnuHeaderIsExtended  = 482
+	gnuHeaderRealSize    = 483
+	gnuExtensionExtended = 504
+)
+
+// writeSparseMember adds a file with holes to the archive, storing only its
+// data regions. The index entry's digest covers the whole file, holes included.
+func writeSparseMember(tw *tar.Writer, counter *countingWriter, source *os.File, header *tar.Header, regions []SparseRegion) (*BundleIndexEntry, error) {
+	// GNU tar marks a file ending in a hole with an empty region at its end
+	if len(regions) == 0 || regions[len(regions)-1].Offset+regions[len(regions)-1].Length < header.Size {
+		regions = append(regions, SparseRegion{Offset: header.Size})
+	}
+	var stored int64
+	for _, region := range regions {
+		stored += region.Length
+	}
+
+	// Flushing pads the previous member, leaving the stream at a block boundary
+	if err := tw.Flush(); err != nil {
+		return nil, fmt.Errorf("failed to write tar header for %s: %w", header.Name, err)
+	}
+	if _, err := counter.Write(gnuSparseHeader(header, regions, stored)); err != nil {
+		return nil, fmt.Errorf("failed to write tar header for %s: %w", header.Name, err)
+	}
+	entry := newIndexEntry(header, counter.n)
+	entry.Length = stored
+	entry.Size = header.Size
+	entry.Sparse = regions
+
+	digest := sha256.New()
+	var position int64
+	for _, region := range regions {
+		if _, err := io.CopyN(digest, zeroReader{}, region.Offset-position); err != nil {
+			return nil, err
+		}
+		if _, err := source.Seek(region.Offset, io.SeekStart); err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", header.Name, err)
+		}
+		if _, err := io.CopyN(io.MultiWriter(counter, digest), source, region.Length); err != nil {
+			return nil, fmt.Errorf("failed to add %s to bundle: %w", header.Name, err)
+		}
+		position = region.Offset + region.Length
+	}
+	if _, err := io.CopyN(digest, zeroReader{}, header.Size-position); err != nil {
+		return nil, err
+	}
+	if _, err := counter.Write(make([]byte, tarPadding(stored))); err != nil {
+		return nil, fmt.Errorf("failed to add %s to bundle: %w", header.Name, err)
+	}
+
+	entry.SHA256 = hex.EncodeToString(digest.Sum(nil))
+	return entry, nil
+}
+
+// gnuSparseHeader encodes the header blocks of a GNU sparse member: a long
+// name entry when the name does not fit, the main header, and extension
+// blocks for sparse map entries beyond the first four
+func gnuSparseHeader(header *tar.Header, regions []SparseRegion, stored int64) []byte {
+	var blocks []byte
+	if len(header.Name) > 100 {
+		name := header.Name + "\x00"
+		longName := gnuHeaderBlock("././@LongLink", gnuLongNameFlag, &tar.Header{ModTime: header.ModTime}, int64(len(name)))
+		blocks = append(blocks, longName...)
+		blocks = append(blocks, name...)
+		blocks = append(blocks, make([]byte, tarPadding(int64(len(name))))...)
+	}
+
+	main := gnuHeaderBlock(header.Name, gnuSparseFlag, header, stored)
+	putTarNumber(main[gnuHeaderRealSize:gnuHeaderRealSize+12], header.Size)
+	rest := putSparseEntries(main[gnuHeaderSparseStart:], regions, gnuHeaderEntries)
+	if len(rest) > 0 {
+		main[gnuHeaderIsExtended] = 1
+	}
+	setTarChecksum(main)
+	blocks = append(blocks, main...)
+
+	for len(rest) > 0 {
+		extension := make([]byte, tarBlockSize)
+		rest = putSparseEntries(extension, rest, gnuExtensionEntries)
+		if len(rest) > 0 {
+			extension[gnuExtensionExtended] = 1
+		}
+		blocks = append(blocks, extension...)
+	}
+	return blocks
+}
+
+// gnuHeaderBlock fills the fields shared by every GNU header. Callers that
+// write more fields must call setTarChecksum again.
+func gnuHeaderBlock(name string, typeflag byte, header *tar.Header, size int64) []byte {
+	block := make([]byte, tarBlockSize)
+	copy(block[0:100], name)
+	putTarNumber(block[100:108], header.Mode)
+	putTarNumber(block[108:116], int64(header.Uid))
+	putTarNumber(block[116:124], int64(header.Gid))
+	putTarNumber(block[124:136], size)
+	putTarNumber(block[136:148], header.ModTime.Unix())
+	block[156] = typeflag
+	copy(block[257:265], "ustar  \x00")
+	copy(block[265:297], header.Uname)
+	copy(block[297:329], header.Gname)
+	setTarChecksum(block)
+	return block
+}
+
+// putSparseEntries writes up to max offset/length pairs and returns the rest
+func putSparseEntries(field []byte, regions []SparseRegion, max int) []SparseRegion {
+	for i := 0; i < max && len(regions) > 0; i++ {
+		putTarNumber(field[i*24:i*24+12], regions[0].Offset)
+		putTarNumber(field[i*24+12:i*24+24], regions[0].Length)
+		regions = regions[1:]
+	}
+	return regions
+}
+
+// putTarNumber writes n as NUL-terminated octal, or in the GNU base-256
+// encoding when it does not fit
+func putTarNumber(field []byte, n int64) {
+	digits := len(field) - 1
+	if n >= 0 && n < 1<<(3*digits) {
+		octal := strconv.FormatInt(n, 8)
+		for i := 0; i < digits-len(octal); i++ {
+			field[i] = '0'
+		}
+		copy(field[digits-len(octal):], octal)
+		field[digits] = 0
+		return
+	}
+	for i := len(field) - 1; i >= 0; i-- {
+		field[i] = byte(n)
+		n >>= 8
+	}
+	field[0] |= 0x80
+}
+
+// setTarChecksum computes a header block's checksum, counting the checksum
+// field itself as spaces
+func setTarChecksum(block []byte) {
+	copy(block[148:156], "        ")
+	var sum int64
+	for _, b := range block {
+		sum += int64(b)
+	}
+	copy(block[148:156], fmt.Sprintf("%06o\x00 ", sum))
+}
+
+// tarPadding is the number of zero bytes that fill out the last block of data
+func tarPadding(size int64) int64 {
+	return -size & (tarBlockSize - 1)
+}
+
+// expandSparse writes a sparse file's full contents from its stored data
+// regions, filling the holes with zeros
+func expandSparse(w io.Writer, stored io.Reader, regions []SparseRegion, size int64) error {
+	var position int64
+	for _, region := range regions {
+		if _, err := io.CopyN(w, zeroReader{}, region.Offset-position); err != nil {
+			return err
+		}
+		if _, err := io.CopyN(w, stored, region.Length); err != nil {
+			return err
+		}
+		position = region.Offset + region.Length
+	}
+	_, err := io.CopyN(w, zeroReader{}, size-position)
+	return err
+}
+
+// zeroReader reads an endless run of zero bytes
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
@@ -0,0 +1,20 @@
+//go:build !linux && !darwin
+
+package data
+
+import (
+	"errors"
+	"os"
+	"time"
+)
+
+// sparseDataRegions reports no holes where SEEK_DATA and SEEK_HOLE are not
+// available, so files are stored in full
+func sparseDataRegions(file *os.File, size int64) ([]SparseRegion, error) {
+	return nil, nil
+}
+
+// restoreLinkTime is not supported where symlink times cannot be set
+func restoreLinkTime(path string, modTime time.Time) error {
+	return errors.ErrUnsupported
+}
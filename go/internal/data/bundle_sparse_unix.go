@@ -0,0 +1,51 @@
+//go:build linux || darwin
+
+package data
+
+import (
+	"errors"
+	"io"
+	"os"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// sparseDataRegions finds the data regions of a file with SEEK_DATA and
+// SEEK_HOLE. It returns nil when the file has no holes, and an empty list when
+// the file is all hole.
+func sparseDataRegions(file *os.File, size int64) ([]SparseRegion, error) {
+	if size == 0 {
+		return nil, nil
+	}
+	defer file.Seek(0, io.SeekStart)
+
+	regions := []SparseRegion{}
+	for offset := int64(0); offset < size; {
+		start, err := file.Seek(offset, unix.SEEK_DATA)
+		if errors.Is(err, unix.ENXIO) {
+			break // Only a hole remains
+		}
+		if err != nil {
+			return nil, err
+		}
+		end, err := file.Seek(start, unix.SEEK_HOLE)
+		if err != nil {
+			return nil, err
+		}
+		end = min(end, size)
+		regions = append(regions, SparseRegion{Offset: start, Length: end - start})
+		offset = end
+	}
+
+	if len(regions) == 1 && regions[0].Offset == 0 && regions[0].Length == size {
+		return nil, nil
+	}
+	return regions, nil
+}
+
+// restoreLinkTime sets a symlink's own modification time
+func restoreLinkTime(path string, modTime time.Time) error {
+	times := []unix.Timespec{unix.NsecToTimespec(modTime.UnixNano()), unix.NsecToTimespec(modTime.UnixNano())}
+	return unix.UtimesNanoAt(unix.AT_FDCWD, path, times, unix.AT_SYMLINK_NOFOLLOW)
+}
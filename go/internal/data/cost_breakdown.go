@@ -0,0 +1,142 @@
+package data
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// FileTypeCost is the monthly cost of one file type in a cost scenario
+type FileTypeCost struct {
+	Extension   string  `json:"extension"`
+	Category    string  `json:"category"`
+	ObjectCount int64   `json:"object_count"`
+	StorageGB   float64 `json:"storage_gb"`
+	StorageCost float64 `json:"storage_cost_monthly"`
+	RequestCost float64 `json:"request_cost_monthly"`
+	TotalCost   float64 `json:"total_cost_monthly"`
+	Percentage  float64 `json:"percentage_of_cost"`
+}
+
+// fileTypeCategories group extensions into the kinds of data users recognize
+var fileTypeCategories = map[string]string{
+	".fastq": "sequence reads", ".fq": "sequence reads",
+	".bam": "alignments", ".sam": "alignments", ".cram": "alignments",
+	".vcf": "variants", ".bcf": "variants",
+	".fasta": "reference sequences", ".fa": "reference sequences", ".bed": "annotations", ".gff": "annotations", ".gtf": "annotations",
+	".nc": "scientific arrays", ".netcdf": "scientific arrays", ".hdf5": "scientific arrays", ".h5": "scientific arrays", ".grib": "scientific arrays", ".zarr": "scientific arrays",
+	".gz": "compressed archives", ".zip": "compressed archives", ".bz2": "compressed archives", ".xz": "compressed archives", ".7z": "compressed archives", ".tar": "compressed archives",
+	".jpg": "images", ".jpeg": "images", ".png": "images", ".gif": "images", ".tif": "images", ".tiff": "images",
+	".csv": "tabular data", ".tsv": "tabular data", ".parquet": "tabular data",
+	".log": "logs",
+	".txt": "text", ".json": "text", ".xml": "text", ".yaml": "text", ".yml": "text",
+	".py": "code", ".js": "code", ".sh": "code", ".r": "code", ".go": "code",
+	".pdf": "documents", ".doc": "documents", ".docx": "documents",
+}
+
+// FileTypeCategory returns the category of a file extension, or "other"
+func FileTypeCategory(extension string) string {
+	if category, exists := fileTypeCategories[strings.ToLower(extension)]; exists {
+		return category
+	}
+	return "other"
+}
+
+// fileTypeBreakdown splits a scenario's storage and request costs across the
+// pattern's file types. Storage is shared out by size, so tiered pricing is
+// applied to the dataset as a whole. When the pattern was built from a sample,
+// counts and sizes are scaled up to the full dataset.
+func (c *S3CostCalculator) fileTypeBreakdown(pattern *DataPattern, scenario CostScenario) []FileTypeCost {
+	var sampledFiles, sampledBytes int64
+	for _, info := range pattern.FileTypes {
+		sampledFiles += info.Count
+		sampledBytes += info.TotalSize
+	}
+	if sampledFiles == 0 {
+		return []FileTypeCost{}
+	}
+
+	countScale, sizeScale := 1.0, 1.0
+	if pattern.TotalFiles > 0 {
+		countScale = float64(pattern.TotalFiles) / float64(sampledFiles)
+	}
+	if pattern.TotalSize > 0 && sampledBytes > 0 {
+		sizeScale = float64(pattern.TotalSize) / float64(sampledBytes)
+	}
+
+	config := scenario.Configuration
+	breakdown := make([]FileTypeCost, 0, len(pattern.FileTypes))
+	var total float64
+	for ext, info := range pattern.FileTypes {
+		objects := float64(info.Count) * countScale
+		bytes := float64(info.TotalSize) * sizeScale
+
+		cost := FileTypeCost{
+			Extension:   ext,
+			Category:    FileTypeCategory(ext),
+			ObjectCount: int64(objects + 0.5),
+			StorageGB:   bytes / (1024 * 1024 * 1024),
+			RequestCost: c.calculateRequestCosts(objects, config),
+		}
+		if sampledBytes > 0 {
+			cost.StorageCost = scenario.MonthlyCosts.Storage * float64(info.TotalSize) / float64(sampledBytes)
+		}
+		cost.TotalCost = cost.StorageCost + cost.RequestCost
+		total += cost.TotalCost
+		breakdown = append(breakdown, cost)
+	}
+
+	for i := range breakdown {
+		if total > 0 {
+			breakdown[i].Percentage = breakdown[i].TotalCost / total * 100
+		}
+	}
+	sort.Slice(breakdown, func(i, j int) bool {
+		if breakdown[i].TotalCost != breakdown[j].TotalCost {
+			return breakdown[i].TotalCost > breakdown[j].TotalCost
+		}
+		return breakdown[i].Extension < breakdown[j].Extension
+	})
+	return breakdown
+}
+
+// maxCostDrivers is how many file types the cost driver summary names
+const maxCostDrivers = 3
+
+// summarizeCostDrivers explains in a sentence per file type what dominates the
+// bill, pointing at request charges where small objects are the problem
+func summarizeCostDrivers(breakdown []FileTypeCost) string {
+	var total float64
+	for _, cost := range breakdown {
+		total += cost.TotalCost
+	}
+	if total <= 0 {
+		return ""
+	}
+
+	sentences := []string{fmt.Sprintf("Storage and requests cost about $%.2f/month as uploaded.", total)}
+	for i, cost := range breakdown {
+		if i == maxCostDrivers || cost.TotalCost <= 0 {
+			break
+		}
+
+		name := fmt.Sprintf("%s files (%s)", cost.Extension, cost.Category)
+		if cost.RequestCost > cost.StorageCost {
+			sentences = append(sentences, fmt.Sprintf(
+				"%s make up %.0f%% ($%.2f), mostly request charges for %d objects averaging %s; bundling them would remove most of that.",
+				name, cost.Percentage, cost.TotalCost, cost.ObjectCount, formatBytes(averageObjectSize(cost))))
+		} else {
+			sentences = append(sentences, fmt.Sprintf(
+				"%s make up %.0f%% ($%.2f), mostly storing %.1f GB.",
+				name, cost.Percentage, cost.TotalCost, cost.StorageGB))
+		}
+	}
+	return strings.Join(sentences, " ")
+}
+
+func averageObjectSize(cost FileTypeCost) int64 {
+	if cost.ObjectCount == 0 {
+		return 0
+	}
+	return int64(cost.StorageGB * 1024 * 1024 * 1024 / float64(cost.ObjectCount))
+}
@@ -0,0 +1,133 @@
+package data
+
+import (
+	"context"
+	"math"
+	"strings"
+	"testing"
+)
+
+const gib = 1024 * 1024 * 1024
+
+// mixedPattern is a genomics run: a few large BAMs, FASTQs, and millions of tiny logs
+func mixedPattern() *DataPattern {
+	fileTypes := map[string]FileTypeInfo{
+		".bam":   {Extension: ".bam", Count: 200, TotalSize: 800 * gib},
+		".fastq": {Extension: ".fastq", Count: 400, TotalSize: 200 * gib},
+		".log":   {Extension: ".log", Count: 2_000_000, TotalSize: 4 * gib},
+	}
+
+	pattern := &DataPattern{FileTypes: fileTypes}
+	for _, info := range fileTypes {
+		pattern.TotalFiles += info.Count
+		pattern.TotalSize += info.TotalSize
+	}
+	return pattern
+}
+
+func closeTo(a, b float64) bool {
+	return math.Abs(a-b) < 1e-6*math.Max(1, math.Abs(b))
+}
+
+func TestFileTypeBreakdownMatchesCurrentState(t *testing.T) {
+	calculator := NewS3CostCalculator("us-east-1")
+	pattern := mixedPattern()
+	scenario := calculator.createCurrentStateScenario(pattern)
+
+	breakdown := calculator.fileTypeBreakdown(pattern, scenario)
+	if len(breakdown) != 3 {
+		t.Fatalf("breakdown has %d types, want 3", len(breakdown))
+	}
+
+	var storage, requests, share float64
+	var objects int64
+	for _, cost := range breakdown {
+		storage += cost.StorageCost
+		requests += cost.RequestCost
+		share += cost.Percentage
+		objects += cost.ObjectCount
+	}
+	if !closeTo(storage, scenario.MonthlyCosts.Storage) {
+		t.Errorf("storage sums to %.4f, scenario has %.4f", storage, scenario.MonthlyCosts.Storage)
+	}
+	if !closeTo(requests, scenario.MonthlyCosts.Requests) {
+		t.Errorf("requests sum to %.4f, scenario has %.4f", requests, scenario.MonthlyCosts.Requests)
+	}
+	if !closeTo(share, 100) || objects != pattern.TotalFiles {
+		t.Errorf("shares sum to %.2f%% over %d objects", share, objects)
+	}
+
+	// 800 GB of BAMs at $0.023 is $18.40; two million log PUTs are $1.00 plus GETs
+	bam := breakdown[0]
+	if bam.Extension != ".bam" || bam.Category != "alignments" || !closeTo(bam.StorageCost, 18.4) {
+		t.Errorf("top driver = %+v, want .bam storage at $18.40", bam)
+	}
+	for _, cost := range breakdown {
+		if cost.Extension == ".log" && cost.RequestCost <= cost.StorageCost {
+			t.Errorf(".log requests $%.2f should exceed storage $%.2f", cost.RequestCost, cost.StorageCost)
+		}
+	}
+	for i := 1; i < len(breakdown); i++ {
+		if breakdown[i].TotalCost > breakdown[i-1].TotalCost {
+			t.Errorf("breakdown not sorted by cost: %+v", breakdown)
+		}
+	}
+}
+
+func TestFileTypeBreakdownScalesSamples(t *testing.T) {
+	calculator := NewS3CostCalculator("us-east-1")
+	pattern := mixedPattern()
+	// The sample saw a tenth of the files and bytes
+	pattern.TotalFiles *= 10
+	pattern.TotalSize *= 10
+
+	breakdown := calculator.fileTypeBreakdown(pattern, calculator.createCurrentStateScenario(pattern))
+	if breakdown[0].ObjectCount != 2000 || !closeTo(breakdown[0].StorageGB, 8000) {
+		t.Errorf("scaled .bam = %+v, want 2000 objects and 8000 GB", breakdown[0])
+	}
+}
+
+func TestSummarizeCostDrivers(t *testing.T) {
+	calculator := NewS3CostCalculator("us-east-1")
+	analysis, err := calculator.AnalyzeCosts(context.Background(), mixedPattern())
+	if err != nil {
+		t.Fatalf("AnalyzeCosts() error = %v", err)
+	}
+
+	summary := analysis.CostDrivers
+	bam := strings.Index(summary, ".bam files (alignments)")
+	fastq := strings.Index(summary, ".fastq files (sequence reads)")
+	logs := strings.Index(summary, ".log files (logs)")
+	if bam < 0 || fastq < 0 || logs < 0 {
+		t.Fatalf("summary should name all three drivers:\n%s", summary)
+	}
+	if bam > fastq || fastq > logs {
+		t.Errorf("drivers out of cost order:\n%s", summary)
+	}
+	if !strings.Contains(summary, "mostly storing 800.0 GB") {
+		t.Errorf("summary should attribute .bam cost to storage:\n%s", summary)
+	}
+	if !strings.Contains(summary, "request charges for 2000000 objects") || !strings.Contains(summary, "bundling") {
+		t.Errorf("summary should attribute .log cost to requests:\n%s", summary)
+	}
+
+	if summarizeCostDrivers(nil) != "" {
+		t.Error("empty breakdown should have no summary")
+	}
+}
+
+func TestFileTypeCategory(t *testing.T) {
+	tests := map[string]string{
+		".bam":     "alignments",
+		".FASTQ":   "sequence reads",
+		".nc":      "scientific arrays",
+		".log":     "logs",
+		".unknown": "other",
+		"":         "other",
+	}
+	for ext, want := range tests {
+		if got := FileTypeCategory(ext); got != want {
+			t.Errorf("FileTypeCategory(%q) = %q, want %q", ext, got, want)
+		}
+	}
+}
@@ -11,6 +11,9 @@ import (
 type S3CostCalculator struct {
 	region       string
 	pricingModel *S3PricingModel
+	replication  *ReplicationOptions
+	bundlePlan   *BundlePlan
+	currentState *BucketAudit
 }
 
 // S3PricingModel contains pricing information for different S3 services and regions
@@ -78,6 +81,13 @@ type CostAnalysis struct {
 	TotalCostRange   CostRange            `json:"total_cost_range"`
 	PotentialSavings float64              `json:"potential_savings_monthly"`
 	AnalysisTime     time.Time            `json:"analysis_time"`
+
+	// FileTypeBreakdown splits the current-state cost by file type, most expensive first
+	FileTypeBreakdown []FileTypeCost `json:"file_type_breakdown"`
+	CostDrivers       string         `json:"cost_drivers"`
+
+	// Replication is set when the calculator models cross-region replication
+	Replication *ReplicationCost `json:"replication,omitempty"`
 }
 
 // CostScenario represents a specific cost scenario (e.g., current state, optimized state)
@@ -313,6 +323,10 @@ func (c *S3CostCalculator) AnalyzeCosts(ctx context.Context, pattern *DataPatter
 
 	analysis.Scenarios = scenarios
 
+	// Explain which file types drive the current-state cost
+	analysis.FileTypeBreakdown = c.fileTypeBreakdown(pattern, scenarios[0])
+	analysis.CostDrivers = summarizeCostDrivers(analysis.FileTypeBreakdown)
+
 	// Generate recommendations
 	analysis.Recommendations = c.generateRecommendations(pattern, scenarios)
 
@@ -323,11 +337,27 @@ func (c *S3CostCalculator) AnalyzeCosts(ctx context.Context, pattern *DataPatter
 	analysis.TotalCostRange = c.calculateCostRange(scenarios)
 	analysis.PotentialSavings = c.calculatePotentialSavings(scenarios)
 
+	// Replication adds to the current state rather than replacing it, so it is
+	// kept out of the cost range and savings
+	if c.replication != nil {
+		current := scenarios[0]
+		replication := c.calculateReplicationCost(pattern.TotalFiles, current.Configuration.TotalSizeGB, *c.replication)
+		analysis.Replication = &replication
+		analysis.Scenarios = append(analysis.Scenarios, c.createReplicationScenario(current, replication))
+		if recommendation, ok := replicationRecommendation(current.MonthlyCosts.Total, replication); ok {
+			analysis.Recommendations = append(analysis.Recommendations, recommendation)
+		}
+	}
+
 	return analysis, nil
 }
 
 // createCurrentStateScenario creates a scenario representing the current state
 func (c *S3CostCalculator) createCurrentStateScenario(pattern *DataPattern) CostScenario {
+	if c.currentState != nil {
+		return c.createAuditedScenario(c.currentState)
+	}
+
 	config := ScenarioConfig{
 		FileCount:          pattern.TotalFiles,
 		TotalSizeGB:        float64(pattern.TotalSize) / (1024 * 1024 * 1024),
@@ -397,15 +427,22 @@ func (c *S3CostCalculator) createOptimizedScenario(pattern *DataPattern) CostSce
 
 // createBundledScenario creates a scenario with small file bundling
 func (c *S3CostCalculator) createBundledScenario(pattern *DataPattern) CostScenario {
-	// Calculate bundling effects
-	smallFileCount := pattern.FileSizes.SmallFiles.CountUnder1MB
-	bundleReduction := float64(0.01) // Assume 100:1 bundling ratio
-	if smallFileCount > 1000 {
-		bundleReduction = 0.01 // 1% of original file count after bundling
+	// Calculate bundling effects, exactly from a dry run when one was supplied
+	var smallFileCount, bundleCount, newFileCount, targetSize int64
+	originalFileCount := pattern.TotalFiles
+	source := "estimated from the file size distribution"
+	if plan := c.bundlePlan; plan != nil {
+		smallFileCount, bundleCount, targetSize = plan.SmallFiles, plan.BundleCount, plan.TargetBundleSize
+		originalFileCount = plan.SmallFiles + plan.UnbundledFiles
+		newFileCount = plan.ObjectCount()
+		source = "from a bundling dry run"
+	} else {
+		targetSize = DefaultBundleTargetSize
+		smallFileCount = pattern.FileSizes.SmallFiles.CountUnder1MB
+		bundleCount = estimateBundleCount(pattern, targetSize)
+		newFileCount = pattern.TotalFiles - smallFileCount + bundleCount
 	}
 
-	newFileCount := pattern.TotalFiles - smallFileCount + int64(float64(smallFileCount)*bundleReduction)
-
 	config := ScenarioConfig{
 		FileCount:          newFileCount,
 		TotalSizeGB:        float64(pattern.TotalSize) / (1024 * 1024 * 1024),
@@ -419,14 +456,16 @@ func (c *S3CostCalculator) createBundledScenario(pattern *DataPattern) CostScena
 
 	return CostScenario{
 		Name:          "Bundled Small Files",
-		Description:   fmt.Sprintf("Small files bundled (reduced from %d to %d objects)", pattern.TotalFiles, newFileCount),
+		Description:   fmt.Sprintf("Small files bundled (reduced from %d to %d objects)", originalFileCount, newFileCount),
 		StorageClass:  "STANDARD",
 		Configuration: config,
 		MonthlyCosts:  c.calculateScenarioCosts(config),
 		YearlyCosts:   c.calculateYearlyCosts(c.calculateScenarioCosts(config)),
 		Assumptions: []string{
 			"Small files bundled using tools like Suitcase",
-			fmt.Sprintf("File count reduced by %d%%", int((1-bundleReduction)*100)),
+			fmt.Sprintf("%d small files packed into %d bundles of up to %s (about %.0f:1), %s",
+				smallFileCount, bundleCount, formatBytes(targetSize), bundlingRatio(smallFileCount, bundleCount), source),
+			fmt.Sprintf("%d objects after bundling", newFileCount),
 			"Additional compression from bundling",
 			"Metadata preserved for extraction",
 		},
@@ -474,26 +513,9 @@ func (c *S3CostCalculator) calculateScenarioCosts(config ScenarioConfig) Detaile
 	storagePricing := c.pricingModel.StorageClasses[config.StorageClass]
 	costs.Storage = c.calculateTieredStorageCost(effectiveSizeGB, storagePricing)
 
-	// Request costs (initial upload)
-	putRequests := float64(config.FileCount)
-	costs.Requests = putRequests * c.pricingModel.RequestPricing.PutCopyPostList / 1000
-
-	// Add monthly access request costs
-	accessFrequencyMultiplier := map[string]float64{
-		"daily":   30.0,
-		"weekly":  4.0,
-		"monthly": 1.0,
-		"yearly":  1.0 / 12.0,
-		"rarely":  1.0 / 24.0,
-	}
-
-	frequency := accessFrequencyMultiplier[config.AccessFrequency]
-	if frequency == 0 {
-		frequency = 1.0 // Default to monthly
-	}
-
-	getRequests := putRequests * frequency * (config.DownloadPercentage / 100)
-	costs.Requests += getRequests * c.pricingModel.RequestPricing.Get / 1000
+	// Request costs for the initial upload and monthly access
+	costs.Requests = c.calculateRequestCosts(float64(config.FileCount), config)
+	frequency := accessFrequency(config.AccessFrequency)
 
 	// Data transfer costs (for downloads)
 	downloadSizeGB := effectiveSizeGB * (config.DownloadPercentage / 100) * frequency
@@ -522,6 +544,45 @@ func (c *S3CostCalculator) calculateScenarioCosts(config ScenarioConfig) Detaile
 	return costs
 }
 
+// accessFrequencyMultipliers convert an access frequency to accesses per month
+var accessFrequencyMultipliers = map[string]float64{
+	"daily":   30.0,
+	"weekly":  4.0,
+	"monthly": 1.0,
+	"yearly":  1.0 / 12.0,
+	"rarely":  1.0 / 24.0,
+}
+
+// accessFrequency returns accesses per month, defaulting to monthly
+func accessFrequency(name string) float64 {
+	if frequency := accessFrequencyMultipliers[name]; frequency != 0 {
+		return frequency
+	}
+	return 1.0
+}
+
+// calculateRequestCosts calculates PUT costs for uploading objects plus the GET
+// costs of the scenario's monthly downloads
+func (c *S3CostCalculator) calculateRequestCosts(objects float64, config ScenarioConfig) float64 {
+	cost := objects * c.pricingModel.RequestPricing.PutCopyPostList / 1000
+
+	getRequests := objects * accessFrequency(config.AccessFrequency) * (config.DownloadPercentage / 100)
+	cost += getRequests * c.pricingModel.RequestPricing.Get / 1000
+
+	return cost
+}
+
+// MonthlyStorageCost returns the monthly cost of storing bytes in an S3 storage
+// class. It reports false for storage classes without a price in the model.
+func (c *S3CostCalculator) MonthlyStorageCost(storageClass string, bytes int64) (float64, bool) {
+	pricing, exists := c.pricingModel.StorageClasses[storageClass]
+	if !exists {
+		return 0, false
+	}
+	sizeGB := float64(bytes) / (1024 * 1024 * 1024)
+	return c.calculateTieredStorageCost(sizeGB, pricing), true
+}
+
 // calculateTieredStorageCost calculates storage cost with tiered pricing
 func (c *S3CostCalculator) calculateTieredStorageCost(sizeGB float64, pricing StoragePricing) float64 {
 	if pricing.FirstTierGB == 0 {
@@ -0,0 +1,339 @@
+package data
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Defaults for duplicate analysis
+const (
+	DefaultDedupeWorkers      = 4
+	DefaultDedupeStorageClass = "STANDARD"
+)
+
+// DedupeOptions configures a duplicate analysis
+type DedupeOptions struct {
+	// MinSize skips files smaller than this; small duplicates rarely matter
+	MinSize int64
+	// MaxHashBytes caps how many bytes are read for hashing; candidates
+	// beyond it are counted as unchecked. Zero hashes every candidate.
+	MaxHashBytes int64
+	// Workers bounds how many files are hashed at once
+	Workers int
+}
+
+// DuplicateSet is a group of files with identical content. The first file is
+// the copy to keep.
+type DuplicateSet struct {
+	SHA256      string   `json:"sha256"`
+	Size        int64    `json:"size"`
+	Files       []string `json:"files"`
+	WastedBytes int64    `json:"wasted_bytes"`
+}
+
+// DedupeReport summarizes the duplicate files under a directory
+type DedupeReport struct {
+	Root         string `json:"root"`
+	MinSize      int64  `json:"min_size"`
+	FilesScanned int    `json:"files_scanned"`
+	BytesScanned int64  `json:"bytes_scanned"`
+
+	// Candidates share their size with another file and were hashed unless
+	// the hash cap was reached first
+	CandidateFiles int   `json:"candidate_files"`
+	HashedFiles    int   `json:"hashed_files"`
+	HashedBytes    int64 `json:"hashed_bytes"`
+	UncheckedFiles int   `json:"unchecked_files"`
+	UncheckedBytes int64 `json:"unchecked_bytes"`
+
+	Sets           []DuplicateSet `json:"duplicate_sets"`
+	DuplicateFiles int            `json:"duplicate_files"`
+	WastedBytes    int64          `json:"wasted_bytes"`
+
+	StorageClass   string  `json:"storage_class,omitempty"`
+	MonthlySavings float64 `json:"monthly_savings"`
+}
+
+// dedupeCandidate is a file that shares its size with at least one other file
+type dedupeCandidate struct {
+	path string
+	size int64
+}
+
+// FindDuplicates finds files with identical content under root. Files are
+// grouped by size first, and only files sharing a size are hashed with
+// SHA-256, largest sizes first so a hash cap spends its budget where the
+// savings are. Nothing is modified.
+func FindDuplicates(ctx context.Context, root string, opts DedupeOptions) (*DedupeReport, error) {
+	if opts.MinSize < 1 {
+		opts.MinSize = 1
+	}
+	if opts.Workers < 1 {
+		opts.Workers = DefaultDedupeWorkers
+	}
+
+	report := &DedupeReport{Root: root, MinSize: opts.MinSize}
+
+	bySize := make(map[int64][]string)
+	err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		report.FilesScanned++
+		report.BytesScanned += info.Size()
+		if info.Size() >= opts.MinSize {
+			bySize[info.Size()] = append(bySize[info.Size()], path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan %s: %w", root, err)
+	}
+
+	sizes := make([]int64, 0, len(bySize))
+	for size, paths := range bySize {
+		if len(paths) > 1 {
+			sizes = append(sizes, size)
+		}
+	}
+	sort.Slice(sizes, func(i, j int) bool { return sizes[i] > sizes[j] })
+
+	// Whole size groups are hashed or skipped together, since a group with
+	// only some members hashed could miss duplicates
+	var candidates []dedupeCandidate
+	for _, size := range sizes {
+		paths := bySize[size]
+		groupBytes := size * int64(len(paths))
+		report.CandidateFiles += len(paths)
+		if opts.MaxHashBytes > 0 && report.HashedBytes+groupBytes > opts.MaxHashBytes {
+			report.UncheckedFiles += len(paths)
+			report.UncheckedBytes += groupBytes
+			continue
+		}
+		report.HashedFiles += len(paths)
+		report.HashedBytes += groupBytes
+		for _, path := range paths {
+			candidates = append(candidates, dedupeCandidate{path: path, size: size})
+		}
+	}
+
+	hashes, err := hashCandidates(ctx, candidates, opts.Workers)
+	if err != nil {
+		return nil, err
+	}
+
+	type contentKey struct {
+		size int64
+		hash string
+	}
+	groups := make(map[contentKey][]string)
+	for _, candidate := range candidates {
+		key := contentKey{candidate.size, hashes[candidate.path]}
+		groups[key] = append(groups[key], candidate.path)
+	}
+
+	for key, paths := range groups {
+		if len(paths) < 2 {
+			continue
+		}
+		files := make([]string, len(paths))
+		for i, path := range paths {
+			files[i] = relativePath(root, path)
+		}
+		sortKeepFirst(files)
+
+		set := DuplicateSet{
+			SHA256:      key.hash,
+			Size:        key.size,
+			Files:       files,
+			WastedBytes: key.size * int64(len(files)-1),
+		}
+		report.Sets = append(report.Sets, set)
+		report.DuplicateFiles += len(files) - 1
+		report.WastedBytes += set.WastedBytes
+	}
+
+	sort.Slice(report.Sets, func(i, j int) bool {
+		a, b := report.Sets[i], report.Sets[j]
+		if a.WastedBytes != b.WastedBytes {
+			return a.WastedBytes > b.WastedBytes
+		}
+		return a.Files[0] < b.Files[0]
+	})
+	return report, nil
+}
+
+// hashCandidates computes the SHA-256 of each candidate, keyed by path
+func hashCandidates(ctx context.Context, candidates []dedupeCandidate, workers int) (map[string]string, error) {
+	hashes := make(map[string]string, len(candidates))
+	var mu sync.Mutex
+	var firstErr error
+	var errOnce sync.Once
+	workerCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	pending := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for path := range pending {
+				sum, err := fileSHA256(workerCtx, path)
+				if err != nil {
+					errOnce.Do(func() {
+						firstErr = err
+						cancel()
+					})
+					continue
+				}
+				mu.Lock()
+				hashes[path] = sum
+				mu.Unlock()
+			}
+		}()
+	}
+
+dispatch:
+	for _, candidate := range candidates {
+		select {
+		case pending <- candidate.path:
+		case <-workerCtx.Done():
+			break dispatch
+		}
+	}
+	close(pending)
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return hashes, nil
+}
+
+// contextReader stops a long read once its context is cancelled
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c contextReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.r.Read(p)
+}
+
+// fileSHA256 streams a file through SHA-256
+func fileSHA256(ctx context.Context, path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer file.Close()
+
+	_, sum, err := copySHA256(io.Discard, contextReader{ctx, file})
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return hex.EncodeToString(sum), nil
+}
+
+// copySHA256 copies src to dst, returning the bytes copied and their SHA-256
+func copySHA256(dst io.Writer, src io.Reader) (int64, []byte, error) {
+	digest := sha256.New()
+	n, err := io.Copy(io.MultiWriter(dst, digest), src)
+	if err != nil {
+		return n, nil, err
+	}
+	return n, digest.Sum(nil), nil
+}
+
+// relativePath returns path relative to root, or path itself when it is outside root
+func relativePath(root, path string) string {
+	rel, err := filepath.Rel(root, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return path
+	}
+	return filepath.ToSlash(rel)
+}
+
+// sortKeepFirst orders a duplicate set so the copy to keep comes first: the
+// shallowest path, then the alphabetically first
+func sortKeepFirst(files []string) {
+	sort.Slice(files, func(i, j int) bool {
+		di, dj := strings.Count(files[i], "/"), strings.Count(files[j], "/")
+		if di != dj {
+			return di < dj
+		}
+		return files[i] < files[j]
+	})
+}
+
+// EstimateSavings sets the monthly storage savings of not uploading the
+// duplicates to the given storage class. Savings are the difference between
+// storing everything scanned and storing it without the duplicates, so tiered
+// pricing is applied at the dataset's real size.
+func (r *DedupeReport) EstimateSavings(calculator *S3CostCalculator, storageClass string) error {
+	withDuplicates, ok := calculator.MonthlyStorageCost(storageClass, r.BytesScanned)
+	if !ok {
+		return fmt.Errorf("no pricing for storage class %s", storageClass)
+	}
+	withoutDuplicates, _ := calculator.MonthlyStorageCost(storageClass, r.BytesScanned-r.WastedBytes)
+	r.StorageClass = storageClass
+	r.MonthlySavings = withDuplicates - withoutDuplicates
+	return nil
+}
+
+// Script returns a shell script that replaces each duplicate with a hard link
+// to the kept copy, or deletes it when run with ACTION=remove. It is meant to
+// be reviewed before it is run; the analysis itself never changes files.
+func (r *DedupeReport) Script() string {
+	var b strings.Builder
+	b.WriteString("#!/bin/sh\n")
+	fmt.Fprintf(&b, "# Duplicate files under %s found by aws-research-wizard data dedupe.\n", r.Root)
+	b.WriteString("# REVIEW BEFORE RUNNING. Each duplicate is replaced with a hard link to the\n")
+	b.WriteString("# first file of its set; run with ACTION=remove to delete duplicates instead.\n")
+	b.WriteString("# Hard links only work within one file system.\n")
+	b.WriteString("set -eu\n")
+	b.WriteString("ACTION=\"${ACTION:-link}\"\n")
+	fmt.Fprintf(&b, "cd %s\n\n", shellQuote(r.Root))
+	b.WriteString("dedupe() {\n")
+	b.WriteString("  if [ \"$ACTION\" = remove ]; then\n")
+	b.WriteString("    rm -- \"$2\"\n")
+	b.WriteString("  else\n")
+	b.WriteString("    ln -f -- \"$1\" \"$2\"\n")
+	b.WriteString("  fi\n")
+	b.WriteString("}\n")
+
+	for _, set := range r.Sets {
+		fmt.Fprintf(&b, "\n# %d copies of %s (sha256 %s)\n", len(set.Files), formatBytes(set.Size), set.SHA256)
+		keep := shellQuote(set.Files[0])
+		for _, duplicate := range set.Files[1:] {
+			fmt.Fprintf(&b, "dedupe %s %s\n", keep, shellQuote(duplicate))
+		}
+	}
+	return b.String()
+}
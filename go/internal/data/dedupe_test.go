@@ -0,0 +1,159 @@
+package data
+
+import (
+	"bytes"
+	"context"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// dedupeTree builds a directory with known duplicates:
+//   - raw/a.bam, copies/a.bam and backup/old/a.bam share 3000 bytes of content
+//   - raw/b.fastq and copies/b.fastq share 2000 bytes of content
+//   - raw/c.fastq has b.fastq's size but different content
+//   - small.txt and copies/small.txt are duplicates below the minimum size
+func dedupeTree(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	files := map[string][]byte{
+		"raw/a.bam":          bytes.Repeat([]byte("A"), 3000),
+		"copies/a.bam":       bytes.Repeat([]byte("A"), 3000),
+		"backup/old/a.bam":   bytes.Repeat([]byte("A"), 3000),
+		"raw/b.fastq":        bytes.Repeat([]byte("B"), 2000),
+		"copies/b.fastq":     bytes.Repeat([]byte("B"), 2000),
+		"raw/c.fastq":        bytes.Repeat([]byte("C"), 2000),
+		"raw/unique.vcf":     bytes.Repeat([]byte("U"), 1500),
+		"small.txt":          []byte("tiny"),
+		"copies/small.txt":   []byte("tiny"),
+		"copies/it's me.bam": bytes.Repeat([]byte("A"), 3000),
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, content, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestFindDuplicates(t *testing.T) {
+	root := dedupeTree(t)
+
+	report, err := FindDuplicates(context.Background(), root, DedupeOptions{MinSize: 1000})
+	if err != nil {
+		t.Fatalf("FindDuplicates() error = %v", err)
+	}
+
+	if report.FilesScanned != 10 || report.BytesScanned != 4*3000+3*2000+1500+8 {
+		t.Errorf("scanned %d files, %d bytes", report.FilesScanned, report.BytesScanned)
+	}
+	// The unique 1500-byte file has no size match and is never hashed
+	if report.CandidateFiles != 7 || report.HashedFiles != 7 || report.UncheckedFiles != 0 {
+		t.Errorf("candidates = %d, hashed = %d, unchecked = %d", report.CandidateFiles, report.HashedFiles, report.UncheckedFiles)
+	}
+
+	if len(report.Sets) != 2 {
+		t.Fatalf("sets = %+v", report.Sets)
+	}
+	bam := report.Sets[0]
+	if bam.Size != 3000 || bam.WastedBytes != 9000 || len(bam.Files) != 4 {
+		t.Errorf("bam set = %+v", bam)
+	}
+	if bam.Files[0] != "copies/a.bam" || bam.Files[3] != "backup/old/a.bam" {
+		t.Errorf("the shallowest, alphabetically first copy should be kept: %v", bam.Files)
+	}
+	fastq := report.Sets[1]
+	if fastq.WastedBytes != 2000 || strings.Join(fastq.Files, ",") != "copies/b.fastq,raw/b.fastq" {
+		t.Errorf("fastq set = %+v", fastq)
+	}
+	if report.DuplicateFiles != 4 || report.WastedBytes != 11000 {
+		t.Errorf("duplicates = %d, wasted = %d", report.DuplicateFiles, report.WastedBytes)
+	}
+}
+
+func TestFindDuplicatesHashCap(t *testing.T) {
+	root := dedupeTree(t)
+
+	// The 3000-byte group (12000 bytes) fits, the 2000-byte group does not
+	report, err := FindDuplicates(context.Background(), root, DedupeOptions{MinSize: 1000, MaxHashBytes: 15000})
+	if err != nil {
+		t.Fatalf("FindDuplicates() error = %v", err)
+	}
+	if report.HashedFiles != 4 || report.HashedBytes != 12000 || report.UncheckedFiles != 3 || report.UncheckedBytes != 6000 {
+		t.Errorf("hashed %d (%d bytes), unchecked %d (%d bytes)", report.HashedFiles, report.HashedBytes, report.UncheckedFiles, report.UncheckedBytes)
+	}
+	if len(report.Sets) != 1 || report.WastedBytes != 9000 {
+		t.Errorf("sets = %+v", report.Sets)
+	}
+}
+
+func TestFindDuplicatesMinSize(t *testing.T) {
+	root := dedupeTree(t)
+
+	report, err := FindDuplicates(context.Background(), root, DedupeOptions{})
+	if err != nil {
+		t.Fatalf("FindDuplicates() error = %v", err)
+	}
+	if len(report.Sets) != 3 || report.WastedBytes != 11004 {
+		t.Errorf("without a minimum size small files should count: %+v", report.Sets)
+	}
+}
+
+func TestDedupeEstimateSavings(t *testing.T) {
+	calculator := NewS3CostCalculator("us-east-1")
+	report := &DedupeReport{BytesScanned: 300 * gib, WastedBytes: 100 * gib}
+
+	if err := report.EstimateSavings(calculator, "STANDARD"); err != nil {
+		t.Fatalf("EstimateSavings() error = %v", err)
+	}
+	// 100GB less at $0.023/GB-month
+	if math.Abs(report.MonthlySavings-2.30) > 0.001 || report.StorageClass != "STANDARD" {
+		t.Errorf("savings = %.4f in %s", report.MonthlySavings, report.StorageClass)
+	}
+
+	if err := report.EstimateSavings(calculator, "GLACIER_IR"); err != nil {
+		t.Fatalf("EstimateSavings() error = %v", err)
+	}
+	if report.MonthlySavings >= 2.30 || report.MonthlySavings <= 0 {
+		t.Errorf("colder storage should save less, got %.4f", report.MonthlySavings)
+	}
+
+	if err := report.EstimateSavings(calculator, "NOT_A_CLASS"); err == nil {
+		t.Error("EstimateSavings() should reject unknown storage classes")
+	}
+}
+
+func TestDedupeScript(t *testing.T) {
+	root := dedupeTree(t)
+	report, err := FindDuplicates(context.Background(), root, DedupeOptions{MinSize: 1000})
+	if err != nil {
+		t.Fatalf("FindDuplicates() error = %v", err)
+	}
+
+	script := report.Script()
+	for _, want := range []string{
+		"#!/bin/sh\n",
+		"cd " + shellQuote(root) + "\n",
+		"dedupe 'copies/a.bam' 'raw/a.bam'\n",
+		`dedupe 'copies/a.bam' 'copies/it'"'"'s me.bam'` + "\n",
+		"dedupe 'copies/b.fastq' 'raw/b.fastq'\n",
+	} {
+		if !strings.Contains(script, want) {
+			t.Errorf("script missing %q:\n%s", want, script)
+		}
+	}
+	if strings.Count(script, "\ndedupe ") != report.DuplicateFiles {
+		t.Errorf("script should have one line per duplicate:\n%s", script)
+	}
+
+	// Generating the report and script must not touch the tree
+	if _, err := os.Stat(filepath.Join(root, "raw", "a.bam")); err != nil {
+		t.Errorf("analysis modified files: %v", err)
+	}
+}
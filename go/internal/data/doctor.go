@@ -0,0 +1,37 @@
+package data
+
+import (
+	"context"
+	"strings"
+
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/doctor"
+)
+
+func init() {
+	doctor.Register(
+		doctor.NewCheck(doctor.CategoryTools, "python3", checkPython),
+		doctor.NewCheck(doctor.CategoryTools, "suitcase", checkSuitcase),
+	)
+	for _, tool := range optionalTools {
+		doctor.Register(doctor.Tool(tool.name, tool.command, tool.features[0], "install "+tool.name+" and make sure it is on PATH", false))
+	}
+}
+
+func checkPython(ctx context.Context, env doctor.Environment) doctor.Result {
+	python, err := FindPython(ctx)
+	if err != nil {
+		return doctor.Warn("install Python 3 and make sure it is on PATH", "%v; Suitcase small-file bundling is unavailable", err)
+	}
+	return doctor.Pass("%s", strings.Join(python, " "))
+}
+
+func checkSuitcase(ctx context.Context, env doctor.Environment) doctor.Result {
+	cmd, err := pythonExec(ctx, "-c", "import suitcase")
+	if err != nil {
+		return doctor.Warn("install Python 3, then: pip install suitcase", "Python 3 not found; Suitcase small-file bundling is unavailable")
+	}
+	if err := cmd.Run(); err != nil {
+		return doctor.Warn("pip install suitcase", "suitcase package not importable; Suitcase small-file bundling is unavailable")
+	}
+	return doctor.Pass("importable")
+}
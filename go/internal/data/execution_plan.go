@@ -0,0 +1,362 @@
+package data
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Execution plan step types, in the order a plan runs them
+const (
+	PlanStepBundle    = "bundle"
+	PlanStepUpload    = "upload"
+	PlanStepLifecycle = "lifecycle"
+)
+
+// Lifecycle transition ages: Standard-IA bills at least 30 days, and archive
+// classes suit data that has gone cold for a quarter
+const (
+	infrequentAccessTransitionDays = 30
+	archiveTransitionDays          = 90
+)
+
+// bundleKeyPrefix is where a plan uploads bundles and their indexes under its prefix
+const bundleKeyPrefix = "bundles"
+
+// PlanStep is one action of an execution plan
+type PlanStep struct {
+	ID          string `json:"id"`
+	Type        string `json:"type"`
+	Description string `json:"description"`
+	// SuggestionID is the recommendation the step carries out; empty for the upload
+	SuggestionID string `json:"suggestion_id,omitempty"`
+
+	Source    string `json:"source,omitempty"`
+	BundleDir string `json:"bundle_dir,omitempty"`
+	Bucket    string `json:"bucket,omitempty"`
+	Prefix    string `json:"prefix,omitempty"`
+
+	SizeThreshold  string `json:"size_threshold,omitempty"`
+	StorageClass   string `json:"storage_class,omitempty"`
+	TransitionDays int    `json:"transition_days,omitempty"`
+}
+
+// SkippedSuggestion is a recommendation a plan cannot carry out automatically
+type SkippedSuggestion struct {
+	ID     string `json:"id"`
+	Title  string `json:"title"`
+	Reason string `json:"reason"`
+}
+
+// ExecutionPlan is an ordered set of steps that applies recommendations to a dataset
+type ExecutionPlan struct {
+	// ID identifies the plan's steps, so a checkpoint is only resumed by the same plan
+	ID      string              `json:"id"`
+	Source  string              `json:"source"`
+	Bucket  string              `json:"bucket"`
+	Prefix  string              `json:"prefix"`
+	Steps   []PlanStep          `json:"steps"`
+	Skipped []SkippedSuggestion `json:"skipped,omitempty"`
+	// EstimatedSavings is the monthly saving of the suggestions the plan applies
+	EstimatedSavings float64 `json:"estimated_savings_monthly"`
+}
+
+// ExecutionPlanOptions choose what a plan applies and where
+type ExecutionPlanOptions struct {
+	Source string
+	Bucket string
+	Prefix string
+	// Suggestions limits the plan to these suggestion IDs; empty applies all
+	Suggestions []string
+	// BundleDir holds bundles until they are uploaded; empty uses the cache directory
+	BundleDir string
+	// StorageClass objects are uploaded with; empty is STANDARD
+	StorageClass string
+}
+
+// BuildExecutionPlan turns a dataset's optimization suggestions into ordered
+// steps: bundle small files, upload, then apply a lifecycle policy. Suggestions
+// with no automatic step, such as compression, are listed as skipped.
+func BuildExecutionPlan(result *RecommendationResult, opts ExecutionPlanOptions) (*ExecutionPlan, error) {
+	if opts.Source == "" || opts.Bucket == "" {
+		return nil, fmt.Errorf("a source directory and target bucket are required")
+	}
+
+	selected := make(map[string]bool, len(opts.Suggestions))
+	for _, id := range opts.Suggestions {
+		selected[id] = true
+	}
+	for _, suggestion := range result.OptimizationSuggestions {
+		delete(selected, suggestion.ID)
+	}
+	if len(selected) > 0 {
+		unknown := make([]string, 0, len(selected))
+		for id := range selected {
+			unknown = append(unknown, id)
+		}
+		sort.Strings(unknown)
+		return nil, fmt.Errorf("unknown suggestion(s): %s", strings.Join(unknown, ", "))
+	}
+
+	plan := &ExecutionPlan{Source: opts.Source, Bucket: opts.Bucket, Prefix: opts.Prefix}
+	upload := PlanStep{
+		ID:           "upload",
+		Type:         PlanStepUpload,
+		Source:       opts.Source,
+		Bucket:       opts.Bucket,
+		Prefix:       opts.Prefix,
+		StorageClass: opts.StorageClass,
+	}
+	if upload.StorageClass == "" {
+		upload.StorageClass = "STANDARD"
+	}
+	var bundle, lifecycle *PlanStep
+
+	for _, suggestion := range result.OptimizationSuggestions {
+		if len(opts.Suggestions) > 0 && !containsString(opts.Suggestions, suggestion.ID) {
+			continue
+		}
+
+		switch suggestion.Type {
+		case "bundling":
+			bundle = &PlanStep{
+				ID:            "bundle",
+				Type:          PlanStepBundle,
+				SuggestionID:  suggestion.ID,
+				Source:        opts.Source,
+				BundleDir:     opts.BundleDir,
+				SizeThreshold: "1MB",
+			}
+			upload.BundleDir = opts.BundleDir
+		case "storage_class":
+			class := ""
+			if result.DataPattern != nil {
+				class = result.DataPattern.Efficiency.RecommendedStorageClass
+			}
+			days := transitionDays(class)
+			if days == 0 {
+				plan.Skipped = append(plan.Skipped, SkippedSuggestion{suggestion.ID, suggestion.Title,
+					fmt.Sprintf("no lifecycle transition to %q", class)})
+				continue
+			}
+			lifecycle = &PlanStep{
+				ID:             "lifecycle",
+				Type:           PlanStepLifecycle,
+				SuggestionID:   suggestion.ID,
+				Bucket:         opts.Bucket,
+				Prefix:         opts.Prefix,
+				StorageClass:   class,
+				TransitionDays: days,
+			}
+		case "compression":
+			plan.Skipped = append(plan.Skipped, SkippedSuggestion{suggestion.ID, suggestion.Title,
+				"compressing rewrites the source files; compress them yourself before applying the plan"})
+			continue
+		default:
+			plan.Skipped = append(plan.Skipped, SkippedSuggestion{suggestion.ID, suggestion.Title,
+				"advice only; there is nothing to apply"})
+			continue
+		}
+		plan.EstimatedSavings += suggestion.Impact.CostSavingsMonthly
+	}
+
+	if bundle != nil {
+		plan.Steps = append(plan.Steps, *bundle)
+	}
+	plan.Steps = append(plan.Steps, upload)
+	if lifecycle != nil {
+		plan.Steps = append(plan.Steps, *lifecycle)
+	}
+
+	plan.ID = planID(plan)
+	for i := range plan.Steps {
+		step := &plan.Steps[i]
+		if bundle != nil && step.Type != PlanStepLifecycle && step.BundleDir == "" {
+			step.BundleDir = filepath.Join(DefaultCacheDirectory(), "plans", plan.ID, "bundles")
+		}
+		step.Description = describePlanStep(*step)
+	}
+	return plan, nil
+}
+
+// transitionDays returns the age at which a lifecycle rule moves objects to a
+// storage class, or 0 when the class is not a lifecycle target
+func transitionDays(class string) int {
+	switch class {
+	case "STANDARD_IA", "ONEZONE_IA", "INTELLIGENT_TIERING":
+		return infrequentAccessTransitionDays
+	case "GLACIER", "GLACIER_IR", "DEEP_ARCHIVE":
+		return archiveTransitionDays
+	}
+	return 0
+}
+
+func describePlanStep(step PlanStep) string {
+	target := "s3://" + step.Bucket + "/" + step.Prefix
+	switch step.Type {
+	case PlanStepBundle:
+		return fmt.Sprintf("Bundle files under %s in %s into indexed tar bundles", step.SizeThreshold, step.Source)
+	case PlanStepUpload:
+		if step.BundleDir != "" {
+			return fmt.Sprintf("Upload the bundles and remaining files of %s to %s as %s", step.Source, target, step.StorageClass)
+		}
+		return fmt.Sprintf("Upload %s to %s as %s", step.Source, target, step.StorageClass)
+	case PlanStepLifecycle:
+		return fmt.Sprintf("Add a lifecycle rule moving %s to %s after %d days", target, step.StorageClass, step.TransitionDays)
+	}
+	return step.Type
+}
+
+// planID hashes what the plan does, before a bundle directory is generated from it
+func planID(plan *ExecutionPlan) string {
+	content, _ := json.Marshal(struct {
+		Source, Bucket, Prefix string
+		Steps                  []PlanStep
+	}{plan.Source, plan.Bucket, plan.Prefix, plan.Steps})
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:8])
+}
+
+// DefaultPlanStatePath returns the checkpoint file for a plan
+func DefaultPlanStatePath(plan *ExecutionPlan) string {
+	return filepath.Join(DefaultCacheDirectory(), "plans", plan.ID+".json")
+}
+
+// PlanState checkpoints which steps of a plan have completed
+type PlanState struct {
+	PlanID    string               `json:"plan_id"`
+	Completed map[string]time.Time `json:"completed"`
+	// LastError is the failure that stopped the previous run
+	LastError string `json:"last_error,omitempty"`
+}
+
+// LoadPlanState reads a plan checkpoint, returning an empty state when there is none
+func LoadPlanState(path string) (*PlanState, error) {
+	content, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return &PlanState{Completed: make(map[string]time.Time)}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plan state: %w", err)
+	}
+
+	var state PlanState
+	if err := json.Unmarshal(content, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse plan state %s: %w", path, err)
+	}
+	if state.Completed == nil {
+		state.Completed = make(map[string]time.Time)
+	}
+	return &state, nil
+}
+
+func (s *PlanState) save(path string) error {
+	content, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode plan state: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create plan state directory: %w", err)
+	}
+
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, content, 0644); err != nil {
+		return fmt.Errorf("failed to write plan state: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to replace plan state: %w", err)
+	}
+	return nil
+}
+
+// PlanStepRunner carries out one type of plan step
+type PlanStepRunner func(ctx context.Context, step PlanStep) error
+
+// PlanProgress reports a step starting, finishing or being skipped
+type PlanProgress struct {
+	Step   PlanStep
+	Index  int
+	Total  int
+	Status string // "skipped", "running", "done" or "failed"
+	Err    error
+}
+
+// PlanExecutor runs a plan's steps in order, checkpointing each completed step
+// so that a failed or interrupted run resumes where it stopped
+type PlanExecutor struct {
+	runners   map[string]PlanStepRunner
+	statePath string
+	progress  func(PlanProgress)
+}
+
+// NewPlanExecutor creates an executor with a runner for each step type
+func NewPlanExecutor(runners map[string]PlanStepRunner, statePath string, progress func(PlanProgress)) *PlanExecutor {
+	if progress == nil {
+		progress = func(PlanProgress) {}
+	}
+	return &PlanExecutor{runners: runners, statePath: statePath, progress: progress}
+}
+
+// Execute runs the steps not yet completed. A checkpoint left by a different
+// plan is refused rather than mixed with this one.
+func (e *PlanExecutor) Execute(ctx context.Context, plan *ExecutionPlan) (*PlanState, error) {
+	for _, step := range plan.Steps {
+		if e.runners[step.Type] == nil {
+			return nil, fmt.Errorf("no runner for %s steps", step.Type)
+		}
+	}
+
+	state, err := LoadPlanState(e.statePath)
+	if err != nil {
+		return nil, err
+	}
+	if state.PlanID != "" && state.PlanID != plan.ID {
+		return nil, fmt.Errorf("%s checkpoints plan %s, not %s; remove it to start over", e.statePath, state.PlanID, plan.ID)
+	}
+	state.PlanID = plan.ID
+
+	for i, step := range plan.Steps {
+		progress := PlanProgress{Step: step, Index: i + 1, Total: len(plan.Steps)}
+		if _, done := state.Completed[step.ID]; done {
+			progress.Status = "skipped"
+			e.progress(progress)
+			continue
+		}
+
+		progress.Status = "running"
+		e.progress(progress)
+
+		if err := ctx.Err(); err != nil {
+			return state, err
+		}
+		if err := e.runners[step.Type](ctx, step); err != nil {
+			progress.Status, progress.Err = "failed", err
+			e.progress(progress)
+
+			state.LastError = fmt.Sprintf("%s: %v", step.ID, err)
+			if saveErr := state.save(e.statePath); saveErr != nil {
+				return state, fmt.Errorf("step %s failed: %w (and the checkpoint could not be saved: %v)", step.ID, err, saveErr)
+			}
+			return state, fmt.Errorf("step %s failed: %w", step.ID, err)
+		}
+
+		state.Completed[step.ID] = time.Now().UTC()
+		state.LastError = ""
+		if err := state.save(e.statePath); err != nil {
+			return state, err
+		}
+		progress.Status = "done"
+		e.progress(progress)
+	}
+	return state, nil
+}
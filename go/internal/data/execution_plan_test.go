@@ -0,0 +1,282 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+)
+
+func planRecommendations() *RecommendationResult {
+	return &RecommendationResult{
+		DataPattern: &DataPattern{Efficiency: EfficiencyMetrics{RecommendedStorageClass: "STANDARD_IA"}},
+		OptimizationSuggestions: []OptimizationSuggestion{
+			{ID: "storage-class-optimization", Type: "storage_class", Title: "Use Standard-IA", Impact: ImpactAssessment{CostSavingsMonthly: 40}},
+			{ID: "bundling-small-files", Type: "bundling", Title: "Bundle small files", Impact: ImpactAssessment{CostSavingsMonthly: 25}},
+			{ID: "enable-compression", Type: "compression", Title: "Compress text files", Impact: ImpactAssessment{CostSavingsMonthly: 10}},
+			{ID: "optimize-tool-chain", Type: "tool_chain", Title: "Use s5cmd"},
+		},
+	}
+}
+
+func TestBuildExecutionPlan(t *testing.T) {
+	opts := ExecutionPlanOptions{Source: "/data/run1", Bucket: "lab", Prefix: "run1", BundleDir: "/scratch/bundles"}
+	plan, err := BuildExecutionPlan(planRecommendations(), opts)
+	if err != nil {
+		t.Fatalf("BuildExecutionPlan() error = %v", err)
+	}
+
+	// Steps run bundle, upload, lifecycle whatever order the suggestions come in
+	var stepTypes []string
+	for _, step := range plan.Steps {
+		stepTypes = append(stepTypes, step.Type)
+	}
+	if strings.Join(stepTypes, ",") != "bundle,upload,lifecycle" {
+		t.Fatalf("steps = %v", stepTypes)
+	}
+	upload, lifecycle := plan.Steps[1], plan.Steps[2]
+	if upload.BundleDir != "/scratch/bundles" || upload.StorageClass != "STANDARD" {
+		t.Errorf("upload = %+v", upload)
+	}
+	if lifecycle.StorageClass != "STANDARD_IA" || lifecycle.TransitionDays != 30 || lifecycle.SuggestionID != "storage-class-optimization" {
+		t.Errorf("lifecycle = %+v", lifecycle)
+	}
+	if plan.EstimatedSavings != 65 {
+		t.Errorf("estimated savings = %v", plan.EstimatedSavings)
+	}
+	if len(plan.Skipped) != 2 || plan.Skipped[0].ID != "enable-compression" || plan.Skipped[1].ID != "optimize-tool-chain" {
+		t.Errorf("skipped = %+v", plan.Skipped)
+	}
+
+	// The same inputs give the same plan, so its checkpoint can be found again
+	again, _ := BuildExecutionPlan(planRecommendations(), opts)
+	if again.ID != plan.ID {
+		t.Errorf("plan ID changed: %s then %s", plan.ID, again.ID)
+	}
+	opts.Prefix = "run2"
+	if other, _ := BuildExecutionPlan(planRecommendations(), opts); other.ID == plan.ID {
+		t.Error("a different target should give a different plan ID")
+	}
+}
+
+func TestBuildExecutionPlanSelection(t *testing.T) {
+	opts := ExecutionPlanOptions{Source: "/data/run1", Bucket: "lab", Suggestions: []string{"bundling-small-files"}}
+	plan, err := BuildExecutionPlan(planRecommendations(), opts)
+	if err != nil {
+		t.Fatalf("BuildExecutionPlan() error = %v", err)
+	}
+	if len(plan.Steps) != 2 || plan.Steps[0].Type != PlanStepBundle || len(plan.Skipped) != 0 {
+		t.Fatalf("plan = %+v", plan)
+	}
+	// Without --bundle-dir bundles go to a directory named after the plan
+	want := filepath.Join("plans", plan.ID, "bundles")
+	if !strings.HasSuffix(plan.Steps[0].BundleDir, want) || plan.Steps[1].BundleDir != plan.Steps[0].BundleDir {
+		t.Errorf("bundle dirs = %s, %s", plan.Steps[0].BundleDir, plan.Steps[1].BundleDir)
+	}
+
+	opts.Suggestions = []string{"bundling-small-files", "dedupe", "archive"}
+	if _, err := BuildExecutionPlan(planRecommendations(), opts); err == nil || !strings.Contains(err.Error(), "archive, dedupe") {
+		t.Errorf("BuildExecutionPlan() error = %v", err)
+	}
+
+	// A recommended class with no lifecycle transition is skipped
+	result := planRecommendations()
+	result.DataPattern.Efficiency.RecommendedStorageClass = "STANDARD"
+	opts.Suggestions = nil
+	plan, err = BuildExecutionPlan(result, opts)
+	if err != nil || plan.Steps[len(plan.Steps)-1].Type != PlanStepUpload || plan.Skipped[0].ID != "storage-class-optimization" {
+		t.Errorf("plan = %+v, %v", plan, err)
+	}
+}
+
+// recordingRunners returns runners that log each step and fail the steps in failures once
+func recordingRunners(ran *[]string, failures map[string]error) map[string]PlanStepRunner {
+	run := func(ctx context.Context, step PlanStep) error {
+		*ran = append(*ran, step.ID)
+		if err := failures[step.ID]; err != nil {
+			delete(failures, step.ID)
+			return err
+		}
+		return nil
+	}
+	return map[string]PlanStepRunner{PlanStepBundle: run, PlanStepUpload: run, PlanStepLifecycle: run}
+}
+
+func TestPlanExecutorResumes(t *testing.T) {
+	plan, err := BuildExecutionPlan(planRecommendations(), ExecutionPlanOptions{Source: "/data/run1", Bucket: "lab", BundleDir: "/scratch"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	statePath := filepath.Join(t.TempDir(), "plan.json")
+
+	var ran, statuses []string
+	failures := map[string]error{"upload": errors.New("connection reset")}
+	executor := NewPlanExecutor(recordingRunners(&ran, failures), statePath, func(p PlanProgress) {
+		statuses = append(statuses, p.Step.ID+":"+p.Status)
+	})
+
+	if _, err := executor.Execute(context.Background(), plan); err == nil || !strings.Contains(err.Error(), "step upload failed: connection reset") {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if strings.Join(ran, ",") != "bundle,upload" {
+		t.Errorf("ran = %v", ran)
+	}
+	state, err := LoadPlanState(statePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, done := state.Completed["bundle"]; !done || len(state.Completed) != 1 || state.LastError != "upload: connection reset" {
+		t.Errorf("checkpoint = %+v", state)
+	}
+
+	// The rerun skips the bundle step and clears the error
+	ran, statuses = nil, nil
+	state, err = executor.Execute(context.Background(), plan)
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if strings.Join(ran, ",") != "upload,lifecycle" {
+		t.Errorf("ran = %v", ran)
+	}
+	if statuses[0] != "bundle:skipped" || len(state.Completed) != 3 || state.LastError != "" {
+		t.Errorf("statuses = %v, state = %+v", statuses, state)
+	}
+
+	// A checkpoint of another plan is not reused
+	other, _ := BuildExecutionPlan(planRecommendations(), ExecutionPlanOptions{Source: "/data/run2", Bucket: "lab", BundleDir: "/scratch"})
+	if _, err := executor.Execute(context.Background(), other); err == nil || !strings.Contains(err.Error(), "remove it to start over") {
+		t.Errorf("Execute() error = %v", err)
+	}
+}
+
+func TestPlanExecutorNeedsRunners(t *testing.T) {
+	plan, _ := BuildExecutionPlan(planRecommendations(), ExecutionPlanOptions{Source: "/data/run1", Bucket: "lab", BundleDir: "/scratch"})
+	var ran []string
+	runners := recordingRunners(&ran, nil)
+	delete(runners, PlanStepLifecycle)
+
+	statePath := filepath.Join(t.TempDir(), "plan.json")
+	if _, err := NewPlanExecutor(runners, statePath, nil).Execute(context.Background(), plan); err == nil || len(ran) != 0 {
+		t.Errorf("Execute() error = %v after running %v", err, ran)
+	}
+	if _, err := os.Stat(statePath); err == nil {
+		t.Error("no checkpoint should be written before any step runs")
+	}
+}
+
+type fakeUploader struct {
+	keys    []string
+	classes map[string]string
+}
+
+func (f *fakeUploader) UploadFileWithClass(ctx context.Context, bucket, key, filePath, storageClass string, callback ProgressCallback) error {
+	f.keys = append(f.keys, key)
+	f.classes[storageClass] = key
+	return nil
+}
+
+func TestBundleAndUploadSteps(t *testing.T) {
+	source := filepath.Join(t.TempDir(), "run1")
+	files := map[string]int{
+		"reads/a.fastq": 100,
+		"reads/b.fastq": 200,
+		"big.bam":       2 * 1024 * 1024,
+	}
+	for name, size := range files {
+		path := filepath.Join(source, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, make([]byte, size), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	bundleDir := filepath.Join(t.TempDir(), "bundles")
+	uploader := &fakeUploader{classes: make(map[string]string)}
+	runners := NewPlanStepRunners(uploader, nil)
+	bundle := PlanStep{Type: PlanStepBundle, Source: source, BundleDir: bundleDir, SizeThreshold: "1MB"}
+	upload := PlanStep{Type: PlanStepUpload, Source: source, BundleDir: bundleDir, Bucket: "lab", Prefix: "run1", StorageClass: "STANDARD_IA"}
+
+	// Rerunning the bundle step replaces the bundles of the failed attempt
+	for i := 0; i < 2; i++ {
+		if err := runners[PlanStepBundle](context.Background(), bundle); err != nil {
+			t.Fatalf("bundle step error = %v", err)
+		}
+	}
+	if err := runners[PlanStepUpload](context.Background(), upload); err != nil {
+		t.Fatalf("upload step error = %v", err)
+	}
+
+	sort.Strings(uploader.keys)
+	if len(uploader.keys) != 3 || uploader.keys[0] != "run1/big.bam" ||
+		!strings.HasPrefix(uploader.keys[1], "run1/bundles/") || !strings.HasSuffix(uploader.keys[1], BundleIndexSuffix) ||
+		!strings.HasSuffix(uploader.keys[2], ".tar.gz") {
+		t.Errorf("uploaded = %v", uploader.keys)
+	}
+	if len(uploader.classes) != 1 || uploader.classes["STANDARD_IA"] == "" {
+		t.Errorf("storage classes = %v", uploader.classes)
+	}
+
+	// A directory the plan did not create is not cleared
+	foreign := t.TempDir()
+	os.WriteFile(filepath.Join(foreign, "notes.txt"), []byte("keep"), 0644)
+	bundle.BundleDir = foreign
+	if err := runners[PlanStepBundle](context.Background(), bundle); err == nil {
+		t.Error("bundle step should refuse a directory it does not own")
+	}
+	if _, err := os.Stat(filepath.Join(foreign, "notes.txt")); err != nil {
+		t.Errorf("foreign file removed: %v", err)
+	}
+}
+
+type fakeLifecycle struct {
+	rules []types.LifecycleRule
+	puts  int
+}
+
+func (f *fakeLifecycle) GetBucketLifecycleConfiguration(ctx context.Context, params *s3.GetBucketLifecycleConfigurationInput, optFns ...func(*s3.Options)) (*s3.GetBucketLifecycleConfigurationOutput, error) {
+	if f.rules == nil {
+		return nil, &smithy.GenericAPIError{Code: "NoSuchLifecycleConfiguration", Message: "The lifecycle configuration does not exist"}
+	}
+	return &s3.GetBucketLifecycleConfigurationOutput{Rules: f.rules}, nil
+}
+
+func (f *fakeLifecycle) PutBucketLifecycleConfiguration(ctx context.Context, params *s3.PutBucketLifecycleConfigurationInput, optFns ...func(*s3.Options)) (*s3.PutBucketLifecycleConfigurationOutput, error) {
+	f.puts++
+	f.rules = params.LifecycleConfiguration.Rules
+	return &s3.PutBucketLifecycleConfigurationOutput{}, nil
+}
+
+func TestLifecycleStep(t *testing.T) {
+	api := &fakeLifecycle{}
+	run := NewPlanStepRunners(nil, api)[PlanStepLifecycle]
+	step := PlanStep{Type: PlanStepLifecycle, Bucket: "lab", Prefix: "projects/run1", StorageClass: "STANDARD_IA", TransitionDays: 30}
+
+	if err := run(context.Background(), step); err != nil {
+		t.Fatalf("lifecycle step error = %v", err)
+	}
+	rule := api.rules[0]
+	if aws.ToString(rule.ID) != "aws-research-wizard-projects-run1" || aws.ToString(rule.Filter.Prefix) != "projects/run1/" ||
+		aws.ToInt32(rule.Transitions[0].Days) != 30 || rule.Transitions[0].StorageClass != types.TransitionStorageClassStandardIa {
+		t.Errorf("rule = %+v", rule)
+	}
+
+	// Other rules are kept and the plan's own rule is replaced, not duplicated
+	api.rules = append(api.rules, types.LifecycleRule{ID: aws.String("expire-logs"), Status: types.ExpirationStatusEnabled})
+	step.StorageClass, step.TransitionDays = "GLACIER", 90
+	if err := run(context.Background(), step); err != nil {
+		t.Fatalf("lifecycle step error = %v", err)
+	}
+	if len(api.rules) != 2 || aws.ToString(api.rules[1].ID) != "expire-logs" || api.rules[0].Transitions[0].StorageClass != types.TransitionStorageClassGlacier {
+		t.Errorf("rules = %+v", api.rules)
+	}
+}
@@ -0,0 +1,530 @@
+package data
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Formats of a sync manifest
+const (
+	ManifestFormatList = "list" // One path or glob pattern per line
+	ManifestFormatCSV  = "csv"  // Comma-separated, with a header naming the path column
+	ManifestFormatTSV  = "tsv"  // Tab-separated, with a header naming the path column
+)
+
+// manifestSeparators are the field separators of the table formats, tab
+// first since a tab-separated header may name columns containing commas
+var manifestSeparators = []struct{ format, separator string }{
+	{ManifestFormatTSV, "\t"},
+	{ManifestFormatCSV, ","},
+}
+
+// manifestPathColumns are the header names recognised as the path column of
+// a CSV or TSV manifest, compared case-insensitively
+var manifestPathColumns = []string{"path", "file", "filepath", "file_path", "filename", "file_name"}
+
+// ManifestEntry is one path or glob pattern listed in a sync manifest
+type ManifestEntry struct {
+	Line    int    `json:"line"`
+	Path    string `json:"path"`
+	Pattern bool   `json:"pattern"`
+}
+
+// SyncManifest is the list of files a selective sync transfers
+type SyncManifest struct {
+	Format  string          `json:"format"`
+	Column  string          `json:"column,omitempty"`
+	Entries []ManifestEntry `json:"entries"`
+}
+
+// LoadSyncManifest reads a sync manifest from a file. See ParseSyncManifest.
+func LoadSyncManifest(manifestPath, column string) (*SyncManifest, error) {
+	file, err := os.Open(manifestPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open manifest: %w", err)
+	}
+	defer file.Close()
+	return ParseSyncManifest(file, manifestPath, column)
+}
+
+// ParseSyncManifest parses a manifest of files to sync. A .csv or .tsv file,
+// or one whose first line is a header naming a path column, is read as a
+// table and the path column used; anything else is a plain list with one
+// path per line. Blank lines and lines starting with # are ignored. Paths are
+// relative to the source directory and may be glob patterns, where ** matches
+// any number of directories and a pattern without a slash matches at any
+// depth. column names the path column, overriding the recognised names.
+func ParseSyncManifest(r io.Reader, name, column string) (*SyncManifest, error) {
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+	content = bytes.TrimPrefix(content, []byte("\xef\xbb\xbf"))
+
+	manifest := &SyncManifest{Format: detectManifestFormat(name, content, column)}
+	if manifest.Format == ManifestFormatList {
+		if column != "" {
+			return nil, fmt.Errorf("manifest has no header with a %q column", column)
+		}
+		err = manifest.parseList(content)
+	} else {
+		err = manifest.parseTable(content, column)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// detectManifestFormat picks the format from the file extension, or else from
+// whether the first line is a header naming a path column
+func detectManifestFormat(name string, content []byte, column string) string {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".csv":
+		return ManifestFormatCSV
+	case ".tsv", ".tab":
+		return ManifestFormatTSV
+	}
+
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		for _, table := range manifestSeparators {
+			if !strings.Contains(line, table.separator) {
+				continue
+			}
+			for _, field := range strings.Split(line, table.separator) {
+				if isManifestPathColumn(strings.Trim(strings.TrimSpace(field), `"`), column) {
+					return table.format
+				}
+			}
+		}
+		break
+	}
+	return ManifestFormatList
+}
+
+// isManifestPathColumn reports whether a header field names the path column
+func isManifestPathColumn(field, column string) bool {
+	if column != "" {
+		return strings.EqualFold(field, column)
+	}
+	for _, name := range manifestPathColumns {
+		if strings.EqualFold(field, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseList reads one path or pattern per line
+func (m *SyncManifest) parseList(content []byte) error {
+	for i, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if err := m.add(i+1, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseTable reads the path column of a CSV or TSV manifest
+func (m *SyncManifest) parseTable(content []byte, column string) error {
+	reader := csv.NewReader(bytes.NewReader(content))
+	reader.Comment = '#'
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	if m.Format == ManifestFormatTSV {
+		reader.Comma = '\t'
+		reader.LazyQuotes = true
+	}
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read manifest header: %w", err)
+	}
+	pathColumn := -1
+	for i, field := range header {
+		if isManifestPathColumn(strings.TrimSpace(field), column) {
+			pathColumn = i
+			m.Column = strings.TrimSpace(field)
+			break
+		}
+	}
+	if pathColumn < 0 {
+		if column != "" {
+			return fmt.Errorf("manifest header has no %q column", column)
+		}
+		return fmt.Errorf("manifest header has no path column: name one of %s, or choose the column explicitly",
+			strings.Join(manifestPathColumns, ", "))
+	}
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read manifest: %w", err)
+		}
+		line, _ := reader.FieldPos(0)
+		if pathColumn >= len(record) {
+			return fmt.Errorf("manifest line %d has no %s column", line, m.Column)
+		}
+		value := strings.TrimSpace(record[pathColumn])
+		if value == "" {
+			continue
+		}
+		if err := m.add(line, value); err != nil {
+			return err
+		}
+	}
+}
+
+// add normalises and records one manifest entry
+func (m *SyncManifest) add(line int, value string) error {
+	entryPath := path.Clean(filepath.ToSlash(value))
+	entry := ManifestEntry{Line: line, Path: entryPath, Pattern: strings.ContainsAny(entryPath, "*?[")}
+	if entry.Pattern {
+		for _, segment := range strings.Split(entryPath, "/") {
+			if _, err := path.Match(segment, ""); err != nil {
+				return fmt.Errorf("manifest line %d: invalid pattern %q", line, value)
+			}
+		}
+	}
+	m.Entries = append(m.Entries, entry)
+	return nil
+}
+
+// Reasons a manifest entry matched no files
+const (
+	ManifestNotFound   = "not found"
+	ManifestOutside    = "outside the source directory"
+	ManifestNotRegular = "not a regular file"
+	ManifestEmptyDir   = "directory has no files"
+	ManifestNoMatch    = "pattern matched no files"
+)
+
+// SyncFile is a local file selected by a manifest
+type SyncFile struct {
+	Path         string    `json:"path"`
+	RelativePath string    `json:"relative_path"` // Slash-separated, relative to the source
+	Size         int64     `json:"size"`
+	ModTime      time.Time `json:"mod_time"`
+}
+
+// ManifestMiss is a manifest entry that selected no files
+type ManifestMiss struct {
+	Line   int    `json:"line"`
+	Entry  string `json:"entry"`
+	Reason string `json:"reason"`
+}
+
+// ManifestResolution is the set of files a manifest selects from a source
+// directory and the entries that selected nothing
+type ManifestResolution struct {
+	Files   []SyncFile     `json:"files"`
+	Missing []ManifestMiss `json:"missing"`
+	// Duplicates counts files selected by more than one entry, which are
+	// transferred once
+	Duplicates int   `json:"duplicates"`
+	TotalBytes int64 `json:"total_bytes"`
+}
+
+// ResolveSyncManifest matches the manifest entries against the files under
+// source. A directory entry selects every file below it. Entries that select
+// nothing are reported in Missing with their line numbers rather than
+// failing the resolution.
+func ResolveSyncManifest(source string, manifest *SyncManifest) (*ManifestResolution, error) {
+	info, err := os.Stat(source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read source: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("source %s is not a directory", source)
+	}
+
+	resolution := &ManifestResolution{}
+	selected := make(map[string]bool)
+	add := func(file SyncFile) {
+		if selected[file.RelativePath] {
+			resolution.Duplicates++
+			return
+		}
+		selected[file.RelativePath] = true
+		resolution.Files = append(resolution.Files, file)
+		resolution.TotalBytes += file.Size
+	}
+	miss := func(entry ManifestEntry, reason string) {
+		resolution.Missing = append(resolution.Missing, ManifestMiss{Line: entry.Line, Entry: entry.Path, Reason: reason})
+	}
+
+	// The source is walked at most once, when the first pattern needs it
+	var sourceFiles []SyncFile
+	walked := false
+
+	for _, entry := range manifest.Entries {
+		relative, ok := manifestRelativePath(source, entry.Path)
+		if !ok {
+			miss(entry, ManifestOutside)
+			continue
+		}
+
+		if entry.Pattern {
+			if !walked {
+				if sourceFiles, err = listSyncFiles(source, "."); err != nil {
+					return nil, err
+				}
+				walked = true
+			}
+			pattern := relative
+			if !strings.Contains(pattern, "/") {
+				pattern = "**/" + pattern
+			}
+			matched := false
+			for _, file := range sourceFiles {
+				if matchManifestPattern(pattern, file.RelativePath) {
+					add(file)
+					matched = true
+				}
+			}
+			if !matched {
+				miss(entry, ManifestNoMatch)
+			}
+			continue
+		}
+
+		localPath := filepath.Join(source, filepath.FromSlash(relative))
+		info, err := os.Stat(localPath)
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			miss(entry, ManifestNotFound)
+		case err != nil:
+			miss(entry, err.Error())
+		case info.IsDir():
+			files, err := listSyncFiles(source, relative)
+			if err != nil {
+				return nil, err
+			}
+			if len(files) == 0 {
+				miss(entry, ManifestEmptyDir)
+			}
+			for _, file := range files {
+				add(file)
+			}
+		case !info.Mode().IsRegular():
+			miss(entry, ManifestNotRegular)
+		default:
+			add(SyncFile{Path: localPath, RelativePath: relative, Size: info.Size(), ModTime: info.ModTime()})
+		}
+	}
+
+	sort.Slice(resolution.Files, func(i, j int) bool {
+		return resolution.Files[i].RelativePath < resolution.Files[j].RelativePath
+	})
+	return resolution, nil
+}
+
+// manifestRelativePath turns a manifest path into a slash-separated path
+// relative to source, reporting false when it points outside source
+func manifestRelativePath(source, entryPath string) (string, bool) {
+	relative := entryPath
+	if filepath.IsAbs(filepath.FromSlash(entryPath)) {
+		absSource, err := filepath.Abs(source)
+		if err != nil {
+			return "", false
+		}
+		rel, err := filepath.Rel(absSource, filepath.FromSlash(entryPath))
+		if err != nil {
+			return "", false
+		}
+		relative = path.Clean(filepath.ToSlash(rel))
+	}
+	if relative == ".." || strings.HasPrefix(relative, "../") || path.IsAbs(relative) {
+		return "", false
+	}
+	return relative, true
+}
+
+// listSyncFiles returns the regular files below the relative directory dir
+func listSyncFiles(source, dir string) ([]SyncFile, error) {
+	var files []SyncFile
+	root := filepath.Join(source, filepath.FromSlash(dir))
+	err := filepath.WalkDir(root, func(localPath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		relative, err := filepath.Rel(source, localPath)
+		if err != nil {
+			return err
+		}
+		files = append(files, SyncFile{
+			Path:         localPath,
+			RelativePath: filepath.ToSlash(relative),
+			Size:         info.Size(),
+			ModTime:      info.ModTime(),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk %s: %w", root, err)
+	}
+	return files, nil
+}
+
+// matchManifestPattern matches a slash-separated path against a glob pattern
+// in which a ** segment matches any number of directories
+func matchManifestPattern(pattern, name string) bool {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for skip := 0; skip <= len(name); skip++ {
+				if matchSegments(pattern[1:], name[skip:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if matched, _ := path.Match(pattern[0], name[0]); !matched {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+// SplitSmallFiles separates the files at or below threshold, which are worth
+// bundling, from the rest
+func SplitSmallFiles(files []SyncFile, threshold int64) (small, large []SyncFile) {
+	for _, file := range files {
+		if file.Size <= threshold {
+			small = append(small, file)
+		} else {
+			large = append(large, file)
+		}
+	}
+	return small, large
+}
+
+// FileEntries converts files selected by a manifest for bundling
+func FileEntries(files []SyncFile) []FileEntry {
+	entries := make([]FileEntry, 0, len(files))
+	for _, file := range files {
+		entries = append(entries, FileEntry{
+			Path:         file.Path,
+			Size:         file.Size,
+			ModTime:      file.ModTime,
+			Extension:    strings.ToLower(path.Ext(file.RelativePath)),
+			RelativePath: filepath.FromSlash(file.RelativePath),
+		})
+	}
+	return entries
+}
+
+// What SyncManifestFiles did with a file
+const (
+	SyncUploaded  = "uploaded"
+	SyncUnchanged = "unchanged"
+	SyncResumed   = "resumed"
+)
+
+// SyncProgress reports each file as SyncManifestFiles finishes with it
+type SyncProgress func(done, total int, file SyncFile, status string)
+
+// SyncReport counts what a selective sync did
+type SyncReport struct {
+	Uploaded      int   `json:"uploaded"`
+	Unchanged     int   `json:"unchanged"`
+	Resumed       int   `json:"resumed"`
+	UploadedBytes int64 `json:"uploaded_bytes"`
+	SkippedBytes  int64 `json:"skipped_bytes"`
+}
+
+// SyncManifestFiles uploads each file to prefix plus its relative path,
+// skipping files whose object in S3 already matches. Files uploaded or found
+// unchanged are recorded in the journal, so an interrupted run resumes
+// without checking them again. Objects whose checksum cannot be compared,
+// such as those encrypted with KMS, are uploaded again.
+func SyncManifestFiles(ctx context.Context, api s3VerifyAPI, bucket, prefix string, files []SyncFile, journal *VerifyJournal, upload BundleUploader, progress SyncProgress) (*SyncReport, error) {
+	report := &SyncReport{}
+	var remote map[string]*RemoteObject
+
+	for i, file := range files {
+		key := path.Join(prefix, file.RelativePath)
+		status := SyncUploaded
+
+		if journal != nil && journal.Verified(key, file.Size, file.ModTime) {
+			status = SyncResumed
+		} else {
+			if remote == nil {
+				var err error
+				if remote, err = listRemoteObjects(ctx, api, bucket, prefix); err != nil {
+					return report, err
+				}
+			}
+			job := verifyJob{path: file.Path, key: key, size: file.Size, modTime: file.ModTime}
+			verified, _, _, err := verifyFile(ctx, api, bucket, job, remote[key], 0)
+			if err != nil {
+				return report, err
+			}
+			if verified == VerifyOK {
+				status = SyncUnchanged
+			} else if err := upload(ctx, key, file.Path); err != nil {
+				return report, fmt.Errorf("failed to upload %s: %w", file.Path, err)
+			}
+			if journal != nil {
+				if err := journal.Record(key, file.Size, file.ModTime); err != nil {
+					return report, err
+				}
+			}
+		}
+
+		switch status {
+		case SyncUploaded:
+			report.Uploaded++
+			report.UploadedBytes += file.Size
+		case SyncUnchanged:
+			report.Unchanged++
+			report.SkippedBytes += file.Size
+		case SyncResumed:
+			report.Resumed++
+			report.SkippedBytes += file.Size
+		}
+		if progress != nil {
+			progress(i+1, len(files), file, status)
+		}
+	}
+	return report, nil
+}
@@ -0,0 +1,358 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// manifestPaths returns the line:path pairs of the entries, marking patterns
+func manifestPaths(manifest *SyncManifest) string {
+	var parts []string
+	for _, entry := range manifest.Entries {
+		part := fmt.Sprintf("%d:%s", entry.Line, entry.Path)
+		if entry.Pattern {
+			part += "*"
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, " ")
+}
+
+func TestParseSyncManifest(t *testing.T) {
+	tests := []struct {
+		name       string
+		file       string
+		column     string
+		content    string
+		wantFormat string
+		want       string
+	}{
+		{
+			name:       "plain list",
+			file:       "files.txt",
+			content:    "# run 42\nreads/a.fastq\n\n  ./reads/b.fastq  \nnotes/../summary.txt\n",
+			wantFormat: ManifestFormatList,
+			want:       "2:reads/a.fastq 4:reads/b.fastq 5:summary.txt",
+		},
+		{
+			name:       "CSV by extension",
+			file:       "samples.csv",
+			content:    "sample,Path,size\nS1,reads/a.fastq,10\n# skipped\nS2,\"reads/b c.fastq\",20\nS3,,0\n",
+			wantFormat: ManifestFormatCSV,
+			want:       "2:reads/a.fastq 4:reads/b c.fastq",
+		},
+		{
+			name:       "CSV detected from header",
+			file:       "samples",
+			content:    "\ufeffid,filename\n1,a.txt\n2,b.txt\n",
+			wantFormat: ManifestFormatCSV,
+			want:       "2:a.txt 3:b.txt",
+		},
+		{
+			name:       "TSV with a chosen column",
+			file:       "samples.tsv",
+			column:     "r1",
+			content:    "sample\tpath\tr1\nS1\tignored\treads/S1_R1.fastq\n",
+			wantFormat: ManifestFormatTSV,
+			want:       "2:reads/S1_R1.fastq",
+		},
+		{
+			name:       "glob patterns",
+			file:       "patterns.txt",
+			content:    "reads/*.fastq\n**/*.json\nsample_?.csv\nplain.txt\n",
+			wantFormat: ManifestFormatList,
+			want:       "1:reads/*.fastq* 2:**/*.json* 3:sample_?.csv* 4:plain.txt",
+		},
+		{
+			name:       "commas in a plain list",
+			file:       "files",
+			content:    "a,b.txt\nc.txt\n",
+			wantFormat: ManifestFormatList,
+			want:       "1:a,b.txt 2:c.txt",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manifest, err := ParseSyncManifest(strings.NewReader(tt.content), tt.file, tt.column)
+			if err != nil {
+				t.Fatalf("ParseSyncManifest() error = %v", err)
+			}
+			if manifest.Format != tt.wantFormat {
+				t.Errorf("format = %s, want %s", manifest.Format, tt.wantFormat)
+			}
+			if got := manifestPaths(manifest); got != tt.want {
+				t.Errorf("entries = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSyncManifestErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		column  string
+		content string
+		wantErr string
+	}{
+		{"CSV without a path column", "a.csv", "", "sample,size\nS1,10\n", "no path column"},
+		{"missing chosen column", "a.csv", "r2", "sample,r1\nS1,a\n", `no "r2" column`},
+		{"column for a plain list", "a.txt", "path", "a.txt\n", `no header with a "path" column`},
+		{"short row", "a.csv", "", "sample,path\nS1\n", "line 2 has no path column"},
+		{"invalid pattern", "a.txt", "", "ok.txt\nreads/[a-.fastq\n", "line 2: invalid pattern"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseSyncManifest(strings.NewReader(tt.content), tt.file, tt.column)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ParseSyncManifest() error = %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMatchManifestPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{"reads/*.fastq", "reads/a.fastq", true},
+		{"reads/*.fastq", "reads/lane1/a.fastq", false},
+		{"reads/**/*.fastq", "reads/a.fastq", true},
+		{"reads/**/*.fastq", "reads/lane1/deep/a.fastq", true},
+		{"**/*.json", "meta.json", true},
+		{"**/*.json", "a/b/meta.json", true},
+		{"**", "a/b/c", true},
+		{"sample_?.csv", "sample_1.csv", true},
+		{"sample_?.csv", "sample_10.csv", false},
+		{"reads/**", "other/a.fastq", false},
+	}
+
+	for _, tt := range tests {
+		if got := matchManifestPattern(tt.pattern, tt.name); got != tt.want {
+			t.Errorf("matchManifestPattern(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
+
+// writeSyncSource creates files, keyed by slash-separated path, under a new directory
+func writeSyncSource(t *testing.T, files map[string]string) string {
+	t.Helper()
+	source := t.TempDir()
+	for name, content := range files {
+		localPath := filepath.Join(source, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(localPath), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(localPath, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return source
+}
+
+func syncFilePaths(files []SyncFile) string {
+	var paths []string
+	for _, file := range files {
+		paths = append(paths, file.RelativePath)
+	}
+	return strings.Join(paths, " ")
+}
+
+func TestResolveSyncManifest(t *testing.T) {
+	source := writeSyncSource(t, map[string]string{
+		"reads/a.fastq":       "aaaa",
+		"reads/lane1/b.fastq": "bbbbbb",
+		"meta/run.json":       "{}",
+		"summary.txt":         "summary",
+		"other.txt":           "not listed",
+	})
+	if err := os.MkdirAll(filepath.Join(source, "empty"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	manifest, err := ParseSyncManifest(strings.NewReader(strings.Join([]string{
+		"summary.txt",
+		"reads/**/*.fastq",
+		"missing.txt",
+		"*.json",
+		"reads/a.fastq",
+		"../outside.txt",
+		"*.bam",
+		"meta",
+		"empty",
+		filepath.ToSlash(filepath.Join(source, "other.txt")),
+	}, "\n")), "files.txt", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resolution, err := ResolveSyncManifest(source, manifest)
+	if err != nil {
+		t.Fatalf("ResolveSyncManifest() error = %v", err)
+	}
+
+	wantFiles := "meta/run.json other.txt reads/a.fastq reads/lane1/b.fastq summary.txt"
+	if got := syncFilePaths(resolution.Files); got != wantFiles {
+		t.Errorf("files = %q, want %q", got, wantFiles)
+	}
+	// reads/a.fastq is listed after a pattern matching it, and meta after *.json
+	if resolution.Duplicates != 2 {
+		t.Errorf("duplicates = %d, want 2", resolution.Duplicates)
+	}
+	if want := int64(len("{}") + len("not listed") + 4 + 6 + len("summary")); resolution.TotalBytes != want {
+		t.Errorf("total bytes = %d, want %d", resolution.TotalBytes, want)
+	}
+
+	wantMissing := []ManifestMiss{
+		{Line: 3, Entry: "missing.txt", Reason: ManifestNotFound},
+		{Line: 6, Entry: "../outside.txt", Reason: ManifestOutside},
+		{Line: 7, Entry: "*.bam", Reason: ManifestNoMatch},
+		{Line: 9, Entry: "empty", Reason: ManifestEmptyDir},
+	}
+	if fmt.Sprint(resolution.Missing) != fmt.Sprint(wantMissing) {
+		t.Errorf("missing = %+v, want %+v", resolution.Missing, wantMissing)
+	}
+}
+
+func TestResolveSyncManifestRequiresDirectory(t *testing.T) {
+	source := writeSyncSource(t, map[string]string{"a.txt": "a"})
+	_, err := ResolveSyncManifest(filepath.Join(source, "a.txt"), &SyncManifest{})
+	if err == nil || !strings.Contains(err.Error(), "not a directory") {
+		t.Errorf("ResolveSyncManifest() error = %v, want a not-a-directory error", err)
+	}
+}
+
+func TestSplitSmallFiles(t *testing.T) {
+	files := []SyncFile{{RelativePath: "a", Size: 10}, {RelativePath: "b", Size: 100}, {RelativePath: "c", Size: 11}}
+	small, large := SplitSmallFiles(files, 10)
+	if syncFilePaths(small) != "a" || syncFilePaths(large) != "b c" {
+		t.Errorf("small = %v, large = %v", small, large)
+	}
+}
+
+func TestSyncManifestFiles(t *testing.T) {
+	contents := map[string]string{
+		"a.txt":     "alpha",
+		"sub/b.txt": "bravo",
+		"c.txt":     "charlie",
+	}
+	source := writeSyncSource(t, contents)
+	manifest, err := ParseSyncManifest(strings.NewReader("a.txt\nsub/b.txt\nc.txt\n"), "files.txt", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resolution, err := ResolveSyncManifest(source, manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	api := &fakeVerifyAPI{objects: []s3types.Object{
+		// Unchanged
+		{Key: aws.String("run/a.txt"), Size: aws.Int64(5), ETag: aws.String(`"` + md5Hex([]byte("alpha")) + `"`)},
+		// Changed with the same size
+		{Key: aws.String("run/sub/b.txt"), Size: aws.Int64(5), ETag: aws.String(`"` + md5Hex([]byte("BRAVO")) + `"`)},
+	}}
+	var uploaded []string
+	upload := func(ctx context.Context, key, localPath string) error {
+		uploaded = append(uploaded, key)
+		return nil
+	}
+	var progressed []string
+	progress := func(done, total int, file SyncFile, status string) {
+		progressed = append(progressed, fmt.Sprintf("%d/%d %s %s", done, total, file.RelativePath, status))
+	}
+
+	journal, err := OpenVerifyJournal(filepath.Join(t.TempDir(), "sync.journal"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer journal.Close()
+
+	report, err := SyncManifestFiles(context.Background(), api, "bucket", "run", resolution.Files, journal, upload, progress)
+	if err != nil {
+		t.Fatalf("SyncManifestFiles() error = %v", err)
+	}
+	if report.Uploaded != 2 || report.Unchanged != 1 || report.Resumed != 0 {
+		t.Errorf("report = %+v, want 2 uploaded and 1 unchanged", report)
+	}
+	if report.UploadedBytes != int64(len("charlie")+len("bravo")) || report.SkippedBytes != 5 {
+		t.Errorf("report bytes = %+v", report)
+	}
+	if want := "run/c.txt run/sub/b.txt"; strings.Join(uploaded, " ") != want {
+		t.Errorf("uploaded %v, want %s", uploaded, want)
+	}
+	wantProgress := "1/3 a.txt unchanged, 2/3 c.txt uploaded, 3/3 sub/b.txt uploaded"
+	if strings.Join(progressed, ", ") != wantProgress {
+		t.Errorf("progress = %v, want %s", progressed, wantProgress)
+	}
+
+	// A re-run with the journal skips every file without listing the bucket
+	uploaded = nil
+	report, err = SyncManifestFiles(context.Background(), &fakeVerifyAPI{}, "bucket", "run", resolution.Files, journal, upload, nil)
+	if err != nil {
+		t.Fatalf("SyncManifestFiles() resume error = %v", err)
+	}
+	if report.Resumed != 3 || len(uploaded) != 0 {
+		t.Errorf("resumed report = %+v, uploaded %v", report, uploaded)
+	}
+
+	// Without the journal, files whose objects match are still skipped
+	report, err = SyncManifestFiles(context.Background(), &fakeVerifyAPI{objects: []s3types.Object{
+		{Key: aws.String("run/a.txt"), Size: aws.Int64(5), ETag: aws.String(`"` + md5Hex([]byte("alpha")) + `"`)},
+		{Key: aws.String("run/sub/b.txt"), Size: aws.Int64(5), ETag: aws.String(`"` + md5Hex([]byte("bravo")) + `"`)},
+		{Key: aws.String("run/c.txt"), Size: aws.Int64(7), ETag: aws.String(`"` + md5Hex([]byte("charlie")) + `"`)},
+	}}, "bucket", "run", resolution.Files, nil, upload, nil)
+	if err != nil {
+		t.Fatalf("SyncManifestFiles() re-run error = %v", err)
+	}
+	if report.Unchanged != 3 || len(uploaded) != 0 {
+		t.Errorf("re-run report = %+v, uploaded %v", report, uploaded)
+	}
+}
+
+func TestBundleFileList(t *testing.T) {
+	source := writeSyncSource(t, map[string]string{
+		"small/a.txt": "alpha",
+		"small/b.txt": "bravo",
+		"skipped.txt": "not listed",
+	})
+	files := []SyncFile{
+		{Path: filepath.Join(source, "small", "a.txt"), RelativePath: "small/a.txt", Size: 5},
+		{Path: filepath.Join(source, "small", "b.txt"), RelativePath: "small/b.txt", Size: 5},
+	}
+
+	engine := NewSuitcaseEngine(&SuitcaseConfig{
+		Backend:          NativeTarBackend,
+		Chunked:          true,
+		TargetBundleSize: "1MB",
+		OutputDirectory:  t.TempDir(),
+	})
+	go func() {
+		for range engine.GetProgress() {
+		}
+	}()
+
+	result, err := engine.BundleFileList(context.Background(), source, FileEntries(files))
+	if err != nil {
+		t.Fatalf("BundleFileList() error = %v", err)
+	}
+	if result.BundledFileCount != 2 {
+		t.Errorf("bundled %d files, want 2", result.BundledFileCount)
+	}
+
+	suitcase := NewSuitcaseEngine(&SuitcaseConfig{OutputDirectory: t.TempDir()})
+	if _, err := suitcase.BundleFileList(context.Background(), source, FileEntries(files)); err == nil {
+		t.Error("BundleFileList() with the suitcase backend succeeded, want an error")
+	}
+}
@@ -289,7 +289,7 @@ func (odr *OpenDataRegistry) DownloadDatasetSample(ctx context.Context, dataset
 					break
 				}
 
-				localFile := fmt.Sprintf("%s/%s", localPath, *obj.Key)
+				localFile := localPathForKey(localPath, *obj.Key)
 				err := odr.s3Manager.DownloadFile(ctx, resource.Bucket, *obj.Key, localFile, nil)
 				if err != nil {
 					return fmt.Errorf("failed to download sample file %s: %w", *obj.Key, err)
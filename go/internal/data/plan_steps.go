@@ -0,0 +1,248 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+)
+
+// planDirMarker marks a bundle directory as owned by a plan, so that a rerun
+// of the bundle step may clear it
+const planDirMarker = ".arw-plan"
+
+// lifecycleRulePrefix names the lifecycle rules plans add, one per target prefix
+const lifecycleRulePrefix = "aws-research-wizard-"
+
+// planUploader uploads local files to S3 in a storage class
+type planUploader interface {
+	UploadFileWithClass(ctx context.Context, bucket, key, filePath, storageClass string, callback ProgressCallback) error
+}
+
+// s3LifecycleAPI is the subset of the S3 API used to manage lifecycle rules
+type s3LifecycleAPI interface {
+	GetBucketLifecycleConfiguration(ctx context.Context, params *s3.GetBucketLifecycleConfigurationInput, optFns ...func(*s3.Options)) (*s3.GetBucketLifecycleConfigurationOutput, error)
+	PutBucketLifecycleConfiguration(ctx context.Context, params *s3.PutBucketLifecycleConfigurationInput, optFns ...func(*s3.Options)) (*s3.PutBucketLifecycleConfigurationOutput, error)
+}
+
+// NewPlanStepRunners returns runners for each plan step type: bundling with
+// the native tar backend, uploading through uploader and adding lifecycle
+// rules through api
+func NewPlanStepRunners(uploader planUploader, api s3LifecycleAPI) map[string]PlanStepRunner {
+	return map[string]PlanStepRunner{
+		PlanStepBundle: runBundleStep,
+		PlanStepUpload: func(ctx context.Context, step PlanStep) error {
+			return runUploadStep(ctx, uploader, step)
+		},
+		PlanStepLifecycle: func(ctx context.Context, step PlanStep) error {
+			return runLifecycleStep(ctx, api, step)
+		},
+	}
+}
+
+// runBundleStep bundles a source's small files into an emptied bundle
+// directory, so a rerun after a failure starts from scratch
+func runBundleStep(ctx context.Context, step PlanStep) error {
+	if err := resetPlanDir(step.BundleDir); err != nil {
+		return err
+	}
+
+	engine := NewSuitcaseEngine(&SuitcaseConfig{
+		Backend:          NativeTarBackend,
+		TargetBundleSize: "100MB",
+		SizeThreshold:    step.SizeThreshold,
+		CompressionLevel: 6,
+		OutputDirectory:  step.BundleDir,
+	})
+	// Progress is reported per step, but the channel must not fill up
+	go func() {
+		for range engine.GetProgress() {
+		}
+	}()
+
+	_, err := engine.BundleFiles(ctx, step.Source)
+	return err
+}
+
+// resetPlanDir empties a plan's bundle directory. A directory with content
+// but no marker was not created by a plan and is left alone.
+func resetPlanDir(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to read bundle directory: %w", err)
+	}
+	if len(entries) > 0 {
+		if _, err := os.Stat(filepath.Join(dir, planDirMarker)); err != nil {
+			return fmt.Errorf("bundle directory %s is not empty; choose an empty or new directory", dir)
+		}
+		if err := os.RemoveAll(dir); err != nil {
+			return fmt.Errorf("failed to clear bundle directory: %w", err)
+		}
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create bundle directory: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, planDirMarker), nil, 0644); err != nil {
+		return fmt.Errorf("failed to mark bundle directory: %w", err)
+	}
+	return nil
+}
+
+// runUploadStep uploads the source files that were not bundled under the
+// step's prefix, keeping their relative paths, and the bundles with their
+// indexes under the bundles prefix
+func runUploadStep(ctx context.Context, uploader planUploader, step PlanStep) error {
+	bundled, bundleFiles, err := readPlanBundles(step.BundleDir)
+	if err != nil {
+		return err
+	}
+
+	bundleDir := ""
+	if step.BundleDir != "" {
+		bundleDir, _ = filepath.Abs(step.BundleDir)
+	}
+	err = filepath.WalkDir(step.Source, func(filePath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			// A bundle directory inside the source is uploaded as bundles, not files
+			if abs, _ := filepath.Abs(filePath); bundleDir != "" && abs == bundleDir {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(step.Source, filePath)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if bundled[rel] {
+			return nil
+		}
+		return uploader.UploadFileWithClass(ctx, step.Bucket, path.Join(step.Prefix, rel), filePath, step.StorageClass, nil)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to upload %s: %w", step.Source, err)
+	}
+
+	for _, local := range bundleFiles {
+		key := path.Join(step.Prefix, bundleKeyPrefix, filepath.Base(local))
+		if err := uploader.UploadFileWithClass(ctx, step.Bucket, key, local, step.StorageClass, nil); err != nil {
+			return fmt.Errorf("failed to upload %s: %w", local, err)
+		}
+	}
+	return nil
+}
+
+// readPlanBundles lists the bundles and indexes in a bundle directory and the
+// source-relative paths of the files they hold
+func readPlanBundles(dir string) (map[string]bool, []string, error) {
+	bundled := make(map[string]bool)
+	if dir == "" {
+		return bundled, nil, nil
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read bundle directory: %w", err)
+	}
+
+	var files []string
+	for _, entry := range entries {
+		if entry.IsDir() || entry.Name() == planDirMarker {
+			continue
+		}
+		local := filepath.Join(dir, entry.Name())
+		files = append(files, local)
+		if !strings.HasSuffix(entry.Name(), BundleIndexSuffix) {
+			continue
+		}
+
+		file, err := os.Open(local)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to open bundle index: %w", err)
+		}
+		index, err := ReadBundleIndex(file)
+		file.Close()
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", local, err)
+		}
+		for _, member := range index.Entries {
+			bundled[member.Path] = true
+		}
+	}
+	return bundled, files, nil
+}
+
+// runLifecycleStep adds or replaces the plan's transition rule for its prefix,
+// keeping the bucket's other lifecycle rules
+func runLifecycleStep(ctx context.Context, api s3LifecycleAPI, step PlanStep) error {
+	var rules []types.LifecycleRule
+	output, err := api.GetBucketLifecycleConfiguration(ctx, &s3.GetBucketLifecycleConfigurationInput{
+		Bucket: aws.String(step.Bucket),
+	})
+	var apiErr smithy.APIError
+	switch {
+	case err == nil:
+		rules = output.Rules
+	case errors.As(err, &apiErr) && apiErr.ErrorCode() == "NoSuchLifecycleConfiguration":
+	default:
+		return fmt.Errorf("failed to get lifecycle rules of %s: %w", step.Bucket, err)
+	}
+
+	rule := planLifecycleRule(step)
+	replaced := false
+	for i := range rules {
+		if aws.ToString(rules[i].ID) == aws.ToString(rule.ID) {
+			rules[i], replaced = rule, true
+		}
+	}
+	if !replaced {
+		rules = append(rules, rule)
+	}
+
+	_, err = api.PutBucketLifecycleConfiguration(ctx, &s3.PutBucketLifecycleConfigurationInput{
+		Bucket:                 aws.String(step.Bucket),
+		LifecycleConfiguration: &types.BucketLifecycleConfiguration{Rules: rules},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to put lifecycle rules on %s: %w", step.Bucket, err)
+	}
+	return nil
+}
+
+// planLifecycleRule returns the transition rule for a lifecycle step. The
+// filter ends in a slash so that prefix "raw" does not also match "raw2".
+func planLifecycleRule(step PlanStep) types.LifecycleRule {
+	prefix := strings.Trim(step.Prefix, "/")
+	id := lifecycleRulePrefix + "root"
+	if prefix != "" {
+		id = lifecycleRulePrefix + strings.ReplaceAll(prefix, "/", "-")
+		prefix += "/"
+	}
+
+	return types.LifecycleRule{
+		ID:     aws.String(id),
+		Status: types.ExpirationStatusEnabled,
+		Filter: &types.LifecycleRuleFilter{Prefix: aws.String(prefix)},
+		Transitions: []types.Transition{{
+			Days:         aws.Int32(int32(step.TransitionDays)),
+			StorageClass: types.TransitionStorageClass(step.StorageClass),
+		}},
+	}
+}
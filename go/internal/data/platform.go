@@ -0,0 +1,187 @@
+package data
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// appDirName is the directory name used under the temp and cache directories
+const appDirName = "aws-research-wizard"
+
+// pythonCandidates returns the commands tried, in order, to run Python 3 on an OS.
+// Windows installs the py launcher and usually names the interpreter python.exe;
+// elsewhere python may still be Python 2, so python3 comes first.
+func pythonCandidates(goos string) [][]string {
+	if goos == "windows" {
+		return [][]string{{"py", "-3"}, {"python"}, {"python3"}}
+	}
+	return [][]string{{"python3"}, {"python"}}
+}
+
+var (
+	pythonOnce    sync.Once
+	pythonCommand []string
+	pythonErr     error
+)
+
+// FindPython returns the command prefix that runs a Python 3 interpreter,
+// e.g. ["python3"] or ["py", "-3"]. The result is cached for the process.
+func FindPython(ctx context.Context) ([]string, error) {
+	pythonOnce.Do(func() {
+		pythonCommand, pythonErr = findPython(ctx, pythonCandidates(runtime.GOOS))
+	})
+	return pythonCommand, pythonErr
+}
+
+func findPython(ctx context.Context, candidates [][]string) ([]string, error) {
+	tried := make([]string, 0, len(candidates))
+	for _, candidate := range candidates {
+		tried = append(tried, strings.Join(candidate, " "))
+		if _, err := exec.LookPath(candidate[0]); err != nil {
+			continue
+		}
+
+		args := append(append([]string{}, candidate[1:]...), "--version")
+		output, err := exec.CommandContext(ctx, candidate[0], args...).CombinedOutput()
+		if err != nil {
+			continue
+		}
+		if strings.HasPrefix(strings.TrimSpace(string(output)), "Python 3") {
+			return candidate, nil
+		}
+	}
+	return nil, fmt.Errorf("python 3 not found in PATH (tried %s)", strings.Join(tried, ", "))
+}
+
+// pythonExec builds a command running Python 3 with the given arguments
+func pythonExec(ctx context.Context, args ...string) (*exec.Cmd, error) {
+	python, err := FindPython(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return exec.CommandContext(ctx, python[0], append(append([]string{}, python[1:]...), args...)...), nil
+}
+
+// FileMD5 returns the hex MD5 digest of a file
+func FileMD5(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := md5.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// DefaultWorkingDirectory returns the scratch directory used for transfers and bundling
+func DefaultWorkingDirectory() string {
+	return filepath.Join(os.TempDir(), appDirName)
+}
+
+// DefaultCacheDirectory returns the per-user cache directory, falling back to the
+// temp directory when the platform has none
+func DefaultCacheDirectory() string {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return filepath.Join(DefaultWorkingDirectory(), "cache")
+	}
+	return filepath.Join(cacheDir, appDirName)
+}
+
+// localPathForKey maps an S3 key, which always uses forward slashes, to a path under root
+func localPathForKey(root, key string) string {
+	return filepath.Join(root, filepath.FromSlash(key))
+}
+
+// safeNameComponent makes a relative path usable inside a file name on any OS
+func safeNameComponent(relativePath string) string {
+	name := filepath.ToSlash(relativePath)
+	if name == "." || name == "" {
+		return "root"
+	}
+	return strings.NewReplacer("/", "_", ":", "_", "\\", "_").Replace(name)
+}
+
+// ToolStatus reports whether an optional external tool was found
+type ToolStatus struct {
+	Name    string
+	Command string
+	Path    string
+	Found   bool
+	// Features lists what is unavailable without the tool
+	Features []string
+}
+
+// optionalTools are the external tools the data features use when available
+var optionalTools = []struct {
+	name     string
+	command  string
+	features []string
+}{
+	{name: "s5cmd", command: "s5cmd", features: []string{"s5cmd transfer engine (high-throughput S3 transfers)"}},
+	{name: "rclone", command: "rclone", features: []string{"rclone transfer engine (resumable and multi-cloud sync)"}},
+}
+
+// DetectTools reports which optional tools are installed and which data features
+// are degraded without them
+func DetectTools(ctx context.Context) []ToolStatus {
+	statuses := make([]ToolStatus, 0, len(optionalTools)+2)
+
+	python, pythonErr := FindPython(ctx)
+	pythonStatus := ToolStatus{
+		Name:     "Python 3",
+		Command:  strings.Join(pythonCandidatesLabel(runtime.GOOS), " | "),
+		Features: []string{"Suitcase small-file bundling"},
+	}
+	if pythonErr == nil {
+		pythonStatus.Found = true
+		pythonStatus.Command = strings.Join(python, " ")
+		pythonStatus.Path, _ = exec.LookPath(python[0])
+	}
+	statuses = append(statuses, pythonStatus)
+
+	suitcaseStatus := ToolStatus{
+		Name:     "suitcase",
+		Command:  "python -m suitcase",
+		Features: []string{"Suitcase small-file bundling (install with: pip install suitcase)"},
+	}
+	if pythonErr == nil {
+		if cmd, err := pythonExec(ctx, "-c", "import suitcase"); err == nil && cmd.Run() == nil {
+			suitcaseStatus.Found = true
+		}
+	}
+	statuses = append(statuses, suitcaseStatus)
+
+	for _, tool := range optionalTools {
+		status := ToolStatus{Name: tool.name, Command: tool.command, Features: tool.features}
+		if path, err := exec.LookPath(tool.command); err == nil {
+			status.Found = true
+			status.Path = path
+		}
+		statuses = append(statuses, status)
+	}
+
+	return statuses
+}
+
+func pythonCandidatesLabel(goos string) []string {
+	candidates := pythonCandidates(goos)
+	labels := make([]string, 0, len(candidates))
+	for _, candidate := range candidates {
+		labels = append(labels, strings.Join(candidate, " "))
+	}
+	return labels
+}
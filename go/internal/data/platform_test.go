@@ -0,0 +1,102 @@
+package data
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPythonCandidates(t *testing.T) {
+	windows := pythonCandidates("windows")
+	if strings.Join(windows[0], " ") != "py -3" {
+		t.Errorf("Expected the py launcher first on Windows, got %v", windows[0])
+	}
+
+	for _, goos := range []string{"linux", "darwin"} {
+		candidates := pythonCandidates(goos)
+		if candidates[0][0] != "python3" {
+			t.Errorf("Expected python3 first on %s, got %v", goos, candidates[0])
+		}
+	}
+}
+
+func TestFindPythonNotFound(t *testing.T) {
+	_, err := findPython(context.Background(), [][]string{{"no-such-python-xyz"}, {"no-such-py", "-3"}})
+	if err == nil {
+		t.Fatal("Expected error when no candidate exists")
+	}
+	if !strings.Contains(err.Error(), "no-such-py -3") {
+		t.Errorf("Expected error to list the candidates tried, got %v", err)
+	}
+}
+
+func TestFileMD5(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hello.txt")
+	if err := os.WriteFile(path, []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	sum, err := FileMD5(path)
+	if err != nil {
+		t.Fatalf("FileMD5 failed: %v", err)
+	}
+	if sum != "5d41402abc4b2a76b9719d911017c592" {
+		t.Errorf("Unexpected MD5: %s", sum)
+	}
+
+	if _, err := FileMD5(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("Expected error for missing file")
+	}
+}
+
+func TestLocalPathForKey(t *testing.T) {
+	root := t.TempDir()
+	got := localPathForKey(root, "phase3/data/sample.vcf")
+	want := filepath.Join(root, "phase3", "data", "sample.vcf")
+	if got != want {
+		t.Errorf("Expected %s, got %s", want, got)
+	}
+}
+
+func TestSafeNameComponent(t *testing.T) {
+	tests := map[string]string{
+		".":                               "root",
+		"":                                "root",
+		"reads":                           "reads",
+		filepath.Join("reads", "lane1"):   "reads_lane1",
+		`reads\lane1`:                     "reads_lane1",
+		"C:" + string(filepath.Separator): "C__",
+	}
+
+	for input, want := range tests {
+		if got := safeNameComponent(input); got != want {
+			t.Errorf("safeNameComponent(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestExpandPathSeparators(t *testing.T) {
+	home := filepath.Join(string(filepath.Separator), "home", "researcher")
+
+	if got := expandPath("~/cache", home); got != filepath.Join(home, "cache") {
+		t.Errorf("Unexpected expansion of ~/cache: %s", got)
+	}
+	native := "~" + string(filepath.Separator) + "cache"
+	if got := expandPath(native, home); got != filepath.Join(home, "cache") {
+		t.Errorf("Unexpected expansion of %s: %s", native, got)
+	}
+	if got := expandPath("~other/cache", home); got != "~other/cache" {
+		t.Errorf("Expected ~user paths to be left alone, got %s", got)
+	}
+}
+
+func TestDefaultDirectories(t *testing.T) {
+	if !strings.HasPrefix(DefaultWorkingDirectory(), os.TempDir()) {
+		t.Errorf("Expected working directory under %s, got %s", os.TempDir(), DefaultWorkingDirectory())
+	}
+	if filepath.Base(DefaultCacheDirectory()) != appDirName && filepath.Base(DefaultCacheDirectory()) != "cache" {
+		t.Errorf("Unexpected cache directory: %s", DefaultCacheDirectory())
+	}
+}
@@ -447,11 +447,11 @@ func (pcm *ProjectConfigManager) generateSettings(pattern *DataPattern, recommen
 	return ProjectSettings{
 		DefaultRegion:    "us-east-1",
 		DefaultEngine:    "auto",
-		WorkingDirectory: "/tmp/aws-research-wizard",
+		WorkingDirectory: DefaultWorkingDirectory(),
 		LogLevel:         "info",
 		ConfigDirectory:  "~/.aws-research-wizard/config",
-		CacheDirectory:   "~/.aws-research-wizard/cache",
-		TempDirectory:    "/tmp",
+		CacheDirectory:   DefaultCacheDirectory(),
+		TempDirectory:    os.TempDir(),
 		MaxConcurrent:    5,
 		GlobalTags: map[string]string{
 			"project":    "research",
@@ -0,0 +1,148 @@
+package data
+
+import (
+	"fmt"
+)
+
+// ReplicationScenarioName names the cross-region replication cost scenario
+const ReplicationScenarioName = "Cross-Region Replication"
+
+// ReplicationRecommendationThreshold is the share of the current monthly cost
+// above which replication earns a recommendation to consider same-region access
+const ReplicationRecommendationThreshold = 0.5
+
+// minReplicationRecommendationCost keeps trivially small replicas from being flagged
+const minReplicationRecommendationCost = 1.0
+
+// ReplicationOptions describe replicating a dataset to a second region
+type ReplicationOptions struct {
+	DestinationRegion string
+	// MonthlyChangeRate is the fraction of the dataset rewritten each month, 0-1
+	MonthlyChangeRate float64
+}
+
+// ReplicationCost breaks down what replicating a dataset to another region costs
+type ReplicationCost struct {
+	SourceRegion      string  `json:"source_region"`
+	DestinationRegion string  `json:"destination_region"`
+	MonthlyChangeRate float64 `json:"monthly_change_rate"`
+
+	// Monthly costs once the initial copy has completed
+	DestinationStorage  float64 `json:"destination_storage_monthly"`
+	ReplicationRequests float64 `json:"replication_requests_monthly"`
+	InterRegionTransfer float64 `json:"inter_region_transfer_monthly"`
+	MonthlyTotal        float64 `json:"monthly_total"`
+
+	// One-time costs of copying the existing dataset
+	InitialRequests float64 `json:"initial_requests"`
+	InitialTransfer float64 `json:"initial_transfer"`
+	InitialTotal    float64 `json:"initial_total"`
+}
+
+// SetReplication adds a cross-region replication scenario to later analyses
+func (c *S3CostCalculator) SetReplication(opts ReplicationOptions) error {
+	if opts.DestinationRegion == "" {
+		return fmt.Errorf("replication destination region is required")
+	}
+	if opts.DestinationRegion == c.region {
+		return fmt.Errorf("replication destination %s is the source region", opts.DestinationRegion)
+	}
+	if opts.MonthlyChangeRate < 0 || opts.MonthlyChangeRate > 1 {
+		return fmt.Errorf("monthly change rate %.2f is outside 0-100%%", opts.MonthlyChangeRate*100)
+	}
+	c.replication = &opts
+	return nil
+}
+
+// calculateReplicationCost prices replicating a dataset of the given size.
+// Replicas are stored and written at destination-region prices; the transfer
+// out of the source region is charged at the source region's inter-region rate.
+func (c *S3CostCalculator) calculateReplicationCost(fileCount int64, sizeGB float64, opts ReplicationOptions) ReplicationCost {
+	destination := NewS3CostCalculator(opts.DestinationRegion)
+	destinationStorage := destination.pricingModel.StorageClasses["STANDARD"]
+	putPrice := destination.pricingModel.RequestPricing.PutCopyPostList
+	transferPrice := c.pricingModel.TransferPricing.CrossRegionPer
+
+	changedObjects := float64(fileCount) * opts.MonthlyChangeRate
+	changedGB := sizeGB * opts.MonthlyChangeRate
+
+	cost := ReplicationCost{
+		SourceRegion:        c.region,
+		DestinationRegion:   opts.DestinationRegion,
+		MonthlyChangeRate:   opts.MonthlyChangeRate,
+		DestinationStorage:  destination.calculateTieredStorageCost(sizeGB, destinationStorage),
+		ReplicationRequests: changedObjects * putPrice / 1000,
+		InterRegionTransfer: changedGB * transferPrice,
+		InitialRequests:     float64(fileCount) * putPrice / 1000,
+		InitialTransfer:     sizeGB * transferPrice,
+	}
+	cost.MonthlyTotal = cost.DestinationStorage + cost.ReplicationRequests + cost.InterRegionTransfer
+	cost.InitialTotal = cost.InitialRequests + cost.InitialTransfer
+	return cost
+}
+
+// createReplicationScenario adds replication to the current state scenario
+func (c *S3CostCalculator) createReplicationScenario(current CostScenario, replication ReplicationCost) CostScenario {
+	monthly := current.MonthlyCosts
+	monthly.Storage += replication.DestinationStorage
+	monthly.Requests += replication.ReplicationRequests
+	monthly.DataTransfer += replication.InterRegionTransfer
+	monthly.Total += replication.MonthlyTotal
+
+	yearly := c.calculateYearlyCosts(monthly)
+	yearly.Requests += replication.InitialRequests
+	yearly.DataTransfer += replication.InitialTransfer
+	yearly.Total += replication.InitialTotal
+
+	return CostScenario{
+		Name:          ReplicationScenarioName,
+		Description:   fmt.Sprintf("Current state replicated from %s to %s", replication.SourceRegion, replication.DestinationRegion),
+		StorageClass:  current.StorageClass,
+		Configuration: current.Configuration,
+		MonthlyCosts:  monthly,
+		YearlyCosts:   yearly,
+		CostBreakdown: map[string]float64{
+			"destination_storage":   replication.DestinationStorage,
+			"replication_requests":  replication.ReplicationRequests,
+			"inter_region_transfer": replication.InterRegionTransfer,
+			"initial_copy":          replication.InitialTotal,
+		},
+		Assumptions: []string{
+			fmt.Sprintf("Replicas stored in S3 Standard at %s pricing", replication.DestinationRegion),
+			fmt.Sprintf("%.1f%% of the dataset changes and is replicated each month", replication.MonthlyChangeRate*100),
+			fmt.Sprintf("Initial copy of the full dataset costs $%.2f once, included in yearly costs", replication.InitialTotal),
+		},
+	}
+}
+
+// replicationRecommendation suggests same-region access when replication adds
+// more than the threshold share of the current monthly cost
+func replicationRecommendation(currentMonthly float64, replication ReplicationCost) (CostRecommendation, bool) {
+	if replication.MonthlyTotal < minReplicationRecommendationCost ||
+		replication.MonthlyTotal <= currentMonthly*ReplicationRecommendationThreshold {
+		return CostRecommendation{}, false
+	}
+
+	return CostRecommendation{
+		Type:  "replication",
+		Title: "Consider Same-Region Access Instead of Replication",
+		Description: fmt.Sprintf("Replicating to %s adds $%.2f/month (%.0f%% of the current cost) plus $%.2f up front. "+
+			"Collaborators can often read the data in %s directly instead.",
+			replication.DestinationRegion, replication.MonthlyTotal, replication.MonthlyTotal/currentMonthly*100,
+			replication.InitialTotal, replication.SourceRegion),
+		EstimatedSavings: replication.MonthlyTotal,
+		Confidence:       0.7,
+		Complexity:       "low",
+		Implementation: "Share the bucket in place with a bucket policy or S3 Access Point, enable Requester Pays for " +
+			"external collaborators, or front read-heavy data with CloudFront. If a replica is needed, " +
+			"limit replication rules to the prefixes that are actually read remotely.",
+		Tradeoffs: []string{
+			"Remote readers see inter-region latency",
+			"No second-region copy for disaster recovery",
+		},
+		Metadata: map[string]interface{}{
+			"destination_region":  replication.DestinationRegion,
+			"monthly_change_rate": replication.MonthlyChangeRate,
+		},
+	}, true
+}
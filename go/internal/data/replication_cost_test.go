@@ -0,0 +1,145 @@
+package data
+
+import (
+	"context"
+	"testing"
+)
+
+func TestCalculateReplicationCost(t *testing.T) {
+	tests := []struct {
+		name        string
+		source      string
+		destination string
+		want        ReplicationCost
+	}{
+		{
+			// Replicas are stored and written at Ireland's 5% premium
+			name:        "us-east-1 to eu-west-1",
+			source:      "us-east-1",
+			destination: "eu-west-1",
+			want: ReplicationCost{
+				DestinationStorage:  1000 * 0.023 * 1.05,
+				ReplicationRequests: 5000 * 0.0005 * 1.05 / 1000,
+				InterRegionTransfer: 50 * 0.02,
+				InitialRequests:     100000 * 0.0005 * 1.05 / 1000,
+				InitialTransfer:     1000 * 0.02,
+			},
+		},
+		{
+			name:        "eu-west-1 to us-east-1",
+			source:      "eu-west-1",
+			destination: "us-east-1",
+			want: ReplicationCost{
+				DestinationStorage:  1000 * 0.023,
+				ReplicationRequests: 5000 * 0.0005 / 1000,
+				InterRegionTransfer: 50 * 0.02,
+				InitialRequests:     100000 * 0.0005 / 1000,
+				InitialTransfer:     1000 * 0.02,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calculator := NewS3CostCalculator(tt.source)
+			got := calculator.calculateReplicationCost(100000, 1000, ReplicationOptions{
+				DestinationRegion: tt.destination,
+				MonthlyChangeRate: 0.05,
+			})
+
+			checks := map[string][2]float64{
+				"destination storage":   {got.DestinationStorage, tt.want.DestinationStorage},
+				"replication requests":  {got.ReplicationRequests, tt.want.ReplicationRequests},
+				"inter-region transfer": {got.InterRegionTransfer, tt.want.InterRegionTransfer},
+				"initial requests":      {got.InitialRequests, tt.want.InitialRequests},
+				"initial transfer":      {got.InitialTransfer, tt.want.InitialTransfer},
+				"monthly total": {got.MonthlyTotal,
+					tt.want.DestinationStorage + tt.want.ReplicationRequests + tt.want.InterRegionTransfer},
+				"initial total": {got.InitialTotal, tt.want.InitialRequests + tt.want.InitialTransfer},
+			}
+			for name, pair := range checks {
+				if !closeTo(pair[0], pair[1]) {
+					t.Errorf("%s = %.6f, want %.6f", name, pair[0], pair[1])
+				}
+			}
+			if got.SourceRegion != tt.source || got.DestinationRegion != tt.destination {
+				t.Errorf("regions = %s → %s", got.SourceRegion, got.DestinationRegion)
+			}
+		})
+	}
+}
+
+func TestAnalyzeCostsWithReplication(t *testing.T) {
+	calculator := NewS3CostCalculator("us-east-1")
+	without, err := calculator.AnalyzeCosts(context.Background(), mixedPattern())
+	if err != nil {
+		t.Fatalf("AnalyzeCosts() error = %v", err)
+	}
+
+	if err := calculator.SetReplication(ReplicationOptions{DestinationRegion: "eu-west-1", MonthlyChangeRate: 0.05}); err != nil {
+		t.Fatalf("SetReplication() error = %v", err)
+	}
+	analysis, err := calculator.AnalyzeCosts(context.Background(), mixedPattern())
+	if err != nil {
+		t.Fatalf("AnalyzeCosts() error = %v", err)
+	}
+
+	if analysis.Replication == nil {
+		t.Fatal("analysis should include replication costs")
+	}
+	last := analysis.Scenarios[len(analysis.Scenarios)-1]
+	if last.Name != ReplicationScenarioName || len(analysis.Scenarios) != len(without.Scenarios)+1 {
+		t.Fatalf("scenarios = %d, last = %q", len(analysis.Scenarios), last.Name)
+	}
+
+	current := analysis.Scenarios[0].MonthlyCosts.Total
+	if !closeTo(last.MonthlyCosts.Total, current+analysis.Replication.MonthlyTotal) {
+		t.Errorf("replication scenario = $%.2f, want current $%.2f plus $%.2f",
+			last.MonthlyCosts.Total, current, analysis.Replication.MonthlyTotal)
+	}
+	if !closeTo(last.YearlyCosts.Total-analysis.Replication.InitialTotal, last.MonthlyCosts.Total*12) {
+		t.Errorf("yearly cost $%.2f should be twelve months plus the initial copy", last.YearlyCosts.Total)
+	}
+
+	// Replication is an addition, not an alternative, so it leaves savings alone
+	if analysis.TotalCostRange.MaxMonthly != without.TotalCostRange.MaxMonthly ||
+		analysis.PotentialSavings != without.PotentialSavings {
+		t.Errorf("replication changed cost range or savings: %+v, $%.2f", analysis.TotalCostRange, analysis.PotentialSavings)
+	}
+
+	// A full replica of a storage-dominated dataset roughly doubles the bill
+	var recommended bool
+	for _, rec := range analysis.Recommendations {
+		recommended = recommended || rec.Type == "replication"
+	}
+	if !recommended {
+		t.Error("expected a same-region access recommendation")
+	}
+}
+
+func TestReplicationRecommendationThreshold(t *testing.T) {
+	cost := ReplicationCost{SourceRegion: "us-east-1", DestinationRegion: "eu-west-1", MonthlyTotal: 40}
+	if _, ok := replicationRecommendation(100, cost); ok {
+		t.Error("replication at 40% of current cost should not be flagged")
+	}
+	if _, ok := replicationRecommendation(60, cost); !ok {
+		t.Error("replication at 67% of current cost should be flagged")
+	}
+	if _, ok := replicationRecommendation(0.01, ReplicationCost{MonthlyTotal: 0.5}); ok {
+		t.Error("replication under a dollar a month should not be flagged")
+	}
+}
+
+func TestSetReplicationValidation(t *testing.T) {
+	calculator := NewS3CostCalculator("us-east-1")
+	for _, opts := range []ReplicationOptions{
+		{},
+		{DestinationRegion: "us-east-1"},
+		{DestinationRegion: "eu-west-1", MonthlyChangeRate: 1.5},
+		{DestinationRegion: "eu-west-1", MonthlyChangeRate: -0.1},
+	} {
+		if err := calculator.SetReplication(opts); err == nil {
+			t.Errorf("SetReplication(%+v) should fail", opts)
+		}
+	}
+}
@@ -0,0 +1,453 @@
+package data
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+)
+
+// Restore tiers, from cheapest and slowest to fastest
+const (
+	RestoreTierBulk      = "bulk"
+	RestoreTierStandard  = "standard"
+	RestoreTierExpedited = "expedited"
+)
+
+// Restore states reported for an archived object
+const (
+	RestoreNotRequested = "not-requested"
+	RestoreInProgress   = "in-progress"
+	RestoreRestored     = "restored"
+)
+
+// DefaultRestoreWorkers is the number of restore requests or status checks made concurrently
+const DefaultRestoreWorkers = 16
+
+// restoreStandardStoragePerGBMonth prices the temporary copy a restore creates,
+// which is billed as S3 Standard for the days it is kept
+const restoreStandardStoragePerGBMonth = 0.023
+
+// restorePricing is the us-east-1 retrieval price of a tier for one storage class
+type restorePricing struct {
+	PerGB       float64
+	PerThousand float64
+	Typical     time.Duration
+}
+
+// restorePrices lists the tiers each archive class supports. Deep Archive has
+// no expedited tier.
+var restorePrices = map[string]map[string]restorePricing{
+	"GLACIER": {
+		RestoreTierExpedited: {PerGB: 0.03, PerThousand: 10.00, Typical: 5 * time.Minute},
+		RestoreTierStandard:  {PerGB: 0.01, PerThousand: 0.05, Typical: 5 * time.Hour},
+		RestoreTierBulk:      {PerGB: 0, PerThousand: 0.025, Typical: 12 * time.Hour},
+	},
+	"DEEP_ARCHIVE": {
+		RestoreTierStandard: {PerGB: 0.02, PerThousand: 0.10, Typical: 12 * time.Hour},
+		RestoreTierBulk:     {PerGB: 0.0025, PerThousand: 0.025, Typical: 48 * time.Hour},
+	},
+}
+
+// ArchivedObject is an object whose storage class must be restored before it can be read
+type ArchivedObject struct {
+	Key          string `json:"key"`
+	Size         int64  `json:"size"`
+	StorageClass string `json:"storage_class"`
+}
+
+// s3RestoreAPI is the subset of the S3 API used to request and track restores
+type s3RestoreAPI interface {
+	s3.ListObjectsV2APIClient
+	RestoreObject(ctx context.Context, params *s3.RestoreObjectInput, optFns ...func(*s3.Options)) (*s3.RestoreObjectOutput, error)
+	HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error)
+}
+
+// ValidateRestoreTier checks a tier name, returning it in lower case
+func ValidateRestoreTier(tier string) (string, error) {
+	tier = strings.ToLower(tier)
+	switch tier {
+	case RestoreTierBulk, RestoreTierStandard, RestoreTierExpedited:
+		return tier, nil
+	}
+	return "", fmt.Errorf("unknown restore tier %q: use bulk, standard or expedited", tier)
+}
+
+// ListArchivedObjects lists the objects under a prefix stored in Glacier
+// Flexible Retrieval or Deep Archive. Glacier Instant Retrieval objects are
+// readable directly and are not included.
+func ListArchivedObjects(ctx context.Context, lister s3.ListObjectsV2APIClient, bucket, prefix string) ([]ArchivedObject, error) {
+	var archived []ArchivedObject
+	paginator := s3.NewListObjectsV2Paginator(lister, &s3.ListObjectsV2Input{
+		Bucket: aws.String(bucket),
+		Prefix: aws.String(prefix),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list s3://%s/%s: %w", bucket, prefix, err)
+		}
+		for _, object := range page.Contents {
+			class := string(object.StorageClass)
+			if _, ok := restorePrices[class]; !ok {
+				continue
+			}
+			archived = append(archived, ArchivedObject{
+				Key:          aws.ToString(object.Key),
+				Size:         aws.ToInt64(object.Size),
+				StorageClass: class,
+			})
+		}
+	}
+	return archived, nil
+}
+
+// RestoreEstimate is the expected cost and duration of restoring a set of objects
+type RestoreEstimate struct {
+	Tier    string
+	Days    int32
+	Objects int
+	Bytes   int64
+	// RetrievalCost covers the per-GB and per-request retrieval fees
+	RetrievalCost float64
+	// StorageCost covers keeping the restored copies for Days
+	StorageCost float64
+	// Typical is how long the slowest storage class usually takes to restore
+	Typical time.Duration
+}
+
+// TotalCost returns the retrieval and temporary storage cost together
+func (e *RestoreEstimate) TotalCost() float64 {
+	return e.RetrievalCost + e.StorageCost
+}
+
+// EstimateRestore prices restoring objects with a tier, failing if any
+// object's storage class does not support it
+func EstimateRestore(objects []ArchivedObject, tier string, days int32) (*RestoreEstimate, error) {
+	if days < 1 {
+		return nil, fmt.Errorf("restored copies must be kept for at least 1 day, got %d", days)
+	}
+
+	estimate := &RestoreEstimate{Tier: tier, Days: days, Objects: len(objects)}
+	requests := make(map[string]int)
+	for _, object := range objects {
+		pricing, ok := restorePrices[object.StorageClass][tier]
+		if !ok {
+			return nil, fmt.Errorf("%s objects cannot be restored with the %s tier (%s)", object.StorageClass, tier, object.Key)
+		}
+		gb := float64(object.Size) / (1024 * 1024 * 1024)
+		estimate.Bytes += object.Size
+		estimate.RetrievalCost += gb * pricing.PerGB
+		estimate.StorageCost += gb * restoreStandardStoragePerGBMonth * float64(days) / 30
+		requests[object.StorageClass]++
+		if pricing.Typical > estimate.Typical {
+			estimate.Typical = pricing.Typical
+		}
+	}
+	for class, count := range requests {
+		estimate.RetrievalCost += float64(count) / 1000 * restorePrices[class][tier].PerThousand
+	}
+	return estimate, nil
+}
+
+// RestoreSubmission summarizes a batch of restore requests
+type RestoreSubmission struct {
+	Requested int
+	// AlreadyInProgress counts objects S3 was already restoring
+	AlreadyInProgress int
+	Failures          []RestoreFailure
+}
+
+// RestoreFailure records an object whose restore could not be requested
+type RestoreFailure struct {
+	Key string
+	Err error
+}
+
+// RequestRestores asks S3 to restore each object for days using a tier.
+// Requesting an object that is already being restored is not an error, so a
+// partly submitted batch can simply be submitted again.
+func RequestRestores(ctx context.Context, api s3RestoreAPI, bucket string, objects []ArchivedObject, tier string, days int32, workers int) (*RestoreSubmission, error) {
+	if workers < 1 {
+		workers = DefaultRestoreWorkers
+	}
+
+	submission := &RestoreSubmission{}
+	var mu sync.Mutex
+	pending := make(chan ArchivedObject)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for object := range pending {
+				_, err := api.RestoreObject(ctx, &s3.RestoreObjectInput{
+					Bucket: aws.String(bucket),
+					Key:    aws.String(object.Key),
+					RestoreRequest: &s3types.RestoreRequest{
+						Days:                 aws.Int32(days),
+						GlacierJobParameters: &s3types.GlacierJobParameters{Tier: s3types.Tier(restoreTierName(tier))},
+					},
+				})
+
+				mu.Lock()
+				var apiErr smithy.APIError
+				switch {
+				case err == nil:
+					submission.Requested++
+				case errors.As(err, &apiErr) && apiErr.ErrorCode() == "RestoreAlreadyInProgress":
+					submission.AlreadyInProgress++
+				default:
+					submission.Failures = append(submission.Failures, RestoreFailure{Key: object.Key, Err: err})
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+dispatch:
+	for _, object := range objects {
+		select {
+		case pending <- object:
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+	close(pending)
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return submission, err
+	}
+	sort.Slice(submission.Failures, func(i, j int) bool {
+		return submission.Failures[i].Key < submission.Failures[j].Key
+	})
+	return submission, nil
+}
+
+// restoreTierName converts a tier to the capitalized form the S3 API expects
+func restoreTierName(tier string) string {
+	if tier == "" {
+		return tier
+	}
+	return strings.ToUpper(tier[:1]) + tier[1:]
+}
+
+// parseRestoreHeader derives an object's restore state from its x-amz-restore header
+func parseRestoreHeader(header *string) string {
+	switch {
+	case header == nil:
+		return RestoreNotRequested
+	case strings.Contains(*header, `ongoing-request="true"`):
+		return RestoreInProgress
+	default:
+		return RestoreRestored
+	}
+}
+
+// RestoreJob records a submitted restore so its status can be followed later
+type RestoreJob struct {
+	Bucket      string    `json:"bucket"`
+	Prefix      string    `json:"prefix"`
+	Tier        string    `json:"tier"`
+	Days        int32     `json:"days"`
+	RequestedAt time.Time `json:"requested_at"`
+}
+
+// DefaultRestoreJobPath returns where the restore job for a prefix is recorded
+func DefaultRestoreJobPath(bucket, prefix string) string {
+	sum := sha256.Sum256([]byte(bucket + "\x00" + prefix))
+	return filepath.Join(DefaultCacheDirectory(), "restores", hex.EncodeToString(sum[:8])+".json")
+}
+
+// SaveRestoreJob writes a restore job record
+func SaveRestoreJob(path string, job *RestoreJob) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create restore job directory: %w", err)
+	}
+	content, err := json.MarshalIndent(job, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode restore job: %w", err)
+	}
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		return fmt.Errorf("failed to write restore job: %w", err)
+	}
+	return nil
+}
+
+// LoadRestoreJob reads a restore job record, returning nil if there is none
+func LoadRestoreJob(path string) (*RestoreJob, error) {
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read restore job: %w", err)
+	}
+	var job RestoreJob
+	if err := json.Unmarshal(content, &job); err != nil {
+		return nil, fmt.Errorf("failed to parse restore job %s: %w", path, err)
+	}
+	return &job, nil
+}
+
+// RestoreStatusReport aggregates the restore state of archived objects
+type RestoreStatusReport struct {
+	Counts map[string]int
+	Bytes  map[string]int64
+	// Ready lists the restored objects, which can be downloaded now
+	Ready []ArchivedObject
+	// ETA is when the last in-progress restore is typically done; zero when
+	// nothing is in progress or the restore job is unknown
+	ETA time.Time
+}
+
+// Complete reports whether every object has been restored
+func (r *RestoreStatusReport) Complete() bool {
+	return r.Counts[RestoreNotRequested] == 0 && r.Counts[RestoreInProgress] == 0
+}
+
+// RestoreStatus checks the restore state of each object. With the job that
+// requested the restores, the report includes an ETA from the tier's typical
+// restore time.
+func RestoreStatus(ctx context.Context, api s3RestoreAPI, bucket string, objects []ArchivedObject, job *RestoreJob, workers int) (*RestoreStatusReport, error) {
+	if workers < 1 {
+		workers = DefaultRestoreWorkers
+	}
+
+	report := &RestoreStatusReport{Counts: make(map[string]int), Bytes: make(map[string]int64)}
+	var mu sync.Mutex
+	var firstErr error
+	var errOnce sync.Once
+	workerCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	pending := make(chan ArchivedObject)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for object := range pending {
+				output, err := api.HeadObject(workerCtx, &s3.HeadObjectInput{
+					Bucket: aws.String(bucket),
+					Key:    aws.String(object.Key),
+				})
+				if err != nil {
+					errOnce.Do(func() {
+						firstErr = fmt.Errorf("failed to check restore of %s: %w", object.Key, err)
+						cancel()
+					})
+					continue
+				}
+
+				state := parseRestoreHeader(output.Restore)
+				mu.Lock()
+				report.Counts[state]++
+				report.Bytes[state] += object.Size
+				if state == RestoreRestored {
+					report.Ready = append(report.Ready, object)
+				}
+				if state == RestoreInProgress && job != nil {
+					if done := job.RequestedAt.Add(restorePrices[object.StorageClass][job.Tier].Typical); done.After(report.ETA) {
+						report.ETA = done
+					}
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+dispatch:
+	for _, object := range objects {
+		select {
+		case pending <- object:
+		case <-workerCtx.Done():
+			break dispatch
+		}
+	}
+	close(pending)
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	sort.Slice(report.Ready, func(i, j int) bool {
+		return report.Ready[i].Key < report.Ready[j].Key
+	})
+	return report, nil
+}
+
+// restoreDownloader is the subset of S3Manager used to copy restored objects
+type restoreDownloader interface {
+	DownloadFile(ctx context.Context, bucket, key, filePath string, callback ProgressCallback) error
+}
+
+// DownloadRestoredObjects copies restored objects under prefix into dir,
+// keeping their paths relative to the prefix. Files already present with the
+// object's size are skipped, so polling can call this repeatedly. Nothing is
+// downloaded if any key would land outside dir.
+func DownloadRestoredObjects(ctx context.Context, downloader restoreDownloader, bucket, prefix string, objects []ArchivedObject, dir string) (int, error) {
+	targets := make([]string, len(objects))
+	for i, object := range objects {
+		relative, err := restoreRelativePath(prefix, object.Key)
+		if err != nil {
+			return 0, err
+		}
+		targets[i] = localPathForKey(dir, relative)
+	}
+
+	downloaded := 0
+	for i, object := range objects {
+		target := targets[i]
+		if info, err := os.Stat(target); err == nil && info.Size() == object.Size {
+			continue
+		}
+		if err := downloader.DownloadFile(ctx, bucket, object.Key, target, nil); err != nil {
+			return downloaded, fmt.Errorf("failed to download %s: %w", object.Key, err)
+		}
+		downloaded++
+	}
+	return downloaded, nil
+}
+
+// restoreRelativePath maps a key to its path below the download directory.
+// The prefix is trimmed only at a / boundary, so with prefix run the key
+// run/a maps to a but run-2/a keeps its name. Keys that would escape the
+// directory, such as run/../../.bashrc, are rejected.
+func restoreRelativePath(prefix, key string) (string, error) {
+	base := prefix
+	if base != "" && !strings.HasSuffix(base, "/") {
+		if strings.HasPrefix(key, base+"/") {
+			base += "/"
+		} else {
+			base = base[:strings.LastIndex(base, "/")+1]
+		}
+	}
+	relative := strings.TrimPrefix(key, base)
+	if relative == "" {
+		relative = path.Base(key)
+	}
+	if !filepath.IsLocal(filepath.FromSlash(relative)) {
+		return "", fmt.Errorf("object %s is outside the download directory", key)
+	}
+	return relative, nil
+}
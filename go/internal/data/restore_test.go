@@ -0,0 +1,289 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+)
+
+// fakeRestoreAPI serves a paginated listing and tracks restore requests per key
+type fakeRestoreAPI struct {
+	fakeS3Lister
+	mu       sync.Mutex
+	restores map[string]*s3.RestoreObjectInput
+	// restored keys report a completed restore
+	restored map[string]bool
+	failKeys map[string]bool
+}
+
+func (f *fakeRestoreAPI) RestoreObject(ctx context.Context, params *s3.RestoreObjectInput, optFns ...func(*s3.Options)) (*s3.RestoreObjectOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	key := aws.ToString(params.Key)
+	if f.failKeys[key] {
+		return nil, &smithy.GenericAPIError{Code: "AccessDenied", Message: "Access Denied"}
+	}
+	if _, exists := f.restores[key]; exists {
+		return nil, &smithy.GenericAPIError{Code: "RestoreAlreadyInProgress", Message: "Object restore is already in progress"}
+	}
+	f.restores[key] = params
+	return &s3.RestoreObjectOutput{}, nil
+}
+
+func (f *fakeRestoreAPI) HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	key := aws.ToString(params.Key)
+	output := &s3.HeadObjectOutput{}
+	switch {
+	case f.restored[key]:
+		output.Restore = aws.String(`ongoing-request="false", expiry-date="Fri, 21 Dec 2035 00:00:00 GMT"`)
+	case f.restores[key] != nil:
+		output.Restore = aws.String(`ongoing-request="true"`)
+	}
+	return output, nil
+}
+
+func newFakeRestoreAPI() *fakeRestoreAPI {
+	archived := func(key, class string, size int64) s3types.Object {
+		return s3types.Object{Key: aws.String(key), Size: aws.Int64(size), StorageClass: s3types.ObjectStorageClass(class)}
+	}
+	return &fakeRestoreAPI{
+		fakeS3Lister: fakeS3Lister{pages: [][]s3types.Object{
+			{
+				archived("run1/a.bam", "DEEP_ARCHIVE", 4<<30),
+				archived("run1/b.bam", "GLACIER", 2<<30),
+				archived("run1/README", "STANDARD", 100),
+			},
+			{
+				archived("run1/c.bam", "DEEP_ARCHIVE", 4<<30),
+				archived("run1/d.idx", "GLACIER_IR", 10),
+			},
+		}},
+		restores: make(map[string]*s3.RestoreObjectInput),
+		restored: make(map[string]bool),
+		failKeys: make(map[string]bool),
+	}
+}
+
+func TestListArchivedObjects(t *testing.T) {
+	api := newFakeRestoreAPI()
+	objects, err := ListArchivedObjects(context.Background(), api, "lab-data", "run1/")
+	if err != nil {
+		t.Fatalf("ListArchivedObjects() error = %v", err)
+	}
+	if api.requests != 2 || len(objects) != 3 {
+		t.Fatalf("listed %d pages, objects = %+v", api.requests, objects)
+	}
+	if objects[2].Key != "run1/c.bam" || objects[2].StorageClass != "DEEP_ARCHIVE" {
+		t.Errorf("objects[2] = %+v", objects[2])
+	}
+}
+
+func TestEstimateRestore(t *testing.T) {
+	objects := []ArchivedObject{
+		{Key: "a", Size: 1 << 30, StorageClass: "DEEP_ARCHIVE"},
+		{Key: "b", Size: 1 << 30, StorageClass: "GLACIER"},
+	}
+
+	estimate, err := EstimateRestore(objects, RestoreTierStandard, 30)
+	if err != nil {
+		t.Fatalf("EstimateRestore() error = %v", err)
+	}
+	// $0.02 + $0.01 per GB, $0.10 and $0.05 per thousand requests
+	wantRetrieval := 0.02 + 0.01 + 0.0001 + 0.00005
+	if diff := estimate.RetrievalCost - wantRetrieval; diff > 1e-9 || diff < -1e-9 {
+		t.Errorf("RetrievalCost = %f, want %f", estimate.RetrievalCost, wantRetrieval)
+	}
+	if diff := estimate.StorageCost - 2*restoreStandardStoragePerGBMonth; diff > 1e-9 || diff < -1e-9 {
+		t.Errorf("StorageCost = %f", estimate.StorageCost)
+	}
+	if estimate.Typical != 12*time.Hour || estimate.Bytes != 2<<30 {
+		t.Errorf("estimate = %+v", estimate)
+	}
+
+	bulk, _ := EstimateRestore(objects, RestoreTierBulk, 30)
+	if bulk.RetrievalCost >= estimate.RetrievalCost {
+		t.Errorf("bulk retrieval $%f should be cheaper than standard $%f", bulk.RetrievalCost, estimate.RetrievalCost)
+	}
+
+	if _, err := EstimateRestore(objects, RestoreTierExpedited, 7); err == nil || !strings.Contains(err.Error(), "DEEP_ARCHIVE") {
+		t.Errorf("expedited Deep Archive restore error = %v", err)
+	}
+	if _, err := EstimateRestore(objects, RestoreTierBulk, 0); err == nil {
+		t.Error("EstimateRestore() should reject zero days")
+	}
+	if _, err := ValidateRestoreTier("Fast"); err == nil {
+		t.Error("ValidateRestoreTier() should reject an unknown tier")
+	}
+}
+
+func TestRequestRestores(t *testing.T) {
+	ctx := context.Background()
+	api := newFakeRestoreAPI()
+	objects, _ := ListArchivedObjects(ctx, api, "lab-data", "run1/")
+
+	submission, err := RequestRestores(ctx, api, "lab-data", objects, RestoreTierBulk, 7, 2)
+	if err != nil {
+		t.Fatalf("RequestRestores() error = %v", err)
+	}
+	if submission.Requested != 3 || submission.AlreadyInProgress != 0 || len(submission.Failures) != 0 {
+		t.Errorf("submission = %+v", submission)
+	}
+	input := api.restores["run1/a.bam"]
+	if aws.ToInt32(input.RestoreRequest.Days) != 7 || input.RestoreRequest.GlacierJobParameters.Tier != s3types.TierBulk {
+		t.Errorf("RestoreObject input = %+v", input.RestoreRequest)
+	}
+
+	// Resubmitting is idempotent; other errors are reported per object
+	api.failKeys["run1/c.bam"] = true
+	delete(api.restores, "run1/c.bam")
+	submission, err = RequestRestores(ctx, api, "lab-data", objects, RestoreTierBulk, 7, 2)
+	if err != nil {
+		t.Fatalf("RequestRestores() error = %v", err)
+	}
+	if submission.Requested != 0 || submission.AlreadyInProgress != 2 || len(submission.Failures) != 1 || submission.Failures[0].Key != "run1/c.bam" {
+		t.Errorf("resubmission = %+v", submission)
+	}
+}
+
+func TestRestoreStatus(t *testing.T) {
+	ctx := context.Background()
+	api := newFakeRestoreAPI()
+	objects, _ := ListArchivedObjects(ctx, api, "lab-data", "run1/")
+	api.restores["run1/a.bam"] = &s3.RestoreObjectInput{}
+	api.restores["run1/b.bam"] = &s3.RestoreObjectInput{}
+	api.restored["run1/b.bam"] = true
+
+	requestedAt := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	job := &RestoreJob{Bucket: "lab-data", Prefix: "run1/", Tier: RestoreTierBulk, Days: 7, RequestedAt: requestedAt}
+	report, err := RestoreStatus(ctx, api, "lab-data", objects, job, 4)
+	if err != nil {
+		t.Fatalf("RestoreStatus() error = %v", err)
+	}
+	if report.Counts[RestoreRestored] != 1 || report.Counts[RestoreInProgress] != 1 || report.Counts[RestoreNotRequested] != 1 {
+		t.Errorf("counts = %v", report.Counts)
+	}
+	if report.Bytes[RestoreRestored] != 2<<30 || len(report.Ready) != 1 || report.Ready[0].Key != "run1/b.bam" {
+		t.Errorf("report = %+v", report)
+	}
+	// Only the in-progress Deep Archive object counts toward the ETA
+	if !report.ETA.Equal(requestedAt.Add(48 * time.Hour)) {
+		t.Errorf("ETA = %v", report.ETA)
+	}
+	if report.Complete() {
+		t.Error("Complete() = true with restores outstanding")
+	}
+
+	report, _ = RestoreStatus(ctx, api, "lab-data", objects, nil, 4)
+	if !report.ETA.IsZero() {
+		t.Errorf("ETA without a job = %v", report.ETA)
+	}
+}
+
+func TestRestoreJobRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "restores", "job.json")
+	if job, err := LoadRestoreJob(path); err != nil || job != nil {
+		t.Fatalf("LoadRestoreJob() of a missing job = %+v, %v", job, err)
+	}
+
+	saved := &RestoreJob{Bucket: "lab-data", Prefix: "run1/", Tier: RestoreTierStandard, Days: 3, RequestedAt: time.Now().UTC().Truncate(time.Second)}
+	if err := SaveRestoreJob(path, saved); err != nil {
+		t.Fatalf("SaveRestoreJob() error = %v", err)
+	}
+	loaded, err := LoadRestoreJob(path)
+	if err != nil || *loaded != *saved {
+		t.Errorf("LoadRestoreJob() = %+v, %v", loaded, err)
+	}
+}
+
+// fakeDownloader writes placeholder content of each object's size
+type fakeDownloader struct {
+	sizes      map[string]int64
+	downloaded []string
+	err        error
+}
+
+func (f *fakeDownloader) DownloadFile(ctx context.Context, bucket, key, filePath string, callback ProgressCallback) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.downloaded = append(f.downloaded, key)
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(filePath, make([]byte, f.sizes[key]), 0644)
+}
+
+func TestDownloadRestoredObjects(t *testing.T) {
+	dir := t.TempDir()
+	ready := []ArchivedObject{
+		{Key: "run1/a.bam", Size: 8},
+		{Key: "run1/sub/b.bam", Size: 4},
+	}
+	downloader := &fakeDownloader{sizes: map[string]int64{"run1/a.bam": 8, "run1/sub/b.bam": 4}}
+
+	count, err := DownloadRestoredObjects(context.Background(), downloader, "lab-data", "run1/", ready, dir)
+	if err != nil || count != 2 {
+		t.Fatalf("DownloadRestoredObjects() = %d, %v", count, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "sub", "b.bam")); err != nil {
+		t.Errorf("download not placed relative to the prefix: %v", err)
+	}
+
+	// Files already downloaded are skipped on the next poll
+	downloader.err = errors.New("unexpected download")
+	if count, err := DownloadRestoredObjects(context.Background(), downloader, "lab-data", "run1/", ready, dir); err != nil || count != 0 {
+		t.Errorf("second DownloadRestoredObjects() = %d, %v", count, err)
+	}
+}
+
+func TestRestoreRelativePath(t *testing.T) {
+	tests := []struct {
+		prefix, key, want string
+	}{
+		{"run1/", "run1/a.bam", "a.bam"},
+		{"run1/", "run1/sub/b.bam", "sub/b.bam"},
+		{"run", "run/a.bam", "a.bam"},
+		{"run", "run-2/a.bam", "run-2/a.bam"},
+		{"data/run", "data/run-2/a.bam", "run-2/a.bam"},
+		{"data/run.bam", "data/run.bam", "run.bam"},
+		{"", "a.bam", "a.bam"},
+	}
+	for _, tt := range tests {
+		got, err := restoreRelativePath(tt.prefix, tt.key)
+		if err != nil || got != tt.want {
+			t.Errorf("restoreRelativePath(%q, %q) = %q, %v, want %q", tt.prefix, tt.key, got, err, tt.want)
+		}
+	}
+}
+
+func TestDownloadRestoredObjectsRejectsEscapingKeys(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "restored")
+	ready := []ArchivedObject{
+		{Key: "run1/a.bam", Size: 8},
+		{Key: "run1/../../.bashrc", Size: 4},
+	}
+	downloader := &fakeDownloader{sizes: map[string]int64{"run1/a.bam": 8, "run1/../../.bashrc": 4}}
+
+	count, err := DownloadRestoredObjects(context.Background(), downloader, "lab-data", "run1/", ready, dir)
+	if err == nil || !strings.Contains(err.Error(), "outside the download directory") {
+		t.Fatalf("DownloadRestoredObjects() error = %v, want an outside-the-directory error", err)
+	}
+	if count != 0 || len(downloader.downloaded) != 0 {
+		t.Errorf("downloaded %v before rejecting the escaping key", downloader.downloaded)
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(dir), ".bashrc")); !os.IsNotExist(err) {
+		t.Errorf("file written outside the download directory: %v", err)
+	}
+}
@@ -47,6 +47,9 @@ type S3Manager struct {
 	activeTransfers map[string]*TransferProgress
 }
 
+// DefaultUploadPartSize is the part size S3Manager uploads with unless configured
+const DefaultUploadPartSize int64 = 16 * 1024 * 1024
+
 // S3ManagerConfig holds configuration for S3Manager
 type S3ManagerConfig struct {
 	PartSize    int64 // Default: 16MB
@@ -58,7 +61,7 @@ type S3ManagerConfig struct {
 func NewS3Manager(client *s3.Client, region string, config *S3ManagerConfig) *S3Manager {
 	if config == nil {
 		config = &S3ManagerConfig{
-			PartSize:    16 * 1024 * 1024, // 16MB
+			PartSize:    DefaultUploadPartSize,
 			Concurrency: 10,
 			MaxRetries:  3,
 		}
@@ -88,6 +91,11 @@ func NewS3Manager(client *s3.Client, region string, config *S3ManagerConfig) *S3
 
 // UploadFile uploads a file to S3 with progress tracking and optimization
 func (sm *S3Manager) UploadFile(ctx context.Context, bucket, key, filePath string, callback ProgressCallback) error {
+	return sm.UploadFileWithClass(ctx, bucket, key, filePath, "", callback)
+}
+
+// UploadFileWithClass uploads a file to S3 in a storage class; empty uses the bucket default
+func (sm *S3Manager) UploadFileWithClass(ctx context.Context, bucket, key, filePath, storageClass string, callback ProgressCallback) error {
 	file, err := os.Open(filePath)
 	if err != nil {
 		return fmt.Errorf("failed to open file %s: %w", filePath, err)
@@ -123,11 +131,15 @@ func (sm *S3Manager) UploadFile(ctx context.Context, bucket, key, filePath strin
 		callback: callback,
 	}
 
-	_, err = sm.uploader.Upload(ctx, &s3.PutObjectInput{
+	input := &s3.PutObjectInput{
 		Bucket: aws.String(bucket),
 		Key:    aws.String(key),
 		Body:   progressReader,
-	})
+	}
+	if storageClass != "" {
+		input.StorageClass = types.StorageClass(storageClass)
+	}
+	_, err = sm.uploader.Upload(ctx, input)
 
 	if err != nil {
 		return fmt.Errorf("failed to upload file to s3://%s/%s: %w", bucket, key, err)
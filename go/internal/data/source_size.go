@@ -0,0 +1,112 @@
+package data
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+const (
+	// DefaultSourceSizeObjectLimit bounds how many objects are listed to size a
+	// dataset; Open Data buckets can hold billions
+	DefaultSourceSizeObjectLimit = 100000
+
+	// DefaultSourceSizeMaxAge is how long a measured size is reused
+	DefaultSourceSizeMaxAge = 7 * 24 * time.Hour
+)
+
+// SourceSize is the measured size of a dataset's S3 prefix
+type SourceSize struct {
+	Bucket  string `json:"bucket"`
+	Prefix  string `json:"prefix"`
+	Objects int64  `json:"objects"`
+	Bytes   int64  `json:"bytes"`
+	// Complete is false when listing stopped at the object limit, making the
+	// counts a lower bound
+	Complete   bool      `json:"complete"`
+	MeasuredAt time.Time `json:"measured_at"`
+}
+
+// MeasurePrefix counts the objects and bytes under a prefix, listing at most
+// maxObjects objects
+func MeasurePrefix(ctx context.Context, lister s3.ListObjectsV2APIClient, bucket, prefix string, maxObjects int64) (*SourceSize, error) {
+	if maxObjects <= 0 {
+		maxObjects = DefaultSourceSizeObjectLimit
+	}
+
+	size := &SourceSize{Bucket: bucket, Prefix: prefix, Complete: true}
+	paginator := s3.NewListObjectsV2Paginator(lister, &s3.ListObjectsV2Input{
+		Bucket: aws.String(bucket),
+		Prefix: aws.String(prefix),
+	})
+	for paginator.HasMorePages() {
+		if size.Objects >= maxObjects {
+			size.Complete = false
+			break
+		}
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list s3://%s/%s: %w", bucket, prefix, err)
+		}
+		for _, object := range page.Contents {
+			if size.Objects >= maxObjects {
+				size.Complete = false
+				break
+			}
+			size.Objects++
+			size.Bytes += aws.ToInt64(object.Size)
+		}
+	}
+	size.MeasuredAt = time.Now()
+	return size, nil
+}
+
+// DefaultSourceSizePath returns where the measured size of a prefix is cached
+func DefaultSourceSizePath(bucket, prefix string) string {
+	sum := sha256.Sum256([]byte(bucket + "\x00" + prefix))
+	return filepath.Join(DefaultCacheDirectory(), "sources", hex.EncodeToString(sum[:8])+".json")
+}
+
+// LoadSourceSize reads a cached size, returning nil if there is none or it is
+// older than maxAge
+func LoadSourceSize(path string, maxAge time.Duration) (*SourceSize, error) {
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cached size: %w", err)
+	}
+
+	var size SourceSize
+	if err := json.Unmarshal(content, &size); err != nil {
+		return nil, fmt.Errorf("failed to parse cached size %s: %w", path, err)
+	}
+	if time.Since(size.MeasuredAt) > maxAge {
+		return nil, nil
+	}
+	return &size, nil
+}
+
+// SaveSourceSize caches a measured size
+func SaveSourceSize(path string, size *SourceSize) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	content, err := json.MarshalIndent(size, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode size: %w", err)
+	}
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		return fmt.Errorf("failed to write cached size: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package data
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+func TestMeasurePrefix(t *testing.T) {
+	lister := &fakeS3Lister{pages: [][]s3types.Object{
+		objects("run/", 100, 200, 300),
+		objects("run/", 400, 500),
+	}}
+
+	size, err := MeasurePrefix(context.Background(), lister, "open-data", "run/", 0)
+	if err != nil {
+		t.Fatalf("MeasurePrefix failed: %v", err)
+	}
+	if size.Objects != 5 || size.Bytes != 1500 || !size.Complete {
+		t.Errorf("Unexpected size %+v", size)
+	}
+
+	// The object limit bounds the listing and marks the counts as a lower bound
+	lister.requests = 0
+	size, err = MeasurePrefix(context.Background(), lister, "open-data", "run/", 2)
+	if err != nil {
+		t.Fatalf("MeasurePrefix failed: %v", err)
+	}
+	if size.Objects != 2 || size.Bytes != 300 || size.Complete || lister.requests != 1 {
+		t.Errorf("Unexpected bounded size %+v after %d requests", size, lister.requests)
+	}
+}
+
+func TestSourceSizeCache(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sources", "size.json")
+	if size, err := LoadSourceSize(path, time.Hour); err != nil || size != nil {
+		t.Fatalf("Expected no cached size, got %+v, %v", size, err)
+	}
+
+	measured := &SourceSize{Bucket: "open-data", Prefix: "run/", Objects: 5, Bytes: 1500, Complete: true, MeasuredAt: time.Now().Add(-2 * time.Hour)}
+	if err := SaveSourceSize(path, measured); err != nil {
+		t.Fatalf("SaveSourceSize failed: %v", err)
+	}
+
+	size, err := LoadSourceSize(path, 3*time.Hour)
+	if err != nil || size == nil || size.Bytes != 1500 {
+		t.Errorf("Expected the cached size, got %+v, %v", size, err)
+	}
+	if size, err := LoadSourceSize(path, time.Hour); err != nil || size != nil {
+		t.Errorf("Expected a stale size to be ignored, got %+v, %v", size, err)
+	}
+}
@@ -0,0 +1,381 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"gopkg.in/yaml.v3"
+
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/config"
+)
+
+const (
+	// DefaultStageThroughputMBps is the assumed S3 to EC2 throughput within a region
+	DefaultStageThroughputMBps = 100.0
+
+	// StageHeadroomFraction is the share of free disk space kept free after staging
+	StageHeadroomFraction = 0.05
+
+	// maxCopyObjectBytes is the largest object a single CopyObject call can copy
+	maxCopyObjectBytes = 5 * 1024 * 1024 * 1024
+)
+
+// OpenDataset describes an AWS Open Data dataset from the local registry catalog
+type OpenDataset struct {
+	ID          string   `yaml:"-"`
+	Category    string   `yaml:"-"`
+	Name        string   `yaml:"name"`
+	Description string   `yaml:"description"`
+	Location    string   `yaml:"location"`
+	SizeTB      float64  `yaml:"size_tb"`
+	Format      string   `yaml:"format"`
+	License     string   `yaml:"license"`
+	RegistryURL string   `yaml:"registry_url"`
+	Domains     []string `yaml:"domains"`
+}
+
+// Bucket returns the S3 bucket holding the dataset
+func (d *OpenDataset) Bucket() string {
+	bucket, _, _ := strings.Cut(strings.TrimPrefix(d.Location, "s3://"), "/")
+	return bucket
+}
+
+// Prefix returns the key prefix of the dataset within its bucket
+func (d *OpenDataset) Prefix() string {
+	_, prefix, _ := strings.Cut(strings.TrimPrefix(d.Location, "s3://"), "/")
+	return prefix
+}
+
+// OpenDataCatalog is the registry of AWS Open Data datasets shipped with the configs
+type OpenDataCatalog struct {
+	Datasets []OpenDataset
+}
+
+// OpenDataCatalogPath returns the location of the registry catalog under a config root
+func OpenDataCatalogPath(configRoot string) string {
+	return filepath.Join(configRoot, "configs", "demo_data", "aws_open_data_registry.yaml")
+}
+
+// LoadOpenDataCatalog reads the registry catalog, which groups datasets by category
+func LoadOpenDataCatalog(path string) (*OpenDataCatalog, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read open data catalog: %w", err)
+	}
+
+	var file struct {
+		Datasets map[string]map[string]OpenDataset `yaml:"datasets"`
+	}
+	if err := yaml.Unmarshal(content, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse open data catalog %s: %w", path, err)
+	}
+
+	catalog := &OpenDataCatalog{}
+	for category, datasets := range file.Datasets {
+		for id, dataset := range datasets {
+			dataset.ID = id
+			dataset.Category = category
+			catalog.Datasets = append(catalog.Datasets, dataset)
+		}
+	}
+	sort.Slice(catalog.Datasets, func(i, j int) bool {
+		return catalog.Datasets[i].ID < catalog.Datasets[j].ID
+	})
+
+	return catalog, nil
+}
+
+// DomainDatasets returns the datasets available to a domain: the catalog
+// datasets that list the domain, and the domain pack's data sources. A source
+// naming a bucket is used as declared, filling unset fields from the catalog;
+// one given only by name is looked up in the catalog.
+func (c *OpenDataCatalog) DomainDatasets(domain string, sources []config.DataSource) []OpenDataset {
+	listed := make(map[string]bool, len(sources))
+	declared := make(map[string]OpenDataset)
+	var datasets []OpenDataset
+	for _, source := range sources {
+		if source.Bucket == "" {
+			listed[normalizeDatasetName(source.Name)] = true
+			continue
+		}
+		dataset := c.datasetFromSource(domain, source)
+		declared[dataset.Location] = dataset
+		datasets = append(datasets, dataset)
+	}
+
+	for _, dataset := range c.Datasets {
+		if _, exists := declared[dataset.Location]; exists {
+			continue
+		}
+		if listed[normalizeDatasetName(dataset.Name)] || containsString(dataset.Domains, domain) {
+			datasets = append(datasets, dataset)
+		}
+	}
+	return datasets
+}
+
+// datasetFromSource converts a data source with a bucket to a dataset,
+// starting from the catalog entry at the same location when there is one
+func (c *OpenDataCatalog) datasetFromSource(domain string, source config.DataSource) OpenDataset {
+	location := source.Location()
+	dataset := OpenDataset{ID: normalizeDatasetName(source.Name), Category: "domain", Domains: []string{domain}}
+	for _, candidate := range c.Datasets {
+		if candidate.Location == location {
+			dataset = candidate
+			break
+		}
+	}
+
+	dataset.Location = location
+	if source.Name != "" {
+		dataset.Name = source.Name
+	}
+	if source.Description != "" {
+		dataset.Description = source.Description
+	}
+	if source.SizeTB > 0 {
+		dataset.SizeTB = source.SizeTB
+	}
+	if source.License != "" {
+		dataset.License = source.License
+	}
+	if source.RegistryURL != "" {
+		dataset.RegistryURL = source.RegistryURL
+	}
+	return dataset
+}
+
+// ResolveDataset finds a domain's dataset by catalog ID, bucket name, or display name
+func (c *OpenDataCatalog) ResolveDataset(domain string, sources []config.DataSource, dataset string) (*OpenDataset, error) {
+	wanted := normalizeDatasetName(dataset)
+	if wanted == "" {
+		return nil, fmt.Errorf("dataset name is required")
+	}
+
+	available := c.DomainDatasets(domain, sources)
+	for i := range available {
+		candidate := &available[i]
+		if normalizeDatasetName(candidate.ID) == wanted ||
+			normalizeDatasetName(candidate.Bucket()) == wanted ||
+			normalizeDatasetName(candidate.Name) == wanted {
+			return candidate, nil
+		}
+	}
+
+	ids := make([]string, 0, len(available))
+	for _, candidate := range available {
+		ids = append(ids, candidate.Bucket())
+	}
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("domain '%s' has no datasets in the open data catalog", domain)
+	}
+	return nil, fmt.Errorf("dataset '%s' is not available for domain '%s' (available: %s)", dataset, domain, strings.Join(ids, ", "))
+}
+
+// normalizeDatasetName lowercases a name and drops everything but letters and digits
+func normalizeDatasetName(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// SameDatasetName reports whether two dataset names match, ignoring case and punctuation
+func SameDatasetName(a, b string) bool {
+	return normalizeDatasetName(a) == normalizeDatasetName(b)
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// StageEstimate is the size of an S3 prefix to be staged
+type StageEstimate struct {
+	Bucket     string
+	Prefix     string
+	Objects    int
+	TotalBytes int64
+	// LargestObject is the size of the biggest object seen
+	LargestObject int64
+	// ExceedsLimit is set when listing stopped because the size limit was passed
+	ExceedsLimit bool
+}
+
+// EstimatePrefixSize lists a prefix and sums its object sizes. When limit is positive,
+// listing stops as soon as the total passes it so huge datasets are not listed in full.
+func EstimatePrefixSize(ctx context.Context, lister s3.ListObjectsV2APIClient, bucket, prefix string, limit int64) (*StageEstimate, error) {
+	estimate := &StageEstimate{Bucket: bucket, Prefix: prefix}
+
+	paginator := s3.NewListObjectsV2Paginator(lister, &s3.ListObjectsV2Input{
+		Bucket: aws.String(bucket),
+		Prefix: aws.String(prefix),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list s3://%s/%s: %w", bucket, prefix, err)
+		}
+
+		for _, object := range page.Contents {
+			size := aws.ToInt64(object.Size)
+			estimate.Objects++
+			estimate.TotalBytes += size
+			if size > estimate.LargestObject {
+				estimate.LargestObject = size
+			}
+		}
+
+		if limit > 0 && estimate.TotalBytes > limit {
+			estimate.ExceedsLimit = true
+			break
+		}
+	}
+
+	return estimate, nil
+}
+
+// EstimateTransferDuration returns how long copying bytes takes at a throughput in MB/s
+func EstimateTransferDuration(bytes int64, throughputMBps float64) time.Duration {
+	if throughputMBps <= 0 {
+		throughputMBps = DefaultStageThroughputMBps
+	}
+	seconds := float64(bytes) / (throughputMBps * 1024 * 1024)
+	return time.Duration(seconds * float64(time.Second)).Round(time.Second)
+}
+
+// InsufficientSpaceError reports a stage that would not fit on the instance's disk
+type InsufficientSpaceError struct {
+	Path      string
+	Required  int64
+	Available int64
+}
+
+func (e *InsufficientSpaceError) Error() string {
+	return fmt.Sprintf("staging needs %d bytes but only %d bytes are free on %s (keeping %.0f%% headroom)",
+		e.Required, e.Available, e.Path, StageHeadroomFraction*100)
+}
+
+// CheckFreeSpace refuses a stage that would leave less than the headroom free
+func CheckFreeSpace(path string, required, available int64) error {
+	usable := available - int64(float64(available)*StageHeadroomFraction)
+	if required > usable {
+		return &InsufficientSpaceError{Path: path, Required: required, Available: available}
+	}
+	return nil
+}
+
+// FreeSpaceCommand returns a shell command printing the free bytes of the filesystem
+// holding path, creating the directory first so df has something to inspect
+func FreeSpaceCommand(path string) string {
+	quoted := shellQuote(path)
+	return fmt.Sprintf("mkdir -p %s && df -B1 --output=avail %s", quoted, quoted)
+}
+
+// ParseFreeSpace reads the byte count printed by FreeSpaceCommand
+func ParseFreeSpace(output string) (int64, error) {
+	fields := strings.Fields(output)
+	if len(fields) == 0 {
+		return 0, fmt.Errorf("empty df output")
+	}
+
+	available, err := strconv.ParseInt(fields[len(fields)-1], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("unexpected df output %q: %w", strings.TrimSpace(output), err)
+	}
+	return available, nil
+}
+
+// UsedSpaceCommand returns a shell command printing the bytes stored under path
+func UsedSpaceCommand(path string) string {
+	return fmt.Sprintf("du -sb %s 2>/dev/null | cut -f1", shellQuote(path))
+}
+
+// StageCopyCommand returns the AWS CLI command that copies a prefix to an instance path
+func StageCopyCommand(bucket, prefix, destination string) string {
+	source := fmt.Sprintf("s3://%s/%s", bucket, prefix)
+	return fmt.Sprintf("aws s3 cp --recursive --only-show-errors %s %s", shellQuote(source), shellQuote(destination))
+}
+
+// shellQuote wraps a value in single quotes for a POSIX shell
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'"'"'`) + "'"
+}
+
+// s3CopyAPI is the subset of the S3 API used for bucket to bucket staging
+type s3CopyAPI interface {
+	s3.ListObjectsV2APIClient
+	CopyObject(ctx context.Context, params *s3.CopyObjectInput, optFns ...func(*s3.Options)) (*s3.CopyObjectOutput, error)
+}
+
+// StageProgress reports an in-process copy as it runs
+type StageProgress func(copiedObjects int, copiedBytes int64)
+
+// CopyPrefix copies every object under a source prefix to a destination prefix
+// server-side, reporting progress after each object
+func CopyPrefix(ctx context.Context, api s3CopyAPI, srcBucket, srcPrefix, dstBucket, dstPrefix string, progress StageProgress) error {
+	paginator := s3.NewListObjectsV2Paginator(api, &s3.ListObjectsV2Input{
+		Bucket: aws.String(srcBucket),
+		Prefix: aws.String(srcPrefix),
+	})
+
+	copiedObjects := 0
+	var copiedBytes int64
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to list s3://%s/%s: %w", srcBucket, srcPrefix, err)
+		}
+
+		for _, object := range page.Contents {
+			key := aws.ToString(object.Key)
+			size := aws.ToInt64(object.Size)
+			if size > maxCopyObjectBytes {
+				return fmt.Errorf("s3://%s/%s is larger than 5GB; stage it to an instance instead", srcBucket, key)
+			}
+
+			destinationKey := dstPrefix + strings.TrimPrefix(key, srcPrefix)
+			_, err := api.CopyObject(ctx, &s3.CopyObjectInput{
+				Bucket:     aws.String(dstBucket),
+				Key:        aws.String(destinationKey),
+				CopySource: aws.String(copySource(srcBucket, key)),
+			})
+			if err != nil {
+				return fmt.Errorf("failed to copy s3://%s/%s: %w", srcBucket, key, err)
+			}
+
+			copiedObjects++
+			copiedBytes += size
+			if progress != nil {
+				progress(copiedObjects, copiedBytes)
+			}
+		}
+	}
+
+	return nil
+}
+
+// copySource builds the URL-encoded CopySource value for an object
+func copySource(bucket, key string) string {
+	segments := strings.Split(key, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return bucket + "/" + strings.Join(segments, "/")
+}
@@ -0,0 +1,278 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/config"
+)
+
+// fakeS3Lister serves fixed pages of objects and records how many were requested
+type fakeS3Lister struct {
+	pages    [][]s3types.Object
+	requests int
+	copied   []string
+}
+
+func (f *fakeS3Lister) ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	page := 0
+	if params.ContinuationToken != nil {
+		page = int(aws.ToString(params.ContinuationToken)[0] - '0')
+	}
+	f.requests++
+
+	output := &s3.ListObjectsV2Output{Contents: f.pages[page]}
+	if page+1 < len(f.pages) {
+		output.IsTruncated = aws.Bool(true)
+		output.NextContinuationToken = aws.String(string(rune('0' + page + 1)))
+	}
+	return output, nil
+}
+
+func (f *fakeS3Lister) CopyObject(ctx context.Context, params *s3.CopyObjectInput, optFns ...func(*s3.Options)) (*s3.CopyObjectOutput, error) {
+	f.copied = append(f.copied, aws.ToString(params.CopySource)+" -> "+aws.ToString(params.Key))
+	return &s3.CopyObjectOutput{}, nil
+}
+
+func objects(keyPrefix string, sizes ...int64) []s3types.Object {
+	result := make([]s3types.Object, 0, len(sizes))
+	for i, size := range sizes {
+		result = append(result, s3types.Object{
+			Key:  aws.String(keyPrefix + string(rune('a'+i))),
+			Size: aws.Int64(size),
+		})
+	}
+	return result
+}
+
+const testCatalog = `
+datasets:
+  genomics_bioinformatics:
+    thousandgenomes:
+      name: "1000 Genomes Project"
+      location: "s3://1000genomes/"
+      domains: ["genomics"]
+    gnomad:
+      name: "Genome Aggregation Database"
+      location: "s3://gnomad-public-us-east-1/release/"
+      domains: ["population_genetics"]
+  climate_atmospheric:
+    era5_reanalysis:
+      name: "ERA5 Reanalysis Data"
+      location: "s3://era5-pds/"
+      domains: ["climate_modeling"]
+`
+
+func loadTestCatalog(t *testing.T) *OpenDataCatalog {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "aws_open_data_registry.yaml")
+	if err := os.WriteFile(path, []byte(testCatalog), 0644); err != nil {
+		t.Fatal(err)
+	}
+	catalog, err := LoadOpenDataCatalog(path)
+	if err != nil {
+		t.Fatalf("LoadOpenDataCatalog failed: %v", err)
+	}
+	return catalog
+}
+
+func TestResolveDataset(t *testing.T) {
+	catalog := loadTestCatalog(t)
+	dataSources := []config.DataSource{{Name: "Genome Aggregation Database", Description: "Population genomics variant database"}}
+
+	tests := []struct {
+		name       string
+		domain     string
+		dataset    string
+		wantBucket string
+		wantErr    bool
+	}{
+		{name: "bucket name", domain: "genomics", dataset: "1000genomes", wantBucket: "1000genomes"},
+		{name: "catalog ID", domain: "genomics", dataset: "thousandgenomes", wantBucket: "1000genomes"},
+		{name: "display name", domain: "genomics", dataset: "1000 Genomes Project", wantBucket: "1000genomes"},
+		{name: "listed in aws_data_sources", domain: "genomics", dataset: "gnomad", wantBucket: "gnomad-public-us-east-1"},
+		{name: "other domain", domain: "genomics", dataset: "era5-pds", wantErr: true},
+		{name: "unknown", domain: "genomics", dataset: "nope", wantErr: true},
+		{name: "empty", domain: "genomics", dataset: "  ", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dataset, err := catalog.ResolveDataset(tt.domain, dataSources, tt.dataset)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected error, got dataset %s", dataset.ID)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ResolveDataset failed: %v", err)
+			}
+			if dataset.Bucket() != tt.wantBucket {
+				t.Errorf("Expected bucket %s, got %s", tt.wantBucket, dataset.Bucket())
+			}
+		})
+	}
+
+	gnomad, _ := catalog.ResolveDataset("genomics", dataSources, "gnomad")
+	if gnomad.Prefix() != "release/" {
+		t.Errorf("Expected prefix release/, got %q", gnomad.Prefix())
+	}
+}
+
+func TestDomainDatasetsDeclaredSources(t *testing.T) {
+	catalog := loadTestCatalog(t)
+	sources := []config.DataSource{
+		// Declared at a catalog location: catalog fields fill in what is not set
+		{Name: "gnomAD v4", Bucket: "gnomad-public-us-east-1", Prefix: "release/", License: "ODbL"},
+		// Declared outside the catalog
+		{Name: "Lab Reference Panel", Bucket: "lab-reference", Prefix: "panels/v2", SizeTB: 1.5},
+	}
+
+	datasets := catalog.DomainDatasets("genomics", sources)
+	if len(datasets) != 3 {
+		t.Fatalf("Expected the declared sources and thousandgenomes, got %+v", datasets)
+	}
+	if datasets[0].ID != "gnomad" || datasets[0].Name != "gnomAD v4" || datasets[0].License != "ODbL" || datasets[0].Category != "genomics_bioinformatics" {
+		t.Errorf("Unexpected merged dataset %+v", datasets[0])
+	}
+	if datasets[1].Location != "s3://lab-reference/panels/v2" || datasets[1].SizeTB != 1.5 || datasets[1].Prefix() != "panels/v2" {
+		t.Errorf("Unexpected declared dataset %+v", datasets[1])
+	}
+
+	dataset, err := catalog.ResolveDataset("genomics", sources, "lab-reference")
+	if err != nil || dataset.Name != "Lab Reference Panel" {
+		t.Errorf("ResolveDataset(lab-reference) = %+v, %v", dataset, err)
+	}
+}
+
+func TestEstimatePrefixSize(t *testing.T) {
+	lister := &fakeS3Lister{pages: [][]s3types.Object{
+		objects("phase3/1-", 100, 300),
+		objects("phase3/2-", 500),
+		objects("phase3/3-", 50, 50),
+	}}
+
+	estimate, err := EstimatePrefixSize(context.Background(), lister, "1000genomes", "phase3/", 0)
+	if err != nil {
+		t.Fatalf("EstimatePrefixSize failed: %v", err)
+	}
+	if estimate.Objects != 5 || estimate.TotalBytes != 1000 || estimate.LargestObject != 500 {
+		t.Errorf("Unexpected estimate: %+v", estimate)
+	}
+	if estimate.ExceedsLimit {
+		t.Error("Expected no limit to be applied")
+	}
+	if lister.requests != 3 {
+		t.Errorf("Expected 3 list requests, got %d", lister.requests)
+	}
+}
+
+func TestEstimatePrefixSizeStopsAtLimit(t *testing.T) {
+	lister := &fakeS3Lister{pages: [][]s3types.Object{
+		objects("a-", 400),
+		objects("b-", 400),
+		objects("c-", 400),
+	}}
+
+	estimate, err := EstimatePrefixSize(context.Background(), lister, "bucket", "", 700)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !estimate.ExceedsLimit {
+		t.Error("Expected estimate to exceed the limit")
+	}
+	if lister.requests != 2 {
+		t.Errorf("Expected listing to stop after 2 pages, got %d", lister.requests)
+	}
+}
+
+func TestEstimateTransferDuration(t *testing.T) {
+	if got := EstimateTransferDuration(200*1024*1024*1024, 100); got != 2048*time.Second {
+		t.Errorf("Expected 2048s, got %v", got)
+	}
+	if got := EstimateTransferDuration(100*1024*1024, 0); got != time.Second {
+		t.Errorf("Expected default throughput to give 1s, got %v", got)
+	}
+}
+
+func TestCheckFreeSpace(t *testing.T) {
+	tests := []struct {
+		name      string
+		required  int64
+		available int64
+		wantErr   bool
+	}{
+		{name: "fits", required: 500, available: 1000},
+		{name: "fits within headroom", required: 950, available: 1000},
+		{name: "eats headroom", required: 951, available: 1000, wantErr: true},
+		{name: "larger than disk", required: 5000, available: 1000, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckFreeSpace("/data", tt.required, tt.available)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CheckFreeSpace() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			var spaceErr *InsufficientSpaceError
+			if tt.wantErr && !errors.As(err, &spaceErr) {
+				t.Errorf("Expected InsufficientSpaceError, got %T", err)
+			}
+		})
+	}
+}
+
+func TestParseFreeSpace(t *testing.T) {
+	available, err := ParseFreeSpace("        Avail\n107374182400\n")
+	if err != nil {
+		t.Fatalf("ParseFreeSpace failed: %v", err)
+	}
+	if available != 107374182400 {
+		t.Errorf("Expected 107374182400, got %d", available)
+	}
+
+	if _, err := ParseFreeSpace("df: /data: No such file or directory"); err == nil {
+		t.Error("Expected error for df failure output")
+	}
+}
+
+func TestStageCopyCommandQuotes(t *testing.T) {
+	got := StageCopyCommand("1000genomes", "phase3/it's/", "/data/1000genomes")
+	want := `aws s3 cp --recursive --only-show-errors 's3://1000genomes/phase3/it'"'"'s/' '/data/1000genomes'`
+	if got != want {
+		t.Errorf("Unexpected command:\n got: %s\nwant: %s", got, want)
+	}
+}
+
+func TestCopyPrefix(t *testing.T) {
+	api := &fakeS3Lister{pages: [][]s3types.Object{
+		objects("phase3/", 10, 20),
+		{{Key: aws.String("phase3/a b"), Size: aws.Int64(5)}},
+	}}
+
+	var lastObjects int
+	var lastBytes int64
+	err := CopyPrefix(context.Background(), api, "1000genomes", "phase3/", "my-bucket", "staged/", func(objects int, bytes int64) {
+		lastObjects, lastBytes = objects, bytes
+	})
+	if err != nil {
+		t.Fatalf("CopyPrefix failed: %v", err)
+	}
+
+	if lastObjects != 3 || lastBytes != 35 {
+		t.Errorf("Expected final progress 3 objects/35 bytes, got %d/%d", lastObjects, lastBytes)
+	}
+	want := "1000genomes/phase3/a%20b -> staged/a b"
+	if api.copied[2] != want {
+		t.Errorf("Expected %q, got %q", want, api.copied[2])
+	}
+}
@@ -36,6 +36,10 @@ type SuitcaseConfig struct {
 	SizeThreshold   string   `json:"size_threshold"`   // Bundle files smaller than this
 
 	// Output options
+	Backend         string `json:"backend"`          // "suitcase" (default) or "native"
+	Seekable        bool   `json:"seekable"`         // Native only: uncompressed tar for exact ranged reads
+	Chunked         bool   `json:"chunked"`          // Native only: content-defined groups that re-bundle identically
+	FollowSymlinks  bool   `json:"follow_symlinks"`  // Native only: bundle what links point to instead of the links
 	OutputFormat    string `json:"output_format"`    // "tar", "tar.gz", "zip"
 	OutputDirectory string `json:"output_directory"` // Where to place bundles
 	NamingTemplate  string `json:"naming_template"`  // Bundle naming pattern
@@ -84,6 +88,7 @@ type BundleResult struct {
 	BundleManifest    []BundleManifestEntry  `json:"bundle_manifest"`
 	ProcessingTime    time.Duration          `json:"processing_time"`
 	Metadata          map[string]interface{} `json:"metadata"`
+	Warnings          []string               `json:"warnings,omitempty"`
 }
 
 // BundleManifestEntry describes a single bundle in the result
@@ -96,7 +101,10 @@ type BundleManifestEntry struct {
 	CompressionRatio float64   `json:"compression_ratio"`
 	Files            []string  `json:"files"`
 	Checksum         string    `json:"checksum"`
+	IndexPath        string    `json:"index_path,omitempty"`
 	CreatedAt        time.Time `json:"created_at"`
+	// Links maps each symlink stored in the bundle to its target
+	Links map[string]string `json:"links,omitempty"`
 }
 
 // CostSavingsEstimate estimates the cost savings from bundling
@@ -148,6 +156,11 @@ func NewSuitcaseEngine(config *SuitcaseConfig) *SuitcaseEngine {
 
 // IsAvailable checks if Suitcase is installed and available
 func (se *SuitcaseEngine) IsAvailable(ctx context.Context) error {
+	// The native backend needs nothing outside the binary
+	if se.config.Backend == NativeTarBackend {
+		return nil
+	}
+
 	// Check if Python is available
 	if err := se.checkPython(ctx); err != nil {
 		return fmt.Errorf("python not available: %w", err)
@@ -170,6 +183,10 @@ func (se *SuitcaseEngine) BundleFiles(ctx context.Context, sourcePath string) (*
 		return nil, fmt.Errorf("source path not accessible: %w", err)
 	}
 
+	if se.config.Chunked && se.config.Backend != NativeTarBackend {
+		return nil, fmt.Errorf("chunked bundles require the native backend")
+	}
+
 	// Create output directory
 	outputDir := se.config.OutputDirectory
 	if outputDir == "" {
@@ -185,6 +202,49 @@ func (se *SuitcaseEngine) BundleFiles(ctx context.Context, sourcePath string) (*
 		return nil, fmt.Errorf("failed to analyze source files: %w", err)
 	}
 
+	return se.bundleAnalysis(ctx, sourcePath, outputDir, fileAnalysis, startTime)
+}
+
+// BundleFileList bundles the given files, such as those selected by a sync
+// manifest, instead of walking sourcePath. Every file is bundled whatever its
+// size. Only the native backend bundles a list of files, and OutputDirectory
+// must be set.
+func (se *SuitcaseEngine) BundleFileList(ctx context.Context, sourcePath string, files []FileEntry) (*BundleResult, error) {
+	startTime := time.Now()
+
+	if se.config.Backend != NativeTarBackend {
+		return nil, fmt.Errorf("bundling a list of files requires the native backend")
+	}
+	outputDir := se.config.OutputDirectory
+	if outputDir == "" {
+		return nil, fmt.Errorf("an output directory is required to bundle a list of files")
+	}
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	analysis := &FileAnalysis{
+		SmallFiles:         files,
+		LargeFiles:         make([]FileEntry, 0),
+		SizeDistribution:   make(map[string]int64),
+		FileTypes:          make(map[string]int64),
+		DirectoryStructure: make(map[string]int64),
+	}
+	for _, file := range files {
+		analysis.TotalFiles++
+		analysis.TotalSize += file.Size
+		analysis.FileTypes[file.Extension]++
+		analysis.SizeDistribution[se.getSizeCategory(file.Size)]++
+	}
+	if analysis.TotalFiles > 0 {
+		analysis.AverageSize = analysis.TotalSize / analysis.TotalFiles
+	}
+
+	return se.bundleAnalysis(ctx, sourcePath, outputDir, analysis, startTime)
+}
+
+// bundleAnalysis bundles the small files of an analysis into outputDir
+func (se *SuitcaseEngine) bundleAnalysis(ctx context.Context, sourcePath, outputDir string, fileAnalysis *FileAnalysis, startTime time.Time) (*BundleResult, error) {
 	// Create bundling strategy based on analysis
 	strategy, err := se.createBundlingStrategy(fileAnalysis)
 	if err != nil {
@@ -200,6 +260,7 @@ func (se *SuitcaseEngine) BundleFiles(ctx context.Context, sourcePath string) (*
 	// Calculate final metrics
 	result.ProcessingTime = time.Since(startTime)
 	result.CostSavings = se.calculateCostSavings(fileAnalysis, result)
+	result.Warnings = fileAnalysis.Warnings
 
 	return result, nil
 }
@@ -209,30 +270,21 @@ func (se *SuitcaseEngine) GetProgress() <-chan *BundleProgress {
 	return se.progressChan
 }
 
-// checkPython verifies Python is available
+// checkPython verifies Python 3 is available
 func (se *SuitcaseEngine) checkPython(ctx context.Context) error {
-	cmd := exec.CommandContext(ctx, "python3", "--version")
-	if err := cmd.Run(); err != nil {
-		// Try python instead of python3
-		cmd = exec.CommandContext(ctx, "python", "--version")
-		if err := cmd.Run(); err != nil {
-			return fmt.Errorf("python not found in PATH")
-		}
-	}
-	return nil
+	_, err := FindPython(ctx)
+	return err
 }
 
 // checkSuitcase verifies Suitcase is installed
 func (se *SuitcaseEngine) checkSuitcase(ctx context.Context) error {
-	cmd := exec.CommandContext(ctx, "python3", "-c", "import suitcase; print(suitcase.__version__)")
+	cmd, err := pythonExec(ctx, "-c", "import suitcase; print(suitcase.__version__)")
+	if err != nil {
+		return err
+	}
 	output, err := cmd.CombinedOutput()
 	if err != nil {
-		// Try with python
-		cmd = exec.CommandContext(ctx, "python", "-c", "import suitcase; print(suitcase.__version__)")
-		output, err = cmd.CombinedOutput()
-		if err != nil {
-			return fmt.Errorf("suitcase not installed. Install with: pip install suitcase")
-		}
+		return fmt.Errorf("suitcase not installed. Install with: pip install suitcase")
 	}
 
 	version := strings.TrimSpace(string(output))
@@ -253,6 +305,7 @@ type FileAnalysis struct {
 	SizeDistribution   map[string]int64
 	FileTypes          map[string]int64
 	DirectoryStructure map[string]int64
+	Warnings           []string // Files that were skipped, such as named pipes
 }
 
 // FileEntry represents a file to be processed
@@ -262,6 +315,7 @@ type FileEntry struct {
 	ModTime      time.Time
 	Extension    string
 	RelativePath string
+	LinkTarget   string // Set for a symlink bundled as a link rather than followed
 }
 
 // analyzeSourceFiles analyzes the source directory to create optimal bundling strategy
@@ -279,13 +333,34 @@ func (se *SuitcaseEngine) analyzeSourceFiles(ctx context.Context, sourcePath str
 		sizeThreshold = 1024 * 1024 // Default 1MB
 	}
 
-	err = filepath.WalkDir(sourcePath, func(path string, d os.DirEntry, err error) error {
+	// The real paths of directories being walked are tracked so that following
+	// a link back into one of them is caught instead of looping forever
+	root, err := filepath.EvalSymlinks(sourcePath)
+	if err != nil {
+		root = sourcePath
+	}
+	err = se.walkSourceFiles(analysis, sourcePath, "", []string{root}, sizeThreshold)
+
+	if analysis.TotalFiles > 0 {
+		analysis.AverageSize = analysis.TotalSize / analysis.TotalFiles
+	}
+
+	return analysis, err
+}
+
+// walkSourceFiles adds the files under dir to the analysis, naming them relative
+// to relDir. Symlinks are recorded as links unless FollowSymlinks is set, and
+// named pipes, sockets and devices are skipped with a warning.
+func (se *SuitcaseEngine) walkSourceFiles(analysis *FileAnalysis, dir, relDir string, walking []string, sizeThreshold int64) error {
+	return filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
 		if err != nil {
 			return nil // Skip files with errors
 		}
 
+		relPath, _ := filepath.Rel(dir, path)
+		relPath = filepath.Join(relDir, relPath)
+
 		if d.IsDir() {
-			relPath, _ := filepath.Rel(sourcePath, path)
 			analysis.DirectoryStructure[relPath]++
 			return nil
 		}
@@ -300,40 +375,104 @@ func (se *SuitcaseEngine) analyzeSourceFiles(ctx context.Context, sourcePath str
 			return nil
 		}
 
-		relPath, _ := filepath.Rel(sourcePath, path)
+		var linkTarget string
+		if info.Mode()&os.ModeSymlink != 0 {
+			linkTarget, err = os.Readlink(path)
+			if err != nil {
+				analysis.warn("skipped %s: %v", relPath, err)
+				return nil
+			}
+			if se.config.FollowSymlinks {
+				resolved, err := os.Stat(path)
+				switch {
+				case err != nil:
+					analysis.warn("%s: cannot follow link to %s, bundled as a link", relPath, linkTarget)
+				case resolved.IsDir():
+					realDir, err := filepath.EvalSymlinks(path)
+					if err != nil {
+						analysis.warn("skipped %s: %v", relPath, err)
+						return nil
+					}
+					realParent, err := filepath.EvalSymlinks(filepath.Dir(path))
+					if err != nil {
+						realParent = filepath.Dir(path)
+					}
+					if containsPath(realDir, append(walking[:len(walking):len(walking)], realParent)) {
+						analysis.warn("skipped %s: link to %s leads back into a directory being bundled", relPath, linkTarget)
+						return nil
+					}
+					return se.walkSourceFiles(analysis, realDir, relPath, append(walking[:len(walking):len(walking)], realDir), sizeThreshold)
+				default:
+					info, linkTarget = resolved, ""
+				}
+			}
+		}
+		if linkTarget == "" && !info.Mode().IsRegular() {
+			analysis.warn("skipped %s: %s", relPath, specialFileKind(info.Mode()))
+			return nil
+		}
+
 		ext := strings.ToLower(filepath.Ext(path))
+		size := info.Size()
+		if linkTarget != "" {
+			size = 0 // A link is stored as its target name, without data
+		}
 
 		entry := FileEntry{
 			Path:         path,
-			Size:         info.Size(),
+			Size:         size,
 			ModTime:      info.ModTime(),
 			Extension:    ext,
 			RelativePath: relPath,
+			LinkTarget:   linkTarget,
 		}
 
 		analysis.TotalFiles++
-		analysis.TotalSize += info.Size()
+		analysis.TotalSize += size
 		analysis.FileTypes[ext]++
 
 		// Categorize by size
-		if info.Size() <= sizeThreshold {
+		if size <= sizeThreshold {
 			analysis.SmallFiles = append(analysis.SmallFiles, entry)
 		} else {
 			analysis.LargeFiles = append(analysis.LargeFiles, entry)
 		}
 
 		// Size distribution buckets
-		sizeCategory := se.getSizeCategory(info.Size())
+		sizeCategory := se.getSizeCategory(size)
 		analysis.SizeDistribution[sizeCategory]++
 
 		return nil
 	})
+}
 
-	if analysis.TotalFiles > 0 {
-		analysis.AverageSize = analysis.TotalSize / analysis.TotalFiles
+// warn records a file the analysis skipped or could not handle as asked
+func (fa *FileAnalysis) warn(format string, args ...interface{}) {
+	fa.Warnings = append(fa.Warnings, fmt.Sprintf(format, args...))
+}
+
+// specialFileKind names a file type that cannot be bundled
+func specialFileKind(mode os.FileMode) string {
+	switch {
+	case mode&os.ModeNamedPipe != 0:
+		return "named pipe"
+	case mode&os.ModeSocket != 0:
+		return "socket"
+	case mode&os.ModeDevice != 0:
+		return "device"
+	default:
+		return "not a regular file"
 	}
+}
 
-	return analysis, err
+// containsPath reports whether path is one of dirs or a directory above one of them
+func containsPath(path string, dirs []string) bool {
+	for _, dir := range dirs {
+		if dir == path || strings.HasPrefix(dir, strings.TrimSuffix(path, string(filepath.Separator))+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
 }
 
 // BundlingStrategy defines how files should be bundled
@@ -362,16 +501,19 @@ func (se *SuitcaseEngine) createBundlingStrategy(analysis *FileAnalysis) (*Bundl
 		Metadata:     make(map[string]interface{}),
 	}
 
-	targetBundleSize, err := se.parseSize(se.config.TargetBundleSize)
-	if err != nil {
-		targetBundleSize = 100 * 1024 * 1024 // Default 100MB
-	}
+	targetBundleSize := se.targetBundleSize()
 
 	// Apply domain-specific optimizations
 	se.applyDomainOptimizations(strategy, analysis)
 
-	// Group small files by type and directory for optimal bundling
-	bundleGroups := se.groupFilesForBundling(analysis.SmallFiles, targetBundleSize)
+	// Group small files by type and directory for optimal bundling, or into
+	// chunks that stay stable as files change
+	var bundleGroups []BundleGroup
+	if se.config.Chunked {
+		bundleGroups = se.groupFilesIntoChunks(analysis.SmallFiles, targetBundleSize)
+	} else {
+		bundleGroups = se.groupFilesForBundling(analysis.SmallFiles, targetBundleSize)
+	}
 	strategy.BundleGroups = bundleGroups
 
 	// Set compression mode based on file types
@@ -488,7 +630,7 @@ func (se *SuitcaseEngine) groupFilesForBundling(files []FileEntry, targetSize in
 	// Group by directory and file type
 	dirGroups := make(map[string][]FileEntry)
 	for _, file := range sortedFiles {
-		dir := filepath.Dir(file.RelativePath)
+		dir := safeNameComponent(filepath.Dir(file.RelativePath))
 		ext := file.Extension
 		key := fmt.Sprintf("%s|%s", dir, ext)
 		dirGroups[key] = append(dirGroups[key], file)
@@ -544,6 +686,10 @@ func (se *SuitcaseEngine) groupFilesForBundling(files []FileEntry, targetSize in
 
 // createBundle creates a single bundle file
 func (se *SuitcaseEngine) createBundle(ctx context.Context, group BundleGroup, outputPath string, progress *BundleProgress) (*BundleManifestEntry, error) {
+	if se.config.Backend == NativeTarBackend {
+		return se.createNativeBundle(group, outputPath)
+	}
+
 	startTime := time.Now()
 
 	// Create temporary file list
@@ -559,12 +705,15 @@ func (se *SuitcaseEngine) createBundle(ctx context.Context, group BundleGroup, o
 	filePaths := make([]string, 0, len(group.Files))
 	for _, file := range group.Files {
 		fmt.Fprintln(fileList, file.Path)
-		filePaths = append(filePaths, file.RelativePath)
+		filePaths = append(filePaths, filepath.ToSlash(file.RelativePath))
 	}
 	fileList.Close()
 
 	// Build suitcase command
-	cmd := se.buildSuitcaseCommand(ctx, fileListPath, outputPath)
+	cmd, err := se.buildSuitcaseCommand(ctx, fileListPath, outputPath)
+	if err != nil {
+		return nil, err
+	}
 
 	// Execute command with progress monitoring
 	if err := se.runCommandWithProgress(cmd, progress); err != nil {
@@ -598,20 +747,102 @@ func (se *SuitcaseEngine) createBundle(ctx context.Context, group BundleGroup, o
 	return manifest, nil
 }
 
+// createNativeBundle writes a bundle with archive/tar and an index sidecar
+// next to it, so members can later be fetched individually with ranged reads
+func (se *SuitcaseEngine) createNativeBundle(group BundleGroup, outputPath string) (*BundleManifestEntry, error) {
+	startTime := time.Now()
+
+	bundleFile, err := os.Create(outputPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create bundle file: %w", err)
+	}
+	defer bundleFile.Close()
+
+	index, err := WriteTarBundle(bundleFile, group.Files, se.nativeSeekable(), se.config.CompressionLevel, se.config.PreserveMetadata)
+	if err != nil {
+		return nil, err
+	}
+	if err := bundleFile.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write bundle file: %w", err)
+	}
+	index.Bundle = filepath.Base(outputPath)
+	if se.config.Chunked {
+		// Chunked bundles are compared across runs, so the index must not vary either
+		index.CreatedAt = chunkModTime(group.Files).UTC()
+		if err := os.Chtimes(outputPath, time.Now(), chunkModTime(group.Files)); err != nil {
+			return nil, fmt.Errorf("failed to set bundle time: %w", err)
+		}
+	}
+
+	indexPath := BundleIndexPath(outputPath)
+	if err := WriteBundleIndex(indexPath, index); err != nil {
+		return nil, err
+	}
+
+	bundleInfo, err := os.Stat(outputPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat bundle file: %w", err)
+	}
+	checksum, err := se.calculateChecksum(outputPath)
+	if err != nil {
+		checksum = "unknown"
+	}
+
+	filePaths := make([]string, 0, len(index.Entries))
+	for _, entry := range index.Entries {
+		filePaths = append(filePaths, entry.Path)
+	}
+
+	var links map[string]string
+	if indexLinks := index.Links(); len(indexLinks) > 0 {
+		links = indexLinks
+	}
+
+	compressionRatio := 0.0
+	if group.ExpectedSize > 0 {
+		compressionRatio = float64(bundleInfo.Size()) / float64(group.ExpectedSize)
+	}
+
+	return &BundleManifestEntry{
+		BundleName:       filepath.Base(outputPath),
+		BundlePath:       outputPath,
+		FileCount:        int64(len(group.Files)),
+		Size:             bundleInfo.Size(),
+		OriginalSize:     group.ExpectedSize,
+		CompressionRatio: compressionRatio,
+		Files:            filePaths,
+		Checksum:         checksum,
+		IndexPath:        indexPath,
+		CreatedAt:        startTime,
+		Links:            links,
+	}, nil
+}
+
+// nativeSeekable reports whether native bundles are written as plain tar, which
+// is always the case when seekable bundles are requested or tar is the format
+func (se *SuitcaseEngine) nativeSeekable() bool {
+	return se.config.Seekable || se.config.OutputFormat == "tar"
+}
+
 // Helper functions
 
 func (se *SuitcaseEngine) parseSize(sizeStr string) (int64, error) {
 	sizeStr = strings.ToUpper(strings.TrimSpace(sizeStr))
 
-	multipliers := map[string]int64{
-		"B":  1,
-		"KB": 1024,
-		"MB": 1024 * 1024,
-		"GB": 1024 * 1024 * 1024,
-		"TB": 1024 * 1024 * 1024 * 1024,
+	// "B" is last so that it does not match the end of the other suffixes
+	multipliers := []struct {
+		suffix     string
+		multiplier int64
+	}{
+		{"KB", 1024},
+		{"MB", 1024 * 1024},
+		{"GB", 1024 * 1024 * 1024},
+		{"TB", 1024 * 1024 * 1024 * 1024},
+		{"B", 1},
 	}
 
-	for suffix, multiplier := range multipliers {
+	for _, m := range multipliers {
+		suffix, multiplier := m.suffix, m.multiplier
 		if strings.HasSuffix(sizeStr, suffix) {
 			numStr := strings.TrimSuffix(sizeStr, suffix)
 			num, err := strconv.ParseFloat(numStr, 64)
@@ -721,9 +952,21 @@ func (se *SuitcaseEngine) generateBundleName(index int, groupName string) string
 	bundleName := strings.ReplaceAll(template, "{index:04d}", fmt.Sprintf("%04d", index))
 	bundleName = strings.ReplaceAll(bundleName, "{timestamp}", timestamp)
 	bundleName = strings.ReplaceAll(bundleName, "{group}", groupName)
+	if se.config.Chunked {
+		// A chunk keeps its name for as long as its members are the same
+		bundleName = groupName
+	}
 
-	// Add appropriate extension
-	switch se.config.OutputFormat {
+	// Add appropriate extension; the native backend writes tar or tar.gz only
+	format := se.config.OutputFormat
+	if se.config.Backend == NativeTarBackend {
+		format = "tar.gz"
+		if se.nativeSeekable() {
+			format = "tar"
+		}
+	}
+
+	switch format {
 	case "tar":
 		bundleName += ".tar"
 	case "tar.gz":
@@ -737,7 +980,7 @@ func (se *SuitcaseEngine) generateBundleName(index int, groupName string) string
 	return bundleName
 }
 
-func (se *SuitcaseEngine) buildSuitcaseCommand(ctx context.Context, fileListPath, outputPath string) *exec.Cmd {
+func (se *SuitcaseEngine) buildSuitcaseCommand(ctx context.Context, fileListPath, outputPath string) (*exec.Cmd, error) {
 	args := []string{
 		"-m", "suitcase",
 		"pack",
@@ -768,7 +1011,7 @@ func (se *SuitcaseEngine) buildSuitcaseCommand(ctx context.Context, fileListPath
 		args = append(args, "--metadata", fmt.Sprintf("%s=%s", key, value))
 	}
 
-	return exec.CommandContext(ctx, "python3", args...)
+	return pythonExec(ctx, args...)
 }
 
 func (se *SuitcaseEngine) runCommandWithProgress(cmd *exec.Cmd, progress *BundleProgress) error {
@@ -861,25 +1104,7 @@ func (se *SuitcaseEngine) monitorProgress(reader io.Reader, progress *BundleProg
 
 func (se *SuitcaseEngine) calculateChecksum(filePath string) (string, error) {
 	// Calculate MD5 checksum of the bundle file
-	file, err := os.Open(filePath)
-	if err != nil {
-		return "", err
-	}
-	defer file.Close()
-
-	cmd := exec.Command("md5sum", filePath)
-	output, err := cmd.Output()
-	if err != nil {
-		return "", err
-	}
-
-	// Extract just the hash part
-	parts := strings.Fields(string(output))
-	if len(parts) > 0 {
-		return parts[0], nil
-	}
-
-	return "", fmt.Errorf("could not parse checksum output")
+	return FileMD5(filePath)
 }
 
 func (se *SuitcaseEngine) calculateCostSavings(analysis *FileAnalysis, result *BundleResult) CostSavingsEstimate {
@@ -943,7 +1168,10 @@ func (se *SuitcaseEngine) ExtractBundle(ctx context.Context, bundlePath, outputD
 		args = append(args, "--preserve-metadata")
 	}
 
-	cmd := exec.CommandContext(ctx, "python3", args...)
+	cmd, err := pythonExec(ctx, args...)
+	if err != nil {
+		return err
+	}
 
 	// Execute command
 	output, err := cmd.CombinedOutput()
@@ -963,7 +1191,10 @@ func (se *SuitcaseEngine) ListBundleContents(ctx context.Context, bundlePath str
 		"--json", // Get machine-readable output
 	}
 
-	cmd := exec.CommandContext(ctx, "python3", args...)
+	cmd, err := pythonExec(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
 	output, err := cmd.Output()
 	if err != nil {
 		return nil, fmt.Errorf("failed to list bundle contents: %w", err)
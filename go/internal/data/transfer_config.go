@@ -494,7 +494,7 @@ func (cm *ConfigManager) createDefaultConfig() *TransferConfig {
 		},
 
 		Storage: StorageConfig{
-			TempDirectory:     "/tmp/aws-research-wizard",
+			TempDirectory:     DefaultWorkingDirectory(),
 			CleanupInterval:   1 * time.Hour,
 			MaxTempSize:       "10GB",
 			MetadataDirectory: "~/.aws-research-wizard/metadata",
@@ -502,7 +502,7 @@ func (cm *ConfigManager) createDefaultConfig() *TransferConfig {
 			ResumeDirectory:   "~/.aws-research-wizard/resume",
 			ResumeRetention:   7 * 24 * time.Hour, // 7 days
 			EnableCache:       true,
-			CacheDirectory:    "~/.aws-research-wizard/cache",
+			CacheDirectory:    DefaultCacheDirectory(),
 			CacheSize:         "1GB",
 			CacheTTL:          24 * time.Hour,
 		},
@@ -565,7 +565,7 @@ func expandPath(path, homeDir string) string {
 		if len(path) == 1 {
 			return homeDir
 		}
-		if path[1] == '/' {
+		if path[1] == '/' || path[1] == filepath.Separator {
 			return filepath.Join(homeDir, path[2:])
 		}
 	}
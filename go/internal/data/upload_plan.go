@@ -0,0 +1,301 @@
+package data
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+const (
+	// S3 multipart limits
+	minUploadPartSize int64 = 8 * 1024 * 1024
+	maxUploadParts    int64 = 10000
+	maxObjectSize     int64 = 5 * 1024 * 1024 * 1024 * 1024
+
+	// perStreamBandwidth is what one upload stream typically sustains to S3
+	// over a wide-area link, in bits per second
+	perStreamBandwidth = 100e6
+
+	minUploadConcurrency   = 4
+	maxUploadConcurrency   = 64
+	smallFileConcurrency   = 32
+	defaultRequestOverhead = 50 * time.Millisecond
+
+	// requestOverheadWarnShare is the share of the estimate spent on
+	// per-request overhead above which bundling is suggested
+	requestOverheadWarnShare = 0.5
+
+	// offlineTransferThreshold is the upload time above which an offline
+	// transfer device is worth considering
+	offlineTransferThreshold = 7 * 24 * time.Hour
+)
+
+// UploadPlanOptions describe the link an upload will run over
+type UploadPlanOptions struct {
+	// Bandwidth is the usable upload bandwidth in bits per second
+	Bandwidth float64
+	// BandwidthSource records where the bandwidth figure came from
+	BandwidthSource string
+	// RequestOverhead is the fixed latency of each S3 request (default 50ms)
+	RequestOverhead time.Duration
+}
+
+// UploadPlan is an upload time estimate with recommended transfer settings
+type UploadPlan struct {
+	TotalFiles      int64   `json:"total_files"`
+	TotalBytes      int64   `json:"total_bytes"`
+	SmallFiles      int64   `json:"small_files"`
+	LargestFile     int64   `json:"largest_file"`
+	Bandwidth       float64 `json:"bandwidth_bps"`
+	BandwidthSource string  `json:"bandwidth_source"`
+
+	PartSize    int64 `json:"part_size"`
+	Concurrency int   `json:"concurrency"`
+	Requests    int64 `json:"requests"`
+
+	TransferTime  time.Duration `json:"transfer_time"`
+	OverheadTime  time.Duration `json:"overhead_time"`
+	EstimatedTime time.Duration `json:"estimated_time"`
+
+	// BundledTime is the estimate after bundling small files, when bundling is suggested
+	BundledTime      time.Duration `json:"bundled_time,omitempty"`
+	BundleCount      int64         `json:"bundle_count,omitempty"`
+	RecommendsBundle bool          `json:"recommends_bundling"`
+
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// OverheadShare is the fraction of the estimate spent on per-request overhead
+func (p *UploadPlan) OverheadShare() float64 {
+	if p.EstimatedTime <= 0 {
+		return 0
+	}
+	return float64(p.OverheadTime) / float64(p.EstimatedTime)
+}
+
+// PlanUpload estimates how long uploading a dataset will take and picks the
+// multipart part size and concurrency for it
+func PlanUpload(pattern *DataPattern, opts UploadPlanOptions) (*UploadPlan, error) {
+	if opts.Bandwidth <= 0 {
+		return nil, fmt.Errorf("bandwidth must be positive")
+	}
+	if opts.RequestOverhead <= 0 {
+		opts.RequestOverhead = defaultRequestOverhead
+	}
+
+	small := pattern.FileSizes.SmallFiles
+	plan := &UploadPlan{
+		TotalFiles:      pattern.TotalFiles,
+		TotalBytes:      pattern.TotalSize,
+		SmallFiles:      small.CountUnder1MB,
+		LargestFile:     pattern.FileSizes.MaxSize,
+		Bandwidth:       opts.Bandwidth,
+		BandwidthSource: opts.BandwidthSource,
+	}
+	plan.PartSize = choosePartSize(opts.Bandwidth, plan.LargestFile)
+	plan.Concurrency = chooseConcurrency(opts.Bandwidth, plan.TotalFiles, plan.SmallFiles)
+
+	largeBytes := plan.TotalBytes - small.SizeUnder1MB
+	plan.Requests = uploadRequests(plan.TotalFiles, largeBytes, plan.PartSize)
+	plan.TransferTime = transferTime(plan.TotalBytes, opts.Bandwidth)
+	plan.OverheadTime = overheadTime(plan.Requests, plan.Concurrency, opts.RequestOverhead)
+	plan.EstimatedTime = plan.TransferTime + plan.OverheadTime
+
+	if plan.SmallFiles > 0 && plan.OverheadShare() > requestOverheadWarnShare {
+		plan.RecommendsBundle = true
+		plan.BundleCount = estimateBundleCount(pattern, DefaultBundleTargetSize)
+		objects := plan.TotalFiles - plan.SmallFiles + plan.BundleCount
+		requests := uploadRequests(objects, plan.TotalBytes, plan.PartSize)
+		plan.BundledTime = plan.TransferTime + overheadTime(requests, plan.Concurrency, opts.RequestOverhead)
+		plan.Warnings = append(plan.Warnings, fmt.Sprintf(
+			"Per-request overhead is %.0f%% of the estimate: bundling the %d small files into about %d bundles would cut the upload to about %s (see 'data bundle create')",
+			plan.OverheadShare()*100, plan.SmallFiles, plan.BundleCount, FormatPlanDuration(plan.BundledTime)))
+	}
+	if plan.LargestFile > maxObjectSize {
+		plan.Warnings = append(plan.Warnings, fmt.Sprintf(
+			"The largest file (%s) exceeds the 5TB S3 object limit and must be split before upload",
+			formatBytes(plan.LargestFile)))
+	}
+	if plan.EstimatedTime > offlineTransferThreshold {
+		plan.Warnings = append(plan.Warnings, fmt.Sprintf(
+			"The upload would take more than %d days; an AWS Snowball device may be faster",
+			int(offlineTransferThreshold.Hours()/24)))
+	}
+
+	return plan, nil
+}
+
+// choosePartSize picks larger parts for faster links, doubling as needed so
+// the largest file fits in S3's 10,000 part limit
+func choosePartSize(bandwidth float64, largestFile int64) int64 {
+	partSize := minUploadPartSize
+	switch {
+	case bandwidth >= 10e9:
+		partSize = 128 * 1024 * 1024
+	case bandwidth >= 1e9:
+		partSize = 64 * 1024 * 1024
+	case bandwidth >= 250e6:
+		partSize = 16 * 1024 * 1024
+	}
+	for largestFile > partSize*maxUploadParts {
+		partSize *= 2
+	}
+	return partSize
+}
+
+// chooseConcurrency runs enough streams to fill the link; small files are
+// latency bound, so datasets made mostly of them get more streams
+func chooseConcurrency(bandwidth float64, files, smallFiles int64) int {
+	concurrency := int(math.Ceil(bandwidth / perStreamBandwidth))
+	if files > 0 && smallFiles*2 > files {
+		concurrency = max(concurrency, smallFileConcurrency)
+	}
+	return min(max(concurrency, minUploadConcurrency), maxUploadConcurrency)
+}
+
+// uploadRequests counts one request per object plus one per part of the
+// bytes that are large enough to be uploaded in parts
+func uploadRequests(objects, multipartBytes, partSize int64) int64 {
+	parts := int64(0)
+	if multipartBytes > 0 {
+		parts = (multipartBytes + partSize - 1) / partSize
+	}
+	return objects + parts
+}
+
+func transferTime(totalBytes int64, bandwidth float64) time.Duration {
+	return time.Duration(float64(totalBytes) * 8 / bandwidth * float64(time.Second))
+}
+
+func overheadTime(requests int64, concurrency int, perRequest time.Duration) time.Duration {
+	return time.Duration(requests) * perRequest / time.Duration(concurrency)
+}
+
+// UploadCommand is the command that runs the upload with the plan's settings
+func (p *UploadPlan) UploadCommand(source, target string) string {
+	return fmt.Sprintf("aws-research-wizard data upload %s %s --concurrency %d --part-size %dMB",
+		shellQuote(source), shellQuote(target), p.Concurrency, p.PartSize/(1024*1024))
+}
+
+// ParseBandwidth parses a link speed such as "500Mbps", "10Gbps" or
+// "100MB/s" into bits per second. A capital B means bytes; units are
+// decimal, as link speeds are.
+func ParseBandwidth(value string) (float64, error) {
+	text := strings.TrimSpace(value)
+	lower := strings.ToLower(text)
+
+	bitsPerUnit := 1.0
+	switch {
+	case strings.HasSuffix(text, "B/s"):
+		text = text[:len(text)-3]
+		bitsPerUnit = 8
+	case strings.HasSuffix(lower, "bps"), strings.HasSuffix(lower, "b/s"):
+		text = text[:len(text)-3]
+	default:
+		return 0, fmt.Errorf("bandwidth %q needs a unit such as Mbps or MB/s", value)
+	}
+
+	scales := map[byte]float64{'k': 1e3, 'm': 1e6, 'g': 1e9, 't': 1e12}
+	if text != "" {
+		if scale, ok := scales[strings.ToLower(text[len(text)-1:])[0]]; ok {
+			bitsPerUnit *= scale
+			text = text[:len(text)-1]
+		}
+	}
+
+	number, err := strconv.ParseFloat(strings.TrimSpace(text), 64)
+	if err != nil || number <= 0 {
+		return 0, fmt.Errorf("invalid bandwidth %q", value)
+	}
+	return number * bitsPerUnit, nil
+}
+
+// FormatBandwidth renders bits per second in the largest whole unit
+func FormatBandwidth(bps float64) string {
+	for _, unit := range []struct {
+		scale float64
+		name  string
+	}{{1e12, "Tbps"}, {1e9, "Gbps"}, {1e6, "Mbps"}, {1e3, "Kbps"}} {
+		if bps >= unit.scale {
+			return fmt.Sprintf("%.1f %s", bps/unit.scale, unit.name)
+		}
+	}
+	return fmt.Sprintf("%.0f bps", bps)
+}
+
+// FormatPlanDuration renders an estimate at a useful precision
+func FormatPlanDuration(d time.Duration) string {
+	switch {
+	case d >= 48*time.Hour:
+		return fmt.Sprintf("%.1f days", d.Hours()/24)
+	case d >= time.Hour:
+		return fmt.Sprintf("%.1f hours", d.Hours())
+	case d >= time.Minute:
+		return fmt.Sprintf("%.0f minutes", d.Minutes())
+	}
+	return d.Round(time.Second).String()
+}
+
+// s3ProbeAPI is the subset of the S3 API used to measure upload bandwidth
+type s3ProbeAPI interface {
+	PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+	DeleteObject(ctx context.Context, params *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error)
+}
+
+// MeasureUploadBandwidth uploads streams probe objects of objectSize bytes in
+// parallel under prefix, returns the aggregate bits per second achieved, and
+// deletes the probe objects again
+func MeasureUploadBandwidth(ctx context.Context, api s3ProbeAPI, bucket, prefix string, streams int, objectSize int64) (float64, error) {
+	if streams < 1 || objectSize < 1 {
+		return 0, fmt.Errorf("probe needs at least one stream and one byte")
+	}
+
+	keys := make([]string, streams)
+	stamp := time.Now().UnixNano()
+	for i := range keys {
+		keys[i] = path.Join(prefix, fmt.Sprintf(".aws-research-wizard-probe-%d-%d", stamp, i))
+	}
+	defer func() {
+		for _, key := range keys {
+			api.DeleteObject(context.Background(), &s3.DeleteObjectInput{Bucket: aws.String(bucket), Key: aws.String(key)})
+		}
+	}()
+
+	payload := make([]byte, objectSize)
+	errs := make([]error, streams)
+	var wg sync.WaitGroup
+	start := time.Now()
+	for i, key := range keys {
+		wg.Add(1)
+		go func(i int, key string) {
+			defer wg.Done()
+			_, errs[i] = api.PutObject(ctx, &s3.PutObjectInput{
+				Bucket:        aws.String(bucket),
+				Key:           aws.String(key),
+				Body:          bytes.NewReader(payload),
+				ContentLength: aws.Int64(objectSize),
+			})
+		}(i, key)
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	for _, err := range errs {
+		if err != nil {
+			return 0, fmt.Errorf("bandwidth probe upload failed: %w", err)
+		}
+	}
+	if elapsed <= 0 {
+		elapsed = time.Nanosecond
+	}
+	return float64(objectSize*int64(streams)) * 8 / elapsed.Seconds(), nil
+}
@@ -0,0 +1,193 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+func TestPlanUploadLargeFiles(t *testing.T) {
+	// 8TB of 1GB files over a 500Mbps campus link
+	pattern := skewedPattern(map[string][2]int64{".bam": {8192, gib}})
+	pattern.FileSizes.MaxSize = gib
+
+	plan, err := PlanUpload(pattern, UploadPlanOptions{Bandwidth: 500e6})
+	if err != nil {
+		t.Fatalf("PlanUpload() error = %v", err)
+	}
+	if plan.PartSize != 16*1024*1024 || plan.Concurrency != 5 {
+		t.Errorf("part size = %d, concurrency = %d", plan.PartSize, plan.Concurrency)
+	}
+	// 8TiB at 500Mbps is about 39 hours on the wire plus about 1.5 hours of request overhead
+	if hours := plan.EstimatedTime.Hours(); hours < 40 || hours > 41 {
+		t.Errorf("estimated time = %s", plan.EstimatedTime)
+	}
+	if plan.RecommendsBundle || len(plan.Warnings) != 0 {
+		t.Errorf("large files should not need warnings: %v", plan.Warnings)
+	}
+
+	want := "aws-research-wizard data upload '/data/run 1' 's3://bucket/run1' --concurrency 5 --part-size 16MB"
+	if got := plan.UploadCommand("/data/run 1", "s3://bucket/run1"); got != want {
+		t.Errorf("UploadCommand() = %s", got)
+	}
+}
+
+func TestPlanUploadSmallFilesRecommendsBundling(t *testing.T) {
+	pattern := skewedPattern(map[string][2]int64{".json": {1_000_000, 4096}})
+	pattern.FileSizes.MaxSize = 4096
+
+	plan, err := PlanUpload(pattern, UploadPlanOptions{Bandwidth: 1e9})
+	if err != nil {
+		t.Fatalf("PlanUpload() error = %v", err)
+	}
+	if plan.Concurrency != smallFileConcurrency {
+		t.Errorf("concurrency = %d, want %d for small files", plan.Concurrency, smallFileConcurrency)
+	}
+	if plan.OverheadShare() < 0.9 {
+		t.Errorf("overhead share = %.2f, want request overhead to dominate", plan.OverheadShare())
+	}
+	if !plan.RecommendsBundle || plan.BundleCount != 40 {
+		t.Fatalf("recommends bundling = %v with %d bundles", plan.RecommendsBundle, plan.BundleCount)
+	}
+	if plan.BundledTime >= plan.EstimatedTime/10 {
+		t.Errorf("bundled time %s should be far below %s", plan.BundledTime, plan.EstimatedTime)
+	}
+	if len(plan.Warnings) != 1 || !strings.Contains(plan.Warnings[0], "data bundle create") {
+		t.Errorf("warnings = %v", plan.Warnings)
+	}
+}
+
+func TestPlanUploadWarnings(t *testing.T) {
+	pattern := skewedPattern(map[string][2]int64{".raw": {10, 6 * 1024 * gib}})
+	pattern.FileSizes.MaxSize = 6 * 1024 * gib
+
+	plan, err := PlanUpload(pattern, UploadPlanOptions{Bandwidth: 100e6})
+	if err != nil {
+		t.Fatalf("PlanUpload() error = %v", err)
+	}
+	warnings := strings.Join(plan.Warnings, "\n")
+	if !strings.Contains(warnings, "5TB S3 object limit") || !strings.Contains(warnings, "Snowball") {
+		t.Errorf("warnings = %v", plan.Warnings)
+	}
+
+	if _, err := PlanUpload(pattern, UploadPlanOptions{}); err == nil {
+		t.Error("PlanUpload() should require a bandwidth")
+	}
+}
+
+func TestChoosePartSize(t *testing.T) {
+	tests := []struct {
+		bandwidth float64
+		largest   int64
+		want      int64
+	}{
+		{50e6, gib, 8 * 1024 * 1024},
+		{300e6, gib, 16 * 1024 * 1024},
+		{1e9, gib, 64 * 1024 * 1024},
+		{25e9, gib, 128 * 1024 * 1024},
+		// 2TB needs 256MB parts to stay within 10,000 parts
+		{50e6, 2 * 1024 * gib, 256 * 1024 * 1024},
+	}
+	for _, tt := range tests {
+		if got := choosePartSize(tt.bandwidth, tt.largest); got != tt.want {
+			t.Errorf("choosePartSize(%g, %d) = %d, want %d", tt.bandwidth, tt.largest, got, tt.want)
+		}
+		if tt.largest > choosePartSize(tt.bandwidth, tt.largest)*maxUploadParts {
+			t.Errorf("part size for %d bytes exceeds the part limit", tt.largest)
+		}
+	}
+}
+
+func TestParseBandwidth(t *testing.T) {
+	tests := []struct {
+		value string
+		want  float64
+	}{
+		{"500Mbps", 500e6},
+		{"10 Gbps", 10e9},
+		{"1.5gbps", 1.5e9},
+		{"100MB/s", 800e6},
+		{"100Mb/s", 100e6},
+		{"64kbps", 64e3},
+	}
+	for _, tt := range tests {
+		got, err := ParseBandwidth(tt.value)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseBandwidth(%q) = %g, %v, want %g", tt.value, got, err, tt.want)
+		}
+	}
+	for _, value := range []string{"", "500", "fast", "-1Mbps", "0Gbps"} {
+		if _, err := ParseBandwidth(value); err == nil {
+			t.Errorf("ParseBandwidth(%q) should fail", value)
+		}
+	}
+}
+
+func TestFormatPlanDuration(t *testing.T) {
+	tests := map[time.Duration]string{
+		45 * time.Second:        "45s",
+		90 * time.Minute:        "1.5 hours",
+		72 * time.Hour:          "3.0 days",
+		20 * time.Minute:        "20 minutes",
+		1500 * time.Millisecond: "2s",
+	}
+	for d, want := range tests {
+		if got := FormatPlanDuration(d); got != want {
+			t.Errorf("FormatPlanDuration(%s) = %s, want %s", d, got, want)
+		}
+	}
+}
+
+// probeStore records probe uploads and deletes
+type probeStore struct {
+	mu      sync.Mutex
+	puts    map[string]int64
+	deletes []string
+	failPut bool
+}
+
+func (p *probeStore) PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	if p.failPut {
+		return nil, errors.New("access denied")
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.puts[*params.Key] = *params.ContentLength
+	return &s3.PutObjectOutput{}, nil
+}
+
+func (p *probeStore) DeleteObject(ctx context.Context, params *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.deletes = append(p.deletes, *params.Key)
+	return &s3.DeleteObjectOutput{}, nil
+}
+
+func TestMeasureUploadBandwidth(t *testing.T) {
+	store := &probeStore{puts: make(map[string]int64)}
+	bandwidth, err := MeasureUploadBandwidth(context.Background(), store, "bucket", "incoming", 4, 1024)
+	if err != nil {
+		t.Fatalf("MeasureUploadBandwidth() error = %v", err)
+	}
+	if bandwidth <= 0 || len(store.puts) != 4 || len(store.deletes) != 4 {
+		t.Errorf("bandwidth = %g, puts = %d, deletes = %d", bandwidth, len(store.puts), len(store.deletes))
+	}
+	for key, size := range store.puts {
+		if !strings.HasPrefix(key, "incoming/.aws-research-wizard-probe-") || size != 1024 {
+			t.Errorf("probe object %s of %d bytes", key, size)
+		}
+	}
+
+	failing := &probeStore{puts: make(map[string]int64), failPut: true}
+	if _, err := MeasureUploadBandwidth(context.Background(), failing, "bucket", "", 2, 1024); err == nil {
+		t.Error("MeasureUploadBandwidth() should fail when uploads fail")
+	}
+	if len(failing.deletes) != 2 {
+		t.Errorf("probe objects should be cleaned up after a failure, deleted %d", len(failing.deletes))
+	}
+}
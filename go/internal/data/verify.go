@@ -0,0 +1,757 @@
+package data
+
+import (
+	"bufio"
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// Verification statuses
+const (
+	VerifyOK           = "ok"
+	VerifyMissing      = "missing"
+	VerifyExtra        = "extra"
+	VerifyMismatch     = "mismatch"
+	VerifyUnverifiable = "unverifiable"
+)
+
+// DefaultVerifyWorkers is the number of files checksummed concurrently
+const DefaultVerifyWorkers = 8
+
+const mebibyte = 1024 * 1024
+
+// multipartPartSizes are the part sizes tried when matching a multipart ETag: the
+// SDK upload manager (5MB), AWS CLI (8MB) and S3Manager (16MB) defaults, and the
+// round sizes tools commonly use for large files
+var multipartPartSizes = []int64{
+	5 * mebibyte, 8 * mebibyte, 16 * mebibyte, 32 * mebibyte, 64 * mebibyte,
+	100 * mebibyte, 128 * mebibyte, 256 * mebibyte, 512 * mebibyte, 1024 * mebibyte,
+}
+
+// RemoteObject is the integrity metadata S3 reports for an object
+type RemoteObject struct {
+	Key  string
+	Size int64
+	ETag string
+	// HasSHA256 is set when the listing shows a SHA-256 checksum, which only
+	// HeadObject returns
+	HasSHA256 bool
+	// ChecksumSHA256 is the base64 SHA-256 when the object was uploaded with one
+	ChecksumSHA256 string
+	ChecksumType   string
+	// Encryption is the server-side encryption, which decides whether the ETag is an MD5
+	Encryption string
+}
+
+// LocalChecksums are the digests of a local file needed to compare it with S3
+type LocalChecksums struct {
+	Size   int64
+	SHA256 string
+	MD5    string
+	// PartETags maps a part size to the multipart ETag the file would have
+	PartETags map[int64]string
+}
+
+// ParseETag splits an S3 ETag into its hex digest and multipart part count, which
+// is zero for single-part uploads
+func ParseETag(etag string) (string, int, bool) {
+	etag = strings.Trim(etag, `"`)
+	digest, count, multipart := strings.Cut(etag, "-")
+	if len(digest) != 32 {
+		return "", 0, false
+	}
+	if _, err := hex.DecodeString(digest); err != nil {
+		return "", 0, false
+	}
+	if !multipart {
+		return strings.ToLower(digest), 0, true
+	}
+
+	parts, err := strconv.Atoi(count)
+	if err != nil || parts < 1 {
+		return "", 0, false
+	}
+	return strings.ToLower(digest), parts, true
+}
+
+// MultipartETag builds the ETag S3 assigns to a multipart upload: the MD5 of the
+// concatenated binary part MD5s, followed by the part count
+func MultipartETag(partDigests [][]byte) string {
+	hash := md5.New()
+	for _, digest := range partDigests {
+		hash.Write(digest)
+	}
+	return fmt.Sprintf("%s-%d", hex.EncodeToString(hash.Sum(nil)), len(partDigests))
+}
+
+// CandidatePartSizes returns the part sizes that split an object of the given size
+// into exactly the given number of parts. Common tool defaults are tried first,
+// followed by the size/parts quotient rounded up to a whole MiB.
+func CandidatePartSizes(size int64, parts int) []int64 {
+	if parts < 1 || size < 0 {
+		return nil
+	}
+
+	var candidates []int64
+	add := func(partSize int64) {
+		if partSize <= 0 || partCount(size, partSize) != parts {
+			return
+		}
+		for _, existing := range candidates {
+			if existing == partSize {
+				return
+			}
+		}
+		candidates = append(candidates, partSize)
+	}
+
+	for _, partSize := range multipartPartSizes {
+		add(partSize)
+	}
+	quotient := (size + int64(parts) - 1) / int64(parts)
+	add((quotient + mebibyte - 1) / mebibyte * mebibyte)
+	if parts == 1 {
+		add(size)
+	}
+	return candidates
+}
+
+// partCount is the number of parts a multipart upload of size bytes would use
+func partCount(size, partSize int64) int {
+	if size == 0 {
+		return 1
+	}
+	return int((size + partSize - 1) / partSize)
+}
+
+// partHasher accumulates the per-part MD5s of a stream for one part size
+type partHasher struct {
+	partSize int64
+	written  int64
+	current  hash.Hash
+	digests  [][]byte
+}
+
+func (ph *partHasher) Write(p []byte) (int, error) {
+	total := len(p)
+	for len(p) > 0 {
+		if ph.current == nil {
+			ph.current = md5.New()
+		}
+		chunk := ph.partSize - ph.written
+		if int64(len(p)) < chunk {
+			chunk = int64(len(p))
+		}
+		ph.current.Write(p[:chunk])
+		ph.written += chunk
+		p = p[chunk:]
+		if ph.written == ph.partSize {
+			ph.finishPart()
+		}
+	}
+	return total, nil
+}
+
+func (ph *partHasher) finishPart() {
+	ph.digests = append(ph.digests, ph.current.Sum(nil))
+	ph.current = nil
+	ph.written = 0
+}
+
+func (ph *partHasher) etag() string {
+	if ph.current != nil || len(ph.digests) == 0 {
+		if ph.current == nil {
+			ph.current = md5.New()
+		}
+		ph.finishPart()
+	}
+	return MultipartETag(ph.digests)
+}
+
+// ComputeLocalChecksums streams a file once, computing its SHA-256, MD5 and the
+// multipart ETag it would have for each of the given part sizes
+func ComputeLocalChecksums(path string, partSizes []int64) (*LocalChecksums, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	md := md5.New()
+	writers := []io.Writer{md}
+	hashers := make([]*partHasher, 0, len(partSizes))
+	for _, partSize := range partSizes {
+		ph := &partHasher{partSize: partSize}
+		hashers = append(hashers, ph)
+		writers = append(writers, ph)
+	}
+
+	size, sha, err := copySHA256(io.MultiWriter(writers...), file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	checksums := &LocalChecksums{
+		Size:      size,
+		SHA256:    base64.StdEncoding.EncodeToString(sha),
+		MD5:       hex.EncodeToString(md.Sum(nil)),
+		PartETags: make(map[int64]string, len(hashers)),
+	}
+	for _, ph := range hashers {
+		checksums.PartETags[ph.partSize] = ph.etag()
+	}
+	return checksums, nil
+}
+
+// etagIsMD5 reports whether S3 computes the object's ETag from its content; objects
+// encrypted with KMS or customer keys get opaque ETags
+func (r *RemoteObject) etagIsMD5() bool {
+	return r.Encryption == "" || r.Encryption == string(s3types.ServerSideEncryptionAes256)
+}
+
+// fullObjectSHA256 returns the object's whole-file SHA-256, if S3 has one
+func (r *RemoteObject) fullObjectSHA256() (string, bool) {
+	if r.ChecksumSHA256 == "" || r.ChecksumType == string(s3types.ChecksumTypeComposite) || strings.Contains(r.ChecksumSHA256, "-") {
+		return "", false
+	}
+	return r.ChecksumSHA256, true
+}
+
+// CompareChecksums decides whether a local file matches an S3 object, preferring the
+// full-object SHA-256 and falling back to the ETag. A multipart ETag that matches
+// no candidate part size is a mismatch when DefaultUploadPartSize or a recorded
+// part size fits its part count, and unverifiable otherwise.
+func CompareChecksums(local *LocalChecksums, remote *RemoteObject, recorded ...int64) (string, string) {
+	if local.Size != remote.Size {
+		return VerifyMismatch, fmt.Sprintf("size differs: local %d bytes, S3 %d bytes", local.Size, remote.Size)
+	}
+
+	if checksum, ok := remote.fullObjectSHA256(); ok {
+		if checksum == local.SHA256 {
+			return VerifyOK, "SHA-256 matches"
+		}
+		return VerifyMismatch, "SHA-256 differs"
+	}
+
+	if !remote.etagIsMD5() {
+		return VerifyUnverifiable, fmt.Sprintf("size matches; ETag of %s-encrypted objects is not a content hash", remote.Encryption)
+	}
+
+	digest, parts, ok := ParseETag(remote.ETag)
+	if !ok {
+		return VerifyUnverifiable, fmt.Sprintf("size matches; unrecognized ETag %s", remote.ETag)
+	}
+	if parts == 0 {
+		if digest == local.MD5 {
+			return VerifyOK, "MD5 ETag matches"
+		}
+		return VerifyMismatch, "MD5 ETag differs"
+	}
+
+	candidates := matchingPartSizes(local.Size, parts, recorded)
+	if len(candidates) == 0 {
+		return VerifyUnverifiable, fmt.Sprintf("size matches; no part size splits the file into %d parts", parts)
+	}
+	want := fmt.Sprintf("%s-%d", digest, parts)
+	for _, partSize := range candidates {
+		if local.PartETags[partSize] == want {
+			return VerifyOK, fmt.Sprintf("multipart ETag matches with %s parts", formatBytes(partSize))
+		}
+	}
+	// The part size this tool uploads with fits, so the content differs
+	for _, partSize := range append([]int64{DefaultUploadPartSize}, recorded...) {
+		if slices.Contains(candidates, partSize) {
+			return VerifyMismatch, fmt.Sprintf("multipart ETag differs with %s parts", formatBytes(partSize))
+		}
+	}
+	// A changed file and an upload with a part size not guessed look the same
+	return VerifyUnverifiable, fmt.Sprintf("size matches; multipart ETag matches no candidate part size (%d parts), so the part size used is unknown", parts)
+}
+
+// matchingPartSizes adds the recorded part sizes that split an object into the
+// given number of parts to its candidate part sizes
+func matchingPartSizes(size int64, parts int, recorded []int64) []int64 {
+	candidates := CandidatePartSizes(size, parts)
+	for _, partSize := range recorded {
+		if partSize > 0 && partCount(size, partSize) == parts && !slices.Contains(candidates, partSize) {
+			candidates = append(candidates, partSize)
+		}
+	}
+	return candidates
+}
+
+// partSizesFor returns the part sizes a local file must be hashed with to check a remote object
+func partSizesFor(remote *RemoteObject, recorded ...int64) []int64 {
+	if _, ok := remote.fullObjectSHA256(); ok || !remote.etagIsMD5() {
+		return nil
+	}
+	_, parts, ok := ParseETag(remote.ETag)
+	if !ok || parts == 0 {
+		return nil
+	}
+	return matchingPartSizes(remote.Size, parts, recorded)
+}
+
+// s3VerifyAPI is the subset of the S3 API used to verify uploads
+type s3VerifyAPI interface {
+	s3.ListObjectsV2APIClient
+	HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error)
+}
+
+// VerifyDiscrepancy is a file or object that did not verify cleanly
+type VerifyDiscrepancy struct {
+	Status     string `json:"status"`
+	Key        string `json:"key"`
+	Path       string `json:"path,omitempty"`
+	LocalSize  int64  `json:"local_size,omitempty"`
+	RemoteSize int64  `json:"remote_size,omitempty"`
+	Detail     string `json:"detail"`
+}
+
+// VerifyReport summarizes a verification run
+type VerifyReport struct {
+	Source        string              `json:"source"`
+	Bucket        string              `json:"bucket"`
+	Prefix        string              `json:"prefix"`
+	Files         int                 `json:"files"`
+	Verified      int                 `json:"verified"`
+	Resumed       int                 `json:"resumed"`
+	Bytes         int64               `json:"bytes"`
+	Counts        map[string]int      `json:"counts"`
+	Discrepancies []VerifyDiscrepancy `json:"discrepancies"`
+	StartedAt     time.Time           `json:"started_at"`
+	CompletedAt   time.Time           `json:"completed_at"`
+}
+
+// Failed reports whether any file is missing from S3 or differs from its object
+func (r *VerifyReport) Failed() bool {
+	return r.Counts[VerifyMissing] > 0 || r.Counts[VerifyMismatch] > 0
+}
+
+// VerifyProgress reports how many local files have been checked
+type VerifyProgress func(checkedFiles, totalFiles int)
+
+// VerifyOptions configures a verification run
+type VerifyOptions struct {
+	// Workers bounds how many files are checksummed at once
+	Workers int
+	// Journal records verified files so an interrupted run can resume
+	Journal  *VerifyJournal
+	Progress VerifyProgress
+	// PartSize is the part size the files were uploaded with, when known
+	PartSize int64
+}
+
+// verifyJob is a local file and the key it should have been uploaded to
+type verifyJob struct {
+	path    string
+	key     string
+	size    int64
+	modTime time.Time
+}
+
+// VerifyUpload compares every file under source with the objects under an S3 prefix
+// and reports missing, extra, mismatched and unverifiable entries
+func VerifyUpload(ctx context.Context, api s3VerifyAPI, source, bucket, prefix string, opts VerifyOptions) (*VerifyReport, error) {
+	if opts.Workers < 1 {
+		opts.Workers = DefaultVerifyWorkers
+	}
+
+	report := &VerifyReport{
+		Source:    source,
+		Bucket:    bucket,
+		Prefix:    prefix,
+		Counts:    make(map[string]int),
+		StartedAt: time.Now(),
+	}
+
+	jobs, err := collectVerifyJobs(source, prefix)
+	if err != nil {
+		return nil, err
+	}
+	report.Files = len(jobs)
+
+	remote, err := listRemoteObjects(ctx, api, bucket, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	var mu sync.Mutex
+	checked := 0
+	record := func(job verifyJob, status, detail string, remoteSize int64) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		report.Counts[status]++
+		report.Bytes += job.size
+		if status != VerifyOK {
+			report.Discrepancies = append(report.Discrepancies, VerifyDiscrepancy{
+				Status:     status,
+				Key:        job.key,
+				Path:       job.path,
+				LocalSize:  job.size,
+				RemoteSize: remoteSize,
+				Detail:     detail,
+			})
+		}
+		checked++
+		if opts.Progress != nil {
+			opts.Progress(checked, len(jobs))
+		}
+	}
+
+	pending := make(chan verifyJob)
+	var firstErr error
+	var errOnce sync.Once
+	workerCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for i := 0; i < opts.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range pending {
+				status, detail, remoteSize, err := verifyFile(workerCtx, api, bucket, job, remote[job.key], opts.PartSize)
+				if err != nil {
+					errOnce.Do(func() {
+						firstErr = err
+						cancel()
+					})
+					continue
+				}
+				if status == VerifyOK && opts.Journal != nil {
+					if err := opts.Journal.Record(job.key, job.size, job.modTime); err != nil {
+						errOnce.Do(func() {
+							firstErr = err
+							cancel()
+						})
+					}
+				}
+				record(job, status, detail, remoteSize)
+			}
+		}()
+	}
+
+dispatch:
+	for _, job := range jobs {
+		if opts.Journal != nil && opts.Journal.Verified(job.key, job.size, job.modTime) {
+			if object, exists := remote[job.key]; exists && object.Size == job.size {
+				mu.Lock()
+				report.Resumed++
+				mu.Unlock()
+				record(job, VerifyOK, "verified in a previous run", object.Size)
+				continue
+			}
+		}
+		select {
+		case pending <- job:
+		case <-workerCtx.Done():
+			break dispatch
+		}
+	}
+	close(pending)
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	local := make(map[string]bool, len(jobs))
+	for _, job := range jobs {
+		local[job.key] = true
+	}
+	for key, object := range remote {
+		if !local[key] {
+			report.Counts[VerifyExtra]++
+			report.Discrepancies = append(report.Discrepancies, VerifyDiscrepancy{
+				Status:     VerifyExtra,
+				Key:        key,
+				RemoteSize: object.Size,
+				Detail:     "object has no matching local file",
+			})
+		}
+	}
+
+	sort.Slice(report.Discrepancies, func(i, j int) bool {
+		return report.Discrepancies[i].Key < report.Discrepancies[j].Key
+	})
+	report.Verified = report.Counts[VerifyOK]
+	report.CompletedAt = time.Now()
+	return report, nil
+}
+
+// verifyFile checksums one local file against its object
+func verifyFile(ctx context.Context, api s3VerifyAPI, bucket string, job verifyJob, object *RemoteObject, partSize int64) (string, string, int64, error) {
+	if object == nil {
+		return VerifyMissing, "no object at this key", 0, nil
+	}
+	if object.Size != job.size {
+		return VerifyMismatch, fmt.Sprintf("size differs: local %d bytes, S3 %d bytes", job.size, object.Size), object.Size, nil
+	}
+
+	headed := false
+	if object.HasSHA256 {
+		detailed, err := headObject(ctx, api, bucket, object)
+		if err != nil {
+			return "", "", 0, err
+		}
+		object, headed = detailed, true
+	}
+
+	local, err := ComputeLocalChecksums(job.path, partSizesFor(object, partSize))
+	if err != nil {
+		return "", "", 0, fmt.Errorf("failed to checksum %s: %w", job.path, err)
+	}
+
+	status, detail := CompareChecksums(local, object, partSize)
+	if status == VerifyMismatch && !headed {
+		// The listing does not show encryption; a KMS-encrypted object has an
+		// opaque ETag rather than a corrupt one
+		detailed, err := headObject(ctx, api, bucket, object)
+		if err != nil {
+			return "", "", 0, err
+		}
+		if !detailed.etagIsMD5() {
+			status, detail = CompareChecksums(local, detailed, partSize)
+		}
+	}
+	return status, detail, object.Size, nil
+}
+
+// headObject fetches the checksum and encryption metadata the listing omits
+func headObject(ctx context.Context, api s3VerifyAPI, bucket string, object *RemoteObject) (*RemoteObject, error) {
+	head, err := api.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket:       aws.String(bucket),
+		Key:          aws.String(object.Key),
+		ChecksumMode: s3types.ChecksumModeEnabled,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get metadata for s3://%s/%s: %w", bucket, object.Key, err)
+	}
+
+	detailed := *object
+	detailed.ChecksumSHA256 = aws.ToString(head.ChecksumSHA256)
+	detailed.ChecksumType = string(head.ChecksumType)
+	detailed.Encryption = string(head.ServerSideEncryption)
+	if head.SSECustomerAlgorithm != nil {
+		detailed.Encryption = "customer-key"
+	}
+	return &detailed, nil
+}
+
+// collectVerifyJobs walks source and maps each regular file to its expected key
+func collectVerifyJobs(source, prefix string) ([]verifyJob, error) {
+	info, err := os.Stat(source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read source: %w", err)
+	}
+	if !info.IsDir() {
+		key := prefix
+		if key == "" || strings.HasSuffix(key, "/") {
+			key += filepath.Base(source)
+		}
+		return []verifyJob{{path: source, key: key, size: info.Size(), modTime: info.ModTime()}}, nil
+	}
+
+	keyPrefix := prefix
+	if keyPrefix != "" && !strings.HasSuffix(keyPrefix, "/") {
+		keyPrefix += "/"
+	}
+
+	var jobs []verifyJob
+	err = filepath.WalkDir(source, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		relative, err := filepath.Rel(source, path)
+		if err != nil {
+			return err
+		}
+		jobs = append(jobs, verifyJob{
+			path:    path,
+			key:     keyPrefix + filepath.ToSlash(relative),
+			size:    info.Size(),
+			modTime: info.ModTime(),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk %s: %w", source, err)
+	}
+	return jobs, nil
+}
+
+// listRemoteObjects indexes every object under a prefix by key
+func listRemoteObjects(ctx context.Context, api s3.ListObjectsV2APIClient, bucket, prefix string) (map[string]*RemoteObject, error) {
+	paginator := s3.NewListObjectsV2Paginator(api, &s3.ListObjectsV2Input{
+		Bucket: aws.String(bucket),
+		Prefix: aws.String(prefix),
+	})
+
+	objects := make(map[string]*RemoteObject)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list s3://%s/%s: %w", bucket, prefix, err)
+		}
+		for _, object := range page.Contents {
+			key := aws.ToString(object.Key)
+			if strings.HasSuffix(key, "/") {
+				continue
+			}
+			remote := &RemoteObject{
+				Key:  key,
+				Size: aws.ToInt64(object.Size),
+				ETag: aws.ToString(object.ETag),
+			}
+			for _, algorithm := range object.ChecksumAlgorithm {
+				if algorithm == s3types.ChecksumAlgorithmSha256 {
+					remote.HasSHA256 = true
+				}
+			}
+			objects[key] = remote
+		}
+	}
+	return objects, nil
+}
+
+// verifyJournalEntry is one line of a verification journal
+type verifyJournalEntry struct {
+	Key     string `json:"key"`
+	Size    int64  `json:"size"`
+	ModTime int64  `json:"mod_time"`
+}
+
+// VerifyJournal records files that verified successfully, one JSON object per line,
+// so a later run can skip them while the local file is unchanged
+type VerifyJournal struct {
+	path     string
+	mu       sync.Mutex
+	file     *os.File
+	verified map[string]verifyJournalEntry
+}
+
+// OpenVerifyJournal loads an existing journal and opens it for appending
+func OpenVerifyJournal(path string) (*VerifyJournal, error) {
+	journal := &VerifyJournal{path: path, verified: make(map[string]verifyJournalEntry)}
+
+	if existing, err := os.Open(path); err == nil {
+		scanner := bufio.NewScanner(existing)
+		for scanner.Scan() {
+			var entry verifyJournalEntry
+			// A line cut short by an interrupted run is ignored
+			if json.Unmarshal(scanner.Bytes(), &entry) == nil && entry.Key != "" {
+				journal.verified[entry.Key] = entry
+			}
+		}
+		existing.Close()
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read journal %s: %w", path, err)
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("failed to open journal %s: %w", path, err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create journal directory: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open journal %s: %w", path, err)
+	}
+	journal.file = file
+	return journal, nil
+}
+
+// Path returns the journal file location
+func (j *VerifyJournal) Path() string {
+	return j.path
+}
+
+// Len returns the number of files recorded as verified
+func (j *VerifyJournal) Len() int {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return len(j.verified)
+}
+
+// Verified reports whether a file with this size and modification time was verified before
+func (j *VerifyJournal) Verified(key string, size int64, modTime time.Time) bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	entry, exists := j.verified[key]
+	return exists && entry.Size == size && entry.ModTime == modTime.UnixNano()
+}
+
+// Record appends a verified file to the journal
+func (j *VerifyJournal) Record(key string, size int64, modTime time.Time) error {
+	entry := verifyJournalEntry{Key: key, Size: size, ModTime: modTime.UnixNano()}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if _, err := j.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write journal %s: %w", j.path, err)
+	}
+	j.verified[key] = entry
+	return nil
+}
+
+// Close closes the journal file
+func (j *VerifyJournal) Close() error {
+	return j.file.Close()
+}
+
+// Remove closes and deletes the journal once a run has finished cleanly
+func (j *VerifyJournal) Remove() error {
+	j.Close()
+	if err := os.Remove(j.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// DefaultVerifyJournalPath returns the journal location for a source and destination pair
+func DefaultVerifyJournalPath(source, bucket, prefix string) string {
+	absolute, err := filepath.Abs(source)
+	if err != nil {
+		absolute = source
+	}
+	sum := sha256.Sum256([]byte(absolute + "\x00" + bucket + "\x00" + prefix))
+	return filepath.Join(DefaultCacheDirectory(), "verify", hex.EncodeToString(sum[:8])+".journal")
+}
@@ -0,0 +1,465 @@
+package data
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+func md5Hex(content []byte) string {
+	sum := md5.Sum(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// expectedMultipartETag computes a multipart ETag the way S3 documents it
+func expectedMultipartETag(content []byte, partSize int) string {
+	var concatenated []byte
+	parts := 0
+	for start := 0; start < len(content); start += partSize {
+		end := start + partSize
+		if end > len(content) {
+			end = len(content)
+		}
+		sum := md5.Sum(content[start:end])
+		concatenated = append(concatenated, sum[:]...)
+		parts++
+	}
+	return fmt.Sprintf("%s-%d", md5Hex(concatenated), parts)
+}
+
+func patternedContent(size int) []byte {
+	content := make([]byte, size)
+	for i := range content {
+		content[i] = byte(i % 251)
+	}
+	return content
+}
+
+func TestParseETag(t *testing.T) {
+	tests := []struct {
+		etag   string
+		digest string
+		parts  int
+		ok     bool
+	}{
+		{`"5d41402abc4b2a76b9719d911017c592"`, "5d41402abc4b2a76b9719d911017c592", 0, true},
+		{`"D41D8CD98F00B204E9800998ECF8427E-3"`, "d41d8cd98f00b204e9800998ecf8427e", 3, true},
+		{"5d41402abc4b2a76b9719d911017c592-1", "5d41402abc4b2a76b9719d911017c592", 1, true},
+		{`"5d41402abc4b2a76b9719d911017c592-0"`, "", 0, false},
+		{`"5d41402abc4b2a76b9719d911017c592-x"`, "", 0, false},
+		{`"not-an-md5"`, "", 0, false},
+		{`"zz41402abc4b2a76b9719d911017c592"`, "", 0, false},
+	}
+
+	for _, tt := range tests {
+		digest, parts, ok := ParseETag(tt.etag)
+		if digest != tt.digest || parts != tt.parts || ok != tt.ok {
+			t.Errorf("ParseETag(%s) = %q, %d, %v; want %q, %d, %v", tt.etag, digest, parts, ok, tt.digest, tt.parts, tt.ok)
+		}
+	}
+}
+
+func TestMultipartETag(t *testing.T) {
+	hello := md5.Sum([]byte("hello"))
+	world := md5.Sum([]byte("world"))
+
+	got := MultipartETag([][]byte{hello[:], world[:]})
+	want := md5Hex(append(hello[:], world[:]...)) + "-2"
+	if got != want {
+		t.Errorf("Expected %s, got %s", want, got)
+	}
+
+	// A single-part multipart upload is not the plain MD5 of the content
+	single := MultipartETag([][]byte{hello[:]})
+	if single == md5Hex([]byte("hello"))+"-1" {
+		t.Error("Expected single-part multipart ETag to hash the part digest again")
+	}
+}
+
+func TestCandidatePartSizes(t *testing.T) {
+	contains := func(sizes []int64, size int64) bool {
+		for _, candidate := range sizes {
+			if candidate == size {
+				return true
+			}
+		}
+		return false
+	}
+
+	// 20MiB in 3 parts: 8MiB (AWS CLI) and the rounded quotient 7MiB both fit;
+	// 5MiB would need 4 parts and 16MiB only 2
+	sizes := CandidatePartSizes(20*mebibyte, 3)
+	if !contains(sizes, 8*mebibyte) || !contains(sizes, 7*mebibyte) {
+		t.Errorf("Expected 8MiB and 7MiB candidates, got %v", sizes)
+	}
+	if contains(sizes, 5*mebibyte) || contains(sizes, 16*mebibyte) {
+		t.Errorf("Expected only sizes giving 3 parts, got %v", sizes)
+	}
+	for _, size := range sizes {
+		if partCount(20*mebibyte, size) != 3 {
+			t.Errorf("Candidate %d does not give 3 parts", size)
+		}
+	}
+
+	// The SDK default is tried first
+	sizes = CandidatePartSizes(12*mebibyte+3, 3)
+	if len(sizes) == 0 || sizes[0] != 5*mebibyte {
+		t.Errorf("Expected 5MiB first, got %v", sizes)
+	}
+
+	// A tool using an unusual part size is still matched through the quotient
+	sizes = CandidatePartSizes(1000*mebibyte, 10)
+	if !contains(sizes, 100*mebibyte) {
+		t.Errorf("Expected 100MiB for 1000MiB in 10 parts, got %v", sizes)
+	}
+	sizes = CandidatePartSizes(900*mebibyte, 30)
+	if !contains(sizes, 30*mebibyte) {
+		t.Errorf("Expected quotient 30MiB for 900MiB in 30 parts, got %v", sizes)
+	}
+
+	if sizes := CandidatePartSizes(100, 1); len(sizes) == 0 {
+		t.Error("Expected candidates for a one-part upload")
+	}
+	if sizes := CandidatePartSizes(100, 0); sizes != nil {
+		t.Errorf("Expected no candidates for zero parts, got %v", sizes)
+	}
+}
+
+func TestComputeLocalChecksums(t *testing.T) {
+	content := patternedContent(12*mebibyte + 3)
+	path := filepath.Join(t.TempDir(), "reads.fastq")
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	checksums, err := ComputeLocalChecksums(path, []int64{5 * mebibyte, 8 * mebibyte, 12*mebibyte + 3})
+	if err != nil {
+		t.Fatalf("ComputeLocalChecksums failed: %v", err)
+	}
+
+	sha := sha256.Sum256(content)
+	if checksums.SHA256 != base64.StdEncoding.EncodeToString(sha[:]) {
+		t.Errorf("Unexpected SHA-256 %s", checksums.SHA256)
+	}
+	if checksums.MD5 != md5Hex(content) || checksums.Size != int64(len(content)) {
+		t.Errorf("Unexpected MD5 or size: %+v", checksums)
+	}
+
+	for _, partSize := range []int{5 * mebibyte, 8 * mebibyte, 12*mebibyte + 3} {
+		want := expectedMultipartETag(content, partSize)
+		if got := checksums.PartETags[int64(partSize)]; got != want {
+			t.Errorf("Part size %d: expected %s, got %s", partSize, want, got)
+		}
+	}
+}
+
+func TestComputeLocalChecksumsEmptyFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "empty")
+	if err := os.WriteFile(path, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	checksums, err := ComputeLocalChecksums(path, []int64{5 * mebibyte})
+	if err != nil {
+		t.Fatalf("ComputeLocalChecksums failed: %v", err)
+	}
+	empty := md5.Sum(nil)
+	if want := MultipartETag([][]byte{empty[:]}); checksums.PartETags[5*mebibyte] != want {
+		t.Errorf("Expected one empty part %s, got %s", want, checksums.PartETags[5*mebibyte])
+	}
+}
+
+func TestCompareChecksums(t *testing.T) {
+	content := patternedContent(20 * mebibyte)
+	path := filepath.Join(t.TempDir(), "data.bin")
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+	sha := sha256.Sum256(content)
+	shaBase64 := base64.StdEncoding.EncodeToString(sha[:])
+	size := int64(len(content))
+
+	tests := []struct {
+		name   string
+		remote RemoteObject
+		want   string
+	}{
+		{"sha256 match", RemoteObject{Size: size, ChecksumSHA256: shaBase64, ETag: `"bogus"`}, VerifyOK},
+		{"sha256 differs", RemoteObject{Size: size, ChecksumSHA256: base64.StdEncoding.EncodeToString(make([]byte, 32))}, VerifyMismatch},
+		{"composite sha256 falls back to etag", RemoteObject{Size: size, ChecksumSHA256: "abc=-3", ChecksumType: "COMPOSITE", ETag: `"` + md5Hex(content) + `"`}, VerifyOK},
+		{"single-part etag", RemoteObject{Size: size, ETag: `"` + md5Hex(content) + `"`}, VerifyOK},
+		{"single-part etag differs", RemoteObject{Size: size, ETag: `"` + md5Hex([]byte("other")) + `"`}, VerifyMismatch},
+		{"multipart etag with cli part size", RemoteObject{Size: size, ETag: `"` + expectedMultipartETag(content, 8*mebibyte) + `"`}, VerifyOK},
+		{"multipart etag with sdk part size", RemoteObject{Size: size, ETag: `"` + expectedMultipartETag(content, 5*mebibyte) + `"`}, VerifyOK},
+		{"multipart etag with s3manager part size", RemoteObject{Size: size, ETag: `"` + expectedMultipartETag(content, 16*mebibyte) + `"`}, VerifyOK},
+		{"multipart etag with quotient part size", RemoteObject{Size: size, ETag: `"` + expectedMultipartETag(content, 7*mebibyte) + `"`}, VerifyOK},
+		{"multipart etag with 10MiB parts", RemoteObject{Size: size, ETag: `"` + expectedMultipartETag(content, 10*mebibyte) + `"`}, VerifyOK},
+		{"multipart etag with part size outside the candidates", RemoteObject{Size: size, ETag: `"` + expectedMultipartETag(content, 6*mebibyte) + `"`}, VerifyUnverifiable},
+		{"multipart etag with part size not rounded to a MiB", RemoteObject{Size: size, ETag: `"` + expectedMultipartETag(content, 6000000) + `"`}, VerifyUnverifiable},
+		{"multipart etag matching no candidate part size", RemoteObject{Size: size, ETag: `"` + md5Hex([]byte("other")) + `-3"`}, VerifyUnverifiable},
+		{"multipart etag with impossible part count", RemoteObject{Size: size, ETag: `"` + md5Hex([]byte("other")) + `-5000"`}, VerifyUnverifiable},
+		{"kms encrypted", RemoteObject{Size: size, ETag: `"` + md5Hex([]byte("other")) + `"`, Encryption: "aws:kms"}, VerifyUnverifiable},
+		{"sse-s3 etag is still an md5", RemoteObject{Size: size, ETag: `"` + md5Hex(content) + `"`, Encryption: "AES256"}, VerifyOK},
+		{"size differs", RemoteObject{Size: size + 1, ETag: `"` + md5Hex(content) + `"`}, VerifyMismatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			local, err := ComputeLocalChecksums(path, partSizesFor(&tt.remote))
+			if err != nil {
+				t.Fatalf("ComputeLocalChecksums failed: %v", err)
+			}
+			status, detail := CompareChecksums(local, &tt.remote)
+			if status != tt.want {
+				t.Errorf("Expected %s, got %s (%s)", tt.want, status, detail)
+			}
+		})
+	}
+}
+
+func TestCompareChecksumsKnownPartSize(t *testing.T) {
+	content := patternedContent(20 * mebibyte)
+	path := filepath.Join(t.TempDir(), "data.bin")
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+	size := int64(len(content))
+
+	tests := []struct {
+		name     string
+		etag     string
+		recorded int64
+		want     string
+	}{
+		{"default part size fits", md5Hex([]byte("other")) + "-2", 0, VerifyMismatch},
+		{"recorded part size matches", expectedMultipartETag(content, 6*mebibyte), 6 * mebibyte, VerifyOK},
+		{"recorded part size fits", md5Hex([]byte("other")) + "-4", 6 * mebibyte, VerifyMismatch},
+		{"recorded part size does not fit", md5Hex([]byte("other")) + "-4", 64 * mebibyte, VerifyUnverifiable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			remote := &RemoteObject{Size: size, ETag: `"` + tt.etag + `"`}
+			local, err := ComputeLocalChecksums(path, partSizesFor(remote, tt.recorded))
+			if err != nil {
+				t.Fatalf("ComputeLocalChecksums failed: %v", err)
+			}
+			status, detail := CompareChecksums(local, remote, tt.recorded)
+			if status != tt.want {
+				t.Errorf("Expected %s, got %s (%s)", tt.want, status, detail)
+			}
+		})
+	}
+}
+
+// fakeVerifyAPI serves a single listing page and per-key HeadObject responses
+type fakeVerifyAPI struct {
+	objects []s3types.Object
+	heads   map[string]*s3.HeadObjectOutput
+
+	mu     sync.Mutex
+	headed []string
+}
+
+func (f *fakeVerifyAPI) ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	return &s3.ListObjectsV2Output{Contents: f.objects}, nil
+}
+
+func (f *fakeVerifyAPI) HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+	key := aws.ToString(params.Key)
+	f.mu.Lock()
+	f.headed = append(f.headed, key)
+	f.mu.Unlock()
+	if head, exists := f.heads[key]; exists {
+		return head, nil
+	}
+	return &s3.HeadObjectOutput{}, nil
+}
+
+func TestVerifyUpload(t *testing.T) {
+	source := t.TempDir()
+	files := map[string][]byte{
+		"a.txt":       []byte("alpha"),
+		"sub/b.txt":   []byte("bravo"),
+		"c.txt":       []byte("charlie"),
+		"sub/kms.txt": []byte("encrypted"),
+		"sub/sha.txt": []byte("checksummed"),
+	}
+	for name, content := range files {
+		path := filepath.Join(source, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, content, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	shaSum := sha256.Sum256(files["sub/sha.txt"])
+	api := &fakeVerifyAPI{
+		objects: []s3types.Object{
+			{Key: aws.String("runs/a.txt"), Size: aws.Int64(5), ETag: aws.String(`"` + md5Hex(files["a.txt"]) + `"`)},
+			{Key: aws.String("runs/sub/b.txt"), Size: aws.Int64(5), ETag: aws.String(`"` + md5Hex([]byte("BRAVO")) + `"`)},
+			{Key: aws.String("runs/sub/kms.txt"), Size: aws.Int64(9), ETag: aws.String(`"` + md5Hex([]byte("opaque")) + `"`)},
+			{Key: aws.String("runs/sub/sha.txt"), Size: aws.Int64(11), ETag: aws.String(`"opaque"`), ChecksumAlgorithm: []s3types.ChecksumAlgorithm{s3types.ChecksumAlgorithmSha256}},
+			{Key: aws.String("runs/d.txt"), Size: aws.Int64(3)},
+			{Key: aws.String("runs/sub/"), Size: aws.Int64(0)},
+		},
+		heads: map[string]*s3.HeadObjectOutput{
+			"runs/sub/kms.txt": {ServerSideEncryption: s3types.ServerSideEncryptionAwsKms},
+			"runs/sub/sha.txt": {ChecksumSHA256: aws.String(base64.StdEncoding.EncodeToString(shaSum[:])), ChecksumType: s3types.ChecksumTypeFullObject},
+		},
+	}
+
+	journal, err := OpenVerifyJournal(filepath.Join(t.TempDir(), "verify.journal"))
+	if err != nil {
+		t.Fatalf("OpenVerifyJournal failed: %v", err)
+	}
+	defer journal.Close()
+
+	var progressCalls int
+	report, err := VerifyUpload(context.Background(), api, source, "bucket", "runs", VerifyOptions{
+		Workers:  2,
+		Journal:  journal,
+		Progress: func(checked, total int) { progressCalls++ },
+	})
+	if err != nil {
+		t.Fatalf("VerifyUpload failed: %v", err)
+	}
+
+	want := map[string]int{VerifyOK: 2, VerifyMismatch: 1, VerifyMissing: 1, VerifyExtra: 1, VerifyUnverifiable: 1}
+	for status, count := range want {
+		if report.Counts[status] != count {
+			t.Errorf("Expected %d %s, got %d (%+v)", count, status, report.Counts[status], report.Discrepancies)
+		}
+	}
+	if report.Files != 5 || report.Verified != 2 || progressCalls != 5 {
+		t.Errorf("Unexpected totals: files %d, verified %d, progress %d", report.Files, report.Verified, progressCalls)
+	}
+	if !report.Failed() {
+		t.Error("Expected a missing file to fail verification")
+	}
+
+	statuses := make(map[string]string)
+	for _, discrepancy := range report.Discrepancies {
+		statuses[discrepancy.Key] = discrepancy.Status
+	}
+	expected := map[string]string{
+		"runs/sub/b.txt":   VerifyMismatch,
+		"runs/c.txt":       VerifyMissing,
+		"runs/d.txt":       VerifyExtra,
+		"runs/sub/kms.txt": VerifyUnverifiable,
+	}
+	for key, status := range expected {
+		if statuses[key] != status {
+			t.Errorf("Expected %s to be %s, got %q", key, status, statuses[key])
+		}
+	}
+
+	// Verified files are journaled and skipped on the next run
+	if journal.Len() != 2 {
+		t.Errorf("Expected 2 journaled files, got %d", journal.Len())
+	}
+	api.headed = nil
+	report, err = VerifyUpload(context.Background(), api, source, "bucket", "runs/", VerifyOptions{Journal: journal})
+	if err != nil {
+		t.Fatalf("VerifyUpload failed: %v", err)
+	}
+	if report.Resumed != 2 || report.Verified != 2 {
+		t.Errorf("Expected 2 files resumed from the journal, got resumed %d verified %d", report.Resumed, report.Verified)
+	}
+	for _, key := range api.headed {
+		if key == "runs/sub/sha.txt" {
+			t.Error("Expected journaled file not to be checked again")
+		}
+	}
+}
+
+func TestVerifyUploadSingleFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "genome.fa")
+	if err := os.WriteFile(path, []byte("ACGT"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	api := &fakeVerifyAPI{objects: []s3types.Object{
+		{Key: aws.String("refs/genome.fa"), Size: aws.Int64(4), ETag: aws.String(`"` + md5Hex([]byte("ACGT")) + `"`)},
+	}}
+
+	report, err := VerifyUpload(context.Background(), api, path, "bucket", "refs/", VerifyOptions{})
+	if err != nil {
+		t.Fatalf("VerifyUpload failed: %v", err)
+	}
+	if report.Verified != 1 || len(report.Discrepancies) != 0 {
+		t.Errorf("Expected the file to verify at refs/genome.fa, got %+v", report.Discrepancies)
+	}
+}
+
+func TestVerifyJournal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "verify.journal")
+	modTime := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	journal, err := OpenVerifyJournal(path)
+	if err != nil {
+		t.Fatalf("OpenVerifyJournal failed: %v", err)
+	}
+	if err := journal.Record("runs/a.txt", 5, modTime); err != nil {
+		t.Fatalf("Record failed: %v", err)
+	}
+	journal.Close()
+
+	// Simulate a run killed mid-write
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString(`{"key":"runs/b.t`)
+	file.Close()
+
+	reopened, err := OpenVerifyJournal(path)
+	if err != nil {
+		t.Fatalf("OpenVerifyJournal failed: %v", err)
+	}
+	if reopened.Len() != 1 {
+		t.Errorf("Expected the partial line to be ignored, got %d entries", reopened.Len())
+	}
+	if !reopened.Verified("runs/a.txt", 5, modTime) {
+		t.Error("Expected unchanged file to be verified")
+	}
+	if reopened.Verified("runs/a.txt", 5, modTime.Add(time.Second)) {
+		t.Error("Expected a modified file to need verification again")
+	}
+	if reopened.Verified("runs/a.txt", 6, modTime) {
+		t.Error("Expected a resized file to need verification again")
+	}
+
+	if err := reopened.Remove(); err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("Expected journal to be deleted")
+	}
+}
+
+func TestDefaultVerifyJournalPath(t *testing.T) {
+	a := DefaultVerifyJournalPath("./data", "bucket", "runs/")
+	b := DefaultVerifyJournalPath("./data", "bucket", "other/")
+	if a == b {
+		t.Error("Expected different destinations to use different journals")
+	}
+	if !strings.HasPrefix(a, DefaultCacheDirectory()) {
+		t.Errorf("Expected journal under the cache directory, got %s", a)
+	}
+}
@@ -0,0 +1,236 @@
+// Package doctor runs environment checks and reports what needs fixing before
+// the wizard can be used. Subsystems contribute checks by registering them.
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// Status is the outcome of a check
+type Status string
+
+const (
+	StatusPass Status = "pass"
+	StatusWarn Status = "warn"
+	StatusFail Status = "fail"
+)
+
+// Check categories, listed in this order
+const (
+	CategoryAWS    = "aws"
+	CategoryConfig = "config"
+	CategoryTools  = "tools"
+)
+
+var categoryOrder = map[string]int{
+	CategoryAWS:    0,
+	CategoryConfig: 1,
+	CategoryTools:  2,
+}
+
+// DefaultTimeout bounds a single check
+const DefaultTimeout = 20 * time.Second
+
+// Environment is what checks know about the invocation
+type Environment struct {
+	Region     string
+	ConfigRoot string
+}
+
+// Result is the outcome of one check, with a hint when something needs fixing
+type Result struct {
+	Category    string `json:"category"`
+	Name        string `json:"name"`
+	Status      Status `json:"status"`
+	Message     string `json:"message"`
+	Remediation string `json:"remediation,omitempty"`
+}
+
+// Pass returns a passing result
+func Pass(format string, args ...interface{}) Result {
+	return Result{Status: StatusPass, Message: fmt.Sprintf(format, args...)}
+}
+
+// Warn returns a warning with a remediation hint
+func Warn(remediation, format string, args ...interface{}) Result {
+	return Result{Status: StatusWarn, Message: fmt.Sprintf(format, args...), Remediation: remediation}
+}
+
+// Fail returns a failure with a remediation hint
+func Fail(remediation, format string, args ...interface{}) Result {
+	return Result{Status: StatusFail, Message: fmt.Sprintf(format, args...), Remediation: remediation}
+}
+
+// Checker is a single diagnostic
+type Checker interface {
+	Category() string
+	Name() string
+	Check(ctx context.Context, env Environment) Result
+}
+
+// Check adapts a function to a Checker
+type Check struct {
+	CheckCategory string
+	CheckName     string
+	Run           func(ctx context.Context, env Environment) Result
+}
+
+// NewCheck creates a check from a function
+func NewCheck(category, name string, run func(ctx context.Context, env Environment) Result) *Check {
+	return &Check{CheckCategory: category, CheckName: name, Run: run}
+}
+
+func (c *Check) Category() string { return c.CheckCategory }
+
+func (c *Check) Name() string { return c.CheckName }
+
+func (c *Check) Check(ctx context.Context, env Environment) Result {
+	return c.Run(ctx, env)
+}
+
+// Registry holds the checks to run
+type Registry struct {
+	mu       sync.Mutex
+	checkers []Checker
+	timeout  time.Duration
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{timeout: DefaultTimeout}
+}
+
+// Register adds checks. Checks run grouped by category, in registration order.
+func (r *Registry) Register(checkers ...Checker) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checkers = append(r.checkers, checkers...)
+}
+
+// Checkers returns the registered checks in run order
+func (r *Registry) Checkers() []Checker {
+	r.mu.Lock()
+	checkers := append([]Checker(nil), r.checkers...)
+	r.mu.Unlock()
+
+	sort.SliceStable(checkers, func(a, b int) bool {
+		return categoryRank(checkers[a].Category()) < categoryRank(checkers[b].Category())
+	})
+	return checkers
+}
+
+// categoryRank puts unknown categories after the built-in ones
+func categoryRank(category string) int {
+	if rank, known := categoryOrder[category]; known {
+		return rank
+	}
+	return len(categoryOrder)
+}
+
+// Run executes every check. A check that panics or overruns its timeout fails
+// without stopping the others.
+func (r *Registry) Run(ctx context.Context, env Environment) *Report {
+	report := &Report{}
+	for _, checker := range r.Checkers() {
+		result := r.runOne(ctx, checker, env)
+		result.Category = checker.Category()
+		result.Name = checker.Name()
+		report.Results = append(report.Results, result)
+	}
+	return report
+}
+
+func (r *Registry) runOne(ctx context.Context, checker Checker, env Environment) Result {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	done := make(chan Result, 1)
+	go func() {
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				done <- Fail("report this as a bug", "check panicked: %v", recovered)
+			}
+		}()
+		done <- checker.Check(ctx, env)
+	}()
+
+	select {
+	case result := <-done:
+		return result
+	case <-ctx.Done():
+		return Fail("check network access and retry", "check did not finish within %s", r.timeout)
+	}
+}
+
+var defaultRegistry = NewRegistry()
+
+// Register adds checks to the default registry run by the doctor command
+func Register(checkers ...Checker) {
+	defaultRegistry.Register(checkers...)
+}
+
+// DefaultRegistry returns the registry the doctor command runs
+func DefaultRegistry() *Registry {
+	return defaultRegistry
+}
+
+// Report collects the results of a run
+type Report struct {
+	Results []Result `json:"results"`
+}
+
+// Count returns how many results have a status
+func (r *Report) Count(status Status) int {
+	count := 0
+	for _, result := range r.Results {
+		if result.Status == status {
+			count++
+		}
+	}
+	return count
+}
+
+// ExitCode is 1 when any check failed; warnings alone do not fail the run
+func (r *Report) ExitCode() int {
+	if r.Count(StatusFail) > 0 {
+		return 1
+	}
+	return 0
+}
+
+var statusIcons = map[Status]string{
+	StatusPass: "✅",
+	StatusWarn: "⚠️ ",
+	StatusFail: "❌",
+}
+
+// Print writes the results as a table followed by the remediation hints
+func (r *Report) Print(w io.Writer) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "STATUS\tCATEGORY\tCHECK\tDETAIL")
+	for _, result := range r.Results {
+		fmt.Fprintf(tw, "%s %s\t%s\t%s\t%s\n", statusIcons[result.Status], result.Status, result.Category, result.Name, result.Message)
+	}
+	tw.Flush()
+
+	var hints []Result
+	for _, result := range r.Results {
+		if result.Status != StatusPass && result.Remediation != "" {
+			hints = append(hints, result)
+		}
+	}
+	if len(hints) > 0 {
+		fmt.Fprintln(w, "\n💡 How to fix:")
+		for _, result := range hints {
+			fmt.Fprintf(w, "  %s: %s\n", result.Name, result.Remediation)
+		}
+	}
+
+	fmt.Fprintf(w, "\n%d passed, %d warnings, %d failed\n", r.Count(StatusPass), r.Count(StatusWarn), r.Count(StatusFail))
+}
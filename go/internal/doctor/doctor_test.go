@@ -0,0 +1,147 @@
+package doctor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeChecker returns a fixed result and records the environment it saw
+type fakeChecker struct {
+	category string
+	name     string
+	result   Result
+	delay    time.Duration
+	panics   bool
+	seen     *Environment
+}
+
+func (f *fakeChecker) Category() string { return f.category }
+
+func (f *fakeChecker) Name() string { return f.name }
+
+func (f *fakeChecker) Check(ctx context.Context, env Environment) Result {
+	if f.seen != nil {
+		*f.seen = env
+	}
+	if f.panics {
+		panic("boom")
+	}
+	if f.delay > 0 {
+		select {
+		case <-time.After(f.delay):
+		case <-ctx.Done():
+		}
+	}
+	return f.result
+}
+
+func TestRegistryRunOrdersByCategory(t *testing.T) {
+	registry := NewRegistry()
+	var seen Environment
+	registry.Register(
+		&fakeChecker{category: CategoryTools, name: "docker", result: Pass("found")},
+		&fakeChecker{category: "plugins", name: "custom", result: Pass("ok")},
+		&fakeChecker{category: CategoryAWS, name: "credentials", result: Pass("valid"), seen: &seen},
+		&fakeChecker{category: CategoryConfig, name: "configs", result: Warn("set --config-root", "not found")},
+		&fakeChecker{category: CategoryAWS, name: "region", result: Pass("reachable")},
+	)
+
+	env := Environment{Region: "eu-west-1", ConfigRoot: "/tmp/configs"}
+	report := registry.Run(context.Background(), env)
+
+	var names []string
+	for _, result := range report.Results {
+		names = append(names, result.Category+"/"+result.Name)
+	}
+	if got := strings.Join(names, ","); got != "aws/credentials,aws/region,config/configs,tools/docker,plugins/custom" {
+		t.Errorf("run order = %s", got)
+	}
+	if seen != env {
+		t.Errorf("checker saw %+v, want %+v", seen, env)
+	}
+	if report.ExitCode() != 0 {
+		t.Error("warnings alone should not fail the run")
+	}
+	if report.Count(StatusPass) != 4 || report.Count(StatusWarn) != 1 {
+		t.Errorf("unexpected counts: %+v", report.Results)
+	}
+}
+
+func TestRegistryRunIsolatesFailures(t *testing.T) {
+	registry := NewRegistry()
+	registry.timeout = 10 * time.Millisecond
+	registry.Register(
+		&fakeChecker{category: CategoryAWS, name: "panics", panics: true},
+		&fakeChecker{category: CategoryAWS, name: "hangs", delay: time.Second, result: Pass("late")},
+		&fakeChecker{category: CategoryAWS, name: "fails", result: Fail("run aws configure", "no credentials")},
+		&fakeChecker{category: CategoryAWS, name: "passes", result: Pass("ok")},
+	)
+
+	report := registry.Run(context.Background(), Environment{})
+	want := map[string]Status{"panics": StatusFail, "hangs": StatusFail, "fails": StatusFail, "passes": StatusPass}
+	for _, result := range report.Results {
+		if result.Status != want[result.Name] {
+			t.Errorf("%s: status %s, want %s (%s)", result.Name, result.Status, want[result.Name], result.Message)
+		}
+	}
+	if !strings.Contains(report.Results[1].Message, "did not finish") {
+		t.Errorf("timeout message = %q", report.Results[1].Message)
+	}
+	if report.ExitCode() != 1 {
+		t.Error("a failed check should fail the run")
+	}
+}
+
+func TestReportPrint(t *testing.T) {
+	report := &Report{Results: []Result{
+		{Category: CategoryAWS, Name: "credentials", Status: StatusFail, Message: "no credentials", Remediation: "run aws configure"},
+		{Category: CategoryTools, Name: "spack", Status: StatusWarn, Message: "spack not found", Remediation: "install Spack"},
+		{Category: CategoryTools, Name: "docker", Status: StatusPass, Message: "/usr/bin/docker", Remediation: "unused"},
+	}}
+
+	var out bytes.Buffer
+	report.Print(&out)
+	text := out.String()
+
+	for _, want := range []string{"credentials: run aws configure", "spack: install Spack", "1 passed, 1 warnings, 1 failed"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("output missing %q:\n%s", want, text)
+		}
+	}
+	if strings.Contains(text, "unused") {
+		t.Error("passing checks should not print remediation hints")
+	}
+}
+
+func TestToolCheck(t *testing.T) {
+	original := lookPath
+	defer func() { lookPath = original }()
+	lookPath = func(command string) (string, error) {
+		if command == "docker" {
+			return "/usr/bin/docker", nil
+		}
+		return "", errors.New("not found")
+	}
+
+	tests := []struct {
+		checker *Check
+		want    Status
+	}{
+		{Tool("docker", "docker", "container hosts", "install Docker", false), StatusPass},
+		{Tool("spack", "spack", "package builds", "install Spack", false), StatusWarn},
+		{Tool("aws", "aws", "SSM sessions", "install the AWS CLI", true), StatusFail},
+	}
+	for _, tt := range tests {
+		result := tt.checker.Check(context.Background(), Environment{})
+		if result.Status != tt.want {
+			t.Errorf("%s: status %s, want %s", tt.checker.Name(), result.Status, tt.want)
+		}
+		if tt.want != StatusPass && result.Remediation == "" {
+			t.Errorf("%s: missing remediation", tt.checker.Name())
+		}
+	}
+}
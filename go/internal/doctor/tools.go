@@ -0,0 +1,24 @@
+package doctor
+
+import (
+	"context"
+	"os/exec"
+)
+
+// lookPath finds executables; tests replace it
+var lookPath = exec.LookPath
+
+// Tool checks that an external command is on PATH. Missing required tools fail;
+// missing optional tools warn, naming the feature that needs them.
+func Tool(name, command, feature, install string, required bool) *Check {
+	return NewCheck(CategoryTools, name, func(ctx context.Context, env Environment) Result {
+		path, err := lookPath(command)
+		if err == nil {
+			return Pass("%s", path)
+		}
+		if required {
+			return Fail(install, "%s not found in PATH; needed for %s", command, feature)
+		}
+		return Warn(install, "%s not found in PATH; %s is unavailable", command, feature)
+	})
+}
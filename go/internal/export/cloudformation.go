@@ -0,0 +1,22 @@
+package export
+
+import (
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/aws/templates"
+)
+
+// CloudFormationTemplateFile is the file name of the exported CloudFormation template
+const CloudFormationTemplateFile = "template.json"
+
+// CloudFormation renders the environment as the single-instance template the
+// deploy command uses. The data bucket and volume tuning are Terraform-only.
+func CloudFormation(env Environment) ([]File, error) {
+	template, err := templates.Build(templates.ArchitectureSingle, env.Options)
+	if err != nil {
+		return nil, err
+	}
+	body, err := template.JSON()
+	if err != nil {
+		return nil, err
+	}
+	return []File{{Name: CloudFormationTemplateFile, Content: body + "\n"}}, nil
+}
@@ -0,0 +1,176 @@
+// Package export renders an intelligent recommendation's resource plan as
+// infrastructure as code, either as the CloudFormation template the deploy
+// command uses or as a Terraform module for institutions that mandate Terraform.
+package export
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/aws/templates"
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/intelligence"
+)
+
+// Format is an infrastructure as code output format
+type Format string
+
+// Supported export formats
+const (
+	FormatCloudFormation Format = "cloudformation"
+	FormatTerraform      Format = "terraform"
+)
+
+// Formats lists the supported export formats
+func Formats() []Format {
+	return []Format{FormatCloudFormation, FormatTerraform}
+}
+
+// ParseFormat looks up an export format by name
+func ParseFormat(name string) (Format, error) {
+	switch format := Format(strings.ToLower(strings.TrimSpace(name))); format {
+	case FormatCloudFormation, FormatTerraform:
+		return format, nil
+	case "cfn":
+		return FormatCloudFormation, nil
+	case "tf":
+		return FormatTerraform, nil
+	}
+	return "", fmt.Errorf("unknown export format %q (available: cloudformation, terraform)", name)
+}
+
+// Default S3 lifecycle transitions for the data bucket, matching the
+// lifecycle policies the intelligence engine recommends
+const (
+	DefaultInfrequentAccessDays = 30
+	DefaultArchiveDays          = 90
+)
+
+// Environment is the research environment an export describes: the template
+// options shared with CloudFormation plus the storage details only Terraform
+// renders
+type Environment struct {
+	templates.Options
+
+	// VolumeType, VolumeIOPS and VolumeThroughput tune the root volume;
+	// zero IOPS and throughput keep the volume type's baseline
+	VolumeType       string
+	VolumeIOPS       int
+	VolumeThroughput int
+
+	// BackupStorageClass and ArchiveStorageClass are the S3 storage classes
+	// the data bucket transitions objects to; a data bucket is only created
+	// when at least one is set
+	BackupStorageClass  string
+	ArchiveStorageClass string
+	BackupAfterDays     int
+	ArchiveAfterDays    int
+}
+
+// DataBucket reports whether the environment includes an S3 data bucket
+func (e Environment) DataBucket() bool {
+	return e.BackupStorageClass != "" || e.ArchiveStorageClass != ""
+}
+
+// s3StorageClasses maps the resource plan's storage types to S3 storage classes
+var s3StorageClasses = map[string]string{
+	"s3_standard_ia":         "STANDARD_IA",
+	"s3_one_zone_ia":         "ONEZONE_IA",
+	"s3_intelligent_tiering": "INTELLIGENT_TIERING",
+	"s3_glacier_ir":          "GLACIER_IR",
+	"s3_glacier":             "GLACIER",
+	"s3_deep_archive":        "DEEP_ARCHIVE",
+}
+
+// provisionedVolumeTypes are the EBS volume types that take IOPS, and for
+// gp3 throughput, settings
+var provisionedVolumeTypes = map[string]bool{"gp3": true, "io1": true, "io2": true}
+
+// NewEnvironment builds the environment for a domain from a resource plan.
+// A nil plan gives the defaults.
+func NewEnvironment(domain string, plan *intelligence.ResourcePlan) Environment {
+	env := Environment{
+		Options:          templates.DefaultOptions(domain, ""),
+		VolumeType:       "gp3",
+		BackupAfterDays:  DefaultInfrequentAccessDays,
+		ArchiveAfterDays: DefaultArchiveDays,
+	}
+	ApplyResourcePlan(&env.Options, plan)
+	if plan == nil {
+		return env
+	}
+
+	primary := plan.StorageConfiguration.PrimaryStorage
+	if primary.Type != "" {
+		env.VolumeType = primary.Type
+	}
+	if provisionedVolumeTypes[env.VolumeType] {
+		env.VolumeIOPS = primary.IOPS
+		if env.VolumeType == "gp3" {
+			env.VolumeThroughput = primary.Throughput
+		}
+	}
+	env.BackupStorageClass = s3StorageClasses[plan.StorageConfiguration.BackupStorage.Type]
+	env.ArchiveStorageClass = s3StorageClasses[plan.StorageConfiguration.ArchiveStorage.Type]
+	return env
+}
+
+// ApplyResourcePlan folds an intelligence resource plan into template options
+func ApplyResourcePlan(opts *templates.Options, plan *intelligence.ResourcePlan) {
+	if plan == nil {
+		return
+	}
+
+	if plan.RecommendedInstance != "" && opts.InstanceType == "" {
+		opts.InstanceType = plan.RecommendedInstance
+	}
+	if size := plan.StorageConfiguration.PrimaryStorage.SizeGB; size > opts.VolumeSizeGB {
+		opts.VolumeSizeGB = size
+	}
+
+	opts.EncryptVolume = opts.EncryptVolume || plan.SecurityConfiguration.EncryptionAtRest
+	opts.PlacementGroup = opts.PlacementGroup || plan.NetworkConfiguration.PlacementGroup
+
+	if len(plan.SecurityConfiguration.IAMRoles) > 0 {
+		opts.IAMRole = true
+	}
+
+	// Compliance profiles allow no public inbound rules, so the environment is
+	// reached through Session Manager
+	if profile := plan.SecurityConfiguration.ComplianceProfile; profile != "" {
+		opts.Compliance = templates.ComplianceProfile(profile)
+		opts.NoSSH = opts.NoSSH || opts.Compliance.Settings().PrivateIngress
+	}
+}
+
+// File is one generated file, named relative to the output directory
+type File struct {
+	Name    string
+	Content string
+}
+
+// Generate renders the environment in the given format
+func Generate(format Format, env Environment) ([]File, error) {
+	switch format {
+	case FormatCloudFormation:
+		return CloudFormation(env)
+	case FormatTerraform:
+		return Terraform(env)
+	}
+	return nil, fmt.Errorf("unknown export format %q", format)
+}
+
+// WriteFiles writes generated files into dir, creating it if needed
+func WriteFiles(dir string, files []File) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+	for _, file := range files {
+		path := filepath.Join(dir, file.Name)
+		if err := os.WriteFile(path, []byte(file.Content), 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", path, err)
+		}
+	}
+	return nil
+}
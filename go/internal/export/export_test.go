@@ -0,0 +1,342 @@
+package export
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/intelligence"
+)
+
+// genomicsPlan is the plan the intelligence engine recommends for a large genomics dataset
+func genomicsPlan() *intelligence.ResourcePlan {
+	return &intelligence.ResourcePlan{
+		RecommendedInstance: "r6i.8xlarge",
+		StorageConfiguration: intelligence.StorageConfiguration{
+			PrimaryStorage: intelligence.StorageType{Type: "gp3", SizeGB: 1200, IOPS: 8000, Throughput: 500},
+			BackupStorage:  intelligence.StorageType{Type: "s3_standard_ia", SizeGB: 600},
+			ArchiveStorage: intelligence.StorageType{Type: "s3_glacier", SizeGB: 1200},
+		},
+		NetworkConfiguration:  intelligence.NetworkConfiguration{PlacementGroup: true},
+		SecurityConfiguration: intelligence.SecurityConfiguration{EncryptionAtRest: true, IAMRoles: []string{"ResearchRole"}},
+	}
+}
+
+func TestExportCompliance(t *testing.T) {
+	plan := genomicsPlan()
+	plan.SecurityConfiguration.ComplianceProfile = "hipaa"
+	env := NewEnvironment("genomics", plan)
+	if env.Compliance != "hipaa" || !env.NoSSH {
+		t.Errorf("hipaa plan should select the profile and Session Manager: %+v", env.Options)
+	}
+
+	files, err := CloudFormation(env)
+	if err != nil {
+		t.Fatalf("CloudFormation() error = %v", err)
+	}
+	if !strings.Contains(files[0].Content, "AWS::CloudTrail::Trail") {
+		t.Error("exported template should include the hipaa audit trail")
+	}
+	if _, err := Terraform(env); err == nil || !strings.Contains(err.Error(), "only exported as CloudFormation") {
+		t.Errorf("Terraform() error = %v, want compliance profiles rejected", err)
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	tests := map[string]Format{
+		"terraform":      FormatTerraform,
+		"TF":             FormatTerraform,
+		"cloudformation": FormatCloudFormation,
+		" cfn ":          FormatCloudFormation,
+	}
+	for name, want := range tests {
+		if got, err := ParseFormat(name); err != nil || got != want {
+			t.Errorf("ParseFormat(%q) = %s, %v, want %s", name, got, err, want)
+		}
+	}
+	if _, err := ParseFormat("pulumi"); err == nil {
+		t.Error("ParseFormat() should reject unknown formats")
+	}
+}
+
+func TestNewEnvironment(t *testing.T) {
+	env := NewEnvironment("genomics", genomicsPlan())
+
+	if env.DomainName != "genomics" || env.InstanceType != "r6i.8xlarge" || env.VolumeSizeGB != 1200 {
+		t.Errorf("options = %+v", env.Options)
+	}
+	if !env.EncryptVolume || !env.PlacementGroup || !env.IAMRole {
+		t.Errorf("plan should enable encryption, placement group and IAM role: %+v", env.Options)
+	}
+	if env.VolumeType != "gp3" || env.VolumeIOPS != 8000 || env.VolumeThroughput != 500 {
+		t.Errorf("volume = %s %d %d", env.VolumeType, env.VolumeIOPS, env.VolumeThroughput)
+	}
+	if !env.DataBucket() || env.BackupStorageClass != "STANDARD_IA" || env.ArchiveStorageClass != "GLACIER" {
+		t.Errorf("storage classes = %s %s", env.BackupStorageClass, env.ArchiveStorageClass)
+	}
+
+	defaults := NewEnvironment("genomics", nil)
+	if defaults.DataBucket() || defaults.IAMRole || defaults.VolumeIOPS != 0 {
+		t.Errorf("nil plan should keep the defaults: %+v", defaults)
+	}
+}
+
+func TestApplyResourcePlanKeepsInstanceType(t *testing.T) {
+	env := NewEnvironment("genomics", nil)
+	env.InstanceType = "c6i.2xlarge"
+	ApplyResourcePlan(&env.Options, genomicsPlan())
+	if env.InstanceType != "c6i.2xlarge" {
+		t.Errorf("an explicit instance type should win over the plan, got %s", env.InstanceType)
+	}
+}
+
+// terraformModuleFiles renders and parses the module, failing on syntax errors
+func terraformModuleFiles(t *testing.T, env Environment) map[string]*hclFile {
+	t.Helper()
+	files, err := Terraform(env)
+	if err != nil {
+		t.Fatalf("Terraform() error = %v", err)
+	}
+
+	parsed := make(map[string]*hclFile)
+	for _, file := range files {
+		hcl, err := parseHCL(file.Content)
+		if err != nil {
+			t.Fatalf("%s does not parse: %v\n%s", file.Name, err, file.Content)
+		}
+		parsed[file.Name] = hcl
+	}
+	if len(parsed) != 3 || parsed[TerraformMainFile] == nil || parsed[TerraformVariablesFile] == nil || parsed[TerraformOutputsFile] == nil {
+		t.Fatalf("module files = %v", files)
+	}
+	return parsed
+}
+
+// checkModuleReferences verifies every block is well formed, declared once,
+// and every variable and resource an expression refers to is declared
+func checkModuleReferences(t *testing.T, module map[string]*hclFile) (resources, variables map[string]bool) {
+	t.Helper()
+	labels := map[string]int{"terraform": 0, "locals": 0, "resource": 2, "variable": 1, "output": 1}
+	resources, variables = make(map[string]bool), make(map[string]bool)
+	declared := make(map[string]bool)
+
+	for name, file := range module {
+		for _, block := range file.blocks {
+			want, known := labels[block[0]]
+			if !known || len(block)-1 != want {
+				t.Errorf("%s: unexpected block %v", name, block)
+				continue
+			}
+			address := strings.Join(block, ".")
+			if declared[address] && want > 0 {
+				t.Errorf("%s: %s declared twice", name, address)
+			}
+			declared[address] = true
+			switch block[0] {
+			case "resource":
+				resources[block[1]+"."+block[2]] = true
+			case "variable":
+				variables[block[1]] = true
+			}
+		}
+	}
+
+	used := make(map[string]bool)
+	for name, file := range module {
+		for _, ref := range file.references {
+			kind, target, _ := strings.Cut(ref, ".")
+			switch {
+			case kind == "var":
+				used[target] = true
+				if !variables[target] {
+					t.Errorf("%s: undeclared variable %s", name, ref)
+				}
+			case strings.HasPrefix(kind, "aws_"):
+				if !resources[ref] {
+					t.Errorf("%s: undeclared resource %s", name, ref)
+				}
+			}
+		}
+	}
+	for variable := range variables {
+		if !used[variable] {
+			t.Errorf("variable %s is never used", variable)
+		}
+	}
+	return resources, variables
+}
+
+func TestTerraformModule(t *testing.T) {
+	env := NewEnvironment("genomics", genomicsPlan())
+	module := terraformModuleFiles(t, env)
+	resources, variables := checkModuleReferences(t, module)
+
+	for _, resource := range []string{
+		"aws_instance.research",
+		"aws_security_group.research",
+		"aws_iam_role.research",
+		"aws_iam_instance_profile.research",
+		"aws_placement_group.research",
+		"aws_s3_bucket.data",
+		"aws_s3_bucket_lifecycle_configuration.data",
+	} {
+		if !resources[resource] {
+			t.Errorf("module should declare %s", resource)
+		}
+	}
+	for _, variable := range []string{"instance_type", "root_volume_iops", "root_volume_throughput", "backup_after_days", "archive_after_days"} {
+		if !variables[variable] {
+			t.Errorf("module should declare variable %s", variable)
+		}
+	}
+
+	instance := module[TerraformMainFile].attributes["resource.aws_instance.research"]
+	if !strings.Contains(strings.Join(instance, " "), "iam_instance_profile") {
+		t.Errorf("instance attributes = %v", instance)
+	}
+	if attributes := module[TerraformVariablesFile].attributes["variable.key_name"]; strings.Contains(strings.Join(attributes, " "), "default") {
+		t.Error("key_name must be supplied by the user")
+	}
+}
+
+func TestTerraformModuleVariableDefaults(t *testing.T) {
+	env := NewEnvironment("genomics", genomicsPlan())
+	files, err := Terraform(env)
+	if err != nil {
+		t.Fatalf("Terraform() error = %v", err)
+	}
+	variables := files[1].Content
+	for _, want := range []string{
+		`default     = "r6i.8xlarge"`,
+		`default     = 1200`,
+		`default     = 8000`,
+		`default     = true`,
+		`default     = ["arn:aws:iam::aws:policy/AmazonSSMManagedInstanceCore"]`,
+	} {
+		if !strings.Contains(variables, want) {
+			t.Errorf("variables.tf should contain %s", want)
+		}
+	}
+	if main := files[0].Content; !strings.Contains(main, `storage_class = "GLACIER"`) || !strings.Contains(main, `user_data              = "#!/bin/bash\nyum update -y`) {
+		t.Errorf("main.tf = %s", main)
+	}
+}
+
+func TestTerraformModuleARM64Image(t *testing.T) {
+	plan := genomicsPlan()
+	plan.RecommendedInstance = "r7g.8xlarge"
+	files, err := Terraform(NewEnvironment("genomics", plan))
+	if err != nil {
+		t.Fatalf("Terraform() error = %v", err)
+	}
+	if want := `default     = "resolve:ssm:/aws/service/ami-amazon-linux-latest/amzn2-ami-hvm-arm64-gp2"`; !strings.Contains(files[1].Content, want) {
+		t.Errorf("variables.tf should look up the arm64 image, got %s", files[1].Content)
+	}
+}
+
+func TestTerraformModuleMinimal(t *testing.T) {
+	// No IAM role, placement group or data bucket; an io-less volume type
+	plan := &intelligence.ResourcePlan{
+		RecommendedInstance: "t3.large",
+		StorageConfiguration: intelligence.StorageConfiguration{
+			PrimaryStorage: intelligence.StorageType{Type: "st1", SizeGB: 500, IOPS: 3000, Throughput: 125},
+		},
+	}
+	env := NewEnvironment("chemistry", plan)
+	module := terraformModuleFiles(t, env)
+	resources, variables := checkModuleReferences(t, module)
+
+	if resources["aws_iam_role.research"] || resources["aws_placement_group.research"] || resources["aws_s3_bucket.data"] {
+		t.Errorf("resources = %v", resources)
+	}
+	if variables["root_volume_iops"] || variables["managed_policy_arns"] {
+		t.Errorf("variables = %v", variables)
+	}
+}
+
+func TestTerraformRejectsInvalidOptions(t *testing.T) {
+	env := NewEnvironment("genomics", nil)
+	if _, err := Terraform(env); err == nil {
+		t.Error("Terraform() should require an instance type")
+	}
+}
+
+func TestHCLStringEscapes(t *testing.T) {
+	tests := map[string]string{
+		`plain`:             `"plain"`,
+		`say "hi"`:          `"say \"hi\""`,
+		"a\\b\nc":           `"a\\b\nc"`,
+		"${HOME} and %{if}": `"$${HOME} and %%{if}"`,
+		"$5 and 100%":       `"$5 and 100%"`,
+		"bell\a":            `"bell\u0007"`,
+	}
+	for value, want := range tests {
+		got := hclString(value)
+		if got != want {
+			t.Errorf("hclString(%q) = %s, want %s", value, got, want)
+		}
+		if _, err := parseHCL("x = " + got + "\n"); err != nil {
+			t.Errorf("hclString(%q) does not parse: %v", value, err)
+		}
+	}
+}
+
+func TestHCLCheckerRejectsBrokenSyntax(t *testing.T) {
+	for _, src := range []string{
+		"resource \"aws_instance\" \"x\" {\n  ami = \"a\"\n",
+		"x = [1, 2\n",
+		"x = \"unterminated\n",
+		"x = \"${var.y\"\n",
+		"x = \"bad \\q escape\"\n",
+		"x =\n",
+		"block {\n  y = (1]\n}\n",
+	} {
+		if _, err := parseHCL(src); err == nil {
+			t.Errorf("parseHCL(%q) should fail", src)
+		}
+	}
+}
+
+func TestCloudFormationExport(t *testing.T) {
+	env := NewEnvironment("genomics", genomicsPlan())
+	files, err := Generate(FormatCloudFormation, env)
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if len(files) != 1 || files[0].Name != CloudFormationTemplateFile {
+		t.Fatalf("files = %v", files)
+	}
+
+	var template struct {
+		Parameters map[string]struct{ Default string }
+		Resources  map[string]struct{ Type string }
+	}
+	if err := json.Unmarshal([]byte(files[0].Content), &template); err != nil {
+		t.Fatalf("template is not JSON: %v", err)
+	}
+	if template.Parameters["InstanceType"].Default != "r6i.8xlarge" {
+		t.Errorf("instance type = %s", template.Parameters["InstanceType"].Default)
+	}
+	if template.Resources["ResearchInstanceRole"].Type != "AWS::IAM::Role" || template.Resources["ResearchPlacementGroup"].Type == "" {
+		t.Errorf("resources = %v", template.Resources)
+	}
+}
+
+func TestWriteFiles(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "tf")
+	env := NewEnvironment("genomics", genomicsPlan())
+	files, err := Generate(FormatTerraform, env)
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if err := WriteFiles(dir, files); err != nil {
+		t.Fatalf("WriteFiles() error = %v", err)
+	}
+	for _, name := range []string{TerraformMainFile, TerraformVariablesFile, TerraformOutputsFile} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("%s not written: %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,320 @@
+package export
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// This file is a small HCL syntax checker for the generated Terraform. It
+// covers the native syntax the module templates use (blocks, attributes,
+// strings with escapes and interpolation, lists, objects and function calls)
+// and records what each file declares and references, standing in for
+// `terraform validate` without needing the terraform binary.
+
+type hclTokenKind int
+
+const (
+	tokenIdent hclTokenKind = iota
+	tokenString
+	tokenNumber
+	tokenPunct
+	tokenNewline
+)
+
+type hclToken struct {
+	kind hclTokenKind
+	text string
+	line int
+}
+
+// hclFile is what a parsed file declares and references
+type hclFile struct {
+	// blocks are the top-level blocks, as the type followed by their labels
+	blocks [][]string
+	// attributes are the attribute names set in each top-level block, keyed by
+	// the block's type and labels joined with dots
+	attributes map[string][]string
+	// references are the traversals used in expressions, such as var.key_name
+	// or aws_instance.research
+	references []string
+}
+
+func lexHCL(src string) ([]hclToken, error) {
+	var tokens []hclToken
+	runes := []rune(src)
+	line := 1
+
+	var lex func(i int, inTemplate bool) (int, error)
+	lex = func(i int, inTemplate bool) (int, error) {
+		depth := 0
+		for i < len(runes) {
+			r := runes[i]
+			switch {
+			case r == '\n':
+				if !inTemplate {
+					tokens = append(tokens, hclToken{tokenNewline, "\n", line})
+				}
+				line++
+				i++
+			case r == ' ' || r == '\t' || r == '\r':
+				i++
+			case r == '#' || (r == '/' && i+1 < len(runes) && runes[i+1] == '/'):
+				for i < len(runes) && runes[i] != '\n' {
+					i++
+				}
+			case r == '"':
+				end, err := lexString(runes, i+1, &line, func(start int) (int, error) {
+					return lex(start, true)
+				})
+				if err != nil {
+					return 0, err
+				}
+				tokens = append(tokens, hclToken{tokenString, string(runes[i:end]), line})
+				i = end
+			case unicode.IsLetter(r) || r == '_':
+				start := i
+				for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_' || runes[i] == '-') {
+					i++
+				}
+				tokens = append(tokens, hclToken{tokenIdent, string(runes[start:i]), line})
+			case unicode.IsDigit(r):
+				start := i
+				for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+					i++
+				}
+				tokens = append(tokens, hclToken{tokenNumber, string(runes[start:i]), line})
+			case r == '<' && i+1 < len(runes) && runes[i+1] == '<':
+				return 0, fmt.Errorf("line %d: heredocs are not supported", line)
+			case strings.ContainsRune("{}[]()=,.:?!<>+-*/%&|", r):
+				if inTemplate {
+					if r == '{' {
+						depth++
+					} else if r == '}' {
+						if depth == 0 {
+							return i + 1, nil
+						}
+						depth--
+					}
+				}
+				tokens = append(tokens, hclToken{tokenPunct, string(r), line})
+				i++
+			default:
+				return 0, fmt.Errorf("line %d: unexpected character %q", line, r)
+			}
+		}
+		if inTemplate {
+			return 0, fmt.Errorf("line %d: unterminated interpolation", line)
+		}
+		return i, nil
+	}
+
+	_, err := lex(0, false)
+	return tokens, err
+}
+
+// lexString scans a quoted string starting after its opening quote and returns
+// the index after its closing quote. Interpolations are lexed with lexTemplate.
+func lexString(runes []rune, i int, line *int, lexTemplate func(int) (int, error)) (int, error) {
+	for i < len(runes) {
+		switch r := runes[i]; {
+		case r == '"':
+			return i + 1, nil
+		case r == '\n':
+			return 0, fmt.Errorf("line %d: newline in string", *line)
+		case r == '\\':
+			if i+1 >= len(runes) {
+				return 0, fmt.Errorf("line %d: unterminated escape", *line)
+			}
+			switch runes[i+1] {
+			case 'n', 'r', 't', '"', '\\':
+				i += 2
+			case 'u':
+				if i+6 > len(runes) || !isHex(runes[i+2:i+6]) {
+					return 0, fmt.Errorf("line %d: invalid unicode escape", *line)
+				}
+				i += 6
+			default:
+				return 0, fmt.Errorf("line %d: invalid escape \\%c", *line, runes[i+1])
+			}
+		case (r == '$' || r == '%') && i+2 < len(runes) && runes[i+1] == r && runes[i+2] == '{':
+			// $${ and %%{ are literal
+			i += 3
+		case r == '$' && i+1 < len(runes) && runes[i+1] == '{':
+			end, err := lexTemplate(i + 2)
+			if err != nil {
+				return 0, err
+			}
+			i = end
+		case r == '%' && i+1 < len(runes) && runes[i+1] == '{':
+			return 0, fmt.Errorf("line %d: template directives are not supported", *line)
+		default:
+			i++
+		}
+	}
+	return 0, fmt.Errorf("line %d: unterminated string", *line)
+}
+
+func isHex(runes []rune) bool {
+	for _, r := range runes {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", r) {
+			return false
+		}
+	}
+	return true
+}
+
+type hclParser struct {
+	tokens []hclToken
+	pos    int
+	file   *hclFile
+}
+
+// parseHCL checks a file's syntax and returns its declarations and references
+func parseHCL(src string) (*hclFile, error) {
+	tokens, err := lexHCL(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &hclParser{tokens: tokens, file: &hclFile{attributes: make(map[string][]string)}}
+	if err := p.parseBody(true, ""); err != nil {
+		return nil, err
+	}
+	return p.file, nil
+}
+
+func (p *hclParser) peek() *hclToken {
+	if p.pos < len(p.tokens) {
+		return &p.tokens[p.pos]
+	}
+	return nil
+}
+
+func (p *hclParser) errorf(format string, args ...interface{}) error {
+	line := 0
+	if tok := p.peek(); tok != nil {
+		line = tok.line
+	} else if len(p.tokens) > 0 {
+		line = p.tokens[len(p.tokens)-1].line
+	}
+	return fmt.Errorf("line %d: %s", line, fmt.Sprintf(format, args...))
+}
+
+func (p *hclParser) skipNewlines() {
+	for tok := p.peek(); tok != nil && tok.kind == tokenNewline; tok = p.peek() {
+		p.pos++
+	}
+}
+
+// parseBody parses attributes and blocks until the closing brace of the
+// enclosing block, or the end of the file at the top level. Block is the
+// address attributes are recorded under.
+func (p *hclParser) parseBody(topLevel bool, block string) error {
+	for {
+		p.skipNewlines()
+		tok := p.peek()
+		if tok == nil {
+			if topLevel {
+				return nil
+			}
+			return p.errorf("unclosed block")
+		}
+		if tok.kind == tokenPunct && tok.text == "}" {
+			if topLevel {
+				return p.errorf("unexpected }")
+			}
+			return nil
+		}
+		if tok.kind != tokenIdent {
+			return p.errorf("expected an attribute or block, got %q", tok.text)
+		}
+		name := tok.text
+		p.pos++
+
+		if next := p.peek(); next != nil && next.kind == tokenPunct && next.text == "=" {
+			p.pos++
+			if err := p.parseExpression(); err != nil {
+				return err
+			}
+			if block != "" {
+				p.file.attributes[block] = append(p.file.attributes[block], name)
+			}
+			continue
+		}
+
+		header := []string{name}
+		for next := p.peek(); next != nil && (next.kind == tokenString || next.kind == tokenIdent); next = p.peek() {
+			header = append(header, strings.Trim(next.text, `"`))
+			p.pos++
+		}
+		if next := p.peek(); next == nil || next.kind != tokenPunct || next.text != "{" {
+			return p.errorf("expected { after block %s", strings.Join(header, " "))
+		}
+		p.pos++
+
+		address := block
+		if topLevel {
+			p.file.blocks = append(p.file.blocks, header)
+			address = strings.Join(header, ".")
+		}
+		if next := p.peek(); next != nil && next.kind == tokenPunct && next.text == "}" {
+			// single-line empty block such as filter {}
+			p.pos++
+		} else {
+			if next == nil || next.kind != tokenNewline {
+				return p.errorf("block %s must start a new line after {", strings.Join(header, " "))
+			}
+			if err := p.parseBody(false, address); err != nil {
+				return err
+			}
+			p.pos++
+		}
+		if next := p.peek(); next != nil && next.kind != tokenNewline {
+			return p.errorf("expected a newline after block %s", strings.Join(header, " "))
+		}
+	}
+}
+
+// parseExpression consumes an attribute value up to the end of its line,
+// allowing newlines inside brackets, and records the traversals it uses
+func (p *hclParser) parseExpression() error {
+	var stack []string
+	empty := true
+	closers := map[string]string{"}": "{", "]": "[", ")": "("}
+
+	for tok := p.peek(); tok != nil; tok = p.peek() {
+		if tok.kind == tokenNewline && len(stack) == 0 {
+			break
+		}
+		switch {
+		case tok.kind == tokenPunct && strings.Contains("{[(", tok.text):
+			stack = append(stack, tok.text)
+		case tok.kind == tokenPunct && closers[tok.text] != "":
+			if len(stack) == 0 {
+				if tok.text == "}" && !empty {
+					// closes the enclosing single-line block
+					return nil
+				}
+				return p.errorf("unbalanced %s", tok.text)
+			}
+			if stack[len(stack)-1] != closers[tok.text] {
+				return p.errorf("mismatched %s", tok.text)
+			}
+			stack = stack[:len(stack)-1]
+		case tok.kind == tokenIdent && p.pos+2 < len(p.tokens) && p.tokens[p.pos+1].text == "." && p.tokens[p.pos+2].kind == tokenIdent:
+			if p.pos == 0 || p.tokens[p.pos-1].text != "." {
+				p.file.references = append(p.file.references, tok.text+"."+p.tokens[p.pos+2].text)
+			}
+		}
+		empty = false
+		p.pos++
+	}
+
+	if len(stack) > 0 {
+		return p.errorf("unclosed %s", stack[len(stack)-1])
+	}
+	if empty {
+		return p.errorf("missing attribute value")
+	}
+	return nil
+}
@@ -0,0 +1,428 @@
+package export
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/aws/templates"
+)
+
+// Terraform module file names
+const (
+	TerraformMainFile      = "main.tf"
+	TerraformVariablesFile = "variables.tf"
+	TerraformOutputsFile   = "outputs.tf"
+)
+
+// terraformModule is the data the module templates are rendered from
+type terraformModule struct {
+	Environment
+	ManagedPolicies []string
+	UserData        string
+	// ImageID is the AMI, or an SSM parameter reference EC2 resolves at launch
+	ImageID string
+}
+
+// Terraform renders the environment as a Terraform module of main.tf,
+// variables.tf and outputs.tf. Every setting from the plan becomes a variable
+// default, so the module can be adjusted without editing the resources.
+func Terraform(env Environment) ([]File, error) {
+	if err := env.Options.Validate(templates.ArchitectureSingle); err != nil {
+		return nil, fmt.Errorf("invalid terraform options: %w", err)
+	}
+	if env.VolumeType == "" {
+		return nil, fmt.Errorf("invalid terraform options: volume type is required")
+	}
+	if env.Compliance.Settings().Enforced() {
+		return nil, fmt.Errorf("the %s compliance profile is only exported as CloudFormation", env.Compliance)
+	}
+
+	module := terraformModule{
+		Environment:     env,
+		ManagedPolicies: templates.InstanceRolePolicies(env.Options),
+		UserData:        templates.ResearchUserData,
+		ImageID:         env.ImageID,
+	}
+	if parameter := env.ImageParameter(); parameter != "" {
+		module.ImageID = "resolve:ssm:" + parameter
+	}
+
+	files := []File{
+		{Name: TerraformMainFile},
+		{Name: TerraformVariablesFile},
+		{Name: TerraformOutputsFile},
+	}
+	for i := range files {
+		var buf bytes.Buffer
+		if err := terraformTemplates.ExecuteTemplate(&buf, files[i].Name, module); err != nil {
+			return nil, fmt.Errorf("failed to render %s: %w", files[i].Name, err)
+		}
+		files[i].Content = buf.String()
+	}
+	return files, nil
+}
+
+// hclString quotes a value as an HCL string literal. Template sequences are
+// escaped so values are never interpolated.
+func hclString(value string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	runes := []rune(value)
+	for i, r := range runes {
+		switch {
+		case r == '"':
+			b.WriteString(`\"`)
+		case r == '\\':
+			b.WriteString(`\\`)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r < 0x20 || r == 0x7f:
+			fmt.Fprintf(&b, `\u%04X`, r)
+		case (r == '$' || r == '%') && i+1 < len(runes) && runes[i+1] == '{':
+			b.WriteRune(r)
+			b.WriteRune(r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// hclList renders strings as an HCL list of string literals
+func hclList(values []string) string {
+	quoted := make([]string, len(values))
+	for i, value := range values {
+		quoted[i] = hclString(value)
+	}
+	return "[" + strings.Join(quoted, ", ") + "]"
+}
+
+var terraformTemplates = template.Must(template.New("terraform").Funcs(template.FuncMap{
+	"hcl":     hclString,
+	"hclList": hclList,
+	"bool":    strconv.FormatBool,
+}).Parse(terraformMainTemplate + terraformVariablesTemplate + terraformOutputsTemplate))
+
+const terraformMainTemplate = `{{define "main.tf"}}# Research environment generated by aws-research-wizard from an
+# intelligent recommendation. Settings are in variables.tf.
+
+terraform {
+  required_version = ">= 1.3"
+
+  required_providers {
+    aws = {
+      source  = "hashicorp/aws"
+      version = ">= 5.0"
+    }
+  }
+}
+
+locals {
+  tags = merge(var.tags, {
+    Domain    = var.domain_name
+    CreatedBy = "AWS-Research-Wizard"
+  })
+}
+
+resource "aws_security_group" "research" {
+  name_prefix = "research-wizard-"
+  description = "Security group for research environment"
+  vpc_id      = var.vpc_id
+
+  ingress {
+    description = "SSH"
+    from_port   = 22
+    to_port     = 22
+    protocol    = "tcp"
+    cidr_blocks = [var.ssh_cidr]
+  }
+
+  ingress {
+    description = "Jupyter"
+    from_port   = 8888
+    to_port     = 8888
+    protocol    = "tcp"
+    cidr_blocks = [var.ssh_cidr]
+  }
+
+  egress {
+    from_port   = 0
+    to_port     = 0
+    protocol    = "-1"
+    cidr_blocks = ["0.0.0.0/0"]
+  }
+
+  tags = merge(local.tags, { Name = "research-wizard-sg" })
+}
+{{- if .IAMRole}}
+
+resource "aws_iam_role" "research" {
+  name_prefix        = "research-wizard-"
+  assume_role_policy = jsonencode({
+    Version   = "2012-10-17"
+    Statement = [{
+      Effect    = "Allow"
+      Principal = { Service = "ec2.amazonaws.com" }
+      Action    = "sts:AssumeRole"
+    }]
+  })
+
+  tags = local.tags
+}
+
+resource "aws_iam_role_policy_attachment" "research" {
+  for_each   = toset(var.managed_policy_arns)
+  role       = aws_iam_role.research.name
+  policy_arn = each.value
+}
+
+resource "aws_iam_instance_profile" "research" {
+  name_prefix = "research-wizard-"
+  role        = aws_iam_role.research.name
+  tags        = local.tags
+}
+{{- end}}
+{{- if .PlacementGroup}}
+
+resource "aws_placement_group" "research" {
+  name     = "research-wizard-${var.domain_name}"
+  strategy = "cluster"
+  tags     = local.tags
+}
+{{- end}}
+
+resource "aws_instance" "research" {
+  ami                    = var.ami_id
+  instance_type          = var.instance_type
+  key_name               = var.key_name
+  subnet_id              = var.subnet_id
+  vpc_security_group_ids = [aws_security_group.research.id]
+{{- if .IAMRole}}
+  iam_instance_profile   = aws_iam_instance_profile.research.name
+{{- end}}
+{{- if .PlacementGroup}}
+  placement_group        = aws_placement_group.research.id
+{{- end}}
+  user_data              = {{hcl .UserData}}
+
+  root_block_device {
+    volume_size = var.root_volume_size_gb
+    volume_type = var.root_volume_type
+{{- if .VolumeIOPS}}
+    iops        = var.root_volume_iops
+{{- end}}
+{{- if .VolumeThroughput}}
+    throughput  = var.root_volume_throughput
+{{- end}}
+    encrypted   = var.encrypt_volumes
+  }
+
+  tags = merge(local.tags, { Name = "research-wizard-instance" })
+}
+{{- if .DataBucket}}
+
+resource "aws_s3_bucket" "data" {
+  bucket_prefix = "research-wizard-"
+  tags          = local.tags
+}
+
+resource "aws_s3_bucket_public_access_block" "data" {
+  bucket                  = aws_s3_bucket.data.id
+  block_public_acls       = true
+  block_public_policy     = true
+  ignore_public_acls      = true
+  restrict_public_buckets = true
+}
+
+resource "aws_s3_bucket_server_side_encryption_configuration" "data" {
+  bucket = aws_s3_bucket.data.id
+
+  rule {
+    apply_server_side_encryption_by_default {
+      sse_algorithm = "AES256"
+    }
+  }
+}
+
+resource "aws_s3_bucket_lifecycle_configuration" "data" {
+  bucket = aws_s3_bucket.data.id
+
+  rule {
+    id     = "research-data-tiering"
+    status = "Enabled"
+
+    filter {}
+{{- if .BackupStorageClass}}
+
+    transition {
+      days          = var.backup_after_days
+      storage_class = {{hcl .BackupStorageClass}}
+    }
+{{- end}}
+{{- if .ArchiveStorageClass}}
+
+    transition {
+      days          = var.archive_after_days
+      storage_class = {{hcl .ArchiveStorageClass}}
+    }
+{{- end}}
+  }
+}
+{{- end}}
+{{end}}`
+
+const terraformVariablesTemplate = `{{define "variables.tf"}}variable "domain_name" {
+  description = "Research domain name"
+  type        = string
+  default     = {{hcl .DomainName}}
+}
+
+variable "instance_type" {
+  description = "EC2 instance type for the research environment"
+  type        = string
+  default     = {{hcl .InstanceType}}
+}
+
+variable "ami_id" {
+  description = "AMI the research instance boots from"
+  type        = string
+  default     = {{hcl .ImageID}}
+}
+
+variable "key_name" {
+  description = "EC2 key pair for SSH access"
+  type        = string
+}
+
+variable "ssh_cidr" {
+  description = "CIDR range allowed to reach SSH and Jupyter"
+  type        = string
+  default     = {{hcl .SSHCIDR}}
+}
+
+variable "vpc_id" {
+  description = "VPC for the security group; null uses the default VPC"
+  type        = string
+  default     = null
+}
+
+variable "subnet_id" {
+  description = "Subnet for the instance; null uses a default subnet"
+  type        = string
+  default     = null
+}
+
+variable "root_volume_size_gb" {
+  description = "Root volume size in GB"
+  type        = number
+  default     = {{.VolumeSizeGB}}
+}
+
+variable "root_volume_type" {
+  description = "Root volume EBS type"
+  type        = string
+  default     = {{hcl .VolumeType}}
+}
+{{- if .VolumeIOPS}}
+
+variable "root_volume_iops" {
+  description = "Provisioned IOPS of the root volume"
+  type        = number
+  default     = {{.VolumeIOPS}}
+}
+{{- end}}
+{{- if .VolumeThroughput}}
+
+variable "root_volume_throughput" {
+  description = "Provisioned throughput of the root volume in MiB/s"
+  type        = number
+  default     = {{.VolumeThroughput}}
+}
+{{- end}}
+
+variable "encrypt_volumes" {
+  description = "Encrypt the root volume"
+  type        = bool
+  default     = {{bool .EncryptVolume}}
+}
+{{- if .IAMRole}}
+
+variable "managed_policy_arns" {
+  description = "Managed policies attached to the instance role"
+  type        = list(string)
+  default     = {{hclList .ManagedPolicies}}
+}
+{{- end}}
+{{- if .DataBucket}}
+{{- if .BackupStorageClass}}
+
+variable "backup_after_days" {
+  description = "Days before data bucket objects move to {{.BackupStorageClass}}"
+  type        = number
+  default     = {{.BackupAfterDays}}
+}
+{{- end}}
+{{- if .ArchiveStorageClass}}
+
+variable "archive_after_days" {
+  description = "Days before data bucket objects move to {{.ArchiveStorageClass}}"
+  type        = number
+  default     = {{.ArchiveAfterDays}}
+}
+{{- end}}
+{{- end}}
+
+variable "tags" {
+  description = "Additional tags for every resource"
+  type        = map(string)
+  default     = {}
+}
+{{end}}`
+
+const terraformOutputsTemplate = `{{define "outputs.tf"}}output "instance_id" {
+  description = "Instance ID of the research environment"
+  value       = aws_instance.research.id
+}
+
+output "public_ip" {
+  description = "Public IP address of the research environment"
+  value       = aws_instance.research.public_ip
+}
+
+output "private_ip" {
+  description = "Private IP address of the research environment"
+  value       = aws_instance.research.private_ip
+}
+
+output "security_group_id" {
+  description = "Security group ID"
+  value       = aws_security_group.research.id
+}
+
+output "ssh_command" {
+  description = "SSH command to connect to the instance"
+  value       = "ssh -i ~/.ssh/${var.key_name}.pem ec2-user@${aws_instance.research.public_ip}"
+}
+{{- if .IAMRole}}
+
+output "instance_role_arn" {
+  description = "ARN of the instance role"
+  value       = aws_iam_role.research.arn
+}
+{{- end}}
+{{- if .DataBucket}}
+
+output "data_bucket" {
+  description = "S3 bucket for research data"
+  value       = aws_s3_bucket.data.bucket
+}
+{{- end}}
+{{end}}`
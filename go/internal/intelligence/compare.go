@@ -0,0 +1,176 @@
+package intelligence
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Compared recommendation fields, in display order
+const (
+	DeltaDomain      = "domain"
+	DeltaMode        = "mode"
+	DeltaInstance    = "instance"
+	DeltaMonthlyCost = "monthly_cost"
+	DeltaStorage     = "storage"
+	DeltaComplexity  = "complexity"
+	DeltaConfidence  = "confidence"
+)
+
+// deltaEpsilon is the smallest numeric difference reported as a change
+const deltaEpsilon = 0.005
+
+// FieldDelta compares one field of two recommendations
+type FieldDelta struct {
+	Field   string `json:"field"`
+	A       string `json:"a"`
+	B       string `json:"b"`
+	Changed bool   `json:"changed"`
+	// Difference is B minus A for numeric fields
+	Difference *float64 `json:"difference,omitempty"`
+}
+
+// RecommendationComparison is a what-if analysis of the same data under two
+// sets of hints
+type RecommendationComparison struct {
+	DataPath string                     `json:"data_path"`
+	HintsA   DomainHints                `json:"hints_a"`
+	HintsB   DomainHints                `json:"hints_b"`
+	A        *IntelligentRecommendation `json:"a"`
+	B        *IntelligentRecommendation `json:"b"`
+	Delta    []FieldDelta               `json:"delta"`
+}
+
+// Changed returns the fields that differ between the two recommendations
+func (c *RecommendationComparison) Changed() []FieldDelta {
+	var changed []FieldDelta
+	for _, delta := range c.Delta {
+		if delta.Changed {
+			changed = append(changed, delta)
+		}
+	}
+	return changed
+}
+
+// CompareRecommendations recommends an environment for the data under each
+// set of hints and reports what differs. The data is analyzed once and the
+// analysis shared by both recommendations.
+func (ie *IntelligenceEngine) CompareRecommendations(
+	ctx context.Context,
+	dataPath string,
+	hintsA DomainHints,
+	hintsB DomainHints,
+) (*RecommendationComparison, error) {
+
+	dataRecommendations, err := ie.recommendationEngine.GenerateRecommendations(ctx, dataPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate data recommendations: %w", err)
+	}
+
+	a, err := ie.recommendFromAnalysis(dataPath, hintsA, dataRecommendations)
+	if err != nil {
+		return nil, fmt.Errorf("hints A: %w", err)
+	}
+	b, err := ie.recommendFromAnalysis(dataPath, hintsB, dataRecommendations)
+	if err != nil {
+		return nil, fmt.Errorf("hints B: %w", err)
+	}
+
+	return &RecommendationComparison{
+		DataPath: dataPath,
+		HintsA:   hintsA,
+		HintsB:   hintsB,
+		A:        a,
+		B:        b,
+		Delta:    RecommendationDelta(a, b),
+	}, nil
+}
+
+// RecommendationDelta compares the fields of two recommendations that decide
+// what gets deployed and what it costs
+func RecommendationDelta(a, b *IntelligentRecommendation) []FieldDelta {
+	return []FieldDelta{
+		textDelta(DeltaDomain, a.Domain, b.Domain),
+		textDelta(DeltaMode, a.Mode, b.Mode),
+		textDelta(DeltaInstance, recommendedInstance(a), recommendedInstance(b)),
+		numberDelta(DeltaMonthlyCost, monthlyCost(a), monthlyCost(b), "$%.2f"),
+		textDelta(DeltaStorage, storageSummary(a), storageSummary(b)),
+		textDelta(DeltaComplexity, complexity(a), complexity(b)),
+		numberDelta(DeltaConfidence, a.Confidence, b.Confidence, "%.2f"),
+	}
+}
+
+func textDelta(field, a, b string) FieldDelta {
+	return FieldDelta{Field: field, A: a, B: b, Changed: a != b}
+}
+
+func numberDelta(field string, a, b float64, format string) FieldDelta {
+	difference := b - a
+	return FieldDelta{
+		Field:      field,
+		A:          fmt.Sprintf(format, a),
+		B:          fmt.Sprintf(format, b),
+		Changed:    math.Abs(difference) >= deltaEpsilon,
+		Difference: &difference,
+	}
+}
+
+func recommendedInstance(rec *IntelligentRecommendation) string {
+	if rec.ResourcePlan == nil {
+		return ""
+	}
+	return rec.ResourcePlan.RecommendedInstance
+}
+
+func monthlyCost(rec *IntelligentRecommendation) float64 {
+	if rec.CostOptimization == nil {
+		return 0
+	}
+	return rec.CostOptimization.EstimatedMonthlyCost
+}
+
+// storageSummary describes the primary volume and the S3 classes data moves to
+func storageSummary(rec *IntelligentRecommendation) string {
+	if rec.ResourcePlan == nil {
+		return ""
+	}
+	storage := rec.ResourcePlan.StorageConfiguration
+	summary := fmt.Sprintf("%d GB %s", storage.PrimaryStorage.SizeGB, storage.PrimaryStorage.Type)
+	if storage.PrimaryStorage.IOPS > 0 {
+		summary += fmt.Sprintf(" %d IOPS", storage.PrimaryStorage.IOPS)
+	}
+	if storage.ArchiveStorage.Type != "" {
+		summary += ", archive " + storage.ArchiveStorage.Type
+	}
+	return summary
+}
+
+func complexity(rec *IntelligentRecommendation) string {
+	if rec.Implementation == nil {
+		return ""
+	}
+	return rec.Implementation.Complexity
+}
+
+// LoadDomainHints reads a hint set from a YAML file using the DomainHints
+// field names, e.g. explicit_domain and tool_hints
+func LoadDomainHints(path string) (DomainHints, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return DomainHints{}, fmt.Errorf("failed to read hints file: %w", err)
+	}
+
+	var hints DomainHints
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&hints); err != nil && !errors.Is(err, io.EOF) {
+		return DomainHints{}, fmt.Errorf("failed to parse hints file %s: %w", path, err)
+	}
+	return hints, nil
+}
@@ -0,0 +1,166 @@
+package intelligence
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/data"
+)
+
+// countingRecommendationEngine counts the data scans it is asked for
+type countingRecommendationEngine struct {
+	mockRecommendationEngine
+	scans int
+}
+
+func (c *countingRecommendationEngine) GenerateRecommendations(ctx context.Context, dataPath string) (*data.RecommendationResult, error) {
+	c.scans++
+	return c.mockRecommendationEngine.GenerateRecommendations(ctx, dataPath)
+}
+
+func fixtureRecommendation(domain, instance string, cost float64, complexity string, confidence float64) *IntelligentRecommendation {
+	return &IntelligentRecommendation{
+		Domain: domain,
+		Mode:   ModeAutomatic,
+		ResourcePlan: &ResourcePlan{
+			RecommendedInstance: instance,
+			StorageConfiguration: StorageConfiguration{
+				PrimaryStorage: StorageType{Type: "gp3", SizeGB: 500, IOPS: 3000},
+				ArchiveStorage: StorageType{Type: "s3_glacier", SizeGB: 500},
+			},
+		},
+		CostOptimization: &CostOptimizationPlan{EstimatedMonthlyCost: cost},
+		Implementation:   &ImplementationPlan{Complexity: complexity},
+		Confidence:       confidence,
+	}
+}
+
+func TestRecommendationDelta(t *testing.T) {
+	a := fixtureRecommendation("general", "m6i.xlarge", 150, "low", 0.4)
+	b := fixtureRecommendation("climate", "c6i.4xlarge", 480.25, "medium", 0.8)
+	b.ResourcePlan.StorageConfiguration.PrimaryStorage.SizeGB = 1000
+
+	delta := RecommendationDelta(a, b)
+	fields := []string{DeltaDomain, DeltaMode, DeltaInstance, DeltaMonthlyCost, DeltaStorage, DeltaComplexity, DeltaConfidence}
+	if len(delta) != len(fields) {
+		t.Fatalf("RecommendationDelta() = %+v, want %d fields", delta, len(fields))
+	}
+	for i, field := range fields {
+		if delta[i].Field != field {
+			t.Errorf("delta[%d].Field = %s, want %s", i, delta[i].Field, field)
+		}
+	}
+
+	byField := make(map[string]FieldDelta)
+	for _, d := range delta {
+		byField[d.Field] = d
+	}
+	if d := byField[DeltaInstance]; !d.Changed || d.A != "m6i.xlarge" || d.B != "c6i.4xlarge" {
+		t.Errorf("instance delta = %+v", d)
+	}
+	if d := byField[DeltaMonthlyCost]; !d.Changed || d.A != "$150.00" || d.B != "$480.25" || d.Difference == nil || *d.Difference != 330.25 {
+		t.Errorf("monthly cost delta = %+v", d)
+	}
+	if d := byField[DeltaStorage]; !d.Changed || d.A != "500 GB gp3 3000 IOPS, archive s3_glacier" || d.B != "1000 GB gp3 3000 IOPS, archive s3_glacier" {
+		t.Errorf("storage delta = %+v", d)
+	}
+	if d := byField[DeltaComplexity]; !d.Changed || d.A != "low" || d.B != "medium" {
+		t.Errorf("complexity delta = %+v", d)
+	}
+	if d := byField[DeltaConfidence]; !d.Changed || *d.Difference < 0.39 || *d.Difference > 0.41 {
+		t.Errorf("confidence delta = %+v", d)
+	}
+	if d := byField[DeltaMode]; d.Changed {
+		t.Errorf("mode delta = %+v, want unchanged", d)
+	}
+
+	comparison := &RecommendationComparison{Delta: delta}
+	if changed := comparison.Changed(); len(changed) != 6 {
+		t.Errorf("Changed() = %+v, want 6 fields", changed)
+	}
+}
+
+func TestRecommendationDeltaUnchanged(t *testing.T) {
+	a := fixtureRecommendation("genomics", "r6i.4xlarge", 700, "medium", 0.8)
+	b := fixtureRecommendation("genomics", "r6i.4xlarge", 700.001, "medium", 0.8)
+
+	comparison := &RecommendationComparison{Delta: RecommendationDelta(a, b)}
+	if changed := comparison.Changed(); len(changed) != 0 {
+		t.Errorf("Changed() = %+v, want no changes below rounding", changed)
+	}
+}
+
+func TestRecommendationDeltaMissingPlans(t *testing.T) {
+	a := &IntelligentRecommendation{Domain: "general"}
+	b := fixtureRecommendation("general", "m6i.xlarge", 150, "low", 0.4)
+
+	for _, d := range RecommendationDelta(a, b) {
+		if d.Field == DeltaInstance && (d.A != "" || !d.Changed) {
+			t.Errorf("instance delta = %+v", d)
+		}
+	}
+}
+
+func TestCompareRecommendations(t *testing.T) {
+	ie := createTestIntelligenceEngine()
+	engine := &countingRecommendationEngine{}
+	ie.recommendationEngine = engine
+	ie.domainPackLoader.(*mockDomainPackLoader).domainPacks["climate"] = &DomainPackInfo{Name: "climate"}
+
+	comparison, err := ie.CompareRecommendations(context.Background(), "/data/run-42",
+		DomainHints{ExplicitDomain: "genomics"},
+		DomainHints{ExplicitDomain: "climate"})
+	if err != nil {
+		t.Fatalf("CompareRecommendations() error = %v", err)
+	}
+
+	if engine.scans != 1 {
+		t.Errorf("data scanned %d times, want once", engine.scans)
+	}
+	if comparison.A.DataAnalysis != comparison.B.DataAnalysis {
+		t.Error("recommendations do not share the data analysis")
+	}
+	if comparison.A.Domain != "genomics" || comparison.B.Domain != "climate" {
+		t.Errorf("domains = %s, %s", comparison.A.Domain, comparison.B.Domain)
+	}
+	if comparison.Delta[0].Field != DeltaDomain || !comparison.Delta[0].Changed {
+		t.Errorf("domain delta = %+v", comparison.Delta[0])
+	}
+
+	if _, err := ie.CompareRecommendations(context.Background(), "/data/run-42",
+		DomainHints{ExplicitDomain: "genomics"},
+		DomainHints{ExplicitDomain: "astronomy"}); err == nil {
+		t.Error("CompareRecommendations() with an unknown domain pack succeeded")
+	}
+}
+
+func TestLoadDomainHints(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	hints, err := LoadDomainHints(write("climate.yaml", "explicit_domain: climate\ntool_hints: [cdo, nco]\nbudget_constraint: 500\n"))
+	if err != nil {
+		t.Fatalf("LoadDomainHints() error = %v", err)
+	}
+	if hints.ExplicitDomain != "climate" || len(hints.ToolHints) != 2 || hints.BudgetConstraint != 500 {
+		t.Errorf("hints = %+v", hints)
+	}
+
+	if hints, err := LoadDomainHints(write("empty.yaml", "")); err != nil || hints.ExplicitDomain != "" {
+		t.Errorf("LoadDomainHints() of an empty file = %+v, %v", hints, err)
+	}
+	if _, err := LoadDomainHints(write("typo.yaml", "explicit_domian: climate\n")); err == nil {
+		t.Error("LoadDomainHints() accepted an unknown field")
+	}
+	if _, err := LoadDomainHints(filepath.Join(dir, "missing.yaml")); err == nil {
+		t.Error("LoadDomainHints() of a missing file succeeded")
+	}
+}
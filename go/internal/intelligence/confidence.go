@@ -0,0 +1,136 @@
+package intelligence
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Recommendation modes, from how far a recommendation can be acted on without review
+const (
+	// ModeAutomatic recommendations may be applied as they are
+	ModeAutomatic = "automatic"
+	// ModeAdvisory recommendations are a plausible guess to review before applying
+	ModeAdvisory = "advisory"
+	// ModeInsufficientData means there was too little evidence to recommend anything
+	ModeInsufficientData = "insufficient-data"
+)
+
+// Default confidence thresholds. An explicit domain scores 0.8, so naming the
+// domain is enough for automatic mode when the dataset is large enough.
+const (
+	DefaultAutomaticConfidence = 0.7
+	DefaultAdvisoryConfidence  = AmbiguousConfidenceThreshold
+	DefaultMinFiles            = 10
+)
+
+// ConfidenceThresholds decide a recommendation's mode from its detection
+// confidence and the number of files behind it
+type ConfidenceThresholds struct {
+	// Automatic is the confidence at or above which recommendations apply without review
+	Automatic float64
+	// Advisory is the confidence below which there is too little evidence to recommend
+	Advisory float64
+	// MinFiles is the fewest files analyzed for anything but insufficient data
+	MinFiles int64
+}
+
+// DefaultConfidenceThresholds returns the default thresholds
+func DefaultConfidenceThresholds() ConfidenceThresholds {
+	return ConfidenceThresholds{
+		Automatic: DefaultAutomaticConfidence,
+		Advisory:  DefaultAdvisoryConfidence,
+		MinFiles:  DefaultMinFiles,
+	}
+}
+
+// Validate checks that 0 <= Advisory <= Automatic <= 1
+func (t ConfidenceThresholds) Validate() error {
+	if t.Advisory < 0 || t.Automatic > 1 || t.Advisory > t.Automatic {
+		return fmt.Errorf("confidence thresholds must satisfy 0 <= advisory (%.2f) <= automatic (%.2f) <= 1", t.Advisory, t.Automatic)
+	}
+	if t.MinFiles < 0 {
+		return fmt.Errorf("minimum files must not be negative, got %d", t.MinFiles)
+	}
+	return nil
+}
+
+// RecommendationMode derives a recommendation's mode. Ambiguous detection is
+// never automatic, however high the winning score.
+func RecommendationMode(confidence float64, ambiguous bool, filesAnalyzed int64, t ConfidenceThresholds) string {
+	switch {
+	case filesAnalyzed < t.MinFiles || confidence < t.Advisory:
+		return ModeInsufficientData
+	case confidence >= t.Automatic && !ambiguous:
+		return ModeAutomatic
+	}
+	return ModeAdvisory
+}
+
+// ConfidenceChecklist lists the hints that would raise confidence in a
+// detection, skipping those already given
+func ConfidenceChecklist(detection *DomainDetectionExplanation, hints DomainHints, filesAnalyzed int64, t ConfidenceThresholds) []string {
+	var checklist []string
+	if hints.ExplicitDomain == "" {
+		item := "Name the research domain with --domain"
+		if len(detection.TopCandidates) > 0 {
+			item += fmt.Sprintf(" (closest matches: %s)", strings.Join(detection.TopCandidates, ", "))
+		}
+		checklist = append(checklist, item)
+	}
+	if filesAnalyzed < t.MinFiles {
+		checklist = append(checklist, fmt.Sprintf(
+			"Scan deeper: only %d file(s) were analyzed and at least %d are needed; point at the dataset's top-level directory",
+			filesAnalyzed, t.MinFiles))
+	}
+	if hints.InventoryFiles == 0 {
+		checklist = append(checklist,
+			"Describe the whole dataset with --inventory <manifest> (one path per line, or an S3 Inventory CSV)")
+	}
+	return checklist
+}
+
+// ReadInventoryManifest reads a listing of a dataset's files and returns the
+// file extensions found and the number of files. Each line is a path, or an S3
+// Inventory CSV row of bucket, key and further columns.
+func ReadInventoryManifest(r io.Reader) ([]string, int64, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	reader.ReuseRecord = true
+
+	extensions := make(map[string]bool)
+	var files int64
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to read inventory manifest: %w", err)
+		}
+
+		key := strings.TrimSpace(record[0])
+		if len(record) > 1 {
+			key = strings.TrimSpace(record[1])
+		}
+		if key == "" || strings.HasSuffix(key, "/") {
+			continue
+		}
+		files++
+		if ext := strings.ToLower(path.Ext(key)); ext != "" {
+			extensions[ext] = true
+		}
+	}
+
+	sorted := make([]string, 0, len(extensions))
+	for ext := range extensions {
+		sorted = append(sorted, ext)
+	}
+	sort.Strings(sorted)
+	return sorted, files, nil
+}
@@ -0,0 +1,146 @@
+package intelligence
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestRecommendationMode(t *testing.T) {
+	thresholds := DefaultConfidenceThresholds()
+	tests := []struct {
+		name       string
+		confidence float64
+		ambiguous  bool
+		files      int64
+		want       string
+	}{
+		{"at automatic threshold", 0.7, false, 500, ModeAutomatic},
+		{"just below automatic", 0.69, false, 500, ModeAdvisory},
+		{"ambiguous above automatic", 0.9, true, 500, ModeAdvisory},
+		{"at advisory threshold", 0.3, false, 500, ModeAdvisory},
+		{"just below advisory", 0.29, false, 500, ModeInsufficientData},
+		{"at minimum files", 1.0, false, 10, ModeAutomatic},
+		{"just below minimum files", 1.0, false, 9, ModeInsufficientData},
+		{"no files", 0.8, false, 0, ModeInsufficientData},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RecommendationMode(tt.confidence, tt.ambiguous, tt.files, thresholds); got != tt.want {
+				t.Errorf("RecommendationMode(%.2f, %v, %d) = %s, want %s", tt.confidence, tt.ambiguous, tt.files, got, tt.want)
+			}
+		})
+	}
+
+	// Raising the automatic threshold turns the same detection advisory
+	thresholds.Automatic = 0.9
+	if got := RecommendationMode(0.8, false, 500, thresholds); got != ModeAdvisory {
+		t.Errorf("RecommendationMode() with a stricter threshold = %s", got)
+	}
+}
+
+func TestConfidenceThresholdsValidate(t *testing.T) {
+	tests := []struct {
+		thresholds ConfidenceThresholds
+		valid      bool
+	}{
+		{DefaultConfidenceThresholds(), true},
+		{ConfidenceThresholds{Automatic: 0.5, Advisory: 0.5}, true},
+		{ConfidenceThresholds{Automatic: 1, Advisory: 0}, true},
+		{ConfidenceThresholds{Automatic: 0.4, Advisory: 0.5}, false},
+		{ConfidenceThresholds{Automatic: 1.1, Advisory: 0.3}, false},
+		{ConfidenceThresholds{Automatic: 0.7, Advisory: -0.1}, false},
+		{ConfidenceThresholds{Automatic: 0.7, Advisory: 0.3, MinFiles: -1}, false},
+	}
+	for _, tt := range tests {
+		if err := tt.thresholds.Validate(); (err == nil) != tt.valid {
+			t.Errorf("Validate(%+v) = %v, want valid %v", tt.thresholds, err, tt.valid)
+		}
+	}
+}
+
+func TestConfidenceChecklist(t *testing.T) {
+	thresholds := DefaultConfidenceThresholds()
+	detection := &DomainDetectionExplanation{Status: DetectionStatusAmbiguous, TopCandidates: []string{"genomics", "climate"}}
+
+	checklist := ConfidenceChecklist(detection, DomainHints{}, 3, thresholds)
+	if len(checklist) != 3 {
+		t.Fatalf("checklist = %v", checklist)
+	}
+	if !strings.Contains(checklist[0], "--domain") || !strings.Contains(checklist[0], "genomics, climate") {
+		t.Errorf("domain item = %q", checklist[0])
+	}
+	if !strings.Contains(checklist[1], "only 3 file(s)") || !strings.Contains(checklist[2], "--inventory") {
+		t.Errorf("checklist = %v", checklist)
+	}
+
+	// Hints already given are not asked for again
+	checklist = ConfidenceChecklist(detection, DomainHints{ExplicitDomain: "genomics", InventoryFiles: 2}, 10, thresholds)
+	if len(checklist) != 0 {
+		t.Errorf("checklist = %v", checklist)
+	}
+}
+
+func TestReadInventoryManifest(t *testing.T) {
+	manifest := strings.Join([]string{
+		"run1/sample1.FASTQ",
+		"run1/sample1.bam",
+		"run1/",
+		"",
+		"README",
+	}, "\n")
+	extensions, files, err := ReadInventoryManifest(strings.NewReader(manifest))
+	if err != nil {
+		t.Fatalf("ReadInventoryManifest() error = %v", err)
+	}
+	if files != 3 || strings.Join(extensions, ",") != ".bam,.fastq" {
+		t.Errorf("files = %d, extensions = %v", files, extensions)
+	}
+
+	// S3 Inventory reports are CSV with the key in the second column
+	inventory := `"lab-data","run1/reads, lane 1.fq","1024","2024-05-01T00:00:00.000Z"` + "\n" +
+		`"lab-data","run1/calls.vcf","2048","2024-05-01T00:00:00.000Z"` + "\n"
+	extensions, files, err = ReadInventoryManifest(strings.NewReader(inventory))
+	if err != nil || files != 2 || strings.Join(extensions, ",") != ".fq,.vcf" {
+		t.Errorf("S3 inventory: files = %d, extensions = %v, %v", files, extensions, err)
+	}
+}
+
+func TestGenerateIntelligentRecommendationsMode(t *testing.T) {
+	ie := createTestIntelligenceEngine()
+	ctx := context.Background()
+
+	// An explicit domain over the mock's 500 files is confident enough to apply
+	rec, err := ie.GenerateIntelligentRecommendations(ctx, "/data/samples.fastq", DomainHints{ExplicitDomain: "genomics"})
+	if err != nil {
+		t.Fatalf("GenerateIntelligentRecommendations() error = %v", err)
+	}
+	if rec.Mode != ModeAutomatic || len(rec.Checklist) != 0 {
+		t.Errorf("mode = %s, checklist = %v", rec.Mode, rec.Checklist)
+	}
+
+	if err := ie.SetConfidenceThresholds(ConfidenceThresholds{Automatic: 0.9, Advisory: 0.3}); err != nil {
+		t.Fatal(err)
+	}
+	rec, _ = ie.GenerateIntelligentRecommendations(ctx, "/data/run1", DomainHints{ExplicitDomain: "genomics"})
+	if rec.Mode != ModeAdvisory {
+		t.Errorf("mode with a stricter threshold = %s", rec.Mode)
+	}
+
+	// An inventory listing more files than the scan found counts toward the minimum
+	if err := ie.SetConfidenceThresholds(ConfidenceThresholds{Automatic: 0.7, Advisory: 0.3, MinFiles: 1000}); err != nil {
+		t.Fatal(err)
+	}
+	rec, _ = ie.GenerateIntelligentRecommendations(ctx, "/data/samples.fastq", DomainHints{ExplicitDomain: "genomics"})
+	if rec.Mode != ModeInsufficientData || len(rec.Checklist) != 2 {
+		t.Errorf("mode = %s, checklist = %v", rec.Mode, rec.Checklist)
+	}
+	rec, _ = ie.GenerateIntelligentRecommendations(ctx, "/data/samples.fastq", DomainHints{ExplicitDomain: "genomics", InventoryFiles: 5000})
+	if rec.Mode != ModeAutomatic {
+		t.Errorf("mode with an inventory = %s", rec.Mode)
+	}
+
+	if err := ie.SetConfidenceThresholds(ConfidenceThresholds{Automatic: 0.2, Advisory: 0.3}); err == nil {
+		t.Error("SetConfidenceThresholds() should reject advisory above automatic")
+	}
+}
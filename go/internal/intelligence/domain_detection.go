@@ -0,0 +1,275 @@
+package intelligence
+
+import (
+	"sort"
+	"strings"
+)
+
+// Domain detection scoring factors
+const (
+	FactorFileExtensions = "file_extensions"
+	FactorExplicitDomain = "explicit_domain"
+	FactorWorkflowHints  = "workflow_hints"
+	FactorToolHints      = "tool_hints"
+	FactorContentPattern = "content_patterns"
+)
+
+// Domain detection outcomes
+const (
+	DetectionStatusConfident = "confident"
+	DetectionStatusAmbiguous = "ambiguous"
+)
+
+const (
+	// AmbiguousConfidenceThreshold is the score below which detection is ambiguous
+	AmbiguousConfidenceThreshold = 0.3
+
+	// fallbackDomain is used when no domain scores at all
+	fallbackDomain = "general"
+
+	explicitDomainScore  = 0.8
+	workflowHintScore    = 0.3
+	preferredEngineScore = 0.2
+	domainToolScore      = 0.4
+	contentPatternScore  = 0.6
+	ambiguousCandidates  = 3
+)
+
+// FactorScore is one factor's contribution to a domain's detection score
+type FactorScore struct {
+	Factor  string   `json:"factor"`
+	Score   float64  `json:"score"`
+	Matches []string `json:"matches,omitempty"`
+}
+
+// DomainCandidate is a domain with its total score and per-factor breakdown
+type DomainCandidate struct {
+	Domain  string        `json:"domain"`
+	Score   float64       `json:"score"`
+	Factors []FactorScore `json:"factors"`
+}
+
+// Factor returns the contribution of a named factor, or zero if it did not score
+func (dc DomainCandidate) Factor(name string) FactorScore {
+	for _, factor := range dc.Factors {
+		if factor.Factor == name {
+			return factor
+		}
+	}
+	return FactorScore{Factor: name}
+}
+
+// DomainDetectionExplanation records how a domain was chosen
+type DomainDetectionExplanation struct {
+	Domain        string            `json:"domain"`
+	Confidence    float64           `json:"confidence"`
+	Status        string            `json:"status"`
+	TopCandidates []string          `json:"top_candidates,omitempty"`
+	Candidates    []DomainCandidate `json:"candidates"`
+}
+
+// Ambiguous reports whether no single domain clearly won
+func (e *DomainDetectionExplanation) Ambiguous() bool {
+	return e.Status == DetectionStatusAmbiguous
+}
+
+// ExplainDomainDetection scores every candidate domain factor by factor and ranks them.
+// Detection is ambiguous when the best score is below AmbiguousConfidenceThreshold or
+// when the top candidates tie.
+func (ie *IntelligenceEngine) ExplainDomainDetection(dataPath string, hints DomainHints) *DomainDetectionExplanation {
+	extensions := ie.analyzeFileExtensions(dataPath)
+	for _, ext := range hints.FileExtensions {
+		extensions[strings.ToLower(ext)] = true
+	}
+
+	factors := map[string]map[string]FactorScore{
+		FactorFileExtensions: ie.scoreFileExtensions(extensions),
+		FactorExplicitDomain: scoreExplicitDomain(hints.ExplicitDomain),
+		FactorWorkflowHints:  ie.scoreWorkflowHints(hints.WorkflowHints),
+		FactorToolHints:      ie.scoreToolHints(hints.ToolHints),
+	}
+
+	// Naming patterns only count when the other factors are inconclusive
+	if bestScore(factors) < AmbiguousConfidenceThreshold {
+		factors[FactorContentPattern] = ie.scoreContentPatterns(dataPath)
+	}
+
+	candidates := rankCandidates(factors)
+	explanation := &DomainDetectionExplanation{
+		Domain:     fallbackDomain,
+		Status:     DetectionStatusConfident,
+		Candidates: candidates,
+	}
+
+	if len(candidates) > 0 {
+		explanation.Domain = candidates[0].Domain
+		explanation.Confidence = candidates[0].Score
+		if explanation.Confidence > 1.0 {
+			explanation.Confidence = 1.0
+		}
+	}
+
+	tied := len(candidates) > 1 && candidates[1].Score == candidates[0].Score
+	if explanation.Confidence < AmbiguousConfidenceThreshold || tied {
+		explanation.Status = DetectionStatusAmbiguous
+		for i := 0; i < len(candidates) && i < ambiguousCandidates; i++ {
+			explanation.TopCandidates = append(explanation.TopCandidates, candidates[i].Domain)
+		}
+	}
+
+	return explanation
+}
+
+// scoreFileExtensions scores each domain by the fraction of its file type hints present
+func (ie *IntelligenceEngine) scoreFileExtensions(extensions map[string]bool) map[string]FactorScore {
+	scores := make(map[string]FactorScore)
+	if len(extensions) == 0 {
+		return scores
+	}
+
+	for domain, profile := range ie.domainProfileManager.GetAllProfiles() {
+		if len(profile.FileTypeHints) == 0 {
+			continue
+		}
+
+		var matches []string
+		for ext := range extensions {
+			if _, exists := profile.FileTypeHints[ext]; exists {
+				matches = append(matches, ext)
+			}
+		}
+		if len(matches) == 0 {
+			continue
+		}
+
+		sort.Strings(matches)
+		scores[domain] = FactorScore{
+			Factor:  FactorFileExtensions,
+			Score:   float64(len(matches)) / float64(len(profile.FileTypeHints)),
+			Matches: matches,
+		}
+	}
+
+	return scores
+}
+
+// scoreExplicitDomain credits a domain the user named directly
+func scoreExplicitDomain(explicitDomain string) map[string]FactorScore {
+	scores := make(map[string]FactorScore)
+	if explicitDomain != "" {
+		scores[explicitDomain] = FactorScore{
+			Factor:  FactorExplicitDomain,
+			Score:   explicitDomainScore,
+			Matches: []string{explicitDomain},
+		}
+	}
+	return scores
+}
+
+// scoreWorkflowHints credits domains whose names overlap a workflow hint
+func (ie *IntelligenceEngine) scoreWorkflowHints(workflows []string) map[string]FactorScore {
+	scores := make(map[string]FactorScore)
+	for _, workflow := range workflows {
+		for domain := range ie.domainProfileManager.GetAllProfiles() {
+			if strings.Contains(domain, workflow) || strings.Contains(workflow, domain) {
+				addFactorScore(scores, domain, FactorWorkflowHints, workflowHintScore, workflow)
+			}
+		}
+	}
+	return scores
+}
+
+// scoreToolHints credits domains whose transfer engines or domain tools match a tool hint
+func (ie *IntelligenceEngine) scoreToolHints(tools []string) map[string]FactorScore {
+	scores := make(map[string]FactorScore)
+	for _, tool := range tools {
+		for domain, profile := range ie.domainProfileManager.GetAllProfiles() {
+			for _, preferredEngine := range profile.TransferOptimization.PreferredEngines {
+				if strings.Contains(tool, preferredEngine) || strings.Contains(preferredEngine, tool) {
+					addFactorScore(scores, domain, FactorToolHints, preferredEngineScore, tool)
+				}
+			}
+
+			for _, domainTool := range ie.getDomainSpecificTools(domain) {
+				if ie.toolsMatch(tool, domainTool) {
+					addFactorScore(scores, domain, FactorToolHints, domainToolScore, tool)
+				}
+			}
+		}
+	}
+	return scores
+}
+
+// scoreContentPatterns credits the domain suggested by common naming patterns in the path
+func (ie *IntelligenceEngine) scoreContentPatterns(dataPath string) map[string]FactorScore {
+	scores := make(map[string]FactorScore)
+	if detected := ie.detectFromCommonPatterns(dataPath); detected != "" {
+		scores[detected] = FactorScore{
+			Factor:  FactorContentPattern,
+			Score:   contentPatternScore,
+			Matches: []string{dataPath},
+		}
+	}
+	return scores
+}
+
+func addFactorScore(scores map[string]FactorScore, domain, factor string, score float64, match string) {
+	current := scores[domain]
+	current.Factor = factor
+	current.Score += score
+	for _, existing := range current.Matches {
+		if existing == match {
+			scores[domain] = current
+			return
+		}
+	}
+	current.Matches = append(current.Matches, match)
+	scores[domain] = current
+}
+
+// bestScore returns the highest total score across domains
+func bestScore(factors map[string]map[string]FactorScore) float64 {
+	candidates := rankCandidates(factors)
+	if len(candidates) == 0 {
+		return 0
+	}
+	return candidates[0].Score
+}
+
+// rankCandidates totals each domain's factor scores, highest first and by name on ties
+func rankCandidates(factors map[string]map[string]FactorScore) []DomainCandidate {
+	factorNames := make([]string, 0, len(factors))
+	for name := range factors {
+		factorNames = append(factorNames, name)
+	}
+	sort.Strings(factorNames)
+
+	byDomain := make(map[string]*DomainCandidate)
+	for _, name := range factorNames {
+		for domain, score := range factors[name] {
+			if score.Score <= 0 {
+				continue
+			}
+			candidate, exists := byDomain[domain]
+			if !exists {
+				candidate = &DomainCandidate{Domain: domain}
+				byDomain[domain] = candidate
+			}
+			candidate.Score += score.Score
+			candidate.Factors = append(candidate.Factors, score)
+		}
+	}
+
+	candidates := make([]DomainCandidate, 0, len(byDomain))
+	for _, candidate := range byDomain {
+		candidates = append(candidates, *candidate)
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].Score != candidates[j].Score {
+			return candidates[i].Score > candidates[j].Score
+		}
+		return candidates[i].Domain < candidates[j].Domain
+	})
+
+	return candidates
+}
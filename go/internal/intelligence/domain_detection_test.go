@@ -0,0 +1,175 @@
+package intelligence
+
+import (
+	"context"
+	"math"
+	"testing"
+)
+
+func TestScoreFileExtensions(t *testing.T) {
+	ie := createTestIntelligenceEngine()
+	profiles := ie.domainProfileManager.GetAllProfiles()
+
+	scores := ie.scoreFileExtensions(map[string]bool{".fastq": true, ".bam": true})
+	genomics, ok := scores["genomics"]
+	if !ok {
+		t.Fatal("Expected genomics to score for .fastq and .bam")
+	}
+	want := 2.0 / float64(len(profiles["genomics"].FileTypeHints))
+	if math.Abs(genomics.Score-want) > 1e-9 {
+		t.Errorf("Expected score %f, got %f", want, genomics.Score)
+	}
+	if len(genomics.Matches) != 2 || genomics.Matches[0] != ".bam" {
+		t.Errorf("Expected sorted matches [.bam .fastq], got %v", genomics.Matches)
+	}
+
+	if len(ie.scoreFileExtensions(nil)) != 0 {
+		t.Error("Expected no scores without extensions")
+	}
+}
+
+func TestScoreExplicitDomain(t *testing.T) {
+	scores := scoreExplicitDomain("astronomy")
+	if scores["astronomy"].Score != explicitDomainScore {
+		t.Errorf("Expected explicit domain score %f, got %f", explicitDomainScore, scores["astronomy"].Score)
+	}
+	if len(scoreExplicitDomain("")) != 0 {
+		t.Error("Expected no score without an explicit domain")
+	}
+}
+
+func TestScoreWorkflowHints(t *testing.T) {
+	ie := createTestIntelligenceEngine()
+
+	scores := ie.scoreWorkflowHints([]string{"genomics", "genomics_pipeline"})
+	if math.Abs(scores["genomics"].Score-2*workflowHintScore) > 1e-9 {
+		t.Errorf("Expected two workflow matches for genomics, got %f", scores["genomics"].Score)
+	}
+	if len(scores["genomics"].Matches) != 2 {
+		t.Errorf("Expected both workflows listed, got %v", scores["genomics"].Matches)
+	}
+	if _, ok := scores["climate"]; ok {
+		t.Error("Did not expect climate to match genomics workflows")
+	}
+}
+
+func TestScoreToolHints(t *testing.T) {
+	ie := createTestIntelligenceEngine()
+
+	scores := ie.scoreToolHints([]string{"samtools"})
+	if scores["genomics"].Score < domainToolScore {
+		t.Errorf("Expected samtools to credit genomics at least %f, got %f", domainToolScore, scores["genomics"].Score)
+	}
+	if len(scores["genomics"].Matches) != 1 || scores["genomics"].Matches[0] != "samtools" {
+		t.Errorf("Expected samtools listed once, got %v", scores["genomics"].Matches)
+	}
+}
+
+func TestScoreContentPatterns(t *testing.T) {
+	ie := createTestIntelligenceEngine()
+
+	scores := ie.scoreContentPatterns("/data/weather/forecast")
+	if scores["climate"].Score != contentPatternScore {
+		t.Errorf("Expected climate content score %f, got %f", contentPatternScore, scores["climate"].Score)
+	}
+	if len(ie.scoreContentPatterns("/data/misc")) != 0 {
+		t.Error("Expected no content score for an unrecognized path")
+	}
+}
+
+func TestRankCandidates(t *testing.T) {
+	candidates := rankCandidates(map[string]map[string]FactorScore{
+		FactorExplicitDomain: {"b": {Factor: FactorExplicitDomain, Score: 0.5}},
+		FactorToolHints: {
+			"a": {Factor: FactorToolHints, Score: 0.5},
+			"b": {Factor: FactorToolHints, Score: 0.2},
+			"c": {Factor: FactorToolHints, Score: 0},
+		},
+	})
+
+	if len(candidates) != 2 {
+		t.Fatalf("Expected zero scores to be dropped, got %d candidates", len(candidates))
+	}
+	if candidates[0].Domain != "b" || math.Abs(candidates[0].Score-0.7) > 1e-9 {
+		t.Errorf("Expected b ranked first with 0.7, got %+v", candidates[0])
+	}
+	if candidates[0].Factor(FactorToolHints).Score != 0.2 {
+		t.Errorf("Expected tool factor breakdown, got %+v", candidates[0].Factors)
+	}
+	if candidates[0].Factor(FactorWorkflowHints).Score != 0 {
+		t.Error("Expected missing factors to report zero")
+	}
+}
+
+func TestExplainDomainDetectionConfident(t *testing.T) {
+	ie := createTestIntelligenceEngine()
+
+	explanation := ie.ExplainDomainDetection("/data/unknown/file.dat", DomainHints{ExplicitDomain: "machine_learning"})
+	if explanation.Ambiguous() {
+		t.Fatalf("Expected confident detection, got %+v", explanation)
+	}
+	if explanation.Domain != "machine_learning" || explanation.Confidence < 0.8 {
+		t.Errorf("Unexpected detection: %s (%f)", explanation.Domain, explanation.Confidence)
+	}
+	if len(explanation.TopCandidates) != 0 {
+		t.Errorf("Expected no top candidates for a confident result, got %v", explanation.TopCandidates)
+	}
+}
+
+func TestExplainDomainDetectionAmbiguous(t *testing.T) {
+	ie := createTestIntelligenceEngine()
+
+	t.Run("tie", func(t *testing.T) {
+		explanation := ie.ExplainDomainDetection("/data/run1", DomainHints{WorkflowHints: []string{"genomics", "climate"}})
+		if !explanation.Ambiguous() {
+			t.Fatalf("Expected a tie to be ambiguous, got %+v", explanation)
+		}
+		if len(explanation.TopCandidates) != 2 || explanation.TopCandidates[0] != "climate" || explanation.TopCandidates[1] != "genomics" {
+			t.Errorf("Expected tied candidates in name order, got %v", explanation.TopCandidates)
+		}
+	})
+
+	t.Run("low_confidence", func(t *testing.T) {
+		explanation := ie.ExplainDomainDetection("/data/run1", DomainHints{ToolHints: []string{"s3"}})
+		if explanation.Confidence >= AmbiguousConfidenceThreshold {
+			t.Fatalf("Expected a low-confidence score, got %f", explanation.Confidence)
+		}
+		if !explanation.Ambiguous() {
+			t.Errorf("Expected low confidence to be ambiguous, got %+v", explanation)
+		}
+		if len(explanation.TopCandidates) > ambiguousCandidates {
+			t.Errorf("Expected at most %d top candidates, got %v", ambiguousCandidates, explanation.TopCandidates)
+		}
+	})
+
+	t.Run("no_signal", func(t *testing.T) {
+		explanation := ie.ExplainDomainDetection("", DomainHints{})
+		if !explanation.Ambiguous() || explanation.Domain != fallbackDomain {
+			t.Errorf("Expected ambiguous fallback to %s, got %+v", fallbackDomain, explanation)
+		}
+	})
+}
+
+func TestGenerateIntelligentRecommendationsExplain(t *testing.T) {
+	ie := createTestIntelligenceEngine()
+	ctx := context.Background()
+
+	rec, err := ie.GenerateIntelligentRecommendations(ctx, "/data/samples.fastq", DomainHints{ExplicitDomain: "genomics"})
+	if err != nil {
+		t.Fatalf("GenerateIntelligentRecommendations failed: %v", err)
+	}
+	if rec.DomainDetection != nil {
+		t.Error("Expected no detection breakdown without --explain on a confident result")
+	}
+
+	rec, err = ie.GenerateIntelligentRecommendations(ctx, "/data/samples.fastq", DomainHints{ExplicitDomain: "genomics", Explain: true})
+	if err != nil {
+		t.Fatalf("GenerateIntelligentRecommendations failed: %v", err)
+	}
+	if rec.DomainDetection == nil || len(rec.DomainDetection.Candidates) == 0 {
+		t.Fatal("Expected detection breakdown with Explain set")
+	}
+	if rec.DomainDetection.Candidates[0].Factor(FactorExplicitDomain).Score == 0 {
+		t.Errorf("Expected explicit hint in the breakdown, got %+v", rec.DomainDetection.Candidates[0])
+	}
+}
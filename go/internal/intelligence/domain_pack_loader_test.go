@@ -3,464 +3,236 @@ package intelligence
 import (
 	"os"
 	"path/filepath"
+	"reflect"
+	"sort"
 	"testing"
+
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/config"
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/data"
 )
 
+// repoConfigRoot returns the directory containing the shared configs/domains files
+func repoConfigRoot(t *testing.T) string {
+	t.Helper()
+	root, err := filepath.Abs(filepath.Join("..", "..", ".."))
+	if err != nil {
+		t.Fatalf("failed to resolve config root: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "configs", "domains")); err != nil {
+		t.Fatalf("configs/domains not found under %s: %v", root, err)
+	}
+	return root
+}
+
 func TestDomainPackLoader_LoadDomainPack(t *testing.T) {
 	loader := NewDomainPackLoader()
 
 	tests := []struct {
 		name        string
 		domain      string
+		expectName  string
 		expectError bool
-		expectNil   bool
 	}{
-		{
-			name:        "genomics_lab domain",
-			domain:      "genomics_lab",
-			expectError: false, // Real domain pack files exist
-			expectNil:   false,
-		},
-		{
-			name:        "ai_research_studio domain",
-			domain:      "ai_research_studio",
-			expectError: false, // Real domain pack files exist
-			expectNil:   false,
-		},
-		{
-			name:        "climate_modeling domain",
-			domain:      "climate_modeling",
-			expectError: false, // Real domain pack files exist
-			expectNil:   false,
-		},
-		{
-			name:        "unknown domain",
-			domain:      "unknown_domain",
-			expectError: true,
-			expectNil:   true,
-		},
-		{
-			name:        "empty domain",
-			domain:      "",
-			expectError: true,
-			expectNil:   true,
-		},
+		{name: "exact match", domain: "genomics", expectName: "Genomics & Bioinformatics Laboratory"},
+		{name: "full config name", domain: "climate_modeling", expectName: "Climate Modeling & Atmospheric Science Laboratory"},
+		{name: "profile name prefix", domain: "climate", expectName: "Climate Modeling & Atmospheric Science Laboratory"},
+		{name: "unknown domain", domain: "unknown_domain", expectError: true},
+		{name: "empty domain", domain: "", expectError: true},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			result, err := loader.LoadDomainPack(tt.domain)
 
-			if tt.expectError && err == nil {
-				t.Errorf("expected error but got none")
-			}
-			if !tt.expectError && err != nil {
-				t.Errorf("unexpected error: %v", err)
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("expected error but got %v", result)
+				}
+				return
 			}
-			if tt.expectNil && result != nil {
-				t.Errorf("expected nil result but got: %v", result)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
 			}
-			if !tt.expectNil && result == nil {
-				t.Errorf("expected non-nil result but got nil")
+			if result.Name != tt.expectName {
+				t.Errorf("expected name %q, got %q", tt.expectName, result.Name)
 			}
-
-			// Validate structure if we got a result
-			if result != nil {
-				if result.Name == "" {
-					t.Errorf("expected non-empty name")
-				}
-				if result.Description == "" {
-					t.Errorf("expected non-empty description")
-				}
+			if result.Description == "" {
+				t.Errorf("expected non-empty description")
 			}
 		})
 	}
 }
 
 func TestDomainPackLoader_LoadAllDomainPacks(t *testing.T) {
-	loader := NewDomainPackLoader()
+	loader := NewConfigDomainPackLoader(config.NewConfigLoader(repoConfigRoot(t)))
 
 	packs, err := loader.LoadAllDomainPacks()
-
-	// In test environment, we expect no domain packs to be found
 	if err != nil {
-		t.Logf("expected error in test environment: %v", err)
+		t.Fatalf("LoadAllDomainPacks failed: %v", err)
 	}
-
 	if len(packs) == 0 {
-		t.Logf("no domain packs found in test environment (expected)")
+		t.Fatal("expected domain packs from configs/domains")
 	}
 
-	// Verify each pack has required fields if any are found
 	for domain, pack := range packs {
-		if domain == "" {
-			t.Errorf("found empty domain key")
-		}
-		if pack == nil {
-			t.Errorf("found nil pack for domain: %s", domain)
-			continue
-		}
-		if pack.Name == "" {
-			t.Errorf("pack for domain %s has empty name", domain)
-		}
-		if pack.Description == "" {
-			t.Errorf("pack for domain %s has empty description", domain)
+		if pack == nil || pack.Name == "" {
+			t.Errorf("pack for domain %s has no name", domain)
 		}
 	}
-}
 
-func TestDomainPackLoader_GetAvailableDomains(t *testing.T) {
-	loader := NewDomainPackLoader()
-
-	domains, err := loader.GetAvailableDomains()
+	again, err := loader.LoadAllDomainPacks()
 	if err != nil {
-		t.Logf("expected error in test environment: %v", err)
+		t.Fatalf("LoadAllDomainPacks failed: %v", err)
 	}
-
-	if len(domains) == 0 {
-		t.Logf("no available domains in test environment (expected)")
-	}
-
-	// Only check for expected domains if any domains are found
-	if len(domains) > 0 {
-		expectedDomains := []string{"genomics_lab", "ai_research_studio", "climate_modeling"}
-		for _, expected := range expectedDomains {
-			found := false
-			for _, domain := range domains {
-				if domain == expected {
-					found = true
-					break
-				}
-			}
-			if !found {
-				t.Logf("domain %s not found in available domains (may be expected in test environment)", expected)
-			}
-		}
+	if reflect.ValueOf(again).Pointer() != reflect.ValueOf(packs).Pointer() {
+		t.Error("expected cached packs on the second load")
 	}
 }
 
-func TestDomainPackLoader_ValidateDomainPack(t *testing.T) {
-	loader := NewDomainPackLoader()
-
-	tests := []struct {
-		name        string
-		domainName  string
-		expectValid bool
-	}{
-		{
-			name:        "genomics_lab domain",
-			domainName:  "genomics_lab",
-			expectValid: true, // Real domain pack files exist
-		},
-		{
-			name:        "ai_research_studio domain",
-			domainName:  "ai_research_studio",
-			expectValid: true, // Real domain pack files exist
-		},
-		{
-			name:        "climate_modeling domain",
-			domainName:  "climate_modeling",
-			expectValid: true, // Real domain pack files exist
-		},
-		{
-			name:        "invalid domain",
-			domainName:  "nonexistent",
-			expectValid: false,
-		},
-		{
-			name:        "empty domain",
-			domainName:  "",
-			expectValid: false,
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			err := loader.ValidateDomainPack(tt.domainName)
-			hasError := err != nil
-			if hasError == tt.expectValid {
-				t.Errorf("expected validation result %v, got error: %v", tt.expectValid, err)
-			}
-		})
-	}
-}
-
-func TestDomainPackLoader_ClearCache(t *testing.T) {
-	loader := NewDomainPackLoader()
+func TestDomainPackLoader_GetAvailableDomains(t *testing.T) {
+	loader := NewConfigDomainPackLoader(config.NewConfigLoader(repoConfigRoot(t)))
 
-	// Try to load something into cache first (should work now with real domain packs)
-	_, err := loader.LoadDomainPack("genomics_lab")
+	domains, err := loader.GetAvailableDomains()
 	if err != nil {
-		t.Logf("error loading genomics_lab domain: %v", err)
+		t.Fatalf("GetAvailableDomains failed: %v", err)
 	}
-
-	// Clear cache should not return error
-	loader.ClearCache()
-
-	// Verify cache is cleared by loading again
-	// This is mainly to ensure the function runs without error
-	_, err = loader.LoadDomainPack("genomics_lab")
-	if err != nil {
-		t.Logf("error after cache clear: %v", err)
+	if !sort.StringsAreSorted(domains) {
+		t.Errorf("expected sorted domains, got %v", domains)
 	}
-}
-
-func TestDomainPackLoader_LoadErrorCases(t *testing.T) {
-	loader := NewDomainPackLoader()
 
-	// Test loading non-existent domain
-	_, err := loader.LoadDomainPack("nonexistent-domain")
-	if err == nil {
-		t.Error("expected error for non-existent domain")
+	found := false
+	for _, domain := range domains {
+		if domain == "genomics" {
+			found = true
+		}
 	}
-
-	// Test validating non-existent domain
-	err = loader.ValidateDomainPack("nonexistent-domain")
-	if err == nil {
-		t.Error("expected error for non-existent domain validation")
+	if !found {
+		t.Errorf("expected genomics in %v", domains)
 	}
 }
 
-func TestDomainPackLoader_EdgeCases(t *testing.T) {
-	loader := NewDomainPackLoader()
+func TestDomainPackLoader_ValidateAndClearCache(t *testing.T) {
+	loader := NewConfigDomainPackLoader(config.NewConfigLoader(repoConfigRoot(t)))
 
-	t.Run("load domain pack with special characters", func(t *testing.T) {
-		_, err := loader.LoadDomainPack("domain-with-dashes_and_underscores")
-		// Should not panic or cause issues
-		if err != nil {
-			// Error is acceptable for non-existent domains
-		}
-	})
-
-	t.Run("load domain pack with very long name", func(t *testing.T) {
-		longName := string(make([]byte, 1000))
-		_, err := loader.LoadDomainPack(longName)
-		// Should not panic
-		if err != nil {
-			// Error is acceptable for invalid domains
-		}
-	})
-
-	t.Run("multiple cache operations", func(t *testing.T) {
-		// Multiple clears should be safe
-		loader.ClearCache()
-		loader.ClearCache()
-		loader.ClearCache()
-
-		// Multiple loads should be consistent
-		pack1, _ := loader.LoadDomainPack("genomics_lab")
-		pack2, _ := loader.LoadDomainPack("genomics_lab")
-
-		if pack1 != nil && pack2 != nil {
-			if pack1.Name != pack2.Name {
-				t.Errorf("inconsistent results from cache")
-			}
-		}
-	})
-}
-
-func TestDomainPackLoader_loadConfigFile(t *testing.T) {
-	// Cast to concrete type to access private methods
-	loader := NewDomainPackLoader().(*DomainPackLoader)
-
-	// Create a temporary test file
-	tempDir := t.TempDir()
-	configPath := filepath.Join(tempDir, "test-domain-pack.yaml")
-
-	// Test with invalid YAML
-	invalidYAML := `
-name: test-domain
-description: Test domain pack
-invalid_yaml: [unclosed
-`
-	err := os.WriteFile(configPath, []byte(invalidYAML), 0644)
-	if err != nil {
-		t.Fatalf("Failed to create test file: %v", err)
+	if err := loader.ValidateDomainPack("genomics"); err != nil {
+		t.Errorf("expected genomics to validate: %v", err)
 	}
-
-	_, err = loader.loadConfigFile(configPath)
-	if err == nil {
-		t.Error("Expected error for invalid YAML")
+	if err := loader.ValidateDomainPack("unknown_domain"); err == nil {
+		t.Error("expected error for unknown domain")
 	}
 
-	// Test with valid YAML
-	validYAML := `
-name: test-domain
-description: Test domain pack
-version: "1.0.0"
-categories:
-  - test
-aws_config:
-  instance_types:
-    small: c6i.large
-    medium: c6i.xlarge
-spack_config:
-  packages:
-    - test-package
-workflows:
-  - name: test-workflow
-    description: Test workflow
-    script: test.sh
-    input_data: input.txt
-    expected_output: output.txt
-cost_estimates:
-  small: "$10/month"
-documentation:
-  getting_started: "https://example.com"
-`
-	err = os.WriteFile(configPath, []byte(validYAML), 0644)
-	if err != nil {
-		t.Fatalf("Failed to create test file: %v", err)
+	loader.ClearCache()
+	if loader.(*DomainPackLoader).packs != nil {
+		t.Error("expected ClearCache to drop cached packs")
 	}
-
-	config, err := loader.loadConfigFile(configPath)
-	if err != nil {
-		t.Errorf("Unexpected error for valid YAML: %v", err)
+	if _, err := loader.LoadDomainPack("genomics"); err != nil {
+		t.Errorf("expected reload after ClearCache: %v", err)
 	}
+}
 
-	if config != nil {
-		if config.Name != "test-domain" {
-			t.Errorf("Expected name 'test-domain', got %s", config.Name)
-		}
-		if config.Description != "Test domain pack" {
-			t.Errorf("Expected description 'Test domain pack', got %s", config.Description)
-		}
-		if config.Version != "1.0.0" {
-			t.Errorf("Expected version '1.0.0', got %s", config.Version)
-		}
-	}
+func TestDomainPackLoader_MissingConfigRoot(t *testing.T) {
+	loader := NewConfigDomainPackLoader(config.NewConfigLoader(t.TempDir()))
 
-	// Test with non-existent file
-	_, err = loader.loadConfigFile("/nonexistent/path/config.yaml")
-	if err == nil {
-		t.Error("Expected error for non-existent file")
+	if _, err := loader.LoadDomainPack("genomics"); err == nil {
+		t.Error("expected error without configs/domains")
 	}
 }
 
-func TestDomainPackLoader_convertToDomainPackInfo(t *testing.T) {
-	// Cast to concrete type to access private methods
-	loader := NewDomainPackLoader().(*DomainPackLoader)
-
-	config := &DomainPackConfig{
-		Name:        "test-domain",
-		Description: "Test domain pack",
-		Version:     "1.0.0",
-		Categories:  []string{"test", "development"},
-		AWSConfig: AWSConfig{
-			InstanceTypes: map[string]string{
-				"small":  "c6i.large",
-				"medium": "c6i.xlarge",
-				"large":  "c6i.2xlarge",
-			},
+func TestDomainPackInfoFromConfig(t *testing.T) {
+	domain := &config.DomainPack{
+		Name:           "Test Lab",
+		Version:        "2.1.0",
+		Description:    "Test domain",
+		PrimaryDomains: []string{"Testing"},
+		SpackPackages: map[string]interface{}{
+			"tools": []interface{}{"samtools@1.17", "bwa@0.7.17"},
+			"core":  []interface{}{"gcc@11.3.0"},
 		},
-		SpackConfig: SpackConfig{
-			Packages: []string{"gcc", "python", "numpy"},
+		AWSInstanceRecommendations: map[string]config.InstanceRecommendation{
+			"big":   {InstanceType: "r6i.8xlarge", VCPUs: 32, MemoryGB: 256},
+			"small": {InstanceType: "c6i.xlarge", VCPUs: 4, MemoryGB: 8},
 		},
-		Workflows: []WorkflowConfig{
-			{
-				Name:           "test-workflow",
-				Description:    "A test workflow",
-				Script:         "run_test.sh",
-				InputData:      "input.txt",
-				ExpectedOutput: "output.txt",
-			},
-			{
-				Name:           "benchmark",
-				Description:    "Benchmark workflow",
-				Script:         "benchmark.sh",
-				InputData:      "data.csv",
-				ExpectedOutput: "results.json",
-			},
-		},
-		CostEstimates: map[string]string{
-			"small":  "$10/month",
-			"medium": "$20/month",
-			"large":  "$40/month",
+		EstimatedCost: config.EstimatedCost{Compute: 600, Storage: 0, Total: 900},
+		DemoWorkflows: []config.DemoWorkflow{
+			{Name: "Variant calling", Description: "GATK pipeline", Dataset: "1000 Genomes"},
 		},
 	}
 
-	info := loader.convertToDomainPackInfo(config)
+	info := DomainPackInfoFromConfig(domain)
 
-	if info == nil {
-		t.Fatal("convertToDomainPackInfo() returned nil")
+	if info.Name != "Test Lab" || info.Version != "2.1.0" || info.Description != "Test domain" {
+		t.Errorf("unexpected metadata: %+v", info)
 	}
-
-	if info.Name != config.Name {
-		t.Errorf("Expected name %s, got %s", config.Name, info.Name)
+	if !reflect.DeepEqual(info.Categories, []string{"Testing"}) {
+		t.Errorf("expected categories from primary domains, got %v", info.Categories)
 	}
 
-	if info.Description != config.Description {
-		t.Errorf("Expected description %s, got %s", config.Description, info.Description)
+	wantPackages := []string{"gcc@11.3.0", "samtools@1.17", "bwa@0.7.17"}
+	if !reflect.DeepEqual(info.SpackPackages, wantPackages) {
+		t.Errorf("expected packages %v, got %v", wantPackages, info.SpackPackages)
 	}
 
-	if info.Version != config.Version {
-		t.Errorf("Expected version %s, got %s", config.Version, info.Version)
+	wantInstances := map[string]string{
+		"small":   "c6i.xlarge",
+		"medium":  "c6i.xlarge",
+		"large":   "r6i.8xlarge",
+		"massive": "r6i.8xlarge",
 	}
-
-	if len(info.Categories) != len(config.Categories) {
-		t.Errorf("Expected %d categories, got %d", len(config.Categories), len(info.Categories))
+	if !reflect.DeepEqual(info.InstanceTypes, wantInstances) {
+		t.Errorf("expected instance types %v, got %v", wantInstances, info.InstanceTypes)
 	}
 
-	if len(info.InstanceTypes) != len(config.AWSConfig.InstanceTypes) {
-		t.Errorf("Expected %d instance types, got %d", len(config.AWSConfig.InstanceTypes), len(info.InstanceTypes))
+	if info.EstimatedCost["compute"] != "$600/month" || info.EstimatedCost["total"] != "$900/month" {
+		t.Errorf("unexpected estimated cost: %v", info.EstimatedCost)
 	}
-
-	if len(info.SpackPackages) != len(config.SpackConfig.Packages) {
-		t.Errorf("Expected %d Spack packages, got %d", len(config.SpackConfig.Packages), len(info.SpackPackages))
+	if _, exists := info.EstimatedCost["storage"]; exists {
+		t.Error("expected zero storage cost to be omitted")
 	}
 
-	if len(info.Workflows) != len(config.Workflows) {
-		t.Errorf("Expected %d workflows, got %d", len(config.Workflows), len(info.Workflows))
-	}
-
-	// Check workflow conversion
-	if len(info.Workflows) > 0 {
-		workflow := info.Workflows[0]
-		originalWorkflow := config.Workflows[0]
-
-		if workflow.Name != originalWorkflow.Name {
-			t.Errorf("Expected workflow name %s, got %s", originalWorkflow.Name, workflow.Name)
-		}
-
-		if workflow.Description != originalWorkflow.Description {
-			t.Errorf("Expected workflow description %s, got %s", originalWorkflow.Description, workflow.Description)
-		}
-
-		if workflow.InputData != originalWorkflow.InputData {
-			t.Errorf("Expected input data %s, got %s", originalWorkflow.InputData, workflow.InputData)
-		}
-
-		if workflow.OutputData != originalWorkflow.ExpectedOutput {
-			t.Errorf("Expected output data %s, got %s", originalWorkflow.ExpectedOutput, workflow.OutputData)
-		}
-	}
-
-	if len(info.EstimatedCost) != len(config.CostEstimates) {
-		t.Errorf("Expected %d cost estimates, got %d", len(config.CostEstimates), len(info.EstimatedCost))
+	if len(info.Workflows) != 1 || info.Workflows[0].InputData != "1000 Genomes" {
+		t.Errorf("expected demo workflow to carry its dataset, got %+v", info.Workflows)
 	}
 }
 
-func TestDomainPackLoader_convertToDomainPackInfo_EmptyConfig(t *testing.T) {
-	// Cast to concrete type to access private methods
-	loader := NewDomainPackLoader().(*DomainPackLoader)
-
-	// Test with minimal config
-	config := &DomainPackConfig{
-		Name: "minimal-domain",
+// TestDomainPackParity checks that the config commands and intelligent recommendations
+// report the same instance for a domain and workload size
+func TestDomainPackParity(t *testing.T) {
+	configLoader := config.NewConfigLoader(repoConfigRoot(t))
+	domains, err := configLoader.LoadAllDomains()
+	if err != nil {
+		t.Fatalf("LoadAllDomains failed: %v", err)
 	}
 
-	info := loader.convertToDomainPackInfo(config)
+	ie := NewIntelligenceEngine(data.NewResearchDomainProfileManager(), &mockRecommendationEngine{})
+	ie.domainPackLoader = NewConfigDomainPackLoader(configLoader)
 
-	if info == nil {
-		t.Fatal("convertToDomainPackInfo() returned nil for minimal config")
+	tests := []struct {
+		profile string
+		domain  string
+	}{
+		{"genomics", "genomics"},
+		{"machine_learning", "machine_learning"},
+		{"climate", "climate_modeling"},
 	}
 
-	if info.Name != "minimal-domain" {
-		t.Errorf("Expected name 'minimal-domain', got %s", info.Name)
-	}
+	for _, tt := range tests {
+		t.Run(tt.profile, func(t *testing.T) {
+			domain, exists := domains[tt.domain]
+			if !exists {
+				t.Fatalf("domain %s not found in configs", tt.domain)
+			}
+			sizes := domain.InstanceTypesByWorkloadSize()
+			if len(sizes) == 0 {
+				t.Fatalf("domain %s has no instance recommendations", tt.domain)
+			}
 
-	// Should handle empty workflows gracefully (nil is acceptable for empty workflows)
-	if len(info.Workflows) != 0 {
-		t.Errorf("Expected empty workflows, got %d", len(info.Workflows))
+			profile := &data.ResearchDomainProfile{Name: tt.profile}
+			for _, size := range config.WorkloadSizes {
+				got := ie.selectOptimalInstance(profile, size, DomainHints{})
+				if got != sizes[size] {
+					t.Errorf("%s/%s: intelligence selected %s, config reports %s", tt.profile, size, got, sizes[size])
+				}
+			}
+		})
 	}
 }
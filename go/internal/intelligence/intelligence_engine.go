@@ -7,6 +7,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/scttfrdmn/aws-research-wizard/go/internal/aws/templates"
 	"github.com/scttfrdmn/aws-research-wizard/go/internal/data"
 )
 
@@ -17,20 +18,26 @@ type IntelligenceEngine struct {
 	domainPackLoader     DomainPackLoaderInterface
 	costOptimizer        *CostOptimizer
 	resourceAnalyzer     *ResourceAnalyzer
+	thresholds           ConfidenceThresholds
 }
 
 // IntelligentRecommendation represents a comprehensive recommendation with domain context
 type IntelligentRecommendation struct {
-	ID               string                     `json:"id"`
-	Timestamp        time.Time                  `json:"timestamp"`
-	Domain           string                     `json:"domain"`
-	DomainPack       *DomainPackInfo            `json:"domain_pack,omitempty"`
-	DataAnalysis     *data.RecommendationResult `json:"data_analysis"`
-	ResourcePlan     *ResourcePlan              `json:"resource_plan"`
-	CostOptimization *CostOptimizationPlan      `json:"cost_optimization"`
-	Implementation   *ImplementationPlan        `json:"implementation"`
-	Confidence       float64                    `json:"confidence"`
-	Impact           *ImpactAssessment          `json:"impact"`
+	ID               string                      `json:"id"`
+	Timestamp        time.Time                   `json:"timestamp"`
+	Domain           string                      `json:"domain"`
+	DomainPack       *DomainPackInfo             `json:"domain_pack,omitempty"`
+	DataAnalysis     *data.RecommendationResult  `json:"data_analysis"`
+	ResourcePlan     *ResourcePlan               `json:"resource_plan"`
+	CostOptimization *CostOptimizationPlan       `json:"cost_optimization"`
+	Implementation   *ImplementationPlan         `json:"implementation"`
+	Confidence       float64                     `json:"confidence"`
+	Impact           *ImpactAssessment           `json:"impact"`
+	DomainDetection  *DomainDetectionExplanation `json:"domain_detection,omitempty"`
+	// Mode says whether the recommendation may be applied without review
+	Mode string `json:"mode"`
+	// Checklist lists hints that would raise confidence when data is insufficient
+	Checklist []string `json:"checklist,omitempty"`
 }
 
 // DomainPackInfo contains information about the recommended domain pack
@@ -43,6 +50,8 @@ type DomainPackInfo struct {
 	Workflows     []WorkflowInfo    `json:"workflows"`
 	SpackPackages []string          `json:"spack_packages"`
 	EstimatedCost map[string]string `json:"estimated_cost"`
+	// ComplianceProfile is the compliance profile the domain pack deploys with
+	ComplianceProfile string `json:"compliance_profile,omitempty"`
 }
 
 // WorkflowInfo describes available workflows in a domain pack
@@ -95,6 +104,17 @@ type SecurityConfiguration struct {
 	IAMRoles            []string `json:"iam_roles"`
 	ComplianceFramework string   `json:"compliance_framework"`
 	AuditingEnabled     bool     `json:"auditing_enabled"`
+
+	// ComplianceProfile is the compliance profile forcing the settings below,
+	// empty when none applies
+	ComplianceProfile      string   `json:"compliance_profile,omitempty"`
+	DedicatedTenancyOption bool     `json:"dedicated_tenancy_option,omitempty"`
+	FlowLogs               bool     `json:"flow_logs,omitempty"`
+	CloudTrail             bool     `json:"cloudtrail,omitempty"`
+	NoPublicIP             bool     `json:"no_public_ip,omitempty"`
+	RestrictedEgress       bool     `json:"restricted_egress,omitempty"`
+	ControlsAddressed      []string `json:"controls_addressed,omitempty"`
+	UserResponsibilities   []string `json:"user_responsibilities,omitempty"`
 }
 
 // CostOptimizationPlan contains cost optimization strategies
@@ -144,6 +164,10 @@ type ImplementationPlan struct {
 	DeploymentScript  string               `json:"deployment_script"`
 	ValidationChecks  []string             `json:"validation_checks"`
 	RollbackProcedure []string             `json:"rollback_procedure"`
+	// ComplianceControls are the controls the deployment addresses and
+	// UserResponsibilities the ones left to the user
+	ComplianceControls   []string `json:"compliance_controls,omitempty"`
+	UserResponsibilities []string `json:"user_responsibilities,omitempty"`
 }
 
 // ImplementationStep describes a single implementation step
@@ -222,7 +246,17 @@ func NewIntelligenceEngine(
 		domainPackLoader:     NewDomainPackLoader(),
 		costOptimizer:        NewCostOptimizer(),
 		resourceAnalyzer:     NewResourceAnalyzer(),
+		thresholds:           DefaultConfidenceThresholds(),
+	}
+}
+
+// SetConfidenceThresholds changes the thresholds recommendation modes are derived from
+func (ie *IntelligenceEngine) SetConfidenceThresholds(thresholds ConfidenceThresholds) error {
+	if err := thresholds.Validate(); err != nil {
+		return err
 	}
+	ie.thresholds = thresholds
+	return nil
 }
 
 // GenerateIntelligentRecommendations creates comprehensive domain-aware recommendations
@@ -232,8 +266,26 @@ func (ie *IntelligenceEngine) GenerateIntelligentRecommendations(
 	hints DomainHints,
 ) (*IntelligentRecommendation, error) {
 
+	// Analyze the data; this scan is the expensive part
+	dataRecommendations, err := ie.recommendationEngine.GenerateRecommendations(ctx, dataPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate data recommendations: %w", err)
+	}
+
+	return ie.recommendFromAnalysis(dataPath, hints, dataRecommendations)
+}
+
+// recommendFromAnalysis builds a recommendation from an existing data
+// analysis, so several hint sets can share one scan of the data
+func (ie *IntelligenceEngine) recommendFromAnalysis(
+	dataPath string,
+	hints DomainHints,
+	dataRecommendations *data.RecommendationResult,
+) (*IntelligentRecommendation, error) {
+
 	// Step 1: Detect or validate domain
-	detectedDomain, confidence := ie.detectDomain(dataPath, hints)
+	detection := ie.ExplainDomainDetection(dataPath, hints)
+	detectedDomain, confidence := detection.Domain, detection.Confidence
 
 	// Step 2: Load domain pack information
 	domainPack, err := ie.domainPackLoader.LoadDomainPack(detectedDomain)
@@ -241,26 +293,25 @@ func (ie *IntelligenceEngine) GenerateIntelligentRecommendations(
 		return nil, fmt.Errorf("failed to load domain pack for %s: %w", detectedDomain, err)
 	}
 
-	// Step 3: Generate data analysis recommendations
-	dataRecommendations, err := ie.recommendationEngine.GenerateRecommendations(ctx, dataPath)
+	// Step 3: Generate resource plan, with the compliance profile's settings forced
+	compliance, err := resolveComplianceProfile(hints, domainPack)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate data recommendations: %w", err)
+		return nil, err
 	}
-
-	// Step 4: Generate resource plan
 	resourcePlan := ie.generateResourcePlan(detectedDomain, dataRecommendations, hints)
+	applyComplianceProfile(&resourcePlan.SecurityConfiguration, compliance)
 
-	// Step 5: Generate cost optimization plan
+	// Step 4: Generate cost optimization plan
 	costPlan := ie.costOptimizer.GenerateCostOptimizationPlan(
 		detectedDomain,
 		resourcePlan,
 		dataRecommendations,
 	)
 
-	// Step 6: Generate implementation plan
+	// Step 5: Generate implementation plan
 	implPlan := ie.generateImplementationPlan(detectedDomain, domainPack, resourcePlan)
 
-	// Step 7: Assess overall impact
+	// Step 6: Assess overall impact
 	impact := ie.assessImpact(resourcePlan, costPlan, dataRecommendations)
 
 	recommendation := &IntelligentRecommendation{
@@ -276,84 +327,27 @@ func (ie *IntelligenceEngine) GenerateIntelligentRecommendations(
 		Impact:           impact,
 	}
 
-	return recommendation, nil
-}
-
-// detectDomain identifies the research domain based on data and hints
-func (ie *IntelligenceEngine) detectDomain(dataPath string, hints DomainHints) (string, float64) {
-	scores := make(map[string]float64)
-
-	// Score based on file extensions
-	if extensions := ie.analyzeFileExtensions(dataPath); len(extensions) > 0 {
-		for domain, profile := range ie.domainProfileManager.GetAllProfiles() {
-			domainScore := 0.0
-			totalHints := len(profile.FileTypeHints)
-
-			for ext := range extensions {
-				if _, exists := profile.FileTypeHints[ext]; exists {
-					domainScore += 1.0
-				}
-			}
-
-			if totalHints > 0 {
-				scores[domain] = domainScore / float64(totalHints)
-			}
-		}
+	filesAnalyzed := hints.InventoryFiles
+	if dataRecommendations.DataPattern != nil && dataRecommendations.DataPattern.TotalFiles > filesAnalyzed {
+		filesAnalyzed = dataRecommendations.DataPattern.TotalFiles
 	}
-
-	// Score based on explicit domain hints
-	if hints.ExplicitDomain != "" {
-		scores[hints.ExplicitDomain] += 0.8
+	recommendation.Mode = RecommendationMode(confidence, detection.Ambiguous(), filesAnalyzed, ie.thresholds)
+	if recommendation.Mode == ModeInsufficientData {
+		recommendation.Checklist = ConfidenceChecklist(detection, hints, filesAnalyzed, ie.thresholds)
 	}
 
-	// Score based on workflow hints
-	for _, workflow := range hints.WorkflowHints {
-		for domain := range ie.domainProfileManager.GetAllProfiles() {
-			if strings.Contains(domain, workflow) || strings.Contains(workflow, domain) {
-				scores[domain] += 0.3
-			}
-		}
+	// Ambiguous detection is always surfaced so a weak guess is never silent
+	if hints.Explain || detection.Ambiguous() {
+		recommendation.DomainDetection = detection
 	}
 
-	// Score based on tool hints
-	for _, tool := range hints.ToolHints {
-		for domain, profile := range ie.domainProfileManager.GetAllProfiles() {
-			// Check transfer engines
-			for _, preferredEngine := range profile.TransferOptimization.PreferredEngines {
-				if strings.Contains(tool, preferredEngine) || strings.Contains(preferredEngine, tool) {
-					scores[domain] += 0.2
-				}
-			}
-
-			// Check domain-specific tools
-			domainSpecificTools := ie.getDomainSpecificTools(domain)
-			for _, domainTool := range domainSpecificTools {
-				if ie.toolsMatch(tool, domainTool) {
-					scores[domain] += 0.4 // Higher score for domain-specific tools
-				}
-			}
-		}
-	}
-
-	// Find the highest scoring domain
-	bestDomain := "general"
-	bestScore := 0.0
-
-	for domain, score := range scores {
-		if score > bestScore {
-			bestDomain = domain
-			bestScore = score
-		}
-	}
-
-	// If no domain scored well, try to detect from common patterns
-	if bestScore < 0.3 {
-		if detected := ie.detectFromCommonPatterns(dataPath); detected != "" {
-			return detected, 0.6
-		}
-	}
+	return recommendation, nil
+}
 
-	return bestDomain, bestScore
+// detectDomain identifies the research domain based on data and hints
+func (ie *IntelligenceEngine) detectDomain(dataPath string, hints DomainHints) (string, float64) {
+	explanation := ie.ExplainDomainDetection(dataPath, hints)
+	return explanation.Domain, explanation.Confidence
 }
 
 // analyzeFileExtensions extracts file extensions from the data path
@@ -457,12 +451,20 @@ func (ie *IntelligenceEngine) generateResourcePlan(
 
 // DomainHints provides additional context for domain detection
 type DomainHints struct {
-	ExplicitDomain   string   `json:"explicit_domain,omitempty"`
-	WorkflowHints    []string `json:"workflow_hints,omitempty"`
-	ToolHints        []string `json:"tool_hints,omitempty"`
-	DataSizeHint     string   `json:"data_size_hint,omitempty"`
-	PerformanceHints []string `json:"performance_hints,omitempty"`
-	BudgetConstraint float64  `json:"budget_constraint,omitempty"`
+	ExplicitDomain   string   `json:"explicit_domain,omitempty" yaml:"explicit_domain,omitempty"`
+	WorkflowHints    []string `json:"workflow_hints,omitempty" yaml:"workflow_hints,omitempty"`
+	ToolHints        []string `json:"tool_hints,omitempty" yaml:"tool_hints,omitempty"`
+	DataSizeHint     string   `json:"data_size_hint,omitempty" yaml:"data_size_hint,omitempty"`
+	PerformanceHints []string `json:"performance_hints,omitempty" yaml:"performance_hints,omitempty"`
+	BudgetConstraint float64  `json:"budget_constraint,omitempty" yaml:"budget_constraint,omitempty"`
+	FileExtensions   []string `json:"file_extensions,omitempty" yaml:"file_extensions,omitempty"`
+	// InventoryFiles is the number of files listed in an inventory manifest of the dataset
+	InventoryFiles int64 `json:"inventory_files,omitempty" yaml:"inventory_files,omitempty"`
+	// Explain includes the per-factor domain detection breakdown in the recommendation
+	Explain bool `json:"explain,omitempty" yaml:"explain,omitempty"`
+	// ComplianceProfile selects a compliance profile, e.g. hipaa or cui, over
+	// the domain pack's
+	ComplianceProfile string `json:"compliance_profile,omitempty" yaml:"compliance_profile,omitempty"`
 }
 
 // Additional helper methods would continue here...
@@ -552,6 +554,15 @@ func (ie *IntelligenceEngine) generateImplementationPlan(
 	resourcePlan *ResourcePlan,
 ) *ImplementationPlan {
 
+	security := resourcePlan.SecurityConfiguration
+	deployCommand := fmt.Sprintf("aws-research-wizard deploy --domain %s --instance %s",
+		domain, resourcePlan.RecommendedInstance)
+	if security.ComplianceProfile != "" {
+		// Compliance profiles keep instances off public addresses, so they are
+		// reached through Session Manager from a private subnet
+		deployCommand += fmt.Sprintf(" --compliance %s --no-ssh --vpc <vpc-id> --subnet <private-subnet-id>", security.ComplianceProfile)
+	}
+
 	steps := []ImplementationStep{
 		{
 			Order:       1,
@@ -570,8 +581,7 @@ func (ie *IntelligenceEngine) generateImplementationPlan(
 			Title:       "Deploy Domain Pack Infrastructure",
 			Description: "Deploy the recommended AWS infrastructure",
 			Commands: []string{
-				fmt.Sprintf("aws-research-wizard deploy --domain %s --instance %s",
-					domain, resourcePlan.RecommendedInstance),
+				deployCommand,
 			},
 			Duration:        "20-30 minutes",
 			Dependencies:    []string{"AWS Environment"},
@@ -607,27 +617,36 @@ func (ie *IntelligenceEngine) generateImplementationPlan(
 		complexity = "complex"
 	}
 
+	prerequisites := []string{
+		"AWS account with appropriate permissions",
+		"AWS CLI installed",
+		"Basic knowledge of " + domain + " workflows",
+	}
+	validationChecks := []string{
+		"Instance accessibility",
+		"Software installation status",
+		"Domain pack configuration",
+		"Network connectivity",
+	}
+	if security.ComplianceProfile != "" {
+		prerequisites = append(prerequisites, "VPC with a private subnet that reaches AWS through a NAT gateway or VPC endpoints")
+		validationChecks = append(validationChecks, fmt.Sprintf("%s controls: CloudTrail logging, VPC flow logs, no public IP addresses", security.ComplianceFramework))
+	}
+
 	return &ImplementationPlan{
 		EstimatedDuration: "1.5-2.5 hours",
 		Complexity:        complexity,
-		Prerequisites: []string{
-			"AWS account with appropriate permissions",
-			"AWS CLI installed",
-			"Basic knowledge of " + domain + " workflows",
-		},
-		Steps:            steps,
-		DeploymentScript: ie.generateDeploymentScript(domain, resourcePlan),
-		ValidationChecks: []string{
-			"Instance accessibility",
-			"Software installation status",
-			"Domain pack configuration",
-			"Network connectivity",
-		},
+		Prerequisites:     prerequisites,
+		Steps:             steps,
+		DeploymentScript:  ie.generateDeploymentScript(domain, resourcePlan),
+		ValidationChecks:  validationChecks,
 		RollbackProcedure: []string{
 			"Stop all running instances",
 			"Delete CloudFormation stack",
 			"Clean up S3 resources",
 		},
+		ComplianceControls:   security.ControlsAddressed,
+		UserResponsibilities: security.UserResponsibilities,
 	}
 }
 
@@ -870,6 +889,37 @@ func (ie *IntelligenceEngine) generateSecurityConfiguration(
 	return config
 }
 
+// resolveComplianceProfile picks the compliance profile from the hints, then the domain pack
+func resolveComplianceProfile(hints DomainHints, domainPack *DomainPackInfo) (templates.ComplianceProfile, error) {
+	name := hints.ComplianceProfile
+	if name == "" && domainPack != nil {
+		name = domainPack.ComplianceProfile
+	}
+	return templates.ParseComplianceProfile(name)
+}
+
+// applyComplianceProfile forces the settings of a compliance profile onto a
+// security configuration
+func applyComplianceProfile(config *SecurityConfiguration, profile templates.ComplianceProfile) {
+	settings := profile.Settings()
+	if !settings.Enforced() {
+		return
+	}
+
+	config.ComplianceProfile = string(profile)
+	config.ComplianceFramework = settings.Framework
+	config.EncryptionAtRest = config.EncryptionAtRest || settings.EncryptionAtRest
+	config.EncryptionInTransit = config.EncryptionInTransit || settings.EncryptionInTransit
+	config.AuditingEnabled = config.AuditingEnabled || settings.CloudTrail
+	config.DedicatedTenancyOption = settings.TenancyOption
+	config.FlowLogs = settings.FlowLogs
+	config.CloudTrail = settings.CloudTrail
+	config.NoPublicIP = settings.NoPublicIP
+	config.RestrictedEgress = settings.RestrictedEgress
+	config.ControlsAddressed = settings.ControlsAddressed
+	config.UserResponsibilities = settings.UserResponsibilities
+}
+
 // generateResourceReasoning creates reasoning explanation for resource recommendations
 func (ie *IntelligenceEngine) generateResourceReasoning(
 	domain string,
@@ -407,6 +407,82 @@ func TestIntelligenceEngine_GenerateIntelligentRecommendations(t *testing.T) {
 	}
 }
 
+func TestIntelligenceEngine_ComplianceProfile(t *testing.T) {
+	tests := []struct {
+		name       string
+		hint       string
+		pack       string
+		want       string
+		framework  string
+		forced     bool
+		wantErr    bool
+		wantDeploy string
+	}{
+		{name: "none", want: ""},
+		{name: "explicit none", hint: "none", pack: "hipaa", want: ""},
+		{name: "hint", hint: "hipaa", want: "hipaa", framework: "HIPAA", forced: true, wantDeploy: "--compliance hipaa --no-ssh"},
+		{name: "domain pack", pack: "nist-800-171", want: "cui", framework: "NIST SP 800-171", forced: true, wantDeploy: "--compliance cui --no-ssh"},
+		{name: "hint overrides domain pack", hint: "cui", pack: "hipaa", want: "cui", framework: "NIST SP 800-171", forced: true, wantDeploy: "--compliance cui"},
+		{name: "unknown", hint: "sox", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ie := createTestIntelligenceEngine()
+			profile, err := resolveComplianceProfile(DomainHints{ComplianceProfile: tt.hint}, &DomainPackInfo{Name: "genomics", ComplianceProfile: tt.pack})
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("Expected an error for an unknown compliance profile")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolveComplianceProfile() error = %v", err)
+			}
+
+			plan := ie.generateGeneralResourcePlan(&data.RecommendationResult{}, DomainHints{})
+			applyComplianceProfile(&plan.SecurityConfiguration, profile)
+			security := plan.SecurityConfiguration
+			if security.ComplianceProfile != tt.want || security.ComplianceFramework != tt.framework {
+				t.Errorf("profile = %q (%q), want %q (%q)", security.ComplianceProfile, security.ComplianceFramework, tt.want, tt.framework)
+			}
+			for name, on := range map[string]bool{
+				"DedicatedTenancyOption": security.DedicatedTenancyOption,
+				"FlowLogs":               security.FlowLogs,
+				"CloudTrail":             security.CloudTrail,
+				"NoPublicIP":             security.NoPublicIP,
+				"RestrictedEgress":       security.RestrictedEgress,
+			} {
+				if on != tt.forced {
+					t.Errorf("%s = %v, want %v", name, on, tt.forced)
+				}
+			}
+			if !security.EncryptionAtRest || !security.EncryptionInTransit {
+				t.Error("Expected encryption to stay on")
+			}
+
+			implementation := ie.generateImplementationPlan("genomics", nil, plan)
+			if (len(implementation.ComplianceControls) > 0) != tt.forced || (len(implementation.UserResponsibilities) > 0) != tt.forced {
+				t.Errorf("controls = %v, responsibilities = %v", implementation.ComplianceControls, implementation.UserResponsibilities)
+			}
+			deploy := implementation.Steps[1].Commands[0]
+			if tt.wantDeploy != "" && !strings.Contains(deploy, tt.wantDeploy) {
+				t.Errorf("deploy command = %q, want %q", deploy, tt.wantDeploy)
+			}
+			if tt.wantDeploy == "" && strings.Contains(deploy, "--compliance") {
+				t.Errorf("deploy command = %q, want no compliance profile", deploy)
+			}
+		})
+	}
+
+	// An unknown profile stops the recommendation
+	ie := createTestIntelligenceEngine()
+	ie.recommendationEngine = &mockRecommendationEngine{}
+	if _, err := ie.GenerateIntelligentRecommendations(context.Background(), "/data/genomics/samples.fastq", DomainHints{ExplicitDomain: "genomics", ComplianceProfile: "sox"}); err == nil {
+		t.Error("Expected an unknown compliance profile to be rejected")
+	}
+}
+
 func TestIntelligenceEngine_generateResourcePlan(t *testing.T) {
 	ie := createTestIntelligenceEngine()
 
@@ -0,0 +1,268 @@
+package intelligence
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Right-sizing actions
+const (
+	RightsizeKeep      = "keep"
+	RightsizeDownsize  = "downsize"
+	RightsizeUpsize    = "upsize"
+	RightsizeBurstable = "burstable"
+)
+
+// Right-sizing decision thresholds, as utilization percentages
+const (
+	// DownsizeCPUPercent is the p95 CPU below which an instance is oversized
+	DownsizeCPUPercent = 40.0
+	// UpsizeCPUPercent is the p95 CPU above which an instance is undersized
+	UpsizeCPUPercent = 85.0
+	// UpsizeMemoryPercent is the p95 memory above which an instance is undersized
+	UpsizeMemoryPercent = 90.0
+	// RightsizeTargetPercent is the highest projected p95 accepted after downsizing
+	RightsizeTargetPercent = 60.0
+	// BurstableBaselinePercent is the median CPU below which spiky load suits burstable instances
+	BurstableBaselinePercent = 20.0
+	// BurstablePeakPercent is the p95 CPU that marks a low-baseline instance as bursty
+	BurstablePeakPercent = 50.0
+
+	// burstableMaxVCPUs is the largest t3/t4g size (2xlarge)
+	burstableMaxVCPUs = 8
+	// burstableMemoryPerVCPU is the memory of t3/t4g sizes from large upwards
+	burstableMemoryPerVCPU = 4.0
+	// rightsizeHoursPerMonth matches the cost optimizer's monthly estimate
+	rightsizeHoursPerMonth = 24 * 30
+)
+
+// instanceSizeLadder orders standard sizes with their vCPU counts
+var instanceSizeLadder = []struct {
+	size  string
+	vcpus int
+}{
+	{"large", 2}, {"xlarge", 4}, {"2xlarge", 8}, {"4xlarge", 16}, {"8xlarge", 32},
+	{"12xlarge", 48}, {"16xlarge", 64}, {"24xlarge", 96}, {"32xlarge", 128}, {"48xlarge", 192},
+}
+
+// HourlyPriceFunc returns the on-demand hourly price of an instance type
+type HourlyPriceFunc func(instanceType string) (float64, error)
+
+// UtilizationSamples holds historical utilization for one instance
+type UtilizationSamples struct {
+	CPUPercent []float64
+	// MemoryPercent is empty when the CloudWatch agent is not installed
+	MemoryPercent         []float64
+	NetworkBytesPerSecond []float64
+}
+
+// UtilizationStats summarizes a utilization series
+type UtilizationStats struct {
+	Samples int     `json:"samples"`
+	Mean    float64 `json:"mean"`
+	P50     float64 `json:"p50"`
+	P95     float64 `json:"p95"`
+	Max     float64 `json:"max"`
+}
+
+// RightsizingRecommendation suggests a better instance size from observed utilization
+type RightsizingRecommendation struct {
+	InstanceID             string            `json:"instance_id"`
+	CurrentType            string            `json:"current_type"`
+	RecommendedType        string            `json:"recommended_type"`
+	Action                 string            `json:"action"`
+	CPU                    UtilizationStats  `json:"cpu"`
+	Memory                 *UtilizationStats `json:"memory,omitempty"`
+	Network                UtilizationStats  `json:"network_bytes_per_second"`
+	CurrentMonthlyCost     float64           `json:"current_monthly_cost"`
+	RecommendedMonthlyCost float64           `json:"recommended_monthly_cost"`
+	MonthlySavings         float64           `json:"monthly_savings"`
+	Reasoning              []string          `json:"reasoning"`
+}
+
+// SummarizeUtilization computes the mean, median, p95 and max of a series
+func SummarizeUtilization(values []float64) UtilizationStats {
+	stats := UtilizationStats{Samples: len(values)}
+	if len(values) == 0 {
+		return stats
+	}
+
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+
+	var sum float64
+	for _, value := range sorted {
+		sum += value
+	}
+
+	stats.Mean = sum / float64(len(sorted))
+	stats.P50 = percentile(sorted, 50)
+	stats.P95 = percentile(sorted, 95)
+	stats.Max = sorted[len(sorted)-1]
+	return stats
+}
+
+// percentile uses the nearest-rank method on sorted values
+func percentile(sorted []float64, p float64) float64 {
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	if rank > len(sorted) {
+		rank = len(sorted)
+	}
+	return sorted[rank-1]
+}
+
+// RecommendRightsizing compares an instance's utilization against the decision
+// thresholds and prices the suggested change
+func (ra *ResourceAnalyzer) RecommendRightsizing(instanceID, instanceType string, samples UtilizationSamples, price HourlyPriceFunc) (*RightsizingRecommendation, error) {
+	if len(samples.CPUPercent) == 0 {
+		return nil, fmt.Errorf("no CPU utilization data for %s", instanceID)
+	}
+
+	rec := &RightsizingRecommendation{
+		InstanceID:  instanceID,
+		CurrentType: instanceType,
+		CPU:         SummarizeUtilization(samples.CPUPercent),
+		Network:     SummarizeUtilization(samples.NetworkBytesPerSecond),
+	}
+	if len(samples.MemoryPercent) > 0 {
+		memory := SummarizeUtilization(samples.MemoryPercent)
+		rec.Memory = &memory
+	}
+
+	rec.Action, rec.RecommendedType, rec.Reasoning = ra.decideRightsizing(instanceType, rec.CPU, rec.Memory)
+
+	currentHourly, err := price(instanceType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to price %s: %w", instanceType, err)
+	}
+	recommendedHourly := currentHourly
+	if rec.RecommendedType != instanceType {
+		if recommendedHourly, err = price(rec.RecommendedType); err != nil {
+			return nil, fmt.Errorf("failed to price %s: %w", rec.RecommendedType, err)
+		}
+	}
+
+	rec.CurrentMonthlyCost = currentHourly * rightsizeHoursPerMonth
+	rec.RecommendedMonthlyCost = recommendedHourly * rightsizeHoursPerMonth
+	rec.MonthlySavings = rec.CurrentMonthlyCost - rec.RecommendedMonthlyCost
+
+	return rec, nil
+}
+
+// decideRightsizing applies the thresholds in order: burstable, upsize, downsize, keep
+func (ra *ResourceAnalyzer) decideRightsizing(instanceType string, cpu UtilizationStats, memory *UtilizationStats) (string, string, []string) {
+	family, index, ok := parseInstanceSize(instanceType)
+	if !ok {
+		return RightsizeKeep, instanceType, []string{fmt.Sprintf("Size of %s is not on the standard ladder; no change suggested", instanceType)}
+	}
+	vcpus := instanceSizeLadder[index].vcpus
+
+	memoryPressure := memory != nil && memory.P95 >= UpsizeMemoryPercent
+
+	if cpu.P50 < BurstableBaselinePercent && cpu.P95 >= BurstablePeakPercent && !memoryPressure {
+		if burstable, fits := ra.burstableAlternative(instanceType, family, vcpus, memory); fits {
+			return RightsizeBurstable, burstable, []string{
+				fmt.Sprintf("CPU idles at %.0f%% median but peaks at %.0f%% (p95); a burstable instance absorbs short spikes with CPU credits", cpu.P50, cpu.P95),
+			}
+		}
+	}
+
+	if cpu.P95 >= UpsizeCPUPercent || memoryPressure {
+		if index+1 >= len(instanceSizeLadder) {
+			return RightsizeKeep, instanceType, []string{"Instance is saturated but already the largest standard size; consider a larger family"}
+		}
+		var reasons []string
+		if cpu.P95 >= UpsizeCPUPercent {
+			reasons = append(reasons, fmt.Sprintf("CPU p95 of %.0f%% exceeds %.0f%%", cpu.P95, UpsizeCPUPercent))
+		}
+		if memoryPressure {
+			reasons = append(reasons, fmt.Sprintf("Memory p95 of %.0f%% exceeds %.0f%%", memory.P95, UpsizeMemoryPercent))
+		}
+		return RightsizeUpsize, family + "." + instanceSizeLadder[index+1].size, reasons
+	}
+
+	if cpu.P95 < DownsizeCPUPercent && (memory == nil || memory.P95 < DownsizeCPUPercent) {
+		target := index
+		for target > 0 {
+			ratio := float64(vcpus) / float64(instanceSizeLadder[target-1].vcpus)
+			if cpu.P95*ratio > RightsizeTargetPercent || (memory != nil && memory.P95*ratio > RightsizeTargetPercent) {
+				break
+			}
+			target--
+			// Without memory metrics only step down one size
+			if memory == nil {
+				break
+			}
+		}
+
+		if target < index {
+			reasons := []string{fmt.Sprintf("CPU p95 of %.0f%% is below %.0f%%", cpu.P95, DownsizeCPUPercent)}
+			if memory == nil {
+				reasons = append(reasons, "Memory metrics unavailable (install the CloudWatch agent); limited to one size down")
+			} else {
+				reasons = append(reasons, fmt.Sprintf("Memory p95 of %.0f%% leaves headroom", memory.P95))
+			}
+			return RightsizeDownsize, family + "." + instanceSizeLadder[target].size, reasons
+		}
+	}
+
+	return RightsizeKeep, instanceType, []string{fmt.Sprintf("CPU p95 of %.0f%% is within the target range", cpu.P95)}
+}
+
+// burstableAlternative returns a t3 (or t4g for Graviton families) instance with the
+// same vCPUs, if one exists and has enough memory for the observed peak
+func (ra *ResourceAnalyzer) burstableAlternative(instanceType, family string, vcpus int, memory *UtilizationStats) (string, bool) {
+	if vcpus > burstableMaxVCPUs {
+		return "", false
+	}
+
+	if memory != nil {
+		if spec, exists := ra.instanceSpecs[instanceType]; exists {
+			usedGB := spec.MemoryGB * memory.P95 / 100
+			if usedGB > float64(vcpus)*burstableMemoryPerVCPU*RightsizeTargetPercent/100 {
+				return "", false
+			}
+		}
+	}
+
+	burstableFamily := "t3"
+	if isGravitonFamily(family) {
+		burstableFamily = "t4g"
+	}
+	for _, step := range instanceSizeLadder {
+		if step.vcpus == vcpus {
+			candidate := burstableFamily + "." + step.size
+			return candidate, candidate != instanceType
+		}
+	}
+	return "", false
+}
+
+// parseInstanceSize splits an instance type into its family and ladder position
+func parseInstanceSize(instanceType string) (string, int, bool) {
+	parts := strings.SplitN(instanceType, ".", 2)
+	if len(parts) != 2 {
+		return "", 0, false
+	}
+	for i, step := range instanceSizeLadder {
+		if step.size == parts[1] {
+			return parts[0], i, true
+		}
+	}
+	return "", 0, false
+}
+
+// isGravitonFamily reports whether a family such as c7g or r6gd runs on Graviton
+func isGravitonFamily(family string) bool {
+	for i := len(family) - 1; i > 0; i-- {
+		if family[i] >= '0' && family[i] <= '9' {
+			return strings.HasPrefix(family[i+1:], "g")
+		}
+	}
+	return false
+}
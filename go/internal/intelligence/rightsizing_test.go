@@ -0,0 +1,206 @@
+package intelligence
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+// constantSeries returns n samples of the same value
+func constantSeries(n int, value float64) []float64 {
+	series := make([]float64, n)
+	for i := range series {
+		series[i] = value
+	}
+	return series
+}
+
+// burstySeries idles at base and spikes to peak for one sample in every period
+func burstySeries(n int, base, peak float64, period int) []float64 {
+	series := make([]float64, n)
+	for i := range series {
+		series[i] = base
+		if i%period == 0 {
+			series[i] = peak
+		}
+	}
+	return series
+}
+
+var fixturePrices = map[string]float64{
+	"r6i.large":   0.126,
+	"r6i.xlarge":  0.252,
+	"r6i.2xlarge": 0.504,
+	"r6i.4xlarge": 1.008,
+	"r6i.8xlarge": 2.016,
+	"c6i.xlarge":  0.17,
+	"c6i.2xlarge": 0.34,
+	"t3.xlarge":   0.1664,
+	"t4g.xlarge":  0.1344,
+}
+
+func fixturePrice(instanceType string) (float64, error) {
+	price, ok := fixturePrices[instanceType]
+	if !ok {
+		return 0, fmt.Errorf("no price for %s", instanceType)
+	}
+	return price, nil
+}
+
+func TestSummarizeUtilization(t *testing.T) {
+	values := make([]float64, 100)
+	for i := range values {
+		values[99-i] = float64(i + 1)
+	}
+
+	stats := SummarizeUtilization(values)
+	if stats.Samples != 100 || stats.P50 != 50 || stats.P95 != 95 || stats.Max != 100 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+	if math.Abs(stats.Mean-50.5) > 1e-9 {
+		t.Errorf("Expected mean 50.5, got %f", stats.Mean)
+	}
+
+	if values[0] != 100 {
+		t.Error("SummarizeUtilization must not reorder its input")
+	}
+
+	empty := SummarizeUtilization(nil)
+	if empty.Samples != 0 || empty.P95 != 0 {
+		t.Errorf("Expected zero stats for an empty series, got %+v", empty)
+	}
+}
+
+func TestRecommendRightsizingDownsize(t *testing.T) {
+	ra := NewResourceAnalyzer()
+
+	// Idle at 6% CPU and 5% memory for 14 days of 5-minute samples
+	samples := UtilizationSamples{
+		CPUPercent:    constantSeries(4032, 6),
+		MemoryPercent: constantSeries(4032, 5),
+	}
+
+	rec, err := ra.RecommendRightsizing("i-idle", "r6i.8xlarge", samples, fixturePrice)
+	if err != nil {
+		t.Fatalf("RecommendRightsizing failed: %v", err)
+	}
+	if rec.Action != RightsizeDownsize {
+		t.Fatalf("Expected downsize, got %s (%v)", rec.Action, rec.Reasoning)
+	}
+	// 6% CPU * 32/4 vCPUs = 48% stays under the 60% target; one more step would be 96%
+	if rec.RecommendedType != "r6i.xlarge" {
+		t.Errorf("Expected r6i.xlarge, got %s", rec.RecommendedType)
+	}
+	wantSavings := (2.016 - 0.252) * rightsizeHoursPerMonth
+	if math.Abs(rec.MonthlySavings-wantSavings) > 1e-6 {
+		t.Errorf("Expected savings %.2f, got %.2f", wantSavings, rec.MonthlySavings)
+	}
+
+	// Memory limits the step down: 20% * 32/8 = 80% would exceed the target
+	samples.MemoryPercent = constantSeries(4032, 20)
+	rec, err = ra.RecommendRightsizing("i-idle", "r6i.8xlarge", samples, fixturePrice)
+	if err != nil {
+		t.Fatalf("RecommendRightsizing failed: %v", err)
+	}
+	if rec.RecommendedType != "r6i.4xlarge" {
+		t.Errorf("Expected memory to limit the downsize to r6i.4xlarge, got %s", rec.RecommendedType)
+	}
+}
+
+func TestRecommendRightsizingDownsizeWithoutMemory(t *testing.T) {
+	ra := NewResourceAnalyzer()
+
+	rec, err := ra.RecommendRightsizing("i-idle", "r6i.8xlarge", UtilizationSamples{CPUPercent: constantSeries(100, 6)}, fixturePrice)
+	if err != nil {
+		t.Fatalf("RecommendRightsizing failed: %v", err)
+	}
+	if rec.Action != RightsizeDownsize || rec.RecommendedType != "r6i.4xlarge" {
+		t.Errorf("Expected a single step down without memory metrics, got %s %s", rec.Action, rec.RecommendedType)
+	}
+	if rec.Memory != nil {
+		t.Error("Expected no memory stats without agent data")
+	}
+}
+
+func TestRecommendRightsizingUpsize(t *testing.T) {
+	ra := NewResourceAnalyzer()
+
+	tests := []struct {
+		name    string
+		samples UtilizationSamples
+	}{
+		{"cpu", UtilizationSamples{CPUPercent: constantSeries(100, 92)}},
+		{"memory", UtilizationSamples{CPUPercent: constantSeries(100, 30), MemoryPercent: constantSeries(100, 95)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec, err := ra.RecommendRightsizing("i-busy", "c6i.xlarge", tt.samples, fixturePrice)
+			if err != nil {
+				t.Fatalf("RecommendRightsizing failed: %v", err)
+			}
+			if rec.Action != RightsizeUpsize || rec.RecommendedType != "c6i.2xlarge" {
+				t.Errorf("Expected upsize to c6i.2xlarge, got %s %s", rec.Action, rec.RecommendedType)
+			}
+			if rec.MonthlySavings >= 0 {
+				t.Errorf("Expected a cost increase, got savings %.2f", rec.MonthlySavings)
+			}
+		})
+	}
+}
+
+func TestRecommendRightsizingBurstable(t *testing.T) {
+	ra := NewResourceAnalyzer()
+
+	// 5% baseline with a 90% spike every tenth sample: p50 5%, p95 90%
+	samples := UtilizationSamples{CPUPercent: burstySeries(1000, 5, 90, 10)}
+
+	rec, err := ra.RecommendRightsizing("i-bursty", "c6i.xlarge", samples, fixturePrice)
+	if err != nil {
+		t.Fatalf("RecommendRightsizing failed: %v", err)
+	}
+	if rec.Action != RightsizeBurstable || rec.RecommendedType != "t3.xlarge" {
+		t.Errorf("Expected burstable t3.xlarge, got %s %s", rec.Action, rec.RecommendedType)
+	}
+
+	// Large instances have no burstable equivalent and fall through to upsize
+	rec, err = ra.RecommendRightsizing("i-bursty", "r6i.4xlarge", samples, fixturePrice)
+	if err != nil {
+		t.Fatalf("RecommendRightsizing failed: %v", err)
+	}
+	if rec.Action == RightsizeBurstable {
+		t.Errorf("Expected no burstable suggestion above %d vCPUs", burstableMaxVCPUs)
+	}
+}
+
+func TestRecommendRightsizingKeep(t *testing.T) {
+	ra := NewResourceAnalyzer()
+
+	rec, err := ra.RecommendRightsizing("i-ok", "r6i.2xlarge", UtilizationSamples{CPUPercent: constantSeries(100, 55)}, fixturePrice)
+	if err != nil {
+		t.Fatalf("RecommendRightsizing failed: %v", err)
+	}
+	if rec.Action != RightsizeKeep || rec.RecommendedType != "r6i.2xlarge" || rec.MonthlySavings != 0 {
+		t.Errorf("Expected keep with no savings, got %+v", rec)
+	}
+}
+
+func TestRecommendRightsizingErrors(t *testing.T) {
+	ra := NewResourceAnalyzer()
+
+	if _, err := ra.RecommendRightsizing("i-empty", "r6i.large", UtilizationSamples{}, fixturePrice); err == nil {
+		t.Error("Expected error without CPU data")
+	}
+	if _, err := ra.RecommendRightsizing("i-unpriced", "m5.large", UtilizationSamples{CPUPercent: constantSeries(10, 50)}, fixturePrice); err == nil {
+		t.Error("Expected error when the instance cannot be priced")
+	}
+}
+
+func TestIsGravitonFamily(t *testing.T) {
+	tests := map[string]bool{"c7g": true, "r6gd": true, "t4g": true, "c6i": false, "m5": false, "g5": false}
+	for family, want := range tests {
+		if got := isGravitonFamily(family); got != want {
+			t.Errorf("isGravitonFamily(%q) = %v, want %v", family, got, want)
+		}
+	}
+}
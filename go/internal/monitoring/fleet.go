@@ -0,0 +1,189 @@
+package monitoring
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// FleetCPUWindow is how far back CPU utilization is averaged
+const FleetCPUWindow = time.Hour
+
+// UnknownFleetDomain groups instances without a Domain tag
+const UnknownFleetDomain = "unknown"
+
+// FleetInstance is a research instance as the fleet view sees it
+type FleetInstance struct {
+	InstanceID   string    `json:"instance_id"`
+	InstanceType string    `json:"instance_type"`
+	State        string    `json:"state"`
+	Domain       string    `json:"domain"`
+	Stack        string    `json:"stack,omitempty"`
+	LaunchTime   time.Time `json:"launch_time"`
+}
+
+// running reports whether the instance accrues compute charges
+func (i FleetInstance) running() bool {
+	return i.State == "running" || i.State == "pending"
+}
+
+// CPUFetcher looks up the average CPU utilization of an instance over a
+// window, reporting false when there are no datapoints
+type CPUFetcher interface {
+	AverageCPU(ctx context.Context, instanceID string, start, end time.Time) (float64, bool, error)
+}
+
+// CostFetcher prices instances and looks up what a domain has been billed
+type CostFetcher interface {
+	HourlyCost(instanceType string) (float64, error)
+	// MonthToDate returns a domain's cost from the start of the month, reporting
+	// false when billing data is not available for it
+	MonthToDate(ctx context.Context, domain string, monthStart, now time.Time) (float64, bool, error)
+}
+
+// FleetSummary aggregates the instances of one domain
+type FleetSummary struct {
+	Domain     string         `json:"domain"`
+	Stacks     []string       `json:"stacks"`
+	Instances  int            `json:"instances"`
+	States     map[string]int `json:"states"`
+	HourlyCost float64        `json:"hourly_cost"`
+	// MonthToDateCost is billed cost, or an estimate from the running
+	// instances' hours this month when billing data is unavailable
+	MonthToDateCost      float64 `json:"month_to_date_cost"`
+	MonthToDateEstimated bool    `json:"month_to_date_estimated"`
+	// AverageCPU averages the running instances with CPU data over FleetCPUWindow
+	AverageCPU   float64 `json:"average_cpu"`
+	CPUInstances int     `json:"cpu_instances"`
+	// OldestRunning is the running instance launched longest ago
+	OldestRunning    string        `json:"oldest_running,omitempty"`
+	OldestRunningAge time.Duration `json:"oldest_running_age,omitempty"`
+}
+
+// AggregateFleet groups instances by domain and summarizes each group, in
+// domain order. Only running instances are priced and have their CPU fetched.
+func AggregateFleet(ctx context.Context, instances []FleetInstance, cpu CPUFetcher, costs CostFetcher, now time.Time) ([]FleetSummary, error) {
+	groups := make(map[string][]FleetInstance)
+	for _, instance := range instances {
+		domain := instance.Domain
+		if domain == "" {
+			domain = UnknownFleetDomain
+		}
+		groups[domain] = append(groups[domain], instance)
+	}
+
+	domains := make([]string, 0, len(groups))
+	for domain := range groups {
+		domains = append(domains, domain)
+	}
+	sort.Strings(domains)
+
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	summaries := make([]FleetSummary, 0, len(domains))
+	for _, domain := range domains {
+		summary, err := summarizeDomain(ctx, domain, groups[domain], cpu, costs, monthStart, now)
+		if err != nil {
+			return nil, err
+		}
+		summaries = append(summaries, summary)
+	}
+	return summaries, nil
+}
+
+// summarizeDomain aggregates one domain's instances
+func summarizeDomain(ctx context.Context, domain string, instances []FleetInstance, cpu CPUFetcher, costs CostFetcher, monthStart, now time.Time) (FleetSummary, error) {
+	summary := FleetSummary{
+		Domain:    domain,
+		Instances: len(instances),
+		States:    make(map[string]int),
+	}
+
+	stacks := make(map[string]bool)
+	var cpuTotal, estimate float64
+	var oldest time.Time
+	for _, instance := range instances {
+		summary.States[instance.State]++
+		if instance.Stack != "" && !stacks[instance.Stack] {
+			stacks[instance.Stack] = true
+			summary.Stacks = append(summary.Stacks, instance.Stack)
+		}
+		if !instance.running() {
+			continue
+		}
+
+		hourly, err := costs.HourlyCost(instance.InstanceType)
+		if err != nil {
+			return summary, fmt.Errorf("failed to price %s: %w", instance.InstanceType, err)
+		}
+		summary.HourlyCost += hourly
+
+		// Launch time resets when an instance is started again, so the estimate
+		// only counts the current run
+		since := instance.LaunchTime
+		if since.Before(monthStart) {
+			since = monthStart
+		}
+		if hours := now.Sub(since).Hours(); hours > 0 {
+			estimate += hourly * hours
+		}
+
+		average, ok, err := cpu.AverageCPU(ctx, instance.InstanceID, now.Add(-FleetCPUWindow), now)
+		if err != nil {
+			return summary, fmt.Errorf("failed to get CPU utilization for %s: %w", instance.InstanceID, err)
+		}
+		if ok {
+			cpuTotal += average
+			summary.CPUInstances++
+		}
+
+		if summary.OldestRunning == "" || instance.LaunchTime.Before(oldest) {
+			oldest = instance.LaunchTime
+			summary.OldestRunning = instance.InstanceID
+			summary.OldestRunningAge = now.Sub(instance.LaunchTime)
+		}
+	}
+	sort.Strings(summary.Stacks)
+	if summary.CPUInstances > 0 {
+		summary.AverageCPU = cpuTotal / float64(summary.CPUInstances)
+	}
+
+	billed, ok, err := costs.MonthToDate(ctx, domain, monthStart, now)
+	if err != nil {
+		return summary, fmt.Errorf("failed to get month-to-date cost for %s: %w", domain, err)
+	}
+	if ok {
+		summary.MonthToDateCost = billed
+	} else {
+		summary.MonthToDateCost = estimate
+		summary.MonthToDateEstimated = true
+	}
+	return summary, nil
+}
+
+// TotalFleet sums domain summaries into one for the whole fleet
+func TotalFleet(summaries []FleetSummary) FleetSummary {
+	total := FleetSummary{Domain: "total", States: make(map[string]int)}
+	var cpuTotal float64
+	for _, summary := range summaries {
+		total.Stacks = append(total.Stacks, summary.Stacks...)
+		total.Instances += summary.Instances
+		for state, count := range summary.States {
+			total.States[state] += count
+		}
+		total.HourlyCost += summary.HourlyCost
+		total.MonthToDateCost += summary.MonthToDateCost
+		total.MonthToDateEstimated = total.MonthToDateEstimated || summary.MonthToDateEstimated
+		cpuTotal += summary.AverageCPU * float64(summary.CPUInstances)
+		total.CPUInstances += summary.CPUInstances
+		if summary.OldestRunningAge > total.OldestRunningAge {
+			total.OldestRunning = summary.OldestRunning
+			total.OldestRunningAge = summary.OldestRunningAge
+		}
+	}
+	sort.Strings(total.Stacks)
+	if total.CPUInstances > 0 {
+		total.AverageCPU = cpuTotal / float64(total.CPUInstances)
+	}
+	return total
+}
@@ -0,0 +1,159 @@
+package monitoring
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+var fleetTestNow = time.Date(2026, 3, 11, 0, 0, 0, 0, time.UTC)
+
+// fakeFleetFetcher serves CPU and cost data from maps
+type fakeFleetFetcher struct {
+	cpu     map[string]float64
+	hourly  map[string]float64
+	billed  map[string]float64
+	cpuErr  error
+	cpuSeen []string
+}
+
+func (f *fakeFleetFetcher) AverageCPU(ctx context.Context, instanceID string, start, end time.Time) (float64, bool, error) {
+	f.cpuSeen = append(f.cpuSeen, instanceID)
+	if f.cpuErr != nil {
+		return 0, false, f.cpuErr
+	}
+	average, ok := f.cpu[instanceID]
+	return average, ok, nil
+}
+
+func (f *fakeFleetFetcher) HourlyCost(instanceType string) (float64, error) {
+	hourly, ok := f.hourly[instanceType]
+	if !ok {
+		return 0, errors.New("unknown instance type")
+	}
+	return hourly, nil
+}
+
+func (f *fakeFleetFetcher) MonthToDate(ctx context.Context, domain string, monthStart, now time.Time) (float64, bool, error) {
+	cost, ok := f.billed[domain]
+	return cost, ok, nil
+}
+
+func fleetTestInstances() []FleetInstance {
+	return []FleetInstance{
+		{InstanceID: "i-1", InstanceType: "c6i.xlarge", State: "running", Domain: "genomics", Stack: "genomics-a", LaunchTime: fleetTestNow.Add(-48 * time.Hour)},
+		{InstanceID: "i-2", InstanceType: "c6i.xlarge", State: "running", Domain: "genomics", Stack: "genomics-b", LaunchTime: fleetTestNow.Add(-5 * time.Hour)},
+		{InstanceID: "i-3", InstanceType: "r6i.large", State: "stopped", Domain: "genomics", Stack: "genomics-a", LaunchTime: fleetTestNow.Add(-100 * time.Hour)},
+		{InstanceID: "i-4", InstanceType: "r6i.large", State: "running", Domain: "climate", Stack: "climate-a", LaunchTime: fleetTestNow.AddDate(0, -1, 0)},
+		{InstanceID: "i-5", InstanceType: "t3.micro", State: "stopped"},
+	}
+}
+
+func TestAggregateFleet(t *testing.T) {
+	fetcher := &fakeFleetFetcher{
+		cpu:    map[string]float64{"i-1": 80, "i-4": 10},
+		hourly: map[string]float64{"c6i.xlarge": 0.17, "r6i.large": 0.126},
+		billed: map[string]float64{"climate": 42},
+	}
+
+	summaries, err := AggregateFleet(context.Background(), fleetTestInstances(), fetcher, fetcher, fleetTestNow)
+	if err != nil {
+		t.Fatalf("AggregateFleet() error = %v", err)
+	}
+	if len(summaries) != 3 {
+		t.Fatalf("got %d summaries, want 3", len(summaries))
+	}
+	for i, domain := range []string{"climate", "genomics", UnknownFleetDomain} {
+		if summaries[i].Domain != domain {
+			t.Errorf("summaries[%d].Domain = %q, want %q", i, summaries[i].Domain, domain)
+		}
+	}
+
+	genomics := summaries[1]
+	if genomics.Instances != 3 || genomics.States["running"] != 2 || genomics.States["stopped"] != 1 {
+		t.Errorf("genomics counts = %d instances, states %v", genomics.Instances, genomics.States)
+	}
+	if len(genomics.Stacks) != 2 || genomics.Stacks[0] != "genomics-a" || genomics.Stacks[1] != "genomics-b" {
+		t.Errorf("genomics stacks = %v", genomics.Stacks)
+	}
+	if math.Abs(genomics.HourlyCost-0.34) > 1e-9 {
+		t.Errorf("genomics hourly = %v, want 0.34 (stopped instances unpriced)", genomics.HourlyCost)
+	}
+	if !genomics.MonthToDateEstimated || math.Abs(genomics.MonthToDateCost-0.17*53) > 1e-9 {
+		t.Errorf("genomics month-to-date = %v (estimated %v), want ~%v", genomics.MonthToDateCost, genomics.MonthToDateEstimated, 0.17*53)
+	}
+	if genomics.CPUInstances != 1 || genomics.AverageCPU != 80 {
+		t.Errorf("genomics CPU = %v over %d instances, want 80 over 1", genomics.AverageCPU, genomics.CPUInstances)
+	}
+	if genomics.OldestRunning != "i-1" || genomics.OldestRunningAge != 48*time.Hour {
+		t.Errorf("genomics oldest = %s (%v), want i-1 (48h)", genomics.OldestRunning, genomics.OldestRunningAge)
+	}
+
+	climate := summaries[0]
+	if climate.MonthToDateEstimated || climate.MonthToDateCost != 42 {
+		t.Errorf("climate month-to-date = %v (estimated %v), want billed 42", climate.MonthToDateCost, climate.MonthToDateEstimated)
+	}
+
+	unknown := summaries[2]
+	if unknown.HourlyCost != 0 || unknown.OldestRunning != "" || unknown.MonthToDateCost != 0 {
+		t.Errorf("stopped-only domain = %+v, want no cost or oldest instance", unknown)
+	}
+	for _, id := range fetcher.cpuSeen {
+		if id == "i-3" || id == "i-5" {
+			t.Errorf("CPU fetched for stopped instance %s", id)
+		}
+	}
+}
+
+func TestAggregateFleetEstimateStartsAtMonth(t *testing.T) {
+	fetcher := &fakeFleetFetcher{hourly: map[string]float64{"r6i.large": 0.126}}
+	instances := fleetTestInstances()[3:4]
+
+	summaries, err := AggregateFleet(context.Background(), instances, fetcher, fetcher, fleetTestNow)
+	if err != nil {
+		t.Fatalf("AggregateFleet() error = %v", err)
+	}
+	// Launched last month, so only the 10 days of March count
+	if want := 0.126 * 240; math.Abs(summaries[0].MonthToDateCost-want) > 1e-9 {
+		t.Errorf("month-to-date = %v, want %v", summaries[0].MonthToDateCost, want)
+	}
+}
+
+func TestAggregateFleetErrors(t *testing.T) {
+	instances := fleetTestInstances()[:1]
+
+	unpriced := &fakeFleetFetcher{}
+	if _, err := AggregateFleet(context.Background(), instances, unpriced, unpriced, fleetTestNow); err == nil {
+		t.Error("expected an error for an unpriced instance type")
+	}
+
+	failing := &fakeFleetFetcher{hourly: map[string]float64{"c6i.xlarge": 0.17}, cpuErr: errors.New("throttled")}
+	if _, err := AggregateFleet(context.Background(), instances, failing, failing, fleetTestNow); err == nil {
+		t.Error("expected the CPU fetch error to be returned")
+	}
+}
+
+func TestTotalFleet(t *testing.T) {
+	summaries := []FleetSummary{
+		{Domain: "climate", Stacks: []string{"climate-a"}, Instances: 1, States: map[string]int{"running": 1},
+			HourlyCost: 0.1, MonthToDateCost: 10, AverageCPU: 10, CPUInstances: 1, OldestRunning: "i-4", OldestRunningAge: 240 * time.Hour},
+		{Domain: "genomics", Stacks: []string{"genomics-a"}, Instances: 3, States: map[string]int{"running": 2, "stopped": 1},
+			HourlyCost: 0.3, MonthToDateCost: 5, MonthToDateEstimated: true, AverageCPU: 40, CPUInstances: 2, OldestRunning: "i-1", OldestRunningAge: 48 * time.Hour},
+	}
+
+	total := TotalFleet(summaries)
+	if total.Instances != 4 || total.States["running"] != 3 || len(total.Stacks) != 2 {
+		t.Errorf("total counts = %+v", total)
+	}
+	if math.Abs(total.HourlyCost-0.4) > 1e-9 || total.MonthToDateCost != 15 || !total.MonthToDateEstimated {
+		t.Errorf("total cost = %v hourly, %v month-to-date (estimated %v)", total.HourlyCost, total.MonthToDateCost, total.MonthToDateEstimated)
+	}
+	if total.AverageCPU != 30 || total.CPUInstances != 3 {
+		t.Errorf("total CPU = %v over %d, want 30 over 3", total.AverageCPU, total.CPUInstances)
+	}
+	if total.OldestRunning != "i-4" {
+		t.Errorf("total oldest = %s, want i-4", total.OldestRunning)
+	}
+}
@@ -0,0 +1,405 @@
+package monitoring
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// storageHistoryVersion is the current layout of the storage history file
+const storageHistoryVersion = 1
+
+// storageDateLayout is the day granularity of storage samples
+const storageDateLayout = "2006-01-02"
+
+// Storage growth defaults
+const (
+	// DefaultStorageGrowthAlertPercent is the weekly growth above which a bucket or
+	// prefix is flagged
+	DefaultStorageGrowthAlertPercent = 10.0
+	// StorageGrowthRateDays is the window growth rates are normalized to
+	StorageGrowthRateDays = 7
+)
+
+// Storage sample sources
+const (
+	StorageSourceCloudWatch = "cloudwatch"
+	StorageSourceList       = "list"
+)
+
+// StorageUsage is the size of a set of objects
+type StorageUsage struct {
+	Objects int64 `json:"objects"`
+	Bytes   int64 `json:"bytes"`
+}
+
+// StorageSample is one day's storage measurement of a bucket or prefix
+type StorageSample struct {
+	Date        string    `json:"date"`
+	CollectedAt time.Time `json:"collected_at"`
+	Source      string    `json:"source"`
+	Objects     int64     `json:"objects"`
+	Bytes       int64     `json:"bytes"`
+	// StorageClasses holds bytes per S3 storage class, e.g. STANDARD or GLACIER
+	StorageClasses map[string]int64 `json:"storage_classes,omitempty"`
+	// Prefixes holds usage per child prefix when the sample came from a listing
+	Prefixes map[string]StorageUsage `json:"prefixes,omitempty"`
+	// Partial is set when a listing stopped before reaching every object
+	Partial bool `json:"partial,omitempty"`
+}
+
+// Time returns the day the sample describes
+func (s StorageSample) Time() time.Time {
+	t, _ := time.Parse(storageDateLayout, s.Date)
+	return t
+}
+
+// StorageDate formats a time as a storage sample date
+func StorageDate(t time.Time) string {
+	return t.UTC().Format(storageDateLayout)
+}
+
+// StorageSeries is the tracked storage history of one bucket or prefix, together
+// with the thresholds 'monitor alerts run' checks it against
+type StorageSeries struct {
+	Bucket             string          `json:"bucket"`
+	Prefix             string          `json:"prefix,omitempty"`
+	Region             string          `json:"region"`
+	Source             string          `json:"source"`
+	GrowthAlertPercent float64         `json:"growth_alert_percent"`
+	MaxBytes           int64           `json:"max_bytes,omitempty"`
+	Samples            []StorageSample `json:"samples"`
+}
+
+// Target returns the s3:// location the series tracks
+func (s *StorageSeries) Target() string {
+	return fmt.Sprintf("s3://%s/%s", s.Bucket, s.Prefix)
+}
+
+// AddSample records a sample, replacing any earlier sample for the same day
+func (s *StorageSeries) AddSample(sample StorageSample) {
+	for i := range s.Samples {
+		if s.Samples[i].Date == sample.Date {
+			s.Samples[i] = sample
+			return
+		}
+	}
+	s.Samples = append(s.Samples, sample)
+	sort.Slice(s.Samples, func(i, j int) bool {
+		return s.Samples[i].Date < s.Samples[j].Date
+	})
+}
+
+// Latest returns the most recent sample
+func (s *StorageSeries) Latest() (StorageSample, bool) {
+	if len(s.Samples) == 0 {
+		return StorageSample{}, false
+	}
+	return s.Samples[len(s.Samples)-1], true
+}
+
+// baseline returns the newest sample at least days older than the latest one, or
+// the oldest sample when the history is shorter than that
+func (s *StorageSeries) baseline(days int) (StorageSample, bool) {
+	latest, ok := s.Latest()
+	if !ok || len(s.Samples) < 2 {
+		return StorageSample{}, false
+	}
+
+	cutoff := latest.Time().AddDate(0, 0, -days)
+	for i := len(s.Samples) - 2; i >= 0; i-- {
+		if !s.Samples[i].Time().After(cutoff) {
+			return s.Samples[i], true
+		}
+	}
+	return s.Samples[0], true
+}
+
+// StorageGrowth is the change in stored bytes over a window
+type StorageGrowth struct {
+	// Days is the requested window; SpanDays is the history actually available
+	Days       int   `json:"days"`
+	SpanDays   int   `json:"span_days"`
+	StartBytes int64 `json:"start_bytes"`
+	EndBytes   int64 `json:"end_bytes"`
+	DeltaBytes int64 `json:"delta_bytes"`
+	// Percent is relative to StartBytes, and zero when the window started empty
+	Percent     float64 `json:"percent"`
+	BytesPerDay float64 `json:"bytes_per_day"`
+}
+
+// WeeklyPercent normalizes growth to a seven-day rate so windows of different
+// lengths can be compared with one threshold
+func (g StorageGrowth) WeeklyPercent() float64 {
+	if g.SpanDays == 0 {
+		return 0
+	}
+	return g.Percent * StorageGrowthRateDays / float64(g.SpanDays)
+}
+
+// newStorageGrowth computes growth between two byte counts a number of days apart
+func newStorageGrowth(days, spanDays int, start, end int64) StorageGrowth {
+	growth := StorageGrowth{
+		Days:       days,
+		SpanDays:   spanDays,
+		StartBytes: start,
+		EndBytes:   end,
+		DeltaBytes: end - start,
+	}
+	if spanDays > 0 {
+		growth.BytesPerDay = float64(growth.DeltaBytes) / float64(spanDays)
+	}
+	if start > 0 {
+		growth.Percent = float64(growth.DeltaBytes) / float64(start) * 100
+	}
+	return growth
+}
+
+// Growth compares the latest sample with the one days earlier. It reports false
+// until the series has two samples.
+func (s *StorageSeries) Growth(days int) (StorageGrowth, bool) {
+	start, ok := s.baseline(days)
+	if !ok {
+		return StorageGrowth{}, false
+	}
+	end, _ := s.Latest()
+	return newStorageGrowth(days, daysBetween(start, end), start.Bytes, end.Bytes), true
+}
+
+// PrefixGrowth is the growth of one child prefix
+type PrefixGrowth struct {
+	Prefix string        `json:"prefix"`
+	Growth StorageGrowth `json:"growth"`
+	// New is set when the prefix did not exist at the start of the window
+	New bool `json:"new,omitempty"`
+}
+
+// PrefixGrowth compares each child prefix of the latest sample with the sample
+// days earlier, largest absolute growth first. Prefixes only appear in samples
+// collected by listing.
+func (s *StorageSeries) PrefixGrowth(days int) []PrefixGrowth {
+	start, ok := s.baseline(days)
+	if !ok {
+		return nil
+	}
+	end, _ := s.Latest()
+	if end.Prefixes == nil || start.Prefixes == nil {
+		return nil
+	}
+
+	span := daysBetween(start, end)
+	growth := make([]PrefixGrowth, 0, len(end.Prefixes))
+	for prefix, usage := range end.Prefixes {
+		before, existed := start.Prefixes[prefix]
+		growth = append(growth, PrefixGrowth{
+			Prefix: prefix,
+			Growth: newStorageGrowth(days, span, before.Bytes, usage.Bytes),
+			New:    !existed,
+		})
+	}
+	sort.Slice(growth, func(i, j int) bool {
+		if growth[i].Growth.DeltaBytes != growth[j].Growth.DeltaBytes {
+			return growth[i].Growth.DeltaBytes > growth[j].Growth.DeltaBytes
+		}
+		return growth[i].Prefix < growth[j].Prefix
+	})
+	return growth
+}
+
+// FastGrowingPrefixes returns the prefixes whose weekly growth exceeds the threshold
+// percentage. Prefixes that appeared during the window count as fast growing.
+func FastGrowingPrefixes(growth []PrefixGrowth, thresholdPercent float64) []PrefixGrowth {
+	var fast []PrefixGrowth
+	for _, prefix := range growth {
+		if prefix.Growth.DeltaBytes <= 0 {
+			continue
+		}
+		if prefix.New || prefix.Growth.WeeklyPercent() > thresholdPercent {
+			fast = append(fast, prefix)
+		}
+	}
+	return fast
+}
+
+// daysBetween counts whole days between two samples
+func daysBetween(start, end StorageSample) int {
+	return int(end.Time().Sub(start.Time()).Hours() / 24)
+}
+
+// EvaluateThresholds checks the series against its size limit and growth rate and
+// returns an alert for each breach
+func (s *StorageSeries) EvaluateThresholds(now time.Time) []*Alert {
+	latest, ok := s.Latest()
+	if !ok {
+		return nil
+	}
+
+	var alerts []*Alert
+	newAlert := func(severity AlertSeverity, title, description string, value, threshold float64) {
+		alerts = append(alerts, &Alert{
+			ID:           fmt.Sprintf("storage-%s-%s-%d", s.Bucket, latest.Date, len(alerts)),
+			Type:         AlertTypeStorage,
+			Severity:     severity,
+			Title:        title,
+			Description:  description,
+			Status:       AlertStatusOpen,
+			TriggerValue: value,
+			Threshold:    threshold,
+			Metadata:     map[string]string{"bucket": s.Bucket, "prefix": s.Prefix, "date": latest.Date},
+			CreatedAt:    now,
+		})
+	}
+
+	if s.MaxBytes > 0 && latest.Bytes > s.MaxBytes {
+		newAlert(AlertSeverityCritical,
+			fmt.Sprintf("%s exceeds its size limit", s.Target()),
+			fmt.Sprintf("%d bytes stored, limit is %d bytes", latest.Bytes, s.MaxBytes),
+			float64(latest.Bytes), float64(s.MaxBytes))
+	}
+
+	threshold := s.GrowthAlertPercent
+	if threshold <= 0 {
+		threshold = DefaultStorageGrowthAlertPercent
+	}
+
+	if growth, ok := s.Growth(StorageGrowthRateDays); ok && growth.DeltaBytes > 0 && growth.WeeklyPercent() > threshold {
+		newAlert(AlertSeverityWarning,
+			fmt.Sprintf("%s is growing quickly", s.Target()),
+			fmt.Sprintf("grew %.1f%% per week over the last %d days", growth.WeeklyPercent(), growth.SpanDays),
+			growth.WeeklyPercent(), threshold)
+	}
+
+	for _, prefix := range FastGrowingPrefixes(s.PrefixGrowth(StorageGrowthRateDays), threshold) {
+		description := fmt.Sprintf("grew %.1f%% per week over the last %d days", prefix.Growth.WeeklyPercent(), prefix.Growth.SpanDays)
+		if prefix.New {
+			description = fmt.Sprintf("appeared in the last %d days with %d bytes", prefix.Growth.SpanDays, prefix.Growth.EndBytes)
+		}
+		newAlert(AlertSeverityWarning,
+			fmt.Sprintf("s3://%s/%s is growing quickly", s.Bucket, prefix.Prefix),
+			description, prefix.Growth.WeeklyPercent(), threshold)
+	}
+
+	return alerts
+}
+
+// storageHistoryFile is the on-disk layout of the storage history file
+type storageHistoryFile struct {
+	Version int             `json:"version"`
+	Series  []StorageSeries `json:"series"`
+}
+
+// StorageHistory persists storage series to a local JSON file
+type StorageHistory struct {
+	path string
+	mu   sync.Mutex
+}
+
+// DefaultStorageHistoryPath returns the location of the local storage history file
+func DefaultStorageHistoryPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to determine home directory: %w", err)
+	}
+	return filepath.Join(home, ".aws-research-wizard", "storage_history.json"), nil
+}
+
+// NewStorageHistory creates a storage history backed by the given file
+func NewStorageHistory(path string) *StorageHistory {
+	return &StorageHistory{path: path}
+}
+
+// Series returns every tracked series
+func (h *StorageHistory) Series() ([]StorageSeries, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	file, err := h.load()
+	if err != nil {
+		return nil, err
+	}
+	return file.Series, nil
+}
+
+// Record adds samples to the series for a bucket and prefix, creating it if needed,
+// and updates its region and thresholds. The updated series is returned.
+func (h *StorageHistory) Record(series StorageSeries, samples ...StorageSample) (*StorageSeries, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	file, err := h.load()
+	if err != nil {
+		return nil, err
+	}
+
+	index := -1
+	for i := range file.Series {
+		if file.Series[i].Bucket == series.Bucket && file.Series[i].Prefix == series.Prefix {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		file.Series = append(file.Series, StorageSeries{Bucket: series.Bucket, Prefix: series.Prefix})
+		index = len(file.Series) - 1
+	}
+
+	tracked := &file.Series[index]
+	tracked.Region = series.Region
+	tracked.Source = series.Source
+	tracked.GrowthAlertPercent = series.GrowthAlertPercent
+	tracked.MaxBytes = series.MaxBytes
+	for _, sample := range samples {
+		tracked.AddSample(sample)
+	}
+
+	if err := h.save(file); err != nil {
+		return nil, err
+	}
+	result := *tracked
+	return &result, nil
+}
+
+func (h *StorageHistory) load() (*storageHistoryFile, error) {
+	content, err := os.ReadFile(h.path)
+	if os.IsNotExist(err) {
+		return &storageHistoryFile{Version: storageHistoryVersion}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read storage history: %w", err)
+	}
+
+	var file storageHistoryFile
+	if err := json.Unmarshal(content, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse storage history %s: %w", h.path, err)
+	}
+	if file.Version > storageHistoryVersion {
+		return nil, fmt.Errorf("storage history %s has version %d; upgrade aws-research-wizard", h.path, file.Version)
+	}
+	return &file, nil
+}
+
+func (h *StorageHistory) save(file *storageHistoryFile) error {
+	file.Version = storageHistoryVersion
+
+	content, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal storage history: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(h.path), 0700); err != nil {
+		return fmt.Errorf("failed to create storage history directory: %w", err)
+	}
+
+	tmpPath := h.path + ".tmp"
+	if err := os.WriteFile(tmpPath, content, 0600); err != nil {
+		return fmt.Errorf("failed to write storage history: %w", err)
+	}
+	if err := os.Rename(tmpPath, h.path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to replace storage history: %w", err)
+	}
+	return nil
+}
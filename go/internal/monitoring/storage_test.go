@@ -0,0 +1,276 @@
+package monitoring
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var storageTestStart = time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+
+// syntheticSeries builds a series with one sample per day from the given sizes
+func syntheticSeries(sizes ...int64) *StorageSeries {
+	series := &StorageSeries{Bucket: "research", GrowthAlertPercent: DefaultStorageGrowthAlertPercent}
+	for i, size := range sizes {
+		series.AddSample(StorageSample{
+			Date:   StorageDate(storageTestStart.AddDate(0, 0, i)),
+			Source: StorageSourceCloudWatch,
+			Bytes:  size,
+		})
+	}
+	return series
+}
+
+// linearSizes returns days+1 daily sizes growing by step bytes a day
+func linearSizes(start, step int64, days int) []int64 {
+	sizes := make([]int64, days+1)
+	for i := range sizes {
+		sizes[i] = start + step*int64(i)
+	}
+	return sizes
+}
+
+func approxEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestStorageSeriesAddSample(t *testing.T) {
+	series := &StorageSeries{}
+	series.AddSample(StorageSample{Date: "2026-03-03", Bytes: 3})
+	series.AddSample(StorageSample{Date: "2026-03-01", Bytes: 1})
+	series.AddSample(StorageSample{Date: "2026-03-02", Bytes: 2})
+	series.AddSample(StorageSample{Date: "2026-03-03", Bytes: 30})
+
+	if len(series.Samples) != 3 {
+		t.Fatalf("got %d samples, want 3", len(series.Samples))
+	}
+	for i, want := range []string{"2026-03-01", "2026-03-02", "2026-03-03"} {
+		if series.Samples[i].Date != want {
+			t.Errorf("sample %d date = %s, want %s", i, series.Samples[i].Date, want)
+		}
+	}
+	if latest, _ := series.Latest(); latest.Bytes != 30 {
+		t.Errorf("latest bytes = %d, want the same-day replacement 30", latest.Bytes)
+	}
+}
+
+func TestStorageSeriesGrowth(t *testing.T) {
+	series := syntheticSeries(linearSizes(1000, 10, 30)...)
+
+	week, ok := series.Growth(7)
+	if !ok {
+		t.Fatal("expected 7-day growth")
+	}
+	if week.StartBytes != 1230 || week.EndBytes != 1300 || week.DeltaBytes != 70 || week.SpanDays != 7 {
+		t.Errorf("7-day growth = %+v", week)
+	}
+	if !approxEqual(week.BytesPerDay, 10) {
+		t.Errorf("bytes per day = %v, want 10", week.BytesPerDay)
+	}
+
+	month, ok := series.Growth(30)
+	if !ok {
+		t.Fatal("expected 30-day growth")
+	}
+	if month.StartBytes != 1000 || month.DeltaBytes != 300 || !approxEqual(month.Percent, 30) {
+		t.Errorf("30-day growth = %+v", month)
+	}
+	if !approxEqual(month.WeeklyPercent(), 7) {
+		t.Errorf("weekly percent = %v, want 7", month.WeeklyPercent())
+	}
+}
+
+func TestStorageSeriesGrowthShortHistory(t *testing.T) {
+	if _, ok := syntheticSeries(100).Growth(7); ok {
+		t.Error("expected no growth from a single sample")
+	}
+
+	series := syntheticSeries(100, 110, 120)
+	growth, ok := series.Growth(30)
+	if !ok {
+		t.Fatal("expected growth from the available history")
+	}
+	if growth.Days != 30 || growth.SpanDays != 2 || growth.StartBytes != 100 || !approxEqual(growth.Percent, 20) {
+		t.Errorf("growth = %+v", growth)
+	}
+	if !approxEqual(growth.WeeklyPercent(), 70) {
+		t.Errorf("weekly percent = %v, want 70", growth.WeeklyPercent())
+	}
+}
+
+func TestStorageSeriesGrowthGaps(t *testing.T) {
+	// Days 0, 5 and 9: the 7-day baseline is day 0, the newest at least a week back
+	series := &StorageSeries{}
+	for day, size := range map[int]int64{0: 100, 5: 150, 9: 200} {
+		series.AddSample(StorageSample{Date: StorageDate(storageTestStart.AddDate(0, 0, day)), Bytes: size})
+	}
+
+	growth, ok := series.Growth(7)
+	if !ok {
+		t.Fatal("expected growth")
+	}
+	if growth.StartBytes != 100 || growth.SpanDays != 9 {
+		t.Errorf("growth = %+v, want a 9-day span from 100 bytes", growth)
+	}
+}
+
+func TestStorageGrowthFromEmpty(t *testing.T) {
+	growth, _ := syntheticSeries(0, 0, 500).Growth(7)
+	if growth.Percent != 0 || growth.DeltaBytes != 500 {
+		t.Errorf("growth from empty = %+v, want zero percent and 500 bytes", growth)
+	}
+}
+
+func TestStorageSeriesPrefixGrowth(t *testing.T) {
+	series := &StorageSeries{Bucket: "research", Prefix: "runs/"}
+	series.AddSample(StorageSample{
+		Date: StorageDate(storageTestStart),
+		Prefixes: map[string]StorageUsage{
+			"runs/a/": {Bytes: 1000},
+			"runs/b/": {Bytes: 1000},
+			"runs/c/": {Bytes: 500},
+		},
+	})
+	series.AddSample(StorageSample{
+		Date: StorageDate(storageTestStart.AddDate(0, 0, 7)),
+		Prefixes: map[string]StorageUsage{
+			"runs/a/": {Bytes: 1050},
+			"runs/b/": {Bytes: 1500},
+			"runs/c/": {Bytes: 400},
+			"runs/d/": {Bytes: 200},
+		},
+	})
+
+	growth := series.PrefixGrowth(7)
+	order := []string{"runs/b/", "runs/d/", "runs/a/", "runs/c/"}
+	if len(growth) != len(order) {
+		t.Fatalf("got %d prefixes, want %d", len(growth), len(order))
+	}
+	for i, prefix := range order {
+		if growth[i].Prefix != prefix {
+			t.Errorf("prefix %d = %s, want %s", i, growth[i].Prefix, prefix)
+		}
+	}
+	if !growth[1].New || growth[0].New {
+		t.Error("only runs/d/ should be new")
+	}
+
+	fast := FastGrowingPrefixes(growth, 10)
+	if len(fast) != 2 || fast[0].Prefix != "runs/b/" || fast[1].Prefix != "runs/d/" {
+		t.Errorf("fast growing = %+v, want runs/b/ and runs/d/", fast)
+	}
+}
+
+func TestStorageSeriesPrefixGrowthWithoutListing(t *testing.T) {
+	if growth := syntheticSeries(100, 200).PrefixGrowth(7); growth != nil {
+		t.Errorf("expected no prefix growth from metric samples, got %+v", growth)
+	}
+}
+
+func TestStorageSeriesEvaluateThresholds(t *testing.T) {
+	now := storageTestStart.AddDate(0, 1, 0)
+
+	steady := syntheticSeries(linearSizes(10000, 10, 30)...)
+	if alerts := steady.EvaluateThresholds(now); len(alerts) != 0 {
+		t.Errorf("steady growth raised %d alerts", len(alerts))
+	}
+
+	// 10000 to 12000 bytes over a week is 20% weekly growth
+	fast := syntheticSeries(10000, 10300, 10600, 10900, 11200, 11500, 11800, 12000)
+	alerts := fast.EvaluateThresholds(now)
+	if len(alerts) != 1 {
+		t.Fatalf("got %d alerts, want 1", len(alerts))
+	}
+	if alerts[0].Type != AlertTypeStorage || alerts[0].Severity != AlertSeverityWarning || !approxEqual(alerts[0].TriggerValue, 20) {
+		t.Errorf("alert = %+v", alerts[0])
+	}
+
+	fast.GrowthAlertPercent = 25
+	if alerts := fast.EvaluateThresholds(now); len(alerts) != 0 {
+		t.Errorf("raised %d alerts under a 25%% threshold", len(alerts))
+	}
+
+	fast.MaxBytes = 11000
+	alerts = fast.EvaluateThresholds(now)
+	if len(alerts) != 1 || alerts[0].Severity != AlertSeverityCritical {
+		t.Errorf("alerts = %+v, want one critical size alert", alerts)
+	}
+}
+
+func TestStorageSeriesEvaluatePrefixThresholds(t *testing.T) {
+	series := &StorageSeries{Bucket: "research", Prefix: "runs/"}
+	series.AddSample(StorageSample{
+		Date:     StorageDate(storageTestStart),
+		Bytes:    10000,
+		Prefixes: map[string]StorageUsage{"runs/a/": {Bytes: 10000}},
+	})
+	series.AddSample(StorageSample{
+		Date:     StorageDate(storageTestStart.AddDate(0, 0, 7)),
+		Bytes:    10100,
+		Prefixes: map[string]StorageUsage{"runs/a/": {Bytes: 9900}, "runs/new/": {Bytes: 200}},
+	})
+
+	alerts := series.EvaluateThresholds(time.Now())
+	if len(alerts) != 1 {
+		t.Fatalf("got %d alerts, want 1 for the new prefix", len(alerts))
+	}
+	if alerts[0].Title != "s3://research/runs/new/ is growing quickly" {
+		t.Errorf("alert title = %q", alerts[0].Title)
+	}
+}
+
+func TestStorageHistoryRecord(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "storage_history.json")
+	history := NewStorageHistory(path)
+
+	target := StorageSeries{Bucket: "research", Region: "us-east-1", Source: StorageSourceCloudWatch, GrowthAlertPercent: 5}
+	first := StorageSample{Date: "2026-03-01", Bytes: 100}
+	if _, err := history.Record(target, first); err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+
+	target.Region = "us-west-2"
+	target.MaxBytes = 1000
+	series, err := history.Record(target, StorageSample{Date: "2026-03-02", Bytes: 150}, StorageSample{Date: "2026-03-01", Bytes: 120})
+	if err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+	if len(series.Samples) != 2 || series.Samples[0].Bytes != 120 {
+		t.Errorf("samples = %+v", series.Samples)
+	}
+
+	if _, err := history.Record(StorageSeries{Bucket: "research", Prefix: "runs/"}, first); err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+
+	reloaded, err := NewStorageHistory(path).Series()
+	if err != nil {
+		t.Fatalf("Series() error = %v", err)
+	}
+	if len(reloaded) != 2 {
+		t.Fatalf("got %d series, want 2", len(reloaded))
+	}
+	if reloaded[0].Region != "us-west-2" || reloaded[0].MaxBytes != 1000 || reloaded[0].GrowthAlertPercent != 5 {
+		t.Errorf("series settings = %+v", reloaded[0])
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("stat history: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("history mode = %v, want 0600", info.Mode().Perm())
+	}
+}
+
+func TestStorageHistoryNewerVersion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "storage_history.json")
+	if err := os.WriteFile(path, []byte(`{"version": 99, "series": []}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewStorageHistory(path).Series(); err == nil {
+		t.Error("expected an error for a newer history version")
+	}
+}
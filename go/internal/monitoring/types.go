@@ -117,6 +117,7 @@ const (
 	AlertTypeSecurity    AlertType = "security"
 	AlertTypePerformance AlertType = "performance"
 	AlertTypeCost        AlertType = "cost"
+	AlertTypeStorage     AlertType = "storage"
 )
 
 // AlertSeverity defines the severity level of an alert
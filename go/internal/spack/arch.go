@@ -0,0 +1,134 @@
+package spack
+
+import (
+	"fmt"
+	"strings"
+)
+
+// archARM64 is the CPU architecture of Graviton instances, as instance
+// recommendations name it
+const archARM64 = "arm64"
+
+// x86OnlyPackages are Spack packages that will not build on most Graviton
+// instances, with the reason and what to use instead
+var x86OnlyPackages = map[string]struct{ reason, alternative string }{
+	"intel-mkl":              {x86OnlyReason, "openblas or armpl-gcc"},
+	"intel-oneapi-mkl":       {x86OnlyReason, "openblas or armpl-gcc"},
+	"intel-mpi":              {x86OnlyReason, "openmpi"},
+	"intel-oneapi-mpi":       {x86OnlyReason, "openmpi"},
+	"intel-ipp":              {x86OnlyReason, ""},
+	"intel-oneapi-ipp":       {x86OnlyReason, ""},
+	"intel-oneapi-compilers": {x86OnlyReason, "gcc or acfl"},
+	"intel-parallel-studio":  {x86OnlyReason, "gcc or acfl"},
+	"intel-oneapi-vtune":     {x86OnlyReason, "linux-perf"},
+	"cuda":                   {gpuReason, "a g5g instance"},
+	"cudnn":                  {gpuReason, "a g5g instance"},
+	"nccl":                   {gpuReason, "a g5g instance"},
+}
+
+const (
+	x86OnlyReason = "is x86_64 only"
+	gpuReason     = "needs an NVIDIA GPU, which only g5g Graviton instances have"
+)
+
+// x86OnlyVariants are variants that will not build on most Graviton instances,
+// with the reason and what to use instead
+var x86OnlyVariants = map[string]struct{ reason, alternative string }{
+	"+mkl":  {"links Intel MKL, which is x86_64 only", "~mkl with openblas"},
+	"+cuda": {gpuReason, "~cuda, or a g5g instance"},
+}
+
+// x86OnlyCompilers are compiler prefixes that only target x86_64
+var x86OnlyCompilers = map[string]string{
+	"intel":  "%gcc or %arm",
+	"oneapi": "%gcc or %arm",
+}
+
+// AuditArchitecture finds the declared specs that will not build for the
+// target CPU architecture: x86_64-only packages, and variants or compilers
+// that depend on one. Only arm64 targets have findings. Specs that do not
+// parse are left to Audit.
+func AuditArchitecture(specs []DeclaredSpec, arch string) []Finding {
+	if arch != archARM64 {
+		return nil
+	}
+
+	var findings []Finding
+	for _, declared := range specs {
+		spec, err := ParseSpec(declared.Raw)
+		if err != nil {
+			continue
+		}
+
+		nodes := append([]Spec{spec}, spec.Dependencies...)
+		for _, node := range nodes {
+			for _, finding := range auditNodeArchitecture(node, arch) {
+				finding.Category = declared.Category
+				finding.Spec = declared.Raw
+				findings = append(findings, finding)
+			}
+		}
+	}
+	return findings
+}
+
+// auditNodeArchitecture checks one package, its variants and its compiler
+func auditNodeArchitecture(node Spec, arch string) []Finding {
+	var findings []Finding
+	x86Only := func(message, suggestion string) {
+		findings = append(findings, Finding{
+			Package:    node.Name,
+			Status:     StatusX86Only,
+			Message:    message,
+			Suggestion: suggestion,
+		})
+	}
+
+	if x86, ok := x86OnlyPackages[node.Name]; ok {
+		x86Only(node.Name+" "+x86.reason, x86.alternative)
+	}
+	for _, variant := range node.Variants {
+		if x86, ok := x86OnlyVariants[variant]; ok {
+			x86Only(variant+" "+x86.reason, x86.alternative)
+		}
+	}
+	if node.Compiler != "" {
+		compiler, _, _ := strings.Cut(node.Compiler, "@")
+		if alternative, ok := x86OnlyCompilers[compiler]; ok {
+			x86Only(fmt.Sprintf("%%%s does not target %s", compiler, arch), alternative)
+		}
+	}
+	return findings
+}
+
+// GPUSpecs returns the declared specs that need an NVIDIA GPU: CUDA and its
+// libraries, or any package built with +cuda. Specs that do not parse are left
+// to Audit.
+func GPUSpecs(specs []DeclaredSpec) []DeclaredSpec {
+	var gpu []DeclaredSpec
+	for _, declared := range specs {
+		spec, err := ParseSpec(declared.Raw)
+		if err != nil {
+			continue
+		}
+		if needsGPU(spec) {
+			gpu = append(gpu, declared)
+		}
+	}
+	return gpu
+}
+
+// needsGPU reports whether a spec or any of its dependencies needs a GPU
+func needsGPU(spec Spec) bool {
+	for _, node := range append([]Spec{spec}, spec.Dependencies...) {
+		if x86, ok := x86OnlyPackages[node.Name]; ok && x86.reason == gpuReason {
+			return true
+		}
+		for _, variant := range node.Variants {
+			if variant == "+cuda" {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,75 @@
+package spack
+
+import "testing"
+
+func TestAuditArchitecture(t *testing.T) {
+	specs := []DeclaredSpec{
+		{Category: "core", Raw: "samtools@1.18"},
+		{Category: "math", Raw: "intel-oneapi-mkl@2023"},
+		{Category: "math", Raw: "py-numpy ^openblas"},
+		{Category: "mpi", Raw: "gromacs@2023.3 +mpi ^intel-mpi"},
+		{Category: "ml", Raw: "py-torch+cuda"},
+		{Category: "ml", Raw: "py-torch~cuda"},
+		{Category: "sim", Raw: "wrf %intel@2021.9"},
+		{Category: "sim", Raw: "lammps %oneapi +mkl"},
+		{Category: "bad", Raw: "Not A Spec"},
+	}
+
+	tests := []struct {
+		name string
+		arch string
+		want []struct{ pkg, spec, suggestion string }
+	}{
+		{name: "x86_64", arch: "x86_64"},
+		{name: "unset", arch: ""},
+		{
+			name: "arm64",
+			arch: "arm64",
+			want: []struct{ pkg, spec, suggestion string }{
+				{"intel-oneapi-mkl", "intel-oneapi-mkl@2023", "openblas or armpl-gcc"},
+				{"intel-mpi", "gromacs@2023.3 +mpi ^intel-mpi", "openmpi"},
+				{"py-torch", "py-torch+cuda", "~cuda, or a g5g instance"},
+				{"wrf", "wrf %intel@2021.9", "%gcc or %arm"},
+				{"lammps", "lammps %oneapi +mkl", "~mkl with openblas"},
+				{"lammps", "lammps %oneapi +mkl", "%gcc or %arm"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			findings := AuditArchitecture(specs, tt.arch)
+			if len(findings) != len(tt.want) {
+				t.Fatalf("got %d findings, want %d: %+v", len(findings), len(tt.want), findings)
+			}
+			for i, w := range tt.want {
+				got := findings[i]
+				if got.Package != w.pkg || got.Spec != w.spec || got.Suggestion != w.suggestion || got.Status != StatusX86Only {
+					t.Errorf("finding %d = %+v, want package=%q spec=%q suggestion=%q", i, got, w.pkg, w.spec, w.suggestion)
+				}
+			}
+		})
+	}
+}
+
+func TestGPUSpecs(t *testing.T) {
+	specs := []DeclaredSpec{
+		{Category: "core", Raw: "samtools@1.18"},
+		{Category: "gpu", Raw: "cuda@12.2.2 %gcc@11.4.0"},
+		{Category: "ml", Raw: "py-torch+cuda"},
+		{Category: "ml", Raw: "py-torch~cuda"},
+		{Category: "ml", Raw: "horovod ^nccl@2.18"},
+		{Category: "bad", Raw: "Not A Spec +cuda"},
+	}
+
+	gpu := GPUSpecs(specs)
+	want := []string{"cuda@12.2.2 %gcc@11.4.0", "py-torch+cuda", "horovod ^nccl@2.18"}
+	if len(gpu) != len(want) {
+		t.Fatalf("GPUSpecs() = %v, want %v", gpu, want)
+	}
+	for i, spec := range gpu {
+		if spec.Raw != want[i] {
+			t.Errorf("GPUSpecs()[%d] = %q, want %q", i, spec.Raw, want[i])
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package spack
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Finding statuses
+const (
+	StatusOK            = "ok"
+	StatusUnknown       = "unknown"
+	StatusDeprecated    = "deprecated"
+	StatusUnsatisfiable = "unsatisfiable"
+	StatusInvalid       = "invalid"
+	// StatusX86Only marks a package, variant or compiler that does not build
+	// for arm64
+	StatusX86Only = "x86-only"
+)
+
+// Finding is the audit result for one package in a declared spec
+type Finding struct {
+	Category   string `json:"category"`
+	Spec       string `json:"spec"`
+	Package    string `json:"package"`
+	Status     string `json:"status"`
+	Message    string `json:"message,omitempty"`
+	Suggestion string `json:"suggestion,omitempty"`
+}
+
+// IsProblem reports whether the finding needs attention
+func (f Finding) IsProblem() bool {
+	return f.Status != StatusOK
+}
+
+// maxSuggestionDistance is how many edits apart a name may be to be
+// suggested; short names allow fewer so they do not match everything
+const maxSuggestionDistance = 2
+
+// Audit checks every declared spec, and the dependencies it names, against an index
+func Audit(specs []DeclaredSpec, index *PackageIndex) []Finding {
+	var findings []Finding
+	for _, declared := range specs {
+		spec, err := ParseSpec(declared.Raw)
+		if err != nil {
+			findings = append(findings, Finding{
+				Category: declared.Category,
+				Spec:     declared.Raw,
+				Status:   StatusInvalid,
+				Message:  err.Error(),
+			})
+			continue
+		}
+
+		nodes := append([]Spec{spec}, spec.Dependencies...)
+		for _, node := range nodes {
+			finding := auditNode(node, index)
+			finding.Category = declared.Category
+			finding.Spec = declared.Raw
+			findings = append(findings, finding)
+		}
+	}
+	return findings
+}
+
+// auditNode checks one package name and its version constraint
+func auditNode(node Spec, index *PackageIndex) Finding {
+	finding := Finding{Package: node.Name, Status: StatusOK}
+
+	if replacement, ok := index.Renames[node.Name]; ok {
+		finding.Status = StatusDeprecated
+		finding.Message = fmt.Sprintf("%s has been renamed", node.Name)
+		finding.Suggestion = replacement
+		return finding
+	}
+
+	versions, ok := index.Lookup(node.Name)
+	if !ok {
+		finding.Status = StatusUnknown
+		if index.Source == SourceSpack {
+			finding.Message = "not a known Spack package"
+		} else {
+			finding.Message = "not in the bundled package index"
+		}
+		finding.Suggestion = strings.Join(suggestNames(node.Name, index), ", ")
+		return finding
+	}
+
+	if node.Version == "" {
+		return finding
+	}
+	if len(versions) == 0 {
+		finding.Message = "version not checked"
+		return finding
+	}
+	for _, version := range versions {
+		if Satisfies(version, node.Version) {
+			return finding
+		}
+	}
+	finding.Status = StatusUnsatisfiable
+	finding.Message = fmt.Sprintf("no known version satisfies @%s", node.Version)
+	if latest := LatestVersion(versions); latest != "" {
+		finding.Suggestion = node.Name + "@" + latest
+	}
+	return finding
+}
+
+// suggestNames returns indexed names close to an unknown one, such as the
+// py- form of a Python package
+func suggestNames(name string, index *PackageIndex) []string {
+	var suggestions []string
+	for _, candidate := range []string{"py-" + name, "r-" + name} {
+		if _, ok := index.Packages[candidate]; ok {
+			suggestions = append(suggestions, candidate)
+		}
+	}
+	if len(suggestions) > 0 {
+		return suggestions
+	}
+
+	limit := min(maxSuggestionDistance, len(name)/3)
+	for _, candidate := range index.Names() {
+		if levenshtein(name, candidate) <= limit {
+			suggestions = append(suggestions, candidate)
+		}
+	}
+	return suggestions
+}
+
+// levenshtein returns the edit distance between two names
+func levenshtein(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
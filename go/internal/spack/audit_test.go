@@ -0,0 +1,136 @@
+package spack
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func testIndex() *PackageIndex {
+	return &PackageIndex{
+		Source: SourceSpack,
+		Packages: map[string][]string{
+			"samtools":        {"1.17", "1.18", "1.19"},
+			"hdf5":            {"1.12.2", "1.14.3", "develop"},
+			"netcdf-c":        {"4.9.2"},
+			"py-scikit-learn": {"1.3.2"},
+			"gromacs":         nil,
+		},
+		Renames: map[string]string{"py-sklearn": "py-scikit-learn"},
+	}
+}
+
+func TestAudit(t *testing.T) {
+	specs := []DeclaredSpec{
+		{Category: "core", Raw: "samtools@1.18"},
+		{Category: "core", Raw: "samtools@2:"},
+		{Category: "core", Raw: "samtool"},
+		{Category: "ml", Raw: "py-sklearn"},
+		{Category: "ml", Raw: "scikit-learn"},
+		{Category: "io", Raw: "netcdf-c@4.9 ^hdf5@1.10"},
+		{Category: "md", Raw: "gromacs@2023.3"},
+		{Category: "md", Raw: "Gromacs"},
+	}
+
+	findings := Audit(specs, testIndex())
+	want := []struct {
+		pkg, status, suggestion string
+	}{
+		{"samtools", StatusOK, ""},
+		{"samtools", StatusUnsatisfiable, "samtools@1.19"},
+		{"samtool", StatusUnknown, "samtools"},
+		{"py-sklearn", StatusDeprecated, "py-scikit-learn"},
+		{"scikit-learn", StatusUnknown, "py-scikit-learn"},
+		{"netcdf-c", StatusOK, ""},
+		{"hdf5", StatusUnsatisfiable, "hdf5@1.14.3"},
+		{"gromacs", StatusOK, ""},
+		{"", StatusInvalid, ""},
+	}
+	if len(findings) != len(want) {
+		t.Fatalf("got %d findings, want %d: %+v", len(findings), len(want), findings)
+	}
+	for i, w := range want {
+		got := findings[i]
+		if got.Package != w.pkg || got.Status != w.status || got.Suggestion != w.suggestion {
+			t.Errorf("finding %d = %+v, want package=%q status=%q suggestion=%q", i, got, w.pkg, w.status, w.suggestion)
+		}
+	}
+	if findings[7].Message != "version not checked" {
+		t.Errorf("a package without versions should say so: %+v", findings[7])
+	}
+	if findings[6].Spec != "netcdf-c@4.9 ^hdf5@1.10" || findings[6].Category != "io" {
+		t.Errorf("dependency findings should carry the declaring spec: %+v", findings[6])
+	}
+}
+
+func TestAuditSuggestsPythonPackage(t *testing.T) {
+	index := testIndex()
+	index.Source = SourceBundled
+	index.Packages["py-numpy"] = nil
+
+	findings := Audit([]DeclaredSpec{{Category: "python", Raw: "numpy"}}, index)
+	if findings[0].Suggestion != "py-numpy" || findings[0].Message != "not in the bundled package index" {
+		t.Errorf("finding = %+v", findings[0])
+	}
+}
+
+func TestBundledIndex(t *testing.T) {
+	index, err := BundledIndex()
+	if err != nil {
+		t.Fatalf("BundledIndex() error = %v", err)
+	}
+	if index.Source != SourceBundled || len(index.Packages) == 0 {
+		t.Fatalf("bundled index = %s with %d packages", index.Source, len(index.Packages))
+	}
+	for old, replacement := range index.Renames {
+		if _, ok := index.Packages[replacement]; !ok {
+			t.Errorf("rename %s -> %s points outside the index", old, replacement)
+		}
+	}
+}
+
+func TestLoadIndexFallsBackToBundled(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "index.json")
+	index, err := LoadIndex(path)
+	if err != nil || index.Source != SourceBundled {
+		t.Fatalf("LoadIndex() = %v, %v", index, err)
+	}
+
+	if err := SaveIndex(path, testIndex()); err != nil {
+		t.Fatalf("SaveIndex() error = %v", err)
+	}
+	saved, err := LoadIndex(path)
+	if err != nil {
+		t.Fatalf("LoadIndex() error = %v", err)
+	}
+	if saved.Source != SourceSpack || len(saved.Packages["samtools"]) != 3 {
+		t.Errorf("saved index = %+v", saved)
+	}
+}
+
+func TestIndexFromSpack(t *testing.T) {
+	original := runSpack
+	defer func() { runSpack = original }()
+
+	runSpack = func(ctx context.Context, args ...string) ([]byte, error) {
+		return []byte(`[{"name": "bwa", "versions": ["0.7.17", "0.7.15"]}, {"name": "samtools", "versions": []}]`), nil
+	}
+	index, err := IndexFromSpack(context.Background())
+	if err != nil {
+		t.Fatalf("IndexFromSpack() error = %v", err)
+	}
+	if index.Source != SourceSpack || len(index.Packages) != 2 || len(index.Packages["bwa"]) != 2 {
+		t.Errorf("index = %+v", index)
+	}
+	if index.Renames["py-pytorch"] != "py-torch" {
+		t.Error("renames should come from the bundled index")
+	}
+
+	runSpack = func(ctx context.Context, args ...string) ([]byte, error) {
+		return nil, errors.New("spack: command not found")
+	}
+	if _, err := IndexFromSpack(context.Background()); err == nil {
+		t.Error("IndexFromSpack() should fail when spack fails")
+	}
+}
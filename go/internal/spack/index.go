@@ -0,0 +1,154 @@
+package spack
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Index sources
+const (
+	SourceBundled = "bundled"
+	SourceSpack   = "spack"
+)
+
+//go:embed package_index.json
+var bundledIndex []byte
+
+// PackageIndex lists known Spack packages and, where known, their versions
+type PackageIndex struct {
+	Source      string              `json:"source"`
+	GeneratedAt time.Time           `json:"generated_at"`
+	Packages    map[string][]string `json:"packages"`
+	// Renames maps deprecated or commonly mistaken names to the current package
+	Renames map[string]string `json:"renames"`
+}
+
+// Lookup returns a package's known versions
+func (idx *PackageIndex) Lookup(name string) ([]string, bool) {
+	versions, ok := idx.Packages[name]
+	return versions, ok
+}
+
+// Names returns every package name in the index, sorted
+func (idx *PackageIndex) Names() []string {
+	names := make([]string, 0, len(idx.Packages))
+	for name := range idx.Packages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// BundledIndex returns the package index shipped with the binary
+func BundledIndex() (*PackageIndex, error) {
+	var index PackageIndex
+	if err := json.Unmarshal(bundledIndex, &index); err != nil {
+		return nil, fmt.Errorf("failed to parse bundled package index: %w", err)
+	}
+	return &index, nil
+}
+
+// DefaultIndexPath returns where a refreshed package index is kept
+func DefaultIndexPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to determine home directory: %w", err)
+	}
+	return filepath.Join(home, ".aws-research-wizard", "spack-package-index.json"), nil
+}
+
+// LoadIndex reads a refreshed index from path, falling back to the bundled
+// index when no refreshed index has been saved
+func LoadIndex(path string) (*PackageIndex, error) {
+	content, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return BundledIndex()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read package index: %w", err)
+	}
+
+	var index PackageIndex
+	if err := json.Unmarshal(content, &index); err != nil {
+		return nil, fmt.Errorf("failed to parse package index %s: %w", path, err)
+	}
+	return &index, nil
+}
+
+// SaveIndex writes an index to path, creating its directory
+func SaveIndex(path string, index *PackageIndex) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create index directory: %w", err)
+	}
+	content, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode package index: %w", err)
+	}
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		return fmt.Errorf("failed to write package index: %w", err)
+	}
+	return nil
+}
+
+// lookPath and runSpack reach the spack executable; tests replace them
+var (
+	lookPath = exec.LookPath
+	runSpack = func(ctx context.Context, args ...string) ([]byte, error) {
+		return exec.CommandContext(ctx, "spack", args...).Output()
+	}
+)
+
+// Available reports whether a spack executable is on PATH
+func Available() bool {
+	_, err := lookPath("spack")
+	return err == nil
+}
+
+// spackListEntry is one package in `spack list --format version_json` output
+type spackListEntry struct {
+	Name     string   `json:"name"`
+	Versions []string `json:"versions"`
+}
+
+// IndexFromSpack builds an index of every package and version the local
+// Spack installation knows. Renames come from the bundled index, since Spack
+// itself does not list names it no longer uses.
+func IndexFromSpack(ctx context.Context) (*PackageIndex, error) {
+	output, err := runSpack(ctx, "list", "--format", "version_json")
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			return nil, fmt.Errorf("spack list failed: %s", exitErr.Stderr)
+		}
+		return nil, fmt.Errorf("spack list failed: %w", err)
+	}
+
+	var entries []spackListEntry
+	if err := json.Unmarshal(output, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse spack list output: %w", err)
+	}
+
+	index := &PackageIndex{
+		Source:      SourceSpack,
+		GeneratedAt: time.Now().UTC(),
+		Packages:    make(map[string][]string, len(entries)),
+	}
+	for _, entry := range entries {
+		if entry.Name != "" {
+			index.Packages[entry.Name] = entry.Versions
+		}
+	}
+	if bundled, err := BundledIndex(); err == nil {
+		index.Renames = bundled.Renames
+	}
+	return index, nil
+}
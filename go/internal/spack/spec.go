@@ -0,0 +1,157 @@
+// Package spack parses the Spack specs declared in domain packs and audits
+// them against a package index built from a Spack installation or bundled
+// with the binary.
+package spack
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Spec is a parsed Spack spec such as "python@3.11.5 %gcc@11.4.0 +shared ^openssl"
+type Spec struct {
+	Raw          string
+	Name         string
+	Version      string // Version constraint after '@', empty when unconstrained
+	Compiler     string // Compiler after '%', including any version
+	Variants     []string
+	Dependencies []Spec
+}
+
+// specDelimiters end a name or version token
+const specDelimiters = "@%+~^ \t"
+
+// ParseSpec parses a single spec string
+func ParseSpec(raw string) (Spec, error) {
+	spec := Spec{Raw: strings.TrimSpace(raw)}
+	if spec.Raw == "" {
+		return spec, fmt.Errorf("empty spec")
+	}
+
+	// Dependencies follow '^' and are specs in their own right
+	parts := strings.Split(spec.Raw, "^")
+	if err := spec.parseRoot(parts[0]); err != nil {
+		return spec, err
+	}
+	for _, part := range parts[1:] {
+		dependency, err := ParseSpec(part)
+		if err != nil {
+			return spec, fmt.Errorf("invalid dependency in %q: %w", spec.Raw, err)
+		}
+		spec.Dependencies = append(spec.Dependencies, dependency)
+	}
+	return spec, nil
+}
+
+// parseRoot fills in the name, version, compiler and variants of one node
+func (s *Spec) parseRoot(text string) error {
+	text = strings.TrimSpace(text)
+	end := strings.IndexAny(text, specDelimiters)
+	if end < 0 {
+		end = len(text)
+	}
+	s.Name = text[:end]
+	if s.Name == "" {
+		return fmt.Errorf("spec %q has no package name", s.Raw)
+	}
+	if !validName(s.Name) {
+		return fmt.Errorf("invalid package name %q", s.Name)
+	}
+
+	rest := text[end:]
+	for len(rest) > 0 {
+		switch rest[0] {
+		case ' ', '\t':
+			rest = rest[1:]
+		case '@':
+			var version string
+			version, rest = takeToken(rest[1:], "@%+~ \t")
+			if version == "" {
+				return fmt.Errorf("spec %q has an empty version after '@'", s.Raw)
+			}
+			if s.Version != "" {
+				return fmt.Errorf("spec %q has more than one version", s.Raw)
+			}
+			s.Version = version
+		case '%':
+			var compiler string
+			compiler, rest = takeToken(rest[1:], "+~ \t")
+			if compiler == "" {
+				return fmt.Errorf("spec %q has an empty compiler after '%%'", s.Raw)
+			}
+			s.Compiler = compiler
+		case '+', '~':
+			sign := rest[0]
+			var variant string
+			variant, rest = takeToken(rest[1:], "+~ \t")
+			if variant == "" {
+				return fmt.Errorf("spec %q has an empty variant", s.Raw)
+			}
+			s.Variants = append(s.Variants, string(sign)+variant)
+		default:
+			// key=value variants such as cuda_arch=80
+			var variant string
+			variant, rest = takeToken(rest, "+~ \t")
+			if !strings.Contains(variant, "=") {
+				return fmt.Errorf("unexpected %q in spec %q", variant, s.Raw)
+			}
+			s.Variants = append(s.Variants, variant)
+		}
+	}
+	return nil
+}
+
+// takeToken splits text at the first delimiter
+func takeToken(text, delimiters string) (string, string) {
+	end := strings.IndexAny(text, delimiters)
+	if end < 0 {
+		return text, ""
+	}
+	return text[:end], text[end:]
+}
+
+// validName reports whether name looks like a Spack package name
+func validName(name string) bool {
+	for _, r := range name {
+		if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-' || r == '_' || r == '.') {
+			return false
+		}
+	}
+	return true
+}
+
+// DeclaredSpec is a spec as listed in a domain pack, with its category
+type DeclaredSpec struct {
+	Category string
+	Raw      string
+}
+
+// DeclaredSpecs flattens a domain pack's spack_packages section, which maps
+// categories to lists of spec strings, in category order
+func DeclaredSpecs(packages map[string]interface{}) []DeclaredSpec {
+	categories := make([]string, 0, len(packages))
+	for category := range packages {
+		categories = append(categories, category)
+	}
+	sort.Strings(categories)
+
+	var specs []DeclaredSpec
+	for _, category := range categories {
+		switch values := packages[category].(type) {
+		case []interface{}:
+			for _, value := range values {
+				if raw, ok := value.(string); ok {
+					specs = append(specs, DeclaredSpec{Category: category, Raw: raw})
+				}
+			}
+		case []string:
+			for _, raw := range values {
+				specs = append(specs, DeclaredSpec{Category: category, Raw: raw})
+			}
+		case string:
+			specs = append(specs, DeclaredSpec{Category: category, Raw: values})
+		}
+	}
+	return specs
+}
@@ -0,0 +1,132 @@
+package spack
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseSpec(t *testing.T) {
+	tests := []struct {
+		raw          string
+		name         string
+		version      string
+		compiler     string
+		variants     []string
+		dependencies []string
+	}{
+		{raw: "samtools", name: "samtools"},
+		{raw: "bwa@0.7.17", name: "bwa", version: "0.7.17"},
+		{raw: "python@3.11.5 %gcc@11.4.0", name: "python", version: "3.11.5", compiler: "gcc@11.4.0"},
+		{raw: "gromacs@2023.3%gcc+cuda~mpi", name: "gromacs", version: "2023.3", compiler: "gcc", variants: []string{"+cuda", "~mpi"}},
+		{raw: "hdf5@1.14: +fortran +hl", name: "hdf5", version: "1.14:", variants: []string{"+fortran", "+hl"}},
+		{raw: "py-torch +cuda cuda_arch=80", name: "py-torch", variants: []string{"+cuda", "cuda_arch=80"}},
+		{raw: "netcdf-c@4.9 ^hdf5@1.14 +mpi ^openmpi", name: "netcdf-c", version: "4.9", dependencies: []string{"hdf5", "openmpi"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			spec, err := ParseSpec(tt.raw)
+			if err != nil {
+				t.Fatalf("ParseSpec() error = %v", err)
+			}
+			if spec.Name != tt.name || spec.Version != tt.version || spec.Compiler != tt.compiler {
+				t.Errorf("got name=%q version=%q compiler=%q", spec.Name, spec.Version, spec.Compiler)
+			}
+			if !reflect.DeepEqual(spec.Variants, tt.variants) {
+				t.Errorf("variants = %v, want %v", spec.Variants, tt.variants)
+			}
+			var dependencies []string
+			for _, dependency := range spec.Dependencies {
+				dependencies = append(dependencies, dependency.Name)
+			}
+			if !reflect.DeepEqual(dependencies, tt.dependencies) {
+				t.Errorf("dependencies = %v, want %v", dependencies, tt.dependencies)
+			}
+		})
+	}
+}
+
+func TestParseSpecDependencyVariants(t *testing.T) {
+	spec, err := ParseSpec("netcdf-c ^hdf5@1.14 +mpi")
+	if err != nil {
+		t.Fatalf("ParseSpec() error = %v", err)
+	}
+	hdf5 := spec.Dependencies[0]
+	if hdf5.Version != "1.14" || !reflect.DeepEqual(hdf5.Variants, []string{"+mpi"}) {
+		t.Errorf("dependency = %+v", hdf5)
+	}
+	if len(spec.Variants) != 0 {
+		t.Errorf("root should not take the dependency's variants: %v", spec.Variants)
+	}
+}
+
+func TestParseSpecErrors(t *testing.T) {
+	for _, raw := range []string{"", "@1.2", "Samtools", "bwa@", "bwa@1@2", "gcc %", "bwa stray", "netcdf-c ^"} {
+		if _, err := ParseSpec(raw); err == nil {
+			t.Errorf("ParseSpec(%q) should fail", raw)
+		}
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.2", "1.2", 0},
+		{"1.10", "1.9", 1},
+		{"1.2", "1.2.1", -1},
+		{"2023.3", "2022.5", 1},
+		{"1.2", "1.2rc1", 1},
+		{"develop", "99.0", 1},
+		{"main", "develop", -1},
+	}
+	for _, tt := range tests {
+		if got := CompareVersions(tt.a, tt.b); got != tt.want {
+			t.Errorf("CompareVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestSatisfies(t *testing.T) {
+	tests := []struct {
+		version, constraint string
+		want                bool
+	}{
+		{"1.2.3", "1.2", true},
+		{"1.20", "1.2", false},
+		{"1.2", "=1.2", true},
+		{"1.2.3", "=1.2", false},
+		{"1.3", "1.2:1.4", true},
+		{"1.4.7", "1.2:1.4", true},
+		{"1.5", "1.2:1.4", false},
+		{"1.1", "1.2:1.4", false},
+		{"3.0", "2.1:", true},
+		{"2.0", "2.1:", false},
+		{"0.9", ":1.0", true},
+		{"1.0.5", ":1.0", true},
+		{"1.1", ":1.0", false},
+		{"3.4", "1.2,3.4", true},
+		{"2.0", "1.2,3.4", false},
+	}
+	for _, tt := range tests {
+		if got := Satisfies(tt.version, tt.constraint); got != tt.want {
+			t.Errorf("Satisfies(%q, %q) = %v, want %v", tt.version, tt.constraint, got, tt.want)
+		}
+	}
+}
+
+func TestDeclaredSpecs(t *testing.T) {
+	packages := map[string]interface{}{
+		"visualization": []interface{}{"paraview@5.11"},
+		"core":          []interface{}{"gcc@11.4.0", 42, "cmake"},
+	}
+	want := []DeclaredSpec{
+		{Category: "core", Raw: "gcc@11.4.0"},
+		{Category: "core", Raw: "cmake"},
+		{Category: "visualization", Raw: "paraview@5.11"},
+	}
+	if got := DeclaredSpecs(packages); !reflect.DeepEqual(got, want) {
+		t.Errorf("DeclaredSpecs() = %+v, want %+v", got, want)
+	}
+}
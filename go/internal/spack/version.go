@@ -0,0 +1,122 @@
+package spack
+
+import (
+	"strconv"
+	"strings"
+)
+
+// infiniteVersions sort above every numbered release, as they do in Spack
+var infiniteVersions = map[string]int{"develop": 5, "main": 4, "master": 3, "head": 2, "trunk": 1}
+
+// versionParts splits a version into its dot, dash and underscore separated components
+func versionParts(version string) []string {
+	return strings.FieldsFunc(version, func(r rune) bool {
+		return r == '.' || r == '-' || r == '_'
+	})
+}
+
+// CompareVersions orders two versions, returning -1, 0 or 1. Numeric
+// components compare numerically, and a numeric component sorts above an
+// alphabetic one, so 1.2 > 1.2rc > 1.1.
+func CompareVersions(a, b string) int {
+	if rankA, rankB := infiniteVersions[a], infiniteVersions[b]; rankA > 0 || rankB > 0 {
+		return compareInts(rankA, rankB)
+	}
+
+	partsA, partsB := versionParts(a), versionParts(b)
+	for i := 0; i < len(partsA) && i < len(partsB); i++ {
+		if c := compareComponent(partsA[i], partsB[i]); c != 0 {
+			return c
+		}
+	}
+	return compareInts(len(partsA), len(partsB))
+}
+
+func compareComponent(a, b string) int {
+	numA, errA := strconv.Atoi(a)
+	numB, errB := strconv.Atoi(b)
+	switch {
+	case errA == nil && errB == nil:
+		return compareInts(numA, numB)
+	case errA == nil:
+		return 1
+	case errB == nil:
+		return -1
+	default:
+		return strings.Compare(a, b)
+	}
+}
+
+func compareInts(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// hasVersionPrefix reports whether version starts with every component of prefix,
+// so 1.2.3 is within 1.2 but 1.20 is not
+func hasVersionPrefix(version, prefix string) bool {
+	versionComponents, prefixComponents := versionParts(version), versionParts(prefix)
+	if len(prefixComponents) > len(versionComponents) {
+		return false
+	}
+	for i, component := range prefixComponents {
+		if versionComponents[i] != component {
+			return false
+		}
+	}
+	return true
+}
+
+// Satisfies reports whether a version meets a Spack version constraint:
+//
+//	1.2      1.2 or any 1.2.x
+//	=1.2     exactly 1.2
+//	1.2:1.4  1.2 up to and including any 1.4.x
+//	1.2:     1.2 or later
+//	:1.4     up to and including any 1.4.x
+//	1.2,1.4  either alternative
+func Satisfies(version, constraint string) bool {
+	for _, alternative := range strings.Split(constraint, ",") {
+		if satisfiesOne(version, strings.TrimSpace(alternative)) {
+			return true
+		}
+	}
+	return false
+}
+
+func satisfiesOne(version, constraint string) bool {
+	if exact, ok := strings.CutPrefix(constraint, "="); ok {
+		return version == exact
+	}
+
+	low, high, isRange := strings.Cut(constraint, ":")
+	if !isRange {
+		return hasVersionPrefix(version, constraint)
+	}
+	if low != "" && CompareVersions(version, low) < 0 {
+		return false
+	}
+	if high != "" && CompareVersions(version, high) > 0 && !hasVersionPrefix(version, high) {
+		return false
+	}
+	return true
+}
+
+// LatestVersion returns the highest numbered version, ignoring develop-style branches
+func LatestVersion(versions []string) string {
+	latest := ""
+	for _, version := range versions {
+		if infiniteVersions[version] > 0 {
+			continue
+		}
+		if latest == "" || CompareVersions(version, latest) > 0 {
+			latest = version
+		}
+	}
+	return latest
+}
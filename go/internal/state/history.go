@@ -0,0 +1,119 @@
+package state
+
+import (
+	"sort"
+	"time"
+)
+
+// HistoryEntry is a deployment in the history merged from the local state
+// file and the shared store
+type HistoryEntry struct {
+	Deployment
+	// Local and Shared report which sources recorded the deployment
+	Local  bool `json:"local"`
+	Shared bool `json:"shared"`
+	// Conflict is set when another principal created a stack of the same
+	// name in the same region while this one was active
+	Conflict bool `json:"conflict,omitempty"`
+}
+
+// MergeHistory merges local and shared deployment records, oldest first. A
+// deployment in both sources keeps the local record, which also holds
+// snapshots and validations, taking its deletion time from the shared record
+// when a teammate deleted the stack.
+func MergeHistory(local, shared []Deployment) []HistoryEntry {
+	entries := make([]HistoryEntry, 0, len(local)+len(shared))
+	for _, deployment := range local {
+		entries = append(entries, HistoryEntry{Deployment: deployment, Local: true})
+	}
+
+	for _, deployment := range shared {
+		i := findHistoryEntry(entries, deployment)
+		if i < 0 {
+			entries = append(entries, HistoryEntry{Deployment: deployment, Shared: true})
+			continue
+		}
+		entries[i].Shared = true
+		if entries[i].DeletedAt == nil {
+			entries[i].DeletedAt = deployment.DeletedAt
+		}
+		if entries[i].DeployedBy == "" {
+			entries[i].DeployedBy = deployment.DeployedBy
+		}
+	}
+
+	markConflicts(entries)
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		if !entries[i].CreatedAt.Equal(entries[j].CreatedAt) {
+			return entries[i].CreatedAt.Before(entries[j].CreatedAt)
+		}
+		return entries[i].StackName < entries[j].StackName
+	})
+	return entries
+}
+
+// findHistoryEntry returns the index of the local record of a shared
+// deployment, or -1. Records match on stack ID when both have one, and
+// otherwise on stack name, region and creation time.
+func findHistoryEntry(entries []HistoryEntry, deployment Deployment) int {
+	for i, entry := range entries {
+		if !entry.Local || entry.Shared {
+			continue
+		}
+		if entry.StackID != "" && deployment.StackID != "" {
+			if entry.StackID == deployment.StackID {
+				return i
+			}
+			continue
+		}
+		if entry.StackName == deployment.StackName && entry.Region == deployment.Region && entry.CreatedAt.Equal(deployment.CreatedAt) {
+			return i
+		}
+	}
+	return -1
+}
+
+// markConflicts flags deployments of the same stack name and region by
+// different principals whose lifetimes overlap. Reusing a name after the
+// earlier stack was deleted is not a conflict, and neither is a record with
+// no known principal.
+func markConflicts(entries []HistoryEntry) {
+	now := time.Now()
+	for i := range entries {
+		for j := i + 1; j < len(entries); j++ {
+			a, b := &entries[i], &entries[j]
+			if a.StackName != b.StackName || a.Region != b.Region ||
+				a.DeployedBy == "" || b.DeployedBy == "" || a.DeployedBy == b.DeployedBy {
+				continue
+			}
+			if a.CreatedAt.Before(deletedOr(b.Deployment, now)) && b.CreatedAt.Before(deletedOr(a.Deployment, now)) {
+				a.Conflict = true
+				b.Conflict = true
+			}
+		}
+	}
+}
+
+// deletedOr returns when the deployment was deleted, or now if it is active
+func deletedOr(deployment Deployment, now time.Time) time.Time {
+	if deployment.DeletedAt != nil {
+		return *deployment.DeletedAt
+	}
+	return now
+}
+
+// DomainRegions returns the regions a domain has been deployed to, sorted
+func DomainRegions(deployments []Deployment, domain string) []string {
+	seen := make(map[string]bool)
+	var regions []string
+	for _, deployment := range deployments {
+		if deployment.Domain != domain || deployment.Region == "" || seen[deployment.Region] {
+			continue
+		}
+		seen[deployment.Region] = true
+		regions = append(regions, deployment.Region)
+	}
+	sort.Strings(regions)
+	return regions
+}
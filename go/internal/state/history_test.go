@@ -0,0 +1,113 @@
+package state
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMergeHistory(t *testing.T) {
+	created := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	deletedAt := created.Add(24 * time.Hour)
+
+	local := []Deployment{
+		{StackName: "genomics", StackID: "stack/genomics/1", Region: "us-east-1", CreatedAt: created, DeployedBy: "alice",
+			Snapshots: []Snapshot{{SnapshotID: "snap-1"}}},
+		{StackName: "climate", Region: "us-west-2", CreatedAt: created.Add(time.Hour)},
+	}
+	shared := []Deployment{
+		// A teammate deleted alice's stack
+		{StackName: "genomics", StackID: "stack/genomics/1", Region: "us-east-1", CreatedAt: created, DeployedBy: "alice", DeletedAt: &deletedAt},
+		// The local record predates DeployedBy, so it matches on name and time
+		{StackName: "climate", Region: "us-west-2", CreatedAt: created.Add(time.Hour), DeployedBy: "alice"},
+		{StackName: "chemistry", Region: "us-east-1", CreatedAt: created.Add(-time.Hour), DeployedBy: "bob"},
+	}
+
+	entries := MergeHistory(local, shared)
+	if len(entries) != 3 {
+		t.Fatalf("MergeHistory() = %+v, want 3 entries", entries)
+	}
+
+	chemistry, genomics, climate := entries[0], entries[1], entries[2]
+	if chemistry.StackName != "chemistry" || chemistry.Local || !chemistry.Shared {
+		t.Errorf("chemistry = %+v, want a shared-only entry first", chemistry)
+	}
+	if !genomics.Local || !genomics.Shared || genomics.Active() || len(genomics.Snapshots) != 1 {
+		t.Errorf("genomics = %+v, want the local record deleted by the shared one", genomics)
+	}
+	if !climate.Local || !climate.Shared || climate.DeployedBy != "alice" {
+		t.Errorf("climate = %+v, want the principal filled in from the shared record", climate)
+	}
+	for _, entry := range entries {
+		if entry.Conflict {
+			t.Errorf("%s flagged as a conflict", entry.StackName)
+		}
+	}
+}
+
+func TestMergeHistoryConflicts(t *testing.T) {
+	created := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	deletedAt := created.Add(time.Hour)
+
+	tests := []struct {
+		name   string
+		local  []Deployment
+		shared []Deployment
+		want   bool
+	}{
+		{
+			name:   "overlapping stacks by different principals",
+			local:  []Deployment{{StackName: "s", Region: "us-east-1", CreatedAt: created, DeployedBy: "alice"}},
+			shared: []Deployment{{StackName: "s", Region: "us-east-1", CreatedAt: created.Add(time.Minute), DeployedBy: "bob"}},
+			want:   true,
+		},
+		{
+			name:   "name reused after deletion",
+			local:  []Deployment{{StackName: "s", Region: "us-east-1", CreatedAt: created, DeployedBy: "alice", DeletedAt: &deletedAt}},
+			shared: []Deployment{{StackName: "s", Region: "us-east-1", CreatedAt: created.Add(2 * time.Hour), DeployedBy: "bob"}},
+		},
+		{
+			name:   "same principal",
+			shared: []Deployment{{StackName: "s", Region: "us-east-1", CreatedAt: created, DeployedBy: "alice"}, {StackName: "s", Region: "us-east-1", CreatedAt: created.Add(time.Minute), DeployedBy: "alice"}},
+		},
+		{
+			name:   "different regions",
+			shared: []Deployment{{StackName: "s", Region: "us-east-1", CreatedAt: created, DeployedBy: "alice"}, {StackName: "s", Region: "eu-west-1", CreatedAt: created, DeployedBy: "bob"}},
+		},
+		{
+			name:   "unknown principal",
+			local:  []Deployment{{StackName: "s", Region: "us-east-1", CreatedAt: created}},
+			shared: []Deployment{{StackName: "s", Region: "us-east-1", CreatedAt: created.Add(time.Minute), DeployedBy: "bob"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries := MergeHistory(tt.local, tt.shared)
+			if len(entries) != 2 {
+				t.Fatalf("MergeHistory() = %+v, want 2 entries", entries)
+			}
+			for _, entry := range entries {
+				if entry.Conflict != tt.want {
+					t.Errorf("%s by %q: Conflict = %v, want %v", entry.StackName, entry.DeployedBy, entry.Conflict, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestDomainRegions(t *testing.T) {
+	deployments := []Deployment{
+		{StackName: "genomics-a", Domain: "genomics", Region: "eu-west-1"},
+		{StackName: "climate", Domain: "climate", Region: "us-east-1"},
+		{StackName: "genomics-b", Domain: "genomics", Region: "eu-central-1"},
+		{StackName: "genomics-c", Domain: "genomics", Region: "eu-west-1"},
+	}
+
+	got := DomainRegions(deployments, "genomics")
+	if len(got) != 2 || got[0] != "eu-central-1" || got[1] != "eu-west-1" {
+		t.Errorf("DomainRegions(genomics) = %v, want [eu-central-1 eu-west-1]", got)
+	}
+	if got := DomainRegions(deployments, "chemistry"); len(got) != 0 {
+		t.Errorf("DomainRegions(chemistry) = %v, want none", got)
+	}
+}
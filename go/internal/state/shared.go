@@ -0,0 +1,399 @@
+package state
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+)
+
+// sharedWriteAttempts bounds the read-modify-write retries when another
+// writer changes an object between our read and our conditional write
+const sharedWriteAttempts = 5
+
+// SharedConfig locates a team's shared deployment state in S3
+type SharedConfig struct {
+	Bucket string `json:"bucket"`
+	Prefix string `json:"prefix,omitempty"`
+	// Region is the bucket's region, when it differs from the deployment region
+	Region string `json:"region,omitempty"`
+}
+
+// ParseSharedURI parses an s3://bucket/prefix location
+func ParseSharedURI(uri string) (SharedConfig, error) {
+	if !strings.HasPrefix(uri, "s3://") {
+		return SharedConfig{}, fmt.Errorf("shared state location %q must start with s3://", uri)
+	}
+	bucket, prefix, _ := strings.Cut(strings.TrimPrefix(uri, "s3://"), "/")
+	if bucket == "" {
+		return SharedConfig{}, fmt.Errorf("shared state location %q has no bucket", uri)
+	}
+	return SharedConfig{Bucket: bucket, Prefix: strings.Trim(prefix, "/")}, nil
+}
+
+// URI returns the s3://bucket/prefix location
+func (c SharedConfig) URI() string {
+	if c.Prefix == "" {
+		return "s3://" + c.Bucket
+	}
+	return "s3://" + c.Bucket + "/" + c.Prefix
+}
+
+// DefaultSharedConfigPath returns the location of the shared state configuration
+func DefaultSharedConfigPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to determine home directory: %w", err)
+	}
+	return filepath.Join(home, ".aws-research-wizard", "shared_state.json"), nil
+}
+
+// LoadSharedConfig reads the shared state configuration, returning nil when
+// no shared backend is configured
+func LoadSharedConfig(path string) (*SharedConfig, error) {
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read shared state configuration: %w", err)
+	}
+
+	var config SharedConfig
+	if err := json.Unmarshal(content, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse shared state configuration %s: %w", path, err)
+	}
+	if config.Bucket == "" {
+		return nil, fmt.Errorf("shared state configuration %s has no bucket", path)
+	}
+	return &config, nil
+}
+
+// SaveSharedConfig writes the shared state configuration
+func SaveSharedConfig(path string, config SharedConfig) error {
+	content, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal shared state configuration: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+	if err := os.WriteFile(path, content, 0600); err != nil {
+		return fmt.Errorf("failed to write shared state configuration: %w", err)
+	}
+	return nil
+}
+
+// Reservation claims a stack name in a region for one principal until the
+// stack is deleted
+type Reservation struct {
+	StackName  string    `json:"stack_name"`
+	Region     string    `json:"region"`
+	Owner      string    `json:"owner"`
+	ReservedAt time.Time `json:"reserved_at"`
+}
+
+// ReservationConflictError reports a stack name reserved by another principal
+type ReservationConflictError struct {
+	Reservation Reservation
+}
+
+func (e *ReservationConflictError) Error() string {
+	return fmt.Sprintf("stack name %s in %s is reserved by %s since %s",
+		e.Reservation.StackName, e.Reservation.Region, e.Reservation.Owner, e.Reservation.ReservedAt.Format(time.RFC3339))
+}
+
+// sharedS3API is the subset of the S3 API used by the shared store
+type sharedS3API interface {
+	GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+	PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+	DeleteObject(ctx context.Context, params *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error)
+	ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error)
+}
+
+// SharedStore keeps deployment history and stack name reservations in S3 so
+// a team sees each other's deployments. Every write is conditional on the
+// object's ETag, so concurrent writers never overwrite each other.
+//
+// Layout under the prefix:
+//
+//	history/<region>/<stack>.json  every deployment of the stack
+//	locks/<region>/<stack>.json    the reservation of the stack name
+type SharedStore struct {
+	api    sharedS3API
+	config SharedConfig
+}
+
+// NewSharedStore creates a shared store in the configured bucket
+func NewSharedStore(api sharedS3API, config SharedConfig) *SharedStore {
+	return &SharedStore{api: api, config: config}
+}
+
+// Location returns the s3:// location of the store
+func (s *SharedStore) Location() string {
+	return s.config.URI()
+}
+
+// Check confirms the bucket can be listed with the current credentials
+func (s *SharedStore) Check(ctx context.Context) error {
+	_, err := s.api.ListObjectsV2(ctx, &s3.ListObjectsV2Input{
+		Bucket:  aws.String(s.config.Bucket),
+		Prefix:  aws.String(s.config.Prefix),
+		MaxKeys: aws.Int32(1),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list %s: %w", s.Location(), err)
+	}
+	return nil
+}
+
+func (s *SharedStore) key(kind, stackName, region string) string {
+	return path.Join(s.config.Prefix, kind, region, stackName+".json")
+}
+
+// Reserve claims a stack name for reservation.Owner. Reserving a name the
+// owner already holds succeeds; a name held by anyone else fails with a
+// *ReservationConflictError.
+func (s *SharedStore) Reserve(ctx context.Context, reservation Reservation) error {
+	key := s.key("locks", reservation.StackName, reservation.Region)
+	content, err := json.MarshalIndent(reservation, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal reservation: %w", err)
+	}
+
+	for attempt := 0; attempt < sharedWriteAttempts; attempt++ {
+		err := s.put(ctx, key, content, "", true)
+		if err == nil {
+			return nil
+		}
+		if !isWriteConflict(err) {
+			return err
+		}
+
+		existing, _, err := s.reservation(ctx, key)
+		if err != nil {
+			return err
+		}
+		if existing == nil {
+			// Released between our write and our read; try again
+			continue
+		}
+		if existing.Owner != reservation.Owner {
+			return &ReservationConflictError{Reservation: *existing}
+		}
+		return nil
+	}
+	return fmt.Errorf("gave up reserving %s after %d conflicting writes", s.uri(key), sharedWriteAttempts)
+}
+
+// Reservation returns the reservation of a stack name, or nil when it is free
+func (s *SharedStore) Reservation(ctx context.Context, stackName, region string) (*Reservation, error) {
+	reservation, _, err := s.reservation(ctx, s.key("locks", stackName, region))
+	return reservation, err
+}
+
+// Release frees a stack name. A reservation replaced since it was read is
+// left alone, since it belongs to a newer deployment.
+func (s *SharedStore) Release(ctx context.Context, stackName, region string) error {
+	key := s.key("locks", stackName, region)
+	existing, etag, err := s.reservation(ctx, key)
+	if err != nil || existing == nil {
+		return err
+	}
+
+	_, err = s.api.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket:  aws.String(s.config.Bucket),
+		Key:     aws.String(key),
+		IfMatch: aws.String(etag),
+	})
+	if err != nil && !isWriteConflict(err) && !isNotFound(err) {
+		return fmt.Errorf("failed to delete %s: %w", s.uri(key), err)
+	}
+	return nil
+}
+
+// RecordDeployment adds a deployment to the stack's shared history, replacing
+// an active record by the same principal as Store.RecordDeployment does. A
+// record by another principal is kept, so the clash shows in the history.
+func (s *SharedStore) RecordDeployment(ctx context.Context, deployment Deployment) error {
+	return s.update(ctx, s.key("history", deployment.StackName, deployment.Region), func(state *stateFile) {
+		for i := len(state.Deployments) - 1; i >= 0; i-- {
+			existing := state.Deployments[i]
+			if existing.Active() && existing.DeployedBy == deployment.DeployedBy {
+				state.Deployments[i] = deployment
+				return
+			}
+		}
+		state.Deployments = append(state.Deployments, deployment)
+	})
+}
+
+// MarkDeleted records that a stack has been deleted, closing every active
+// record of it
+func (s *SharedStore) MarkDeleted(ctx context.Context, stackName, region string, deletedAt time.Time) error {
+	return s.update(ctx, s.key("history", stackName, region), func(state *stateFile) {
+		for i := range state.Deployments {
+			if state.Deployments[i].Active() {
+				state.Deployments[i].DeletedAt = &deletedAt
+			}
+		}
+	})
+}
+
+// Deployments returns every deployment in the shared history
+func (s *SharedStore) Deployments(ctx context.Context) ([]Deployment, error) {
+	var deployments []Deployment
+	paginator := s3.NewListObjectsV2Paginator(s.api, &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.config.Bucket),
+		Prefix: aws.String(path.Join(s.config.Prefix, "history") + "/"),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list %s: %w", s.Location(), err)
+		}
+		for _, object := range page.Contents {
+			state, _, err := s.load(ctx, aws.ToString(object.Key))
+			if err != nil {
+				return nil, err
+			}
+			deployments = append(deployments, state.Deployments...)
+		}
+	}
+	return deployments, nil
+}
+
+// update applies fn to a history object and writes it back only if nobody
+// else wrote it in between, retrying from a fresh read when they did
+func (s *SharedStore) update(ctx context.Context, key string, fn func(state *stateFile)) error {
+	for attempt := 0; attempt < sharedWriteAttempts; attempt++ {
+		state, etag, err := s.load(ctx, key)
+		if err != nil {
+			return err
+		}
+		fn(state)
+		state.Version = stateVersion
+
+		content, err := json.MarshalIndent(state, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal state: %w", err)
+		}
+		err = s.put(ctx, key, content, etag, etag == "")
+		if err == nil {
+			return nil
+		}
+		if !isWriteConflict(err) {
+			return err
+		}
+	}
+	return fmt.Errorf("gave up writing %s after %d conflicting writes", s.uri(key), sharedWriteAttempts)
+}
+
+// load reads a history object and its ETag; a missing object is empty with no ETag
+func (s *SharedStore) load(ctx context.Context, key string) (*stateFile, string, error) {
+	content, etag, err := s.get(ctx, key)
+	if err != nil || content == nil {
+		return &stateFile{Version: stateVersion}, "", err
+	}
+
+	var state stateFile
+	if err := json.Unmarshal(content, &state); err != nil {
+		return nil, "", fmt.Errorf("failed to parse %s: %w", s.uri(key), err)
+	}
+	if state.Version > stateVersion {
+		return nil, "", fmt.Errorf("%s has version %d; upgrade aws-research-wizard", s.uri(key), state.Version)
+	}
+	return &state, etag, nil
+}
+
+// reservation reads a lock object and its ETag, or nil when there is none
+func (s *SharedStore) reservation(ctx context.Context, key string) (*Reservation, string, error) {
+	content, etag, err := s.get(ctx, key)
+	if err != nil || content == nil {
+		return nil, "", err
+	}
+
+	var reservation Reservation
+	if err := json.Unmarshal(content, &reservation); err != nil {
+		return nil, "", fmt.Errorf("failed to parse %s: %w", s.uri(key), err)
+	}
+	return &reservation, etag, nil
+}
+
+// get reads an object, returning nil content when it does not exist
+func (s *SharedStore) get(ctx context.Context, key string) ([]byte, string, error) {
+	output, err := s.api.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.config.Bucket),
+		Key:    aws.String(key),
+	})
+	if isNotFound(err) {
+		return nil, "", nil
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read %s: %w", s.uri(key), err)
+	}
+	defer output.Body.Close()
+
+	content, err := io.ReadAll(output.Body)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read %s: %w", s.uri(key), err)
+	}
+	return content, aws.ToString(output.ETag), nil
+}
+
+// put writes an object only if its ETag still matches, or with create only if
+// it does not exist yet
+func (s *SharedStore) put(ctx context.Context, key string, content []byte, etag string, create bool) error {
+	input := &s3.PutObjectInput{
+		Bucket:      aws.String(s.config.Bucket),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(content),
+		ContentType: aws.String("application/json"),
+	}
+	if create {
+		input.IfNoneMatch = aws.String("*")
+	} else {
+		input.IfMatch = aws.String(etag)
+	}
+
+	if _, err := s.api.PutObject(ctx, input); err != nil {
+		return fmt.Errorf("failed to write %s: %w", s.uri(key), err)
+	}
+	return nil
+}
+
+func (s *SharedStore) uri(key string) string {
+	return "s3://" + s.config.Bucket + "/" + key
+}
+
+// isWriteConflict reports whether a conditional write lost to another writer
+func isWriteConflict(err error) bool {
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	switch apiErr.ErrorCode() {
+	case "PreconditionFailed", "ConditionalRequestConflict":
+		return true
+	}
+	return false
+}
+
+// isNotFound reports whether S3 rejected a request because the object is missing
+func isNotFound(err error) bool {
+	var noSuchKey *s3types.NoSuchKey
+	var apiErr smithy.APIError
+	return errors.As(err, &noSuchKey) || (errors.As(err, &apiErr) && apiErr.ErrorCode() == "NoSuchKey")
+}
@@ -0,0 +1,372 @@
+package state
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+)
+
+type fakeObject struct {
+	content []byte
+	etag    string
+}
+
+// fakeSharedS3 is an in-memory bucket honouring If-Match and If-None-Match
+type fakeSharedS3 struct {
+	mu      sync.Mutex
+	objects map[string]fakeObject
+	writes  int
+	// beforePut runs ahead of each write, outside the lock, so a test can
+	// slip in a competing write between a read and a conditional write
+	beforePut func(key string)
+	// err fails every request, as when offline
+	err error
+}
+
+func newFakeSharedS3() *fakeSharedS3 {
+	return &fakeSharedS3{objects: map[string]fakeObject{}}
+}
+
+var errPreconditionFailed = &smithy.GenericAPIError{Code: "PreconditionFailed", Message: "At least one of the pre-conditions you specified did not hold"}
+
+func (f *fakeSharedS3) GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	object, ok := f.objects[aws.ToString(params.Key)]
+	if !ok {
+		return nil, &s3types.NoSuchKey{}
+	}
+	return &s3.GetObjectOutput{Body: io.NopCloser(bytes.NewReader(object.content)), ETag: aws.String(object.etag)}, nil
+}
+
+func (f *fakeSharedS3) PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	key := aws.ToString(params.Key)
+	if f.beforePut != nil {
+		f.beforePut(key)
+	}
+	content, err := io.ReadAll(params.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	existing, exists := f.objects[key]
+	if params.IfNoneMatch != nil && exists {
+		return nil, errPreconditionFailed
+	}
+	if params.IfMatch != nil && (!exists || existing.etag != aws.ToString(params.IfMatch)) {
+		return nil, errPreconditionFailed
+	}
+	f.writes++
+	etag := fmt.Sprintf("\"%d\"", f.writes)
+	f.objects[key] = fakeObject{content: content, etag: etag}
+	return &s3.PutObjectOutput{ETag: aws.String(etag)}, nil
+}
+
+func (f *fakeSharedS3) DeleteObject(ctx context.Context, params *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	key := aws.ToString(params.Key)
+	if existing, exists := f.objects[key]; params.IfMatch != nil && exists && existing.etag != aws.ToString(params.IfMatch) {
+		return nil, errPreconditionFailed
+	}
+	delete(f.objects, key)
+	return &s3.DeleteObjectOutput{}, nil
+}
+
+func (f *fakeSharedS3) ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var keys []string
+	for key := range f.objects {
+		if strings.HasPrefix(key, aws.ToString(params.Prefix)) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	output := &s3.ListObjectsV2Output{}
+	for _, key := range keys {
+		output.Contents = append(output.Contents, s3types.Object{Key: aws.String(key)})
+	}
+	return output, nil
+}
+
+var sharedTestConfig = SharedConfig{Bucket: "team-state", Prefix: "wizard"}
+
+func TestParseSharedURI(t *testing.T) {
+	tests := []struct {
+		uri     string
+		want    SharedConfig
+		wantErr bool
+	}{
+		{uri: "s3://team-state/wizard", want: SharedConfig{Bucket: "team-state", Prefix: "wizard"}},
+		{uri: "s3://team-state/lab/wizard/", want: SharedConfig{Bucket: "team-state", Prefix: "lab/wizard"}},
+		{uri: "s3://team-state", want: SharedConfig{Bucket: "team-state"}},
+		{uri: "team-state/wizard", wantErr: true},
+		{uri: "s3:///wizard", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseSharedURI(tt.uri)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseSharedURI(%q) error = %v, wantErr %v", tt.uri, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseSharedURI(%q) = %+v, want %+v", tt.uri, got, tt.want)
+		}
+		if !tt.wantErr && got.URI() != strings.TrimSuffix(tt.uri, "/") {
+			t.Errorf("URI() = %q, want %q", got.URI(), tt.uri)
+		}
+	}
+}
+
+func TestSharedConfigFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "shared_state.json")
+
+	config, err := LoadSharedConfig(path)
+	if err != nil || config != nil {
+		t.Fatalf("LoadSharedConfig() of a missing file = %+v, %v; want nil, nil", config, err)
+	}
+
+	want := SharedConfig{Bucket: "team-state", Prefix: "wizard", Region: "us-west-2"}
+	if err := SaveSharedConfig(path, want); err != nil {
+		t.Fatalf("SaveSharedConfig() error = %v", err)
+	}
+	config, err = LoadSharedConfig(path)
+	if err != nil || config == nil || *config != want {
+		t.Errorf("LoadSharedConfig() = %+v, %v; want %+v", config, err, want)
+	}
+}
+
+func TestSharedStoreReserve(t *testing.T) {
+	ctx := context.Background()
+	api := newFakeSharedS3()
+	store := NewSharedStore(api, sharedTestConfig)
+	reservedAt := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	alice := Reservation{StackName: "research-wizard-genomics", Region: "us-east-1", Owner: "arn:aws:iam::123456789012:user/alice", ReservedAt: reservedAt}
+
+	if err := store.Reserve(ctx, alice); err != nil {
+		t.Fatalf("Reserve() error = %v", err)
+	}
+	if _, ok := api.objects["wizard/locks/us-east-1/research-wizard-genomics.json"]; !ok {
+		t.Errorf("no lock object written, have %v", api.objects)
+	}
+
+	// Redeploying under the same principal keeps the reservation
+	if err := store.Reserve(ctx, alice); err != nil {
+		t.Errorf("Reserve() by the owner error = %v", err)
+	}
+
+	bob := alice
+	bob.Owner = "arn:aws:iam::123456789012:user/bob"
+	var conflict *ReservationConflictError
+	if err := store.Reserve(ctx, bob); !errors.As(err, &conflict) {
+		t.Fatalf("Reserve() by another principal error = %v, want a conflict", err)
+	}
+	if conflict.Reservation.Owner != alice.Owner || !strings.Contains(conflict.Error(), "user/alice") {
+		t.Errorf("conflict = %v", conflict)
+	}
+
+	// The same name in another region is a different stack
+	bob.Region = "eu-west-1"
+	if err := store.Reserve(ctx, bob); err != nil {
+		t.Errorf("Reserve() in another region error = %v", err)
+	}
+
+	if err := store.Release(ctx, alice.StackName, alice.Region); err != nil {
+		t.Fatalf("Release() error = %v", err)
+	}
+	if reservation, err := store.Reservation(ctx, alice.StackName, alice.Region); err != nil || reservation != nil {
+		t.Errorf("Reservation() after release = %+v, %v", reservation, err)
+	}
+	bob.Region = alice.Region
+	if err := store.Reserve(ctx, bob); err != nil {
+		t.Errorf("Reserve() of a released name error = %v", err)
+	}
+
+	if err := store.Release(ctx, "never-reserved", "us-east-1"); err != nil {
+		t.Errorf("Release() of a free name error = %v", err)
+	}
+}
+
+func TestSharedStoreReserveRace(t *testing.T) {
+	ctx := context.Background()
+	api := newFakeSharedS3()
+	store := NewSharedStore(api, sharedTestConfig)
+
+	// Every principal reads the name as free before any of them writes
+	const principals = 8
+	var ready sync.WaitGroup
+	ready.Add(principals)
+	api.beforePut = func(string) {
+		ready.Done()
+		ready.Wait()
+	}
+
+	errs := make([]error, principals)
+	var done sync.WaitGroup
+	for i := range errs {
+		done.Add(1)
+		go func(i int) {
+			defer done.Done()
+			errs[i] = store.Reserve(ctx, Reservation{StackName: "shared-name", Region: "us-east-1", Owner: fmt.Sprintf("user-%d", i)})
+		}(i)
+	}
+	done.Wait()
+
+	var winners []int
+	for i, err := range errs {
+		var conflict *ReservationConflictError
+		switch {
+		case err == nil:
+			winners = append(winners, i)
+		case !errors.As(err, &conflict):
+			t.Errorf("user-%d: Reserve() error = %v, want a conflict", i, err)
+		}
+	}
+	if len(winners) != 1 {
+		t.Fatalf("%d principals reserved the name, want exactly 1", len(winners))
+	}
+
+	reservation, err := store.Reservation(ctx, "shared-name", "us-east-1")
+	if err != nil || reservation == nil || reservation.Owner != fmt.Sprintf("user-%d", winners[0]) {
+		t.Errorf("Reservation() = %+v, %v; want user-%d", reservation, err, winners[0])
+	}
+}
+
+func TestSharedStoreHistory(t *testing.T) {
+	ctx := context.Background()
+	store := NewSharedStore(newFakeSharedS3(), sharedTestConfig)
+	created := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	alice := Deployment{StackName: "s", Region: "us-east-1", Domain: "genomics", CreatedAt: created, DeployedBy: "alice"}
+	if err := store.RecordDeployment(ctx, alice); err != nil {
+		t.Fatalf("RecordDeployment() error = %v", err)
+	}
+	// Recording the same principal's stack again replaces its record
+	alice.InstanceType = "r6i.4xlarge"
+	if err := store.RecordDeployment(ctx, alice); err != nil {
+		t.Fatal(err)
+	}
+	// Another principal's record of the same name is kept alongside
+	if err := store.RecordDeployment(ctx, Deployment{StackName: "s", Region: "us-east-1", CreatedAt: created.Add(time.Hour), DeployedBy: "bob"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.RecordDeployment(ctx, Deployment{StackName: "other", Region: "eu-west-1", CreatedAt: created}); err != nil {
+		t.Fatal(err)
+	}
+
+	deletedAt := created.Add(2 * time.Hour)
+	if err := store.MarkDeleted(ctx, "s", "us-east-1", deletedAt); err != nil {
+		t.Fatalf("MarkDeleted() error = %v", err)
+	}
+
+	deployments, err := store.Deployments(ctx)
+	if err != nil {
+		t.Fatalf("Deployments() error = %v", err)
+	}
+	if len(deployments) != 3 {
+		t.Fatalf("Deployments() = %+v, want 3 records", deployments)
+	}
+	for _, deployment := range deployments {
+		if deployment.StackName == "s" && (deployment.DeletedAt == nil || !deployment.DeletedAt.Equal(deletedAt)) {
+			t.Errorf("%s by %s not marked deleted", deployment.StackName, deployment.DeployedBy)
+		}
+	}
+	if deployments[0].DeployedBy != "" || deployments[1].InstanceType != "r6i.4xlarge" {
+		t.Errorf("unexpected records: %+v", deployments)
+	}
+}
+
+func TestSharedStoreHistoryWriteRace(t *testing.T) {
+	ctx := context.Background()
+	api := newFakeSharedS3()
+	store := NewSharedStore(api, sharedTestConfig)
+	teammate := NewSharedStore(api, sharedTestConfig)
+	created := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	// A teammate's write lands between our read and our write
+	api.beforePut = func(string) {
+		api.beforePut = nil
+		if err := teammate.RecordDeployment(ctx, Deployment{StackName: "s", Region: "us-east-1", CreatedAt: created, DeployedBy: "bob"}); err != nil {
+			t.Errorf("teammate RecordDeployment() error = %v", err)
+		}
+	}
+	if err := store.RecordDeployment(ctx, Deployment{StackName: "s", Region: "us-east-1", CreatedAt: created, DeployedBy: "alice"}); err != nil {
+		t.Fatalf("RecordDeployment() error = %v", err)
+	}
+
+	deployments, err := store.Deployments(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var principals []string
+	for _, deployment := range deployments {
+		principals = append(principals, deployment.DeployedBy)
+	}
+	if strings.Join(principals, ",") != "bob,alice" {
+		t.Errorf("principals = %v, want the teammate's record kept and ours retried after it", principals)
+	}
+
+	// A writer that always loses eventually gives up rather than overwriting
+	api.beforePut = func(key string) {
+		api.mu.Lock()
+		object := api.objects[key]
+		object.etag += "-changed"
+		api.objects[key] = object
+		api.mu.Unlock()
+	}
+	err = store.MarkDeleted(ctx, "s", "us-east-1", created.Add(time.Hour))
+	if err == nil || !strings.Contains(err.Error(), "conflicting writes") {
+		t.Errorf("MarkDeleted() under constant contention error = %v", err)
+	}
+}
+
+func TestSharedStoreOffline(t *testing.T) {
+	ctx := context.Background()
+	api := newFakeSharedS3()
+	api.err = errors.New("dial tcp: lookup team-state.s3.amazonaws.com: no such host")
+	store := NewSharedStore(api, sharedTestConfig)
+
+	err := store.Reserve(ctx, Reservation{StackName: "s", Region: "us-east-1", Owner: "alice"})
+	var conflict *ReservationConflictError
+	if err == nil || errors.As(err, &conflict) {
+		t.Errorf("Reserve() offline error = %v, want a plain error", err)
+	}
+	if err := store.RecordDeployment(ctx, Deployment{StackName: "s", Region: "us-east-1"}); err == nil {
+		t.Error("RecordDeployment() offline succeeded")
+	}
+	if _, err := store.Deployments(ctx); err == nil {
+		t.Error("Deployments() offline succeeded")
+	}
+	if err := store.Check(ctx); err == nil {
+		t.Error("Check() offline succeeded")
+	}
+}
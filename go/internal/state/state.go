@@ -0,0 +1,294 @@
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// stateVersion is the current layout of the deployments file
+const stateVersion = 1
+
+// Snapshot records an EBS snapshot taken of a research environment
+type Snapshot struct {
+	SnapshotID string    `json:"snapshot_id"`
+	VolumeID   string    `json:"volume_id"`
+	DeviceName string    `json:"device_name,omitempty"`
+	SizeGB     int32     `json:"size_gb"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// Closure summarizes a deployment when it was torn down
+type Closure struct {
+	ClosedAt            time.Time `json:"closed_at"`
+	UptimeHours         float64   `json:"uptime_hours"`
+	EstimatedCost       float64   `json:"estimated_cost"`
+	RetainedMonthlyCost float64   `json:"retained_monthly_cost,omitempty"`
+	Deleted             []string  `json:"deleted,omitempty"`
+	Retained            []string  `json:"retained,omitempty"`
+}
+
+// CheckResult records the outcome of one post-deployment check
+type CheckResult struct {
+	Name     string `json:"name"`
+	Passed   bool   `json:"passed"`
+	Critical bool   `json:"critical"`
+	ExitCode int    `json:"exit_code"`
+	Detail   string `json:"detail,omitempty"`
+}
+
+// Validation records a run of a domain's post-deployment checks
+type Validation struct {
+	RanAt      time.Time     `json:"ran_at"`
+	InstanceID string        `json:"instance_id,omitempty"`
+	Passed     bool          `json:"passed"`
+	Checks     []CheckResult `json:"checks"`
+}
+
+// Deployment records a research environment created by the wizard
+type Deployment struct {
+	StackName string `json:"stack_name"`
+	StackID   string `json:"stack_id,omitempty"`
+	Domain    string `json:"domain"`
+	// RequestedDomain is the deprecated domain asked for when Domain, its
+	// replacement, was deployed instead
+	RequestedDomain string    `json:"requested_domain,omitempty"`
+	Region          string    `json:"region"`
+	InstanceType    string    `json:"instance_type,omitempty"`
+	CreatedAt       time.Time `json:"created_at"`
+	// DeployedBy is the ARN of the identity that created the stack
+	DeployedBy string `json:"deployed_by,omitempty"`
+	// AssumedRole is the role assumed to deploy into a delegated account, if any
+	AssumedRole string     `json:"assumed_role,omitempty"`
+	DeletedAt   *time.Time `json:"deleted_at,omitempty"`
+	Snapshots   []Snapshot `json:"snapshots,omitempty"`
+	Closure     *Closure   `json:"closure,omitempty"`
+	// Validations are appended each time the domain's checks run
+	Validations []Validation `json:"validations,omitempty"`
+}
+
+// Active reports whether the deployment has not been deleted
+func (d *Deployment) Active() bool {
+	return d.DeletedAt == nil
+}
+
+// stateFile is the on-disk layout of the deployments file
+type stateFile struct {
+	Version     int          `json:"version"`
+	Deployments []Deployment `json:"deployments"`
+}
+
+// Store persists deployment records to a local JSON file
+type Store struct {
+	path string
+	mu   sync.Mutex
+}
+
+// DefaultPath returns the location of the local deployments file
+func DefaultPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to determine home directory: %w", err)
+	}
+	return filepath.Join(home, ".aws-research-wizard", "deployments.json"), nil
+}
+
+// NewStore creates a store backed by the given file
+func NewStore(path string) *Store {
+	return &Store{path: path}
+}
+
+// OpenDefaultStore creates a store backed by the default deployments file
+func OpenDefaultStore() (*Store, error) {
+	path, err := DefaultPath()
+	if err != nil {
+		return nil, err
+	}
+	return NewStore(path), nil
+}
+
+// Path returns the file backing the store
+func (s *Store) Path() string {
+	return s.path
+}
+
+// Deployments returns all recorded deployments
+func (s *Store) Deployments() ([]Deployment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	return state.Deployments, nil
+}
+
+// Get returns the most recent record for a stack in a region
+func (s *Store) Get(stackName, region string) (*Deployment, error) {
+	deployments, err := s.Deployments()
+	if err != nil {
+		return nil, err
+	}
+
+	if i := findDeployment(deployments, stackName, region); i >= 0 {
+		return &deployments[i], nil
+	}
+	return nil, fmt.Errorf("no recorded deployment for stack %s in %s", stackName, region)
+}
+
+// RecordDeployment adds a deployment, replacing an earlier record of a redeployed stack
+func (s *Store) RecordDeployment(deployment Deployment) error {
+	return s.update(func(state *stateFile) error {
+		if i := findDeployment(state.Deployments, deployment.StackName, deployment.Region); i >= 0 && state.Deployments[i].Active() {
+			deployment.Snapshots = append(state.Deployments[i].Snapshots, deployment.Snapshots...)
+			deployment.Validations = append(state.Deployments[i].Validations, deployment.Validations...)
+			state.Deployments[i] = deployment
+			return nil
+		}
+		state.Deployments = append(state.Deployments, deployment)
+		return nil
+	})
+}
+
+// MarkDeleted records that a stack has been deleted
+func (s *Store) MarkDeleted(stackName, region string, deletedAt time.Time) error {
+	return s.update(func(state *stateFile) error {
+		i := findDeployment(state.Deployments, stackName, region)
+		if i < 0 {
+			return nil
+		}
+		state.Deployments[i].DeletedAt = &deletedAt
+		return nil
+	})
+}
+
+// RecordClosure marks a stack deleted with a summary of what it cost and left behind,
+// creating a minimal record for stacks deployed before the state file existed
+func (s *Store) RecordClosure(stackName, region string, closure Closure) error {
+	return s.update(func(state *stateFile) error {
+		i := findDeployment(state.Deployments, stackName, region)
+		if i < 0 {
+			state.Deployments = append(state.Deployments, Deployment{
+				StackName: stackName,
+				Region:    region,
+			})
+			i = len(state.Deployments) - 1
+		}
+		closedAt := closure.ClosedAt
+		state.Deployments[i].DeletedAt = &closedAt
+		state.Deployments[i].Closure = &closure
+		return nil
+	})
+}
+
+// AddSnapshot records a snapshot against a stack, creating a minimal record for
+// stacks deployed before the state file existed
+func (s *Store) AddSnapshot(stackName, region string, snapshot Snapshot) error {
+	return s.update(func(state *stateFile) error {
+		i := findDeployment(state.Deployments, stackName, region)
+		if i < 0 {
+			state.Deployments = append(state.Deployments, Deployment{
+				StackName: stackName,
+				Region:    region,
+				CreatedAt: snapshot.CreatedAt,
+			})
+			i = len(state.Deployments) - 1
+		}
+		state.Deployments[i].Snapshots = append(state.Deployments[i].Snapshots, snapshot)
+		return nil
+	})
+}
+
+// AddValidation appends a run of post-deployment checks to a stack's history,
+// creating a minimal record for stacks deployed before the state file existed
+func (s *Store) AddValidation(stackName, region string, validation Validation) error {
+	return s.update(func(state *stateFile) error {
+		i := findDeployment(state.Deployments, stackName, region)
+		if i < 0 {
+			state.Deployments = append(state.Deployments, Deployment{
+				StackName: stackName,
+				Region:    region,
+				CreatedAt: validation.RanAt,
+			})
+			i = len(state.Deployments) - 1
+		}
+		state.Deployments[i].Validations = append(state.Deployments[i].Validations, validation)
+		return nil
+	})
+}
+
+// findDeployment returns the index of the latest record for a stack, or -1
+func findDeployment(deployments []Deployment, stackName, region string) int {
+	for i := len(deployments) - 1; i >= 0; i-- {
+		if deployments[i].StackName == stackName && deployments[i].Region == region {
+			return i
+		}
+	}
+	return -1
+}
+
+func (s *Store) update(fn func(state *stateFile) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state, err := s.load()
+	if err != nil {
+		return err
+	}
+
+	if err := fn(state); err != nil {
+		return err
+	}
+
+	return s.save(state)
+}
+
+func (s *Store) load() (*stateFile, error) {
+	content, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return &stateFile{Version: stateVersion}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state file: %w", err)
+	}
+
+	var state stateFile
+	if err := json.Unmarshal(content, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse state file %s: %w", s.path, err)
+	}
+
+	if state.Version > stateVersion {
+		return nil, fmt.Errorf("state file %s has version %d; upgrade aws-research-wizard", s.path, state.Version)
+	}
+
+	return &state, nil
+}
+
+func (s *Store) save(state *stateFile) error {
+	state.Version = stateVersion
+
+	content, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal state: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+
+	tmpPath := s.path + ".tmp"
+	if err := os.WriteFile(tmpPath, content, 0600); err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, s.path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to replace state file: %w", err)
+	}
+
+	return nil
+}
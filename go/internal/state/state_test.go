@@ -0,0 +1,230 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+	return NewStore(filepath.Join(t.TempDir(), "state", "deployments.json"))
+}
+
+func TestStoreEmpty(t *testing.T) {
+	store := newTestStore(t)
+
+	deployments, err := store.Deployments()
+	if err != nil {
+		t.Fatalf("Deployments failed: %v", err)
+	}
+	if len(deployments) != 0 {
+		t.Errorf("Expected no deployments, got %d", len(deployments))
+	}
+
+	if _, err := store.Get("missing", "us-east-1"); err == nil {
+		t.Error("Expected error for unknown stack")
+	}
+}
+
+func TestStoreRecordAndDelete(t *testing.T) {
+	store := newTestStore(t)
+	created := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	err := store.RecordDeployment(Deployment{
+		StackName:    "research-wizard-genomics",
+		Domain:       "genomics",
+		Region:       "us-east-1",
+		InstanceType: "r6i.4xlarge",
+		CreatedAt:    created,
+	})
+	if err != nil {
+		t.Fatalf("RecordDeployment failed: %v", err)
+	}
+
+	// Same stack name in another region is a different deployment
+	if err := store.RecordDeployment(Deployment{StackName: "research-wizard-genomics", Region: "eu-west-1", CreatedAt: created}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.MarkDeleted("research-wizard-genomics", "us-east-1", created.Add(time.Hour)); err != nil {
+		t.Fatalf("MarkDeleted failed: %v", err)
+	}
+
+	deployment, err := store.Get("research-wizard-genomics", "us-east-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deployment.Active() {
+		t.Error("Expected deployment to be marked deleted")
+	}
+
+	other, err := store.Get("research-wizard-genomics", "eu-west-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !other.Active() {
+		t.Error("Deleting one region should not affect another")
+	}
+
+	// Redeploying after deletion keeps the history
+	if err := store.RecordDeployment(Deployment{StackName: "research-wizard-genomics", Region: "us-east-1", CreatedAt: created.Add(2 * time.Hour)}); err != nil {
+		t.Fatal(err)
+	}
+
+	deployments, err := store.Deployments()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deployments) != 3 {
+		t.Errorf("Expected 3 records, got %d", len(deployments))
+	}
+
+	latest, err := store.Get("research-wizard-genomics", "us-east-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !latest.Active() {
+		t.Error("Expected latest record to be active")
+	}
+}
+
+func TestStoreAddSnapshot(t *testing.T) {
+	store := newTestStore(t)
+	now := time.Now().UTC()
+
+	// Stacks deployed before the state file existed still get a record
+	snapshot := Snapshot{SnapshotID: "snap-1", VolumeID: "vol-1", SizeGB: 100, CreatedAt: now}
+	if err := store.AddSnapshot("legacy-stack", "us-east-1", snapshot); err != nil {
+		t.Fatalf("AddSnapshot failed: %v", err)
+	}
+	if err := store.AddSnapshot("legacy-stack", "us-east-1", Snapshot{SnapshotID: "snap-2", CreatedAt: now}); err != nil {
+		t.Fatal(err)
+	}
+
+	deployment, err := store.Get("legacy-stack", "us-east-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deployment.Snapshots) != 2 || deployment.Snapshots[0].SnapshotID != "snap-1" {
+		t.Errorf("Unexpected snapshots: %+v", deployment.Snapshots)
+	}
+
+	// Re-recording an active deployment keeps its snapshots
+	if err := store.RecordDeployment(Deployment{StackName: "legacy-stack", Region: "us-east-1", Domain: "climate"}); err != nil {
+		t.Fatal(err)
+	}
+	deployment, err = store.Get("legacy-stack", "us-east-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deployment.Domain != "climate" || len(deployment.Snapshots) != 2 {
+		t.Errorf("Unexpected deployment after update: %+v", deployment)
+	}
+}
+
+func TestStoreAddValidation(t *testing.T) {
+	store := newTestStore(t)
+	created := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	if err := store.RecordDeployment(Deployment{StackName: "s", Region: "us-east-1", Domain: "genomics", CreatedAt: created}); err != nil {
+		t.Fatal(err)
+	}
+
+	failed := Validation{
+		RanAt:      created.Add(10 * time.Minute),
+		InstanceID: "i-1",
+		Checks: []CheckResult{
+			{Name: "nextflow", Passed: true, Critical: true},
+			{Name: "torch", Critical: true, ExitCode: 1, Detail: "exit code 1, expected 0"},
+		},
+	}
+	passed := Validation{RanAt: created.Add(time.Hour), InstanceID: "i-1", Passed: true}
+	for _, validation := range []Validation{failed, passed} {
+		if err := store.AddValidation("s", "us-east-1", validation); err != nil {
+			t.Fatalf("AddValidation failed: %v", err)
+		}
+	}
+
+	// Redeploying the stack keeps its history
+	if err := store.RecordDeployment(Deployment{StackName: "s", Region: "us-east-1", Domain: "genomics", CreatedAt: created}); err != nil {
+		t.Fatal(err)
+	}
+
+	deployment, err := store.Get("s", "us-east-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deployment.Validations) != 2 || deployment.Validations[0].Passed || !deployment.Validations[1].Passed {
+		t.Fatalf("Unexpected validations: %+v", deployment.Validations)
+	}
+	if checks := deployment.Validations[0].Checks; len(checks) != 2 || checks[1].Detail != "exit code 1, expected 0" {
+		t.Errorf("Unexpected checks: %+v", checks)
+	}
+
+	if err := store.AddValidation("legacy-stack", "us-east-1", passed); err != nil {
+		t.Fatal(err)
+	}
+	if legacy, err := store.Get("legacy-stack", "us-east-1"); err != nil || len(legacy.Validations) != 1 {
+		t.Errorf("Unexpected legacy record: %+v, %v", legacy, err)
+	}
+}
+
+func TestStoreRecordClosure(t *testing.T) {
+	store := newTestStore(t)
+	created := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	if err := store.RecordDeployment(Deployment{StackName: "s", Region: "us-east-1", CreatedAt: created}); err != nil {
+		t.Fatal(err)
+	}
+
+	closure := Closure{
+		ClosedAt:      created.Add(48 * time.Hour),
+		UptimeHours:   48,
+		EstimatedCost: 12.5,
+		Deleted:       []string{"instance i-1"},
+		Retained:      []string{"snapshot snap-1"},
+	}
+	if err := store.RecordClosure("s", "us-east-1", closure); err != nil {
+		t.Fatalf("RecordClosure failed: %v", err)
+	}
+
+	deployment, err := store.Get("s", "us-east-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deployment.Active() || !deployment.DeletedAt.Equal(closure.ClosedAt) {
+		t.Errorf("Expected deployment deleted at %v, got %v", closure.ClosedAt, deployment.DeletedAt)
+	}
+	if deployment.Closure == nil || deployment.Closure.EstimatedCost != 12.5 || len(deployment.Closure.Retained) != 1 {
+		t.Errorf("Unexpected closure: %+v", deployment.Closure)
+	}
+}
+
+func TestStoreRejectsNewerVersion(t *testing.T) {
+	store := newTestStore(t)
+	if err := os.MkdirAll(filepath.Dir(store.Path()), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(store.Path(), []byte(`{"version": 99, "deployments": []}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := store.Deployments(); err == nil {
+		t.Error("Expected error for newer state file version")
+	}
+}
+
+func TestStoreFilePermissions(t *testing.T) {
+	store := newTestStore(t)
+	if err := store.RecordDeployment(Deployment{StackName: "s", Region: "us-east-1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(store.Path())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Expected mode 0600, got %o", info.Mode().Perm())
+	}
+}